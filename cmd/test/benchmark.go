@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	glog "github.com/Laisky/go-utils/v6/log"
+	"github.com/Laisky/zap"
+	"golang.org/x/sync/errgroup"
+
+	cfg "github.com/songquanpeng/one-api/common/config"
+)
+
+const (
+	benchmarkResultsFile      = "benchmark_results.json"
+	defaultBenchmarkMaxTokens = 512
+)
+
+// Prompt is one benchmark prompt, read from a line of BENCHMARK_PROMPTS_FILE.
+type Prompt struct {
+	ID        string `json:"id"`
+	Prompt    string `json:"prompt"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+}
+
+// BenchmarkClient sends benchmark prompts to a One-API instance.
+type BenchmarkClient struct {
+	HTTP        *http.Client
+	BaseURL     string
+	Token       string
+	Concurrency int
+}
+
+// BenchmarkResult aggregates the measurements of one model over the prompt set.
+type BenchmarkResult struct {
+	Model            string   `json:"model"`
+	Prompts          int      `json:"prompts"`
+	Failures         int      `json:"failures"`
+	AvgTTFTMs        float64  `json:"avg_ttft_ms"`
+	TokensPerSec     float64  `json:"tokens_per_sec"`
+	CompletionTokens int      `json:"completion_tokens"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+// benchmarkReport is the content of benchmark_results.json.
+type benchmarkReport struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	Results     []BenchmarkResult `json:"results"`
+}
+
+// promptMeasurement is the outcome of a single streamed prompt.
+type promptMeasurement struct {
+	ttft             time.Duration
+	total            time.Duration
+	completionTokens int
+	err              error
+}
+
+// benchmarkRegression describes a metric that fell behind the baseline.
+type benchmarkRegression struct {
+	Model    string
+	Metric   string
+	Baseline float64
+	Current  float64
+}
+
+// benchmark measures time to first token and throughput of every configured
+// model, prints them as a Markdown table, saves them to benchmark_results.json
+// and reports regressions against BENCHMARK_BASELINE_FILE.
+func benchmark(ctx context.Context, logger glog.Logger) error {
+	conf, err := loadConfig()
+	if err != nil {
+		return errors.Wrap(err, "load config")
+	}
+	if cfg.BenchmarkPromptsFile == "" {
+		return errors.New("BENCHMARK_PROMPTS_FILE must be set")
+	}
+	if cfg.BenchmarkConcurrency <= 0 {
+		return errors.Errorf("BENCHMARK_CONCURRENCY must be positive, got %d", cfg.BenchmarkConcurrency)
+	}
+	prompts, err := loadPrompts(cfg.BenchmarkPromptsFile)
+	if err != nil {
+		return errors.Wrap(err, "load prompts")
+	}
+
+	client := &BenchmarkClient{
+		HTTP:        &http.Client{Timeout: 120 * time.Second},
+		BaseURL:     conf.APIBase,
+		Token:       conf.Token,
+		Concurrency: cfg.BenchmarkConcurrency,
+	}
+	logger.Info("starting benchmark",
+		zap.String("base_url", conf.APIBase),
+		zap.Int("model_count", len(conf.Models)),
+		zap.Int("prompt_count", len(prompts)),
+		zap.Int("concurrency", client.Concurrency),
+	)
+
+	results := make([]BenchmarkResult, 0, len(conf.Models))
+	for _, modelName := range conf.Models {
+		result := BenchmarkModel(ctx, client, modelName, prompts)
+		logger.Info("model benchmarked",
+			zap.String("model", result.Model),
+			zap.Float64("avg_ttft_ms", result.AvgTTFTMs),
+			zap.Float64("tokens_per_sec", result.TokensPerSec),
+			zap.Int("failures", result.Failures),
+		)
+		results = append(results, result)
+	}
+	sortByThroughput(results)
+
+	if err := saveBenchmarkResults(benchmarkResultsFile, results); err != nil {
+		return errors.Wrap(err, "save benchmark results")
+	}
+
+	baseline, err := loadBenchmarkBaseline(cfg.BenchmarkBaselineFile)
+	if err != nil {
+		return errors.Wrap(err, "load benchmark baseline")
+	}
+	fmt.Println()
+	fmt.Print(renderBenchmarkMarkdown(results, baseline))
+
+	regressions := compareWithBaseline(results, baseline, cfg.BenchmarkRegressionThreshold)
+	if len(regressions) > 0 {
+		fmt.Println()
+		fmt.Println("Regressions:")
+		for _, r := range regressions {
+			fmt.Printf("- %s %s: %.1f -> %.1f\n", r.Model, r.Metric, r.Baseline, r.Current)
+		}
+		return errors.Errorf("%d benchmark regressions against %s", len(regressions), cfg.BenchmarkBaselineFile)
+	}
+	return nil
+}
+
+// loadPrompts reads one Prompt per non-blank line of a JSONL file.
+func loadPrompts(path string) ([]Prompt, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open %s", path)
+	}
+	defer file.Close()
+
+	var prompts []Prompt
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxResponseBodySize)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var prompt Prompt
+		if err := json.Unmarshal([]byte(text), &prompt); err != nil {
+			return nil, errors.Wrapf(err, "parse line %d", line)
+		}
+		if strings.TrimSpace(prompt.Prompt) == "" {
+			return nil, errors.Errorf("line %d has an empty prompt", line)
+		}
+		if prompt.ID == "" {
+			prompt.ID = fmt.Sprintf("prompt-%d", line)
+		}
+		prompts = append(prompts, prompt)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "read %s", path)
+	}
+	if len(prompts) == 0 {
+		return nil, errors.Errorf("no prompts in %s", path)
+	}
+	return prompts, nil
+}
+
+// BenchmarkModel streams every prompt to modelName, at most client.Concurrency
+// at a time, and aggregates the time to first token and the completion tokens
+// per second after it. Failed prompts are counted but not measured.
+func BenchmarkModel(ctx context.Context, client *BenchmarkClient, modelName string, prompts []Prompt) BenchmarkResult {
+	measurements := make([]promptMeasurement, len(prompts))
+	grp, grpCtx := errgroup.WithContext(ctx)
+	grp.SetLimit(max(client.Concurrency, 1))
+	for i, prompt := range prompts {
+		grp.Go(func() error {
+			measurements[i] = client.measurePrompt(grpCtx, modelName, prompt)
+			return nil
+		})
+	}
+	_ = grp.Wait()
+
+	result := BenchmarkResult{Model: modelName, Prompts: len(prompts)}
+	var ttftTotal time.Duration
+	var tokensPerSecTotal float64
+	measured := 0
+	for i, m := range measurements {
+		if m.err != nil {
+			result.Failures++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", prompts[i].ID, shorten(m.err.Error(), 200)))
+			continue
+		}
+		measured++
+		ttftTotal += m.ttft
+		result.CompletionTokens += m.completionTokens
+		if generation := m.total - m.ttft; generation > 0 {
+			tokensPerSecTotal += float64(m.completionTokens) / generation.Seconds()
+		}
+	}
+	if measured > 0 {
+		result.AvgTTFTMs = float64(ttftTotal.Microseconds()) / 1000 / float64(measured)
+		result.TokensPerSec = tokensPerSecTotal / float64(measured)
+	}
+	return result
+}
+
+// measurePrompt sends prompt as a streaming chat completion and times the
+// first content chunk and the end of the stream.
+func (b *BenchmarkClient) measurePrompt(ctx context.Context, modelName string, prompt Prompt) (m promptMeasurement) {
+	maxTokens := prompt.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultBenchmarkMaxTokens
+	}
+	payload, err := json.Marshal(map[string]any{
+		"model":          modelName,
+		"messages":       []map[string]string{{"role": "user", "content": prompt.Prompt}},
+		"max_tokens":     maxTokens,
+		"stream":         true,
+		"stream_options": map[string]bool{"include_usage": true},
+	})
+	if err != nil {
+		m.err = errors.Wrap(err, "marshal payload")
+		return m
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.BaseURL+"/v1/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		m.err = errors.Wrap(err, "build request")
+		return m
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+b.Token)
+	req.Header.Set("User-Agent", "oneapi-test-harness/1.0")
+
+	start := time.Now()
+	resp, err := b.HTTP.Do(req)
+	if err != nil {
+		m.err = errors.Wrap(err, "do request")
+		return m
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedBodyBytes))
+		m.err = errors.Errorf("status %s: %s", resp.Status, snippet(body))
+		return m
+	}
+
+	contentChunks := 0
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxResponseBodySize)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content          string `json:"content"`
+					ReasoningContent string `json:"reasoning_content"`
+					Reasoning        string `json:"reasoning"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct {
+				CompletionTokens int `json:"completion_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		for _, choice := range chunk.Choices {
+			delta := choice.Delta
+			if delta.Content == "" && delta.ReasoningContent == "" && delta.Reasoning == "" {
+				continue
+			}
+			if contentChunks == 0 {
+				m.ttft = time.Since(start)
+			}
+			contentChunks++
+		}
+		if chunk.Usage != nil && chunk.Usage.CompletionTokens > 0 {
+			m.completionTokens = chunk.Usage.CompletionTokens
+		}
+	}
+	m.total = time.Since(start)
+	if err := scanner.Err(); err != nil {
+		m.err = errors.Wrap(err, "read stream")
+		return m
+	}
+	if contentChunks == 0 {
+		m.err = errors.New("stream carried no content")
+		return m
+	}
+	if m.completionTokens == 0 {
+		// Upstream omitted usage; each content chunk is roughly one token
+		m.completionTokens = contentChunks
+	}
+	return m
+}
+
+// sortByThroughput orders results by tokens per second, fastest first.
+func sortByThroughput(results []BenchmarkResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].TokensPerSec != results[j].TokensPerSec {
+			return results[i].TokensPerSec > results[j].TokensPerSec
+		}
+		return results[i].Model < results[j].Model
+	})
+}
+
+// saveBenchmarkResults writes results in the benchmark_results.json format.
+func saveBenchmarkResults(path string, results []BenchmarkResult) error {
+	data, err := json.MarshalIndent(benchmarkReport{GeneratedAt: time.Now().UTC(), Results: results}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal results")
+	}
+	return errors.Wrapf(os.WriteFile(path, data, 0o644), "write %s", path)
+}
+
+// loadBenchmarkBaseline reads the baseline results keyed by model. A missing
+// file yields no baseline.
+func loadBenchmarkBaseline(path string) (map[string]BenchmarkResult, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", path)
+	}
+	var rep benchmarkReport
+	if err := json.Unmarshal(data, &rep); err != nil {
+		return nil, errors.Wrapf(err, "parse %s", path)
+	}
+	baseline := make(map[string]BenchmarkResult, len(rep.Results))
+	for _, result := range rep.Results {
+		baseline[result.Model] = result
+	}
+	return baseline, nil
+}
+
+// compareWithBaseline reports the models whose throughput dropped, or whose
+// time to first token grew, by more than threshold relative to the baseline.
+// Models missing from the baseline or without measurements are skipped.
+func compareWithBaseline(results []BenchmarkResult, baseline map[string]BenchmarkResult, threshold float64) []benchmarkRegression {
+	var regressions []benchmarkRegression
+	for _, result := range results {
+		base, ok := baseline[result.Model]
+		if !ok || result.Prompts == result.Failures {
+			continue
+		}
+		if base.TokensPerSec > 0 && result.TokensPerSec < base.TokensPerSec*(1-threshold) {
+			regressions = append(regressions, benchmarkRegression{result.Model, "tokens/s", base.TokensPerSec, result.TokensPerSec})
+		}
+		if base.AvgTTFTMs > 0 && result.AvgTTFTMs > base.AvgTTFTMs*(1+threshold) {
+			regressions = append(regressions, benchmarkRegression{result.Model, "TTFT ms", base.AvgTTFTMs, result.AvgTTFTMs})
+		}
+	}
+	return regressions
+}
+
+// renderBenchmarkMarkdown renders results, in their given order, as a Markdown
+// table with the baseline throughput when one is available.
+func renderBenchmarkMarkdown(results []BenchmarkResult, baseline map[string]BenchmarkResult) string {
+	var b strings.Builder
+	b.WriteString("| Model | Prompts | Failures | Avg TTFT (ms) | Tokens/s | Baseline tokens/s | Change |\n")
+	b.WriteString("| --- | ---: | ---: | ---: | ---: | ---: | ---: |\n")
+	for _, result := range results {
+		baseTPS, change := "—", "—"
+		if base, ok := baseline[result.Model]; ok && base.TokensPerSec > 0 {
+			baseTPS = fmt.Sprintf("%.1f", base.TokensPerSec)
+			change = fmt.Sprintf("%+.1f%%", (result.TokensPerSec/base.TokensPerSec-1)*100)
+		}
+		fmt.Fprintf(&b, "| %s | %d | %d | %.0f | %.1f | %s | %s |\n",
+			result.Model, result.Prompts, result.Failures, result.AvgTTFTMs, result.TokensPerSec, baseTPS, change)
+	}
+	return b.String()
+}
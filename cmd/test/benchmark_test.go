@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newBenchmarkServer returns an SSE server that waits ttft before the first
+// chunk, then streams chunks content deltas gap apart followed by usage. It
+// records the peak number of requests in flight.
+func newBenchmarkServer(t *testing.T, ttft, gap time.Duration, chunks int, peak *int) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	inFlight := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		*peak = max(*peak, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		require.Equal(t, "/v1/chat/completions", r.URL.Path)
+		require.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+
+		time.Sleep(ttft)
+		for i := range chunks {
+			if i > 0 {
+				time.Sleep(gap)
+			}
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"tok%d \"}}]}\n\n", i)
+			flusher.Flush()
+		}
+		fmt.Fprintf(w, "data: {\"choices\":[],\"usage\":{\"completion_tokens\":%d}}\n\n", chunks*2)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBenchmarkModel(t *testing.T) {
+	peak := 0
+	server := newBenchmarkServer(t, 100*time.Millisecond, 10*time.Millisecond, 11, &peak)
+	client := &BenchmarkClient{HTTP: server.Client(), BaseURL: server.URL, Token: "sk-test", Concurrency: 2}
+	prompts := []Prompt{{ID: "a", Prompt: "one"}, {ID: "b", Prompt: "two"}, {ID: "c", Prompt: "three"}, {ID: "d", Prompt: "four"}}
+
+	result := BenchmarkModel(context.Background(), client, "gpt-test", prompts)
+	require.Equal(t, "gpt-test", result.Model)
+	require.Equal(t, 4, result.Prompts)
+	require.Zero(t, result.Failures, result.Errors)
+	require.Equal(t, 88, result.CompletionTokens)
+	require.Equal(t, 2, peak)
+
+	// 100ms to the first chunk, then 22 tokens over 100ms
+	require.GreaterOrEqual(t, result.AvgTTFTMs, 100.0)
+	require.Less(t, result.AvgTTFTMs, 180.0)
+	require.Greater(t, result.TokensPerSec, 100.0)
+	require.LessOrEqual(t, result.TokensPerSec, 220.0)
+}
+
+func TestBenchmarkModel_CountsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"model not found"}}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+	client := &BenchmarkClient{HTTP: server.Client(), BaseURL: server.URL, Token: "sk-test", Concurrency: 1}
+
+	result := BenchmarkModel(context.Background(), client, "missing", []Prompt{{ID: "a", Prompt: "hi"}})
+	require.Equal(t, 1, result.Failures)
+	require.Len(t, result.Errors, 1)
+	require.Contains(t, result.Errors[0], "404")
+	require.Zero(t, result.TokensPerSec)
+}
+
+func TestLoadPrompts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.jsonl")
+	content := "{\"id\":\"x\",\"prompt\":\"hello\",\"max_tokens\":32}\n\n{\"prompt\":\"world\"}\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	prompts, err := loadPrompts(path)
+	require.NoError(t, err)
+	require.Equal(t, []Prompt{{ID: "x", Prompt: "hello", MaxTokens: 32}, {ID: "prompt-3", Prompt: "world"}}, prompts)
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"id\":\"x\"}\n"), 0o644))
+	_, err = loadPrompts(path)
+	require.ErrorContains(t, err, "empty prompt")
+}
+
+func TestBenchmarkReportAndBaseline(t *testing.T) {
+	results := []BenchmarkResult{
+		{Model: "slow", Prompts: 2, AvgTTFTMs: 300, TokensPerSec: 20},
+		{Model: "fast", Prompts: 2, AvgTTFTMs: 100, TokensPerSec: 90},
+		{Model: "new", Prompts: 2, AvgTTFTMs: 150, TokensPerSec: 50},
+	}
+	sortByThroughput(results)
+	require.Equal(t, "fast", results[0].Model)
+	require.Equal(t, "new", results[1].Model)
+	require.Equal(t, "slow", results[2].Model)
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	require.NoError(t, saveBenchmarkResults(path, []BenchmarkResult{
+		{Model: "fast", Prompts: 2, AvgTTFTMs: 110, TokensPerSec: 100},
+		{Model: "slow", Prompts: 2, AvgTTFTMs: 200, TokensPerSec: 40},
+	}))
+	baseline, err := loadBenchmarkBaseline(path)
+	require.NoError(t, err)
+	require.Len(t, baseline, 2)
+
+	regressions := compareWithBaseline(results, baseline, 0.2)
+	require.Equal(t, []benchmarkRegression{
+		{Model: "slow", Metric: "tokens/s", Baseline: 40, Current: 20},
+		{Model: "slow", Metric: "TTFT ms", Baseline: 200, Current: 300},
+	}, regressions)
+
+	table := renderBenchmarkMarkdown(results, baseline)
+	lines := strings.Split(strings.TrimSpace(table), "\n")
+	require.Len(t, lines, 5)
+	require.Equal(t, "| fast | 2 | 0 | 100 | 90.0 | 100.0 | -10.0% |", lines[2])
+	require.Equal(t, "| new | 2 | 0 | 150 | 50.0 | — | — |", lines[3])
+	require.Equal(t, "| slow | 2 | 0 | 300 | 20.0 | 40.0 | -50.0% |", lines[4])
+
+	missing, err := loadBenchmarkBaseline(filepath.Join(t.TempDir(), "absent.json"))
+	require.NoError(t, err)
+	require.Nil(t, missing)
+}
@@ -37,6 +37,10 @@ func main() {
 		execErr = generate(ctx, logger)
 	case "audio":
 		execErr = audio(ctx, logger, os.Args[2:])
+	case "benchmark":
+		execErr = benchmark(ctx, logger)
+	case "replay":
+		execErr = replay(ctx, logger, os.Args[2:])
 	default:
 		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
 		os.Exit(1)
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	glog "github.com/Laisky/go-utils/v6/log"
+	"github.com/Laisky/zap"
+
+	cfg "github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+)
+
+const replayReportFile = "replay_report.json"
+
+// Diff kinds reported by the replay.
+const (
+	DiffStatusChanged   = "status_changed"
+	DiffMissingField    = "missing_field"
+	DiffTypeChanged     = "type_changed"
+	DiffUnexpectedError = "unexpected_error"
+	DiffInvalidBody     = "invalid_body"
+	DiffRequestFailed   = "request_failed"
+)
+
+// Diff is one structural difference between a recorded response and its replay.
+// Path addresses the field in JSONPath style, with [] standing for any element
+// of an array.
+type Diff struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// ReplayClient replays corpus entries against a One-API instance.
+type ReplayClient struct {
+	HTTP    *http.Client
+	BaseURL string
+	Token   string
+}
+
+// ReplayResult is the outcome of replaying one corpus entry.
+type ReplayResult struct {
+	ID             int    `json:"id"`
+	Model          string `json:"model"`
+	Method         string `json:"method"`
+	URL            string `json:"url"`
+	ExpectedStatus int    `json:"expected_status"`
+	ActualStatus   int    `json:"actual_status"`
+	Diffs          []Diff `json:"diffs,omitempty"`
+}
+
+// ReplayReport is the content of replay_report.json.
+type ReplayReport struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Total       int            `json:"total"`
+	Passed      int            `json:"passed"`
+	Regressions []ReplayResult `json:"regressions"`
+}
+
+// replay loads the replay corpus from the database configured by SQL_DSN or
+// SQLITE_PATH, replays it against API_BASE, prints the regressions and saves
+// them to replay_report.json. Args accept --filter key=value (model or url,
+// repeatable) and --limit.
+func replay(ctx context.Context, logger glog.Logger, args []string) error {
+	filter, err := parseReplayArgs(args)
+	if err != nil {
+		return errors.Wrap(err, "parse replay arguments")
+	}
+	conf, err := loadConfig()
+	if err != nil {
+		return errors.Wrap(err, "load config")
+	}
+
+	// Only read the corpus; migrations belong to the server.
+	cfg.IsMasterNode = false
+	model.InitDB()
+	defer func() { _ = model.CloseDB() }()
+
+	entries, err := model.GetReplayCorpus(ctx, filter)
+	if err != nil {
+		return errors.Wrap(err, "load replay corpus")
+	}
+	if len(entries) == 0 {
+		return errors.New("replay corpus is empty for the given filter")
+	}
+
+	client := &ReplayClient{
+		HTTP:    &http.Client{Timeout: 120 * time.Second},
+		BaseURL: conf.APIBase,
+		Token:   conf.Token,
+	}
+	logger.Info("starting replay",
+		zap.String("base_url", conf.APIBase),
+		zap.Int("entry_count", len(entries)),
+		zap.String("model_filter", filter.ModelName),
+	)
+
+	report := ReplayCorpus(ctx, client, entries)
+	if err := saveReplayReport(replayReportFile, report); err != nil {
+		return errors.Wrap(err, "save replay report")
+	}
+	fmt.Println()
+	fmt.Print(renderReplayReport(report))
+
+	if len(report.Regressions) > 0 {
+		return errors.Errorf("%d of %d replayed requests regressed", len(report.Regressions), report.Total)
+	}
+	return nil
+}
+
+// parseReplayArgs turns the replay flags into a corpus filter.
+func parseReplayArgs(args []string) (model.ReplayCorpusFilter, error) {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var filter model.ReplayCorpusFilter
+	fs.Func("filter", "corpus filter as key=value, where key is model or url", func(value string) error {
+		key, val, ok := strings.Cut(value, "=")
+		val = strings.TrimSpace(val)
+		if !ok || val == "" {
+			return errors.Errorf("filter %q must be key=value", value)
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "model":
+			filter.ModelName = val
+		case "url":
+			filter.URL = val
+		default:
+			return errors.Errorf("unknown filter key %q, want model or url", key)
+		}
+		return nil
+	})
+	fs.IntVar(&filter.Limit, "limit", 0, "maximum number of entries to replay, 0 for all")
+
+	if err := fs.Parse(args); err != nil {
+		return model.ReplayCorpusFilter{}, err
+	}
+	return filter, nil
+}
+
+// ReplayCorpus replays every entry in order and collects the ones whose
+// response regressed.
+func ReplayCorpus(ctx context.Context, client *ReplayClient, entries []model.ReplayCorpusEntry) ReplayReport {
+	report := ReplayReport{
+		GeneratedAt: time.Now().UTC(),
+		Total:       len(entries),
+		Regressions: []ReplayResult{},
+	}
+	for _, entry := range entries {
+		result := client.ReplayEntry(ctx, entry)
+		if len(result.Diffs) == 0 {
+			report.Passed++
+			continue
+		}
+		report.Regressions = append(report.Regressions, result)
+	}
+	return report
+}
+
+// ReplayEntry sends entry to the instance and compares the response with the
+// recorded one. A changed status code is reported on its own, since the body
+// of a different status is not comparable.
+func (r *ReplayClient) ReplayEntry(ctx context.Context, entry model.ReplayCorpusEntry) ReplayResult {
+	result := ReplayResult{
+		ID:             entry.Id,
+		Model:          entry.ModelName,
+		Method:         entry.Method,
+		URL:            entry.URL,
+		ExpectedStatus: entry.ResponseStatus,
+	}
+	status, body, err := r.send(ctx, entry)
+	if err != nil {
+		result.Diffs = []Diff{{Path: "$", Kind: DiffRequestFailed, Actual: err.Error()}}
+		return result
+	}
+	result.ActualStatus = status
+	if status != entry.ResponseStatus {
+		result.Diffs = []Diff{{
+			Path:     "$",
+			Kind:     DiffStatusChanged,
+			Expected: strconv.Itoa(entry.ResponseStatus),
+			Actual:   strconv.Itoa(status) + ": " + snippet(body),
+		}}
+		return result
+	}
+	result.Diffs = CompareResponses([]byte(entry.ResponseBody), body)
+	return result
+}
+
+// send issues the recorded request with the harness token.
+func (r *ReplayClient) send(ctx context.Context, entry model.ReplayCorpusEntry) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, entry.Method, r.BaseURL+entry.URL, strings.NewReader(entry.RequestBody))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "build request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.Token)
+	req.Header.Set("User-Agent", "oneapi-test-harness/1.0")
+
+	resp, err := r.HTTP.Do(req)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "do request")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "read response")
+	}
+	return resp.StatusCode, body, nil
+}
+
+// CompareResponses reports how the structure of actual differs from expected:
+// fields that disappeared, values whose JSON type changed, and an error object
+// where none was expected. Field values are ignored, as are fields that only
+// actual has and values that are null on either side. SSE bodies are compared
+// as the array of their data events. Nothing is reported when expected is not
+// JSON, since there is no structure to compare against.
+func CompareResponses(expected, actual []byte) []Diff {
+	expectedValue, err := decodeResponseBody(expected)
+	if err != nil {
+		return nil
+	}
+	actualValue, err := decodeResponseBody(actual)
+	if err != nil {
+		return []Diff{{Path: "$", Kind: DiffInvalidBody, Actual: snippet(actual)}}
+	}
+
+	if message, ok := responseError(actualValue); ok {
+		if _, expectedError := responseError(expectedValue); !expectedError {
+			return []Diff{{Path: "$.error", Kind: DiffUnexpectedError, Actual: message}}
+		}
+	}
+
+	var diffs []Diff
+	compareSchemas("$", schemaOf(expectedValue), schemaOf(actualValue), &diffs)
+	return diffs
+}
+
+// decodeResponseBody decodes a JSON body, or the data events of an SSE body.
+func decodeResponseBody(body []byte) (any, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, errors.New("empty body")
+	}
+	var value any
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &value); err != nil {
+			return nil, errors.Wrap(err, "decode json body")
+		}
+		return value, nil
+	}
+
+	var events []any
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxResponseBodySize)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		data = strings.TrimSpace(data)
+		if !ok || data == "" || data == "[DONE]" {
+			continue
+		}
+		var event any
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, errors.Wrap(err, "decode stream event")
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read stream")
+	}
+	if len(events) == 0 {
+		return nil, errors.New("body is neither JSON nor an event stream")
+	}
+	return events, nil
+}
+
+// responseError reports whether value is an error response, with its message.
+func responseError(value any) (string, bool) {
+	object, ok := value.(map[string]any)
+	if !ok || object["error"] == nil {
+		return "", false
+	}
+	if detail, ok := object["error"].(map[string]any); ok {
+		if message, ok := detail["message"].(string); ok {
+			return message, true
+		}
+	}
+	return fmt.Sprint(object["error"]), true
+}
+
+// jsonSchema is the shape of a JSON value. Arrays are described by the merged
+// shape of their elements, so objects in an array may have differing fields.
+type jsonSchema struct {
+	Type       string
+	Properties map[string]*jsonSchema
+	Items      *jsonSchema
+}
+
+// schemaOf derives the shape of a decoded JSON value.
+func schemaOf(value any) *jsonSchema {
+	switch v := value.(type) {
+	case map[string]any:
+		schema := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema, len(v))}
+		for key, field := range v {
+			schema.Properties[key] = schemaOf(field)
+		}
+		return schema
+	case []any:
+		schema := &jsonSchema{Type: "array"}
+		for _, item := range v {
+			schema.Items = mergeSchemas(schema.Items, schemaOf(item))
+		}
+		return schema
+	case string:
+		return &jsonSchema{Type: "string"}
+	case float64:
+		return &jsonSchema{Type: "number"}
+	case bool:
+		return &jsonSchema{Type: "boolean"}
+	default:
+		return &jsonSchema{Type: "null"}
+	}
+}
+
+// mergeSchemas combines the shapes of two array elements. Null gives way to
+// any other type; object fields and array items are merged recursively.
+func mergeSchemas(a, b *jsonSchema) *jsonSchema {
+	switch {
+	case a == nil || a.Type == "null":
+		return b
+	case b == nil || b.Type == "null" || a.Type != b.Type:
+		return a
+	}
+	switch a.Type {
+	case "object":
+		for key, field := range b.Properties {
+			a.Properties[key] = mergeSchemas(a.Properties[key], field)
+		}
+	case "array":
+		a.Items = mergeSchemas(a.Items, b.Items)
+	}
+	return a
+}
+
+// compareSchemas appends the differences of actual from expected at path.
+func compareSchemas(path string, expected, actual *jsonSchema, diffs *[]Diff) {
+	if expected == nil || actual == nil || expected.Type == "null" || actual.Type == "null" {
+		return
+	}
+	if expected.Type != actual.Type {
+		*diffs = append(*diffs, Diff{Path: path, Kind: DiffTypeChanged, Expected: expected.Type, Actual: actual.Type})
+		return
+	}
+	switch expected.Type {
+	case "object":
+		keys := make([]string, 0, len(expected.Properties))
+		for key := range expected.Properties {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			field, ok := actual.Properties[key]
+			if !ok && expected.Properties[key].Type != "null" {
+				*diffs = append(*diffs, Diff{Path: path + "." + key, Kind: DiffMissingField, Expected: expected.Properties[key].Type})
+				continue
+			}
+			compareSchemas(path+"."+key, expected.Properties[key], field, diffs)
+		}
+	case "array":
+		compareSchemas(path+"[]", expected.Items, actual.Items, diffs)
+	}
+}
+
+// saveReplayReport writes report as indented JSON to path.
+func saveReplayReport(path string, report ReplayReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal report")
+	}
+	return errors.Wrapf(os.WriteFile(path, data, 0o644), "write %s", path)
+}
+
+// renderReplayReport formats the summary and every regression as Markdown.
+func renderReplayReport(report ReplayReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Replayed %d requests: %d passed, %d regressed\n", report.Total, report.Passed, len(report.Regressions))
+	if len(report.Regressions) == 0 {
+		return sb.String()
+	}
+	sb.WriteString("\n| ID | Model | Request | Path | Kind | Expected | Actual |\n")
+	sb.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, result := range report.Regressions {
+		for _, diff := range result.Diffs {
+			fmt.Fprintf(&sb, "| %d | %s | %s %s | `%s` | %s | %s | %s |\n",
+				result.ID, result.Model, result.Method, result.URL, diff.Path, diff.Kind,
+				markdownCell(diff.Expected), markdownCell(diff.Actual))
+		}
+	}
+	return sb.String()
+}
+
+// markdownCell keeps a value on one table row.
+func markdownCell(text string) string {
+	text = strings.NewReplacer("|", `\|`, "\n", " ").Replace(text)
+	return shorten(text, 80)
+}
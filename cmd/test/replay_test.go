@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestCompareResponses(t *testing.T) {
+	expected := []byte(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"Hi","tool_calls":null}}],"usage":{"total_tokens":3}}`)
+
+	// Different values, an extra field and a filled-in null are not regressions
+	same := []byte(`{"id":"chatcmpl-2","system_fingerprint":"fp","choices":[{"index":0,"message":{"role":"assistant","content":"Hello there","tool_calls":[]}}],"usage":{"total_tokens":9}}`)
+	require.Empty(t, CompareResponses(expected, same))
+
+	changed := []byte(`{"id":"chatcmpl-2","choices":[{"index":"0","message":{"role":"assistant"}}]}`)
+	require.Equal(t, []Diff{
+		{Path: "$.choices[].index", Kind: DiffTypeChanged, Expected: "number", Actual: "string"},
+		{Path: "$.choices[].message.content", Kind: DiffMissingField, Expected: "string"},
+		{Path: "$.usage", Kind: DiffMissingField, Expected: "object"},
+	}, CompareResponses(expected, changed))
+
+	require.Equal(t, []Diff{{Path: "$.error", Kind: DiffUnexpectedError, Actual: "upstream overloaded"}},
+		CompareResponses(expected, []byte(`{"error":{"message":"upstream overloaded","type":"server_error"}}`)))
+	require.Equal(t, DiffInvalidBody, CompareResponses(expected, []byte("<html>bad gateway</html>"))[0].Kind)
+
+	// A non-JSON recording has no structure to compare
+	require.Empty(t, CompareResponses([]byte("audio bytes"), []byte(`{"id":"x"}`)))
+
+	// Streams compare the merged shape of their events
+	stream := []byte("data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"total_tokens\":3}}\n\ndata: [DONE]\n\n")
+	require.Empty(t, CompareResponses(stream, stream))
+	withoutUsage := []byte("data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"Hi\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+	require.Equal(t, []Diff{{Path: "$[].usage", Kind: DiffMissingField, Expected: "object"}},
+		CompareResponses(stream, withoutUsage))
+}
+
+func TestParseReplayArgs(t *testing.T) {
+	filter, err := parseReplayArgs([]string{"--filter", "model=gpt-4o", "--filter", "url=/v1/chat/completions", "--limit", "20"})
+	require.NoError(t, err)
+	require.Equal(t, model.ReplayCorpusFilter{ModelName: "gpt-4o", URL: "/v1/chat/completions", Limit: 20}, filter)
+
+	filter, err = parseReplayArgs(nil)
+	require.NoError(t, err)
+	require.Equal(t, model.ReplayCorpusFilter{}, filter)
+
+	_, err = parseReplayArgs([]string{"--filter", "channel=3"})
+	require.Error(t, err)
+	_, err = parseReplayArgs([]string{"--filter", "model"})
+	require.Error(t, err)
+}
+
+// newReplayServer answers like a healthy instance with fresh content, except
+// that requests for brokenModel fail.
+func newReplayServer(t *testing.T, brokenModel string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var request struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		require.NoError(t, json.Unmarshal(body, &request))
+
+		switch {
+		case request.Model == brokenModel:
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error":{"message":"no available channel","type":"one_api_error"}}`)
+		case r.URL.Path == "/v1/embeddings":
+			fmt.Fprint(w, `{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.3,0.4]}],"usage":{"prompt_tokens":2}}`)
+		case request.Stream:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"Hey\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"total_tokens\":5}}\n\ndata: [DONE]\n\n")
+		default:
+			fmt.Fprintf(w, `{"id":"chatcmpl-new","model":%q,"choices":[{"message":{"role":"assistant","content":"Hey"}}],"usage":{"total_tokens":5}}`, request.Model)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestReplayCorpus(t *testing.T) {
+	corpus := []model.ReplayCorpusEntry{
+		{
+			Id: 1, ModelName: "gpt-4o", Method: http.MethodPost, URL: "/v1/chat/completions",
+			RequestBody:    `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`,
+			ResponseStatus: http.StatusOK,
+			ResponseBody:   `{"id":"chatcmpl-old","model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"Hello"}}],"usage":{"total_tokens":3}}`,
+		},
+		{
+			Id: 2, ModelName: "gpt-4o", Method: http.MethodPost, URL: "/v1/chat/completions",
+			RequestBody:    `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`,
+			ResponseStatus: http.StatusOK,
+			ResponseBody:   "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\ndata: {\"choices\":[],\"usage\":{\"total_tokens\":3}}\n\ndata: [DONE]\n\n",
+		},
+		{
+			Id: 3, ModelName: "text-embedding-3-small", Method: http.MethodPost, URL: "/v1/embeddings",
+			RequestBody:    `{"model":"text-embedding-3-small","input":"hi"}`,
+			ResponseStatus: http.StatusOK,
+			ResponseBody:   `{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]}],"usage":{"prompt_tokens":1}}`,
+		},
+		{
+			Id: 4, ModelName: "claude-3-haiku", Method: http.MethodPost, URL: "/v1/chat/completions",
+			RequestBody:    `{"model":"claude-3-haiku","messages":[{"role":"user","content":"hi"}]}`,
+			ResponseStatus: http.StatusOK,
+			ResponseBody:   `{"id":"msg-old","model":"claude-3-haiku","choices":[{"message":{"role":"assistant","content":"Hello"}}],"usage":{"total_tokens":3}}`,
+		},
+	}
+
+	server := newReplayServer(t, "claude-3-haiku")
+	client := &ReplayClient{HTTP: server.Client(), BaseURL: server.URL, Token: "sk-test"}
+	report := ReplayCorpus(context.Background(), client, corpus)
+
+	require.Equal(t, 4, report.Total)
+	require.Equal(t, 3, report.Passed)
+	require.Len(t, report.Regressions, 1)
+	regression := report.Regressions[0]
+	require.Equal(t, 4, regression.ID)
+	require.Equal(t, http.StatusInternalServerError, regression.ActualStatus)
+	require.Len(t, regression.Diffs, 1)
+	require.Equal(t, DiffStatusChanged, regression.Diffs[0].Kind)
+	require.Equal(t, "200", regression.Diffs[0].Expected)
+	require.Contains(t, regression.Diffs[0].Actual, "no available channel")
+
+	rendered := renderReplayReport(report)
+	require.Contains(t, rendered, "Replayed 4 requests: 3 passed, 1 regressed")
+	require.Contains(t, rendered, "| 4 | claude-3-haiku | POST /v1/chat/completions | `$` | status_changed |")
+
+	path := filepath.Join(t.TempDir(), replayReportFile)
+	require.NoError(t, saveReplayReport(path, report))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var saved ReplayReport
+	require.NoError(t, json.Unmarshal(data, &saved))
+	require.Equal(t, report.Regressions, saved.Regressions)
+
+	// Everything passes once the broken model recovers
+	report = ReplayCorpus(context.Background(), &ReplayClient{HTTP: server.Client(), BaseURL: newReplayServer(t, "").URL, Token: "sk-test"}, corpus)
+	require.Empty(t, report.Regressions)
+}
@@ -0,0 +1,50 @@
+package blacklist
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// ipKeyPrefix prefixes the IP blacklist keys, in memory and in Redis.
+const ipKeyPrefix = "ip_"
+
+// ip2Key returns the blacklist key of ip, normalized so that equivalent
+// notations of an IPv6 address share a key.
+func ip2Key(ip string) string {
+	ip = strings.TrimSpace(ip)
+	if parsed := net.ParseIP(ip); parsed != nil {
+		ip = parsed.String()
+	}
+	return ipKeyPrefix + ip
+}
+
+// BanIP bans the client IP until expiry, or forever when expiry is not
+// positive. Like user bans, IP bans are mirrored to Redis when configured.
+func BanIP(ip string, expiry time.Duration) {
+	storeBan(ip2Key(ip), expiry)
+}
+
+// UnbanIP lifts the ban of the client IP.
+func UnbanIP(ip string) {
+	deleteBan(ip2Key(ip))
+}
+
+// IsIPBanned reports whether the client IP has an unexpired ban. On a miss it
+// falls back to the Redis mirror at most once per missCacheTTL for each IP.
+func IsIPBanned(ip string) bool {
+	key := ip2Key(ip)
+	if localBan(key) {
+		return true
+	}
+	if !redisEnabled() || recentlyChecked(key) {
+		return false
+	}
+	if record, ok := redisBan(context.Background(), key); ok {
+		blackList.Store(key, record)
+		return true
+	}
+	checkedKeys.Store(key, time.Now())
+	return false
+}
@@ -0,0 +1,45 @@
+package blacklist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBanIP(t *testing.T) {
+	t.Cleanup(func() {
+		blackList.Clear()
+		checkedKeys.Clear()
+	})
+
+	BanIP("203.0.113.7", 0)
+	BanIP("2001:db8::1", 50*time.Millisecond)
+
+	require.True(t, IsIPBanned("203.0.113.7"))
+	require.True(t, IsIPBanned(" 2001:0db8:0:0::1 "))
+	require.False(t, IsIPBanned("203.0.113.8"))
+	// IP and user bans live side by side without colliding
+	require.False(t, IsUserBanned(7))
+
+	require.Eventually(t, func() bool {
+		return !IsIPBanned("2001:db8::1")
+	}, time.Second, 10*time.Millisecond)
+
+	UnbanIP("203.0.113.7")
+	require.False(t, IsIPBanned("203.0.113.7"))
+}
+
+func TestSweepExpiredBansDropsStaleLookups(t *testing.T) {
+	t.Cleanup(func() { checkedKeys.Clear() })
+
+	now := time.Now()
+	checkedKeys.Store(ip2Key("203.0.113.9"), now.Add(-missCacheTTL))
+	checkedKeys.Store(ip2Key("203.0.113.10"), now)
+
+	sweepExpiredBans(now)
+	_, ok := checkedKeys.Load(ip2Key("203.0.113.9"))
+	require.False(t, ok)
+	_, ok = checkedKeys.Load(ip2Key("203.0.113.10"))
+	require.True(t, ok)
+}
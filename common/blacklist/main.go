@@ -1,32 +1,360 @@
 package blacklist
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
 )
 
+// missCacheTTL bounds how long a user found unbanned in Redis and the database
+// is trusted before IsUserBanned queries again. Bans issued on other nodes also
+// arrive through state sync events, so this only delays the fallback.
+const missCacheTTL = time.Minute
+
+// sweepInterval is how often expired bans are dropped from memory.
+const sweepInterval = time.Minute
+
+// userKeyPrefix prefixes the blacklist keys, in memory and in Redis.
+const userKeyPrefix = "userid_"
+
+// redisScanBatch is the SCAN batch size used when warming from Redis.
+const redisScanBatch = 500
+
+// banRecord is the value stored in blackList.
+type banRecord struct {
+	// expiresAt is when the ban lapses, zero for a permanent ban.
+	expiresAt time.Time
+}
+
+// expired reports whether the ban has lapsed at now.
+func (r banRecord) expired(now time.Time) bool {
+	return !r.expiresAt.IsZero() && !now.Before(r.expiresAt)
+}
+
 var blackList sync.Map
 
+// checkedKeys maps the keys of users and IPs found unbanned in Redis and the
+// database to the time of the lookup.
+var checkedKeys sync.Map
+
+// db persists bans, nil until Init is called.
+var db atomic.Pointer[gorm.DB]
+
 func init() {
 	blackList = sync.Map{}
+	go func() {
+		for range time.Tick(sweepInterval) {
+			sweepExpiredBans(time.Now())
+		}
+	}()
+}
+
+// Entry is a persisted ban, restored into the in-memory blacklist at startup.
+type Entry struct {
+	UserId    int   `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt int64 `json:"created_at" gorm:"bigint;index"`
+}
+
+// TableName stores bans in blacklist_entries.
+func (Entry) TableName() string {
+	return "blacklist_entries"
+}
+
+// Init enables persistence of bans in gdb and loads the stored bans into the
+// in-memory blacklist. The blacklist_entries table must already be migrated.
+func Init(gdb *gorm.DB) error {
+	var entries []Entry
+	if err := gdb.Find(&entries).Error; err != nil {
+		return errors.Wrap(err, "load blacklist entries")
+	}
+	for _, entry := range entries {
+		blackList.Store(userId2Key(entry.UserId), banRecord{})
+	}
+	db.Store(gdb)
+	logger.Logger.Info("blacklist loaded", zap.Int("banned_users", len(entries)))
+	return nil
 }
 
 func userId2Key(id int) string {
-	return fmt.Sprintf("userid_%d", id)
+	return fmt.Sprintf("%s%d", userKeyPrefix, id)
 }
 
-// BanUser records the user ID in the in-memory blacklist to block further access.
+// BanUser records a permanent ban of the user ID in the in-memory blacklist
+// to block further access.
 func BanUser(id int) {
-	blackList.Store(userId2Key(id), true)
+	BanUserWithExpiry(id, 0)
 }
 
-// UnbanUser removes the user ID from the in-memory blacklist.
+// BanUserWithExpiry records a ban of the user ID that lapses after expiry, or
+// never when expiry is not positive. When Redis is configured the ban is also
+// written as a Redis key with the same TTL, so other nodes see it.
+func BanUserWithExpiry(id int, expiry time.Duration) {
+	storeBan(userId2Key(id), expiry)
+}
+
+// storeBan bans key in memory and in the Redis mirror, until expiry or forever
+// when expiry is not positive.
+func storeBan(key string, expiry time.Duration) {
+	record := banRecord{}
+	if expiry > 0 {
+		record.expiresAt = time.Now().Add(expiry)
+	}
+	blackList.Store(key, record)
+
+	if !redisEnabled() {
+		return
+	}
+	// SETEX for expiring bans, a plain SET for permanent ones
+	if err := common.RDB.Set(context.Background(), key, "1", max(expiry, 0)).Err(); err != nil {
+		logger.Logger.Error("failed to mirror ban to redis",
+			zap.String("key", key),
+			zap.Error(err))
+	}
+}
+
+// deleteBan lifts the ban of key in memory and in the Redis mirror.
+func deleteBan(key string) {
+	blackList.Delete(key)
+	if !redisEnabled() {
+		return
+	}
+	if err := common.RDB.Del(context.Background(), key).Err(); err != nil {
+		logger.Logger.Error("failed to delete ban from redis",
+			zap.String("key", key),
+			zap.Error(err))
+	}
+}
+
+// localBan reports whether key has an unexpired ban in memory, removing an
+// expired one.
+func localBan(key string) bool {
+	value, ok := blackList.Load(key)
+	if !ok {
+		return false
+	}
+	if !value.(banRecord).expired(time.Now()) {
+		return true
+	}
+	blackList.CompareAndDelete(key, value)
+	return false
+}
+
+// recentlyChecked reports whether key was found unbanned within missCacheTTL.
+func recentlyChecked(key string) bool {
+	checkedAt, ok := checkedKeys.Load(key)
+	return ok && time.Since(checkedAt.(time.Time)) < missCacheTTL
+}
+
+// redisEnabled reports whether bans are mirrored to Redis.
+func redisEnabled() bool {
+	return config.RedisConnString != "" && common.IsRedisEnabled() && common.RDB != nil
+}
+
+// BanUserPersistent is like BanUser but also stores the ban in the database,
+// so it survives restarts. Banning a user again renews the ban time. Without
+// Init it only bans the user in memory.
+func BanUserPersistent(ctx context.Context, id int) error {
+	BanUser(id)
+	gdb := db.Load()
+	if gdb == nil {
+		return nil
+	}
+
+	entry := Entry{UserId: id, CreatedAt: time.Now().UTC().Unix()}
+	err := gdb.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"created_at"}),
+	}).Create(&entry).Error
+	if err != nil {
+		return errors.Wrapf(err, "persist ban of user %d", id)
+	}
+	return nil
+}
+
+// UnbanUser removes the user ID from the in-memory blacklist and from the
+// persisted bans.
 func UnbanUser(id int) {
-	blackList.Delete(userId2Key(id))
+	deleteBan(userId2Key(id))
+	gdb := db.Load()
+	if gdb == nil {
+		return
+	}
+	if err := gdb.Delete(&Entry{}, "user_id = ?", id).Error; err != nil {
+		logger.Logger.Error("failed to delete persisted ban",
+			zap.Int("user_id", id),
+			zap.Error(err))
+	}
 }
 
-// IsUserBanned reports whether the given user ID exists in the in-memory blacklist.
+// IsUserBanned reports whether the given user ID has an unexpired ban in the
+// in-memory blacklist; expired bans are removed on the way. On a miss it falls
+// back to the Redis mirror and the persisted bans, at most once per
+// missCacheTTL for each user, and backfills a found ban in memory.
 func IsUserBanned(id int) bool {
-	_, ok := blackList.Load(userId2Key(id))
-	return ok
+	key := userId2Key(id)
+	if localBan(key) {
+		Metrics.record(lookupLocalHit)
+		return true
+	}
+
+	gdb := db.Load()
+	if gdb == nil && !redisEnabled() {
+		Metrics.record(lookupMiss)
+		return false
+	}
+	if recentlyChecked(key) {
+		Metrics.record(lookupMiss)
+		return false
+	}
+	if record, ok := redisBan(context.Background(), key); ok {
+		blackList.Store(key, record)
+		Metrics.record(lookupRedisHit)
+		return true
+	}
+	if gdb == nil {
+		checkedKeys.Store(key, time.Now())
+		Metrics.record(lookupMiss)
+		return false
+	}
+
+	var count int64
+	if err := gdb.Model(&Entry{}).Where("user_id = ?", id).Count(&count).Error; err != nil {
+		logger.Logger.Warn("failed to look up persisted ban",
+			zap.Int("user_id", id),
+			zap.Error(err))
+		Metrics.record(lookupMiss)
+		return false
+	}
+	if count == 0 {
+		checkedKeys.Store(key, time.Now())
+		Metrics.record(lookupMiss)
+		return false
+	}
+	blackList.Store(key, banRecord{})
+	Metrics.record(lookupDatabaseHit)
+	return true
+}
+
+// redisBan returns the ban mirrored in Redis under key, if any. PTTL answers
+// like EXISTS and also returns the remaining ban time, so a backfilled ban
+// lapses together with the Redis key.
+func redisBan(ctx context.Context, key string) (banRecord, bool) {
+	if !redisEnabled() {
+		return banRecord{}, false
+	}
+	ttl, err := common.RDB.PTTL(ctx, key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.Logger.Warn("failed to look up ban in redis",
+			zap.String("key", key),
+			zap.Error(err))
+		return banRecord{}, false
+	}
+	switch {
+	case err != nil || ttl == -2:
+		// The key does not exist
+		return banRecord{}, false
+	case ttl < 0:
+		return banRecord{}, true
+	default:
+		return banRecord{expiresAt: time.Now().Add(ttl)}, true
+	}
+}
+
+// SyncBlacklistFromRedis copies the user and IP bans mirrored in Redis into
+// the in-memory blacklist, so a starting node blocks them without a lookup. It
+// does nothing when Redis is not configured.
+func SyncBlacklistFromRedis(ctx context.Context) error {
+	if !redisEnabled() {
+		return nil
+	}
+
+	synced := 0
+	for _, prefix := range []string{userKeyPrefix, ipKeyPrefix} {
+		var cursor uint64
+		for {
+			keys, next, err := common.RDB.Scan(ctx, cursor, prefix+"*", redisScanBatch).Result()
+			if err != nil {
+				return errors.Wrapf(err, "scan %s bans in redis", prefix)
+			}
+			for _, key := range keys {
+				if record, ok := redisBan(ctx, key); ok {
+					blackList.Store(key, record)
+					synced++
+				}
+			}
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+	}
+	logger.Logger.Info("blacklist synced from redis", zap.Int("bans", synced))
+	return nil
+}
+
+// sweepExpiredBans drops the bans that lapsed at now from memory, along with
+// the lookups older than missCacheTTL, so checkedKeys does not grow with every
+// user and IP ever seen.
+func sweepExpiredBans(now time.Time) {
+	blackList.Range(func(key, value any) bool {
+		if value.(banRecord).expired(now) {
+			blackList.CompareAndDelete(key, value)
+		}
+		return true
+	})
+	checkedKeys.Range(func(key, checkedAt any) bool {
+		if now.Sub(checkedAt.(time.Time)) >= missCacheTTL {
+			checkedKeys.CompareAndDelete(key, checkedAt)
+		}
+		return true
+	})
+}
+
+// ExpireBansOlderThan lifts the persisted bans issued more than olderThan ago,
+// in the database, in memory and in the Redis mirror, and returns how many
+// were lifted.
+func ExpireBansOlderThan(ctx context.Context, olderThan time.Duration) (int64, error) {
+	gdb := db.Load()
+	if gdb == nil {
+		return 0, errors.New("blacklist persistence is not initialized")
+	}
+
+	cutoff := time.Now().UTC().Add(-olderThan).Unix()
+	var expired []Entry
+	err := gdb.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("created_at < ?", cutoff).Find(&expired).Error; err != nil {
+			return errors.Wrap(err, "find expired bans")
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+		userIds := make([]int, 0, len(expired))
+		for _, entry := range expired {
+			userIds = append(userIds, entry.UserId)
+		}
+		if err := tx.Delete(&Entry{}, "user_id IN ?", userIds).Error; err != nil {
+			return errors.Wrap(err, "delete expired bans")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range expired {
+		deleteBan(userId2Key(entry.UserId))
+	}
+	return int64(len(expired)), nil
 }
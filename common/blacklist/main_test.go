@@ -0,0 +1,144 @@
+package blacklist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+func setupBlacklistDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	logger.SetupLogger()
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gdb.AutoMigrate(&Entry{}))
+	t.Cleanup(func() {
+		db.Store(nil)
+		blackList.Clear()
+		checkedKeys.Clear()
+	})
+	return gdb
+}
+
+func TestPersistedBansSurviveRestart(t *testing.T) {
+	gdb := setupBlacklistDB(t)
+	ctx := context.Background()
+	require.NoError(t, Init(gdb))
+
+	require.NoError(t, BanUserPersistent(ctx, 1))
+	require.NoError(t, BanUserPersistent(ctx, 1))
+	require.NoError(t, BanUserPersistent(ctx, 2))
+	BanUser(3)
+	UnbanUser(2)
+
+	// A restarted node only knows the persisted bans
+	blackList.Clear()
+	require.NoError(t, Init(gdb))
+	require.True(t, IsUserBanned(1))
+	require.False(t, IsUserBanned(2))
+	require.False(t, IsUserBanned(3))
+}
+
+func TestIsUserBannedFallsBackToDatabase(t *testing.T) {
+	gdb := setupBlacklistDB(t)
+	require.NoError(t, Init(gdb))
+
+	// Ban written by another node after this one started
+	require.NoError(t, gdb.Create(&Entry{UserId: 7, CreatedAt: time.Now().Unix()}).Error)
+	require.True(t, IsUserBanned(7))
+	_, cached := blackList.Load(userId2Key(7))
+	require.True(t, cached)
+
+	// Misses are not queried again within missCacheTTL
+	require.False(t, IsUserBanned(8))
+	require.NoError(t, gdb.Create(&Entry{UserId: 8, CreatedAt: time.Now().Unix()}).Error)
+	require.False(t, IsUserBanned(8))
+	checkedKeys.Store(userId2Key(8), time.Now().Add(-missCacheTTL))
+	require.True(t, IsUserBanned(8))
+}
+
+func TestExpireBansOlderThan(t *testing.T) {
+	gdb := setupBlacklistDB(t)
+	ctx := context.Background()
+
+	_, err := ExpireBansOlderThan(ctx, time.Hour)
+	require.Error(t, err)
+
+	require.NoError(t, Init(gdb))
+	require.NoError(t, BanUserPersistent(ctx, 1))
+	require.NoError(t, BanUserPersistent(ctx, 2))
+	require.NoError(t, gdb.Model(&Entry{}).Where("user_id = ?", 1).
+		Update("created_at", time.Now().Add(-48*time.Hour).Unix()).Error)
+
+	expired, err := ExpireBansOlderThan(ctx, 24*time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), expired)
+	require.False(t, IsUserBanned(1))
+	require.True(t, IsUserBanned(2))
+
+	expired, err = ExpireBansOlderThan(ctx, 24*time.Hour)
+	require.NoError(t, err)
+	require.Zero(t, expired)
+}
+
+func TestBanUserWithExpiry(t *testing.T) {
+	t.Cleanup(func() { blackList.Clear() })
+
+	BanUserWithExpiry(11, time.Hour)
+	BanUserWithExpiry(12, time.Millisecond)
+	BanUser(13)
+	time.Sleep(5 * time.Millisecond)
+
+	require.True(t, IsUserBanned(11))
+	require.True(t, IsUserBanned(13))
+
+	// Expired bans are absent and removed on lookup
+	require.False(t, IsUserBanned(12))
+	_, ok := blackList.Load(userId2Key(12))
+	require.False(t, ok)
+
+	// The sweeper drops bans nobody looked up
+	BanUserWithExpiry(14, time.Minute)
+	sweepExpiredBans(time.Now())
+	_, ok = blackList.Load(userId2Key(14))
+	require.True(t, ok)
+	sweepExpiredBans(time.Now().Add(time.Minute))
+	_, ok = blackList.Load(userId2Key(14))
+	require.False(t, ok)
+	_, ok = blackList.Load(userId2Key(13))
+	require.True(t, ok)
+
+	// Banning again replaces the expiry
+	BanUserWithExpiry(11, 0)
+	sweepExpiredBans(time.Now().Add(2 * time.Hour))
+	require.True(t, IsUserBanned(11))
+}
+
+func TestBlacklistMetrics(t *testing.T) {
+	gdb := setupBlacklistDB(t)
+	require.NoError(t, Init(gdb))
+	before := struct{ local, database, miss int64 }{
+		Metrics.LocalHits.Load(), Metrics.DatabaseHits.Load(), Metrics.Misses.Load(),
+	}
+
+	// Redis is not configured, so syncing from it does nothing
+	require.NoError(t, SyncBlacklistFromRedis(context.Background()))
+
+	BanUser(21)
+	require.NoError(t, gdb.Create(&Entry{UserId: 22, CreatedAt: time.Now().Unix()}).Error)
+	require.True(t, IsUserBanned(21))
+	require.True(t, IsUserBanned(22))
+	require.True(t, IsUserBanned(22))
+	require.False(t, IsUserBanned(23))
+
+	require.Equal(t, before.local+2, Metrics.LocalHits.Load())
+	require.Equal(t, before.database+1, Metrics.DatabaseHits.Load())
+	require.Equal(t, before.miss+1, Metrics.Misses.Load())
+	require.Zero(t, Metrics.RedisHits.Load())
+}
@@ -0,0 +1,47 @@
+package blacklist
+
+import (
+	"sync/atomic"
+
+	"github.com/songquanpeng/one-api/common/metrics"
+)
+
+// Results of IsUserBanned lookups, used as the Prometheus "result" label.
+const (
+	lookupLocalHit    = "local_hit"
+	lookupRedisHit    = "redis_hit"
+	lookupDatabaseHit = "database_hit"
+	lookupMiss        = "miss"
+)
+
+// BlacklistMetrics counts the IsUserBanned lookups of this node by where the
+// answer came from. The counts are also exported to Prometheus as
+// one_api_blacklist_lookups_total.
+type BlacklistMetrics struct {
+	// LocalHits counts bans found in the in-memory blacklist.
+	LocalHits atomic.Int64
+	// RedisHits counts bans backfilled from Redis.
+	RedisHits atomic.Int64
+	// DatabaseHits counts bans backfilled from the persisted entries.
+	DatabaseHits atomic.Int64
+	// Misses counts lookups of users that are not banned.
+	Misses atomic.Int64
+}
+
+// Metrics holds the lookup counts of this node.
+var Metrics BlacklistMetrics
+
+// record counts one lookup with result.
+func (m *BlacklistMetrics) record(result string) {
+	switch result {
+	case lookupLocalHit:
+		m.LocalHits.Add(1)
+	case lookupRedisHit:
+		m.RedisHits.Add(1)
+	case lookupDatabaseHit:
+		m.DatabaseHits.Add(1)
+	default:
+		m.Misses.Add(1)
+	}
+	metrics.GlobalRecorder.RecordBlacklistLookup(result)
+}
@@ -1,8 +1,10 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"time"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/network"
 )
 
 // HTTPClient is the default outbound client used for relay requests.
@@ -19,6 +22,12 @@ var HTTPClient *http.Client
 // ImpatientHTTPClient is a short-timeout client for quick health checks or metadata requests.
 var ImpatientHTTPClient *http.Client
 
+// GuardedHTTPClient sends requests to upstreams chosen by users rather than
+// admins. It ignores RELAY_PROXY and always refuses connections to non-public
+// addresses, whether or not the channel SSRF protection is enabled, and even
+// when the host is on the admin SSRF allowlist.
+var GuardedHTTPClient *http.Client
+
 // UserContentRequestHTTPClient fetches user-supplied resources, optionally via a dedicated proxy.
 var UserContentRequestHTTPClient *http.Client
 
@@ -60,7 +69,15 @@ func Init() {
 		}
 		transport = createTransport(proxyURL)
 	} else {
-		transport = createTransport(nil)
+		relayTransport := createTransport(nil)
+		if config.ChannelSSRFProtectionEnabled {
+			logger.Logger.Info("channel SSRF protection enabled")
+			relayTransport.DialContext = network.GuardedDialContext(&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			})
+		}
+		transport = relayTransport
 	}
 
 	if config.RelayTimeout == 0 {
@@ -78,4 +95,28 @@ func Init() {
 		Timeout:   5 * time.Second,
 		Transport: transport,
 	}
+
+	guardedTransport := createTransport(nil)
+	guardedTransport.DialContext = network.StrictGuardedDialContext(&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	})
+	GuardedHTTPClient = &http.Client{
+		Timeout:   HTTPClient.Timeout,
+		Transport: guardedTransport,
+	}
+}
+
+type guardedUpstreamKey struct{}
+
+// WithGuardedUpstream marks ctx so that upstream requests made with it are
+// sent through GuardedHTTPClient.
+func WithGuardedUpstream(ctx context.Context) context.Context {
+	return context.WithValue(ctx, guardedUpstreamKey{}, true)
+}
+
+// IsGuardedUpstream reports whether ctx was marked by WithGuardedUpstream.
+func IsGuardedUpstream(ctx context.Context) bool {
+	guarded, _ := ctx.Value(guardedUpstreamKey{}).(bool)
+	return guarded
 }
@@ -0,0 +1,29 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// ADAPTIVE REQUEST TIMEOUT
+// =============================================================================
+// Settings that scale the upstream request timeout with the requested output
+// length, so that long generations are not cut off by a timeout sized for
+// short ones.
+
+var (
+	// AdaptiveTimeoutBaseSeconds is the upstream timeout of a request that asks
+	// for no output tokens. The effective timeout is
+	// base + max_tokens × AdaptiveTimeoutPerTokenMs, capped at RelayTimeout when
+	// RelayTimeout is set. Set to 0 to disable adaptive timeouts.
+	//
+	// Environment variable: ADAPTIVE_TIMEOUT_BASE_SECONDS
+	// Default: 0 (disabled)
+	// Unit: seconds
+	AdaptiveTimeoutBaseSeconds = env.Int("ADAPTIVE_TIMEOUT_BASE_SECONDS", 0)
+
+	// AdaptiveTimeoutPerTokenMs is the extra timeout granted per requested output token.
+	//
+	// Environment variable: ADAPTIVE_TIMEOUT_PER_TOKEN_MS
+	// Default: 0.5
+	// Unit: milliseconds
+	AdaptiveTimeoutPerTokenMs = env.Float64("ADAPTIVE_TIMEOUT_PER_TOKEN_MS", 0.5)
+)
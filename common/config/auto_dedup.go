@@ -0,0 +1,28 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// AUTO DEDUPLICATION
+// =============================================================================
+// Settings for answering repeated chat completions from Redis, for clients
+// that retry requests but cannot send an idempotency header. Only
+// deterministic requests are deduplicated: non-streaming chat completions with
+// a temperature of 0 and a seed. Deduplication is inactive without Redis.
+
+var (
+	// AutoDedupEnabled answers a chat completion identical to one sent with
+	// the same token within AutoDedupWindowSeconds with the cached response,
+	// without calling upstream or charging quota again.
+	//
+	// Environment variable: AUTO_DEDUP_ENABLED
+	// Default: false
+	AutoDedupEnabled = env.Bool("AUTO_DEDUP_ENABLED", false)
+
+	// AutoDedupWindowSeconds is how long a response is kept for deduplication.
+	//
+	// Environment variable: AUTO_DEDUP_WINDOW_SECONDS
+	// Default: 5
+	// Unit: seconds
+	AutoDedupWindowSeconds = env.Int("AUTO_DEDUP_WINDOW_SECONDS", 5)
+)
@@ -0,0 +1,39 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// BATCH API
+// =============================================================================
+// Settings for relaying the OpenAI Batch API (/v1/batches and batch input
+// files), which processes bulk requests asynchronously at a discount.
+
+var (
+	// BatchAPIEnabled exposes POST /v1/batches, GET /v1/batches/:id,
+	// POST /v1/batches/:id/cancel, batch file uploads and result downloads.
+	//
+	// Environment variable: BATCH_API_ENABLED
+	// Default: false
+	BatchAPIEnabled = env.Bool("BATCH_API_ENABLED", false)
+
+	// BatchAPIDiscountRatio multiplies the normal price of the requests in a
+	// batch. It is applied once the batch finishes and its usage is known.
+	//
+	// Environment variable: BATCH_API_DISCOUNT_RATIO
+	// Default: 0.5
+	BatchAPIDiscountRatio = env.Float64("BATCH_API_DISCOUNT_RATIO", 0.5)
+
+	// BatchAPIPollInterval is how often unfinished batches are checked
+	// upstream.
+	//
+	// Environment variable: BATCH_API_POLL_INTERVAL
+	// Default: 60
+	// Unit: seconds
+	BatchAPIPollInterval = env.Int("BATCH_API_POLL_INTERVAL", 60)
+
+	// BatchAPIMaxRequests caps the number of requests in one batch input file.
+	//
+	// Environment variable: BATCH_API_MAX_REQUESTS
+	// Default: 50000
+	BatchAPIMaxRequests = env.Int("BATCH_API_MAX_REQUESTS", 50000)
+)
@@ -0,0 +1,19 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// BODY SIZE LOGGING
+// =============================================================================
+// Settings for recording request and response body sizes in consume log
+// metadata, used when debugging oversized requests. Response sizes are recorded
+// for the requests sampled at REPLAY_CORPUS_SAMPLE_RATE.
+
+var (
+	// LogRequestBodySizeEnabled records the size of the client request body in
+	// bytes as request_body_bytes in the metadata of every consume log.
+	//
+	// Environment variable: LOG_REQUEST_BODY_SIZE_ENABLED
+	// Default: true
+	LogRequestBodySizeEnabled = env.Bool("LOG_REQUEST_BODY_SIZE_ENABLED", true)
+)
@@ -0,0 +1,18 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// CHANNEL TEST ON SAVE
+// =============================================================================
+// Settings for testing a channel before POST/PUT /api/channel persist it.
+
+var (
+	// AutoWarmup tests every channel before it is created or updated, as if
+	// the TestOnSave query parameter was set, so misconfigured API keys or
+	// base URLs are rejected instead of failing the first real request.
+	//
+	// Environment variable: AUTO_WARMUP
+	// Default: false
+	AutoWarmup = env.Bool("AUTO_WARMUP", false)
+)
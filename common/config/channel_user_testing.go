@@ -0,0 +1,22 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// SELF-SERVICE CHANNEL TESTING
+// =============================================================================
+// Settings for POST /api/user/channel/test, which lets users check an upstream
+// API key without creating a channel.
+
+var (
+	// UserChannelTestRateLimitNum caps how many self-service channel tests a
+	// user can run within UserChannelTestRateLimitDuration.
+	//
+	// Environment variable: USER_CHANNEL_TEST_RATE_LIMIT
+	// Default: 10
+	UserChannelTestRateLimitNum = env.Int("USER_CHANNEL_TEST_RATE_LIMIT", 10)
+
+	// UserChannelTestRateLimitDuration is the self-service channel test window.
+	// Unit: seconds
+	UserChannelTestRateLimitDuration int64 = 60 * 60
+)
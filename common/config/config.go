@@ -349,6 +349,32 @@ var (
 	// Default: 60 seconds
 	ChannelSuspendSecondsForAuth = time.Second * time.Duration(env.Int("CHANNEL_SUSPEND_SECONDS_FOR_AUTH", 60))
 
+	// CircuitBreakerFailureThreshold is the number of consecutive channel-origin
+	// failures (5xx, network, auth) of an ability after which its circuit opens
+	// and requests skip the channel without reaching upstream.
+	//
+	// Environment variable: CIRCUIT_BREAKER_FAILURE_THRESHOLD
+	// Default: 5
+	// Set to 0 to disable the circuit breaker.
+	CircuitBreakerFailureThreshold = env.Int("CIRCUIT_BREAKER_FAILURE_THRESHOLD", 5)
+
+	// CircuitBreakerHalfOpenDelay is how long an open circuit blocks requests
+	// before a single probe request is let through. The circuit closes when
+	// the probe succeeds and opens again when it fails.
+	//
+	// Environment variable: CIRCUIT_BREAKER_HALF_OPEN_DELAY_SECONDS
+	// Default: 30 seconds
+	CircuitBreakerHalfOpenDelay = time.Second * time.Duration(env.Int("CIRCUIT_BREAKER_HALF_OPEN_DELAY_SECONDS", 30))
+
+	// ChannelConfigPath points to a YAML or JSON file (such as a mounted
+	// Kubernetes ConfigMap) whose channels are upserted into the database at
+	// startup and on SIGHUP. Channels from the file are read-only in the admin
+	// UI. A ".json" extension selects JSON, anything else is parsed as YAML.
+	//
+	// Environment variable: CHANNEL_CONFIG_PATH
+	// Default: "" (disabled)
+	ChannelConfigPath = strings.TrimSpace(env.String("CHANNEL_CONFIG_PATH", ""))
+
 	// ChannelTestFrequencyRaw retains the raw CHANNEL_TEST_FREQUENCY input for
 	// validation and documentation purposes.
 	//
@@ -523,11 +549,22 @@ var (
 
 	// PreConsumedQuota sets the default quota reservation for requests to avoid
 	// race conditions where multiple concurrent requests could exceed quota.
+	// Models with billing history reserve an adaptive amount instead, capped at
+	// 10× this value; see billing.GetAdaptivePreConsumeQuota.
 	//
 	// Runtime variable (set via admin UI)
 	// Default: 500
 	PreConsumedQuota int64 = 500
 
+	// HardQuotaProtectionEnabled reserves pre-consumed quota with an atomic
+	// check-and-decrement on the cached user quota in Redis, so that concurrent
+	// requests cannot all pass the quota check before any of them is billed.
+	// Without Redis the optimistic check is used.
+	//
+	// Environment variable: HARD_QUOTA_PROTECTION_ENABLED
+	// Default: false
+	HardQuotaProtectionEnabled = env.Bool("HARD_QUOTA_PROTECTION_ENABLED", false)
+
 	// BillingTimeoutSec is the maximum time allowed for billing reconciliation
 	// before failing the request. Long timeouts support streaming responses.
 	//
@@ -684,6 +721,34 @@ var (
 	BatchUpdateTimeoutSec = env.Int("BATCH_UPDATE_TIMEOUT", 180)
 )
 
+// =============================================================================
+// ASYNC LOG WRITER
+// =============================================================================
+// Settings for writing log entries from a background queue instead of the
+// request path, so billing does not wait on database latency. Pending entries
+// are flushed on graceful shutdown within SHUTDOWN_TIMEOUT.
+
+var (
+	// AsyncLogEnabled queues log writes for background workers. Entries are
+	// written synchronously whenever the queue is full.
+	//
+	// Environment variable: ASYNC_LOG_ENABLED
+	// Default: false
+	AsyncLogEnabled = env.Bool("ASYNC_LOG_ENABLED", false)
+
+	// AsyncLogQueueSize is the number of log entries the queue buffers.
+	//
+	// Environment variable: ASYNC_LOG_QUEUE_SIZE
+	// Default: 10000
+	AsyncLogQueueSize = env.Int("ASYNC_LOG_QUEUE_SIZE", 10000)
+
+	// AsyncLogWorkers is the number of goroutines writing queued log entries.
+	//
+	// Environment variable: ASYNC_LOG_WORKERS
+	// Default: 4
+	AsyncLogWorkers = env.Int("ASYNC_LOG_WORKERS", 4)
+)
+
 // =============================================================================
 // METRICS & MONITORING
 // =============================================================================
@@ -799,6 +864,21 @@ var (
 		return v
 	}()
 
+	// UserRetentionDays controls how long soft-deleted users can be restored
+	// before the retention worker removes them permanently. Their logs are
+	// kept. Set to 0 to keep soft-deleted users forever.
+	//
+	// Environment variable: USER_RETENTION_DAYS
+	// Default: 30 days
+	// Unit: days
+	UserRetentionDays = func() int {
+		v := env.Int("USER_RETENTION_DAYS", 30)
+		if v < 0 {
+			return 0
+		}
+		return v
+	}()
+
 	// LogPushAPI defines the webhook endpoint for escalated log alerts.
 	// Leave empty to disable log push.
 	//
@@ -853,6 +933,15 @@ var (
 	// Example: "price", "quality"
 	OpenrouterProviderSort = env.String("OPENROUTER_PROVIDER_SORT", "")
 
+	// TogetherExtraStopTokens lists stop tokens appended to every Together AI
+	// chat request, for open-weight models whose chat templates end turns with
+	// a special token the upstream does not stop on by itself.
+	//
+	// Environment variable: TOGETHER_EXTRA_STOP_TOKENS
+	// Default: "" (no extra stop tokens)
+	// Example: "<|eot_id|>,<|im_end|>"
+	TogetherExtraStopTokens = env.String("TOGETHER_EXTRA_STOP_TOKENS", "")
+
 	// DefaultMaxToken enforces a global max token value when model-specific
 	// limits are unknown. Acts as a fallback limit.
 	//
@@ -940,6 +1029,28 @@ var (
 	// Environment variable: TEST_MAX_TOKENS
 	// Default: 1024
 	TestMaxTokens = env.Int("TEST_MAX_TOKENS", 1024)
+
+	// ChannelPrewarmOnStart probes the most used channels right after startup,
+	// so the first relayed requests reuse pooled connections instead of paying
+	// the TCP and TLS handshakes. The probes run in the background and never
+	// delay startup.
+	//
+	// Environment variable: CHANNEL_PREWARM_ON_START
+	// Default: false
+	ChannelPrewarmOnStart = env.Bool("CHANNEL_PREWARM_ON_START", false)
+
+	// ChannelPrewarmTopN caps how many channels are pre-warmed, ranked by their
+	// consume logs over the last week.
+	//
+	// Environment variable: CHANNEL_PREWARM_TOP_N
+	// Default: 20
+	ChannelPrewarmTopN = env.Int("CHANNEL_PREWARM_TOP_N", 20)
+
+	// PrewarmConcurrency limits the pre-warm probes in flight at once.
+	//
+	// Environment variable: PREWARM_CONCURRENCY
+	// Default: 5
+	PrewarmConcurrency = env.Int("PREWARM_CONCURRENCY", 5)
 )
 
 // =============================================================================
@@ -975,6 +1086,35 @@ var (
 	// Default: "" (test all variants)
 	// Example: "chat,response,claude"
 	OneAPITestVariants = strings.TrimSpace(env.String("ONEAPI_TEST_VARIANTS", ""))
+
+	// BenchmarkPromptsFile points the cmd/test benchmark at a JSONL file with one
+	// prompt object per line, e.g. {"id":"summary","prompt":"...","max_tokens":256}.
+	//
+	// Environment variable: BENCHMARK_PROMPTS_FILE
+	// Default: "" (required by the benchmark command)
+	BenchmarkPromptsFile = strings.TrimSpace(env.String("BENCHMARK_PROMPTS_FILE", ""))
+
+	// BenchmarkConcurrency limits the prompts the benchmark sends at once per model.
+	//
+	// Environment variable: BENCHMARK_CONCURRENCY
+	// Default: 4
+	BenchmarkConcurrency = env.Int("BENCHMARK_CONCURRENCY", 4)
+
+	// BenchmarkBaselineFile holds the results of a previous benchmark run, in the
+	// benchmark_results.json format, to report regressions against. A missing
+	// file skips the comparison.
+	//
+	// Environment variable: BENCHMARK_BASELINE_FILE
+	// Default: "benchmark_baseline.json"
+	BenchmarkBaselineFile = strings.TrimSpace(env.String("BENCHMARK_BASELINE_FILE", "benchmark_baseline.json"))
+
+	// BenchmarkRegressionThreshold is the relative change versus the baseline
+	// that counts as a regression: throughput dropping, or time to first token
+	// growing, by more than this fraction.
+	//
+	// Environment variable: BENCHMARK_REGRESSION_THRESHOLD
+	// Default: 0.2 (20%)
+	BenchmarkRegressionThreshold = env.Float64("BENCHMARK_REGRESSION_THRESHOLD", 0.2)
 )
 
 // =============================================================================
@@ -1187,6 +1327,20 @@ var (
 		"yahoo.com",
 		"foxmail.com",
 	}
+
+	// EmailDomainBlocklistEnabled rejects registrations from EmailDomainBlocklist.
+	// It is mutually exclusive with EmailDomainRestrictionEnabled.
+	//
+	// Runtime variable (set via admin UI)
+	// Default: false
+	EmailDomainBlocklistEnabled = false
+
+	// EmailDomainBlocklist lists domains rejected when EmailDomainBlocklistEnabled is true.
+	// Entries of the form "*.example.com" block every subdomain of example.com.
+	//
+	// Runtime variable (set via admin UI)
+	// Default: empty
+	EmailDomainBlocklist = []string{}
 )
 
 // SMTP server settings for outbound email (password reset, verification, alerts).
@@ -1358,6 +1512,14 @@ var (
 	// Runtime variable (set via admin UI)
 	// Default: "" (no authentication)
 	MessagePusherToken = ""
+
+	// ExternalBillingWebhookURL receives the usage consumed since the previous
+	// reconciliation every ExternalBillingDefaultTimeoutSec seconds, for
+	// enterprises billing their users through an external payment provider.
+	//
+	// Runtime variable (set via admin UI)
+	// Default: "" (disabled)
+	ExternalBillingWebhookURL = ""
 )
 
 // =============================================================================
@@ -0,0 +1,19 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// COST ANOMALY DETECTION
+// =============================================================================
+// Settings for the hourly job that compares the quota each model consumed in
+// the last hour with the same hour of the previous 14 days and alerts admins
+// on outliers. Detections are listed by GET /api/admin/anomalies/cost.
+
+var (
+	// CostAnomalyDetectionEnabled runs the hourly cost anomaly detection on the
+	// master node and notifies admins through every configured channel.
+	//
+	// Environment variable: COST_ANOMALY_DETECTION_ENABLED
+	// Default: false
+	CostAnomalyDetectionEnabled = env.Bool("COST_ANOMALY_DETECTION_ENABLED", false)
+)
@@ -0,0 +1,32 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// COST REPORT
+// =============================================================================
+// Settings controlling the scheduled cost attribution report emailed to finance
+// teams. The report covers the previous calendar month and can be previewed
+// through GET /api/admin/reports/cost/preview.
+
+var (
+	// CostReportEnabled emails the cost report on CostReportSchedule.
+	//
+	// Environment variable: COST_REPORT_ENABLED
+	// Default: false
+	CostReportEnabled = env.Bool("COST_REPORT_ENABLED", false)
+
+	// CostReportRecipients is the comma-separated list of addresses receiving
+	// the cost report.
+	//
+	// Environment variable: COST_REPORT_RECIPIENTS
+	// Default: "" (no recipients, the report is not sent)
+	CostReportRecipients = env.String("COST_REPORT_RECIPIENTS", "")
+
+	// CostReportSchedule is the five-field cron expression, evaluated in the
+	// server's local time zone, on which the report is sent.
+	//
+	// Environment variable: COST_REPORT_SCHEDULE
+	// Default: "0 0 1 * *" (midnight on the first day of every month)
+	CostReportSchedule = env.String("COST_REPORT_SCHEDULE", "0 0 1 * *")
+)
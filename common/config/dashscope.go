@@ -0,0 +1,21 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// DASHSCOPE
+// =============================================================================
+// Settings for the Alibaba Cloud DashScope API used by the Ali channel type.
+
+var (
+	// DashScopeAPIBase is the default base URL of Ali channels. Set it to
+	// https://dashscope-intl.aliyuncs.com for the international region.
+	//
+	// Environment variable: DASHSCOPE_API_BASE
+	// Default: "https://dashscope.aliyuncs.com"
+	DashScopeAPIBase = strings.TrimRight(strings.TrimSpace(env.String("DASHSCOPE_API_BASE", "https://dashscope.aliyuncs.com")), "/")
+)
@@ -0,0 +1,19 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// EMBEDDINGS
+// =============================================================================
+// Settings controlling post-processing of embedding responses.
+
+var (
+	// EmbeddingTruncationEnabled truncates embedding vectors returned by any
+	// channel to the requested `dimensions` and re-normalizes them (Matryoshka
+	// truncation). Use it when upstreams ignore the `dimensions` parameter.
+	// Individual channels can opt in via the truncate_embeddings channel config.
+	//
+	// Environment variable: EMBEDDING_TRUNCATION_ENABLED
+	// Default: false
+	EmbeddingTruncationEnabled = env.Bool("EMBEDDING_TRUNCATION_ENABLED", false)
+)
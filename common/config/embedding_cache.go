@@ -0,0 +1,25 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// EMBEDDING CACHE
+// =============================================================================
+// Settings for caching embedding vectors in Redis. Texts of a batch request
+// that were embedded before are answered from the cache and only the rest is
+// sent upstream and billed. The cache is inactive without Redis.
+
+var (
+	// EmbeddingCacheEnabled caches the vectors returned for /v1/embeddings
+	// requests whose input is a string or a list of strings.
+	//
+	// Environment variable: EMBEDDING_CACHE_ENABLED
+	// Default: false
+	EmbeddingCacheEnabled = env.Bool("EMBEDDING_CACHE_ENABLED", false)
+
+	// EmbeddingCacheTTLHours is how long a cached vector is kept.
+	//
+	// Environment variable: EMBEDDING_CACHE_TTL_HOURS
+	// Default: 24
+	EmbeddingCacheTTLHours = env.Int("EMBEDDING_CACHE_TTL_HOURS", 24)
+)
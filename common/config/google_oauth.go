@@ -0,0 +1,28 @@
+package config
+
+// =============================================================================
+// GOOGLE OAUTH2 CONFIGURATION
+// =============================================================================
+// Settings for the dedicated Google login flow. Unlike generic OIDC, Google's
+// endpoints are well known, so only client credentials are required.
+
+var (
+	// GoogleOAuthEnabled toggles Google login.
+	// Requires GoogleClientId and GoogleClientSecret.
+	//
+	// Runtime variable (set via admin UI)
+	// Default: false
+	GoogleOAuthEnabled = false
+
+	// GoogleClientId stores the OAuth2 client ID issued by Google Cloud Console.
+	//
+	// Runtime variable (set via admin UI)
+	// Default: "" (disabled)
+	GoogleClientId = ""
+
+	// GoogleClientSecret stores the OAuth2 client secret issued by Google Cloud Console.
+	//
+	// Runtime variable (set via admin UI)
+	// Default: ""
+	GoogleClientSecret = ""
+)
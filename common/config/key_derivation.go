@@ -0,0 +1,56 @@
+package config
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha512"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// SESSION KEY DERIVATION
+// =============================================================================
+// Settings for deriving the session cookie keys from SESSION_SECRET. Secrets
+// are stretched with PBKDF2-SHA512 so that short or low-entropy values still
+// yield strong keys.
+
+const (
+	// SessionKeyDerivationIterations is the PBKDF2 iteration count.
+	SessionKeyDerivationIterations = 100_000
+	// sessionKeyLength is the size of each derived key: HMAC and AES-256.
+	sessionKeyLength = 32
+)
+
+var (
+	// KeyDerivationSalt is the application-wide salt mixed into the session key
+	// derivation. It must stay stable across restarts and instances, otherwise
+	// existing sessions become invalid. When SESSION_SECRET is set, startup fails
+	// without it unless GIN_MODE=debug, where the legacy derivation is used.
+	//
+	// Environment variable: KEY_DERIVATION_SALT
+	// Default: "" (legacy derivation)
+	KeyDerivationSalt = env.String("KEY_DERIVATION_SALT", "")
+
+	// KeyDerivationMigration keeps accepting session cookies signed with the
+	// legacy key while new cookies are signed with the PBKDF2-derived key.
+	// Enable it for at least COOKIE_MAX_AGE_HOURS after introducing
+	// KEY_DERIVATION_SALT so users are not logged out, then disable it.
+	//
+	// Environment variable: KEY_DERIVATION_MIGRATION
+	// Default: false
+	KeyDerivationMigration = env.Bool("KEY_DERIVATION_MIGRATION", false)
+)
+
+// DeriveSessionKeys stretches secret with PBKDF2-SHA512 using salt and returns
+// the HMAC key used to authenticate session cookies and the AES key used to
+// encrypt them. The output only depends on its inputs, so it is stable across
+// restarts.
+func DeriveSessionKeys(secret, salt string) (hashKey, blockKey []byte, err error) {
+	key, err := pbkdf2.Key(sha512.New, secret, []byte(salt), SessionKeyDerivationIterations, 2*sessionKeyLength)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "derive session keys")
+	}
+	return key[:sessionKeyLength], key[sessionKeyLength:], nil
+}
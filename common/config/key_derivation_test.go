@@ -0,0 +1,24 @@
+package config
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveSessionKeys(t *testing.T) {
+	hashKey, blockKey, err := DeriveSessionKeys("short", "one-api-test-salt")
+	require.NoError(t, err)
+	// Pinned PBKDF2-SHA512 output, so keys stay identical across restarts and releases
+	require.Equal(t, "5c86761a59b1742a6bc04a5ee5ed6e0f5c36f7f4500f90905e17445061333290", hex.EncodeToString(hashKey))
+	require.Equal(t, "037f750eda0ad8507b70fa7b5e5f5d2a8ab4256d1021406eb403e1dfd8461979", hex.EncodeToString(blockKey))
+
+	again, _, err := DeriveSessionKeys("short", "one-api-test-salt")
+	require.NoError(t, err)
+	require.Equal(t, hashKey, again)
+
+	otherSalt, _, err := DeriveSessionKeys("short", "another-salt")
+	require.NoError(t, err)
+	require.NotEqual(t, hashKey, otherSalt)
+}
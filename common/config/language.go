@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// RESPONSE LANGUAGE OVERRIDE
+// =============================================================================
+// Settings controlling whether the relay forwards the caller's preferred
+// response language (Accept-Language / X-OneApi-Override-Language) to
+// upstream models that accept an explicit `language` request parameter.
+
+var (
+	// LanguageAwareModels is the set of model names that accept a `language`
+	// parameter in the request body. Only requests targeting these models have
+	// the caller's preferred language injected.
+	//
+	// Environment variable: LANGUAGE_AWARE_MODELS
+	// Default: "" (language injection disabled)
+	// Format: comma-separated model names
+	// Example: "gemini-2.5-pro,gemini-2.5-flash"
+	LanguageAwareModels = parseModelSet(env.String("LANGUAGE_AWARE_MODELS", ""))
+)
+
+// IsLanguageAwareModel reports whether modelName accepts an explicit language
+// parameter and should receive the caller's preferred response language.
+func IsLanguageAwareModel(modelName string) bool {
+	_, ok := LanguageAwareModels[strings.TrimSpace(modelName)]
+	return ok
+}
+
+// parseModelSet converts a comma-separated list of model names into a set,
+// ignoring blank entries and surrounding whitespace.
+func parseModelSet(raw string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for item := range strings.SplitSeq(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			set[item] = struct{}{}
+		}
+	}
+
+	return set
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// LOG COMPRESSION
+// =============================================================================
+// Settings for compacting old log entries to reduce database storage.
+
+var (
+	// LogCompressionDays is the age after which log entries are compressed:
+	// their content is collapsed to a short hash and verbose fields are
+	// cleared, keeping only what quota aggregation needs. Set to 0 to disable
+	// compression.
+	//
+	// Environment variable: LOG_COMPRESSION_DAYS
+	// Default: 0 (disabled)
+	// Unit: days
+	LogCompressionDays = env.Int("LOG_COMPRESSION_DAYS", 0)
+)
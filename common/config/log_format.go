@@ -0,0 +1,30 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// LOG FORMAT
+// =============================================================================
+// Settings for the encoding of application logs.
+
+const (
+	// LogFormatConsole prints human readable, colored log lines.
+	LogFormatConsole = "console"
+	// LogFormatJSON prints one Elastic Common Schema compatible JSON object per
+	// line, ready to be shipped to an ELK stack.
+	LogFormatJSON = "json"
+)
+
+var (
+	// LogFormat selects the encoding of application logs on stdout and in
+	// log files.
+	//
+	// Environment variable: LOG_FORMAT
+	// Default: "console"
+	// Allowed: "console", "json"
+	LogFormat = strings.TrimSpace(strings.ToLower(env.String("LOG_FORMAT", LogFormatConsole)))
+)
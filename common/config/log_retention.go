@@ -0,0 +1,53 @@
+package config
+
+import (
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// LOG RETENTION
+// =============================================================================
+// Settings for removing old log entries from the database, per log type.
+// LOG_RETENTION_DAYS only rotates log files on disk.
+
+var (
+	// ConsumeLogRetentionDays is how long consume log entries are kept before
+	// the retention worker deletes them. Set to 0 to keep them forever.
+	//
+	// Environment variable: CONSUME_LOG_RETENTION_DAYS
+	// Default: 0 (disabled)
+	// Unit: days
+	ConsumeLogRetentionDays = env.Int("CONSUME_LOG_RETENTION_DAYS", 0)
+
+	// ManageLogRetentionDays is how long manage log entries are kept before
+	// the retention worker deletes them. Set to 0 to keep them forever.
+	//
+	// Environment variable: MANAGE_LOG_RETENTION_DAYS
+	// Default: 0 (disabled)
+	// Unit: days
+	ManageLogRetentionDays = env.Int("MANAGE_LOG_RETENTION_DAYS", 0)
+
+	// SystemLogRetentionDays is how long system log entries are kept before
+	// the retention worker deletes them. Set to 0 to keep them forever.
+	//
+	// Environment variable: SYSTEM_LOG_RETENTION_DAYS
+	// Default: 0 (disabled)
+	// Unit: days
+	SystemLogRetentionDays = env.Int("SYSTEM_LOG_RETENTION_DAYS", 0)
+
+	// LoginLogRetentionDays is how long login history entries are kept before
+	// the retention worker deletes them. Set to 0 to keep them forever.
+	//
+	// Environment variable: LOGIN_LOG_RETENTION_DAYS
+	// Default: 90
+	// Unit: days
+	LoginLogRetentionDays = env.Int("LOGIN_LOG_RETENTION_DAYS", 90)
+
+	// RetentionBatchSize caps the log entries a single delete of a retention
+	// cycle removes, so a large backlog is worked off in short batches with a
+	// pause in between instead of locking the table in one long delete.
+	//
+	// Environment variable: RETENTION_BATCH_SIZE
+	// Default: 10000
+	RetentionBatchSize = env.Int("RETENTION_BATCH_SIZE", 10000)
+)
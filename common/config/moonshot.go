@@ -0,0 +1,37 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// MOONSHOT
+// =============================================================================
+// Settings for the Moonshot AI (Kimi) channel type.
+
+var (
+	// MoonshotAPIBase is the default base URL of Moonshot channels, used when a
+	// channel does not set its own.
+	//
+	// Environment variable: MOONSHOT_API_BASE
+	// Default: "https://api.moonshot.cn"
+	MoonshotAPIBase = strings.TrimRight(strings.TrimSpace(env.String("MOONSHOT_API_BASE", "https://api.moonshot.cn")), "/")
+
+	// MoonshotMaxFileSizeMB caps files fetched from file_url content parts
+	// before they are uploaded to Moonshot's files API.
+	//
+	// Environment variable: MOONSHOT_MAX_FILE_SIZE_MB
+	// Default: 100
+	MoonshotMaxFileSizeMB = env.Int("MOONSHOT_MAX_FILE_SIZE_MB", 100)
+
+	// MoonshotInlineImageURLs downloads http(s) images of vision requests and
+	// sends them to Moonshot as base64 data URLs, for images Moonshot cannot
+	// reach itself. Inlined images larger than MaxInlineImageSizeMB are
+	// rejected. Images are only fetched from public addresses.
+	//
+	// Environment variable: MOONSHOT_INLINE_IMAGE_URLS
+	// Default: false
+	MoonshotInlineImageURLs = env.Bool("MOONSHOT_INLINE_IMAGE_URLS", false)
+)
@@ -0,0 +1,26 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// UPSTREAM NETWORK RETRY
+// =============================================================================
+// Settings for retrying upstream requests that fail with transient network
+// errors (connection resets, DNS failures, dial timeouts) before any HTTP
+// response is received. HTTP error responses are never retried here.
+
+var (
+	// NetworkRetryEnabled retries upstream requests on transient network errors
+	// with exponential backoff and jitter.
+	//
+	// Environment variable: NETWORK_RETRY_ENABLED
+	// Default: false
+	NetworkRetryEnabled = env.Bool("NETWORK_RETRY_ENABLED", false)
+
+	// NetworkRetryMaxAttempts is the total number of attempts of one upstream
+	// request, including the first one.
+	//
+	// Environment variable: NETWORK_RETRY_MAX_ATTEMPTS
+	// Default: 3
+	NetworkRetryMaxAttempts = env.Int("NETWORK_RETRY_MAX_ATTEMPTS", 3)
+)
@@ -0,0 +1,19 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// QUEUE DEPTH ROUTING
+// =============================================================================
+// Settings for spreading requests across channels of the same priority by the
+// number of requests each channel is currently serving.
+
+var (
+	// QueueDepthRoutingEnabled prefers channels with fewer in-flight requests
+	// when several channels share the highest priority. Depths are counted in
+	// Redis under channel_queue:{channel_id}, or in memory without Redis.
+	//
+	// Environment variable: QUEUE_DEPTH_ROUTING_ENABLED
+	// Default: false
+	QueueDepthRoutingEnabled = env.Bool("QUEUE_DEPTH_ROUTING_ENABLED", false)
+)
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// QUOTA OVERAGE
+// =============================================================================
+// Settings for letting users spend past their remaining quota.
+
+var (
+	// QuotaOveragePercent lets a request cost up to this percentage more than
+	// the user's remaining quota, leaving the balance negative. 0 rejects
+	// requests costing more than the remaining quota. Reservations made under
+	// HARD_QUOTA_PROTECTION_ENABLED do not take the overage into account.
+	//
+	// Environment variable: QUOTA_OVERAGE_PERCENT
+	// Default: 0
+	QuotaOveragePercent = env.Float64("QUOTA_OVERAGE_PERCENT", 0)
+
+	// QuotaOverageMultiplier scales the part of a request's cost that exceeds
+	// the user's remaining quota.
+	//
+	// Environment variable: QUOTA_OVERAGE_MULTIPLIER
+	// Default: 1.0
+	QuotaOverageMultiplier = env.Float64("QUOTA_OVERAGE_MULTIPLIER", 1.0)
+)
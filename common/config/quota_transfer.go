@@ -0,0 +1,26 @@
+package config
+
+import (
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// QUOTA TRANSFER
+// =============================================================================
+// Settings for moving quota between user accounts.
+
+var (
+	// AllowUserQuotaTransfer lets users transfer their own quota to other
+	// users. Administrators can always transfer quota between any users.
+	//
+	// Environment variable: ALLOW_USER_QUOTA_TRANSFER
+	// Default: false
+	AllowUserQuotaTransfer = env.Bool("ALLOW_USER_QUOTA_TRANSFER", false)
+
+	// MinQuotaTransferAmount is the smallest quota a single transfer may move,
+	// keeping accounts from being spammed with tiny transfers.
+	//
+	// Environment variable: MIN_QUOTA_TRANSFER_AMOUNT
+	// Default: 10000
+	MinQuotaTransferAmount = int64(env.Int("MIN_QUOTA_TRANSFER_AMOUNT", 10000))
+)
@@ -0,0 +1,17 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// REALTIME API
+// =============================================================================
+// Settings for relaying OpenAI Realtime API WebSocket sessions (/v1/realtime).
+
+var (
+	// RealtimeAPIEnabled exposes GET /v1/realtime. Sessions are billed from the
+	// usage reported in response.done events once the WebSocket closes.
+	//
+	// Environment variable: REALTIME_API_ENABLED
+	// Default: true
+	RealtimeAPIEnabled = env.Bool("REALTIME_API_ENABLED", true)
+)
@@ -0,0 +1,18 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// REAL-TIME BILLING EVENTS
+// =============================================================================
+// Settings for publishing token usage to external billing systems over Redis
+// pub/sub as each consume log is recorded.
+
+var (
+	// RealtimeBillingPubSubEnabled publishes a billing event to the Redis
+	// channel oneapi:billing:{user_id} after each consume log. Requires Redis.
+	//
+	// Environment variable: REALTIME_BILLING_PUBSUB_ENABLED
+	// Default: false
+	RealtimeBillingPubSubEnabled = env.Bool("REALTIME_BILLING_PUBSUB_ENABLED", false)
+)
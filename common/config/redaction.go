@@ -0,0 +1,73 @@
+package config
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// REQUEST REDACTION
+// =============================================================================
+// Settings scrubbing sensitive values such as card or social security numbers
+// from message content before request bodies are written to debug logs or
+// captured for replay. Requests sent upstream are never modified.
+
+var (
+	// RequestRedactionPatterns are the regular expressions whose matches in
+	// messages[].content are replaced by RedactionReplacement. They are
+	// compiled once at startup; an invalid pattern stops the server.
+	//
+	// Environment variable: REQUEST_REDACTION_PATTERNS
+	// Default: "" (no redaction)
+	// Format: JSON array of RE2 regular expressions
+	// Example: ["\\b\\d{4}[- ]?\\d{4}[- ]?\\d{4}[- ]?\\d{4}\\b", "\\b\\d{3}-\\d{2}-\\d{4}\\b"]
+	RequestRedactionPatterns = mustLoadRedactionPatterns(env.String("REQUEST_REDACTION_PATTERNS", ""))
+
+	// RedactionReplacement replaces every match of RequestRedactionPatterns.
+	//
+	// Environment variable: REDACTION_REPLACEMENT
+	// Default: "[REDACTED]"
+	RedactionReplacement = env.String("REDACTION_REPLACEMENT", "[REDACTED]")
+)
+
+// ParseRedactionPatterns compiles the JSON array of regular expressions in raw.
+// An empty raw string yields no patterns.
+func ParseRedactionPatterns(raw string) ([]*regexp.Regexp, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var sources []string
+	if err := json.Unmarshal([]byte(raw), &sources); err != nil {
+		return nil, errors.Wrap(err, "parse REQUEST_REDACTION_PATTERNS")
+	}
+	patterns := make([]*regexp.Regexp, 0, len(sources))
+	for _, source := range sources {
+		if source == "" {
+			return nil, errors.New("parse REQUEST_REDACTION_PATTERNS: empty pattern")
+		}
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse REQUEST_REDACTION_PATTERNS: pattern %q", source)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// mustLoadRedactionPatterns compiles REQUEST_REDACTION_PATTERNS at startup and
+// panics on malformed input so misconfiguration fails fast.
+func mustLoadRedactionPatterns(raw string) []*regexp.Regexp {
+	patterns, err := ParseRedactionPatterns(raw)
+	if err != nil {
+		panic(err)
+	}
+
+	return patterns
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRedactionPatterns(t *testing.T) {
+	patterns, err := ParseRedactionPatterns("")
+	require.NoError(t, err)
+	require.Empty(t, patterns)
+
+	patterns, err = ParseRedactionPatterns(`["\\d{3}-\\d{2}-\\d{4}", "(?i)secret"]`)
+	require.NoError(t, err)
+	require.Len(t, patterns, 2)
+	require.True(t, patterns[0].MatchString("SSN 123-45-6789"))
+	require.True(t, patterns[1].MatchString("TOP SECRET"))
+
+	for _, raw := range []string{`"\\d+"`, `["("]`, `[""]`} {
+		_, err = ParseRedactionPatterns(raw)
+		require.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// REQUEST REPLAY CAPTURE
+// =============================================================================
+// Settings controlling whether failed relay requests are persisted so that
+// administrators can replay them against a (possibly different) channel when
+// debugging upstream issues.
+
+var (
+	// RequestReplayCaptureEnabled stores the full request of relay calls that
+	// fail with a 5xx status in the request_traces table. Captured requests can
+	// be replayed through POST /api/admin/replay/:trace_id.
+	//
+	// Environment variable: REQUEST_REPLAY_CAPTURE_ENABLED
+	// Default: false
+	RequestReplayCaptureEnabled = env.Bool("REQUEST_REPLAY_CAPTURE_ENABLED", false)
+
+	// RequestReplayMaxBodyBytes caps the request and response bodies stored per
+	// captured request. Larger request bodies are not captured at all because a
+	// truncated request cannot be replayed; response bodies are truncated.
+	//
+	// Environment variable: REQUEST_REPLAY_MAX_BODY_BYTES
+	// Default: 1048576 (1 MiB)
+	// Unit: bytes
+	RequestReplayMaxBodyBytes = env.Int("REQUEST_REPLAY_MAX_BODY_BYTES", 1<<20)
+)
+
+// =============================================================================
+// REPLAY CORPUS
+// =============================================================================
+// Settings for sampling production relay traffic into the replay_corpus table.
+// The `replay` command of cmd/test replays the corpus against a test instance
+// and reports responses whose structure changed.
+
+var (
+	// ReplayCorpusSampleRate is the fraction of relay requests stored in the
+	// replay corpus together with their response. 0 disables sampling, 1
+	// stores every request. Request and response bodies are redacted with
+	// REQUEST_REDACTION_PATTERNS and capped at REQUEST_REPLAY_MAX_BODY_BYTES.
+	//
+	// Environment variable: REPLAY_CORPUS_SAMPLE_RATE
+	// Default: 0.01
+	ReplayCorpusSampleRate = env.Float64("REPLAY_CORPUS_SAMPLE_RATE", 0.01)
+)
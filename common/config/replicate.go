@@ -0,0 +1,20 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// REPLICATE
+// =============================================================================
+// Settings for the Replicate adaptor. Replicate runs predictions
+// asynchronously: the relay creates a prediction and polls it until it
+// finishes, giving up after RelayTimeout seconds when RelayTimeout is set.
+
+var (
+	// ReplicatePollIntervalMs is the delay between two polls of a running
+	// Replicate prediction.
+	//
+	// Environment variable: REPLICATE_POLL_INTERVAL_MS
+	// Default: 1000
+	// Unit: milliseconds
+	ReplicatePollIntervalMs = env.Int("REPLICATE_POLL_INTERVAL_MS", 1000)
+)
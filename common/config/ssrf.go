@@ -0,0 +1,56 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// CHANNEL SSRF PROTECTION
+// =============================================================================
+// Settings keeping channel base URLs from reaching internal services.
+
+var (
+	// ChannelSSRFProtectionEnabled refuses relay connections to loopback,
+	// private (RFC 1918), link-local and other non-public addresses unless the
+	// channel host is allowlisted. The check runs on the resolved address right
+	// before dialing, so DNS names pointing inward are caught as well. It is
+	// skipped when RELAY_PROXY is set, since the proxy dials upstream.
+	//
+	// Channels already pointing at internal hosts when the protection is first
+	// enabled are added to ChannelSSRFAllowedHosts by a one-time migration.
+	//
+	// Environment variable: CHANNEL_SSRF_PROTECTION_ENABLED
+	// Default: false
+	ChannelSSRFProtectionEnabled = env.Bool("CHANNEL_SSRF_PROTECTION_ENABLED", false)
+
+	// ChannelAllowedDomains lists the hosts channels may reach regardless of
+	// the address they resolve to. An entry also allows its subdomains.
+	//
+	// Environment variable: CHANNEL_ALLOWED_DOMAINS
+	// Default: "" (none)
+	// Format: comma-separated host names or IP addresses
+	// Example: "llm.internal.example.com,10.0.0.8"
+	ChannelAllowedDomains = ParseHostList(env.String("CHANNEL_ALLOWED_DOMAINS", ""))
+
+	// ChannelSSRFAllowedHosts lists the exact channel hosts exempt from the
+	// SSRF protection, seeded with the internal hosts of existing channels.
+	//
+	// Runtime variable (set via admin UI)
+	// Default: empty
+	ChannelSSRFAllowedHosts []string
+)
+
+// ParseHostList converts a comma-separated list of hosts into a slice of
+// lowercase host names, ignoring blank entries and surrounding whitespace.
+func ParseHostList(raw string) []string {
+	var hosts []string
+	for item := range strings.SplitSeq(raw, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			hosts = append(hosts, item)
+		}
+	}
+
+	return hosts
+}
@@ -0,0 +1,31 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// CROSS-REGION STATE SYNC
+// =============================================================================
+// Settings for propagating channel suspensions, user bans and large quota
+// adjustments between regions over Redis pub/sub.
+
+var (
+	// StateSyncEnabled turns on cross-region state synchronization.
+	//
+	// Environment variable: STATE_SYNC_ENABLED
+	// Default: false
+	StateSyncEnabled = env.Bool("STATE_SYNC_ENABLED", false)
+
+	// StateSyncRedisConnString is the Redis shared by all regions. When empty,
+	// REDIS_CONN_STRING is used.
+	//
+	// Environment variable: STATE_SYNC_REDIS_CONN_STRING
+	// Default: ""
+	StateSyncRedisConnString = env.String("STATE_SYNC_REDIS_CONN_STRING", "")
+
+	// StateSyncQuotaThreshold is the quota adjustment, in quota units, above
+	// which the change is announced to other regions.
+	//
+	// Environment variable: STATE_SYNC_QUOTA_THRESHOLD
+	// Default: 10000
+	StateSyncQuotaThreshold = int64(env.Int("STATE_SYNC_QUOTA_THRESHOLD", 10000))
+)
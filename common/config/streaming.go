@@ -0,0 +1,20 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// STREAMING
+// =============================================================================
+// Settings for supervising streaming responses from upstream providers.
+
+var (
+	// StreamingTokenIdleTimeout closes a streaming upstream response when no
+	// tokens arrive for this many seconds. SSE keep-alive comments do not count
+	// as tokens. Channels can override it per model via model_streaming_timeouts.
+	// Unlike IdleTimeout, it only applies while a stream is being relayed.
+	//
+	// Environment variable: STREAMING_TOKEN_IDLE_TIMEOUT
+	// Default: 0 (disabled)
+	// Unit: seconds
+	StreamingTokenIdleTimeout = env.Int("STREAMING_TOKEN_IDLE_TIMEOUT", 0)
+)
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// SUBSCRIPTION TIERS
+// =============================================================================
+// Settings describing what each subscription tier may do on the relay. Tiers
+// are assigned to user groups by administrators (see the GroupTier option);
+// groups without a tier are not subject to any tier restriction.
+
+const (
+	// TierFree is the entry-level tier with strict relay limits.
+	TierFree = "free"
+	// TierPro is the paid tier with relaxed limits and streaming enabled.
+	TierPro = "pro"
+	// TierEnterprise is the unrestricted tier.
+	TierEnterprise = "enterprise"
+)
+
+// TierCapabilities describes the relay features granted to a subscription tier.
+type TierCapabilities struct {
+	// RelayRequestsPerMinute caps relay requests per user per minute. Zero means unlimited.
+	RelayRequestsPerMinute int `json:"relay_requests_per_minute"`
+	// StreamingEnabled reports whether the tier may issue streaming requests.
+	StreamingEnabled bool `json:"streaming_enabled"`
+}
+
+var (
+	// TierCapabilitiesByName maps tier names to their capabilities. Entries
+	// supplied through the environment replace the built-in defaults per tier
+	// once LoadTierCapabilities runs at startup.
+	//
+	// Environment variable: TIER_CAPABILITIES
+	// Default: free=1 rpm/no streaming, pro=50 rpm/streaming, enterprise=unlimited
+	// Format: JSON object keyed by tier name
+	// Example: {"free":{"relay_requests_per_minute":5,"streaming_enabled":false}}
+	TierCapabilitiesByName = defaultTierCapabilities()
+)
+
+// defaultTierCapabilities returns the built-in capabilities for the standard tiers.
+func defaultTierCapabilities() map[string]TierCapabilities {
+	return map[string]TierCapabilities{
+		TierFree:       {RelayRequestsPerMinute: 1, StreamingEnabled: false},
+		TierPro:        {RelayRequestsPerMinute: 50, StreamingEnabled: true},
+		TierEnterprise: {RelayRequestsPerMinute: 0, StreamingEnabled: true},
+	}
+}
+
+// ParseTierCapabilities merges the JSON tier definitions in raw over the
+// built-in defaults. An empty raw string yields the defaults unchanged.
+func ParseTierCapabilities(raw string) (map[string]TierCapabilities, error) {
+	capabilities := defaultTierCapabilities()
+	if strings.TrimSpace(raw) == "" {
+		return capabilities, nil
+	}
+
+	overrides := make(map[string]TierCapabilities)
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return nil, errors.Wrap(err, "parse TIER_CAPABILITIES")
+	}
+	for name, capability := range overrides {
+		if capability.RelayRequestsPerMinute < 0 {
+			return nil, errors.Errorf("parse TIER_CAPABILITIES: tier %q has negative relay_requests_per_minute", name)
+		}
+		capabilities[strings.ToLower(strings.TrimSpace(name))] = capability
+	}
+
+	return capabilities, nil
+}
+
+// LoadTierCapabilities parses TIER_CAPABILITIES into TierCapabilitiesByName.
+// It is called once at startup, which fails on malformed input.
+// TierCapabilitiesByName is left unchanged when it returns an error.
+func LoadTierCapabilities() error {
+	capabilities, err := ParseTierCapabilities(env.String("TIER_CAPABILITIES", ""))
+	if err != nil {
+		return errors.Wrap(err, "load tier capabilities")
+	}
+
+	TierCapabilitiesByName = capabilities
+	return nil
+}
+
+// GetTierCapabilities returns the capabilities granted to tier. Unknown or empty
+// tiers are unrestricted so that groups without an assigned tier keep working.
+func GetTierCapabilities(tier string) TierCapabilities {
+	if capability, ok := TierCapabilitiesByName[strings.ToLower(strings.TrimSpace(tier))]; ok {
+		return capability
+	}
+
+	return TierCapabilities{StreamingEnabled: true}
+}
+
+// IsValidTier reports whether tier names a configured subscription tier.
+func IsValidTier(tier string) bool {
+	_, ok := TierCapabilitiesByName[strings.ToLower(strings.TrimSpace(tier))]
+	return ok
+}
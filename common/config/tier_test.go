@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTierCapabilities(t *testing.T) {
+	capabilities, err := ParseTierCapabilities("")
+	require.NoError(t, err)
+	require.Equal(t, TierCapabilities{RelayRequestsPerMinute: 1}, capabilities[TierFree])
+	require.True(t, capabilities[TierPro].StreamingEnabled)
+	require.Zero(t, capabilities[TierEnterprise].RelayRequestsPerMinute)
+
+	capabilities, err = ParseTierCapabilities(`{"Free":{"relay_requests_per_minute":5,"streaming_enabled":true}}`)
+	require.NoError(t, err)
+	require.Equal(t, TierCapabilities{RelayRequestsPerMinute: 5, StreamingEnabled: true}, capabilities[TierFree])
+	require.Equal(t, 50, capabilities[TierPro].RelayRequestsPerMinute)
+
+	_, err = ParseTierCapabilities(`{"free":{"relay_requests_per_minute":-1}}`)
+	require.Error(t, err)
+	_, err = ParseTierCapabilities(`not json`)
+	require.Error(t, err)
+}
+
+func TestGetTierCapabilities_UnknownTierIsUnrestricted(t *testing.T) {
+	require.Equal(t, TierCapabilities{StreamingEnabled: true}, GetTierCapabilities("platinum"))
+	require.False(t, IsValidTier("platinum"))
+	require.True(t, IsValidTier("PRO"))
+}
+
+func TestLoadTierCapabilities(t *testing.T) {
+	original := TierCapabilitiesByName
+	t.Cleanup(func() { TierCapabilitiesByName = original })
+
+	t.Setenv("TIER_CAPABILITIES", `{"free":{"relay_requests_per_minute":5}}`)
+	require.NoError(t, LoadTierCapabilities())
+	require.Equal(t, 5, GetTierCapabilities(TierFree).RelayRequestsPerMinute)
+
+	// Malformed input is reported instead of panicking and keeps the loaded tiers
+	t.Setenv("TIER_CAPABILITIES", `not json`)
+	require.Error(t, LoadTierCapabilities())
+	require.Equal(t, 5, GetTierCapabilities(TierFree).RelayRequestsPerMinute)
+}
@@ -0,0 +1,18 @@
+package config
+
+import "github.com/songquanpeng/one-api/common/env"
+
+// =============================================================================
+// TOKEN COUNT ACCURACY
+// =============================================================================
+// Settings for comparing locally estimated prompt tokens with upstream usage.
+
+var (
+	// TokenCountDiscrepancyThreshold is the ratio between the estimated and the
+	// upstream-reported prompt tokens (larger over smaller) above which a
+	// discrepancy is logged and counted in one_api_token_count_discrepancy_total.
+	//
+	// Environment variable: TOKEN_COUNT_DISCREPANCY_THRESHOLD
+	// Default: 1.5
+	TokenCountDiscrepancyThreshold = env.Float64("TOKEN_COUNT_DISCREPANCY_THRESHOLD", 1.5)
+)
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// TOKEN HEADERS
+// =============================================================================
+// Settings controlling where the relay looks for API tokens on a request.
+
+var (
+	// TokenHeaderNames lists the request headers checked for an API token, in
+	// order. "Authorization" accepts both "Bearer <token>" and a plain token;
+	// any other header is read as the raw token. The first header holding a
+	// valid token wins.
+	//
+	// Environment variable: TOKEN_HEADER_NAMES
+	// Default: "Authorization,X-Api-Key"
+	// Format: comma-separated header names
+	// Example: "Authorization,X-API-Key,api-key"
+	TokenHeaderNames = parseHeaderNames(env.String("TOKEN_HEADER_NAMES", "Authorization,X-Api-Key"))
+)
+
+// parseHeaderNames converts a comma-separated list of header names into a
+// slice, ignoring blank entries and surrounding whitespace.
+func parseHeaderNames(raw string) []string {
+	var names []string
+	for item := range strings.SplitSeq(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			names = append(names, item)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// TOKEN RATE LIMIT
+// =============================================================================
+// Per-token limits, enforced in addition to GLOBAL_RELAY_RATE_LIMIT.
+
+var (
+	// DefaultTokenRpmLimit caps the relay requests an API token can make per
+	// minute when the token has no rpm_limit of its own. 0 disables the limit.
+	//
+	// Environment variable: DEFAULT_TOKEN_RPM_LIMIT
+	// Default: 0
+	DefaultTokenRpmLimit = env.Int("DEFAULT_TOKEN_RPM_LIMIT", 0)
+
+	// DefaultTokenTpmLimit caps the prompt and completion tokens an API token
+	// can use per minute when the token has no tpm_limit of its own. A request
+	// is rejected once the limit is reached, so the request that crosses it
+	// still completes. 0 disables the limit.
+	//
+	// Environment variable: DEFAULT_TOKEN_TPM_LIMIT
+	// Default: 0
+	DefaultTokenTpmLimit = env.Int("DEFAULT_TOKEN_TPM_LIMIT", 0)
+)
@@ -88,6 +88,21 @@ func ValidateLogRotationInterval(value string) error {
 	return nil
 }
 
+// ValidateLogFormat validates LOG_FORMAT.
+// Allowed values: "console", "json".
+func ValidateLogFormat(value string) error {
+	allowed := []string{LogFormatConsole, LogFormatJSON}
+	if !slices.Contains(allowed, value) {
+		return &ConfigValidationError{
+			Variable:    "LOG_FORMAT",
+			Value:       value,
+			Constraint:  "must be a valid log format",
+			AllowedVals: allowed,
+		}
+	}
+	return nil
+}
+
 // ValidateTheme validates the THEME environment variable.
 // Allowed values: "default", "berry", "air", "modern".
 func ValidateTheme(value string) error {
@@ -273,6 +288,9 @@ func ValidateAllEnvVars() *ValidationResult {
 	if err := ValidateLogRotationInterval(LogRotationInterval); err != nil {
 		result.Errors = append(result.Errors, err)
 	}
+	if err := ValidateLogFormat(LogFormat); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
 	if err := ValidateTheme(Theme); err != nil {
 		result.Errors = append(result.Errors, err)
 	}
@@ -329,6 +347,12 @@ func ValidateAllEnvVars() *ValidationResult {
 	if err := ValidatePositiveInt("TEST_MAX_TOKENS", TestMaxTokens); err != nil {
 		result.Errors = append(result.Errors, err)
 	}
+	if err := ValidatePositiveInt("CHANNEL_PREWARM_TOP_N", ChannelPrewarmTopN); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidatePositiveInt("PREWARM_CONCURRENCY", PrewarmConcurrency); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
 
 	// Non-negative integer validators (can be 0 to disable)
 	if err := ValidateNonNegativeInt("RELAY_TIMEOUT", RelayTimeout); err != nil {
@@ -343,6 +367,14 @@ func ValidateAllEnvVars() *ValidationResult {
 	if err := ValidateNonNegativeInt("BATCH_UPDATE_TIMEOUT", BatchUpdateTimeoutSec); err != nil {
 		result.Errors = append(result.Errors, err)
 	}
+	if AsyncLogEnabled {
+		if err := ValidatePositiveInt("ASYNC_LOG_QUEUE_SIZE", AsyncLogQueueSize); err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+		if err := ValidatePositiveInt("ASYNC_LOG_WORKERS", AsyncLogWorkers); err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+	}
 	if err := ValidateNonNegativeInt("METRIC_QUEUE_SIZE", MetricQueueSize); err != nil {
 		result.Errors = append(result.Errors, err)
 	}
@@ -355,11 +387,59 @@ func ValidateAllEnvVars() *ValidationResult {
 	if err := ValidateNonNegativeInt("PRECONSUME_TOKEN_FOR_BACKGROUND_REQUEST", PreconsumeTokenForBackgroundRequest); err != nil {
 		result.Errors = append(result.Errors, err)
 	}
+	if err := ValidateNonNegativeInt("ADAPTIVE_TIMEOUT_BASE_SECONDS", AdaptiveTimeoutBaseSeconds); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateNonNegativeInt("STATE_SYNC_QUOTA_THRESHOLD", int(StateSyncQuotaThreshold)); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateNonNegativeInt("LOG_COMPRESSION_DAYS", LogCompressionDays); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateNonNegativeInt("CONSUME_LOG_RETENTION_DAYS", ConsumeLogRetentionDays); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateNonNegativeInt("MANAGE_LOG_RETENTION_DAYS", ManageLogRetentionDays); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateNonNegativeInt("SYSTEM_LOG_RETENTION_DAYS", SystemLogRetentionDays); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateNonNegativeInt("LOGIN_LOG_RETENTION_DAYS", LoginLogRetentionDays); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidatePositiveInt("RETENTION_BATCH_SIZE", RetentionBatchSize); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidatePositiveInt("MIN_QUOTA_TRANSFER_AMOUNT", int(MinQuotaTransferAmount)); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
 
 	// Float range validators
 	if err := ValidateFloatRange("METRIC_SUCCESS_RATE_THRESHOLD", MetricSuccessRateThreshold, 0.0, 1.0); err != nil {
 		result.Errors = append(result.Errors, err)
 	}
+	if err := ValidateFloatRange("ADAPTIVE_TIMEOUT_PER_TOKEN_MS", AdaptiveTimeoutPerTokenMs, 0.0, 60000.0); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateFloatRange("BATCH_API_DISCOUNT_RATIO", BatchAPIDiscountRatio, 0.0, 1.0); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateFloatRange("REPLAY_CORPUS_SAMPLE_RATE", ReplayCorpusSampleRate, 0.0, 1.0); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateFloatRange("QUOTA_OVERAGE_PERCENT", QuotaOveragePercent, 0.0, 1000.0); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateFloatRange("QUOTA_OVERAGE_MULTIPLIER", QuotaOverageMultiplier, 0.0, 100.0); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateNonNegativeInt("DEFAULT_TOKEN_RPM_LIMIT", DefaultTokenRpmLimit); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateNonNegativeInt("DEFAULT_TOKEN_TPM_LIMIT", DefaultTokenTpmLimit); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
 
 	// Rate limit validators (must be positive)
 	if err := ValidatePositiveInt("GLOBAL_API_RATE_LIMIT", GlobalApiRateLimitNum); err != nil {
@@ -374,6 +454,27 @@ func ValidateAllEnvVars() *ValidationResult {
 	if err := ValidatePositiveInt("CRITICAL_RATE_LIMIT", CriticalRateLimitNum); err != nil {
 		result.Errors = append(result.Errors, err)
 	}
+	if err := ValidatePositiveInt("USER_CHANNEL_TEST_RATE_LIMIT", UserChannelTestRateLimitNum); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidatePositiveInt("MOONSHOT_MAX_FILE_SIZE_MB", MoonshotMaxFileSizeMB); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidatePositiveInt("BATCH_API_POLL_INTERVAL", BatchAPIPollInterval); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidatePositiveInt("BATCH_API_MAX_REQUESTS", BatchAPIMaxRequests); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidatePositiveInt("NETWORK_RETRY_MAX_ATTEMPTS", NetworkRetryMaxAttempts); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidatePositiveInt("REPLICATE_POLL_INTERVAL_MS", ReplicatePollIntervalMs); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidatePositiveInt("AUTO_DEDUP_WINDOW_SECONDS", AutoDedupWindowSeconds); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
 
 	// String format validators
 	if err := ValidateTokenKeyPrefix(TokenKeyPrefix); err != nil {
@@ -387,6 +488,15 @@ func ValidateAllEnvVars() *ValidationResult {
 	if err := ValidateURLFormat("RELAY_PROXY", RelayProxy); err != nil {
 		result.Errors = append(result.Errors, err)
 	}
+	if err := ValidateURLFormat("MOONSHOT_API_BASE", MoonshotAPIBase); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateURLFormat("DASHSCOPE_API_BASE", DashScopeAPIBase); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	if err := ValidateURLFormat("VOLCENGINE_API_BASE", VolcengineAPIBase); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
 	if err := ValidateURLFormat("USER_CONTENT_REQUEST_PROXY", UserContentRequestProxy); err != nil {
 		result.Errors = append(result.Errors, err)
 	}
@@ -81,6 +81,13 @@ func TestValidateLogRotationInterval(t *testing.T) {
 	}
 }
 
+func TestValidateLogFormat(t *testing.T) {
+	require.NoError(t, ValidateLogFormat("console"))
+	require.NoError(t, ValidateLogFormat("json"))
+	require.Error(t, ValidateLogFormat("logfmt"))
+	require.Error(t, ValidateLogFormat(""))
+}
+
 func TestValidateTheme(t *testing.T) {
 	tests := []struct {
 		name    string
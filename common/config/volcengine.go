@@ -0,0 +1,21 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/songquanpeng/one-api/common/env"
+)
+
+// =============================================================================
+// VOLCENGINE
+// =============================================================================
+// Settings for the Volcengine (ByteDance) Ark API used by the Doubao channel type.
+
+var (
+	// VolcengineAPIBase is the default base URL of Doubao channels, used when a
+	// channel does not set its own.
+	//
+	// Environment variable: VOLCENGINE_API_BASE
+	// Default: "https://ark.cn-beijing.volces.com"
+	VolcengineAPIBase = strings.TrimRight(strings.TrimSpace(env.String("VOLCENGINE_API_BASE", "https://ark.cn-beijing.volces.com")), "/")
+)
@@ -0,0 +1,59 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustParse(t *testing.T, expr string) *Schedule {
+	t.Helper()
+	schedule, err := Parse(expr)
+	require.NoError(t, err)
+	return schedule
+}
+
+func TestNext_Monthly(t *testing.T) {
+	schedule := mustParse(t, "0 0 1 * *")
+
+	next := schedule.Next(time.Date(2026, 3, 15, 10, 30, 0, 0, time.UTC))
+	require.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), next)
+
+	// The activation itself is excluded
+	next = schedule.Next(next)
+	require.Equal(t, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), next)
+
+	next = schedule.Next(time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC))
+	require.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), next)
+}
+
+func TestNext_StepsRangesAndLists(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 7, 0, 0, time.UTC) // a Monday
+
+	require.Equal(t, time.Date(2026, 3, 2, 9, 15, 0, 0, time.UTC), mustParse(t, "*/15 * * * *").Next(start))
+	require.Equal(t, time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC), mustParse(t, "30 9-17/8 * * *").Next(start))
+	require.Equal(t, time.Date(2026, 3, 2, 17, 30, 0, 0, time.UTC), mustParse(t, "30 9-17/8 * * *").Next(start.Add(time.Hour)))
+	require.Equal(t, time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC), mustParse(t, "0 8 * * 3,5").Next(start))
+	// Sunday as 7
+	require.Equal(t, time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), mustParse(t, "0 0 * * 7").Next(start))
+}
+
+func TestNext_DayOfMonthOrDayOfWeek(t *testing.T) {
+	// Fires on the 15th and on every Friday
+	schedule := mustParse(t, "0 0 15 * 5")
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC), schedule.Next(start))
+	require.Equal(t, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), schedule.Next(time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestNext_Never(t *testing.T) {
+	require.True(t, mustParse(t, "0 0 31 2 *").Next(time.Now()).IsZero())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Parse(expr)
+		require.Error(t, err, expr)
+	}
+}
@@ -123,6 +123,12 @@ const (
 	// Read in: image controller logs and metrics.
 	TokenName = "token_name"
 
+	// TokenKey is the key of the API token that authenticated this request,
+	// which need not come from the first token header present.
+	// Set in: middleware/auth.TokenAuth.
+	// Read in: rate limiters, which count requests per token key.
+	TokenKey = "token_key"
+
 	// TokenQuota is the remaining quota on the API token at the time of auth.
 	// Set in: middleware/auth.TokenAuth.
 	// Read in: controllers for pre-consumption logic.
@@ -158,6 +164,11 @@ const (
 	// Read in: async task persistence to capture request context for later diagnostics.
 	AsyncTaskRequestMetadata = "async_task_request_metadata"
 
+	// BatchInput is the validated *relay/model.BatchInput of a Batch API input file upload.
+	// Set in: middleware.BindBatchChannel when POST /v1/files carries purpose=batch.
+	// Read in: relay/controller batch file upload to forward the file without parsing it again.
+	BatchInput = "batch_input"
+
 	// SystemPrompt is a forced/extra system prompt configured on the channel.
 	// Set in: middleware/distributor if channel.SystemPrompt is non-empty.
 	// Read in: text controller to inject as system prompt when present.
@@ -173,6 +184,16 @@ const (
 	// Read in: middleware/rate-limit to enforce QPS/RPM limits.
 	RateLimit = "rate_limit"
 
+	// TokenRpmLimit is the effective requests-per-minute limit of the API token, 0 when unlimited.
+	// Set in: middleware/auth.TokenAuth.
+	// Read in: middleware/rate-limit.TokenRateLimit.
+	TokenRpmLimit = "token_rpm_limit"
+
+	// TokenTpmLimit is the effective tokens-per-minute limit of the API token, 0 when unlimited.
+	// Set in: middleware/auth.TokenAuth.
+	// Read in: middleware/rate-limit.TokenRateLimit.
+	TokenTpmLimit = "token_tpm_limit"
+
 	// ClaudeMessagesConversion flags that this request/response should be converted
 	// between Claude Messages API and another provider format.
 	// Set in: many non-Anthropic adaptors when supporting Claude Messages via conversion.
@@ -254,4 +275,99 @@ const (
 	// Set in: relay/controller/text when initializing a streaming request.
 	// Read in: streaming adaptors to record completion progress and enforce quota limits mid-stream.
 	StreamingQuotaTracker = "streaming_quota_tracker"
+
+	// StreamingTimeout stores the *model.StreamingTimeoutEvent raised when the stream idle watchdog closes the upstream.
+	// Set in: relay/controller stream watchdog when no tokens arrive within the idle timeout.
+	// Read in: relay/controller billing helpers to record the event in log metadata.
+	StreamingTimeout = "streaming_timeout"
+
+	// RequestReceivedAt stores the time.Time at which the request entered the server.
+	// Set in: middleware/tracing.TracingMiddleware.
+	// Read in: relay/controller stream timing to measure elapsed time from request receipt.
+	RequestReceivedAt = "request_received_at"
+
+	// ChannelSelectedAt stores the time.Time at which a channel was selected for the request.
+	// Set in: middleware/distributor.SetupContextForSelectedChannel.
+	// Read in: relay/controller stream timing for the channel_selected event.
+	ChannelSelectedAt = "channel_selected_at"
+
+	// StreamTiming stores the *model.StreamTiming measured for a stream that requested timing events.
+	// Set in: relay/controller stream timing once the stream completes.
+	// Read in: relay/controller billing helpers to record it in log metadata.
+	StreamTiming = "stream_timing"
+
+	// SystemPromptTranslation stores the *model.SystemPromptTranslation performed for the request.
+	// Set in: relay/controller when the channel auto-translates system prompts.
+	// Read in: relay/controller billing helpers to charge it and record it in log metadata.
+	SystemPromptTranslation = "system_prompt_translation"
+
+	// RedactionCount stores the number of sensitive values found in the request messages.
+	// Set in: middleware.RequestRedaction when REQUEST_REDACTION_PATTERNS is configured.
+	// Read in: model.RecordConsumeLog to record it in log metadata.
+	RedactionCount = "redaction_count"
+
+	// DocumentPages stores the number of inline document pages sent upstream.
+	// Set in: relay/adaptor/openai.CountTokenMessages while counting prompt
+	// tokens, or relay/adaptor/gemini.CountDocumentPages when that was skipped.
+	// Read in: model.RecordConsumeLog to record it in log metadata.
+	DocumentPages = "document_pages"
+
+	// EmbeddingCacheInputs stores the number of texts in an embedding request
+	// looked up in the embedding cache.
+	// Set in: relay/controller.RelayTextHelper when the embedding cache is enabled.
+	// Read in: model.RecordConsumeLog to record the hit ratio in log metadata.
+	EmbeddingCacheInputs = "embedding_cache_inputs"
+
+	// EmbeddingCacheHits stores how many of those texts were answered from the cache.
+	// Set in: relay/controller.RelayTextHelper when the embedding cache is enabled.
+	// Read in: model.RecordConsumeLog to record the hit ratio in log metadata.
+	EmbeddingCacheHits = "embedding_cache_hits"
+
+	// ReplicatePredictionID stores the ID of the Replicate prediction that served the request.
+	// Set in: relay/adaptor/replicate handlers once the prediction is created.
+	// Read in: model.RecordConsumeLog to record it in log metadata.
+	ReplicatePredictionID = "replicate_prediction_id"
+
+	// AutoDedupHit marks a chat completion answered with the cached response of
+	// an identical earlier request.
+	// Set in: relay/controller.RelayTextHelper when auto deduplication is enabled.
+	// Read in: model.RecordConsumeLog to record the hit in log metadata.
+	AutoDedupHit = "auto_dedup_hit"
+
+	// CitationCount stores the number of sources upstream cited in its answer.
+	// Set in: relay/adaptor/perplexity handlers when the response carries citations.
+	// Read in: model.RecordConsumeLog to record it in log metadata.
+	CitationCount = "citation_count"
+
+	// UpstreamQueueDurationMs stores how long upstream queued the request, in milliseconds.
+	// Set in: relay/adaptor/fireworks handlers when usage carries queue_duration_ms.
+	// Read in: model.RecordConsumeLog to record it in log metadata.
+	UpstreamQueueDurationMs = "upstream_queue_duration_ms"
+
+	// UpstreamRequestBytes stores the size of the body sent upstream, when known.
+	// Set in: relay/adaptor.DoRequestHelper before forwarding the request.
+	// Read in: relay/controller.captureUpstreamBodySizes for the body size metrics.
+	UpstreamRequestBytes = "upstream_request_bytes"
+
+	// UpstreamResponseBytes stores an *atomic.Int64 counting the bytes read from the upstream response body.
+	// Set in: relay/adaptor.DoRequestHelper once the upstream responded.
+	// Read in: relay/controller.captureUpstreamBodySizes for the body size metrics.
+	UpstreamResponseBytes = "upstream_response_bytes"
+
+	// ResponseLanguage stores the caller's preferred response language for a
+	// language-aware model.
+	// Set in: middleware.InjectResponseLanguage from the language headers.
+	// Read in: relay/controller.applyResponseLanguage to fill the request's language field.
+	ResponseLanguage = "response_language"
+
+	// ResponseLanguageApplied marks a request whose language field was filled
+	// from ResponseLanguage, so the raw body can no longer be forwarded as is.
+	// Set in: relay/controller.applyResponseLanguage.
+	// Read in: relay/controller.getRequestBody to skip the passthrough path.
+	ResponseLanguageApplied = "response_language_applied"
+
+	// ReplayCorpusSampled marks a request sampled into the replay corpus.
+	// Set in: middleware.CaptureReplayCorpus once the sampling decision is made.
+	// Read in: model.CaptureBodySizes to also measure the response body size.
+	ReplayCorpusSampled = "replay_corpus_sampled"
 )
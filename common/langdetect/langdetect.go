@@ -0,0 +1,144 @@
+// Package langdetect is a lightweight language identifier for prompts. It
+// classifies text by Unicode script and separates Latin-script languages by
+// their most frequent function words, which is enough to decide whether a
+// system prompt is already written in a channel's target language.
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minLatinStopwordHits is the number of function words needed before a
+// Latin-script text is attributed to a language other than English.
+const minLatinStopwordHits = 2
+
+// latinStopwords lists frequent function words that are rare in the other
+// supported Latin-script languages.
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "you", "are", "is", "of", "to", "that", "for", "with", "your", "be", "this", "will", "not", "it", "on", "an", "as", "should"},
+	"fr": {"le", "les", "et", "vous", "est", "des", "une", "pour", "dans", "du", "avec", "pas", "ne", "sur", "au", "ce", "qui", "tu", "êtes", "votre"},
+	"de": {"der", "die", "das", "und", "ist", "du", "sie", "nicht", "ein", "eine", "mit", "den", "zu", "auf", "für", "ich", "dem", "bist", "wir", "auch"},
+	"es": {"el", "los", "las", "y", "eres", "una", "por", "con", "para", "del", "lo", "se", "su", "al", "como", "tu", "debes", "usuario", "siempre", "muy"},
+	"pt": {"o", "os", "e", "é", "você", "um", "não", "com", "do", "da", "dos", "em", "ao", "seu", "sua", "deve", "usuário", "sempre", "uma", "mas"},
+	"it": {"il", "gli", "è", "sei", "che", "non", "per", "di", "della", "sono", "nel", "come", "anche", "devi", "utente", "sempre", "una", "tuo", "lo", "ai"},
+}
+
+// latinLanguages fixes the tie-breaking order of Latin-script languages.
+var latinLanguages = []string{"en", "fr", "de", "es", "pt", "it"}
+
+var stopwordLookup = func() map[string][]string {
+	lookup := make(map[string][]string)
+	for _, lang := range latinLanguages {
+		seen := make(map[string]bool)
+		for _, word := range latinStopwords[lang] {
+			if !seen[word] {
+				seen[word] = true
+				lookup[word] = append(lookup[word], lang)
+			}
+		}
+	}
+	return lookup
+}()
+
+// Detect returns the ISO 639-1 code of the dominant language of text, or ""
+// when text contains no letters. Han characters without kana are reported
+// as Chinese.
+func Detect(text string) string {
+	scripts := make(map[string]int)
+	var latinWords []string
+	var word strings.Builder
+	flushWord := func() {
+		if word.Len() > 0 {
+			latinWords = append(latinWords, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range text {
+		if unicode.Is(unicode.Latin, r) {
+			word.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		flushWord()
+		switch {
+		case unicode.Is(unicode.Han, r):
+			scripts["zh"]++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			scripts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		}
+	}
+	flushWord()
+
+	// Japanese mixes kanji into kana text, any noticeable share of kana wins
+	if scripts["ja"] > 0 && scripts["ja"]*5 >= scripts["zh"] {
+		scripts["ja"] += scripts["zh"]
+		scripts["zh"] = 0
+	}
+
+	// Latin text is counted in words, other scripts in characters, as a
+	// single CJK character carries about as much meaning as a Latin word.
+	best, bestCount := "", 0
+	for _, lang := range []string{"zh", "ja", "ko", "ru", "ar", "el", "he", "th", "hi"} {
+		if scripts[lang] > bestCount {
+			best, bestCount = lang, scripts[lang]
+		}
+	}
+	if len(latinWords) > bestCount {
+		return detectLatin(latinWords)
+	}
+	return best
+}
+
+// detectLatin picks the Latin-script language whose function words occur most
+// often, falling back to English.
+func detectLatin(words []string) string {
+	hits := make(map[string]int, len(latinLanguages))
+	for _, w := range words {
+		for _, lang := range stopwordLookup[w] {
+			hits[lang]++
+		}
+	}
+	best, bestHits := "en", hits["en"]
+	for _, lang := range latinLanguages[1:] {
+		if hits[lang] > bestHits && hits[lang] >= minLatinStopwordHits {
+			best, bestHits = lang, hits[lang]
+		}
+	}
+	return best
+}
+
+// IsLanguage reports whether text is written in the language of the BCP 47
+// tag, comparing primary subtags only ("zh-CN" matches any Chinese). Text
+// without letters matches every language.
+func IsLanguage(text, tag string) bool {
+	detected := Detect(text)
+	if detected == "" {
+		return true
+	}
+	return detected == PrimarySubtag(tag)
+}
+
+// PrimarySubtag returns the lowercase language part of a BCP 47 tag, e.g.
+// "zh" for "zh-CN" or "zh_Hans".
+func PrimarySubtag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if idx := strings.IndexAny(tag, "-_"); idx >= 0 {
+		tag = tag[:idx]
+	}
+	return tag
+}
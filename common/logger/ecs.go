@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+	"github.com/Laisky/zap/zapcore"
+)
+
+// ecsVersion is the Elastic Common Schema version JSON logs comply with.
+const ecsVersion = "1.6.0"
+
+// ecsEncoderConfig returns the JSON encoder configuration of LOG_FORMAT=json:
+// an ISO8601 UTC `@timestamp`, the lowercase severity as `level` and
+// camelCase keys for the remaining entry fields.
+func ecsEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "@timestamp",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "message",
+		StacktraceKey:  "stackTrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     encodeISO8601UTC,
+		EncodeDuration: zapcore.MillisDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+func encodeISO8601UTC(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+}
+
+// newECSCore returns a JSON core writing Elastic Common Schema compatible
+// entries to ws. Entries are filtered by the level of enabler.
+func newECSCore(ws zapcore.WriteSyncer, enabler zapcore.LevelEnabler) zapcore.Core {
+	core := ecsCore{Core: zapcore.NewCore(zapcore.NewJSONEncoder(ecsEncoderConfig()), ws, enabler)}
+	return core.With([]zapcore.Field{zap.String("ecs.version", ecsVersion)})
+}
+
+// ecsCore rewrites field keys to camelCase and expands errors into the ECS
+// `error.message` and `error.type` fields before encoding.
+type ecsCore struct {
+	zapcore.Core
+}
+
+func (c ecsCore) With(fields []zapcore.Field) zapcore.Core {
+	return ecsCore{Core: c.Core.With(ecsFields(fields))}
+}
+
+func (c ecsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c ecsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, ecsFields(fields))
+}
+
+// ecsFields returns a copy of fields with camelCase keys and errors encoded
+// as ECS error objects.
+func ecsFields(fields []zapcore.Field) []zapcore.Field {
+	converted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		field.Key = camelCaseKey(field.Key)
+		if field.Type == zapcore.ErrorType {
+			if err, ok := field.Interface.(error); ok {
+				field = zap.Object(field.Key, ecsError{err: err})
+			}
+		}
+		converted[i] = field
+	}
+	return converted
+}
+
+// camelCaseKey converts snake_case and kebab-case keys to camelCase. Dots
+// separating ECS namespaces are kept.
+func camelCaseKey(key string) string {
+	if !strings.ContainsAny(key, "_-") {
+		return key
+	}
+	var b strings.Builder
+	b.Grow(len(key))
+	upperNext := false
+	for _, r := range key {
+		if r == '_' || r == '-' {
+			upperNext = b.Len() > 0
+			continue
+		}
+		if upperNext && r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		upperNext = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ecsError encodes an error as the ECS error object. The type is the one of
+// the innermost wrapped error, which identifies the failure better than the
+// wrappers adding context and stack traces.
+type ecsError struct {
+	err error
+}
+
+func (e ecsError) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	message := e.err.Error()
+	enc.AddString("message", message)
+
+	cause := errors.Cause(e.err)
+	for {
+		wrapper, ok := cause.(interface{ Unwrap() error })
+		if !ok || wrapper.Unwrap() == nil {
+			break
+		}
+		cause = errors.Cause(wrapper.Unwrap())
+	}
+	enc.AddString("type", fmt.Sprintf("%T", cause))
+
+	if verbose := fmt.Sprintf("%+v", e.err); verbose != message {
+		enc.AddString("stackTrace", verbose)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	glog "github.com/Laisky/go-utils/v6/log"
+	"github.com/Laisky/zap"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func TestJSONLogFormatIsECSCompatible(t *testing.T) {
+	originalFormat := config.LogFormat
+	config.LogFormat = config.LogFormatJSON
+	t.Cleanup(func() { config.LogFormat = originalFormat })
+
+	logPath := filepath.Join(t.TempDir(), "oneapi.log")
+	lg, err := newLogger(glog.LevelDebug, []string{logPath}, nil)
+	require.NoError(t, err)
+
+	const lines = 1000
+	rootErr := errors.New("upstream unavailable")
+	reqLogger := lg.With(zap.String("request_id", "req-1"))
+	for i := range lines {
+		switch i % 3 {
+		case 0:
+			reqLogger.Info("relay finished", zap.Int("prompt_tokens", i), zap.Duration("elapsed_time", time.Second))
+		case 1:
+			reqLogger.Warn("relay failed", zap.Error(errors.Wrap(rootErr, "do request")))
+		default:
+			lg.Debug("plain message")
+		}
+	}
+	require.NoError(t, lg.Sync())
+
+	f, err := os.Open(logPath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1<<20), 1<<20)
+	for scanner.Scan() {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry), scanner.Text())
+
+		ts, ok := entry["@timestamp"].(string)
+		require.True(t, ok, "missing @timestamp: %s", scanner.Text())
+		parsed, err := time.Parse(time.RFC3339Nano, ts)
+		require.NoError(t, err)
+		require.True(t, strings.HasSuffix(ts, "Z"), "timestamp %s is not UTC", ts)
+		require.WithinDuration(t, time.Now(), parsed, time.Minute)
+		require.Contains(t, []any{"debug", "info", "warn"}, entry["level"])
+		require.NotEmpty(t, entry["message"])
+		require.Equal(t, "1.6.0", entry["ecs.version"])
+		for key := range entry {
+			require.NotContains(t, key, "_", "key %s is not camelCase", key)
+		}
+
+		switch entry["message"] {
+		case "relay finished":
+			require.Equal(t, "req-1", entry["requestId"])
+			require.Contains(t, entry, "promptTokens")
+			require.InDelta(t, 1000, entry["elapsedTime"], 0)
+		case "relay failed":
+			errField, ok := entry["error"].(map[string]any)
+			require.True(t, ok, "error is not an object: %s", scanner.Text())
+			require.Equal(t, "do request: upstream unavailable", errField["message"])
+			require.NotEmpty(t, errField["type"])
+			require.NotContains(t, errField["type"], "withStack")
+		}
+		count++
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, lines, count)
+}
+
+func TestCamelCaseKey(t *testing.T) {
+	require.Equal(t, "logDir", camelCaseKey("log_dir"))
+	require.Equal(t, "retentionDays", camelCaseKey("retention-days"))
+	require.Equal(t, "ecs.version", camelCaseKey("ecs.version"))
+	require.Equal(t, "userId", camelCaseKey("userId"))
+	require.Equal(t, "private", camelCaseKey("_private"))
+}
@@ -40,7 +40,7 @@ func initLogger() {
 			level = glog.LevelDebug
 		}
 
-		Logger, err = glog.NewConsoleWithName("one-api", level)
+		Logger, err = newLogger(level, []string{"stdout"}, nil)
 		if err != nil {
 			panic(fmt.Sprintf("failed to create logger: %+v", err))
 		}
@@ -121,22 +121,45 @@ func SetupLogger() {
 
 // configureGlobalLogger reinitializes the shared logger with the provided output paths.
 func configureGlobalLogger(outputPaths, errorPaths []string) error {
-	level := Logger.Level()
-	newLogger, err := glog.New(
-		glog.WithName("one-api"),
-		glog.WithLevel(level),
-		glog.WithEncoding(glog.EncodingConsole),
-		glog.WithOutputPaths(outputPaths),
-		glog.WithErrorOutputPaths(errorPaths),
-	)
+	logger, err := newLogger(Logger.Level(), outputPaths, errorPaths)
 	if err != nil {
 		return errors.Wrap(err, "create file logger")
 	}
 
-	Logger = newLogger
+	Logger = logger
 	return nil
 }
 
+// newLogger creates a logger writing to outputPaths in the encoding selected
+// by LOG_FORMAT. Internal zap errors go to errorPaths and stderr.
+func newLogger(level glog.Level, outputPaths, errorPaths []string) (*glog.LoggerT, error) {
+	opts := []glog.Option{
+		glog.WithName("one-api"),
+		glog.WithLevel(level),
+		glog.WithErrorOutputPaths(errorPaths),
+	}
+	if config.LogFormat != config.LogFormatJSON {
+		return glog.New(append(opts,
+			glog.WithEncoding(glog.EncodingConsole),
+			glog.WithOutputPaths(outputPaths),
+		)...)
+	}
+
+	// The encoder config of glog cannot be customized, so its core is built
+	// without outputs and replaced by an ECS core sharing its level.
+	ws, _, err := zap.Open(outputPaths...)
+	if err != nil {
+		return nil, errors.Wrap(err, "open log outputs")
+	}
+	return glog.New(append(opts,
+		glog.WithEncoding(glog.EncodingJSON),
+		glog.WithOutputPaths(nil),
+		glog.WithZapOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newECSCore(ws, core)
+		})),
+	)...)
+}
+
 // applyGinWriters routes Gin's default writers through the structured logger while retaining stdout/stderr output.
 func applyGinWriters() {
 	gin.DefaultWriter = &ginZapWriter{level: zapcore.InfoLevel, fallback: os.Stdout}
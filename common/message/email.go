@@ -175,6 +175,29 @@ func dialSMTPClient(ctx context.Context, addr, localName string) (*smtp.Client,
 // SendEmail transmits an HTML email using the configured SMTP server, authenticating when credentials are provided.
 // It returns an error when the message cannot be constructed or delivered.
 func SendEmail(subject string, receiver string, content string) error {
+	return sendEmail(subject, receiver, "text/html; charset=UTF-8", content)
+}
+
+// SendEmailWithPlainText transmits an email carrying both an HTML and a plain-text
+// rendering of the same content as multipart/alternative, so clients that do not
+// display HTML still show the text version.
+func SendEmailWithPlainText(subject string, receiver string, htmlContent string, textContent string) error {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return errors.Wrap(err, "failed to generate random bytes for MIME boundary")
+	}
+	boundary := fmt.Sprintf("%x", buf)
+	body := fmt.Sprintf("--%s\r\n"+
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n"+
+		"--%s\r\n"+
+		"Content-Type: text/html; charset=UTF-8\r\n\r\n%s\r\n"+
+		"--%s--",
+		boundary, textContent, boundary, htmlContent, boundary)
+	return sendEmail(subject, receiver, fmt.Sprintf("multipart/alternative; boundary=%q", boundary), body)
+}
+
+// sendEmail delivers a message whose body has the given MIME content type.
+func sendEmail(subject string, receiver string, contentType string, content string) error {
 	if receiver == "" {
 		return errors.Errorf("receiver is empty")
 	}
@@ -202,8 +225,9 @@ func SendEmail(subject string, receiver string, content string) error {
 		"Subject: %s\r\n"+
 		"Message-ID: %s\r\n"+
 		"Date: %s\r\n"+
-		"Content-Type: text/html; charset=UTF-8\r\n\r\n%s\r\n",
-		receiver, config.SystemName, config.SMTPFrom, encodedSubject, messageId, time.Now().Format(time.RFC1123Z), content)
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: %s\r\n\r\n%s\r\n",
+		receiver, config.SystemName, config.SMTPFrom, encodedSubject, messageId, time.Now().Format(time.RFC1123Z), contentType, content)
 
 	addr := net.JoinHostPort(config.SMTPServer, fmt.Sprintf("%d", config.SMTPPort))
 
@@ -143,6 +143,45 @@ func TestSendEmailWithoutSTARTTLSNoAuth(t *testing.T) {
 	}
 }
 
+func TestSendEmailWithPlainText(t *testing.T) {
+	captureCh := make(chan smtpCapture, 1)
+	var firstConn atomic.Int32
+
+	addr, shutdown := startMockSMTPServer(t, func(conn net.Conn) {
+		if firstConn.Add(1) == 1 {
+			_ = conn.Close()
+			return
+		}
+
+		capture, err := handlePlainSession(conn)
+		capture.err = err
+		captureCh <- capture
+	})
+	defer shutdown()
+
+	host, port := mustSplitAddr(t, addr)
+
+	restore := overrideSMTPConfig(host, port, "sender@example.com", "", "")
+	defer restore()
+
+	err := SendEmailWithPlainText("Report", "finance@example.com", "<p>html body</p>", "text body")
+	require.NoError(t, err)
+
+	select {
+	case capture := <-captureCh:
+		require.NoError(t, capture.err)
+		require.Contains(t, capture.message, "Content-Type: multipart/alternative; boundary=")
+		require.Contains(t, capture.message, "Content-Type: text/plain; charset=UTF-8")
+		require.Contains(t, capture.message, "text body")
+		require.Contains(t, capture.message, "Content-Type: text/html; charset=UTF-8")
+		require.Contains(t, capture.message, "<p>html body</p>")
+		// The plain-text part comes first so clients prefer the HTML one
+		require.Less(t, strings.Index(capture.message, "text body"), strings.Index(capture.message, "<p>html body</p>"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for SMTP capture")
+	}
+}
+
 func TestSendEmailWithoutSTARTTLSAuthAllowed(t *testing.T) {
 	captureCh := make(chan smtpCapture, 1)
 	var firstConn atomic.Int32
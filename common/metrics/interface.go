@@ -16,6 +16,7 @@ type MetricsRecorder interface {
 	// Channel metrics
 	UpdateChannelMetrics(channelId int, channelName, channelType string, status int, balance float64, responseTimeMs int, successRate float64)
 	UpdateChannelRequestsInFlight(channelId int, channelName, channelType string, delta float64)
+	UpdateChannelConcurrentRequests(channelId int, delta float64)
 
 	// User metrics
 	RecordUserMetrics(userId, username, group string, quotaUsed float64, promptTokens, completionTokens int, balance float64)
@@ -23,6 +24,8 @@ type MetricsRecorder interface {
 	// Database metrics
 	RecordDBQuery(startTime time.Time, operation, table string, success bool)
 	UpdateDBConnectionMetrics(inUse, idle int)
+	UpdateLogQueueDepth(depth int)
+	RecordLogRetentionDeleted(logType string, deleted int64)
 
 	// Redis metrics
 	RecordRedisCommand(startTime time.Time, command string, success bool)
@@ -41,6 +44,24 @@ type MetricsRecorder interface {
 
 	// Model metrics
 	RecordModelUsage(modelName, channelType string, latency time.Duration)
+	RecordTokenCountDiscrepancy(modelName string, ratio float64, exceeded bool)
+	RecordUpstreamBodySizes(modelName string, channelId int, responseType string, requestBytes, responseBytes int64)
+
+	// Config metrics
+	RecordConfigReload(success bool)
+
+	// Embedding cache metrics
+	RecordEmbeddingCacheLookup(modelName string, hits, total int)
+
+	// Auto deduplication metrics
+	RecordAutoDedupHit(modelName string)
+
+	// Blacklist metrics
+	RecordBlacklistLookup(result string)
+
+	// Cost allocation metrics
+	RecordQuotaConsumed(userGroup, modelName string, quota int64)
+	UpdateActiveUsers(activeUsers map[string]int)
 
 	// Billing metrics
 	RecordBillingOperation(startTime time.Time, operation string, success bool, userId int, channelId int, modelName string, quotaAmount float64)
@@ -110,6 +131,40 @@ func (n *NoOpRecorder) RecordError(errorType, component string) {}
 // RecordModelUsage implements MetricsRecorder.RecordModelUsage without collecting any data.
 func (n *NoOpRecorder) RecordModelUsage(modelName, channelType string, latency time.Duration) {}
 
+// RecordTokenCountDiscrepancy implements MetricsRecorder.RecordTokenCountDiscrepancy without collecting any data.
+func (n *NoOpRecorder) RecordTokenCountDiscrepancy(modelName string, ratio float64, exceeded bool) {}
+
+// RecordUpstreamBodySizes implements MetricsRecorder.RecordUpstreamBodySizes without collecting any data.
+func (n *NoOpRecorder) RecordUpstreamBodySizes(modelName string, channelId int, responseType string, requestBytes, responseBytes int64) {
+}
+
+// RecordConfigReload implements MetricsRecorder.RecordConfigReload without collecting any data.
+func (n *NoOpRecorder) RecordConfigReload(success bool) {}
+
+// RecordEmbeddingCacheLookup implements MetricsRecorder.RecordEmbeddingCacheLookup without collecting any data.
+func (n *NoOpRecorder) RecordEmbeddingCacheLookup(modelName string, hits, total int) {}
+
+// UpdateChannelConcurrentRequests implements MetricsRecorder.UpdateChannelConcurrentRequests without collecting any data.
+func (n *NoOpRecorder) UpdateChannelConcurrentRequests(channelId int, delta float64) {}
+
+// UpdateLogQueueDepth implements MetricsRecorder.UpdateLogQueueDepth without collecting any data.
+func (n *NoOpRecorder) UpdateLogQueueDepth(depth int) {}
+
+// RecordLogRetentionDeleted implements MetricsRecorder.RecordLogRetentionDeleted without collecting any data.
+func (n *NoOpRecorder) RecordLogRetentionDeleted(logType string, deleted int64) {}
+
+// RecordAutoDedupHit implements MetricsRecorder.RecordAutoDedupHit without collecting any data.
+func (n *NoOpRecorder) RecordAutoDedupHit(modelName string) {}
+
+// RecordBlacklistLookup implements MetricsRecorder.RecordBlacklistLookup without collecting any data.
+func (n *NoOpRecorder) RecordBlacklistLookup(result string) {}
+
+// RecordQuotaConsumed implements MetricsRecorder.RecordQuotaConsumed without collecting any data.
+func (n *NoOpRecorder) RecordQuotaConsumed(userGroup, modelName string, quota int64) {}
+
+// UpdateActiveUsers implements MetricsRecorder.UpdateActiveUsers without collecting any data.
+func (n *NoOpRecorder) UpdateActiveUsers(activeUsers map[string]int) {}
+
 // RecordBillingOperation implements MetricsRecorder.RecordBillingOperation without collecting any data.
 func (n *NoOpRecorder) RecordBillingOperation(startTime time.Time, operation string, success bool, userId int, channelId int, modelName string, quotaAmount float64) {
 }
@@ -0,0 +1,14 @@
+package metrics
+
+import "strings"
+
+// ModelFamily returns the family of modelName used as a metric label: the
+// lowercased prefix before the first "-", e.g. "gpt" for "gpt-4o" and
+// "claude" for "claude-sonnet-4-5". Names without "-" are their own family.
+func ModelFamily(modelName string) string {
+	family, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(modelName)), "-")
+	if family == "" {
+		return "unknown"
+	}
+	return family
+}
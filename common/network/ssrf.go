@@ -0,0 +1,176 @@
+package network
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// ErrNonPublicAddress is returned when the channel SSRF protection refuses a
+// connection to a non-public address.
+var ErrNonPublicAddress = errors.New("connection to non-public address blocked by channel SSRF protection")
+
+// nonPublicBlocks are the special-purpose ranges the net.IP predicates do not
+// cover, such as carrier-grade NAT and benchmarking networks.
+var nonPublicBlocks = func() []*net.IPNet {
+	var blocks []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",
+		"100.64.0.0/10",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"240.0.0.0/4",
+		"2001:db8::/32",
+	} {
+		_, block, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}()
+
+// IsPublicIP reports whether ip is a globally routable unicast address, that
+// is not loopback, private (RFC 1918 or IPv6 unique local), link-local,
+// multicast, unspecified or reserved for special use.
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, block := range nonPublicBlocks {
+		if block.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsHostAllowed reports whether the channel SSRF protection exempts host,
+// either through CHANNEL_ALLOWED_DOMAINS, which also covers subdomains, or
+// through the ChannelSSRFAllowedHosts option.
+func IsHostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, domain := range config.ChannelAllowedDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return slices.Contains(config.ChannelSSRFAllowedHosts, host)
+}
+
+// CheckPublicHost resolves host and returns ErrNonPublicAddress if any of its
+// addresses is not public, unless the host is allowlisted.
+func CheckPublicHost(ctx context.Context, host string) error {
+	if IsHostAllowed(host) {
+		return nil
+	}
+	return CheckStrictPublicHost(ctx, host)
+}
+
+// CheckStrictPublicHost is CheckPublicHost without the allowlist. It guards
+// hosts chosen by users, which must never reach addresses only admins may
+// exempt.
+func CheckStrictPublicHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return errors.Wrapf(err, "resolve host %s", host)
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return errors.Wrapf(ErrNonPublicAddress, "%s resolves to %s", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// CheckPublicURL runs CheckPublicHost on the host of rawURL.
+func CheckPublicURL(ctx context.Context, rawURL string) error {
+	host, err := urlHost(rawURL)
+	if err != nil {
+		return err
+	}
+	return CheckPublicHost(ctx, host)
+}
+
+// CheckStrictPublicURL runs CheckStrictPublicHost on the host of rawURL.
+func CheckStrictPublicURL(ctx context.Context, rawURL string) error {
+	host, err := urlHost(rawURL)
+	if err != nil {
+		return err
+	}
+	return CheckStrictPublicHost(ctx, host)
+}
+
+// urlHost returns the host name of rawURL.
+func urlHost(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", errors.Wrap(err, "parse url")
+	}
+	if parsed.Hostname() == "" {
+		return "", errors.Errorf("url %q has no host", rawURL)
+	}
+	return parsed.Hostname(), nil
+}
+
+// GuardedDialContext wraps dialer so that connections to hosts that are not
+// allowlisted fail with ErrNonPublicAddress when the resolved address is not
+// public. The address is checked right before the socket connects, so DNS
+// answers changing between validation and use cannot bypass it.
+func GuardedDialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return guardedDialContext(dialer, true)
+}
+
+// StrictGuardedDialContext is GuardedDialContext without the allowlist, for
+// connections to hosts chosen by users.
+func StrictGuardedDialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return guardedDialContext(dialer, false)
+}
+
+// guardedDialContext implements GuardedDialContext, exempting allowlisted
+// hosts only when honorAllowlist is set.
+func guardedDialContext(dialer *net.Dialer, honorAllowlist bool) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, errors.Wrap(err, "split dial address")
+		}
+		if honorAllowlist && IsHostAllowed(host) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		guarded := *dialer
+		guarded.Control = func(_, address string, _ syscall.RawConn) error {
+			ip, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return errors.Wrap(err, "split resolved address")
+			}
+			if !IsPublicIP(net.ParseIP(ip)) {
+				logger.Logger.Warn("security event: blocked connection to non-public address",
+					zap.String("event", "ssrf_blocked"),
+					zap.String("host", host),
+					zap.String("ip", ip))
+				return errors.Wrapf(ErrNonPublicAddress, "%s resolves to %s", host, ip)
+			}
+			return nil
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+}
@@ -0,0 +1,97 @@
+package network
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		require.True(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{
+		"127.0.0.1", "::1", "0.0.0.0", "10.1.2.3", "172.16.0.1", "192.168.0.1",
+		"169.254.169.254", "100.64.0.1", "fd00::1", "fe80::1", "224.0.0.1", "::ffff:192.168.0.1",
+	} {
+		require.False(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	require.False(t, IsPublicIP(nil))
+}
+
+func TestIsHostAllowed(t *testing.T) {
+	originalDomains, originalHosts := config.ChannelAllowedDomains, config.ChannelSSRFAllowedHosts
+	t.Cleanup(func() {
+		config.ChannelAllowedDomains, config.ChannelSSRFAllowedHosts = originalDomains, originalHosts
+	})
+	config.ChannelAllowedDomains = []string{"internal.example.com"}
+	config.ChannelSSRFAllowedHosts = []string{"192.168.0.1"}
+
+	require.True(t, IsHostAllowed("internal.example.com"))
+	require.True(t, IsHostAllowed("LLM.Internal.Example.com."))
+	require.True(t, IsHostAllowed("192.168.0.1"))
+	require.False(t, IsHostAllowed("notinternal.example.com"))
+	require.False(t, IsHostAllowed("192.168.0.2"))
+
+	require.NoError(t, CheckPublicURL(context.Background(), "http://192.168.0.1:8080/v1"))
+	require.ErrorIs(t, CheckPublicURL(context.Background(), "http://192.168.0.2"), ErrNonPublicAddress)
+	require.ErrorIs(t, CheckPublicURL(context.Background(), "http://127.0.0.1"), ErrNonPublicAddress)
+	require.NoError(t, CheckPublicURL(context.Background(), "https://8.8.8.8"))
+}
+
+func TestGuardedDialContext(t *testing.T) {
+	originalDomains, originalHosts := config.ChannelAllowedDomains, config.ChannelSSRFAllowedHosts
+	t.Cleanup(func() {
+		config.ChannelAllowedDomains, config.ChannelSSRFAllowedHosts = originalDomains, originalHosts
+	})
+	config.ChannelAllowedDomains, config.ChannelSSRFAllowedHosts = nil, nil
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{DialContext: GuardedDialContext(&net.Dialer{})}}
+	for _, url := range []string{server.URL, "http://localhost:" + port} {
+		_, err = client.Get(url)
+		require.Error(t, err, url)
+		require.True(t, errors.Is(err, ErrNonPublicAddress), url)
+	}
+
+	config.ChannelAllowedDomains = []string{"127.0.0.1"}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestStrictChecksIgnoreAllowlist(t *testing.T) {
+	originalDomains, originalHosts := config.ChannelAllowedDomains, config.ChannelSSRFAllowedHosts
+	t.Cleanup(func() {
+		config.ChannelAllowedDomains, config.ChannelSSRFAllowedHosts = originalDomains, originalHosts
+	})
+	config.ChannelAllowedDomains = []string{"127.0.0.1"}
+	config.ChannelSSRFAllowedHosts = []string{"127.0.0.1", "192.168.0.1"}
+
+	require.ErrorIs(t, CheckStrictPublicURL(context.Background(), "http://192.168.0.1:8080/v1"), ErrNonPublicAddress)
+	require.NoError(t, CheckStrictPublicURL(context.Background(), "https://8.8.8.8"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{DialContext: StrictGuardedDialContext(&net.Dialer{})}}
+	_, err := client.Get(server.URL)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrNonPublicAddress))
+}
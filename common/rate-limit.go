@@ -73,3 +73,25 @@ func (l *InMemoryRateLimiter) Request(key string, maxRequestNum int, duration in
 	}
 	return true
 }
+
+// Usage reports how many requests the key made within the last duration
+// seconds and the timestamp of the oldest of them, which is 0 when there are
+// none.
+func (l *InMemoryRateLimiter) Usage(key string, duration int64) (used int, oldest int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	queue, ok := l.store[key]
+	if !ok {
+		return 0, 0
+	}
+	now := time.Now().Unix()
+	for _, ts := range *queue {
+		if now-ts < duration {
+			if used == 0 {
+				oldest = ts
+			}
+			used++
+		}
+	}
+	return used, oldest
+}
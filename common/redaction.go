@@ -0,0 +1,122 @@
+package common
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+// RedactRequestBody returns body with every match of patterns in the
+// messages[].content strings, including the text of content parts, replaced by
+// config.RedactionReplacement. Other fields are left alone, and body is
+// returned unchanged when it is not a JSON object or nothing matches. It is
+// meant for copies of the request written to logs and traces, never for the
+// request sent upstream.
+func RedactRequestBody(body []byte, patterns []*regexp.Regexp) []byte {
+	redacted, _ := RedactRequestBodyWithCount(body, patterns)
+	return redacted
+}
+
+// RedactRequestBodyWithCount is RedactRequestBody that also returns the number
+// of replacements made.
+func RedactRequestBodyWithCount(body []byte, patterns []*regexp.Regexp) ([]byte, int) {
+	if len(patterns) == 0 {
+		return body, 0
+	}
+
+	var request map[string]json.RawMessage
+	if err := json.Unmarshal(body, &request); err != nil {
+		return body, 0
+	}
+	var messages []map[string]json.RawMessage
+	if err := json.Unmarshal(request["messages"], &messages); err != nil {
+		return body, 0
+	}
+
+	total := 0
+	for _, message := range messages {
+		content, count := redactMessageContent(message["content"], patterns)
+		if count > 0 {
+			message["content"] = content
+			total += count
+		}
+	}
+	if total == 0 {
+		return body, 0
+	}
+
+	redactedMessages, err := json.Marshal(messages)
+	if err != nil {
+		return body, 0
+	}
+	request["messages"] = redactedMessages
+	redacted, err := json.Marshal(request)
+	if err != nil {
+		return body, 0
+	}
+	return redacted, total
+}
+
+// RedactAll returns data with every match of patterns replaced by
+// config.RedactionReplacement, wherever it occurs. It is meant for bodies that
+// RedactRequestBody does not cover, such as responses and previews cut off
+// mid-JSON.
+func RedactAll(data []byte, patterns []*regexp.Regexp) []byte {
+	for _, pattern := range patterns {
+		data = pattern.ReplaceAllLiteral(data, []byte(config.RedactionReplacement))
+	}
+	return data
+}
+
+// redactMessageContent redacts a message content that is either a string or
+// an array of content parts.
+func redactMessageContent(raw json.RawMessage, patterns []*regexp.Regexp) (json.RawMessage, int) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return redactJSONString(text, patterns)
+	}
+
+	var parts []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return raw, 0
+	}
+	total := 0
+	for _, part := range parts {
+		if err := json.Unmarshal(part["text"], &text); err != nil {
+			continue
+		}
+		redacted, count := redactJSONString(text, patterns)
+		if count > 0 {
+			part["text"] = redacted
+			total += count
+		}
+	}
+	if total == 0 {
+		return raw, 0
+	}
+	redacted, err := json.Marshal(parts)
+	if err != nil {
+		return raw, 0
+	}
+	return redacted, total
+}
+
+// redactJSONString replaces the matches of patterns in text and returns the
+// result encoded as a JSON string.
+func redactJSONString(text string, patterns []*regexp.Regexp) (json.RawMessage, int) {
+	total := 0
+	for _, pattern := range patterns {
+		count := len(pattern.FindAllStringIndex(text, -1))
+		if count == 0 {
+			continue
+		}
+		text = pattern.ReplaceAllLiteralString(text, config.RedactionReplacement)
+		total += count
+	}
+	encoded, err := json.Marshal(text)
+	if err != nil {
+		return nil, 0
+	}
+	return encoded, total
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var cardNumberPattern = regexp.MustCompile(`\b\d{4}[ -]?\d{4}[ -]?\d{4}[ -]?\d{4}\b`)
+
+func TestRedactRequestBody_CardNumbers(t *testing.T) {
+	body := []byte(`{
+		"model": "gpt-4o",
+		"temperature": 0.25,
+		"messages": [
+			{"role": "system", "content": "Order 1234 is pending."},
+			{"role": "user", "content": "My card is 4111 1111 1111 1111, backup 5500-0000-0000-0004."},
+			{"role": "user", "content": [
+				{"type": "text", "text": "Charge \"4242424242424242\" please"},
+				{"type": "image_url", "image_url": {"url": "https://example.com/1234123412341234.png"}}
+			]}
+		]
+	}`)
+
+	redacted, count := RedactRequestBodyWithCount(body, []*regexp.Regexp{cardNumberPattern})
+	require.Equal(t, 3, count)
+	require.True(t, json.Valid(redacted), string(redacted))
+
+	var request struct {
+		Model       string  `json:"model"`
+		Temperature float64 `json:"temperature"`
+		Messages    []struct {
+			Role    string `json:"role"`
+			Content any    `json:"content"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(redacted, &request))
+	require.Equal(t, "gpt-4o", request.Model)
+	require.Equal(t, 0.25, request.Temperature)
+	require.Len(t, request.Messages, 3)
+	require.Equal(t, "Order 1234 is pending.", request.Messages[0].Content)
+	require.Equal(t, "My card is [REDACTED], backup [REDACTED].", request.Messages[1].Content)
+	parts := request.Messages[2].Content.([]any)
+	require.Equal(t, `Charge "[REDACTED]" please`, parts[0].(map[string]any)["text"])
+	// Only message text is redacted
+	require.Equal(t, "https://example.com/1234123412341234.png",
+		parts[1].(map[string]any)["image_url"].(map[string]any)["url"])
+
+	require.Equal(t, redacted, RedactRequestBody(body, []*regexp.Regexp{cardNumberPattern}))
+}
+
+func TestRedactRequestBody_Unchanged(t *testing.T) {
+	patterns := []*regexp.Regexp{cardNumberPattern}
+	for _, body := range []string{
+		`{"messages":[{"role":"user","content":"nothing to hide"}]}`,
+		`{"input":"4111 1111 1111 1111"}`,
+		`not json 4111 1111 1111 1111`,
+		`[{"content":"4111 1111 1111 1111"}]`,
+	} {
+		redacted, count := RedactRequestBodyWithCount([]byte(body), patterns)
+		require.Zero(t, count, body)
+		require.Equal(t, body, string(redacted))
+	}
+
+	body := []byte(`{"messages":[{"role":"user","content":"4111 1111 1111 1111"}]}`)
+	require.Equal(t, body, RedactRequestBody(body, nil))
+}
+
+func TestRedactAll_TruncatedBody(t *testing.T) {
+	preview := []byte(`{"messages":[{"role":"user","content":"My card is 4111 1111 1111 1111, backup 5500-0000`)
+
+	redacted := RedactAll(preview, []*regexp.Regexp{cardNumberPattern})
+	require.Equal(t, `{"messages":[{"role":"user","content":"My card is [REDACTED], backup 5500-0000`, string(redacted))
+	require.Equal(t, preview, RedactAll(preview, nil))
+}
@@ -17,6 +17,9 @@ import (
 // RDB exposes the active Redis client used throughout the application.
 var RDB redis.Cmdable
 
+// ErrRedisKeyNotFound reports a conditional update of a key that does not exist.
+var ErrRedisKeyNotFound = errors.New("redis key not found")
+
 var redisEnabled atomic.Bool
 
 func init() {
@@ -120,6 +123,34 @@ func RedisDel(ctx context.Context, key string) error {
 	return nil
 }
 
+// redisDecreaseIfEnoughScript decrements KEYS[1] by ARGV[1] unless less than
+// ARGV[1] is left. It returns 1 when decremented, 0 when not enough is left and
+// -1 when the key does not exist.
+var redisDecreaseIfEnoughScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if not cur then return -1 end
+if tonumber(cur) < tonumber(ARGV[1]) then return 0 end
+redis.call('DECRBY', KEYS[1], ARGV[1])
+return 1
+`)
+
+// RedisDecreaseIfEnough atomically decreases the numeric value stored at key by
+// value unless the stored value is lower than value. It reports whether the
+// value was decreased; a missing key returns ErrRedisKeyNotFound.
+func RedisDecreaseIfEnough(ctx context.Context, key string, value int64) (bool, error) {
+	if RDB == nil {
+		return false, errors.New("redis not initialized")
+	}
+	result, err := redisDecreaseIfEnoughScript.Run(ctx, RDB, []string{key}, value).Int64()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to decrease redis key: %s by %d", key, value)
+	}
+	if result < 0 {
+		return false, errors.Wrapf(ErrRedisKeyNotFound, "decrease redis key: %s", key)
+	}
+	return result == 1, nil
+}
+
 // RedisDecrease atomically decreases the numeric value stored at key by the provided delta.
 func RedisDecrease(ctx context.Context, key string, value int64) error {
 	if RDB == nil {
@@ -131,3 +162,25 @@ func RedisDecrease(ctx context.Context, key string, value int64) error {
 	}
 	return nil
 }
+
+// redisIncreaseIfExistsScript increments KEYS[1] by ARGV[1] only when the key
+// exists, keeping its expiration. It returns 1 when incremented and 0 when the
+// key does not exist.
+var redisIncreaseIfExistsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then return 0 end
+redis.call('INCRBY', KEYS[1], ARGV[1])
+return 1
+`)
+
+// RedisIncreaseIfExists atomically increases the numeric value stored at key by
+// value. A missing key is left missing, so a cache entry expiring concurrently
+// is not recreated without its expiration.
+func RedisIncreaseIfExists(ctx context.Context, key string, value int64) error {
+	if RDB == nil {
+		return errors.New("redis not initialized")
+	}
+	if err := redisIncreaseIfExistsScript.Run(ctx, RDB, []string{key}, value).Err(); err != nil {
+		return errors.Wrapf(err, "failed to increase redis key: %s by %d", key, value)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"encoding/base64"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// legacySessionKeyPair returns the cookie keys used before PBKDF2 derivation:
+// the base64-decoded session secret as both HMAC and AES key, or the raw
+// secret as HMAC key only when it is not base64.
+func legacySessionKeyPair(secret string) [][]byte {
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return [][]byte{[]byte(secret), nil}
+	}
+	return [][]byte{decoded, decoded}
+}
+
+// SessionKeyPairs returns the hash/block key pairs of the session cookie store.
+// The first pair signs new cookies; later pairs are only accepted when reading
+// cookies. A configured SESSION_SECRET is stretched with PBKDF2 using
+// KEY_DERIVATION_SALT, which is required outside GIN_MODE=debug. During
+// KEY_DERIVATION_MIGRATION cookies signed with the legacy key remain valid.
+func SessionKeyPairs() ([][]byte, error) {
+	legacy := legacySessionKeyPair(config.SessionSecret)
+	if config.SessionSecretEnvValue == "" {
+		// Random per-process secret, there is nothing to derive or migrate
+		return legacy, nil
+	}
+
+	if config.KeyDerivationSalt == "" {
+		if config.GinMode != gin.DebugMode {
+			return nil, errors.New("KEY_DERIVATION_SALT must be set when SESSION_SECRET is configured outside GIN_MODE=debug. " +
+				"Set a random KEY_DERIVATION_SALT together with KEY_DERIVATION_MIGRATION=true to upgrade without logging users out")
+		}
+		logger.Logger.Warn("KEY_DERIVATION_SALT is not set, session keys use the legacy SESSION_SECRET derivation")
+		return legacy, nil
+	}
+
+	hashKey, blockKey, err := config.DeriveSessionKeys(config.SessionSecretEnvValue, config.KeyDerivationSalt)
+	if err != nil {
+		return nil, errors.Wrap(err, "derive session keys")
+	}
+	pairs := [][]byte{hashKey, blockKey}
+	if config.KeyDerivationMigration {
+		logger.Logger.Warn("KEY_DERIVATION_MIGRATION is enabled, session cookies signed with the legacy key are still accepted. " +
+			"Disable it once existing sessions have expired.")
+		pairs = append(pairs, legacy...)
+	}
+	return pairs, nil
+}
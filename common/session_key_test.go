@@ -0,0 +1,120 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func setSessionKeyConfig(t *testing.T, secret, salt string, migration bool) {
+	t.Helper()
+	originalEnv, originalSecret := config.SessionSecretEnvValue, config.SessionSecret
+	originalSalt, originalMigration := config.KeyDerivationSalt, config.KeyDerivationMigration
+	originalGinMode := config.GinMode
+	config.SessionSecretEnvValue, config.SessionSecret = secret, secret
+	config.KeyDerivationSalt, config.KeyDerivationMigration = salt, migration
+	config.GinMode = gin.DebugMode
+	t.Cleanup(func() {
+		config.SessionSecretEnvValue, config.SessionSecret = originalEnv, originalSecret
+		config.KeyDerivationSalt, config.KeyDerivationMigration = originalSalt, originalMigration
+		config.GinMode = originalGinMode
+	})
+}
+
+// sessionRouter serves /login, which stores a user id in the session, and
+// /me, which reads it back, using a cookie store built from the current config.
+func sessionRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	pairs, err := SessionKeyPairs()
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore(pairs...)))
+	router.GET("/login", func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set("id", 42)
+		require.NoError(t, session.Save())
+	})
+	router.GET("/me", func(c *gin.Context) {
+		if id, ok := sessions.Default(c).Get("id").(int); ok && id == 42 {
+			c.Status(http.StatusOK)
+			return
+		}
+		c.Status(http.StatusUnauthorized)
+	})
+	return router
+}
+
+func login(t *testing.T, router *gin.Engine) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	return cookies[0]
+}
+
+func me(router *gin.Engine, sessionCookie *http.Cookie) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.AddCookie(sessionCookie)
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestSessionKeyPairs_LegacyWithoutSalt(t *testing.T) {
+	setSessionKeyConfig(t, "weak-secret", "", false)
+	pairs, err := SessionKeyPairs()
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("weak-secret"), nil}, pairs)
+}
+
+func TestSessionKeyPairs_SaltRequiredOutsideDebug(t *testing.T) {
+	setSessionKeyConfig(t, "weak-secret", "", false)
+	config.GinMode = gin.ReleaseMode
+	_, err := SessionKeyPairs()
+	require.ErrorContains(t, err, "KEY_DERIVATION_SALT")
+
+	// A random per-process secret needs no salt
+	config.SessionSecretEnvValue = ""
+	_, err = SessionKeyPairs()
+	require.NoError(t, err)
+}
+
+func TestSessionKeyPairs_DerivedKeysAreStable(t *testing.T) {
+	setSessionKeyConfig(t, "weak-secret", "app-salt", false)
+	sessionCookie := login(t, sessionRouter(t))
+
+	// A restarted instance derives the same keys and accepts the cookie
+	require.Equal(t, http.StatusOK, me(sessionRouter(t), sessionCookie))
+}
+
+func TestSessionKeyPairs_MigrationWindow(t *testing.T) {
+	setSessionKeyConfig(t, "weak-secret", "", false)
+	legacyCookie := login(t, sessionRouter(t))
+
+	// During the migration window legacy and derived cookies are both accepted
+	config.KeyDerivationSalt, config.KeyDerivationMigration = "app-salt", true
+	migrating := sessionRouter(t)
+	require.Equal(t, http.StatusOK, me(migrating, legacyCookie))
+	derivedCookie := login(t, migrating)
+	require.Equal(t, http.StatusOK, me(migrating, derivedCookie))
+
+	// New cookies are signed with the derived key only
+	config.KeyDerivationSalt, config.KeyDerivationMigration = "", false
+	require.Equal(t, http.StatusUnauthorized, me(sessionRouter(t), derivedCookie))
+
+	// After the window legacy cookies are rejected
+	config.KeyDerivationSalt, config.KeyDerivationMigration = "app-salt", false
+	migrated := sessionRouter(t)
+	require.Equal(t, http.StatusUnauthorized, me(migrated, legacyCookie))
+	require.Equal(t, http.StatusOK, me(migrated, derivedCookie))
+}
@@ -0,0 +1,225 @@
+// Package statesync propagates state that each process keeps in memory, such
+// as channel suspensions and user bans, to the other regions of a multi-region
+// deployment over a shared Redis pub/sub channel.
+package statesync
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/random"
+)
+
+// PubSubChannel is the pub/sub channel carrying state sync events.
+const PubSubChannel = "state_sync"
+
+// publishQueueSize bounds the events waiting to be published. Publishing keeps
+// the order of events, so a ban followed by an unban is replayed in order.
+const publishQueueSize = 1024
+
+// EventType identifies the kind of state change carried by an Event.
+type EventType string
+
+const (
+	// EventChannelSuspended announces that a channel's ability was suspended.
+	EventChannelSuspended EventType = "channel_suspended"
+	// EventUserBanned announces that a user was banned.
+	EventUserBanned EventType = "user_banned"
+	// EventUserUnbanned announces that a user was unbanned.
+	EventUserUnbanned EventType = "user_unbanned"
+	// EventQuotaAdjusted announces a quota adjustment above config.StateSyncQuotaThreshold.
+	EventQuotaAdjusted EventType = "quota_adjusted"
+	// EventIPBanned announces that a client IP was banned.
+	EventIPBanned EventType = "ip_banned"
+	// EventIPUnbanned announces that a client IP was unbanned.
+	EventIPUnbanned EventType = "ip_unbanned"
+)
+
+// Event is a state change published to the other regions.
+type Event struct {
+	Type EventType `json:"type"`
+	// Origin is the node that published the event. Nodes skip their own events.
+	Origin    string `json:"origin"`
+	ChannelId int    `json:"channel_id,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Model     string `json:"model,omitempty"`
+	// SuspendUntil is the end of a channel suspension, in unix milliseconds.
+	SuspendUntil int64 `json:"suspend_until,omitempty"`
+	UserId       int   `json:"user_id,omitempty"`
+	// QuotaDelta is the signed quota change of the user.
+	QuotaDelta int64  `json:"quota_delta,omitempty"`
+	IP         string `json:"ip,omitempty"`
+	// BanUntil is the end of an IP ban in unix milliseconds, zero for a
+	// permanent ban.
+	BanUntil int64 `json:"ban_until,omitempty"`
+	// CreatedAt is when the event was published, in unix milliseconds.
+	CreatedAt int64 `json:"created_at"`
+}
+
+// Handler applies an event received from another node to the local state.
+type Handler func(ctx context.Context, event Event)
+
+// Transport moves event payloads between nodes.
+type Transport interface {
+	// Publish sends payload to every subscriber of channel.
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe delivers the payloads published to channel until ctx is done.
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[EventType][]Handler)
+
+	active atomic.Pointer[Service]
+)
+
+// Handle registers h for events of type t on every Service created afterwards.
+// Packages owning in-memory state register their handlers from init.
+func Handle(t EventType, h Handler) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[t] = append(registry[t], h)
+}
+
+// Service publishes local state changes and applies the ones of other nodes.
+type Service struct {
+	nodeID    string
+	transport Transport
+	handlers  map[EventType][]Handler
+	queue     chan Event
+}
+
+// NewService creates a Service for nodeID with the handlers registered through Handle.
+func NewService(nodeID string, transport Transport) *Service {
+	registryMu.Lock()
+	handlers := make(map[EventType][]Handler, len(registry))
+	for t, hs := range registry {
+		handlers[t] = append([]Handler(nil), hs...)
+	}
+	registryMu.Unlock()
+
+	return &Service{
+		nodeID:    nodeID,
+		transport: transport,
+		handlers:  handlers,
+		queue:     make(chan Event, publishQueueSize),
+	}
+}
+
+// Start subscribes to PubSubChannel and starts delivering and publishing
+// events until ctx is done.
+func (s *Service) Start(ctx context.Context) error {
+	payloads, err := s.transport.Subscribe(ctx, PubSubChannel)
+	if err != nil {
+		return errors.Wrapf(err, "subscribe to %s", PubSubChannel)
+	}
+
+	go func() {
+		for payload := range payloads {
+			s.dispatch(ctx, payload)
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-s.queue:
+				s.send(ctx, event)
+			}
+		}
+	}()
+	return nil
+}
+
+// Publish queues event for the other nodes without blocking. Events are
+// dropped, with a warning, when the queue is full.
+func (s *Service) Publish(event Event) {
+	event.Origin = s.nodeID
+	event.CreatedAt = time.Now().UnixMilli()
+	select {
+	case s.queue <- event:
+	default:
+		logger.Logger.Warn("state sync queue is full, dropping event",
+			zap.String("type", string(event.Type)))
+	}
+}
+
+func (s *Service) send(ctx context.Context, event Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.Logger.Error("failed to marshal state sync event", zap.Error(err))
+		return
+	}
+	if err = s.transport.Publish(ctx, PubSubChannel, payload); err != nil {
+		logger.Logger.Warn("failed to publish state sync event",
+			zap.String("type", string(event.Type)),
+			zap.Error(err))
+	}
+}
+
+func (s *Service) dispatch(ctx context.Context, payload []byte) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		logger.Logger.Warn("ignoring malformed state sync event", zap.Error(err))
+		return
+	}
+	if event.Origin == s.nodeID {
+		return
+	}
+
+	logger.Logger.Debug("applying state sync event",
+		zap.String("type", string(event.Type)),
+		zap.String("origin", event.Origin))
+	for _, h := range s.handlers[event.Type] {
+		h(ctx, event)
+	}
+}
+
+// Use installs s as the Service behind the package-level Publish.
+func Use(s *Service) {
+	active.Store(s)
+}
+
+// Enabled reports whether a Service is installed.
+func Enabled() bool {
+	return active.Load() != nil
+}
+
+// Publish queues event on the installed Service. It is a no-op when state sync
+// is disabled.
+func Publish(event Event) {
+	if s := active.Load(); s != nil {
+		s.Publish(event)
+	}
+}
+
+// Init starts state sync over the Redis configured by
+// STATE_SYNC_REDIS_CONN_STRING, falling back to the main Redis client, when
+// STATE_SYNC_ENABLED is set.
+func Init(ctx context.Context) error {
+	if !config.StateSyncEnabled {
+		return nil
+	}
+
+	transport, err := NewRedisTransport(ctx, config.StateSyncRedisConnString)
+	if err != nil {
+		return errors.Wrap(err, "create state sync transport")
+	}
+	s := NewService(random.GetUUID(), transport)
+	if err = s.Start(ctx); err != nil {
+		return errors.Wrap(err, "start state sync")
+	}
+	Use(s)
+	logger.Logger.Info("cross-region state sync enabled", zap.String("node_id", s.nodeID))
+	return nil
+}
@@ -0,0 +1,60 @@
+package statesync
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_DeliversEventsToOtherNodesInOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broker := NewMemoryBroker()
+	nodeA := NewService("node-a", broker)
+	nodeB := NewService("node-b", broker)
+
+	var (
+		mu       sync.Mutex
+		received = map[string][]Event{}
+	)
+	record := func(node string) Handler {
+		return func(_ context.Context, event Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			received[node] = append(received[node], event)
+		}
+	}
+	for _, t := range []EventType{EventUserBanned, EventUserUnbanned} {
+		nodeA.handlers[t] = []Handler{record("node-a")}
+		nodeB.handlers[t] = []Handler{record("node-b")}
+	}
+	require.NoError(t, nodeA.Start(ctx))
+	require.NoError(t, nodeB.Start(ctx))
+
+	nodeA.Publish(Event{Type: EventUserBanned, UserId: 42})
+	nodeA.Publish(Event{Type: EventUserUnbanned, UserId: 42})
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received["node-b"]) == 2
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Empty(t, received["node-a"], "nodes must skip their own events")
+	require.Equal(t, EventUserBanned, received["node-b"][0].Type)
+	require.Equal(t, EventUserUnbanned, received["node-b"][1].Type)
+	require.Equal(t, "node-a", received["node-b"][0].Origin)
+	require.Equal(t, 42, received["node-b"][1].UserId)
+}
+
+func TestPublish_NoopWithoutService(t *testing.T) {
+	Use(nil)
+	require.False(t, Enabled())
+	Publish(Event{Type: EventUserBanned, UserId: 1})
+}
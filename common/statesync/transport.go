@@ -0,0 +1,130 @@
+package statesync
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+// redisTransport carries payloads over Redis pub/sub.
+type redisTransport struct {
+	client redis.UniversalClient
+}
+
+// NewRedisTransport connects to the Redis at connString, or reuses the main
+// Redis client when connString is empty.
+func NewRedisTransport(ctx context.Context, connString string) (Transport, error) {
+	if connString == "" {
+		client, ok := common.RDB.(redis.UniversalClient)
+		if !common.IsRedisEnabled() || !ok {
+			return nil, errors.New("no redis connection string is set and Redis is not enabled")
+		}
+		return &redisTransport{client: client}, nil
+	}
+
+	opt, err := redis.ParseURL(connString)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse redis connection string")
+	}
+	client := redis.NewClient(opt)
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err = client.Ping(pingCtx).Err(); err != nil {
+		_ = client.Close()
+		return nil, errors.Wrap(err, "ping redis")
+	}
+	return &redisTransport{client: client}, nil
+}
+
+// Publish sends payload to channel.
+func (t *redisTransport) Publish(ctx context.Context, channel string, payload []byte) error {
+	return t.client.Publish(ctx, channel, payload).Err()
+}
+
+// Subscribe delivers the payloads published to channel until ctx is done.
+func (t *redisTransport) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	pubsub := t.client.Subscribe(ctx, channel)
+	// Wait for the subscription to be confirmed so no event is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, errors.Wrapf(err, "subscribe to %s", channel)
+	}
+
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// MemoryBroker is an in-process Transport that connects Services running in
+// the same process, such as several simulated nodes in tests.
+type MemoryBroker struct {
+	mu          sync.Mutex
+	subscribers map[string][]chan []byte
+}
+
+// NewMemoryBroker creates an empty MemoryBroker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[string][]chan []byte)}
+}
+
+// Publish sends payload to every current subscriber of channel.
+func (b *MemoryBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	// Hold the lock while sending so unsubscribing cannot close a channel in use
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subscribers[channel] {
+		select {
+		case ch <- append([]byte(nil), payload...):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Subscribe delivers the payloads published to channel until ctx is done.
+func (b *MemoryBroker) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	ch := make(chan []byte, publishQueueSize)
+	b.mu.Lock()
+	b.subscribers[channel] = append(b.subscribers[channel], ch)
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subscribers := b.subscribers[channel]
+		for i, sub := range subscribers {
+			if sub == ch {
+				b.subscribers[channel] = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// GetAuditLogs lists the audit log of admin write operations, newest first.
+// It filters on admin_id, target_type, action and the inclusive
+// start_timestamp/end_timestamp range, and pages with p and size like
+// GetAllLogs.
+func GetAuditLogs(c *gin.Context) {
+	p, _ := strconv.Atoi(c.Query("p"))
+	if p < 0 {
+		p = 0
+	}
+	itemsPerPage, err := strconv.Atoi(c.Query("size"))
+	if err != nil || itemsPerPage <= 0 {
+		itemsPerPage = config.DefaultItemsPerPage
+	}
+	if itemsPerPage > config.MaxItemsPerPage {
+		itemsPerPage = config.MaxItemsPerPage
+	}
+
+	filters := model.AuditLogFilters{
+		TargetType: c.Query("target_type"),
+		Action:     c.Query("action"),
+	}
+	var queryErr error
+	if raw := c.Query("admin_id"); raw != "" {
+		if filters.AdminId, err = strconv.Atoi(raw); err != nil || filters.AdminId < 0 {
+			queryErr = errors.New("admin_id must be a non-negative integer")
+		}
+	}
+	if queryErr == nil {
+		if filters.StartTimestamp, queryErr = parseUnixQuery(c, "start_timestamp", 0); queryErr == nil {
+			filters.EndTimestamp, queryErr = parseUnixQuery(c, "end_timestamp", 0)
+		}
+	}
+	if queryErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": queryErr.Error(),
+		})
+		return
+	}
+
+	entries, total, err := model.GetAuditLogs(gmw.Ctx(c), filters, p*itemsPerPage, itemsPerPage)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    entries,
+		"total":   total,
+	})
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestAdminMutationsAreAudited(t *testing.T) {
+	setupUserControllerTest(t)
+
+	user := &model.User{
+		Username:    "audited",
+		Password:    "hashed-password",
+		Role:        model.RoleCommonUser,
+		Status:      model.UserStatusEnabled,
+		AccessToken: "audited-access-token",
+		AffCode:     "adtd",
+	}
+	require.NoError(t, model.DB.Create(user).Error)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(ctxkey.Id, 42)
+		c.Set(ctxkey.Role, model.RoleRootUser)
+		c.Set(helper.RequestIdKey, "audit-request")
+	})
+	router.DELETE("/api/user/:id", DeleteUser)
+	router.GET("/api/admin/audit", GetAuditLogs)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/user/%d", user.Id), nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/audit?admin_id=42&target_type=user&action=delete", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Success bool `json:"success"`
+		Total   int  `json:"total"`
+		Data    []struct {
+			AdminId   int            `json:"admin_id"`
+			TargetId  string         `json:"target_id"`
+			RequestId string         `json:"request_id"`
+			OldValue  map[string]any `json:"old_value"`
+			NewValue  map[string]any `json:"new_value"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.True(t, resp.Success)
+	require.Equal(t, 1, resp.Total)
+	entry := resp.Data[0]
+	require.Equal(t, fmt.Sprint(user.Id), entry.TargetId)
+	require.Equal(t, "audit-request", entry.RequestId)
+	require.Equal(t, "audited", entry.OldValue["username"])
+	require.NotContains(t, w.Body.String(), "hashed-password")
+	require.NotContains(t, w.Body.String(), "audited-access-token")
+	require.Nil(t, entry.NewValue)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/audit?start_timestamp=abc", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeleteHistoryLogsIsAudited(t *testing.T) {
+	setupUserControllerTest(t)
+	require.NoError(t, model.LOG_DB.Create(&model.Log{UserId: 1, CreatedAt: 100, Content: "old"}).Error)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(ctxkey.Id, 42)
+		c.Set(ctxkey.Role, model.RoleAdminUser)
+	})
+	router.DELETE("/api/log/", DeleteHistoryLogs)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/log/?target_timestamp=200", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	entries, total, err := model.GetAuditLogs(context.Background(), model.AuditLogFilters{TargetType: model.AuditTargetLog}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, 42, entries[0].AdminId)
+	require.Equal(t, model.AuditActionDelete, entries[0].Action)
+	require.JSONEq(t, `{"deleted":1}`, string(entries[0].NewValue))
+}
+
+func TestUpdateOptionRedactsSecretsInAuditLog(t *testing.T) {
+	setupUserControllerTest(t)
+	originalSecret, originalOptions := config.TurnstileSecretKey, config.OptionMap
+	config.OptionMap = map[string]string{"TurnstileSecretKey": ""}
+	t.Cleanup(func() { config.TurnstileSecretKey, config.OptionMap = originalSecret, originalOptions })
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(ctxkey.Id, 42) })
+	router.PUT("/api/option/", UpdateOption)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/option/",
+		strings.NewReader(`{"key":"TurnstileSecretKey","value":"turnstile-secret"}`))
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "turnstile-secret", config.TurnstileSecretKey)
+
+	entries, total, err := model.GetAuditLogs(context.Background(), model.AuditLogFilters{TargetType: model.AuditTargetOption}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, "TurnstileSecretKey", entries[0].TargetId)
+	require.NotContains(t, string(entries[0].NewValue), "turnstile-secret")
+	require.JSONEq(t, `{"value":"[REDACTED]"}`, string(entries[0].NewValue))
+}
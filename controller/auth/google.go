@@ -0,0 +1,354 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/random"
+	"github.com/songquanpeng/one-api/controller"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// Google's well-known OAuth2 endpoints. They are variables so tests can point
+// them at a mock server.
+var (
+	googleAuthorizationEndpoint = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenEndpoint         = "https://oauth2.googleapis.com/token"
+	googleJWKSEndpoint          = "https://www.googleapis.com/oauth2/v3/certs"
+	googleIssuers               = []string{"https://accounts.google.com", "accounts.google.com"}
+)
+
+// googleJWKSCacheTTL is how long Google's signing keys are cached.
+const googleJWKSCacheTTL = time.Hour
+
+// googleKeys caches Google's ID token signing keys by key ID.
+var googleKeys = &googleJWKSCache{}
+
+type googleJWKSCache struct {
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+type googleTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+}
+
+// GoogleUser holds the identity claims extracted from a verified Google ID token.
+type GoogleUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// googleRedirectURI returns the callback URL registered with Google. It is the
+// frontend page that forwards code and state to GoogleOAuthCallback.
+func googleRedirectURI() string {
+	return fmt.Sprintf("%s/oauth/google", config.ServerAddress)
+}
+
+// GoogleOAuthLogin starts the Google login flow by storing a random state in the
+// session and redirecting the browser to Google's consent screen.
+func GoogleOAuthLogin(c *gin.Context) {
+	if !config.GoogleOAuthEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "Administrator has not enabled Google Log in and Sign up",
+		})
+		return
+	}
+
+	session := sessions.Default(c)
+	state := random.GetRandomString(12)
+	session.Set("oauth_state", state)
+	if err := session.Save(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	values := url.Values{}
+	values.Set("client_id", config.GoogleClientId)
+	values.Set("redirect_uri", googleRedirectURI())
+	values.Set("response_type", "code")
+	values.Set("scope", "openid email profile")
+	values.Set("state", state)
+	c.Redirect(http.StatusFound, googleAuthorizationEndpoint+"?"+values.Encode())
+}
+
+// GoogleOAuthCallback completes the Google login flow: it exchanges the
+// authorization code for an ID token, verifies the token against Google's JWKS,
+// then finds or creates the matching user and logs them in.
+func GoogleOAuthCallback(c *gin.Context) {
+	ctx := gmw.Ctx(c)
+	lg := gmw.GetLogger(c)
+	session := sessions.Default(c)
+	state := c.Query("state")
+	if state == "" || session.Get("oauth_state") == nil || state != session.Get("oauth_state").(string) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "state is empty or not same",
+		})
+		return
+	}
+	if !config.GoogleOAuthEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "Administrator has not enabled Google Log in and Sign up",
+		})
+		return
+	}
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "Google authorization failed: " + errCode,
+		})
+		return
+	}
+
+	googleUser, err := getGoogleUserInfoByCode(ctx, c.Query("code"))
+	if err != nil {
+		lg.Warn("google oauth callback failed", zap.Error(err))
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	user := model.User{
+		GoogleId: googleUser.Subject,
+	}
+	if model.IsGoogleIdAlreadyTaken(user.GoogleId) {
+		if err := user.FillUserByGoogleId(); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	} else {
+		if !config.RegisterEnabled {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "The administrator has turned off new user registration",
+			})
+			return
+		}
+		user.Username = "google_" + strconv.Itoa(model.GetMaxUserId()+1)
+		if googleUser.Name != "" {
+			user.DisplayName = googleUser.Name
+		} else {
+			user.DisplayName = "Google User"
+		}
+		if googleUser.EmailVerified {
+			user.Email = googleUser.Email
+		}
+		user.Role = model.RoleCommonUser
+		user.Status = model.UserStatusEnabled
+		if err := user.Insert(ctx, 0); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	if user.Status != model.UserStatusEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "User has been banned",
+			"success": false,
+		})
+		return
+	}
+	controller.SetupLogin(&user, c)
+}
+
+// getGoogleUserInfoByCode exchanges code for tokens and returns the identity
+// carried by the verified ID token.
+func getGoogleUserInfoByCode(ctx context.Context, code string) (*GoogleUser, error) {
+	if code == "" {
+		return nil, errors.New("Invalid parameter")
+	}
+
+	values := url.Values{}
+	values.Set("client_id", config.GoogleClientId)
+	values.Set("client_secret", config.GoogleClientSecret)
+	values.Set("code", code)
+	values.Set("grant_type", "authorization_code")
+	values.Set("redirect_uri", googleRedirectURI())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, googleTokenEndpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "build Google token request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	client := http.Client{
+		Timeout: 5 * time.Second,
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to Google server")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Google token endpoint returned status %d", res.StatusCode)
+	}
+
+	var tokenResponse googleTokenResponse
+	if err = json.NewDecoder(res.Body).Decode(&tokenResponse); err != nil {
+		return nil, errors.Wrap(err, "decode Google token response")
+	}
+	if tokenResponse.IDToken == "" {
+		return nil, errors.New("Google token response has no id_token")
+	}
+
+	return verifyGoogleIDToken(ctx, tokenResponse.IDToken)
+}
+
+// verifyGoogleIDToken checks the signature, issuer, audience and expiry of a
+// Google ID token and extracts its identity claims.
+func verifyGoogleIDToken(ctx context.Context, rawToken string) (*GoogleUser, error) {
+	token, err := jwt.Parse(rawToken, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.Errorf("unexpected ID token signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return googleKeys.get(ctx, kid)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "verify Google ID token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid Google ID token")
+	}
+	if !claims.VerifyExpiresAt(time.Now().UTC().Unix(), true) {
+		return nil, errors.New("Google ID token has expired")
+	}
+	if !claims.VerifyAudience(config.GoogleClientId, true) {
+		return nil, errors.New("Google ID token was issued for another client")
+	}
+	issuer, _ := claims["iss"].(string)
+	if !slices.Contains(googleIssuers, issuer) {
+		return nil, errors.Errorf("unexpected Google ID token issuer %q", issuer)
+	}
+
+	user := &GoogleUser{}
+	user.Subject, _ = claims["sub"].(string)
+	user.Email, _ = claims["email"].(string)
+	user.Name, _ = claims["name"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		user.EmailVerified = verified
+	case string:
+		user.EmailVerified = verified == "true"
+	}
+	if user.Subject == "" {
+		return nil, errors.New("Google ID token has no subject")
+	}
+
+	return user, nil
+}
+
+// get returns the signing key identified by kid, refreshing the cached key set
+// when it is older than googleJWKSCacheTTL.
+func (cache *googleJWKSCache) get(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.keys == nil || time.Since(cache.fetchedAt) > googleJWKSCacheTTL {
+		keys, err := fetchGoogleJWKS(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "fetch Google JWKS")
+		}
+		cache.keys = keys
+		cache.fetchedAt = time.Now().UTC()
+	}
+
+	key, ok := cache.keys[kid]
+	if !ok {
+		return nil, errors.Errorf("unknown Google signing key %q", kid)
+	}
+	return key, nil
+}
+
+// fetchGoogleJWKS downloads Google's current RSA signing keys.
+func fetchGoogleJWKS(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, googleJWKSEndpoint, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "build JWKS request")
+	}
+	client := http.Client{
+		Timeout: 5 * time.Second,
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "request JWKS")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("JWKS endpoint returned status %d", res.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&jwks); err != nil {
+		return nil, errors.Wrap(err, "decode JWKS")
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode modulus of key %s", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode exponent of key %s", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// mockGoogle is a fake Google OAuth2 server issuing RS256-signed ID tokens.
+type mockGoogle struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	jwksHits  atomic.Int32
+	lastCode  atomic.Value
+	subject   string
+	audience  string
+	emailAddr string
+}
+
+// newMockGoogle starts a mock Google server and points the package endpoints at it.
+func newMockGoogle(t *testing.T) *mockGoogle {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	m := &mockGoogle{key: key, subject: "google-sub-1", audience: "test-client-id", emailAddr: "alice@example.com"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		m.lastCode.Store(r.PostForm.Get("code"))
+		require.Equal(t, "test-client-secret", r.PostForm.Get("client_secret"))
+
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            "https://accounts.google.com",
+			"aud":            m.audience,
+			"sub":            m.subject,
+			"email":          m.emailAddr,
+			"email_verified": true,
+			"name":           "Alice",
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Hour).Unix(),
+		})
+		idToken.Header["kid"] = "test-kid"
+		signed, err := idToken.SignedString(key)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "at", "id_token": signed, "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/certs", func(w http.ResponseWriter, r *http.Request) {
+		m.jwksHits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": "test-kid",
+			"kty": "RSA",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	originalAuth, originalToken, originalJWKS := googleAuthorizationEndpoint, googleTokenEndpoint, googleJWKSEndpoint
+	googleAuthorizationEndpoint = m.server.URL + "/auth"
+	googleTokenEndpoint = m.server.URL + "/token"
+	googleJWKSEndpoint = m.server.URL + "/certs"
+	googleKeys = &googleJWKSCache{}
+	t.Cleanup(func() {
+		googleAuthorizationEndpoint, googleTokenEndpoint, googleJWKSEndpoint = originalAuth, originalToken, originalJWKS
+		googleKeys = &googleJWKSCache{}
+	})
+	return m
+}
+
+// setupGoogleOAuthTest configures Google OAuth, an in-memory database and a router
+// serving the login and callback endpoints.
+func setupGoogleOAuthTest(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:google_oauth_%d?mode=memory&cache=shared", time.Now().UnixNano())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Token{}, &model.Log{}))
+	originalDB, originalLogDB := model.DB, model.LOG_DB
+	model.DB, model.LOG_DB = db, db
+	originalUsingSQLite := common.UsingSQLite.Load()
+	common.UsingSQLite.Store(true)
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+
+	originalEnabled, originalId, originalSecret := config.GoogleOAuthEnabled, config.GoogleClientId, config.GoogleClientSecret
+	originalRegister, originalServer := config.RegisterEnabled, config.ServerAddress
+	config.GoogleOAuthEnabled = true
+	config.GoogleClientId = "test-client-id"
+	config.GoogleClientSecret = "test-client-secret"
+	config.RegisterEnabled = true
+	config.ServerAddress = "https://one-api.example.com"
+	t.Cleanup(func() {
+		model.DB, model.LOG_DB = originalDB, originalLogDB
+		common.UsingSQLite.Store(originalUsingSQLite)
+		common.SetRedisEnabled(originalRedis)
+		config.GoogleOAuthEnabled, config.GoogleClientId, config.GoogleClientSecret = originalEnabled, originalId, originalSecret
+		config.RegisterEnabled, config.ServerAddress = originalRegister, originalServer
+	})
+
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("test-secret"))))
+	router.Use(func(c *gin.Context) {
+		gmw.SetLogger(c, logger.Logger)
+		c.Next()
+	})
+	router.GET("/api/auth/google/login", GoogleOAuthLogin)
+	router.GET("/api/auth/google/callback", GoogleOAuthCallback)
+	return router
+}
+
+// googleLogin drives one full login round trip and returns the callback response.
+func googleLogin(t *testing.T, router *gin.Engine, code string) map[string]any {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/google/login", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "test-client-id", location.Query().Get("client_id"))
+	require.Equal(t, "openid email profile", location.Query().Get("scope"))
+	require.Equal(t, "https://one-api.example.com/oauth/google", location.Query().Get("redirect_uri"))
+	state := location.Query().Get("state")
+	require.NotEmpty(t, state)
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/auth/google/callback?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(state), nil)
+	for _, ck := range w.Result().Cookies() {
+		req.AddCookie(ck)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	resp := make(map[string]any)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestGoogleOAuth_FullFlowCreatesThenReusesUser(t *testing.T) {
+	router := setupGoogleOAuthTest(t)
+	mock := newMockGoogle(t)
+
+	resp := googleLogin(t, router, "code-1")
+	require.Equal(t, true, resp["success"], resp["message"])
+	require.Equal(t, "code-1", mock.lastCode.Load())
+
+	var user model.User
+	require.NoError(t, model.DB.Where("google_id = ?", "google-sub-1").First(&user).Error)
+	require.Equal(t, "Alice", user.DisplayName)
+	require.Equal(t, "alice@example.com", user.Email)
+
+	resp = googleLogin(t, router, "code-2")
+	require.Equal(t, true, resp["success"], resp["message"])
+	data := resp["data"].(map[string]any)
+	require.EqualValues(t, user.Id, data["id"])
+
+	var count int64
+	require.NoError(t, model.DB.Model(&model.User{}).Count(&count).Error)
+	require.EqualValues(t, 1, count)
+	require.EqualValues(t, 1, mock.jwksHits.Load(), "JWKS should be cached between logins")
+}
+
+func TestGoogleOAuth_RejectsTokenForAnotherClient(t *testing.T) {
+	router := setupGoogleOAuthTest(t)
+	mock := newMockGoogle(t)
+	mock.audience = "someone-elses-client"
+
+	resp := googleLogin(t, router, "code-1")
+	require.Equal(t, false, resp["success"])
+	require.Contains(t, resp["message"], "another client")
+}
+
+func TestGoogleOAuth_RejectsMismatchedState(t *testing.T) {
+	router := setupGoogleOAuthTest(t)
+	newMockGoogle(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/google/callback?code=c&state=forged", nil))
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -28,7 +28,7 @@ func GetSubscription(c *gin.Context) {
 		}
 	} else {
 		userId := c.GetInt(ctxkey.Id)
-		remainQuota, err = model.GetUserQuota(userId)
+		remainQuota, err = model.GetUserEffectiveQuota(userId)
 		if err != nil {
 			usedQuota, err = model.GetUserUsedQuota(userId)
 		}
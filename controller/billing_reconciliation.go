@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+)
+
+const (
+	// billingReconciliationMaxAttempts bounds the webhook deliveries of one
+	// reconciliation. An unacknowledged period is retried by the next run.
+	billingReconciliationMaxAttempts = 5
+	// billingReconciliationBaseBackoff is the wait before the first retry,
+	// doubled after every failed attempt.
+	billingReconciliationBaseBackoff = 2 * time.Second
+	// billingReconciliationRequestTimeout bounds a single webhook delivery.
+	billingReconciliationRequestTimeout = 30 * time.Second
+	// billingReconciliationGracePeriod keeps the most recent usage out of a
+	// period, so consume logs still queued for writing with an earlier
+	// created_at are not skipped once the period is marked as billed.
+	billingReconciliationGracePeriod = 5 * time.Minute
+)
+
+// BillingReconciliationPayload is the body POSTed to the external billing
+// webhook.
+type BillingReconciliationPayload struct {
+	PeriodStart int64                        `json:"period_start"`
+	PeriodEnd   int64                        `json:"period_end"`
+	Usage       []BillingReconciliationUsage `json:"usage"`
+}
+
+// BillingReconciliationUsage is the consumption of one user in the period.
+type BillingReconciliationUsage struct {
+	UserId        int     `json:"user_id"`
+	Quota         int64   `json:"quota"`
+	UsdEquivalent float64 `json:"usd_equivalent"`
+}
+
+// billingReconciler reports unbilled usage to config.ExternalBillingWebhookURL.
+// now and sleep are the clock, replaceable in tests.
+type billingReconciler struct {
+	client      *http.Client
+	now         func() time.Time
+	sleep       func(ctx context.Context, d time.Duration) error
+	maxAttempts int
+	baseBackoff time.Duration
+}
+
+func newBillingReconciler() *billingReconciler {
+	return &billingReconciler{
+		client:      &http.Client{Timeout: billingReconciliationRequestTimeout},
+		now:         time.Now,
+		sleep:       sleepContext,
+		maxAttempts: billingReconciliationMaxAttempts,
+		baseBackoff: billingReconciliationBaseBackoff,
+	}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reconcile sends the usage consumed since the previous reconciliation to the
+// webhook and, once acknowledged, marks it as billed. The first period starts
+// when the webhook was configured, and every period ends
+// billingReconciliationGracePeriod before now. Periods without usage are not
+// sent; they are folded into the next one.
+func (r *billingReconciler) reconcile(ctx context.Context) error {
+	webhookURL := config.ExternalBillingWebhookURL
+	if webhookURL == "" {
+		return nil
+	}
+
+	periodStart, err := model.GetLastBillingReconciliationEnd(ctx)
+	if err != nil {
+		return err
+	}
+	if periodStart == 0 {
+		// Usage from before the webhook was configured is not reported
+		if periodStart, err = model.GetOptionUpdatedAt(ctx, "ExternalBillingWebhookURL"); err != nil {
+			return err
+		}
+	}
+	periodEnd := r.now().Add(-billingReconciliationGracePeriod).Unix()
+	if periodEnd <= periodStart {
+		return nil
+	}
+	usage, err := model.GetUnbilledUsage(ctx, periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+	if len(usage) == 0 {
+		return nil
+	}
+
+	payload := BillingReconciliationPayload{PeriodStart: periodStart, PeriodEnd: periodEnd}
+	for _, u := range usage {
+		payload.Usage = append(payload.Usage, BillingReconciliationUsage{
+			UserId:        u.UserId,
+			Quota:         u.Quota,
+			UsdEquivalent: float64(u.Quota) / config.QuotaPerUnit,
+		})
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "marshal billing reconciliation")
+	}
+	// Lets the receiver drop a period delivered twice, e.g. when the
+	// acknowledgement was lost
+	idempotencyKey := fmt.Sprintf("%d-%d", periodStart, periodEnd)
+	if err = r.deliver(ctx, webhookURL, idempotencyKey, body); err != nil {
+		return err
+	}
+
+	billedAt := r.now().Unix()
+	records := make([]model.BillingReconciliation, 0, len(payload.Usage))
+	for _, u := range payload.Usage {
+		records = append(records, model.BillingReconciliation{
+			PeriodStart:   periodStart,
+			PeriodEnd:     periodEnd,
+			UserId:        u.UserId,
+			Quota:         u.Quota,
+			UsdEquivalent: u.UsdEquivalent,
+			BilledAt:      billedAt,
+		})
+	}
+	if err = model.CreateBillingReconciliations(ctx, records); err != nil {
+		return err
+	}
+	logger.Logger.Info("billing reconciliation acknowledged",
+		zap.Int64("period_start", periodStart),
+		zap.Int64("period_end", periodEnd),
+		zap.Int("users", len(records)))
+	return nil
+}
+
+// deliver POSTs body to webhookURL until it answers 200, retrying with
+// exponential backoff up to maxAttempts times.
+func (r *billingReconciler) deliver(ctx context.Context, webhookURL, idempotencyKey string, body []byte) error {
+	backoff := r.baseBackoff
+	var lastErr error
+	for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		if lastErr = r.post(ctx, webhookURL, idempotencyKey, body); lastErr == nil {
+			return nil
+		}
+		if attempt == r.maxAttempts {
+			break
+		}
+		logger.Logger.Warn("billing reconciliation webhook failed, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(lastErr))
+		if err := r.sleep(ctx, backoff); err != nil {
+			return errors.Wrap(err, "wait to retry billing reconciliation webhook")
+		}
+		backoff *= 2
+	}
+	return errors.Wrapf(lastErr, "billing reconciliation webhook failed after %d attempts", r.maxAttempts)
+}
+
+func (r *billingReconciler) post(ctx context.Context, webhookURL, idempotencyKey string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "new request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send request")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// StartBillingReconciliation reports unbilled usage to the external billing
+// webhook every config.ExternalBillingDefaultTimeoutSec seconds until ctx is
+// done. The webhook URL is a runtime option, so the job runs on the master node
+// regardless and skips runs while no URL is configured.
+func StartBillingReconciliation(ctx context.Context) {
+	if !config.IsMasterNode {
+		return
+	}
+	interval := time.Duration(config.ExternalBillingDefaultTimeoutSec) * time.Second
+	reconciler := newBillingReconciler()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := reconciler.reconcile(ctx); err != nil {
+				logger.Logger.Error("billing reconciliation failed", zap.Error(err))
+			}
+		}
+	}()
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// newTestBillingReconciler returns a reconciler reporting to webhookURL, saved
+// as an option two hours before *clock, whose clock reads *clock and whose
+// backoffs are recorded instead of slept.
+func newTestBillingReconciler(t *testing.T, webhookURL string, clock *time.Time, backoffs *[]time.Duration) *billingReconciler {
+	t.Helper()
+	originalURL := config.ExternalBillingWebhookURL
+	config.ExternalBillingWebhookURL = webhookURL
+	t.Cleanup(func() { config.ExternalBillingWebhookURL = originalURL })
+	configuredAt := clock.Add(-2 * time.Hour).UnixMilli()
+	require.NoError(t, model.DB.Create(&model.Option{
+		Key: "ExternalBillingWebhookURL", Value: webhookURL, CreatedAt: configuredAt, UpdatedAt: configuredAt,
+	}).Error)
+
+	reconciler := newBillingReconciler()
+	reconciler.now = func() time.Time { return *clock }
+	reconciler.sleep = func(_ context.Context, d time.Duration) error {
+		*backoffs = append(*backoffs, d)
+		return nil
+	}
+	return reconciler
+}
+
+func TestBillingReconciliation_MarksAcknowledgedUsage(t *testing.T) {
+	setupUserControllerTest(t)
+
+	var payloads []BillingReconciliationPayload
+	var idempotencyKeys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload BillingReconciliationPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads = append(payloads, payload)
+		idempotencyKeys = append(idempotencyKeys, r.Header.Get("Idempotency-Key"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	clock := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	var backoffs []time.Duration
+	reconciler := newTestBillingReconciler(t, server.URL, &clock, &backoffs)
+
+	configuredAt := clock.Add(-2 * time.Hour).Unix()
+	periodEnd := clock.Add(-billingReconciliationGracePeriod).Unix()
+	base := clock.Add(-time.Hour).Unix()
+	require.NoError(t, model.LOG_DB.Create(&[]model.Log{
+		{UserId: 1, Quota: 500_000, Type: model.LogTypeConsume, CreatedAt: base},
+		{UserId: 1, Quota: 250_000, Type: model.LogTypeConsume, CreatedAt: base + 60},
+		{UserId: 2, Quota: 1_000_000, Type: model.LogTypeConsume, CreatedAt: base + 120},
+		// Not consumption
+		{UserId: 2, Quota: 9_000_000, Type: model.LogTypeTopup, CreatedAt: base},
+		// Before the webhook was configured
+		{UserId: 3, Quota: 70_000, Type: model.LogTypeConsume, CreatedAt: configuredAt - 1},
+		// Within the grace period, left for the next run
+		{UserId: 3, Quota: 80_000, Type: model.LogTypeConsume, CreatedAt: periodEnd},
+	}).Error)
+
+	require.NoError(t, reconciler.reconcile(context.Background()))
+	require.Len(t, payloads, 1)
+	require.Equal(t, configuredAt, payloads[0].PeriodStart)
+	require.Equal(t, periodEnd, payloads[0].PeriodEnd)
+	require.Equal(t, []BillingReconciliationUsage{
+		{UserId: 1, Quota: 750_000, UsdEquivalent: 750_000 / config.QuotaPerUnit},
+		{UserId: 2, Quota: 1_000_000, UsdEquivalent: 1_000_000 / config.QuotaPerUnit},
+	}, payloads[0].Usage)
+	require.Equal(t, fmt.Sprintf("%d-%d", configuredAt, periodEnd), idempotencyKeys[0])
+	require.Empty(t, backoffs)
+
+	var records []model.BillingReconciliation
+	require.NoError(t, model.DB.Order("user_id").Find(&records).Error)
+	require.Len(t, records, 2)
+	require.Equal(t, int64(750_000), records[0].Quota)
+	require.Equal(t, periodEnd, records[0].PeriodEnd)
+	require.Equal(t, clock.Unix(), records[0].BilledAt)
+
+	// Billed usage is not reported again, the usage left in the grace period is
+	clock = clock.Add(10 * time.Minute)
+	require.NoError(t, reconciler.reconcile(context.Background()))
+	require.Len(t, payloads, 2)
+	require.Equal(t, payloads[0].PeriodEnd, payloads[1].PeriodStart)
+	require.Equal(t, []BillingReconciliationUsage{
+		{UserId: 3, Quota: 80_000, UsdEquivalent: 80_000 / config.QuotaPerUnit},
+	}, payloads[1].Usage)
+}
+
+func TestBillingReconciliation_RetriesWithBackoff(t *testing.T) {
+	setupUserControllerTest(t)
+
+	var attempts int
+	failures := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	clock := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	var backoffs []time.Duration
+	reconciler := newTestBillingReconciler(t, server.URL, &clock, &backoffs)
+	require.NoError(t, model.LOG_DB.Create(&model.Log{UserId: 1, Quota: 500_000, Type: model.LogTypeConsume, CreatedAt: clock.Unix() - 3600}).Error)
+
+	require.NoError(t, reconciler.reconcile(context.Background()))
+	require.Equal(t, 3, attempts)
+	require.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second}, backoffs)
+	var count int64
+	require.NoError(t, model.DB.Model(&model.BillingReconciliation{}).Count(&count).Error)
+	require.EqualValues(t, 1, count)
+
+	// A webhook that never acknowledges leaves the usage unbilled
+	require.NoError(t, model.LOG_DB.Create(&model.Log{UserId: 1, Quota: 10_000, Type: model.LogTypeConsume, CreatedAt: clock.Unix()}).Error)
+	clock = clock.Add(billingReconciliationGracePeriod + time.Minute)
+	attempts, failures, backoffs = 0, 100, nil
+	require.Error(t, reconciler.reconcile(context.Background()))
+	require.Equal(t, billingReconciliationMaxAttempts, attempts)
+	require.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second}, backoffs)
+	require.NoError(t, model.DB.Model(&model.BillingReconciliation{}).Count(&count).Error)
+	require.EqualValues(t, 1, count)
+}
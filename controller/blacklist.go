@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/blacklist"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// BanIP blocks a client IP on the web, API and relay routes. A positive
+// expiry_seconds lifts the ban after that many seconds, 0 bans the IP
+// permanently. The ban is recorded as a management log entry attributed to
+// the acting administrator.
+func BanIP(c *gin.Context) {
+	var req struct {
+		IP            string `json:"ip"`
+		ExpirySeconds int64  `json:"expiry_seconds"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": invalidParameterMessage})
+		return
+	}
+	ip := net.ParseIP(strings.TrimSpace(req.IP))
+	if ip == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ip must be a valid IPv4 or IPv6 address"})
+		return
+	}
+	if req.ExpirySeconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "expiry_seconds must not be negative"})
+		return
+	}
+
+	previous := blacklist.IsIPBanned(ip.String())
+	model.BanIP(ip.String(), time.Duration(req.ExpirySeconds)*time.Second)
+
+	adminId := c.GetInt(ctxkey.Id)
+	model.RecordManageLog(gmw.Ctx(c), adminId, "blacklist_ip:"+ip.String(), previous, true,
+		fmt.Sprintf("admin_id=%d expiry_seconds=%d", adminId, req.ExpirySeconds))
+	model.RecordAuditLog(gmw.Ctx(c), adminId, model.AuditTargetBlacklist, ip.String(), "ban",
+		gin.H{"banned": previous}, gin.H{"banned": true, "expiry_seconds": req.ExpirySeconds})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"ip":             ip.String(),
+			"expiry_seconds": req.ExpirySeconds,
+		},
+	})
+}
+
+// UnbanIP lifts the ban of the client IP in the ip path parameter and records
+// it as a management log entry.
+func UnbanIP(c *gin.Context) {
+	ip := net.ParseIP(strings.TrimSpace(c.Param("ip")))
+	if ip == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ip must be a valid IPv4 or IPv6 address"})
+		return
+	}
+
+	previous := blacklist.IsIPBanned(ip.String())
+	model.UnbanIP(ip.String())
+
+	adminId := c.GetInt(ctxkey.Id)
+	model.RecordManageLog(gmw.Ctx(c), adminId, "blacklist_ip:"+ip.String(), previous, false,
+		fmt.Sprintf("admin_id=%d", adminId))
+	model.RecordAuditLog(gmw.Ctx(c), adminId, model.AuditTargetBlacklist, ip.String(), "unban",
+		gin.H{"banned": previous}, gin.H{"banned": false})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": ""})
+}
@@ -8,12 +8,16 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
 	"github.com/Laisky/zap"
 	"github.com/gin-gonic/gin"
 
 	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/network"
 	"github.com/songquanpeng/one-api/model"
 	"github.com/songquanpeng/one-api/relay"
 	"github.com/songquanpeng/one-api/relay/adaptor"
@@ -31,9 +35,26 @@ func bindChannelPayload(c *gin.Context) (*model.Channel, json.RawMessage, error)
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		return nil, nil, err
 	}
+	// Only the channel config file may mark channels as file-sourced
+	payload.Channel.Source = ""
 	return payload.Channel, payload.Tooling, nil
 }
 
+// rejectReadOnlyChannel responds with an error and returns true when the
+// channel identified by id is loaded from CHANNEL_CONFIG_PATH and therefore
+// cannot be modified through the admin API.
+func rejectReadOnlyChannel(c *gin.Context, id int) bool {
+	channel, err := model.GetChannelById(id, false)
+	if err != nil || !channel.IsReadOnly() {
+		return false
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": false,
+		"message": "Channel is managed by the channel config file and is read-only",
+	})
+	return true
+}
+
 func parseToolingConfigPayload(raw json.RawMessage) (*model.ChannelToolingConfig, bool, error) {
 	if raw == nil {
 		return nil, false, nil
@@ -205,6 +226,24 @@ func GetChannel(c *gin.Context) {
 	})
 }
 
+// checkChannelBaseURL rejects base URLs resolving to non-public addresses
+// while the channel SSRF protection is enabled, logging the attempt as a
+// security event.
+func checkChannelBaseURL(c *gin.Context, channel *model.Channel) error {
+	if !config.ChannelSSRFProtectionEnabled || channel.GetBaseURL() == "" {
+		return nil
+	}
+	err := network.CheckPublicURL(c.Request.Context(), channel.GetBaseURL())
+	if errors.Is(err, network.ErrNonPublicAddress) {
+		gmw.GetLogger(c).Warn("security event: rejected channel base URL pointing at a non-public address",
+			zap.String("event", "ssrf_blocked"),
+			zap.Int("channel_id", channel.Id),
+			zap.String("base_url", channel.GetBaseURL()),
+			zap.Int("user_id", c.GetInt(ctxkey.Id)))
+	}
+	return err
+}
+
 // AddChannel creates one or more channels using the posted configuration payload.
 func AddChannel(c *gin.Context) {
 	channel, toolingRaw, err := bindChannelPayload(c)
@@ -237,6 +276,62 @@ func AddChannel(c *gin.Context) {
 		}
 	}
 
+	if channel.ResponseAnnotations != nil && *channel.ResponseAnnotations != "" {
+		if err = model.ValidateResponseAnnotationsJSON(*channel.ResponseAnnotations); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Invalid response annotations: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if channel.ModelStreamingTimeouts != nil && *channel.ModelStreamingTimeouts != "" {
+		if err = model.ValidateModelStreamingTimeoutsJSON(*channel.ModelStreamingTimeouts); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Invalid model streaming timeouts: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if channel.EndpointIdMapping != nil && *channel.EndpointIdMapping != "" {
+		if err = model.ValidateEndpointIdMappingJSON(*channel.EndpointIdMapping); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Invalid endpoint id mapping: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if channel.ErrorResponseTemplate != nil && *channel.ErrorResponseTemplate != "" {
+		if err = model.ValidateErrorResponseTemplateJSON(*channel.ErrorResponseTemplate); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Invalid error response template: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if err = checkChannelBaseURL(c, channel); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "Invalid base URL: " + err.Error(),
+		})
+		return
+	}
+
+	if err = channel.ValidateSystemPromptTranslation(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "Invalid system prompt translation: " + err.Error(),
+		})
+		return
+	}
+
 	if toolingCfg, provided, err := parseToolingConfigPayload(toolingRaw); err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -293,6 +388,15 @@ func AddChannel(c *gin.Context) {
 		}
 		channels = append(channels, localChannel)
 	}
+	if shouldTestChannelOnSave(c) {
+		candidates := make([]*model.Channel, 0, len(channels))
+		for i := range channels {
+			candidates = append(candidates, &channels[i])
+		}
+		if !testChannelsBeforeSave(c, candidates) {
+			return
+		}
+	}
 	err = model.BatchInsertChannels(channels)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -301,15 +405,32 @@ func AddChannel(c *gin.Context) {
 		})
 		return
 	}
+	for i := range channels {
+		model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetChannel, channels[i].Id, model.AuditActionCreate, nil, &channels[i])
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
 	})
 }
 
+// channelAuditSnapshot loads the channel recorded as the previous value of an
+// audit log entry, or nil when it cannot be loaded.
+func channelAuditSnapshot(id int) *model.Channel {
+	channel, err := model.GetChannelById(id, false)
+	if err != nil {
+		return nil
+	}
+	return channel
+}
+
 // DeleteChannel removes the channel identified by the path parameter.
 func DeleteChannel(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	if rejectReadOnlyChannel(c, id) {
+		return
+	}
+	previous := channelAuditSnapshot(id)
 	channel := model.Channel{Id: id}
 	err := channel.Delete()
 	if err != nil {
@@ -319,6 +440,7 @@ func DeleteChannel(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetChannel, id, model.AuditActionDelete, previous, nil)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -335,6 +457,7 @@ func DeleteDisabledChannel(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetChannel, "disabled", model.AuditActionDelete, gin.H{"deleted": rows}, nil)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -353,6 +476,9 @@ func UpdateChannel(c *gin.Context) {
 		})
 		return
 	}
+	if rejectReadOnlyChannel(c, channel.Id) {
+		return
+	}
 
 	// Validate inference profile ARN map if provided
 	if channel.InferenceProfileArnMap != nil && *channel.InferenceProfileArnMap != "" {
@@ -366,13 +492,76 @@ func UpdateChannel(c *gin.Context) {
 		}
 	}
 
+	if channel.ResponseAnnotations != nil && *channel.ResponseAnnotations != "" {
+		if err = model.ValidateResponseAnnotationsJSON(*channel.ResponseAnnotations); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Invalid response annotations: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if channel.ModelStreamingTimeouts != nil && *channel.ModelStreamingTimeouts != "" {
+		if err = model.ValidateModelStreamingTimeoutsJSON(*channel.ModelStreamingTimeouts); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Invalid model streaming timeouts: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if channel.EndpointIdMapping != nil && *channel.EndpointIdMapping != "" {
+		if err = model.ValidateEndpointIdMappingJSON(*channel.EndpointIdMapping); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Invalid endpoint id mapping: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if channel.ErrorResponseTemplate != nil && *channel.ErrorResponseTemplate != "" {
+		if err = model.ValidateErrorResponseTemplateJSON(*channel.ErrorResponseTemplate); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Invalid error response template: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if err = checkChannelBaseURL(c, channel); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "Invalid base URL: " + err.Error(),
+		})
+		return
+	}
+
+	if err = channel.ValidateSystemPromptTranslation(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "Invalid system prompt translation: " + err.Error(),
+		})
+		return
+	}
+
 	if statusOnly != "" {
 		// Only update status safely
 		if channel.Id == 0 {
 			c.JSON(http.StatusOK, gin.H{"success": false, "message": "Channel id is required"})
 			return
 		}
+		previous := channelAuditSnapshot(channel.Id)
 		model.UpdateChannelStatusById(channel.Id, channel.Status)
+		var previousStatus any
+		if previous != nil {
+			previousStatus = gin.H{"status": previous.Status}
+		}
+		model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetChannel, channel.Id, model.AuditActionUpdate,
+			previousStatus, gin.H{"status": channel.Status})
 		c.JSON(http.StatusOK, gin.H{"success": true, "message": ""})
 		return
 	}
@@ -402,6 +591,21 @@ func UpdateChannel(c *gin.Context) {
 		}
 	}
 
+	if shouldTestChannelOnSave(c) {
+		candidate, err := mergeChannelForSaveTest(channel)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		if !testChannelsBeforeSave(c, []*model.Channel{candidate}) {
+			return
+		}
+	}
+
+	previous := channelAuditSnapshot(channel.Id)
 	err = channel.Update()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -410,6 +614,7 @@ func UpdateChannel(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetChannel, channel.Id, model.AuditActionUpdate, previous, channel)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -476,6 +681,10 @@ func UpdateChannelPricing(c *gin.Context) {
 		return
 	}
 
+	if rejectReadOnlyChannel(c, id) {
+		return
+	}
+
 	var request struct {
 		ModelRatio      map[string]float64                `json:"model_ratio"`
 		CompletionRatio map[string]float64                `json:"completion_ratio"`
@@ -500,6 +709,7 @@ func UpdateChannelPricing(c *gin.Context) {
 		})
 		return
 	}
+	previous := *channel
 
 	// Handle both old format (separate model_ratio and completion_ratio) and new format (unified model_configs)
 	if len(request.ModelConfigs) > 0 {
@@ -582,6 +792,7 @@ func UpdateChannelPricing(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetChannel, id, "update_pricing", &previous, channel)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
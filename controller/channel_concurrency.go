@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/metrics"
+	dbmodel "github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+// channelAtCapacityRetryAfter is the Retry-After, in seconds, sent when every
+// channel tried was at its concurrency limit. Slots free up as soon as
+// in-flight requests finish.
+const channelAtCapacityRetryAfter = 1
+
+// acquireChannelSlot takes a concurrency slot of the selected channel when it
+// sets MaxConcurrency. A channel at capacity yields a 429 channel_at_capacity
+// error, which makes Relay retry on another channel; the client only sees it
+// once retries are exhausted, as a 503, see respondChannelAtCapacity.
+func acquireChannelSlot(c *gin.Context) (release func(), bizErr *model.ErrorWithStatusCode) {
+	v, ok := c.Get(ctxkey.ChannelModel)
+	if !ok {
+		return func() {}, nil
+	}
+	channel, ok := v.(*dbmodel.Channel)
+	if !ok || channel == nil || channel.MaxConcurrency <= 0 {
+		return func() {}, nil
+	}
+
+	releaseSlot, ok := dbmodel.AcquireChannelConcurrency(gmw.Ctx(c), channel.Id, channel.MaxConcurrency)
+	if ok {
+		metrics.GlobalRecorder.UpdateChannelConcurrentRequests(channel.Id, 1)
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				releaseSlot()
+				metrics.GlobalRecorder.UpdateChannelConcurrentRequests(channel.Id, -1)
+			})
+		}, nil
+	}
+	message := fmt.Sprintf("channel %d is at its concurrency limit of %d requests", channel.Id, channel.MaxConcurrency)
+	return nil, &model.ErrorWithStatusCode{
+		StatusCode: http.StatusTooManyRequests,
+		Error: model.Error{
+			Message:  message,
+			Type:     model.ErrorTypeChannelAtCapacity,
+			Code:     "channel_at_capacity",
+			RawError: errors.New(message),
+		},
+	}
+}
+
+// respondChannelAtCapacity turns a channel_at_capacity error into the 503
+// returned to the client, with a Retry-After header.
+func respondChannelAtCapacity(c *gin.Context, bizErr *model.ErrorWithStatusCode) {
+	if bizErr.Type != model.ErrorTypeChannelAtCapacity {
+		return
+	}
+	c.Header("Retry-After", strconv.Itoa(channelAtCapacityRetryAfter))
+	bizErr.StatusCode = http.StatusServiceUnavailable
+}
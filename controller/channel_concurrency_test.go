@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	dbmodel "github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+func TestAcquireChannelSlotRejectsChannelAtCapacity(t *testing.T) {
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(originalRedis) })
+	gin.SetMode(gin.TestMode)
+
+	newContext := func() *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		c.Set(ctxkey.ChannelModel, &dbmodel.Channel{Id: 916600, MaxConcurrency: 2})
+		return c
+	}
+
+	var releases []func()
+	for range 2 {
+		release, bizErr := acquireChannelSlot(newContext())
+		require.Nil(t, bizErr)
+		releases = append(releases, release)
+	}
+
+	_, bizErr := acquireChannelSlot(newContext())
+	require.NotNil(t, bizErr)
+	require.Equal(t, http.StatusTooManyRequests, bizErr.StatusCode)
+	require.Equal(t, model.ErrorTypeChannelAtCapacity, bizErr.Type)
+	body, err := json.Marshal(gin.H{"error": bizErr.Error})
+	require.NoError(t, err)
+	require.Contains(t, string(body), `"type":"channel_at_capacity"`)
+
+	for _, release := range releases {
+		release()
+	}
+	release, bizErr := acquireChannelSlot(newContext())
+	require.Nil(t, bizErr)
+	release()
+
+	// Channels without a limit are never rejected
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(ctxkey.ChannelModel, &dbmodel.Channel{Id: 916601})
+	_, bizErr = acquireChannelSlot(c)
+	require.Nil(t, bizErr)
+}
+
+func TestRespondChannelAtCapacitySetsRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	bizErr := &model.ErrorWithStatusCode{
+		StatusCode: http.StatusTooManyRequests,
+		Error:      model.Error{Type: model.ErrorTypeChannelAtCapacity},
+	}
+	respondChannelAtCapacity(c, bizErr)
+	require.Equal(t, http.StatusServiceUnavailable, bizErr.StatusCode)
+	require.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// Upstream rate limits keep their status
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	rateLimited := &model.ErrorWithStatusCode{StatusCode: http.StatusTooManyRequests}
+	respondChannelAtCapacity(c, rateLimited)
+	require.Equal(t, http.StatusTooManyRequests, rateLimited.StatusCode)
+	require.Empty(t, w.Header().Get("Retry-After"))
+}
+
+func TestProcessChannelRelayErrorSuspendsChannelAtCapacityBriefly(t *testing.T) {
+	db, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	require.NoError(t, db.Create(&dbmodel.Ability{Group: "default", Model: "gpt-4o", ChannelId: 916602, Enabled: true}).Error)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	start := time.Now()
+	processChannelRelayError(c, 1, 916602, "at-capacity", "default", "gpt-4o", model.ErrorWithStatusCode{
+		StatusCode: http.StatusTooManyRequests,
+		Error:      model.Error{Type: model.ErrorTypeChannelAtCapacity},
+	})
+
+	var ability dbmodel.Ability
+	require.NoError(t, db.Where("channel_id = ?", 916602).First(&ability).Error)
+	require.NotNil(t, ability.SuspendUntil)
+	require.WithinDuration(t, start.Add(config.ChannelSuspendSecondsFor429/4), *ability.SuspendUntil, 5*time.Second)
+}
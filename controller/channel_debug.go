@@ -4,8 +4,10 @@ import (
 	"net/http"
 	"strconv"
 
+	gmw "github.com/Laisky/gin-middlewares/v7"
 	"github.com/gin-gonic/gin"
 
+	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/model"
 )
 
@@ -73,6 +75,7 @@ func FixChannelModelConfigs(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetChannel, channelId, "fix_model_configs", nil, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -107,6 +110,7 @@ func RemigratAllChannels(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetChannel, "all", "remigrate_model_configs", nil, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -202,6 +206,7 @@ func CleanAllMixedModelData(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetChannel, "all", "clean_mixed_model_data", nil, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestFileChannelsAreReadOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	model.InitDB()
+
+	originalMemoryCache := config.MemoryCacheEnabled
+	config.MemoryCacheEnabled = false
+	t.Cleanup(func() { config.MemoryCacheEnabled = originalMemoryCache })
+
+	path := filepath.Join(t.TempDir(), "channels.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+- name: file-managed-readonly
+  type: 1
+  key: sk-file
+  models: [gpt-4o]
+`), 0o600))
+	require.NoError(t, model.SyncFileChannels(path))
+
+	var channel model.Channel
+	require.NoError(t, model.DB.Where("name = ? AND source = ?", "file-managed-readonly", model.ChannelSourceFile).First(&channel).Error)
+	t.Cleanup(func() {
+		model.DB.Exec("DELETE FROM abilities WHERE channel_id = ?", channel.Id)
+		model.DB.Exec("DELETE FROM channels WHERE id = ?", channel.Id)
+	})
+
+	router := gin.New()
+	router.PUT("/api/channel/", UpdateChannel)
+	router.PUT("/api/channel/pricing/:id", UpdateChannelPricing)
+	router.DELETE("/api/channel/:id", DeleteChannel)
+
+	send := func(method, target string, payload any) {
+		t.Helper()
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, target, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var resp struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.False(t, resp.Success, target)
+		require.Contains(t, resp.Message, "read-only", target)
+	}
+
+	send(http.MethodPut, "/api/channel/", map[string]any{"id": channel.Id, "name": "renamed", "type": 1, "models": "gpt-4o", "key": "sk-admin"})
+	send(http.MethodPut, "/api/channel/?status_only=1", map[string]any{"id": channel.Id, "status": model.ChannelStatusManuallyDisabled})
+	send(http.MethodPut, fmt.Sprintf("/api/channel/pricing/%d", channel.Id), map[string]any{"model_ratio": map[string]float64{"gpt-4o": 1}})
+	send(http.MethodDelete, fmt.Sprintf("/api/channel/%d", channel.Id), nil)
+
+	var unchanged model.Channel
+	require.NoError(t, model.DB.First(&unchanged, channel.Id).Error)
+	require.Equal(t, "file-managed-readonly", unchanged.Name)
+	require.Equal(t, "sk-file", unchanged.Key)
+	require.Equal(t, model.ChannelStatusEnabled, unchanged.Status)
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+const (
+	// channelPrewarmUsageWindow is how far back consume logs rank the channels.
+	channelPrewarmUsageWindow = 7 * 24 * time.Hour
+	// channelPrewarmProbeTimeout bounds a single pre-warm probe.
+	channelPrewarmProbeTimeout = 10 * time.Second
+)
+
+// channelPrewarmResult is the outcome of probing one upstream origin.
+type channelPrewarmResult struct {
+	ChannelId int
+	Origin    string
+	Latency   time.Duration
+	Err       error
+}
+
+// PrewarmChannelConnections opens pooled connections to the upstreams of the
+// most used enabled channels, so the first relayed requests after a restart
+// skip the TCP and TLS handshakes. Each origin is probed once with a HEAD
+// request on client.HTTPClient, the client relays use; any HTTP response counts
+// as warm, since only the connection matters. Failures are logged and ignored.
+func PrewarmChannelConnections(ctx context.Context) []channelPrewarmResult {
+	lg := logger.Logger.Named("channel_prewarm")
+	since := time.Now().Add(-channelPrewarmUsageWindow).Unix()
+	channelIds, err := model.GetMostUsedChannelIds(ctx, since, config.ChannelPrewarmTopN)
+	if err != nil {
+		lg.Warn("skip channel pre-warm", zap.Error(err))
+		return nil
+	}
+
+	// Channels sharing an upstream share its connection pool
+	targets := make(map[string]int)
+	var origins []string
+	for _, id := range channelIds {
+		channel, err := model.GetChannelById(id, true)
+		if err != nil || channel.Status != model.ChannelStatusEnabled {
+			continue
+		}
+		origin := channelOrigin(channel)
+		if origin == "" {
+			continue
+		}
+		if _, ok := targets[origin]; !ok {
+			targets[origin] = id
+			origins = append(origins, origin)
+		}
+	}
+
+	results := make([]channelPrewarmResult, len(origins))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(config.PrewarmConcurrency)
+	for i, origin := range origins {
+		group.Go(func() error {
+			start := time.Now()
+			err := probeChannelOrigin(groupCtx, origin)
+			results[i] = channelPrewarmResult{ChannelId: targets[origin], Origin: origin, Latency: time.Since(start), Err: err}
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	warmed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			lg.Warn("channel pre-warm probe failed",
+				zap.Int("channel_id", result.ChannelId),
+				zap.String("origin", result.Origin),
+				zap.Error(result.Err))
+			continue
+		}
+		warmed++
+		lg.Debug("channel connection pre-warmed",
+			zap.Int("channel_id", result.ChannelId),
+			zap.String("origin", result.Origin),
+			zap.Duration("latency", result.Latency))
+	}
+	lg.Info("channel pre-warm finished", zap.Int("origins", len(results)), zap.Int("warmed", warmed))
+	return results
+}
+
+// channelOrigin returns the scheme and host the channel relays to, or "" when
+// it has no usable base URL.
+func channelOrigin(channel *model.Channel) string {
+	baseURL := channel.GetBaseURL()
+	if baseURL == "" && channel.Type < len(channeltype.ChannelBaseURLs) {
+		baseURL = channeltype.ChannelBaseURLs[channel.Type]
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// probeChannelOrigin sends a HEAD request to origin and drains the response so
+// the connection returns to the idle pool.
+func probeChannelOrigin(ctx context.Context, origin string) error {
+	ctx, cancel := context.WithTimeout(ctx, channelPrewarmProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, origin, nil)
+	if err != nil {
+		return errors.Wrap(err, "new request")
+	}
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send probe")
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
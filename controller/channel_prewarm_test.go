@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// useTestRelayClient replaces client.HTTPClient with one on a fresh connection
+// pool for the duration of the test.
+func useTestRelayClient(t *testing.T) *http.Client {
+	t.Helper()
+	original := client.HTTPClient
+	client.HTTPClient = &http.Client{Transport: &http.Transport{}}
+	t.Cleanup(func() {
+		client.HTTPClient.CloseIdleConnections()
+		client.HTTPClient = original
+	})
+	return client.HTTPClient
+}
+
+func createPrewarmChannel(t *testing.T, id int, baseURL string, uses int) {
+	t.Helper()
+	require.NoError(t, model.DB.Create(&model.Channel{Id: id, Name: "prewarm", Type: 1, Key: "sk-test", BaseURL: &baseURL, Status: model.ChannelStatusEnabled}).Error)
+	now := time.Now().Unix()
+	for range uses {
+		require.NoError(t, model.LOG_DB.Create(&model.Log{ChannelId: id, Type: model.LogTypeConsume, CreatedAt: now}).Error)
+	}
+}
+
+func TestPrewarmChannelConnections_ReusedByFirstRequest(t *testing.T) {
+	setupUserControllerTest(t)
+	relayClient := useTestRelayClient(t)
+
+	var newConns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	createPrewarmChannel(t, 51, server.URL+"/v1", 3)
+
+	results := PrewarmChannelConnections(context.Background())
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	require.Equal(t, 51, results[0].ChannelId)
+	require.EqualValues(t, 1, newConns.Load())
+
+	// The first relayed request reuses the pre-warmed connection
+	var reused bool
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodPost, server.URL+"/v1/chat/completions", nil)
+	require.NoError(t, err)
+	resp, err := relayClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.True(t, reused)
+	require.EqualValues(t, 1, newConns.Load())
+}
+
+func TestPrewarmChannelConnections_TopChannelsAndFailures(t *testing.T) {
+	setupUserControllerTest(t)
+	useTestRelayClient(t)
+
+	originalTopN := config.ChannelPrewarmTopN
+	config.ChannelPrewarmTopN = 2
+	t.Cleanup(func() { config.ChannelPrewarmTopN = originalTopN })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	// Nothing listens on a closed server's address
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unreachable.Close()
+
+	createPrewarmChannel(t, 61, server.URL, 5)
+	createPrewarmChannel(t, 62, unreachable.URL, 4)
+	// Below the top 2
+	createPrewarmChannel(t, 63, "http://127.0.0.1:1", 1)
+
+	start := time.Now()
+	results := PrewarmChannelConnections(context.Background())
+	require.Less(t, time.Since(start), channelPrewarmProbeTimeout)
+	require.Len(t, results, 2)
+	require.Equal(t, 61, results[0].ChannelId)
+	require.NoError(t, results[0].Err)
+	require.Equal(t, 62, results[1].ChannelId)
+	require.Error(t, results[1].Err)
+}
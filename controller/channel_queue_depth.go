@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"net/http"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// GetChannelQueueDepths returns the number of requests currently in flight on
+// each enabled channel, as used by queue depth routing.
+func GetChannelQueueDepths(c *gin.Context) {
+	depths, err := model.ListChannelQueueDepths(gmw.Ctx(c))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"enabled":  config.QueueDepthRoutingEnabled,
+			"channels": depths,
+		},
+	})
+}
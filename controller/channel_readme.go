@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	gutils "github.com/Laisky/go-utils/v6"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+// channelReadmeCacheTTL bounds how long a generated channel readme is served from cache.
+const channelReadmeCacheTTL = time.Hour
+
+// channelReadmeCache caches generated readmes by channel type and format.
+var channelReadmeCache = gutils.NewExpCache[string](context.Background(), channelReadmeCacheTTL)
+
+// Output formats of the channel readme and changelog endpoints.
+const (
+	channelDocFormatMarkdown = "markdown"
+	channelDocFormatHTML     = "html"
+)
+
+// parseChannelDocRequest reads the channel type path parameter and the format
+// query parameter shared by the channel readme and changelog endpoints.
+func parseChannelDocRequest(c *gin.Context) (int, string, error) {
+	channelType, err := strconv.Atoi(c.Param("channel_type"))
+	if err != nil || channelType <= channeltype.Unknown || channelType >= channeltype.Dummy {
+		return 0, "", errors.Errorf("invalid channel type: %s", c.Param("channel_type"))
+	}
+
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", channelDocFormatMarkdown)))
+	if format != channelDocFormatMarkdown && format != channelDocFormatHTML {
+		return 0, "", errors.Errorf("unsupported format %q, expected markdown or html", format)
+	}
+	return channelType, format, nil
+}
+
+// renderChannelDoc converts markdown to the requested format.
+func renderChannelDoc(markdown, format string) string {
+	if format == channelDocFormatHTML {
+		return channeltype.ReadmeToHTML(markdown)
+	}
+	return markdown
+}
+
+// GetChannelReadme returns a generated document describing a channel type:
+// supported models, configuration, default pricing, known limitations and
+// example requests. Supports ?format=markdown|html and is cached for an hour.
+func GetChannelReadme(c *gin.Context) {
+	lg := gmw.GetLogger(c)
+	channelType, format, err := parseChannelDocRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	cacheKey := fmt.Sprintf("%d:%s", channelType, format)
+	content, ok := channelReadmeCache.Load(cacheKey)
+	if !ok {
+		if err := recordChannelPricingSnapshot(channelType); err != nil {
+			lg.Warn("failed to record channel pricing snapshot", zap.Int("channel_type", channelType), zap.Error(err))
+		}
+
+		markdown := channeltype.GenerateChannelReadme(channelType)
+		if markdown == "" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("no adaptor metadata for channel type %d", channelType),
+			})
+			return
+		}
+		content = renderChannelDoc(markdown, format)
+		channelReadmeCache.Store(cacheKey, content)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"channel_type": channelType,
+			"format":       format,
+			"content":      content,
+		},
+	})
+}
+
+// GetChannelPricingChangelog returns the CHANGELOG.md of the default pricing
+// of a channel type, assembled from the pricing changes detected between
+// stored snapshots. Supports ?format=markdown|html.
+func GetChannelPricingChangelog(c *gin.Context) {
+	channelType, format, err := parseChannelDocRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := recordChannelPricingSnapshot(channelType); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	entries, err := model.GetChannelPricingChangelog(channelType)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("# Changelog\n\n")
+	if len(entries) == 0 {
+		b.WriteString("No pricing changes detected.\n")
+	}
+	for _, entry := range entries {
+		b.WriteString(entry.Changelog)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"channel_type": channelType,
+			"format":       format,
+			"content":      renderChannelDoc(b.String(), format),
+		},
+	})
+}
+
+// recordChannelPricingSnapshot compares the current default pricing of
+// channelType with the latest stored snapshot and stores a new snapshot,
+// with its changelog entry, when they differ.
+func recordChannelPricingSnapshot(channelType int) error {
+	if channeltype.ReadmeMetadataProvider == nil {
+		return nil
+	}
+	metadata, ok := channeltype.ReadmeMetadataProvider(channelType)
+	if !ok {
+		return nil
+	}
+	pricing, err := json.Marshal(metadata.Models)
+	if err != nil {
+		return errors.Wrap(err, "marshal channel pricing")
+	}
+
+	latest, err := model.GetLatestChannelPricingSnapshot(channelType)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if latest != nil && latest.Pricing == string(pricing) {
+		return nil
+	}
+
+	snapshot := &model.ChannelPricingSnapshot{
+		ChannelType: channelType,
+		Pricing:     string(pricing),
+	}
+	if latest != nil {
+		var previous []channeltype.ReadmeModel
+		if err := json.Unmarshal([]byte(latest.Pricing), &previous); err != nil {
+			return errors.Wrapf(err, "decode pricing snapshot %d", latest.Id)
+		}
+		snapshot.Changelog = channeltype.GeneratePricingChangelog(metadata.ChannelName, previous, metadata.Models, time.Now())
+	}
+	return model.CreateChannelPricingSnapshot(snapshot)
+}
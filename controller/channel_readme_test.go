@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+type channelDocResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Data    struct {
+		Format  string `json:"format"`
+		Content string `json:"content"`
+	} `json:"data"`
+}
+
+func getChannelDoc(t *testing.T, target string) (int, channelDocResponse) {
+	t.Helper()
+	router := gin.New()
+	router.GET("/api/admin/channel/:channel_type/readme", GetChannelReadme)
+	router.GET("/api/admin/channel/:channel_type/changelog", GetChannelPricingChangelog)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var resp channelDocResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestGetChannelReadme(t *testing.T) {
+	setupUserControllerTest(t)
+
+	code, resp := getChannelDoc(t, "/api/admin/channel/1/readme")
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, resp.Success, resp.Message)
+	require.Equal(t, channelDocFormatMarkdown, resp.Data.Format)
+	require.Contains(t, resp.Data.Content, "## Pricing")
+
+	code, resp = getChannelDoc(t, "/api/admin/channel/1/readme?format=html")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, resp.Data.Content, "<h2>Pricing</h2>")
+
+	code, _ = getChannelDoc(t, "/api/admin/channel/1/readme?format=pdf")
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = getChannelDoc(t, "/api/admin/channel/abc/readme")
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGetChannelPricingChangelog_DetectsChanges(t *testing.T) {
+	setupUserControllerTest(t)
+
+	prices := []channeltype.ReadmeModel{{Name: "model-a", InputUsdPer1M: 1, OutputUsdPer1M: 2}}
+	originalProvider := channeltype.ReadmeMetadataProvider
+	channeltype.ReadmeMetadataProvider = func(channelType int) (channeltype.ReadmeMetadata, bool) {
+		return channeltype.ReadmeMetadata{ChannelName: "demo", Models: prices}, true
+	}
+	t.Cleanup(func() { channeltype.ReadmeMetadataProvider = originalProvider })
+
+	// The first request only records the baseline snapshot
+	_, resp := getChannelDoc(t, "/api/admin/channel/1/changelog")
+	require.True(t, resp.Success, resp.Message)
+	require.Contains(t, resp.Data.Content, "No pricing changes detected.")
+
+	prices = []channeltype.ReadmeModel{{Name: "model-a", InputUsdPer1M: 1.5, OutputUsdPer1M: 3}}
+	_, resp = getChannelDoc(t, "/api/admin/channel/1/changelog")
+	require.True(t, resp.Success, resp.Message)
+	require.Contains(t, resp.Data.Content, "# Changelog")
+	require.Contains(t, resp.Data.Content, "- `model-a`: input $1 → $1.5, output $2 → $3")
+
+	// Unchanged pricing adds no entry
+	_, again := getChannelDoc(t, "/api/admin/channel/1/changelog")
+	require.Equal(t, resp.Data.Content, again.Data.Content)
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+// channelSaveTestTimeout bounds all upstream requests made by one save-time channel test.
+var channelSaveTestTimeout = 15 * time.Second
+
+// shouldTestChannelOnSave reports whether the channel must pass a health check
+// before it is persisted, requested by the TestOnSave query parameter or
+// enforced by config.AutoWarmup.
+func shouldTestChannelOnSave(c *gin.Context) bool {
+	if config.AutoWarmup {
+		return true
+	}
+	testOnSave, _ := strconv.ParseBool(c.Query("TestOnSave"))
+	return testOnSave
+}
+
+// firstChannelModel returns the first model of the channel's model list.
+func firstChannelModel(channel *model.Channel) string {
+	for name := range strings.SplitSeq(channel.Models, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// testChannelsBeforeSave sends config.TestPrompt to the first model of each
+// channel. On failure it responds 422 with the upstream error and returns
+// false, in which case nothing must be persisted.
+func testChannelsBeforeSave(c *gin.Context, channels []*model.Channel) bool {
+	lg := gmw.GetLogger(c).Named("test_channel_on_save")
+	ctx, cancel := context.WithTimeout(gmw.SetLogger(gmw.Ctx(c), lg), channelSaveTestTimeout)
+	defer cancel()
+
+	for _, channel := range channels {
+		modelName := firstChannelModel(channel)
+		if modelName == "" {
+			respondChannelSaveTestFailure(c, channel, "", "channel has no model to test", nil)
+			return false
+		}
+
+		_, err, openaiErr := testChannel(ctx, channel, buildTestRequest(modelName))
+		if err == nil && openaiErr == nil {
+			continue
+		}
+
+		message := ""
+		if err != nil {
+			message = err.Error()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				message = "upstream did not respond within " + channelSaveTestTimeout.String()
+			}
+		} else {
+			message = openaiErr.Message
+		}
+		lg.Debug("channel test on save failed",
+			zap.Int("channel_id", channel.Id),
+			zap.Int("channel_type", channel.Type),
+			zap.String("model", modelName),
+			zap.String("error", message))
+		respondChannelSaveTestFailure(c, channel, modelName, message, openaiErr)
+		return false
+	}
+
+	return true
+}
+
+// respondChannelSaveTestFailure writes the 422 response of a failed save-time channel test.
+func respondChannelSaveTestFailure(c *gin.Context, channel *model.Channel, modelName, message string, upstreamErr *relaymodel.Error) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"success": false,
+		"message": "channel test failed: " + message,
+		"data": gin.H{
+			"channel_name": channel.Name,
+			"modelName":    modelName,
+			"error":        upstreamErr,
+		},
+	})
+}
+
+// mergeChannelForSaveTest overlays the fields of a partial update payload on
+// the stored channel, yielding the channel as it would be after the update.
+func mergeChannelForSaveTest(payload *model.Channel) (*model.Channel, error) {
+	existing, err := model.GetChannelById(payload.Id, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get channel %d", payload.Id)
+	}
+
+	merged := *existing
+	if payload.Type != 0 {
+		merged.Type = payload.Type
+	}
+	if payload.Key != "" {
+		merged.Key = payload.Key
+	}
+	if payload.Name != "" {
+		merged.Name = payload.Name
+	}
+	if strings.TrimSpace(payload.Models) != "" {
+		merged.Models = payload.Models
+	}
+	if payload.BaseURL != nil {
+		merged.BaseURL = payload.BaseURL
+	}
+	if payload.ModelMapping != nil {
+		merged.ModelMapping = payload.ModelMapping
+	}
+	if payload.Config != "" {
+		merged.Config = payload.Config
+	}
+	return &merged, nil
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+const channelSaveTestGoodKey = "sk-good-key"
+
+// newChannelSaveTestUpstream accepts only channelSaveTestGoodKey and records
+// the max_tokens of the last test request.
+func newChannelSaveTestUpstream(t *testing.T, maxTokens *atomic.Int64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+channelSaveTestGoodKey {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`))
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		var req relaymodel.GeneralOpenAIRequest
+		require.NoError(t, json.Unmarshal(body, &req))
+		// The OpenAI adaptor may move max_tokens to max_completion_tokens
+		if req.MaxCompletionTokens != nil {
+			maxTokens.Store(int64(*req.MaxCompletionTokens))
+		} else {
+			maxTokens.Store(int64(req.MaxTokens))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"` + req.Model + `",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"4"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":8,"completion_tokens":1,"total_tokens":9}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func sendChannelRequest(t *testing.T, method, target string, payload any) (int, map[string]any) {
+	t.Helper()
+	router := gin.New()
+	router.POST("/api/channel/", AddChannel)
+	router.PUT("/api/channel/", UpdateChannel)
+
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func countChannelsByName(t *testing.T, name string) int64 {
+	t.Helper()
+	var count int64
+	require.NoError(t, model.DB.Model(&model.Channel{}).Where("name = ?", name).Count(&count).Error)
+	return count
+}
+
+// waitForChannelTestLogs waits until count channel test logs are recorded,
+// which happens asynchronously.
+func waitForChannelTestLogs(t *testing.T, count int64) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		var n int64
+		require.NoError(t, model.LOG_DB.Model(&model.Log{}).Where("type = ?", model.LogTypeTest).Count(&n).Error)
+		return n == count
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestAddChannel_TestOnSave(t *testing.T) {
+	setupUserChannelTest(t)
+	originalMaxTokens := config.TestMaxTokens
+	config.TestMaxTokens = 64
+	t.Cleanup(func() { config.TestMaxTokens = originalMaxTokens })
+
+	var maxTokens atomic.Int64
+	upstream := newChannelSaveTestUpstream(t, &maxTokens)
+	newPayload := func(name, key string) map[string]any {
+		return map[string]any{
+			"name":     name,
+			"type":     channeltype.OpenAICompatible,
+			"key":      key,
+			"base_url": upstream.URL,
+			"models":   "gpt-4o-mini,gpt-4o",
+		}
+	}
+
+	code, resp := sendChannelRequest(t, http.MethodPost, "/api/channel/?TestOnSave=true", newPayload("save-test-bad", "sk-wrong"))
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	require.Equal(t, false, resp["success"])
+	require.Contains(t, resp["message"], "Incorrect API key provided")
+	data := resp["data"].(map[string]any)
+	require.Equal(t, "gpt-4o-mini", data["modelName"])
+	require.NotNil(t, data["error"])
+	require.Zero(t, countChannelsByName(t, "save-test-bad"))
+
+	code, resp = sendChannelRequest(t, http.MethodPost, "/api/channel/?TestOnSave=true", newPayload("save-test-good", channelSaveTestGoodKey))
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, true, resp["success"], resp["message"])
+	require.Equal(t, int64(1), countChannelsByName(t, "save-test-good"))
+	require.Equal(t, int64(config.TestMaxTokens), maxTokens.Load())
+
+	// Without TestOnSave nothing is sent upstream
+	code, resp = sendChannelRequest(t, http.MethodPost, "/api/channel/", newPayload("save-test-untested", "sk-wrong"))
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, true, resp["success"], resp["message"])
+	require.Equal(t, int64(1), countChannelsByName(t, "save-test-untested"))
+
+	// AutoWarmup enforces the test even without the query parameter
+	config.AutoWarmup = true
+	t.Cleanup(func() { config.AutoWarmup = false })
+	code, _ = sendChannelRequest(t, http.MethodPost, "/api/channel/", newPayload("save-test-warmup", "sk-wrong"))
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	require.Zero(t, countChannelsByName(t, "save-test-warmup"))
+	waitForChannelTestLogs(t, 3)
+}
+
+func TestUpdateChannel_TestOnSave(t *testing.T) {
+	setupUserChannelTest(t)
+	var maxTokens atomic.Int64
+	upstream := newChannelSaveTestUpstream(t, &maxTokens)
+
+	baseURL := upstream.URL
+	channel := &model.Channel{
+		Name:    "save-test-update",
+		Type:    channeltype.OpenAICompatible,
+		Key:     channelSaveTestGoodKey,
+		BaseURL: &baseURL,
+		Models:  "gpt-4o-mini",
+		Status:  model.ChannelStatusEnabled,
+	}
+	require.NoError(t, model.BatchInsertChannels([]model.Channel{*channel}))
+	require.NoError(t, model.DB.Where("name = ?", channel.Name).First(channel).Error)
+
+	code, resp := sendChannelRequest(t, http.MethodPut, "/api/channel/?TestOnSave=true", map[string]any{
+		"id":   channel.Id,
+		"name": channel.Name,
+		"key":  "sk-rotated-but-wrong",
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	require.Equal(t, false, resp["success"])
+	stored, err := model.GetChannelById(channel.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, channelSaveTestGoodKey, stored.Key)
+
+	// The stored key is used when the payload leaves it out
+	code, resp = sendChannelRequest(t, http.MethodPut, "/api/channel/?TestOnSave=true", map[string]any{
+		"id":     channel.Id,
+		"name":   channel.Name,
+		"models": "gpt-4o-mini,gpt-4o",
+	})
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, true, resp["success"], resp["message"])
+	stored, err = model.GetChannelById(channel.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, "gpt-4o-mini,gpt-4o", stored.Models)
+	waitForChannelTestLogs(t, 2)
+}
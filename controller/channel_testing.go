@@ -101,16 +101,23 @@ func calculateTestCost(usage *relaymodel.Usage, meta *meta.Meta, request *relaym
 }
 
 func testChannel(ctx context.Context, channel *model.Channel, request *relaymodel.GeneralOpenAIRequest) (responseMessage string, err error, openaiErr *relaymodel.Error) {
+	return testChannelWithLog(ctx, channel, request, nil)
+}
+
+// testChannelWithLog is testChannel with a hook that can adjust the test log
+// before it is recorded. The upstream request inherits ctx's deadline.
+func testChannelWithLog(ctx context.Context, channel *model.Channel, request *relaymodel.GeneralOpenAIRequest,
+	decorateLog func(*model.Log)) (responseMessage string, err error, openaiErr *relaymodel.Error) {
 	lg := gmw.GetLogger(ctx)
 	startTime := time.Now()
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Request = &http.Request{
+	c.Request = (&http.Request{
 		Method: http.MethodPost,
 		URL:    &url.URL{Path: "/v1/chat/completions"},
 		Body:   nil,
 		Header: make(http.Header),
-	}
+	}).WithContext(ctx)
 	c.Request.Header.Set("Authorization", "Bearer "+channel.Key)
 	c.Request.Header.Set("Content-Type", "application/json")
 	c.Set(ctxkey.Channel, channel.Type)
@@ -221,6 +228,10 @@ func testChannel(ctx context.Context, channel *model.Channel, request *relaymode
 			testLog.Quota = int(actualCost)
 		}
 
+		if decorateLog != nil {
+			decorateLog(testLog)
+		}
+
 		// Record test log once (DB), avoid duplicate console error logs
 		go model.RecordTestLog(ctx, testLog)
 	}()
@@ -388,6 +399,8 @@ func TestChannel(c *gin.Context) {
 		return
 	}
 
+	// A passing test proves the channel healthy, free slots leaked by stuck requests
+	model.ResetChannelConcurrency(ctx, channel.Id)
 	c.JSON(http.StatusOK, gin.H{
 		"success":   true,
 		"message":   responseMessage,
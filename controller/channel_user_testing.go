@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/network"
+	"github.com/songquanpeng/one-api/middleware"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+// userChannelTestTimeout bounds the upstream request of a self-service channel test.
+var userChannelTestTimeout = 10 * time.Second
+
+// userChannelTestURLCheck validates the user-supplied base URL. It ignores the
+// admin SSRF allowlist, which only exempts upstreams chosen by admins.
+var userChannelTestURLCheck = network.CheckStrictPublicURL
+
+// userChannelTestLogPrefix marks test logs produced by self-service channel tests.
+const userChannelTestLogPrefix = "[self-service] "
+
+// UserChannelTestRequest is the payload of POST /api/user/channel/test.
+type UserChannelTestRequest struct {
+	BaseURL     string `json:"base_url"`
+	APIKey      string `json:"api_key"`
+	Model       string `json:"model"`
+	ChannelType int    `json:"channel_type"`
+}
+
+// validate checks the request and normalizes its fields.
+func (r *UserChannelTestRequest) validate() error {
+	r.BaseURL = strings.TrimSpace(r.BaseURL)
+	r.APIKey = strings.TrimSpace(r.APIKey)
+	r.Model = strings.TrimSpace(r.Model)
+
+	if r.APIKey == "" {
+		return errors.New("api_key is required")
+	}
+	if r.Model == "" {
+		return errors.New("model is required")
+	}
+	if r.ChannelType <= channeltype.Unknown || r.ChannelType >= channeltype.Dummy ||
+		relay.GetAdaptor(channeltype.ToAPIType(r.ChannelType)) == nil {
+		return errors.Errorf("unsupported channel_type: %d", r.ChannelType)
+	}
+	if r.BaseURL != "" {
+		parsed, err := url.Parse(r.BaseURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.New("base_url must be an absolute http(s) URL")
+		}
+	}
+	return nil
+}
+
+// TestUserChannel lets a user verify an upstream API key without creating a
+// channel. Only config.TestPrompt is sent, the upstream request is bounded by
+// userChannelTestTimeout, and the key is never persisted.
+func TestUserChannel(c *gin.Context) {
+	userId := c.GetInt(ctxkey.Id)
+	lg := gmw.GetLogger(c).Named("test_user_channel").With(zap.Int("user_id", userId))
+
+	var req UserChannelTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "invalid request body",
+		})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if !middleware.CheckUserChannelTestRateLimit(c, userId) {
+		lg.Debug("self-service channel test rate limited")
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"message": "too many channel tests, please try again later",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(gmw.SetLogger(gmw.Ctx(c), lg), userChannelTestTimeout)
+	defer cancel()
+
+	// The base URL comes from the user, so it may only point at public
+	// addresses regardless of the channel SSRF protection setting and allowlist
+	if req.BaseURL != "" {
+		if err := userChannelTestURLCheck(ctx, req.BaseURL); err != nil {
+			lg.Warn("security event: blocked self-service channel test to non-public address",
+				zap.String("event", "ssrf_blocked"),
+				zap.String("base_url", req.BaseURL),
+				zap.Error(err))
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "base_url must point to a public address",
+			})
+			return
+		}
+	}
+	ctx = client.WithGuardedUpstream(ctx)
+
+	baseURL := req.BaseURL
+	// Transient channel: it only lives for this request and is never saved
+	channel := &model.Channel{
+		Type:    req.ChannelType,
+		Key:     req.APIKey,
+		Name:    "self-service",
+		Models:  req.Model,
+		BaseURL: &baseURL,
+	}
+
+	username := model.GetUsernameById(userId)
+	tik := time.Now()
+	responseMessage, err, openaiErr := testChannelWithLog(ctx, channel, buildTestRequest(req.Model), func(log *model.Log) {
+		log.UserId = userId
+		log.Username = username
+		log.Content = userChannelTestLogPrefix + log.Content
+	})
+	consumedTime := float64(time.Since(tik).Milliseconds()) / 1000.0
+
+	if err != nil || openaiErr != nil {
+		message := ""
+		if err != nil {
+			message = err.Error()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				message = "upstream did not respond within " + userChannelTestTimeout.String()
+			}
+		} else {
+			message = openaiErr.Message
+		}
+		lg.Debug("self-service channel test failed",
+			zap.Int("channel_type", req.ChannelType),
+			zap.String("model", req.Model),
+			zap.String("error", message))
+		c.JSON(http.StatusOK, gin.H{
+			"success":   false,
+			"message":   message,
+			"time":      consumedTime,
+			"modelName": req.Model,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   responseMessage,
+		"time":      consumedTime,
+		"modelName": req.Model,
+	})
+}
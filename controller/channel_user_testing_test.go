@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/network"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+const userChannelTestKey = "sk-user-provided-secret"
+
+type userChannelTestResponse struct {
+	Success bool    `json:"success"`
+	Message string  `json:"message"`
+	Time    float64 `json:"time"`
+}
+
+func setupUserChannelTest(t *testing.T) {
+	t.Helper()
+	setupUserControllerTest(t)
+
+	originalApproximate := config.ApproximateTokenEnabled
+	config.ApproximateTokenEnabled = true
+	t.Cleanup(func() { config.ApproximateTokenEnabled = originalApproximate })
+
+	// The fake upstreams listen on loopback, which self-service tests always
+	// refuse, so swap in a URL check and client that accept it
+	if client.HTTPClient == nil {
+		client.Init()
+	}
+	originalCheck, originalClient := userChannelTestURLCheck, client.GuardedHTTPClient
+	userChannelTestURLCheck = func(context.Context, string) error { return nil }
+	client.GuardedHTTPClient = &http.Client{}
+	t.Cleanup(func() {
+		userChannelTestURLCheck, client.GuardedHTTPClient = originalCheck, originalClient
+	})
+}
+
+func postUserChannelTest(t *testing.T, userId int, baseURL string) (int, userChannelTestResponse) {
+	t.Helper()
+	router := gin.New()
+	router.POST("/api/user/channel/test", func(c *gin.Context) {
+		c.Set(ctxkey.Id, userId)
+		TestUserChannel(c)
+	})
+
+	payload, err := json.Marshal(UserChannelTestRequest{
+		BaseURL:     baseURL,
+		APIKey:      userChannelTestKey,
+		Model:       "gpt-4o-mini",
+		ChannelType: channeltype.OpenAICompatible,
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/user/channel/test", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var resp userChannelTestResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func newChatCompletionUpstream(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req relaymodel.GeneralOpenAIRequest
+		if json.Unmarshal(body, &req) != nil || len(req.Messages) != 1 || req.Messages[0].StringContent() != config.TestPrompt {
+			http.Error(w, `{"error":{"message":"unexpected prompt"}}`, http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+userChannelTestKey {
+			http.Error(w, `{"error":{"message":"bad key"}}`, http.StatusUnauthorized)
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o-mini",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"4"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":8,"completion_tokens":1,"total_tokens":9}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// waitForUserChannelTestLogs waits until userId has count test logs, which are
+// recorded asynchronously.
+func waitForUserChannelTestLogs(t *testing.T, userId int, count int) []model.Log {
+	t.Helper()
+	var logs []model.Log
+	require.Eventually(t, func() bool {
+		logs = nil
+		require.NoError(t, model.LOG_DB.Where("user_id = ?", userId).Find(&logs).Error)
+		return len(logs) == count
+	}, 2*time.Second, 10*time.Millisecond)
+	return logs
+}
+
+func TestTestUserChannel_SendsTestPromptAndLogsWithoutKey(t *testing.T) {
+	setupUserChannelTest(t)
+	upstream := newChatCompletionUpstream(t, 0)
+
+	code, resp := postUserChannelTest(t, 9101, upstream.URL)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, resp.Success, resp.Message)
+	require.Equal(t, "4", resp.Message)
+	require.GreaterOrEqual(t, resp.Time, 0.0)
+
+	logs := waitForUserChannelTestLogs(t, 9101, 1)
+	require.Equal(t, model.LogTypeTest, logs[0].Type)
+	require.True(t, strings.HasPrefix(logs[0].Content, userChannelTestLogPrefix), logs[0].Content)
+	require.NotContains(t, logs[0].Content, userChannelTestKey)
+
+	var channels int64
+	require.NoError(t, model.DB.Model(&model.Channel{}).Count(&channels).Error)
+	require.Zero(t, channels)
+}
+
+func TestTestUserChannel_RateLimited(t *testing.T) {
+	setupUserChannelTest(t)
+	upstream := newChatCompletionUpstream(t, 0)
+
+	originalLimit := config.UserChannelTestRateLimitNum
+	config.UserChannelTestRateLimitNum = 2
+	t.Cleanup(func() { config.UserChannelTestRateLimitNum = originalLimit })
+
+	for range 2 {
+		code, resp := postUserChannelTest(t, 9102, upstream.URL)
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, resp.Success, resp.Message)
+	}
+
+	code, resp := postUserChannelTest(t, 9102, upstream.URL)
+	require.Equal(t, http.StatusTooManyRequests, code)
+	require.False(t, resp.Success)
+
+	// Budgets are per user
+	code, resp = postUserChannelTest(t, 9103, upstream.URL)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, resp.Success, resp.Message)
+
+	waitForUserChannelTestLogs(t, 9102, 2)
+	waitForUserChannelTestLogs(t, 9103, 1)
+}
+
+func TestTestUserChannel_EnforcesTimeout(t *testing.T) {
+	setupUserChannelTest(t)
+	upstream := newChatCompletionUpstream(t, 5*time.Second)
+
+	originalTimeout := userChannelTestTimeout
+	userChannelTestTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { userChannelTestTimeout = originalTimeout })
+
+	start := time.Now()
+	code, resp := postUserChannelTest(t, 9104, upstream.URL)
+	require.Less(t, time.Since(start), 2*time.Second)
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, resp.Success)
+	require.Contains(t, resp.Message, "did not respond within")
+	waitForUserChannelTestLogs(t, 9104, 1)
+}
+
+func TestTestUserChannel_RejectsNonPublicBaseURL(t *testing.T) {
+	setupUserChannelTest(t)
+	userChannelTestURLCheck = network.CheckStrictPublicURL
+	upstream := newChatCompletionUpstream(t, 0)
+
+	// The admin allowlist does not extend to self-service tests
+	originalAllowed := config.ChannelSSRFAllowedHosts
+	config.ChannelSSRFAllowedHosts = []string{"127.0.0.1", "10.0.0.1"}
+	t.Cleanup(func() { config.ChannelSSRFAllowedHosts = originalAllowed })
+
+	for _, baseURL := range []string{upstream.URL, "http://10.0.0.1", "http://169.254.169.254"} {
+		status, resp := postUserChannelTest(t, 1, baseURL)
+		require.Equal(t, http.StatusOK, status, baseURL)
+		require.False(t, resp.Success, baseURL)
+		require.Contains(t, resp.Message, "public address", baseURL)
+	}
+}
+
+func TestUserChannelTestRequestValidate(t *testing.T) {
+	valid := UserChannelTestRequest{APIKey: "sk-x", Model: "gpt-4o-mini", ChannelType: channeltype.OpenAI}
+	require.NoError(t, valid.validate())
+
+	for _, req := range []UserChannelTestRequest{
+		{Model: "gpt-4o-mini", ChannelType: channeltype.OpenAI},
+		{APIKey: "sk-x", ChannelType: channeltype.OpenAI},
+		{APIKey: "sk-x", Model: "gpt-4o-mini", ChannelType: channeltype.Dummy},
+		{APIKey: "sk-x", Model: "gpt-4o-mini", ChannelType: channeltype.OpenAI, BaseURL: "file:///etc/passwd"},
+	} {
+		require.Error(t, req.validate())
+	}
+}
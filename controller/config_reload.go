@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/metrics"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/pricing"
+)
+
+// ReloadConfig re-reads the runtime configuration from the database without
+// restarting the server: options, the channel config file when
+// CHANNEL_CONFIG_PATH is set, the channel cache, the cached group model lists,
+// the global model pricing and the model pricing overrides. OptionMapRWMutex
+// is held for writing throughout, so readers never observe a half-applied
+// reload.
+func ReloadConfig(ctx context.Context) (err error) {
+	start := time.Now()
+	defer func() { metrics.GlobalRecorder.RecordConfigReload(err == nil) }()
+
+	config.OptionMapRWMutex.Lock()
+	defer config.OptionMapRWMutex.Unlock()
+
+	before := maps.Clone(config.OptionMap)
+	if err = model.ReloadOptionMapLocked(); err != nil {
+		return errors.Wrap(err, "reload options")
+	}
+	if config.ChannelConfigPath != "" {
+		if err = model.SyncFileChannels(config.ChannelConfigPath); err != nil {
+			return errors.Wrap(err, "reload channel config file")
+		}
+	}
+	if config.MemoryCacheEnabled {
+		model.InitChannelCache()
+	}
+	if err = model.InvalidateGroupModelsCache(ctx); err != nil {
+		return errors.Wrap(err, "invalidate group models cache")
+	}
+	pricing.ReloadGlobalPricing()
+	if err = model.LoadModelPricingOverrides(); err != nil {
+		return errors.Wrap(err, "reload model pricing overrides")
+	}
+
+	changes := diffOptions(before, config.OptionMap)
+	logger.Logger.Info("config reloaded",
+		zap.Strings("changed_keys", slices.Sorted(maps.Keys(changes))),
+		zap.Any("changes", changes),
+		zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+// HandleReloadSignal calls ReloadConfig whenever the process receives SIGHUP,
+// until ctx is done. In-flight requests are not interrupted.
+func HandleReloadSignal(ctx context.Context) {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(reload)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reload:
+				logger.Logger.Info("reload signal received, reloading config")
+				if err := ReloadConfig(ctx); err != nil {
+					logger.Logger.Error("config reload failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// diffOptions returns the options whose value differs between before and
+// after as "old -> new", with sensitive values redacted.
+func diffOptions(before, after map[string]string) map[string]string {
+	changes := make(map[string]string)
+	for key, value := range after {
+		old, ok := before[key]
+		if ok && old == value {
+			continue
+		}
+		if isSensitiveOption(key) {
+			changes[key] = "[redacted]"
+			continue
+		}
+		changes[key] = old + " -> " + value
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changes[key] = "removed"
+		}
+	}
+	return changes
+}
+
+// sensitiveOptions lists the options holding a credential. It is explicit
+// rather than derived from key suffixes, which both miss credentials such as
+// TurnstileSecretKey and would hide public values such as TurnstileSiteKey.
+var sensitiveOptions = map[string]bool{
+	"SMTPToken":          true,
+	"GitHubClientSecret": true,
+	"GoogleClientSecret": true,
+	"LarkClientSecret":   true,
+	"OidcClientSecret":   true,
+	"WeChatServerToken":  true,
+	"MessagePusherToken": true,
+	"TurnstileSecretKey": true,
+}
+
+// isSensitiveOption reports whether the option holds a credential, whose value
+// must not leave the server. GetOptions hides these options from the
+// dashboard, and config reloads and the audit log redact their values.
+func isSensitiveOption(key string) bool {
+	return sensitiveOptions[key]
+}
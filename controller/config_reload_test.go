@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func setupConfigReloadTest(t *testing.T) {
+	t.Helper()
+	setupUserControllerTest(t)
+	originalRetryTimes, originalFooter := config.RetryTimes, config.Footer
+	t.Cleanup(func() {
+		config.RetryTimes, config.Footer = originalRetryTimes, originalFooter
+	})
+	model.InitOptionMap()
+}
+
+func currentRetryTimes() int {
+	config.OptionMapRWMutex.RLock()
+	defer config.OptionMapRWMutex.RUnlock()
+	return config.RetryTimes
+}
+
+func TestReloadConfigAppliesDatabaseOptions(t *testing.T) {
+	setupConfigReloadTest(t)
+
+	// Changes written straight to the database are invisible until a reload
+	require.NoError(t, model.DB.Save(&model.Option{Key: "RetryTimes", Value: "7"}).Error)
+	require.NoError(t, model.DB.Save(&model.Option{Key: "Footer", Value: "reloaded"}).Error)
+	require.NotEqual(t, 7, currentRetryTimes())
+
+	require.NoError(t, ReloadConfig(context.Background()))
+	require.Equal(t, 7, currentRetryTimes())
+	require.Equal(t, "reloaded", config.Footer)
+	require.Equal(t, "7", config.OptionMap["RetryTimes"])
+}
+
+func TestHandleReloadSignal(t *testing.T) {
+	setupConfigReloadTest(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	HandleReloadSignal(ctx)
+
+	require.NoError(t, model.DB.Save(&model.Option{Key: "RetryTimes", Value: "9"}).Error)
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, process.Signal(syscall.SIGHUP))
+	require.Eventually(t, func() bool { return currentRetryTimes() == 9 }, 5*time.Second, 10*time.Millisecond)
+
+	// Requests keep being served after the reload
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	GetOptions(c)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"key":"RetryTimes","value":"9"`)
+}
+
+func TestDiffOptionsRedactsSecrets(t *testing.T) {
+	changes := diffOptions(
+		map[string]string{"Footer": "a", "SMTPToken": "old-token", "Theme": "modern", "Stale": "x"},
+		map[string]string{"Footer": "b", "SMTPToken": "new-token", "Theme": "modern", "GitHubClientSecret": "s"},
+	)
+	require.Equal(t, map[string]string{
+		"Footer":             "a -> b",
+		"SMTPToken":          "[redacted]",
+		"GitHubClientSecret": "[redacted]",
+		"Stale":              "removed",
+	}, changes)
+}
+
+func TestIsSensitiveOption(t *testing.T) {
+	for _, key := range []string{"SMTPToken", "GitHubClientSecret", "OidcClientSecret", "TurnstileSecretKey", "WeChatServerToken"} {
+		require.True(t, isSensitiveOption(key), key)
+	}
+	for _, key := range []string{"TurnstileSiteKey", "ApproximateTokenEnabled", "OidcTokenEndpoint", "Footer"} {
+		require.False(t, isSensitiveOption(key), key)
+	}
+}
@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/message"
+	"github.com/songquanpeng/one-api/model"
+)
+
+const (
+	// costAnomalyBaselineDays is how many previous days of the same hour form
+	// the baseline of a model.
+	costAnomalyBaselineDays = 14
+	// costAnomalyZThreshold is the number of standard deviations above the
+	// baseline mean that makes an hour anomalous.
+	costAnomalyZThreshold = 2.0
+	// maxCostAnomalyDays bounds the window accepted by GetCostAnomalies.
+	maxCostAnomalyDays = 90
+)
+
+// costAnomalyStats returns the mean and population standard deviation of
+// samples and the z-score of value against them. The z-score is 0 when the
+// samples do not vary.
+func costAnomalyStats(value float64, samples []float64) (mean, stddev, z float64) {
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+	for _, s := range samples {
+		mean += s
+	}
+	mean /= float64(len(samples))
+	for _, s := range samples {
+		stddev += (s - mean) * (s - mean)
+	}
+	stddev = math.Sqrt(stddev / float64(len(samples)))
+	if stddev == 0 {
+		return mean, 0, 0
+	}
+	return mean, stddev, (value - mean) / stddev
+}
+
+// isCostAnomaly reports whether value is anomalous against a baseline with
+// mean, stddev and the z-score of value. Against a baseline that does not
+// vary, any value above the mean is anomalous.
+func isCostAnomaly(value, mean, stddev, z float64) bool {
+	if stddev == 0 {
+		return value > mean
+	}
+	return z > costAnomalyZThreshold
+}
+
+// costAnomalyDetector compares the last complete hour of every model with the
+// same hour of the previous costAnomalyBaselineDays days. now and notify are
+// replaceable in tests.
+type costAnomalyDetector struct {
+	now    func() time.Time
+	notify func(by, title, description, content string) error
+}
+
+func newCostAnomalyDetector() *costAnomalyDetector {
+	return &costAnomalyDetector{now: time.Now, notify: message.Notify}
+}
+
+// detect records and alerts on every model whose quota in the last complete
+// hour exceeds the baseline mean by more than costAnomalyZThreshold standard
+// deviations, or exceeds it at all when the baseline does not vary, as for
+// models without history. Hours already recorded are not alerted twice.
+func (d *costAnomalyDetector) detect(ctx context.Context) ([]model.CostAnomaly, error) {
+	hourStart := d.now().Truncate(time.Hour).Add(-time.Hour)
+	current, err := model.GetModelQuota(ctx, hourStart.Unix(), hourStart.Add(time.Hour).Unix())
+	if err != nil {
+		return nil, errors.Wrap(err, "aggregate current hour")
+	}
+	if len(current) == 0 {
+		return nil, nil
+	}
+
+	history := make([]map[string]int64, 0, costAnomalyBaselineDays)
+	for day := 1; day <= costAnomalyBaselineDays; day++ {
+		from := hourStart.AddDate(0, 0, -day)
+		quota, err := model.GetModelQuota(ctx, from.Unix(), from.Add(time.Hour).Unix())
+		if err != nil {
+			return nil, errors.Wrapf(err, "aggregate baseline %d days ago", day)
+		}
+		history = append(history, quota)
+	}
+
+	models := make([]string, 0, len(current))
+	for name := range current {
+		models = append(models, name)
+	}
+	sort.Strings(models)
+
+	var anomalies []model.CostAnomaly
+	for _, name := range models {
+		// Days without consumption count as zero
+		samples := make([]float64, 0, len(history))
+		for _, day := range history {
+			samples = append(samples, float64(day[name]))
+		}
+		quota := current[name]
+		mean, stddev, z := costAnomalyStats(float64(quota), samples)
+		if !isCostAnomaly(float64(quota), mean, stddev, z) {
+			continue
+		}
+
+		exists, err := model.CostAnomalyExists(ctx, name, hourStart.Unix())
+		if err != nil {
+			return anomalies, err
+		}
+		if exists {
+			continue
+		}
+		anomaly := model.CostAnomaly{
+			ModelName:      name,
+			HourStart:      hourStart.Unix(),
+			Quota:          quota,
+			BaselineMean:   mean,
+			BaselineStdDev: stddev,
+			ZScore:         z,
+		}
+		if err := model.CreateCostAnomaly(ctx, &anomaly); err != nil {
+			return anomalies, err
+		}
+		anomalies = append(anomalies, anomaly)
+		d.alert(anomaly)
+	}
+	return anomalies, nil
+}
+
+// alert notifies admins of anomaly. Delivery failures are logged, the
+// detection is kept.
+func (d *costAnomalyDetector) alert(anomaly model.CostAnomaly) {
+	hour := time.Unix(anomaly.HourStart, 0).Format("2006-01-02 15:04")
+	title := fmt.Sprintf("Cost anomaly: %s", anomaly.ModelName)
+	description := fmt.Sprintf("Spending on %s in the hour from %s is %.1f standard deviations above its %d-day baseline.",
+		anomaly.ModelName, hour, anomaly.ZScore, costAnomalyBaselineDays)
+	zScore := fmt.Sprintf("%.2f", anomaly.ZScore)
+	if anomaly.BaselineStdDev == 0 {
+		description = fmt.Sprintf("Spending on %s in the hour from %s is above its constant %d-day baseline.",
+			anomaly.ModelName, hour, costAnomalyBaselineDays)
+		zScore = "n/a, the baseline does not vary"
+	}
+	content := fmt.Sprintf("Model: %s\n\nHour: %s\n\nCurrent cost: $%.4f (%d quota)\n\nBaseline mean: $%.4f (%.0f quota)\n\nZ-score: %s",
+		anomaly.ModelName, hour,
+		float64(anomaly.Quota)/config.QuotaPerUnit, anomaly.Quota,
+		anomaly.BaselineMean/config.QuotaPerUnit, anomaly.BaselineMean,
+		zScore)
+	if err := d.notify(message.ByAll, title, description, content); err != nil {
+		logger.Logger.Error("failed to send cost anomaly alert",
+			zap.String("model", anomaly.ModelName), zap.Error(err))
+	}
+}
+
+// StartCostAnomalyDetection checks the previous hour for cost anomalies at the
+// start of every hour until ctx is done. It does nothing unless
+// config.CostAnomalyDetectionEnabled is set, and only runs on the master node
+// so each anomaly is alerted once.
+func StartCostAnomalyDetection(ctx context.Context) {
+	if !config.CostAnomalyDetectionEnabled || !config.IsMasterNode {
+		return
+	}
+	detector := newCostAnomalyDetector()
+	go func() {
+		for {
+			now := time.Now()
+			// A minute past the hour leaves time for late consume logs
+			next := now.Truncate(time.Hour).Add(time.Hour + time.Minute)
+			if err := sleepContext(ctx, next.Sub(now)); err != nil {
+				return
+			}
+			anomalies, err := detector.detect(ctx)
+			if err != nil {
+				logger.Logger.Error("cost anomaly detection failed", zap.Error(err))
+				continue
+			}
+			if len(anomalies) > 0 {
+				logger.Logger.Warn("cost anomalies detected", zap.Int("count", len(anomalies)))
+			}
+		}
+	}()
+	logger.Logger.Info("cost anomaly detection started")
+}
+
+// GetCostAnomalies lists the cost anomalies detected over the last `days`
+// days (default 7), newest first.
+func GetCostAnomalies(c *gin.Context) {
+	days := 7
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxCostAnomalyDays {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "days must be an integer between 1 and " + strconv.Itoa(maxCostAnomalyDays),
+			})
+			return
+		}
+		days = parsed
+	}
+
+	since := time.Now().AddDate(0, 0, -days).Unix()
+	anomalies, err := model.GetCostAnomalies(gmw.Ctx(c), since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    anomalies,
+	})
+}
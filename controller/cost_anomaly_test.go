@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/message"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestCostAnomalyStats(t *testing.T) {
+	mean, stddev, z := costAnomalyStats(9, []float64{2, 4, 4, 4, 5, 5, 7, 9})
+	require.InDelta(t, 5, mean, 1e-9)
+	require.InDelta(t, 2, stddev, 1e-9)
+	require.InDelta(t, 2, z, 1e-9)
+
+	mean, stddev, z = costAnomalyStats(100, []float64{10, 10, 10})
+	require.InDelta(t, 10, mean, 1e-9)
+	require.Zero(t, stddev)
+	require.Zero(t, z)
+
+	_, _, z = costAnomalyStats(100, nil)
+	require.Zero(t, z)
+}
+
+func TestIsCostAnomaly(t *testing.T) {
+	require.True(t, isCostAnomaly(9, 5, 2, 2.01))
+	require.False(t, isCostAnomaly(9, 5, 2, 2))
+	// A baseline that does not vary flags any increase
+	require.True(t, isCostAnomaly(11, 10, 0, 0))
+	require.False(t, isCostAnomaly(10, 10, 0, 0))
+	require.True(t, isCostAnomaly(1, 0, 0, 0))
+}
+
+type sentAlert struct {
+	by, title, description, content string
+}
+
+func TestCostAnomalyDetector(t *testing.T) {
+	setupUserControllerTest(t)
+
+	now := time.Date(2026, 5, 15, 13, 1, 0, 0, time.UTC)
+	hourStart := time.Date(2026, 5, 15, 12, 0, 0, 0, time.UTC)
+	var logs []model.Log
+	for day := 1; day <= costAnomalyBaselineDays; day++ {
+		at := hourStart.AddDate(0, 0, -day).Add(10 * time.Minute).Unix()
+		// gpt-4o alternates between 900k and 1.1M quota, so mean 1M, stddev 100k
+		quota := int64(900_000)
+		if day%2 == 0 {
+			quota = 1_100_000
+		}
+		logs = append(logs,
+			model.Log{ModelName: "gpt-4o", Quota: int(quota), Type: model.LogTypeConsume, CreatedAt: at},
+			model.Log{ModelName: "claude-sonnet-4-5", Quota: int(quota), Type: model.LogTypeConsume, CreatedAt: at},
+			// Outside the compared hour
+			model.Log{ModelName: "gpt-4o", Quota: 50_000_000, Type: model.LogTypeConsume, CreatedAt: at + 3600},
+		)
+	}
+	at := hourStart.Add(30 * time.Minute).Unix()
+	logs = append(logs,
+		// z = (1.5M - 1M) / 100k = 5
+		model.Log{ModelName: "gpt-4o", Quota: 1_000_000, Type: model.LogTypeConsume, CreatedAt: at},
+		model.Log{ModelName: "gpt-4o", Quota: 500_000, Type: model.LogTypeConsume, CreatedAt: at + 60},
+		// z = 1.5, within the baseline
+		model.Log{ModelName: "claude-sonnet-4-5", Quota: 1_150_000, Type: model.LogTypeConsume, CreatedAt: at},
+		// No history, any spending is anomalous
+		model.Log{ModelName: "new-model", Quota: 9_000_000, Type: model.LogTypeConsume, CreatedAt: at},
+		// Not consumption
+		model.Log{ModelName: "claude-sonnet-4-5", Quota: 90_000_000, Type: model.LogTypeTopup, CreatedAt: at},
+	)
+	require.NoError(t, model.LOG_DB.Create(&logs).Error)
+
+	var alerts []sentAlert
+	detector := &costAnomalyDetector{
+		now: func() time.Time { return now },
+		notify: func(by, title, description, content string) error {
+			alerts = append(alerts, sentAlert{by, title, description, content})
+			return nil
+		},
+	}
+
+	anomalies, err := detector.detect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, anomalies, 2)
+	require.Equal(t, "gpt-4o", anomalies[0].ModelName)
+	require.Equal(t, hourStart.Unix(), anomalies[0].HourStart)
+	require.Equal(t, int64(1_500_000), anomalies[0].Quota)
+	require.InDelta(t, 1_000_000, anomalies[0].BaselineMean, 1e-6)
+	require.InDelta(t, 100_000, anomalies[0].BaselineStdDev, 1e-6)
+	require.InDelta(t, 5, anomalies[0].ZScore, 1e-9)
+	require.Equal(t, "new-model", anomalies[1].ModelName)
+	require.Zero(t, anomalies[1].BaselineMean)
+	require.Zero(t, anomalies[1].BaselineStdDev)
+
+	require.Len(t, alerts, 2)
+	require.Equal(t, message.ByAll, alerts[0].by)
+	require.Contains(t, alerts[0].title, "gpt-4o")
+	require.Contains(t, alerts[0].content, "Model: gpt-4o")
+	require.Contains(t, alerts[0].content, "Current cost: $3.0000 (1500000 quota)")
+	require.Contains(t, alerts[0].content, "Baseline mean: $2.0000")
+	require.Contains(t, alerts[0].content, "Z-score: 5.00")
+	require.Contains(t, alerts[1].description, "above its constant 14-day baseline")
+	require.Contains(t, alerts[1].content, "Z-score: n/a")
+
+	// A second run over the same hour neither records nor alerts again
+	anomalies, err = detector.detect(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, anomalies)
+	require.Len(t, alerts, 2)
+
+	stored, err := model.GetCostAnomalies(context.Background(), hourStart.Unix())
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+}
+
+func TestGetCostAnomalies(t *testing.T) {
+	setupUserControllerTest(t)
+
+	recent := time.Now().Add(-2 * time.Hour).Truncate(time.Hour).Unix()
+	old := time.Now().AddDate(0, 0, -10).Unix()
+	for _, anomaly := range []model.CostAnomaly{
+		{ModelName: "gpt-4o", HourStart: recent, Quota: 10, ZScore: 3},
+		{ModelName: "gpt-4o", HourStart: old, Quota: 20, ZScore: 4},
+	} {
+		require.NoError(t, model.CreateCostAnomaly(context.Background(), &anomaly))
+	}
+
+	router := gin.New()
+	router.GET("/api/admin/anomalies/cost", GetCostAnomalies)
+	get := func(query string) (*httptest.ResponseRecorder, []model.CostAnomaly) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/anomalies/cost"+query, nil))
+		var resp struct {
+			Data []model.CostAnomaly `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w, resp.Data
+	}
+
+	w, data := get("")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, data, 1)
+	require.Equal(t, recent, data[0].HourStart)
+
+	_, data = get("?days=14")
+	require.Len(t, data, 2)
+
+	w, _ = get("?days=0")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
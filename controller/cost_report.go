@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/cron"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/message"
+	"github.com/songquanpeng/one-api/model"
+)
+
+const (
+	// costReportPeriodLastMonth reports the previous calendar month.
+	costReportPeriodLastMonth = "last_month"
+	// costReportPeriodThisMonth reports the current calendar month up to now.
+	costReportPeriodThisMonth = "this_month"
+	// costReportTopN is the number of models and users listed in a report.
+	costReportTopN = 10
+)
+
+// CostReport is the cost attribution report of a period, compared with the
+// same span of the month before.
+type CostReport struct {
+	Period   string                 `json:"period"`
+	From     time.Time              `json:"from"`
+	To       time.Time              `json:"to"`
+	Current  *model.CostReportUsage `json:"current"`
+	Previous *model.CostReportUsage `json:"previous"`
+	// QuotaChangePercent is the month-over-month change of the total quota,
+	// nil when the previous period has no usage.
+	QuotaChangePercent *float64 `json:"quota_change_percent"`
+}
+
+// TopModels returns the costliest models of the period.
+func (r *CostReport) TopModels() []model.CostReportEntry {
+	return r.Current.Models[:min(len(r.Current.Models), costReportTopN)]
+}
+
+// TopUsers returns the users who consumed the most quota in the period.
+func (r *CostReport) TopUsers() []model.CostReportEntry {
+	return r.Current.Users[:min(len(r.Current.Users), costReportTopN)]
+}
+
+// costReportRange returns the [from, to) range of a named period relative to
+// now, in now's location.
+func costReportRange(period string, now time.Time) (from, to time.Time, err error) {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	switch period {
+	case costReportPeriodLastMonth:
+		return monthStart.AddDate(0, -1, 0), monthStart, nil
+	case costReportPeriodThisMonth:
+		return monthStart, now, nil
+	default:
+		return time.Time{}, time.Time{}, errors.Errorf("period must be one of %s or %s", costReportPeriodLastMonth, costReportPeriodThisMonth)
+	}
+}
+
+// BuildCostReport compiles the cost report of period relative to now.
+func BuildCostReport(period string, now time.Time) (*CostReport, error) {
+	from, to, err := costReportRange(period, now)
+	if err != nil {
+		return nil, err
+	}
+	current, err := model.GetCostReportUsage(from.Unix(), to.Unix())
+	if err != nil {
+		return nil, errors.Wrap(err, "aggregate current period")
+	}
+	// Compare with the same span of the month before, so a partial month is
+	// compared with the matching part of the previous one
+	previous, err := model.GetCostReportUsage(from.AddDate(0, -1, 0).Unix(), to.AddDate(0, -1, 0).Unix())
+	if err != nil {
+		return nil, errors.Wrap(err, "aggregate previous period")
+	}
+
+	report := &CostReport{Period: period, From: from, To: to, Current: current, Previous: previous}
+	if previous.TotalQuota > 0 {
+		change := float64(current.TotalQuota-previous.TotalQuota) / float64(previous.TotalQuota) * 100
+		report.QuotaChangePercent = &change
+	}
+	return report, nil
+}
+
+// costReportFuncs are the helpers shared by the HTML and plain-text templates.
+var costReportFuncs = map[string]any{
+	"usd": func(quota int64) string {
+		return fmt.Sprintf("$%.2f", float64(quota)/config.QuotaPerUnit)
+	},
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+	"change": func(percent *float64) string {
+		if percent == nil {
+			return "n/a"
+		}
+		return fmt.Sprintf("%+.1f%%", *percent)
+	},
+	"dict": costReportDict,
+}
+
+const costReportHTML = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<h2>{{.SystemName}} cost report</h2>
+<p>Period: {{date .Report.From}} to {{date .Report.To}} (exclusive)</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Total cost</th><td>{{usd .Report.Current.TotalQuota}} ({{.Report.Current.TotalQuota}} quota)</td></tr>
+<tr><th>Requests</th><td>{{.Report.Current.Requests}}</td></tr>
+<tr><th>Previous period</th><td>{{usd .Report.Previous.TotalQuota}} ({{.Report.Previous.TotalQuota}} quota)</td></tr>
+<tr><th>Month over month</th><td>{{change .Report.QuotaChangePercent}}</td></tr>
+</table>
+{{template "breakdown" dict "Title" "Cost by group" "Entries" .Report.Current.Groups}}
+{{template "breakdown" dict "Title" "Top 10 models by cost" "Entries" .Report.TopModels}}
+{{template "breakdown" dict "Title" "Top 10 users by quota" "Entries" .Report.TopUsers}}
+{{template "breakdown" dict "Title" "Cost by channel" "Entries" .Report.Current.Channels}}
+</body>
+</html>
+{{define "breakdown"}}<h3>{{.Title}}</h3>
+{{if .Entries}}<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Name</th><th>Cost</th><th>Quota</th><th>Requests</th></tr>
+{{range .Entries}}<tr><td>{{.Name}}</td><td>{{usd .Quota}}</td><td>{{.Quota}}</td><td>{{.Requests}}</td></tr>
+{{end}}</table>{{else}}<p>No usage.</p>{{end}}
+{{end}}`
+
+const costReportText = `{{.SystemName}} cost report
+Period: {{date .Report.From}} to {{date .Report.To}} (exclusive)
+
+Total cost: {{usd .Report.Current.TotalQuota}} ({{.Report.Current.TotalQuota}} quota)
+Requests: {{.Report.Current.Requests}}
+Previous period: {{usd .Report.Previous.TotalQuota}} ({{.Report.Previous.TotalQuota}} quota)
+Month over month: {{change .Report.QuotaChangePercent}}
+{{template "breakdown" dict "Title" "Cost by group" "Entries" .Report.Current.Groups}}
+{{- template "breakdown" dict "Title" "Top 10 models by cost" "Entries" .Report.TopModels}}
+{{- template "breakdown" dict "Title" "Top 10 users by quota" "Entries" .Report.TopUsers}}
+{{- template "breakdown" dict "Title" "Cost by channel" "Entries" .Report.Current.Channels}}
+{{- define "breakdown"}}
+{{.Title}}
+{{range .Entries}}- {{.Name}}: {{usd .Quota}} ({{.Quota}} quota, {{.Requests}} requests)
+{{else}}No usage.
+{{end}}{{end}}`
+
+// costReportDict builds a map from alternating keys and values, for passing
+// several arguments to a nested template.
+func costReportDict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict needs an even number of arguments")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, errors.Errorf("dict key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+var (
+	costReportHTMLTemplate = htmltemplate.Must(htmltemplate.New("cost_report").
+				Funcs(costReportFuncs).Parse(costReportHTML))
+	costReportTextTemplate = texttemplate.Must(texttemplate.New("cost_report").
+				Funcs(costReportFuncs).Parse(costReportText))
+)
+
+// RenderCostReport renders report as an HTML and a plain-text email body.
+func RenderCostReport(report *CostReport) (html string, text string, err error) {
+	data := map[string]any{"SystemName": config.SystemName, "Report": report}
+	var htmlBuf, textBuf bytes.Buffer
+	if err = costReportHTMLTemplate.Execute(&htmlBuf, data); err != nil {
+		return "", "", errors.Wrap(err, "render html cost report")
+	}
+	if err = costReportTextTemplate.Execute(&textBuf, data); err != nil {
+		return "", "", errors.Wrap(err, "render text cost report")
+	}
+	return htmlBuf.String(), textBuf.String(), nil
+}
+
+// PreviewCostReport renders the cost report of the requested period without
+// sending it. The period query parameter is last_month (default) or
+// this_month; format=text returns the plain-text body and format=json the raw
+// figures instead of the HTML body.
+func PreviewCostReport(c *gin.Context) {
+	report, err := BuildCostReport(c.DefaultQuery("period", costReportPeriodLastMonth), time.Now())
+	if err != nil {
+		gmw.GetLogger(c).Warn("failed to build cost report preview", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "",
+			"data":    report,
+		})
+		return
+	}
+	html, text, err := RenderCostReport(report)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if c.Query("format") == "text" {
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(text))
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+}
+
+// sendCostReport emails the report of the month before firedAt to the
+// configured recipients.
+func sendCostReport(firedAt time.Time) error {
+	report, err := BuildCostReport(costReportPeriodLastMonth, firedAt)
+	if err != nil {
+		return errors.Wrap(err, "build cost report")
+	}
+	html, text, err := RenderCostReport(report)
+	if err != nil {
+		return err
+	}
+	// SendEmail separates recipients with semicolons
+	recipients := strings.ReplaceAll(config.CostReportRecipients, ",", ";")
+	subject := fmt.Sprintf("%s cost report %s", config.SystemName, report.From.Format("2006-01"))
+	if err = message.SendEmailWithPlainText(subject, recipients, html, text); err != nil {
+		return errors.Wrap(err, "send cost report")
+	}
+	return nil
+}
+
+// costReportScheduler invokes send every time schedule fires. now and after
+// are the clock, replaceable in tests.
+type costReportScheduler struct {
+	schedule *cron.Schedule
+	now      func() time.Time
+	after    func(time.Duration) <-chan time.Time
+	send     func(firedAt time.Time) error
+}
+
+func (s *costReportScheduler) run(ctx context.Context) {
+	// Check ctx before waiting, as select picks randomly among ready channels
+	for ctx.Err() == nil {
+		next := s.schedule.Next(s.now())
+		if next.IsZero() {
+			logger.Logger.Warn("cost report schedule never fires, scheduler stopped")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.after(next.Sub(s.now())):
+		}
+		if err := s.send(next); err != nil {
+			logger.Logger.Error("failed to send cost report", zap.Error(err))
+			continue
+		}
+		logger.Logger.Info("cost report sent", zap.Time("fired_at", next))
+	}
+}
+
+// StartCostReportScheduler emails the cost report on config.CostReportSchedule
+// until ctx is done. It does nothing unless config.CostReportEnabled is set and
+// recipients are configured, and only runs on the master node so each report is
+// sent once.
+func StartCostReportScheduler(ctx context.Context) {
+	if !config.CostReportEnabled || !config.IsMasterNode {
+		return
+	}
+	if strings.TrimSpace(config.CostReportRecipients) == "" {
+		logger.Logger.Warn("cost report enabled without COST_REPORT_RECIPIENTS, scheduler not started")
+		return
+	}
+	schedule, err := cron.Parse(config.CostReportSchedule)
+	if err != nil {
+		logger.Logger.Error("invalid COST_REPORT_SCHEDULE, scheduler not started", zap.Error(err))
+		return
+	}
+
+	scheduler := &costReportScheduler{
+		schedule: schedule,
+		now:      time.Now,
+		after:    time.After,
+		send:     sendCostReport,
+	}
+	go scheduler.run(ctx)
+	logger.Logger.Info("cost report scheduler started", zap.String("schedule", config.CostReportSchedule))
+}
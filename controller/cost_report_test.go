@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/cron"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestCostReportScheduler_FiresOnSchedule(t *testing.T) {
+	schedule, err := cron.Parse(config.CostReportSchedule)
+	require.NoError(t, err)
+
+	clock := time.Date(2026, 3, 15, 10, 30, 0, 0, time.UTC)
+	var waits []time.Duration
+	var fired []time.Time
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduler := &costReportScheduler{
+		schedule: schedule,
+		now:      func() time.Time { return clock },
+		after: func(d time.Duration) <-chan time.Time {
+			waits = append(waits, d)
+			clock = clock.Add(d)
+			ch := make(chan time.Time, 1)
+			ch <- clock
+			return ch
+		},
+		send: func(firedAt time.Time) error {
+			fired = append(fired, firedAt)
+			if len(fired) == 3 {
+				cancel()
+			}
+			// A failed send does not stop the schedule
+			return errors.New("smtp unavailable")
+		},
+	}
+	scheduler.run(ctx)
+
+	require.Equal(t, []time.Time{
+		time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+	}, fired)
+	require.Equal(t, 16*24*time.Hour+13*time.Hour+30*time.Minute, waits[0])
+	require.Equal(t, 30*24*time.Hour, waits[1])
+}
+
+func TestBuildCostReport_RendersValidHTML(t *testing.T) {
+	setupUserControllerTest(t)
+
+	alice := &model.User{Username: "report-alice", Password: "password", Group: "finance", Status: model.UserStatusEnabled, AccessToken: "report-alice-token", AffCode: "rpa1"}
+	bob := &model.User{Username: "report-bob", Password: "password", Group: "research", Status: model.UserStatusEnabled, AccessToken: "report-bob-token", AffCode: "rpb1"}
+	require.NoError(t, model.DB.Create(alice).Error)
+	require.NoError(t, model.DB.Create(bob).Error)
+	require.NoError(t, model.DB.Create(&model.Channel{Id: 41, Name: "primary <openai>", Type: 1, Key: "sk-test"}).Error)
+
+	now := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	march := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC).Unix()
+	february := time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC).Unix()
+	require.NoError(t, model.LOG_DB.Create(&[]model.Log{
+		{UserId: alice.Id, Username: alice.Username, ModelName: "gpt-4o", ChannelId: 41, Quota: 500_000, Type: model.LogTypeConsume, CreatedAt: march},
+		{UserId: alice.Id, Username: alice.Username, ModelName: "claude-3", ChannelId: 41, Quota: 250_000, Type: model.LogTypeConsume, CreatedAt: march + 60},
+		{UserId: bob.Id, Username: bob.Username, ModelName: "gpt-4o", ChannelId: 42, Quota: 1_000_000, Type: model.LogTypeConsume, CreatedAt: march + 120},
+		// Not consumption, or outside the period
+		{UserId: bob.Id, Username: bob.Username, ModelName: "gpt-4o", Quota: 9_000_000, Type: model.LogTypeTopup, CreatedAt: march},
+		{UserId: bob.Id, Username: bob.Username, ModelName: "gpt-4o", Quota: 7_000_000, Type: model.LogTypeConsume, CreatedAt: now.Unix()},
+		{UserId: bob.Id, Username: bob.Username, ModelName: "gpt-4o", ChannelId: 42, Quota: 875_000, Type: model.LogTypeConsume, CreatedAt: february},
+	}).Error)
+
+	report, err := BuildCostReport(costReportPeriodLastMonth, now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), report.From)
+	require.Equal(t, now, report.To)
+	require.EqualValues(t, 1_750_000, report.Current.TotalQuota)
+	require.EqualValues(t, 3, report.Current.Requests)
+	require.Equal(t, []model.CostReportEntry{
+		{Name: "research", Quota: 1_000_000, Requests: 1},
+		{Name: "finance", Quota: 750_000, Requests: 2},
+	}, report.Current.Groups)
+	require.Equal(t, []model.CostReportEntry{
+		{Name: "gpt-4o", Quota: 1_500_000, Requests: 2},
+		{Name: "claude-3", Quota: 250_000, Requests: 1},
+	}, report.TopModels())
+	require.Equal(t, "report-bob", report.TopUsers()[0].Name)
+	require.Equal(t, []model.CostReportEntry{
+		{Name: "#42", Quota: 1_000_000, Requests: 1},
+		{Name: "#41 primary <openai>", Quota: 750_000, Requests: 2},
+	}, report.Current.Channels)
+	require.EqualValues(t, 875_000, report.Previous.TotalQuota)
+	require.NotNil(t, report.QuotaChangePercent)
+	require.InDelta(t, 100.0, *report.QuotaChangePercent, 1e-9)
+
+	html, text, err := RenderCostReport(report)
+	require.NoError(t, err)
+
+	// The HTML is well-formed and escapes channel names
+	decoder := xml.NewDecoder(strings.NewReader(html))
+	decoder.Strict = true
+	decoder.AutoClose = nil
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.Contains(t, html, "primary &lt;openai&gt;")
+	require.Contains(t, html, "<td>$3.50 (1750000 quota)</td>")
+	require.Contains(t, html, "&#43;100.0%")
+
+	require.Contains(t, text, "Total cost: $3.50 (1750000 quota)")
+	require.Contains(t, text, "- gpt-4o: $3.00 (1500000 quota, 2 requests)")
+	require.Contains(t, text, "Month over month: +100.0%")
+}
+
+func TestPreviewCostReport(t *testing.T) {
+	setupUserControllerTest(t)
+	router := gin.New()
+	router.GET("/api/admin/reports/cost/preview", PreviewCostReport)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/reports/cost/preview?period=last_month", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, w.Body.String(), "No usage.")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/reports/cost/preview?period=this_month&format=text", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "Month over month: n/a")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/reports/cost/preview?period=last_year", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+// useEmailDomainBlocklist enables the blocklist with the given entries for one test.
+func useEmailDomainBlocklist(t *testing.T, entries ...string) {
+	t.Helper()
+	originalEnabled, originalList := config.EmailDomainBlocklistEnabled, config.EmailDomainBlocklist
+	originalWhitelistEnabled := config.EmailDomainRestrictionEnabled
+	config.EmailDomainBlocklistEnabled = true
+	config.EmailDomainBlocklist = entries
+	config.EmailDomainRestrictionEnabled = false
+	t.Cleanup(func() {
+		config.EmailDomainBlocklistEnabled, config.EmailDomainBlocklist = originalEnabled, originalList
+		config.EmailDomainRestrictionEnabled = originalWhitelistEnabled
+	})
+}
+
+// postJSON sends payload to handler and returns the decoded response.
+func postJSON(t *testing.T, handler gin.HandlerFunc, payload any) map[string]any {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.POST("/", handler)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	resp := make(map[string]any)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestIsEmailDomainBlocked(t *testing.T) {
+	useEmailDomainBlocklist(t, "mailinator.com", "*.tempmail.org")
+
+	require.True(t, isEmailDomainBlocked("user@mailinator.com"))
+	require.True(t, isEmailDomainBlocked("user@MAILINATOR.com"))
+	require.False(t, isEmailDomainBlocked("user@sub.mailinator.com"))
+	require.True(t, isEmailDomainBlocked("user@a.tempmail.org"))
+	require.True(t, isEmailDomainBlocked("user@a.b.tempmail.org"))
+	require.False(t, isEmailDomainBlocked("user@tempmail.org.example.com"))
+	require.False(t, isEmailDomainBlocked("user@gmail.com"))
+
+	config.EmailDomainBlocklistEnabled = false
+	require.False(t, isEmailDomainBlocked("user@mailinator.com"))
+}
+
+func TestRegister_RejectsBlockedEmailDomains(t *testing.T) {
+	setupUserControllerTest(t)
+	useEmailDomainBlocklist(t, "mailinator.com", "*.tempmail.org")
+	originalVerification := config.EmailVerificationEnabled
+	config.EmailVerificationEnabled = false
+	t.Cleanup(func() { config.EmailVerificationEnabled = originalVerification })
+
+	for _, email := range []string{"spam@mailinator.com", "spam@x.tempmail.org"} {
+		resp := postJSON(t, Register, map[string]any{
+			"username": "blocked-user",
+			"password": "password123",
+			"email":    email,
+		})
+		require.Equal(t, false, resp["success"], email)
+		require.Equal(t, emailDomainNotAllowedMessage, resp["message"], email)
+	}
+
+	resp := postJSON(t, Register, map[string]any{
+		"username": "allowed-user",
+		"password": "password123",
+		"email":    "someone@example.com",
+	})
+	require.Equal(t, true, resp["success"], resp["message"])
+}
+
+func TestUpdateOption_EmailDomainModesMutuallyExclusive(t *testing.T) {
+	useEmailDomainBlocklist(t, "mailinator.com")
+	gin.SetMode(gin.TestMode)
+
+	resp := postJSON(t, UpdateOption, map[string]any{"key": "EmailDomainRestrictionEnabled", "value": "true"})
+	require.Equal(t, false, resp["success"])
+	require.Contains(t, resp["message"], "mutually exclusive")
+
+	config.EmailDomainBlocklistEnabled = false
+	config.EmailDomainRestrictionEnabled = true
+	resp = postJSON(t, UpdateOption, map[string]any{"key": "EmailDomainBlocklistEnabled", "value": "true"})
+	require.Equal(t, false, resp["success"])
+	require.Contains(t, resp["message"], "mutually exclusive")
+}
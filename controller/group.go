@@ -1,10 +1,18 @@
 package controller
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
 	"net/http"
+	"strings"
 
+	gmw "github.com/Laisky/gin-middlewares/v7"
 	"github.com/gin-gonic/gin"
 
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
 	billingratio "github.com/songquanpeng/one-api/relay/billing/ratio"
 )
 
@@ -20,3 +28,86 @@ func GetGroups(c *gin.Context) {
 		"data":    groupNames,
 	})
 }
+
+// GetGroupTiers returns the subscription tier assigned to each group together
+// with the capabilities of every configured tier.
+func GetGroupTiers(c *gin.Context) {
+	groupTiers := make(map[string]string)
+	if err := json.Unmarshal([]byte(billingratio.GroupTier2JSONString()), &groupTiers); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"groups": groupTiers,
+			"tiers":  config.TierCapabilitiesByName,
+		},
+	})
+}
+
+// UpdateGroupTier assigns a subscription tier to a group, or clears it when the
+// tier is empty. The change is persisted in the GroupTier option and recorded
+// as a management log entry attributed to the acting administrator.
+func UpdateGroupTier(c *gin.Context) {
+	var req struct {
+		Group string `json:"group"`
+		Tier  string `json:"tier"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": invalidParameterMessage})
+		return
+	}
+	req.Group = strings.TrimSpace(req.Group)
+	req.Tier = strings.ToLower(strings.TrimSpace(req.Tier))
+	if req.Group == "" {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": "group is required"})
+		return
+	}
+	if _, ok := billingratio.GroupRatio[req.Group]; !ok {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": fmt.Sprintf("unknown group: %s", req.Group)})
+		return
+	}
+	if req.Tier != "" && !config.IsValidTier(req.Tier) {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": fmt.Sprintf("unknown tier: %s", req.Tier)})
+		return
+	}
+
+	groupTiers := make(map[string]string)
+	if err := json.Unmarshal([]byte(billingratio.GroupTier2JSONString()), &groupTiers); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	previous := groupTiers[req.Group]
+	updated := maps.Clone(groupTiers)
+	if req.Tier == "" {
+		delete(updated, req.Group)
+	} else {
+		updated[req.Group] = req.Tier
+	}
+
+	payload, err := json.Marshal(updated)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err = model.UpdateOption("GroupTier", string(payload)); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	if previous != req.Tier {
+		adminId := c.GetInt(ctxkey.Id)
+		model.RecordManageLog(gmw.Ctx(c), adminId, "group_tier:"+req.Group, previous, req.Tier,
+			fmt.Sprintf("admin_id=%d", adminId))
+		model.RecordAuditLog(gmw.Ctx(c), adminId, model.AuditTargetGroup, req.Group, "update_tier",
+			gin.H{"tier": previous}, gin.H{"tier": req.Tier})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    updated,
+	})
+}
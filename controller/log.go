@@ -1,9 +1,14 @@
 package controller
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
 	"github.com/gin-gonic/gin"
 
 	"github.com/songquanpeng/one-api/common/config"
@@ -85,6 +90,57 @@ func GetAllLogs(c *gin.Context) {
 	})
 }
 
+// ExportLogs streams the logs matching the GetAllLogs filters as a CSV
+// attachment. Exports over model.MaxLogExportRows entries are rejected with
+// 422 Unprocessable Entity.
+func ExportLogs(c *gin.Context) {
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "unsupported export format: " + format,
+		})
+		return
+	}
+
+	filters := model.LogExportFilters{
+		Username:  c.Query("username"),
+		TokenName: c.Query("token_name"),
+		ModelName: c.Query("model_name"),
+		SortBy:    c.DefaultQuery("sort_by", c.Query("sort")),
+		SortOrder: c.DefaultQuery("sort_order", "desc"),
+	}
+	filters.LogType, _ = strconv.Atoi(c.Query("type"))
+	filters.StartTimestamp, _ = strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	filters.EndTimestamp, _ = strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	filters.Channel, _ = strconv.Atoi(c.Query("channel"))
+	if order := c.Query("order"); order != "" {
+		filters.SortOrder = order
+	}
+
+	body, err := model.ExportLogsToCsv(gmw.Ctx(c), filters)
+	if err != nil {
+		if errors.Is(err, model.ErrLogExportTooLarge) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		gmw.GetLogger(c).Error("failed to export logs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "failed to export logs",
+		})
+		return
+	}
+	// Closing stops the export when the client goes away mid-stream
+	defer body.Close()
+
+	filename := fmt.Sprintf("logs-%s.csv", time.Now().UTC().Format("20060102T150405Z"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.DataFromReader(http.StatusOK, -1, "text/csv; charset=utf-8", body, nil)
+}
+
 // GetUserLogs lists logs scoped to the current user, honoring filter and sorting options.
 func GetUserLogs(c *gin.Context) {
 	p, _ := strconv.Atoi(c.Query("p"))
@@ -154,6 +210,13 @@ func GetUserLogs(c *gin.Context) {
 	})
 }
 
+// searchUsesFullText reports whether a log search uses the database full-text
+// index. It does unless the full_text query parameter is false.
+func searchUsesFullText(c *gin.Context) bool {
+	useFullText, err := strconv.ParseBool(c.DefaultQuery("full_text", "true"))
+	return err != nil || useFullText
+}
+
 // SearchAllLogs performs full-text search across all logs and returns paginated results.
 func SearchAllLogs(c *gin.Context) {
 	keyword := c.Query("keyword")
@@ -173,7 +236,7 @@ func SearchAllLogs(c *gin.Context) {
 	if sortOrder == "" {
 		sortOrder = "desc"
 	}
-	logs, total, err := model.SearchAllLogs(keyword, p*size, size, sortBy, sortOrder)
+	logs, total, err := model.SearchAllLogs(keyword, p*size, size, sortBy, sortOrder, searchUsesFullText(c))
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -210,7 +273,7 @@ func SearchUserLogs(c *gin.Context) {
 	if sortOrder == "" {
 		sortOrder = "desc"
 	}
-	logs, total, err := model.SearchUserLogs(userId, keyword, p*size, size, sortBy, sortOrder)
+	logs, total, err := model.SearchUserLogs(userId, keyword, p*size, size, sortBy, sortOrder, searchUsesFullText(c))
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -238,12 +301,17 @@ func GetLogsStat(c *gin.Context) {
 	channel, _ := strconv.Atoi(c.Query("channel"))
 	quotaNum := model.SumUsedQuota(logType, startTimestamp, endTimestamp, modelName, username, tokenName, channel)
 	//tokenNum := model.SumUsedToken(logType, startTimestamp, endTimestamp, modelName, username, "")
+	compression, err := model.GetLogCompressionStats()
+	if err != nil {
+		gmw.GetLogger(c).Warn("failed to get log compression stats", zap.Error(err))
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
 		"data": gin.H{
 			"quota": quotaNum,
 			//"token": tokenNum,
+			"compression": compression,
 		},
 	})
 }
@@ -287,6 +355,8 @@ func DeleteHistoryLogs(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetLog, "history", model.AuditActionDelete,
+		gin.H{"before_timestamp": targetTimestamp}, gin.H{"deleted": count})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
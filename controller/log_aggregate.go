@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	gutils "github.com/Laisky/go-utils/v6"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/dto"
+	"github.com/songquanpeng/one-api/model"
+)
+
+const (
+	// logAggregatesCacheTTL bounds how long log aggregates are served from cache.
+	logAggregatesCacheTTL = time.Minute
+	// defaultLogAggregatesWindow is the range aggregated when start_timestamp is omitted.
+	defaultLogAggregatesWindow = 30 * 24 * time.Hour
+)
+
+// logAggregatesCache caches log aggregates by range and user.
+var logAggregatesCache = gutils.NewExpCache[*LogAggregatesResponse](context.Background(), logAggregatesCacheTTL)
+
+// LogAggregatesResponse is the payload of GetLogAggregates.
+type LogAggregatesResponse struct {
+	StartTimestamp int64                        `json:"start_timestamp"`
+	EndTimestamp   int64                        `json:"end_timestamp"`
+	UserId         int                          `json:"user_id,omitempty"`
+	ByModel        []*dto.LogAggregateByModel   `json:"by_model"`
+	ByChannel      []*dto.LogAggregateByChannel `json:"by_channel"`
+	ByUser         []*dto.LogAggregateByUser    `json:"by_user"`
+	ByDay          []*dto.LogAggregateByDay     `json:"by_day"`
+}
+
+// parseLogAggregatesQuery validates the range and user of GetLogAggregates.
+func parseLogAggregatesQuery(c *gin.Context) (start, end int64, userId int, err error) {
+	// Default to whole UTC days so repeated requests share a cache entry
+	endOfToday := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour).Unix()
+	if end, err = parseUnixQuery(c, "end_timestamp", endOfToday); err != nil {
+		return 0, 0, 0, err
+	}
+	defaultStart := max(end-int64(defaultLogAggregatesWindow/time.Second), 0)
+	if start, err = parseUnixQuery(c, "start_timestamp", defaultStart); err != nil {
+		return 0, 0, 0, err
+	}
+	if start >= end {
+		return 0, 0, 0, errors.Errorf("start_timestamp must be before end_timestamp")
+	}
+	if raw := c.Query("user_id"); raw != "" {
+		if userId, err = strconv.Atoi(raw); err != nil || userId < 0 {
+			return 0, 0, 0, errors.Errorf("user_id must be a non-negative integer")
+		}
+	}
+	return start, end, userId, nil
+}
+
+// loadLogAggregates runs the four aggregations of GetLogAggregates.
+func loadLogAggregates(start, end int64, userId int) (resp *LogAggregatesResponse, err error) {
+	resp = &LogAggregatesResponse{StartTimestamp: start, EndTimestamp: end, UserId: userId}
+	if resp.ByModel, err = model.AggregateLogsByModel(userId, start, end); err != nil {
+		return nil, err
+	}
+	if resp.ByChannel, err = model.AggregateLogsByChannel(userId, start, end); err != nil {
+		return nil, err
+	}
+	if resp.ByUser, err = model.AggregateLogsByUser(userId, start, end); err != nil {
+		return nil, err
+	}
+	if resp.ByDay, err = model.AggregateLogsByDay(userId, start, end); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetLogAggregates sums consume logs by model, channel, user and day for
+// [start_timestamp, end_timestamp), optionally for a single user_id.
+// end_timestamp defaults to the end of the current UTC day and
+// start_timestamp to 30 days before it. Results are cached for a minute.
+func GetLogAggregates(c *gin.Context) {
+	start, end, userId, err := parseLogAggregatesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	cacheKey := fmt.Sprintf("%d:%d:%d", start, end, userId)
+	resp, ok := logAggregatesCache.Load(cacheKey)
+	if !ok {
+		if resp, err = loadLogAggregates(start, end, userId); err != nil {
+			gmw.GetLogger(c).Error("failed to aggregate logs", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		logAggregatesCache.Store(cacheKey, resp)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    resp,
+	})
+}
@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// GetSelfLoginHistory lists the latest login attempts of the current user,
+// newest first.
+func GetSelfLoginHistory(c *gin.Context) {
+	respondLoginHistory(c, c.GetInt(ctxkey.Id))
+}
+
+// GetUserLoginHistory lists the latest login attempts of the user in the id
+// path parameter, newest first.
+func GetUserLoginHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": invalidParameterMessage})
+		return
+	}
+	respondLoginHistory(c, id)
+}
+
+func respondLoginHistory(c *gin.Context, userId int) {
+	entries, err := model.GetLoginLogs(gmw.Ctx(c), userId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": entries})
+}
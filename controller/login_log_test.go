@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestLoginHistory(t *testing.T) {
+	testDB, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	require.NoError(t, testDB.AutoMigrate(&model.LoginLog{}))
+
+	hashed, err := common.Password2Hash("password123")
+	require.NoError(t, err)
+	require.NoError(t, testDB.Model(&model.User{}).Where("id = ?", 1).Update("password", hashed).Error)
+
+	router := setupTestRouter()
+	router.POST("/login", Login)
+	router.GET("/login-history", func(c *gin.Context) {
+		c.Set(ctxkey.Id, 1)
+		GetSelfLoginHistory(c)
+	})
+	login := func(password string) {
+		body, err := json.Marshal(LoginRequest{Username: "testuser", Password: password})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+		req.Header.Set("User-Agent", "login-test")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	login("wrong-password")
+	login("password123")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login-history", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Success bool              `json:"success"`
+		Data    []*model.LoginLog `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.True(t, resp.Success)
+	require.Len(t, resp.Data, 2)
+	require.True(t, resp.Data[0].Success)
+	require.False(t, resp.Data[1].Success)
+	require.NotEmpty(t, resp.Data[1].FailureReason)
+	require.Equal(t, "login-test", resp.Data[1].UserAgent)
+}
@@ -26,6 +26,7 @@ func GetStatus(c *gin.Context) {
 			"email_verification":          config.EmailVerificationEnabled,
 			"github_oauth":                config.GitHubOAuthEnabled,
 			"github_client_id":            config.GitHubClientId,
+			"google_oauth":                config.GoogleOAuthEnabled,
 			"lark_client_id":              config.LarkClientId,
 			"system_name":                 config.SystemName,
 			"logo":                        config.Logo,
@@ -82,6 +83,37 @@ func GetHomePageContent(c *gin.Context) {
 	})
 }
 
+// emailDomainNotAllowedMessage is returned when an email domain is on the blocklist.
+const emailDomainNotAllowedMessage = "Email domain not allowed"
+
+// isEmailDomainBlocked reports whether email belongs to a domain on
+// config.EmailDomainBlocklist. Entries like "*.example.com" match any subdomain
+// of example.com; other entries must match the domain exactly.
+func isEmailDomainBlocked(email string) bool {
+	if !config.EmailDomainBlocklistEnabled {
+		return false
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for _, entry := range config.EmailDomainBlocklist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if suffix, ok := strings.CutPrefix(entry, "*."); ok {
+			if strings.HasSuffix(domain, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if entry != "" && domain == entry {
+			return true
+		}
+	}
+
+	return false
+}
+
 // SendEmailVerification issues a verification code to the provided email address.
 func SendEmailVerification(c *gin.Context) {
 	email := c.Query("email")
@@ -108,6 +140,13 @@ func SendEmailVerification(c *gin.Context) {
 			return
 		}
 	}
+	if isEmailDomainBlocked(email) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": emailDomainNotAllowedMessage,
+		})
+		return
+	}
 	if model.IsEmailAlreadyTaken(email) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -164,12 +164,13 @@ type listAllModelsCacheEntry struct {
 	Version string
 }
 
-// cachedListAllModels is a short-term cache for ListAllModels to reduce load.
-var cachedListAllModels = gutils.NewSingleItemExpCache[listAllModelsCacheEntry](time.Minute)
+// cachedListAllModels is a short-term cache for ListAllModels to reduce load,
+// shared across nodes through Redis when it is enabled.
+var cachedListAllModels = newModelListCache()
 
 // ListAllModels returns every known model in the OpenAI-compatible format regardless of user permissions.
 func ListAllModels(c *gin.Context) {
-	models, err := getSupportedModelsSnapshot()
+	models, err := getSupportedModelsSnapshot(gmw.Ctx(c))
 	if err != nil {
 		middleware.AbortWithError(c, http.StatusInternalServerError, errors.Wrap(err, "load supported models"))
 		return
@@ -181,26 +182,16 @@ func ListAllModels(c *gin.Context) {
 	})
 }
 
-func getSupportedModelsSnapshot() ([]OpenAIModels, error) {
+func getSupportedModelsSnapshot(ctx context.Context) ([]OpenAIModels, error) {
 	version, err := model.GetEnabledChannelsVersionSignature()
 	if err != nil {
 		return nil, errors.Wrap(err, "channels version signature")
 	}
 
-	if entry, ok := cachedListAllModels.Get(); ok && entry.Version == version {
-		return entry.Models, nil
-	}
-
-	models, err := listAllSupportedModels()
+	models, err := cachedListAllModels.Get(ctx, version, listAllSupportedModels)
 	if err != nil {
 		return nil, errors.Wrap(err, "list models")
 	}
-
-	cachedListAllModels.Set(listAllModelsCacheEntry{
-		Models:  models,
-		Version: version,
-	})
-
 	return models, nil
 }
 
@@ -595,7 +586,7 @@ func ListModels(c *gin.Context) {
 		return
 	}
 
-	snapshot, err := getSupportedModelsSnapshot()
+	snapshot, err := getSupportedModelsSnapshot(ctx)
 	if err != nil {
 		middleware.AbortWithError(c, http.StatusInternalServerError, errors.Wrap(err, "load supported models snapshot"))
 		return
@@ -10,7 +10,6 @@ import (
 	"testing"
 	"time"
 
-	gutils "github.com/Laisky/go-utils/v6"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -501,7 +500,7 @@ func setupListModelsTestEnv(t *testing.T) {
 	})
 
 	originalCache := cachedListAllModels
-	cachedListAllModels = gutils.NewSingleItemExpCache[listAllModelsCacheEntry](time.Minute)
+	cachedListAllModels = newModelListCache()
 	t.Cleanup(func() { cachedListAllModels = originalCache })
 }
 
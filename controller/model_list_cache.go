@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gutils "github.com/Laisky/go-utils/v6"
+	"github.com/Laisky/zap"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+const (
+	// modelListCacheTTL is how long a model list stays cached, locally and in Redis.
+	modelListCacheTTL = time.Minute
+	// modelListRedisKeyPrefix prefixes the shared model list, keyed by channel version.
+	modelListRedisKeyPrefix = "models:all:"
+	// modelListLockTTL bounds how long a node may hold the rebuild lock.
+	modelListLockTTL = 10 * time.Second
+	// modelListLockWait is how long other nodes wait for the lock holder to
+	// publish the list before building it themselves.
+	modelListLockWait = 2 * time.Second
+	// modelListLockPoll is the interval at which waiting nodes check Redis.
+	modelListLockPoll = 20 * time.Millisecond
+)
+
+// modelListStore is the cluster-wide layer shared by the model list caches of
+// all nodes.
+type modelListStore interface {
+	// Get returns the value of key and whether it exists.
+	Get(ctx context.Context, key string) (string, bool, error)
+	// SetNX sets key only if it does not exist and reports whether it did.
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+}
+
+// redisModelListStore implements modelListStore on the shared Redis client.
+type redisModelListStore struct{}
+
+func (redisModelListStore) Get(ctx context.Context, key string) (string, bool, error) {
+	value, err := common.RDB.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, errors.Wrapf(err, "get redis key: %s", key)
+	}
+	return value, true, nil
+}
+
+func (redisModelListStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	ok, err := common.RDB.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "setnx redis key: %s", key)
+	}
+	return ok, nil
+}
+
+func (redisModelListStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return common.RedisSet(ctx, key, value, ttl)
+}
+
+// modelListCache caches the list of all supported models. A local miss is
+// served from Redis when another node already built the list; otherwise one
+// caller per node builds it, and a Redis lock keeps the other nodes waiting
+// for its result instead of querying the database at the same time.
+type modelListCache struct {
+	local *gutils.SingleItemExpCache[listAllModelsCacheEntry]
+	group singleflight.Group
+	// shared overrides the Redis store, which is used when Redis is enabled
+	shared modelListStore
+}
+
+func newModelListCache() *modelListCache {
+	return &modelListCache{
+		local: gutils.NewSingleItemExpCache[listAllModelsCacheEntry](modelListCacheTTL),
+	}
+}
+
+func (m *modelListCache) store() modelListStore {
+	if m.shared != nil {
+		return m.shared
+	}
+	if common.IsRedisEnabled() && common.RDB != nil {
+		return redisModelListStore{}
+	}
+	return nil
+}
+
+// Get returns the model list for the channel version, calling load only when
+// neither this node nor Redis has it cached.
+func (m *modelListCache) Get(ctx context.Context, version string, load func() ([]OpenAIModels, error)) ([]OpenAIModels, error) {
+	if entry, ok := m.local.Get(); ok && entry.Version == version {
+		return entry.Models, nil
+	}
+
+	v, err, _ := m.group.Do(version, func() (any, error) {
+		if entry, ok := m.local.Get(); ok && entry.Version == version {
+			return entry.Models, nil
+		}
+		models, err := m.loadShared(ctx, version, load)
+		if err != nil {
+			return nil, err
+		}
+		m.local.Set(listAllModelsCacheEntry{Models: models, Version: version})
+		return models, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]OpenAIModels), nil
+}
+
+func (m *modelListCache) loadShared(ctx context.Context, version string, load func() ([]OpenAIModels, error)) ([]OpenAIModels, error) {
+	store := m.store()
+	if store == nil {
+		return load()
+	}
+
+	key := modelListRedisKeyPrefix + version
+	if models, ok := readSharedModelList(ctx, store, key); ok {
+		return models, nil
+	}
+
+	acquired, err := store.SetNX(ctx, key+":lock", "1", modelListLockTTL)
+	if err != nil {
+		logger.Logger.Warn("failed to acquire model list lock", zap.Error(err))
+	} else if !acquired {
+		// Another node is building the list, wait for it to be published
+		deadline := time.Now().Add(modelListLockWait)
+		for time.Now().Before(deadline) {
+			select {
+			case <-ctx.Done():
+				return nil, errors.Wrap(ctx.Err(), "wait for shared model list")
+			case <-time.After(modelListLockPoll):
+			}
+			if models, ok := readSharedModelList(ctx, store, key); ok {
+				return models, nil
+			}
+		}
+	}
+
+	models, err := load()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(models)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal model list")
+	}
+	if err := store.Set(ctx, key, string(payload), modelListCacheTTL); err != nil {
+		logger.Logger.Warn("failed to share model list", zap.Error(err))
+	}
+	return models, nil
+}
+
+func readSharedModelList(ctx context.Context, store modelListStore, key string) ([]OpenAIModels, bool) {
+	payload, ok, err := store.Get(ctx, key)
+	if err != nil {
+		logger.Logger.Warn("failed to read shared model list", zap.Error(err))
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	var models []OpenAIModels
+	if err := json.Unmarshal([]byte(payload), &models); err != nil {
+		logger.Logger.Warn("failed to decode shared model list", zap.Error(err))
+		return nil, false
+	}
+	return models, true
+}
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+// memoryModelListStore stands in for Redis shared by several nodes.
+type memoryModelListStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *memoryModelListStore) Get(_ context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+func (s *memoryModelListStore) SetNX(_ context.Context, key, value string, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+	s.values[key] = value
+	return true, nil
+}
+
+func (s *memoryModelListStore) Set(_ context.Context, key, value string, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func TestModelListCacheSharesListAcrossNodes(t *testing.T) {
+	setupListModelsTestEnv(t)
+	createTestChannelForGroup(t, "custom", "default", "custom-model-a,custom-model-b", channeltype.OpenAI)
+
+	// Count the queries listing enabled channels
+	var listQueries atomic.Int32
+	require.NoError(t, model.DB.Callback().Query().After("gorm:query").Register("test:count_channel_lists", func(db *gorm.DB) {
+		if _, ok := db.Statement.Dest.(*[]*model.Channel); ok {
+			listQueries.Add(1)
+		}
+	}))
+	t.Cleanup(func() { _ = model.DB.Callback().Query().Remove("test:count_channel_lists") })
+
+	version, err := model.GetEnabledChannelsVersionSignature()
+	require.NoError(t, err)
+
+	store := &memoryModelListStore{values: map[string]string{}}
+	const nodes = 10
+	results := make([][]OpenAIModels, nodes)
+	var wg sync.WaitGroup
+	for i := range nodes {
+		node := newModelListCache()
+		node.shared = store
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			models, err := node.Get(context.Background(), version, listAllSupportedModels)
+			require.NoError(t, err)
+			results[i] = models
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), listQueries.Load())
+	for _, models := range results {
+		require.Len(t, models, len(results[0]))
+		require.Contains(t, modelIds(models), "custom-model-a")
+	}
+
+	// A node that joins later reads the shared entry as well
+	node := newModelListCache()
+	node.shared = store
+	_, err = node.Get(context.Background(), version, listAllSupportedModels)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), listQueries.Load())
+}
+
+func TestModelListCacheRebuildsOnNewVersion(t *testing.T) {
+	store := &memoryModelListStore{values: map[string]string{}}
+	node := newModelListCache()
+	node.shared = store
+
+	var loads int
+	load := func() ([]OpenAIModels, error) {
+		loads++
+		return []OpenAIModels{{Id: "model-" + string(rune('a'+loads-1))}}, nil
+	}
+
+	models, err := node.Get(context.Background(), "1:100", load)
+	require.NoError(t, err)
+	require.Equal(t, "model-a", models[0].Id)
+	models, err = node.Get(context.Background(), "1:100", load)
+	require.NoError(t, err)
+	require.Equal(t, "model-a", models[0].Id)
+	require.Equal(t, 1, loads)
+
+	models, err = node.Get(context.Background(), "2:200", load)
+	require.NoError(t, err)
+	require.Equal(t, "model-b", models[0].Id)
+	require.Equal(t, 2, loads)
+}
+
+func modelIds(models []OpenAIModels) []string {
+	ids := make([]string, 0, len(models))
+	for _, m := range models {
+		ids = append(ids, m.Id)
+	}
+	return ids
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// GetModelPricingOverrides lists the admin model pricing overrides.
+func GetModelPricingOverrides(c *gin.Context) {
+	overrides, err := model.GetAllModelPricingOverrides()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    overrides,
+	})
+}
+
+// CreateModelPricingOverride stores a pricing override authored by the
+// current user and applies it on this node right away; other nodes pick it up
+// on their next sync.
+func CreateModelPricingOverride(c *gin.Context) {
+	override := &model.ModelPricingOverride{}
+	if err := c.ShouldBindJSON(override); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": invalidParameterMessage,
+		})
+		return
+	}
+	override.Id = 0
+	override.CreatedBy = c.GetString(ctxkey.Username)
+	if err := override.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := model.CreateModelPricingOverride(override); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetModelPricing, override.Id, model.AuditActionCreate, nil, override)
+	respondModelPricingOverride(c, override)
+}
+
+// UpdateModelPricingOverride replaces the pattern, ratios and max tokens of the
+// override identified by the id path parameter.
+func UpdateModelPricingOverride(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	override := &model.ModelPricingOverride{}
+	if err == nil {
+		err = c.ShouldBindJSON(override)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": invalidParameterMessage,
+		})
+		return
+	}
+	override.Id = id
+	if err := override.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	previous, err := model.GetModelPricingOverrideById(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := model.UpdateModelPricingOverride(override); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	updated, err := model.GetModelPricingOverrideById(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetModelPricing, id, model.AuditActionUpdate, previous, updated)
+	respondModelPricingOverride(c, updated)
+}
+
+// DeleteModelPricingOverride removes the override identified by the id path
+// parameter, restoring the compiled pricing of the models it matched.
+func DeleteModelPricingOverride(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": invalidParameterMessage,
+		})
+		return
+	}
+	previous, _ := model.GetModelPricingOverrideById(id)
+	if err := model.DeleteModelPricingOverrideById(id); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetModelPricing, id, model.AuditActionDelete, previous, nil)
+	respondModelPricingOverride(c, nil)
+}
+
+// respondModelPricingOverride reloads the in-memory overrides after a change
+// and writes data, the changed override or nil after a delete.
+func respondModelPricingOverride(c *gin.Context, data *model.ModelPricingOverride) {
+	if err := model.LoadModelPricingOverrides(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    data,
+	})
+}
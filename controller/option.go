@@ -3,11 +3,14 @@ package controller
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"strings"
 
+	gmw "github.com/Laisky/gin-middlewares/v7"
 	"github.com/gin-gonic/gin"
 
 	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/model"
 )
@@ -17,7 +20,7 @@ func GetOptions(c *gin.Context) {
 	var options []*model.Option
 	config.OptionMapRWMutex.Lock()
 	for k, v := range config.OptionMap {
-		if strings.HasSuffix(k, "Token") || strings.HasSuffix(k, "Secret") {
+		if isSensitiveOption(k) {
 			continue
 		}
 		options = append(options, &model.Option{
@@ -61,6 +64,14 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "GoogleOAuthEnabled":
+		if option.Value == "true" && config.GoogleClientId == "" {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Unable to enable Google OAuth, please fill in the Google Client Id and Google Client Secret first!",
+			})
+			return
+		}
 	case "EmailDomainRestrictionEnabled":
 		if option.Value == "true" && len(config.EmailDomainWhitelist) == 0 {
 			c.JSON(http.StatusOK, gin.H{
@@ -69,6 +80,28 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+		if option.Value == "true" && config.EmailDomainBlocklistEnabled {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Email domain whitelist and blocklist are mutually exclusive, please disable the blocklist first!",
+			})
+			return
+		}
+	case "EmailDomainBlocklistEnabled":
+		if option.Value == "true" && len(config.EmailDomainBlocklist) == 0 {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Unable to enable email domain blocklist, please fill in the blocked email domains first!",
+			})
+			return
+		}
+		if option.Value == "true" && config.EmailDomainRestrictionEnabled {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "Email domain whitelist and blocklist are mutually exclusive, please disable the whitelist first!",
+			})
+			return
+		}
 	case "WeChatAuthEnabled":
 		if option.Value == "true" && config.WeChatServerAddress == "" {
 			c.JSON(http.StatusOK, gin.H{
@@ -85,7 +118,20 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "ExternalBillingWebhookURL":
+		if value := strings.TrimSpace(option.Value); value != "" {
+			if parsed, err := url.Parse(value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				c.JSON(http.StatusOK, gin.H{
+					"success": false,
+					"message": "external billing webhook URL must be an http(s) URL",
+				})
+				return
+			}
+		}
 	}
+	config.OptionMapRWMutex.RLock()
+	previous := config.OptionMap[option.Key]
+	config.OptionMapRWMutex.RUnlock()
 	err = model.UpdateOption(option.Key, option.Value)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -94,6 +140,12 @@ func UpdateOption(c *gin.Context) {
 		})
 		return
 	}
+	next := option.Value
+	if isSensitiveOption(option.Key) {
+		previous, next = "[REDACTED]", "[REDACTED]"
+	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetOption, option.Key, model.AuditActionUpdate,
+		gin.H{"value": previous}, gin.H{"value": next})
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
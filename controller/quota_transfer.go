@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// quotaTransferRequest is the body of the quota transfer endpoints.
+// FromUserId is ignored by TransferSelfQuota, which always transfers from the
+// current user.
+type quotaTransferRequest struct {
+	FromUserId int    `json:"from_user_id"`
+	ToUserId   int    `json:"to_user_id"`
+	Amount     int64  `json:"amount"`
+	Note       string `json:"note"`
+}
+
+// AdminTransferQuota moves quota between two users. Like the other user
+// management endpoints, only the root user may take quota from users at the
+// same level or higher.
+func AdminTransferQuota(c *gin.Context) {
+	var req quotaTransferRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil || req.FromUserId == 0 || req.ToUserId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": invalidParameterMessage})
+		return
+	}
+	fromUser, err := model.GetUserById(req.FromUserId, false)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	myRole := c.GetInt(ctxkey.Role)
+	if myRole <= fromUser.Role && myRole != model.RoleRootUser {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "No permission to transfer quota from users at the same level or higher"})
+		return
+	}
+	if !transferQuota(c, req) {
+		return
+	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetUser, req.FromUserId, "transfer_quota", nil, req)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": ""})
+}
+
+// TransferSelfQuota moves quota from the current user to another user. It is
+// only available when config.AllowUserQuotaTransfer is set.
+func TransferSelfQuota(c *gin.Context) {
+	if !config.AllowUserQuotaTransfer {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "quota transfer is disabled"})
+		return
+	}
+	var req quotaTransferRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil || req.ToUserId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": invalidParameterMessage})
+		return
+	}
+	req.FromUserId = c.GetInt(ctxkey.Id)
+	if !transferQuota(c, req) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": ""})
+}
+
+// transferQuota performs req and writes the failure response if it fails.
+func transferQuota(c *gin.Context, req quotaTransferRequest) bool {
+	if req.Amount < config.MinQuotaTransferAmount {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("amount must be at least %d", config.MinQuotaTransferAmount),
+		})
+		return false
+	}
+	if err := model.TransferUserQuota(gmw.Ctx(c), req.FromUserId, req.ToUserId, req.Amount, req.Note); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return false
+	}
+	return true
+}
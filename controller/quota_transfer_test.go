@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestTransferSelfQuota(t *testing.T) {
+	setupUserControllerTest(t)
+	originalAllow, originalMin := config.AllowUserQuotaTransfer, config.MinQuotaTransferAmount
+	config.MinQuotaTransferAmount = 100
+	t.Cleanup(func() {
+		config.AllowUserQuotaTransfer, config.MinQuotaTransferAmount = originalAllow, originalMin
+	})
+
+	from := &model.User{Username: "sender", Password: "x", AccessToken: "sender-token", AffCode: "sndr", Status: model.UserStatusEnabled, Quota: 1000}
+	to := &model.User{Username: "receiver", Password: "x", AccessToken: "receiver-token", AffCode: "rcvr", Status: model.UserStatusEnabled}
+	require.NoError(t, model.DB.Create(from).Error)
+	require.NoError(t, model.DB.Create(to).Error)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(ctxkey.Id, from.Id) })
+	router.POST("/api/user/quota/transfer", TransferSelfQuota)
+	transfer := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/user/quota/transfer", strings.NewReader(body)))
+		return w
+	}
+	// from_user_id is ignored, users can only send their own quota
+	body := fmt.Sprintf(`{"from_user_id": %d, "to_user_id": %d, "amount": 300}`, to.Id, to.Id)
+
+	config.AllowUserQuotaTransfer = false
+	require.Equal(t, http.StatusForbidden, transfer(body).Code)
+
+	config.AllowUserQuotaTransfer = true
+	require.Equal(t, http.StatusBadRequest, transfer(fmt.Sprintf(`{"to_user_id": %d, "amount": 10}`, to.Id)).Code)
+	w := transfer(body)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"success":true`)
+
+	fromQuota, err := model.GetUserQuota(from.Id)
+	require.NoError(t, err)
+	require.EqualValues(t, 700, fromQuota)
+	toQuota, err := model.GetUserQuota(to.Id)
+	require.NoError(t, err)
+	require.EqualValues(t, 300, toQuota)
+}
+
+func TestAdminTransferQuotaRespectsRoles(t *testing.T) {
+	setupUserControllerTest(t)
+	originalMin := config.MinQuotaTransferAmount
+	config.MinQuotaTransferAmount = 1
+	t.Cleanup(func() { config.MinQuotaTransferAmount = originalMin })
+
+	peer := &model.User{Username: "peer-admin", Password: "x", AccessToken: "peer-token", AffCode: "peer", Role: model.RoleAdminUser, Status: model.UserStatusEnabled, Quota: 1000}
+	member := &model.User{Username: "common", Password: "x", AccessToken: "common-token", AffCode: "cmmn", Role: model.RoleCommonUser, Status: model.UserStatusEnabled, Quota: 1000}
+	require.NoError(t, model.DB.Create(peer).Error)
+	require.NoError(t, model.DB.Create(member).Error)
+
+	transfer := func(role int, fromUserId int, toUserId int) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set(ctxkey.Id, 1)
+			c.Set(ctxkey.Role, role)
+		})
+		router.POST("/api/admin/quota/transfer", AdminTransferQuota)
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"from_user_id": %d, "to_user_id": %d, "amount": 100}`, fromUserId, toUserId)
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/quota/transfer", strings.NewReader(body)))
+		return w
+	}
+
+	// Admins cannot drain other admins, but may move quota of common users
+	require.Equal(t, http.StatusForbidden, transfer(model.RoleAdminUser, peer.Id, member.Id).Code)
+	require.Contains(t, transfer(model.RoleAdminUser, member.Id, peer.Id).Body.String(), `"success":true`)
+	// The root user may move anyone's quota
+	require.Contains(t, transfer(model.RoleRootUser, peer.Id, member.Id).Body.String(), `"success":true`)
+
+	peerQuota, err := model.GetUserQuota(peer.Id)
+	require.NoError(t, err)
+	require.EqualValues(t, 1000, peerQuota)
+}
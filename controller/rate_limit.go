@@ -0,0 +1,16 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/middleware"
+)
+
+// GetRateLimitStatus returns the relay rate limit of the calling API token:
+// the request limit, how many requests remain in the current window and when
+// capacity frees up. The request itself is not counted against the limit.
+func GetRateLimitStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, middleware.GetRelayRateLimitStatus(c))
+}
@@ -6,22 +6,20 @@ import (
 	gmw "github.com/Laisky/gin-middlewares/v7"
 	"github.com/gin-gonic/gin"
 
-	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+	rcontroller "github.com/songquanpeng/one-api/relay/controller"
 	"github.com/songquanpeng/one-api/relay/meta"
 )
 
 // RelayRealtime handles WebSocket Realtime proxying for OpenAI Realtime API.
-// It does not perform request conversion or pre-consume quota; billing is best-effort based on upstream usage if emitted.
+// Quota is not pre-consumed; the session is billed from upstream usage once it closes.
 func RelayRealtime(c *gin.Context) {
-	ctx := gmw.Ctx(c)
-	_ = ctx // reserved for future use (timeouts, logging)
 	start := time.Now()
 	relayMeta := meta.GetByContext(c)
 
 	// Record channel requests in flight
 	PrometheusMonitor.RecordChannelRequest(relayMeta, start)
 
-	if bizErr, _ := openai.RealtimeHandler(c, relayMeta); bizErr != nil {
+	if bizErr := rcontroller.RelayRealtimeHelper(c); bizErr != nil {
 		// On handshake/connection error, return JSON error (no WS established)
 		c.JSON(bizErr.StatusCode, gin.H{"error": bizErr.Error})
 		PrometheusMonitor.RecordRelayRequest(c, relayMeta, start, false, 0, 0, 0)
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"net/http"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// GetReasoningTrace returns the reasoning stored for a request. Users can only
+// read the reasoning of their own requests, while admins can read any.
+func GetReasoningTrace(c *gin.Context) {
+	requestId := c.Param("request_id")
+	if requestId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": invalidParameterMessage,
+		})
+		return
+	}
+
+	trace, err := model.GetReasoningTraceByRequestId(gmw.Ctx(c), requestId)
+	// Report traces of other users as missing so request IDs cannot be probed
+	if err != nil || (trace.UserId != c.GetInt(ctxkey.Id) && c.GetInt(ctxkey.Role) < model.RoleAdminUser) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "reasoning trace not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"request_id": trace.RequestId,
+			"model_name": trace.ModelName,
+			"channel_id": trace.ChannelId,
+			"reasoning":  trace.Reasoning,
+			"created_at": trace.CreatedAt,
+		},
+	})
+}
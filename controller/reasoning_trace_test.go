@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func getReasoningTraceAs(t *testing.T, userId, role int, requestId string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/user/reasoning/"+requestId, nil)
+	c.Params = gin.Params{{Key: "request_id", Value: requestId}}
+	c.Set(ctxkey.Id, userId)
+	c.Set(ctxkey.Role, role)
+	GetReasoningTrace(c)
+	return w
+}
+
+func TestGetReasoningTrace_OwnerAndAdminOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dsn := fmt.Sprintf("file:reasoning_trace_api_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.RequestTrace{}))
+	originalDB := model.DB
+	model.DB = db
+	t.Cleanup(func() { model.DB = originalDB })
+
+	require.NoError(t, model.CreateRequestTrace(t.Context(), &model.RequestTrace{
+		TraceId: "trace-1", RequestId: "req-1", UserId: 5, ChannelId: 2, ModelName: "o3",
+		Method: http.MethodPost, URL: "/v1/chat/completions", ResponseStatus: http.StatusOK,
+		Reasoning: "six times seven",
+	}))
+
+	w := getReasoningTraceAs(t, 5, model.RoleCommonUser, "req-1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"reasoning":"six times seven"`)
+
+	w = getReasoningTraceAs(t, 6, model.RoleCommonUser, "req-1")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.NotContains(t, w.Body.String(), "six times seven")
+
+	w = getReasoningTraceAs(t, 1, model.RoleAdminUser, "req-1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"reasoning":"six times seven"`)
+
+	w = getReasoningTraceAs(t, 5, model.RoleCommonUser, "req-missing")
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	gmw "github.com/Laisky/gin-middlewares/v7"
 	"github.com/gin-gonic/gin"
 
 	"github.com/songquanpeng/one-api/common/config"
@@ -173,6 +174,7 @@ func AddRedemption(c *gin.Context) {
 			return
 		}
 		keys = append(keys, key)
+		model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetRedemption, cleanRedemption.Id, model.AuditActionCreate, nil, &cleanRedemption)
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -184,6 +186,7 @@ func AddRedemption(c *gin.Context) {
 // DeleteRedemption removes a redemption code by ID.
 func DeleteRedemption(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	previous, _ := model.GetRedemptionById(id)
 	err := model.DeleteRedemptionById(id)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -192,6 +195,7 @@ func DeleteRedemption(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetRedemption, id, model.AuditActionDelete, previous, nil)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -218,6 +222,7 @@ func UpdateRedemption(c *gin.Context) {
 		})
 		return
 	}
+	previous := *cleanRedemption
 	if statusOnly != "" {
 		cleanRedemption.Status = redemption.Status
 	} else {
@@ -237,6 +242,7 @@ func UpdateRedemption(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetRedemption, cleanRedemption.Id, model.AuditActionUpdate, &previous, cleanRedemption)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
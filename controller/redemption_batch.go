@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/random"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// RedemptionBatchIdHeader carries the id of a created batch, which the CSV
+// body has no room for.
+const RedemptionBatchIdHeader = "X-Redemption-Batch-Id"
+
+// RedemptionBatchRequest is the payload of CreateRedemptionBatch.
+type RedemptionBatchRequest struct {
+	Count      int    `json:"count"`
+	Quota      int64  `json:"quota"`
+	NamePrefix string `json:"name_prefix"`
+	// ExpiryDays is the number of days the codes stay redeemable, 0 never expires.
+	ExpiryDays int `json:"expiry_days"`
+}
+
+func (r *RedemptionBatchRequest) validate() error {
+	r.NamePrefix = strings.TrimSpace(r.NamePrefix)
+	switch {
+	case r.Count <= 0 || r.Count > model.MaxRedemptionBatchSize:
+		return errors.Errorf("count must be between 1 and %d", model.MaxRedemptionBatchSize)
+	case r.Quota <= 0:
+		return errors.New("quota must be greater than 0")
+	case r.NamePrefix == "" || len(r.NamePrefix) > 20:
+		return errors.New("the length of name_prefix must be between 1-20")
+	case r.ExpiryDays < 0:
+		return errors.New("expiry_days cannot be negative")
+	}
+	return nil
+}
+
+// CreateRedemptionBatch generates up to 10,000 redemption codes in one
+// transaction and returns them as a gzip-compressed CSV with the columns
+// code, quota, expires_at and created_at. Times are RFC 3339 in UTC and
+// expires_at is empty for codes that never expire. The batch id is returned
+// in the X-Redemption-Batch-Id header.
+func CreateRedemptionBatch(c *gin.Context) {
+	var req RedemptionBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	batchId := random.GetUUID()
+	createdTime := helper.GetTimestamp()
+	var expiredTime int64
+	if req.ExpiryDays > 0 {
+		expiredTime = createdTime + int64(req.ExpiryDays)*24*60*60
+	}
+	redemptions := make([]*model.Redemption, req.Count)
+	for i := range redemptions {
+		redemptions[i] = &model.Redemption{
+			UserId:      c.GetInt(ctxkey.Id),
+			Name:        req.NamePrefix,
+			Key:         random.GetUUID(),
+			Status:      model.RedemptionCodeStatusEnabled,
+			Quota:       req.Quota,
+			CreatedTime: createdTime,
+			ExpiredTime: expiredTime,
+			BatchId:     batchId,
+		}
+	}
+	if err := model.CreateRedemptionBatch(gmw.Ctx(c), redemptions); err != nil {
+		gmw.GetLogger(c).Error("failed to create redemption batch", zap.Int("count", req.Count), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "failed to create redemption codes"})
+		return
+	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetRedemption, batchId, "create_batch", nil, req)
+
+	body, err := redemptionBatchCSV(redemptions)
+	if err != nil {
+		gmw.GetLogger(c).Error("failed to encode redemption batch", zap.String("batch_id", batchId), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "failed to encode redemption codes"})
+		return
+	}
+	gmw.GetLogger(c).Info("created redemption batch",
+		zap.String("batch_id", batchId), zap.Int("count", req.Count), zap.Int64("quota", req.Quota))
+	c.Header(RedemptionBatchIdHeader, batchId)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.csv.gz"`, req.NamePrefix, batchId))
+	c.Data(http.StatusOK, "application/gzip", body)
+}
+
+// redemptionBatchCSV renders redemptions as a gzip-compressed CSV.
+func redemptionBatchCSV(redemptions []*model.Redemption) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := csv.NewWriter(gz)
+	if err := w.Write([]string{"code", "quota", "expires_at", "created_at"}); err != nil {
+		return nil, errors.Wrap(err, "write csv header")
+	}
+	for _, r := range redemptions {
+		expiresAt := ""
+		if r.ExpiredTime != 0 {
+			expiresAt = time.Unix(r.ExpiredTime, 0).UTC().Format(time.RFC3339)
+		}
+		record := []string{
+			r.Key,
+			strconv.FormatInt(r.Quota, 10),
+			expiresAt,
+			time.Unix(r.CreatedTime, 0).UTC().Format(time.RFC3339),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, errors.Wrap(err, "write csv record")
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, errors.Wrap(err, "flush csv")
+	}
+	if err := gz.Close(); err != nil {
+		return nil, errors.Wrap(err, "close gzip writer")
+	}
+	return buf.Bytes(), nil
+}
+
+// GetRedemptionBatchStats reports how many codes of a batch were redeemed.
+func GetRedemptionBatchStats(c *gin.Context) {
+	stats, err := model.GetRedemptionBatchStats(gmw.Ctx(c), c.Param("batch_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "redemption batch not found"})
+			return
+		}
+		gmw.GetLogger(c).Error("failed to get redemption batch stats", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "failed to get redemption batch stats"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": stats})
+}
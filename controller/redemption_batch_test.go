@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/model"
+)
+
+func newRedemptionBatchRouter() *gin.Engine {
+	router := gin.New()
+	router.POST("/api/admin/redemption/batch", CreateRedemptionBatch)
+	router.GET("/api/admin/redemption/batch/:batch_id/stats", GetRedemptionBatchStats)
+	return router
+}
+
+func TestCreateRedemptionBatch(t *testing.T) {
+	setupUserControllerTest(t)
+	router := newRedemptionBatchRouter()
+
+	body := `{"count": 25, "quota": 5000, "name_prefix": "test-promo", "expiry_days": 30}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/redemption/batch", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	batchId := w.Header().Get(RedemptionBatchIdHeader)
+	require.NotEmpty(t, batchId)
+	require.Contains(t, w.Header().Get("Content-Disposition"), ".csv.gz")
+
+	gz, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	records, err := csv.NewReader(gz).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 26)
+	require.Equal(t, []string{"code", "quota", "expires_at", "created_at"}, records[0])
+
+	codes := make(map[string]bool)
+	for _, record := range records[1:] {
+		require.Len(t, record[0], 32)
+		codes[record[0]] = true
+		require.Equal(t, "5000", record[1])
+		expiresAt, err := time.Parse(time.RFC3339, record[2])
+		require.NoError(t, err)
+		createdAt, err := time.Parse(time.RFC3339, record[3])
+		require.NoError(t, err)
+		require.Equal(t, 30*24*time.Hour, expiresAt.Sub(createdAt))
+	}
+	require.Len(t, codes, 25)
+
+	redemption := &model.Redemption{}
+	require.NoError(t, model.DB.Where("batch_id = ?", batchId).First(redemption).Error)
+	require.NoError(t, model.DB.Model(redemption).Update("status", model.RedemptionCodeStatusUsed).Error)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/redemption/batch/"+batchId+"/stats", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data model.RedemptionBatchStats `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, model.RedemptionBatchStats{BatchId: batchId, Total: 25, Redeemed: 1}, resp.Data)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/redemption/batch/unknown/stats", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateRedemptionBatch_Validation(t *testing.T) {
+	setupUserControllerTest(t)
+	router := newRedemptionBatchRouter()
+
+	for _, body := range []string{
+		`{"count": 0, "quota": 1, "name_prefix": "test"}`,
+		`{"count": ` + strconv.Itoa(model.MaxRedemptionBatchSize+1) + `, "quota": 1, "name_prefix": "test"}`,
+		`{"count": 1, "quota": 0, "name_prefix": "test"}`,
+		`{"count": 1, "quota": 1, "name_prefix": ""}`,
+		`{"count": 1, "quota": 1, "name_prefix": "test", "expiry_days": -1}`,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/redemption/batch", bytes.NewBufferString(body)))
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
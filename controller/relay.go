@@ -31,16 +31,23 @@ import (
 // https://platform.openai.com/docs/api-reference/chat
 
 func relayHelper(c *gin.Context, relayMode int) *model.ErrorWithStatusCode {
+	releaseSlot, capacityErr := acquireChannelSlot(c)
+	if capacityErr != nil {
+		return capacityErr
+	}
+	defer releaseSlot()
+	release := dbmodel.TrackChannelQueueDepth(gmw.Ctx(c), c.GetInt(ctxkey.ChannelId))
+	defer release()
+
 	var err *model.ErrorWithStatusCode
 	switch relayMode {
 	case relaymode.Realtime:
-		// For Phase 1, route through text helper which will delegate to adaptor based on meta.Mode
-		// Realtime adaptor code will handle websocket upgrade and upstream pass-through.
-		err = rcontroller.RelayTextHelper(c)
+		err = rcontroller.RelayRealtimeHelper(c)
 	case relaymode.ImagesGenerations,
 		relaymode.ImagesEdits:
 		err = rcontroller.RelayImageHelper(c, relayMode)
-	case relaymode.AudioSpeech:
+	case relaymode.AudioSpeech,
+		relaymode.TextToSpeech:
 		fallthrough
 	case relaymode.AudioTranslation:
 		fallthrough
@@ -56,6 +63,10 @@ func relayHelper(c *gin.Context, relayMode int) *model.ErrorWithStatusCode {
 		err = rcontroller.RelayRerankHelper(c)
 	case relaymode.Videos:
 		err = rcontroller.RelayVideoHelper(c)
+	case relaymode.Batches:
+		err = rcontroller.RelayBatchHelper(c)
+	case relaymode.Files:
+		err = rcontroller.RelayBatchFileHelper(c)
 	default:
 		err = rcontroller.RelayTextHelper(c)
 	}
@@ -68,6 +79,12 @@ func Relay(c *gin.Context) {
 	relayMode := relaymode.GetByPath(c.Request.URL.Path)
 	channelId := c.GetInt(ctxkey.ChannelId)
 	userId := c.GetInt(ctxkey.Id)
+	annotator := rcontroller.EnsureResponseAnnotationWriter(c)
+	defer func() {
+		if err := annotator.Finish(); err != nil {
+			lg.Warn("failed to finish annotated response", zap.Error(err))
+		}
+	}()
 	shouldDebugLog := relayMode == relaymode.ChatCompletions || relayMode == relaymode.ResponseAPI || relayMode == relaymode.ClaudeMessages
 	if shouldDebugLog {
 		rcontroller.EnsureDebugResponseWriter(c)
@@ -97,6 +114,7 @@ func Relay(c *gin.Context) {
 	bizErr := relayHelper(c, relayMode)
 	if bizErr == nil {
 		monitor.Emit(channelId, true)
+		dbmodel.RecordChannelCircuitSuccess(ctx, channelId, c.GetString(ctxkey.RequestModel))
 
 		// Record successful relay request metrics
 		PrometheusMonitor.RecordRelayRequest(c, relayMeta, startTime, true, 0, 0, 0)
@@ -194,6 +212,7 @@ func Relay(c *gin.Context) {
 	// For 5xx/server transient errors, avoid reusing the same ability first, probe within tier
 	isServerTransient := bizErr.StatusCode >= 500 && bizErr.StatusCode <= 599
 
+	negotiator := middleware.NewCapabilityNegotiator(c)
 	for i := retryTimes; i > 0; i-- {
 		var channel *dbmodel.Channel
 		var err error
@@ -244,6 +263,19 @@ func Relay(c *gin.Context) {
 			break
 		}
 
+		if !negotiator.Accepts(channel, originalModel) {
+			// Skipping an incapable channel does not use up a retry attempt.
+			failedChannels[channel.Id] = true
+			i++
+			continue
+		}
+		if !dbmodel.AllowChannelCircuit(ctx, channel.Id, originalModel) {
+			// Neither does skipping a channel whose circuit breaker is open.
+			failedChannels[channel.Id] = true
+			i++
+			continue
+		}
+
 		lg.Info("using channel to retry",
 			zap.Int("channel_id", channel.Id),
 			zap.Int("remaining_attempts", i),
@@ -258,6 +290,7 @@ func Relay(c *gin.Context) {
 
 		bizErr = relayHelper(c, relayMode)
 		if bizErr == nil {
+			dbmodel.RecordChannelCircuitSuccess(ctx, channel.Id, originalModel)
 			// Record successful retry
 			PrometheusMonitor.RecordRelayRequest(c, retryMeta, retryStartTime, true, 0, 0, 0)
 			return
@@ -287,7 +320,8 @@ func Relay(c *gin.Context) {
 	}
 
 	if bizErr != nil {
-		if bizErr.StatusCode == http.StatusTooManyRequests {
+		// A channel's error response template takes precedence over the generic 429 messages
+		if !applyErrorResponseTemplate(c, bizErr) && bizErr.StatusCode == http.StatusTooManyRequests {
 			// Provide more specific messaging for 429 errors after exhausting retries
 			if len(failedChannels) > 1 {
 				bizErr.Error.Message = fmt.Sprintf("All available channels (%d) for this model are currently rate limited, please try again later", len(failedChannels)) // Message for client, not logger
@@ -296,6 +330,8 @@ func Relay(c *gin.Context) {
 			}
 		}
 
+		respondChannelAtCapacity(c, bizErr)
+
 		// BUG: bizErr is in race condition
 		bizErr.Error.Message = helper.MessageWithRequestId(bizErr.Error.Message, requestId)
 		c.JSON(bizErr.StatusCode, gin.H{
@@ -471,6 +507,25 @@ func processChannelRelayError(ctx context.Context, userId int, channelId int, ch
 	// Always use a local logger variable
 	lg := gmw.GetLogger(ctx)
 
+	// A channel at its concurrency limit is healthy and its slots free up as
+	// soon as in-flight requests finish, so it is only skipped briefly
+	if err.Type == model.ErrorTypeChannelAtCapacity {
+		lg.Debug("suspending model on channel at capacity",
+			zap.String("model", originalModel),
+			zap.String("group", group),
+			zap.Int("channel_id", channelId))
+		if suspendErr := dbmodel.SuspendAbility(ctx,
+			group, originalModel, channelId,
+			config.ChannelSuspendSecondsFor429/4); suspendErr != nil {
+			lg.Warn("failed to suspend ability for channel at capacity",
+				zap.Int("channel_id", channelId),
+				zap.String("model", originalModel),
+				zap.String("group", group),
+				zap.Error(suspendErr))
+		}
+		return
+	}
+
 	// Downgrade to WARN for client-side cancellations/timeouts to avoid noisy alerts
 	if isClientContextCancel(err.StatusCode, err.RawError) {
 		lg.Warn("relay aborted by client (context canceled/deadline)",
@@ -571,6 +626,7 @@ func processChannelRelayError(ctx context.Context, userId int, channelId int, ch
 		if suspendErr := dbmodel.SuspendAbility(ctx, group, originalModel, channelId, config.ChannelSuspendSecondsFor5XX); suspendErr != nil {
 			lg.Error("failed to suspend ability for 5xx", zap.Error(errors.Wrap(suspendErr, "suspend ability failed")))
 		}
+		dbmodel.RecordChannelCircuitFailure(ctx, channelId, originalModel)
 		// Do not immediately auto-disable; transient
 		monitor.Emit(channelId, false)
 		return
@@ -587,6 +643,7 @@ func processChannelRelayError(ctx context.Context, userId int, channelId int, ch
 		if suspendErr := dbmodel.SuspendAbility(ctx, group, originalModel, channelId, config.ChannelSuspendSecondsForAuth); suspendErr != nil {
 			lg.Error("failed to suspend ability for auth/permission", zap.Error(errors.Wrap(suspendErr, "suspend ability failed")))
 		}
+		dbmodel.RecordChannelCircuitFailure(ctx, channelId, originalModel)
 
 		if monitor.ShouldDisableChannel(&err.Error, err.StatusCode) {
 			monitor.DisableChannel(channelId, channelName, err.Message)
@@ -597,6 +654,7 @@ func processChannelRelayError(ctx context.Context, userId int, channelId int, ch
 	}
 
 	// Default: not fatal -> record failure only. If fatal per policy, auto-disable.
+	dbmodel.RecordChannelCircuitFailure(ctx, channelId, originalModel)
 	if monitor.ShouldDisableChannel(&err.Error, err.StatusCode) {
 		monitor.DisableChannel(channelId, channelName, err.Message)
 	} else {
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"context"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/graceful"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/tracing"
+	dbmodel "github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+// applyErrorResponseTemplate replaces the message of the error returned to the
+// client with the last tried channel's template for its status code, reporting
+// whether it did. The upstream error is kept in a log entry's metadata for
+// debugging.
+func applyErrorResponseTemplate(c *gin.Context, bizErr *model.ErrorWithStatusCode) bool {
+	v, ok := c.Get(ctxkey.ChannelModel)
+	if !ok {
+		return false
+	}
+	channel, ok := v.(*dbmodel.Channel)
+	if !ok || channel == nil {
+		return false
+	}
+	message, ok := channel.ErrorResponseMessage(bizErr.StatusCode)
+	if !ok {
+		return false
+	}
+
+	upstreamError := map[string]any{
+		"status_code": bizErr.StatusCode,
+		"message":     bizErr.Message,
+		"type":        bizErr.Type,
+		"code":        bizErr.Code,
+	}
+	bizErr.Message = message
+
+	// gin recycles the context once the handler returns
+	entry := &dbmodel.Log{
+		UserId:    c.GetInt(ctxkey.Id),
+		TokenName: c.GetString(ctxkey.TokenName),
+		ModelName: c.GetString(ctxkey.RequestModel),
+		ChannelId: channel.Id,
+		Content:   "Upstream error replaced by the channel's error response template",
+		RequestId: c.GetString(helper.RequestIdKey),
+		TraceId:   tracing.GetTraceID(c),
+	}
+	graceful.GoCritical(gmw.Ctx(c), "recordUpstreamErrorLog", func(ctx context.Context) {
+		dbmodel.RecordUpstreamErrorLog(ctx, entry, upstreamError)
+	})
+	return true
+}
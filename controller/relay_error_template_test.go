@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/middleware"
+	dbmodel "github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+func TestRelay_ErrorResponseTemplateOverridesUpstreamMessage(t *testing.T) {
+	setupUserControllerTest(t)
+	if client.HTTPClient == nil {
+		client.Init()
+	}
+	// Avoids loading the tiktoken encoders
+	originalApproximate := config.ApproximateTokenEnabled
+	config.ApproximateTokenEnabled = true
+	t.Cleanup(func() { config.ApproximateTokenEnabled = originalApproximate })
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"message":"Rate limit reached for gpt-4o-mini in organization org-secret","type":"requests","code":"rate_limit_exceeded"}}`))
+	}))
+	defer upstream.Close()
+
+	user := &dbmodel.User{Username: "template-user", Password: "password", Quota: 1_000_000_000, Status: dbmodel.UserStatusEnabled, AccessToken: "template-access-token", AffCode: "tpl1"}
+	require.NoError(t, dbmodel.DB.Create(user).Error)
+	token := &dbmodel.Token{UserId: user.Id, Key: "template-token-key", Name: "template-token", Status: dbmodel.TokenStatusEnabled, UnlimitedQuota: true}
+	require.NoError(t, dbmodel.DB.Create(token).Error)
+	baseURL := upstream.URL
+	template := `{"429": "Rate limit exceeded. Contact admin.", "503": "Service maintenance in progress."}`
+	channel := &dbmodel.Channel{Id: 71, Type: channeltype.OpenAI, Name: "templated", Key: "sk-test", Status: dbmodel.ChannelStatusEnabled,
+		BaseURL: &baseURL, Models: "gpt-4o-mini", ErrorResponseTemplate: &template}
+	require.NoError(t, dbmodel.DB.Create(channel).Error)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	body := []byte(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}]}`)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(ctxkey.Id, user.Id)
+	c.Set(ctxkey.TokenId, token.Id)
+	c.Set(ctxkey.TokenName, token.Name)
+	c.Set(ctxkey.TokenQuotaUnlimited, true)
+	c.Set(ctxkey.Group, "default")
+	c.Set(ctxkey.RequestModel, "gpt-4o-mini")
+	// Pinning the channel disables retries
+	c.Set(ctxkey.SpecificChannelId, channel.Id)
+	middleware.SetupContextForSelectedChannel(c, channel, "gpt-4o-mini")
+
+	Relay(c)
+
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Contains(t, response.Error.Message, "Rate limit exceeded. Contact admin.")
+	require.NotContains(t, response.Error.Message, "org-secret")
+
+	// The upstream error is preserved for debugging
+	var entry dbmodel.Log
+	require.Eventually(t, func() bool {
+		return dbmodel.LOG_DB.Where("user_id = ? AND type = ?", user.Id, dbmodel.LogTypeSystem).First(&entry).Error == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Equal(t, channel.Id, entry.ChannelId)
+	upstreamError, ok := entry.Metadata[dbmodel.LogMetadataKeyUpstreamError].(map[string]any)
+	require.True(t, ok)
+	require.EqualValues(t, http.StatusTooManyRequests, upstreamError["status_code"])
+	require.Contains(t, upstreamError["message"], "org-secret")
+}
@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	gutils "github.com/Laisky/go-utils/v6"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/middleware"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// ReplayOverrides lists the fields an administrator may change when replaying a request.
+type ReplayOverrides struct {
+	// ChannelId routes the replay to a specific channel instead of the original one.
+	ChannelId int `json:"channel_id"`
+	// Body holds top-level request body fields that replace the captured values.
+	Body map[string]any `json:"body"`
+}
+
+// ReplayFieldDiff describes a top-level response field that differs between runs.
+type ReplayFieldDiff struct {
+	Field    string `json:"field"`
+	Original any    `json:"original"`
+	Replay   any    `json:"replay"`
+}
+
+// ReplayDiff summarizes how the replayed response differs from the captured one.
+type ReplayDiff struct {
+	StatusChanged bool              `json:"status_changed"`
+	BodyChanged   bool              `json:"body_changed"`
+	Fields        []ReplayFieldDiff `json:"fields,omitempty"`
+}
+
+// ReplayRequest re-runs a captured failed relay request through the relay pipeline.
+//
+// The request is loaded from the request_traces table by trace ID, optionally
+// patched with ReplayOverrides, and dispatched to the original (or overridden)
+// channel without rate limiting. The replay is billed to the acting administrator
+// under a fresh request ID, so it is logged separately from the original request,
+// which was already billed to its caller. The response includes both results and a
+// diff between them.
+func ReplayRequest(c *gin.Context) {
+	ctx := gmw.Ctx(c)
+	lg := gmw.GetLogger(c)
+	traceId := c.Param("trace_id")
+
+	captured, err := model.GetRequestTraceByTraceId(ctx, traceId)
+	// Reasoning traces share the table but hold no request to replay
+	if err == nil && captured.Reasoning != "" && captured.RequestBody == "" {
+		err = errors.Errorf("trace %s is a reasoning trace", traceId)
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "captured request not found"})
+		return
+	}
+
+	var overrides ReplayOverrides
+	if c.Request.ContentLength != 0 {
+		if err = json.NewDecoder(c.Request.Body).Decode(&overrides); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": invalidParameterMessage})
+			return
+		}
+	}
+
+	channelId := captured.ChannelId
+	if overrides.ChannelId != 0 {
+		channelId = overrides.ChannelId
+	}
+	channel, err := model.GetChannelById(channelId, true)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": fmt.Sprintf("channel #%d not found", channelId)})
+		return
+	}
+
+	requestBody, modelName, err := applyReplayBodyOverrides(captured, overrides.Body)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	adminId := c.GetInt(ctxkey.Id)
+	token, err := model.GetFirstEnabledUserToken(adminId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": "an enabled API token is required to bill the replay"})
+		return
+	}
+	headers, err := captured.GetHeaders()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	replayRequestId := gutils.UUID7()
+	recorder := httptest.NewRecorder()
+	rc, _ := gin.CreateTestContext(recorder)
+	rc.Request = httptest.NewRequestWithContext(ctx, captured.Method, captured.URL, bytes.NewReader(requestBody))
+	maps.Copy(rc.Request.Header, headers)
+	rc.Set(ctxkey.Id, adminId)
+	rc.Set(ctxkey.TokenId, token.Id)
+	rc.Set(ctxkey.TokenName, token.Name)
+	rc.Set(ctxkey.TokenQuota, token.RemainQuota)
+	rc.Set(ctxkey.TokenQuotaUnlimited, token.UnlimitedQuota)
+	rc.Set(ctxkey.RequestModel, modelName)
+	rc.Set(ctxkey.SpecificChannelId, channel.Id)
+	rc.Set(ctxkey.RequestId, replayRequestId)
+	rc.Set(helper.RequestIdKey, replayRequestId)
+	group, _ := model.CacheGetUserGroup(ctx, adminId)
+	rc.Set(ctxkey.Group, group)
+	replayLogger := lg.With(
+		zap.String("replay_of", traceId),
+		zap.String("replay_request_id", replayRequestId))
+	gmw.SetLogger(rc, replayLogger)
+	middleware.SetupContextForSelectedChannel(rc, channel, modelName)
+
+	replayLogger.Info("replaying captured request",
+		zap.Int("original_channel_id", captured.ChannelId),
+		zap.Int("replay_channel_id", channel.Id),
+		zap.Int("admin_id", adminId))
+	Relay(rc)
+
+	replayBody := recorder.Body.String()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"trace_id":          traceId,
+			"replay_request_id": replayRequestId,
+			"channel_id":        channel.Id,
+			"original": gin.H{
+				"request_id": captured.RequestId,
+				"channel_id": captured.ChannelId,
+				"status":     captured.ResponseStatus,
+				"body":       captured.ResponseBody,
+			},
+			"replay": gin.H{
+				"status": recorder.Code,
+				"body":   replayBody,
+			},
+			"diff": diffReplayResponses(captured.ResponseStatus, captured.ResponseBody, recorder.Code, replayBody),
+		},
+	})
+}
+
+// applyReplayBodyOverrides merges top-level body overrides into the captured JSON
+// request body and returns the new body along with the effective model name.
+func applyReplayBodyOverrides(captured *model.RequestTrace, overrides map[string]any) ([]byte, string, error) {
+	if len(overrides) == 0 {
+		return []byte(captured.RequestBody), captured.ModelName, nil
+	}
+
+	payload := make(map[string]any)
+	if err := json.Unmarshal([]byte(captured.RequestBody), &payload); err != nil {
+		return nil, "", errors.Wrap(err, "body overrides require a JSON request body")
+	}
+	maps.Copy(payload, overrides)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "marshal overridden request body")
+	}
+
+	modelName := captured.ModelName
+	if m, ok := payload["model"].(string); ok && m != "" {
+		modelName = m
+	}
+	return body, modelName, nil
+}
+
+// diffReplayResponses compares the captured and replayed responses. When both bodies
+// are JSON objects, differing top-level fields are listed individually.
+func diffReplayResponses(originalStatus int, originalBody string, replayStatus int, replayBody string) ReplayDiff {
+	diff := ReplayDiff{
+		StatusChanged: originalStatus != replayStatus,
+		BodyChanged:   originalBody != replayBody,
+	}
+	if !diff.BodyChanged {
+		return diff
+	}
+
+	var original, replay map[string]any
+	if json.Unmarshal([]byte(originalBody), &original) != nil || json.Unmarshal([]byte(replayBody), &replay) != nil {
+		return diff
+	}
+
+	fields := slices.Sorted(maps.Keys(original))
+	for field := range replay {
+		if _, ok := original[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		if !reflect.DeepEqual(original[field], replay[field]) {
+			diff.Fields = append(diff.Fields, ReplayFieldDiff{
+				Field:    field,
+				Original: original[field],
+				Replay:   replay[field],
+			})
+		}
+	}
+	return diff
+}
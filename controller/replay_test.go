@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+// setupReplayTest prepares an in-memory database holding an admin with a token,
+// the original caller, and two OpenAI channels backed by httptest upstreams.
+func setupReplayTest(t *testing.T, upstreams ...*httptest.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dsn := fmt.Sprintf("file:replay_test_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Token{}, &model.Channel{}, &model.Ability{},
+		&model.Log{}, &model.RequestTrace{}, &model.UserRequestCost{}, &model.Trace{}))
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	originalDB, originalLOG := model.DB, model.LOG_DB
+	model.DB, model.LOG_DB = db, db
+	originalUsingSQLite := common.UsingSQLite.Load()
+	common.UsingSQLite.Store(true)
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	// Avoid downloading tiktoken encodings in offline test environments.
+	originalApproximate := config.ApproximateTokenEnabled
+	config.ApproximateTokenEnabled = true
+	t.Cleanup(func() {
+		model.DB, model.LOG_DB = originalDB, originalLOG
+		common.UsingSQLite.Store(originalUsingSQLite)
+		common.SetRedisEnabled(originalRedis)
+		config.ApproximateTokenEnabled = originalApproximate
+	})
+
+	require.NoError(t, db.Create(&model.User{Id: 1, Username: "replay-admin", Password: "password",
+		Role: model.RoleAdminUser, Status: model.UserStatusEnabled, Quota: 1_000_000_000, Group: "default", AccessToken: "replay-admin-access", AffCode: "ra01"}).Error)
+	require.NoError(t, db.Create(&model.User{Id: 2, Username: "replay-caller", Password: "password",
+		Role: model.RoleCommonUser, Status: model.UserStatusEnabled, Quota: 1_000_000_000, Group: "default", AccessToken: "replay-caller-access", AffCode: "rc01"}).Error)
+	require.NoError(t, db.Create(&model.Token{Id: 1, UserId: 1, Key: strings.Repeat("r", 48),
+		Status: model.TokenStatusEnabled, Name: "admin-token", UnlimitedQuota: true,
+		CreatedTime: helper.GetTimestamp(), AccessedTime: helper.GetTimestamp()}).Error)
+
+	for i, upstream := range upstreams {
+		baseURL := upstream.URL
+		require.NoError(t, db.Create(&model.Channel{Id: i + 1, Type: channeltype.OpenAI, Key: "sk-upstream",
+			Status: model.ChannelStatusEnabled, Name: fmt.Sprintf("replay-channel-%d", i+1),
+			BaseURL: &baseURL, Models: "gpt-4o-mini", Group: "default"}).Error)
+	}
+}
+
+// newOpenAIUpstream returns an upstream that answers chat completions and counts hits.
+func newOpenAIUpstream(t *testing.T, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o-mini",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"4"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestReplayRequest_UsesOverriddenChannelAndBillsAdminSeparately(t *testing.T) {
+	var originalHits, overrideHits atomic.Int32
+	setupReplayTest(t, newOpenAIUpstream(t, &originalHits), newOpenAIUpstream(t, &overrideHits))
+
+	// The original request was already billed to the caller.
+	require.NoError(t, model.LOG_DB.Create(&model.Log{UserId: 2, Type: model.LogTypeConsume, ChannelId: 1,
+		ModelName: "gpt-4o-mini", Quota: 10, RequestId: "orig-request", CreatedAt: helper.GetTimestamp()}).Error)
+	require.NoError(t, model.CreateRequestTrace(t.Context(), &model.RequestTrace{
+		TraceId: "trace-replay-1", RequestId: "orig-request", UserId: 2, TokenId: 9, ChannelId: 1,
+		ModelName: "gpt-4o-mini", Method: http.MethodPost, URL: "/v1/chat/completions",
+		Headers:        `{"Content-Type":["application/json"]}`,
+		RequestBody:    `{"model":"gpt-4o-mini","messages":[{"role":"user","content":"2+2?"}]}`,
+		ResponseStatus: http.StatusBadGateway,
+		ResponseBody:   `{"error":{"message":"upstream failed","type":"upstream_error"}}`,
+	}))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/admin/replay/trace-replay-1",
+		bytes.NewBufferString(`{"channel_id":2}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "trace_id", Value: "trace-replay-1"}}
+	c.Set(ctxkey.Id, 1)
+	gmw.SetLogger(c, logger.Logger)
+
+	ReplayRequest(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			ReplayRequestId string `json:"replay_request_id"`
+			ChannelId       int    `json:"channel_id"`
+			Replay          struct {
+				Status int `json:"status"`
+			} `json:"replay"`
+			Diff ReplayDiff `json:"diff"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	require.True(t, resp.Success, w.Body.String())
+	require.Equal(t, 2, resp.Data.ChannelId)
+	require.Equal(t, http.StatusOK, resp.Data.Replay.Status)
+	require.True(t, resp.Data.Diff.StatusChanged)
+	require.Zero(t, originalHits.Load())
+	require.EqualValues(t, 1, overrideHits.Load())
+
+	require.Eventually(t, func() bool {
+		var logs []model.Log
+		require.NoError(t, model.LOG_DB.Where("type = ?", model.LogTypeConsume).Order("id").Find(&logs).Error)
+		if len(logs) != 2 {
+			return false
+		}
+		require.Equal(t, 2, logs[0].UserId)
+		require.Equal(t, "orig-request", logs[0].RequestId)
+		require.Equal(t, 1, logs[1].UserId)
+		require.Equal(t, 2, logs[1].ChannelId)
+		require.Equal(t, resp.Data.ReplayRequestId, logs[1].RequestId)
+		return true
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestReplayRequest_UnknownTrace(t *testing.T) {
+	setupReplayTest(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/admin/replay/missing", nil)
+	c.Params = gin.Params{{Key: "trace_id", Value: "missing"}}
+	c.Set(ctxkey.Id, 1)
+	gmw.SetLogger(c, logger.Logger)
+
+	ReplayRequest(c)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDiffReplayResponses(t *testing.T) {
+	diff := diffReplayResponses(http.StatusBadGateway, `{"error":"boom"}`, http.StatusOK, `{"id":"1"}`)
+	require.True(t, diff.StatusChanged)
+	require.True(t, diff.BodyChanged)
+	require.Len(t, diff.Fields, 2)
+	require.Equal(t, "error", diff.Fields[0].Field)
+	require.Equal(t, "id", diff.Fields[1].Field)
+
+	diff = diffReplayResponses(http.StatusOK, "same", http.StatusOK, "same")
+	require.False(t, diff.StatusChanged)
+	require.False(t, diff.BodyChanged)
+	require.Empty(t, diff.Fields)
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/monitor/prometheus"
+)
+
+// maxRequestSizeDays bounds the window accepted by GetRequestSizes.
+const maxRequestSizeDays = 90
+
+// requestSizePercentiles lists the percentiles GetRequestSizes can report,
+// each enabled by a query flag of the same name such as ?p95=true.
+var requestSizePercentiles = []struct {
+	flag    string
+	percent int
+}{
+	{"p50", 50},
+	{"p90", 90},
+	{"p95", 95},
+	{"p99", 99},
+}
+
+// RequestSizesResponse is the payload of GetRequestSizes.
+type RequestSizesResponse struct {
+	Model             string               `json:"model"`
+	Days              int                  `json:"days"`
+	RequestBodyBytes  *model.BodySizeStats `json:"request_body_bytes"`
+	ResponseBodyBytes *model.BodySizeStats `json:"response_body_bytes"`
+}
+
+// GetRequestSizes reports percentiles of the request and (sampled) response
+// body sizes recorded in consume log metadata over the last `days` days
+// (default 7), optionally filtered by `model`. Percentiles are selected with
+// p50, p90, p95 and p99 flags and default to p50 and p95.
+func GetRequestSizes(c *gin.Context) {
+	days := 7
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxRequestSizeDays {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "days must be an integer between 1 and " + strconv.Itoa(maxRequestSizeDays),
+			})
+			return
+		}
+		days = parsed
+	}
+
+	var percentiles []int
+	for _, p := range requestSizePercentiles {
+		if enabled, _ := strconv.ParseBool(c.Query(p.flag)); enabled {
+			percentiles = append(percentiles, p.percent)
+		}
+	}
+	if len(percentiles) == 0 {
+		percentiles = []int{50, 95}
+	}
+
+	ctx := gmw.Ctx(c)
+	modelName := c.Query("model")
+	resp := RequestSizesResponse{Model: modelName, Days: days}
+	var err error
+	resp.RequestBodyBytes, err = model.GetBodySizeStats(ctx, model.LogMetadataKeyRequestBodyBytes, modelName, days, percentiles)
+	if err == nil {
+		resp.ResponseBodyBytes, err = model.GetBodySizeStats(ctx, model.LogMetadataKeyResponseBodyBytes, modelName, days, percentiles)
+	}
+	if err != nil {
+		gmw.GetLogger(c).Error("failed to compute request size percentiles", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    resp,
+	})
+}
+
+// ResponseSizeQuantile is one model of the GetResponseSizes payload.
+type ResponseSizeQuantile struct {
+	Model    string  `json:"model"`
+	P99Bytes float64 `json:"p99_bytes"`
+}
+
+// GetResponseSizes reports the current 99th percentile response size of each
+// model, estimated from the one_api_upstream_response_bytes histogram of this
+// node since it started. Models are sorted by size, largest first.
+func GetResponseSizes(c *gin.Context) {
+	if !config.EnablePrometheusMetrics {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "prometheus metrics are disabled",
+		})
+		return
+	}
+
+	quantiles, err := prometheus.ResponseSizeQuantiles(0.99)
+	if err != nil {
+		gmw.GetLogger(c).Error("failed to compute response size percentiles", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	sizes := make([]ResponseSizeQuantile, 0, len(quantiles))
+	for modelName, p99 := range quantiles {
+		sizes = append(sizes, ResponseSizeQuantile{Model: modelName, P99Bytes: p99})
+	}
+	slices.SortFunc(sizes, func(a, b ResponseSizeQuantile) int {
+		if c := cmp.Compare(b.P99Bytes, a.P99Bytes); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Model, b.Model)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    sizes,
+	})
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/random"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/monitor/prometheus"
+)
+
+func getRequestSizes(t *testing.T, query string) (int, RequestSizesResponse) {
+	t.Helper()
+	router := gin.New()
+	router.GET("/api/admin/analytics/request-sizes", GetRequestSizes)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/analytics/request-sizes"+query, nil))
+
+	var resp struct {
+		Success bool                 `json:"success"`
+		Data    RequestSizesResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp.Data
+}
+
+func TestGetRequestSizes(t *testing.T) {
+	setupUserControllerTest(t)
+
+	modelName := "test-request-sizes-" + random.GetRandomString(6)
+	t.Cleanup(func() { model.LOG_DB.Exec("DELETE FROM logs WHERE model_name = ?", modelName) })
+	for _, size := range []int64{100, 200, 300, 4000} {
+		require.NoError(t, model.LOG_DB.Create(&model.Log{
+			UserId:    1,
+			ModelName: modelName,
+			Type:      model.LogTypeConsume,
+			CreatedAt: helper.GetTimestamp(),
+			Metadata:  model.AppendBodySizeMetadata(nil, size, size/2),
+		}).Error)
+	}
+
+	code, resp := getRequestSizes(t, "?model="+modelName)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 7, resp.Days)
+	require.EqualValues(t, 4, resp.RequestBodyBytes.Samples)
+	require.Equal(t, map[string]int64{"p50": 200, "p95": 4000}, resp.RequestBodyBytes.Percentiles)
+	require.Equal(t, map[string]int64{"p50": 100, "p95": 2000}, resp.ResponseBodyBytes.Percentiles)
+
+	code, resp = getRequestSizes(t, "?model="+modelName+"&p50=true&p95=false&p99=true")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]int64{"p50": 200, "p99": 4000}, resp.RequestBodyBytes.Percentiles)
+
+	code, _ = getRequestSizes(t, "?days=0")
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGetResponseSizes(t *testing.T) {
+	original := config.EnablePrometheusMetrics
+	t.Cleanup(func() { config.EnablePrometheusMetrics = original })
+	config.EnablePrometheusMetrics = true
+
+	recorder := &prometheus.PrometheusRecorder{}
+	modelName := "test-response-sizes-" + random.GetRandomString(6)
+	for range 100 {
+		recorder.RecordUpstreamBodySizes(modelName, 1, "stream", 100, 5000)
+	}
+
+	router := gin.New()
+	router.GET("/api/admin/analytics/response-sizes", GetResponseSizes)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/analytics/response-sizes", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Success bool                   `json:"success"`
+		Data    []ResponseSizeQuantile `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.True(t, resp.Success)
+	var found bool
+	for _, size := range resp.Data {
+		if size.Model == modelName {
+			found = true
+			// Rank 99 of 100 samples in the (1KB, 10KB] bucket
+			require.InDelta(t, 1024+9216*0.99, size.P99Bytes, 1e-6)
+		}
+	}
+	require.True(t, found)
+
+	config.EnablePrometheusMetrics = false
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/analytics/response-sizes", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/model"
+)
+
+const (
+	// defaultSlowQueryThresholdMs is the threshold GetSlowQueries applies by default.
+	defaultSlowQueryThresholdMs = 100
+	// maxSlowQueryLimit bounds the statements GetSlowQueries returns per database.
+	maxSlowQueryLimit = 100
+)
+
+// DatabaseSlowQueries are the slow queries found on one database.
+type DatabaseSlowQueries struct {
+	// Database is "main", or "log" for a separate log database.
+	Database string            `json:"database"`
+	Queries  []model.SlowQuery `json:"queries"`
+	// Error explains why the database could not be analyzed.
+	Error string `json:"error,omitempty"`
+}
+
+// GetSlowQueries reports the statements of the main and log databases that
+// took at least `threshold_ms` (default 100), up to `limit` (default 20) per
+// database, with their query plans and index suggestions.
+func GetSlowQueries(c *gin.Context) {
+	thresholdMs, err := strconv.Atoi(c.DefaultQuery("threshold_ms", strconv.Itoa(defaultSlowQueryThresholdMs)))
+	if err != nil || thresholdMs < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "threshold_ms must be a non-negative integer",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > maxSlowQueryLimit {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "limit must be an integer between 1 and " + strconv.Itoa(maxSlowQueryLimit),
+		})
+		return
+	}
+
+	type namedDB struct {
+		name string
+		db   *gorm.DB
+	}
+	databases := []namedDB{{"main", model.DB}}
+	if model.LOG_DB != model.DB {
+		databases = append(databases, namedDB{"log", model.LOG_DB})
+	}
+
+	ctx := gmw.Ctx(c)
+	threshold := time.Duration(thresholdMs) * time.Millisecond
+	results := make([]DatabaseSlowQueries, 0, len(databases))
+	for _, database := range databases {
+		result := DatabaseSlowQueries{Database: database.name, Queries: []model.SlowQuery{}}
+		analyzer, err := model.NewSlowQueryAnalyzer(database.db)
+		if err == nil {
+			var queries []model.SlowQuery
+			if queries, err = analyzer.SlowQueries(ctx, threshold, limit); err == nil && queries != nil {
+				result.Queries = queries
+			}
+		}
+		if err != nil {
+			gmw.GetLogger(c).Warn("failed to analyze slow queries", zap.String("database", database.name), zap.Error(err))
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    results,
+	})
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/model"
+)
+
+func getSlowQueries(t *testing.T, query string) (int, []DatabaseSlowQueries) {
+	t.Helper()
+	router := gin.New()
+	router.GET("/api/admin/db/slow-queries", GetSlowQueries)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/db/slow-queries"+query, nil))
+
+	var resp struct {
+		Success bool                  `json:"success"`
+		Data    []DatabaseSlowQueries `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp.Data
+}
+
+func TestGetSlowQueries_SuggestsIndexForTableScan(t *testing.T) {
+	setupUserControllerTest(t)
+
+	// request_id is not indexed; repeated to rank among the most executed
+	for range 50 {
+		var logs []model.Log
+		require.NoError(t, model.LOG_DB.Where("request_id = ?", "req-slow").Find(&logs).Error)
+	}
+
+	code, results := getSlowQueries(t, "?threshold_ms=0&limit=100")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, results, 1)
+	require.Equal(t, "main", results[0].Database)
+	require.Empty(t, results[0].Error)
+
+	var suggestions []string
+	for _, query := range results[0].Queries {
+		if query.SQL == "SELECT * FROM `logs` WHERE request_id = ?" {
+			suggestions = query.Suggestions
+		}
+	}
+	require.Equal(t, []string{"Consider adding index on logs(request_id)"}, suggestions)
+
+	code, _ = getSlowQueries(t, "?limit=0")
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = getSlowQueries(t, "?threshold_ms=-1")
+	require.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+type setUserParentRequest struct {
+	// ParentUserId is the account whose quota pool the user draws from, 0 detaches it.
+	ParentUserId int `json:"parent_user_id"`
+}
+
+// SetUserParent makes the user a sub-account of another user, or detaches it.
+//
+// Admins may manage users below their own role. Other users may only manage
+// their own sub-accounts: they can offer to attach users they invited, who
+// must accept with RespondUserParentOffer, and detach users currently
+// attached to them.
+func SetUserParent(c *gin.Context) {
+	ctx := gmw.Ctx(c)
+	lg := gmw.GetLogger(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": invalidParameterMessage,
+		})
+		return
+	}
+	req := setUserParentRequest{}
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": invalidParameterMessage,
+		})
+		return
+	}
+
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	callerId := c.GetInt(ctxkey.Id)
+	callerRole := c.GetInt(ctxkey.Role)
+	var allowed bool
+	switch {
+	case callerRole >= model.RoleAdminUser:
+		allowed = callerRole > user.Role || callerRole == model.RoleRootUser
+	case req.ParentUserId == 0:
+		allowed = user.ParentUserId == callerId
+	default:
+		allowed = req.ParentUserId == callerId &&
+			user.InviterId == callerId &&
+			(user.ParentUserId == 0 || user.ParentUserId == callerId)
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "only admins and the parent account can change the parent of this user",
+		})
+		return
+	}
+
+	// A user's spending is capped by its parent, so only admins may attach
+	// it without its consent
+	if callerRole < model.RoleAdminUser && req.ParentUserId != 0 {
+		if user.ParentUserId == req.ParentUserId {
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"message": "",
+			})
+			return
+		}
+		if err = model.OfferUserParent(ctx, user.Id, req.ParentUserId); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		lg.Info("user parent offered",
+			zap.Int("user_id", user.Id),
+			zap.Int("parent_user_id", req.ParentUserId),
+			zap.Int("operator_id", callerId))
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "the user must accept to become a sub-account",
+		})
+		return
+	}
+
+	if err = model.SetUserParent(ctx, user.Id, req.ParentUserId); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	lg.Info("user parent updated",
+		zap.Int("user_id", user.Id),
+		zap.Int("parent_user_id", req.ParentUserId),
+		zap.Int("previous_parent_user_id", user.ParentUserId),
+		zap.Int("operator_id", callerId))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+type respondUserParentOfferRequest struct {
+	// Accept attaches the caller to the parent that made the pending offer,
+	// otherwise the offer is declined.
+	Accept bool `json:"accept"`
+}
+
+// RespondUserParentOffer accepts or declines the pending offer of another
+// user to take the caller as a sub-account, see SetUserParent.
+func RespondUserParentOffer(c *gin.Context) {
+	ctx := gmw.Ctx(c)
+	id := c.GetInt(ctxkey.Id)
+	req := respondUserParentOfferRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": invalidParameterMessage,
+		})
+		return
+	}
+
+	var err error
+	if req.Accept {
+		err = model.AcceptUserParent(ctx, id)
+	} else {
+		err = model.DeclineUserParent(ctx, id)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	gmw.GetLogger(c).Info("user parent offer answered",
+		zap.Int("user_id", id),
+		zap.Bool("accepted", req.Accept))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
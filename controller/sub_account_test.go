@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/random"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func createSetParentTestUser(t *testing.T, username string, role int, inviterId int) *model.User {
+	t.Helper()
+	user := &model.User{
+		Username:    username,
+		Password:    "hashed-password",
+		Role:        role,
+		Quota:       100,
+		Group:       "default",
+		Status:      model.UserStatusEnabled,
+		AccessToken: random.GetUUID(),
+		AffCode:     random.GetRandomString(8),
+		InviterId:   inviterId,
+	}
+	require.NoError(t, model.DB.Create(user).Error)
+	return user
+}
+
+func setUserParentAs(t *testing.T, callerId int, callerRole int, userId int, parentId int) *httptest.ResponseRecorder {
+	t.Helper()
+	router := gin.New()
+	router.POST("/api/admin/users/:id/set-parent", func(c *gin.Context) {
+		c.Set(ctxkey.Id, callerId)
+		c.Set(ctxkey.Role, callerRole)
+		SetUserParent(c)
+	})
+	body, err := json.Marshal(map[string]any{"parent_user_id": parentId})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/set-parent", userId), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func respondUserParentOfferAs(t *testing.T, userId int, accept bool) bool {
+	t.Helper()
+	router := gin.New()
+	router.POST("/api/user/parent/respond", func(c *gin.Context) {
+		c.Set(ctxkey.Id, userId)
+		RespondUserParentOffer(c)
+	})
+	body, err := json.Marshal(map[string]any{"accept": accept})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/api/user/parent/respond", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp struct {
+		Success bool `json:"success"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Success
+}
+
+func TestSetUserParentPermissions(t *testing.T) {
+	setupUserControllerTest(t)
+
+	parent := createSetParentTestUser(t, "parent", model.RoleCommonUser, 0)
+	invitee := createSetParentTestUser(t, "invitee", model.RoleCommonUser, parent.Id)
+	stranger := createSetParentTestUser(t, "stranger", model.RoleCommonUser, 0)
+	admin := createSetParentTestUser(t, "admin", model.RoleAdminUser, 0)
+
+	// A regular user cannot attach accounts they did not invite
+	w := setUserParentAs(t, parent.Id, model.RoleCommonUser, stranger.Id, parent.Id)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	// Nor attach accounts to someone else
+	w = setUserParentAs(t, stranger.Id, model.RoleCommonUser, invitee.Id, parent.Id)
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	// The parent can offer to attach its invitee, who must accept it
+	w = setUserParentAs(t, parent.Id, model.RoleCommonUser, invitee.Id, parent.Id)
+	require.Equal(t, http.StatusOK, w.Code)
+	updated, err := model.GetUserById(invitee.Id, false)
+	require.NoError(t, err)
+	require.Zero(t, updated.ParentUserId)
+	require.Equal(t, parent.Id, updated.PendingParentUserId)
+	require.True(t, respondUserParentOfferAs(t, invitee.Id, true))
+	updated, err = model.GetUserById(invitee.Id, false)
+	require.NoError(t, err)
+	require.Equal(t, parent.Id, updated.ParentUserId)
+	require.Zero(t, updated.PendingParentUserId)
+	require.False(t, respondUserParentOfferAs(t, invitee.Id, true), "the offer is used up")
+
+	// Only the parent or an admin may detach it
+	w = setUserParentAs(t, stranger.Id, model.RoleCommonUser, invitee.Id, 0)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	w = setUserParentAs(t, parent.Id, model.RoleCommonUser, invitee.Id, 0)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// A declined offer leaves the user detached
+	w = setUserParentAs(t, parent.Id, model.RoleCommonUser, invitee.Id, parent.Id)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, respondUserParentOfferAs(t, invitee.Id, false))
+	updated, err = model.GetUserById(invitee.Id, false)
+	require.NoError(t, err)
+	require.Zero(t, updated.ParentUserId)
+	require.Zero(t, updated.PendingParentUserId)
+
+	// Admins can attach any user below their role
+	w = setUserParentAs(t, admin.Id, model.RoleAdminUser, stranger.Id, parent.Id)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.True(t, resp.Success, resp.Message)
+
+	// Sub-accounts display both their own quota and the parent pool
+	require.NoError(t, model.DB.Model(&model.User{}).Where("id = ?", parent.Id).Update("quota", 40).Error)
+	router := gin.New()
+	router.GET("/api/user/self", func(c *gin.Context) {
+		c.Set(ctxkey.Id, stranger.Id)
+		GetSelf(c)
+	})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/user/self", nil))
+	var self struct {
+		Success bool       `json:"success"`
+		Data    model.User `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &self))
+	require.True(t, self.Success)
+	require.Equal(t, int64(100), self.Data.Quota)
+	require.NotNil(t, self.Data.ParentQuota)
+	require.Equal(t, int64(40), *self.Data.ParentQuota)
+}
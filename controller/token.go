@@ -172,6 +172,18 @@ func validateToken(_ *gin.Context, token *model.Token) error {
 		}
 	}
 
+	if token.RpmLimit < 0 || token.TpmLimit < 0 {
+		return errors.Errorf("rate limits must not be negative")
+	}
+
+	if token.Scopes != nil {
+		scopes, err := model.NormalizeTokenScopes(*token.Scopes)
+		if err != nil {
+			return errors.Wrap(err, "invalid scopes")
+		}
+		token.Scopes = &scopes
+	}
+
 	return nil
 }
 
@@ -212,12 +224,16 @@ func AddToken(c *gin.Context) {
 		UnlimitedQuota: token.UnlimitedQuota,
 		Models:         token.Models,
 		Subnet:         token.Subnet,
+		Scopes:         token.Scopes,
+		RpmLimit:       token.RpmLimit,
+		TpmLimit:       token.TpmLimit,
 	}
 	err = cleanToken.Insert(gmw.Ctx(c))
 	if err != nil {
 		helper.RespondError(c, err)
 		return
 	}
+	recordTokenAudit(c, cleanToken.Id, model.AuditActionCreate, nil, &cleanToken)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -228,17 +244,37 @@ func AddToken(c *gin.Context) {
 func DeleteToken(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	userId := c.GetInt(ctxkey.Id)
+	var previous *model.Token
+	if isAdminRequest(c) {
+		previous, _ = model.GetTokenByIds(id, userId)
+	}
 	err := model.DeleteTokenById(gmw.Ctx(c), id, userId)
 	if err != nil {
 		helper.RespondError(c, err)
 		return
 	}
+	recordTokenAudit(c, id, model.AuditActionDelete, previous, nil)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
 	})
 }
 
+// isAdminRequest reports whether the request was authenticated as an
+// administrator or the root user.
+func isAdminRequest(c *gin.Context) bool {
+	return c.GetInt(ctxkey.Role) >= model.RoleAdminUser
+}
+
+// recordTokenAudit records a token mutation in the audit log when an
+// administrator performs it. Token keys are redacted by model.RecordAuditLog.
+func recordTokenAudit(c *gin.Context, tokenId int, action string, oldValue any, newValue any) {
+	if !isAdminRequest(c) {
+		return
+	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetToken, tokenId, action, oldValue, newValue)
+}
+
 // ConsumePhase defines the allowed lifecycle phases for external billing events.
 type ConsumePhase string
 
@@ -400,7 +436,9 @@ func processPreConsume(ctx context.Context, _ *gin.Context, token *model.Token,
 		TraceId:   traceID,
 	}
 
-	model.RecordConsumeLog(ctx, logEntry)
+	// The transaction keeps the log id to finalize the entry, so it must not
+	// go through the async log writer
+	model.RecordConsumeLogSync(ctx, logEntry)
 
 	transaction := &model.TokenTransaction{
 		TransactionID: transactionID,
@@ -853,6 +891,7 @@ func UpdateToken(c *gin.Context) {
 		helper.RespondError(c, err)
 		return
 	}
+	previous := *cleanToken
 
 	switch token.Status {
 	case model.TokenStatusEnabled:
@@ -893,6 +932,9 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.UnlimitedQuota = token.UnlimitedQuota
 		cleanToken.Models = token.Models
 		cleanToken.Subnet = token.Subnet
+		cleanToken.Scopes = token.Scopes
+		cleanToken.RpmLimit = token.RpmLimit
+		cleanToken.TpmLimit = token.TpmLimit
 		cleanToken.RemainQuota = token.RemainQuota
 		cleanToken.Status = token.Status
 	}
@@ -902,6 +944,7 @@ func UpdateToken(c *gin.Context) {
 		helper.RespondError(c, err)
 		return
 	}
+	recordTokenAudit(c, cleanToken.Id, model.AuditActionUpdate, &previous, cleanToken)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -20,6 +20,7 @@ import (
 	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/graceful"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/common/logger"
 	"github.com/songquanpeng/one-api/model"
@@ -177,6 +178,48 @@ func TestConsumeTokenPreAndPostFlow(t *testing.T) {
 	require.Equal(t, int64(920), refreshedUser.Quota)
 }
 
+func TestConsumeTokenPreConsumeLogWithAsyncLogWriter(t *testing.T) {
+	cleanup, user, token := setupConsumeTokenTest(t)
+	defer cleanup()
+
+	originalLogConsume := config.IsLogConsumeEnabled()
+	config.SetLogConsumeEnabled(true)
+	t.Cleanup(func() { config.SetLogConsumeEnabled(originalLogConsume) })
+
+	model.InitAsyncLogWriter()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		model.StopAsyncLogWriter(ctx)
+		require.NoError(t, graceful.Drain(ctx))
+	})
+
+	preBody := `{"phase":"pre","add_used_quota":100,"add_reason":"serviceC","timeout_seconds":30}`
+	c, recorder := newConsumeTokenContext(t, http.MethodPost, preBody, user.Id, token.Id, "req-pre-async")
+	ConsumeToken(c)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var preResp map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &preResp))
+	require.True(t, preResp["success"].(bool))
+	transactionID := preResp["transaction"].(map[string]any)["transaction_id"].(string)
+
+	txn, err := model.GetTokenTransactionByTokenAndID(context.Background(), token.Id, transactionID)
+	require.NoError(t, err)
+	require.NotNil(t, txn.LogId)
+	require.Positive(t, *txn.LogId)
+
+	postBody := fmt.Sprintf(`{"phase":"post","add_reason":"serviceC","transaction_id":"%s","final_used_quota":70}`, transactionID)
+	c, recorder = newConsumeTokenContext(t, http.MethodPost, postBody, user.Id, token.Id, "req-post-async")
+	ConsumeToken(c)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var logEntry model.Log
+	require.NoError(t, model.LOG_DB.First(&logEntry, *txn.LogId).Error)
+	require.Equal(t, 70, logEntry.Quota)
+	require.Contains(t, logEntry.Content, "finalized")
+}
+
 func TestConsumeTokenCancelFlow(t *testing.T) {
 	cleanup, user, token := setupConsumeTokenTest(t)
 	defer cleanup()
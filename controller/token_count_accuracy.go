@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/model"
+)
+
+// maxTokenCountAccuracyDays bounds the window accepted by GetTokenCountAccuracy.
+const maxTokenCountAccuracyDays = 90
+
+// GetTokenCountAccuracy reports, per model, the mean absolute percentage error
+// between estimated and upstream-reported prompt tokens over the last `days`
+// days (default 7), optionally filtered by `model`.
+func GetTokenCountAccuracy(c *gin.Context) {
+	days := 7
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxTokenCountAccuracyDays {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "days must be an integer between 1 and " + strconv.Itoa(maxTokenCountAccuracyDays),
+			})
+			return
+		}
+		days = parsed
+	}
+
+	stats, err := model.GetTokenCountAccuracy(gmw.Ctx(c), c.Query("model"), days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    stats,
+	})
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/model"
+)
+
+func getTokenCountAccuracy(t *testing.T, query string) (int, []model.TokenCountAccuracyStat) {
+	t.Helper()
+	router := gin.New()
+	router.GET("/api/admin/token-count-accuracy", GetTokenCountAccuracy)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/token-count-accuracy"+query, nil))
+
+	var resp struct {
+		Success bool                           `json:"success"`
+		Data    []model.TokenCountAccuracyStat `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp.Data
+}
+
+func TestGetTokenCountAccuracy_ReportsMAPEPerModel(t *testing.T) {
+	setupUserControllerTest(t)
+	ctx := context.Background()
+	// drop samples buffered by relay tests that ran earlier in this package
+	require.NoError(t, model.FlushTokenCountAccuracy(ctx))
+	require.NoError(t, model.DB.Where("1 = 1").Delete(&model.TokenCountAccuracy{}).Error)
+
+	// gpt-4o: errors of 10% and 30% -> MAPE 20%
+	model.RecordTokenCountAccuracy("gpt-4o", 110, 100, false)
+	model.RecordTokenCountAccuracy("gpt-4o", 70, 100, false)
+	// claude: one exact and one 3x overestimate -> MAPE 100%
+	model.RecordTokenCountAccuracy("claude-3-5-sonnet", 50, 50, false)
+	model.RecordTokenCountAccuracy("claude-3-5-sonnet", 600, 200, true)
+	// unusable samples are ignored
+	model.RecordTokenCountAccuracy("gpt-4o", 0, 100, false)
+	require.NoError(t, model.FlushTokenCountAccuracy(ctx))
+
+	code, stats := getTokenCountAccuracy(t, "?days=7")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, stats, 2)
+
+	require.Equal(t, "claude-3-5-sonnet", stats[0].ModelName)
+	require.EqualValues(t, 2, stats[0].Samples)
+	require.EqualValues(t, 1, stats[0].Discrepancies)
+	require.InDelta(t, 100.0, stats[0].MAPE, 1e-9)
+
+	require.Equal(t, "gpt-4o", stats[1].ModelName)
+	require.EqualValues(t, 2, stats[1].Samples)
+	require.InDelta(t, 20.0, stats[1].MAPE, 1e-9)
+
+	code, stats = getTokenCountAccuracy(t, "?model=gpt-4o")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, stats, 1)
+	require.Equal(t, "gpt-4o", stats[0].ModelName)
+
+	code, _ = getTokenCountAccuracy(t, "?days=0")
+	require.Equal(t, http.StatusBadRequest, code)
+}
@@ -19,11 +19,11 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/songquanpeng/one-api/common"
-	"github.com/songquanpeng/one-api/common/blacklist"
 	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/network"
 	"github.com/songquanpeng/one-api/common/random"
 	"github.com/songquanpeng/one-api/common/utils"
 	"github.com/songquanpeng/one-api/dto"
@@ -80,8 +80,12 @@ func Login(c *gin.Context) {
 		Username: username,
 		Password: password,
 	}
+	recordLogin := func(failureReason string) {
+		model.RecordLoginLog(ctx, user.Id, username, c.ClientIP(), c.Request.UserAgent(), failureReason)
+	}
 	err = user.ValidateAndFill()
 	if err != nil {
+		recordLogin("invalid username or password, or user disabled")
 		c.JSON(http.StatusOK, gin.H{
 			"message": err.Error(),
 			"success": false,
@@ -114,8 +118,9 @@ func Login(c *gin.Context) {
 			return
 		}
 
-		// Verify TOTP code
-		if !verifyTotpCode(ctx, user.Id, user.TotpSecret, loginRequest.TotpCode) {
+		// Verify TOTP code, falling back to a backup code
+		if !verifyTotpOrBackupCode(ctx, user.Id, user.TotpSecret, loginRequest.TotpCode) {
+			recordLogin("invalid TOTP code")
 			c.JSON(http.StatusOK, gin.H{
 				"message": "Invalid TOTP code",
 				"success": false,
@@ -124,11 +129,27 @@ func Login(c *gin.Context) {
 		}
 	}
 
-	SetupLogin(&user, c)
+	if SetupLogin(&user, c) {
+		recordLogin("")
+	}
 }
 
 // setup session & cookies and then return user info
-func SetupLogin(user *model.User, c *gin.Context) {
+//
+// SetupLogin is shared by the password and OAuth logins, so it enforces the
+// user's IP allowlist for all of them. It reports whether the session was
+// established; otherwise the error response has already been written.
+func SetupLogin(user *model.User, c *gin.Context) bool {
+	ctx := gmw.Ctx(c)
+	if user.IpAllowlist != "" && !network.IsIpInSubnets(ctx, c.ClientIP(), user.IpAllowlist) {
+		model.RecordLoginLog(ctx, user.Id, user.Username, c.ClientIP(), c.Request.UserAgent(), "IP address not in allowlist")
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Login from this IP address is not allowed",
+			"success": false,
+		})
+		return false
+	}
+
 	// BUG: 如果用户发送了一段不合法的 session cookie，因为 gorilla 对无法识别的 session 会默认返回 nil，
 	// 导致 session.Set 中会出现 panic
 	//
@@ -145,15 +166,21 @@ func SetupLogin(user *model.User, c *gin.Context) {
 	//
 	// BUG: https://github.com/gin-contrib/sessions/issues/287
 	// github.com/gin-contrib/sessions 不要使用 v1.0.3
+	sessionVersion, err := model.CacheGetUserSessionVersion(ctx, user.Id)
+	if err != nil {
+		helper.RespondError(c, errors.Wrap(err, "unable to load session version"))
+		return false
+	}
 	session := sessions.Default(c)
 	session.Set("id", user.Id)
 	session.Set("username", user.Username)
 	session.Set("role", user.Role)
 	session.Set("status", user.Status)
-	err := session.Save()
+	session.Set("session_version", sessionVersion)
+	err = session.Save()
 	if err != nil {
 		helper.RespondError(c, errors.Wrap(err, "unable to save login session information"))
-		return
+		return false
 	}
 
 	// set auth header
@@ -173,6 +200,7 @@ func SetupLogin(user *model.User, c *gin.Context) {
 		"success": true,
 		"data":    cleanUser,
 	})
+	return true
 }
 
 func Logout(c *gin.Context) {
@@ -240,6 +268,13 @@ func Register(c *gin.Context) {
 			return
 		}
 	}
+	if user.Email != "" && isEmailDomainBlocked(user.Email) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": emailDomainNotAllowedMessage,
+		})
+		return
+	}
 	affCode := user.AffCode // this code is the inviter's code, not the user's own code
 	inviterId, _ := model.GetUserIdByAffCode(affCode)
 	cleanUser := model.User{
@@ -363,6 +398,13 @@ func GetUser(c *gin.Context) {
 		})
 		return
 	}
+	if err = user.LoadParentQuota(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -755,6 +797,13 @@ func GetSelf(c *gin.Context) {
 		})
 		return
 	}
+	if err = user.LoadParentQuota(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -953,6 +1002,35 @@ func UpdateUser(c *gin.Context) {
 		}
 	}
 
+	if rawFieldPresent(raw, "ip_allowlist") && !jsonRawIsNull(raw["ip_allowlist"]) {
+		allowlist, err := model.NormalizeIpAllowlist(*payload.IpAllowlist)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+		updates["ip_allowlist"] = allowlist
+	}
+
+	budgetUpdated := false
+	for field, value := range map[string]*int64{"daily_budget": payload.DailyBudget, "weekly_budget": payload.WeeklyBudget} {
+		if !rawFieldPresent(raw, field) || jsonRawIsNull(raw[field]) {
+			continue
+		}
+		if value == nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": invalidParameterMessage,
+			})
+			return
+		}
+		if *value < 0 {
+			c.JSON(http.StatusOK, gin.H{"success": false, "message": "Budget must be non-negative"})
+			return
+		}
+		updates[field] = *value
+		budgetUpdated = true
+	}
+
 	if rawFieldPresent(raw, "password") {
 		if jsonRawIsNull(raw["password"]) {
 			// nil => no change
@@ -1053,16 +1131,31 @@ func UpdateUser(c *gin.Context) {
 	if statusChanged {
 		switch newStatus {
 		case model.UserStatusDisabled:
-			blacklist.BanUser(payload.Id)
+			model.BanUser(payload.Id)
 		case model.UserStatusEnabled:
-			blacklist.UnbanUser(payload.Id)
+			model.UnbanUser(payload.Id)
+		}
+	}
+
+	if budgetUpdated {
+		model.InvalidateUserBudgetCache(ctx, payload.Id)
+	}
+	if _, passwordChanged := updates["password"]; passwordChanged {
+		if _, err := model.RevokeUserSessions(ctx, payload.Id); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
 		}
 	}
 
 	if quotaUpdated && originUser.Quota != newQuota {
 		note := fmt.Sprintf("admin_id=%d", adminUserID)
 		model.RecordManageLog(ctx, originUser.Id, "quota", common.LogQuota(originUser.Quota), common.LogQuota(newQuota), note)
+		model.PublishQuotaAdjustment(originUser.Id, newQuota-originUser.Quota)
 	}
+	model.RecordAuditLog(ctx, adminUserID, model.AuditTargetUser, originUser.Id, model.AuditActionUpdate, originUser, updates)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -1119,6 +1212,16 @@ func UpdateSelf(c *gin.Context) {
 		})
 		return
 	}
+	if updatePassword {
+		// The password change signed every session out, keep this one
+		if err := keepCurrentSessionAfterRevoke(c, cleanUser.Id); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -1154,11 +1257,56 @@ func DeleteUser(c *gin.Context) {
 	err = model.DeleteUserById(id)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"message": "",
+			"success": false,
+			"message": err.Error(),
 		})
 		return
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetUser, id, model.AuditActionDelete, originUser, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// RestoreUser reverts the soft deletion of a user within the retention window
+// and enables it again.
+func RestoreUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	deletedUser, err := model.GetDeletedUserById(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if c.GetInt(ctxkey.Role) <= deletedUser.Role {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "No permission to restore users with the same permission level or higher permission level",
+		})
+		return
+	}
+	if err = model.RestoreUserById(id); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetUser, id, "restore", deletedUser, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
 }
 
 func DeleteSelf(c *gin.Context) {
@@ -1238,6 +1386,7 @@ func CreateUser(c *gin.Context) {
 		})
 		return
 	}
+	model.RecordAuditLog(ctx, c.GetInt(ctxkey.Id), model.AuditTargetUser, cleanUser.Id, model.AuditActionCreate, nil, cleanUser)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -1282,6 +1431,7 @@ func ManageUser(c *gin.Context) {
 		})
 		return
 	}
+	previous := gin.H{"role": user.Role, "status": user.Status}
 	switch req.Action {
 	case "disable":
 		user.Status = model.UserStatusDisabled
@@ -1354,6 +1504,8 @@ func ManageUser(c *gin.Context) {
 		Role:   user.Role,
 		Status: user.Status,
 	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetUser, user.Id, req.Action,
+		previous, gin.H{"role": clearUser.Role, "status": clearUser.Status})
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -1462,6 +1614,7 @@ func AdminTopUp(c *gin.Context) {
 		req.Remark = fmt.Sprintf("Recharged via API %s", common.LogQuota(int64(req.Quota)))
 	}
 	model.RecordTopupLog(ctx, req.UserId, req.Remark, req.Quota)
+	model.RecordAuditLog(ctx, c.GetInt(ctxkey.Id), model.AuditTargetUser, req.UserId, "topup", nil, req)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -1626,9 +1779,85 @@ func ConfirmTotp(c *gin.Context) {
 	session.Delete("temp_totp_secret")
 	session.Save()
 
+	backupCodes, err := model.GenerateTotpBackupCodes(user.Id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "TOTP has been successfully enabled",
+		"data": gin.H{
+			"backup_codes": backupCodes,
+		},
+	})
+}
+
+// GenerateTotpBackupCodes replaces the user's TOTP backup codes after
+// verifying a current TOTP code. The new codes are only returned once.
+func GenerateTotpBackupCodes(c *gin.Context) {
+	ctx := gmw.Ctx(c)
+	userId := c.GetInt(ctxkey.Id)
+
+	// Check rate limit for TOTP verification
+	if !middleware.CheckTotpRateLimit(c, userId) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"message": "Too many TOTP verification attempts. Please wait before trying again.",
+		})
+		return
+	}
+
+	var req TotpSetupRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": invalidParameterMessage,
+		})
+		return
+	}
+
+	user, err := model.GetUserById(userId, true)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if user.TotpSecret == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "TOTP is not enabled for this user",
+		})
+		return
+	}
+	if !verifyTotpCode(ctx, user.Id, user.TotpSecret, req.TotpCode) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "Invalid TOTP code",
+		})
+		return
+	}
+
+	backupCodes, err := model.GenerateTotpBackupCodes(user.Id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"backup_codes": backupCodes,
+		},
 	})
 }
 
@@ -1691,6 +1920,13 @@ func DisableTotp(c *gin.Context) {
 		})
 		return
 	}
+	if err = keepCurrentSessionAfterRevoke(c, user.Id); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -1737,6 +1973,23 @@ func verifyTotpCode(ctx context.Context, uid int, secret, code string) bool {
 	return true
 }
 
+// verifyTotpOrBackupCode accepts either a current TOTP code or one of the
+// user's unused backup codes, which is used up on success.
+func verifyTotpOrBackupCode(ctx context.Context, uid int, secret, code string) bool {
+	if verifyTotpCode(ctx, uid, secret, code) {
+		return true
+	}
+	consumed, err := model.ConsumeTotpBackupCode(uid, code)
+	if err != nil {
+		logger.Logger.Error("failed to check TOTP backup code", zap.Int("user_id", uid), zap.Error(err))
+		return false
+	}
+	if consumed {
+		logger.Logger.Info("user logged in with a TOTP backup code", zap.Int("user_id", uid))
+	}
+	return consumed
+}
+
 // GetTotpStatus returns whether TOTP is enabled for the current user
 func GetTotpStatus(c *gin.Context) {
 	userId := c.GetInt(ctxkey.Id)
@@ -1753,7 +2006,8 @@ func GetTotpStatus(c *gin.Context) {
 		"success": true,
 		"message": "",
 		"data": gin.H{
-			"totp_enabled": user.TotpSecret != "",
+			"totp_enabled":           user.TotpSecret != "",
+			"backup_codes_remaining": model.CountTotpBackupCodes(user),
 		},
 	})
 }
@@ -1823,6 +2077,8 @@ func AdminDisableUserTotp(c *gin.Context) {
 	adminUserId := c.GetInt(ctxkey.Id)
 	note := fmt.Sprintf("admin_id=%d target_username=%s", adminUserId, user.Username)
 	model.RecordManageLog(ctx, user.Id, "totp_enabled", true, false, note)
+	model.RecordAuditLog(ctx, adminUserId, model.AuditTargetUser, user.Id, "disable_totp",
+		gin.H{"totp_enabled": true}, gin.H{"totp_enabled": false})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	gutils "github.com/Laisky/go-utils/v6"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/model"
+)
+
+const (
+	// activeUsersCacheTTL bounds how long active user analytics are served from cache.
+	activeUsersCacheTTL = 15 * time.Minute
+	// defaultActiveUsersWindow is the range reported when `from` is omitted.
+	defaultActiveUsersWindow = 30 * 24 * time.Hour
+	// maxActiveUsersWindow bounds the range accepted by GetActiveUsers.
+	maxActiveUsersWindow = 366 * 24 * time.Hour
+)
+
+// activeUsersCache caches active user analytics by range and granularity.
+var activeUsersCache = gutils.NewExpCache[[]model.UserActivityDataPoint](context.Background(), activeUsersCacheTTL)
+
+// ActiveUsersResponse is the payload of GetActiveUsers.
+type ActiveUsersResponse struct {
+	From        int64                         `json:"from"`
+	To          int64                         `json:"to"`
+	Granularity string                        `json:"granularity"`
+	DataPoints  []model.UserActivityDataPoint `json:"data_points"`
+}
+
+// parseUnixQuery reads an optional Unix seconds query parameter.
+func parseUnixQuery(c *gin.Context, key string, fallback int64) (int64, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value < 0 {
+		return 0, errors.Errorf("%s must be a non-negative Unix timestamp in seconds", key)
+	}
+	return value, nil
+}
+
+// parseActiveUsersQuery validates the range and granularity of GetActiveUsers.
+func parseActiveUsersQuery(c *gin.Context) (from, to int64, granularity string, err error) {
+	granularity = c.DefaultQuery("granularity", model.ActivityGranularityDaily)
+	switch granularity {
+	case model.ActivityGranularityDaily, model.ActivityGranularityWeekly, model.ActivityGranularityMonthly:
+	default:
+		return 0, 0, "", errors.Errorf("granularity must be one of daily, weekly or monthly")
+	}
+
+	// Default to whole UTC days so repeated requests share a cache entry
+	endOfToday := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour).Unix()
+	if to, err = parseUnixQuery(c, "to", endOfToday); err != nil {
+		return 0, 0, "", err
+	}
+	defaultFrom := max(to-int64(defaultActiveUsersWindow/time.Second), 0)
+	if from, err = parseUnixQuery(c, "from", defaultFrom); err != nil {
+		return 0, 0, "", err
+	}
+	if from >= to {
+		return 0, 0, "", errors.Errorf("from must be before to")
+	}
+	if to-from > int64(maxActiveUsersWindow/time.Second) {
+		return 0, 0, "", errors.Errorf("the range between from and to must not exceed %d days", int(maxActiveUsersWindow/(24*time.Hour)))
+	}
+	return from, to, granularity, nil
+}
+
+// GetActiveUsers reports active users (DAU/WAU/MAU), new registrations and
+// model diversity per period for [from, to). `to` defaults to the end of the
+// current UTC day and `from` to 30 days before `to`. Results are cached for
+// 15 minutes.
+func GetActiveUsers(c *gin.Context) {
+	from, to, granularity, err := parseActiveUsersQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	cacheKey := fmt.Sprintf("%d:%d:%s", from, to, granularity)
+	points, ok := activeUsersCache.Load(cacheKey)
+	if !ok {
+		points, err = model.SearchActiveUsers(from, to, granularity)
+		if err != nil {
+			gmw.GetLogger(c).Error("failed to search active users", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		activeUsersCache.Store(cacheKey, points)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": ActiveUsersResponse{
+			From:        from,
+			To:          to,
+			Granularity: granularity,
+			DataPoints:  points,
+		},
+	})
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/model"
+)
+
+func getActiveUsers(t *testing.T, query string) (int, ActiveUsersResponse) {
+	t.Helper()
+	router := gin.New()
+	router.GET("/api/admin/analytics/active-users", GetActiveUsers)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/analytics/active-users"+query, nil))
+
+	var resp struct {
+		Success bool                `json:"success"`
+		Data    ActiveUsersResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp.Data
+}
+
+func TestGetActiveUsers(t *testing.T) {
+	setupUserControllerTest(t)
+
+	const marker = "test-active-users-api"
+	t.Cleanup(func() { model.LOG_DB.Exec("DELETE FROM logs WHERE content = ?", marker) })
+
+	day := time.Date(2002, 3, 4, 0, 0, 0, 0, time.UTC).Unix()
+	require.NoError(t, model.LOG_DB.Create(&[]model.Log{
+		{UserId: 1, ModelName: "gpt-4", Type: model.LogTypeConsume, Content: marker, CreatedAt: day + 60},
+		{UserId: 1, ModelName: "claude-3", Type: model.LogTypeConsume, Content: marker, CreatedAt: day + 120},
+		{UserId: 2, ModelName: "gpt-4", Type: model.LogTypeConsume, Content: marker, CreatedAt: day + 180},
+	}).Error)
+
+	query := "?granularity=daily&from=" + strconv.FormatInt(day, 10) + "&to=" + strconv.FormatInt(day+86400, 10)
+	code, resp := getActiveUsers(t, query)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, day, resp.From)
+	require.Equal(t, "daily", resp.Granularity)
+	require.Equal(t, []model.UserActivityDataPoint{
+		{Period: "2002-03-04", ActiveUsers: 2, ModelDiversity: 1.5},
+	}, resp.DataPoints)
+
+	// Results are served from cache until it expires
+	require.NoError(t, model.LOG_DB.Create(&model.Log{
+		UserId: 3, ModelName: "gpt-4", Type: model.LogTypeConsume, Content: marker, CreatedAt: day + 240,
+	}).Error)
+	_, resp = getActiveUsers(t, query)
+	require.EqualValues(t, 2, resp.DataPoints[0].ActiveUsers)
+
+	// Defaults cover the 30 days up to the end of the current UTC day
+	code, resp = getActiveUsers(t, "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "daily", resp.Granularity)
+	require.Equal(t, int64(30*86400), resp.To-resp.From)
+	require.Zero(t, resp.To%86400)
+	require.Greater(t, resp.To, time.Now().Unix())
+
+	for _, bad := range []string{
+		"?granularity=hourly",
+		"?from=abc",
+		"?from=100&to=100",
+		"?from=0&to=" + strconv.FormatInt(400*86400, 10),
+	} {
+		code, _ = getActiveUsers(t, bad)
+		require.Equal(t, http.StatusBadRequest, code, bad)
+	}
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// autoRefillRequest is the body of UpdateUserAutoRefill.
+type autoRefillRequest struct {
+	AutoRefillQuota        int64 `json:"auto_refill_quota"`
+	AutoRefillIntervalDays int   `json:"auto_refill_interval_days"`
+}
+
+// UpdateUserAutoRefill sets how much quota the user in the id path parameter
+// is refilled with and every how many days. Setting both to 0 disables auto
+// refill.
+func UpdateUserAutoRefill(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	var req autoRefillRequest
+	if err == nil {
+		err = json.NewDecoder(c.Request.Body).Decode(&req)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": invalidParameterMessage})
+		return
+	}
+	if req.AutoRefillQuota < 0 || req.AutoRefillIntervalDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "auto_refill_quota and auto_refill_interval_days must not be negative"})
+		return
+	}
+	if (req.AutoRefillQuota == 0) != (req.AutoRefillIntervalDays == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "auto_refill_quota and auto_refill_interval_days must be set together"})
+		return
+	}
+
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	myRole := c.GetInt(ctxkey.Role)
+	if myRole <= user.Role && myRole != model.RoleRootUser {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "No permission to update user information with the same permission level or higher permission level",
+		})
+		return
+	}
+
+	if err = model.UpdateUserAutoRefill(id, req.AutoRefillQuota, req.AutoRefillIntervalDays); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	model.RecordAuditLog(gmw.Ctx(c), c.GetInt(ctxkey.Id), model.AuditTargetUser, id, "update_auto_refill",
+		autoRefillRequest{AutoRefillQuota: user.AutoRefillQuota, AutoRefillIntervalDays: user.AutoRefillIntervalDays}, req)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": req})
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestUpdateUserAutoRefill(t *testing.T) {
+	setupUserControllerTest(t)
+
+	user := &model.User{Username: "refill", Password: "x", AccessToken: "refill-access-token", AffCode: "rfil", Status: model.UserStatusEnabled}
+	require.NoError(t, model.DB.Create(user).Error)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(ctxkey.Role, model.RoleAdminUser) })
+	router.PATCH("/api/user/:id/auto-refill", UpdateUserAutoRefill)
+	patch := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/user/%d/auto-refill", user.Id), strings.NewReader(body)))
+		return w
+	}
+
+	require.Equal(t, http.StatusBadRequest, patch(`{"auto_refill_quota": 100}`).Code)
+	require.Equal(t, http.StatusBadRequest, patch(`{"auto_refill_quota": -1, "auto_refill_interval_days": 7}`).Code)
+
+	w := patch(`{"auto_refill_quota": 100, "auto_refill_interval_days": 7}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"success":true`)
+	stored, err := model.GetUserById(user.Id, false)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, stored.AutoRefillQuota)
+	require.Equal(t, 7, stored.AutoRefillIntervalDays)
+}
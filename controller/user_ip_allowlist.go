@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/network"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// ipAllowlistRequest is the body of UpdateSelfIpAllowlist.
+type ipAllowlistRequest struct {
+	IpAllowlist string `json:"ip_allowlist"`
+}
+
+// UpdateSelfIpAllowlist sets the comma-separated CIDR ranges the current user
+// may log in from, or clears them when empty. A list excluding the address
+// making the request is rejected so users cannot lock themselves out.
+func UpdateSelfIpAllowlist(c *gin.Context) {
+	var req ipAllowlistRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": invalidParameterMessage})
+		return
+	}
+	allowlist, err := model.NormalizeIpAllowlist(req.IpAllowlist)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if allowlist != "" && !network.IsIpInSubnets(gmw.Ctx(c), c.ClientIP(), allowlist) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "The allowlist must include your current IP address " + c.ClientIP(),
+		})
+		return
+	}
+
+	if err = model.UpdateUserIpAllowlist(c.GetInt(ctxkey.Id), allowlist); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": ipAllowlistRequest{IpAllowlist: allowlist}})
+}
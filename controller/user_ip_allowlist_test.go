@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestUserIpAllowlist(t *testing.T) {
+	testDB, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	require.NoError(t, testDB.AutoMigrate(&model.LoginLog{}))
+
+	hashed, err := common.Password2Hash("password123")
+	require.NoError(t, err)
+	require.NoError(t, testDB.Model(&model.User{}).Where("id = ?", 1).Update("password", hashed).Error)
+
+	router := setupTestRouter()
+	router.POST("/login", Login)
+	router.PATCH("/self/ip-allowlist", func(c *gin.Context) {
+		c.Set(ctxkey.Id, 1)
+		UpdateSelfIpAllowlist(c)
+	})
+	send := func(method, path, remoteAddr string, payload any) map[string]any {
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	credentials := LoginRequest{Username: "testuser", Password: "password123"}
+
+	// A list excluding the current address would lock the user out
+	resp := send(http.MethodPatch, "/self/ip-allowlist", "192.0.2.10:4000", ipAllowlistRequest{IpAllowlist: "198.51.100.0/24"})
+	require.False(t, resp["success"].(bool))
+	resp = send(http.MethodPatch, "/self/ip-allowlist", "192.0.2.10:4000", ipAllowlistRequest{IpAllowlist: "not-a-cidr"})
+	require.False(t, resp["success"].(bool))
+
+	resp = send(http.MethodPatch, "/self/ip-allowlist", "192.0.2.10:4000", ipAllowlistRequest{IpAllowlist: "192.0.2.0/24, 198.51.100.0/24"})
+	require.True(t, resp["success"].(bool), resp["message"])
+
+	resp = send(http.MethodPost, "/login", "192.0.2.20:4000", credentials)
+	require.True(t, resp["success"].(bool), resp["message"])
+	resp = send(http.MethodPost, "/login", "203.0.113.5:4000", credentials)
+	require.False(t, resp["success"].(bool))
+
+	entries, err := model.GetLoginLogs(t.Context(), 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.False(t, entries[0].Success)
+	require.Equal(t, "203.0.113.5", entries[0].Ip)
+
+	// OAuth logins go through SetupLogin, which enforces the list as well
+	router.POST("/oauth", func(c *gin.Context) {
+		user := model.User{Id: 1}
+		require.NoError(t, user.FillUserById())
+		SetupLogin(&user, c)
+	})
+	resp = send(http.MethodPost, "/oauth", "203.0.113.5:4000", nil)
+	require.False(t, resp["success"].(bool))
+	resp = send(http.MethodPost, "/oauth", "198.51.100.7:4000", nil)
+	require.True(t, resp["success"].(bool), resp["message"])
+
+	// Clearing the list lifts the restriction
+	resp = send(http.MethodPatch, "/self/ip-allowlist", "192.0.2.10:4000", ipAllowlistRequest{})
+	require.True(t, resp["success"].(bool), resp["message"])
+	resp = send(http.MethodPost, "/login", "203.0.113.5:4000", credentials)
+	require.True(t, resp["success"].(bool), resp["message"])
+}
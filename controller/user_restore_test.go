@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestUserSoftDeleteAndRestore(t *testing.T) {
+	setupUserControllerTest(t)
+
+	hashed, err := common.Password2Hash("password123")
+	require.NoError(t, err)
+	user := &model.User{
+		Username:    "soft-deleted",
+		Password:    hashed,
+		DisplayName: "Soft Deleted",
+		GitHubId:    "soft-deleted-gh",
+		Role:        model.RoleCommonUser,
+		Status:      model.UserStatusEnabled,
+		AccessToken: "soft-deleted-access-token",
+		AffCode:     "sdel",
+	}
+	require.NoError(t, model.DB.Create(user).Error)
+	require.NoError(t, model.DB.Create(&model.Token{UserId: user.Id, Key: "soft-deleted-token-key", Name: "default"}).Error)
+	require.NoError(t, model.LOG_DB.Create(&model.Log{UserId: user.Id, Quota: 1000, Type: model.LogTypeConsume, CreatedAt: time.Now().Unix()}).Error)
+
+	router := gin.New()
+	withRole := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set(ctxkey.Role, model.RoleAdminUser)
+			handler(c)
+		}
+	}
+	router.DELETE("/api/user/:id", withRole(DeleteUser))
+	router.POST("/api/admin/users/:id/restore", withRole(RestoreUser))
+	send := func(method, target string) bool {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		var resp struct {
+			Success bool `json:"success"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Success
+	}
+	login := func() error {
+		return (&model.User{Username: "soft-deleted", Password: "password123"}).ValidateAndFill()
+	}
+
+	require.NoError(t, login())
+	require.False(t, send(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/restore", user.Id)))
+	require.True(t, send(http.MethodDelete, fmt.Sprintf("/api/user/%d", user.Id)))
+
+	// Hidden and locked out, the username and login identities are released
+	// and the logs are kept
+	users, err := model.GetAllUsers(0, 10, "", "", "")
+	require.NoError(t, err)
+	require.Empty(t, users)
+	require.Error(t, login())
+	require.False(t, model.IsUsernameAlreadyTaken("soft-deleted"))
+	require.False(t, model.IsGitHubIdAlreadyTaken("soft-deleted-gh"))
+	var logs int64
+	require.NoError(t, model.LOG_DB.Model(&model.Log{}).Where("user_id = ?", user.Id).Count(&logs).Error)
+	require.EqualValues(t, 1, logs)
+
+	require.True(t, send(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/restore", user.Id)))
+	restored, err := model.GetUserById(user.Id, false)
+	require.NoError(t, err)
+	require.Equal(t, model.UserStatusEnabled, restored.Status)
+	require.Equal(t, "soft-deleted", restored.Username)
+	require.Equal(t, "soft-deleted-gh", restored.GitHubId)
+	require.NoError(t, login())
+
+	// A released username taken in the meantime blocks the restore
+	require.True(t, send(http.MethodDelete, fmt.Sprintf("/api/user/%d", user.Id)))
+	require.NoError(t, model.DB.Create(&model.User{
+		Username:    "soft-deleted",
+		Password:    hashed,
+		Status:      model.UserStatusEnabled,
+		AccessToken: "soft-deleted-successor-token",
+		AffCode:     "sdsc",
+	}).Error)
+	require.False(t, send(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/restore", user.Id)))
+	deleted, err := model.GetDeletedUserById(user.Id)
+	require.NoError(t, err)
+	require.NotEqual(t, "soft-deleted", deleted.Username)
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	setupUserControllerTest(t)
+
+	expired := &model.User{Username: "expired", Password: "x", AccessToken: "expired-access-token", AffCode: "expd"}
+	recent := &model.User{Username: "recent", Password: "x", AccessToken: "recent-access-token", AffCode: "rcnt"}
+	require.NoError(t, model.DB.Create(expired).Error)
+	require.NoError(t, model.DB.Create(recent).Error)
+	require.NoError(t, model.DB.Create(&model.Token{UserId: expired.Id, Key: "expired-token-key", Name: "default"}).Error)
+	require.NoError(t, model.LOG_DB.Create(&model.Log{UserId: expired.Id, Quota: 1000, Type: model.LogTypeConsume}).Error)
+	require.NoError(t, model.DeleteUserById(expired.Id))
+	require.NoError(t, model.DeleteUserById(recent.Id))
+	require.NoError(t, model.DB.Unscoped().Model(&model.User{}).Where("id = ?", expired.Id).
+		Update("deleted_at", time.Now().AddDate(0, 0, -31)).Error)
+
+	purged, err := model.PurgeDeletedUsers(context.Background(), 30)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, purged)
+
+	var remaining []model.User
+	require.NoError(t, model.DB.Unscoped().Find(&remaining).Error)
+	require.Len(t, remaining, 1)
+	require.Equal(t, recent.Id, remaining[0].Id)
+
+	var tokens, logs int64
+	require.NoError(t, model.DB.Model(&model.Token{}).Where("user_id = ?", expired.Id).Count(&tokens).Error)
+	require.Zero(t, tokens)
+	require.NoError(t, model.LOG_DB.Model(&model.Log{}).Where("user_id = ?", expired.Id).Count(&logs).Error)
+	require.EqualValues(t, 1, logs)
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// RevokeUserSessions signs the user in the id path parameter out of every
+// session.
+func RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": invalidParameterMessage})
+		return
+	}
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	myRole := c.GetInt(ctxkey.Role)
+	if myRole <= user.Role && myRole != model.RoleRootUser {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "No permission to update user information with the same permission level or higher permission level",
+		})
+		return
+	}
+
+	ctx := gmw.Ctx(c)
+	if _, err = model.RevokeUserSessions(ctx, id); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	model.RecordAuditLog(ctx, c.GetInt(ctxkey.Id), model.AuditTargetUser, id, "revoke_sessions", nil, nil)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": ""})
+}
+
+// RevokeSelfSessions signs the current user out of every other session. The
+// session making the request stays signed in.
+func RevokeSelfSessions(c *gin.Context) {
+	userId := c.GetInt(ctxkey.Id)
+	version, err := model.RevokeUserSessions(gmw.Ctx(c), userId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err = keepCurrentSession(c, version); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": ""})
+}
+
+// keepCurrentSession moves the session making the request to the session
+// version a revocation left the user at, so revoking on behalf of the user
+// does not sign them out too. Requests authenticated by access token have no
+// session to keep.
+func keepCurrentSession(c *gin.Context, version int) error {
+	session := sessions.Default(c)
+	if session.Get("id") == nil {
+		return nil
+	}
+	session.Set("session_version", version)
+	return session.Save()
+}
+
+// keepCurrentSessionAfterRevoke is keepCurrentSession for callers that revoked
+// the user's sessions indirectly, such as by changing the password.
+func keepCurrentSessionAfterRevoke(c *gin.Context, userId int) error {
+	version, err := model.CacheGetUserSessionVersion(gmw.Ctx(c), userId)
+	if err != nil {
+		return err
+	}
+	return keepCurrentSession(c, version)
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/middleware"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestRevokeSessions(t *testing.T) {
+	testDB, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	hashed, err := common.Password2Hash("password123")
+	require.NoError(t, err)
+	user := &model.User{
+		Username:    "sessionuser",
+		Password:    hashed,
+		Role:        model.RoleCommonUser,
+		Status:      model.UserStatusEnabled,
+		AccessToken: "test-access-token-session",
+		AffCode:     "SESSION",
+	}
+	require.NoError(t, testDB.Create(user).Error)
+	// Other tests may have banned a user with the same id
+	model.UnbanUser(user.Id)
+
+	router := setupTestRouter()
+	router.POST("/login", Login)
+	router.GET("/self", middleware.UserAuth(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	router.POST("/self/revoke-sessions", middleware.UserAuth(), RevokeSelfSessions)
+
+	login := func() []*http.Cookie {
+		body, err := json.Marshal(LoginRequest{Username: "sessionuser", Password: "password123"})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Result().Cookies()
+	}
+	request := func(method, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first, second := login(), login()
+	require.Equal(t, http.StatusOK, request(http.MethodGet, "/self", first).Code)
+
+	// Revoking from the second session keeps it and signs the first out
+	w := request(http.MethodPost, "/self/revoke-sessions", second)
+	require.Equal(t, http.StatusOK, w.Code)
+	if refreshed := w.Result().Cookies(); len(refreshed) > 0 {
+		second = refreshed
+	}
+	require.Equal(t, http.StatusOK, request(http.MethodGet, "/self", second).Code)
+	require.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/self", first).Code)
+}
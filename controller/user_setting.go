@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// userSettingsResponse is a user's settings with the threshold in effect, the
+// system default when the user has not set one.
+type userSettingsResponse struct {
+	*model.UserSetting
+	EffectiveSpendingAlertThreshold int64 `json:"effective_spending_alert_threshold"`
+}
+
+func newUserSettingsResponse(setting *model.UserSetting) userSettingsResponse {
+	return userSettingsResponse{
+		UserSetting:                     setting,
+		EffectiveSpendingAlertThreshold: setting.SpendingAlertThresholdOrDefault(),
+	}
+}
+
+// GetUserSettings returns the settings of the current user.
+func GetUserSettings(c *gin.Context) {
+	setting, err := model.GetUserSetting(c.GetInt(ctxkey.Id))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    newUserSettingsResponse(setting),
+	})
+}
+
+// UpdateUserSettings changes the settings present in the body and keeps the
+// others. A null spending_alert_threshold restores the system default.
+func UpdateUserSettings(c *gin.Context) {
+	var payload map[string]json.RawMessage
+	if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": invalidParameterMessage,
+		})
+		return
+	}
+
+	setting, err := model.GetUserSetting(c.GetInt(ctxkey.Id))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if raw, ok := payload["spending_alert_threshold"]; ok {
+		var threshold *int64
+		if err := json.Unmarshal(raw, &threshold); err != nil || (threshold != nil && *threshold < 0) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "spending_alert_threshold must be a non-negative integer or null",
+			})
+			return
+		}
+		setting.SpendingAlertThreshold = threshold
+	}
+	if raw, ok := payload["email_alerts_enabled"]; ok {
+		if err := json.Unmarshal(raw, &setting.EmailAlertsEnabled); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "email_alerts_enabled must be a boolean",
+			})
+			return
+		}
+	}
+
+	if err := model.SaveUserSetting(setting); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    newUserSettingsResponse(setting),
+	})
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestUpdateUserSettings(t *testing.T) {
+	setupUserControllerTest(t)
+
+	user := &model.User{Username: "settings-user", Password: "x", AccessToken: "settings-access-token", AffCode: "stng"}
+	require.NoError(t, model.DB.Create(user).Error)
+
+	router := gin.New()
+	withUser := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set(ctxkey.Id, user.Id)
+			handler(c)
+		}
+	}
+	router.GET("/api/user/settings", withUser(GetUserSettings))
+	router.PATCH("/api/user/settings", withUser(UpdateUserSettings))
+
+	type settingsResponse struct {
+		Success bool `json:"success"`
+		Data    struct {
+			SpendingAlertThreshold          *int64 `json:"spending_alert_threshold"`
+			EmailAlertsEnabled              bool   `json:"email_alerts_enabled"`
+			EffectiveSpendingAlertThreshold int64  `json:"effective_spending_alert_threshold"`
+		} `json:"data"`
+	}
+	send := func(method, body string) (int, settingsResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/api/user/settings", strings.NewReader(body)))
+		var resp settingsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+
+	_, resp := send(http.MethodGet, "")
+	require.True(t, resp.Success)
+	require.Nil(t, resp.Data.SpendingAlertThreshold)
+	require.True(t, resp.Data.EmailAlertsEnabled)
+	require.Equal(t, config.QuotaRemindThreshold, resp.Data.EffectiveSpendingAlertThreshold)
+
+	_, resp = send(http.MethodPatch, `{"spending_alert_threshold":500000}`)
+	require.True(t, resp.Success)
+	require.EqualValues(t, 500000, resp.Data.EffectiveSpendingAlertThreshold)
+
+	// Omitted fields are kept
+	_, resp = send(http.MethodPatch, `{"email_alerts_enabled":false}`)
+	require.True(t, resp.Success)
+	require.False(t, resp.Data.EmailAlertsEnabled)
+	require.EqualValues(t, 500000, resp.Data.EffectiveSpendingAlertThreshold)
+
+	code, _ := send(http.MethodPatch, `{"spending_alert_threshold":-1}`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// null restores the system default
+	_, resp = send(http.MethodPatch, `{"spending_alert_threshold":null}`)
+	require.True(t, resp.Success)
+	require.Nil(t, resp.Data.SpendingAlertThreshold)
+	require.False(t, resp.Data.EmailAlertsEnabled)
+
+	stored, err := model.GetUserSetting(user.Id)
+	require.NoError(t, err)
+	require.Nil(t, stored.SpendingAlertThreshold)
+	require.False(t, stored.EmailAlertsEnabled)
+}
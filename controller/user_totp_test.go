@@ -422,3 +422,51 @@ func TestAdminDisableUserTotp(t *testing.T) {
 	assert.False(t, response["success"].(bool))
 	assert.Equal(t, "Invalid user ID", response["message"])
 }
+
+func TestLoginWithTotpBackupCode(t *testing.T) {
+	testDB, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// A user of its own keeps the TOTP rate limit of the other tests out of the way
+	hashed, err := common.Password2Hash("password123")
+	require.NoError(t, err)
+	user := &model.User{
+		Username:    "backupuser",
+		Password:    hashed,
+		Role:        model.RoleCommonUser,
+		Status:      model.UserStatusEnabled,
+		AccessToken: "test-access-token-backup",
+		AffCode:     "BACKUP",
+		TotpSecret:  "JBSWY3DPEHPK3PXP",
+	}
+	require.NoError(t, testDB.Create(user).Error)
+	codes, err := model.GenerateTotpBackupCodes(user.Id)
+	require.NoError(t, err)
+
+	router := setupTestRouter()
+	router.POST("/login", Login)
+	login := func(totpCode string) map[string]any {
+		body, err := json.Marshal(LoginRequest{Username: "backupuser", Password: "password123", TotpCode: totpCode})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	response := login(codes[0])
+	require.True(t, response["success"].(bool), response["message"])
+
+	// The used code is gone
+	stored, err := model.GetUserById(user.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, model.TotpBackupCodeCount-1, model.CountTotpBackupCodes(stored))
+	consumed, err := model.ConsumeTotpBackupCode(user.Id, codes[0])
+	require.NoError(t, err)
+	require.False(t, consumed)
+}
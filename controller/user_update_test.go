@@ -188,3 +188,55 @@ func TestUpdateUserEmailNullSkipsChange(t *testing.T) {
 	require.Equal(t, "existing@example.com", updated.Email)
 	require.Equal(t, int64(42), updated.Quota)
 }
+
+func TestUpdateUserBudgets(t *testing.T) {
+	setupUserControllerTest(t)
+
+	user := &model.User{
+		Username:     "budget-user",
+		Password:     "hashed-password",
+		DisplayName:  "Budget",
+		Group:        "default",
+		Status:       model.UserStatusEnabled,
+		WeeklyBudget: 7000,
+	}
+	require.NoError(t, model.DB.Create(user).Error)
+
+	router := gin.New()
+	router.PUT("/api/user/", func(c *gin.Context) {
+		c.Set(ctxkey.Role, model.RoleRootUser)
+		UpdateUser(c)
+	})
+	update := func(payload map[string]any) (success bool, message string) {
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPut, "/api/user/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Success, resp.Message
+	}
+
+	success, message := update(map[string]any{"id": user.Id, "daily_budget": 1000})
+	require.True(t, success, message)
+	updated, err := model.GetUserById(user.Id, true)
+	require.NoError(t, err)
+	require.EqualValues(t, 1000, updated.DailyBudget)
+	require.EqualValues(t, 7000, updated.WeeklyBudget)
+
+	success, _ = update(map[string]any{"id": user.Id, "weekly_budget": -1})
+	require.False(t, success)
+
+	success, message = update(map[string]any{"id": user.Id, "weekly_budget": 0})
+	require.True(t, success, message)
+	updated, err = model.GetUserById(user.Id, true)
+	require.NoError(t, err)
+	require.Zero(t, updated.WeeklyBudget)
+}
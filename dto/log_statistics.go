@@ -32,3 +32,36 @@ type LogStatisticByToken struct {
 	PromptTokens     int    `gorm:"column:prompt_tokens"`
 	CompletionTokens int    `gorm:"column:completion_tokens"`
 }
+
+// LogAggregateTotals holds the consume log metrics summed over one group.
+type LogAggregateTotals struct {
+	RequestCount     int64 `json:"request_count" gorm:"column:request_count"`
+	Quota            int64 `json:"quota" gorm:"column:quota"`
+	PromptTokens     int64 `json:"prompt_tokens" gorm:"column:prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens" gorm:"column:completion_tokens"`
+}
+
+// LogAggregateByModel captures consume log totals of one model.
+type LogAggregateByModel struct {
+	ModelName          string `json:"model_name" gorm:"column:model_name"`
+	LogAggregateTotals `gorm:"embedded"`
+}
+
+// LogAggregateByChannel captures consume log totals of one channel.
+type LogAggregateByChannel struct {
+	ChannelId          int `json:"channel_id" gorm:"column:channel_id"`
+	LogAggregateTotals `gorm:"embedded"`
+}
+
+// LogAggregateByUser captures consume log totals of one user.
+type LogAggregateByUser struct {
+	UserId             int    `json:"user_id" gorm:"column:user_id"`
+	Username           string `json:"username" gorm:"column:username"`
+	LogAggregateTotals `gorm:"embedded"`
+}
+
+// LogAggregateByDay captures consume log totals of one day (YYYY-MM-DD).
+type LogAggregateByDay struct {
+	Day                string `json:"day" gorm:"column:day"`
+	LogAggregateTotals `gorm:"embedded"`
+}
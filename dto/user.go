@@ -14,4 +14,9 @@ type UserAdminUpdatePayload struct {
 	Group       *string `json:"group"`
 	Role        *int    `json:"role"`
 	Status      *int    `json:"status"`
+	// DailyBudget and WeeklyBudget cap the user's spend per period, 0 removes the cap.
+	DailyBudget  *int64 `json:"daily_budget"`
+	WeeklyBudget *int64 `json:"weekly_budget"`
+	// IpAllowlist replaces the CIDR ranges logins must come from, "" removes the restriction.
+	IpAllowlist *string `json:"ip_allowlist"`
 }
@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"embed"
-	"encoding/base64"
 	"net/http"
 	"os"
 	"os/signal"
@@ -23,16 +22,20 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/blacklist"
 	"github.com/songquanpeng/one-api/common/client"
 	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/graceful"
 	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/statesync"
 	"github.com/songquanpeng/one-api/controller"
 	"github.com/songquanpeng/one-api/middleware"
 	"github.com/songquanpeng/one-api/model"
 	"github.com/songquanpeng/one-api/monitor"
 	"github.com/songquanpeng/one-api/relay"
 	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+	"github.com/songquanpeng/one-api/relay/billing"
+	rcontroller "github.com/songquanpeng/one-api/relay/controller"
 	"github.com/songquanpeng/one-api/router"
 )
 
@@ -55,6 +58,9 @@ func main() {
 	if config.GinMode != gin.DebugMode {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	if err := config.LoadTierCapabilities(); err != nil {
+		logger.Logger.Fatal("failed to load tier capabilities", zap.Error(err))
+	}
 
 	// check theme
 	logger.Logger.Info("using theme", zap.String("theme", config.Theme))
@@ -65,8 +71,15 @@ func main() {
 	// Initialize SQL Database
 	model.InitDB()
 	model.InitLogDB()
+	if err := blacklist.Init(model.DB); err != nil {
+		logger.Logger.Error("failed to load persisted bans", zap.Error(err))
+	}
 	model.StartTraceRetentionCleaner(ctx, config.TraceRetentionDays)
 	model.StartAsyncTaskRetentionCleaner(ctx, config.AsyncTaskRetentionDays)
+	model.StartUserRetentionCleaner(ctx, config.UserRetentionDays)
+	model.StartLogCompressor(ctx, config.LogCompressionDays)
+	model.StartLogRetentionWorker(ctx)
+	model.StartLoginLogRetentionWorker(ctx)
 
 	var err error
 	err = model.CreateRootAccountIfNeed()
@@ -85,9 +98,28 @@ func main() {
 	if err != nil {
 		logger.Logger.Fatal("failed to initialize Redis", zap.Error(err))
 	}
+	if err = blacklist.SyncBlacklistFromRedis(ctx); err != nil {
+		logger.Logger.Error("failed to sync blacklist from Redis", zap.Error(err))
+	}
+
+	// Initialize cross-region state sync
+	if err = statesync.Init(context.Background()); err != nil {
+		logger.Logger.Fatal("failed to initialize state sync", zap.Error(err))
+	}
+	if err = model.InitBillingEventPublisher(ctx); err != nil {
+		logger.Logger.Fatal("failed to initialize real-time billing events", zap.Error(err))
+	}
 
 	// Initialize options
 	model.InitOptionMap()
+	if err = model.LoadModelPricingOverrides(); err != nil {
+		logger.Logger.Fatal("failed to load model pricing overrides", zap.Error(err))
+	}
+	if config.ChannelConfigPath != "" {
+		if err = model.SyncFileChannels(config.ChannelConfigPath); err != nil {
+			logger.Logger.Fatal("failed to load channel config file", zap.Error(err))
+		}
+	}
 	if common.IsRedisEnabled() {
 		// for compatibility with old versions
 		config.MemoryCacheEnabled = true
@@ -100,6 +132,14 @@ func main() {
 		go model.SyncOptions(config.SyncFrequency)
 		go model.SyncChannelCache(config.SyncFrequency)
 	}
+	if config.SyncFrequency > 0 {
+		go model.SyncModelPricingOverrides(config.SyncFrequency)
+	}
+	rcontroller.StartBatchPoller(ctx)
+	controller.StartCostReportScheduler(ctx)
+	controller.StartBillingReconciliation(ctx)
+	controller.StartCostAnomalyDetection(ctx)
+	billing.StartAutoRefill(ctx)
 	if config.ChannelTestFrequency > 0 {
 		go controller.AutomaticallyTestChannels(config.ChannelTestFrequency)
 	}
@@ -107,6 +147,11 @@ func main() {
 		logger.Logger.Info("batch update enabled with interval " + strconv.Itoa(config.BatchUpdateInterval) + "s")
 		model.InitBatchUpdater()
 	}
+	model.InitTokenCountAccuracyFlusher()
+	if config.AsyncLogEnabled {
+		logger.Logger.Info("async log writer enabled with " + strconv.Itoa(config.AsyncLogWorkers) + " workers")
+		model.InitAsyncLogWriter()
+	}
 	if config.EnableMetric {
 		logger.Logger.Info("metric enabled, will disable channel if too much request failed")
 	}
@@ -132,6 +177,11 @@ func main() {
 
 	openai.InitTokenEncoders()
 	client.Init()
+	if config.ChannelPrewarmOnStart {
+		// Runs in the background so a slow or unreachable upstream never
+		// delays startup
+		go controller.PrewarmChannelConnections(ctx)
+	}
 
 	// Initialize global pricing manager
 	relay.InitializeGlobalPricing()
@@ -166,14 +216,11 @@ func main() {
 	// middleware.SetUpLogger(server)
 
 	// Initialize session store
-	sessionSecret, err := base64.StdEncoding.DecodeString(config.SessionSecret)
-	var sessionStore cookie.Store
+	sessionKeyPairs, err := common.SessionKeyPairs()
 	if err != nil {
-		logger.Logger.Info("session secret is not base64 encoded, using raw value instead")
-		sessionStore = cookie.NewStore([]byte(config.SessionSecret))
-	} else {
-		sessionStore = cookie.NewStore(sessionSecret, sessionSecret)
+		logger.Logger.Fatal("failed to initialize session keys", zap.Error(err))
 	}
+	sessionStore := cookie.NewStore(sessionKeyPairs...)
 
 	cookieSecure := false
 	if config.EnableCookieSecure {
@@ -212,6 +259,9 @@ func main() {
 		}
 	}()
 
+	// Reload runtime config from the database on SIGHUP
+	controller.HandleReloadSignal(ctx)
+
 	// Handle shutdown signals
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
@@ -231,6 +281,9 @@ func main() {
 	if config.BatchUpdateEnabled {
 		model.StopBatchUpdater(shutdownCtx)
 	}
+	model.StopTokenCountAccuracyFlusher(shutdownCtx)
+	// Flush queued log entries and stop the log workers before the database is closed
+	model.StopAsyncLogWriter(shutdownCtx)
 
 	// Drain critical background tasks (billing, refunds, etc.)
 	if err := graceful.Drain(shutdownCtx); err != nil {
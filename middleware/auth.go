@@ -26,6 +26,7 @@ import (
 
 	"github.com/Laisky/errors/v2"
 	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 
@@ -33,6 +34,7 @@ import (
 	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/network"
 	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
 )
 
 // authHelper is a shared authentication helper function that validates user sessions or access tokens.
@@ -47,9 +49,10 @@ func authHelper(c *gin.Context, minRole int) {
 	role := session.Get("role")
 	id := session.Get("id")
 	status := session.Get("status")
+	fromSession := username != nil
 
 	// First, try to authenticate using session data (cookies)
-	if username == nil {
+	if !fromSession {
 		gmw.GetLogger(c).Info("no user session found, try to use access token")
 		// If no session exists, try to authenticate using the Authorization header
 		accessToken := c.Request.Header.Get("Authorization")
@@ -84,6 +87,14 @@ func authHelper(c *gin.Context, minRole int) {
 		return
 	}
 
+	// Reject sessions issued before the user's sessions were revoked
+	if fromSession && !sessionVersionCurrent(c, id.(int), session.Get("session_version")) {
+		respondAuthError(c, http.StatusUnauthorized, "Session has been revoked, please log in again")
+		session.Clear()
+		_ = session.Save()
+		return
+	}
+
 	// Check if user has sufficient role permissions
 	if role.(int) < minRole {
 		respondAuthError(c, http.StatusForbidden, "No permission to perform this operation, insufficient permissions")
@@ -97,6 +108,19 @@ func authHelper(c *gin.Context, minRole int) {
 	c.Next()
 }
 
+// sessionVersionCurrent reports whether a session carrying sessionVersion,
+// nil for sessions issued before versioning, matches the user's current session
+// version, see model.RevokeUserSessions.
+func sessionVersionCurrent(c *gin.Context, userId int, sessionVersion any) bool {
+	current, err := model.CacheGetUserSessionVersion(gmw.Ctx(c), userId)
+	if err != nil {
+		gmw.GetLogger(c).Error("failed to get user session version", zap.Int("user_id", userId), zap.Error(err))
+		return false
+	}
+	version, _ := sessionVersion.(int)
+	return version == current
+}
+
 // UserAuth returns a middleware function that requires basic user authentication.
 // This allows access to any logged-in user (common users, admins, and root users).
 // Use this for endpoints that require authentication but don't need special privileges.
@@ -131,6 +155,7 @@ func RootAuth() func(c *gin.Context) {
 //   - Token validity and expiration
 //   - IP subnet restrictions (if configured)
 //   - Model access permissions
+//   - Endpoint scopes (if configured)
 //   - Quota limits
 //   - Channel-specific access (for admin users)
 //
@@ -139,12 +164,7 @@ func TokenAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		ctx := gmw.Ctx(c)
 		// Parse the token key from the request (could include channel specification)
-		// Parse the token key from the request (could include channel specification)
-		parts := GetTokenKeyParts(c)
-		key := parts[0]
-
-		// Validate the API token against the database
-		token, err := model.ValidateUserToken(ctx, key)
+		token, parts, err := authenticateTokenHeaders(c)
 		if err != nil {
 			AbortWithError(c, http.StatusUnauthorized, err)
 			return
@@ -186,12 +206,21 @@ func TokenAuth() func(c *gin.Context) {
 			}
 		}
 
+		// Check the requested endpoint is within the token's scopes
+		if err := tokenScopeError(token, relaymode.GetByPath(c.Request.URL.Path)); err != nil {
+			AbortWithError(c, http.StatusForbidden, err)
+			return
+		}
+
 		// Set token-related context for downstream handlers
 		c.Set(ctxkey.Id, token.UserId)
 		c.Set(ctxkey.TokenId, token.Id)
+		c.Set(ctxkey.TokenKey, parts[0])
 		c.Set(ctxkey.TokenName, token.Name)
 		c.Set(ctxkey.TokenQuota, token.RemainQuota)
 		c.Set(ctxkey.TokenQuotaUnlimited, token.UnlimitedQuota)
+		c.Set(ctxkey.TokenRpmLimit, model.EffectiveTokenRpmLimit(token.RpmLimit))
+		c.Set(ctxkey.TokenTpmLimit, model.EffectiveTokenTpmLimit(token.TpmLimit))
 
 		// Handle channel-specific routing (admin feature)
 		// Format: token_key:channel_id allows admins to specify which channel to use
@@ -225,6 +254,32 @@ func TokenAuth() func(c *gin.Context) {
 	}
 }
 
+// authenticateTokenHeaders validates the tokens found in the configured token
+// headers in order and returns the first valid one with its key parts. When
+// none is valid, the error for the first candidate is returned.
+func authenticateTokenHeaders(c *gin.Context) (*model.Token, []string, error) {
+	ctx := gmw.Ctx(c)
+	candidates := GetTokenKeyCandidates(c)
+	if len(candidates) == 0 {
+		token, err := model.ValidateUserToken(ctx, "")
+		return token, []string{""}, err
+	}
+
+	var firstErr error
+	for _, candidate := range candidates {
+		token, err := model.ValidateUserToken(ctx, candidate.Parts[0])
+		if err == nil {
+			gmw.GetLogger(c).Debug("api token authenticated", zap.String("header", candidate.Header))
+			return token, candidate.Parts, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return nil, nil, firstErr
+}
+
 // shouldCheckModel determines whether the current endpoint requires model validation.
 // This helper function checks if the request path corresponds to AI/ML API endpoints
 // that need to validate which AI model the user is trying to access.
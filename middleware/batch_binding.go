@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+// BatchFilePurpose is the upload purpose of Batch API input files.
+const BatchFilePurpose = "batch"
+
+// BindBatchChannel resolves the model and channel of Batch API requests before
+// channel distribution. Batch input uploads are validated and routed by the
+// model they use; batch creation, retrieval, cancellation and result downloads
+// are pinned to the channel holding the input file or the batch. Files and
+// batches of other users are reported as missing.
+func BindBatchChannel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.BatchAPIEnabled || c.Request == nil {
+			c.Next()
+			return
+		}
+
+		path := c.Request.URL.Path
+		switch {
+		case c.Request.Method == http.MethodPost && path == "/v1/files":
+			bindBatchInputUpload(c)
+		case c.Request.Method == http.MethodPost && path == "/v1/batches":
+			bindBatchCreation(c)
+		case strings.HasPrefix(path, "/v1/batches/"):
+			bindBatch(c, c.Param("batch_id"))
+		case c.Request.Method == http.MethodGet && strings.HasPrefix(path, "/v1/files/") && strings.HasSuffix(path, "/content"):
+			bindBatchFile(c, c.Param("id"))
+		}
+		if !c.IsAborted() {
+			c.Next()
+		}
+	}
+}
+
+// bindBatchInputUpload validates an uploaded batch input file and selects a
+// channel for the model it uses.
+func bindBatchInputUpload(c *gin.Context) {
+	if c.PostForm("purpose") != BatchFilePurpose {
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, errors.Wrap(err, "batch input file is required"))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, errors.Wrap(err, "open batch input file"))
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, errors.Wrap(err, "read batch input file"))
+		return
+	}
+
+	input, err := relaymodel.ParseBatchInput(data, config.BatchAPIMaxRequests)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, errors.Wrap(err, "invalid batch input file"))
+		return
+	}
+	if models := c.GetString(ctxkey.AvailableModels); models != "" && !isModelInList(input.Model, models) {
+		AbortWithError(c, http.StatusForbidden, errors.Errorf("This API key does not have permission to use the model: %s", input.Model))
+		return
+	}
+	c.Set(ctxkey.RequestModel, input.Model)
+	c.Set(ctxkey.BatchInput, input)
+}
+
+// bindBatchCreation pins batch creation to the channel its input file was
+// uploaded to.
+func bindBatchCreation(c *gin.Context) {
+	var request struct {
+		InputFileID string `json:"input_file_id"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		AbortWithError(c, http.StatusBadRequest, errors.Wrap(err, "parse batch request"))
+		return
+	}
+	if request.InputFileID == "" {
+		AbortWithError(c, http.StatusBadRequest, errors.New("input_file_id is required"))
+		return
+	}
+
+	binding, err := model.GetAsyncTaskBindingByTaskID(gmw.Ctx(c), request.InputFileID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		AbortWithError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err != nil || binding.TaskType != model.AsyncTaskTypeBatchInputFile || binding.UserID != c.GetInt(ctxkey.Id) {
+		AbortWithError(c, http.StatusNotFound, errors.Errorf("batch input file %s not found", request.InputFileID))
+		return
+	}
+	c.Set(ctxkey.SpecificChannelId, binding.ChannelID)
+	c.Set(ctxkey.RequestModel, binding.OriginModel)
+}
+
+// bindBatch pins requests for a batch to the channel that runs it.
+func bindBatch(c *gin.Context, batchId string) {
+	batch, err := model.GetRelayBatch(gmw.Ctx(c), batchId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		AbortWithError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err != nil || batch.UserId != c.GetInt(ctxkey.Id) {
+		AbortWithError(c, http.StatusNotFound, errors.Errorf("batch %s not found", batchId))
+		return
+	}
+	pinBatchChannel(c, batch)
+}
+
+// bindBatchFile pins result downloads to the channel that ran the batch
+// producing the file.
+func bindBatchFile(c *gin.Context, fileId string) {
+	batch, err := model.GetRelayBatchByFileId(gmw.Ctx(c), fileId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		AbortWithError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err != nil || batch.UserId != c.GetInt(ctxkey.Id) {
+		AbortWithError(c, http.StatusNotFound, errors.Errorf("file %s not found", fileId))
+		return
+	}
+	pinBatchChannel(c, batch)
+}
+
+func pinBatchChannel(c *gin.Context, batch *model.RelayBatch) {
+	c.Set(ctxkey.SpecificChannelId, batch.ChannelId)
+	c.Set(ctxkey.RequestModel, batch.OriginModel)
+	gmw.GetLogger(c).Debug("batch channel resolved",
+		zap.String("batch_id", batch.BatchId),
+		zap.Int("channel_id", batch.ChannelId))
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	dbmodel "github.com/songquanpeng/one-api/model"
+)
+
+func setupBatchBindingTest(t *testing.T, userId int) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&dbmodel.AsyncTaskBinding{}, &dbmodel.RelayBatch{}))
+	originalDB := dbmodel.DB
+	dbmodel.DB = db
+	originalEnabled := config.BatchAPIEnabled
+	config.BatchAPIEnabled = true
+	t.Cleanup(func() {
+		dbmodel.DB = originalDB
+		config.BatchAPIEnabled = originalEnabled
+	})
+
+	require.NoError(t, dbmodel.CreateRelayBatch(context.Background(), &dbmodel.RelayBatch{
+		BatchId:      "batch_1",
+		UserId:       10,
+		ChannelId:    5,
+		OriginModel:  "gpt-4o-mini",
+		OutputFileId: "file-out",
+	}))
+
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set(ctxkey.Id, userId)
+		c.Next()
+	}, BindBatchChannel())
+	ok := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"channel_id": c.GetInt(ctxkey.SpecificChannelId),
+			"model":      c.GetString(ctxkey.RequestModel),
+		})
+	}
+	engine.POST("/v1/files", ok)
+	engine.GET("/v1/files/:id/content", ok)
+	engine.GET("/v1/batches/:batch_id", ok)
+	return engine
+}
+
+func TestBindBatchChannelPinsOwnBatch(t *testing.T) {
+	engine := setupBatchBindingTest(t, 10)
+
+	for _, path := range []string{"/v1/batches/batch_1", "/v1/files/file-out/content"} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code, path)
+		require.JSONEq(t, `{"channel_id":5,"model":"gpt-4o-mini"}`, w.Body.String(), path)
+	}
+}
+
+func TestBindBatchChannelHidesOtherUsersBatches(t *testing.T) {
+	engine := setupBatchBindingTest(t, 11)
+
+	for _, path := range []string{"/v1/batches/batch_1", "/v1/files/file-out/content", "/v1/batches/missing"} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
+
+func TestBindBatchChannelValidatesInputUpload(t *testing.T) {
+	engine := setupBatchBindingTest(t, 10)
+
+	upload := func(content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		require.NoError(t, writer.WriteField("purpose", "batch"))
+		part, err := writer.CreateFormFile("file", "input.jsonl")
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		req := httptest.NewRequest(http.MethodPost, "/v1/files", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload(`{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini"}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"channel_id":0,"model":"gpt-4o-mini"}`, w.Body.String())
+
+	w = upload(`{"custom_id":"a","method":"GET","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini"}}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
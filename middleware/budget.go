@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// ErrorTypeBudgetExceeded is the error type returned when the caller has spent
+// their daily or weekly budget.
+const ErrorTypeBudgetExceeded = "budget_exceeded"
+
+// BudgetResetHeader carries the Unix time in seconds at which an exhausted
+// user budget resets.
+const BudgetResetHeader = "X-Budget-Reset-At"
+
+// UserBudget returns a middleware that rejects relay requests with 429 once
+// the caller has exhausted their daily or weekly budget, see
+// model.CheckUserBudget. Requests pass through when the budget cannot be
+// checked, so a database hiccup does not block relaying.
+func UserBudget() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId := c.GetInt(ctxkey.Id)
+		allowed, resetAt, err := model.CheckUserBudget(gmw.Ctx(c), userId, time.Now())
+		if err != nil {
+			gmw.GetLogger(c).Warn("failed to check user budget", zap.Int("user_id", userId), zap.Error(err))
+			c.Next()
+			return
+		}
+		if allowed {
+			c.Next()
+			return
+		}
+
+		gmw.GetLogger(c).Info("request rejected by user budget",
+			zap.Int("user_id", userId),
+			zap.Int64("reset_at", resetAt))
+		c.Header(BudgetResetHeader, strconv.FormatInt(resetAt, 10))
+		message := fmt.Sprintf("budget exhausted, resets at %s", time.Unix(resetAt, 0).UTC().Format(time.RFC3339))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": gin.H{
+				"message": helper.MessageWithRequestId(message, c.GetString(helper.RequestIdKey)),
+				"type":    ErrorTypeBudgetExceeded,
+			},
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/model"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+// CapabilityNegotiator decides during routing whether a channel can serve the
+// chat completion of the current request, by negotiating the features the
+// request uses against the channel's capability map. Other routes are never
+// negotiated. The request body is only decoded once a candidate channel
+// declares capabilities for the model.
+type CapabilityNegotiator struct {
+	c           *gin.Context
+	request     *relaymodel.GeneralOpenAIRequest
+	decoded     bool
+	unsupported []string
+}
+
+// NewCapabilityNegotiator returns a negotiator for the request of c.
+func NewCapabilityNegotiator(c *gin.Context) *CapabilityNegotiator {
+	return &CapabilityNegotiator{c: c}
+}
+
+// Accepts reports whether channel supports every capability the request needs
+// for modelName. Requests that cannot be negotiated are accepted.
+func (n *CapabilityNegotiator) Accepts(channel *model.Channel, modelName string) bool {
+	if len(channel.Capabilities(modelName)) == 0 {
+		return true
+	}
+	request := n.chatRequest()
+	if request == nil {
+		return true
+	}
+	if request.Model != modelName {
+		mapped := *request
+		mapped.Model = modelName
+		request = &mapped
+	}
+
+	supported, unsupported, err := model.NegotiateCapabilities(gmw.Ctx(n.c), channel, request)
+	if err != nil {
+		gmw.GetLogger(n.c).Debug("skip capability negotiation", zap.Error(err))
+		return true
+	}
+	if !supported {
+		n.unsupported = unsupported
+	}
+	return supported
+}
+
+// Unsupported returns the capabilities missing on the last rejected channel.
+func (n *CapabilityNegotiator) Unsupported() []string {
+	return n.unsupported
+}
+
+// chatRequest decodes the chat completion request once and returns it, or nil
+// when the request is not a JSON chat completion.
+func (n *CapabilityNegotiator) chatRequest() *relaymodel.GeneralOpenAIRequest {
+	if n.decoded {
+		return n.request
+	}
+	n.decoded = true
+	if relaymode.GetByPath(n.c.Request.URL.Path) != relaymode.ChatCompletions {
+		return nil
+	}
+
+	lg := gmw.GetLogger(n.c)
+	body, err := common.GetRequestBody(n.c)
+	if err != nil {
+		lg.Debug("skip capability negotiation: read request body", zap.Error(err))
+		return nil
+	}
+	n.c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	request := &relaymodel.GeneralOpenAIRequest{}
+	if err = json.Unmarshal(body, request); err != nil {
+		lg.Debug("skip capability negotiation: decode chat request", zap.Error(err))
+		return nil
+	}
+	n.request = request
+	return n.request
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+func TestDistributeRoutesByCapabilities(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, cleanup := setupDistributorTestDB(t)
+	defer cleanup()
+
+	originalMemoryCache := config.MemoryCacheEnabled
+	config.MemoryCacheEnabled = false
+	defer func() { config.MemoryCacheEnabled = originalMemoryCache }()
+
+	user := &model.User{Id: 51, Username: "vision-user", Password: "hashed", Group: "default", Status: model.UserStatusEnabled}
+	require.NoError(t, db.Create(user).Error)
+
+	priority := int64(100)
+	textOnlyConfigs := `{"gpt-4o":{"ratio":1,"features":{"supports_vision":false}}}`
+	textOnly := &model.Channel{
+		Id: 510, Name: "text-only", Type: channeltype.OpenAI, Models: "gpt-4o", Group: "default",
+		Status: model.ChannelStatusEnabled, Priority: &priority, ModelConfigs: &textOnlyConfigs,
+	}
+	vision := &model.Channel{
+		Id: 511, Name: "vision", Type: channeltype.OpenAI, Models: "gpt-4o", Group: "default",
+		Status: model.ChannelStatusEnabled, Priority: &priority,
+	}
+	for _, channel := range []*model.Channel{textOnly, vision} {
+		require.NoError(t, db.Create(channel).Error)
+		require.NoError(t, channel.AddAbilities())
+	}
+
+	distribute := func(body string) (*gin.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = req
+		c.Set(ctxkey.Id, user.Id)
+		c.Set(ctxkey.RequestModel, "gpt-4o")
+		gmw.SetLogger(c, logger.Logger)
+		Distribute()(c)
+		return c, rec
+	}
+
+	visionRequest := `{"model":"gpt-4o","messages":[{"role":"user","content":[` +
+		`{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}]}`
+	for range 30 {
+		c, _ := distribute(visionRequest)
+		require.False(t, c.IsAborted())
+		require.Equal(t, vision.Id, c.GetInt(ctxkey.ChannelId))
+	}
+
+	// Text requests may use either channel
+	selected := map[int]bool{}
+	for range 30 {
+		c, _ := distribute(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`)
+		require.False(t, c.IsAborted())
+		selected[c.GetInt(ctxkey.ChannelId)] = true
+	}
+	require.True(t, selected[textOnly.Id])
+
+	// Without a capable channel the request is rejected with the missing capability
+	require.NoError(t, db.Model(vision).Update("status", model.ChannelStatusManuallyDisabled).Error)
+	require.NoError(t, db.Model(&model.Ability{}).Where("channel_id = ?", vision.Id).Update("enabled", false).Error)
+	c, rec := distribute(visionRequest)
+	require.True(t, c.IsAborted())
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "supports_vision")
+}
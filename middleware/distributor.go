@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Laisky/errors/v2"
 	gmw "github.com/Laisky/gin-middlewares/v7"
@@ -50,20 +51,33 @@ func Distribute() func(c *gin.Context) {
 			}
 		} else {
 			requestModel = c.GetString(ctxkey.RequestModel)
+			negotiator := NewCapabilityNegotiator(c)
 			selectChannel := func(ignoreFirstPriority bool, exclude map[int]bool) (*model.Channel, error) {
 				for {
 					candidate, err := model.CacheGetRandomSatisfiedChannelExcluding(userGroup, requestModel, ignoreFirstPriority, exclude, false)
 					if err != nil {
 						return nil, errors.Wrap(err, "select channel from cache")
 					}
-					if requestModel == "" || candidate.SupportsModel(requestModel) {
-						return candidate, nil
+					if requestModel != "" && !candidate.SupportsModel(requestModel) {
+						exclude[candidate.Id] = true
+						lg.Warn("channel skipped - does not support requested model",
+							zap.Int("channel_id", candidate.Id),
+							zap.String("channel_name", candidate.Name),
+							zap.String("requested_model", requestModel))
+						continue
 					}
-					exclude[candidate.Id] = true
-					lg.Warn("channel skipped - does not support requested model",
-						zap.Int("channel_id", candidate.Id),
-						zap.String("channel_name", candidate.Name),
-						zap.String("requested_model", requestModel))
+					if !negotiator.Accepts(candidate, requestModel) {
+						exclude[candidate.Id] = true
+						continue
+					}
+					if !model.AllowChannelCircuit(ctx, candidate.Id, requestModel) {
+						exclude[candidate.Id] = true
+						lg.Debug("channel skipped - circuit breaker open",
+							zap.Int("channel_id", candidate.Id),
+							zap.String("requested_model", requestModel))
+						continue
+					}
+					return candidate, nil
 				}
 			}
 
@@ -74,6 +88,12 @@ func Distribute() func(c *gin.Context) {
 				lg.Info(fmt.Sprintf("No highest priority channels available for model %s in group %s, trying lower priority channels", requestModel, userGroup))
 				channel, err = selectChannel(true, exclude)
 				if err != nil {
+					if unsupported := negotiator.Unsupported(); len(unsupported) > 0 {
+						message := fmt.Sprintf("No channel for Model %s under Group %s supports the requested features: %s",
+							requestModel, userGroup, strings.Join(unsupported, ", "))
+						AbortWithError(c, http.StatusBadRequest, errors.New(message))
+						return
+					}
 					message := fmt.Sprintf("No available channels for Model %s under Group %s", requestModel, userGroup)
 					AbortWithError(c, http.StatusServiceUnavailable, errors.New(message))
 					return
@@ -101,6 +121,7 @@ func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, mode
 	lg.Info(fmt.Sprintf("set channel %s ratio to %f", channel.Name, minimalRatio))
 	c.Set(ctxkey.ChannelRatio, minimalRatio)
 	c.Set(ctxkey.ChannelModel, channel)
+	c.Set(ctxkey.ChannelSelectedAt, time.Now().UTC())
 
 	// generate an unique cost id for each request
 	if _, ok := c.Get(ctxkey.RequestId); !ok {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/Laisky/errors/v2"
 	gmw "github.com/Laisky/gin-middlewares/v7"
@@ -327,3 +328,72 @@ func TestDistributeAutoSkipsUnsupportedChannel(t *testing.T) {
 	selectedChannelId := c.GetInt(ctxkey.ChannelId)
 	assert.Equal(t, goodChannel.Id, selectedChannelId, "should select the channel that still supports the model")
 }
+
+func TestDistributeSkipsChannelWithOpenCircuit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, cleanup := setupDistributorTestDB(t)
+	defer cleanup()
+	require.NoError(t, db.AutoMigrate(&model.Log{}))
+
+	originalLogDB := model.LOG_DB
+	originalMemoryCache := config.MemoryCacheEnabled
+	originalThreshold := config.CircuitBreakerFailureThreshold
+	originalDelay := config.CircuitBreakerHalfOpenDelay
+	model.LOG_DB = db
+	config.MemoryCacheEnabled = false
+	config.CircuitBreakerFailureThreshold = 1
+	config.CircuitBreakerHalfOpenDelay = time.Hour
+	defer func() {
+		model.LOG_DB = originalLogDB
+		config.MemoryCacheEnabled = originalMemoryCache
+		config.CircuitBreakerFailureThreshold = originalThreshold
+		config.CircuitBreakerHalfOpenDelay = originalDelay
+	}()
+
+	user := &model.User{Id: 52, Username: "circuit-user", Password: "hashed", Group: "default", Status: model.UserStatusEnabled}
+	require.NoError(t, db.Create(user).Error)
+
+	priority := int64(100)
+	broken := &model.Channel{
+		Id: 520, Name: "broken", Type: channeltype.OpenAI, Models: "gpt-4o", Group: "default",
+		Status: model.ChannelStatusEnabled, Priority: &priority,
+	}
+	healthy := &model.Channel{
+		Id: 521, Name: "healthy", Type: channeltype.OpenAI, Models: "gpt-4o", Group: "default",
+		Status: model.ChannelStatusEnabled, Priority: &priority,
+	}
+	for _, channel := range []*model.Channel{broken, healthy} {
+		require.NoError(t, db.Create(channel).Error)
+		require.NoError(t, channel.AddAbilities())
+	}
+
+	ctx := t.Context()
+	model.RecordChannelCircuitFailure(ctx, broken.Id, "gpt-4o")
+	defer model.RecordChannelCircuitSuccess(ctx, broken.Id, "gpt-4o")
+
+	distribute := func() (*gin.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"gpt-4o"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = req
+		c.Set(ctxkey.Id, user.Id)
+		c.Set(ctxkey.RequestModel, "gpt-4o")
+		gmw.SetLogger(c, logger.Logger)
+		Distribute()(c)
+		return c, rec
+	}
+
+	for range 30 {
+		c, _ := distribute()
+		require.False(t, c.IsAborted())
+		require.Equal(t, healthy.Id, c.GetInt(ctxkey.ChannelId))
+	}
+
+	// With every circuit open no channel is available
+	model.RecordChannelCircuitFailure(ctx, healthy.Id, "gpt-4o")
+	defer model.RecordChannelCircuitSuccess(ctx, healthy.Id, "gpt-4o")
+	c, rec := distribute()
+	require.True(t, c.IsAborted())
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/blacklist"
+)
+
+// RejectBannedIP aborts requests whose client IP is in the blacklist with 403
+// Forbidden. Relay routes run it before the token is looked up.
+func RejectBannedIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rejectBannedIP(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// withIPBlacklist rejects banned client IPs before applying limiter, even when
+// rate limiting is disabled.
+func withIPBlacklist(limiter gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rejectBannedIP(c) {
+			return
+		}
+		limiter(c)
+	}
+}
+
+// rejectBannedIP aborts the request when its client IP is banned and reports
+// whether it did.
+func rejectBannedIP(c *gin.Context) bool {
+	if !blacklist.IsIPBanned(c.ClientIP()) {
+		return false
+	}
+	AbortWithError(c, http.StatusForbidden, errors.New("your IP address has been banned"))
+	return true
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/blacklist"
+)
+
+func TestRejectBannedIPRunsBeforeTokenLookup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const bannedIP = "198.51.100.23"
+	blacklist.BanIP(bannedIP, 0)
+	t.Cleanup(func() { blacklist.UnbanIP(bannedIP) })
+
+	tokenLookups := 0
+	router := gin.New()
+	router.POST("/v1/chat/completions", RejectBannedIP(), func(c *gin.Context) {
+		tokenLookups++
+		c.Status(http.StatusOK)
+	})
+
+	for ip, status := range map[string]int{bannedIP: http.StatusForbidden, "198.51.100.24": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		req.RemoteAddr = ip + ":12345"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, status, w.Code, ip)
+	}
+	require.Equal(t, 1, tokenLookups)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"strings"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+)
+
+// OverrideLanguageHeader lets callers pin the response language explicitly.
+// It takes precedence over the standard Accept-Language header.
+const OverrideLanguageHeader = "X-OneApi-Override-Language"
+
+// InjectResponseLanguage returns a middleware that forwards the caller's preferred
+// response language to language-aware upstream models.
+//
+// The language is taken from X-OneApi-Override-Language, falling back to the first
+// tag of Accept-Language. When the requested model is listed in
+// config.LanguageAwareModels, the tag is stored under ctxkey.ResponseLanguage and
+// the text relay sets it as the request's `language` field unless the caller
+// already sent one. Requests for other models, or without a language preference,
+// pass through untouched.
+func InjectResponseLanguage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		language := preferredLanguage(c)
+		modelName := c.GetString(ctxkey.RequestModel)
+		if language != "" && config.IsLanguageAwareModel(modelName) {
+			gmw.GetLogger(c).Debug("forward response language",
+				zap.String("language", language),
+				zap.String("model", modelName))
+			c.Set(ctxkey.ResponseLanguage, language)
+		}
+		c.Next()
+	}
+}
+
+// preferredLanguage resolves the caller's preferred language tag. The explicit
+// override header wins; otherwise the first non-wildcard Accept-Language entry
+// is used with its quality weight stripped. It returns "" when none is set.
+func preferredLanguage(c *gin.Context) string {
+	if override := strings.TrimSpace(c.GetHeader(OverrideLanguageHeader)); override != "" {
+		return override
+	}
+
+	for entry := range strings.SplitSeq(c.GetHeader("Accept-Language"), ",") {
+		tag, _, _ := strings.Cut(entry, ";")
+		if tag = strings.TrimSpace(tag); tag != "" && tag != "*" {
+			return tag
+		}
+	}
+
+	return ""
+}
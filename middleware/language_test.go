@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+)
+
+// runLanguageMiddleware sends a request through InjectResponseLanguage for the
+// given model and headers. It returns the language recorded for the relay and
+// checks that the body reaches the downstream handler unchanged.
+func runLanguageMiddleware(t *testing.T, modelName string, headers map[string]string) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	body := []byte(`{"model":"` + modelName + `","seed":9007199254740993,"messages":[{"role":"user","content":"hi"}]}`)
+	var language string
+	r := gin.New()
+	r.POST("/v1/chat/completions",
+		func(c *gin.Context) { c.Set(ctxkey.RequestModel, modelName); c.Next() },
+		InjectResponseLanguage(),
+		func(c *gin.Context) {
+			received, err := io.ReadAll(c.Request.Body)
+			require.NoError(t, err)
+			require.Equal(t, body, received)
+			language = c.GetString(ctxkey.ResponseLanguage)
+			c.Status(http.StatusOK)
+		})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	return language
+}
+
+func TestInjectResponseLanguage_OnlyForAllowlistedModels(t *testing.T) {
+	old := config.LanguageAwareModels
+	config.LanguageAwareModels = map[string]struct{}{"gemini-2.5-pro": {}}
+	defer func() { config.LanguageAwareModels = old }()
+
+	headers := map[string]string{"Accept-Language": "zh-CN,zh;q=0.9,en;q=0.8"}
+
+	require.Equal(t, "zh-CN", runLanguageMiddleware(t, "gemini-2.5-pro", headers))
+	require.Empty(t, runLanguageMiddleware(t, "gpt-4o", headers))
+}
+
+func TestInjectResponseLanguage_OverrideHeaderWins(t *testing.T) {
+	old := config.LanguageAwareModels
+	config.LanguageAwareModels = map[string]struct{}{"gemini-2.5-pro": {}}
+	defer func() { config.LanguageAwareModels = old }()
+
+	language := runLanguageMiddleware(t, "gemini-2.5-pro", map[string]string{
+		"Accept-Language":      "zh-CN",
+		OverrideLanguageHeader: "ja-JP",
+	})
+	require.Equal(t, "ja-JP", language)
+}
+
+func TestInjectResponseLanguage_NoPreference(t *testing.T) {
+	old := config.LanguageAwareModels
+	config.LanguageAwareModels = map[string]struct{}{"gemini-2.5-pro": {}}
+	defer func() { config.LanguageAwareModels = old }()
+
+	require.Empty(t, runLanguageMiddleware(t, "gemini-2.5-pro", map[string]string{"Accept-Language": "*"}))
+}
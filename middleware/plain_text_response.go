@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+)
+
+const plainTextContentType = "text/plain; charset=utf-8"
+
+// PlainTextResponse returns a middleware that answers chat completions with
+// the bare assistant text when the client prefers text/plain over JSON, for
+// curl scripts and legacy integrations. A non-streaming completion becomes the
+// content of its first choice; a stream becomes one line per content delta,
+// without SSE framing. Error and other non-completion responses are left as
+// JSON. It must only be mounted on the chat completions route.
+func PlainTextResponse() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !prefersPlainText(c.GetHeader("Accept")) {
+			c.Next()
+			return
+		}
+
+		writer := &plainTextResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		defer func() {
+			c.Writer = writer.ResponseWriter
+			if err := writer.finish(); err != nil {
+				gmw.GetLogger(c).Debug("failed to write plain text response", zap.Error(err))
+			}
+		}()
+		c.Next()
+	}
+}
+
+// prefersPlainText reports whether the Accept header ranks text/plain above
+// application/json. Wildcards do not count, so curl's default */* keeps JSON.
+func prefersPlainText(accept string) bool {
+	if accept == "" {
+		return false
+	}
+	var plainQ, jsonQ float64
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		switch mediaType {
+		case "text/plain":
+			plainQ = max(plainQ, q)
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return plainQ > 0 && plainQ > jsonQ
+}
+
+type plainTextMode int
+
+const (
+	plainTextUndecided plainTextMode = iota
+	plainTextBuffer
+	plainTextStream
+)
+
+// plainTextResponseWriter buffers a JSON completion until the handler returns,
+// or converts an SSE stream line by line as it is written.
+type plainTextResponseWriter struct {
+	gin.ResponseWriter
+	mode plainTextMode
+	// buffer holds the JSON body, or the incomplete last line of a stream.
+	buffer bytes.Buffer
+}
+
+// decide picks the mode on the first write, once the adaptor has set the
+// response content type.
+func (w *plainTextResponseWriter) decide() {
+	if w.mode != plainTextUndecided {
+		return
+	}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		w.mode = plainTextStream
+		w.Header().Set("Content-Type", plainTextContentType)
+		w.Header().Del("Content-Length")
+		return
+	}
+	w.mode = plainTextBuffer
+}
+
+func (w *plainTextResponseWriter) Write(data []byte) (int, error) {
+	w.decide()
+	w.buffer.Write(data)
+	if w.mode == plainTextStream {
+		if err := w.emitLines(false); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *plainTextResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow is deferred for buffered bodies so the content type can
+// still change when the handler returns.
+func (w *plainTextResponseWriter) WriteHeaderNow() {
+	if w.mode == plainTextStream {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *plainTextResponseWriter) Flush() {
+	if w.mode == plainTextStream {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *plainTextResponseWriter) Written() bool {
+	return w.buffer.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// emitLines writes the content delta of every complete SSE line, and of the
+// trailing partial line when final is set.
+func (w *plainTextResponseWriter) emitLines(final bool) error {
+	for {
+		pending := w.buffer.Bytes()
+		idx := bytes.IndexByte(pending, '\n')
+		if idx < 0 && !(final && len(pending) > 0) {
+			return nil
+		}
+		line := pending
+		if idx >= 0 {
+			line = pending[:idx]
+		}
+		content, ok := streamDeltaContent(line)
+		if idx >= 0 {
+			w.buffer.Next(idx + 1)
+		} else {
+			w.buffer.Reset()
+		}
+		if !ok {
+			continue
+		}
+		if _, err := w.ResponseWriter.WriteString(content + "\n"); err != nil {
+			return err
+		}
+	}
+}
+
+// finish writes the buffered completion as plain text, or unchanged when it
+// is not a successful completion with text content.
+func (w *plainTextResponseWriter) finish() error {
+	switch w.mode {
+	case plainTextStream:
+		return w.emitLines(true)
+	case plainTextBuffer:
+		body := w.buffer.Bytes()
+		if w.Status() == http.StatusOK {
+			if content, ok := completionContent(body); ok {
+				w.Header().Set("Content-Type", plainTextContentType)
+				w.Header().Del("Content-Length")
+				body = []byte(content)
+			}
+		}
+		_, err := w.ResponseWriter.Write(body)
+		return err
+	}
+	return nil
+}
+
+// completionContent extracts choices[0].message.content from a chat completion.
+func completionContent(body []byte) (string, bool) {
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content json.RawMessage `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil || len(completion.Choices) == 0 {
+		return "", false
+	}
+	return messageText(completion.Choices[0].Message.Content)
+}
+
+// streamDeltaContent extracts choices[0].delta.content from one SSE line.
+// Lines without content, such as [DONE] and usage chunks, report false.
+func streamDeltaContent(line []byte) (string, bool) {
+	payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !ok {
+		return "", false
+	}
+	var chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content json.RawMessage `json:"content"`
+			} `json:"delta"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(payload), &chunk); err != nil || len(chunk.Choices) == 0 {
+		return "", false
+	}
+	content, ok := messageText(chunk.Choices[0].Delta.Content)
+	return content, ok && content != ""
+}
+
+// messageText reads message content given either as a string or as an array
+// of text parts.
+func messageText(raw json.RawMessage) (string, bool) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "", false
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, true
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", false
+	}
+	var builder strings.Builder
+	for _, part := range parts {
+		if part.Type == "text" {
+			builder.WriteString(part.Text)
+		}
+	}
+	return builder.String(), true
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefersPlainText(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                                    false,
+		"*/*":                                 false,
+		"application/json":                    false,
+		"text/plain":                          true,
+		"text/plain; charset=utf-8":           true,
+		"application/json, text/plain":        false,
+		"application/json;q=0.5, text/plain":  true,
+		"text/plain;q=0, application/json":    false,
+		"text/event-stream, text/plain;q=0.9": true,
+		"TEXT/PLAIN;Q=0.8, application/json;q=0.7": true,
+	} {
+		require.Equal(t, want, prefersPlainText(accept), accept)
+	}
+}
+
+// newPlainTextServer serves chat completions the way adaptors write them: a
+// JSON body, an SSE stream, or a JSON error.
+func newPlainTextServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/chat/completions", PlainTextResponse(), func(c *gin.Context) {
+		switch c.Query("case") {
+		case "stream":
+			c.Writer.Header().Set("Content-Type", "text/event-stream")
+			c.Writer.WriteHeader(http.StatusOK)
+			for _, delta := range []string{`{"role":"assistant"}`, `{"content":"Hello"}`, `{"content":", world"}`} {
+				// Split writes across line boundaries like a proxied stream
+				event := fmt.Sprintf(`data: {"choices":[{"index":0,"delta":%s}]}`+"\n\n", delta)
+				_, _ = c.Writer.WriteString(event[:10])
+				_, _ = c.Writer.WriteString(event[10:])
+				c.Writer.Flush()
+			}
+			_, _ = c.Writer.WriteString(`data: {"choices":[],"usage":{"total_tokens":3}}` + "\n\ndata: [DONE]\n\n")
+		case "error":
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"message": "bad request"}})
+		case "parts":
+			c.JSON(http.StatusOK, gin.H{"choices": []gin.H{{"message": gin.H{
+				"role":    "assistant",
+				"content": []gin.H{{"type": "text", "text": "part one, "}, {"type": "text", "text": "part two"}},
+			}}}})
+		default:
+			c.JSON(http.StatusOK, gin.H{
+				"id":      "chatcmpl-1",
+				"object":  "chat.completion",
+				"choices": []gin.H{{"index": 0, "message": gin.H{"role": "assistant", "content": "Hello, world"}, "finish_reason": "stop"}},
+				"usage":   gin.H{"total_tokens": 3},
+			})
+		}
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPlainTextResponse(t *testing.T) {
+	server := newPlainTextServer(t)
+	// curl -H 'Accept: text/plain' -d '{...}' $URL
+	curl := func(query, accept string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions"+query,
+			strings.NewReader(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}]}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	resp, body := curl("", "text/plain")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, plainTextContentType, resp.Header.Get("Content-Type"))
+	require.Equal(t, "Hello, world", body)
+
+	_, body = curl("?case=parts", "text/plain")
+	require.Equal(t, "part one, part two", body)
+
+	resp, body = curl("?case=stream", "text/plain")
+	require.Equal(t, plainTextContentType, resp.Header.Get("Content-Type"))
+	require.Equal(t, "Hello\n, world\n", body)
+
+	resp, body = curl("?case=error", "text/plain")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+	require.JSONEq(t, `{"error":{"message":"bad request"}}`, body)
+
+	// curl's default Accept keeps JSON
+	resp, body = curl("", "*/*")
+	require.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+	require.Contains(t, body, `"chat.completion"`)
+
+	resp, body = curl("?case=stream", "")
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Contains(t, body, "data: [DONE]")
+}
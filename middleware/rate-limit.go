@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/Laisky/errors/v2"
@@ -15,6 +16,7 @@ import (
 	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
 )
 
 var timeFormat = "2006-01-02T15:04:05.000Z"
@@ -28,14 +30,13 @@ func redisRateLimiter(c *gin.Context, maxRequestNum int, duration int64, mark st
 
 	switch mark {
 	case "GR":
-		hashedToken := sha256.Sum256([]byte(GetTokenKeyParts(c)[0]))
-		key = fmt.Sprintf("rateLimit:%s:%s", mark, hex.EncodeToString(hashedToken[:8]))
+		key = globalRelayRateLimitKey(c)
 	case "CR":
 		maxRequestNum = c.GetInt(ctxkey.RateLimit)
 		if maxRequestNum <= 0 {
 			return
 		}
-		hashedToken := sha256.Sum256([]byte(GetTokenKeyParts(c)[0]))
+		hashedToken := sha256.Sum256([]byte(authenticatedTokenKey(c)))
 		key = fmt.Sprintf("rateLimit:%s:%s:%d", mark, hex.EncodeToString(hashedToken[:8]), c.GetInt(ctxkey.ChannelId))
 	}
 
@@ -87,14 +88,13 @@ func memoryRateLimiter(c *gin.Context, maxRequestNum int, duration int64, mark s
 
 	switch mark {
 	case "GR":
-		hashedToken := sha256.Sum256([]byte(GetTokenKeyParts(c)[0]))
-		key = fmt.Sprintf("rateLimit:%s:%s", mark, hex.EncodeToString(hashedToken[:8]))
+		key = globalRelayRateLimitKey(c)
 	case "CR":
 		maxRequestNum = c.GetInt(ctxkey.RateLimit)
 		if maxRequestNum <= 0 {
 			return
 		}
-		hashedToken := sha256.Sum256([]byte(GetTokenKeyParts(c)[0]))
+		hashedToken := sha256.Sum256([]byte(authenticatedTokenKey(c)))
 		key = fmt.Sprintf("rateLimit:%s:%s:%d", mark, hex.EncodeToString(hashedToken[:8]), c.GetInt(ctxkey.ChannelId))
 	}
 
@@ -123,12 +123,14 @@ func rateLimitFactory(maxRequestNum int, duration int64, mark string) func(c *gi
 	}
 }
 
+// GlobalWebRateLimit rejects banned client IPs and limits web requests per IP.
 func GlobalWebRateLimit() func(c *gin.Context) {
-	return rateLimitFactory(config.GlobalWebRateLimitNum, config.GlobalWebRateLimitDuration, "GW")
+	return withIPBlacklist(rateLimitFactory(config.GlobalWebRateLimitNum, config.GlobalWebRateLimitDuration, "GW"))
 }
 
+// GlobalAPIRateLimit rejects banned client IPs and limits API requests per IP.
 func GlobalAPIRateLimit() func(c *gin.Context) {
-	return rateLimitFactory(config.GlobalApiRateLimitNum, config.GlobalApiRateLimitDuration, "GA")
+	return withIPBlacklist(rateLimitFactory(config.GlobalApiRateLimitNum, config.GlobalApiRateLimitDuration, "GA"))
 }
 
 func CriticalRateLimit() func(c *gin.Context) {
@@ -143,8 +145,50 @@ func UploadRateLimit() func(c *gin.Context) {
 	return rateLimitFactory(config.UploadRateLimitNum, config.UploadRateLimitDuration, "UP")
 }
 
+// GlobalRelayRateLimit limits relay requests per token and reports the
+// token's remaining budget in X-RateLimit-* response headers.
 func GlobalRelayRateLimit() func(c *gin.Context) {
-	return rateLimitFactory(config.GlobalRelayRateLimitNum, config.GlobalRelayRateLimitDuration, "GR")
+	limiter := rateLimitFactory(config.GlobalRelayRateLimitNum, config.GlobalRelayRateLimitDuration, "GR")
+	if !globalRelayRateLimitEnabled() {
+		return limiter
+	}
+	return func(c *gin.Context) {
+		limiter(c)
+		if !c.IsAborted() {
+			setRelayRateLimitHeaders(c)
+		}
+	}
+}
+
+// TokenRateLimit enforces the RPM and TPM limits of the calling API token over
+// a sliding window of model.TokenRateLimitWindow, and reports the token's
+// remaining budget in X-RateLimit-*-Requests and X-RateLimit-*-Tokens headers.
+func TokenRateLimit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := gmw.Ctx(c)
+		tokenId := c.GetInt(ctxkey.TokenId)
+		now := time.Now()
+
+		if tpmLimit := c.GetInt(ctxkey.TokenTpmLimit); tpmLimit > 0 {
+			allowed, used := model.CheckTokenTpm(ctx, tokenId, tpmLimit, now)
+			c.Header(RateLimitLimitTokensHeader, strconv.Itoa(tpmLimit))
+			c.Header(RateLimitRemainingTokensHeader, strconv.FormatInt(max(int64(tpmLimit)-used, 0), 10))
+			if !allowed {
+				AbortWithError(c, http.StatusTooManyRequests, errors.Errorf("token rate limit exceeded: %d tokens per minute", tpmLimit))
+				return
+			}
+		}
+
+		if rpmLimit := c.GetInt(ctxkey.TokenRpmLimit); rpmLimit > 0 {
+			allowed, used := model.TakeTokenRequest(ctx, tokenId, rpmLimit, now)
+			c.Header(RateLimitLimitRequestsHeader, strconv.Itoa(rpmLimit))
+			c.Header(RateLimitRemainingRequestsHeader, strconv.FormatInt(max(int64(rpmLimit)-used, 0), 10))
+			if !allowed {
+				AbortWithError(c, http.StatusTooManyRequests, errors.Errorf("token rate limit exceeded: %d requests per minute", rpmLimit))
+				return
+			}
+		}
+	}
 }
 
 func ChannelRateLimit() func(c *gin.Context) {
@@ -176,8 +220,36 @@ func CheckTotpRateLimit(c *gin.Context, userId int) bool {
 	}
 }
 
+// userChannelTestRateLimiter keeps self-service channel test attempts. Its idle
+// keys live as long as the window so a quiet hour does not reset the budget.
+var userChannelTestRateLimiter common.InMemoryRateLimiter
+
+// CheckUserChannelTestRateLimit checks if the user can run another self-service
+// channel test within config.UserChannelTestRateLimitNum per
+// config.UserChannelTestRateLimitDuration seconds.
+func CheckUserChannelTestRateLimit(c *gin.Context, userId int) bool {
+	maxRequestNum := config.UserChannelTestRateLimitNum
+	if maxRequestNum <= 0 {
+		return true
+	}
+
+	duration := config.UserChannelTestRateLimitDuration
+	key := fmt.Sprintf("rateLimit:UCT:%d", userId)
+	if common.IsRedisEnabled() {
+		return checkRedisRateLimitWithExpiration(c, key, maxRequestNum, duration, time.Duration(duration)*time.Second)
+	}
+	userChannelTestRateLimiter.Init(time.Duration(duration) * time.Second)
+	return userChannelTestRateLimiter.Request(key, maxRequestNum, duration)
+}
+
 // checkRedisRateLimit checks rate limit using Redis
 func checkRedisRateLimit(c *gin.Context, key string, maxRequestNum int, duration int64) bool {
+	return checkRedisRateLimitWithExpiration(c, key, maxRequestNum, duration, config.RateLimitKeyExpirationDuration)
+}
+
+// checkRedisRateLimitWithExpiration is checkRedisRateLimit with a custom key expiration,
+// for windows longer than config.RateLimitKeyExpirationDuration.
+func checkRedisRateLimitWithExpiration(c *gin.Context, key string, maxRequestNum int, duration int64, expiration time.Duration) bool {
 	ctx := gmw.Ctx(c)
 	rdb := common.RDB
 	lg := gmw.GetLogger(c)
@@ -191,7 +263,7 @@ func checkRedisRateLimit(c *gin.Context, key string, maxRequestNum int, duration
 
 	if listLength < int64(maxRequestNum) {
 		rdb.LPush(ctx, key, time.Now().Format(timeFormat))
-		rdb.Expire(ctx, key, config.RateLimitKeyExpirationDuration)
+		rdb.Expire(ctx, key, expiration)
 		return true
 	} else {
 		oldTimeStr, err := rdb.LIndex(ctx, key, -1).Result()
@@ -208,12 +280,12 @@ func checkRedisRateLimit(c *gin.Context, key string, maxRequestNum int, duration
 
 		nowTime := time.Now()
 		if int64(nowTime.Sub(oldTime).Seconds()) < duration {
-			rdb.Expire(ctx, key, config.RateLimitKeyExpirationDuration)
+			rdb.Expire(ctx, key, expiration)
 			return false
 		} else {
 			rdb.LPush(ctx, key, nowTime.Format(timeFormat))
 			rdb.LTrim(ctx, key, 0, int64(maxRequestNum-1))
-			rdb.Expire(ctx, key, config.RateLimitKeyExpirationDuration)
+			rdb.Expire(ctx, key, expiration)
 			return true
 		}
 	}
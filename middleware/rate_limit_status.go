@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// Response headers describing the relay rate limit of the calling token.
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// Response headers describing the per-token RPM and TPM limits, see TokenRateLimit.
+const (
+	RateLimitLimitRequestsHeader     = "X-RateLimit-Limit-Requests"
+	RateLimitRemainingRequestsHeader = "X-RateLimit-Remaining-Requests"
+	RateLimitLimitTokensHeader       = "X-RateLimit-Limit-Tokens"
+	RateLimitRemainingTokensHeader   = "X-RateLimit-Remaining-Tokens"
+)
+
+// RateLimitCounter describes one rate limited resource. Nil fields are unknown
+// or unlimited.
+type RateLimitCounter struct {
+	Limit     *int `json:"limit"`
+	Remaining *int `json:"remaining"`
+	// ResetAt is the Unix time at which the oldest request counted in the
+	// window expires and frees up capacity.
+	ResetAt *int64 `json:"reset_at,omitempty"`
+}
+
+// RateLimitStatus is the rate limit state of an API token.
+type RateLimitStatus struct {
+	Requests RateLimitCounter `json:"requests"`
+	// Tokens is the token's TPM limit, counted over model.TokenRateLimitWindow
+	// rather than WindowSeconds.
+	Tokens        RateLimitCounter `json:"tokens"`
+	WindowSeconds int64            `json:"window_seconds"`
+}
+
+// globalRelayRateLimitKey returns the key the relay rate limiter counts the
+// requests of the calling token under.
+func globalRelayRateLimitKey(c *gin.Context) string {
+	hashedToken := sha256.Sum256([]byte(authenticatedTokenKey(c)))
+	return fmt.Sprintf("rateLimit:GR:%s", hex.EncodeToString(hashedToken[:8]))
+}
+
+// globalRelayRateLimitEnabled mirrors the conditions under which
+// GlobalRelayRateLimit enforces a limit.
+func globalRelayRateLimitEnabled() bool {
+	return config.GlobalRelayRateLimitNum > 0 && !config.DebugEnabled
+}
+
+// GetRelayRateLimitStatus reads the relay rate limit counters of the calling
+// token from the same store the rate limiter uses. The remaining count is
+// unknown (nil) when Redis cannot be read.
+func GetRelayRateLimitStatus(c *gin.Context) RateLimitStatus {
+	status := RateLimitStatus{WindowSeconds: config.GlobalRelayRateLimitDuration}
+	if tpmLimit := c.GetInt(ctxkey.TokenTpmLimit); tpmLimit > 0 {
+		_, used := model.CheckTokenTpm(gmw.Ctx(c), c.GetInt(ctxkey.TokenId), tpmLimit, time.Now())
+		remaining := int(max(int64(tpmLimit)-used, 0))
+		status.Tokens.Limit = &tpmLimit
+		status.Tokens.Remaining = &remaining
+	}
+	if !globalRelayRateLimitEnabled() {
+		return status
+	}
+	limit := config.GlobalRelayRateLimitNum
+	status.Requests.Limit = &limit
+
+	key := globalRelayRateLimitKey(c)
+	window := config.GlobalRelayRateLimitDuration
+	var used int
+	var oldest int64
+	if common.IsRedisEnabled() {
+		if common.RDB == nil {
+			return status
+		}
+		entries, err := common.RDB.LRange(gmw.Ctx(c), key, 0, -1).Result()
+		if err != nil {
+			gmw.GetLogger(c).Warn("failed to read relay rate limit counter", zap.String("key", key), zap.Error(err))
+			return status
+		}
+		now := time.Now()
+		for _, entry := range entries {
+			ts, err := time.Parse(timeFormat, entry)
+			if err != nil {
+				continue
+			}
+			// Same truncation to whole seconds as redisRateLimiter
+			if int64(now.Sub(ts).Seconds()) < window {
+				used++
+				if oldest == 0 || ts.Unix() < oldest {
+					oldest = ts.Unix()
+				}
+			}
+		}
+	} else {
+		inMemoryRateLimiter.Init(config.RateLimitKeyExpirationDuration)
+		used, oldest = inMemoryRateLimiter.Usage(key, window)
+	}
+
+	remaining := max(limit-used, 0)
+	status.Requests.Remaining = &remaining
+	resetAt := time.Now().Unix()
+	if used > 0 {
+		resetAt = oldest + window
+	}
+	status.Requests.ResetAt = &resetAt
+	return status
+}
+
+// setRelayRateLimitHeaders adds the X-RateLimit-* headers describing the
+// calling token's relay rate limit to the response.
+func setRelayRateLimitHeaders(c *gin.Context) {
+	status := GetRelayRateLimitStatus(c)
+	if status.Requests.Limit == nil {
+		return
+	}
+	c.Header(RateLimitLimitHeader, strconv.Itoa(*status.Requests.Limit))
+	if status.Requests.Remaining != nil {
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(*status.Requests.Remaining))
+	}
+	if status.Requests.ResetAt != nil {
+		c.Header(RateLimitResetHeader, strconv.FormatInt(*status.Requests.ResetAt, 10))
+	}
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/random"
+)
+
+func setupRelayRateLimitTest(t *testing.T, limit int) *gin.Engine {
+	t.Helper()
+	originalRedis := common.IsRedisEnabled()
+	originalLimit, originalDebug := config.GlobalRelayRateLimitNum, config.DebugEnabled
+	common.SetRedisEnabled(false)
+	config.GlobalRelayRateLimitNum = limit
+	config.DebugEnabled = false
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedis)
+		config.GlobalRelayRateLimitNum, config.DebugEnabled = originalLimit, originalDebug
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/v1/rate-limits", func(c *gin.Context) {
+		c.JSON(http.StatusOK, GetRelayRateLimitStatus(c))
+	})
+	router.POST("/v1/chat/completions", GlobalRelayRateLimit(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func serveWithToken(router *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer sk-"+token)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func getRateLimitStatus(t *testing.T, router *gin.Engine, token string) RateLimitStatus {
+	t.Helper()
+	w := serveWithToken(router, http.MethodGet, "/v1/rate-limits", token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var status RateLimitStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	return status
+}
+
+func TestRelayRateLimitStatus_RemainingDecrements(t *testing.T) {
+	router := setupRelayRateLimitTest(t, 3)
+	token := random.GetRandomString(16)
+
+	status := getRateLimitStatus(t, router, token)
+	require.Equal(t, 3, *status.Requests.Limit)
+	require.Equal(t, 3, *status.Requests.Remaining)
+	require.Equal(t, config.GlobalRelayRateLimitDuration, status.WindowSeconds)
+
+	before := time.Now().Unix()
+	for expected := 2; expected >= 0; expected-- {
+		w := serveWithToken(router, http.MethodPost, "/v1/chat/completions", token)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "3", w.Header().Get(RateLimitLimitHeader))
+		require.Equal(t, strconv.Itoa(expected), w.Header().Get(RateLimitRemainingHeader))
+		resetAt, err := strconv.ParseInt(w.Header().Get(RateLimitResetHeader), 10, 64)
+		require.NoError(t, err)
+		require.InDelta(t, before+config.GlobalRelayRateLimitDuration, resetAt, 1)
+
+		status = getRateLimitStatus(t, router, token)
+		require.Equal(t, expected, *status.Requests.Remaining, "checking the status is not rate limited")
+	}
+
+	w := serveWithToken(router, http.MethodPost, "/v1/chat/completions", token)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Zero(t, *getRateLimitStatus(t, router, token).Requests.Remaining)
+
+	// Other tokens have their own budget
+	require.Equal(t, 3, *getRateLimitStatus(t, router, random.GetRandomString(16)).Requests.Remaining)
+}
+
+func TestRelayRateLimitStatus_RedisUnavailable(t *testing.T) {
+	router := setupRelayRateLimitTest(t, 480)
+	originalRDB := common.RDB
+	common.SetRedisEnabled(true)
+	common.RDB = nil
+	t.Cleanup(func() { common.RDB = originalRDB })
+
+	w := serveWithToken(router, http.MethodGet, "/v1/rate-limits", random.GetRandomString(16))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{
+		"requests": {"limit": 480, "remaining": null},
+		"tokens": {"limit": null, "remaining": null},
+		"window_seconds": 180
+	}`, w.Body.String())
+}
+
+func TestRelayRateLimitStatus_Disabled(t *testing.T) {
+	router := setupRelayRateLimitTest(t, 0)
+	status := getRateLimitStatus(t, router, random.GetRandomString(16))
+	require.Nil(t, status.Requests.Limit)
+	require.Nil(t, status.Requests.Remaining)
+
+	w := serveWithToken(router, http.MethodPost, "/v1/chat/completions", "any")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get(RateLimitLimitHeader))
+}
+
+func TestGlobalRelayRateLimit_KeysOnAuthenticatedToken(t *testing.T) {
+	originalRedis := common.IsRedisEnabled()
+	originalLimit, originalDebug := config.GlobalRelayRateLimitNum, config.DebugEnabled
+	common.SetRedisEnabled(false)
+	config.GlobalRelayRateLimitNum = 2
+	config.DebugEnabled = false
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedis)
+		config.GlobalRelayRateLimitNum, config.DebugEnabled = originalLimit, originalDebug
+	})
+
+	// TokenAuth may authenticate a later token header than the first one, so
+	// a junk first header must not give the token a fresh budget
+	authenticated := random.GetRandomString(16)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		c.Set(ctxkey.TokenKey, authenticated)
+	}, GlobalRelayRateLimit(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for range 2 {
+		w := serveWithToken(router, http.MethodPost, "/v1/chat/completions", random.GetRandomString(16))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	w := serveWithToken(router, http.MethodPost, "/v1/chat/completions", random.GetRandomString(16))
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+}
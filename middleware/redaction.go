@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+)
+
+// RequestRedaction counts the values matched by config.RequestRedactionPatterns
+// in the messages of JSON relay requests, so the consume log can record how many
+// were redacted. The request itself is left untouched: redaction applies only
+// to the copies written to debug logs and replay captures, which call
+// common.RedactRequestBody. It is a no-op when no pattern is configured.
+func RequestRedaction() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(config.RequestRedactionPatterns) == 0 ||
+			!strings.Contains(c.GetHeader("Content-Type"), "application/json") {
+			c.Next()
+			return
+		}
+
+		body, err := common.GetRequestBody(c)
+		if err != nil {
+			gmw.GetLogger(c).Debug("skip request redaction: read request body", zap.Error(err))
+			c.Next()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if _, count := common.RedactRequestBodyWithCount(body, config.RequestRedactionPatterns); count > 0 {
+			c.Set(ctxkey.RedactionCount, count)
+		}
+		c.Next()
+	}
+}
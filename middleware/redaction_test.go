@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+)
+
+func TestRequestRedaction_CountsWithoutChangingRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := config.RequestRedactionPatterns
+	t.Cleanup(func() { config.RequestRedactionPatterns = original })
+	config.RequestRedactionPatterns = []*regexp.Regexp{regexp.MustCompile(`\b\d{4}( \d{4}){3}\b`)}
+
+	var received []byte
+	var count int
+	r := gin.New()
+	r.POST("/v1/chat/completions", RequestRedaction(), func(c *gin.Context) {
+		var err error
+		received, err = io.ReadAll(c.Request.Body)
+		require.NoError(t, err)
+		count = c.GetInt(ctxkey.RedactionCount)
+		c.Status(http.StatusOK)
+	})
+
+	body := []byte(`{"messages":[{"role":"user","content":"cards 4111 1111 1111 1111 and 4242 4242 4242 4242"}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, 2, count)
+	// The upstream request keeps the original content
+	require.Equal(t, body, received)
+}
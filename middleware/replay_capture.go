@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/tracing"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// replaySafeHeaders lists the request headers persisted with a captured request.
+// Credentials (Authorization, X-Api-Key, Cookie) are deliberately excluded.
+var replaySafeHeaders = []string{
+	"Content-Type",
+	"Accept",
+	"Accept-Language",
+	"Anthropic-Version",
+	"Anthropic-Beta",
+	OverrideLanguageHeader,
+}
+
+// CaptureFailedRequest returns a middleware that persists the full request of relay
+// calls failing with a 5xx status into the request_traces table, so administrators
+// can replay them later. It is a no-op unless config.RequestReplayCaptureEnabled is set.
+// It must run after TokenAuth so the caller identity and request model are known.
+func CaptureFailedRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.RequestReplayCaptureEnabled {
+			c.Next()
+			return
+		}
+
+		lg := gmw.GetLogger(c)
+		// Snapshot the body before later middlewares (e.g., language injection) rewrite it.
+		requestBody, err := common.GetRequestBody(c)
+		if err != nil {
+			lg.Debug("skip request capture: read request body", zap.Error(err))
+			c.Next()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(requestBody))
+
+		writer := &captureResponseWriter{ResponseWriter: c.Writer, limit: config.RequestReplayMaxBodyBytes}
+		c.Writer = writer
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < http.StatusInternalServerError {
+			return
+		}
+		if len(requestBody) > config.RequestReplayMaxBodyBytes {
+			lg.Debug("skip request capture: request body exceeds limit",
+				zap.Int("body_size", len(requestBody)),
+				zap.Int("limit", config.RequestReplayMaxBodyBytes))
+			return
+		}
+
+		traceId := tracing.GetTraceID(c)
+		if traceId == "" {
+			return
+		}
+
+		headers := make(http.Header)
+		for _, name := range replaySafeHeaders {
+			if v := c.Request.Header.Values(name); len(v) > 0 {
+				headers[name] = v
+			}
+		}
+		headersJSON, err := json.Marshal(headers)
+		if err != nil {
+			lg.Warn("skip request capture: marshal headers", zap.Error(err))
+			return
+		}
+
+		trace := &model.RequestTrace{
+			TraceId:        traceId,
+			RequestId:      c.GetString(helper.RequestIdKey),
+			UserId:         c.GetInt(ctxkey.Id),
+			TokenId:        c.GetInt(ctxkey.TokenId),
+			ChannelId:      c.GetInt(ctxkey.ChannelId),
+			ModelName:      c.GetString(ctxkey.RequestModel),
+			Method:         c.Request.Method,
+			URL:            c.Request.URL.RequestURI(),
+			Headers:        string(headersJSON),
+			RequestBody:    string(common.RedactRequestBody(requestBody, config.RequestRedactionPatterns)),
+			ResponseStatus: status,
+			ResponseBody:   string(common.RedactAll(writer.body.Bytes(), config.RequestRedactionPatterns)),
+		}
+		if err = model.CreateRequestTrace(gmw.Ctx(c), trace); err != nil {
+			lg.Warn("failed to capture failed request for replay", zap.Error(err))
+			return
+		}
+		lg.Debug("captured failed request for replay",
+			zap.String("trace_id", traceId),
+			zap.Int("status", status),
+			zap.Int("channel_id", trace.ChannelId))
+	}
+}
+
+// CaptureReplayCorpus returns a middleware that stores a sample of relay requests,
+// at config.ReplayCorpusSampleRate, in the replay_corpus table together with the
+// response they received. Matches of config.RequestRedactionPatterns are
+// redacted anywhere in both bodies. Only JSON requests whose request and response both fit
+// in config.RequestReplayMaxBodyBytes are kept, since a truncated body can be
+// neither replayed nor compared. Sampled requests are marked with
+// ctxkey.ReplayCorpusSampled, which also logs their response body size. It must
+// run after TokenAuth and before the body is rewritten by later middlewares.
+func CaptureReplayCorpus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rate := config.ReplayCorpusSampleRate
+		if rate <= 0 || (rate < 1 && rand.Float64() >= rate) ||
+			!strings.HasPrefix(c.GetHeader("Content-Type"), "application/json") {
+			c.Next()
+			return
+		}
+		c.Set(ctxkey.ReplayCorpusSampled, true)
+
+		lg := gmw.GetLogger(c)
+		requestBody, err := common.GetRequestBody(c)
+		if err != nil {
+			lg.Debug("skip replay corpus sample: read request body", zap.Error(err))
+			c.Next()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(requestBody))
+
+		writer := &captureResponseWriter{ResponseWriter: c.Writer, limit: config.RequestReplayMaxBodyBytes}
+		c.Writer = writer
+		c.Next()
+
+		if len(requestBody) > config.RequestReplayMaxBodyBytes || writer.truncated {
+			lg.Debug("skip replay corpus sample: body exceeds limit",
+				zap.Int("request_body_size", len(requestBody)),
+				zap.Int("limit", config.RequestReplayMaxBodyBytes))
+			return
+		}
+
+		entry := &model.ReplayCorpusEntry{
+			ModelName:      c.GetString(ctxkey.RequestModel),
+			Method:         c.Request.Method,
+			URL:            c.Request.URL.RequestURI(),
+			RequestBody:    string(common.RedactAll(requestBody, config.RequestRedactionPatterns)),
+			ResponseStatus: c.Writer.Status(),
+			ResponseBody:   string(common.RedactAll(writer.body.Bytes(), config.RequestRedactionPatterns)),
+		}
+		if err = model.CreateReplayCorpusEntry(gmw.Ctx(c), entry); err != nil {
+			lg.Warn("failed to store replay corpus sample", zap.Error(err))
+		}
+	}
+}
+
+// captureResponseWriter tees the response body into a buffer capped at limit bytes.
+type captureResponseWriter struct {
+	gin.ResponseWriter
+	body  bytes.Buffer
+	limit int
+	// truncated reports that the response outgrew limit.
+	truncated bool
+}
+
+// Write forwards data to the client and keeps a bounded copy.
+func (w *captureResponseWriter) Write(data []byte) (int, error) {
+	w.keep(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString forwards s to the client and keeps a bounded copy.
+func (w *captureResponseWriter) WriteString(s string) (int, error) {
+	w.keep([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *captureResponseWriter) keep(data []byte) {
+	remaining := w.limit - w.body.Len()
+	if len(data) > remaining {
+		w.truncated = true
+	}
+	if remaining > 0 {
+		w.body.Write(data[:min(len(data), remaining)])
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestCaptureReplayCorpus_RedactsStoredBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:replay_corpus_%d?mode=memory&cache=shared", time.Now().UnixNano())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.ReplayCorpusEntry{}))
+	originalDB := model.DB
+	originalRate, originalPatterns := config.ReplayCorpusSampleRate, config.RequestRedactionPatterns
+	model.DB = db
+	config.ReplayCorpusSampleRate = 1
+	config.RequestRedactionPatterns = []*regexp.Regexp{regexp.MustCompile(`\b\d{4}( \d{4}){3}\b`)}
+	t.Cleanup(func() {
+		model.DB = originalDB
+		config.ReplayCorpusSampleRate, config.RequestRedactionPatterns = originalRate, originalPatterns
+	})
+
+	r := gin.New()
+	var sampled bool
+	r.POST("/v1/embeddings", CaptureReplayCorpus(), func(c *gin.Context) {
+		sampled = c.GetBool(ctxkey.ReplayCorpusSampled)
+		c.String(http.StatusOK, `{"echo":"4111 1111 1111 1111"}`)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings",
+		bytes.NewReader([]byte(`{"model":"text-embedding-3-small","input":"card 4111 1111 1111 1111"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// The client still receives the original response
+	require.Equal(t, `{"echo":"4111 1111 1111 1111"}`, w.Body.String())
+	require.True(t, sampled)
+
+	var entries []model.ReplayCorpusEntry
+	require.NoError(t, db.Find(&entries).Error)
+	require.Len(t, entries, 1)
+	require.Equal(t, `{"model":"text-embedding-3-small","input":"card [REDACTED]"}`, entries[0].RequestBody)
+	require.Equal(t, `{"echo":"[REDACTED]"}`, entries[0].ResponseBody)
+}
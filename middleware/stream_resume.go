@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/random"
+)
+
+const (
+	// StreamResumeHeader opts a streaming request into resumable delivery.
+	StreamResumeHeader = "X-OneApi-Stream-Resume"
+	// lastEventIdHeader is sent by SSE clients when reconnecting.
+	lastEventIdHeader = "Last-Event-ID"
+)
+
+// streamResumePollInterval is how often a resumed stream checks the buffer
+// for events produced by the still running original request.
+var streamResumePollInterval = 100 * time.Millisecond
+
+// StreamResume returns a middleware that makes SSE responses resumable for
+// requests sending `X-OneApi-Stream-Resume: true`. Every event is tagged with
+// an id of the form `<request id>:<sequence>` and buffered for IDLE_TIMEOUT.
+// The relay keeps running when the client disconnects, and a request carrying
+// the resume header and `Last-Event-ID` receives the missed events followed by
+// the rest of the live stream instead of being relayed again. It must run
+// after TokenAuth so that streams can only be resumed with the same token.
+func StreamResume() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.EqualFold(c.GetHeader(StreamResumeHeader), "true") {
+			c.Next()
+			return
+		}
+		if lastEventId := c.GetHeader(lastEventIdHeader); lastEventId != "" {
+			resumeStream(c, lastEventId)
+			c.Abort()
+			return
+		}
+
+		streamId := c.GetString(helper.RequestIdKey)
+		if streamId == "" {
+			streamId = random.GetUUID()
+		}
+		// Keep relaying after the client disconnects so a reconnecting client can catch up
+		c.Request = c.Request.WithContext(context.WithoutCancel(c.Request.Context()))
+		writer := &resumableStreamWriter{
+			ResponseWriter: c.Writer,
+			ctx:            gmw.Ctx(c),
+			streamId:       streamId,
+			tokenId:        c.GetInt(ctxkey.TokenId),
+		}
+		c.Writer = writer
+		c.Next()
+		writer.finish()
+	}
+}
+
+// parseStreamEventId splits an event id produced by StreamResume into the
+// stream id and the sequence number.
+func parseStreamEventId(eventId string) (streamId string, seq int, err error) {
+	idx := strings.LastIndexByte(eventId, ':')
+	if idx <= 0 {
+		return "", 0, errors.Errorf("invalid %s %q", lastEventIdHeader, eventId)
+	}
+	seq, err = strconv.Atoi(eventId[idx+1:])
+	if err != nil || seq < 0 {
+		return "", 0, errors.Errorf("invalid %s %q", lastEventIdHeader, eventId)
+	}
+	return eventId[:idx], seq, nil
+}
+
+// writeStreamEvent writes one SSE event tagged with its resumable id.
+func writeStreamEvent(w io.Writer, streamId string, seq int, event string) error {
+	_, err := fmt.Fprintf(w, "id: %s:%d\n%s\n\n", streamId, seq, event)
+	return err
+}
+
+// resumeStream replays the events buffered after lastEventId and then follows
+// the stream until the original request finishes or no event arrives within
+// IDLE_TIMEOUT.
+func resumeStream(c *gin.Context, lastEventId string) {
+	lg := gmw.GetLogger(c)
+	ctx := gmw.Ctx(c)
+	streamId, lastSeq, err := parseStreamEventId(lastEventId)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	snapshot, err := loadStream(ctx, streamId)
+	if err != nil {
+		AbortWithError(c, http.StatusInternalServerError, err)
+		return
+	}
+	// Streams of other tokens are reported as missing rather than forbidden
+	if snapshot == nil || snapshot.TokenId != c.GetInt(ctxkey.TokenId) {
+		AbortWithError(c, http.StatusNotFound, errors.Errorf("stream %s not found or expired", streamId))
+		return
+	}
+	chunks, err := snapshot.after(lastSeq)
+	if err != nil {
+		AbortWithError(c, http.StatusGone, errors.Wrapf(err, "resume stream %s after event %d", streamId, lastSeq))
+		return
+	}
+
+	lg.Debug("resuming stream", zap.String("stream_id", streamId), zap.Int("last_seq", lastSeq))
+	common.SetEventStreamHeaders(c)
+	c.Status(http.StatusOK)
+	idleTimeout := time.Duration(config.IdleTimeout) * time.Second
+	lastEventAt := time.Now()
+	for {
+		for _, chunk := range chunks {
+			lastSeq++
+			if err := writeStreamEvent(c.Writer, streamId, lastSeq, chunk); err != nil {
+				lg.Debug("client disconnected from resumed stream", zap.Error(err))
+				return
+			}
+		}
+		c.Writer.Flush()
+		if len(chunks) > 0 {
+			lastEventAt = time.Now()
+		}
+		if snapshot.Done && len(chunks) == 0 {
+			return
+		}
+		if time.Since(lastEventAt) > idleTimeout {
+			lg.Warn("resumed stream idle, closing", zap.String("stream_id", streamId))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(streamResumePollInterval):
+		}
+
+		if snapshot, err = loadStream(ctx, streamId); err != nil || snapshot == nil {
+			lg.Warn("resumed stream is no longer buffered", zap.String("stream_id", streamId), zap.Error(err))
+			return
+		}
+		if chunks, err = snapshot.after(lastSeq); err != nil {
+			lg.Warn("resumed stream fell behind its buffer", zap.String("stream_id", streamId), zap.Error(err))
+			return
+		}
+	}
+}
+
+// resumableStreamWriter splits SSE output into events, tags each event with a
+// resumable id and buffers it. Once the client is gone, writes are dropped but
+// reported as successful so the relay completes and the buffer stays complete.
+type resumableStreamWriter struct {
+	gin.ResponseWriter
+	ctx          context.Context
+	streamId     string
+	tokenId      int
+	pending      []byte
+	seq          int
+	disconnected bool
+}
+
+func (w *resumableStreamWriter) isEventStream() bool {
+	return strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+}
+
+func (w *resumableStreamWriter) Write(p []byte) (int, error) {
+	if !w.isEventStream() {
+		return w.ResponseWriter.Write(p)
+	}
+	w.pending = append(w.pending, p...)
+	for {
+		idx := bytes.Index(w.pending, []byte("\n\n"))
+		if idx < 0 {
+			break
+		}
+		event := string(w.pending[:idx])
+		w.pending = w.pending[idx+2:]
+		w.emit(event)
+	}
+	return len(p), nil
+}
+
+func (w *resumableStreamWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *resumableStreamWriter) Flush() {
+	if !w.disconnected {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// emit buffers one event and forwards it to the client while connected.
+// Comment-only events, used as keep-alives, are forwarded without an id.
+func (w *resumableStreamWriter) emit(event string) {
+	event = strings.TrimLeft(event, "\n")
+	if event == "" {
+		return
+	}
+	if isSSEComment(event) {
+		w.forward(func() error {
+			_, err := io.WriteString(w.ResponseWriter, event+"\n\n")
+			return err
+		})
+		return
+	}
+
+	w.seq++
+	if err := appendStreamChunk(w.ctx, w.streamId, w.tokenId, w.seq, event); err != nil {
+		gmw.GetLogger(w.ctx).Warn("failed to buffer resumable stream event", zap.Error(err))
+	}
+	w.forward(func() error {
+		return writeStreamEvent(w.ResponseWriter, w.streamId, w.seq, event)
+	})
+}
+
+func (w *resumableStreamWriter) forward(write func() error) {
+	if w.disconnected {
+		return
+	}
+	if err := write(); err != nil {
+		w.disconnected = true
+		gmw.GetLogger(w.ctx).Debug("client disconnected from resumable stream, buffering only",
+			zap.String("stream_id", w.streamId), zap.Int("seq", w.seq), zap.Error(err))
+	}
+}
+
+// finish emits a trailing event without a blank line terminator and marks the
+// stream complete.
+func (w *resumableStreamWriter) finish() {
+	if len(w.pending) > 0 {
+		event := strings.TrimRight(string(w.pending), "\n")
+		w.pending = nil
+		w.emit(event)
+		w.Flush()
+	}
+	if w.seq == 0 {
+		return
+	}
+	if err := finishStream(w.ctx, w.streamId); err != nil {
+		gmw.GetLogger(w.ctx).Warn("failed to finish resumable stream", zap.Error(err))
+	}
+}
+
+func isSSEComment(event string) bool {
+	for line := range strings.SplitSeq(event, "\n") {
+		if !strings.HasPrefix(line, ":") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+// streamResumeMaxChunks caps the number of buffered events per stream. Older
+// events are dropped, so a client can only resume within the last 1000 events.
+const streamResumeMaxChunks = 1000
+
+// streamResumeSweepInterval is how often expired in-memory streams are dropped.
+const streamResumeSweepInterval = time.Minute
+
+// errStreamResumeGap means events after the client's last event id were
+// already dropped from the buffer.
+var errStreamResumeGap = errors.New("missed events are no longer buffered")
+
+// streamSnapshot is the buffered state of a stream at one point in time.
+type streamSnapshot struct {
+	TokenId int
+	// FirstSeq is the sequence number of Chunks[0]; sequence numbers start at 1.
+	FirstSeq int
+	Chunks   []string
+	Done     bool
+}
+
+// after returns the buffered events following lastSeq.
+func (s *streamSnapshot) after(lastSeq int) ([]string, error) {
+	if lastSeq+1 < s.FirstSeq {
+		return nil, errStreamResumeGap
+	}
+	offset := lastSeq + 1 - s.FirstSeq
+	if offset >= len(s.Chunks) {
+		return nil, nil
+	}
+	return s.Chunks[offset:], nil
+}
+
+func streamResumeTTL() time.Duration {
+	return time.Duration(config.IdleTimeout) * time.Second
+}
+
+func streamChunksKey(streamId string) string {
+	return fmt.Sprintf("stream:%s", streamId)
+}
+
+func streamMetaKey(streamId string) string {
+	return fmt.Sprintf("stream:%s:meta", streamId)
+}
+
+// memoryStream is the in-process buffer of one stream, used when Redis is unavailable.
+type memoryStream struct {
+	streamSnapshot
+	expiresAt time.Time
+}
+
+// memoryStreams buffers resumable streams when Redis is unavailable. It only
+// allows resuming on the instance that served the original request.
+var memoryStreams = struct {
+	sync.Mutex
+	streams map[string]*memoryStream
+}{streams: make(map[string]*memoryStream)}
+
+func init() {
+	go func() {
+		for range time.Tick(streamResumeSweepInterval) {
+			sweepMemoryStreams(time.Now())
+		}
+	}()
+}
+
+// sweepMemoryStreams drops the in-memory streams that expired at now, so
+// streams that are never resumed do not accumulate.
+func sweepMemoryStreams(now time.Time) {
+	memoryStreams.Lock()
+	defer memoryStreams.Unlock()
+	for id, stream := range memoryStreams.streams {
+		if now.After(stream.expiresAt) {
+			delete(memoryStreams.streams, id)
+		}
+	}
+}
+
+// appendStreamChunk buffers the event with sequence number seq, trimming the
+// buffer to the most recent streamResumeMaxChunks events. The buffer expires
+// IDLE_TIMEOUT after its last update.
+func appendStreamChunk(ctx context.Context, streamId string, tokenId, seq int, chunk string) error {
+	if common.IsRedisEnabled() && common.RDB != nil {
+		chunksKey, metaKey := streamChunksKey(streamId), streamMetaKey(streamId)
+		pipe := common.RDB.TxPipeline()
+		pipe.RPush(ctx, chunksKey, chunk)
+		pipe.LTrim(ctx, chunksKey, -streamResumeMaxChunks, -1)
+		pipe.HSet(ctx, metaKey, "token_id", tokenId, "total", seq)
+		pipe.Expire(ctx, chunksKey, streamResumeTTL())
+		pipe.Expire(ctx, metaKey, streamResumeTTL())
+		if _, err := pipe.Exec(ctx); err != nil {
+			return errors.Wrapf(err, "buffer chunk %d of stream %s", seq, streamId)
+		}
+		return nil
+	}
+
+	memoryStreams.Lock()
+	defer memoryStreams.Unlock()
+	stream, ok := memoryStreams.streams[streamId]
+	if !ok {
+		stream = &memoryStream{streamSnapshot: streamSnapshot{TokenId: tokenId, FirstSeq: seq}}
+		memoryStreams.streams[streamId] = stream
+	}
+	stream.Chunks = append(stream.Chunks, chunk)
+	if overflow := len(stream.Chunks) - streamResumeMaxChunks; overflow > 0 {
+		stream.Chunks = append([]string(nil), stream.Chunks[overflow:]...)
+		stream.FirstSeq += overflow
+	}
+	stream.expiresAt = time.Now().Add(streamResumeTTL())
+	return nil
+}
+
+// finishStream marks the stream as complete so resuming clients stop waiting
+// for more events.
+func finishStream(ctx context.Context, streamId string) error {
+	if common.IsRedisEnabled() && common.RDB != nil {
+		pipe := common.RDB.TxPipeline()
+		pipe.HSet(ctx, streamMetaKey(streamId), "done", 1)
+		pipe.Expire(ctx, streamMetaKey(streamId), streamResumeTTL())
+		if _, err := pipe.Exec(ctx); err != nil {
+			return errors.Wrapf(err, "finish stream %s", streamId)
+		}
+		return nil
+	}
+
+	memoryStreams.Lock()
+	defer memoryStreams.Unlock()
+	if stream, ok := memoryStreams.streams[streamId]; ok {
+		stream.Done = true
+		stream.expiresAt = time.Now().Add(streamResumeTTL())
+	}
+	return nil
+}
+
+// loadStream returns the buffered state of a stream, or nil if nothing is
+// buffered under streamId.
+func loadStream(ctx context.Context, streamId string) (*streamSnapshot, error) {
+	if common.IsRedisEnabled() && common.RDB != nil {
+		pipe := common.RDB.TxPipeline()
+		metaCmd := pipe.HGetAll(ctx, streamMetaKey(streamId))
+		chunksCmd := pipe.LRange(ctx, streamChunksKey(streamId), 0, -1)
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, errors.Wrapf(err, "load stream %s", streamId)
+		}
+		meta := metaCmd.Val()
+		if len(meta) == 0 {
+			return nil, nil
+		}
+		tokenId, _ := strconv.Atoi(meta["token_id"])
+		total, _ := strconv.Atoi(meta["total"])
+		chunks := chunksCmd.Val()
+		return &streamSnapshot{
+			TokenId:  tokenId,
+			FirstSeq: total - len(chunks) + 1,
+			Chunks:   chunks,
+			Done:     meta["done"] == "1",
+		}, nil
+	}
+
+	memoryStreams.Lock()
+	defer memoryStreams.Unlock()
+	stream, ok := memoryStreams.streams[streamId]
+	if !ok || time.Now().After(stream.expiresAt) {
+		return nil, nil
+	}
+	snapshot := stream.streamSnapshot
+	snapshot.Chunks = append([]string(nil), stream.Chunks...)
+	return &snapshot, nil
+}
@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+)
+
+const streamResumeTotalChunks = 100
+
+type sseEvent struct {
+	id   string
+	data string
+}
+
+func setupStreamResumeTest(t *testing.T) {
+	t.Helper()
+	originalRedis := common.IsRedisEnabled()
+	originalInterval := streamResumePollInterval
+	common.SetRedisEnabled(false)
+	streamResumePollInterval = 5 * time.Millisecond
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedis)
+		streamResumePollInterval = originalInterval
+		memoryStreams.Lock()
+		memoryStreams.streams = make(map[string]*memoryStream)
+		memoryStreams.Unlock()
+	})
+}
+
+// newStreamResumeServer serves a 100-chunk stream that keeps producing after
+// the client goes away. handlerDone is closed once the stream has ended.
+func newStreamResumeServer(t *testing.T) (server *httptest.Server, handlerDone chan struct{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	handlerDone = make(chan struct{})
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(helper.RequestIdKey, "req-resume")
+		c.Set(ctxkey.TokenId, 7)
+		if c.GetHeader("X-Test-Token") == "other" {
+			c.Set(ctxkey.TokenId, 8)
+		}
+		c.Next()
+	})
+	router.Use(StreamResume())
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		defer close(handlerDone)
+		common.SetEventStreamHeaders(c)
+		for i := 1; i <= streamResumeTotalChunks; i++ {
+			c.Render(-1, common.CustomEvent{Data: fmt.Sprintf(`data: {"n":%d}`, i)})
+			c.Writer.Flush()
+			time.Sleep(2 * time.Millisecond)
+		}
+		c.Render(-1, common.CustomEvent{Data: "data: [DONE]"})
+		c.Writer.Flush()
+	})
+	server = httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, handlerDone
+}
+
+func postStream(t *testing.T, ctx context.Context, url string, headers map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/v1/chat/completions", strings.NewReader(`{"stream":true}`))
+	require.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// readEvents parses SSE events until limit events were read or the stream ends.
+func readEvents(t *testing.T, resp *http.Response, limit int) []sseEvent {
+	t.Helper()
+	var (
+		events  []sseEvent
+		current sseEvent
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if current.data != "" {
+				events = append(events, current)
+				if len(events) == limit {
+					return events
+				}
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return events
+}
+
+func TestStreamResume_ReconnectAfterDisconnect(t *testing.T) {
+	setupStreamResumeTest(t)
+	server, handlerDone := newStreamResumeServer(t)
+
+	// The client disconnects after receiving chunk 50
+	ctx, cancel := context.WithCancel(context.Background())
+	resp := postStream(t, ctx, server.URL, map[string]string{StreamResumeHeader: "true"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	received := readEvents(t, resp, 50)
+	cancel()
+	_ = resp.Body.Close()
+	require.Len(t, received, 50)
+	for i, event := range received {
+		require.Equal(t, fmt.Sprintf("req-resume:%d", i+1), event.id)
+		require.Equal(t, fmt.Sprintf(`{"n":%d}`, i+1), event.data)
+	}
+
+	// Reconnecting replays the missed chunks, then follows the live stream to the end
+	resp = postStream(t, context.Background(), server.URL, map[string]string{
+		StreamResumeHeader: "true",
+		lastEventIdHeader:  received[49].id,
+	})
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	resumed := readEvents(t, resp, 0)
+
+	require.Len(t, resumed, streamResumeTotalChunks-50+1)
+	for i, event := range resumed[:streamResumeTotalChunks-50] {
+		require.Equal(t, fmt.Sprintf("req-resume:%d", 51+i), event.id)
+		require.Equal(t, fmt.Sprintf(`{"n":%d}`, 51+i), event.data)
+	}
+	require.Equal(t, "[DONE]", resumed[len(resumed)-1].data)
+
+	select {
+	case <-handlerDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay did not finish after the client disconnected")
+	}
+}
+
+func TestStreamResume_Rejections(t *testing.T) {
+	setupStreamResumeTest(t)
+	server, handlerDone := newStreamResumeServer(t)
+
+	resp := postStream(t, context.Background(), server.URL, map[string]string{StreamResumeHeader: "true"})
+	require.Len(t, readEvents(t, resp, 0), streamResumeTotalChunks+1)
+	_ = resp.Body.Close()
+	<-handlerDone
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{"malformed id", map[string]string{lastEventIdHeader: "no-sequence"}, http.StatusBadRequest},
+		{"unknown stream", map[string]string{lastEventIdHeader: "missing:3"}, http.StatusNotFound},
+		{"other token", map[string]string{lastEventIdHeader: "req-resume:3", "X-Test-Token": "other"}, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.headers[StreamResumeHeader] = "true"
+			resp := postStream(t, context.Background(), server.URL, tc.headers)
+			defer resp.Body.Close()
+			require.Equal(t, tc.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestStreamResume_BufferCap(t *testing.T) {
+	setupStreamResumeTest(t)
+	ctx := context.Background()
+	for seq := 1; seq <= streamResumeMaxChunks+5; seq++ {
+		require.NoError(t, appendStreamChunk(ctx, "capped", 1, seq, fmt.Sprintf("data: %d", seq)))
+	}
+
+	snapshot, err := loadStream(ctx, "capped")
+	require.NoError(t, err)
+	require.Len(t, snapshot.Chunks, streamResumeMaxChunks)
+	require.Equal(t, 6, snapshot.FirstSeq)
+
+	_, err = snapshot.after(4)
+	require.ErrorIs(t, err, errStreamResumeGap)
+	chunks, err := snapshot.after(streamResumeMaxChunks + 3)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		fmt.Sprintf("data: %d", streamResumeMaxChunks+4),
+		fmt.Sprintf("data: %d", streamResumeMaxChunks+5),
+	}, chunks)
+}
+
+func TestStreamResume_SweepDropsExpiredStreams(t *testing.T) {
+	setupStreamResumeTest(t)
+	ctx := context.Background()
+	require.NoError(t, appendStreamChunk(ctx, "abandoned", 1, 1, "data: 1"))
+
+	sweepMemoryStreams(time.Now())
+	memoryStreams.Lock()
+	require.Contains(t, memoryStreams.streams, "abandoned")
+	memoryStreams.Unlock()
+
+	sweepMemoryStreams(time.Now().Add(streamResumeTTL() + time.Second))
+	memoryStreams.Lock()
+	require.NotContains(t, memoryStreams.streams, "abandoned")
+	memoryStreams.Unlock()
+}
+
+func TestStreamResume_PassesThroughWithoutHeader(t *testing.T) {
+	setupStreamResumeTest(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(StreamResume())
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		common.SetEventStreamHeaders(c)
+		c.Render(-1, common.CustomEvent{Data: "data: hello"})
+	})
+	router.POST("/v1/json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	require.Equal(t, "data: hello\n\n", w.Body.String())
+
+	// Non-SSE responses of resumable requests are not modified
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/json", nil)
+	req.Header.Set(StreamResumeHeader, "true")
+	router.ServeHTTP(w, req)
+	require.JSONEq(t, `{"ok":true}`, w.Body.String())
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/relay/billing/ratio"
+)
+
+// ErrorTypeTierRestriction is the error type returned when a request exceeds
+// the capabilities of the caller's subscription tier.
+const ErrorTypeTierRestriction = "tier_restriction"
+
+// tierRateLimitWindowSec is the sliding window used for per-tier relay limits.
+const tierRateLimitWindowSec = 60
+
+// TierGate returns a middleware that enforces the subscription tier assigned to
+// the caller's user group. It must run after Distribute so that ctxkey.Group is
+// populated. Groups without a tier pass through unchanged.
+//
+// Streaming requests are rejected for tiers without streaming, and relay
+// requests beyond the tier's per-minute budget are rejected with 429.
+func TierGate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tier := ratio.GetGroupTier(c.GetString(ctxkey.Group))
+		if tier == "" {
+			c.Next()
+			return
+		}
+
+		capabilities := config.GetTierCapabilities(tier)
+		if !capabilities.StreamingEnabled && isStreamRequest(c) {
+			abortWithTierRestriction(c, http.StatusForbidden,
+				fmt.Sprintf("streaming is not available on the %s tier", tier))
+			return
+		}
+
+		if limit := capabilities.RelayRequestsPerMinute; limit > 0 && !config.DebugEnabled {
+			key := fmt.Sprintf("rateLimit:TIER:%d", c.GetInt(ctxkey.Id))
+			var allowed bool
+			if common.IsRedisEnabled() {
+				allowed = checkRedisRateLimit(c, key, limit, tierRateLimitWindowSec)
+			} else {
+				inMemoryRateLimiter.Init(config.RateLimitKeyExpirationDuration)
+				allowed = inMemoryRateLimiter.Request(key, limit, tierRateLimitWindowSec)
+			}
+			if !allowed {
+				abortWithTierRestriction(c, http.StatusTooManyRequests,
+					fmt.Sprintf("the %s tier allows %d requests per minute", tier, limit))
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// isStreamRequest reports whether the JSON request body asks for a streaming response.
+func isStreamRequest(c *gin.Context) bool {
+	var req struct {
+		Stream bool `json:"stream" form:"stream"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		gmw.GetLogger(c).Debug("unable to inspect stream flag for tier check", zap.Error(err))
+		return false
+	}
+
+	return req.Stream
+}
+
+// abortWithTierRestriction aborts the request with a tier_restriction error body.
+func abortWithTierRestriction(c *gin.Context, statusCode int, message string) {
+	gmw.GetLogger(c).Info("request rejected by tier restriction",
+		zap.Int("status_code", statusCode),
+		zap.Int("user_id", c.GetInt(ctxkey.Id)),
+		zap.String("group", c.GetString(ctxkey.Group)),
+		zap.String("reason", message))
+	c.JSON(statusCode, gin.H{
+		"error": gin.H{
+			"message": helper.MessageWithRequestId(message, c.GetString(helper.RequestIdKey)),
+			"type":    ErrorTypeTierRestriction,
+		},
+	})
+	c.Abort()
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/relay/billing/ratio"
+)
+
+// newTierTestEngine builds an engine that runs TierGate for a user in group,
+// using the in-memory rate limiter.
+func newTierTestEngine(t *testing.T, userId int, group string) *gin.Engine {
+	t.Helper()
+	prevRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(prevRedis) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/v1/chat/completions",
+		func(c *gin.Context) {
+			c.Set(ctxkey.Id, userId)
+			c.Set(ctxkey.Group, group)
+			c.Next()
+		},
+		TierGate(),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func postChat(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestTierGate_FreeTierRejectsStreaming(t *testing.T) {
+	require.NoError(t, ratio.UpdateGroupTierByJSONString(`{"starter":"free"}`))
+	defer func() { require.NoError(t, ratio.UpdateGroupTierByJSONString(`{}`)) }()
+
+	w := postChat(newTierTestEngine(t, 910001, "starter"), `{"model":"gpt-4o","stream":true}`)
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	var resp struct {
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, ErrorTypeTierRestriction, resp.Error.Type)
+}
+
+func TestTierGate_FreeTierRateLimited(t *testing.T) {
+	require.NoError(t, ratio.UpdateGroupTierByJSONString(`{"starter":"free"}`))
+	defer func() { require.NoError(t, ratio.UpdateGroupTierByJSONString(`{}`)) }()
+
+	r := newTierTestEngine(t, 910002, "starter")
+	require.Equal(t, http.StatusOK, postChat(r, `{"model":"gpt-4o"}`).Code)
+	require.Equal(t, http.StatusTooManyRequests, postChat(r, `{"model":"gpt-4o"}`).Code)
+}
+
+func TestTierGate_ProTierAllowsStreaming(t *testing.T) {
+	require.NoError(t, ratio.UpdateGroupTierByJSONString(`{"team":"pro"}`))
+	defer func() { require.NoError(t, ratio.UpdateGroupTierByJSONString(`{}`)) }()
+
+	w := postChat(newTierTestEngine(t, 910003, "team"), `{"model":"gpt-4o","stream":true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestTierGate_NoTierPassesThrough(t *testing.T) {
+	w := postChat(newTierTestEngine(t, 910004, "default"), `{"model":"gpt-4o","stream":true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+}
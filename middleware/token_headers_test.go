@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+)
+
+const validTestTokenKey = "validtokenkey0123456789abcdef"
+
+// setupTokenHeaderTest configures the token headers and a database holding one
+// enabled token with key validTestTokenKey.
+func setupTokenHeaderTest(t *testing.T, headers ...string) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:token_headers_%d?mode=memory&cache=shared", time.Now().UnixNano())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Token{}))
+	require.NoError(t, db.Create(&model.Token{
+		Id: 1, UserId: 1, Key: validTestTokenKey, Status: model.TokenStatusEnabled,
+		Name: "test", ExpiredTime: -1, UnlimitedQuota: true,
+	}).Error)
+
+	originalDB := model.DB
+	originalRedis := common.IsRedisEnabled()
+	originalHeaders, originalPrefix := config.TokenHeaderNames, config.TokenKeyPrefix
+	model.DB = db
+	common.SetRedisEnabled(false)
+	config.TokenHeaderNames = headers
+	config.TokenKeyPrefix = "sk-"
+	t.Cleanup(func() {
+		model.DB = originalDB
+		common.SetRedisEnabled(originalRedis)
+		config.TokenHeaderNames, config.TokenKeyPrefix = originalHeaders, originalPrefix
+	})
+}
+
+// authenticateWithHeaders runs authenticateTokenHeaders for a request carrying headers.
+func authenticateWithHeaders(t *testing.T, headers map[string]string) (*model.Token, []string, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	for name, value := range headers {
+		c.Request.Header.Set(name, value)
+	}
+	gmw.SetLogger(c, logger.Logger)
+	return authenticateTokenHeaders(c)
+}
+
+func TestAuthenticateTokenHeaders_AcceptsEveryConfiguredHeader(t *testing.T) {
+	setupTokenHeaderTest(t, "Authorization", "X-API-Key", "api-key")
+
+	for _, headers := range []map[string]string{
+		{"Authorization": "Bearer sk-" + validTestTokenKey},
+		{"Authorization": "sk-" + validTestTokenKey},
+		{"X-API-Key": "sk-" + validTestTokenKey},
+		{"api-key": validTestTokenKey},
+	} {
+		token, parts, err := authenticateWithHeaders(t, headers)
+		require.NoError(t, err, headers)
+		require.Equal(t, 1, token.Id, headers)
+		require.Equal(t, []string{validTestTokenKey}, parts, headers)
+	}
+}
+
+func TestAuthenticateTokenHeaders_InvalidHeaderFallsThrough(t *testing.T) {
+	setupTokenHeaderTest(t, "Authorization", "X-API-Key", "api-key")
+
+	token, _, err := authenticateWithHeaders(t, map[string]string{
+		"Authorization": "Bearer sk-not-a-real-token",
+		"X-API-Key":     "also-invalid",
+		"api-key":       "sk-" + validTestTokenKey,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, token.Id)
+
+	_, _, err = authenticateWithHeaders(t, map[string]string{
+		"Authorization": "Bearer sk-not-a-real-token",
+		"X-API-Key":     "also-invalid",
+	})
+	require.ErrorContains(t, err, "token not found")
+}
+
+func TestAuthenticateTokenHeaders_IgnoresUnconfiguredHeaders(t *testing.T) {
+	setupTokenHeaderTest(t, "Authorization")
+
+	_, _, err := authenticateWithHeaders(t, map[string]string{"api-key": validTestTokenKey})
+	require.ErrorContains(t, err, "No token provided")
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestTokenRateLimit(t *testing.T) {
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(originalRedis) })
+
+	gin.SetMode(gin.TestMode)
+	newRouter := func(tokenId, rpmLimit, tpmLimit int) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set(ctxkey.TokenId, tokenId)
+			c.Set(ctxkey.TokenRpmLimit, rpmLimit)
+			c.Set(ctxkey.TokenTpmLimit, tpmLimit)
+		}, TokenRateLimit())
+		router.POST("/v1/chat/completions", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	serve := func(router *gin.Engine) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+		return w
+	}
+
+	t.Run("rpm", func(t *testing.T) {
+		router := newRouter(920001, 2, 0)
+		w := serve(router)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "2", w.Header().Get(RateLimitLimitRequestsHeader))
+		require.Equal(t, "1", w.Header().Get(RateLimitRemainingRequestsHeader))
+		require.Empty(t, w.Header().Get(RateLimitLimitTokensHeader))
+
+		require.Equal(t, http.StatusOK, serve(router).Code)
+		w = serve(router)
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		require.Equal(t, "0", w.Header().Get(RateLimitRemainingRequestsHeader))
+	})
+
+	t.Run("tpm", func(t *testing.T) {
+		originalTpm := config.DefaultTokenTpmLimit
+		config.DefaultTokenTpmLimit = 100
+		t.Cleanup(func() { config.DefaultTokenTpmLimit = originalTpm })
+
+		router := newRouter(920002, 0, 100)
+		w := serve(router)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "100", w.Header().Get(RateLimitLimitTokensHeader))
+		require.Equal(t, "100", w.Header().Get(RateLimitRemainingTokensHeader))
+
+		model.RecordTokenTpmUsage(t.Context(), 920002, 100, time.Now())
+		w = serve(router)
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		require.Equal(t, "0", w.Header().Get(RateLimitRemainingTokensHeader))
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		router := newRouter(920003, 0, 0)
+		for range 5 {
+			w := serve(router)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Empty(t, w.Header().Get(RateLimitLimitRequestsHeader))
+		}
+	})
+}
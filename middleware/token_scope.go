@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+// tokenScopeForRelayMode returns the token scope required by a relay mode.
+// Modes outside the scope model, such as the proxy, batch and file endpoints,
+// return an empty scope; only tokens without explicit scopes may call them,
+// see tokenScopeError.
+func tokenScopeForRelayMode(mode int) string {
+	switch mode {
+	case relaymode.ChatCompletions, relaymode.Completions, relaymode.Edits,
+		relaymode.Moderations, relaymode.ClaudeMessages, relaymode.Realtime:
+		return model.TokenScopeChat
+	case relaymode.Embeddings, relaymode.Rerank:
+		return model.TokenScopeEmbeddings
+	case relaymode.ImagesGenerations, relaymode.ImagesEdits, relaymode.Videos:
+		return model.TokenScopeImages
+	case relaymode.AudioSpeech, relaymode.AudioTranscription,
+		relaymode.AudioTranslation, relaymode.TextToSpeech:
+		return model.TokenScopeAudio
+	case relaymode.ResponseAPI:
+		return model.TokenScopeResponses
+	default:
+		return ""
+	}
+}
+
+// tokenScopeError returns why token may not call the relay endpoint of mode,
+// or nil when it may. Tokens restricted to explicit scopes are refused relay
+// modes outside the scope model, which no scope grants.
+func tokenScopeError(token *model.Token, mode int) error {
+	if mode == relaymode.Unknown {
+		return nil
+	}
+	scope := tokenScopeForRelayMode(mode)
+	switch {
+	case scope != "" && !token.HasScope(scope):
+		return errors.Errorf("This API key does not have the %q scope required by this endpoint, allowed scopes: %s",
+			scope, strings.Join(token.EffectiveScopes(), ","))
+	case scope == "" && token.HasExplicitScopes():
+		return errors.Errorf("This API key is restricted to the scopes %s and cannot call this endpoint",
+			strings.Join(token.EffectiveScopes(), ","))
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+func TestTokenScopeForRelayMode(t *testing.T) {
+	require.Equal(t, model.TokenScopeChat, tokenScopeForRelayMode(relaymode.ChatCompletions))
+	require.Equal(t, model.TokenScopeChat, tokenScopeForRelayMode(relaymode.ClaudeMessages))
+	require.Equal(t, model.TokenScopeEmbeddings, tokenScopeForRelayMode(relaymode.Rerank))
+	require.Equal(t, model.TokenScopeImages, tokenScopeForRelayMode(relaymode.ImagesEdits))
+	require.Equal(t, model.TokenScopeAudio, tokenScopeForRelayMode(relaymode.AudioTranscription))
+	require.Equal(t, model.TokenScopeResponses, tokenScopeForRelayMode(relaymode.ResponseAPI))
+	require.Empty(t, tokenScopeForRelayMode(relaymode.Unknown))
+	require.Empty(t, tokenScopeForRelayMode(relaymode.Files))
+}
+
+func TestTokenAuth_EnforcesScopes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:token_scope_%d?mode=memory&cache=shared", time.Now().UnixNano())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Token{}))
+	require.NoError(t, db.Create(&model.User{
+		Id: 4201, Username: "scope-user", Status: model.UserStatusEnabled,
+	}).Error)
+	scopes := "embeddings"
+	require.NoError(t, db.Create(&model.Token{
+		Id: 1, UserId: 4201, Key: validTestTokenKey, Status: model.TokenStatusEnabled,
+		Name: "test", ExpiredTime: -1, UnlimitedQuota: true, Scopes: &scopes,
+	}).Error)
+
+	originalDB := model.DB
+	originalRedis := common.IsRedisEnabled()
+	originalHeaders, originalPrefix := config.TokenHeaderNames, config.TokenKeyPrefix
+	model.DB = db
+	common.SetRedisEnabled(false)
+	config.TokenHeaderNames = []string{"Authorization"}
+	config.TokenKeyPrefix = "sk-"
+	t.Cleanup(func() {
+		model.DB = originalDB
+		common.SetRedisEnabled(originalRedis)
+		config.TokenHeaderNames, config.TokenKeyPrefix = originalHeaders, originalPrefix
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(TokenAuth())
+	router.POST("/v1/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{"model":"text-embedding-3-small"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer sk-"+validTestTokenKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, request("/v1/embeddings").Code)
+
+	w := request("/v1/chat/completions")
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), `\"chat\" scope`)
+
+	// No scope grants the file and batch endpoints to a scoped token
+	require.Equal(t, http.StatusForbidden, request("/v1/files").Code)
+	require.Equal(t, http.StatusForbidden, request("/v1/batches").Code)
+}
+
+func TestTokenScopeError(t *testing.T) {
+	unscoped := &model.Token{}
+	scopes := "chat"
+	scoped := &model.Token{Scopes: &scopes}
+
+	require.NoError(t, tokenScopeError(unscoped, relaymode.Files))
+	require.NoError(t, tokenScopeError(unscoped, relaymode.Proxy))
+	require.NoError(t, tokenScopeError(scoped, relaymode.ChatCompletions))
+	require.NoError(t, tokenScopeError(scoped, relaymode.Unknown))
+	require.Error(t, tokenScopeError(scoped, relaymode.Embeddings))
+	require.Error(t, tokenScopeError(scoped, relaymode.Proxy))
+	require.Error(t, tokenScopeError(scoped, relaymode.Batches))
+}
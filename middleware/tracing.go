@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/tracing"
 	"github.com/songquanpeng/one-api/model"
 )
@@ -10,6 +13,7 @@ import (
 // TracingMiddleware creates a middleware that records request tracing information
 func TracingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		c.Set(ctxkey.RequestReceivedAt, time.Now().UTC())
 		// Record the start of the request
 		tracing.RecordTraceStart(c)
 
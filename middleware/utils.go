@@ -11,6 +11,7 @@ import (
 
 	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/relay/model"
 )
@@ -91,17 +92,62 @@ func isModelInList(modelName string, models string) bool {
 	return slices.Contains(modelList, modelName)
 }
 
-// GetTokenKeyParts extracts the token key parts from the Authorization header
+// TokenKeyCandidate is an API token found in one of config.TokenHeaderNames.
+type TokenKeyCandidate struct {
+	// Header is the request header the token was read from.
+	Header string
+	// Parts holds the token key and, optionally, a channel id.
+	Parts []string
+}
+
+// GetTokenKeyCandidates returns the tokens carried by the headers listed in
+// config.TokenHeaderNames, in configured order, skipping empty headers.
+//
+// key like `sk-{token}[-{channelid}]`
+func GetTokenKeyCandidates(c *gin.Context) []TokenKeyCandidate {
+	headers := config.TokenHeaderNames
+	if len(headers) == 0 {
+		headers = []string{"Authorization"}
+	}
+
+	candidates := make([]TokenKeyCandidate, 0, len(headers))
+	for _, header := range headers {
+		key := strings.TrimSpace(c.Request.Header.Get(header))
+		if strings.EqualFold(header, "Authorization") {
+			key = strings.TrimSpace(strings.TrimPrefix(key, "Bearer "))
+		}
+		if key == "" {
+			continue
+		}
+		candidates = append(candidates, TokenKeyCandidate{Header: header, Parts: splitTokenKey(key)})
+	}
+
+	return candidates
+}
+
+// GetTokenKeyParts extracts the token key parts from the first configured token
+// header that is present on the request.
 //
 // key like `sk-{token}[-{channelid}]`
 func GetTokenKeyParts(c *gin.Context) []string {
-	key := c.Request.Header.Get("Authorization")
-	if key == "" {
-		// compatible with Anthropic
-		key = c.Request.Header.Get("X-Api-Key")
+	if candidates := GetTokenKeyCandidates(c); len(candidates) > 0 {
+		return candidates[0].Parts
 	}
+	return []string{""}
+}
+
+// authenticatedTokenKey returns the key of the token TokenAuth authenticated
+// the request with, falling back to the first token header present.
+func authenticatedTokenKey(c *gin.Context) string {
+	if key := c.GetString(ctxkey.TokenKey); key != "" {
+		return key
+	}
+	return GetTokenKeyParts(c)[0]
+}
 
-	key = strings.TrimPrefix(key, "Bearer ")
+// splitTokenKey strips known token prefixes from key and splits off the
+// optional channel id.
+func splitTokenKey(key string) []string {
 	// Trim current configured prefix first
 	if p := config.TokenKeyPrefix; p != "" {
 		key = strings.TrimPrefix(key, p)
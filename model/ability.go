@@ -12,6 +12,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/random"
 	"github.com/songquanpeng/one-api/common/utils"
 	"github.com/songquanpeng/one-api/dto"
 )
@@ -49,11 +50,7 @@ func GetRandomSatisfiedChannel(group string, model string, ignoreFirstPriority b
 		maxPrioritySubQuery := DB.Model(&Ability{}).Select("MAX(priority)").Where(groupCol+" = ? AND model = ? AND enabled = "+trueVal+" AND (suspend_until IS NULL OR suspend_until < ?)", group, model, now)
 		channelQuery = DB.Where(groupCol+" = ? AND model = ? AND enabled = "+trueVal+" AND priority = (?) AND (suspend_until IS NULL OR suspend_until < ?)", group, model, maxPrioritySubQuery, now)
 	}
-	if common.UsingSQLite.Load() || common.UsingPostgreSQL.Load() {
-		err = channelQuery.Order("RANDOM()").First(&ability).Error
-	} else {
-		err = channelQuery.Order("RAND()").First(&ability).Error
-	}
+	ability.ChannelId, err = pickAbilityChannelId(channelQuery, group, model)
 	if err != nil {
 		return nil, errors.Wrap(err, "get random satisfied channel")
 	}
@@ -71,6 +68,12 @@ func GetRandomSatisfiedChannel(group string, model string, ignoreFirstPriority b
 }
 
 func (channel *Channel) AddAbilities() error {
+	return channel.addAbilities(DB)
+}
+
+// addAbilities creates the abilities of this channel using db, which may be a
+// transaction.
+func (channel *Channel) addAbilities(db *gorm.DB) error {
 	models_ := strings.Split(channel.Models, ",")
 	models_ = utils.DeDuplication(models_)
 	groups_ := strings.Split(channel.Group, ",")
@@ -88,24 +91,34 @@ func (channel *Channel) AddAbilities() error {
 			abilities = append(abilities, ability)
 		}
 	}
-	return DB.Create(&abilities).Error
+	return db.Create(&abilities).Error
 }
 
 func (channel *Channel) DeleteAbilities() error {
-	return DB.Where("channel_id = ?", channel.Id).Delete(&Ability{}).Error
+	return channel.deleteAbilities(DB)
+}
+
+// deleteAbilities deletes the abilities of this channel using db.
+func (channel *Channel) deleteAbilities(db *gorm.DB) error {
+	return db.Where("channel_id = ?", channel.Id).Delete(&Ability{}).Error
 }
 
 // UpdateAbilities updates abilities of this channel.
 // Make sure the channel is completed before calling this function.
 func (channel *Channel) UpdateAbilities() error {
+	return channel.updateAbilities(DB)
+}
+
+// updateAbilities rebuilds the abilities of this channel using db.
+func (channel *Channel) updateAbilities(db *gorm.DB) error {
 	// A quick and dirty way to update abilities
 	// First delete all abilities of this channel
-	err := channel.DeleteAbilities()
+	err := channel.deleteAbilities(db)
 	if err != nil {
 		return errors.Wrapf(err, "delete abilities for channel %d", channel.Id)
 	}
 	// Then add new abilities
-	err = channel.AddAbilities()
+	err = channel.addAbilities(db)
 	if err != nil {
 		return errors.Wrapf(err, "add abilities for channel %d", channel.Id)
 	}
@@ -186,10 +199,15 @@ func SuspendAbility(ctx context.Context, group string, modelName string, channel
 		channelCol = `"channel_id"`
 	}
 
-	return DB.Model(&Ability{}).
+	err := DB.Model(&Ability{}).
 		Where(groupCol+" = ? AND "+modelCol+" = ? AND "+channelCol+" = ?",
 			group, modelName, channelId).
 		Update("suspend_until", suspendTime).Error
+	if err != nil {
+		return err
+	}
+	publishChannelSuspension(group, modelName, channelId, suspendTime)
+	return nil
 }
 
 func GetRandomSatisfiedChannelExcluding(group string, model string, ignoreFirstPriority bool, excludeChannelIds map[int]bool) (*Channel, error) {
@@ -282,11 +300,7 @@ func GetRandomSatisfiedChannelExcluding(group string, model string, ignoreFirstP
 		}
 	}
 
-	if common.UsingSQLite.Load() || common.UsingPostgreSQL.Load() {
-		err = channelQuery.Order("RANDOM()").First(&ability).Error
-	} else {
-		err = channelQuery.Order("RAND()").First(&ability).Error
-	}
+	ability.ChannelId, err = pickAbilityChannelId(channelQuery, group, model)
 	if err != nil {
 		return nil, errors.Wrap(err, "get random satisfied channel excluding failed ones")
 	}
@@ -302,3 +316,33 @@ func GetRandomSatisfiedChannelExcluding(group string, model string, ignoreFirstP
 	}
 	return &channel, nil
 }
+
+// pickAbilityChannelId selects the channel of one of the abilities matched by
+// query, like the memory cache path does: channels with different weights are
+// picked by smooth weighted round-robin, otherwise by queue depth when queue
+// depth routing is enabled, or uniformly.
+func pickAbilityChannelId(query *gorm.DB, group string, model string) (int, error) {
+	var abilities []Ability
+	if err := query.Find(&abilities).Error; err != nil {
+		return 0, err
+	}
+	if len(abilities) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+
+	channelIds := make([]int, 0, len(abilities))
+	for _, ability := range abilities {
+		channelIds = append(channelIds, ability.ChannelId)
+	}
+	var candidates []*Channel
+	if err := DB.Select("id", "weight").Where("id IN ?", channelIds).Find(&candidates).Error; err != nil {
+		return 0, errors.Wrap(err, "load channel weights")
+	}
+	if channel, ok := pickWeightedChannel(context.Background(), group, model, candidates); ok {
+		return channel.Id, nil
+	}
+	if len(candidates) > 0 {
+		return pickChannelByQueueDepth(context.Background(), candidates).Id, nil
+	}
+	return abilities[random.RandRange(0, len(abilities))].ChannelId, nil
+}
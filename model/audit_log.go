@@ -0,0 +1,258 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// Target types of audit log entries.
+const (
+	AuditTargetUser         = "user"
+	AuditTargetChannel      = "channel"
+	AuditTargetToken        = "token"
+	AuditTargetOption       = "option"
+	AuditTargetRedemption   = "redemption"
+	AuditTargetModelPricing = "model_pricing"
+	AuditTargetGroup        = "group"
+	AuditTargetBlacklist    = "blacklist"
+	AuditTargetLog          = "log"
+)
+
+// Actions of audit log entries.
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// auditRedactedFields lists the JSON fields whose values never reach the
+// audit log. Names are compared case-insensitively at any depth.
+var auditRedactedFields = map[string]bool{
+	"password":     true,
+	"key":          true,
+	"access_token": true,
+	"totp_secret":  true,
+	"secret":       true,
+	// Channel.Config credentials of AWS, Vertex AI and similar channels
+	"sk":            true,
+	"ak":            true,
+	"vertex_ai_adc": true,
+}
+
+// auditEmbeddedJSONFields lists the fields holding a JSON document serialized
+// as a string, such as Channel.Config, which are parsed so the credentials
+// inside them are redacted too.
+var auditEmbeddedJSONFields = map[string]bool{
+	"config": true,
+	"other":  true,
+}
+
+const auditRedactedPlaceholder = "[REDACTED]"
+
+// AuditValue is a JSON document stored as text, rendered as embedded JSON in
+// API responses.
+type AuditValue json.RawMessage
+
+// Value implements driver.Valuer.
+func (v AuditValue) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	return string(v), nil
+}
+
+// Scan implements sql.Scanner.
+func (v *AuditValue) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		*v = nil
+	case string:
+		*v = AuditValue(data)
+	case []byte:
+		*v = append(AuditValue(nil), data...)
+	default:
+		return errors.Errorf("unsupported audit value type %T", src)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v AuditValue) MarshalJSON() ([]byte, error) {
+	if len(v) == 0 {
+		return []byte("null"), nil
+	}
+	return v, nil
+}
+
+// AuditLog records one write operation performed by an administrator.
+type AuditLog struct {
+	Id         int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	AdminId    int    `json:"admin_id" gorm:"index"`
+	TargetType string `json:"target_type" gorm:"type:varchar(32);index:idx_audit_target,priority:1"`
+	// TargetId identifies the target within its type, e.g. a user id or an option key.
+	TargetId  string     `json:"target_id" gorm:"type:varchar(255);index:idx_audit_target,priority:2"`
+	Action    string     `json:"action" gorm:"type:varchar(64);index"`
+	OldValue  AuditValue `json:"old_value" gorm:"type:text"`
+	NewValue  AuditValue `json:"new_value" gorm:"type:text"`
+	RequestId string     `json:"request_id" gorm:"type:varchar(64);default:''"`
+	Ip        string     `json:"ip" gorm:"type:varchar(64);default:''"`
+	CreatedAt int64      `json:"created_at" gorm:"bigint;index"`
+}
+
+// TableName stores audit entries in audit_logs.
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// auditValue serializes value for the audit log with credentials redacted.
+// Nil values, including nil pointers, are stored as NULL.
+func auditValue(value any) (AuditValue, error) {
+	if value == nil {
+		return nil, nil
+	}
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal audit value")
+	}
+	var doc any
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, errors.Wrap(err, "unmarshal audit value")
+	}
+	if doc == nil { // e.g. a nil pointer
+		return nil, nil
+	}
+	if payload, err = json.Marshal(redactAuditValue(doc)); err != nil {
+		return nil, errors.Wrap(err, "marshal redacted audit value")
+	}
+	return AuditValue(payload), nil
+}
+
+func redactAuditValue(doc any) any {
+	switch value := doc.(type) {
+	case map[string]any:
+		for field, nested := range value {
+			if auditRedactedFields[strings.ToLower(field)] {
+				if nested != nil && nested != "" {
+					value[field] = auditRedactedPlaceholder
+				}
+				continue
+			}
+			if encoded, ok := nested.(string); ok && auditEmbeddedJSONFields[strings.ToLower(field)] {
+				value[field] = redactEmbeddedAuditJSON(encoded)
+				continue
+			}
+			value[field] = redactAuditValue(nested)
+		}
+	case []any:
+		for i, nested := range value {
+			value[i] = redactAuditValue(nested)
+		}
+	}
+	return doc
+}
+
+// redactEmbeddedAuditJSON redacts the JSON object or array serialized in
+// encoded. Other strings are returned unchanged.
+func redactEmbeddedAuditJSON(encoded string) string {
+	var doc any
+	if json.Unmarshal([]byte(encoded), &doc) != nil {
+		return encoded
+	}
+	switch doc.(type) {
+	case map[string]any, []any:
+	default:
+		return encoded
+	}
+	payload, err := json.Marshal(redactAuditValue(doc))
+	if err != nil {
+		return encoded
+	}
+	return string(payload)
+}
+
+// RecordAuditLog records that adminId performed action on the target
+// identified by targetType and targetId, changing it from oldValue to
+// newValue. Either value may be nil, e.g. for creations and deletions; both
+// are stored as JSON with credentials redacted. The request id and client IP
+// are taken from the gin context behind ctx when there is one. Failures are
+// logged rather than returned so auditing never fails the operation itself.
+func RecordAuditLog(ctx context.Context, adminId int, targetType string, targetId any, action string, oldValue any, newValue any) {
+	entry := &AuditLog{
+		AdminId:    adminId,
+		TargetType: targetType,
+		TargetId:   fmt.Sprint(targetId),
+		Action:     action,
+		CreatedAt:  helper.GetTimestamp(),
+	}
+	if c, ok := gmw.GetGinCtxFromStdCtx(ctx); ok {
+		entry.RequestId = c.GetString(helper.RequestIdKey)
+		entry.Ip = c.ClientIP()
+	}
+
+	var err error
+	if entry.OldValue, err = auditValue(oldValue); err == nil {
+		entry.NewValue, err = auditValue(newValue)
+	}
+	if err == nil {
+		err = DB.WithContext(context.WithoutCancel(ctx)).Create(entry).Error
+	}
+	if err != nil {
+		logger.Logger.Error("failed to record audit log",
+			zap.Int("admin_id", adminId),
+			zap.String("target_type", targetType),
+			zap.String("target_id", entry.TargetId),
+			zap.String("action", action),
+			zap.Error(err))
+	}
+}
+
+// AuditLogFilters selects audit log entries. Zero values match everything;
+// the timestamps bound created_at inclusively.
+type AuditLogFilters struct {
+	AdminId        int
+	TargetType     string
+	Action         string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+// GetAuditLogs returns a page of the entries matching filters, newest first,
+// together with the number of matching entries.
+func GetAuditLogs(ctx context.Context, filters AuditLogFilters, startIdx int, num int) ([]*AuditLog, int64, error) {
+	tx := DB.WithContext(ctx).Model(&AuditLog{})
+	if filters.AdminId != 0 {
+		tx = tx.Where("admin_id = ?", filters.AdminId)
+	}
+	if filters.TargetType != "" {
+		tx = tx.Where("target_type = ?", filters.TargetType)
+	}
+	if filters.Action != "" {
+		tx = tx.Where("action = ?", filters.Action)
+	}
+	if filters.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", filters.StartTimestamp)
+	}
+	if filters.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", filters.EndTimestamp)
+	}
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "count audit logs")
+	}
+	var entries []*AuditLog
+	if err := tx.Order("id desc").Limit(num).Offset(startIdx).Find(&entries).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "list audit logs")
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRecordAuditLog(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&AuditLog{}))
+	originalDB := DB
+	DB = db
+	t.Cleanup(func() { DB = originalDB })
+
+	ctx := context.Background()
+	RecordAuditLog(ctx, 1, AuditTargetChannel, 7, AuditActionUpdate,
+		&Channel{Id: 7, Name: "old", Key: "sk-old"},
+		map[string]any{"name": "new", "config": map[string]any{"secret": "s3cr3t", "region": "us"}})
+	RecordAuditLog(ctx, 2, AuditTargetOption, "Theme", AuditActionUpdate, map[string]any{"value": "default"}, map[string]any{"value": "air"})
+	RecordAuditLog(ctx, 1, AuditTargetUser, 3, AuditActionDelete, nil, nil)
+
+	entries, total, err := GetAuditLogs(ctx, AuditLogFilters{AdminId: 1}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, entries, 2)
+	require.Equal(t, AuditActionDelete, entries[0].Action) // newest first
+	require.Nil(t, entries[0].OldValue)
+	require.Nil(t, entries[0].NewValue)
+
+	update := entries[1]
+	require.Equal(t, "7", update.TargetId)
+	var oldValue, newValue map[string]any
+	require.NoError(t, json.Unmarshal(update.OldValue, &oldValue))
+	require.NoError(t, json.Unmarshal(update.NewValue, &newValue))
+	require.Equal(t, "old", oldValue["name"])
+	require.Equal(t, auditRedactedPlaceholder, oldValue["key"])
+	require.Equal(t, map[string]any{"secret": auditRedactedPlaceholder, "region": "us"}, newValue["config"])
+
+	entries, total, err = GetAuditLogs(ctx, AuditLogFilters{TargetType: AuditTargetOption, Action: AuditActionUpdate}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, "Theme", entries[0].TargetId)
+	require.Equal(t, 2, entries[0].AdminId)
+
+	entries, total, err = GetAuditLogs(ctx, AuditLogFilters{}, 1, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, total)
+	require.Len(t, entries, 1)
+	require.Equal(t, AuditTargetOption, entries[0].TargetType)
+}
+
+func TestRecordAuditLog_RedactsChannelConfig(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&AuditLog{}))
+	originalDB := DB
+	DB = db
+	t.Cleanup(func() { DB = originalDB })
+
+	awsConfig, err := json.Marshal(ChannelConfig{Region: "us-east-1", AK: "AKIAEXAMPLE", SK: "aws-secret-key"})
+	require.NoError(t, err)
+	vertexConfig, err := json.Marshal(ChannelConfig{Region: "us-central1", VertexAIProjectID: "proj", VertexAIADC: `{"private_key":"pk"}`})
+	require.NoError(t, err)
+	other := `{"secret":"legacy"}`
+
+	ctx := context.Background()
+	RecordAuditLog(ctx, 1, AuditTargetChannel, 1, AuditActionCreate, nil, &Channel{Id: 1, Config: string(awsConfig)})
+	RecordAuditLog(ctx, 1, AuditTargetChannel, 2, AuditActionCreate, nil, &Channel{Id: 2, Config: string(vertexConfig), Other: &other})
+
+	entries, _, err := GetAuditLogs(ctx, AuditLogFilters{AdminId: 1}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.NotContains(t, string(entry.NewValue), "AKIAEXAMPLE")
+		require.NotContains(t, string(entry.NewValue), "aws-secret-key")
+		require.NotContains(t, string(entry.NewValue), "private_key")
+		require.NotContains(t, string(entry.NewValue), "legacy")
+	}
+
+	var vertex, aws map[string]any
+	require.NoError(t, json.Unmarshal(entries[0].NewValue, &vertex))
+	require.NoError(t, json.Unmarshal(entries[1].NewValue, &aws))
+	require.JSONEq(t, `{"region":"us-east-1","ak":"[REDACTED]","sk":"[REDACTED]"}`, aws["config"].(string))
+	require.JSONEq(t, `{"region":"us-central1","vertex_ai_project_id":"proj","vertex_ai_adc":"[REDACTED]"}`, vertex["config"].(string))
+	require.JSONEq(t, `{"secret":"[REDACTED]"}`, vertex["other"].(string))
+}
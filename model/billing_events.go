@@ -0,0 +1,168 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/statesync"
+)
+
+// billingEventQueueSize bounds the billing events waiting to be published.
+// When the queue is full events are published inline rather than dropped.
+const billingEventQueueSize = 4096
+
+// billingEventPublishWorkers is the number of goroutines publishing queued
+// billing events.
+const billingEventPublishWorkers = 4
+
+// BillingEvent is the token usage of one request, published to external
+// billing systems after its consume log is recorded.
+type BillingEvent struct {
+	UserId           int    `json:"user_id"`
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Quota            int    `json:"quota"`
+	// Timestamp is the creation time of the consume log, in unix seconds.
+	Timestamp int64  `json:"timestamp"`
+	RequestId string `json:"request_id"`
+}
+
+// BillingEventChannel returns the pub/sub channel carrying the billing events of userId.
+func BillingEventChannel(userId int) string {
+	return fmt.Sprintf("oneapi:billing:%d", userId)
+}
+
+// billingEventPublisher publishes billing events off the request path.
+type billingEventPublisher struct {
+	transport statesync.Transport
+	queue     chan BillingEvent
+}
+
+var activeBillingEventPublisher atomic.Pointer[billingEventPublisher]
+
+// InitBillingEventPublisher starts publishing billing events over the main
+// Redis when REALTIME_BILLING_PUBSUB_ENABLED is set.
+func InitBillingEventPublisher(ctx context.Context) error {
+	if !config.RealtimeBillingPubSubEnabled {
+		return nil
+	}
+
+	transport, err := statesync.NewRedisTransport(ctx, "")
+	if err != nil {
+		return errors.Wrap(err, "create billing event transport")
+	}
+	StartBillingEventPublisher(ctx, transport)
+	logger.Logger.Info("real-time billing events enabled")
+	return nil
+}
+
+// StartBillingEventPublisher publishes the billing events of consume logs
+// recorded from now on through transport until ctx is done.
+func StartBillingEventPublisher(ctx context.Context, transport statesync.Transport) {
+	p := &billingEventPublisher{
+		transport: transport,
+		queue:     make(chan BillingEvent, billingEventQueueSize),
+	}
+	for range billingEventPublishWorkers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-p.queue:
+					p.send(ctx, event)
+				}
+			}
+		}()
+	}
+	activeBillingEventPublisher.Store(p)
+
+	go func() {
+		<-ctx.Done()
+		activeBillingEventPublisher.CompareAndSwap(p, nil)
+	}()
+}
+
+func (p *billingEventPublisher) send(ctx context.Context, event BillingEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.Logger.Error("failed to marshal billing event", zap.Error(err))
+		return
+	}
+	if err = p.transport.Publish(ctx, BillingEventChannel(event.UserId), payload); err != nil {
+		logger.Logger.Warn("failed to publish billing event",
+			zap.Int("user_id", event.UserId),
+			zap.String("request_id", event.RequestId),
+			zap.Error(err))
+	}
+}
+
+// publishBillingEvent announces the usage of a consume log when real-time
+// billing events are enabled.
+func publishBillingEvent(log *Log) {
+	p := activeBillingEventPublisher.Load()
+	if p == nil {
+		return
+	}
+
+	timestamp := log.CreatedAt
+	if timestamp == 0 {
+		timestamp = time.Now().UTC().Unix()
+	}
+	event := BillingEvent{
+		UserId:           log.UserId,
+		Model:            log.ModelName,
+		PromptTokens:     log.PromptTokens,
+		CompletionTokens: log.CompletionTokens,
+		Quota:            log.Quota,
+		Timestamp:        timestamp,
+		RequestId:        log.RequestId,
+	}
+	select {
+	case p.queue <- event:
+	default:
+		// External billing must see every request, so fall back to publishing inline
+		p.send(context.Background(), event)
+	}
+}
+
+// SubscribeToBillingEvents delivers the billing events of userId until ctx is
+// done. It fails when real-time billing events are not enabled.
+func SubscribeToBillingEvents(ctx context.Context, userID int) (<-chan BillingEvent, error) {
+	p := activeBillingEventPublisher.Load()
+	if p == nil {
+		return nil, errors.New("real-time billing events are not enabled")
+	}
+
+	payloads, err := p.transport.Subscribe(ctx, BillingEventChannel(userID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "subscribe to billing events of user %d", userID)
+	}
+
+	events := make(chan BillingEvent)
+	go func() {
+		defer close(events)
+		for payload := range payloads {
+			var event BillingEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				logger.Logger.Warn("ignoring malformed billing event", zap.Error(err))
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/statesync"
+)
+
+func setupBillingEventTest(t *testing.T) context.Context {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "billing.db")), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&User{}, &Log{}))
+	require.NoError(t, db.Create(&User{Id: 7, Username: "billing-user", Password: "hashed-password"}).Error)
+
+	originalDB, originalLogDB := DB, LOG_DB
+	DB, LOG_DB = db, db
+	originalLogConsume := config.IsLogConsumeEnabled()
+	config.SetLogConsumeEnabled(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	StartBillingEventPublisher(ctx, statesync.NewMemoryBroker())
+	t.Cleanup(func() {
+		cancel()
+		activeBillingEventPublisher.Store(nil)
+		config.SetLogConsumeEnabled(originalLogConsume)
+		DB, LOG_DB = originalDB, originalLogDB
+		_ = sqlDB.Close()
+	})
+	return ctx
+}
+
+func TestBillingEvents_PublishedAfterConsumeLog(t *testing.T) {
+	ctx := setupBillingEventTest(t)
+	events, err := SubscribeToBillingEvents(ctx, 7)
+	require.NoError(t, err)
+	otherUser, err := SubscribeToBillingEvents(ctx, 8)
+	require.NoError(t, err)
+
+	RecordConsumeLog(ctx, &Log{
+		UserId:           7,
+		ModelName:        "gpt-4o",
+		PromptTokens:     100,
+		CompletionTokens: 50,
+		Quota:            200,
+		RequestId:        "req-billing-1",
+	})
+	inserted := time.Now()
+
+	select {
+	case event := <-events:
+		require.Less(t, time.Since(inserted), 100*time.Millisecond)
+		require.Equal(t, 7, event.UserId)
+		require.Equal(t, "gpt-4o", event.Model)
+		require.Equal(t, 100, event.PromptTokens)
+		require.Equal(t, 50, event.CompletionTokens)
+		require.Equal(t, 200, event.Quota)
+		require.Equal(t, "req-billing-1", event.RequestId)
+		require.NotZero(t, event.Timestamp)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("billing event was not published within 100ms")
+	}
+
+	var stored Log
+	require.NoError(t, LOG_DB.Where("request_id = ?", "req-billing-1").First(&stored).Error)
+	require.Equal(t, "billing-user", stored.Username)
+
+	// Events only reach the subscribers of their user
+	select {
+	case event := <-otherUser:
+		t.Fatalf("unexpected event for user 8: %+v", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestBillingEvents_NoEventsDroppedUnderLoad(t *testing.T) {
+	ctx := setupBillingEventTest(t)
+	events, err := SubscribeToBillingEvents(ctx, 7)
+	require.NoError(t, err)
+
+	const total = 1000
+	received := make(map[string]bool, total)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range events {
+			received[event.RequestId] = true
+			if len(received) == total {
+				return
+			}
+		}
+	}()
+
+	// 1000 requests spread over one second
+	var wg sync.WaitGroup
+	ticker := time.NewTicker(time.Second / total)
+	defer ticker.Stop()
+	for i := range total {
+		<-ticker.C
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RecordConsumeLog(ctx, &Log{
+				UserId:    7,
+				ModelName: "gpt-4o",
+				Quota:     1,
+				RequestId: fmt.Sprintf("req-load-%d", i),
+			})
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("received %d of %d billing events", len(received), total)
+	}
+	require.Len(t, received, total)
+}
+
+func TestSubscribeToBillingEvents_Disabled(t *testing.T) {
+	activeBillingEventPublisher.Store(nil)
+	_, err := SubscribeToBillingEvents(context.Background(), 1)
+	require.Error(t, err)
+	// Recording consume logs must not require the publisher
+	publishBillingEvent(&Log{UserId: 1})
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"context"
+
+	"github.com/Laisky/errors/v2"
+)
+
+// BillingReconciliation records the quota of one user that was reported to the
+// external billing webhook for the period [PeriodStart, PeriodEnd) of Unix
+// seconds. The latest PeriodEnd is where the next reconciliation starts, so
+// every consume log is reported exactly once.
+type BillingReconciliation struct {
+	Id            int     `json:"id" gorm:"primaryKey;autoIncrement"`
+	PeriodStart   int64   `json:"period_start" gorm:"bigint;index"`
+	PeriodEnd     int64   `json:"period_end" gorm:"bigint;index"`
+	UserId        int     `json:"user_id" gorm:"index"`
+	Quota         int64   `json:"quota"`
+	UsdEquivalent float64 `json:"usd_equivalent"`
+	// BilledAt is when the webhook acknowledged the period.
+	BilledAt int64 `json:"billed_at" gorm:"bigint"`
+}
+
+// TableName stores reconciliations in billing_reconciliations.
+func (BillingReconciliation) TableName() string {
+	return "billing_reconciliations"
+}
+
+// UnbilledUsage is the quota a user consumed in a reconciliation period.
+type UnbilledUsage struct {
+	UserId int
+	Quota  int64
+}
+
+// GetLastBillingReconciliationEnd returns the end of the latest reconciled
+// period, or 0 when nothing was reconciled yet.
+func GetLastBillingReconciliationEnd(ctx context.Context) (int64, error) {
+	var end int64
+	if err := DB.WithContext(ctx).Model(&BillingReconciliation{}).
+		Select("COALESCE(MAX(period_end), 0)").
+		Scan(&end).Error; err != nil {
+		return 0, errors.Wrap(err, "get last billing reconciliation")
+	}
+	return end, nil
+}
+
+// GetUnbilledUsage aggregates per user the quota of the consume logs created in
+// [from, to) of Unix seconds. Users without consumption are left out.
+func GetUnbilledUsage(ctx context.Context, from, to int64) ([]UnbilledUsage, error) {
+	var usage []UnbilledUsage
+	if err := LOG_DB.WithContext(ctx).Model(&Log{}).
+		Select("user_id, COALESCE(SUM(quota), 0) as quota").
+		Where("type = ? AND created_at >= ? AND created_at < ?", LogTypeConsume, from, to).
+		Group("user_id").
+		Having("SUM(quota) > 0").
+		Order("user_id").
+		Scan(&usage).Error; err != nil {
+		return nil, errors.Wrap(err, "aggregate unbilled usage")
+	}
+	return usage, nil
+}
+
+// CreateBillingReconciliations marks the usage of a period as billed.
+func CreateBillingReconciliations(ctx context.Context, records []BillingReconciliation) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := DB.WithContext(ctx).Create(&records).Error; err != nil {
+		return errors.Wrap(err, "create billing reconciliations")
+	}
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"sort"
 	"strconv"
 	"strings"
@@ -95,6 +94,28 @@ func CacheGetUserGroup(ctx context.Context, id int) (group string, err error) {
 	return group, nil
 }
 
+// CacheGetUserParentId returns the parent of a sub-account, or 0, see GetUserParentId.
+func CacheGetUserParentId(ctx context.Context, id int) (parentId int, err error) {
+	if !common.IsRedisEnabled() {
+		return GetUserParentId(id)
+	}
+	key := fmt.Sprintf("user_parent:%d", id)
+	if cached, err := common.RedisGet(ctx, key); err == nil {
+		if parentId, err = strconv.Atoi(cached); err == nil {
+			return parentId, nil
+		}
+	}
+	parentId, err = GetUserParentId(id)
+	if err != nil {
+		return 0, err
+	}
+	err = common.RedisSet(ctx, key, strconv.Itoa(parentId), time.Duration(UserId2GroupCacheSeconds)*time.Second)
+	if err != nil {
+		logger.Logger.Warn("Redis set user parent failed, continuing without cache", zap.Int("user_id", id), zap.Error(err))
+	}
+	return parentId, nil
+}
+
 func fetchAndUpdateUserQuota(ctx context.Context, id int) (quota int64, err error) {
 	quota, err = GetUserQuota(id)
 	if err != nil {
@@ -107,7 +128,29 @@ func fetchAndUpdateUserQuota(ctx context.Context, id int) (quota int64, err erro
 	return
 }
 
+// CacheGetUserQuota returns the quota a user can spend. For sub-accounts this
+// is min(own quota, parent pool remaining), see GetUserEffectiveQuota.
 func CacheGetUserQuota(ctx context.Context, id int) (quota int64, err error) {
+	quota, err = cacheGetOwnUserQuota(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	parentId, err := CacheGetUserParentId(ctx, id)
+	if err != nil {
+		return 0, errors.Wrapf(err, "get cached quota for user %d", id)
+	}
+	if parentId == 0 {
+		return quota, nil
+	}
+	parentQuota, err := cacheGetOwnUserQuota(ctx, parentId)
+	if err != nil {
+		return 0, errors.Wrapf(err, "get cached parent pool of user %d", id)
+	}
+	return min(quota, parentQuota), nil
+}
+
+// cacheGetOwnUserQuota returns the user's own quota, ignoring any parent pool.
+func cacheGetOwnUserQuota(ctx context.Context, id int) (quota int64, err error) {
 	if !common.IsRedisEnabled() {
 		return GetUserQuota(id)
 	}
@@ -130,7 +173,7 @@ func CacheUpdateUserQuota(ctx context.Context, id int) error {
 	if !common.IsRedisEnabled() {
 		return nil
 	}
-	quota, err := CacheGetUserQuota(ctx, id)
+	quota, err := cacheGetOwnUserQuota(ctx, id)
 	if err != nil {
 		return errors.Wrapf(err, "get cached quota for user %d", id)
 	}
@@ -149,6 +192,67 @@ func CacheDecreaseUserQuota(ctx context.Context, id int, quota int64) error {
 	if err != nil {
 		return errors.Wrapf(err, "decrease cached quota for user %d", id)
 	}
+	// Sub-accounts also draw from the parent's pool
+	parentId, err := CacheGetUserParentId(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "decrease cached quota for user %d", id)
+	}
+	if parentId != 0 {
+		if err = common.RedisDecrease(ctx, fmt.Sprintf("user_quota:%d", parentId), quota); err != nil {
+			return errors.Wrapf(err, "decrease cached parent pool of user %d", id)
+		}
+	}
+	return nil
+}
+
+// cachedQuotaStore updates cached user quotas atomically; tests replace it.
+type cachedQuotaStore interface {
+	decreaseIfEnough(ctx context.Context, key string, value int64) (bool, error)
+	decrease(ctx context.Context, key string, value int64) error
+}
+
+type redisCachedQuotaStore struct{}
+
+func (redisCachedQuotaStore) decreaseIfEnough(ctx context.Context, key string, value int64) (bool, error) {
+	return common.RedisDecreaseIfEnough(ctx, key, value)
+}
+
+func (redisCachedQuotaStore) decrease(ctx context.Context, key string, value int64) error {
+	return common.RedisDecrease(ctx, key, value)
+}
+
+var cachedQuota cachedQuotaStore = redisCachedQuotaStore{}
+
+// HardQuotaProtectionActive reports whether PreConsumeTokenQuota reserves quota
+// with an atomic check-and-decrement on the cached user quota, see
+// config.HardQuotaProtectionEnabled.
+func HardQuotaProtectionActive() bool {
+	return config.HardQuotaProtectionEnabled && common.IsRedisEnabled()
+}
+
+// cacheReserveUserQuota takes quota off the cached quota of user id unless less
+// than quota is left, loading the quota into the cache when it is not cached.
+// It reports whether the quota was reserved.
+func cacheReserveUserQuota(ctx context.Context, id int, quota int64) (bool, error) {
+	key := fmt.Sprintf("user_quota:%d", id)
+	reserved, err := cachedQuota.decreaseIfEnough(ctx, key, quota)
+	if errors.Is(err, common.ErrRedisKeyNotFound) {
+		if _, err = fetchAndUpdateUserQuota(ctx, id); err != nil {
+			return false, errors.Wrapf(err, "load quota of user %d", id)
+		}
+		reserved, err = cachedQuota.decreaseIfEnough(ctx, key, quota)
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "reserve cached quota for user %d", id)
+	}
+	return reserved, nil
+}
+
+// cacheReleaseUserQuota returns quota reserved by cacheReserveUserQuota.
+func cacheReleaseUserQuota(ctx context.Context, id int, quota int64) error {
+	if err := cachedQuota.decrease(ctx, fmt.Sprintf("user_quota:%d", id), -quota); err != nil {
+		return errors.Wrapf(err, "release cached quota for user %d", id)
+	}
 	return nil
 }
 
@@ -237,9 +341,80 @@ func CacheGetGroupModelsV2(ctx context.Context, group string) (models []dto.Enab
 	return models, nil
 }
 
+// InvalidateGroupModelsCache drops the cached model lists of every group, so
+// CacheGetGroupModels and CacheGetGroupModelsV2 rebuild them from the
+// abilities table on next use.
+func InvalidateGroupModelsCache(ctx context.Context) error {
+	if !common.IsRedisEnabled() || common.RDB == nil {
+		return nil
+	}
+	for _, pattern := range []string{"group_models:*", "group_models_v2:*"} {
+		var cursor uint64
+		for {
+			keys, next, err := common.RDB.Scan(ctx, cursor, pattern, 100).Result()
+			if err != nil {
+				return errors.Wrapf(err, "scan redis keys %s", pattern)
+			}
+			if len(keys) > 0 {
+				if err := common.RDB.Del(ctx, keys...).Err(); err != nil {
+					return errors.Wrapf(err, "delete redis keys %s", pattern)
+				}
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
 var group2model2channels map[string]map[string][]*Channel
 var channelSyncLock sync.RWMutex
 
+// remoteSuspensions holds ability suspensions announced by other regions until
+// they expire, keyed by "group:model:channelId". Regions may not share a
+// database, so InitChannelCache keeps honoring them across cache rebuilds.
+var remoteSuspensions sync.Map
+
+func abilityCacheKey(group string, modelName string, channelId int) string {
+	return fmt.Sprintf("%s:%s:%d", group, modelName, channelId)
+}
+
+// isRemotelySuspended reports whether another region suspended the ability behind key.
+func isRemotelySuspended(key string, now time.Time) bool {
+	v, ok := remoteSuspensions.Load(key)
+	if !ok {
+		return false
+	}
+	if v.(time.Time).After(now) {
+		return true
+	}
+	remoteSuspensions.Delete(key)
+	return false
+}
+
+// suspendCachedAbility removes the channel from the in-memory channel cache of
+// group and modelName until the given time.
+func suspendCachedAbility(group string, modelName string, channelId int, until time.Time) {
+	if !until.After(time.Now()) {
+		return
+	}
+	remoteSuspensions.Store(abilityCacheKey(group, modelName, channelId), until)
+
+	channelSyncLock.Lock()
+	defer channelSyncLock.Unlock()
+	channels := group2model2channels[group][modelName]
+	remaining := make([]*Channel, 0, len(channels))
+	for _, channel := range channels {
+		if channel.Id != channelId {
+			remaining = append(remaining, channel)
+		}
+	}
+	if len(remaining) != len(channels) {
+		group2model2channels[group][modelName] = remaining
+	}
+}
+
 func InitChannelCache() {
 	newChannelId2channel := make(map[int]*Channel)
 	var channels []*Channel
@@ -262,8 +437,8 @@ func InitChannelCache() {
 		// The ability.Enabled should have been set correctly based on channel.Status during AddAbilities/UpdateAbilities.
 		if ability.Enabled && (ability.SuspendUntil == nil || ability.SuspendUntil.Before(now)) {
 			// Check if the channel itself is in our list of enabled channels
-			if _, channelExists := newChannelId2channel[ability.ChannelId]; channelExists {
-				key := fmt.Sprintf("%s:%s:%d", ability.Group, ability.Model, ability.ChannelId)
+			key := abilityCacheKey(ability.Group, ability.Model, ability.ChannelId)
+			if _, channelExists := newChannelId2channel[ability.ChannelId]; channelExists && !isRemotelySuspended(key, now) {
 				validAbilityMap[key] = true
 			}
 		}
@@ -282,7 +457,7 @@ func InitChannelCache() {
 			}
 			for _, modelName := range channelModels {
 				// Check if this specific ability (group, model, channel.Id) is in our valid map
-				abilityKey := fmt.Sprintf("%s:%s:%d", groupName, modelName, channel.Id)
+				abilityKey := abilityCacheKey(groupName, modelName, channel.Id)
 				if _, isValidAbility := validAbilityMap[abilityKey]; isValidAbility {
 					if _, ok := newGroup2model2channels[groupName][modelName]; !ok {
 						newGroup2model2channels[groupName][modelName] = make([]*Channel, 0)
@@ -416,7 +591,8 @@ func CacheGetRandomSatisfiedChannel(group string, model string, ignoreFirstPrior
 		}
 	}
 
-	idx := rand.Intn(endIdx)
+	// idx stays -1 when picking within the highest priority tier
+	idx := -1
 	if ignoreFirstPriority {
 		if endIdx < len(candidateChannels) { // which means there are more than one priority
 			idx = random.RandRange(endIdx, len(candidateChannels))
@@ -436,7 +612,18 @@ func CacheGetRandomSatisfiedChannel(group string, model string, ignoreFirstPrior
 			// This seems okay.
 		}
 	}
-	channel := candidateChannels[idx]
+	var channel *Channel
+	if idx >= 0 {
+		if weighted, ok := pickWeightedChannel(context.Background(), group, model, candidateChannels[endIdx:]); ok {
+			channel = weighted
+		} else {
+			channel = candidateChannels[idx]
+		}
+	} else if weighted, ok := pickWeightedChannel(context.Background(), group, model, candidateChannels[:endIdx]); ok {
+		channel = weighted
+	} else {
+		channel = pickChannelByQueueDepth(context.Background(), candidateChannels[:endIdx])
+	}
 	logger.Logger.Info("select channel in cache", zap.String("channel_name", channel.Name), zap.Int("channel_id", channel.Id))
 	return channel, nil
 }
@@ -519,8 +706,10 @@ func CacheGetRandomSatisfiedChannelExcluding(group string, model string, ignoreF
 
 		// If there are lower priority channels available, select from them
 		if endIdx < len(candidateChannels) {
-			idx := random.RandRange(endIdx, len(candidateChannels))
-			channel := candidateChannels[idx]
+			channel, ok := pickWeightedChannel(context.Background(), group, model, candidateChannels[endIdx:])
+			if !ok {
+				channel = candidateChannels[random.RandRange(endIdx, len(candidateChannels))]
+			}
 			logger.Logger.Info("select channel in cache", zap.String("channel_name", channel.Name), zap.Int("channel_id", channel.Id))
 			return channel, nil
 		} else {
@@ -555,8 +744,10 @@ func CacheGetRandomSatisfiedChannelExcluding(group string, model string, ignoreF
 			return nil, errors.New("no channels with maximum priority available")
 		}
 
-		idx := rand.Intn(len(maxPriorityChannels))
-		channel := maxPriorityChannels[idx]
+		channel, ok := pickWeightedChannel(context.Background(), group, model, maxPriorityChannels)
+		if !ok {
+			channel = pickChannelByQueueDepth(context.Background(), maxPriorityChannels)
+		}
 		logger.Logger.Info("select channel in cache", zap.String("channel_name", channel.Name), zap.Int("channel_id", channel.Id))
 		return channel, nil
 	}
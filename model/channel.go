@@ -20,6 +20,7 @@ import (
 	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/common/logger"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
 )
 
 const (
@@ -63,6 +64,35 @@ type Channel struct {
 	UpdatedAt       int64   `json:"updated_at" gorm:"bigint;autoUpdateTime:milli"`
 	// AWS-specific configuration
 	InferenceProfileArnMap *string `json:"inference_profile_arn_map" gorm:"type:text"` // JSON string mapping model names to AWS Bedrock Inference Profile ARNs
+	// ResponseAnnotations is a JSON-encoded ChannelResponseAnnotations controlling the
+	// attribution header and metadata injected into relay responses.
+	ResponseAnnotations *string `json:"response_annotations" gorm:"type:text"`
+	// ModelStreamingTimeouts is a JSON object mapping model names to the number of
+	// seconds a stream may go without new tokens before it is closed.
+	ModelStreamingTimeouts *string `json:"model_streaming_timeouts" gorm:"type:text"`
+	// EndpointIdMapping is a JSON object mapping model names to the Volcengine Ark
+	// endpoint IDs (ep-...) serving them on Doubao channels. Keys ending in "*"
+	// match by prefix.
+	EndpointIdMapping *string `json:"endpoint_id_mapping" gorm:"type:text"`
+	// ErrorResponseTemplate is a JSON object mapping HTTP status codes to the
+	// messages returned to clients instead of the upstream error message.
+	ErrorResponseTemplate *string `json:"error_response_template" gorm:"type:text"`
+	// MaxConcurrency caps the requests in flight on this channel across all
+	// instances; further requests are rejected with 429. 0 means unlimited.
+	MaxConcurrency int `json:"max_concurrency" gorm:"default:0"`
+	// AutoTranslateSystemPrompt translates system prompts that are not written in
+	// TranslateTargetLanguage before forwarding requests to this channel.
+	AutoTranslateSystemPrompt bool `json:"auto_translate_system_prompt" gorm:"default:false"`
+	// TranslateTargetLanguage is the BCP 47 tag of the channel's preferred prompt language, e.g. "zh-CN".
+	TranslateTargetLanguage string `json:"translate_target_language" gorm:"type:varchar(35);default:''"`
+	// TranslationChannelId is the OpenAI-compatible channel whose model performs the translation.
+	TranslationChannelId int `json:"translation_channel_id" gorm:"default:0"`
+	// StoreReasoningTrace saves the reasoning returned by models on this channel
+	// to request_traces and hides it from clients unless they opt in.
+	StoreReasoningTrace bool `json:"store_reasoning_trace" gorm:"default:false"`
+	// Source is ChannelSourceFile for channels loaded from CHANNEL_CONFIG_PATH,
+	// which the admin API refuses to modify, and empty otherwise.
+	Source string `json:"source" gorm:"type:varchar(16);default:'';index"`
 }
 
 type ChannelConfig struct {
@@ -78,6 +108,12 @@ type ChannelConfig struct {
 	AuthType          string                `json:"auth_type,omitempty"`
 	APIFormat         string                `json:"api_format,omitempty"`
 	Tooling           *ChannelToolingConfig `json:"tooling,omitempty"`
+	// TruncateEmbeddings truncates embedding vectors to the requested dimensions
+	// for upstreams that ignore the `dimensions` parameter.
+	TruncateEmbeddings bool `json:"truncate_embeddings,omitempty"`
+	// PassthroughReasoningContent forwards the reasoning_content of DeepSeek
+	// responses even to clients that did not ask for reasoning.
+	PassthroughReasoningContent bool `json:"passthrough_reasoning_content,omitempty"`
 }
 
 type ModelConfig struct {
@@ -96,6 +132,18 @@ type ModelConfigLocal struct {
 	Video           *VideoPricingLocal `json:"video,omitempty"`
 	Audio           *AudioPricingLocal `json:"audio,omitempty"`
 	Image           *ImagePricingLocal `json:"image,omitempty"`
+	// Features overrides the adaptor's model feature flags for this channel.
+	Features map[string]bool `json:"features,omitempty"`
+}
+
+// modelFeatureFlagNames lists the feature flags accepted in model configs.
+var modelFeatureFlagNames = map[string]bool{
+	relaymodel.FeatureVision:           true,
+	relaymodel.FeatureTools:            true,
+	relaymodel.FeatureStructuredOutput: true,
+	relaymodel.FeatureJSONMode:         true,
+	relaymodel.FeatureStreaming:        true,
+	relaymodel.FeatureExtendedThinking: true,
 }
 
 // VideoPricingLocal represents channel-scoped video pricing metadata stored alongside model configs.
@@ -247,6 +295,16 @@ func normalizeModelConfigLocal(cfg ModelConfigLocal) (ModelConfigLocal, error) {
 		CompletionRatio: cfg.CompletionRatio,
 		MaxTokens:       cfg.MaxTokens,
 	}
+	for rawName, enabled := range cfg.Features {
+		name := strings.ToLower(strings.TrimSpace(rawName))
+		if !modelFeatureFlagNames[name] {
+			return ModelConfigLocal{}, errors.Errorf("unknown model feature flag %q", rawName)
+		}
+		if normalized.Features == nil {
+			normalized.Features = make(map[string]bool, len(cfg.Features))
+		}
+		normalized.Features[name] = enabled
+	}
 	if video != nil {
 		normalized.Video = video
 	}
@@ -731,7 +789,7 @@ func (channel *Channel) validateModelPriceConfigs(configs map[string]ModelConfig
 		}
 
 		// Validate that at least one field has meaningful data
-		if config.Ratio == 0 && config.CompletionRatio == 0 && config.MaxTokens == 0 && !hasVideoData && !hasAudioData && !hasImageData {
+		if config.Ratio == 0 && config.CompletionRatio == 0 && config.MaxTokens == 0 && !hasVideoData && !hasAudioData && !hasImageData && len(config.Features) == 0 {
 			return errors.Errorf("model %s has no meaningful configuration data", modelName)
 		}
 	}
@@ -1347,6 +1405,25 @@ func ValidateInferenceProfileArnMapJSON(jsonStr string) error {
 	return nil
 }
 
+// ValidateSystemPromptTranslation checks that an enabled system prompt
+// translation names a target language and a translation channel other than
+// the channel itself.
+func (channel *Channel) ValidateSystemPromptTranslation() error {
+	if !channel.AutoTranslateSystemPrompt {
+		return nil
+	}
+	if strings.TrimSpace(channel.TranslateTargetLanguage) == "" {
+		return errors.New("translate_target_language is required when auto_translate_system_prompt is enabled")
+	}
+	if channel.TranslationChannelId <= 0 {
+		return errors.New("translation_channel_id is required when auto_translate_system_prompt is enabled")
+	}
+	if channel.TranslationChannelId == channel.Id {
+		return errors.New("translation_channel_id must reference another channel")
+	}
+	return nil
+}
+
 func (channel *Channel) Insert() error {
 	err := DB.Create(channel).Error
 	if err != nil {
@@ -1402,6 +1479,14 @@ func (channel *Channel) Update() error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to update channel: id=%d, name=%s", channel.Id, channel.Name)
 	}
+	// Updates skips zero values, which would keep translation, the concurrency
+	// limit and reasoning storage from being turned off
+	err = DB.Model(channel).
+		Select("auto_translate_system_prompt", "translate_target_language", "translation_channel_id", "max_concurrency", "store_reasoning_trace").
+		Updates(channel).Error
+	if err != nil {
+		return errors.Wrapf(err, "failed to update optional settings of channel: id=%d", channel.Id)
+	}
 	DB.Model(channel).First(channel, "id = ?", channel.Id)
 	if clearTestingModel {
 		if err := DB.Model(channel).Where("id = ?", channel.Id).Update("testing_model", nil).Error; err != nil {
@@ -1414,6 +1499,7 @@ func (channel *Channel) Update() error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to update abilities for channel: id=%d, name=%s", channel.Id, channel.Name)
 	}
+	channelCapabilityCache.Delete(channel.Id)
 	InitChannelCache()
 	return nil
 }
@@ -1445,6 +1531,7 @@ func (channel *Channel) Delete() error {
 	if err := channel.DeleteAbilities(); err != nil {
 		return errors.Wrapf(err, "delete abilities for channel %d", channel.Id)
 	}
+	channelCapabilityCache.Delete(channel.Id)
 	InitChannelCache()
 	return nil
 }
@@ -1578,14 +1665,18 @@ func updateChannelUsedQuota(id int, quota int64) {
 func DeleteChannelByStatus(status int64) (int64, error) {
 	result := DB.Where("status = ?", status).Delete(&Channel{})
 	if result.Error == nil {
+		channelCapabilityCache.Clear()
 		InitChannelCache()
 	}
 	return result.RowsAffected, result.Error
 }
 
 func DeleteDisabledChannel() (int64, error) {
-	result := DB.Where("status = ? or status = ?", ChannelStatusAutoDisabled, ChannelStatusManuallyDisabled).Delete(&Channel{})
+	result := DB.Where("status = ? or status = ?", ChannelStatusAutoDisabled, ChannelStatusManuallyDisabled).
+		Where("source <> ?", ChannelSourceFile).
+		Delete(&Channel{})
 	if result.Error == nil {
+		channelCapabilityCache.Clear()
 		InitChannelCache()
 	}
 	return result.RowsAffected, result.Error
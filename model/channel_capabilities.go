@@ -0,0 +1,96 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+// channelCapabilities caches the feature flags parsed from one version of a
+// channel's model configs.
+type channelCapabilities struct {
+	modelConfigs string
+	byModel      map[string]map[string]bool
+}
+
+// channelCapabilityCache holds *channelCapabilities by channel ID, so routing
+// does not parse the model configs of every candidate channel per request.
+// Entries are dropped when their channel is updated or deleted.
+var channelCapabilityCache sync.Map
+
+// Capabilities returns the capability map of modelName on the channel, read
+// from its model config under the requested name or else under the upstream
+// name it is mapped to. Capabilities missing from the map are supported; only
+// those set to false are not. The map must not be modified.
+func (channel *Channel) Capabilities(modelName string) map[string]bool {
+	modelConfigs := ""
+	if channel.ModelConfigs != nil {
+		modelConfigs = *channel.ModelConfigs
+	}
+
+	var entry *channelCapabilities
+	if cached, ok := channelCapabilityCache.Load(channel.Id); ok {
+		entry = cached.(*channelCapabilities)
+	}
+	if entry == nil || entry.modelConfigs != modelConfigs {
+		entry = &channelCapabilities{modelConfigs: modelConfigs, byModel: make(map[string]map[string]bool)}
+		for name, cfg := range channel.GetModelPriceConfigs() {
+			for flag, enabled := range cfg.Features {
+				flag = strings.ToLower(strings.TrimSpace(flag))
+				if !modelFeatureFlagNames[flag] {
+					continue
+				}
+				if entry.byModel[name] == nil {
+					entry.byModel[name] = make(map[string]bool, len(cfg.Features))
+				}
+				entry.byModel[name][flag] = enabled
+			}
+		}
+		channelCapabilityCache.Store(channel.Id, entry)
+	}
+
+	if capabilities, ok := entry.byModel[modelName]; ok {
+		return capabilities
+	}
+	if mapped := channel.GetModelMapping()[modelName]; mapped != "" {
+		return entry.byModel[mapped]
+	}
+	return nil
+}
+
+// NegotiateCapabilities checks the feature flags a chat request uses, see
+// GeneralOpenAIRequest.RequestedFeatures, against the channel's capability map
+// for the requested model. It reports whether the channel can serve the
+// request and, if not, the capabilities it lacks.
+func NegotiateCapabilities(ctx context.Context, channel *Channel, req *relaymodel.GeneralOpenAIRequest) (supported bool, unsupported []string, err error) {
+	if channel == nil || req == nil {
+		return false, nil, errors.New("channel and request are required for capability negotiation")
+	}
+
+	capabilities := channel.Capabilities(req.Model)
+	if len(capabilities) == 0 {
+		return true, nil, nil
+	}
+	for _, feature := range req.RequestedFeatures() {
+		if enabled, ok := capabilities[feature.Flag]; ok && !enabled {
+			unsupported = append(unsupported, feature.Flag)
+		}
+	}
+	if len(unsupported) == 0 {
+		return true, nil, nil
+	}
+
+	if lg := gmw.GetLogger(ctx); lg != nil {
+		lg.Debug("channel lacks requested capabilities",
+			zap.Int("channel_id", channel.Id),
+			zap.String("model", req.Model),
+			zap.Strings("unsupported", unsupported))
+	}
+	return false, unsupported, nil
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+func TestNegotiateCapabilities(t *testing.T) {
+	configs := `{"legacy-upstream":{"ratio":1,"features":{"supports_tools":false,"Supports_Streaming ":false,"supports_vision":true,"unknown_flag":false}}}`
+	mapping := `{"legacy":"legacy-upstream"}`
+	channel := &Channel{Id: 9101, ModelConfigs: &configs, ModelMapping: &mapping}
+	t.Cleanup(func() { channelCapabilityCache.Delete(channel.Id) })
+
+	request := &relaymodel.GeneralOpenAIRequest{
+		Model:  "legacy",
+		Stream: true,
+		Tools:  []relaymodel.Tool{{Type: "function", Function: &relaymodel.Function{Name: "lookup"}}},
+		Messages: []relaymodel.Message{{Role: "user", Content: []any{
+			map[string]any{"type": "image_url", "image_url": map[string]any{"url": "https://example.com/a.png"}},
+		}}},
+	}
+	supported, unsupported, err := NegotiateCapabilities(context.Background(), channel, request)
+	require.NoError(t, err)
+	require.False(t, supported)
+	require.Equal(t, []string{"supports_tools", "supports_streaming"}, unsupported)
+	require.NotContains(t, channel.Capabilities("legacy"), "unknown_flag")
+
+	// JSON mode is not declared, so it stays supported
+	jsonRequest := &relaymodel.GeneralOpenAIRequest{
+		Model:          "legacy",
+		ResponseFormat: &relaymodel.ResponseFormat{Type: "json_object"},
+	}
+	supported, unsupported, err = NegotiateCapabilities(context.Background(), channel, jsonRequest)
+	require.NoError(t, err)
+	require.True(t, supported)
+	require.Empty(t, unsupported)
+
+	// Cached capabilities follow edits of the model configs
+	updated := `{"legacy-upstream":{"ratio":1,"features":{"supports_json_mode":false}}}`
+	channel.ModelConfigs = &updated
+	supported, unsupported, err = NegotiateCapabilities(context.Background(), channel, jsonRequest)
+	require.NoError(t, err)
+	require.False(t, supported)
+	require.Equal(t, []string{"supports_json_mode"}, unsupported)
+
+	_, _, err = NegotiateCapabilities(context.Background(), nil, jsonRequest)
+	require.Error(t, err)
+}
@@ -0,0 +1,239 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Laisky/zap"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// Circuit states of an ability, as recorded in the manage log.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// channelCircuitTTL bounds how long an idle circuit is remembered. A circuit
+// nobody probes within it is forgotten, i.e. closes.
+const channelCircuitTTL = 24 * time.Hour
+
+// channelCircuit is the state of one circuit. openedAt is zero while the
+// circuit is closed; probeAt is set while the half-open probe is in flight.
+type channelCircuit struct {
+	failures int
+	openedAt time.Time
+	probeAt  time.Time
+}
+
+// memoryChannelCircuits holds the circuits per ability when Redis is
+// unavailable. They are only shared by the requests of a single instance.
+var memoryChannelCircuits = struct {
+	sync.Mutex
+	circuits map[string]*channelCircuit
+}{circuits: make(map[string]*channelCircuit)}
+
+// The circuit hash holds f (consecutive failures), o (opened at, unix ms) and
+// p (half-open probe started at, unix ms).
+var (
+	// circuitAllowScript returns 0 to block, 1 to allow and 2 to allow the
+	// request as the probe of a circuit that just turned half-open.
+	// ARGV: now, half-open delay, ttl (all ms).
+	circuitAllowScript = redis.NewScript(`
+local opened = tonumber(redis.call('HGET', KEYS[1], 'o'))
+if not opened then return 1 end
+local now, delay = tonumber(ARGV[1]), tonumber(ARGV[2])
+if now < opened + delay then return 0 end
+local probe = tonumber(redis.call('HGET', KEYS[1], 'p'))
+if probe and now < probe + delay then return 0 end
+redis.call('HSET', KEYS[1], 'p', ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+if probe then return 1 end
+return 2
+`)
+
+	// circuitSuccessScript resets the failures and returns 1 when it closed
+	// an open circuit.
+	circuitSuccessScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], 'o') == 1 then
+  redis.call('DEL', KEYS[1])
+  return 1
+end
+redis.call('HDEL', KEYS[1], 'f')
+return 0
+`)
+
+	// circuitFailureScript counts a failure and returns 1 when it opened a
+	// closed circuit and 2 when it reopened a half-open one.
+	// ARGV: now (ms), failure threshold, ttl (ms).
+	circuitFailureScript = redis.NewScript(`
+local failures = redis.call('HINCRBY', KEYS[1], 'f', 1)
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+if redis.call('HEXISTS', KEYS[1], 'o') == 1 then
+  if redis.call('HDEL', KEYS[1], 'p') == 1 then
+    redis.call('HSET', KEYS[1], 'o', ARGV[1])
+    return 2
+  end
+  return 0
+end
+if failures >= tonumber(ARGV[2]) then
+  redis.call('HSET', KEYS[1], 'o', ARGV[1])
+  return 1
+end
+return 0
+`)
+)
+
+func channelCircuitKey(channelId int, modelName string) string {
+	return fmt.Sprintf("cb:%d:%s", channelId, modelName)
+}
+
+func circuitBreakerEnabled() bool {
+	return config.CircuitBreakerFailureThreshold > 0
+}
+
+// AllowChannelCircuit reports whether a request for modelName may be sent to
+// channelId. An open circuit blocks requests until
+// config.CircuitBreakerHalfOpenDelay has passed, then lets a single probe
+// through; its outcome, reported by RecordChannelCircuitSuccess or
+// RecordChannelCircuitFailure, closes or reopens the circuit. A probe that
+// never reports is replaced after another delay.
+func AllowChannelCircuit(ctx context.Context, channelId int, modelName string) bool {
+	if !circuitBreakerEnabled() || channelId == 0 {
+		return true
+	}
+	now := time.Now()
+	delay := config.CircuitBreakerHalfOpenDelay
+
+	var allowed, probing bool
+	if common.IsRedisEnabled() && common.RDB != nil {
+		result, err := circuitAllowScript.Run(ctx, common.RDB, []string{channelCircuitKey(channelId, modelName)},
+			now.UnixMilli(), delay.Milliseconds(), channelCircuitTTL.Milliseconds()).Int()
+		if err != nil {
+			// Fail open, an unreachable Redis must not block all traffic
+			logger.Logger.Warn("failed to check channel circuit",
+				zap.Int("channel_id", channelId), zap.String("model", modelName), zap.Error(err))
+			return true
+		}
+		allowed, probing = result != 0, result == 2
+	} else {
+		memoryChannelCircuits.Lock()
+		circuit := memoryChannelCircuits.circuits[channelCircuitKey(channelId, modelName)]
+		switch {
+		case circuit == nil || circuit.openedAt.IsZero():
+			allowed = true
+		case now.Before(circuit.openedAt.Add(delay)):
+		case !circuit.probeAt.IsZero() && now.Before(circuit.probeAt.Add(delay)):
+		default:
+			allowed, probing = true, circuit.probeAt.IsZero()
+			circuit.probeAt = now
+		}
+		memoryChannelCircuits.Unlock()
+	}
+
+	if probing {
+		recordCircuitTransition(ctx, channelId, modelName, CircuitOpen, CircuitHalfOpen,
+			"letting one probe request through")
+	}
+	return allowed
+}
+
+// RecordChannelCircuitSuccess resets the consecutive failures of the circuit
+// and closes it when it was open.
+func RecordChannelCircuitSuccess(ctx context.Context, channelId int, modelName string) {
+	if !circuitBreakerEnabled() || channelId == 0 {
+		return
+	}
+
+	var closed bool
+	key := channelCircuitKey(channelId, modelName)
+	if common.IsRedisEnabled() && common.RDB != nil {
+		result, err := circuitSuccessScript.Run(ctx, common.RDB, []string{key}).Int()
+		if err != nil {
+			logger.Logger.Warn("failed to record channel circuit success",
+				zap.Int("channel_id", channelId), zap.String("model", modelName), zap.Error(err))
+			return
+		}
+		closed = result == 1
+	} else {
+		memoryChannelCircuits.Lock()
+		if circuit := memoryChannelCircuits.circuits[key]; circuit != nil {
+			closed = !circuit.openedAt.IsZero()
+			delete(memoryChannelCircuits.circuits, key)
+		}
+		memoryChannelCircuits.Unlock()
+	}
+
+	if closed {
+		recordCircuitTransition(ctx, channelId, modelName, CircuitHalfOpen, CircuitClosed,
+			"probe request succeeded")
+	}
+}
+
+// RecordChannelCircuitFailure counts a channel-origin failure of the circuit.
+// It opens a closed circuit after config.CircuitBreakerFailureThreshold
+// consecutive failures and reopens a half-open one whose probe failed.
+func RecordChannelCircuitFailure(ctx context.Context, channelId int, modelName string) {
+	if !circuitBreakerEnabled() || channelId == 0 {
+		return
+	}
+	now := time.Now()
+	threshold := config.CircuitBreakerFailureThreshold
+
+	var opened, reopened bool
+	key := channelCircuitKey(channelId, modelName)
+	if common.IsRedisEnabled() && common.RDB != nil {
+		result, err := circuitFailureScript.Run(ctx, common.RDB, []string{key},
+			now.UnixMilli(), threshold, channelCircuitTTL.Milliseconds()).Int()
+		if err != nil {
+			logger.Logger.Warn("failed to record channel circuit failure",
+				zap.Int("channel_id", channelId), zap.String("model", modelName), zap.Error(err))
+			return
+		}
+		opened, reopened = result == 1, result == 2
+	} else {
+		memoryChannelCircuits.Lock()
+		circuit := memoryChannelCircuits.circuits[key]
+		if circuit == nil {
+			circuit = &channelCircuit{}
+			memoryChannelCircuits.circuits[key] = circuit
+		}
+		circuit.failures++
+		switch {
+		case !circuit.openedAt.IsZero() && !circuit.probeAt.IsZero():
+			circuit.openedAt, circuit.probeAt = now, time.Time{}
+			reopened = true
+		case circuit.openedAt.IsZero() && circuit.failures >= threshold:
+			circuit.openedAt = now
+			opened = true
+		}
+		memoryChannelCircuits.Unlock()
+	}
+
+	switch {
+	case opened:
+		recordCircuitTransition(ctx, channelId, modelName, CircuitClosed, CircuitOpen,
+			fmt.Sprintf("%d consecutive failures", threshold))
+	case reopened:
+		recordCircuitTransition(ctx, channelId, modelName, CircuitHalfOpen, CircuitOpen,
+			"probe request failed")
+	}
+}
+
+// recordCircuitTransition reports a circuit state change in the manage log,
+// attributed to no user since the relay, not an admin, caused it.
+func recordCircuitTransition(ctx context.Context, channelId int, modelName string, previous string, next string, note string) {
+	logger.Logger.Info("channel circuit state changed",
+		zap.Int("channel_id", channelId),
+		zap.String("model", modelName),
+		zap.String("from", previous),
+		zap.String("to", next))
+	RecordManageLog(ctx, 0, fmt.Sprintf("circuit_breaker:%d:%s", channelId, modelName), previous, next, note)
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func setupChannelCircuitTest(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&User{}, &Log{}))
+
+	originalDB, originalLogDB := DB, LOG_DB
+	originalRedis := common.IsRedisEnabled()
+	originalThreshold := config.CircuitBreakerFailureThreshold
+	originalDelay := config.CircuitBreakerHalfOpenDelay
+	DB, LOG_DB = db, db
+	common.SetRedisEnabled(false)
+	config.CircuitBreakerFailureThreshold = 3
+	config.CircuitBreakerHalfOpenDelay = 50 * time.Millisecond
+	t.Cleanup(func() {
+		DB, LOG_DB = originalDB, originalLogDB
+		common.SetRedisEnabled(originalRedis)
+		config.CircuitBreakerFailureThreshold = originalThreshold
+		config.CircuitBreakerHalfOpenDelay = originalDelay
+		memoryChannelCircuits.Lock()
+		memoryChannelCircuits.circuits = make(map[string]*channelCircuit)
+		memoryChannelCircuits.Unlock()
+	})
+}
+
+func circuitTransitions(t *testing.T) []string {
+	t.Helper()
+	var logs []Log
+	require.NoError(t, LOG_DB.Where("type = ?", LogTypeManage).Order("id").Find(&logs).Error)
+	contents := make([]string, 0, len(logs))
+	for _, log := range logs {
+		contents = append(contents, log.Content)
+	}
+	return contents
+}
+
+func TestChannelCircuitLifecycle(t *testing.T) {
+	setupChannelCircuitTest(t)
+	ctx := context.Background()
+
+	for range 2 {
+		RecordChannelCircuitFailure(ctx, 5, "gpt-4o")
+	}
+	require.True(t, AllowChannelCircuit(ctx, 5, "gpt-4o"))
+
+	// A success resets the consecutive failures
+	RecordChannelCircuitSuccess(ctx, 5, "gpt-4o")
+	for range 2 {
+		RecordChannelCircuitFailure(ctx, 5, "gpt-4o")
+	}
+	require.True(t, AllowChannelCircuit(ctx, 5, "gpt-4o"))
+	require.Empty(t, circuitTransitions(t))
+
+	RecordChannelCircuitFailure(ctx, 5, "gpt-4o")
+	require.False(t, AllowChannelCircuit(ctx, 5, "gpt-4o"))
+	require.True(t, AllowChannelCircuit(ctx, 5, "gpt-4o-mini"), "circuits are per model")
+	require.True(t, AllowChannelCircuit(ctx, 6, "gpt-4o"), "circuits are per channel")
+
+	// After the delay exactly one probe goes through; its failure reopens the circuit
+	time.Sleep(60 * time.Millisecond)
+	require.True(t, AllowChannelCircuit(ctx, 5, "gpt-4o"))
+	require.False(t, AllowChannelCircuit(ctx, 5, "gpt-4o"))
+	RecordChannelCircuitFailure(ctx, 5, "gpt-4o")
+	require.False(t, AllowChannelCircuit(ctx, 5, "gpt-4o"))
+
+	// A successful probe closes it
+	time.Sleep(60 * time.Millisecond)
+	require.True(t, AllowChannelCircuit(ctx, 5, "gpt-4o"))
+	RecordChannelCircuitSuccess(ctx, 5, "gpt-4o")
+	require.True(t, AllowChannelCircuit(ctx, 5, "gpt-4o"))
+	require.True(t, AllowChannelCircuit(ctx, 5, "gpt-4o"))
+
+	require.Equal(t, []string{
+		"Field circuit_breaker:5:gpt-4o changed from closed to open (3 consecutive failures)",
+		"Field circuit_breaker:5:gpt-4o changed from open to half_open (letting one probe request through)",
+		"Field circuit_breaker:5:gpt-4o changed from half_open to open (probe request failed)",
+		"Field circuit_breaker:5:gpt-4o changed from open to half_open (letting one probe request through)",
+		"Field circuit_breaker:5:gpt-4o changed from half_open to closed (probe request succeeded)",
+	}, circuitTransitions(t))
+}
+
+func TestChannelCircuitDisabled(t *testing.T) {
+	setupChannelCircuitTest(t)
+	config.CircuitBreakerFailureThreshold = 0
+	ctx := context.Background()
+
+	for range 10 {
+		RecordChannelCircuitFailure(ctx, 5, "gpt-4o")
+	}
+	require.True(t, AllowChannelCircuit(ctx, 5, "gpt-4o"))
+	require.Empty(t, circuitTransitions(t))
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// memoryChannelConcurrency counts the concurrency slots taken per channel when
+// Redis is unavailable. It is only accurate for a single instance.
+var memoryChannelConcurrency = struct {
+	sync.Mutex
+	inFlight map[int]int
+}{inFlight: make(map[int]int)}
+
+func channelConcurrencyKey(channelId int) string {
+	return fmt.Sprintf("channel_concurrency:%d", channelId)
+}
+
+// AcquireChannelConcurrency takes one of the maxConcurrency request slots of
+// channelId. It reports false when every slot is taken; otherwise the
+// returned function frees the slot. A maxConcurrency of 0 means unlimited.
+// Slots leaked by crashed instances expire with the counter, like queue depths.
+func AcquireChannelConcurrency(ctx context.Context, channelId int, maxConcurrency int) (release func(), ok bool) {
+	if maxConcurrency <= 0 || channelId == 0 {
+		return func() {}, true
+	}
+
+	if common.IsRedisEnabled() && common.RDB != nil {
+		key := channelConcurrencyKey(channelId)
+		pipe := common.RDB.TxPipeline()
+		incr := pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, channelQueueDepthTTL())
+		if _, err := pipe.Exec(ctx); err != nil {
+			// Fail open, an unreachable Redis must not block all traffic
+			logger.Logger.Warn("failed to acquire channel concurrency slot",
+				zap.Int("channel_id", channelId), zap.Error(err))
+			return func() {}, true
+		}
+		release = releaseRedisChannelConcurrency(ctx, channelId)
+		if incr.Val() > int64(maxConcurrency) {
+			release()
+			return nil, false
+		}
+		return release, true
+	}
+
+	memoryChannelConcurrency.Lock()
+	defer memoryChannelConcurrency.Unlock()
+	if memoryChannelConcurrency.inFlight[channelId] >= maxConcurrency {
+		return nil, false
+	}
+	memoryChannelConcurrency.inFlight[channelId]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			memoryChannelConcurrency.Lock()
+			defer memoryChannelConcurrency.Unlock()
+			if memoryChannelConcurrency.inFlight[channelId] <= 1 {
+				delete(memoryChannelConcurrency.inFlight, channelId)
+				return
+			}
+			memoryChannelConcurrency.inFlight[channelId]--
+		})
+	}, true
+}
+
+func releaseRedisChannelConcurrency(ctx context.Context, channelId int) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// The request context may already be cancelled when the relay ends
+			ctx := context.WithoutCancel(ctx)
+			key := channelConcurrencyKey(channelId)
+			remaining, err := common.RDB.Decr(ctx, key).Result()
+			if err != nil {
+				logger.Logger.Warn("failed to release channel concurrency slot",
+					zap.Int("channel_id", channelId), zap.Error(err))
+				return
+			}
+			// A counter that expired while requests were in flight goes negative on release
+			if remaining < 0 {
+				if err := common.RedisDel(ctx, key); err != nil {
+					logger.Logger.Warn("failed to reset negative channel concurrency",
+						zap.Int("channel_id", channelId), zap.Error(err))
+				}
+			}
+		})
+	}
+}
+
+// ResetChannelConcurrency frees every concurrency slot of channelId, so slots
+// leaked by requests that never released them stop throttling the channel.
+// Requests still in flight release into the fresh counter, whose negative
+// values are reset on release.
+func ResetChannelConcurrency(ctx context.Context, channelId int) {
+	if common.IsRedisEnabled() && common.RDB != nil {
+		if err := common.RedisDel(ctx, channelConcurrencyKey(channelId)); err != nil {
+			logger.Logger.Warn("failed to reset channel concurrency",
+				zap.Int("channel_id", channelId), zap.Error(err))
+		}
+		return
+	}
+
+	memoryChannelConcurrency.Lock()
+	defer memoryChannelConcurrency.Unlock()
+	delete(memoryChannelConcurrency.inFlight, channelId)
+}
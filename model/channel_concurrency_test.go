@@ -0,0 +1,99 @@
+package model
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+func setupChannelConcurrencyTest(t *testing.T) {
+	t.Helper()
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedis)
+		memoryChannelConcurrency.Lock()
+		memoryChannelConcurrency.inFlight = make(map[int]int)
+		memoryChannelConcurrency.Unlock()
+	})
+}
+
+func TestAcquireChannelConcurrencyAdmitsExactlyMax(t *testing.T) {
+	setupChannelConcurrencyTest(t)
+	const maxConcurrency = 5
+
+	var admitted, rejected atomic.Int32
+	releases := make(chan func(), maxConcurrency+1)
+	var wg sync.WaitGroup
+	for range maxConcurrency + 1 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, ok := AcquireChannelConcurrency(context.Background(), 9, maxConcurrency)
+			if !ok {
+				rejected.Add(1)
+				return
+			}
+			admitted.Add(1)
+			releases <- release
+		}()
+	}
+	wg.Wait()
+	close(releases)
+	require.Equal(t, int32(maxConcurrency), admitted.Load())
+	require.Equal(t, int32(1), rejected.Load())
+
+	// Freeing a slot admits the next request; releasing twice frees it once
+	release := <-releases
+	release()
+	release()
+	next, ok := AcquireChannelConcurrency(context.Background(), 9, maxConcurrency)
+	require.True(t, ok)
+	_, ok = AcquireChannelConcurrency(context.Background(), 9, maxConcurrency)
+	require.False(t, ok)
+
+	next()
+	for release := range releases {
+		release()
+	}
+	memoryChannelConcurrency.Lock()
+	require.Empty(t, memoryChannelConcurrency.inFlight)
+	memoryChannelConcurrency.Unlock()
+}
+
+func TestAcquireChannelConcurrencyUnlimited(t *testing.T) {
+	setupChannelConcurrencyTest(t)
+
+	for range 100 {
+		_, ok := AcquireChannelConcurrency(context.Background(), 9, 0)
+		require.True(t, ok)
+	}
+	memoryChannelConcurrency.Lock()
+	require.Empty(t, memoryChannelConcurrency.inFlight)
+	memoryChannelConcurrency.Unlock()
+}
+
+func TestResetChannelConcurrencyFreesLeakedSlots(t *testing.T) {
+	setupChannelConcurrencyTest(t)
+
+	inFlight, ok := AcquireChannelConcurrency(context.Background(), 9, 1)
+	require.True(t, ok)
+	_, ok = AcquireChannelConcurrency(context.Background(), 9, 1)
+	require.False(t, ok)
+
+	ResetChannelConcurrency(context.Background(), 9)
+	next, ok := AcquireChannelConcurrency(context.Background(), 9, 1)
+	require.True(t, ok)
+
+	// Releases of requests admitted before the reset never leave a negative count
+	inFlight()
+	next()
+	memoryChannelConcurrency.Lock()
+	require.Empty(t, memoryChannelConcurrency.inFlight)
+	memoryChannelConcurrency.Unlock()
+}
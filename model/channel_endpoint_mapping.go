@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// GetEndpointIdMapping returns the model name to Volcengine Ark endpoint ID
+// mapping of a Doubao channel, or nil when none is configured or the stored
+// JSON is invalid.
+func (channel *Channel) GetEndpointIdMapping() map[string]string {
+	if channel.EndpointIdMapping == nil || *channel.EndpointIdMapping == "" || *channel.EndpointIdMapping == "{}" {
+		return nil
+	}
+	mapping := make(map[string]string)
+	if err := json.Unmarshal([]byte(*channel.EndpointIdMapping), &mapping); err != nil {
+		logger.Logger.Error("failed to unmarshal endpoint id mapping for channel",
+			zap.Int("channel_id", channel.Id),
+			zap.Error(err))
+		return nil
+	}
+	return mapping
+}
+
+// ValidateEndpointIdMappingJSON validates a JSON string mapping model names to endpoint IDs
+func ValidateEndpointIdMappingJSON(jsonStr string) error {
+	if jsonStr == "" {
+		return nil // Empty is allowed
+	}
+
+	var mapping map[string]string
+	if err := json.Unmarshal([]byte(jsonStr), &mapping); err != nil {
+		return errors.Errorf("invalid JSON format: %v", err)
+	}
+
+	for modelName, endpointId := range mapping {
+		if modelName == "" {
+			return errors.New("endpoint id mapping cannot contain empty model names")
+		}
+		if strings.TrimSpace(endpointId) == "" {
+			return errors.Errorf("endpoint id for model %s cannot be empty", modelName)
+		}
+		if strings.Contains(strings.TrimSuffix(modelName, "*"), "*") {
+			return errors.Errorf("model %s may only use a trailing * wildcard", modelName)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// GetErrorResponseTemplate returns the channel's custom error messages keyed by
+// HTTP status code, or nil when none are configured or the stored JSON is invalid.
+func (channel *Channel) GetErrorResponseTemplate() map[string]string {
+	if channel.ErrorResponseTemplate == nil || *channel.ErrorResponseTemplate == "" || *channel.ErrorResponseTemplate == "{}" {
+		return nil
+	}
+	template := make(map[string]string)
+	if err := json.Unmarshal([]byte(*channel.ErrorResponseTemplate), &template); err != nil {
+		logger.Logger.Error("failed to unmarshal error response template for channel",
+			zap.Int("channel_id", channel.Id),
+			zap.Error(err))
+		return nil
+	}
+	return template
+}
+
+// ErrorResponseMessage returns the custom message the channel shows clients for
+// an error with statusCode, if it configures one.
+func (channel *Channel) ErrorResponseMessage(statusCode int) (string, bool) {
+	message, ok := channel.GetErrorResponseTemplate()[strconv.Itoa(statusCode)]
+	if !ok || message == "" {
+		return "", false
+	}
+	return message, true
+}
+
+// ValidateErrorResponseTemplateJSON validates a JSON string for channel error response templates
+func ValidateErrorResponseTemplateJSON(jsonStr string) error {
+	if jsonStr == "" {
+		return nil // Empty is allowed
+	}
+
+	var template map[string]string
+	if err := json.Unmarshal([]byte(jsonStr), &template); err != nil {
+		return errors.Errorf("invalid JSON format: %v", err)
+	}
+
+	for status, message := range template {
+		code, err := strconv.Atoi(status)
+		if err != nil || code < 400 || code > 599 {
+			return errors.Errorf("error response template key %q must be an HTTP error status code (400-599)", status)
+		}
+		if message == "" {
+			return errors.Errorf("error response template for status %s cannot be empty", status)
+		}
+	}
+
+	return nil
+}
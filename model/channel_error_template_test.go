@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateErrorResponseTemplateJSON(t *testing.T) {
+	require.NoError(t, ValidateErrorResponseTemplateJSON(""))
+	require.NoError(t, ValidateErrorResponseTemplateJSON(`{"429": "Slow down", "503": "Maintenance"}`))
+	require.Error(t, ValidateErrorResponseTemplateJSON(`{"429": ""}`))
+	require.Error(t, ValidateErrorResponseTemplateJSON(`{"200": "ok"}`))
+	require.Error(t, ValidateErrorResponseTemplateJSON(`{"rate": "limited"}`))
+	require.Error(t, ValidateErrorResponseTemplateJSON(`["429"]`))
+}
+
+func TestChannelErrorResponseMessage(t *testing.T) {
+	template := `{"429": "Rate limit exceeded. Contact admin."}`
+	channel := &Channel{ErrorResponseTemplate: &template}
+
+	message, ok := channel.ErrorResponseMessage(429)
+	require.True(t, ok)
+	require.Equal(t, "Rate limit exceeded. Contact admin.", message)
+	_, ok = channel.ErrorResponseMessage(500)
+	require.False(t, ok)
+	_, ok = (&Channel{}).ErrorResponseMessage(429)
+	require.False(t, ok)
+}
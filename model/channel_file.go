@@ -0,0 +1,187 @@
+package model
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// ChannelSourceFile marks channels loaded from CHANNEL_CONFIG_PATH. Channels
+// created through the admin API have an empty source.
+const ChannelSourceFile = "file"
+
+// FileChannel is one entry of the channel config file.
+type FileChannel struct {
+	Name     string   `json:"name" yaml:"name"`
+	Type     int      `json:"type" yaml:"type"`
+	BaseURL  string   `json:"base_url" yaml:"base_url"`
+	Key      string   `json:"key" yaml:"key"`
+	Models   []string `json:"models" yaml:"models"`
+	Group    string   `json:"group" yaml:"group"`
+	Priority int64    `json:"priority" yaml:"priority"`
+}
+
+// IsReadOnly reports whether the channel is managed outside the admin API.
+func (channel *Channel) IsReadOnly() bool {
+	return channel.Source == ChannelSourceFile
+}
+
+// LoadChannelConfigFile reads and validates the channel config file at path.
+// Files ending in ".json" are parsed as JSON, all others as YAML.
+func LoadChannelConfigFile(path string) ([]FileChannel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read channel config file %s", path)
+	}
+
+	var channels []FileChannel
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &channels)
+	} else {
+		err = yaml.Unmarshal(data, &channels)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse channel config file %s", path)
+	}
+
+	names := make(map[string]bool, len(channels))
+	for i := range channels {
+		ch := &channels[i]
+		if err := ch.normalize(); err != nil {
+			return nil, errors.Wrapf(err, "channel #%d in %s", i+1, path)
+		}
+		if names[ch.Name] {
+			return nil, errors.Errorf("duplicate channel name %q in %s", ch.Name, path)
+		}
+		names[ch.Name] = true
+	}
+	return channels, nil
+}
+
+// normalize trims the entry, applies defaults and checks required fields.
+func (f *FileChannel) normalize() error {
+	f.Name = strings.TrimSpace(f.Name)
+	f.BaseURL = strings.TrimSpace(f.BaseURL)
+	f.Key = strings.TrimSpace(f.Key)
+	f.Group = strings.TrimSpace(f.Group)
+	if f.Group == "" {
+		f.Group = "default"
+	}
+	models := make([]string, 0, len(f.Models))
+	for _, m := range f.Models {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+	f.Models = models
+
+	switch {
+	case f.Name == "":
+		return errors.New("name is required")
+	case f.Type <= 0:
+		return errors.Errorf("channel %q: type is required", f.Name)
+	case f.Key == "":
+		return errors.Errorf("channel %q: key is required", f.Name)
+	case len(f.Models) == 0:
+		return errors.Errorf("channel %q: models is required", f.Name)
+	}
+	return nil
+}
+
+// SyncFileChannels upserts the channels of the config file at path, matched
+// by name against the channels previously loaded from a file, and deletes
+// file channels no longer listed. The file is fully validated before the
+// database is touched, and all changes are applied in one transaction so a
+// failed sync leaves the channels as they were. Callers refresh the channel
+// cache afterwards.
+func SyncFileChannels(path string) error {
+	entries, err := LoadChannelConfigFile(path)
+	if err != nil {
+		return errors.Wrap(err, "load channel config file")
+	}
+
+	created, updated, removed := 0, 0, 0
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var existing []*Channel
+		if err := tx.Where("source = ?", ChannelSourceFile).Find(&existing).Error; err != nil {
+			return errors.Wrap(err, "query file channels")
+		}
+		byName := make(map[string]*Channel, len(existing))
+		for _, ch := range existing {
+			byName[ch.Name] = ch
+		}
+
+		for _, entry := range entries {
+			if ch, ok := byName[entry.Name]; ok {
+				delete(byName, entry.Name)
+				entry.applyTo(ch)
+				if err := tx.Model(ch).
+					Select("type", "key", "base_url", "models", "group", "priority").
+					Updates(ch).Error; err != nil {
+					return errors.Wrapf(err, "update file channel %q", entry.Name)
+				}
+				if err := ch.updateAbilities(tx); err != nil {
+					return errors.Wrapf(err, "update abilities of file channel %q", entry.Name)
+				}
+				updated++
+				continue
+			}
+
+			ch := &Channel{
+				Status:      ChannelStatusEnabled,
+				CreatedTime: helper.GetTimestamp(),
+				Source:      ChannelSourceFile,
+			}
+			entry.applyTo(ch)
+			if err := tx.Create(ch).Error; err != nil {
+				return errors.Wrapf(err, "create file channel %q", entry.Name)
+			}
+			if err := ch.addAbilities(tx); err != nil {
+				return errors.Wrapf(err, "add abilities of file channel %q", entry.Name)
+			}
+			created++
+		}
+
+		removed = len(byName)
+		for name, ch := range byName {
+			if err := tx.Delete(ch).Error; err != nil {
+				return errors.Wrapf(err, "delete file channel %q", name)
+			}
+			if err := ch.deleteAbilities(tx); err != nil {
+				return errors.Wrapf(err, "delete abilities of file channel %q", name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.Info("channel config file synced",
+		zap.String("path", path),
+		zap.Int("created", created),
+		zap.Int("updated", updated),
+		zap.Int("removed", removed))
+	return nil
+}
+
+// applyTo copies the file entry onto ch.
+func (f *FileChannel) applyTo(ch *Channel) {
+	baseURL, priority := f.BaseURL, f.Priority
+	ch.Name = f.Name
+	ch.Type = f.Type
+	ch.Key = f.Key
+	ch.BaseURL = &baseURL
+	ch.Models = strings.Join(f.Models, ",")
+	ch.Group = f.Group
+	ch.Priority = &priority
+}
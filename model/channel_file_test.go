@@ -0,0 +1,132 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+const testChannelConfigYAML = `
+- name: openai-primary
+  type: 1
+  base_url: https://api.openai.com
+  key: sk-primary
+  models: [gpt-4o, gpt-4o-mini]
+  group: default,vip
+  priority: 10
+- name: claude
+  type: 14
+  key: sk-ant
+  models:
+    - claude-sonnet-4-5
+`
+
+func writeChannelConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func useChannelTestDB(t *testing.T) {
+	t.Helper()
+	originalDB := DB
+	DB = setupTestDB(t)
+	t.Cleanup(func() { DB = originalDB })
+}
+
+func TestSyncFileChannels(t *testing.T) {
+	originalUsingSQLite := common.UsingSQLite.Load()
+	common.UsingSQLite.Store(true)
+	t.Cleanup(func() { common.UsingSQLite.Store(originalUsingSQLite) })
+	useChannelTestDB(t)
+	path := writeChannelConfig(t, "channels.yaml", testChannelConfigYAML)
+
+	require.NoError(t, SyncFileChannels(path))
+	var channels []Channel
+	require.NoError(t, DB.Order("name").Find(&channels).Error)
+	require.Len(t, channels, 2)
+	claude, openai := channels[0], channels[1]
+	require.Equal(t, "openai-primary", openai.Name)
+	require.Equal(t, ChannelSourceFile, openai.Source)
+	require.True(t, openai.IsReadOnly())
+	require.Equal(t, "gpt-4o,gpt-4o-mini", openai.Models)
+	require.Equal(t, "https://api.openai.com", openai.GetBaseURL())
+	require.Equal(t, int64(10), openai.GetPriority())
+	require.Equal(t, ChannelStatusEnabled, openai.Status)
+	require.Equal(t, "default", claude.Group)
+
+	var abilities int64
+	require.NoError(t, DB.Model(&Ability{}).Where("channel_id = ?", openai.Id).Count(&abilities).Error)
+	require.EqualValues(t, 4, abilities)
+
+	// A restart against an empty database restores the channels from the file
+	useChannelTestDB(t)
+	require.NoError(t, SyncFileChannels(path))
+	var restored []Channel
+	require.NoError(t, DB.Order("name").Find(&restored).Error)
+	require.Len(t, restored, 2)
+	require.Equal(t, "sk-primary", restored[1].Key)
+
+	// Reloading an edited file updates channels in place and drops removed ones
+	admin := &Channel{Name: "admin-managed", Type: 1, Key: "sk-admin", Models: "gpt-4o", Group: "default", Status: ChannelStatusEnabled}
+	require.NoError(t, DB.Create(admin).Error)
+	edited := writeChannelConfig(t, "channels.json",
+		`[{"name":"openai-primary","type":1,"key":"sk-rotated","models":["gpt-4o"],"group":"default"}]`)
+	require.NoError(t, SyncFileChannels(edited))
+
+	var after []Channel
+	require.NoError(t, DB.Order("name").Find(&after).Error)
+	require.Len(t, after, 2)
+	require.Equal(t, "admin-managed", after[0].Name)
+	require.Equal(t, "openai-primary", after[1].Name)
+	require.Equal(t, restored[1].Id, after[1].Id)
+	require.Equal(t, "sk-rotated", after[1].Key)
+	require.Equal(t, "gpt-4o", after[1].Models)
+	require.NoError(t, DB.Model(&Ability{}).Where("channel_id = ?", restored[0].Id).Count(&abilities).Error)
+	require.Zero(t, abilities)
+}
+
+func TestSyncFileChannels_RollsBackOnFailure(t *testing.T) {
+	originalUsingSQLite := common.UsingSQLite.Load()
+	common.UsingSQLite.Store(true)
+	t.Cleanup(func() { common.UsingSQLite.Store(originalUsingSQLite) })
+	useChannelTestDB(t)
+	require.NoError(t, DB.Callback().Create().Before("gorm:create").Register("test:fail_claude", func(tx *gorm.DB) {
+		if ch, ok := tx.Statement.Dest.(*Channel); ok && ch.Name == "claude" {
+			_ = tx.AddError(errors.New("create rejected"))
+		}
+	}))
+
+	require.Error(t, SyncFileChannels(writeChannelConfig(t, "channels.yaml", testChannelConfigYAML)))
+
+	// The channel created before the failure is rolled back with its abilities
+	var channels, abilities int64
+	require.NoError(t, DB.Model(&Channel{}).Count(&channels).Error)
+	require.Zero(t, channels)
+	require.NoError(t, DB.Model(&Ability{}).Count(&abilities).Error)
+	require.Zero(t, abilities)
+}
+
+func TestLoadChannelConfigFile_Validation(t *testing.T) {
+	for name, content := range map[string]string{
+		"name is required":          `[{"type":1,"key":"sk","models":["gpt-4o"]}]`,
+		"type is required":          `[{"name":"a","key":"sk","models":["gpt-4o"]}]`,
+		"key is required":           `[{"name":"a","type":1,"models":["gpt-4o"]}]`,
+		"models is required":        `[{"name":"a","type":1,"key":"sk","models":[" "]}]`,
+		"duplicate channel name":    `[{"name":"a","type":1,"key":"sk","models":["m"]},{"name":"a","type":1,"key":"sk","models":["m"]}]`,
+		"parse channel config file": `{"name":"a"}`,
+	} {
+		_, err := LoadChannelConfigFile(writeChannelConfig(t, "channels.json", content))
+		require.ErrorContains(t, err, name)
+	}
+
+	_, err := LoadChannelConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"github.com/Laisky/errors/v2"
+	"gorm.io/gorm"
+)
+
+// ChannelPricingSnapshot records the default pricing of a channel type at a
+// point in time, so pricing changes between releases can be detected.
+type ChannelPricingSnapshot struct {
+	Id          int `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChannelType int `json:"channel_type" gorm:"index;not null"`
+	// Pricing is the JSON encoded model price list of the channel type.
+	Pricing string `json:"pricing" gorm:"type:text"`
+	// Changelog is the markdown entry describing the change from the previous
+	// snapshot, empty for the first snapshot of a channel type.
+	Changelog string `json:"changelog" gorm:"type:text"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint;autoCreateTime"`
+}
+
+// GetLatestChannelPricingSnapshot returns the newest pricing snapshot of
+// channelType, or nil when none was recorded.
+func GetLatestChannelPricingSnapshot(channelType int) (*ChannelPricingSnapshot, error) {
+	var snapshot ChannelPricingSnapshot
+	err := DB.Where("channel_type = ?", channelType).Order("id desc").First(&snapshot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "get latest pricing snapshot of channel type %d", channelType)
+	}
+	return &snapshot, nil
+}
+
+// CreateChannelPricingSnapshot stores a new pricing snapshot.
+func CreateChannelPricingSnapshot(snapshot *ChannelPricingSnapshot) error {
+	if err := DB.Create(snapshot).Error; err != nil {
+		return errors.Wrapf(err, "create pricing snapshot of channel type %d", snapshot.ChannelType)
+	}
+	return nil
+}
+
+// GetChannelPricingChangelog returns the snapshots of channelType that carry a
+// changelog entry, newest first.
+func GetChannelPricingChangelog(channelType int) ([]ChannelPricingSnapshot, error) {
+	var snapshots []ChannelPricingSnapshot
+	err := DB.Where("channel_type = ? AND changelog <> ?", channelType, "").
+		Order("id desc").
+		Find(&snapshots).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "get pricing changelog of channel type %d", channelType)
+	}
+	return snapshots, nil
+}
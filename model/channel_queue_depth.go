@@ -0,0 +1,210 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+const (
+	// channelQueueDepthTTLSlack is added to the relay timeout to get the lifetime
+	// of a queue depth counter, so counters leaked by crashed instances expire.
+	channelQueueDepthTTLSlack = 10 * time.Second
+	// channelQueueDepthDefaultTimeout stands in for the relay timeout when
+	// RELAY_TIMEOUT is unset.
+	channelQueueDepthDefaultTimeout = 10 * time.Minute
+)
+
+// memoryChannelQueueDepths counts in-flight requests per channel when Redis is
+// unavailable. It is only accurate for a single instance.
+var memoryChannelQueueDepths = struct {
+	sync.Mutex
+	depths map[int]int64
+}{depths: make(map[int]int64)}
+
+func channelQueueDepthKey(channelId int) string {
+	return fmt.Sprintf("channel_queue:%d", channelId)
+}
+
+// channelQueueDepthTTL returns RelayTimeout + 10s, the longest a request can
+// legitimately stay in flight plus some slack.
+func channelQueueDepthTTL() time.Duration {
+	timeout := time.Duration(config.RelayTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = channelQueueDepthDefaultTimeout
+	}
+	return timeout + channelQueueDepthTTLSlack
+}
+
+// TrackChannelQueueDepth counts a request in flight on channelId and returns
+// the function that releases it. It is a no-op unless queue depth routing is
+// enabled.
+func TrackChannelQueueDepth(ctx context.Context, channelId int) (release func()) {
+	if !config.QueueDepthRoutingEnabled || channelId == 0 {
+		return func() {}
+	}
+	adjustChannelQueueDepth(ctx, channelId, 1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// The request context may already be cancelled when the relay ends
+			adjustChannelQueueDepth(context.WithoutCancel(ctx), channelId, -1)
+		})
+	}
+}
+
+func adjustChannelQueueDepth(ctx context.Context, channelId int, delta int64) {
+	if common.IsRedisEnabled() && common.RDB != nil {
+		key := channelQueueDepthKey(channelId)
+		pipe := common.RDB.TxPipeline()
+		incr := pipe.IncrBy(ctx, key, delta)
+		pipe.Expire(ctx, key, channelQueueDepthTTL())
+		if _, err := pipe.Exec(ctx); err != nil {
+			logger.Logger.Warn("failed to update channel queue depth",
+				zap.Int("channel_id", channelId), zap.Int64("delta", delta), zap.Error(err))
+			return
+		}
+		// A counter that expired while requests were in flight goes negative on release
+		if incr.Val() < 0 {
+			if err := common.RedisDel(ctx, key); err != nil {
+				logger.Logger.Warn("failed to reset negative channel queue depth",
+					zap.Int("channel_id", channelId), zap.Error(err))
+			}
+		}
+		return
+	}
+
+	memoryChannelQueueDepths.Lock()
+	defer memoryChannelQueueDepths.Unlock()
+	depth := memoryChannelQueueDepths.depths[channelId] + delta
+	if depth <= 0 {
+		delete(memoryChannelQueueDepths.depths, channelId)
+		return
+	}
+	memoryChannelQueueDepths.depths[channelId] = depth
+}
+
+// GetChannelQueueDepths returns the number of in-flight requests of each of
+// the given channels. Channels without requests in flight map to 0.
+func GetChannelQueueDepths(ctx context.Context, channelIds []int) (map[int]int64, error) {
+	depths := make(map[int]int64, len(channelIds))
+	if len(channelIds) == 0 {
+		return depths, nil
+	}
+
+	if common.IsRedisEnabled() && common.RDB != nil {
+		keys := make([]string, len(channelIds))
+		for i, id := range channelIds {
+			keys[i] = channelQueueDepthKey(id)
+		}
+		values, err := common.RDB.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, errors.Wrap(err, "read channel queue depths")
+		}
+		for i, id := range channelIds {
+			depths[id] = 0
+			s, ok := values[i].(string)
+			if !ok {
+				continue
+			}
+			depth, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse queue depth of channel %d", id)
+			}
+			depths[id] = max(depth, 0)
+		}
+		return depths, nil
+	}
+
+	memoryChannelQueueDepths.Lock()
+	defer memoryChannelQueueDepths.Unlock()
+	for _, id := range channelIds {
+		depths[id] = memoryChannelQueueDepths.depths[id]
+	}
+	return depths, nil
+}
+
+// pickChannelByQueueDepth picks one of the equal-priority candidates, weighting
+// each by 1/(depth+1) so that channels with shorter queues receive
+// proportionally more requests. It falls back to a uniform pick when queue
+// depth routing is disabled or depths cannot be read.
+func pickChannelByQueueDepth(ctx context.Context, candidates []*Channel) *Channel {
+	if len(candidates) == 1 || !config.QueueDepthRoutingEnabled {
+		return candidates[rand.Intn(len(candidates))]
+	}
+
+	ids := make([]int, len(candidates))
+	for i, ch := range candidates {
+		ids[i] = ch.Id
+	}
+	depths, err := GetChannelQueueDepths(ctx, ids)
+	if err != nil {
+		logger.Logger.Warn("failed to read channel queue depths, selecting channel uniformly", zap.Error(err))
+		return candidates[rand.Intn(len(candidates))]
+	}
+
+	weights := make([]float64, len(candidates))
+	var total float64
+	for i, ch := range candidates {
+		weights[i] = 1 / float64(depths[ch.Id]+1)
+		total += weights[i]
+	}
+	r := rand.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return candidates[i]
+		}
+		r -= w
+	}
+	return candidates[len(candidates)-1]
+}
+
+// ChannelQueueDepth is the number of requests in flight on an enabled channel.
+type ChannelQueueDepth struct {
+	ChannelId   int    `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	Priority    int64  `json:"priority"`
+	Depth       int64  `json:"depth"`
+}
+
+// ListChannelQueueDepths returns the queue depth of every enabled channel.
+func ListChannelQueueDepths(ctx context.Context) ([]ChannelQueueDepth, error) {
+	var channels []*Channel
+	err := DB.Select("id", "name", "priority").
+		Where("status = ?", ChannelStatusEnabled).
+		Order("id").
+		Find(&channels).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list enabled channels")
+	}
+
+	ids := make([]int, len(channels))
+	for i, ch := range channels {
+		ids[i] = ch.Id
+	}
+	depths, err := GetChannelQueueDepths(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ChannelQueueDepth, len(channels))
+	for i, ch := range channels {
+		result[i] = ChannelQueueDepth{
+			ChannelId:   ch.Id,
+			ChannelName: ch.Name,
+			Priority:    ch.GetPriority(),
+			Depth:       depths[ch.Id],
+		}
+	}
+	return result, nil
+}
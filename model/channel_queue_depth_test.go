@@ -0,0 +1,185 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func setupQueueDepthRoutingTest(t *testing.T) {
+	t.Helper()
+	originalRedis := common.IsRedisEnabled()
+	originalEnabled := config.QueueDepthRoutingEnabled
+	originalMemoryCache := config.MemoryCacheEnabled
+	common.SetRedisEnabled(false)
+	config.QueueDepthRoutingEnabled = true
+	config.MemoryCacheEnabled = true
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedis)
+		config.QueueDepthRoutingEnabled = originalEnabled
+		config.MemoryCacheEnabled = originalMemoryCache
+		memoryChannelQueueDepths.Lock()
+		memoryChannelQueueDepths.depths = make(map[int]int64)
+		memoryChannelQueueDepths.Unlock()
+	})
+}
+
+func TestTrackChannelQueueDepth(t *testing.T) {
+	setupQueueDepthRoutingTest(t)
+	ctx := context.Background()
+
+	releaseA := TrackChannelQueueDepth(ctx, 7)
+	releaseB := TrackChannelQueueDepth(ctx, 7)
+	depths, err := GetChannelQueueDepths(ctx, []int{7, 8})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int64{7: 2, 8: 0}, depths)
+
+	releaseA()
+	releaseA() // releasing twice counts once
+	depths, err = GetChannelQueueDepths(ctx, []int{7})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), depths[7])
+
+	releaseB()
+	depths, err = GetChannelQueueDepths(ctx, []int{7})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), depths[7])
+
+	config.QueueDepthRoutingEnabled = false
+	TrackChannelQueueDepth(ctx, 7)
+	depths, err = GetChannelQueueDepths(ctx, []int{7})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), depths[7])
+}
+
+func TestChannelQueueDepthTTL(t *testing.T) {
+	original := config.RelayTimeout
+	t.Cleanup(func() { config.RelayTimeout = original })
+
+	config.RelayTimeout = 120
+	require.Equal(t, 130*time.Second, channelQueueDepthTTL())
+	config.RelayTimeout = 0
+	require.Equal(t, channelQueueDepthDefaultTimeout+10*time.Second, channelQueueDepthTTL())
+}
+
+func TestQueueDepthRoutingDistributesByDepth(t *testing.T) {
+	setupQueueDepthRoutingTest(t)
+	ctx := context.Background()
+
+	priority := int64(10)
+	lowPriority := int64(1)
+	channels := []*Channel{
+		{Id: 101, Name: "idle", Models: "queue-model", Priority: &priority},
+		{Id: 102, Name: "busy", Models: "queue-model", Priority: &priority},
+		{Id: 103, Name: "busier", Models: "queue-model", Priority: &priority},
+		{Id: 104, Name: "fallback", Models: "queue-model", Priority: &lowPriority},
+	}
+	channelSyncLock.Lock()
+	if group2model2channels == nil {
+		group2model2channels = make(map[string]map[string][]*Channel)
+	}
+	if group2model2channels["queue-depth-test"] == nil {
+		group2model2channels["queue-depth-test"] = make(map[string][]*Channel)
+	}
+	group2model2channels["queue-depth-test"]["queue-model"] = channels
+	channelSyncLock.Unlock()
+	t.Cleanup(func() {
+		channelSyncLock.Lock()
+		delete(group2model2channels, "queue-depth-test")
+		channelSyncLock.Unlock()
+	})
+
+	// Simulate load: depths 0, 1 and 3 weight the channels 1 : 1/2 : 1/4
+	TrackChannelQueueDepth(ctx, 102)
+	for range 3 {
+		TrackChannelQueueDepth(ctx, 103)
+	}
+
+	const draws = 14000
+	counts := make(map[int]int)
+	for i := range draws {
+		var (
+			channel *Channel
+			err     error
+		)
+		if i%2 == 0 {
+			channel, err = CacheGetRandomSatisfiedChannel("queue-depth-test", "queue-model", false)
+		} else {
+			channel, err = CacheGetRandomSatisfiedChannelExcluding("queue-depth-test", "queue-model", false, map[int]bool{}, false)
+		}
+		require.NoError(t, err)
+		counts[channel.Id]++
+	}
+
+	require.Zero(t, counts[104], "lower priority channel must not be selected")
+	// Expected shares are 4/7, 2/7 and 1/7
+	require.InDelta(t, 4.0/7, float64(counts[101])/draws, 0.03)
+	require.InDelta(t, 2.0/7, float64(counts[102])/draws, 0.03)
+	require.InDelta(t, 1.0/7, float64(counts[103])/draws, 0.03)
+}
+
+func TestQueueDepthRoutingDisabledIsUniform(t *testing.T) {
+	setupQueueDepthRoutingTest(t)
+	config.QueueDepthRoutingEnabled = false
+
+	// Depths are ignored when routing is disabled
+	memoryChannelQueueDepths.Lock()
+	memoryChannelQueueDepths.depths[202] = 50
+	memoryChannelQueueDepths.Unlock()
+
+	candidates := []*Channel{{Id: 201}, {Id: 202}}
+	const draws = 4000
+	counts := make(map[int]int)
+	for range draws {
+		counts[pickChannelByQueueDepth(context.Background(), candidates).Id]++
+	}
+	require.InDelta(t, 0.5, float64(counts[202])/draws, 0.05)
+}
+
+func TestQueueDepthRoutingOnDatabasePath(t *testing.T) {
+	setupQueueDepthRoutingTest(t)
+	config.MemoryCacheEnabled = false
+	testDB := setupTestDB(t)
+	originalDB := DB
+	DB = testDB
+	t.Cleanup(func() { DB = originalDB })
+	originalUsingSQLite := common.UsingSQLite.Load()
+	common.UsingSQLite.Store(true)
+	t.Cleanup(func() { common.UsingSQLite.Store(originalUsingSQLite) })
+	ctx := context.Background()
+
+	for _, channel := range []Channel{
+		{Id: 111, Name: "idle", Status: ChannelStatusEnabled, Models: "queue-model", Group: "default"},
+		{Id: 112, Name: "busy", Status: ChannelStatusEnabled, Models: "queue-model", Group: "default"},
+	} {
+		require.NoError(t, DB.Create(&channel).Error)
+		require.NoError(t, channel.AddAbilities())
+	}
+
+	// Depths 0 and 3 weight the channels 1 : 1/4
+	for range 3 {
+		TrackChannelQueueDepth(ctx, 112)
+	}
+
+	const draws = 2000
+	counts := make(map[int]int)
+	for i := range draws {
+		var (
+			channel *Channel
+			err     error
+		)
+		if i%2 == 0 {
+			channel, err = CacheGetRandomSatisfiedChannel("default", "queue-model", false)
+		} else {
+			channel, err = CacheGetRandomSatisfiedChannelExcluding("default", "queue-model", false, map[int]bool{}, false)
+		}
+		require.NoError(t, err)
+		counts[channel.Id]++
+	}
+	require.InDelta(t, 4.0/5, float64(counts[111])/draws, 0.05)
+}
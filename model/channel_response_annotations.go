@@ -0,0 +1,77 @@
+package model
+
+import (
+	"encoding/json"
+	"net/textproto"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// ChannelResponseAnnotations configures upstream attribution added to responses
+// relayed through a channel.
+type ChannelResponseAnnotations struct {
+	// InjectHeader is the response header that receives the channel name, e.g.
+	// "X-OneApi-Channel". Empty disables the header.
+	InjectHeader string `json:"inject_header,omitempty"`
+	// InjectMetadata appends a top-level "oneapi_metadata" object to JSON
+	// responses, or a final event to streaming responses.
+	InjectMetadata bool `json:"inject_metadata,omitempty"`
+}
+
+// Enabled reports whether the annotations change the response at all.
+func (a *ChannelResponseAnnotations) Enabled() bool {
+	return a != nil && (a.InjectHeader != "" || a.InjectMetadata)
+}
+
+// GetResponseAnnotations returns the channel's parsed response annotations, or
+// nil when none are configured or the stored JSON is invalid.
+func (channel *Channel) GetResponseAnnotations() *ChannelResponseAnnotations {
+	if channel.ResponseAnnotations == nil || *channel.ResponseAnnotations == "" || *channel.ResponseAnnotations == "{}" {
+		return nil
+	}
+	annotations := &ChannelResponseAnnotations{}
+	if err := json.Unmarshal([]byte(*channel.ResponseAnnotations), annotations); err != nil {
+		logger.Logger.Error("failed to unmarshal response annotations for channel",
+			zap.Int("channel_id", channel.Id),
+			zap.Error(err))
+		return nil
+	}
+	if !annotations.Enabled() {
+		return nil
+	}
+	return annotations
+}
+
+// ValidateResponseAnnotationsJSON validates a JSON string for channel response annotations
+func ValidateResponseAnnotationsJSON(jsonStr string) error {
+	if jsonStr == "" {
+		return nil // Empty is allowed
+	}
+
+	var annotations ChannelResponseAnnotations
+	decoder := json.NewDecoder(strings.NewReader(jsonStr))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&annotations); err != nil {
+		return errors.Errorf("invalid JSON format: %v", err)
+	}
+
+	if annotations.InjectHeader != "" && !isValidHeaderName(annotations.InjectHeader) {
+		return errors.Errorf("inject_header %q is not a valid HTTP header name", annotations.InjectHeader)
+	}
+
+	return nil
+}
+
+// isValidHeaderName reports whether name consists only of HTTP token characters.
+func isValidHeaderName(name string) bool {
+	for _, r := range name {
+		if r >= 0x80 || r <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r) {
+			return false
+		}
+	}
+	return textproto.CanonicalMIMEHeaderKey(name) != ""
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/network"
+)
+
+const (
+	// channelSSRFAllowedHostsOption holds the hosts exempt from the channel SSRF protection.
+	channelSSRFAllowedHostsOption = "ChannelSSRFAllowedHosts"
+	// channelHostLookupTimeout bounds the DNS lookup of each channel host during the migration.
+	channelHostLookupTimeout = 5 * time.Second
+)
+
+// MigrateChannelSSRFAllowlist grandfathers the channels that already point at
+// internal hosts when the channel SSRF protection is enabled for the first
+// time, by adding their hosts to the ChannelSSRFAllowedHosts option. It runs
+// once: an existing option means the allowlist is managed by admins from then on.
+func MigrateChannelSSRFAllowlist() error {
+	if !config.ChannelSSRFProtectionEnabled {
+		return nil
+	}
+	var existing []Option
+	if err := DB.Limit(1).Find(&existing, Option{Key: channelSSRFAllowedHostsOption}).Error; err != nil {
+		return errors.Wrap(err, "check channel SSRF allowlist option")
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+
+	var channels []Channel
+	if err := DB.Select("id", "base_url").Find(&channels).Error; err != nil {
+		return errors.Wrap(err, "fetch channels for SSRF allowlist migration")
+	}
+	var hosts []string
+	for _, channel := range channels {
+		parsed, err := url.Parse(channel.GetBaseURL())
+		if err != nil || parsed.Hostname() == "" {
+			continue
+		}
+		host := strings.ToLower(parsed.Hostname())
+		if slices.Contains(hosts, host) {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), channelHostLookupTimeout)
+		err = network.CheckPublicHost(ctx, host)
+		cancel()
+		switch {
+		case err == nil:
+		case errors.Is(err, network.ErrNonPublicAddress):
+			logger.Logger.Info("allowlisting internal channel host for SSRF protection",
+				zap.Int("channel_id", channel.Id),
+				zap.String("host", host))
+			hosts = append(hosts, host)
+		default:
+			logger.Logger.Warn("failed to resolve channel host for SSRF allowlist migration",
+				zap.Int("channel_id", channel.Id),
+				zap.String("host", host),
+				zap.Error(err))
+		}
+	}
+
+	value := strings.Join(hosts, ",")
+	if err := DB.Create(&Option{Key: channelSSRFAllowedHostsOption, Value: value}).Error; err != nil {
+		return errors.Wrap(err, "save channel SSRF allowlist option")
+	}
+	config.ChannelSSRFAllowedHosts = config.ParseHostList(value)
+	return nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func TestMigrateChannelSSRFAllowlist(t *testing.T) {
+	testDB := setupTestDB(t)
+	require.NoError(t, testDB.AutoMigrate(&Option{}))
+	originalDB := DB
+	DB = testDB
+	originalEnabled := config.ChannelSSRFProtectionEnabled
+	originalHosts := config.ChannelSSRFAllowedHosts
+	t.Cleanup(func() {
+		DB = originalDB
+		config.ChannelSSRFProtectionEnabled = originalEnabled
+		config.ChannelSSRFAllowedHosts = originalHosts
+	})
+
+	baseURLs := []string{"http://127.0.0.1:8080", "http://192.168.0.1/v1", "https://8.8.8.8", "http://192.168.0.1:9000", ""}
+	for i, baseURL := range baseURLs {
+		require.NoError(t, DB.Create(&Channel{Id: i + 1, Name: "ch", Key: "k", BaseURL: &baseURL}).Error)
+	}
+
+	// Nothing happens until the protection is enabled
+	config.ChannelSSRFProtectionEnabled = false
+	require.NoError(t, MigrateChannelSSRFAllowlist())
+	var count int64
+	require.NoError(t, DB.Model(&Option{}).Count(&count).Error)
+	require.Zero(t, count)
+
+	config.ChannelSSRFProtectionEnabled = true
+	require.NoError(t, MigrateChannelSSRFAllowlist())
+	var option Option
+	require.NoError(t, DB.First(&option, Option{Key: channelSSRFAllowedHostsOption}).Error)
+	require.Equal(t, "127.0.0.1,192.168.0.1", option.Value)
+	require.Equal(t, []string{"127.0.0.1", "192.168.0.1"}, config.ChannelSSRFAllowedHosts)
+
+	// Channels added afterwards are not grandfathered
+	internal := "http://10.0.0.5"
+	require.NoError(t, DB.Create(&Channel{Id: 10, Name: "late", Key: "k", BaseURL: &internal}).Error)
+	require.NoError(t, MigrateChannelSSRFAllowlist())
+	require.NoError(t, DB.First(&option, Option{Key: channelSSRFAllowedHostsOption}).Error)
+	require.Equal(t, "127.0.0.1,192.168.0.1", option.Value)
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// GetModelStreamingTimeouts returns the per-model streaming idle timeouts, in
+// seconds, or nil when none are configured or the stored JSON is invalid.
+func (channel *Channel) GetModelStreamingTimeouts() map[string]int {
+	if channel.ModelStreamingTimeouts == nil || *channel.ModelStreamingTimeouts == "" || *channel.ModelStreamingTimeouts == "{}" {
+		return nil
+	}
+	timeouts := make(map[string]int)
+	if err := json.Unmarshal([]byte(*channel.ModelStreamingTimeouts), &timeouts); err != nil {
+		logger.Logger.Error("failed to unmarshal model streaming timeouts for channel",
+			zap.Int("channel_id", channel.Id),
+			zap.Error(err))
+		return nil
+	}
+	return timeouts
+}
+
+// ValidateModelStreamingTimeoutsJSON validates a JSON string for per-model streaming timeouts
+func ValidateModelStreamingTimeoutsJSON(jsonStr string) error {
+	if jsonStr == "" {
+		return nil // Empty is allowed
+	}
+
+	var timeouts map[string]int
+	if err := json.Unmarshal([]byte(jsonStr), &timeouts); err != nil {
+		return errors.Errorf("invalid JSON format: %v", err)
+	}
+
+	for modelName, seconds := range timeouts {
+		if modelName == "" {
+			return errors.New("model streaming timeouts cannot contain empty model names")
+		}
+		if seconds <= 0 {
+			return errors.Errorf("streaming timeout for model %s must be a positive number of seconds", modelName)
+		}
+	}
+
+	return nil
+}
@@ -790,3 +790,20 @@ func TestChannel_validateModelPriceConfigs(t *testing.T) {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestChannelValidateSystemPromptTranslation(t *testing.T) {
+	channel := &Channel{Id: 7}
+	require.NoError(t, channel.ValidateSystemPromptTranslation())
+
+	channel.AutoTranslateSystemPrompt = true
+	require.Error(t, channel.ValidateSystemPromptTranslation())
+
+	channel.TranslateTargetLanguage = "zh-CN"
+	require.Error(t, channel.ValidateSystemPromptTranslation())
+
+	channel.TranslationChannelId = 7
+	require.Error(t, channel.ValidateSystemPromptTranslation())
+
+	channel.TranslationChannelId = 8
+	require.NoError(t, channel.ValidateSystemPromptTranslation())
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Laisky/zap"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// channelWeightStateTTL is how long the round-robin state of a group and model
+// outlives its last selection.
+const channelWeightStateTTL = 24 * time.Hour
+
+// memoryChannelWeightState holds the current weights of the smooth weighted
+// round-robin per group and model when Redis is unavailable. It is only
+// shared by the requests of a single instance.
+var memoryChannelWeightState = struct {
+	sync.Mutex
+	current map[string]map[int]int64
+}{current: make(map[string]map[int]int64)}
+
+// channelWeightScript runs one smooth weighted round-robin step on the hash
+// KEYS[1]: every candidate in ARGV[2..] (id, weight pairs) gains its weight,
+// the one with the highest current weight is selected and loses the total.
+// It returns the selected id and refreshes the TTL of ARGV[1] seconds.
+var channelWeightScript = redis.NewScript(`
+local total = 0
+local best, bestCurrent
+for i = 2, #ARGV, 2 do
+  local weight = tonumber(ARGV[i + 1])
+  local current = redis.call('HINCRBY', KEYS[1], ARGV[i], weight)
+  total = total + weight
+  if bestCurrent == nil or current > bestCurrent then
+    best, bestCurrent = ARGV[i], current
+  end
+end
+redis.call('HINCRBY', KEYS[1], best, -total)
+redis.call('EXPIRE', KEYS[1], ARGV[1])
+return best
+`)
+
+func channelWeightStateKey(group string, modelName string) string {
+	return fmt.Sprintf("channel_weight:%s:%s", group, modelName)
+}
+
+// GetEffectiveWeight returns the load balancing weight of the channel. Unset
+// and zero weights count as 1, so channels nobody weighted keep an even share.
+func (channel *Channel) GetEffectiveWeight() int64 {
+	if channel.Weight == nil || *channel.Weight == 0 {
+		return 1
+	}
+	return int64(*channel.Weight)
+}
+
+// pickWeightedChannel selects one of candidates with smooth weighted
+// round-robin, as nginx does for upstreams, so that channels serving
+// group and modelName are picked in proportion to their weights without
+// bursts. It reports false when all candidates share one weight, leaving the
+// choice to the caller's usual strategy.
+func pickWeightedChannel(ctx context.Context, group string, modelName string, candidates []*Channel) (*Channel, bool) {
+	if len(candidates) < 2 {
+		return nil, false
+	}
+	firstWeight := candidates[0].GetEffectiveWeight()
+	equal := true
+	for _, ch := range candidates[1:] {
+		if ch.GetEffectiveWeight() != firstWeight {
+			equal = false
+			break
+		}
+	}
+	if equal {
+		return nil, false
+	}
+
+	// A stable order keeps ties resolving the same way on every node
+	ordered := make([]*Channel, len(candidates))
+	copy(ordered, candidates)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Id < ordered[j].Id })
+
+	key := channelWeightStateKey(group, modelName)
+	if common.IsRedisEnabled() && common.RDB != nil {
+		args := make([]any, 0, 1+2*len(ordered))
+		args = append(args, int(channelWeightStateTTL.Seconds()))
+		for _, ch := range ordered {
+			args = append(args, ch.Id, ch.GetEffectiveWeight())
+		}
+		selected, err := channelWeightScript.Run(ctx, common.RDB, []string{key}, args...).Text()
+		if err == nil {
+			if id, convErr := strconv.Atoi(selected); convErr == nil {
+				for _, ch := range ordered {
+					if ch.Id == id {
+						return ch, true
+					}
+				}
+			}
+		}
+		logger.Logger.Warn("failed to run weighted channel selection in redis, using local state",
+			zap.String("group", group), zap.String("model", modelName), zap.Error(err))
+	}
+
+	memoryChannelWeightState.Lock()
+	defer memoryChannelWeightState.Unlock()
+	current := memoryChannelWeightState.current[key]
+	if current == nil {
+		current = make(map[int]int64)
+		memoryChannelWeightState.current[key] = current
+	}
+	var total int64
+	var best *Channel
+	for _, ch := range ordered {
+		weight := ch.GetEffectiveWeight()
+		current[ch.Id] += weight
+		total += weight
+		if best == nil || current[ch.Id] > current[best.Id] {
+			best = ch
+		}
+	}
+	current[best.Id] -= total
+	return best, true
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+func setupChannelWeightTest(t *testing.T) {
+	t.Helper()
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedis)
+		memoryChannelWeightState.Lock()
+		memoryChannelWeightState.current = make(map[string]map[int]int64)
+		memoryChannelWeightState.Unlock()
+	})
+}
+
+func weightOf(weight uint) *uint {
+	return &weight
+}
+
+func TestPickWeightedChannelSmoothRoundRobin(t *testing.T) {
+	setupChannelWeightTest(t)
+	candidates := []*Channel{
+		{Id: 1, Weight: weightOf(5)},
+		{Id: 2, Weight: weightOf(1)},
+		{Id: 3, Weight: weightOf(1)},
+	}
+
+	// The nginx reference sequence for weights 5, 1, 1
+	var picked []int
+	for range 7 {
+		channel, ok := pickWeightedChannel(context.Background(), "default", "gpt-4o", candidates)
+		require.True(t, ok)
+		picked = append(picked, channel.Id)
+	}
+	require.Equal(t, []int{1, 1, 2, 1, 3, 1, 1}, picked)
+}
+
+func TestPickWeightedChannelDistribution(t *testing.T) {
+	setupChannelWeightTest(t)
+	candidates := []*Channel{
+		{Id: 11, Weight: weightOf(3)},
+		{Id: 12}, // unset weights count as 1
+	}
+
+	counts := make(map[int]int)
+	for range 400 {
+		channel, ok := pickWeightedChannel(context.Background(), "default", "gpt-4o", candidates)
+		require.True(t, ok)
+		counts[channel.Id]++
+	}
+	require.Equal(t, map[int]int{11: 300, 12: 100}, counts)
+
+	// State is kept per group and model
+	channel, ok := pickWeightedChannel(context.Background(), "vip", "gpt-4o", candidates)
+	require.True(t, ok)
+	require.Equal(t, 11, channel.Id)
+}
+
+func TestPickWeightedChannelEqualWeights(t *testing.T) {
+	setupChannelWeightTest(t)
+
+	_, ok := pickWeightedChannel(context.Background(), "default", "gpt-4o", []*Channel{
+		{Id: 21}, {Id: 22, Weight: weightOf(0)}, {Id: 23, Weight: weightOf(1)},
+	})
+	require.False(t, ok)
+
+	_, ok = pickWeightedChannel(context.Background(), "default", "gpt-4o", []*Channel{
+		{Id: 24, Weight: weightOf(10)},
+	})
+	require.False(t, ok)
+}
+
+func TestGetRandomSatisfiedChannelHonorsWeight(t *testing.T) {
+	setupChannelWeightTest(t)
+	testDB := setupTestDB(t)
+	originalDB := DB
+	DB = testDB
+	defer func() { DB = originalDB }()
+	originalUsingSQLite := common.UsingSQLite.Load()
+	common.UsingSQLite.Store(true)
+	defer func() { common.UsingSQLite.Store(originalUsingSQLite) }()
+
+	for _, channel := range []Channel{
+		{Id: 31, Name: "heavy", Status: ChannelStatusEnabled, Models: "gpt-4o", Group: "default", Weight: weightOf(3)},
+		{Id: 32, Name: "light", Status: ChannelStatusEnabled, Models: "gpt-4o", Group: "default", Weight: weightOf(1)},
+	} {
+		require.NoError(t, DB.Create(&channel).Error)
+		require.NoError(t, channel.AddAbilities())
+	}
+
+	counts := make(map[int]int)
+	for range 40 {
+		channel, err := GetRandomSatisfiedChannel("default", "gpt-4o", false)
+		require.NoError(t, err)
+		counts[channel.Id]++
+	}
+	require.Equal(t, map[int]int{31: 30, 32: 10}, counts)
+}
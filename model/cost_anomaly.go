@@ -0,0 +1,81 @@
+package model
+
+import (
+	"context"
+
+	"github.com/Laisky/errors/v2"
+)
+
+// CostAnomaly records a model whose hourly quota exceeded its baseline, the
+// mean plus two standard deviations of the same hour over the previous days.
+type CostAnomaly struct {
+	Id        int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ModelName string `json:"model_name" gorm:"type:varchar(255);uniqueIndex:idx_cost_anomaly_model_hour"`
+	// HourStart is the Unix second the anomalous hour began.
+	HourStart      int64   `json:"hour_start" gorm:"bigint;uniqueIndex:idx_cost_anomaly_model_hour;index"`
+	Quota          int64   `json:"quota"`
+	BaselineMean   float64 `json:"baseline_mean"`
+	BaselineStdDev float64 `json:"baseline_stddev"`
+	ZScore         float64 `json:"z_score"`
+	CreatedAt      int64   `json:"created_at" gorm:"bigint;autoCreateTime"`
+}
+
+// TableName stores detections in cost_anomalies.
+func (CostAnomaly) TableName() string {
+	return "cost_anomalies"
+}
+
+// GetModelQuota aggregates per model the quota of the consume logs created in
+// [from, to) of Unix seconds. Models without consumption are left out.
+func GetModelQuota(ctx context.Context, from, to int64) (map[string]int64, error) {
+	var rows []struct {
+		ModelName string
+		Quota     int64
+	}
+	if err := LOG_DB.WithContext(ctx).Model(&Log{}).
+		Select("model_name, COALESCE(SUM(quota), 0) as quota").
+		Where("type = ? AND created_at >= ? AND created_at < ?", LogTypeConsume, from, to).
+		Group("model_name").
+		Having("SUM(quota) > 0").
+		Scan(&rows).Error; err != nil {
+		return nil, errors.Wrap(err, "aggregate model quota")
+	}
+	quota := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		quota[row.ModelName] = row.Quota
+	}
+	return quota, nil
+}
+
+// CostAnomalyExists reports whether an anomaly of modelName was already
+// recorded for the hour starting at hourStart.
+func CostAnomalyExists(ctx context.Context, modelName string, hourStart int64) (bool, error) {
+	var count int64
+	if err := DB.WithContext(ctx).Model(&CostAnomaly{}).
+		Where("model_name = ? AND hour_start = ?", modelName, hourStart).
+		Count(&count).Error; err != nil {
+		return false, errors.Wrap(err, "check cost anomaly")
+	}
+	return count > 0, nil
+}
+
+// CreateCostAnomaly records a detection.
+func CreateCostAnomaly(ctx context.Context, anomaly *CostAnomaly) error {
+	if err := DB.WithContext(ctx).Create(anomaly).Error; err != nil {
+		return errors.Wrap(err, "create cost anomaly")
+	}
+	return nil
+}
+
+// GetCostAnomalies returns the anomalies of the hours starting at or after
+// since, newest first.
+func GetCostAnomalies(ctx context.Context, since int64) ([]CostAnomaly, error) {
+	var anomalies []CostAnomaly
+	if err := DB.WithContext(ctx).
+		Where("hour_start >= ?", since).
+		Order("hour_start desc, id desc").
+		Find(&anomalies).Error; err != nil {
+		return nil, errors.Wrap(err, "list cost anomalies")
+	}
+	return anomalies, nil
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+
+	"github.com/Laisky/errors/v2"
+	"gorm.io/gorm"
+)
+
+// CostReportEntry is one row of a cost report breakdown.
+type CostReportEntry struct {
+	Name     string `json:"name"`
+	Quota    int64  `json:"quota"`
+	Requests int64  `json:"requests"`
+}
+
+// CostReportUsage aggregates the consume logs of a period for the cost report.
+// Every breakdown is sorted by quota in descending order.
+type CostReportUsage struct {
+	TotalQuota int64             `json:"total_quota"`
+	Requests   int64             `json:"requests"`
+	Groups     []CostReportEntry `json:"groups"`
+	Models     []CostReportEntry `json:"models"`
+	Users      []CostReportEntry `json:"users"`
+	Channels   []CostReportEntry `json:"channels"`
+}
+
+// GetCostReportUsage aggregates the consume logs created in the half-open range
+// [from, to) of Unix seconds by group, model, user and channel. Users are
+// attributed to the group they belong to now, since logs do not record it.
+func GetCostReportUsage(from, to int64) (*CostReportUsage, error) {
+	consumeLogs := func() *gorm.DB {
+		return LOG_DB.Model(&Log{}).
+			Where("type = ? AND created_at >= ? AND created_at < ?", LogTypeConsume, from, to)
+	}
+	const totals = "COALESCE(SUM(quota), 0) as quota, COUNT(*) as requests"
+
+	usage := &CostReportUsage{}
+	if err := consumeLogs().
+		Select("model_name as name, " + totals).
+		Group("model_name").
+		Scan(&usage.Models).Error; err != nil {
+		return nil, errors.Wrap(err, "aggregate cost by model")
+	}
+
+	var userRows []struct {
+		UserId   int
+		Username string
+		Quota    int64
+		Requests int64
+	}
+	if err := consumeLogs().
+		Select("user_id, username, " + totals).
+		Group("user_id, username").
+		Scan(&userRows).Error; err != nil {
+		return nil, errors.Wrap(err, "aggregate cost by user")
+	}
+
+	var channelRows []struct {
+		ChannelId int
+		Quota     int64
+		Requests  int64
+	}
+	if err := consumeLogs().
+		Select("channel_id, " + totals).
+		Group("channel_id").
+		Scan(&channelRows).Error; err != nil {
+		return nil, errors.Wrap(err, "aggregate cost by channel")
+	}
+
+	userIds := make([]int, 0, len(userRows))
+	userIndex := make(map[int]int, len(userRows))
+	for _, row := range userRows {
+		// A renamed user has several rows, merge them under the latest name
+		if i, ok := userIndex[row.UserId]; ok {
+			usage.Users[i].Quota += row.Quota
+			usage.Users[i].Requests += row.Requests
+			continue
+		}
+		userIndex[row.UserId] = len(usage.Users)
+		userIds = append(userIds, row.UserId)
+		usage.Users = append(usage.Users, CostReportEntry{Name: row.Username, Quota: row.Quota, Requests: row.Requests})
+	}
+
+	var users []User
+	if len(userIds) > 0 {
+		if err := DB.Select("id", "username", "group").Where("id IN ?", userIds).Find(&users).Error; err != nil {
+			return nil, errors.Wrap(err, "load groups of users")
+		}
+	}
+	userGroups := make(map[int]string, len(users))
+	for _, user := range users {
+		userGroups[user.Id] = user.Group
+		usage.Users[userIndex[user.Id]].Name = user.Username
+	}
+	groups := make(map[string]*CostReportEntry)
+	for userId, i := range userIndex {
+		name := userGroups[userId]
+		if name == "" {
+			name = "(deleted users)"
+		}
+		entry, ok := groups[name]
+		if !ok {
+			entry = &CostReportEntry{Name: name}
+			groups[name] = entry
+		}
+		entry.Quota += usage.Users[i].Quota
+		entry.Requests += usage.Users[i].Requests
+	}
+	for _, entry := range groups {
+		usage.Groups = append(usage.Groups, *entry)
+	}
+
+	channelIds := make([]int, 0, len(channelRows))
+	for _, row := range channelRows {
+		channelIds = append(channelIds, row.ChannelId)
+	}
+	var channels []Channel
+	if len(channelIds) > 0 {
+		if err := DB.Select("id", "name").Where("id IN ?", channelIds).Find(&channels).Error; err != nil {
+			return nil, errors.Wrap(err, "load channel names")
+		}
+	}
+	channelNames := make(map[int]string, len(channels))
+	for _, channel := range channels {
+		channelNames[channel.Id] = channel.Name
+	}
+	for _, row := range channelRows {
+		name := "#" + strconv.Itoa(row.ChannelId)
+		if channelName := channelNames[row.ChannelId]; channelName != "" {
+			name += " " + channelName
+		}
+		usage.Channels = append(usage.Channels, CostReportEntry{Name: name, Quota: row.Quota, Requests: row.Requests})
+	}
+
+	for _, entry := range usage.Models {
+		usage.TotalQuota += entry.Quota
+		usage.Requests += entry.Requests
+	}
+	for _, breakdown := range [][]CostReportEntry{usage.Groups, usage.Models, usage.Users, usage.Channels} {
+		sortCostReportEntries(breakdown)
+	}
+	return usage, nil
+}
+
+// sortCostReportEntries orders entries by quota descending, then by name so
+// that reports are stable.
+func sortCostReportEntries(entries []CostReportEntry) {
+	slices.SortFunc(entries, func(a, b CostReportEntry) int {
+		if c := cmp.Compare(b.Quota, a.Quota); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+}
@@ -6,17 +6,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"maps"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
 	"github.com/Laisky/zap"
 	"gorm.io/gorm"
 
 	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/metrics"
 	"github.com/songquanpeng/one-api/dto"
 )
 
@@ -45,6 +49,8 @@ type Log struct {
 	CachedCompletionTokens int `json:"cached_completion_tokens" gorm:"default:0;index"`
 	// Metadata holds provider-specific attributes serialized as JSON (e.g., cache write tokens).
 	Metadata LogMetadata `json:"metadata,omitempty" gorm:"type:text"`
+	// Compressed marks entries whose content was collapsed by CompressOldLogs.
+	Compressed bool `json:"compressed" gorm:"default:false;index"`
 }
 
 // LogMetadata stores structured provider-specific attributes associated with a log entry.
@@ -61,8 +67,62 @@ const (
 	LogMetadataKeyCacheWrite1h = "ephemeral_1h"
 	// LogMetadataKeyToolUsage stores structured metadata about built-in tool usage and charges.
 	LogMetadataKeyToolUsage = "tool_usage"
+	// LogMetadataKeyStreamingTimeout records that a stream was closed by the token idle watchdog.
+	LogMetadataKeyStreamingTimeout = "streaming_timeout"
+	// LogMetadataKeyStreamTiming records the latency decomposition of a stream that requested timing events.
+	LogMetadataKeyStreamTiming = "stream_timing"
+	// LogMetadataKeyRequestBodyBytes records the size of the client request body in bytes.
+	LogMetadataKeyRequestBodyBytes = "request_body_bytes"
+	// LogMetadataKeyResponseBodyBytes records the size of the response written to the client, for requests sampled into the replay corpus.
+	LogMetadataKeyResponseBodyBytes = "response_body_bytes"
+	// LogMetadataKeyImageTokens records the prompt tokens upstream attributed to input images.
+	LogMetadataKeyImageTokens = "image_tokens"
+	// LogMetadataKeyRedactionCount records how many sensitive values were redacted from the request in logs and traces.
+	LogMetadataKeyRedactionCount = "redaction_count"
+	// LogMetadataKeyTranslationCost records the system prompt translation billed with the request.
+	LogMetadataKeyTranslationCost = "translation_cost"
+	// LogMetadataKeyUpstreamError preserves an upstream error hidden from the client by a channel error response template.
+	LogMetadataKeyUpstreamError = "upstream_error"
+	// LogMetadataKeyDocumentPages records the inline document pages sent upstream, billed at approximately 756 tokens per page.
+	LogMetadataKeyDocumentPages = "document_pages"
+	// LogMetadataKeyEmbeddingCache records how many embedding inputs were answered from the embedding cache.
+	LogMetadataKeyEmbeddingCache = "embedding_cache"
+	// LogMetadataKeyReplicatePredictionID records the ID of the Replicate prediction that served the request.
+	LogMetadataKeyReplicatePredictionID = "replicate_prediction_id"
+	// LogMetadataKeyAutoDedup records that the response was served from the auto deduplication cache.
+	LogMetadataKeyAutoDedup = "auto_dedup"
+	// LogMetadataKeyCitationCount records how many sources upstream cited in its answer.
+	LogMetadataKeyCitationCount = "citation_count"
+	// LogMetadataKeyQueueDurationMs records how long upstream queued the request, in milliseconds.
+	LogMetadataKeyQueueDurationMs = "queue_duration_ms"
 )
 
+// StreamingTimeoutEvent describes a stream closed because no tokens arrived in time.
+type StreamingTimeoutEvent struct {
+	Model          string // Model whose timeout was applied
+	TimeoutSeconds int    // Idle timeout that elapsed
+	ModelSpecific  bool   // Whether the timeout came from the channel's per-model settings
+}
+
+// StreamTiming is the latency decomposition of a streamed response.
+type StreamTiming struct {
+	TTFTMs       int64   // Time from request receipt to the first streamed chunk
+	TotalMs      int64   // Time from request receipt to the end of the stream
+	TokensPerSec float64 // Completion tokens per second after the first chunk
+}
+
+// SystemPromptTranslation describes the translation of a request's system prompt.
+type SystemPromptTranslation struct {
+	ChannelId        int    // Channel that performed the translation
+	Model            string // Model used for the translation
+	SourceLanguage   string // Detected language of the original prompt
+	TargetLanguage   string // Language the prompt was translated into
+	PromptTokens     int    // Prompt tokens of the translation request
+	CompletionTokens int    // Completion tokens of the translation request
+	Quota            int64  // Quota charged for the translation, 0 for cached translations
+	Cached           bool   // Whether the translation was served from the cache
+}
+
 // ToolUsageSummary captures built-in tool invocation details for billing and logging purposes.
 type ToolUsageSummary struct {
 	TotalCost  int64            // Aggregated quota consumed by tools
@@ -162,6 +222,213 @@ func AppendCacheWriteTokensMetadata(metadata LogMetadata, cacheWrite5m, cacheWri
 	return metadata
 }
 
+// AppendStreamingTimeoutMetadata records a streaming idle timeout into the metadata map when present.
+func AppendStreamingTimeoutMetadata(metadata LogMetadata, event *StreamingTimeoutEvent) LogMetadata {
+	if event == nil {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyStreamingTimeout] = map[string]any{
+		"model":           event.Model,
+		"timeout_seconds": event.TimeoutSeconds,
+		"model_specific":  event.ModelSpecific,
+	}
+	return metadata
+}
+
+// AppendStreamTimingMetadata records the stream latency decomposition into the metadata map when present.
+func AppendStreamTimingMetadata(metadata LogMetadata, timing *StreamTiming) LogMetadata {
+	if timing == nil {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyStreamTiming] = map[string]any{
+		"ttft_ms":        timing.TTFTMs,
+		"total_ms":       timing.TotalMs,
+		"tokens_per_sec": timing.TokensPerSec,
+	}
+	return metadata
+}
+
+// AppendImageTokensMetadata records the prompt image token count reported by upstream when present.
+func AppendImageTokensMetadata(metadata LogMetadata, imageTokens int) LogMetadata {
+	if imageTokens <= 0 {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyImageTokens] = imageTokens
+	return metadata
+}
+
+// appendRedactionMetadata records the number of values redacted from the
+// request behind ctx, as counted by middleware.RequestRedaction.
+func appendRedactionMetadata(ctx context.Context, metadata LogMetadata) LogMetadata {
+	c, ok := gmw.GetGinCtxFromStdCtx(ctx)
+	if !ok {
+		return metadata
+	}
+	count := c.GetInt(ctxkey.RedactionCount)
+	if count <= 0 {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyRedactionCount] = count
+	return metadata
+}
+
+// appendDocumentPagesMetadata records the number of document pages in the
+// request behind ctx, as counted by the adaptor that converted it.
+func appendDocumentPagesMetadata(ctx context.Context, metadata LogMetadata) LogMetadata {
+	c, ok := gmw.GetGinCtxFromStdCtx(ctx)
+	if !ok {
+		return metadata
+	}
+	pages := c.GetInt(ctxkey.DocumentPages)
+	if pages <= 0 {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyDocumentPages] = pages
+	return metadata
+}
+
+// appendReplicatePredictionMetadata records the Replicate prediction behind
+// ctx, so that a request can be looked up in the Replicate dashboard.
+func appendReplicatePredictionMetadata(ctx context.Context, metadata LogMetadata) LogMetadata {
+	c, ok := gmw.GetGinCtxFromStdCtx(ctx)
+	if !ok {
+		return metadata
+	}
+	predictionID := c.GetString(ctxkey.ReplicatePredictionID)
+	if predictionID == "" {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyReplicatePredictionID] = predictionID
+	return metadata
+}
+
+// appendCitationCountMetadata records the number of sources cited in the
+// answer to the request behind ctx, as counted by the adaptor.
+func appendCitationCountMetadata(ctx context.Context, metadata LogMetadata) LogMetadata {
+	c, ok := gmw.GetGinCtxFromStdCtx(ctx)
+	if !ok {
+		return metadata
+	}
+	citations := c.GetInt(ctxkey.CitationCount)
+	if citations <= 0 {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyCitationCount] = citations
+	return metadata
+}
+
+// appendQueueDurationMetadata records how long upstream queued the request
+// behind ctx, as reported by the adaptor.
+func appendQueueDurationMetadata(ctx context.Context, metadata LogMetadata) LogMetadata {
+	c, ok := gmw.GetGinCtxFromStdCtx(ctx)
+	if !ok {
+		return metadata
+	}
+	value, exists := c.Get(ctxkey.UpstreamQueueDurationMs)
+	if !exists {
+		return metadata
+	}
+	queueDurationMs, ok := value.(float64)
+	if !ok {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyQueueDurationMs] = queueDurationMs
+	return metadata
+}
+
+// appendAutoDedupMetadata records that the request behind ctx was answered
+// with the cached response of an identical earlier request.
+func appendAutoDedupMetadata(ctx context.Context, metadata LogMetadata) LogMetadata {
+	c, ok := gmw.GetGinCtxFromStdCtx(ctx)
+	if !ok || !c.GetBool(ctxkey.AutoDedupHit) {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyAutoDedup] = map[string]any{"hit": true}
+	return metadata
+}
+
+// appendEmbeddingCacheMetadata records the embedding cache hits of the
+// request behind ctx. Only the missed inputs were sent upstream and billed.
+func appendEmbeddingCacheMetadata(ctx context.Context, metadata LogMetadata) LogMetadata {
+	c, ok := gmw.GetGinCtxFromStdCtx(ctx)
+	if !ok {
+		return metadata
+	}
+	total := c.GetInt(ctxkey.EmbeddingCacheInputs)
+	if total <= 0 {
+		return metadata
+	}
+	hits := c.GetInt(ctxkey.EmbeddingCacheHits)
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyEmbeddingCache] = map[string]any{
+		"hits":      hits,
+		"inputs":    total,
+		"hit_ratio": float64(hits) / float64(total),
+	}
+	return metadata
+}
+
+// AppendTranslationCostMetadata records the system prompt translation into the metadata map when present.
+func AppendTranslationCostMetadata(metadata LogMetadata, translation *SystemPromptTranslation) LogMetadata {
+	if translation == nil {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+
+	metadata[LogMetadataKeyTranslationCost] = map[string]any{
+		"channel_id":        translation.ChannelId,
+		"model":             translation.Model,
+		"source_language":   translation.SourceLanguage,
+		"target_language":   translation.TargetLanguage,
+		"prompt_tokens":     translation.PromptTokens,
+		"completion_tokens": translation.CompletionTokens,
+		"quota":             translation.Quota,
+		"cached":            translation.Cached,
+	}
+	return metadata
+}
+
 // AppendToolUsageMetadata attaches tool invocation details to the metadata map when present.
 func AppendToolUsageMetadata(metadata LogMetadata, summary *ToolUsageSummary) LogMetadata {
 	if summary == nil {
@@ -412,6 +679,15 @@ func recordLogHelper(_ context.Context, log *Log) {
 	// IDs must be pre-populated by the caller from gin.Context
 	ensureLogContent(log)
 
+	if enqueueLogWrite(log) {
+		return
+	}
+	writeLog(log)
+}
+
+// writeLog persists log, reporting failures instead of returning them since
+// the request that produced it has usually been answered already.
+func writeLog(log *Log) {
 	err := LOG_DB.Create(log).Error
 	if err != nil {
 		// For billing logs (consume type), this is critical as it means we sent upstream request but failed to log it
@@ -518,17 +794,72 @@ func RecordTopupLogWithIDs(ctx context.Context, userId int, content string, quot
 
 // RecordConsumeLog stores a model consumption log and populates audit fields automatically.
 func RecordConsumeLog(ctx context.Context, log *Log) {
+	recordConsumeLog(ctx, log, false)
+}
+
+// RecordConsumeLogSync is RecordConsumeLog bypassing the async log writer, so
+// that log.Id is set on return for callers updating the entry later, such as
+// the two-phase external billing flow.
+func RecordConsumeLogSync(ctx context.Context, log *Log) {
+	recordConsumeLog(ctx, log, true)
+}
+
+func recordConsumeLog(ctx context.Context, log *Log, sync bool) {
+	recordQuotaConsumedMetric(ctx, log)
 	if !config.IsLogConsumeEnabled() {
+		publishBillingEvent(log)
 		return
 	}
 	log.Username = GetUsernameById(log.UserId)
 	log.CreatedAt = helper.GetTimestamp()
 	log.Type = LogTypeConsume
-	recordLogHelper(ctx, log)
+	log.Metadata = appendBodySizeMetadata(ctx, log.Metadata)
+	log.Metadata = appendRedactionMetadata(ctx, log.Metadata)
+	log.Metadata = appendDocumentPagesMetadata(ctx, log.Metadata)
+	log.Metadata = appendEmbeddingCacheMetadata(ctx, log.Metadata)
+	log.Metadata = appendReplicatePredictionMetadata(ctx, log.Metadata)
+	log.Metadata = appendAutoDedupMetadata(ctx, log.Metadata)
+	log.Metadata = appendCitationCountMetadata(ctx, log.Metadata)
+	log.Metadata = appendQueueDurationMetadata(ctx, log.Metadata)
+	if sync {
+		ensureLogContent(log)
+		writeLog(log)
+	} else {
+		recordLogHelper(ctx, log)
+	}
+	recordUserBudgetSpend(ctx, log.UserId, int64(log.Quota), time.Unix(log.CreatedAt, 0))
+	publishBillingEvent(log)
+}
+
+// recordQuotaConsumedMetric attributes the quota of a consume log to the
+// user's group and the model family, for cost allocation dashboards.
+func recordQuotaConsumedMetric(ctx context.Context, log *Log) {
+	if log.Quota <= 0 {
+		return
+	}
+	group, err := CacheGetUserGroup(ctx, log.UserId)
+	if err != nil || group == "" {
+		group = "unknown"
+	}
+	metrics.GlobalRecorder.RecordQuotaConsumed(group, log.ModelName, int64(log.Quota))
 }
 
 // RecordConsumeLogWithTraceID removed: pass IDs directly and call RecordConsumeLog
 
+// RecordUpstreamErrorLog records that the client of a failed request received
+// a channel's templated error message, preserving the upstream error in the
+// metadata for debugging.
+func RecordUpstreamErrorLog(ctx context.Context, log *Log, upstreamError any) {
+	log.Username = GetUsernameById(log.UserId)
+	log.CreatedAt = helper.GetTimestamp()
+	log.Type = LogTypeSystem
+	if log.Metadata == nil {
+		log.Metadata = LogMetadata{}
+	}
+	log.Metadata[LogMetadataKeyUpstreamError] = upstreamError
+	recordLogHelper(ctx, log)
+}
+
 // RecordTestLog persists a synthetic channel test log entry.
 func RecordTestLog(ctx context.Context, log *Log) {
 	log.CreatedAt = helper.GetTimestamp()
@@ -709,11 +1040,10 @@ func GetUserLogsCount(userId int, logType int, startTimestamp int64, endTimestam
 }
 
 // SearchAllLogs performs a keyword search across all log entries with pagination.
-func SearchAllLogs(keyword string, startIdx int, num int, sortBy string, sortOrder string) (logs []*Log, total int64, err error) {
-	db := LOG_DB.Model(&Log{})
-	if keyword != "" {
-		db = db.Where("content LIKE ?", "%"+keyword+"%")
-	}
+// useFullText selects the database full-text index over a LIKE scan where the
+// engine supports it, see applyLogContentSearch.
+func SearchAllLogs(keyword string, startIdx int, num int, sortBy string, sortOrder string, useFullText bool) (logs []*Log, total int64, err error) {
+	db := applyLogContentSearch(LOG_DB.Model(&Log{}), keyword, useFullText)
 	orderClause := GetLogOrderClause(sortBy, sortOrder)
 	db = db.Order(orderClause)
 	err = db.Count(&total).Limit(num).Offset(startIdx).Find(&logs).Error
@@ -721,11 +1051,9 @@ func SearchAllLogs(keyword string, startIdx int, num int, sortBy string, sortOrd
 }
 
 // SearchUserLogs searches logs owned by a specific user using a keyword filter.
-func SearchUserLogs(userId int, keyword string, startIdx int, num int, sortBy string, sortOrder string) (logs []*Log, total int64, err error) {
-	db := LOG_DB.Model(&Log{}).Where("user_id = ?", userId)
-	if keyword != "" {
-		db = db.Where("content LIKE ?", "%"+keyword+"%")
-	}
+// useFullText is as for SearchAllLogs.
+func SearchUserLogs(userId int, keyword string, startIdx int, num int, sortBy string, sortOrder string, useFullText bool) (logs []*Log, total int64, err error) {
+	db := applyLogContentSearch(LOG_DB.Model(&Log{}).Where("user_id = ?", userId), keyword, useFullText)
 	orderClause := GetLogOrderClause(sortBy, sortOrder)
 	db = db.Order(orderClause)
 	err = db.Count(&total).Limit(num).Offset(startIdx).Find(&logs).Error
@@ -787,12 +1115,68 @@ func SumUsedToken(logType int, startTimestamp int64, endTimestamp int64, modelNa
 	return token
 }
 
+// GetMostUsedChannelIds returns up to limit channel ids ordered by the number of
+// consume logs they recorded since the given Unix timestamp, most used first.
+func GetMostUsedChannelIds(ctx context.Context, since int64, limit int) ([]int, error) {
+	var channelIds []int
+	if err := LOG_DB.WithContext(ctx).Table("logs").
+		Select("channel_id").
+		Where("type = ? AND created_at >= ? AND channel_id <> 0", LogTypeConsume, since).
+		Group("channel_id").
+		Order("COUNT(*) DESC, channel_id").
+		Limit(limit).
+		Pluck("channel_id", &channelIds).Error; err != nil {
+		return nil, errors.Wrap(err, "rank channels by usage")
+	}
+	return channelIds, nil
+}
+
 // DeleteOldLog removes log entries older than the provided timestamp and returns the number deleted.
 func DeleteOldLog(targetTimestamp int64) (int64, error) {
 	result := LOG_DB.Where("created_at < ?", targetTimestamp).Delete(&Log{})
 	return result.RowsAffected, result.Error
 }
 
+// CompressOldLogs compresses the log entries created before beforeTimestamp and
+// returns the number compressed. The content is collapsed to a short hash and
+// verbose fields such as trace_id, request_id and metadata are cleared, while
+// the columns used by quota aggregation, including user_id, model_name, quota,
+// created_at and type, are kept.
+func CompressOldLogs(beforeTimestamp int64) (compressed int64, err error) {
+	for {
+		var batch []Log
+		if err = LOG_DB.Select("id", "content").
+			Where("created_at < ? AND compressed = ?", beforeTimestamp, false).
+			Order("id").
+			Limit(logCompressionBatchSize).
+			Find(&batch).Error; err != nil {
+			return compressed, errors.Wrap(err, "find logs to compress")
+		}
+		if len(batch) == 0 {
+			return compressed, nil
+		}
+
+		err = LOG_DB.Transaction(func(tx *gorm.DB) error {
+			for _, log := range batch {
+				if err := tx.Model(&Log{}).Where("id = ?", log.Id).Updates(map[string]any{
+					"content":    compressedLogContent(log.Content),
+					"trace_id":   "",
+					"request_id": "",
+					"metadata":   nil,
+					"compressed": true,
+				}).Error; err != nil {
+					return errors.Wrapf(err, "compress log %d", log.Id)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return compressed, err
+		}
+		compressed += int64(len(batch))
+	}
+}
+
 // GetLogById retrieves a log entry by its ID
 func GetLogById(id int) (*Log, error) {
 	var log Log
@@ -957,3 +1341,236 @@ func SearchLogsByDayAndToken(userId, start, endExclusive int) ([]*dto.LogStatist
 	err := LOG_DB.Raw(query, args...).Scan(&stats).Error
 	return stats, err
 }
+
+// Granularities accepted by SearchActiveUsers.
+const (
+	ActivityGranularityDaily   = "daily"
+	ActivityGranularityWeekly  = "weekly"
+	ActivityGranularityMonthly = "monthly"
+)
+
+// UserActivityDataPoint summarizes user activity within one period.
+type UserActivityDataPoint struct {
+	// Period is YYYY-MM-DD for daily buckets, the Monday starting the week
+	// (YYYY-MM-DD) for weekly buckets and YYYY-MM for monthly buckets.
+	Period string `json:"period"`
+	// ActiveUsers is the number of distinct users with consume logs.
+	ActiveUsers int64 `json:"active_users"`
+	// NewUsers is the number of users registered within the period.
+	NewUsers int64 `json:"new_users"`
+	// ModelDiversity is the average number of distinct models used per active user.
+	ModelDiversity float64 `json:"model_diversity"`
+}
+
+// activityBucketSelect returns the SQL expression that maps a timestamp column
+// to the period label of the given granularity. Buckets are computed in UTC
+// regardless of the session time zone so every database engine agrees.
+// When millis is true the column holds Unix milliseconds instead of seconds.
+func activityBucketSelect(granularity, column string, millis bool) (string, error) {
+	switch {
+	case common.UsingPostgreSQL.Load():
+		if millis {
+			column = column + " / 1000"
+		}
+		ts := "(to_timestamp(" + column + ") AT TIME ZONE 'UTC')"
+		switch granularity {
+		case ActivityGranularityDaily:
+			return "TO_CHAR(" + ts + ", 'YYYY-MM-DD')", nil
+		case ActivityGranularityWeekly:
+			return "TO_CHAR(date_trunc('week', " + ts + "), 'YYYY-MM-DD')", nil
+		case ActivityGranularityMonthly:
+			return "TO_CHAR(" + ts + ", 'YYYY-MM')", nil
+		}
+	case common.UsingSQLite.Load():
+		if millis {
+			column = column + " / 1000"
+		}
+		switch granularity {
+		case ActivityGranularityDaily:
+			return "strftime('%Y-%m-%d', " + column + ", 'unixepoch')", nil
+		case ActivityGranularityWeekly:
+			// 'weekday 0' advances to the next Sunday (or stays), six days back is Monday
+			return "date(" + column + ", 'unixepoch', 'weekday 0', '-6 days')", nil
+		case ActivityGranularityMonthly:
+			return "strftime('%Y-%m', " + column + ", 'unixepoch')", nil
+		}
+	default:
+		if millis {
+			column = column + " DIV 1000"
+		}
+		// FROM_UNIXTIME converts to the session time zone, so add seconds to the epoch instead
+		ts := "DATE_ADD('1970-01-01', INTERVAL " + column + " SECOND)"
+		switch granularity {
+		case ActivityGranularityDaily:
+			return "DATE_FORMAT(" + ts + ", '%Y-%m-%d')", nil
+		case ActivityGranularityWeekly:
+			return "DATE_FORMAT(DATE_SUB(" + ts + ", INTERVAL WEEKDAY(" + ts + ") DAY), '%Y-%m-%d')", nil
+		case ActivityGranularityMonthly:
+			return "DATE_FORMAT(" + ts + ", '%Y-%m')", nil
+		}
+	}
+	return "", errors.Errorf("unsupported granularity %q", granularity)
+}
+
+// SearchActiveUsers returns the number of active users, new registrations and
+// model diversity per period for the half-open range [from, to) of Unix
+// seconds. granularity is one of daily, weekly or monthly. Periods without any
+// activity or registration are omitted.
+func SearchActiveUsers(from, to int64, granularity string) ([]UserActivityDataPoint, error) {
+	logBucket, err := activityBucketSelect(granularity, "created_at", false)
+	if err != nil {
+		return nil, err
+	}
+	userBucket, err := activityBucketSelect(granularity, "created_at", true)
+	if err != nil {
+		return nil, err
+	}
+
+	var activity []struct {
+		Bucket      string
+		ActiveUsers int64
+		UserModels  int64
+	}
+	// The inner query yields one row per user and period with the number of
+	// distinct models that user called, so the outer sum divided by the active
+	// user count is the average model diversity.
+	activityQuery := `
+		SELECT bucket,
+		COUNT(DISTINCT user_id) as active_users,
+		SUM(models) as user_models
+		FROM (
+			SELECT ` + logBucket + ` as bucket,
+			user_id,
+			COUNT(DISTINCT model_name) as models
+			FROM logs
+			WHERE type = ?
+			AND created_at >= ? AND created_at < ?
+			GROUP BY bucket, user_id
+		) user_activity
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+	if err = LOG_DB.Raw(activityQuery, LogTypeConsume, from, to).Scan(&activity).Error; err != nil {
+		return nil, errors.Wrap(err, "count active users")
+	}
+
+	var registrations []struct {
+		Bucket   string
+		NewUsers int64
+	}
+	registrationQuery := `
+		SELECT ` + userBucket + ` as bucket,
+		COUNT(*) as new_users
+		FROM users
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+	if err = DB.Raw(registrationQuery, from*1000, to*1000).Scan(&registrations).Error; err != nil {
+		return nil, errors.Wrap(err, "count new users")
+	}
+
+	points := make(map[string]*UserActivityDataPoint, len(activity))
+	for _, row := range activity {
+		point := &UserActivityDataPoint{Period: row.Bucket, ActiveUsers: row.ActiveUsers}
+		if row.ActiveUsers > 0 {
+			point.ModelDiversity = float64(row.UserModels) / float64(row.ActiveUsers)
+		}
+		points[row.Bucket] = point
+	}
+	for _, row := range registrations {
+		point, ok := points[row.Bucket]
+		if !ok {
+			point = &UserActivityDataPoint{Period: row.Bucket}
+			points[row.Bucket] = point
+		}
+		point.NewUsers = row.NewUsers
+	}
+
+	result := make([]UserActivityDataPoint, 0, len(points))
+	for _, period := range slices.Sorted(maps.Keys(points)) {
+		result = append(result, *points[period])
+	}
+	return result, nil
+}
+
+// ActiveUserWindow is how recently a user must have consumed quota to count
+// as active in GetGroupQuotaStats.
+const ActiveUserWindow = 24 * time.Hour
+
+// GroupQuotaStat summarizes the consumption of one user group within
+// ActiveUserWindow.
+type GroupQuotaStat struct {
+	Group string `json:"group"`
+	// ActiveUsers is the number of distinct users of the group with consume logs.
+	ActiveUsers int `json:"active_users"`
+	// Quota is the total quota consumed by the group.
+	Quota int64 `json:"quota"`
+}
+
+// GetGroupQuotaStats returns the active users and consumed quota of every user
+// group within ActiveUserWindow, sorted by group. Logs may live in a separate
+// database, so users are mapped to groups in a second query.
+func GetGroupQuotaStats(ctx context.Context) ([]GroupQuotaStat, error) {
+	var usage []struct {
+		UserId int
+		Quota  int64
+	}
+	since := time.Now().Add(-ActiveUserWindow).Unix()
+	err := LOG_DB.WithContext(ctx).Model(&Log{}).
+		Select("user_id, SUM(quota) as quota").
+		Where("type = ? AND created_at >= ?", LogTypeConsume, since).
+		Group("user_id").
+		Scan(&usage).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "sum consumed quota by user")
+	}
+
+	groupCol := "`group`"
+	if common.UsingPostgreSQL.Load() {
+		groupCol = `"group"`
+	}
+	userGroups := make(map[int]string, len(usage))
+	userIds := make([]int, 0, len(usage))
+	for _, row := range usage {
+		userIds = append(userIds, row.UserId)
+	}
+	for chunk := range slices.Chunk(userIds, 500) {
+		var users []struct {
+			Id    int
+			Group string
+		}
+		// Soft-deleted users still count towards their group's spend
+		err = DB.WithContext(ctx).Unscoped().Model(&User{}).
+			Select("id, "+groupCol).
+			Where("id IN ?", chunk).
+			Scan(&users).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "get groups of active users")
+		}
+		for _, user := range users {
+			userGroups[user.Id] = user.Group
+		}
+	}
+
+	stats := make(map[string]*GroupQuotaStat)
+	for _, row := range usage {
+		group := userGroups[row.UserId]
+		if group == "" {
+			group = "unknown"
+		}
+		stat, ok := stats[group]
+		if !ok {
+			stat = &GroupQuotaStat{Group: group}
+			stats[group] = stat
+		}
+		stat.ActiveUsers++
+		stat.Quota += row.Quota
+	}
+
+	result := make([]GroupQuotaStat, 0, len(stats))
+	for _, group := range slices.Sorted(maps.Keys(stats)) {
+		result = append(result, *stats[group])
+	}
+	return result, nil
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/random"
+)
+
+// useDialect switches the SQL dialect flags for the duration of a test.
+func useDialect(t *testing.T, postgres, sqlite, mysql bool) {
+	t.Helper()
+	prevPostgres, prevSQLite, prevMySQL := common.UsingPostgreSQL.Load(), common.UsingSQLite.Load(), common.UsingMySQL.Load()
+	common.UsingPostgreSQL.Store(postgres)
+	common.UsingSQLite.Store(sqlite)
+	common.UsingMySQL.Store(mysql)
+	t.Cleanup(func() {
+		common.UsingPostgreSQL.Store(prevPostgres)
+		common.UsingSQLite.Store(prevSQLite)
+		common.UsingMySQL.Store(prevMySQL)
+	})
+}
+
+func TestActivityBucketSelect(t *testing.T) {
+	cases := []struct {
+		name    string
+		dialect func(t *testing.T)
+		seconds map[string]string
+		millis  string
+	}{
+		{
+			name:    "postgres",
+			dialect: func(t *testing.T) { useDialect(t, true, false, false) },
+			seconds: map[string]string{
+				ActivityGranularityDaily:   "TO_CHAR((to_timestamp(created_at) AT TIME ZONE 'UTC'), 'YYYY-MM-DD')",
+				ActivityGranularityWeekly:  "TO_CHAR(date_trunc('week', (to_timestamp(created_at) AT TIME ZONE 'UTC')), 'YYYY-MM-DD')",
+				ActivityGranularityMonthly: "TO_CHAR((to_timestamp(created_at) AT TIME ZONE 'UTC'), 'YYYY-MM')",
+			},
+			millis: "TO_CHAR((to_timestamp(created_at / 1000) AT TIME ZONE 'UTC'), 'YYYY-MM-DD')",
+		},
+		{
+			name:    "sqlite",
+			dialect: func(t *testing.T) { useDialect(t, false, true, false) },
+			seconds: map[string]string{
+				ActivityGranularityDaily:   "strftime('%Y-%m-%d', created_at, 'unixepoch')",
+				ActivityGranularityWeekly:  "date(created_at, 'unixepoch', 'weekday 0', '-6 days')",
+				ActivityGranularityMonthly: "strftime('%Y-%m', created_at, 'unixepoch')",
+			},
+			millis: "strftime('%Y-%m-%d', created_at / 1000, 'unixepoch')",
+		},
+		{
+			name:    "mysql",
+			dialect: func(t *testing.T) { useDialect(t, false, false, true) },
+			seconds: map[string]string{
+				ActivityGranularityDaily:   "DATE_FORMAT(DATE_ADD('1970-01-01', INTERVAL created_at SECOND), '%Y-%m-%d')",
+				ActivityGranularityWeekly:  "DATE_FORMAT(DATE_SUB(DATE_ADD('1970-01-01', INTERVAL created_at SECOND), INTERVAL WEEKDAY(DATE_ADD('1970-01-01', INTERVAL created_at SECOND)) DAY), '%Y-%m-%d')",
+				ActivityGranularityMonthly: "DATE_FORMAT(DATE_ADD('1970-01-01', INTERVAL created_at SECOND), '%Y-%m')",
+			},
+			millis: "DATE_FORMAT(DATE_ADD('1970-01-01', INTERVAL created_at DIV 1000 SECOND), '%Y-%m-%d')",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.dialect(t)
+			for granularity, expected := range tc.seconds {
+				expr, err := activityBucketSelect(granularity, "created_at", false)
+				require.NoError(t, err)
+				require.Equal(t, expected, expr, granularity)
+			}
+			expr, err := activityBucketSelect(ActivityGranularityDaily, "created_at", true)
+			require.NoError(t, err)
+			require.Equal(t, tc.millis, expr)
+
+			_, err = activityBucketSelect("hourly", "created_at", false)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSearchActiveUsers(t *testing.T) {
+	setupTestDatabase(t)
+	require.True(t, common.UsingSQLite.Load(), "activity queries are exercised against SQLite")
+
+	const marker = "test-active-users"
+	require.NoError(t, LOG_DB.Exec("DELETE FROM logs WHERE content = ?", marker).Error)
+	t.Cleanup(func() {
+		LOG_DB.Exec("DELETE FROM logs WHERE content = ?", marker)
+		DB.Exec("DELETE FROM users WHERE username LIKE 'test_active_%'")
+	})
+
+	// 2001-01-01 is a Monday, far from data written by other tests
+	at := func(month time.Month, day, hour int) int64 {
+		return time.Date(2001, month, day, hour, 0, 0, 0, time.UTC).Unix()
+	}
+	logs := []Log{
+		{UserId: 9001, ModelName: "gpt-4", CreatedAt: at(1, 1, 0)},
+		{UserId: 9001, ModelName: "claude-3", CreatedAt: at(1, 2, 10)},
+		{UserId: 9001, ModelName: "gpt-4", CreatedAt: at(1, 2, 11)},
+		{UserId: 9002, ModelName: "gpt-4", CreatedAt: at(1, 2, 12)},
+		// Sunday late evening still belongs to the week starting 2001-01-01
+		{UserId: 9002, ModelName: "gemini", CreatedAt: at(1, 7, 23)},
+		{UserId: 9001, ModelName: "gpt-4", CreatedAt: at(1, 8, 0)},
+		{UserId: 9003, ModelName: "gpt-4", CreatedAt: at(2, 1, 5)},
+	}
+	for i := range logs {
+		logs[i].Type = LogTypeConsume
+		logs[i].Content = marker
+	}
+	// Non-consume logs do not count as activity
+	logs = append(logs, Log{UserId: 9004, ModelName: "gpt-4", Type: LogTypeTopup, Content: marker, CreatedAt: at(1, 1, 1)})
+	require.NoError(t, LOG_DB.Create(&logs).Error)
+
+	for i, created := range []int64{at(1, 1, 8), at(1, 9, 8)} {
+		require.NoError(t, DB.Create(&User{
+			Username:    "test_active_" + random.GetRandomString(6),
+			Password:    "password123",
+			AccessToken: random.GetUUID(),
+			AffCode:     random.GetRandomString(8),
+			Status:      UserStatusEnabled,
+			CreatedAt:   created * 1000,
+		}).Error, i)
+	}
+
+	from, to := at(1, 1, 0), at(3, 1, 0)
+
+	daily, err := SearchActiveUsers(from, to, ActivityGranularityDaily)
+	require.NoError(t, err)
+	require.Equal(t, []UserActivityDataPoint{
+		{Period: "2001-01-01", ActiveUsers: 1, NewUsers: 1, ModelDiversity: 1},
+		{Period: "2001-01-02", ActiveUsers: 2, ModelDiversity: 1.5},
+		{Period: "2001-01-07", ActiveUsers: 1, ModelDiversity: 1},
+		{Period: "2001-01-08", ActiveUsers: 1, ModelDiversity: 1},
+		{Period: "2001-01-09", NewUsers: 1},
+		{Period: "2001-02-01", ActiveUsers: 1, ModelDiversity: 1},
+	}, daily)
+
+	weekly, err := SearchActiveUsers(from, to, ActivityGranularityWeekly)
+	require.NoError(t, err)
+	require.Equal(t, []UserActivityDataPoint{
+		{Period: "2001-01-01", ActiveUsers: 2, NewUsers: 1, ModelDiversity: 2},
+		{Period: "2001-01-08", ActiveUsers: 1, NewUsers: 1, ModelDiversity: 1},
+		{Period: "2001-01-29", ActiveUsers: 1, ModelDiversity: 1},
+	}, weekly)
+
+	monthly, err := SearchActiveUsers(from, to, ActivityGranularityMonthly)
+	require.NoError(t, err)
+	require.Equal(t, []UserActivityDataPoint{
+		{Period: "2001-01", ActiveUsers: 2, NewUsers: 2, ModelDiversity: 2},
+		{Period: "2001-02", ActiveUsers: 1, ModelDiversity: 1},
+	}, monthly)
+
+	// The range is half-open
+	daily, err = SearchActiveUsers(from, at(1, 2, 0), ActivityGranularityDaily)
+	require.NoError(t, err)
+	require.Equal(t, []UserActivityDataPoint{
+		{Period: "2001-01-01", ActiveUsers: 1, NewUsers: 1, ModelDiversity: 1},
+	}, daily)
+
+	_, err = SearchActiveUsers(from, to, "hourly")
+	require.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/dto"
+)
+
+// aggregateLogs sums the consume logs in [start, endExclusive), optionally
+// scoped to userId, grouped by groupBy, and scans one row per group into dest.
+// selectColumns names the group columns in the result.
+func aggregateLogs(dest any, selectColumns, groupBy, orderBy string, userId int, start, endExclusive int64) error {
+	query := `
+		SELECT ` + selectColumns + `,
+		count(1) as request_count,
+		sum(quota) as quota,
+		sum(prompt_tokens) as prompt_tokens,
+		sum(completion_tokens) as completion_tokens
+		FROM logs
+		WHERE type = ?
+		AND created_at >= ? AND created_at < ?`
+	args := []any{LogTypeConsume, start, endExclusive}
+	if userId != 0 {
+		query += `
+		AND user_id = ?`
+		args = append(args, userId)
+	}
+	query += `
+		GROUP BY ` + groupBy + `
+		ORDER BY ` + orderBy
+
+	return LOG_DB.Raw(query, args...).Scan(dest).Error
+}
+
+// AggregateLogsByModel returns consume log totals per model for the half-open
+// range [start, endExclusive) of Unix seconds, most expensive first. A userId
+// of 0 covers all users.
+func AggregateLogsByModel(userId int, start, endExclusive int64) ([]*dto.LogAggregateByModel, error) {
+	var rows []*dto.LogAggregateByModel
+	if err := aggregateLogs(&rows, "model_name", "model_name", "quota DESC, model_name",
+		userId, start, endExclusive); err != nil {
+		return nil, errors.Wrap(err, "aggregate logs by model")
+	}
+	return rows, nil
+}
+
+// AggregateLogsByChannel returns consume log totals per channel, like
+// AggregateLogsByModel.
+func AggregateLogsByChannel(userId int, start, endExclusive int64) ([]*dto.LogAggregateByChannel, error) {
+	var rows []*dto.LogAggregateByChannel
+	if err := aggregateLogs(&rows, "channel_id", "channel_id", "quota DESC, channel_id",
+		userId, start, endExclusive); err != nil {
+		return nil, errors.Wrap(err, "aggregate logs by channel")
+	}
+	return rows, nil
+}
+
+// AggregateLogsByUser returns consume log totals per user, like
+// AggregateLogsByModel.
+func AggregateLogsByUser(userId int, start, endExclusive int64) ([]*dto.LogAggregateByUser, error) {
+	var rows []*dto.LogAggregateByUser
+	if err := aggregateLogs(&rows, "user_id, username", "user_id, username", "quota DESC, user_id",
+		userId, start, endExclusive); err != nil {
+		return nil, errors.Wrap(err, "aggregate logs by user")
+	}
+	return rows, nil
+}
+
+// AggregateLogsByDay returns consume log totals per day in chronological
+// order, with days bucketed like SearchLogsByDayAndModel.
+func AggregateLogsByDay(userId int, start, endExclusive int64) ([]*dto.LogAggregateByDay, error) {
+	var rows []*dto.LogAggregateByDay
+	if err := aggregateLogs(&rows, dayAggregationSelect(), "day", "day",
+		userId, start, endExclusive); err != nil {
+		return nil, errors.Wrap(err, "aggregate logs by day")
+	}
+	return rows, nil
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+func TestAggregateLogs(t *testing.T) {
+	setupLogExportDB(t)
+	originalUsingSQLite := common.UsingSQLite.Load()
+	common.UsingSQLite.Store(true)
+	t.Cleanup(func() { common.UsingSQLite.Store(originalUsingSQLite) })
+
+	const day1, day2 = 1700006400, 1700092800 // 2023-11-15 and 2023-11-16 UTC midnight
+	logs := []Log{
+		{UserId: 1, Username: "alice", ModelName: "gpt-4o", ChannelId: 3, Type: LogTypeConsume, CreatedAt: day1 + 10, Quota: 100, PromptTokens: 10, CompletionTokens: 5},
+		{UserId: 1, Username: "alice", ModelName: "gpt-4o", ChannelId: 4, Type: LogTypeConsume, CreatedAt: day2 + 10, Quota: 50, PromptTokens: 4, CompletionTokens: 2},
+		{UserId: 2, Username: "bob", ModelName: "claude-3", ChannelId: 3, Type: LogTypeConsume, CreatedAt: day2 + 20, Quota: 300, PromptTokens: 30, CompletionTokens: 15},
+		// Outside the range or not consume logs
+		{UserId: 2, Username: "bob", ModelName: "claude-3", ChannelId: 3, Type: LogTypeConsume, CreatedAt: day2 + 86400, Quota: 999},
+		{UserId: 1, Username: "alice", Type: LogTypeManage, CreatedAt: day1 + 30, Quota: 999},
+	}
+	require.NoError(t, LOG_DB.Create(&logs).Error)
+	end := int64(day2 + 86400)
+
+	byModel, err := AggregateLogsByModel(0, day1, end)
+	require.NoError(t, err)
+	require.Len(t, byModel, 2)
+	require.Equal(t, "claude-3", byModel[0].ModelName)
+	require.Equal(t, "gpt-4o", byModel[1].ModelName)
+	require.Equal(t, int64(2), byModel[1].RequestCount)
+	require.Equal(t, int64(150), byModel[1].Quota)
+	require.Equal(t, int64(14), byModel[1].PromptTokens)
+	require.Equal(t, int64(7), byModel[1].CompletionTokens)
+
+	byChannel, err := AggregateLogsByChannel(0, day1, end)
+	require.NoError(t, err)
+	require.Len(t, byChannel, 2)
+	require.Equal(t, 3, byChannel[0].ChannelId)
+	require.Equal(t, int64(400), byChannel[0].Quota)
+
+	byUser, err := AggregateLogsByUser(1, day1, end)
+	require.NoError(t, err)
+	require.Len(t, byUser, 1)
+	require.Equal(t, "alice", byUser[0].Username)
+	require.Equal(t, int64(150), byUser[0].Quota)
+
+	byDay, err := AggregateLogsByDay(0, day1, end)
+	require.NoError(t, err)
+	require.Len(t, byDay, 2)
+	require.Equal(t, "2023-11-15", byDay[0].Day)
+	require.Equal(t, int64(1), byDay[0].RequestCount)
+	require.Equal(t, "2023-11-16", byDay[1].Day)
+	require.Equal(t, int64(350), byDay[1].Quota)
+}
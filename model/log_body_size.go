@@ -0,0 +1,175 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+)
+
+// AppendBodySizeMetadata records the request and response body sizes into the
+// metadata map. Negative sizes are unknown and left out.
+func AppendBodySizeMetadata(metadata LogMetadata, requestBytes, responseBytes int64) LogMetadata {
+	if requestBytes < 0 && responseBytes < 0 {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+	if requestBytes >= 0 {
+		metadata[LogMetadataKeyRequestBodyBytes] = requestBytes
+	}
+	if responseBytes >= 0 {
+		metadata[LogMetadataKeyResponseBodyBytes] = responseBytes
+	}
+	return metadata
+}
+
+// BodySizes are the client request and response body sizes of a relay
+// request in bytes. Negative sizes were not measured.
+type BodySizes struct {
+	RequestBytes  int64
+	ResponseBytes int64
+}
+
+// bodySizesKey is the context key under which WithBodySizes stores BodySizes.
+type bodySizesKey struct{}
+
+// CaptureBodySizes measures the body sizes of the request behind c. It must run
+// on the handler goroutine once the response is written, since gin recycles c
+// after the handler returns while consume logs are recorded by the billing
+// goroutine. The request size is measured when enabled; the response size only
+// for requests sampled into the replay corpus. For streams it covers every
+// chunk written so far.
+func CaptureBodySizes(c *gin.Context) BodySizes {
+	sizes := BodySizes{RequestBytes: -1, ResponseBytes: -1}
+	if config.LogRequestBodySizeEnabled {
+		sizes.RequestBytes = requestBodyBytes(c)
+	}
+	if c.GetBool(ctxkey.ReplayCorpusSampled) {
+		sizes.ResponseBytes = int64(max(c.Writer.Size(), 0))
+	}
+	return sizes
+}
+
+// WithBodySizes returns a copy of ctx carrying sizes for RecordConsumeLog.
+func WithBodySizes(ctx context.Context, sizes BodySizes) context.Context {
+	return context.WithValue(ctx, bodySizesKey{}, sizes)
+}
+
+// requestBodyBytes returns the size of the client request body, preferring the
+// cached body over Content-Length, which is unknown for chunked uploads.
+func requestBodyBytes(c *gin.Context) int64 {
+	if cached, ok := c.Get(ctxkey.KeyRequestBody); ok {
+		if body, ok := cached.([]byte); ok {
+			return int64(len(body))
+		}
+	}
+	if c.Request == nil {
+		return -1
+	}
+	return c.Request.ContentLength
+}
+
+// appendBodySizeMetadata adds the body sizes captured into ctx by
+// WithBodySizes to metadata.
+func appendBodySizeMetadata(ctx context.Context, metadata LogMetadata) LogMetadata {
+	sizes, ok := ctx.Value(bodySizesKey{}).(BodySizes)
+	if !ok {
+		return metadata
+	}
+	return AppendBodySizeMetadata(metadata, sizes.RequestBytes, sizes.ResponseBytes)
+}
+
+// BodySizeStats summarizes a body size recorded in consume log metadata.
+type BodySizeStats struct {
+	// Field is the metadata key the sizes were read from.
+	Field string `json:"field"`
+	// Samples is the number of logs carrying the field.
+	Samples int64 `json:"samples"`
+	// Max is the largest recorded size in bytes.
+	Max int64 `json:"max"`
+	// Percentiles maps labels such as "p95" to nearest-rank percentiles in bytes.
+	Percentiles map[string]int64 `json:"percentiles"`
+}
+
+// metadataIntSelect returns the SQL expression extracting an integer field
+// from the JSON text in logs.metadata for the configured database engine.
+func metadataIntSelect(key string) string {
+	if common.UsingPostgreSQL.Load() {
+		return "CAST(CAST(metadata AS jsonb) ->> '" + key + "' AS BIGINT)"
+	}
+	if common.UsingSQLite.Load() {
+		return "CAST(json_extract(metadata, '$." + key + "') AS INTEGER)"
+	}
+	return "CAST(JSON_UNQUOTE(JSON_EXTRACT(metadata, '$." + key + "')) AS SIGNED)"
+}
+
+// bodySizeQuery returns the query selecting the sizes recorded under key in
+// consume logs since the given Unix timestamp, optionally for one model. The
+// created_at/type filter is served by idx_created_at_type, and the LIKE
+// pre-filter skips JSON parsing for logs without the field.
+func bodySizeQuery(key, modelName string, since int64) (string, []any) {
+	query := "SELECT " + metadataIntSelect(key) + " AS size FROM logs" +
+		" WHERE created_at >= ? AND type = ? AND metadata LIKE ?"
+	args := []any{since, LogTypeConsume, `%"` + key + `"%`}
+	if modelName != "" {
+		query += " AND model_name = ?"
+		args = append(args, modelName)
+	}
+	return query, args
+}
+
+// GetBodySizeStats computes nearest-rank percentiles of the body size recorded
+// under key (request_body_bytes or response_body_bytes) in consume logs of the
+// last `days` days. percentiles are whole percents in [1, 100]. Sorting happens
+// in the database, one row is read per percentile.
+func GetBodySizeStats(ctx context.Context, key, modelName string, days int, percentiles []int) (*BodySizeStats, error) {
+	if key != LogMetadataKeyRequestBodyBytes && key != LogMetadataKeyResponseBodyBytes {
+		return nil, errors.Errorf("unsupported body size field %q", key)
+	}
+	since := time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour).Unix()
+	query, args := bodySizeQuery(key, modelName, since)
+	db := LOG_DB.WithContext(ctx)
+
+	var summary struct {
+		Samples int64
+		MaxSize int64
+	}
+	err := db.Raw("SELECT COUNT(size) AS samples, COALESCE(MAX(size), 0) AS max_size FROM ("+query+") body_sizes", args...).
+		Scan(&summary).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "summarize %s", key)
+	}
+
+	stats := &BodySizeStats{
+		Field:       key,
+		Samples:     summary.Samples,
+		Max:         summary.MaxSize,
+		Percentiles: make(map[string]int64, len(percentiles)),
+	}
+	if summary.Samples == 0 {
+		return stats, nil
+	}
+
+	for _, p := range percentiles {
+		if p < 1 || p > 100 {
+			return nil, errors.Errorf("percentile %d out of range [1, 100]", p)
+		}
+		rank := (int64(p)*summary.Samples + 99) / 100
+		var size int64
+		err := db.Raw(query+" AND "+metadataIntSelect(key)+" IS NOT NULL ORDER BY size LIMIT 1 OFFSET ?", append(args, rank-1)...).
+			Scan(&size).Error
+		if err != nil {
+			return nil, errors.Wrapf(err, "compute p%d of %s", p, key)
+		}
+		stats.Percentiles[fmt.Sprintf("p%d", p)] = size
+	}
+	return stats, nil
+}
@@ -0,0 +1,173 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/random"
+)
+
+func setupBodySizeTest(t *testing.T) {
+	t.Helper()
+	setupTestDatabase(t)
+	originalEnabled := config.LogRequestBodySizeEnabled
+	originalConsume := config.IsLogConsumeEnabled()
+	config.LogRequestBodySizeEnabled = true
+	config.SetLogConsumeEnabled(true)
+	t.Cleanup(func() {
+		config.LogRequestBodySizeEnabled = originalEnabled
+		config.SetLogConsumeEnabled(originalConsume)
+		LOG_DB.Exec("DELETE FROM logs WHERE content = ?", "test-body-size")
+	})
+}
+
+// recordBodySizeLog relays requestBody through a gin context, lets write
+// produce the response and records a consume log for it. Like the relay
+// handlers, it captures the sizes before the gin context is recycled.
+func recordBodySizeLog(t *testing.T, requestBody string, chunked, sampled bool, write func(c *gin.Context)) *Log {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(requestBody))
+	c.Set(ctxkey.ReplayCorpusSampled, sampled)
+	if chunked {
+		c.Request.ContentLength = -1
+		// Relay handlers read and cache the body before the log is written
+		_, err := common.GetRequestBody(c)
+		require.NoError(t, err)
+	}
+	write(c)
+	sizes := CaptureBodySizes(c)
+	c.Keys = nil
+	c.Writer = nil
+
+	requestId := "test-body-size-" + random.GetUUID()
+	RecordConsumeLog(WithBodySizes(context.Background(), sizes), &Log{UserId: 1, ModelName: "test-body-size-model", Content: "test-body-size", RequestId: requestId})
+
+	var saved Log
+	require.NoError(t, LOG_DB.Where("request_id = ?", requestId).First(&saved).Error)
+	return &saved
+}
+
+func TestRecordConsumeLogBodySizes(t *testing.T) {
+	requestBody := `{"model":"gpt-4o","messages":[{"role":"user","content":"hello"}]}`
+
+	t.Run("non-streaming", func(t *testing.T) {
+		setupBodySizeTest(t)
+		response := `{"id":"chatcmpl-1","choices":[{"message":{"content":"hi"}}]}`
+		saved := recordBodySizeLog(t, requestBody, false, true, func(c *gin.Context) {
+			c.Data(http.StatusOK, "application/json", []byte(response))
+		})
+		require.EqualValues(t, len(requestBody), saved.Metadata[LogMetadataKeyRequestBodyBytes])
+		require.EqualValues(t, len(response), saved.Metadata[LogMetadataKeyResponseBodyBytes])
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		setupBodySizeTest(t)
+		var written int
+		saved := recordBodySizeLog(t, requestBody, true, true, func(c *gin.Context) {
+			c.Writer.Header().Set("Content-Type", "text/event-stream")
+			for i := range 5 {
+				n, err := io.WriteString(c.Writer, fmt.Sprintf("data: {\"choices\":[{\"delta\":{\"content\":\"chunk %d\"}}]}\n\n", i))
+				require.NoError(t, err)
+				written += n
+				c.Writer.Flush()
+			}
+			n, err := io.WriteString(c.Writer, "data: [DONE]\n\n")
+			require.NoError(t, err)
+			written += n
+		})
+		require.EqualValues(t, len(requestBody), saved.Metadata[LogMetadataKeyRequestBodyBytes])
+		require.EqualValues(t, written, saved.Metadata[LogMetadataKeyResponseBodyBytes])
+	})
+
+	t.Run("response size not sampled", func(t *testing.T) {
+		setupBodySizeTest(t)
+		saved := recordBodySizeLog(t, requestBody, false, false, func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+		require.EqualValues(t, len(requestBody), saved.Metadata[LogMetadataKeyRequestBodyBytes])
+		require.NotContains(t, saved.Metadata, LogMetadataKeyResponseBodyBytes)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		setupBodySizeTest(t)
+		config.LogRequestBodySizeEnabled = false
+		saved := recordBodySizeLog(t, requestBody, false, false, func(c *gin.Context) {})
+		require.Empty(t, saved.Metadata)
+	})
+}
+
+func TestGetBodySizeStats(t *testing.T) {
+	setupBodySizeTest(t)
+	ctx := context.Background()
+
+	modelName := "test-body-size-" + random.GetRandomString(6)
+	logs := make([]Log, 0, 101)
+	for size := 1; size <= 100; size++ {
+		logs = append(logs, Log{
+			UserId:    1,
+			ModelName: modelName,
+			Type:      LogTypeConsume,
+			Content:   "test-body-size",
+			CreatedAt: helper.GetTimestamp(),
+			Metadata:  AppendBodySizeMetadata(nil, int64(size*10), -1),
+		})
+	}
+	// Logs without the field are not samples
+	logs = append(logs, Log{UserId: 1, ModelName: modelName, Type: LogTypeConsume, Content: "test-body-size", CreatedAt: helper.GetTimestamp()})
+	require.NoError(t, LOG_DB.Create(&logs).Error)
+
+	stats, err := GetBodySizeStats(ctx, LogMetadataKeyRequestBodyBytes, modelName, 1, []int{50, 95, 100})
+	require.NoError(t, err)
+	require.Equal(t, &BodySizeStats{
+		Field:       LogMetadataKeyRequestBodyBytes,
+		Samples:     100,
+		Max:         1000,
+		Percentiles: map[string]int64{"p50": 500, "p95": 950, "p100": 1000},
+	}, stats)
+
+	stats, err = GetBodySizeStats(ctx, LogMetadataKeyResponseBodyBytes, modelName, 1, []int{50})
+	require.NoError(t, err)
+	require.Zero(t, stats.Samples)
+	require.Empty(t, stats.Percentiles)
+
+	_, err = GetBodySizeStats(ctx, "quota", modelName, 1, []int{50})
+	require.Error(t, err)
+	_, err = GetBodySizeStats(ctx, LogMetadataKeyRequestBodyBytes, modelName, 1, []int{0})
+	require.Error(t, err)
+}
+
+func TestBodySizeQueryUsesIndex(t *testing.T) {
+	setupTestDatabase(t)
+	require.True(t, common.UsingSQLite.Load())
+
+	query, args := bodySizeQuery(LogMetadataKeyRequestBodyBytes, "", 0)
+	var plan []struct {
+		Detail string
+	}
+	require.NoError(t, LOG_DB.Raw("EXPLAIN QUERY PLAN "+query, args...).Scan(&plan).Error)
+	require.NotEmpty(t, plan)
+	require.Contains(t, plan[0].Detail, "USING INDEX", "the percentile query must not scan the whole logs table")
+}
+
+func TestMetadataIntSelect(t *testing.T) {
+	useDialect(t, true, false, false)
+	require.Equal(t, "CAST(CAST(metadata AS jsonb) ->> 'request_body_bytes' AS BIGINT)", metadataIntSelect(LogMetadataKeyRequestBodyBytes))
+	useDialect(t, false, true, false)
+	require.Equal(t, "CAST(json_extract(metadata, '$.request_body_bytes') AS INTEGER)", metadataIntSelect(LogMetadataKeyRequestBodyBytes))
+	useDialect(t, false, false, true)
+	require.Equal(t, "CAST(JSON_UNQUOTE(JSON_EXTRACT(metadata, '$.request_body_bytes')) AS SIGNED)", metadataIntSelect(LogMetadataKeyRequestBodyBytes))
+}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+const (
+	logCompressionSweepInterval = 24 * time.Hour
+	// logCompressionBatchSize bounds the rows rewritten per transaction by CompressOldLogs.
+	logCompressionBatchSize = 500
+	// logCompressionStatsTTL bounds how often GetLogCompressionStats counts the logs table.
+	logCompressionStatsTTL = 10 * time.Minute
+	// compressedLogContentPrefix prefixes the hash that replaces compressed log content.
+	compressedLogContentPrefix = "compressed:sha256:"
+)
+
+// LogCompressionStats reports how many log entries have been compressed.
+type LogCompressionStats struct {
+	Compressed int64 `json:"compressed"`
+	Total      int64 `json:"total"`
+	// Ratio is Compressed divided by Total, or 0 when there are no logs.
+	Ratio float64 `json:"ratio"`
+}
+
+var logCompressionStatsCache struct {
+	sync.Mutex
+	stats       LogCompressionStats
+	refreshedAt time.Time
+}
+
+// compressedLogContent returns the short summary that replaces content.
+func compressedLogContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return compressedLogContentPrefix + hex.EncodeToString(sum[:8])
+}
+
+// GetLogCompressionStats returns the compressed/total ratio of the logs table.
+// Counts are cached for a few minutes since counting large tables is expensive.
+func GetLogCompressionStats() (LogCompressionStats, error) {
+	logCompressionStatsCache.Lock()
+	defer logCompressionStatsCache.Unlock()
+	if !logCompressionStatsCache.refreshedAt.IsZero() &&
+		time.Since(logCompressionStatsCache.refreshedAt) < logCompressionStatsTTL {
+		return logCompressionStatsCache.stats, nil
+	}
+
+	var stats LogCompressionStats
+	if err := LOG_DB.Model(&Log{}).Count(&stats.Total).Error; err != nil {
+		return stats, errors.Wrap(err, "count logs")
+	}
+	if err := LOG_DB.Model(&Log{}).Where("compressed = ?", true).Count(&stats.Compressed).Error; err != nil {
+		return stats, errors.Wrap(err, "count compressed logs")
+	}
+	if stats.Total > 0 {
+		stats.Ratio = float64(stats.Compressed) / float64(stats.Total)
+	}
+
+	logCompressionStatsCache.stats = stats
+	logCompressionStatsCache.refreshedAt = time.Now()
+	return stats, nil
+}
+
+// invalidateLogCompressionStats makes the next GetLogCompressionStats recount.
+func invalidateLogCompressionStats() {
+	logCompressionStatsCache.Lock()
+	defer logCompressionStatsCache.Unlock()
+	logCompressionStatsCache.refreshedAt = time.Time{}
+}
+
+// StartLogCompressor launches a background worker that compresses log entries
+// older than compressionDays, see CompressOldLogs.
+func StartLogCompressor(ctx context.Context, compressionDays int) {
+	if compressionDays <= 0 {
+		logger.Logger.Debug("log compression disabled", zap.Int("log_compression_days", compressionDays))
+		return
+	}
+
+	compress := func() {
+		cutoff := time.Now().UTC().Add(-time.Duration(compressionDays) * 24 * time.Hour).Unix()
+		compressed, err := CompressOldLogs(cutoff)
+		if compressed > 0 {
+			invalidateLogCompressionStats()
+		}
+		if err != nil {
+			logger.Logger.Warn("log compression failed", zap.Int64("compressed_rows", compressed), zap.Error(err))
+			return
+		}
+
+		if compressed > 0 {
+			logger.Logger.Info("compressed old log entries", zap.Int64("compressed_rows", compressed), zap.Int("log_compression_days", compressionDays))
+		} else {
+			logger.Logger.Debug("log compression completed", zap.Int("log_compression_days", compressionDays))
+		}
+	}
+
+	ticker := time.NewTicker(logCompressionSweepInterval)
+
+	go func() {
+		defer ticker.Stop()
+		// The first sweep may rewrite many rows, so keep it off the startup path
+		compress()
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Logger.Info("log compressor stopped")
+				return
+			case <-ticker.C:
+				compress()
+			}
+		}
+	}()
+
+	logger.Logger.Info("log compressor started", zap.Int("log_compression_days", compressionDays))
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupLogCompressionDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Log{}))
+
+	originalLogDB := LOG_DB
+	LOG_DB = db
+	t.Cleanup(func() { LOG_DB = originalLogDB })
+	invalidateLogCompressionStats()
+	t.Cleanup(invalidateLogCompressionStats)
+}
+
+func TestCompressOldLogs(t *testing.T) {
+	setupLogCompressionDB(t)
+
+	now := time.Now().UTC()
+	old := now.Add(-40 * 24 * time.Hour).Unix()
+	recent := now.Add(-time.Hour).Unix()
+	logs := []Log{
+		{UserId: 1, Username: "alice", ModelName: "gpt-4o", Type: LogTypeConsume, CreatedAt: old, Quota: 100,
+			Content: "old request with secret-keyword", TraceId: "trace-1", RequestId: "req-1",
+			Metadata: LogMetadata{"tool_usage": map[string]any{"web_search": 1}}},
+		{UserId: 1, Username: "alice", ModelName: "gpt-4o", Type: LogTypeConsume, CreatedAt: old + 60, Quota: 50,
+			Content: "another secret-keyword request", TraceId: "trace-2"},
+		{UserId: 1, Username: "alice", ModelName: "gpt-4o", Type: LogTypeConsume, CreatedAt: recent, Quota: 25,
+			Content: "recent secret-keyword request", TraceId: "trace-3"},
+	}
+	for i := range logs {
+		require.NoError(t, LOG_DB.Create(&logs[i]).Error)
+	}
+
+	cutoff := now.Add(-30 * 24 * time.Hour).Unix()
+	compressed, err := CompressOldLogs(cutoff)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, compressed)
+
+	// Running again is a no-op
+	compressed, err = CompressOldLogs(cutoff)
+	require.NoError(t, err)
+	require.Zero(t, compressed)
+
+	got, err := GetLogById(logs[0].Id)
+	require.NoError(t, err)
+	require.True(t, got.Compressed)
+	require.True(t, strings.HasPrefix(got.Content, compressedLogContentPrefix))
+	require.Equal(t, compressedLogContent("old request with secret-keyword"), got.Content)
+	require.Empty(t, got.TraceId)
+	require.Empty(t, got.RequestId)
+	require.Empty(t, got.Metadata)
+	require.Equal(t, 1, got.UserId)
+	require.Equal(t, "gpt-4o", got.ModelName)
+	require.Equal(t, 100, got.Quota)
+	require.Equal(t, old, got.CreatedAt)
+	require.Equal(t, LogTypeConsume, got.Type)
+
+	got, err = GetLogById(logs[2].Id)
+	require.NoError(t, err)
+	require.False(t, got.Compressed)
+	require.Equal(t, "trace-3", got.TraceId)
+
+	// Quota aggregation still counts compressed logs
+	require.EqualValues(t, 175, SumUsedQuota(LogTypeConsume, 0, 0, "gpt-4o", "alice", "", 0))
+
+	// Detailed content search skips them, even when searching for the hash
+	found, total, err := SearchUserLogs(1, "secret-keyword", 0, 10, "", "", true)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, logs[2].Id, found[0].Id)
+	_, total, err = SearchAllLogs(compressedLogContentPrefix, 0, 10, "", "", true)
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	stats, err := GetLogCompressionStats()
+	require.NoError(t, err)
+	require.Equal(t, LogCompressionStats{Compressed: 2, Total: 3, Ratio: 2.0 / 3}, stats)
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"gorm.io/gorm"
+)
+
+// MaxLogExportRows caps the number of log entries a single export may contain.
+const MaxLogExportRows = 100000
+
+// ErrLogExportTooLarge is returned by ExportLogsToCsv when the filters match
+// more than MaxLogExportRows entries.
+var ErrLogExportTooLarge = errors.Errorf("log export is limited to %d rows, narrow the filters", MaxLogExportRows)
+
+// LogExportFilters selects the logs to export. The fields mirror the filters
+// and sorting accepted by GetAllLogs.
+type LogExportFilters struct {
+	LogType        int
+	StartTimestamp int64
+	EndTimestamp   int64
+	ModelName      string
+	Username       string
+	TokenName      string
+	Channel        int
+	SortBy         string
+	SortOrder      string
+}
+
+func (f LogExportFilters) apply(tx *gorm.DB) *gorm.DB {
+	if f.LogType != LogTypeUnknown {
+		tx = tx.Where("type = ?", f.LogType)
+	}
+	if f.ModelName != "" {
+		tx = tx.Where("model_name = ?", f.ModelName)
+	}
+	if f.Username != "" {
+		tx = tx.Where("username = ?", f.Username)
+	}
+	if f.TokenName != "" {
+		tx = tx.Where("token_name = ?", f.TokenName)
+	}
+	if f.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", f.StartTimestamp)
+	}
+	if f.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", f.EndTimestamp)
+	}
+	if f.Channel != 0 {
+		tx = tx.Where("channel_id = ?", f.Channel)
+	}
+	return tx
+}
+
+var logExportHeader = []string{
+	"id", "created_at", "type", "username", "token_name", "model_name", "channel_id",
+	"quota", "prompt_tokens", "completion_tokens", "cached_prompt_tokens", "cached_completion_tokens",
+	"elapsed_time_ms", "is_stream", "request_id", "trace_id", "content",
+}
+
+func logExportRecord(log *Log) []string {
+	return []string{
+		strconv.Itoa(log.Id),
+		time.Unix(log.CreatedAt, 0).UTC().Format(time.RFC3339),
+		strconv.Itoa(log.Type),
+		csvSafe(log.Username),
+		csvSafe(log.TokenName),
+		csvSafe(log.ModelName),
+		strconv.Itoa(log.ChannelId),
+		strconv.Itoa(log.Quota),
+		strconv.Itoa(log.PromptTokens),
+		strconv.Itoa(log.CompletionTokens),
+		strconv.Itoa(log.CachedPromptTokens),
+		strconv.Itoa(log.CachedCompletionTokens),
+		strconv.FormatInt(log.ElapsedTime, 10),
+		strconv.FormatBool(log.IsStream),
+		log.RequestId,
+		log.TraceId,
+		csvSafe(log.Content),
+	}
+}
+
+// csvSafe prefixes values starting with a formula character with a single
+// quote, so spreadsheets opening the export show them as text instead of
+// evaluating them.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// ExportLogsToCsv streams the logs matching filters as CSV. Rows are read
+// from the database and encoded while the caller consumes the returned
+// reader, so the export is never held in memory; closing the reader early
+// stops the query. Exports matching more than MaxLogExportRows entries fail
+// with ErrLogExportTooLarge before anything is written.
+func ExportLogsToCsv(ctx context.Context, filters LogExportFilters) (io.ReadCloser, error) {
+	var count int64
+	if err := filters.apply(LOG_DB.WithContext(ctx).Model(&Log{})).Count(&count).Error; err != nil {
+		return nil, errors.Wrap(err, "count logs to export")
+	}
+	if count > MaxLogExportRows {
+		return nil, errors.Wrapf(ErrLogExportTooLarge, "%d logs match", count)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeLogsCsv(ctx, pw, filters))
+	}()
+	return pr, nil
+}
+
+func writeLogsCsv(ctx context.Context, out io.Writer, filters LogExportFilters) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(logExportHeader); err != nil {
+		return errors.Wrap(err, "write csv header")
+	}
+
+	rows, err := filters.apply(LOG_DB.WithContext(ctx).Model(&Log{})).
+		Order(GetLogOrderClause(filters.SortBy, filters.SortOrder)).
+		Limit(MaxLogExportRows).
+		Rows()
+	if err != nil {
+		return errors.Wrap(err, "query logs to export")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var log Log
+		if err := LOG_DB.ScanRows(rows, &log); err != nil {
+			return errors.Wrap(err, "scan log row")
+		}
+		if err := w.Write(logExportRecord(&log)); err != nil {
+			return errors.Wrap(err, "write csv record")
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "iterate log rows")
+	}
+	w.Flush()
+	return errors.Wrap(w.Error(), "flush csv")
+}
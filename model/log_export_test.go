@@ -0,0 +1,87 @@
+package model
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"testing"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupLogExportDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Log{}))
+
+	originalLogDB := LOG_DB
+	LOG_DB = db
+	t.Cleanup(func() { LOG_DB = originalLogDB })
+}
+
+func TestExportLogsToCsv(t *testing.T) {
+	setupLogExportDB(t)
+	logs := []Log{
+		{UserId: 1, Username: "alice", ModelName: "gpt-4o", Type: LogTypeConsume, CreatedAt: 1700000000, Quota: 100, PromptTokens: 10, Content: "first, with comma"},
+		{UserId: 2, Username: "bob", ModelName: "gpt-4o", Type: LogTypeConsume, CreatedAt: 1700000100, Quota: 200},
+		{UserId: 1, Username: "alice", ModelName: "claude-3", Type: LogTypeConsume, CreatedAt: 1700000200, Quota: 300},
+		{UserId: 1, Username: "alice", Type: LogTypeManage, CreatedAt: 1700000300, Content: "manage"},
+	}
+	require.NoError(t, LOG_DB.Create(&logs).Error)
+
+	body, err := ExportLogsToCsv(context.Background(), LogExportFilters{
+		LogType:   LogTypeConsume,
+		Username:  "alice",
+		SortBy:    "created_time",
+		SortOrder: "asc",
+	})
+	require.NoError(t, err)
+	defer body.Close()
+
+	records, err := csv.NewReader(body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, logExportHeader, records[0])
+	require.Equal(t, "2023-11-14T22:13:20Z", records[1][1])
+	require.Equal(t, "gpt-4o", records[1][5])
+	require.Equal(t, "100", records[1][7])
+	require.Equal(t, "first, with comma", records[1][16])
+	require.Equal(t, "claude-3", records[2][5])
+}
+
+func TestExportLogsToCsvRejectsLargeExports(t *testing.T) {
+	setupLogExportDB(t)
+	require.NoError(t, LOG_DB.Exec(`INSERT INTO logs (created_at, type)
+		WITH RECURSIVE seq(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < ?)
+		SELECT n, ? FROM seq`, MaxLogExportRows+1, LogTypeConsume).Error)
+
+	_, err := ExportLogsToCsv(context.Background(), LogExportFilters{})
+	require.True(t, errors.Is(err, ErrLogExportTooLarge))
+
+	// Narrower filters fit within the limit
+	body, err := ExportLogsToCsv(context.Background(), LogExportFilters{StartTimestamp: 2})
+	require.NoError(t, err)
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NotEmpty(t, data)
+}
+
+func TestLogExportRecordEscapesFormulas(t *testing.T) {
+	record := logExportRecord(&Log{
+		Username:  "=HYPERLINK(\"http://evil\")",
+		TokenName: "+cmd",
+		ModelName: "gpt-4o",
+		Content:   "@SUM(A1)",
+	})
+	require.Equal(t, "'=HYPERLINK(\"http://evil\")", record[3])
+	require.Equal(t, "'+cmd", record[4])
+	require.Equal(t, "gpt-4o", record[5])
+	require.Equal(t, "'@SUM(A1)", record[16])
+	require.Equal(t, "'-1", csvSafe("-1"))
+	require.Empty(t, csvSafe(""))
+}
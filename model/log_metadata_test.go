@@ -57,3 +57,11 @@ func TestAppendCacheWriteTokensMetadata(t *testing.T) {
 	require.Equal(t, 10, tokens[LogMetadataKeyCacheWrite5m])
 	require.Equal(t, 5, tokens[LogMetadataKeyCacheWrite1h])
 }
+
+// TestAppendImageTokensMetadata confirms image tokens are recorded only when reported.
+func TestAppendImageTokensMetadata(t *testing.T) {
+	require.Nil(t, AppendImageTokensMetadata(nil, 0))
+
+	metadata := AppendImageTokensMetadata(LogMetadata{"foo": 1}, 1024)
+	require.Equal(t, LogMetadata{"foo": 1, LogMetadataKeyImageTokens: 1024}, metadata)
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/metrics"
+)
+
+// logWriteQueue hands log entries from recordLogHelper to the workers started
+// by InitAsyncLogWriter. mu guards closing it against concurrent sends.
+var logWriteQueue struct {
+	mu      sync.RWMutex
+	entries chan *Log
+	closed  bool
+	// stop makes the workers exit before the queue is empty, once shutdown
+	// can wait no longer
+	stop chan struct{}
+	done chan struct{}
+}
+
+// InitAsyncLogWriter starts config.AsyncLogWorkers goroutines writing the
+// log entries queued by recordLogHelper, which then no longer blocks on the
+// database. Call StopAsyncLogWriter on shutdown to flush the queue.
+func InitAsyncLogWriter() {
+	entries := make(chan *Log, config.AsyncLogQueueSize)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	var workers sync.WaitGroup
+	for range max(config.AsyncLogWorkers, 1) {
+		workers.Go(func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				select {
+				case <-stop:
+					return
+				case log, ok := <-entries:
+					if !ok {
+						return
+					}
+					metrics.GlobalRecorder.UpdateLogQueueDepth(len(entries))
+					writeLog(log)
+				}
+			}
+		})
+	}
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	logWriteQueue.mu.Lock()
+	logWriteQueue.entries, logWriteQueue.closed = entries, false
+	logWriteQueue.stop, logWriteQueue.done = stop, done
+	logWriteQueue.mu.Unlock()
+}
+
+// enqueueLogWrite queues a copy of log for the async writer, so the caller
+// may keep reading it. It reports false when the writer is not running or the
+// queue is full, in which case the caller must write the entry itself so none
+// is lost.
+func enqueueLogWrite(log *Log) bool {
+	logWriteQueue.mu.RLock()
+	defer logWriteQueue.mu.RUnlock()
+	if logWriteQueue.entries == nil || logWriteQueue.closed {
+		return false
+	}
+	entry := *log
+	select {
+	case logWriteQueue.entries <- &entry:
+		metrics.GlobalRecorder.UpdateLogQueueDepth(len(logWriteQueue.entries))
+		return true
+	default:
+		return false
+	}
+}
+
+// StopAsyncLogWriter stops accepting log entries and waits, bounded by ctx,
+// for the workers to write the ones still queued. Later entries are written
+// synchronously. When ctx expires first, the workers are stopped after their
+// current write and the entries left in the queue are dropped; their number
+// is logged and returned. Either way no worker touches the database once it
+// returns, so call it after graceful.SetDraining() but before closing the
+// database.
+func StopAsyncLogWriter(ctx context.Context) (dropped int) {
+	logWriteQueue.mu.Lock()
+	if logWriteQueue.entries == nil || logWriteQueue.closed {
+		logWriteQueue.mu.Unlock()
+		return 0
+	}
+	logWriteQueue.closed = true
+	close(logWriteQueue.entries)
+	entries, stop, done := logWriteQueue.entries, logWriteQueue.stop, logWriteQueue.done
+	logWriteQueue.mu.Unlock()
+
+	select {
+	case <-done:
+		logger.Logger.Info("async log writer flushed pending log entries")
+		return 0
+	case <-ctx.Done():
+	}
+
+	close(stop)
+	<-done
+	for range entries {
+		dropped++
+	}
+	metrics.GlobalRecorder.UpdateLogQueueDepth(0)
+	logger.Logger.Error("async log writer shutdown context expired before the queue was flushed, log entries dropped",
+		zap.Int("dropped_entries", dropped))
+	return dropped
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/graceful"
+)
+
+func TestAsyncLogWriterFlushesOnStop(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// Every connection to :memory: opens a separate database
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&Log{}))
+
+	originalLogDB := LOG_DB
+	originalSize, originalWorkers := config.AsyncLogQueueSize, config.AsyncLogWorkers
+	LOG_DB = db
+	config.AsyncLogQueueSize, config.AsyncLogWorkers = 16, 3
+	t.Cleanup(func() {
+		LOG_DB = originalLogDB
+		config.AsyncLogQueueSize, config.AsyncLogWorkers = originalSize, originalWorkers
+		logWriteQueue.mu.Lock()
+		logWriteQueue.entries, logWriteQueue.closed = nil, false
+		logWriteQueue.stop, logWriteQueue.done = nil, nil
+		logWriteQueue.mu.Unlock()
+	})
+
+	InitAsyncLogWriter()
+	const queued = 50 // more than the queue holds, the overflow is written inline
+	for i := range queued {
+		recordLogHelper(context.Background(), &Log{UserId: i + 1, Type: LogTypeSystem, Content: "queued"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	StopAsyncLogWriter(ctx)
+	require.NoError(t, graceful.Drain(ctx))
+
+	var count int64
+	require.NoError(t, LOG_DB.Model(&Log{}).Count(&count).Error)
+	require.Equal(t, int64(queued), count)
+
+	// Entries recorded after the writer stopped are written synchronously
+	recordLogHelper(context.Background(), &Log{UserId: 999, Type: LogTypeSystem, Content: "late"})
+	require.NoError(t, LOG_DB.Model(&Log{}).Count(&count).Error)
+	require.Equal(t, int64(queued+1), count)
+}
+
+func TestAsyncLogWriterStopsWorkersOnTimeout(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&Log{}))
+
+	originalLogDB := LOG_DB
+	originalSize, originalWorkers := config.AsyncLogQueueSize, config.AsyncLogWorkers
+	LOG_DB = db
+	config.AsyncLogQueueSize, config.AsyncLogWorkers = 16, 1
+	t.Cleanup(func() {
+		LOG_DB = originalLogDB
+		config.AsyncLogQueueSize, config.AsyncLogWorkers = originalSize, originalWorkers
+		logWriteQueue.mu.Lock()
+		logWriteQueue.entries, logWriteQueue.closed = nil, false
+		logWriteQueue.stop, logWriteQueue.done = nil, nil
+		logWriteQueue.mu.Unlock()
+	})
+
+	// Hold the only connection so the worker blocks on its first write
+	conn, err := sqlDB.Conn(context.Background())
+	require.NoError(t, err)
+
+	InitAsyncLogWriter()
+	const queued = 10
+	for i := range queued {
+		recordLogHelper(context.Background(), &Log{UserId: i + 1, Type: LogTypeSystem, Content: "queued"})
+	}
+	require.Eventually(t, func() bool { return len(logWriteQueue.entries) == queued-1 }, time.Second, time.Millisecond)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = conn.Close()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.Equal(t, queued-1, StopAsyncLogWriter(ctx))
+
+	// The worker finished its in-flight write and wrote nothing afterwards
+	var count int64
+	require.NoError(t, LOG_DB.Model(&Log{}).Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
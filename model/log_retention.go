@@ -0,0 +1,125 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/metrics"
+)
+
+// logRetentionSweepInterval is how often each log type is swept. Each sweep
+// deletes in batches of config.RetentionBatchSize rows until the backlog is
+// cleared.
+const logRetentionSweepInterval = time.Hour
+
+// logRetentionBatchPause is the pause between the batches of one sweep, which
+// lets other queries take the table locks in between.
+var logRetentionBatchPause = time.Second
+
+// logRetentionPolicy is the retention of one log type.
+type logRetentionPolicy struct {
+	logType int
+	// name labels the type in logs and the one_api_log_retention_deleted_total metric.
+	name string
+	days int
+}
+
+func logRetentionPolicies() []logRetentionPolicy {
+	return []logRetentionPolicy{
+		{logType: LogTypeConsume, name: "consume", days: config.ConsumeLogRetentionDays},
+		{logType: LogTypeManage, name: "manage", days: config.ManageLogRetentionDays},
+		{logType: LogTypeSystem, name: "system", days: config.SystemLogRetentionDays},
+	}
+}
+
+// DeleteOldLogByType removes log entries of logType created before
+// targetTimestamp and returns the number deleted. At most
+// config.RetentionBatchSize entries are deleted per call so a large backlog
+// does not hold table locks for long; the remainder is left for later calls.
+func DeleteOldLogByType(logType int, targetTimestamp int64) (int64, error) {
+	var ids []int
+	if err := LOG_DB.Model(&Log{}).
+		Where("type = ? AND created_at < ?", logType, targetTimestamp).
+		Order("id").
+		Limit(config.RetentionBatchSize).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, errors.Wrapf(err, "find logs of type %d to delete", logType)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := LOG_DB.Where("id IN ?", ids).Delete(&Log{})
+	if result.Error != nil {
+		return 0, errors.Wrapf(result.Error, "delete logs of type %d", logType)
+	}
+	return result.RowsAffected, nil
+}
+
+// StartLogRetentionWorker launches a background worker per log type with a
+// positive retention, see config.ConsumeLogRetentionDays and its siblings.
+// Each type is swept on its own schedule so a backlog of one type does not
+// delay the others.
+func StartLogRetentionWorker(ctx context.Context) {
+	for _, policy := range logRetentionPolicies() {
+		if policy.days <= 0 {
+			logger.Logger.Debug("log retention disabled", zap.String("log_type", policy.name))
+			continue
+		}
+		go runLogRetention(ctx, policy)
+		logger.Logger.Info("log retention worker started",
+			zap.String("log_type", policy.name), zap.Int("retention_days", policy.days))
+	}
+}
+
+func runLogRetention(ctx context.Context, policy logRetentionPolicy) {
+	ticker := time.NewTicker(logRetentionSweepInterval)
+	defer ticker.Stop()
+	for {
+		sweepLogRetention(ctx, policy)
+		select {
+		case <-ctx.Done():
+			logger.Logger.Info("log retention worker stopped", zap.String("log_type", policy.name))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepLogRetention runs one deletion cycle of policy. It deletes batches of
+// config.RetentionBatchSize entries, pausing for logRetentionBatchPause in
+// between, until a short batch shows the backlog is cleared or ctx is done.
+func sweepLogRetention(ctx context.Context, policy logRetentionPolicy) {
+	cutoff := time.Now().UTC().Add(-time.Duration(policy.days) * 24 * time.Hour).Unix()
+	var deleted int64
+	for {
+		batch, err := DeleteOldLogByType(policy.logType, cutoff)
+		metrics.GlobalRecorder.RecordLogRetentionDeleted(policy.name, batch)
+		deleted += batch
+		if err != nil {
+			logger.Logger.Warn("log retention cleanup failed",
+				zap.String("log_type", policy.name), zap.Int64("deleted_rows", deleted), zap.Error(err))
+			return
+		}
+		if batch < int64(config.RetentionBatchSize) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(logRetentionBatchPause):
+		}
+	}
+
+	if deleted > 0 {
+		logger.Logger.Info("deleted expired log entries",
+			zap.String("log_type", policy.name), zap.Int64("deleted_rows", deleted), zap.Int("retention_days", policy.days))
+	} else {
+		logger.Logger.Debug("log retention cleanup completed", zap.String("log_type", policy.name))
+	}
+}
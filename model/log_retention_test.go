@@ -0,0 +1,80 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func TestDeleteOldLogByType(t *testing.T) {
+	setupLogExportDB(t)
+	originalBatchSize := config.RetentionBatchSize
+	config.RetentionBatchSize = 2
+	t.Cleanup(func() { config.RetentionBatchSize = originalBatchSize })
+
+	const cutoff = 1700000000
+	logs := []Log{
+		{Type: LogTypeConsume, CreatedAt: cutoff - 30},
+		{Type: LogTypeConsume, CreatedAt: cutoff - 20},
+		{Type: LogTypeConsume, CreatedAt: cutoff - 10},
+		{Type: LogTypeConsume, CreatedAt: cutoff + 10},
+		{Type: LogTypeManage, CreatedAt: cutoff - 10},
+	}
+	require.NoError(t, LOG_DB.Create(&logs).Error)
+
+	countType := func(logType int) int64 {
+		var count int64
+		require.NoError(t, LOG_DB.Model(&Log{}).Where("type = ?", logType).Count(&count).Error)
+		return count
+	}
+
+	// Each call deletes at most RetentionBatchSize entries, oldest first
+	deleted, err := DeleteOldLogByType(LogTypeConsume, cutoff)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, deleted)
+	require.EqualValues(t, 2, countType(LogTypeConsume))
+
+	deleted, err = DeleteOldLogByType(LogTypeConsume, cutoff)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, deleted)
+
+	deleted, err = DeleteOldLogByType(LogTypeConsume, cutoff)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+
+	// Newer consume logs and other log types are kept
+	require.EqualValues(t, 1, countType(LogTypeConsume))
+	require.EqualValues(t, 1, countType(LogTypeManage))
+}
+
+func TestSweepLogRetentionDrainsBacklog(t *testing.T) {
+	setupLogExportDB(t)
+	originalBatchSize, originalPause := config.RetentionBatchSize, logRetentionBatchPause
+	config.RetentionBatchSize = 2
+	logRetentionBatchPause = 0
+	t.Cleanup(func() {
+		config.RetentionBatchSize = originalBatchSize
+		logRetentionBatchPause = originalPause
+	})
+
+	expired := time.Now().Add(-48 * time.Hour).Unix()
+	logs := []Log{
+		{Type: LogTypeConsume, CreatedAt: expired},
+		{Type: LogTypeConsume, CreatedAt: expired},
+		{Type: LogTypeConsume, CreatedAt: expired},
+		{Type: LogTypeConsume, CreatedAt: expired},
+		{Type: LogTypeConsume, CreatedAt: expired},
+		{Type: LogTypeConsume, CreatedAt: time.Now().Unix()},
+	}
+	require.NoError(t, LOG_DB.Create(&logs).Error)
+
+	// One sweep deletes every expired entry across several batches
+	sweepLogRetention(context.Background(), logRetentionPolicy{logType: LogTypeConsume, name: "consume", days: 1})
+	var count int64
+	require.NoError(t, LOG_DB.Model(&Log{}).Where("type = ?", LogTypeConsume).Count(&count).Error)
+	require.EqualValues(t, 1, count)
+}
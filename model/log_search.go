@@ -0,0 +1,75 @@
+package model
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Laisky/errors/v2"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+// logContentFullTextIndex indexes logs.content for full-text search on MySQL
+// and PostgreSQL.
+const logContentFullTextIndex = "idx_logs_content_fulltext"
+
+// ensureLogContentFullTextIndex creates the full-text index used by
+// applyLogContentSearch. It is a no-op on SQLite, which keeps the LIKE scan.
+func ensureLogContentFullTextIndex(db *gorm.DB) error {
+	var ddl string
+	switch {
+	case common.UsingMySQL.Load():
+		// The ngram parser indexes text without word separators, such as
+		// CJK, and parts of identifiers like request IDs
+		ddl = "CREATE FULLTEXT INDEX " + logContentFullTextIndex + " ON logs (content) WITH PARSER ngram"
+	case common.UsingPostgreSQL.Load():
+		// The expression must match the one in applyLogContentSearch for the
+		// planner to use the index
+		ddl = "CREATE INDEX " + logContentFullTextIndex + " ON logs USING GIN (to_tsvector('simple', content))"
+	default:
+		return nil
+	}
+	if db.Migrator().HasIndex(&Log{}, logContentFullTextIndex) {
+		return nil
+	}
+	if err := db.Exec(ddl).Error; err != nil {
+		return errors.Wrap(err, "create log content full-text index")
+	}
+	return nil
+}
+
+// mysqlNgramTokenSize is MySQL's default ngram_token_size. Shorter keywords
+// have no ngram in the full-text index.
+const mysqlNgramTokenSize = 2
+
+// likeEscaper escapes the LIKE wildcards of a keyword, using ! as the escape
+// character, which needs no escaping in any supported engine's string literals.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// applyLogContentSearch restricts tx to logs whose content matches keyword.
+// With useFullText the match uses the engine's full-text index: MATCH ...
+// AGAINST on MySQL, with the keyword quoted as a phrase so boolean operators
+// in it are taken literally, and a tsvector query on PostgreSQL, which
+// matches whole words rather than substrings. SQLite, callers passing
+// useFullText false, and keywords MySQL cannot look up in its index fall back
+// to a LIKE scan. Compressed logs only keep a hash of their content and never
+// match.
+func applyLogContentSearch(tx *gorm.DB, keyword string, useFullText bool) *gorm.DB {
+	if keyword == "" {
+		return tx
+	}
+	tx = tx.Where("compressed = ?", false)
+	// Double quotes cannot be escaped inside a boolean mode phrase
+	phrase := strings.TrimSpace(strings.ReplaceAll(keyword, `"`, " "))
+	switch {
+	case useFullText && common.UsingMySQL.Load() && utf8.RuneCountInString(phrase) >= mysqlNgramTokenSize:
+		return tx.Where("MATCH(content) AGAINST(? IN BOOLEAN MODE)", `"`+phrase+`"`)
+	case useFullText && common.UsingPostgreSQL.Load():
+		// plainto_tsquery accepts arbitrary user input, unlike to_tsquery
+		// which rejects anything that is not valid tsquery syntax
+		return tx.Where("to_tsvector('simple', content) @@ plainto_tsquery('simple', ?)", keyword)
+	default:
+		return tx.Where("content LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(keyword)+"%")
+	}
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+func TestApplyLogContentSearchQueryPerEngine(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	originalMySQL, originalPostgreSQL := common.UsingMySQL.Load(), common.UsingPostgreSQL.Load()
+	t.Cleanup(func() {
+		common.UsingMySQL.Store(originalMySQL)
+		common.UsingPostgreSQL.Store(originalPostgreSQL)
+	})
+
+	cases := []struct {
+		name        string
+		mysql       bool
+		postgres    bool
+		useFullText bool
+		expected    string
+	}{
+		{"mysql", true, false, true, "MATCH(content) AGAINST(\"\"\"timeout\"\"\" IN BOOLEAN MODE)"},
+		{"postgres", false, true, true, "to_tsvector('simple', content) @@ plainto_tsquery('simple', \"timeout\")"},
+		{"sqlite", false, false, true, "content LIKE \"%timeout%\""},
+		{"mysql without full text", true, false, false, "content LIKE \"%timeout%\""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			common.UsingMySQL.Store(tc.mysql)
+			common.UsingPostgreSQL.Store(tc.postgres)
+			query := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				return applyLogContentSearch(tx.Model(&Log{}), "timeout", tc.useFullText).Find(&[]*Log{})
+			})
+			require.Contains(t, query, tc.expected)
+			require.Contains(t, query, "compressed = false")
+		})
+	}
+
+	// Boolean operators are quoted into a phrase, short keywords and
+	// wildcards go through an escaped LIKE scan
+	common.UsingMySQL.Store(true)
+	common.UsingPostgreSQL.Store(false)
+	for keyword, expected := range map[string]string{
+		`-error +"rate*`: "AGAINST(\"\"\"-error + rate*\"\"\" IN BOOLEAN MODE)",
+		"5":              "content LIKE \"%5%\" ESCAPE '!'",
+	} {
+		query := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return applyLogContentSearch(tx.Model(&Log{}), keyword, true).Find(&[]*Log{})
+		})
+		require.Contains(t, query, expected, keyword)
+	}
+	query := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return applyLogContentSearch(tx.Model(&Log{}), "50%_!", false).Find(&[]*Log{})
+	})
+	require.Contains(t, query, "content LIKE \"%50!%!_!!%\" ESCAPE '!'")
+
+	query = db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return applyLogContentSearch(tx.Model(&Log{}), "", true).Find(&[]*Log{})
+	})
+	require.NotContains(t, query, "WHERE")
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/metrics"
+)
+
+// LoginHistoryLimit is how many login history entries GetLoginLogs returns.
+const LoginHistoryLimit = 50
+
+// LoginLog records one password login attempt.
+type LoginLog struct {
+	Id int `json:"id" gorm:"primaryKey;autoIncrement"`
+	// UserId is 0 when the username matched no user.
+	UserId int `json:"user_id" gorm:"index:idx_login_logs_user_created,priority:1"`
+	// Username is the name the attempt was made with, which may also be an email.
+	Username      string `json:"username" gorm:"type:varchar(64);default:''"`
+	Ip            string `json:"ip" gorm:"type:varchar(64);default:''"`
+	UserAgent     string `json:"user_agent" gorm:"type:varchar(255);default:''"`
+	Success       bool   `json:"success"`
+	FailureReason string `json:"failure_reason" gorm:"type:varchar(255);default:''"`
+	CreatedAt     int64  `json:"created_at" gorm:"bigint;index:idx_login_logs_user_created,priority:2;index"`
+}
+
+// RecordLoginLog records a login attempt. failureReason is empty for
+// successful attempts. Failures are logged rather than returned so recording
+// never fails the login itself.
+func RecordLoginLog(ctx context.Context, userId int, username string, ip string, userAgent string, failureReason string) {
+	entry := &LoginLog{
+		UserId:        userId,
+		Username:      truncateRunes(username, 64),
+		Ip:            ip,
+		UserAgent:     truncateRunes(userAgent, 255),
+		Success:       failureReason == "",
+		FailureReason: truncateRunes(failureReason, 255),
+		CreatedAt:     helper.GetTimestamp(),
+	}
+	if err := DB.WithContext(context.WithoutCancel(ctx)).Create(entry).Error; err != nil {
+		logger.Logger.Error("failed to record login log",
+			zap.Int("user_id", userId),
+			zap.Bool("success", entry.Success),
+			zap.Error(err))
+	}
+}
+
+// GetLoginLogs returns the latest LoginHistoryLimit login attempts of a user,
+// newest first.
+func GetLoginLogs(ctx context.Context, userId int) ([]*LoginLog, error) {
+	var entries []*LoginLog
+	err := DB.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("created_at desc, id desc").
+		Limit(LoginHistoryLimit).
+		Find(&entries).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "get login history of user %d", userId)
+	}
+	return entries, nil
+}
+
+// DeleteOldLoginLogs removes login history entries created before
+// targetTimestamp, at most config.RetentionBatchSize per call, and returns
+// the number deleted.
+func DeleteOldLoginLogs(targetTimestamp int64) (int64, error) {
+	var ids []int
+	if err := DB.Model(&LoginLog{}).
+		Where("created_at < ?", targetTimestamp).
+		Order("id").
+		Limit(config.RetentionBatchSize).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, errors.Wrap(err, "find login logs to delete")
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := DB.Where("id IN ?", ids).Delete(&LoginLog{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "delete login logs")
+	}
+	return result.RowsAffected, nil
+}
+
+// StartLoginLogRetentionWorker launches a background worker deleting login
+// history older than config.LoginLogRetentionDays, see StartLogRetentionWorker.
+func StartLoginLogRetentionWorker(ctx context.Context) {
+	days := config.LoginLogRetentionDays
+	if days <= 0 {
+		logger.Logger.Debug("login log retention disabled")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(logRetentionSweepInterval)
+		defer ticker.Stop()
+		for {
+			sweepLoginLogRetention(days)
+			select {
+			case <-ctx.Done():
+				logger.Logger.Info("login log retention worker stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	logger.Logger.Info("login log retention worker started", zap.Int("retention_days", days))
+}
+
+func sweepLoginLogRetention(days int) {
+	cutoff := time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour).Unix()
+	deleted, err := DeleteOldLoginLogs(cutoff)
+	metrics.GlobalRecorder.RecordLogRetentionDeleted("login", deleted)
+	if err != nil {
+		logger.Logger.Warn("login log retention cleanup failed", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		logger.Logger.Info("deleted expired login log entries", zap.Int64("deleted_rows", deleted), zap.Int("retention_days", days))
+	}
+}
+
+// truncateRunes shortens s to at most n runes so it fits its column.
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
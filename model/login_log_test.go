@@ -0,0 +1,58 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func TestLoginLogs(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&LoginLog{}))
+	originalDB := DB
+	originalBatchSize := config.RetentionBatchSize
+	DB = db
+	config.RetentionBatchSize = 40
+	t.Cleanup(func() {
+		DB = originalDB
+		config.RetentionBatchSize = originalBatchSize
+	})
+	ctx := context.Background()
+
+	RecordLoginLog(ctx, 7, "alice", "10.0.0.1", "curl/8", "invalid TOTP code")
+	RecordLoginLog(ctx, 7, "alice", "10.0.0.1", "curl/8", "")
+	RecordLoginLog(ctx, 8, "bob", "10.0.0.2", "curl/8", "")
+
+	entries, err := GetLoginLogs(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.True(t, entries[0].Success)
+	require.False(t, entries[1].Success)
+	require.Equal(t, "invalid TOTP code", entries[1].FailureReason)
+	require.Equal(t, "10.0.0.1", entries[1].Ip)
+
+	for i := range LoginHistoryLimit + 10 {
+		require.NoError(t, db.Create(&LoginLog{UserId: 9, CreatedAt: int64(1000 + i)}).Error)
+	}
+	entries, err = GetLoginLogs(ctx, 9)
+	require.NoError(t, err)
+	require.Len(t, entries, LoginHistoryLimit)
+	require.EqualValues(t, 1000+LoginHistoryLimit+9, entries[0].CreatedAt)
+
+	// Deletion is capped per call
+	deleted, err := DeleteOldLoginLogs(2000)
+	require.NoError(t, err)
+	require.EqualValues(t, 40, deleted)
+	deleted, err = DeleteOldLoginLogs(2000)
+	require.NoError(t, err)
+	require.EqualValues(t, 20, deleted)
+	entries, err = GetLoginLogs(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
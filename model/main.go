@@ -14,6 +14,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/blacklist"
 	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/common/logger"
@@ -123,6 +124,11 @@ func InitDB() {
 		return
 	}
 
+	if err = useQueryStats(DB); err != nil {
+		logger.Logger.Fatal("failed to register statement timing", zap.Error(err))
+		return
+	}
+
 	if config.DebugSQLEnabled {
 		logger.Logger.Debug("debug sql enabled")
 		DB = DB.Debug()
@@ -196,6 +202,10 @@ func InitDB() {
 		logger.Logger.Error("failed to migrate legacy image pricing", zap.Error(err))
 	}
 
+	if err = MigrateChannelSSRFAllowlist(); err != nil {
+		logger.Logger.Error("failed to migrate channel SSRF allowlist", zap.Error(err))
+	}
+
 	logger.Logger.Info("database migration completed")
 }
 
@@ -222,6 +232,9 @@ func migrateDB() error {
 	if err = DB.AutoMigrate(&Log{}); err != nil {
 		return errors.Wrapf(err, "failed to migrate Log")
 	}
+	if err = ensureLogContentFullTextIndex(DB); err != nil {
+		return errors.Wrapf(err, "failed to migrate Log")
+	}
 	if err = DB.AutoMigrate(&TokenTransaction{}); err != nil {
 		return errors.Wrapf(err, "failed to migrate TokenTransaction")
 	}
@@ -234,6 +247,42 @@ func migrateDB() error {
 	if err = DB.AutoMigrate(&AsyncTaskBinding{}); err != nil {
 		return errors.Wrapf(err, "failed to migrate AsyncTaskBinding")
 	}
+	if err = DB.AutoMigrate(&RequestTrace{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate RequestTrace")
+	}
+	if err = DB.AutoMigrate(&ReplayCorpusEntry{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate ReplayCorpusEntry")
+	}
+	if err = DB.AutoMigrate(&TokenCountAccuracy{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate TokenCountAccuracy")
+	}
+	if err = DB.AutoMigrate(&ChannelPricingSnapshot{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate ChannelPricingSnapshot")
+	}
+	if err = DB.AutoMigrate(&RelayBatch{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate RelayBatch")
+	}
+	if err = DB.AutoMigrate(&BillingReconciliation{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate BillingReconciliation")
+	}
+	if err = DB.AutoMigrate(&CostAnomaly{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate CostAnomaly")
+	}
+	if err = DB.AutoMigrate(&UserSetting{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate UserSetting")
+	}
+	if err = DB.AutoMigrate(&ModelPricingOverride{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate ModelPricingOverride")
+	}
+	if err = DB.AutoMigrate(&AuditLog{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate AuditLog")
+	}
+	if err = DB.AutoMigrate(&LoginLog{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate LoginLog")
+	}
+	if err = DB.AutoMigrate(&blacklist.Entry{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate blacklist entries")
+	}
 	return nil
 }
 
@@ -250,6 +299,10 @@ func InitLogDB() {
 		logger.Logger.Fatal("failed to initialize secondary database", zap.Error(err))
 		return
 	}
+	if err = useQueryStats(LOG_DB); err != nil {
+		logger.Logger.Fatal("failed to register statement timing on secondary database", zap.Error(err))
+		return
+	}
 
 	setDBConns(LOG_DB)
 
@@ -271,6 +324,9 @@ func migrateLOGDB() error {
 	if err = LOG_DB.AutoMigrate(&Log{}); err != nil {
 		return errors.Wrap(err, "auto migrate log database")
 	}
+	if err = ensureLogContentFullTextIndex(LOG_DB); err != nil {
+		return errors.Wrap(err, "migrate log database")
+	}
 	return nil
 }
 
@@ -0,0 +1,177 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// ModelPricingOverride replaces the compiled pricing of the models matching
+// ModelPattern without a server restart. Overrides apply to every channel
+// unless the channel prices the model in its own model configs.
+type ModelPricingOverride struct {
+	Id int `json:"id" gorm:"primaryKey;autoIncrement"`
+	// ModelPattern is either an exact model name or a prefix ending in "*",
+	// such as "gpt-4o*". An exact pattern wins over prefixes, and a longer
+	// prefix wins over a shorter one.
+	ModelPattern    string  `json:"model_pattern" gorm:"type:varchar(255);uniqueIndex;not null"`
+	InputRatio      float64 `json:"input_ratio"`
+	CompletionRatio float64 `json:"completion_ratio"`
+	// CachedInputRatio is the per-token price of cached prompt tokens, 0 to
+	// bill them like other prompt tokens.
+	CachedInputRatio float64 `json:"cached_input_ratio"`
+	// MaxTokens is the output token limit of the model, 0 to keep the default.
+	MaxTokens int32  `json:"max_tokens"`
+	CreatedBy string `json:"created_by" gorm:"type:varchar(64)"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint;autoCreateTime"`
+}
+
+// TableName stores overrides in model_pricing_overrides.
+func (ModelPricingOverride) TableName() string {
+	return "model_pricing_overrides"
+}
+
+// Validate checks the pattern and that the ratios are positive.
+func (o *ModelPricingOverride) Validate() error {
+	o.ModelPattern = strings.TrimSpace(o.ModelPattern)
+	switch {
+	case o.ModelPattern == "" || o.ModelPattern == "*":
+		return errors.New("model_pattern is required")
+	case strings.Contains(strings.TrimSuffix(o.ModelPattern, "*"), "*"):
+		return errors.New("model_pattern may only end with *")
+	case o.InputRatio <= 0:
+		return errors.New("input_ratio must be positive")
+	case o.CompletionRatio <= 0:
+		return errors.New("completion_ratio must be positive")
+	case o.CachedInputRatio < 0:
+		return errors.New("cached_input_ratio must be positive or 0")
+	case o.MaxTokens < 0:
+		return errors.New("max_tokens must not be negative")
+	}
+	return nil
+}
+
+// GetAllModelPricingOverrides returns every override ordered by pattern.
+func GetAllModelPricingOverrides() ([]*ModelPricingOverride, error) {
+	var overrides []*ModelPricingOverride
+	if err := DB.Order("model_pattern").Find(&overrides).Error; err != nil {
+		return nil, errors.Wrap(err, "get model pricing overrides")
+	}
+	return overrides, nil
+}
+
+// GetModelPricingOverrideById returns the override with id.
+func GetModelPricingOverrideById(id int) (*ModelPricingOverride, error) {
+	override := &ModelPricingOverride{}
+	if err := DB.First(override, "id = ?", id).Error; err != nil {
+		return nil, errors.Wrapf(err, "get model pricing override %d", id)
+	}
+	return override, nil
+}
+
+// CreateModelPricingOverride validates and stores a new override.
+func CreateModelPricingOverride(override *ModelPricingOverride) error {
+	if err := override.Validate(); err != nil {
+		return err
+	}
+	if err := DB.Create(override).Error; err != nil {
+		return errors.Wrapf(err, "create model pricing override %q", override.ModelPattern)
+	}
+	return nil
+}
+
+// UpdateModelPricingOverride validates override and replaces the pattern,
+// ratios and max tokens of the stored row. The author and creation time are
+// kept.
+func UpdateModelPricingOverride(override *ModelPricingOverride) error {
+	if err := override.Validate(); err != nil {
+		return err
+	}
+	err := DB.Model(&ModelPricingOverride{}).
+		Where("id = ?", override.Id).
+		Select("model_pattern", "input_ratio", "completion_ratio", "cached_input_ratio", "max_tokens").
+		Updates(override).Error
+	if err != nil {
+		return errors.Wrapf(err, "update model pricing override %d", override.Id)
+	}
+	return nil
+}
+
+// DeleteModelPricingOverrideById removes the override with id.
+func DeleteModelPricingOverrideById(id int) error {
+	if err := DB.Delete(&ModelPricingOverride{}, "id = ?", id).Error; err != nil {
+		return errors.Wrapf(err, "delete model pricing override %d", id)
+	}
+	return nil
+}
+
+// modelPricingOverrides is the in-memory copy of the table read by pricing,
+// refreshed by LoadModelPricingOverrides.
+var modelPricingOverrides = struct {
+	sync.RWMutex
+	exact map[string]*ModelPricingOverride
+	// prefixes holds the "*" patterns, longest prefix first.
+	prefixes []*ModelPricingOverride
+}{}
+
+// LoadModelPricingOverrides replaces the in-memory overrides with the rows
+// of the table, so pricing changes apply without a restart.
+func LoadModelPricingOverrides() error {
+	overrides, err := GetAllModelPricingOverrides()
+	if err != nil {
+		return err
+	}
+
+	exact := make(map[string]*ModelPricingOverride, len(overrides))
+	var prefixes []*ModelPricingOverride
+	for _, override := range overrides {
+		if strings.HasSuffix(override.ModelPattern, "*") {
+			prefixes = append(prefixes, override)
+			continue
+		}
+		exact[override.ModelPattern] = override
+	}
+	slices.SortStableFunc(prefixes, func(a, b *ModelPricingOverride) int {
+		return len(b.ModelPattern) - len(a.ModelPattern)
+	})
+
+	modelPricingOverrides.Lock()
+	modelPricingOverrides.exact = exact
+	modelPricingOverrides.prefixes = prefixes
+	modelPricingOverrides.Unlock()
+	return nil
+}
+
+// SyncModelPricingOverrides reloads the overrides every frequency seconds.
+func SyncModelPricingOverrides(frequency int) {
+	for {
+		time.Sleep(time.Duration(frequency) * time.Second)
+		logger.Logger.Info("syncing model pricing overrides from database")
+		if err := LoadModelPricingOverrides(); err != nil {
+			logger.Logger.Error("failed to sync model pricing overrides", zap.Error(err))
+		}
+	}
+}
+
+// MatchModelPricingOverride returns a copy of the loaded override that
+// applies to modelName, or false when the model keeps its default pricing.
+func MatchModelPricingOverride(modelName string) (ModelPricingOverride, bool) {
+	modelPricingOverrides.RLock()
+	defer modelPricingOverrides.RUnlock()
+
+	if override, ok := modelPricingOverrides.exact[modelName]; ok {
+		return *override, true
+	}
+	for _, override := range modelPricingOverrides.prefixes {
+		if strings.HasPrefix(modelName, strings.TrimSuffix(override.ModelPattern, "*")) {
+			return *override, true
+		}
+	}
+	return ModelPricingOverride{}, false
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelPricingOverrideMatching(t *testing.T) {
+	testDB := setupTestDB(t)
+	require.NoError(t, testDB.AutoMigrate(&ModelPricingOverride{}))
+	originalDB := DB
+	DB = testDB
+	t.Cleanup(func() {
+		// Drop the loaded overrides before restoring the database
+		DB.Where("1 = 1").Delete(&ModelPricingOverride{})
+		require.NoError(t, LoadModelPricingOverrides())
+		DB = originalDB
+	})
+
+	for _, invalid := range []ModelPricingOverride{
+		{ModelPattern: "", InputRatio: 1, CompletionRatio: 1},
+		{ModelPattern: "gpt-*-mini", InputRatio: 1, CompletionRatio: 1},
+		{ModelPattern: "gpt-4o", InputRatio: 0, CompletionRatio: 1},
+		{ModelPattern: "gpt-4o", InputRatio: 1, CompletionRatio: -1},
+		{ModelPattern: "gpt-4o", InputRatio: 1, CompletionRatio: 1, CachedInputRatio: -1},
+	} {
+		require.Error(t, CreateModelPricingOverride(&invalid), invalid.ModelPattern)
+	}
+
+	require.NoError(t, CreateModelPricingOverride(&ModelPricingOverride{ModelPattern: "gpt-4*", InputRatio: 1, CompletionRatio: 2}))
+	require.NoError(t, CreateModelPricingOverride(&ModelPricingOverride{ModelPattern: "gpt-4o*", InputRatio: 3, CompletionRatio: 4}))
+	exact := &ModelPricingOverride{ModelPattern: " gpt-4o ", InputRatio: 5, CompletionRatio: 6, CreatedBy: "root"}
+	require.NoError(t, CreateModelPricingOverride(exact))
+	require.Equal(t, "gpt-4o", exact.ModelPattern)
+
+	// Nothing applies until the overrides are loaded
+	_, ok := MatchModelPricingOverride("gpt-4o")
+	require.False(t, ok)
+	require.NoError(t, LoadModelPricingOverrides())
+
+	for modelName, ratio := range map[string]float64{"gpt-4o": 5, "gpt-4o-mini": 3, "gpt-4.1": 1} {
+		override, ok := MatchModelPricingOverride(modelName)
+		require.True(t, ok, modelName)
+		require.Equal(t, ratio, override.InputRatio, modelName)
+	}
+	_, ok = MatchModelPricingOverride("claude-3-opus")
+	require.False(t, ok)
+
+	exact.InputRatio = 7
+	require.NoError(t, UpdateModelPricingOverride(exact))
+	require.NoError(t, DeleteModelPricingOverrideById(1))
+	require.NoError(t, LoadModelPricingOverrides())
+
+	override, ok := MatchModelPricingOverride("gpt-4o")
+	require.True(t, ok)
+	require.Equal(t, 7.0, override.InputRatio)
+	require.Equal(t, "root", override.CreatedBy)
+	_, ok = MatchModelPricingOverride("gpt-4.1")
+	require.False(t, ok)
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"strconv"
 	"strings"
 	"time"
@@ -36,6 +37,7 @@ func InitOptionMap() {
 	config.OptionMap["EmailVerificationEnabled"] = strconv.FormatBool(config.EmailVerificationEnabled)
 	config.OptionMap["GitHubOAuthEnabled"] = strconv.FormatBool(config.GitHubOAuthEnabled)
 	config.OptionMap["OidcEnabled"] = strconv.FormatBool(config.OidcEnabled)
+	config.OptionMap["GoogleOAuthEnabled"] = strconv.FormatBool(config.GoogleOAuthEnabled)
 	config.OptionMap["WeChatAuthEnabled"] = strconv.FormatBool(config.WeChatAuthEnabled)
 	config.OptionMap["TurnstileCheckEnabled"] = strconv.FormatBool(config.TurnstileCheckEnabled)
 	config.OptionMap["RegisterEnabled"] = strconv.FormatBool(config.RegisterEnabled)
@@ -48,6 +50,9 @@ func InitOptionMap() {
 	config.OptionMap["ChannelDisableThreshold"] = strconv.FormatFloat(config.ChannelDisableThreshold, 'f', -1, 64)
 	config.OptionMap["EmailDomainRestrictionEnabled"] = strconv.FormatBool(config.EmailDomainRestrictionEnabled)
 	config.OptionMap["EmailDomainWhitelist"] = strings.Join(config.EmailDomainWhitelist, ",")
+	config.OptionMap["EmailDomainBlocklistEnabled"] = strconv.FormatBool(config.EmailDomainBlocklistEnabled)
+	config.OptionMap["EmailDomainBlocklist"] = strings.Join(config.EmailDomainBlocklist, ",")
+	config.OptionMap["ChannelSSRFAllowedHosts"] = strings.Join(config.ChannelSSRFAllowedHosts, ",")
 	config.OptionMap["SMTPServer"] = ""
 	config.OptionMap["SMTPFrom"] = ""
 	config.OptionMap["SMTPPort"] = strconv.Itoa(config.SMTPPort)
@@ -62,6 +67,8 @@ func InitOptionMap() {
 	config.OptionMap["ServerAddress"] = ""
 	config.OptionMap["GitHubClientId"] = ""
 	config.OptionMap["GitHubClientSecret"] = ""
+	config.OptionMap["GoogleClientId"] = ""
+	config.OptionMap["GoogleClientSecret"] = ""
 	config.OptionMap["LarkClientId"] = config.LarkClientId
 	config.OptionMap["LarkClientSecret"] = ""
 	config.OptionMap["OidcClientId"] = config.OidcClientId
@@ -75,6 +82,7 @@ func InitOptionMap() {
 	config.OptionMap["WeChatAccountQRCodeImageURL"] = ""
 	config.OptionMap["MessagePusherAddress"] = ""
 	config.OptionMap["MessagePusherToken"] = ""
+	config.OptionMap["ExternalBillingWebhookURL"] = ""
 	config.OptionMap["TurnstileSiteKey"] = ""
 	config.OptionMap["TurnstileSecretKey"] = ""
 	config.OptionMap["QuotaForNewUser"] = strconv.FormatInt(config.QuotaForNewUser, 10)
@@ -83,6 +91,7 @@ func InitOptionMap() {
 	config.OptionMap["QuotaRemindThreshold"] = strconv.FormatInt(config.QuotaRemindThreshold, 10)
 	config.OptionMap["PreConsumedQuota"] = strconv.FormatInt(config.PreConsumedQuota, 10)
 	config.OptionMap["GroupRatio"] = billingratio.GroupRatio2JSONString()
+	config.OptionMap["GroupTier"] = billingratio.GroupTier2JSONString()
 	config.OptionMap["TopUpLink"] = config.TopUpLink
 	config.OptionMap["ChatLink"] = config.ChatLink
 	config.OptionMap["QuotaPerUnit"] = strconv.FormatFloat(config.QuotaPerUnit, 'f', -1, 64)
@@ -106,6 +115,26 @@ func loadOptionsFromDatabase() {
 	}
 }
 
+// ReloadOptionMapLocked re-applies every option stored in the database to
+// OptionMap and the config variables it drives. The caller must hold
+// config.OptionMapRWMutex for writing.
+func ReloadOptionMapLocked() error {
+	options, err := AllOption()
+	if err != nil {
+		return errors.Wrap(err, "load options")
+	}
+	for _, option := range options {
+		// Skip deprecated global pricing options
+		if option.Key == "ModelRatio" || option.Key == "CompletionRatio" {
+			continue
+		}
+		if err := applyOption(option.Key, option.Value); err != nil {
+			logger.Logger.Error("failed to update option map", zap.Error(err))
+		}
+	}
+	return nil
+}
+
 func SyncOptions(frequency int) {
 	for {
 		time.Sleep(time.Duration(frequency) * time.Second)
@@ -114,6 +143,19 @@ func SyncOptions(frequency int) {
 	}
 }
 
+// GetOptionUpdatedAt returns when the option key was last saved, in Unix
+// seconds, or 0 when it was never saved.
+func GetOptionUpdatedAt(ctx context.Context, key string) (int64, error) {
+	var updatedAt int64
+	if err := DB.WithContext(ctx).Model(&Option{}).
+		Select("COALESCE(MAX(updated_at), 0)").
+		Where(&Option{Key: key}).
+		Scan(&updatedAt).Error; err != nil {
+		return 0, errors.Wrapf(err, "get updated time of option %s", key)
+	}
+	return updatedAt / 1000, nil
+}
+
 func UpdateOption(key string, value string) error {
 	// Save to database first
 	option := Option{
@@ -130,9 +172,15 @@ func UpdateOption(key string, value string) error {
 	return updateOptionMap(key, value)
 }
 
-func updateOptionMap(key string, value string) (err error) {
+func updateOptionMap(key string, value string) error {
 	config.OptionMapRWMutex.Lock()
 	defer config.OptionMapRWMutex.Unlock()
+	return applyOption(key, value)
+}
+
+// applyOption stores value in OptionMap and updates the config variable
+// behind key. The caller must hold config.OptionMapRWMutex for writing.
+func applyOption(key string, value string) (err error) {
 	config.OptionMap[key] = value
 	if strings.HasSuffix(key, "Enabled") {
 		boolValue := value == "true"
@@ -147,6 +195,8 @@ func updateOptionMap(key string, value string) (err error) {
 			config.GitHubOAuthEnabled = boolValue
 		case "OidcEnabled":
 			config.OidcEnabled = boolValue
+		case "GoogleOAuthEnabled":
+			config.GoogleOAuthEnabled = boolValue
 		case "WeChatAuthEnabled":
 			config.WeChatAuthEnabled = boolValue
 		case "TurnstileCheckEnabled":
@@ -155,6 +205,8 @@ func updateOptionMap(key string, value string) (err error) {
 			config.RegisterEnabled = boolValue
 		case "EmailDomainRestrictionEnabled":
 			config.EmailDomainRestrictionEnabled = boolValue
+		case "EmailDomainBlocklistEnabled":
+			config.EmailDomainBlocklistEnabled = boolValue
 		case "AutomaticDisableChannelEnabled":
 			config.AutomaticDisableChannelEnabled = boolValue
 		case "AutomaticEnableChannelEnabled":
@@ -170,8 +222,18 @@ func updateOptionMap(key string, value string) (err error) {
 		}
 	}
 	switch key {
+	case "ChannelSSRFAllowedHosts":
+		config.ChannelSSRFAllowedHosts = config.ParseHostList(value)
 	case "EmailDomainWhitelist":
 		config.EmailDomainWhitelist = strings.Split(value, ",")
+	case "EmailDomainBlocklist":
+		blocklist := make([]string, 0)
+		for _, domain := range strings.Split(value, ",") {
+			if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+				blocklist = append(blocklist, domain)
+			}
+		}
+		config.EmailDomainBlocklist = blocklist
 	case "SMTPServer":
 		config.SMTPServer = value
 	case "SMTPPort":
@@ -189,6 +251,10 @@ func updateOptionMap(key string, value string) (err error) {
 		config.GitHubClientId = value
 	case "GitHubClientSecret":
 		config.GitHubClientSecret = value
+	case "GoogleClientId":
+		config.GoogleClientId = value
+	case "GoogleClientSecret":
+		config.GoogleClientSecret = value
 	case "LarkClientId":
 		config.LarkClientId = value
 	case "LarkClientSecret":
@@ -221,6 +287,8 @@ func updateOptionMap(key string, value string) (err error) {
 		config.MessagePusherAddress = value
 	case "MessagePusherToken":
 		config.MessagePusherToken = value
+	case "ExternalBillingWebhookURL":
+		config.ExternalBillingWebhookURL = strings.TrimSpace(value)
 	case "TurnstileSiteKey":
 		config.TurnstileSiteKey = value
 	case "TurnstileSecretKey":
@@ -242,6 +310,8 @@ func updateOptionMap(key string, value string) (err error) {
 		return nil
 	case "GroupRatio":
 		err = billingratio.UpdateGroupRatioByJSONString(value)
+	case "GroupTier":
+		err = billingratio.UpdateGroupTierByJSONString(value)
 	case "TopUpLink":
 		config.TopUpLink = value
 	case "ChatLink":
@@ -254,7 +324,7 @@ func updateOptionMap(key string, value string) (err error) {
 		config.Theme = value
 	}
 	if err != nil {
-		return errors.Wrap(err, "update group ratio configuration")
+		return errors.Wrapf(err, "update option %s", key)
 	}
 	return nil
 }
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// queryStatsPluginName is the name QueryStatsRecorder registers under.
+	queryStatsPluginName = "query-stats-recorder"
+	// queryStatsStartKey holds the start time of the statement being timed.
+	queryStatsStartKey = "query_stats:start_time"
+	// maxQueryPatterns bounds the distinct statements tracked per database;
+	// statements first seen beyond it are not tracked.
+	maxQueryPatterns = 1000
+)
+
+// analyzableVerbs are the statement kinds QueryStatsRecorder tracks.
+var analyzableVerbs = []string{"SELECT", "WITH", "UPDATE", "DELETE"}
+
+// QueryPatternStats aggregates the executions of one SQL statement pattern.
+// Patterns are the statements GORM builds, with bound values as placeholders.
+type QueryPatternStats struct {
+	SQL      string        // Statement with placeholders for bound values
+	VarCount int           // Number of bound values
+	Count    int64         // Executions since startup
+	Total    time.Duration // Total execution time
+	Max      time.Duration // Slowest execution
+}
+
+// QueryStatsRecorder is a GORM plugin timing the statements executed on a
+// database, for SlowQueryAnalyzer backends without a server-side slow query log.
+type QueryStatsRecorder struct {
+	mu       sync.Mutex
+	patterns map[string]*QueryPatternStats
+}
+
+// Name implements gorm.Plugin.
+func (r *QueryStatsRecorder) Name() string {
+	return queryStatsPluginName
+}
+
+// Initialize implements gorm.Plugin. Inserts are not recorded since they never
+// scan a table.
+func (r *QueryStatsRecorder) Initialize(db *gorm.DB) error {
+	r.patterns = make(map[string]*QueryPatternStats)
+
+	db.Callback().Query().Before("gorm:query").Register("query_stats:before_query", r.before)
+	db.Callback().Update().Before("gorm:update").Register("query_stats:before_update", r.before)
+	db.Callback().Delete().Before("gorm:delete").Register("query_stats:before_delete", r.before)
+	db.Callback().Row().Before("gorm:row").Register("query_stats:before_row", r.before)
+	db.Callback().Raw().Before("gorm:raw").Register("query_stats:before_raw", r.before)
+
+	db.Callback().Query().After("gorm:query").Register("query_stats:after_query", r.after)
+	db.Callback().Update().After("gorm:update").Register("query_stats:after_update", r.after)
+	db.Callback().Delete().After("gorm:delete").Register("query_stats:after_delete", r.after)
+	db.Callback().Row().After("gorm:row").Register("query_stats:after_row", r.after)
+	db.Callback().Raw().After("gorm:raw").Register("query_stats:after_raw", r.after)
+
+	return nil
+}
+
+func (r *QueryStatsRecorder) before(db *gorm.DB) {
+	db.Set(queryStatsStartKey, time.Now())
+}
+
+func (r *QueryStatsRecorder) after(db *gorm.DB) {
+	v, ok := db.Get(queryStatsStartKey)
+	if !ok || db.Statement == nil {
+		return
+	}
+	start, ok := v.(time.Time)
+	if !ok {
+		return
+	}
+	r.record(db.Statement.SQL.String(), len(db.Statement.Vars), time.Since(start))
+}
+
+// record adds one execution of sql, bound to varCount values, that took elapsed.
+func (r *QueryStatsRecorder) record(sql string, varCount int, elapsed time.Duration) {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if sql == "" {
+		return
+	}
+	// Only statements reading rows have a plan worth explaining; this also
+	// skips migrations and the analyzer's own EXPLAIN statements
+	verb, _, _ := strings.Cut(strings.ToUpper(sql), " ")
+	if !slices.Contains(analyzableVerbs, verb) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.patterns[sql]
+	if !ok {
+		if len(r.patterns) >= maxQueryPatterns {
+			return
+		}
+		stats = &QueryPatternStats{SQL: sql, VarCount: varCount}
+		r.patterns[sql] = stats
+	}
+	stats.Count++
+	stats.Total += elapsed
+	stats.Max = max(stats.Max, elapsed)
+}
+
+// MostExecuted returns up to limit patterns whose slowest execution took at
+// least threshold, most executed first.
+func (r *QueryStatsRecorder) MostExecuted(threshold time.Duration, limit int) []QueryPatternStats {
+	r.mu.Lock()
+	patterns := make([]QueryPatternStats, 0, len(r.patterns))
+	for _, stats := range r.patterns {
+		if stats.Max >= threshold {
+			patterns = append(patterns, *stats)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Count != patterns[j].Count {
+			return patterns[i].Count > patterns[j].Count
+		}
+		return patterns[i].SQL < patterns[j].SQL
+	})
+	if len(patterns) > limit {
+		patterns = patterns[:limit]
+	}
+	return patterns
+}
+
+// useQueryStats registers a QueryStatsRecorder on db when it is SQLite, the
+// only backend whose SlowQueryAnalyzer reads it. Elsewhere the callbacks would
+// time every statement for nothing.
+func useQueryStats(db *gorm.DB) error {
+	if db.Dialector.Name() != "sqlite" {
+		return nil
+	}
+	return db.Use(&QueryStatsRecorder{})
+}
+
+// queryStatsOf returns the QueryStatsRecorder registered on db, if any.
+func queryStatsOf(db *gorm.DB) *QueryStatsRecorder {
+	if db == nil || db.Config == nil {
+		return nil
+	}
+	recorder, _ := db.Config.Plugins[queryStatsPluginName].(*QueryStatsRecorder)
+	return recorder
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/message"
+)
+
+// LogMetadataKeyQuotaOverage marks consume logs of requests that spent past
+// the user's remaining quota.
+const LogMetadataKeyQuotaOverage = "overage"
+
+// QuotaWithOverage returns the most a user with quota remaining may spend,
+// the remaining quota plus config.QuotaOveragePercent of it.
+func QuotaWithOverage(quota int64) int64 {
+	if quota <= 0 || config.QuotaOveragePercent <= 0 {
+		return quota
+	}
+	return quota + int64(float64(quota)*config.QuotaOveragePercent/100)
+}
+
+// minQuotaForCharge returns the smallest remaining quota that may be charged
+// amount, the inverse of QuotaWithOverage.
+func minQuotaForCharge(amount int64) int64 {
+	if amount <= 0 || config.QuotaOveragePercent <= 0 {
+		return amount
+	}
+	return int64(math.Ceil(float64(amount) / (1 + config.QuotaOveragePercent/100)))
+}
+
+// AppendQuotaOverageMetadata marks the consume log as overage.
+func AppendQuotaOverageMetadata(metadata LogMetadata) LogMetadata {
+	if metadata == nil {
+		metadata = LogMetadata{}
+	}
+	metadata[LogMetadataKeyQuotaOverage] = true
+	return metadata
+}
+
+// NotifyQuotaOverdrawn tells the administrators that the user's balance went
+// negative by spending into the overage allowance.
+func NotifyQuotaOverdrawn(userId int, balance int64) {
+	username := GetUsernameById(userId)
+	go func() {
+		title := fmt.Sprintf("User %s overdrew their quota", username)
+		content := fmt.Sprintf("User %s (id %d) spent past their quota, the balance is now %s.",
+			username, userId, common.LogQuota(balance))
+		if err := message.Notify(message.ByAll, title, "", content); err != nil {
+			logger.Logger.Error("failed to send quota overdrawn notification", zap.Int("user_id", userId), zap.Error(err))
+		}
+	}()
+}
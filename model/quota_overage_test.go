@@ -0,0 +1,59 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func TestQuotaWithOverage(t *testing.T) {
+	originalPercent := config.QuotaOveragePercent
+	t.Cleanup(func() { config.QuotaOveragePercent = originalPercent })
+
+	config.QuotaOveragePercent = 0
+	require.EqualValues(t, 1000, QuotaWithOverage(1000))
+	require.EqualValues(t, 1000, minQuotaForCharge(1000))
+
+	config.QuotaOveragePercent = 10
+	require.EqualValues(t, 1100, QuotaWithOverage(1000))
+	require.EqualValues(t, -5, QuotaWithOverage(-5))
+	require.EqualValues(t, 1000, minQuotaForCharge(1100))
+}
+
+func TestDecreaseUserQuotaWithOverage(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&User{}))
+	originalDB := DB
+	originalPercent := config.QuotaOveragePercent
+	originalBatch := config.BatchUpdateEnabled
+	DB = db
+	config.BatchUpdateEnabled = false
+	t.Cleanup(func() {
+		DB = originalDB
+		config.QuotaOveragePercent = originalPercent
+		config.BatchUpdateEnabled = originalBatch
+	})
+
+	user := &User{Username: "overage", AccessToken: "t1", AffCode: "a1", Quota: 1000}
+	require.NoError(t, db.Create(user).Error)
+	ctx := context.Background()
+
+	config.QuotaOveragePercent = 0
+	require.Error(t, decreaseUserQuota(ctx, user.Id, 1050))
+
+	config.QuotaOveragePercent = 10
+	require.Error(t, decreaseUserQuota(ctx, user.Id, 1101))
+	require.NoError(t, decreaseUserQuota(ctx, user.Id, 1050))
+	quota, err := GetUserQuota(user.Id)
+	require.NoError(t, err)
+	require.EqualValues(t, -50, quota)
+
+	// A negative balance leaves no overage allowance
+	require.Error(t, decreaseUserQuota(ctx, user.Id, 1))
+}
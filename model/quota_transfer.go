@@ -0,0 +1,88 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Laisky/errors/v2"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+var (
+	// ErrQuotaTransferInsufficient is returned when the source user has less
+	// quota than the transfer amount.
+	ErrQuotaTransferInsufficient = errors.New("insufficient quota for transfer")
+	// ErrQuotaTransferTarget is returned when the destination user does not
+	// exist or is not enabled.
+	ErrQuotaTransferTarget = errors.New("transfer destination user not found or disabled")
+)
+
+// TransferUserQuota moves amount quota from the user fromId to the user toId
+// in a single transaction, so either both balances change or neither does.
+// The amount must be at least config.MinQuotaTransferAmount. Each side gets
+// a manage log entry recording its balance change and note.
+func TransferUserQuota(ctx context.Context, fromId int, toId int, amount int64, note string) error {
+	if fromId == toId {
+		return errors.New("cannot transfer quota to the same user")
+	}
+	if amount < config.MinQuotaTransferAmount {
+		return errors.Errorf("transfer amount must be at least %d", config.MinQuotaTransferAmount)
+	}
+
+	var fromQuota, toQuota int64
+	err := runWithSQLiteBusyRetry(ctx, func() error {
+		return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&User{}).
+				Where("id = ? AND quota >= ?", fromId, amount).
+				Update("quota", gorm.Expr("quota - ?", amount))
+			if result.Error != nil {
+				return errors.Wrapf(result.Error, "deduct quota from user %d", fromId)
+			}
+			if result.RowsAffected == 0 {
+				return ErrQuotaTransferInsufficient
+			}
+			result = tx.Model(&User{}).
+				Where("id = ? AND status = ?", toId, UserStatusEnabled).
+				Update("quota", gorm.Expr("quota + ?", amount))
+			if result.Error != nil {
+				return errors.Wrapf(result.Error, "add quota to user %d", toId)
+			}
+			if result.RowsAffected == 0 {
+				return ErrQuotaTransferTarget
+			}
+
+			if err := tx.Model(&User{}).Where("id = ?", fromId).Select("quota").Scan(&fromQuota).Error; err != nil {
+				return errors.Wrapf(err, "get quota of user %d", fromId)
+			}
+			if err := tx.Model(&User{}).Where("id = ?", toId).Select("quota").Scan(&toQuota).Error; err != nil {
+				return errors.Wrapf(err, "get quota of user %d", toId)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, userId := range []int{fromId, toId} {
+		invalidateCachedUserQuota(ctx, userId)
+	}
+	PublishQuotaAdjustment(fromId, -amount)
+	PublishQuotaAdjustment(toId, amount)
+
+	RecordManageLog(ctx, fromId, "quota", common.LogQuota(fromQuota+amount), common.LogQuota(fromQuota),
+		quotaTransferNote(fmt.Sprintf("transfer to user_id=%d", toId), note))
+	RecordManageLog(ctx, toId, "quota", common.LogQuota(toQuota-amount), common.LogQuota(toQuota),
+		quotaTransferNote(fmt.Sprintf("transfer from user_id=%d", fromId), note))
+	return nil
+}
+
+func quotaTransferNote(direction string, note string) string {
+	if note == "" {
+		return direction
+	}
+	return direction + ": " + note
+}
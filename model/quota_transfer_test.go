@@ -0,0 +1,64 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func TestTransferUserQuota(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&User{}, &Log{}))
+	originalDB, originalLogDB := DB, LOG_DB
+	originalRedisEnabled := common.IsRedisEnabled()
+	originalMin := config.MinQuotaTransferAmount
+	DB, LOG_DB = db, db
+	common.SetRedisEnabled(false)
+	config.MinQuotaTransferAmount = 100
+	t.Cleanup(func() {
+		DB, LOG_DB = originalDB, originalLogDB
+		common.SetRedisEnabled(originalRedisEnabled)
+		config.MinQuotaTransferAmount = originalMin
+	})
+
+	users := []*User{
+		{Username: "from", AccessToken: "t1", AffCode: "a1", Status: UserStatusEnabled, Quota: 1000},
+		{Username: "to", AccessToken: "t2", AffCode: "a2", Status: UserStatusEnabled, Quota: 50},
+		{Username: "disabled", AccessToken: "t3", AffCode: "a3", Status: UserStatusDisabled, Quota: 0},
+	}
+	require.NoError(t, db.Create(&users).Error)
+	from, to, disabled := users[0].Id, users[1].Id, users[2].Id
+	quotaOf := func(id int) int64 {
+		quota, err := GetUserQuota(id)
+		require.NoError(t, err)
+		return quota
+	}
+	ctx := context.Background()
+
+	require.NoError(t, TransferUserQuota(ctx, from, to, 400, "monthly share"))
+	require.EqualValues(t, 600, quotaOf(from))
+	require.EqualValues(t, 450, quotaOf(to))
+
+	var logs []Log
+	require.NoError(t, db.Where("type = ?", LogTypeManage).Order("id").Find(&logs).Error)
+	require.Len(t, logs, 2)
+	require.Equal(t, from, logs[0].UserId)
+	require.Contains(t, logs[0].Content, "monthly share")
+	require.Equal(t, to, logs[1].UserId)
+
+	// Failed transfers leave both balances untouched
+	require.ErrorIs(t, TransferUserQuota(ctx, from, to, 5000, ""), ErrQuotaTransferInsufficient)
+	require.ErrorIs(t, TransferUserQuota(ctx, from, disabled, 100, ""), ErrQuotaTransferTarget)
+	require.Error(t, TransferUserQuota(ctx, from, to, 99, ""))
+	require.Error(t, TransferUserQuota(ctx, from, from, 100, ""))
+	require.EqualValues(t, 600, quotaOf(from))
+	require.EqualValues(t, 450, quotaOf(to))
+	require.EqualValues(t, 0, quotaOf(disabled))
+}
@@ -26,9 +26,13 @@ type Redemption struct {
 	Quota        int64  `json:"quota" gorm:"bigint;default:100"`
 	CreatedTime  int64  `json:"created_time" gorm:"bigint"`
 	RedeemedTime int64  `json:"redeemed_time" gorm:"bigint"`
-	Count        int    `json:"count" gorm:"-:all"` // only for api request
-	CreatedAt    int64  `json:"created_at" gorm:"bigint;autoCreateTime:milli"`
-	UpdatedAt    int64  `json:"updated_at" gorm:"bigint;autoUpdateTime:milli"`
+	// ExpiredTime is the Unix time after which the code can no longer be redeemed, 0 never expires.
+	ExpiredTime int64 `json:"expired_time" gorm:"bigint;default:0"`
+	// BatchId groups codes generated together by CreateRedemptionBatch.
+	BatchId   string `json:"batch_id" gorm:"type:varchar(32);index;default:''"`
+	Count     int    `json:"count" gorm:"-:all"` // only for api request
+	CreatedAt int64  `json:"created_at" gorm:"bigint;autoCreateTime:milli"`
+	UpdatedAt int64  `json:"updated_at" gorm:"bigint;autoUpdateTime:milli"`
 }
 
 func GetAllRedemptions(startIdx int, num int) ([]*Redemption, error) {
@@ -95,6 +99,9 @@ func Redeem(ctx context.Context, key string, userId int) (quota int64, err error
 		if redemption.Status != RedemptionCodeStatusEnabled {
 			return errors.New("The redemption code has been used")
 		}
+		if redemption.ExpiredTime != 0 && helper.GetTimestamp() > redemption.ExpiredTime {
+			return errors.New("The redemption code has expired")
+		}
 		err = tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota + ?", redemption.Quota)).Error
 		if err != nil {
 			return errors.Wrapf(err, "increase user %d quota with redemption", userId)
@@ -109,6 +116,7 @@ func Redeem(ctx context.Context, key string, userId int) (quota int64, err error
 	if err != nil {
 		return 0, errors.Wrap(err, "Redeem failed")
 	}
+	PublishQuotaAdjustment(userId, redemption.Quota)
 	RecordLog(ctx, userId, LogTypeTopup, fmt.Sprintf("Recharged %s using redemption code", common.LogQuota(redemption.Quota)))
 	return redemption.Quota, nil
 }
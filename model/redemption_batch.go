@@ -0,0 +1,63 @@
+package model
+
+import (
+	"context"
+
+	"github.com/Laisky/errors/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxRedemptionBatchSize is the largest number of codes one batch may create.
+	MaxRedemptionBatchSize = 10000
+	// redemptionInsertChunkSize keeps each INSERT below the bind variable
+	// limits of SQLite and PostgreSQL.
+	redemptionInsertChunkSize = 500
+)
+
+// RedemptionBatchStats summarizes the codes of a redemption batch.
+type RedemptionBatchStats struct {
+	BatchId  string `json:"batch_id"`
+	Total    int64  `json:"total"`
+	Redeemed int64  `json:"redeemed"`
+}
+
+// CreateRedemptionBatch stores all redemptions in a single transaction, so a
+// batch is either created completely or not at all. A duplicated key violates
+// the unique index and rolls the whole batch back.
+func CreateRedemptionBatch(ctx context.Context, redemptions []*Redemption) error {
+	if len(redemptions) == 0 {
+		return errors.New("redemption batch is empty")
+	}
+	if len(redemptions) > MaxRedemptionBatchSize {
+		return errors.Errorf("redemption batch exceeds %d codes", MaxRedemptionBatchSize)
+	}
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(redemptions, redemptionInsertChunkSize).Error
+	})
+	if err != nil {
+		return errors.Wrapf(err, "insert batch of %d redemptions", len(redemptions))
+	}
+	return nil
+}
+
+// GetRedemptionBatchStats counts the codes of a batch and how many of them
+// have been redeemed. It returns gorm.ErrRecordNotFound for unknown batches.
+func GetRedemptionBatchStats(ctx context.Context, batchId string) (*RedemptionBatchStats, error) {
+	if batchId == "" {
+		return nil, errors.New("batch id is empty")
+	}
+	stats := &RedemptionBatchStats{}
+	err := DB.WithContext(ctx).Model(&Redemption{}).
+		Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS redeemed", RedemptionCodeStatusUsed).
+		Where("batch_id = ?", batchId).
+		Scan(stats).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "count redemptions of batch %s", batchId)
+	}
+	if stats.Total == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	stats.BatchId = batchId
+	return stats, nil
+}
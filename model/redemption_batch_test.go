@@ -0,0 +1,87 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/random"
+)
+
+func newTestRedemptionBatch(n int, batchId string, expiredTime int64) []*Redemption {
+	redemptions := make([]*Redemption, n)
+	for i := range redemptions {
+		redemptions[i] = &Redemption{
+			Name:        "test-batch",
+			Key:         random.GetUUID(),
+			Status:      RedemptionCodeStatusEnabled,
+			Quota:       5000,
+			CreatedTime: helper.GetTimestamp(),
+			ExpiredTime: expiredTime,
+			BatchId:     batchId,
+		}
+	}
+	return redemptions
+}
+
+func TestCreateRedemptionBatch_UniqueCodes(t *testing.T) {
+	setupTestDatabase(t)
+	ctx := context.Background()
+	batchId := random.GetUUID()
+
+	require.NoError(t, CreateRedemptionBatch(ctx, newTestRedemptionBatch(MaxRedemptionBatchSize, batchId, 0)))
+
+	var total, distinct int64
+	require.NoError(t, DB.Model(&Redemption{}).Where("batch_id = ?", batchId).Count(&total).Error)
+	require.NoError(t, DB.Model(&Redemption{}).Where("batch_id = ?", batchId).Distinct("key").Count(&distinct).Error)
+	require.EqualValues(t, MaxRedemptionBatchSize, total)
+	require.Equal(t, total, distinct)
+
+	stats, err := GetRedemptionBatchStats(ctx, batchId)
+	require.NoError(t, err)
+	require.EqualValues(t, MaxRedemptionBatchSize, stats.Total)
+	require.Zero(t, stats.Redeemed)
+
+	require.Error(t, CreateRedemptionBatch(ctx, newTestRedemptionBatch(MaxRedemptionBatchSize+1, random.GetUUID(), 0)))
+}
+
+func TestCreateRedemptionBatch_DuplicateRollsBack(t *testing.T) {
+	setupTestDatabase(t)
+	ctx := context.Background()
+	batchId := random.GetUUID()
+
+	redemptions := newTestRedemptionBatch(10, batchId, 0)
+	redemptions[9].Key = redemptions[0].Key
+	require.Error(t, CreateRedemptionBatch(ctx, redemptions))
+
+	_, err := GetRedemptionBatchStats(ctx, batchId)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestRedeemBatchCode(t *testing.T) {
+	setupTestDatabase(t)
+	ctx := context.Background()
+	user := &User{Username: "test-redeem-" + random.GetRandomString(6), Password: "password123",
+		AccessToken: random.GetRandomString(32), AffCode: random.GetRandomString(8), Status: UserStatusEnabled}
+	require.NoError(t, DB.Create(user).Error)
+
+	batchId := random.GetUUID()
+	redemptions := newTestRedemptionBatch(3, batchId, helper.GetTimestamp()+3600)
+	redemptions[2].ExpiredTime = helper.GetTimestamp() - 1
+	require.NoError(t, CreateRedemptionBatch(ctx, redemptions))
+
+	quota, err := Redeem(ctx, redemptions[0].Key, user.Id)
+	require.NoError(t, err)
+	require.EqualValues(t, 5000, quota)
+
+	_, err = Redeem(ctx, redemptions[2].Key, user.Id)
+	require.ErrorContains(t, err, "expired")
+
+	stats, err := GetRedemptionBatchStats(ctx, batchId)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, stats.Total)
+	require.EqualValues(t, 1, stats.Redeemed)
+}
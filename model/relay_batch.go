@@ -0,0 +1,175 @@
+package model
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+)
+
+// AsyncTaskTypeBatchInputFile marks async task bindings of uploaded Batch API
+// input files, pinning later batch creation to the channel holding the file.
+const AsyncTaskTypeBatchInputFile = "batch_input_file"
+
+// Upstream batch statuses that no longer change.
+const (
+	RelayBatchStatusCompleted = "completed"
+	RelayBatchStatusFailed    = "failed"
+	RelayBatchStatusExpired   = "expired"
+	RelayBatchStatusCancelled = "cancelled"
+)
+
+// RelayBatch tracks a Batch API batch created through the relay until it is
+// finished upstream and billed.
+type RelayBatch struct {
+	Id          int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	BatchId     string `json:"batch_id" gorm:"size:191;uniqueIndex;not null"`
+	UserId      int    `json:"user_id" gorm:"index;not null"`
+	TokenId     int    `json:"token_id"`
+	TokenName   string `json:"token_name" gorm:"size:64"`
+	ChannelId   int    `json:"channel_id" gorm:"index;not null"`
+	OriginModel string `json:"origin_model" gorm:"size:128"`
+	ActualModel string `json:"actual_model" gorm:"size:128"`
+	Endpoint    string `json:"endpoint" gorm:"size:64"`
+	// GroupRatio is the group ratio at creation, applied when the batch is billed.
+	GroupRatio       float64 `json:"group_ratio"`
+	InputFileId      string  `json:"input_file_id" gorm:"size:191"`
+	OutputFileId     string  `json:"output_file_id" gorm:"size:191;index"`
+	ErrorFileId      string  `json:"error_file_id" gorm:"size:191;index"`
+	Status           string  `json:"status" gorm:"size:32;index"`
+	RequestCount     int     `json:"request_count"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Quota            int64   `json:"quota"`
+	// PreConsumedQuota is the estimated quota reserved when the batch was
+	// created, reconciled against Quota when the batch is settled.
+	PreConsumedQuota int64 `json:"pre_consumed_quota"`
+	CreatedAt        int64 `json:"created_at" gorm:"bigint"`
+	UpdatedAt        int64 `json:"updated_at" gorm:"bigint"`
+	// SettledAt is when the finished batch was billed, 0 while it is pending.
+	SettledAt int64 `json:"settled_at" gorm:"bigint;index"`
+}
+
+// TableName stores batches in relay_batches.
+func (RelayBatch) TableName() string {
+	return "relay_batches"
+}
+
+// IsFinished reports whether the upstream batch reached a final status.
+func (b *RelayBatch) IsFinished() bool {
+	return slices.Contains([]string{
+		RelayBatchStatusCompleted,
+		RelayBatchStatusFailed,
+		RelayBatchStatusExpired,
+		RelayBatchStatusCancelled,
+	}, b.Status)
+}
+
+// CreateRelayBatch stores a newly created batch.
+func CreateRelayBatch(ctx context.Context, batch *RelayBatch) error {
+	if batch.BatchId == "" {
+		return errors.New("relay batch requires batch id")
+	}
+	now := time.Now().UTC().Unix()
+	batch.CreatedAt = now
+	batch.UpdatedAt = now
+	if err := DB.WithContext(ctx).Create(batch).Error; err != nil {
+		return errors.Wrapf(err, "create relay batch %s", batch.BatchId)
+	}
+	return nil
+}
+
+// GetRelayBatch returns the batch with the upstream id batchId.
+func GetRelayBatch(ctx context.Context, batchId string) (*RelayBatch, error) {
+	batch := &RelayBatch{}
+	if err := DB.WithContext(ctx).Where("batch_id = ?", batchId).First(batch).Error; err != nil {
+		return nil, errors.Wrapf(err, "get relay batch %s", batchId)
+	}
+	return batch, nil
+}
+
+// GetRelayBatchByFileId returns the batch whose output or error file is fileId.
+func GetRelayBatchByFileId(ctx context.Context, fileId string) (*RelayBatch, error) {
+	batch := &RelayBatch{}
+	err := DB.WithContext(ctx).
+		Where("output_file_id = ? OR error_file_id = ?", fileId, fileId).
+		First(batch).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "get relay batch of file %s", fileId)
+	}
+	return batch, nil
+}
+
+// GetUnsettledRelayBatches returns up to limit batches that were not billed
+// yet, least recently polled first. Pollers touch every batch they check, see
+// TouchRelayBatch, so batches that keep failing cannot starve the others.
+func GetUnsettledRelayBatches(ctx context.Context, limit int) ([]*RelayBatch, error) {
+	var batches []*RelayBatch
+	err := DB.WithContext(ctx).
+		Where("settled_at = ?", 0).
+		Order("updated_at, id").
+		Limit(limit).
+		Find(&batches).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list unsettled relay batches")
+	}
+	return batches, nil
+}
+
+// UpdateRelayBatchStatus records the upstream status and result files of a batch.
+func UpdateRelayBatchStatus(ctx context.Context, batch *RelayBatch) error {
+	batch.UpdatedAt = time.Now().UTC().Unix()
+	err := DB.WithContext(ctx).Model(&RelayBatch{}).
+		Where("id = ?", batch.Id).
+		Updates(map[string]any{
+			"status":         batch.Status,
+			"output_file_id": batch.OutputFileId,
+			"error_file_id":  batch.ErrorFileId,
+			"updated_at":     batch.UpdatedAt,
+		}).Error
+	if err != nil {
+		return errors.Wrapf(err, "update relay batch %s", batch.BatchId)
+	}
+	return nil
+}
+
+// TouchRelayBatch marks an unsettled batch as just polled, moving it to the
+// back of GetUnsettledRelayBatches.
+func TouchRelayBatch(ctx context.Context, batch *RelayBatch) error {
+	now := time.Now().UTC().Unix()
+	err := DB.WithContext(ctx).Model(&RelayBatch{}).
+		Where("id = ? AND settled_at = ?", batch.Id, 0).
+		Update("updated_at", now).Error
+	if err != nil {
+		return errors.Wrapf(err, "touch relay batch %s", batch.BatchId)
+	}
+	batch.UpdatedAt = now
+	return nil
+}
+
+// SettleRelayBatch records the usage and quota of a finished batch. It
+// returns false without changes when the batch was already settled, so that
+// concurrent pollers bill every batch exactly once.
+func SettleRelayBatch(ctx context.Context, batch *RelayBatch) (bool, error) {
+	now := time.Now().UTC().Unix()
+	tx := DB.WithContext(ctx).Model(&RelayBatch{}).
+		Where("id = ? AND settled_at = ?", batch.Id, 0).
+		Updates(map[string]any{
+			"status":            batch.Status,
+			"prompt_tokens":     batch.PromptTokens,
+			"completion_tokens": batch.CompletionTokens,
+			"quota":             batch.Quota,
+			"settled_at":        now,
+			"updated_at":        now,
+		})
+	if tx.Error != nil {
+		return false, errors.Wrapf(tx.Error, "settle relay batch %s", batch.BatchId)
+	}
+	if tx.RowsAffected == 0 {
+		return false, nil
+	}
+	batch.SettledAt = now
+	batch.UpdatedAt = now
+	return true, nil
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"context"
+
+	"github.com/Laisky/errors/v2"
+)
+
+// ReplayCorpusEntry is a relay request sampled from production traffic
+// together with the response it received. The `replay` command of cmd/test
+// replays the corpus against a test instance and compares the structure of
+// the new responses with the recorded ones. Entries are sampled at
+// config.ReplayCorpusSampleRate and never store credentials.
+type ReplayCorpusEntry struct {
+	Id             int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ModelName      string `json:"model_name" gorm:"type:varchar(255);index"`
+	Method         string `json:"method" gorm:"type:varchar(16);not null"`
+	URL            string `json:"url" gorm:"type:text;not null"`
+	RequestBody    string `json:"request_body" gorm:"type:text"`
+	ResponseStatus int    `json:"response_status"`
+	ResponseBody   string `json:"response_body" gorm:"type:text"`
+	CreatedAt      int64  `json:"created_at" gorm:"bigint;autoCreateTime:milli;index"`
+}
+
+// TableName keeps the corpus in a single replay_corpus table.
+func (ReplayCorpusEntry) TableName() string {
+	return "replay_corpus"
+}
+
+// ReplayCorpusFilter selects a subset of the replay corpus. Empty fields do
+// not filter; Limit <= 0 loads every matching entry.
+type ReplayCorpusFilter struct {
+	ModelName string
+	URL       string
+	Limit     int
+}
+
+// CreateReplayCorpusEntry persists a sampled request. Sampling is best-effort,
+// so callers should log rather than surface the returned error.
+func CreateReplayCorpusEntry(ctx context.Context, entry *ReplayCorpusEntry) error {
+	if entry == nil {
+		return errors.New("replay corpus entry is nil")
+	}
+	if err := traceDBWithContext(ctx).Create(entry).Error; err != nil {
+		return errors.Wrapf(err, "create replay corpus entry for model: %s", entry.ModelName)
+	}
+	return nil
+}
+
+// GetReplayCorpus loads the entries matching filter, oldest first.
+func GetReplayCorpus(ctx context.Context, filter ReplayCorpusFilter) ([]ReplayCorpusEntry, error) {
+	query := traceDBWithContext(ctx).Order("id asc")
+	if filter.ModelName != "" {
+		query = query.Where("model_name = ?", filter.ModelName)
+	}
+	if filter.URL != "" {
+		query = query.Where("url = ?", filter.URL)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var entries []ReplayCorpusEntry
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, errors.Wrap(err, "get replay corpus")
+	}
+	return entries, nil
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Laisky/errors/v2"
+)
+
+// RequestTrace stores the full request of a failed relay call so that it can be
+// replayed later for debugging. Only requests that failed with a 5xx status are
+// captured, and only when config.RequestReplayCaptureEnabled is set.
+//
+// Successful requests on channels with StoreReasoningTrace enabled also get a
+// row holding only the reasoning the model returned, looked up by request ID.
+type RequestTrace struct {
+	Id             int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	TraceId        string `json:"trace_id" gorm:"type:varchar(64);uniqueIndex;not null"`
+	RequestId      string `json:"request_id" gorm:"type:varchar(64);index"`
+	UserId         int    `json:"user_id" gorm:"index"`
+	TokenId        int    `json:"token_id"`
+	ChannelId      int    `json:"channel_id" gorm:"index"`
+	ModelName      string `json:"model_name" gorm:"type:varchar(255)"`
+	Method         string `json:"method" gorm:"type:varchar(16);not null"`
+	URL            string `json:"url" gorm:"type:text;not null"`
+	Headers        string `json:"headers" gorm:"type:text"` // JSON object of replay-safe request headers
+	RequestBody    string `json:"request_body" gorm:"type:text"`
+	ResponseStatus int    `json:"response_status"`
+	ResponseBody   string `json:"response_body" gorm:"type:text"` // possibly truncated
+	Reasoning      string `json:"reasoning" gorm:"type:text"`     // plain-text reasoning of the model
+	CreatedAt      int64  `json:"created_at" gorm:"bigint;autoCreateTime:milli;index"`
+}
+
+// CreateRequestTrace persists a captured request. Captures are best-effort
+// diagnostics, so callers should log rather than surface the returned error.
+func CreateRequestTrace(ctx context.Context, trace *RequestTrace) error {
+	if trace == nil {
+		return errors.New("request trace is nil")
+	}
+	if err := traceDBWithContext(ctx).Create(trace).Error; err != nil {
+		return errors.Wrapf(err, "create request trace for trace_id: %s", trace.TraceId)
+	}
+	return nil
+}
+
+// GetRequestTraceByTraceId loads the captured request for traceId.
+func GetRequestTraceByTraceId(ctx context.Context, traceId string) (*RequestTrace, error) {
+	var trace RequestTrace
+	if err := traceDBWithContext(ctx).Where("trace_id = ?", traceId).First(&trace).Error; err != nil {
+		return nil, errors.Wrapf(err, "get request trace by trace_id: %s", traceId)
+	}
+	return &trace, nil
+}
+
+// GetReasoningTraceByRequestId loads the stored reasoning of requestId.
+func GetReasoningTraceByRequestId(ctx context.Context, requestId string) (*RequestTrace, error) {
+	var trace RequestTrace
+	if err := traceDBWithContext(ctx).
+		Where("request_id = ? AND reasoning <> ''", requestId).
+		Order("id desc").
+		First(&trace).Error; err != nil {
+		return nil, errors.Wrapf(err, "get reasoning trace by request_id: %s", requestId)
+	}
+	return &trace, nil
+}
+
+// GetHeaders decodes the stored request headers.
+func (t *RequestTrace) GetHeaders() (http.Header, error) {
+	headers := make(http.Header)
+	if t.Headers == "" {
+		return headers, nil
+	}
+	if err := json.Unmarshal([]byte(t.Headers), &headers); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal headers of request trace %s", t.TraceId)
+	}
+	return headers, nil
+}
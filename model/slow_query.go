@@ -0,0 +1,257 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"gorm.io/gorm"
+)
+
+// sqliteAnalyzedPatterns caps the statement patterns the SQLite analyzer
+// explains per call.
+const sqliteAnalyzedPatterns = 10
+
+// SlowQuery is a slow statement with its query plan and index suggestions.
+type SlowQuery struct {
+	SQL         string   `json:"sql"`
+	Count       int64    `json:"count"`
+	AvgMs       float64  `json:"avg_ms"`
+	MaxMs       float64  `json:"max_ms"`
+	Plan        []string `json:"plan,omitempty"`
+	PlanError   string   `json:"plan_error,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// SlowQueryAnalyzer finds the slow statements of one database backend and
+// suggests indexes for the tables they scan.
+type SlowQueryAnalyzer interface {
+	// SlowQueries returns up to limit statements that took at least threshold.
+	SlowQueries(ctx context.Context, threshold time.Duration, limit int) ([]SlowQuery, error)
+}
+
+// NewSlowQueryAnalyzer returns the analyzer for the backend of db. MySQL reports
+// the statements running in information_schema.processlist; SQLite reports the
+// most executed statements timed by QueryStatsRecorder.
+func NewSlowQueryAnalyzer(db *gorm.DB) (SlowQueryAnalyzer, error) {
+	switch name := db.Dialector.Name(); name {
+	case "mysql":
+		return &mysqlSlowQueryAnalyzer{db: db}, nil
+	case "sqlite":
+		stats := queryStatsOf(db)
+		if stats == nil {
+			return nil, errors.New("statement timing is not enabled on this database")
+		}
+		return &sqliteSlowQueryAnalyzer{db: db, stats: stats}, nil
+	default:
+		return nil, errors.Errorf("slow query analysis is not supported on %s", name)
+	}
+}
+
+// sqliteSlowQueryAnalyzer explains the most executed slow statement patterns
+// with EXPLAIN QUERY PLAN.
+type sqliteSlowQueryAnalyzer struct {
+	db    *gorm.DB
+	stats *QueryStatsRecorder
+}
+
+func (a *sqliteSlowQueryAnalyzer) SlowQueries(ctx context.Context, threshold time.Duration, limit int) ([]SlowQuery, error) {
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "get sqlite connection")
+	}
+
+	patterns := a.stats.MostExecuted(threshold, min(limit, sqliteAnalyzedPatterns))
+	queries := make([]SlowQuery, 0, len(patterns))
+	for _, pattern := range patterns {
+		query := SlowQuery{
+			SQL:   pattern.SQL,
+			Count: pattern.Count,
+			AvgMs: durationMs(pattern.Total) / float64(pattern.Count),
+			MaxMs: durationMs(pattern.Max),
+		}
+		var scanned []string
+		query.Plan, scanned, err = explainSQLiteQuery(ctx, sqlDB, pattern.SQL, pattern.VarCount)
+		if err != nil {
+			query.PlanError = err.Error()
+		}
+		query.Suggestions = suggestIndexes(pattern.SQL, scanned)
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
+
+// sqliteTableScan matches a plan step reading every row of a table; scans
+// through an index read "SCAN t USING INDEX ..." instead.
+var sqliteTableScan = regexp.MustCompile(`^SCAN (?:TABLE )?(\w+)$`)
+
+// explainSQLiteQuery returns the plan steps of query, whose varCount
+// placeholders are bound to NULL, and the tables it scans.
+func explainSQLiteQuery(ctx context.Context, sqlDB *sql.DB, query string, varCount int) (plan []string, scanned []string, err error) {
+	rows, err := sqlDB.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, make([]any, varCount)...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "explain query plan")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err = rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			return nil, nil, errors.Wrap(err, "scan query plan")
+		}
+		plan = append(plan, detail)
+		if m := sqliteTableScan.FindStringSubmatch(detail); m != nil {
+			scanned = append(scanned, m[1])
+		}
+	}
+	return plan, scanned, errors.Wrap(rows.Err(), "read query plan")
+}
+
+// mysqlSlowQueryAnalyzer explains the statements that have been running for at
+// least the threshold, as listed in information_schema.processlist.
+type mysqlSlowQueryAnalyzer struct {
+	db *gorm.DB
+}
+
+func (a *mysqlSlowQueryAnalyzer) SlowQueries(ctx context.Context, threshold time.Duration, limit int) ([]SlowQuery, error) {
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "get mysql connection")
+	}
+	rows, err := sqlDB.QueryContext(ctx,
+		"SELECT TIME, INFO FROM information_schema.processlist"+
+			" WHERE COMMAND = 'Query' AND INFO IS NOT NULL AND ID <> CONNECTION_ID() AND TIME * 1000 >= ?"+
+			" ORDER BY TIME DESC LIMIT ?",
+		threshold.Milliseconds(), limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "query processlist")
+	}
+	var queries []SlowQuery
+	for rows.Next() {
+		var seconds int64
+		var info string
+		if err = rows.Scan(&seconds, &info); err != nil {
+			rows.Close()
+			return nil, errors.Wrap(err, "scan processlist")
+		}
+		elapsedMs := float64(seconds * 1000)
+		queries = append(queries, SlowQuery{SQL: strings.Join(strings.Fields(info), " "), Count: 1, AvgMs: elapsedMs, MaxMs: elapsedMs})
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "read processlist")
+	}
+
+	for i := range queries {
+		// EXPLAIN only accepts data manipulation statements
+		verb, _, _ := strings.Cut(strings.ToUpper(queries[i].SQL), " ")
+		if !slices.Contains(analyzableVerbs, verb) {
+			continue
+		}
+		var scanned []string
+		queries[i].Plan, scanned, err = explainMySQLQuery(ctx, sqlDB, queries[i].SQL)
+		if err != nil {
+			queries[i].PlanError = err.Error()
+		}
+		queries[i].Suggestions = suggestIndexes(queries[i].SQL, scanned)
+	}
+	return queries, nil
+}
+
+// explainMySQLQuery returns one plan step per table accessed by query and the
+// tables it reads with a full table scan (access type ALL).
+func explainMySQLQuery(ctx context.Context, sqlDB *sql.DB, query string) (plan []string, scanned []string, err error) {
+	rows, err := sqlDB.QueryContext(ctx, "EXPLAIN "+query)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "explain")
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read explain columns")
+	}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, nil, errors.Wrap(err, "scan explain")
+		}
+		step := make(map[string]string, len(columns))
+		for i, column := range columns {
+			step[strings.ToLower(column)] = values[i].String
+		}
+		plan = append(plan, fmt.Sprintf("table=%s type=%s key=%s rows=%s", step["table"], step["type"], step["key"], step["rows"]))
+		if step["type"] == "ALL" && step["table"] != "" {
+			scanned = append(scanned, step["table"])
+		}
+	}
+	return plan, scanned, errors.Wrap(rows.Err(), "read explain")
+}
+
+var (
+	// whereClause captures the filter of a statement, up to the clauses that follow it.
+	whereClause = regexp.MustCompile(`(?is)\bWHERE\b(.*?)(?:\bGROUP\s+BY\b|\bORDER\s+BY\b|\bHAVING\b|\bLIMIT\b|$)`)
+	// columnCondition matches a possibly table-qualified column compared by an
+	// operator an index can serve.
+	columnCondition = regexp.MustCompile("(?i)(?:[`\"]?(\\w+)[`\"]?\\.)?[`\"]?([A-Za-z_]\\w*)[`\"]?\\s*(>=|<=|=|<|>|\\bIN\\b|\\bBETWEEN\\b|\\bLIKE\\b)")
+)
+
+// conditionKeywords are words the column pattern can mistake for columns.
+var conditionKeywords = map[string]bool{"AND": true, "OR": true, "NOT": true, "WHERE": true}
+
+// suggestIndexes suggests an index for every table in scanned, on the columns
+// the WHERE clause of query filters it by: equality columns first, then range
+// columns, since an index serves a range only on its last used column.
+func suggestIndexes(query string, scanned []string) []string {
+	if len(scanned) == 0 {
+		return nil
+	}
+	where := whereClause.FindStringSubmatch(query)
+	if where == nil {
+		return nil
+	}
+	conditions := columnCondition.FindAllStringSubmatch(where[1], -1)
+
+	var suggestions []string
+	for _, table := range scanned {
+		var equality, ranged []string
+		for _, condition := range conditions {
+			qualifier, column, operator := condition[1], condition[2], strings.ToUpper(condition[3])
+			if conditionKeywords[strings.ToUpper(column)] {
+				continue
+			}
+			if qualifier != "" && !strings.EqualFold(qualifier, table) {
+				continue
+			}
+			if slices.Contains(equality, column) || slices.Contains(ranged, column) {
+				continue
+			}
+			if operator == "=" || operator == "IN" {
+				equality = append(equality, column)
+			} else {
+				ranged = append(ranged, column)
+			}
+		}
+		columns := append(equality, ranged...)
+		if len(columns) == 0 {
+			continue
+		}
+		suggestion := fmt.Sprintf("Consider adding index on %s(%s)", table, strings.Join(columns, ", "))
+		if !slices.Contains(suggestions, suggestion) {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
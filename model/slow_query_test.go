@@ -0,0 +1,97 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type slowQueryItem struct {
+	Id        int
+	OwnerId   int
+	Status    int `gorm:"index"`
+	CreatedAt int64
+}
+
+func TestSQLiteSlowQueryAnalyzer_SuggestsIndexForTableScan(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, useQueryStats(db))
+	require.NoError(t, db.AutoMigrate(&slowQueryItem{}))
+	require.NoError(t, db.Create(&[]slowQueryItem{{OwnerId: 1, Status: 1, CreatedAt: 10}, {OwnerId: 2, Status: 1, CreatedAt: 20}}).Error)
+
+	// Filters on unindexed columns scan the whole table
+	for range 3 {
+		var items []slowQueryItem
+		require.NoError(t, db.Where("owner_id = ? AND created_at >= ?", 1, 5).Find(&items).Error)
+	}
+	// The status index serves this one
+	var items []slowQueryItem
+	require.NoError(t, db.Where("status = ?", 1).Find(&items).Error)
+
+	analyzer, err := NewSlowQueryAnalyzer(db)
+	require.NoError(t, err)
+	queries, err := analyzer.SlowQueries(context.Background(), 0, 20)
+	require.NoError(t, err)
+
+	var scan, indexed *SlowQuery
+	for i := range queries {
+		switch queries[i].SQL {
+		case "SELECT * FROM `slow_query_items` WHERE owner_id = ? AND created_at >= ?":
+			scan = &queries[i]
+		case "SELECT * FROM `slow_query_items` WHERE status = ?":
+			indexed = &queries[i]
+		}
+	}
+	require.NotNil(t, scan)
+	require.EqualValues(t, 3, scan.Count)
+	require.Empty(t, scan.PlanError)
+	require.Contains(t, scan.Plan, "SCAN slow_query_items")
+	require.Equal(t, []string{"Consider adding index on slow_query_items(owner_id, created_at)"}, scan.Suggestions)
+	require.NotNil(t, indexed)
+	require.Empty(t, indexed.Suggestions)
+	// Most executed first
+	require.Equal(t, scan.SQL, queries[0].SQL)
+
+	// Nothing is that slow
+	queries, err = analyzer.SlowQueries(context.Background(), time.Hour, 20)
+	require.NoError(t, err)
+	require.Empty(t, queries)
+}
+
+func TestSuggestIndexes(t *testing.T) {
+	require.Equal(t,
+		[]string{"Consider adding index on logs(user_id, type, created_at)"},
+		suggestIndexes("SELECT * FROM logs WHERE created_at >= ? AND `logs`.`user_id` = ? AND type IN (?,?) ORDER BY id DESC LIMIT 10", []string{"logs"}))
+	// Columns of other tables are not suggested
+	require.Equal(t,
+		[]string{"Consider adding index on tokens(name)"},
+		suggestIndexes("SELECT * FROM tokens JOIN users ON users.id = tokens.user_id WHERE users.status = ? AND tokens.name LIKE ?", []string{"tokens"}))
+	require.Nil(t, suggestIndexes("SELECT * FROM logs", []string{"logs"}))
+	require.Nil(t, suggestIndexes("SELECT * FROM logs WHERE user_id = ?", nil))
+}
+
+func TestNewSlowQueryAnalyzer_RequiresStatementTiming(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	_, err = NewSlowQueryAnalyzer(db)
+	require.Error(t, err)
+}
+
+func TestUseQueryStats_OnlyOnSQLite(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, useQueryStats(db))
+	require.NotNil(t, queryStatsOf(db))
+
+	db, err = gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:1)/oneapi", SkipInitializeWithVersion: true}),
+		&gorm.Config{DisableAutomaticPing: true})
+	require.NoError(t, err)
+	require.NoError(t, useQueryStats(db))
+	require.Nil(t, queryStatsOf(db))
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/blacklist"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/statesync"
+)
+
+func init() {
+	statesync.Handle(statesync.EventChannelSuspended, func(_ context.Context, event statesync.Event) {
+		suspendCachedAbility(event.Group, event.Model, event.ChannelId, time.UnixMilli(event.SuspendUntil))
+	})
+	statesync.Handle(statesync.EventUserBanned, func(ctx context.Context, event statesync.Event) {
+		persistBan(ctx, event.UserId)
+	})
+	statesync.Handle(statesync.EventUserUnbanned, func(_ context.Context, event statesync.Event) {
+		blacklist.UnbanUser(event.UserId)
+	})
+	statesync.Handle(statesync.EventQuotaAdjusted, func(ctx context.Context, event statesync.Event) {
+		invalidateCachedUserQuota(ctx, event.UserId)
+	})
+	statesync.Handle(statesync.EventIPBanned, func(_ context.Context, event statesync.Event) {
+		applyIPBan(event.IP, event.BanUntil)
+	})
+	statesync.Handle(statesync.EventIPUnbanned, func(_ context.Context, event statesync.Event) {
+		blacklist.UnbanIP(event.IP)
+	})
+}
+
+// BanUser blocks the user in the blacklist, persisting the ban so it survives
+// restarts, and announces the ban to the other regions.
+func BanUser(id int) {
+	persistBan(context.Background(), id)
+	statesync.Publish(statesync.Event{Type: statesync.EventUserBanned, UserId: id})
+}
+
+// persistBan bans the user in memory and in the database. A failed write is
+// logged: the in-memory ban still blocks the user until the next restart.
+func persistBan(ctx context.Context, id int) {
+	if err := blacklist.BanUserPersistent(ctx, id); err != nil {
+		logger.Logger.Error("failed to persist user ban",
+			zap.Int("user_id", id),
+			zap.Error(err))
+	}
+}
+
+// UnbanUser lifts the ban of the user, including the persisted one, and
+// announces it to the other regions.
+func UnbanUser(id int) {
+	blacklist.UnbanUser(id)
+	statesync.Publish(statesync.Event{Type: statesync.EventUserUnbanned, UserId: id})
+}
+
+// BanIP blocks the client IP until expiry, or forever when expiry is not
+// positive, and announces the ban to the other regions.
+func BanIP(ip string, expiry time.Duration) {
+	blacklist.BanIP(ip, expiry)
+	var until int64
+	if expiry > 0 {
+		until = time.Now().Add(expiry).UnixMilli()
+	}
+	statesync.Publish(statesync.Event{Type: statesync.EventIPBanned, IP: ip, BanUntil: until})
+}
+
+// applyIPBan bans ip until the unix milliseconds until, or forever when until
+// is zero. A ban that already lapsed in transit is ignored.
+func applyIPBan(ip string, until int64) {
+	if until == 0 {
+		blacklist.BanIP(ip, 0)
+		return
+	}
+	if remaining := time.Until(time.UnixMilli(until)); remaining > 0 {
+		blacklist.BanIP(ip, remaining)
+	}
+}
+
+// UnbanIP lifts the ban of the client IP and announces it to the other regions.
+func UnbanIP(ip string) {
+	blacklist.UnbanIP(ip)
+	statesync.Publish(statesync.Event{Type: statesync.EventIPUnbanned, IP: ip})
+}
+
+// publishChannelSuspension announces an ability suspension to the other regions.
+func publishChannelSuspension(group string, modelName string, channelId int, until time.Time) {
+	statesync.Publish(statesync.Event{
+		Type:         statesync.EventChannelSuspended,
+		Group:        group,
+		Model:        modelName,
+		ChannelId:    channelId,
+		SuspendUntil: until.UnixMilli(),
+	})
+}
+
+// PublishQuotaAdjustment announces quota changes larger than
+// config.StateSyncQuotaThreshold to the other regions.
+func PublishQuotaAdjustment(userId int, delta int64) {
+	if max(delta, -delta) <= config.StateSyncQuotaThreshold {
+		return
+	}
+	statesync.Publish(statesync.Event{Type: statesync.EventQuotaAdjusted, UserId: userId, QuotaDelta: delta})
+}
+
+// invalidateCachedUserQuota drops the cached quota of the user so that the next
+// read reloads it.
+func invalidateCachedUserQuota(ctx context.Context, userId int) {
+	if !common.IsRedisEnabled() {
+		return
+	}
+	if err := common.RedisDel(ctx, fmt.Sprintf("user_quota:%d", userId)); err != nil {
+		logger.Logger.Warn("failed to invalidate cached user quota",
+			zap.Int("user_id", userId),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/blacklist"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/statesync"
+)
+
+// startStateSyncNodes connects node A, installed as the publishing service, and
+// node B, which applies events to this process's in-memory state.
+func startStateSyncNodes(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	broker := statesync.NewMemoryBroker()
+	nodeA := statesync.NewService("node-a", broker)
+	nodeB := statesync.NewService("node-b", broker)
+	require.NoError(t, nodeA.Start(ctx))
+	require.NoError(t, nodeB.Start(ctx))
+
+	statesync.Use(nodeA)
+	t.Cleanup(func() { statesync.Use(nil) })
+}
+
+func cachedChannelIds(t *testing.T, group string, modelName string) []int {
+	t.Helper()
+	channels, err := GetChannelsFromCache(group, modelName)
+	if err != nil {
+		return nil
+	}
+	ids := make([]int, 0, len(channels))
+	for _, channel := range channels {
+		ids = append(ids, channel.Id)
+	}
+	return ids
+}
+
+func setupStateSyncChannels(t *testing.T) {
+	t.Helper()
+	originalDB := DB
+	DB = setupTestDB(t)
+	t.Cleanup(func() { DB = originalDB })
+
+	originalUsingSQLite := common.UsingSQLite.Load()
+	common.UsingSQLite.Store(true)
+	t.Cleanup(func() { common.UsingSQLite.Store(originalUsingSQLite) })
+
+	remoteSuspensions.Clear()
+	t.Cleanup(remoteSuspensions.Clear)
+
+	originalMemoryCache := config.MemoryCacheEnabled
+	config.MemoryCacheEnabled = true
+	t.Cleanup(func() { config.MemoryCacheEnabled = originalMemoryCache })
+
+	for _, channel := range []Channel{
+		{Id: 701, Name: "region-a", Status: ChannelStatusEnabled, Models: "gpt-4o", Group: "default"},
+		{Id: 702, Name: "region-b", Status: ChannelStatusEnabled, Models: "gpt-4o", Group: "default"},
+	} {
+		require.NoError(t, DB.Create(&channel).Error)
+		require.NoError(t, channel.AddAbilities())
+	}
+	InitChannelCache()
+	require.ElementsMatch(t, []int{701, 702}, cachedChannelIds(t, "default", "gpt-4o"))
+}
+
+func TestStateSync_ChannelSuspensionPropagatesToOtherNode(t *testing.T) {
+	setupStateSyncChannels(t)
+	startStateSyncNodes(t)
+
+	start := time.Now()
+	require.NoError(t, SuspendAbility(context.Background(), "default", "gpt-4o", 701, time.Minute))
+	require.Eventually(t, func() bool {
+		ids := cachedChannelIds(t, "default", "gpt-4o")
+		return len(ids) == 1 && ids[0] == 702
+	}, 100*time.Millisecond, time.Millisecond)
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestStateSync_RemoteSuspensionSurvivesCacheRebuild(t *testing.T) {
+	setupStateSyncChannels(t)
+
+	// The local database knows nothing about the suspension
+	suspendCachedAbility("default", "gpt-4o", 702, time.Now().Add(time.Minute))
+	InitChannelCache()
+	require.Equal(t, []int{701}, cachedChannelIds(t, "default", "gpt-4o"))
+
+	// Expired suspensions are ignored
+	suspendCachedAbility("default", "gpt-4o", 701, time.Now().Add(-time.Second))
+	remoteSuspensions.Store(abilityCacheKey("default", "gpt-4o", 702), time.Now().Add(-time.Second))
+	InitChannelCache()
+	require.ElementsMatch(t, []int{701, 702}, cachedChannelIds(t, "default", "gpt-4o"))
+}
+
+func TestStateSync_BanPropagatesToOtherNode(t *testing.T) {
+	startStateSyncNodes(t)
+	const userId = 7301
+
+	// Simulate node A banning a user that node B has not seen yet
+	statesync.Publish(statesync.Event{Type: statesync.EventUserBanned, UserId: userId})
+	require.Eventually(t, func() bool { return blacklist.IsUserBanned(userId) }, 100*time.Millisecond, time.Millisecond)
+
+	statesync.Publish(statesync.Event{Type: statesync.EventUserUnbanned, UserId: userId})
+	require.Eventually(t, func() bool { return !blacklist.IsUserBanned(userId) }, 100*time.Millisecond, time.Millisecond)
+}
+
+func TestStateSync_IPBanPropagatesToOtherNode(t *testing.T) {
+	startStateSyncNodes(t)
+	const ip = "198.51.100.23"
+
+	statesync.Publish(statesync.Event{Type: statesync.EventIPBanned, IP: ip})
+	require.Eventually(t, func() bool { return blacklist.IsIPBanned(ip) }, 100*time.Millisecond, time.Millisecond)
+
+	statesync.Publish(statesync.Event{Type: statesync.EventIPUnbanned, IP: ip})
+	require.Eventually(t, func() bool { return !blacklist.IsIPBanned(ip) }, 100*time.Millisecond, time.Millisecond)
+
+	// A ban that lapsed in transit is not applied
+	applyIPBan(ip, time.Now().Add(-time.Second).UnixMilli())
+	require.False(t, blacklist.IsIPBanned(ip))
+}
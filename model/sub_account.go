@@ -0,0 +1,218 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// GetUserParentId returns the id of the account whose quota pool the user
+// consumes from, or 0 when the user is not a sub-account.
+func GetUserParentId(id int) (parentId int, err error) {
+	err = DB.Model(&User{}).Where("id = ?", id).Select("parent_user_id").Find(&parentId).Error
+	if err != nil {
+		return 0, errors.Wrapf(err, "get parent of user %d", id)
+	}
+	return parentId, nil
+}
+
+// SetUserParent makes childId a sub-account of parentId, or detaches it when
+// parentId is 0. Parentage is a single level: a parent cannot itself be a
+// sub-account and a sub-account cannot have sub-accounts of its own.
+func SetUserParent(ctx context.Context, childId int, parentId int) error {
+	if parentId < 0 {
+		return errors.Errorf("invalid parent user id %d", parentId)
+	}
+	if parentId == childId {
+		return errors.New("a user cannot be its own parent")
+	}
+	child, err := GetUserById(childId, false)
+	if err != nil {
+		return errors.Wrapf(err, "get sub-account %d", childId)
+	}
+	if parentId != 0 {
+		parent, err := GetUserById(parentId, false)
+		if err != nil {
+			return errors.Wrapf(err, "get parent user %d", parentId)
+		}
+		if parent.Status == UserStatusDeleted {
+			return errors.Errorf("parent user %d is deleted", parentId)
+		}
+		if parent.ParentUserId != 0 {
+			return errors.Errorf("user %d is a sub-account and cannot be a parent", parentId)
+		}
+		var children int64
+		if err = DB.Model(&User{}).Where("parent_user_id = ?", child.Id).Count(&children).Error; err != nil {
+			return errors.Wrapf(err, "count sub-accounts of user %d", child.Id)
+		}
+		if children > 0 {
+			return errors.Errorf("user %d has sub-accounts and cannot become one", child.Id)
+		}
+	}
+
+	err = runWithSQLiteBusyRetry(ctx, func() error {
+		return DB.Model(&User{}).Where("id = ?", child.Id).Update("parent_user_id", parentId).Error
+	})
+	if err != nil {
+		return errors.Wrapf(err, "set parent of user %d", child.Id)
+	}
+	if common.IsRedisEnabled() {
+		err = common.RedisSet(ctx, fmt.Sprintf("user_parent:%d", child.Id), strconv.Itoa(parentId), time.Duration(UserId2GroupCacheSeconds)*time.Second)
+		if err != nil {
+			logger.Logger.Warn("Redis set user parent failed, cached parentage may be stale", zap.Int("user_id", child.Id), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// OfferUserParent records that parentId offers to take childId as a
+// sub-account. The offer only takes effect once the child accepts it with
+// AcceptUserParent, since a sub-account's spending is capped by the parent.
+func OfferUserParent(ctx context.Context, childId int, parentId int) error {
+	if parentId <= 0 {
+		return errors.Errorf("invalid parent user id %d", parentId)
+	}
+	if parentId == childId {
+		return errors.New("a user cannot be its own parent")
+	}
+	err := runWithSQLiteBusyRetry(ctx, func() error {
+		return DB.Model(&User{}).Where("id = ?", childId).Update("pending_parent_user_id", parentId).Error
+	})
+	if err != nil {
+		return errors.Wrapf(err, "offer parent %d to user %d", parentId, childId)
+	}
+	return nil
+}
+
+// AcceptUserParent makes childId a sub-account of the parent whose offer is
+// pending, see OfferUserParent.
+func AcceptUserParent(ctx context.Context, childId int) error {
+	var pendingParentId int
+	err := DB.Model(&User{}).Where("id = ?", childId).Select("pending_parent_user_id").Find(&pendingParentId).Error
+	if err != nil {
+		return errors.Wrapf(err, "get pending parent of user %d", childId)
+	}
+	if pendingParentId == 0 {
+		return errors.Errorf("user %d has no pending parent offer", childId)
+	}
+	if err = SetUserParent(ctx, childId, pendingParentId); err != nil {
+		return err
+	}
+	return DeclineUserParent(ctx, childId)
+}
+
+// DeclineUserParent drops the parent offer pending for childId, if any.
+func DeclineUserParent(ctx context.Context, childId int) error {
+	err := runWithSQLiteBusyRetry(ctx, func() error {
+		return DB.Model(&User{}).Where("id = ?", childId).Update("pending_parent_user_id", 0).Error
+	})
+	if err != nil {
+		return errors.Wrapf(err, "clear pending parent of user %d", childId)
+	}
+	return nil
+}
+
+// LoadParentQuota fills ParentQuota with the remaining quota of the parent's
+// pool, so sub-accounts can display both their own and the shared balance.
+func (user *User) LoadParentQuota() error {
+	if user.ParentUserId == 0 {
+		return nil
+	}
+	parentQuota, err := GetUserQuota(user.ParentUserId)
+	if err != nil {
+		return errors.Wrapf(err, "get parent pool of user %d", user.Id)
+	}
+	user.ParentQuota = &parentQuota
+	return nil
+}
+
+// GetUserEffectiveQuota returns the quota a user can spend right now: its own
+// quota, capped by the parent's pool for sub-accounts.
+func GetUserEffectiveQuota(id int) (quota int64, err error) {
+	quota, err = GetUserQuota(id)
+	if err != nil {
+		return 0, err
+	}
+	parentId, err := GetUserParentId(id)
+	if err != nil {
+		return 0, err
+	}
+	if parentId == 0 {
+		return quota, nil
+	}
+	parentQuota, err := GetUserQuota(parentId)
+	if err != nil {
+		return 0, errors.Wrapf(err, "get parent pool of user %d", id)
+	}
+	return min(quota, parentQuota), nil
+}
+
+// ConsumeUserQuota charges quota to a user and, for sub-accounts, to the
+// parent's pool as well. Either both are charged or neither is: without batch
+// updates both are charged in one transaction, so concurrent requests of
+// sibling sub-accounts cannot overdraw the parent's pool.
+func ConsumeUserQuota(ctx context.Context, id int, quota int64) error {
+	parentId, err := CacheGetUserParentId(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "consume quota for user %d", id)
+	}
+	if parentId == 0 {
+		return DecreaseUserQuota(ctx, id, quota)
+	}
+	if quota < 0 {
+		return errors.New("quota cannot be negative!")
+	}
+	if config.BatchUpdateEnabled {
+		// Batched updates are applied later and cannot fail here
+		addNewRecord(BatchUpdateTypeUserQuota, id, -quota)
+		addNewRecord(BatchUpdateTypeUserQuota, parentId, -quota)
+	} else {
+		err = runWithSQLiteBusyRetry(ctx, func() error {
+			return DB.Transaction(func(tx *gorm.DB) error {
+				for _, userId := range []int{id, parentId} {
+					result := tx.Model(&User{}).
+						Where("id = ? AND quota >= ?", userId, minQuotaForCharge(quota)).
+						Update("quota", gorm.Expr("quota - ?", quota))
+					if result.Error != nil {
+						return result.Error
+					}
+					if result.RowsAffected == 0 {
+						return errors.Errorf("insufficient user quota for user %d", userId)
+					}
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return errors.Wrapf(err, "consume quota for sub-account %d and parent %d", id, parentId)
+		}
+	}
+	PublishQuotaAdjustment(id, -quota)
+	PublishQuotaAdjustment(parentId, -quota)
+	return nil
+}
+
+// RefundUserQuota returns quota consumed by ConsumeUserQuota to the user and,
+// for sub-accounts, to the parent's pool.
+func RefundUserQuota(ctx context.Context, id int, quota int64) error {
+	if err := IncreaseUserQuota(ctx, id, quota); err != nil {
+		return err
+	}
+	parentId, err := CacheGetUserParentId(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "refund quota for user %d", id)
+	}
+	if parentId == 0 {
+		return nil
+	}
+	return IncreaseUserQuota(ctx, parentId, quota)
+}
@@ -0,0 +1,173 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/random"
+)
+
+func setupSubAccountTest(t *testing.T) {
+	t.Helper()
+	setupTestDatabase(t)
+	originalRedisEnabled := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(originalRedisEnabled) })
+}
+
+func createSubAccountTestUser(t *testing.T, name string, quota int64) *User {
+	t.Helper()
+	user := &User{
+		Username:    fmt.Sprintf("test-sub-%s-%d", name, time.Now().UnixNano()),
+		Password:    "testpassword12345",
+		Status:      UserStatusEnabled,
+		Role:        RoleCommonUser,
+		Quota:       quota,
+		AccessToken: random.GetUUID(),
+		AffCode:     random.GetRandomString(8),
+	}
+	require.NoError(t, DB.Create(user).Error)
+	t.Cleanup(func() { DB.Exec("DELETE FROM users WHERE id = ?", user.Id) })
+	return user
+}
+
+func createSubAccountTestToken(t *testing.T, userId int) *Token {
+	t.Helper()
+	token := &Token{
+		UserId:         userId,
+		Key:            fmt.Sprintf("testsubaccount%d", time.Now().UnixNano()),
+		Name:           fmt.Sprintf("test-sub-token-%d", userId),
+		Status:         TokenStatusEnabled,
+		ExpiredTime:    -1,
+		UnlimitedQuota: true,
+	}
+	require.NoError(t, DB.Create(token).Error)
+	t.Cleanup(func() { DB.Exec("DELETE FROM tokens WHERE id = ?", token.Id) })
+	return token
+}
+
+func TestSubAccountExhaustionDoesNotAffectSiblings(t *testing.T) {
+	setupSubAccountTest(t)
+	ctx := context.Background()
+
+	parent := createSubAccountTestUser(t, "parent", 1000)
+	childA := createSubAccountTestUser(t, "a", 300)
+	childB := createSubAccountTestUser(t, "b", 500)
+	require.NoError(t, SetUserParent(ctx, childA.Id, parent.Id))
+	require.NoError(t, SetUserParent(ctx, childB.Id, parent.Id))
+	tokenA := createSubAccountTestToken(t, childA.Id)
+	tokenB := createSubAccountTestToken(t, childB.Id)
+
+	// A spends its whole share, which also draws down the parent pool
+	require.NoError(t, PreConsumeTokenQuota(ctx, tokenA.Id, 300))
+	require.Error(t, PreConsumeTokenQuota(ctx, tokenA.Id, 1))
+
+	quota, err := CacheGetUserQuota(ctx, childA.Id)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), quota)
+	parentQuota, err := GetUserQuota(parent.Id)
+	require.NoError(t, err)
+	require.Equal(t, int64(700), parentQuota)
+
+	// B keeps its own share and can still spend it
+	quota, err = CacheGetUserQuota(ctx, childB.Id)
+	require.NoError(t, err)
+	require.Equal(t, int64(500), quota)
+	require.NoError(t, PreConsumeTokenQuota(ctx, tokenB.Id, 200))
+	require.NoError(t, PostConsumeTokenQuota(ctx, tokenB.Id, -50))
+
+	quota, err = GetUserQuota(childB.Id)
+	require.NoError(t, err)
+	require.Equal(t, int64(350), quota)
+	parentQuota, err = GetUserQuota(parent.Id)
+	require.NoError(t, err)
+	require.Equal(t, int64(550), parentQuota)
+}
+
+func TestSubAccountCappedByParentPool(t *testing.T) {
+	setupSubAccountTest(t)
+	ctx := context.Background()
+
+	parent := createSubAccountTestUser(t, "pool", 100)
+	child := createSubAccountTestUser(t, "capped", 400)
+	require.NoError(t, SetUserParent(ctx, child.Id, parent.Id))
+
+	quota, err := CacheGetUserQuota(ctx, child.Id)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), quota)
+
+	// The parent charge fails, so the sub-account is not charged either
+	require.Error(t, ConsumeUserQuota(ctx, child.Id, 150))
+	childQuota, err := GetUserQuota(child.Id)
+	require.NoError(t, err)
+	require.Equal(t, int64(400), childQuota)
+
+	require.NoError(t, SetUserParent(ctx, child.Id, 0))
+	quota, err = CacheGetUserQuota(ctx, child.Id)
+	require.NoError(t, err)
+	require.Equal(t, int64(400), quota)
+}
+
+func TestSetUserParentSingleLevel(t *testing.T) {
+	setupSubAccountTest(t)
+	ctx := context.Background()
+
+	root := createSubAccountTestUser(t, "root", 100)
+	middle := createSubAccountTestUser(t, "middle", 100)
+	leaf := createSubAccountTestUser(t, "leaf", 100)
+
+	require.Error(t, SetUserParent(ctx, root.Id, root.Id))
+	require.NoError(t, SetUserParent(ctx, middle.Id, root.Id))
+	// A sub-account cannot be a parent
+	require.Error(t, SetUserParent(ctx, leaf.Id, middle.Id))
+	// A parent cannot become a sub-account
+	require.Error(t, SetUserParent(ctx, root.Id, leaf.Id))
+
+	middle.ParentUserId = root.Id
+	require.NoError(t, middle.LoadParentQuota())
+	require.NotNil(t, middle.ParentQuota)
+	require.Equal(t, int64(100), *middle.ParentQuota)
+}
+
+func TestConsumeUserQuotaSiblingsCannotOverdrawParent(t *testing.T) {
+	setupSubAccountTest(t)
+	ctx := context.Background()
+
+	parent := createSubAccountTestUser(t, "shared", 100)
+	childA := createSubAccountTestUser(t, "sibling-a", 100)
+	childB := createSubAccountTestUser(t, "sibling-b", 100)
+	require.NoError(t, SetUserParent(ctx, childA.Id, parent.Id))
+	require.NoError(t, SetUserParent(ctx, childB.Id, parent.Id))
+
+	var wg sync.WaitGroup
+	var charged atomic.Int64
+	for i := range 20 {
+		child := childA
+		if i%2 == 1 {
+			child = childB
+		}
+		wg.Go(func() {
+			if ConsumeUserQuota(ctx, child.Id, 10) == nil {
+				charged.Add(10)
+			}
+		})
+	}
+	wg.Wait()
+
+	parentQuota, err := GetUserQuota(parent.Id)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, parentQuota, int64(0))
+	require.Equal(t, int64(100)-charged.Load(), parentQuota)
+	quotaA, err := GetUserQuota(childA.Id)
+	require.NoError(t, err)
+	quotaB, err := GetUserQuota(childB.Id)
+	require.NoError(t, err)
+	require.Equal(t, charged.Load(), 200-quotaA-quotaB)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/Laisky/errors/v2"
 	"github.com/Laisky/zap"
@@ -38,8 +39,11 @@ type Token struct {
 	UsedQuota      int64   `json:"used_quota" gorm:"bigint;default:0"` // used quota
 	CreatedAt      int64   `json:"created_at" gorm:"bigint;autoCreateTime:milli"`
 	UpdatedAt      int64   `json:"updated_at" gorm:"bigint;autoUpdateTime:milli"`
-	Models         *string `json:"models" gorm:"type:text"`  // allowed models
-	Subnet         *string `json:"subnet" gorm:"default:''"` // allowed subnet
+	Models         *string `json:"models" gorm:"type:text"`    // allowed models
+	Subnet         *string `json:"subnet" gorm:"default:''"`   // allowed subnet
+	Scopes         *string `json:"scopes" gorm:"type:text"`    // allowed relay scopes, empty means all
+	RpmLimit       int     `json:"rpm_limit" gorm:"default:0"` // requests per minute, 0 uses the default
+	TpmLimit       int     `json:"tpm_limit" gorm:"default:0"` // tokens per minute, 0 uses the default
 }
 
 // MarshalJSON ensures that any token serialized to JSON will include the configured key prefix.
@@ -70,6 +74,11 @@ func (t Token) MarshalJSON() ([]byte, error) {
 		UpdatedAt      int64   `json:"updated_at"`
 		Models         *string `json:"models"`
 		Subnet         *string `json:"subnet"`
+		Scopes         *string `json:"scopes"`
+		RpmLimit       int     `json:"rpm_limit"`
+		TpmLimit       int     `json:"tpm_limit"`
+		// EffectiveScopes lists the scopes the token can use, for display
+		EffectiveScopes []string `json:"effective_scopes"`
 	}
 	dto := tokenDTO{
 		Id:             t.Id,
@@ -87,7 +96,11 @@ func (t Token) MarshalJSON() ([]byte, error) {
 		UpdatedAt:      t.UpdatedAt,
 		Models:         t.Models,
 		Subnet:         t.Subnet,
+		Scopes:         t.Scopes,
+		RpmLimit:       t.RpmLimit,
+		TpmLimit:       t.TpmLimit,
 	}
+	dto.EffectiveScopes = t.EffectiveScopes()
 	return json.Marshal(dto)
 }
 
@@ -241,6 +254,20 @@ func GetTokenById(id int) (*Token, error) {
 	return &token, nil
 }
 
+// GetFirstEnabledUserToken returns the oldest enabled token owned by userId.
+// It is used when the system must bill an operation to a user without a token in hand.
+func GetFirstEnabledUserToken(userId int) (*Token, error) {
+	if userId == 0 {
+		return nil, errors.Errorf("invalid user id: %d", userId)
+	}
+	var token Token
+	err := DB.Where("user_id = ? AND status = ?", userId, TokenStatusEnabled).Order("id asc").First(&token).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get enabled token for userId=%d", userId)
+	}
+	return &token, nil
+}
+
 func (t *Token) Insert(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
@@ -260,7 +287,7 @@ func (t *Token) Update(ctx context.Context) error {
 		ctx = context.Background()
 	}
 	var err error
-	err = DB.Model(t).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "models", "subnet").Updates(t).Error
+	err = DB.Model(t).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "models", "subnet", "scopes", "rpm_limit", "tpm_limit").Updates(t).Error
 	if err == nil {
 		clearTokenCache(ctx, t.Key)
 		return nil
@@ -418,63 +445,123 @@ func PreConsumeTokenQuota(ctx context.Context, tokenId int, quota int64) (err er
 	if !token.UnlimitedQuota && token.RemainQuota < quota {
 		return errors.Errorf("insufficient token quota: required=%d, available=%d, tokenId=%d", quota, token.RemainQuota, tokenId)
 	}
-	userQuota, err := GetUserQuota(token.UserId)
+	if config.HardQuotaProtectionEnabled {
+		reserved, reserveErr := reserveUserQuota(ctx, token.UserId, quota)
+		if reserveErr != nil {
+			return errors.Wrapf(reserveErr, "tokenId=%d", tokenId)
+		}
+		if reserved {
+			defer func() {
+				if err == nil {
+					return
+				}
+				if releaseErr := cacheReleaseUserQuota(ctx, token.UserId, quota); releaseErr != nil {
+					logger.Logger.Error("failed to release reserved quota", zap.Int("user_id", token.UserId), zap.Error(releaseErr))
+				}
+			}()
+		}
+	}
+	userQuota, err := GetUserEffectiveQuota(token.UserId)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get user quota for pre-consume: userId=%d, tokenId=%d", token.UserId, tokenId)
 	}
-	if userQuota < quota {
+	if QuotaWithOverage(userQuota) < quota {
 		return errors.Errorf("insufficient user quota: required=%d, available=%d, userId=%d, tokenId=%d", quota, userQuota, token.UserId, tokenId)
 	}
-	quotaTooLow := userQuota >= config.QuotaRemindThreshold && userQuota-quota < config.QuotaRemindThreshold
-	noMoreQuota := userQuota-quota <= 0
-	var reminderEmail string
-	if quotaTooLow || noMoreQuota {
-		var emailErr error
-		reminderEmail, emailErr = GetUserEmail(token.UserId)
-		if emailErr != nil {
-			logger.Logger.Error("failed to fetch user email", zap.Int("user_id", token.UserId), zap.Error(emailErr))
-		}
-		go func(email string, exhausted bool, quotaRemaining int64) {
-			prompt := "Quota Reminder"
-			var contentText string
-			if exhausted {
-				contentText = "Your quota has been exhausted"
-			} else {
-				contentText = "Your quota is about to be exhausted"
-			}
-			if email != "" {
-				topUpLink := fmt.Sprintf("%s/topup", config.ServerAddress)
-				content := message.EmailTemplate(
-					prompt,
-					fmt.Sprintf(`
-								<p>Hello!</p>
-								<p>%s, your current remaining quota is <strong>%d</strong>.</p>
-								<p>To avoid any disruption to your service, please top up in a timely manner.</p>
-								<p style="text-align: center; margin: 30px 0;">
-									<a href="%s" style="background-color: #007bff; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Top Up Now</a>
-								</p>
-								<p style="color: #666;">If the button does not work, please copy the following link and paste it into your browser:</p>
-								<p style="background-color: #f8f8f8; padding: 10px; border-radius: 4px; word-break: break-all;">%s</p>
-					`, contentText, quotaRemaining, topUpLink, topUpLink),
-				)
-				err = message.SendEmail(prompt, email, content)
-				if err != nil {
-					logger.Logger.Error("failed to send email", zap.String("email", email), zap.Error(err))
-				}
-			}
-		}(reminderEmail, noMoreQuota, userQuota)
-	}
+	remindLowQuota(ctx, token.UserId, userQuota, userQuota-quota)
 	if !token.UnlimitedQuota {
 		if err = DecreaseTokenQuota(ctx, tokenId, quota); err != nil {
 			return errors.Wrapf(err, "decrease quota for token %d", tokenId)
 		}
 	}
-	if err = DecreaseUserQuota(ctx, token.UserId, quota); err != nil {
+	if err = ConsumeUserQuota(ctx, token.UserId, quota); err != nil {
 		return errors.Wrapf(err, "decrease quota for user %d in pre-consume", token.UserId)
 	}
 	return nil
 }
 
+// reserveUserQuota reserves quota for userId under hard quota protection. It
+// reports false without error when the reservation could not be attempted, in
+// which case the optimistic quota check applies.
+func reserveUserQuota(ctx context.Context, userId int, quota int64) (bool, error) {
+	if !common.IsRedisEnabled() {
+		hardQuotaProtectionFallbackOnce.Do(func() {
+			logger.Logger.Warn("HARD_QUOTA_PROTECTION_ENABLED requires redis, falling back to optimistic quota checks")
+		})
+		return false, nil
+	}
+	reserved, err := cacheReserveUserQuota(ctx, userId, quota)
+	if err != nil {
+		logger.Logger.Warn("hard quota protection unavailable, falling back to optimistic quota check",
+			zap.Int("user_id", userId), zap.Error(err))
+		return false, nil
+	}
+	if !reserved {
+		return false, errors.Errorf("insufficient user quota: required=%d, userId=%d", quota, userId)
+	}
+	return true, nil
+}
+
+var hardQuotaProtectionFallbackOnce sync.Once
+
+// quotaReminder delivers a quota reminder to a user; tests replace it.
+var quotaReminder = sendQuotaReminderEmail
+
+// remindLowQuota reminds userId when consumption takes the remaining quota from
+// before to after across the user's spending alert threshold, or exhausts it.
+// Users who disabled email alerts are not reminded. It runs on every
+// consumption, so the settings are read from the cache.
+func remindLowQuota(ctx context.Context, userId int, before, after int64) {
+	exhausted := before > 0 && after <= 0
+	if !exhausted && after >= before {
+		return
+	}
+	setting, err := CacheGetUserSetting(ctx, userId)
+	if err != nil {
+		logger.Logger.Error("failed to fetch user settings", zap.Int("user_id", userId), zap.Error(err))
+		return
+	}
+	threshold := setting.SpendingAlertThresholdOrDefault()
+	tooLow := before >= threshold && after < threshold
+	if !setting.EmailAlertsEnabled || !(tooLow || exhausted) {
+		return
+	}
+	email, err := GetUserEmail(userId)
+	if err != nil {
+		logger.Logger.Error("failed to fetch user email", zap.Int("user_id", userId), zap.Error(err))
+	}
+	go quotaReminder(email, exhausted, before)
+}
+
+// sendQuotaReminderEmail emails the remaining quota and a top up link.
+func sendQuotaReminderEmail(email string, exhausted bool, quotaRemaining int64) {
+	if email == "" {
+		return
+	}
+	prompt := "Quota Reminder"
+	contentText := "Your quota is about to be exhausted"
+	if exhausted {
+		contentText = "Your quota has been exhausted"
+	}
+	topUpLink := fmt.Sprintf("%s/topup", config.ServerAddress)
+	content := message.EmailTemplate(
+		prompt,
+		fmt.Sprintf(`
+					<p>Hello!</p>
+					<p>%s, your current remaining quota is <strong>%d</strong>.</p>
+					<p>To avoid any disruption to your service, please top up in a timely manner.</p>
+					<p style="text-align: center; margin: 30px 0;">
+						<a href="%s" style="background-color: #007bff; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Top Up Now</a>
+					</p>
+					<p style="color: #666;">If the button does not work, please copy the following link and paste it into your browser:</p>
+					<p style="background-color: #f8f8f8; padding: 10px; border-radius: 4px; word-break: break-all;">%s</p>
+		`, contentText, quotaRemaining, topUpLink, topUpLink),
+	)
+	if err := message.SendEmail(prompt, email, content); err != nil {
+		logger.Logger.Error("failed to send email", zap.String("email", email), zap.Error(err))
+	}
+}
+
 func PostConsumeTokenQuota(ctx context.Context, tokenId int, quota int64) (err error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -484,9 +571,14 @@ func PostConsumeTokenQuota(ctx context.Context, tokenId int, quota int64) (err e
 		return errors.Wrapf(err, "get token %d for post-consume", tokenId)
 	}
 	if quota > 0 {
-		err = DecreaseUserQuota(ctx, token.UserId, quota)
+		if userQuota, quotaErr := CacheGetUserQuota(ctx, token.UserId); quotaErr != nil {
+			logger.Logger.Warn("failed to get user quota for reminder", zap.Int("user_id", token.UserId), zap.Error(quotaErr))
+		} else {
+			remindLowQuota(ctx, token.UserId, userQuota, userQuota-quota)
+		}
+		err = ConsumeUserQuota(ctx, token.UserId, quota)
 	} else {
-		err = IncreaseUserQuota(ctx, token.UserId, -quota)
+		err = RefundUserQuota(ctx, token.UserId, -quota)
 	}
 	if !token.UnlimitedQuota {
 		if quota > 0 {
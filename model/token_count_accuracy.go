@@ -0,0 +1,243 @@
+package model
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/graceful"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// tokenCountAccuracyDayLayout is the UTC day bucket format of TokenCountAccuracy rows.
+const tokenCountAccuracyDayLayout = "2006-01-02"
+
+// TokenCountAccuracy aggregates, per UTC day and model, how closely locally
+// estimated prompt tokens matched the upstream-reported prompt tokens.
+type TokenCountAccuracy struct {
+	Id        int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Day       string `json:"day" gorm:"type:varchar(10);uniqueIndex:idx_token_count_accuracy_day_model;not null"`
+	ModelName string `json:"model_name" gorm:"type:varchar(255);uniqueIndex:idx_token_count_accuracy_day_model;not null"`
+	Samples   int64  `json:"samples" gorm:"bigint;default:0"`
+	// AbsPercentErrorSum is the sum of |estimated-actual|/actual over all samples.
+	AbsPercentErrorSum float64 `json:"abs_percent_error_sum" gorm:"default:0"`
+	// Discrepancies counts samples whose ratio exceeded the configured threshold.
+	Discrepancies int64 `json:"discrepancies" gorm:"bigint;default:0"`
+	UpdatedAt     int64 `json:"updated_at" gorm:"bigint;autoUpdateTime:milli"`
+}
+
+// TokenCountAccuracyStat summarizes token estimation accuracy of one model.
+type TokenCountAccuracyStat struct {
+	ModelName string `json:"model_name"`
+	Samples   int64  `json:"samples"`
+	// MAPE is the mean absolute percentage error, in percent.
+	MAPE          float64 `json:"mape"`
+	Discrepancies int64   `json:"discrepancies"`
+}
+
+// tokenCountAccuracyKey identifies a TokenCountAccuracy row.
+type tokenCountAccuracyKey struct {
+	day       string
+	modelName string
+}
+
+// tokenCountAccuracyDelta is the part of a TokenCountAccuracy row recorded
+// since the last flush.
+type tokenCountAccuracyDelta struct {
+	samples            int64
+	absPercentErrorSum float64
+	discrepancies      int64
+}
+
+// pendingTokenCountAccuracy accumulates samples in memory until
+// FlushTokenCountAccuracy writes them, so relayed requests do not each update
+// the database.
+var pendingTokenCountAccuracy = struct {
+	sync.Mutex
+	deltas map[tokenCountAccuracyKey]*tokenCountAccuracyDelta
+}{deltas: make(map[tokenCountAccuracyKey]*tokenCountAccuracyDelta)}
+
+// tokenCountAccuracyFlusherStop and tokenCountAccuracyFlusherDone coordinate
+// the shutdown of the flusher started by InitTokenCountAccuracyFlusher.
+var tokenCountAccuracyFlusherStop, tokenCountAccuracyFlusherDone chan struct{}
+
+// RecordTokenCountAccuracy adds one estimate/actual sample to today's bucket for
+// modelName. Samples are aggregated in memory and written by
+// FlushTokenCountAccuracy. Samples with non-positive counts are ignored.
+func RecordTokenCountAccuracy(modelName string, estimated, actual int, discrepancy bool) {
+	if modelName == "" || estimated <= 0 || actual <= 0 {
+		return
+	}
+
+	key := tokenCountAccuracyKey{day: time.Now().UTC().Format(tokenCountAccuracyDayLayout), modelName: modelName}
+	pendingTokenCountAccuracy.Lock()
+	defer pendingTokenCountAccuracy.Unlock()
+	delta := pendingTokenCountAccuracy.deltas[key]
+	if delta == nil {
+		delta = &tokenCountAccuracyDelta{}
+		pendingTokenCountAccuracy.deltas[key] = delta
+	}
+	delta.samples++
+	delta.absPercentErrorSum += math.Abs(float64(estimated-actual)) / float64(actual)
+	if discrepancy {
+		delta.discrepancies++
+	}
+}
+
+// InitTokenCountAccuracyFlusher starts a background goroutine flushing the
+// samples of RecordTokenCountAccuracy every config.BatchUpdateInterval seconds.
+// Call StopTokenCountAccuracyFlusher on shutdown to write the pending samples.
+func InitTokenCountAccuracyFlusher() {
+	tokenCountAccuracyFlusherStop = make(chan struct{})
+	tokenCountAccuracyFlusherDone = make(chan struct{})
+
+	go func() {
+		defer close(tokenCountAccuracyFlusherDone)
+
+		ticker := time.NewTicker(time.Duration(max(config.BatchUpdateInterval, 1)) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-tokenCountAccuracyFlusherStop:
+				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.BatchUpdateTimeoutSec)*time.Second)
+				if err := FlushTokenCountAccuracy(ctx); err != nil {
+					logger.Logger.Error("failed to flush token count accuracy on shutdown", zap.Error(err))
+				}
+				cancel()
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.BatchUpdateTimeoutSec)*time.Second)
+				if err := FlushTokenCountAccuracy(ctx); err != nil {
+					logger.Logger.Warn("failed to flush token count accuracy", zap.Error(err))
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// StopTokenCountAccuracyFlusher stops the flusher after a final flush, waiting
+// for it until ctx expires.
+func StopTokenCountAccuracyFlusher(ctx context.Context) {
+	if tokenCountAccuracyFlusherStop == nil {
+		return
+	}
+
+	graceful.GoCritical(ctx, "tokenCountAccuracyFinalFlush", func(_ context.Context) {
+		close(tokenCountAccuracyFlusherStop)
+		select {
+		case <-ctx.Done():
+			logger.Logger.Warn("token count accuracy shutdown context expired before final flush completed")
+		case <-tokenCountAccuracyFlusherDone:
+		}
+	})
+}
+
+// FlushTokenCountAccuracy writes the samples recorded since the last flush,
+// adding each day and model aggregate to its row. Aggregates that fail to be
+// written are dropped, like the quota deltas of the batch updater.
+func FlushTokenCountAccuracy(ctx context.Context) error {
+	pendingTokenCountAccuracy.Lock()
+	deltas := pendingTokenCountAccuracy.deltas
+	pendingTokenCountAccuracy.deltas = make(map[tokenCountAccuracyKey]*tokenCountAccuracyDelta)
+	pendingTokenCountAccuracy.Unlock()
+
+	var flushErr error
+	for key, delta := range deltas {
+		if err := addTokenCountAccuracy(ctx, key, delta); err != nil {
+			logger.Logger.Error("failed to flush token count accuracy",
+				zap.String("day", key.day),
+				zap.String("model", key.modelName),
+				zap.Int64("samples", delta.samples),
+				zap.Error(err))
+			flushErr = err
+		}
+	}
+	return flushErr
+}
+
+// addTokenCountAccuracy adds delta to the row of key, creating it if needed.
+func addTokenCountAccuracy(ctx context.Context, key tokenCountAccuracyKey, delta *tokenCountAccuracyDelta) error {
+	db := DB.WithContext(ctx)
+	increment := func() (int64, error) {
+		tx := db.Model(&TokenCountAccuracy{}).
+			Where("day = ? AND model_name = ?", key.day, key.modelName).
+			Updates(map[string]any{
+				"samples":               gorm.Expr("samples + ?", delta.samples),
+				"abs_percent_error_sum": gorm.Expr("abs_percent_error_sum + ?", delta.absPercentErrorSum),
+				"discrepancies":         gorm.Expr("discrepancies + ?", delta.discrepancies),
+			})
+		return tx.RowsAffected, tx.Error
+	}
+
+	// Update-first approach, mirroring UpdateUserRequestCostQuotaByRequestID
+	affected, err := increment()
+	if err != nil {
+		return errors.Wrapf(err, "update token count accuracy for model %s", key.modelName)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	row := &TokenCountAccuracy{
+		Day:                key.day,
+		ModelName:          key.modelName,
+		Samples:            delta.samples,
+		AbsPercentErrorSum: delta.absPercentErrorSum,
+		Discrepancies:      delta.discrepancies,
+	}
+	if err = db.Create(row).Error; err == nil {
+		return nil
+	}
+	// Another instance may have created the bucket concurrently
+	if _, err = increment(); err != nil {
+		return errors.Wrapf(err, "update token count accuracy for model %s after create race", key.modelName)
+	}
+	return nil
+}
+
+// GetTokenCountAccuracy returns per-model estimation accuracy over the last days
+// UTC days, including today. An empty modelName returns every model.
+func GetTokenCountAccuracy(ctx context.Context, modelName string, days int) ([]TokenCountAccuracyStat, error) {
+	if days <= 0 {
+		return nil, errors.Errorf("days must be positive, got %d", days)
+	}
+	since := time.Now().UTC().AddDate(0, 0, -(days - 1)).Format(tokenCountAccuracyDayLayout)
+
+	var rows []struct {
+		ModelName          string
+		Samples            int64
+		AbsPercentErrorSum float64
+		Discrepancies      int64
+	}
+	query := DB.WithContext(ctx).Model(&TokenCountAccuracy{}).
+		Select("model_name, SUM(samples) AS samples, SUM(abs_percent_error_sum) AS abs_percent_error_sum, SUM(discrepancies) AS discrepancies").
+		Where("day >= ?", since)
+	if modelName != "" {
+		query = query.Where("model_name = ?", modelName)
+	}
+	if err := query.Group("model_name").Order("model_name").Scan(&rows).Error; err != nil {
+		return nil, errors.Wrap(err, "query token count accuracy")
+	}
+
+	stats := make([]TokenCountAccuracyStat, 0, len(rows))
+	for _, row := range rows {
+		stat := TokenCountAccuracyStat{
+			ModelName:     row.ModelName,
+			Samples:       row.Samples,
+			Discrepancies: row.Discrepancies,
+		}
+		if row.Samples > 0 {
+			stat.MAPE = row.AbsPercentErrorSum / float64(row.Samples) * 100
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
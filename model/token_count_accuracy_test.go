@@ -0,0 +1,40 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestFlushTokenCountAccuracy_AggregatesSamples(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TokenCountAccuracy{}))
+	originalDB := DB
+	DB = db
+	t.Cleanup(func() { DB = originalDB })
+	ctx := context.Background()
+
+	RecordTokenCountAccuracy("gpt-4o", 110, 100, false)
+	RecordTokenCountAccuracy("gpt-4o", 300, 100, true)
+	// Nothing reaches the database before a flush
+	var count int64
+	require.NoError(t, db.Model(&TokenCountAccuracy{}).Count(&count).Error)
+	require.Zero(t, count)
+
+	require.NoError(t, FlushTokenCountAccuracy(ctx))
+	RecordTokenCountAccuracy("gpt-4o", 100, 100, false)
+	require.NoError(t, FlushTokenCountAccuracy(ctx))
+	// A flush without samples writes nothing
+	require.NoError(t, FlushTokenCountAccuracy(ctx))
+
+	var rows []TokenCountAccuracy
+	require.NoError(t, db.Find(&rows).Error)
+	require.Len(t, rows, 1)
+	require.EqualValues(t, 3, rows[0].Samples)
+	require.InDelta(t, 2.1, rows[0].AbsPercentErrorSum, 1e-9)
+	require.EqualValues(t, 1, rows[0].Discrepancies)
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+// memoryCachedQuota mimics the Redis check-and-decrement script with a mutex.
+type memoryCachedQuota struct {
+	mu     sync.Mutex
+	values map[string]int64
+}
+
+func (m *memoryCachedQuota) decreaseIfEnough(_ context.Context, key string, value int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.values[key]
+	if !ok {
+		return false, common.ErrRedisKeyNotFound
+	}
+	if cur < value {
+		return false, nil
+	}
+	m.values[key] = cur - value
+	return true, nil
+}
+
+func (m *memoryCachedQuota) decrease(_ context.Context, key string, value int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] -= value
+	return nil
+}
+
+// exhaustQuotaConcurrently sends 50 concurrent pre-consumes of up to 1000
+// against a user with 10000 quota and returns the user's final quota. Batch
+// updates delay the database writes, so every optimistic check passes.
+func exhaustQuotaConcurrently(t *testing.T, hardProtection bool) (finalQuota, maxPreConsume int64) {
+	t.Helper()
+	user := createSubAccountTestUser(t, "hard-floor", 10000)
+	token := createSubAccountTestToken(t, user.Id)
+
+	store := &memoryCachedQuota{values: map[string]int64{"user_quota:" + strconv.Itoa(user.Id): user.Quota}}
+	originalStore := cachedQuota
+	cachedQuota = store
+	originalProtection, originalBatch := config.HardQuotaProtectionEnabled, config.BatchUpdateEnabled
+	config.HardQuotaProtectionEnabled = hardProtection
+	config.BatchUpdateEnabled = true
+	common.SetRedisEnabled(hardProtection)
+	t.Cleanup(func() {
+		cachedQuota = originalStore
+		config.HardQuotaProtectionEnabled = originalProtection
+		config.BatchUpdateEnabled = originalBatch
+		common.SetRedisEnabled(false)
+	})
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		amount := int64(500 + (i%6)*100)
+		maxPreConsume = max(maxPreConsume, amount)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = PreConsumeTokenQuota(context.Background(), token.Id, amount)
+		}()
+	}
+	wg.Wait()
+
+	batchUpdate(context.Background())
+	finalQuota, err := GetUserQuota(user.Id)
+	require.NoError(t, err)
+	return finalQuota, maxPreConsume
+}
+
+func TestPreConsumeTokenQuotaHardFloor(t *testing.T) {
+	setupSubAccountTest(t)
+	require.NoError(t, DB.AutoMigrate(&UserSetting{}))
+	captureQuotaReminders(t)
+
+	finalQuota, maxPreConsume := exhaustQuotaConcurrently(t, true)
+	require.GreaterOrEqual(t, finalQuota, -2*maxPreConsume)
+
+	// Without the atomic reservation the same burst drives the quota deeply negative
+	finalQuota, maxPreConsume = exhaustQuotaConcurrently(t, false)
+	require.Less(t, finalQuota, -2*maxPreConsume)
+}
+
+func TestPreConsumeTokenQuotaReleasesReservationOnFailure(t *testing.T) {
+	setupSubAccountTest(t)
+	user := createSubAccountTestUser(t, "hard-floor-release", 1000)
+	token := createSubAccountTestToken(t, user.Id)
+	require.NoError(t, DB.Model(token).Updates(map[string]any{"unlimited_quota": false, "remain_quota": 5000}).Error)
+
+	key := "user_quota:" + strconv.Itoa(user.Id)
+	store := &memoryCachedQuota{values: map[string]int64{key: 5000}}
+	originalStore := cachedQuota
+	cachedQuota = store
+	originalProtection := config.HardQuotaProtectionEnabled
+	config.HardQuotaProtectionEnabled = true
+	common.SetRedisEnabled(true)
+	t.Cleanup(func() {
+		cachedQuota = originalStore
+		config.HardQuotaProtectionEnabled = originalProtection
+		common.SetRedisEnabled(false)
+	})
+
+	// The cache allows 2000 but the database only holds 1000
+	require.Error(t, PreConsumeTokenQuota(context.Background(), token.Id, 2000))
+	require.EqualValues(t, 5000, store.values[key])
+
+	// Not enough cached quota is rejected before touching the database
+	store.values[key] = 100
+	require.ErrorContains(t, PreConsumeTokenQuota(context.Background(), token.Id, 500), "insufficient user quota")
+	require.EqualValues(t, 100, store.values[key])
+}
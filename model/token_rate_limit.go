@@ -0,0 +1,195 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/random"
+)
+
+// TokenRateLimitWindow is the sliding window of the per-token RPM and TPM limits.
+const TokenRateLimitWindow = time.Minute
+
+// tokenRateWindowEntry is an amount counted in a window at a point in time.
+type tokenRateWindowEntry struct {
+	at     time.Time
+	amount int64
+}
+
+// memoryTokenRateWindows holds the per-token windows when Redis is
+// unavailable. They are only shared by the requests of a single instance.
+// Windows of idle tokens are evicted once per TokenRateLimitWindow.
+var memoryTokenRateWindows = struct {
+	sync.Mutex
+	windows map[string][]tokenRateWindowEntry
+	evictor sync.Once
+}{windows: make(map[string][]tokenRateWindowEntry)}
+
+// evictIdleTokenRateWindows drops the in-memory windows whose newest entry
+// left the window before now.
+func evictIdleTokenRateWindows(now time.Time) {
+	memoryTokenRateWindows.Lock()
+	defer memoryTokenRateWindows.Unlock()
+	for key, entries := range memoryTokenRateWindows.windows {
+		if len(entries) == 0 || now.Sub(entries[len(entries)-1].at) >= TokenRateLimitWindow {
+			delete(memoryTokenRateWindows.windows, key)
+		}
+	}
+}
+
+// tokenRateWindowScript drops the entries of sorted set KEYS[1] that left the
+// window and sums the amounts of the rest. Each member ends with ":<amount>".
+// When the sum is below the limit, or the limit is 0, it adds the amount and
+// returns {1, sum}; otherwise it returns {0, sum} and adds nothing.
+// ARGV: now (ms), window (ms), limit, amount, unique member prefix.
+var tokenRateWindowScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', tonumber(ARGV[1]) - tonumber(ARGV[2]))
+local used = 0
+for _, member in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+  used = used + tonumber(string.match(member, ':(%d+)$'))
+end
+local limit = tonumber(ARGV[3])
+if limit > 0 and used >= limit then
+  return {0, used}
+end
+local amount = tonumber(ARGV[4])
+if amount > 0 then
+  redis.call('ZADD', KEYS[1], ARGV[1], ARGV[5] .. ':' .. amount)
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  used = used + amount
+end
+return {1, used}
+`)
+
+func tokenRpmKey(tokenId int) string {
+	return fmt.Sprintf("token_rpm:%d", tokenId)
+}
+
+func tokenTpmKey(tokenId int) string {
+	return fmt.Sprintf("token_tpm:%d", tokenId)
+}
+
+// EffectiveTokenRpmLimit returns the RPM limit of a token whose own limit is
+// rpmLimit, falling back to config.DefaultTokenRpmLimit. 0 means unlimited.
+func EffectiveTokenRpmLimit(rpmLimit int) int {
+	if rpmLimit > 0 {
+		return rpmLimit
+	}
+	return config.DefaultTokenRpmLimit
+}
+
+// EffectiveTokenTpmLimit returns the TPM limit of a token whose own limit is
+// tpmLimit, falling back to config.DefaultTokenTpmLimit. 0 means unlimited.
+func EffectiveTokenTpmLimit(tpmLimit int) int {
+	if tpmLimit > 0 {
+		return tpmLimit
+	}
+	return config.DefaultTokenTpmLimit
+}
+
+// takeTokenRateWindow counts amount in the window stored under key unless the
+// amounts already in it reached limit. It returns whether amount was counted
+// and the window total afterwards. A limit of 0 always counts the amount.
+func takeTokenRateWindow(ctx context.Context, key string, limit int, amount int64, now time.Time) (bool, int64) {
+	if common.IsRedisEnabled() && common.RDB != nil {
+		result, err := tokenRateWindowScript.Run(ctx, common.RDB, []string{key},
+			now.UnixMilli(), TokenRateLimitWindow.Milliseconds(), limit, amount,
+			fmt.Sprintf("%d-%s", now.UnixNano(), random.GetRandomString(8))).Int64Slice()
+		if err != nil || len(result) != 2 {
+			// Fail open, an unreachable Redis must not block all traffic
+			logger.Logger.Warn("failed to update token rate limit window", zap.String("key", key), zap.Error(err))
+			return true, 0
+		}
+		return result[0] == 1, result[1]
+	}
+
+	memoryTokenRateWindows.evictor.Do(func() {
+		go func() {
+			ticker := time.NewTicker(TokenRateLimitWindow)
+			defer ticker.Stop()
+			for now := range ticker.C {
+				evictIdleTokenRateWindows(now)
+			}
+		}()
+	})
+
+	memoryTokenRateWindows.Lock()
+	defer memoryTokenRateWindows.Unlock()
+	entries := memoryTokenRateWindows.windows[key]
+	var used int64
+	kept := entries[:0]
+	for _, entry := range entries {
+		if now.Sub(entry.at) < TokenRateLimitWindow {
+			kept = append(kept, entry)
+			used += entry.amount
+		}
+	}
+	if limit > 0 && used >= int64(limit) {
+		memoryTokenRateWindows.windows[key] = kept
+		return false, used
+	}
+	if amount > 0 {
+		kept = append(kept, tokenRateWindowEntry{at: now, amount: amount})
+		used += amount
+	}
+	if len(kept) == 0 {
+		delete(memoryTokenRateWindows.windows, key)
+	} else {
+		memoryTokenRateWindows.windows[key] = kept
+	}
+	return true, used
+}
+
+// TakeTokenRequest counts a request against the token's RPM limit. It
+// returns false without counting it when rpmLimit requests were already made
+// within the last TokenRateLimitWindow, and the requests made in the window.
+func TakeTokenRequest(ctx context.Context, tokenId int, rpmLimit int, now time.Time) (bool, int64) {
+	return takeTokenRateWindow(ctx, tokenRpmKey(tokenId), rpmLimit, 1, now)
+}
+
+// CheckTokenTpm reports whether the token used fewer than tpmLimit tokens
+// within the last TokenRateLimitWindow, and how many it used.
+func CheckTokenTpm(ctx context.Context, tokenId int, tpmLimit int, now time.Time) (bool, int64) {
+	return takeTokenRateWindow(ctx, tokenTpmKey(tokenId), tpmLimit, 0, now)
+}
+
+// RecordTokenTpmUsage counts the prompt and completion tokens of a finished
+// request against the token's TPM limit. Tokens without a TPM limit are not
+// tracked.
+func RecordTokenTpmUsage(ctx context.Context, tokenId int, tokens int64, now time.Time) {
+	if tokens <= 0 || tokenTpmLimit(ctx, tokenId) <= 0 {
+		return
+	}
+	takeTokenRateWindow(ctx, tokenTpmKey(tokenId), 0, tokens, now)
+}
+
+// tokenTpmLimit returns the effective TPM limit of the token, as set by
+// TokenAuth on the request behind ctx. Requests billed without one, such as
+// background jobs, fall back to the default limit or to reading the token.
+func tokenTpmLimit(ctx context.Context, tokenId int) int {
+	if c, ok := gmw.GetGinCtxFromStdCtx(ctx); ok {
+		if limit, exists := c.Get(ctxkey.TokenTpmLimit); exists {
+			tpmLimit, _ := limit.(int)
+			return tpmLimit
+		}
+	}
+	if tpmLimit := EffectiveTokenTpmLimit(0); tpmLimit > 0 {
+		return tpmLimit
+	}
+	token, err := GetTokenById(tokenId)
+	if err != nil {
+		logger.Logger.Warn("failed to get token for TPM usage", zap.Int("token_id", tokenId), zap.Error(err))
+		return 0
+	}
+	return EffectiveTokenTpmLimit(token.TpmLimit)
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+)
+
+func TestTokenRateLimitWindow(t *testing.T) {
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(originalRedis) })
+
+	ctx := context.Background()
+	now := time.Now()
+	tokenId := 910001
+
+	for i := range 2 {
+		allowed, used := TakeTokenRequest(ctx, tokenId, 2, now)
+		require.True(t, allowed)
+		require.EqualValues(t, i+1, used)
+	}
+	allowed, used := TakeTokenRequest(ctx, tokenId, 2, now.Add(30*time.Second))
+	require.False(t, allowed)
+	require.EqualValues(t, 2, used)
+	// The first requests leave the window a minute later
+	allowed, used = TakeTokenRequest(ctx, tokenId, 2, now.Add(TokenRateLimitWindow))
+	require.True(t, allowed)
+	require.EqualValues(t, 1, used)
+
+	originalTpm := config.DefaultTokenTpmLimit
+	config.DefaultTokenTpmLimit = 100
+	t.Cleanup(func() { config.DefaultTokenTpmLimit = originalTpm })
+
+	allowed, used = CheckTokenTpm(ctx, tokenId, 100, now)
+	require.True(t, allowed)
+	require.Zero(t, used)
+	RecordTokenTpmUsage(ctx, tokenId, 60, now)
+	RecordTokenTpmUsage(ctx, tokenId, 50, now.Add(10*time.Second))
+	allowed, used = CheckTokenTpm(ctx, tokenId, 100, now.Add(20*time.Second))
+	require.False(t, allowed)
+	require.EqualValues(t, 110, used)
+	allowed, used = CheckTokenTpm(ctx, tokenId, 100, now.Add(TokenRateLimitWindow))
+	require.True(t, allowed)
+	require.EqualValues(t, 50, used)
+}
+
+func TestEvictIdleTokenRateWindows(t *testing.T) {
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(originalRedis) })
+
+	ctx := context.Background()
+	now := time.Now()
+	idle, active := 910101, 910102
+	TakeTokenRequest(ctx, idle, 10, now)
+	TakeTokenRequest(ctx, active, 10, now)
+	TakeTokenRequest(ctx, active, 10, now.Add(30*time.Second))
+
+	evictIdleTokenRateWindows(now.Add(TokenRateLimitWindow))
+
+	memoryTokenRateWindows.Lock()
+	defer memoryTokenRateWindows.Unlock()
+	require.NotContains(t, memoryTokenRateWindows.windows, tokenRpmKey(idle))
+	require.Contains(t, memoryTokenRateWindows.windows, tokenRpmKey(active))
+	delete(memoryTokenRateWindows.windows, tokenRpmKey(active))
+}
+
+func TestEffectiveTokenRateLimits(t *testing.T) {
+	originalRpm, originalTpm := config.DefaultTokenRpmLimit, config.DefaultTokenTpmLimit
+	config.DefaultTokenRpmLimit, config.DefaultTokenTpmLimit = 60, 0
+	t.Cleanup(func() { config.DefaultTokenRpmLimit, config.DefaultTokenTpmLimit = originalRpm, originalTpm })
+
+	require.Equal(t, 60, EffectiveTokenRpmLimit(0))
+	require.Equal(t, 5, EffectiveTokenRpmLimit(5))
+	require.Zero(t, EffectiveTokenTpmLimit(0))
+	require.Equal(t, 1000, EffectiveTokenTpmLimit(1000))
+}
+
+func TestRecordTokenTpmUsageReadsLimitFromRequest(t *testing.T) {
+	originalRedis, originalDB := common.IsRedisEnabled(), DB
+	common.SetRedisEnabled(false)
+	// The limit comes from the request, so the token is never read
+	DB = nil
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedis)
+		DB = originalDB
+	})
+
+	now := time.Now()
+	requestCtx := func(tpmLimit int) context.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		c.Set(ctxkey.TokenTpmLimit, tpmLimit)
+		return gmw.Ctx(c)
+	}
+
+	RecordTokenTpmUsage(requestCtx(0), 910101, 80, now)
+	_, used := CheckTokenTpm(context.Background(), 910101, 100, now)
+	require.Zero(t, used)
+
+	RecordTokenTpmUsage(requestCtx(100), 910102, 80, now)
+	_, used = CheckTokenTpm(context.Background(), 910102, 100, now)
+	require.EqualValues(t, 80, used)
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+)
+
+// Token scopes restrict which relay endpoints an API token can call.
+const (
+	TokenScopeChat       = "chat"
+	TokenScopeEmbeddings = "embeddings"
+	TokenScopeImages     = "images"
+	TokenScopeAudio      = "audio"
+	TokenScopeResponses  = "responses"
+)
+
+// AllTokenScopes lists every token scope, in display order. A token without
+// scopes is allowed all of them.
+var AllTokenScopes = []string{
+	TokenScopeChat,
+	TokenScopeEmbeddings,
+	TokenScopeImages,
+	TokenScopeAudio,
+	TokenScopeResponses,
+}
+
+// NormalizeTokenScopes validates a comma-separated scope list and returns it
+// lowercased, deduplicated and in AllTokenScopes order. An empty list stays
+// empty, meaning all scopes.
+func NormalizeTokenScopes(raw string) (string, error) {
+	requested := make(map[string]bool)
+	for _, scope := range strings.Split(raw, ",") {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" {
+			continue
+		}
+		if !slices.Contains(AllTokenScopes, scope) {
+			return "", errors.Errorf("unknown scope %q, supported scopes: %s",
+				scope, strings.Join(AllTokenScopes, ","))
+		}
+		requested[scope] = true
+	}
+
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range AllTokenScopes {
+		if requested[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+	return strings.Join(scopes, ","), nil
+}
+
+// EffectiveScopes returns the scopes the token is allowed to use. Tokens
+// created before scopes existed have none set and keep access to all of them.
+func (t *Token) EffectiveScopes() []string {
+	if t.Scopes == nil || strings.TrimSpace(*t.Scopes) == "" {
+		return slices.Clone(AllTokenScopes)
+	}
+	var scopes []string
+	for _, scope := range strings.Split(*t.Scopes, ",") {
+		if scope = strings.ToLower(strings.TrimSpace(scope)); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// HasExplicitScopes reports whether the token was restricted to a list of
+// scopes, rather than allowed all of them.
+func (t *Token) HasExplicitScopes() bool {
+	return t.Scopes != nil && strings.TrimSpace(*t.Scopes) != ""
+}
+
+// HasScope reports whether the token is allowed to use scope.
+func (t *Token) HasScope(scope string) bool {
+	return slices.Contains(t.EffectiveScopes(), scope)
+}
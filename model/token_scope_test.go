@@ -0,0 +1,40 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTokenScopes(t *testing.T) {
+	scopes, err := NormalizeTokenScopes(" Audio,chat,,chat ")
+	require.NoError(t, err)
+	require.Equal(t, "chat,audio", scopes)
+
+	scopes, err = NormalizeTokenScopes("")
+	require.NoError(t, err)
+	require.Empty(t, scopes)
+
+	_, err = NormalizeTokenScopes("chat,video")
+	require.ErrorContains(t, err, `unknown scope "video"`)
+}
+
+func TestTokenEffectiveScopes(t *testing.T) {
+	token := &Token{}
+	require.Equal(t, AllTokenScopes, token.EffectiveScopes())
+	require.True(t, token.HasScope(TokenScopeImages))
+
+	scopes := "embeddings,responses"
+	token.Scopes = &scopes
+	require.Equal(t, []string{TokenScopeEmbeddings, TokenScopeResponses}, token.EffectiveScopes())
+	require.True(t, token.HasScope(TokenScopeResponses))
+	require.False(t, token.HasScope(TokenScopeChat))
+
+	encoded, err := json.Marshal(token)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Equal(t, "embeddings,responses", decoded["scopes"])
+	require.Equal(t, []any{"embeddings", "responses"}, decoded["effective_scopes"])
+}
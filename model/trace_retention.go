@@ -57,7 +57,8 @@ func StartTraceRetentionCleaner(ctx context.Context, retentionDays int) {
 	logger.Logger.Info("trace retention cleaner started", zap.Int("trace_retention_days", retentionDays))
 }
 
-// CleanExpiredTraces deletes trace records whose creation time is older than the configured retentionDays window.
+// CleanExpiredTraces deletes trace records, including captured replay requests and the replay corpus, whose creation
+// time is older than the configured retentionDays window. It returns the total number of rows deleted.
 func CleanExpiredTraces(retentionDays int) (int64, error) {
 	if retentionDays <= 0 {
 		return 0, nil
@@ -69,6 +70,18 @@ func CleanExpiredTraces(retentionDays int) (int64, error) {
 	if tx.Error != nil {
 		return 0, errors.Wrap(tx.Error, "delete expired trace records")
 	}
+	deleted := tx.RowsAffected
 
-	return tx.RowsAffected, nil
+	tx = DB.Where("created_at < ?", cutoff).Delete(&RequestTrace{})
+	if tx.Error != nil {
+		return deleted, errors.Wrap(tx.Error, "delete expired request trace records")
+	}
+	deleted += tx.RowsAffected
+
+	tx = DB.Where("created_at < ?", cutoff).Delete(&ReplayCorpusEntry{})
+	if tx.Error != nil {
+		return deleted, errors.Wrap(tx.Error, "delete expired replay corpus records")
+	}
+
+	return deleted + tx.RowsAffected, nil
 }
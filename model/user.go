@@ -2,7 +2,9 @@ package model
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"strings"
 
 	"github.com/Laisky/errors/v2"
@@ -10,7 +12,6 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/songquanpeng/one-api/common"
-	"github.com/songquanpeng/one-api/common/blacklist"
 	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/common/logger"
@@ -44,22 +45,53 @@ type User struct {
 	WeChatId         string `json:"wechat_id" gorm:"column:wechat_id;index"`
 	LarkId           string `json:"lark_id" gorm:"column:lark_id;index"`
 	OidcId           string `json:"oidc_id" gorm:"column:oidc_id;index"`
+	GoogleId         string `json:"google_id" gorm:"column:google_id;index"`
 	VerificationCode string `json:"verification_code" gorm:"-:all"`                                    // this field is only for Email verification, don't save it to database!
 	AccessToken      string `json:"access_token" gorm:"type:char(32);column:access_token;uniqueIndex"` // this token is for system management
 	TotpSecret       string `json:"totp_secret,omitempty" gorm:"type:varchar(64);column:totp_secret"`  // TOTP secret for 2FA, omit from JSON when empty
+	TotpBackupCodes  string `json:"-" gorm:"type:text;column:totp_backup_codes"`                       // JSON array of bcrypt hashes of unused TOTP backup codes, never sent to clients
 	Quota            int64  `json:"quota" gorm:"bigint;default:0"`
 	UsedQuota        int64  `json:"used_quota" gorm:"bigint;default:0;column:used_quota"` // used quota
 	RequestCount     int    `json:"request_count" gorm:"type:int;default:0;"`             // request number
 	Group            string `json:"group" gorm:"type:varchar(32);default:'default'"`
 	AffCode          string `json:"aff_code" gorm:"type:varchar(32);column:aff_code;uniqueIndex"`
 	InviterId        int    `json:"inviter_id" gorm:"type:int;column:inviter_id;index"`
-	CreatedAt        int64  `json:"created_at" gorm:"bigint;autoCreateTime:milli"`
-	UpdatedAt        int64  `json:"updated_at" gorm:"bigint;autoUpdateTime:milli"`
+	ParentUserId     int    `json:"parent_user_id" gorm:"type:int;default:0;index"` // 0 means no parent, otherwise quota is also drawn from the parent's pool
+	// PendingParentUserId is the account that offered to take the user as a
+	// sub-account, see OfferUserParent. 0 means no offer is pending.
+	PendingParentUserId int    `json:"pending_parent_user_id" gorm:"type:int;default:0;index"`
+	ParentQuota         *int64 `json:"parent_quota,omitempty" gorm:"-:all"` // remaining quota of the parent's pool, only filled for display
+	CreatedAt           int64  `json:"created_at" gorm:"bigint;autoCreateTime:milli"`
+	UpdatedAt           int64  `json:"updated_at" gorm:"bigint;autoUpdateTime:milli"`
+	// AutoRefillQuota is added to the quota every AutoRefillIntervalDays days,
+	// see billing.StartAutoRefill. Either being 0 disables auto refill.
+	AutoRefillQuota        int64 `json:"auto_refill_quota" gorm:"bigint;default:0"`
+	AutoRefillIntervalDays int   `json:"auto_refill_interval_days" gorm:"type:int;default:0"`
+	// LastRefillAt is the Unix time in seconds of the last auto refill, 0 if never.
+	LastRefillAt int64 `json:"last_refill_at" gorm:"bigint;default:0"`
+	// SessionVersion is stored in login sessions at login. Bumping it with
+	// RevokeUserSessions invalidates every session issued before.
+	SessionVersion int `json:"-" gorm:"type:int;default:0"`
+	// IpAllowlist is a comma-separated list of CIDR ranges password and OAuth
+	// logins must come from, see NormalizeIpAllowlist. Empty allows any address.
+	IpAllowlist string `json:"ip_allowlist" gorm:"type:text;column:ip_allowlist"`
+	// DailyBudget and WeeklyBudget cap the quota spent per UTC day and per
+	// ISO week, see CheckUserBudget. 0 means no cap.
+	DailyBudget  int64 `json:"daily_budget" gorm:"bigint;default:0"`
+	WeeklyBudget int64 `json:"weekly_budget" gorm:"bigint;default:0"`
+	// DeletedAt marks a soft-deleted user. Soft-deleted users are hidden from
+	// queries and cannot log in until restored, and are purged after
+	// config.UserRetentionDays.
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	// DeletedIdentity keeps, as JSON, the username and login identities a
+	// soft-deleted user gave up so others may take them, see Delete.
+	DeletedIdentity string `json:"-" gorm:"type:text"`
 }
 
 func GetMaxUserId() int {
 	var user User
-	DB.Last(&user)
+	// Soft-deleted users keep their id and username until purged
+	DB.Unscoped().Last(&user)
 	return user.Id
 }
 
@@ -206,39 +238,177 @@ func (user *User) Update(updatePassword bool) error {
 		}
 	}
 	if user.Status == UserStatusDisabled {
-		blacklist.BanUser(user.Id)
+		BanUser(user.Id)
 	} else if user.Status == UserStatusEnabled {
-		blacklist.UnbanUser(user.Id)
+		UnbanUser(user.Id)
 	}
 	err = DB.Model(user).Updates(user).Error
 	if err != nil {
 		return errors.Wrapf(err, "failed to update user: id=%d, username=%s", user.Id, user.Username)
 	}
+	if updatePassword {
+		if _, err = RevokeUserSessions(context.Background(), user.Id); err != nil {
+			return errors.Wrapf(err, "failed to revoke sessions after password change: id=%d", user.Id)
+		}
+	}
 	return nil
 }
 
-// ClearTotpSecret clears the TOTP secret for the user
+// ClearTotpSecret clears the TOTP secret and backup codes for the user and
+// revokes the sessions signed in with them.
 func (user *User) ClearTotpSecret() error {
-	err := DB.Model(user).Select("totp_secret").Updates(map[string]any{
-		"totp_secret": "",
+	err := DB.Model(user).Select("totp_secret", "totp_backup_codes").Updates(map[string]any{
+		"totp_secret":       "",
+		"totp_backup_codes": "",
 	}).Error
 	if err != nil {
 		return errors.Wrapf(err, "failed to clear TOTP secret for user: id=%d", user.Id)
 	}
+	if _, err = RevokeUserSessions(context.Background(), user.Id); err != nil {
+		return errors.Wrapf(err, "failed to revoke sessions after clearing TOTP: id=%d", user.Id)
+	}
 	return nil
 }
 
+// deletedUserIdentity is the identity a user gives up when soft-deleted and
+// gets back when restored.
+type deletedUserIdentity struct {
+	Username string `json:"username"`
+	Email    string `json:"email,omitempty"`
+	GitHubId string `json:"github_id,omitempty"`
+	WeChatId string `json:"wechat_id,omitempty"`
+	LarkId   string `json:"lark_id,omitempty"`
+	OidcId   string `json:"oidc_id,omitempty"`
+	GoogleId string `json:"google_id,omitempty"`
+}
+
+// columns returns the user columns holding the identity.
+func (identity deletedUserIdentity) columns() map[string]any {
+	return map[string]any{
+		"username":  identity.Username,
+		"email":     identity.Email,
+		"github_id": identity.GitHubId,
+		"wechat_id": identity.WeChatId,
+		"lark_id":   identity.LarkId,
+		"oidc_id":   identity.OidcId,
+		"google_id": identity.GoogleId,
+	}
+}
+
+// takenBy returns the first column of identity already used by another user.
+func (identity deletedUserIdentity) takenBy() string {
+	checks := []struct {
+		column string
+		value  string
+		taken  func(string) bool
+	}{
+		{"username", identity.Username, IsUsernameAlreadyTaken},
+		{"email", identity.Email, IsEmailAlreadyTaken},
+		{"github_id", identity.GitHubId, IsGitHubIdAlreadyTaken},
+		{"wechat_id", identity.WeChatId, IsWeChatIdAlreadyTaken},
+		{"lark_id", identity.LarkId, IsLarkIdAlreadyTaken},
+		{"oidc_id", identity.OidcId, IsOidcIdAlreadyTaken},
+		{"google_id", identity.GoogleId, IsGoogleIdAlreadyTaken},
+	}
+	for _, check := range checks {
+		if check.value != "" && check.taken(check.value) {
+			return check.column
+		}
+	}
+	return ""
+}
+
+// Delete soft-deletes the user: it is marked UserStatusDeleted and hidden from
+// queries, and its username and login identities are released so they can be
+// registered again. The originals and the logs are kept so RestoreUserById can
+// bring the user back within config.UserRetentionDays.
 func (user *User) Delete() error {
 	if user.Id == 0 {
 		return errors.New("id is empty!")
 	}
-	blacklist.BanUser(user.Id)
-	user.Username = fmt.Sprintf("deleted_%s", random.GetUUID())
-	user.Status = UserStatusDeleted
-	err := DB.Model(user).Updates(user).Error
+	BanUser(user.Id)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var current User
+		if err := tx.Select("id", "username", "email", "github_id", "wechat_id", "lark_id", "oidc_id", "google_id").
+			First(&current, user.Id).Error; err != nil {
+			return err
+		}
+		identity, err := json.Marshal(deletedUserIdentity{
+			Username: current.Username,
+			Email:    current.Email,
+			GitHubId: current.GitHubId,
+			WeChatId: current.WeChatId,
+			LarkId:   current.LarkId,
+			OidcId:   current.OidcId,
+			GoogleId: current.GoogleId,
+		})
+		if err != nil {
+			return errors.Wrap(err, "marshal deleted user identity")
+		}
+		released := deletedUserIdentity{Username: "deleted_" + random.GetUUID()}.columns()
+		released["status"] = UserStatusDeleted
+		released["deleted_identity"] = string(identity)
+		if err := tx.Model(&User{}).Where("id = ?", user.Id).Updates(released).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&User{}, user.Id).Error
+	})
 	if err != nil {
 		return errors.Wrapf(err, "failed to delete user: id=%d", user.Id)
 	}
+	user.Status = UserStatusDeleted
+	return nil
+}
+
+// GetDeletedUserById returns the soft-deleted user with the given id.
+func GetDeletedUserById(id int) (*User, error) {
+	var user User
+	err := DB.Unscoped().Omit("password", "access_token").
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&user).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "get deleted user %d", id)
+	}
+	return &user, nil
+}
+
+// RestoreUserById reverts the soft deletion of a user and enables it again.
+func RestoreUserById(id int) error {
+	if id == 0 {
+		return errors.New("id is empty!")
+	}
+	var deleted User
+	if err := DB.Unscoped().Select("id", "deleted_identity").
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&deleted).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.Errorf("no deleted user with id %d", id)
+		}
+		return errors.Wrapf(err, "failed to restore user: id=%d", id)
+	}
+
+	updates := map[string]any{"deleted_at": nil, "status": UserStatusEnabled, "deleted_identity": ""}
+	// Users deleted before identities were released have none stored
+	if deleted.DeletedIdentity != "" {
+		var identity deletedUserIdentity
+		if err := json.Unmarshal([]byte(deleted.DeletedIdentity), &identity); err != nil {
+			return errors.Wrapf(err, "failed to restore user: id=%d", id)
+		}
+		if column := identity.takenBy(); column != "" {
+			return errors.Errorf("cannot restore user %d: its %s has been taken by another user", id, column)
+		}
+		maps.Copy(updates, identity.columns())
+	}
+	result := DB.Unscoped().Model(&User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(updates)
+	if result.Error != nil {
+		return errors.Wrapf(result.Error, "failed to restore user: id=%d", id)
+	}
+	if result.RowsAffected == 0 {
+		return errors.Errorf("no deleted user with id %d", id)
+	}
+	UnbanUser(id)
 	return nil
 }
 
@@ -287,7 +457,7 @@ func (user *User) FillUserByGitHubId() error {
 	if user.GitHubId == "" {
 		return errors.New("GitHub id is empty!")
 	}
-	DB.Where(User{GitHubId: user.GitHubId}).First(user)
+	DB.Unscoped().Where(User{GitHubId: user.GitHubId}).First(user)
 	return nil
 }
 
@@ -295,7 +465,7 @@ func (user *User) FillUserByLarkId() error {
 	if user.LarkId == "" {
 		return errors.New("lark id is empty!")
 	}
-	DB.Where(User{LarkId: user.LarkId}).First(user)
+	DB.Unscoped().Where(User{LarkId: user.LarkId}).First(user)
 	return nil
 }
 
@@ -303,7 +473,16 @@ func (user *User) FillUserByOidcId() error {
 	if user.OidcId == "" {
 		return errors.New("oidc id is empty!")
 	}
-	DB.Where(User{OidcId: user.OidcId}).First(user)
+	DB.Unscoped().Where(User{OidcId: user.OidcId}).First(user)
+	return nil
+}
+
+// FillUserByGoogleId loads the user bound to user.GoogleId (the Google "sub" claim).
+func (user *User) FillUserByGoogleId() error {
+	if user.GoogleId == "" {
+		return errors.New("google id is empty!")
+	}
+	DB.Unscoped().Where(User{GoogleId: user.GoogleId}).First(user)
 	return nil
 }
 
@@ -311,7 +490,7 @@ func (user *User) FillUserByWeChatId() error {
 	if user.WeChatId == "" {
 		return errors.New("WeChat id is empty!")
 	}
-	DB.Where(User{WeChatId: user.WeChatId}).First(user)
+	DB.Unscoped().Where(User{WeChatId: user.WeChatId}).First(user)
 	return nil
 }
 
@@ -323,28 +502,37 @@ func (user *User) FillUserByUsername() error {
 	return nil
 }
 
+// The IsXxxAlreadyTaken checks include soft-deleted users, whose identities stay
+// reserved until they are purged, so a deleted user cannot sign up again and
+// OAuth logins of deleted users are refused instead of creating new accounts.
+
 func IsEmailAlreadyTaken(email string) bool {
-	return DB.Where("email = ?", email).Find(&User{}).RowsAffected == 1
+	return DB.Unscoped().Where("email = ?", email).Find(&User{}).RowsAffected == 1
 }
 
 func IsWeChatIdAlreadyTaken(wechatId string) bool {
-	return DB.Where("wechat_id = ?", wechatId).Find(&User{}).RowsAffected == 1
+	return DB.Unscoped().Where("wechat_id = ?", wechatId).Find(&User{}).RowsAffected == 1
 }
 
 func IsGitHubIdAlreadyTaken(githubId string) bool {
-	return DB.Where("github_id = ?", githubId).Find(&User{}).RowsAffected == 1
+	return DB.Unscoped().Where("github_id = ?", githubId).Find(&User{}).RowsAffected == 1
 }
 
 func IsLarkIdAlreadyTaken(githubId string) bool {
-	return DB.Where("lark_id = ?", githubId).Find(&User{}).RowsAffected == 1
+	return DB.Unscoped().Where("lark_id = ?", githubId).Find(&User{}).RowsAffected == 1
 }
 
 func IsOidcIdAlreadyTaken(oidcId string) bool {
-	return DB.Where("oidc_id = ?", oidcId).Find(&User{}).RowsAffected == 1
+	return DB.Unscoped().Where("oidc_id = ?", oidcId).Find(&User{}).RowsAffected == 1
+}
+
+// IsGoogleIdAlreadyTaken reports whether a user is already bound to googleId.
+func IsGoogleIdAlreadyTaken(googleId string) bool {
+	return DB.Unscoped().Where("google_id = ?", googleId).Find(&User{}).RowsAffected == 1
 }
 
 func IsUsernameAlreadyTaken(username string) bool {
-	return DB.Where("username = ?", username).Find(&User{}).RowsAffected == 1
+	return DB.Unscoped().Where("username = ?", username).Find(&User{}).RowsAffected == 1
 }
 
 func ResetUserPasswordByEmail(email string, password string) error {
@@ -355,9 +543,18 @@ func ResetUserPasswordByEmail(email string, password string) error {
 	if err != nil {
 		return errors.Wrap(err, "hash password for reset")
 	}
+	var ids []int
+	if err = DB.Model(&User{}).Where("email = ?", email).Pluck("id", &ids).Error; err != nil {
+		return errors.Wrapf(err, "find users with email %s", email)
+	}
 	if err = DB.Model(&User{}).Where("email = ?", email).Update("password", hashedPassword).Error; err != nil {
 		return errors.Wrapf(err, "update password for email %s", email)
 	}
+	for _, id := range ids {
+		if _, err = RevokeUserSessions(context.Background(), id); err != nil {
+			return errors.Wrapf(err, "revoke sessions after password reset for email %s", email)
+		}
+	}
 	return nil
 }
 
@@ -446,9 +643,14 @@ func IncreaseUserQuota(ctx context.Context, id int, quota int64) (err error) {
 	}
 	if config.BatchUpdateEnabled {
 		addNewRecord(BatchUpdateTypeUserQuota, id, quota)
+		PublishQuotaAdjustment(id, quota)
 		return nil
 	}
-	return increaseUserQuota(ctx, id, quota)
+	if err = increaseUserQuota(ctx, id, quota); err != nil {
+		return err
+	}
+	PublishQuotaAdjustment(id, quota)
+	return nil
 }
 
 func increaseUserQuota(ctx context.Context, id int, quota int64) (err error) {
@@ -475,9 +677,14 @@ func DecreaseUserQuota(ctx context.Context, id int, quota int64) (err error) {
 	}
 	if config.BatchUpdateEnabled {
 		addNewRecord(BatchUpdateTypeUserQuota, id, -quota)
+		PublishQuotaAdjustment(id, -quota)
 		return nil
 	}
-	return decreaseUserQuota(ctx, id, quota)
+	if err = decreaseUserQuota(ctx, id, quota); err != nil {
+		return err
+	}
+	PublishQuotaAdjustment(id, -quota)
+	return nil
 }
 
 func decreaseUserQuota(ctx context.Context, id int, quota int64) (err error) {
@@ -487,7 +694,7 @@ func decreaseUserQuota(ctx context.Context, id int, quota int64) (err error) {
 	var result *gorm.DB
 	err = runWithSQLiteBusyRetry(ctx, func() error {
 		result = DB.Model(&User{}).
-			Where("id = ? AND quota >= ?", id, quota).
+			Where("id = ? AND quota >= ?", id, minQuotaForCharge(quota)).
 			Update("quota", gorm.Expr("quota - ?", quota))
 		return result.Error
 	})
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"github.com/Laisky/errors/v2"
+)
+
+// autoRefillDaySeconds converts AutoRefillIntervalDays to seconds in SQL.
+const autoRefillDaySeconds = 24 * 60 * 60
+
+// UpdateUserAutoRefill sets the auto refill quota and interval of a user.
+// Setting either to 0 disables auto refill. The refill schedule is not reset,
+// so a user that was never refilled is refilled on the next sweep.
+func UpdateUserAutoRefill(id int, quota int64, intervalDays int) error {
+	if quota < 0 || intervalDays < 0 {
+		return errors.New("auto refill quota and interval must not be negative")
+	}
+	result := DB.Model(&User{}).Where("id = ?", id).Updates(map[string]any{
+		"auto_refill_quota":         quota,
+		"auto_refill_interval_days": intervalDays,
+	})
+	if result.Error != nil {
+		return errors.Wrapf(result.Error, "update auto refill of user %d", id)
+	}
+	if result.RowsAffected == 0 {
+		return errors.Errorf("user %d not found", id)
+	}
+	return nil
+}
+
+// GetUsersDueForAutoRefill returns the enabled users with auto refill whose
+// interval has elapsed since their last refill at the Unix time now.
+func GetUsersDueForAutoRefill(now int64) ([]*User, error) {
+	var users []*User
+	err := DB.Select("id", "username", "auto_refill_quota", "auto_refill_interval_days", "last_refill_at").
+		Where("status = ? AND auto_refill_quota > 0 AND auto_refill_interval_days > 0", UserStatusEnabled).
+		Where("last_refill_at + auto_refill_interval_days * ? <= ?", autoRefillDaySeconds, now).
+		Order("id").
+		Find(&users).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "find users due for auto refill")
+	}
+	return users, nil
+}
+
+// ClaimUserAutoRefill moves the last refill time of a user from previous to
+// now and reports whether it did. The update only applies while the stored
+// time still equals previous, so a refill is claimed by one sweep even when
+// sweeps overlap.
+func ClaimUserAutoRefill(id int, previous int64, now int64) (bool, error) {
+	result := DB.Model(&User{}).
+		Where("id = ? AND last_refill_at = ?", id, previous).
+		Update("last_refill_at", now)
+	if result.Error != nil {
+		return false, errors.Wrapf(result.Error, "claim auto refill of user %d", id)
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,251 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// budgetPeriod is a window a user budget applies to.
+type budgetPeriod struct {
+	// key identifies the window in the user_budget cache key, e.g. day-20260102.
+	key   string
+	start time.Time
+	// end is the exclusive end of the window.
+	end time.Time
+}
+
+// userBudgetPeriods returns the UTC day and the ISO week, starting on
+// Monday, that contain now.
+func userBudgetPeriods(now time.Time) (day budgetPeriod, week budgetPeriod) {
+	now = now.UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day = budgetPeriod{
+		key:   "day-" + dayStart.Format("20060102"),
+		start: dayStart,
+		end:   dayStart.AddDate(0, 0, 1),
+	}
+
+	weekStart := dayStart.AddDate(0, 0, -((int(dayStart.Weekday()) + 6) % 7))
+	week = budgetPeriod{
+		key:   "week-" + weekStart.Format("20060102"),
+		start: weekStart,
+		end:   weekStart.AddDate(0, 0, 7),
+	}
+	return day, week
+}
+
+// userBudgetLimits holds the budget columns of a user.
+type userBudgetLimits struct {
+	Username     string `json:"username"`
+	DailyBudget  int64  `json:"daily_budget"`
+	WeeklyBudget int64  `json:"weekly_budget"`
+}
+
+// cachedUserBudgetLimits is a user budget cached in memory until expiresAt.
+type cachedUserBudgetLimits struct {
+	limits    userBudgetLimits
+	expiresAt time.Time
+}
+
+// cachedUserBudgetSpend is the spend of a user in one budget period, cached in
+// memory until the period ends.
+type cachedUserBudgetSpend struct {
+	period    string
+	spend     atomic.Int64
+	expiresAt time.Time
+}
+
+// userBudgetLimitsCache caches budgets by user ID when Redis is disabled.
+var userBudgetLimitsCache sync.Map
+
+// userBudgetSpendCache caches *cachedUserBudgetSpend by user ID and period
+// kind, see userBudgetSpendMemoryKey, when Redis is disabled. Keying by kind
+// rather than by period replaces the entry of an ended period instead of
+// keeping it around.
+var userBudgetSpendCache sync.Map
+
+func userBudgetSpendMemoryKey(userId int, period budgetPeriod) string {
+	kind, _, _ := strings.Cut(period.key, "-")
+	return fmt.Sprintf("%d:%s", userId, kind)
+}
+
+func userBudgetLimitsKey(userId int) string {
+	return fmt.Sprintf("user_budget_limit:%d", userId)
+}
+
+func userBudgetSpendKey(userId int, period budgetPeriod) string {
+	return fmt.Sprintf("user_budget:%d:%s", userId, period.key)
+}
+
+func getUserBudgetLimits(userId int) (limits userBudgetLimits, err error) {
+	err = DB.Model(&User{}).
+		Select("username", "daily_budget", "weekly_budget").
+		Where("id = ?", userId).
+		Take(&limits).Error
+	if err != nil {
+		return limits, errors.Wrapf(err, "get budget of user %d", userId)
+	}
+	return limits, nil
+}
+
+// cacheGetUserBudgetLimits returns the budget of userId, cached for
+// UserId2GroupCacheSeconds in Redis, or in memory when Redis is disabled,
+// since it is read on every request.
+func cacheGetUserBudgetLimits(ctx context.Context, userId int) (userBudgetLimits, error) {
+	if !common.IsRedisEnabled() {
+		if cached, ok := userBudgetLimitsCache.Load(userId); ok {
+			if entry := cached.(cachedUserBudgetLimits); time.Now().Before(entry.expiresAt) {
+				return entry.limits, nil
+			}
+		}
+		limits, err := getUserBudgetLimits(userId)
+		if err != nil {
+			return limits, err
+		}
+		userBudgetLimitsCache.Store(userId, cachedUserBudgetLimits{
+			limits:    limits,
+			expiresAt: time.Now().Add(time.Duration(UserId2GroupCacheSeconds) * time.Second),
+		})
+		return limits, nil
+	}
+	key := userBudgetLimitsKey(userId)
+	if cached, err := common.RedisGet(ctx, key); err == nil {
+		var limits userBudgetLimits
+		if err = json.Unmarshal([]byte(cached), &limits); err == nil {
+			return limits, nil
+		}
+	}
+	limits, err := getUserBudgetLimits(userId)
+	if err != nil {
+		return limits, err
+	}
+	encoded, err := json.Marshal(limits)
+	if err != nil {
+		return limits, errors.Wrap(err, "marshal user budget")
+	}
+	if err = common.RedisSet(ctx, key, string(encoded), time.Duration(UserId2GroupCacheSeconds)*time.Second); err != nil {
+		logger.Logger.Warn("Redis set user budget failed, continuing without cache", zap.Int("user_id", userId), zap.Error(err))
+	}
+	return limits, nil
+}
+
+// InvalidateUserBudgetCache drops the cached budget of a user so a changed
+// budget applies to the next request.
+func InvalidateUserBudgetCache(ctx context.Context, userId int) {
+	userBudgetLimitsCache.Delete(userId)
+	if !common.IsRedisEnabled() {
+		return
+	}
+	if err := common.RedisDel(ctx, userBudgetLimitsKey(userId)); err != nil {
+		logger.Logger.Warn("failed to invalidate cached user budget", zap.Int("user_id", userId), zap.Error(err))
+	}
+}
+
+// cacheGetUserBudgetSpend returns the quota the user spent in period until now.
+// The spend is read from the consume logs and cached until the period ends, in
+// Redis or in memory when Redis is disabled; RecordConsumeLog adds later spend
+// to the cached value.
+func cacheGetUserBudgetSpend(ctx context.Context, userId int, username string, period budgetPeriod, now time.Time) int64 {
+	if !common.IsRedisEnabled() || common.RDB == nil {
+		memoryKey := userBudgetSpendMemoryKey(userId, period)
+		if cached, ok := userBudgetSpendCache.Load(memoryKey); ok {
+			if entry := cached.(*cachedUserBudgetSpend); entry.period == period.key && now.Before(entry.expiresAt) {
+				return entry.spend.Load()
+			}
+		}
+		spend := SumUsedQuota(LogTypeConsume, period.start.Unix(), period.end.Unix()-1, "", username, "", 0)
+		if period.end.After(now) {
+			entry := &cachedUserBudgetSpend{period: period.key, expiresAt: period.end}
+			entry.spend.Store(spend)
+			userBudgetSpendCache.Store(memoryKey, entry)
+		}
+		return spend
+	}
+
+	key := userBudgetSpendKey(userId, period)
+	if cached, err := common.RedisGet(ctx, key); err == nil {
+		if spend, err := strconv.ParseInt(cached, 10, 64); err == nil {
+			return spend
+		}
+	}
+	spend := SumUsedQuota(LogTypeConsume, period.start.Unix(), period.end.Unix()-1, "", username, "", 0)
+	if ttl := period.end.Sub(now); ttl > 0 {
+		if err := common.RedisSet(ctx, key, strconv.FormatInt(spend, 10), ttl); err != nil {
+			logger.Logger.Warn("Redis set user budget spend failed, continuing without cache", zap.Int("user_id", userId), zap.Error(err))
+		}
+	}
+	return spend
+}
+
+// recordUserBudgetSpend adds quota to the cached spend of the user's current
+// periods. Periods without a cached spend are left alone, they are read from
+// the consume logs on the next budget check.
+func recordUserBudgetSpend(ctx context.Context, userId int, quota int64, now time.Time) {
+	if quota <= 0 {
+		return
+	}
+	day, week := userBudgetPeriods(now)
+	if !common.IsRedisEnabled() || common.RDB == nil {
+		for _, period := range []budgetPeriod{day, week} {
+			if cached, ok := userBudgetSpendCache.Load(userBudgetSpendMemoryKey(userId, period)); ok {
+				if entry := cached.(*cachedUserBudgetSpend); entry.period == period.key {
+					entry.spend.Add(quota)
+				}
+			}
+		}
+		return
+	}
+	for _, period := range []budgetPeriod{day, week} {
+		if err := common.RedisIncreaseIfExists(ctx, userBudgetSpendKey(userId, period), quota); err != nil {
+			logger.Logger.Warn("failed to update cached user budget spend", zap.Int("user_id", userId), zap.Error(err))
+		}
+	}
+}
+
+// CheckUserBudget reports whether the user may start a request at now under
+// their daily and weekly budgets. A budget is exhausted once less than
+// config.PreConsumedQuota of it remains. When a budget is exhausted it returns
+// false and the Unix time in seconds at which all exhausted budgets reset.
+//
+// Spend is summed from consume logs, so budgets are not enforced while
+// consume logging is disabled.
+func CheckUserBudget(ctx context.Context, userId int, now time.Time) (allowed bool, resetAt int64, err error) {
+	limits, err := cacheGetUserBudgetLimits(ctx, userId)
+	if err != nil {
+		return false, 0, err
+	}
+	if limits.DailyBudget <= 0 && limits.WeeklyBudget <= 0 {
+		return true, 0, nil
+	}
+
+	day, week := userBudgetPeriods(now)
+	for _, budget := range []struct {
+		limit  int64
+		period budgetPeriod
+	}{
+		{limits.DailyBudget, day},
+		{limits.WeeklyBudget, week},
+	} {
+		if budget.limit <= 0 {
+			continue
+		}
+		remaining := budget.limit - cacheGetUserBudgetSpend(ctx, userId, limits.Username, budget.period, now)
+		if remaining <= 0 || remaining < config.PreConsumedQuota {
+			resetAt = max(resetAt, budget.period.end.Unix())
+		}
+	}
+	return resetAt == 0, resetAt, nil
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func TestUserBudgetPeriods(t *testing.T) {
+	// Thursday
+	now := time.Date(2026, 1, 8, 15, 30, 0, 0, time.UTC)
+	day, week := userBudgetPeriods(now)
+	require.Equal(t, "day-20260108", day.key)
+	require.Equal(t, time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC), day.end)
+	require.Equal(t, "week-20260105", week.key)
+	require.Equal(t, time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC), week.end)
+
+	// Sunday belongs to the week started on the previous Monday
+	_, week = userBudgetPeriods(time.Date(2026, 1, 11, 23, 0, 0, 0, time.UTC))
+	require.Equal(t, "week-20260105", week.key)
+}
+
+func TestCheckUserBudget(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&User{}, &Log{}))
+	originalDB, originalLogDB := DB, LOG_DB
+	originalRedisEnabled := common.IsRedisEnabled()
+	originalPreConsumed := config.PreConsumedQuota
+	DB, LOG_DB = db, db
+	common.SetRedisEnabled(false)
+	config.PreConsumedQuota = 100
+	t.Cleanup(func() {
+		DB, LOG_DB = originalDB, originalLogDB
+		common.SetRedisEnabled(originalRedisEnabled)
+		config.PreConsumedQuota = originalPreConsumed
+		userBudgetLimitsCache.Clear()
+		userBudgetSpendCache.Clear()
+	})
+
+	users := []*User{
+		{Username: "capped", AccessToken: "t1", AffCode: "a1", DailyBudget: 1000, WeeklyBudget: 3000},
+		{Username: "free", AccessToken: "t2", AffCode: "a2"},
+	}
+	require.NoError(t, db.Create(&users).Error)
+	capped, free := users[0], users[1]
+
+	now := time.Date(2026, 1, 8, 15, 30, 0, 0, time.UTC)
+	ctx := context.Background()
+	// Like RecordConsumeLog, add the spend to the cached spend of its periods
+	consume := func(user *User, quota int, at time.Time) {
+		require.NoError(t, db.Create(&Log{UserId: user.Id, Username: user.Username, Type: LogTypeConsume, Quota: quota, CreatedAt: at.Unix()}).Error)
+		recordUserBudgetSpend(ctx, user.Id, int64(quota), at)
+	}
+
+	consume(capped, 850, now.Add(-time.Hour))
+	consume(capped, 5000, now.AddDate(0, 0, -7)) // previous week
+	allowed, _, err := CheckUserBudget(ctx, capped.Id, now)
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	// 99 left is less than the pre-consumed quota
+	consume(capped, 51, now.Add(-time.Minute))
+	allowed, resetAt, err := CheckUserBudget(ctx, capped.Id, now)
+	require.NoError(t, err)
+	require.False(t, allowed)
+	require.Equal(t, time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC).Unix(), resetAt)
+
+	// Exhausting the weekly budget as well defers the reset to the week's end
+	consume(capped, 2000, now.AddDate(0, 0, -2))
+	allowed, resetAt, err = CheckUserBudget(ctx, capped.Id, now)
+	require.NoError(t, err)
+	require.False(t, allowed)
+	require.Equal(t, time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC).Unix(), resetAt)
+
+	consume(free, 100000, now.Add(-time.Hour))
+	allowed, _, err = CheckUserBudget(ctx, free.Id, now)
+	require.NoError(t, err)
+	require.True(t, allowed)
+}
+
+func TestCheckUserBudgetCachesInMemoryWithoutRedis(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&User{}, &Log{}))
+	originalDB, originalLogDB := DB, LOG_DB
+	originalRedisEnabled := common.IsRedisEnabled()
+	DB, LOG_DB = db, db
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() {
+		DB, LOG_DB = originalDB, originalLogDB
+		common.SetRedisEnabled(originalRedisEnabled)
+		userBudgetLimitsCache.Clear()
+		userBudgetSpendCache.Clear()
+	})
+
+	user := &User{Username: "cached", AccessToken: "t3", AffCode: "a3", DailyBudget: 1000}
+	require.NoError(t, db.Create(user).Error)
+	now := time.Now()
+	ctx := context.Background()
+	require.NoError(t, db.Create(&Log{UserId: user.Id, Username: user.Username, Type: LogTypeConsume, Quota: 500, CreatedAt: now.Unix()}).Error)
+
+	allowed, _, err := CheckUserBudget(ctx, user.Id, now)
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	// Neither the budget nor the spend is read from the database again
+	require.NoError(t, db.Model(user).Update("daily_budget", 100).Error)
+	require.NoError(t, db.Where("user_id = ?", user.Id).Delete(&Log{}).Error)
+	day, _ := userBudgetPeriods(now)
+	require.EqualValues(t, 500, cacheGetUserBudgetSpend(ctx, user.Id, user.Username, day, now))
+	allowed, _, err = CheckUserBudget(ctx, user.Id, now)
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	// Recorded spend and invalidated budgets apply at once
+	recordUserBudgetSpend(ctx, user.Id, 200, now)
+	require.EqualValues(t, 700, cacheGetUserBudgetSpend(ctx, user.Id, user.Username, day, now))
+	InvalidateUserBudgetCache(ctx, user.Id)
+	allowed, _, err = CheckUserBudget(ctx, user.Id, now)
+	require.NoError(t, err)
+	require.False(t, allowed)
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/common/network"
+)
+
+// NormalizeIpAllowlist validates a comma-separated list of CIDR ranges and
+// returns it trimmed, with empty entries dropped. A list without entries
+// normalizes to the empty string, which disables the restriction.
+func NormalizeIpAllowlist(raw string) (string, error) {
+	var ranges []string
+	for _, cidr := range strings.Split(raw, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			ranges = append(ranges, cidr)
+		}
+	}
+	allowlist := strings.Join(ranges, ",")
+	if allowlist == "" {
+		return "", nil
+	}
+	if err := network.IsValidSubnets(allowlist); err != nil {
+		return "", errors.Wrap(err, "invalid IP allowlist")
+	}
+	return allowlist, nil
+}
+
+// UpdateUserIpAllowlist stores an allowlist normalized by NormalizeIpAllowlist.
+func UpdateUserIpAllowlist(id int, allowlist string) error {
+	if err := DB.Model(&User{}).Where("id = ?", id).Update("ip_allowlist", allowlist).Error; err != nil {
+		return errors.Wrapf(err, "update IP allowlist of user %d", id)
+	}
+	return nil
+}
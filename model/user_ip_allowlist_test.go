@@ -0,0 +1,20 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeIpAllowlist(t *testing.T) {
+	allowlist, err := NormalizeIpAllowlist(" 10.0.0.0/8, ,2001:db8::/32 ")
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.0/8,2001:db8::/32", allowlist)
+
+	allowlist, err = NormalizeIpAllowlist(" , ")
+	require.NoError(t, err)
+	require.Empty(t, allowlist)
+
+	_, err = NormalizeIpAllowlist("10.0.0.0/8,10.0.0.1")
+	require.Error(t, err)
+}
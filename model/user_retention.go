@@ -0,0 +1,88 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+const userRetentionSweepInterval = 24 * time.Hour
+
+// StartUserRetentionCleaner launches a background worker that permanently removes users soft-deleted more than retentionDays ago.
+func StartUserRetentionCleaner(ctx context.Context, retentionDays int) {
+	if retentionDays <= 0 {
+		logger.Logger.Debug("user retention disabled", zap.Int("user_retention_days", retentionDays))
+		return
+	}
+
+	cleanup := func() {
+		purged, err := PurgeDeletedUsers(ctx, retentionDays)
+		if err != nil {
+			logger.Logger.Warn("user retention cleanup failed", zap.Error(err))
+			return
+		}
+		if purged > 0 {
+			logger.Logger.Info("purged deleted users", zap.Int64("deleted_rows", purged), zap.Int("user_retention_days", retentionDays))
+		} else {
+			logger.Logger.Debug("user retention sweep completed", zap.Int("user_retention_days", retentionDays))
+		}
+	}
+
+	cleanup()
+
+	ticker := time.NewTicker(userRetentionSweepInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Logger.Info("user retention cleaner stopped")
+				return
+			case <-ticker.C:
+				cleanup()
+			}
+		}
+	}()
+
+	logger.Logger.Info("user retention cleaner started", zap.Int("user_retention_days", retentionDays))
+}
+
+// PurgeDeletedUsers permanently removes the users soft-deleted more than
+// retentionDays ago, together with their tokens and settings. Their logs are
+// kept for billing history. It returns the number of users removed.
+func PurgeDeletedUsers(ctx context.Context, retentionDays int) (int64, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	var ids []int
+	if err := DB.WithContext(ctx).Unscoped().Model(&User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, errors.Wrap(err, "find expired deleted users")
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	var purged int64
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id IN ?", ids).Delete(&Token{}).Error; err != nil {
+			return errors.Wrap(err, "delete tokens of purged users")
+		}
+		if err := tx.Where("user_id IN ?", ids).Delete(&UserSetting{}).Error; err != nil {
+			return errors.Wrap(err, "delete settings of purged users")
+		}
+		result := tx.Unscoped().Where("id IN ?", ids).Delete(&User{})
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "delete purged users")
+		}
+		purged = result.RowsAffected
+		return nil
+	})
+	return purged, err
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+func userSessionVersionKey(id int) string {
+	return fmt.Sprintf("user_session_version:%d", id)
+}
+
+// GetUserSessionVersion returns the session version of a user, see User.SessionVersion.
+func GetUserSessionVersion(id int) (version int, err error) {
+	err = DB.Model(&User{}).Where("id = ?", id).Select("session_version").Take(&version).Error
+	if err != nil {
+		return 0, errors.Wrapf(err, "get session version of user %d", id)
+	}
+	return version, nil
+}
+
+// CacheGetUserSessionVersion returns the session version of a user, cached in
+// Redis when enabled. RevokeUserSessions drops the cached value.
+func CacheGetUserSessionVersion(ctx context.Context, id int) (int, error) {
+	if !common.IsRedisEnabled() {
+		return GetUserSessionVersion(id)
+	}
+	key := userSessionVersionKey(id)
+	if cached, err := common.RedisGet(ctx, key); err == nil {
+		if version, err := strconv.Atoi(cached); err == nil {
+			return version, nil
+		}
+	}
+	version, err := GetUserSessionVersion(id)
+	if err != nil {
+		return 0, err
+	}
+	if err = common.RedisSet(ctx, key, strconv.Itoa(version), time.Duration(UserId2GroupCacheSeconds)*time.Second); err != nil {
+		logger.Logger.Warn("Redis set user session version failed, continuing without cache", zap.Int("user_id", id), zap.Error(err))
+	}
+	return version, nil
+}
+
+// RevokeUserSessions invalidates every login session of the user by bumping
+// its session version, and returns the new version.
+func RevokeUserSessions(ctx context.Context, id int) (int, error) {
+	if err := DB.Model(&User{}).Where("id = ?", id).
+		Update("session_version", gorm.Expr("session_version + 1")).Error; err != nil {
+		return 0, errors.Wrapf(err, "bump session version of user %d", id)
+	}
+	if common.IsRedisEnabled() && common.RDB != nil {
+		// A stale cached version would keep revoked sessions alive until it expires
+		if err := common.RedisDel(ctx, userSessionVersionKey(id)); err != nil {
+			return 0, errors.Wrapf(err, "invalidate cached session version of user %d", id)
+		}
+	}
+	return GetUserSessionVersion(id)
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+func TestRevokeUserSessions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&User{}))
+	originalDB := DB
+	originalRedisEnabled := common.IsRedisEnabled()
+	DB = db
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() {
+		DB = originalDB
+		common.SetRedisEnabled(originalRedisEnabled)
+	})
+
+	user := &User{Username: "session", Password: "password123", AccessToken: "t1", AffCode: "a1", TotpSecret: "SECRET", Email: "s@example.com"}
+	require.NoError(t, db.Create(user).Error)
+	ctx := context.Background()
+
+	version, err := CacheGetUserSessionVersion(ctx, user.Id)
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	version, err = RevokeUserSessions(ctx, user.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+
+	// Password changes and disabling TOTP revoke sessions too
+	update := &User{Id: user.Id, Password: "newpassword1"}
+	require.NoError(t, update.Update(true))
+	require.NoError(t, user.ClearTotpSecret())
+	require.NoError(t, ResetUserPasswordByEmail("s@example.com", "newpassword2"))
+	version, err = CacheGetUserSessionVersion(ctx, user.Id)
+	require.NoError(t, err)
+	require.Equal(t, 4, version)
+}
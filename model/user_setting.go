@@ -0,0 +1,144 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// UserSetting holds the preferences a user customised. Users without a row
+// get the system defaults, see GetUserSetting.
+type UserSetting struct {
+	UserId int `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	// SpendingAlertThreshold is the remaining quota below which the user is
+	// reminded to top up. Nil falls back to config.QuotaRemindThreshold.
+	SpendingAlertThreshold *int64 `json:"spending_alert_threshold"`
+	// EmailAlertsEnabled controls the low and exhausted quota reminder emails.
+	EmailAlertsEnabled bool  `json:"email_alerts_enabled"`
+	UpdatedAt          int64 `json:"updated_at" gorm:"bigint"`
+}
+
+// TableName stores settings in user_settings.
+func (UserSetting) TableName() string {
+	return "user_settings"
+}
+
+// GetUserSetting returns the settings of userId, or the defaults when the user
+// never customised them.
+func GetUserSetting(userId int) (*UserSetting, error) {
+	var settings []UserSetting
+	if err := DB.Where("user_id = ?", userId).Limit(1).Find(&settings).Error; err != nil {
+		return nil, errors.Wrapf(err, "get settings of user %d", userId)
+	}
+	if len(settings) == 0 {
+		return &UserSetting{UserId: userId, EmailAlertsEnabled: true}, nil
+	}
+	return &settings[0], nil
+}
+
+// cachedUserSetting is a user setting cached in memory until expiresAt.
+type cachedUserSetting struct {
+	setting   UserSetting
+	expiresAt time.Time
+}
+
+// userSettingCache caches settings by user ID when Redis is disabled.
+var userSettingCache sync.Map
+
+func userSettingKey(userId int) string {
+	return fmt.Sprintf("user_setting:%d", userId)
+}
+
+// CacheGetUserSetting returns the settings of userId like GetUserSetting,
+// cached for UserId2GroupCacheSeconds in Redis, or in memory when Redis is
+// disabled, since it is read on every consumption.
+func CacheGetUserSetting(ctx context.Context, userId int) (*UserSetting, error) {
+	ttl := time.Duration(UserId2GroupCacheSeconds) * time.Second
+	if !common.IsRedisEnabled() {
+		if cached, ok := userSettingCache.Load(userId); ok {
+			if entry := cached.(cachedUserSetting); time.Now().Before(entry.expiresAt) {
+				setting := entry.setting
+				return &setting, nil
+			}
+		}
+		setting, err := GetUserSetting(userId)
+		if err != nil {
+			return nil, err
+		}
+		userSettingCache.Store(userId, cachedUserSetting{setting: *setting, expiresAt: time.Now().Add(ttl)})
+		return setting, nil
+	}
+
+	key := userSettingKey(userId)
+	if cached, err := common.RedisGet(ctx, key); err == nil {
+		var setting UserSetting
+		if err = json.Unmarshal([]byte(cached), &setting); err == nil {
+			return &setting, nil
+		}
+	}
+	setting, err := GetUserSetting(userId)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(setting)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal user setting")
+	}
+	if err = common.RedisSet(ctx, key, string(encoded), ttl); err != nil {
+		logger.Logger.Warn("Redis set user setting failed, continuing without cache", zap.Int("user_id", userId), zap.Error(err))
+	}
+	return setting, nil
+}
+
+// invalidateUserSettingCache drops the cached settings of userId so changed
+// settings apply to the next consumption.
+func invalidateUserSettingCache(ctx context.Context, userId int) {
+	userSettingCache.Delete(userId)
+	if !common.IsRedisEnabled() {
+		return
+	}
+	if err := common.RedisDel(ctx, userSettingKey(userId)); err != nil {
+		logger.Logger.Warn("failed to invalidate cached user setting", zap.Int("user_id", userId), zap.Error(err))
+	}
+}
+
+// SaveUserSetting stores setting, creating the row on the first save.
+func SaveUserSetting(setting *UserSetting) error {
+	defer invalidateUserSettingCache(context.Background(), setting.UserId)
+
+	setting.UpdatedAt = helper.GetTimestamp()
+	// Update first so zero values such as disabled email alerts are written
+	tx := DB.Model(&UserSetting{}).
+		Where("user_id = ?", setting.UserId).
+		Select("spending_alert_threshold", "email_alerts_enabled", "updated_at").
+		Updates(setting)
+	if tx.Error != nil {
+		return errors.Wrapf(tx.Error, "update settings of user %d", setting.UserId)
+	}
+	if tx.RowsAffected > 0 {
+		return nil
+	}
+	if err := DB.Select("*").Create(setting).Error; err != nil {
+		return errors.Wrapf(err, "create settings of user %d", setting.UserId)
+	}
+	return nil
+}
+
+// SpendingAlertThresholdOrDefault returns the user's threshold, or the
+// system-wide QuotaRemindThreshold when the user has not set one.
+func (s *UserSetting) SpendingAlertThresholdOrDefault() int64 {
+	if s.SpendingAlertThreshold != nil {
+		return *s.SpendingAlertThreshold
+	}
+	return config.QuotaRemindThreshold
+}
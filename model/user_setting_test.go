@@ -0,0 +1,121 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+type quotaReminderCall struct {
+	email     string
+	exhausted bool
+	remaining int64
+}
+
+func captureQuotaReminders(t *testing.T) chan quotaReminderCall {
+	t.Helper()
+	calls := make(chan quotaReminderCall, 4)
+	original := quotaReminder
+	quotaReminder = func(email string, exhausted bool, remaining int64) {
+		calls <- quotaReminderCall{email, exhausted, remaining}
+	}
+	t.Cleanup(func() { quotaReminder = original })
+	return calls
+}
+
+func TestUserSettingDefaults(t *testing.T) {
+	setupSubAccountTest(t)
+	require.NoError(t, DB.AutoMigrate(&UserSetting{}))
+	user := createSubAccountTestUser(t, "settings", 0)
+	t.Cleanup(func() { DB.Where("user_id = ?", user.Id).Delete(&UserSetting{}) })
+
+	setting, err := GetUserSetting(user.Id)
+	require.NoError(t, err)
+	require.Nil(t, setting.SpendingAlertThreshold)
+	require.True(t, setting.EmailAlertsEnabled)
+	require.Equal(t, config.QuotaRemindThreshold, setting.SpendingAlertThresholdOrDefault())
+
+	threshold := int64(42)
+	setting.SpendingAlertThreshold = &threshold
+	setting.EmailAlertsEnabled = false
+	require.NoError(t, SaveUserSetting(setting))
+	require.NoError(t, SaveUserSetting(setting))
+
+	stored, err := GetUserSetting(user.Id)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), stored.SpendingAlertThresholdOrDefault())
+	require.False(t, stored.EmailAlertsEnabled)
+}
+
+func TestCacheGetUserSetting(t *testing.T) {
+	setupSubAccountTest(t)
+	require.NoError(t, DB.AutoMigrate(&UserSetting{}))
+	user := createSubAccountTestUser(t, "cached-settings", 0)
+	t.Cleanup(func() {
+		DB.Where("user_id = ?", user.Id).Delete(&UserSetting{})
+		userSettingCache.Delete(user.Id)
+	})
+	ctx := context.Background()
+
+	setting, err := CacheGetUserSetting(ctx, user.Id)
+	require.NoError(t, err)
+	require.True(t, setting.EmailAlertsEnabled)
+
+	// Served from memory without reading the database again
+	require.NoError(t, DB.Create(&UserSetting{UserId: user.Id, EmailAlertsEnabled: false}).Error)
+	setting, err = CacheGetUserSetting(ctx, user.Id)
+	require.NoError(t, err)
+	require.True(t, setting.EmailAlertsEnabled)
+
+	// Saving drops the cached copy
+	threshold := int64(42)
+	require.NoError(t, SaveUserSetting(&UserSetting{UserId: user.Id, SpendingAlertThreshold: &threshold}))
+	setting, err = CacheGetUserSetting(ctx, user.Id)
+	require.NoError(t, err)
+	require.False(t, setting.EmailAlertsEnabled)
+	require.Equal(t, int64(42), setting.SpendingAlertThresholdOrDefault())
+}
+
+func TestPostConsumeTokenQuotaCustomSpendingAlert(t *testing.T) {
+	setupSubAccountTest(t)
+	require.NoError(t, DB.AutoMigrate(&UserSetting{}))
+	calls := captureQuotaReminders(t)
+
+	originalThreshold := config.QuotaRemindThreshold
+	config.QuotaRemindThreshold = 1000
+	t.Cleanup(func() { config.QuotaRemindThreshold = originalThreshold })
+
+	custom := createSubAccountTestUser(t, "custom-alert", 6000)
+	require.NoError(t, DB.Model(custom).Update("email", "custom@example.com").Error)
+	threshold := int64(5000)
+	require.NoError(t, SaveUserSetting(&UserSetting{UserId: custom.Id, SpendingAlertThreshold: &threshold, EmailAlertsEnabled: true}))
+	t.Cleanup(func() { DB.Where("user_id = ?", custom.Id).Delete(&UserSetting{}) })
+	defaulted := createSubAccountTestUser(t, "default-alert", 6000)
+
+	// 6000 -> 4500 crosses the custom 5000 threshold, far above the global 1000
+	require.NoError(t, PostConsumeTokenQuota(context.Background(), createSubAccountTestToken(t, custom.Id).Id, 1500))
+	select {
+	case call := <-calls:
+		require.Equal(t, "custom@example.com", call.email)
+		require.False(t, call.exhausted)
+		require.Equal(t, int64(6000), call.remaining)
+	case <-time.After(time.Second):
+		t.Fatal("expected a spending alert for the custom threshold")
+	}
+
+	// Below the custom threshold already, and refunds never alert
+	tokenId := createSubAccountTestToken(t, custom.Id).Id
+	require.NoError(t, PostConsumeTokenQuota(context.Background(), tokenId, 500))
+	require.NoError(t, PostConsumeTokenQuota(context.Background(), tokenId, -500))
+	// The same consumption stays above the global default
+	require.NoError(t, PostConsumeTokenQuota(context.Background(), createSubAccountTestToken(t, defaulted.Id).Id, 1500))
+	select {
+	case call := <-calls:
+		t.Fatalf("unexpected reminder %+v", call)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
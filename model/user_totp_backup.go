@@ -0,0 +1,108 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+const (
+	// TotpBackupCodeCount is how many backup codes GenerateTotpBackupCodes issues.
+	TotpBackupCodeCount = 8
+	// totpBackupCodeBytes is the random length of a backup code, 8 hex characters.
+	totpBackupCodeBytes = 4
+)
+
+// GenerateTotpBackupCodes replaces the user's TOTP backup codes with
+// TotpBackupCodeCount new single-use codes and returns them. Only bcrypt
+// hashes are stored, so the codes cannot be shown again.
+func GenerateTotpBackupCodes(userId int) ([]string, error) {
+	codes := make([]string, 0, TotpBackupCodeCount)
+	hashes := make([]string, 0, TotpBackupCodeCount)
+	for range TotpBackupCodeCount {
+		raw := make([]byte, totpBackupCodeBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, errors.Wrap(err, "generate TOTP backup code")
+		}
+		code := hex.EncodeToString(raw)
+		hash, err := common.Password2Hash(code)
+		if err != nil {
+			return nil, errors.Wrap(err, "hash TOTP backup code")
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+
+	encoded, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal TOTP backup codes")
+	}
+	if err = DB.Model(&User{}).Where("id = ?", userId).
+		Update("totp_backup_codes", string(encoded)).Error; err != nil {
+		return nil, errors.Wrapf(err, "save TOTP backup codes for user %d", userId)
+	}
+	return codes, nil
+}
+
+// ConsumeTotpBackupCode reports whether code is one of the user's unused TOTP
+// backup codes, and if so removes it so it cannot be used again.
+func ConsumeTotpBackupCode(userId int, code string) (bool, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return false, nil
+	}
+	var stored string
+	if err := DB.Model(&User{}).Where("id = ?", userId).
+		Select("totp_backup_codes").Scan(&stored).Error; err != nil {
+		return false, errors.Wrapf(err, "get TOTP backup codes for user %d", userId)
+	}
+	hashes, err := decodeTotpBackupCodes(stored)
+	if err != nil {
+		return false, errors.Wrapf(err, "user %d", userId)
+	}
+
+	for i, hash := range hashes {
+		if !common.ValidatePasswordAndHash(code, hash) {
+			continue
+		}
+		remaining, err := json.Marshal(append(hashes[:i:i], hashes[i+1:]...))
+		if err != nil {
+			return false, errors.Wrap(err, "marshal TOTP backup codes")
+		}
+		// Matching the stored value keeps two concurrent logins from both
+		// spending the same code
+		result := DB.Model(&User{}).
+			Where("id = ? AND totp_backup_codes = ?", userId, stored).
+			Update("totp_backup_codes", string(remaining))
+		if result.Error != nil {
+			return false, errors.Wrapf(result.Error, "consume TOTP backup code for user %d", userId)
+		}
+		return result.RowsAffected > 0, nil
+	}
+	return false, nil
+}
+
+// CountTotpBackupCodes returns how many unused TOTP backup codes the user has.
+func CountTotpBackupCodes(user *User) int {
+	hashes, err := decodeTotpBackupCodes(user.TotpBackupCodes)
+	if err != nil {
+		return 0
+	}
+	return len(hashes)
+}
+
+func decodeTotpBackupCodes(stored string) ([]string, error) {
+	if stored == "" {
+		return nil, nil
+	}
+	var hashes []string
+	if err := json.Unmarshal([]byte(stored), &hashes); err != nil {
+		return nil, errors.Wrap(err, "decode TOTP backup codes")
+	}
+	return hashes, nil
+}
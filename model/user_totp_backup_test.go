@@ -0,0 +1,63 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+func TestTotpBackupCodes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&User{}))
+	originalDB := DB
+	originalRedisEnabled := common.IsRedisEnabled()
+	DB = db
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() {
+		DB = originalDB
+		common.SetRedisEnabled(originalRedisEnabled)
+	})
+
+	user := &User{Username: "totp", AccessToken: "t1", AffCode: "a1", TotpSecret: "SECRET"}
+	require.NoError(t, db.Create(user).Error)
+
+	codes, err := GenerateTotpBackupCodes(user.Id)
+	require.NoError(t, err)
+	require.Len(t, codes, TotpBackupCodeCount)
+	for _, code := range codes {
+		require.Regexp(t, "^[0-9a-f]{8}$", code)
+	}
+
+	stored, err := GetUserById(user.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, TotpBackupCodeCount, CountTotpBackupCodes(stored))
+	require.NotContains(t, stored.TotpBackupCodes, codes[0])
+
+	consumed, err := ConsumeTotpBackupCode(user.Id, "00000000")
+	require.NoError(t, err)
+	require.False(t, consumed)
+
+	consumed, err = ConsumeTotpBackupCode(user.Id, codes[3])
+	require.NoError(t, err)
+	require.True(t, consumed)
+
+	// Each code works once
+	consumed, err = ConsumeTotpBackupCode(user.Id, codes[3])
+	require.NoError(t, err)
+	require.False(t, consumed)
+
+	stored, err = GetUserById(user.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, TotpBackupCodeCount-1, CountTotpBackupCodes(stored))
+
+	// Disabling TOTP drops the remaining codes
+	require.NoError(t, stored.ClearTotpSecret())
+	consumed, err = ConsumeTotpBackupCode(user.Id, codes[0])
+	require.NoError(t, err)
+	require.False(t, consumed)
+}
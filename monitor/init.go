@@ -1,10 +1,17 @@
 package monitor
 
 import (
+	"context"
 	"runtime"
 	"time"
 
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
 	"github.com/songquanpeng/one-api/common/metrics"
+	"github.com/songquanpeng/one-api/model"
 	"github.com/songquanpeng/one-api/monitor/prometheus"
 )
 
@@ -48,12 +55,38 @@ func collectChannelMetrics() {
 	}
 }
 
-// collectUserMetrics collects user-related metrics periodically
+// collectUserMetrics refreshes the active users of every user group each
+// SyncFrequency seconds. It only runs on the master node, so the query does not
+// run on every node and the gauge is exported once. Per-request user metrics
+// are recorded through the metrics interface.
 func collectUserMetrics() {
-	ticker := time.NewTicker(2 * time.Minute)
+	if !config.IsMasterNode {
+		return
+	}
+	interval := time.Duration(config.SyncFrequency) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		// User metrics are recorded per-request through the metrics interface
+		if err := updateActiveUserMetrics(context.Background()); err != nil {
+			logger.Logger.Warn("failed to update active user metrics", zap.Error(err))
+		}
+	}
+}
+
+// updateActiveUserMetrics publishes the active users of every user group.
+func updateActiveUserMetrics(ctx context.Context) error {
+	stats, err := model.GetGroupQuotaStats(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get group quota stats")
 	}
+	activeUsers := make(map[string]int, len(stats))
+	for _, stat := range stats {
+		activeUsers[stat.Group] = stat.ActiveUsers
+	}
+	metrics.GlobalRecorder.UpdateActiveUsers(activeUsers)
+	return nil
 }
@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/metrics"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/monitor/prometheus"
+)
+
+func setupMonitorTestDB(t *testing.T) {
+	t.Helper()
+
+	originalRedisEnabled := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	originalSQLitePath := common.SQLitePath
+	common.SQLitePath = filepath.Join(t.TempDir(), "monitor.db")
+	originalLogConsume := config.IsLogConsumeEnabled()
+	config.SetLogConsumeEnabled(true)
+	originalRecorder := metrics.GlobalRecorder
+	metrics.GlobalRecorder = &prometheus.PrometheusRecorder{}
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedisEnabled)
+		common.SQLitePath = originalSQLitePath
+		config.SetLogConsumeEnabled(originalLogConsume)
+		metrics.GlobalRecorder = originalRecorder
+	})
+
+	model.InitDB()
+	model.InitLogDB()
+	t.Cleanup(func() {
+		if model.DB != nil {
+			require.NoError(t, model.CloseDB())
+			model.DB = nil
+			model.LOG_DB = nil
+		}
+	})
+}
+
+func TestQuotaConsumedByGroupAndModelFamily(t *testing.T) {
+	setupMonitorTestDB(t)
+
+	// Three users in the default group and two vip users
+	var userIds []int
+	for i, group := range []string{"alloc-default", "alloc-default", "alloc-default", "alloc-vip", "alloc-vip"} {
+		user := &model.User{
+			Username:    fmt.Sprintf("alloc-user-%d", i),
+			Password:    "x",
+			Group:       group,
+			AccessToken: fmt.Sprintf("alloc-access-token-%d", i),
+			AffCode:     fmt.Sprintf("al%02d", i),
+		}
+		require.NoError(t, model.DB.Create(user).Error)
+		userIds = append(userIds, user.Id)
+	}
+
+	// 100 consume logs: 60 from the default group, 40 from the vip group,
+	// alternating between two model families
+	for i := range 100 {
+		userId := userIds[i%3]
+		if i >= 60 {
+			userId = userIds[3+i%2]
+		}
+		modelName := "gpt-4o-mini"
+		if i%2 == 1 {
+			modelName = "Claude-3-5-Sonnet"
+		}
+		model.RecordConsumeLog(context.Background(), &model.Log{UserId: userId, ModelName: modelName, Quota: 10})
+	}
+
+	require.Equal(t, map[string]float64{
+		"gpt/alloc-default":    300,
+		"claude/alloc-default": 300,
+		"gpt/alloc-vip":        200,
+		"claude/alloc-vip":     200,
+	}, gatherSeries(t, "one_api_quota_consumed_total", "alloc-"))
+
+	stats, err := model.GetGroupQuotaStats(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []model.GroupQuotaStat{
+		{Group: "alloc-default", ActiveUsers: 3, Quota: 600},
+		{Group: "alloc-vip", ActiveUsers: 2, Quota: 400},
+	}, stats)
+
+	require.NoError(t, updateActiveUserMetrics(context.Background()))
+	require.Equal(t, map[string]float64{"alloc-default": 3, "alloc-vip": 2},
+		gatherSeries(t, "one_api_active_users_total", "alloc-"))
+}
+
+// gatherSeries returns the values of the series of metric name with the
+// user_group label starting with prefix, keyed by their label values in label
+// name order joined with "/".
+func gatherSeries(t *testing.T, name, prefix string) map[string]float64 {
+	t.Helper()
+	families, err := promclient.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	series := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			var values []string
+			matched := false
+			for _, label := range metric.GetLabel() {
+				values = append(values, label.GetValue())
+				if label.GetName() == "user_group" {
+					matched = strings.HasPrefix(label.GetValue(), prefix)
+				}
+			}
+			if !matched {
+				continue
+			}
+			value := metric.GetGauge().GetValue()
+			if metric.GetCounter() != nil {
+				value = metric.GetCounter().GetValue()
+			}
+			series[strings.Join(values, "/")] = value
+		}
+	}
+	return series
+}
@@ -0,0 +1,95 @@
+package prometheus
+
+import (
+	"sort"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// sizeHistogram is a cumulative histogram merged across label values.
+type sizeHistogram struct {
+	count   uint64
+	buckets map[float64]uint64 // upper bound -> cumulative count
+}
+
+// ResponseSizeQuantiles estimates the q-quantile (0 < q <= 1) of the response
+// size of every model recorded in one_api_upstream_response_bytes since the
+// process started, merging all channels and response types. Like PromQL's
+// histogram_quantile, it interpolates linearly within the bucket holding the
+// quantile and reports the largest finite bound when it falls beyond it.
+func ResponseSizeQuantiles(q float64) (map[string]float64, error) {
+	if q <= 0 || q > 1 {
+		return nil, errors.Errorf("quantile %v out of range (0, 1]", q)
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		upstreamResponseBytes.Collect(ch)
+		close(ch)
+	}()
+
+	histograms := make(map[string]*sizeHistogram)
+	var collectErr error
+	for metric := range ch {
+		var pb dto.Metric
+		if err := metric.Write(&pb); err != nil {
+			// Keep draining so the collector goroutine finishes
+			collectErr = errors.Wrap(err, "read response size histogram")
+			continue
+		}
+		var modelName string
+		for _, label := range pb.GetLabel() {
+			if label.GetName() == "model" {
+				modelName = label.GetValue()
+			}
+		}
+		merged, ok := histograms[modelName]
+		if !ok {
+			merged = &sizeHistogram{buckets: make(map[float64]uint64)}
+			histograms[modelName] = merged
+		}
+		merged.count += pb.GetHistogram().GetSampleCount()
+		for _, bucket := range pb.GetHistogram().GetBucket() {
+			merged.buckets[bucket.GetUpperBound()] += bucket.GetCumulativeCount()
+		}
+	}
+	if collectErr != nil {
+		return nil, collectErr
+	}
+
+	quantiles := make(map[string]float64, len(histograms))
+	for modelName, histogram := range histograms {
+		if histogram.count == 0 {
+			continue
+		}
+		quantiles[modelName] = histogram.quantile(q)
+	}
+	return quantiles, nil
+}
+
+// quantile returns the interpolated q-quantile of a non-empty histogram.
+func (h *sizeHistogram) quantile(q float64) float64 {
+	bounds := make([]float64, 0, len(h.buckets))
+	for bound := range h.buckets {
+		bounds = append(bounds, bound)
+	}
+	sort.Float64s(bounds)
+
+	rank := q * float64(h.count)
+	var lowerBound float64
+	var lowerCount uint64
+	for _, bound := range bounds {
+		count := h.buckets[bound]
+		if float64(count) >= rank {
+			if count == lowerCount {
+				return bound
+			}
+			return lowerBound + (bound-lowerBound)*(rank-float64(lowerCount))/float64(count-lowerCount)
+		}
+		lowerBound, lowerCount = bound, count
+	}
+	// The quantile lies in the +Inf bucket
+	return lowerBound
+}
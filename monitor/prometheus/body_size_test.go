@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordUpstreamBodySizes_Buckets(t *testing.T) {
+	recorder := &PrometheusRecorder{}
+	// 100 requests: 40 under 1KB, 30 under 10KB, 20 under 100KB, 5 under 1MB,
+	// 3 under 10MB and 2 above
+	sizes := []struct {
+		bytes int64
+		n     int
+	}{
+		{512, 40},
+		{5 << 10, 30},
+		{50 << 10, 20},
+		{512 << 10, 5},
+		{5 << 20, 3},
+		{20 << 20, 2},
+	}
+	for _, size := range sizes {
+		for range size.n {
+			recorder.RecordUpstreamBodySizes("test-bucket-model", 7, "non_stream", 2048, size.bytes)
+		}
+	}
+	// Exactly on a bound counts in that bucket; the request size is unknown
+	recorder.RecordUpstreamBodySizes("test-bucket-model", 7, "stream", -1, 1<<10)
+
+	var response dto.Metric
+	require.NoError(t, upstreamResponseBytes.WithLabelValues("test-bucket-model", "7", "non_stream").(prometheus.Metric).Write(&response))
+	var cumulative []uint64
+	for _, bucket := range response.GetHistogram().GetBucket() {
+		cumulative = append(cumulative, bucket.GetCumulativeCount())
+	}
+	require.Equal(t, []uint64{40, 70, 90, 95, 98}, cumulative)
+	require.EqualValues(t, 100, response.GetHistogram().GetSampleCount())
+
+	var request dto.Metric
+	require.NoError(t, upstreamRequestBytes.WithLabelValues("test-bucket-model", "7").(prometheus.Metric).Write(&request))
+	require.EqualValues(t, 100, request.GetHistogram().GetSampleCount())
+	require.EqualValues(t, 100, request.GetHistogram().GetBucket()[1].GetCumulativeCount())
+
+	quantiles, err := ResponseSizeQuantiles(0.99)
+	require.NoError(t, err)
+	// 101 samples across both types, the 99th percentile lies above 10MB
+	require.Equal(t, float64(10<<20), quantiles["test-bucket-model"])
+
+	quantiles, err = ResponseSizeQuantiles(0.5)
+	require.NoError(t, err)
+	// Rank 50.5 falls 9.5 samples into the 30 of the (1KB, 10KB] bucket
+	require.InDelta(t, 1024+9216*9.5/30, quantiles["test-bucket-model"], 1e-6)
+
+	_, err = ResponseSizeQuantiles(0)
+	require.Error(t, err)
+}
+
+func TestSizeHistogramQuantile(t *testing.T) {
+	h := &sizeHistogram{count: 10, buckets: map[float64]uint64{100: 0, 200: 10, 300: 10}}
+	require.InDelta(t, 190, h.quantile(0.9), 1e-9)
+	require.InDelta(t, 200, h.quantile(1), 1e-9)
+}
@@ -2,6 +2,7 @@ package prometheus
 
 import (
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -13,6 +14,10 @@ import (
 // PrometheusRecorder implements the MetricsRecorder interface using Prometheus
 type PrometheusRecorder struct{}
 
+// bodySizeBuckets are the histogram buckets of request and response sizes:
+// 1KB, 10KB, 100KB, 1MB and 10MB.
+var bodySizeBuckets = []float64{1 << 10, 10 << 10, 100 << 10, 1 << 20, 10 << 20}
+
 // Prometheus metrics definitions
 var (
 	// HTTP request metrics
@@ -80,6 +85,20 @@ var (
 		Help: "Number of requests currently being processed by channel",
 	}, []string{"channel_id", "channel_name", "channel_type"})
 
+	channelConcurrentRequests = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "one_api_channel_concurrent_requests",
+		Help: "Number of concurrency slots this instance holds on channels with a concurrency limit",
+	}, []string{"channel_id"})
+
+	logQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "one_api_log_queue_depth",
+		Help: "Number of log entries waiting in the async log write queue",
+	})
+	logRetentionDeletedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "one_api_log_retention_deleted_total",
+		Help: "Total number of log entries deleted by the log retention worker",
+	}, []string{"type"})
+
 	// User metrics
 	userRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "one_api_user_requests_total",
@@ -191,6 +210,66 @@ var (
 		Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
 	}, []string{"model_name", "channel_type"})
 
+	// Token count accuracy metrics
+	tokenCountDiscrepancyTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "one_api_token_count_discrepancy_total",
+		Help: "Requests whose estimated prompt tokens deviate from upstream usage beyond the threshold",
+	}, []string{"model"})
+
+	tokenCountDiscrepancyRatio = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "one_api_token_count_discrepancy_ratio",
+		Help:    "Ratio between estimated and upstream-reported prompt tokens (larger over smaller)",
+		Buckets: []float64{1, 1.05, 1.1, 1.25, 1.5, 2, 3, 5, 10},
+	}, []string{"model"})
+
+	// Upstream payload size metrics
+	upstreamRequestBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "one_api_upstream_request_bytes",
+		Help:    "Size of request bodies sent upstream in bytes",
+		Buckets: bodySizeBuckets,
+	}, []string{"model", "channel"})
+
+	upstreamResponseBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "one_api_upstream_response_bytes",
+		Help:    "Size of response bodies read from upstream in bytes, the total of all chunks for streams",
+		Buckets: bodySizeBuckets,
+	}, []string{"model", "channel", "type"})
+
+	// Config metrics
+	configReloadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "one_api_config_reloads_total",
+		Help: "Total number of runtime config reloads triggered by SIGHUP",
+	}, []string{"success"})
+
+	// Embedding cache metrics
+	embeddingCacheHitRatio = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "one_api_embedding_cache_hit_ratio",
+		Help: "Share of embedding inputs answered from the embedding cache since startup",
+	}, []string{"model"})
+
+	// Auto deduplication metrics
+	autoDedupHitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "one_api_auto_dedup_hits_total",
+		Help: "Total chat completions answered with the cached response of an identical request",
+	}, []string{"model"})
+
+	// Blacklist metrics
+	blacklistLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "one_api_blacklist_lookups_total",
+		Help: "Total user ban lookups by result: local_hit, redis_hit, database_hit or miss",
+	}, []string{"result"})
+
+	// Cost allocation metrics
+	quotaConsumedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "one_api_quota_consumed_total",
+		Help: "Total quota consumed, by user group and model family",
+	}, []string{"user_group", "model_family"})
+
+	activeUsersTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "one_api_active_users_total",
+		Help: "Number of users with consumption in the last 24 hours, by user group",
+	}, []string{"user_group"})
+
 	// Billing metrics
 	billingOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "one_api_billing_operation_duration_seconds",
@@ -281,6 +360,21 @@ func (p *PrometheusRecorder) UpdateChannelRequestsInFlight(channelId int, channe
 	channelRequestsInFlight.WithLabelValues(channelIdStr, channelName, channelType).Add(delta)
 }
 
+// UpdateChannelConcurrentRequests updates the concurrency slots held on a channel
+func (p *PrometheusRecorder) UpdateChannelConcurrentRequests(channelId int, delta float64) {
+	channelConcurrentRequests.WithLabelValues(strconv.Itoa(channelId)).Add(delta)
+}
+
+// UpdateLogQueueDepth updates the number of log entries waiting to be written
+func (p *PrometheusRecorder) UpdateLogQueueDepth(depth int) {
+	logQueueDepth.Set(float64(depth))
+}
+
+// RecordLogRetentionDeleted records the log entries of logType deleted by a retention run
+func (p *PrometheusRecorder) RecordLogRetentionDeleted(logType string, deleted int64) {
+	logRetentionDeletedTotal.WithLabelValues(logType).Add(float64(deleted))
+}
+
 // RecordUserMetrics records user-related metrics
 func (p *PrometheusRecorder) RecordUserMetrics(userId, username, group string, quotaUsed float64, promptTokens, completionTokens int, balance float64) {
 	userRequestsTotal.WithLabelValues(userId, username, group).Inc()
@@ -357,6 +451,78 @@ func (p *PrometheusRecorder) RecordModelUsage(modelName, channelType string, lat
 	modelLatency.WithLabelValues(modelName, channelType).Observe(latency.Seconds())
 }
 
+// RecordTokenCountDiscrepancy records how far a prompt token estimate was from upstream usage
+func (p *PrometheusRecorder) RecordTokenCountDiscrepancy(modelName string, ratio float64, exceeded bool) {
+	tokenCountDiscrepancyRatio.WithLabelValues(modelName).Observe(ratio)
+	if exceeded {
+		tokenCountDiscrepancyTotal.WithLabelValues(modelName).Inc()
+	}
+}
+
+// RecordUpstreamBodySizes records the request and response body sizes of a
+// relayed request. Negative sizes are unknown and not observed.
+func (p *PrometheusRecorder) RecordUpstreamBodySizes(modelName string, channelId int, responseType string, requestBytes, responseBytes int64) {
+	channelIdStr := strconv.Itoa(channelId)
+	if requestBytes >= 0 {
+		upstreamRequestBytes.WithLabelValues(modelName, channelIdStr).Observe(float64(requestBytes))
+	}
+	if responseBytes >= 0 {
+		upstreamResponseBytes.WithLabelValues(modelName, channelIdStr, responseType).Observe(float64(responseBytes))
+	}
+}
+
+// RecordConfigReload records a runtime config reload
+func (p *PrometheusRecorder) RecordConfigReload(success bool) {
+	configReloadsTotal.WithLabelValues(strconv.FormatBool(success)).Inc()
+}
+
+// embeddingCacheCounts accumulates the embedding cache hits and lookups of
+// each model behind embeddingCacheHitRatio.
+var embeddingCacheCounts = struct {
+	sync.Mutex
+	hits, total map[string]int64
+}{hits: map[string]int64{}, total: map[string]int64{}}
+
+// RecordEmbeddingCacheLookup records the hits among the total inputs of one
+// embedding request and updates the model's cumulative hit ratio
+func (p *PrometheusRecorder) RecordEmbeddingCacheLookup(modelName string, hits, total int) {
+	if total <= 0 {
+		return
+	}
+	embeddingCacheCounts.Lock()
+	defer embeddingCacheCounts.Unlock()
+	embeddingCacheCounts.hits[modelName] += int64(hits)
+	embeddingCacheCounts.total[modelName] += int64(total)
+	embeddingCacheHitRatio.WithLabelValues(modelName).Set(
+		float64(embeddingCacheCounts.hits[modelName]) / float64(embeddingCacheCounts.total[modelName]))
+}
+
+// RecordAutoDedupHit records a chat completion answered from the auto deduplication cache
+func (p *PrometheusRecorder) RecordAutoDedupHit(modelName string) {
+	autoDedupHitsTotal.WithLabelValues(modelName).Inc()
+}
+
+// RecordBlacklistLookup records a user ban lookup and where its answer came from
+func (p *PrometheusRecorder) RecordBlacklistLookup(result string) {
+	blacklistLookupsTotal.WithLabelValues(result).Inc()
+}
+
+// RecordQuotaConsumed records quota consumed by a user of userGroup on modelName
+func (p *PrometheusRecorder) RecordQuotaConsumed(userGroup, modelName string, quota int64) {
+	if quota <= 0 {
+		return
+	}
+	quotaConsumedTotal.WithLabelValues(userGroup, metrics.ModelFamily(modelName)).Add(float64(quota))
+}
+
+// UpdateActiveUsers replaces the active user counts of every group
+func (p *PrometheusRecorder) UpdateActiveUsers(activeUsers map[string]int) {
+	activeUsersTotal.Reset()
+	for group, count := range activeUsers {
+		activeUsersTotal.WithLabelValues(group).Set(float64(count))
+	}
+}
+
 // RecordBillingOperation records billing operation metrics
 func (p *PrometheusRecorder) RecordBillingOperation(startTime time.Time, operation string, success bool, userId int, channelId int, modelName string, quotaAmount float64) {
 	duration := time.Since(startTime).Seconds()
@@ -0,0 +1,86 @@
+package adaptor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+)
+
+// adaptiveRequestTimeout returns the upstream timeout for a request asking for
+// maxTokens output tokens, or 0 when adaptive timeouts are disabled.
+func adaptiveRequestTimeout(maxTokens int) time.Duration {
+	if config.AdaptiveTimeoutBaseSeconds <= 0 {
+		return 0
+	}
+
+	timeout := time.Duration(config.AdaptiveTimeoutBaseSeconds) * time.Second
+	if maxTokens > 0 && config.AdaptiveTimeoutPerTokenMs > 0 {
+		timeout += time.Duration(float64(maxTokens) * config.AdaptiveTimeoutPerTokenMs * float64(time.Millisecond))
+	}
+	if config.RelayTimeout > 0 {
+		timeout = min(timeout, time.Duration(config.RelayTimeout)*time.Second)
+	}
+	return timeout
+}
+
+// requestedMaxTokens returns the output token limit of the client's JSON
+// request, accepting the field names used by the Chat Completions, Claude
+// Messages and Response APIs. It returns 0 when no limit is set. Only the
+// cached request body is inspected, so an unread body is never drained.
+func requestedMaxTokens(c *gin.Context) int {
+	if !strings.HasPrefix(c.Request.Header.Get("Content-Type"), "application/json") {
+		return 0
+	}
+	cached, _ := c.Get(ctxkey.KeyRequestBody)
+	body, ok := cached.([]byte)
+	if !ok || len(body) == 0 {
+		return 0
+	}
+
+	var limits struct {
+		MaxTokens           int `json:"max_tokens"`
+		MaxCompletionTokens int `json:"max_completion_tokens"`
+		MaxOutputTokens     int `json:"max_output_tokens"`
+	}
+	if err := json.Unmarshal(body, &limits); err != nil {
+		return 0
+	}
+	return max(limits.MaxTokens, limits.MaxCompletionTokens, limits.MaxOutputTokens, 0)
+}
+
+// withAdaptiveTimeout bounds req by the adaptive timeout for the current
+// request. The returned cancel func must be called when the request fails, or
+// handed to the response body via cancelOnCloseBody.
+func withAdaptiveTimeout(c *gin.Context, req *http.Request) (*http.Request, time.Duration, context.CancelFunc) {
+	if config.AdaptiveTimeoutBaseSeconds <= 0 {
+		return req, 0, func() {}
+	}
+	timeout := adaptiveRequestTimeout(requestedMaxTokens(c))
+	if timeout <= 0 {
+		return req, 0, func() {}
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	return req.WithContext(ctx), timeout, cancel
+}
+
+// cancelOnCloseBody releases the adaptive timeout context once the response
+// body has been consumed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels its request context.
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
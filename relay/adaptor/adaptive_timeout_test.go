@@ -0,0 +1,102 @@
+package adaptor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+)
+
+func useAdaptiveTimeout(t *testing.T, baseSeconds int, perTokenMs float64, relayTimeout int) {
+	t.Helper()
+	originalBase, originalPerToken, originalRelay := config.AdaptiveTimeoutBaseSeconds, config.AdaptiveTimeoutPerTokenMs, config.RelayTimeout
+	config.AdaptiveTimeoutBaseSeconds, config.AdaptiveTimeoutPerTokenMs, config.RelayTimeout = baseSeconds, perTokenMs, relayTimeout
+	t.Cleanup(func() {
+		config.AdaptiveTimeoutBaseSeconds, config.AdaptiveTimeoutPerTokenMs, config.RelayTimeout = originalBase, originalPerToken, originalRelay
+	})
+}
+
+// newJSONContext builds a context whose request body was already cached, as the
+// relay distributor does.
+func newJSONContext(body string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(ctxkey.KeyRequestBody, []byte(body))
+	return c
+}
+
+func TestAdaptiveRequestTimeout_ScalesWithMaxTokens(t *testing.T) {
+	useAdaptiveTimeout(t, 30, 0.5, 0)
+
+	short := adaptiveRequestTimeout(100)
+	long := adaptiveRequestTimeout(1000)
+	require.Equal(t, 30*time.Second+50*time.Millisecond, short)
+	require.Equal(t, 30*time.Second+500*time.Millisecond, long)
+	// 900 additional tokens at 0.5ms each
+	require.Equal(t, 450*time.Millisecond, long-short)
+	require.Equal(t, 30*time.Second+64*time.Second, adaptiveRequestTimeout(128000))
+	require.Equal(t, 30*time.Second, adaptiveRequestTimeout(0))
+}
+
+func TestAdaptiveRequestTimeout_CappedAtRelayTimeout(t *testing.T) {
+	useAdaptiveTimeout(t, 30, 0.5, 60)
+	require.Equal(t, 60*time.Second, adaptiveRequestTimeout(128000))
+	require.Equal(t, 30*time.Second+500*time.Millisecond, adaptiveRequestTimeout(1000))
+}
+
+func TestAdaptiveRequestTimeout_DisabledWithoutBase(t *testing.T) {
+	useAdaptiveTimeout(t, 0, 0.5, 60)
+	require.Zero(t, adaptiveRequestTimeout(128000))
+
+	req := httptest.NewRequest(http.MethodPost, "http://upstream/v1/chat/completions", nil)
+	bounded, timeout, cancel := withAdaptiveTimeout(newJSONContext(`{"max_tokens":1000}`), req)
+	defer cancel()
+	require.Zero(t, timeout)
+	require.Same(t, req, bounded)
+}
+
+func TestRequestedMaxTokens(t *testing.T) {
+	require.Equal(t, 1000, requestedMaxTokens(newJSONContext(`{"model":"gpt-4o","max_tokens":1000}`)))
+	require.Equal(t, 2048, requestedMaxTokens(newJSONContext(`{"max_completion_tokens":2048}`)))
+	require.Equal(t, 512, requestedMaxTokens(newJSONContext(`{"max_output_tokens":512}`)))
+	require.Zero(t, requestedMaxTokens(newJSONContext(`{"model":"gpt-4o"}`)))
+	require.Zero(t, requestedMaxTokens(newJSONContext(`not json`)))
+}
+
+func TestWithAdaptiveTimeout_SetsRequestDeadline(t *testing.T) {
+	useAdaptiveTimeout(t, 1, 0.5, 0)
+
+	deadlineFor := func(maxTokens string) time.Time {
+		req := httptest.NewRequest(http.MethodPost, "http://upstream/v1/chat/completions", nil)
+		bounded, timeout, cancel := withAdaptiveTimeout(newJSONContext(`{"max_tokens":`+maxTokens+`}`), req)
+		t.Cleanup(cancel)
+		require.Positive(t, timeout)
+		deadline, ok := bounded.Context().Deadline()
+		require.True(t, ok)
+		return deadline
+	}
+
+	short, long := deadlineFor("100"), deadlineFor("1000")
+	require.InDelta(t, float64(450*time.Millisecond), float64(long.Sub(short)), float64(50*time.Millisecond))
+}
+
+func TestRequestedMaxTokens_DoesNotDrainUncachedBody(t *testing.T) {
+	body := `{"max_tokens":1000}`
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	require.Zero(t, requestedMaxTokens(c))
+	remaining, err := io.ReadAll(c.Request.Body)
+	require.NoError(t, err)
+	require.Equal(t, body, string(remaining))
+}
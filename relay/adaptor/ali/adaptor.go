@@ -229,7 +229,7 @@ func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Read
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
 	if meta.IsStream {
-		err, usage = StreamHandler(c, resp)
+		err, usage = StreamHandler(c, resp, meta.ActualModelName)
 	} else {
 		switch meta.Mode {
 		case relaymode.Embeddings:
@@ -237,7 +237,7 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Met
 		case relaymode.ImagesGenerations:
 			err, usage = ImageHandler(c, resp)
 		default:
-			err, usage = Handler(c, resp)
+			err, usage = Handler(c, resp, meta.ActualModelName)
 		}
 	}
 	return
@@ -290,3 +290,9 @@ func (a *Adaptor) GetCompletionRatio(modelName string) float64 {
 func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return AliToolingDefaults
 }
+
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision)
+}
@@ -0,0 +1,169 @@
+package ali
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+func newAliMeta(baseURL string, stream bool) *meta.Meta {
+	return &meta.Meta{
+		Mode:            relaymode.ChatCompletions,
+		ChannelType:     channeltype.Ali,
+		BaseURL:         baseURL,
+		APIKey:          "sk-dashscope",
+		ActualModelName: "qwen-plus",
+		IsStream:        stream,
+	}
+}
+
+// doChat sends a chat request through the adaptor against a fake DashScope server.
+func doChat(t *testing.T, stream bool, respond http.HandlerFunc) (*httptest.ResponseRecorder, *model.Usage, *model.ErrorWithStatusCode) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/services/aigc/text-generation/generation", r.URL.Path)
+		require.Equal(t, "Bearer sk-dashscope", r.Header.Get("Authorization"))
+		if stream {
+			require.Equal(t, "enable", r.Header.Get("X-DashScope-SSE"))
+		} else {
+			require.Empty(t, r.Header.Get("X-DashScope-SSE"))
+		}
+
+		var body ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, "qwen-plus", body.Model)
+		require.Equal(t, stream, body.Parameters.IncrementalOutput)
+		require.Equal(t, "message", body.Parameters.ResultFormat)
+		respond(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	m := newAliMeta(server.URL, stream)
+	a := &Adaptor{}
+	a.Init(m)
+	converted, err := a.ConvertRequest(c, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{
+		Model:    "qwen-plus",
+		Stream:   stream,
+		Messages: []model.Message{{Role: "user", Content: "Hello"}},
+	})
+	require.NoError(t, err)
+	payload, err := json.Marshal(converted)
+	require.NoError(t, err)
+
+	// Send the request directly, DoRequestHelper records trace timestamps in the database
+	url, err := a.GetRequestURL(m)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	require.NoError(t, err)
+	require.NoError(t, a.SetupRequestHeader(c, req, m))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	usage, bizErr := a.DoResponse(c, resp, m)
+	return recorder, usage, bizErr
+}
+
+func TestDoResponse_Stream(t *testing.T) {
+	events := []string{
+		`{"output":{"choices":[{"message":{"content":"Hel","role":"assistant"},"finish_reason":"null"}]},"usage":{"total_tokens":9,"input_tokens":8,"output_tokens":1},"request_id":"req-1"}`,
+		`{"output":{"choices":[{"message":{"content":"lo!","role":"assistant"},"finish_reason":"stop"}]},"usage":{"total_tokens":10,"input_tokens":8,"output_tokens":2},"request_id":"req-1"}`,
+	}
+	recorder, usage, bizErr := doChat(t, true, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, event := range events {
+			_, _ = io.WriteString(w, "id:"+strconv.Itoa(i+1)+"\nevent:result\n:HTTP_STATUS/200\ndata:"+event+"\n\n")
+		}
+	})
+	require.Nil(t, bizErr)
+	require.Equal(t, &model.Usage{PromptTokens: 8, CompletionTokens: 2, TotalTokens: 10}, usage)
+
+	var contents []string
+	var finishReasons []string
+	for line := range strings.SplitSeq(recorder.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Model   string `json:"model"`
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		require.Equal(t, "qwen-plus", chunk.Model)
+		contents = append(contents, chunk.Choices[0].Delta.Content)
+		if chunk.Choices[0].FinishReason != nil {
+			finishReasons = append(finishReasons, *chunk.Choices[0].FinishReason)
+		}
+	}
+	require.Equal(t, []string{"Hel", "lo!"}, contents)
+	require.Equal(t, []string{"stop"}, finishReasons)
+	require.Contains(t, recorder.Body.String(), "data: [DONE]")
+}
+
+func TestDoResponse_StreamNDJSON(t *testing.T) {
+	recorder, usage, bizErr := doChat(t, true, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"output":{"choices":[{"message":{"content":"Hi","role":"assistant"},"finish_reason":"stop"}]},"usage":{"input_tokens":3,"output_tokens":1},"request_id":"req-2"}`+"\n")
+	})
+	require.Nil(t, bizErr)
+	require.Equal(t, 4, usage.TotalTokens)
+	require.Contains(t, recorder.Body.String(), `"content":"Hi"`)
+}
+
+func TestDoResponse_StreamErrorBeforeOutput(t *testing.T) {
+	_, _, bizErr := doChat(t, true, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, "event:error\ndata:{\"code\":\"InvalidParameter\",\"message\":\"bad input\",\"request_id\":\"req-3\"}\n\n")
+	})
+	require.NotNil(t, bizErr)
+	require.Equal(t, http.StatusBadRequest, bizErr.StatusCode)
+	require.Equal(t, "InvalidParameter", bizErr.Code)
+	require.Equal(t, "bad input", bizErr.Message)
+}
+
+func TestDoResponse_NonStream(t *testing.T) {
+	recorder, usage, bizErr := doChat(t, false, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"output":{"choices":[{"message":{"content":"Hello!","role":"assistant"},"finish_reason":"stop"}]},"usage":{"total_tokens":12,"input_tokens":8,"output_tokens":4},"request_id":"req-4"}`)
+	})
+	require.Nil(t, bizErr)
+	require.Equal(t, 12, usage.TotalTokens)
+
+	var response struct {
+		Id      string `json:"id"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Equal(t, "req-4", response.Id)
+	require.Equal(t, "qwen-plus", response.Model)
+	require.Equal(t, "Hello!", response.Choices[0].Message.Content)
+	require.Equal(t, "stop", response.Choices[0].FinishReason)
+}
@@ -145,67 +145,120 @@ func embeddingResponseAli2OpenAI(response *EmbeddingResponse) *openai.EmbeddingR
 	return &openAIEmbeddingResponse
 }
 
-func responseAli2OpenAI(response *ChatResponse) *openai.TextResponse {
+// finishReasonNull is what DashScope sends as finish_reason before the last event.
+const finishReasonNull = "null"
+
+// errorOf returns the error carried by a DashScope response, or nil.
+func errorOf(response *ChatResponse, statusCode int) *model.ErrorWithStatusCode {
+	if response.Code == "" {
+		return nil
+	}
+	if statusCode < http.StatusBadRequest {
+		statusCode = http.StatusInternalServerError
+	}
+	return &model.ErrorWithStatusCode{
+		Error: model.Error{
+			Message:  response.Message,
+			Type:     model.ErrorType(response.Code),
+			Param:    response.RequestId,
+			Code:     response.Code,
+			RawError: errors.Errorf("dashscope error %s: %s", response.Code, response.Message),
+		},
+		StatusCode: statusCode,
+	}
+}
+
+func usageOf(usage Usage) model.Usage {
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.InputTokens + usage.OutputTokens
+	}
+	return model.Usage{
+		PromptTokens:     usage.InputTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      total,
+	}
+}
+
+func responseAli2OpenAI(response *ChatResponse, modelName string) *openai.TextResponse {
 	fullTextResponse := openai.TextResponse{
 		Id:      response.RequestId,
+		Model:   modelName,
 		Object:  "chat.completion",
 		Created: helper.GetTimestamp(),
 		Choices: response.Output.Choices,
-		Usage: model.Usage{
-			PromptTokens:     response.Usage.InputTokens,
-			CompletionTokens: response.Usage.OutputTokens,
-			TotalTokens:      response.Usage.InputTokens + response.Usage.OutputTokens,
-		},
+		Usage:   usageOf(response.Usage),
+	}
+	for i := range fullTextResponse.Choices {
+		fullTextResponse.Choices[i].Index = i
 	}
 	return &fullTextResponse
 }
 
-func streamResponseAli2OpenAI(aliResponse *ChatResponse) *openai.ChatCompletionsStreamResponse {
+// streamResponseAli2OpenAI converts an incremental stream event into an
+// OpenAI chat completion chunk, or returns nil when the event has no choices.
+func streamResponseAli2OpenAI(aliResponse *ChatResponse, modelName string) *openai.ChatCompletionsStreamResponse {
 	if len(aliResponse.Output.Choices) == 0 {
 		return nil
 	}
-	aliChoice := aliResponse.Output.Choices[0]
-	var choice openai.ChatCompletionsStreamResponseChoice
-	choice.Delta = aliChoice.Message
-	if aliChoice.FinishReason != "null" {
-		finishReason := aliChoice.FinishReason
-		choice.FinishReason = &finishReason
-	}
 	response := openai.ChatCompletionsStreamResponse{
 		Id:      aliResponse.RequestId,
 		Object:  "chat.completion.chunk",
 		Created: helper.GetTimestamp(),
-		Model:   "qwen",
-		Choices: []openai.ChatCompletionsStreamResponseChoice{choice},
+		Model:   modelName,
+		Choices: make([]openai.ChatCompletionsStreamResponseChoice, 0, len(aliResponse.Output.Choices)),
+	}
+	for i, aliChoice := range aliResponse.Output.Choices {
+		choice := openai.ChatCompletionsStreamResponseChoice{
+			Index: i,
+			Delta: aliChoice.Message,
+		}
+		if choice.Delta.Role == "" {
+			choice.Delta.Role = "assistant"
+		}
+		if aliChoice.FinishReason != "" && aliChoice.FinishReason != finishReasonNull {
+			finishReason := aliChoice.FinishReason
+			choice.FinishReason = &finishReason
+		}
+		response.Choices = append(response.Choices, choice)
 	}
 	return &response
 }
 
-func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusCode, *model.Usage) {
+// streamPayload extracts the JSON payload of a stream line. DashScope sends
+// SSE events whose data field holds one JSON object; the other SSE fields (id,
+// event, :HTTP_STATUS comments) carry nothing we need. Bare NDJSON lines are
+// accepted as well.
+func streamPayload(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "data:"):
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		return payload, payload != "" && payload != "[DONE]"
+	case strings.HasPrefix(line, "{"):
+		return line, true
+	default:
+		return "", false
+	}
+}
+
+// StreamHandler relays a DashScope SSE stream, requested with
+// incremental_output, as OpenAI chat completion chunks of modelName. An error
+// event before any output is returned as the request error.
+func StreamHandler(c *gin.Context, resp *http.Response, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
 	var usage model.Usage
 	lg := gmw.GetLogger(c)
 	scanner := bufio.NewScanner(resp.Body)
-	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-		if atEOF && len(data) == 0 {
-			return 0, nil, nil
-		}
-		if i := strings.Index(string(data), "\n"); i >= 0 {
-			return i + 1, data[0:i], nil
-		}
-		if atEOF {
-			return len(data), data, nil
-		}
-		return 0, nil, nil
-	})
-
-	common.SetEventStreamHeaders(c)
+	scanner.Split(bufio.ScanLines)
+	buffer := make([]byte, 10*1024*1024) // 10MB buffer
+	scanner.Buffer(buffer, len(buffer))
 
+	started := false
 	for scanner.Scan() {
-		data := scanner.Text()
-		if len(data) < 5 || data[:5] != "data:" {
+		data, ok := streamPayload(scanner.Text())
+		if !ok {
 			continue
 		}
-		data = data[5:]
 
 		var aliResponse ChatResponse
 		err := json.Unmarshal([]byte(data), &aliResponse)
@@ -213,15 +266,25 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 			lg.Error("error unmarshalling stream response: ", zap.Error(err))
 			continue
 		}
-		if aliResponse.Usage.OutputTokens != 0 {
-			usage.PromptTokens = aliResponse.Usage.InputTokens
-			usage.CompletionTokens = aliResponse.Usage.OutputTokens
-			usage.TotalTokens = aliResponse.Usage.InputTokens + aliResponse.Usage.OutputTokens
+		if bizErr := errorOf(&aliResponse, resp.StatusCode); bizErr != nil {
+			if !started {
+				_ = resp.Body.Close()
+				return bizErr, nil
+			}
+			lg.Warn("dashscope stream returned an error", zap.Error(bizErr.RawError))
+			break
 		}
-		response := streamResponseAli2OpenAI(&aliResponse)
+		if aliResponse.Usage.InputTokens != 0 || aliResponse.Usage.OutputTokens != 0 {
+			usage = usageOf(aliResponse.Usage)
+		}
+		response := streamResponseAli2OpenAI(&aliResponse, modelName)
 		if response == nil {
 			continue
 		}
+		if !started {
+			common.SetEventStreamHeaders(c)
+			started = true
+		}
 		err = render.ObjectData(c, response)
 		if err != nil {
 			lg.Error("error rendering response: ", zap.Error(err))
@@ -232,6 +295,9 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 		lg.Error("error reading stream: ", zap.Error(err))
 	}
 
+	if !started {
+		common.SetEventStreamHeaders(c)
+	}
 	render.Done(c)
 
 	err := resp.Body.Close()
@@ -241,7 +307,9 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 	return nil, &usage
 }
 
-func Handler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusCode, *model.Usage) {
+// Handler relays a non-streaming DashScope response as an OpenAI chat
+// completion of modelName.
+func Handler(c *gin.Context, resp *http.Response, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
 	lg := gmw.GetLogger(c)
 	var aliResponse ChatResponse
 	responseBody, err := io.ReadAll(resp.Body)
@@ -257,21 +325,10 @@ func Handler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusCode, *
 	if err != nil {
 		return openai.ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
 	}
-	if aliResponse.Code != "" {
-		errType := model.ErrorType(aliResponse.Code)
-		return &model.ErrorWithStatusCode{
-			Error: model.Error{
-				Message:  aliResponse.Message,
-				Type:     errType,
-				Param:    aliResponse.RequestId,
-				Code:     aliResponse.Code,
-				RawError: errors.New(aliResponse.Message),
-			},
-			StatusCode: resp.StatusCode,
-		}, nil
+	if bizErr := errorOf(&aliResponse, resp.StatusCode); bizErr != nil {
+		return bizErr, nil
 	}
-	fullTextResponse := responseAli2OpenAI(&aliResponse)
-	fullTextResponse.Model = "qwen"
+	fullTextResponse := responseAli2OpenAI(&aliResponse, modelName)
 	jsonResponse, err := json.Marshal(fullTextResponse)
 	if err != nil {
 		return openai.ErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError), nil
@@ -138,6 +138,12 @@ func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return AnthropicToolingDefaults
 }
 
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureExtendedThinking)
+}
+
 // Pricing methods - Anthropic adapter manages its own model pricing
 func (a *Adaptor) GetDefaultModelPricing() map[string]adaptor.ModelConfig {
 	return ModelRatios
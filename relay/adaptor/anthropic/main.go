@@ -892,7 +892,7 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 	var usage model.Usage
 	var modelName string
 	var id string
-	var lastToolCallChoice openai.ChatCompletionsStreamResponseChoice
+	converter := NewStreamConverter()
 	var streamRewriter openai_compatible.StreamRewriteHandler
 	if rewriteAny, exists := c.Get(ctxkey.ResponseStreamRewriteHandler); exists {
 		if rewriter, ok := rewriteAny.(openai_compatible.StreamRewriteHandler); ok {
@@ -941,7 +941,7 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 			return nil, &usage
 		}
 
-		response, meta := StreamResponseClaude2OpenAI(c, &claudeResponse)
+		response, meta := converter.Convert(c, &claudeResponse)
 		if meta != nil {
 			usage.PromptTokens += meta.Usage.InputTokens
 			usage.CompletionTokens += meta.Usage.OutputTokens
@@ -966,15 +966,6 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 				id = tracing.GenerateChatCompletionID(c)
 				continue
 			} else { // finish_reason case
-				if len(lastToolCallChoice.Delta.ToolCalls) > 0 {
-					lastArgs := lastToolCallChoice.Delta.ToolCalls[len(lastToolCallChoice.Delta.ToolCalls)-1].Function
-					// Safe type assertion for Arguments
-					if argsStr, ok := lastArgs.Arguments.(string); ok && len(argsStr) == 0 { // compatible with OpenAI sending an empty object `{}` when no arguments.
-						lastArgs.Arguments = "{}"
-						response.Choices[len(response.Choices)-1].Delta.Content = nil
-						response.Choices[len(response.Choices)-1].Delta.ToolCalls = lastToolCallChoice.Delta.ToolCalls
-					}
-				}
 				// Add usage information to the final chunk
 				if usage.PromptTokens > 0 || usage.CompletionTokens > 0 {
 					usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
@@ -992,12 +983,6 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 		response.Model = modelName
 		response.Created = createdTime
 
-		for _, choice := range response.Choices {
-			if len(choice.Delta.ToolCalls) > 0 {
-				lastToolCallChoice = choice
-			}
-		}
-
 		if streamRewriter != nil {
 			compatChunk := openai_compatible.ChatCompletionsStreamResponse{
 				Id:      response.Id,
@@ -0,0 +1,115 @@
+package anthropic
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+// pendingToolCall is a tool_use block whose input is still streaming.
+type pendingToolCall struct {
+	index int // OpenAI tool call index
+	id    string
+	name  string
+	args  strings.Builder
+}
+
+// StreamConverter converts a Claude stream into OpenAI chunks, buffering each
+// tool_use block until its content_block_stop so the client receives the tool
+// call as a single delta carrying complete JSON arguments, however many
+// input_json_delta events upstream split them into. Other events are
+// converted by StreamResponseClaude2OpenAI.
+type StreamConverter struct {
+	pending   map[int]*pendingToolCall // keyed by Claude content block index
+	toolCalls int                      // tool calls started so far
+}
+
+// NewStreamConverter returns a converter for one Claude stream.
+func NewStreamConverter() *StreamConverter {
+	return &StreamConverter{pending: make(map[int]*pendingToolCall)}
+}
+
+// Convert converts one Claude stream event. It returns a nil chunk for events
+// that produce no output yet, such as buffered tool_use deltas.
+func (s *StreamConverter) Convert(c *gin.Context, claudeResponse *StreamResponse) (*openai.ChatCompletionsStreamResponse, *Response) {
+	switch claudeResponse.Type {
+	case "content_block_start":
+		if block := claudeResponse.ContentBlock; block != nil && block.Type == "tool_use" {
+			s.pending[claudeResponse.Index] = &pendingToolCall{index: s.toolCalls, id: block.Id, name: block.Name}
+			s.toolCalls++
+			return nil, nil
+		}
+	case "content_block_delta":
+		if call, ok := s.pending[claudeResponse.Index]; ok {
+			if claudeResponse.Delta != nil && claudeResponse.Delta.Type == "input_json_delta" {
+				call.args.WriteString(claudeResponse.Delta.PartialJson)
+			}
+			return nil, nil
+		}
+	case "content_block_stop":
+		if call, ok := s.pending[claudeResponse.Index]; ok {
+			delete(s.pending, claudeResponse.Index)
+			return toolCallsChunk([]model.Tool{call.tool()}), nil
+		}
+	case "message_delta":
+		// A stream cut short of content_block_stop still delivers its tool calls
+		response, meta := StreamResponseClaude2OpenAI(c, claudeResponse)
+		if tools := s.flush(); len(tools) > 0 && response != nil {
+			response.Choices[0].Delta.Content = nil
+			response.Choices[0].Delta.ToolCalls = tools
+		}
+		return response, meta
+	}
+
+	return StreamResponseClaude2OpenAI(c, claudeResponse)
+}
+
+// flush returns the tool calls still buffered, in the order they started.
+func (s *StreamConverter) flush() []model.Tool {
+	calls := make([]*pendingToolCall, 0, len(s.pending))
+	for _, call := range s.pending {
+		calls = append(calls, call)
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].index < calls[j].index })
+	clear(s.pending)
+
+	tools := make([]model.Tool, 0, len(calls))
+	for _, call := range calls {
+		tools = append(tools, call.tool())
+	}
+	return tools
+}
+
+// tool returns the OpenAI tool call of the buffered block. Blocks without
+// input get "{}", as OpenAI sends for calls without arguments.
+func (p *pendingToolCall) tool() model.Tool {
+	args := p.args.String()
+	if strings.TrimSpace(args) == "" {
+		args = "{}"
+	}
+	index := p.index
+	return model.Tool{
+		Id:   p.id,
+		Type: "function",
+		Function: &model.Function{
+			Name:      p.name,
+			Arguments: args,
+		},
+		Index: &index,
+	}
+}
+
+// toolCallsChunk wraps complete tool calls into an assistant delta chunk.
+func toolCallsChunk(tools []model.Tool) *openai.ChatCompletionsStreamResponse {
+	var choice openai.ChatCompletionsStreamResponseChoice
+	choice.Delta.Role = "assistant"
+	choice.Delta.ToolCalls = tools
+	return &openai.ChatCompletionsStreamResponse{
+		Object:  "chat.completion.chunk",
+		Choices: []openai.ChatCompletionsStreamResponseChoice{choice},
+	}
+}
@@ -0,0 +1,129 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+)
+
+// claudeSSE renders Claude stream events as an SSE body.
+func claudeSSE(t *testing.T, events ...map[string]any) string {
+	t.Helper()
+	var b strings.Builder
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		require.NoError(t, err)
+		fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", event["type"], data)
+	}
+	return b.String()
+}
+
+// splitInChunks splits s into n chunks of nearly equal rune length.
+func splitInChunks(s string, n int) []string {
+	runes := []rune(s)
+	chunks := make([]string, 0, n)
+	for i := range n {
+		chunks = append(chunks, string(runes[i*len(runes)/n:(i+1)*len(runes)/n]))
+	}
+	return chunks
+}
+
+// streamedToolCalls runs StreamHandler over body and returns the OpenAI chunks carrying tool calls.
+func streamedToolCalls(t *testing.T, body string) []openai.ChatCompletionsStreamResponse {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+	errResp, usage := StreamHandler(c, resp)
+	require.Nil(t, errResp)
+	require.NotNil(t, usage)
+
+	var chunks []openai.ChatCompletionsStreamResponse
+	for line := range strings.SplitSeq(w.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk openai.ChatCompletionsStreamResponse
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		if len(chunk.Choices) > 0 && len(chunk.Choices[0].Delta.ToolCalls) > 0 {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+func TestStreamHandler_BuffersToolCallArguments(t *testing.T) {
+	arguments := `{"location":"San Francisco, CA","unit":"celsius","days":[1,2,3],"note":"say \"hi\" — ok"}`
+	events := []map[string]any{
+		{"type": "message_start", "message": map[string]any{"id": "msg_1", "model": "claude-sonnet-4-5", "usage": map[string]any{"input_tokens": 12}}},
+		{"type": "content_block_start", "index": 0, "content_block": map[string]any{"type": "text", "text": ""}},
+		{"type": "content_block_delta", "index": 0, "delta": map[string]any{"type": "text_delta", "text": "Checking the weather."}},
+		{"type": "content_block_stop", "index": 0},
+		{"type": "content_block_start", "index": 1, "content_block": map[string]any{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": map[string]any{}}},
+	}
+	for _, chunk := range splitInChunks(arguments, 50) {
+		events = append(events, map[string]any{"type": "content_block_delta", "index": 1, "delta": map[string]any{"type": "input_json_delta", "partial_json": chunk}})
+	}
+	events = append(events,
+		map[string]any{"type": "content_block_stop", "index": 1},
+		map[string]any{"type": "content_block_start", "index": 2, "content_block": map[string]any{"type": "tool_use", "id": "toolu_2", "name": "get_time", "input": map[string]any{}}},
+		map[string]any{"type": "content_block_stop", "index": 2},
+		map[string]any{"type": "message_delta", "delta": map[string]any{"stop_reason": "tool_use"}, "usage": map[string]any{"output_tokens": 40}},
+		map[string]any{"type": "message_stop"},
+	)
+
+	chunks := streamedToolCalls(t, claudeSSE(t, events...))
+	require.Len(t, chunks, 2)
+
+	first := chunks[0].Choices[0].Delta.ToolCalls
+	require.Len(t, first, 1)
+	require.Equal(t, "toolu_1", first[0].Id)
+	require.Equal(t, "get_weather", first[0].Function.Name)
+	require.NotNil(t, first[0].Index)
+	require.Equal(t, 0, *first[0].Index)
+	args, ok := first[0].Function.Arguments.(string)
+	require.True(t, ok)
+	require.True(t, json.Valid([]byte(args)), args)
+	require.JSONEq(t, arguments, args)
+
+	second := chunks[1].Choices[0].Delta.ToolCalls
+	require.Len(t, second, 1)
+	require.Equal(t, "get_time", second[0].Function.Name)
+	require.Equal(t, 1, *second[0].Index)
+	require.Equal(t, "{}", second[0].Function.Arguments)
+}
+
+func TestStreamConverter_FlushesUnterminatedToolCall(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	converter := NewStreamConverter()
+
+	response, _ := converter.Convert(c, &StreamResponse{Type: "content_block_start", Index: 0, ContentBlock: &Content{Type: "tool_use", Id: "toolu_1", Name: "lookup"}})
+	require.Nil(t, response)
+	response, _ = converter.Convert(c, &StreamResponse{Type: "content_block_delta", Index: 0, Delta: &Delta{Type: "input_json_delta", PartialJson: `{"q":"go"}`}})
+	require.Nil(t, response)
+
+	stopReason := "tool_use"
+	response, meta := converter.Convert(c, &StreamResponse{Type: "message_delta", Delta: &Delta{StopReason: &stopReason}, Usage: &Usage{OutputTokens: 5}})
+	require.NotNil(t, meta)
+	require.NotNil(t, response)
+	toolCalls := response.Choices[0].Delta.ToolCalls
+	require.Len(t, toolCalls, 1)
+	require.Equal(t, `{"q":"go"}`, toolCalls[0].Function.Arguments)
+	require.NotNil(t, response.Choices[0].FinishReason)
+	require.Equal(t, "tool_calls", *response.Choices[0].FinishReason)
+}
@@ -53,6 +53,12 @@ func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return AWSToolingDefaults
 }
 
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureExtendedThinking)
+}
+
 func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.GeneralOpenAIRequest) (any, error) {
 	if request == nil {
 		return nil, errors.New("request is nil")
@@ -26,7 +26,6 @@ import (
 	"github.com/songquanpeng/one-api/model"
 	"github.com/songquanpeng/one-api/relay/adaptor/anthropic"
 	"github.com/songquanpeng/one-api/relay/adaptor/aws/utils"
-	"github.com/songquanpeng/one-api/relay/adaptor/openai"
 	relaymodel "github.com/songquanpeng/one-api/relay/model"
 )
 
@@ -239,7 +238,7 @@ func StreamHandler(c *gin.Context, awsCli *bedrockruntime.Client) (*relaymodel.E
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	var usage relaymodel.Usage
 	var id string
-	var lastToolCallChoice openai.ChatCompletionsStreamResponseChoice
+	converter := anthropic.NewStreamConverter()
 
 	c.Stream(func(w io.Writer) bool {
 		event, ok := <-stream.Events()
@@ -257,23 +256,13 @@ func StreamHandler(c *gin.Context, awsCli *bedrockruntime.Client) (*relaymodel.E
 				return false
 			}
 
-			response, meta := anthropic.StreamResponseClaude2OpenAI(c, claudeResp)
+			response, meta := converter.Convert(c, claudeResp)
 			if meta != nil {
 				usage.PromptTokens += meta.Usage.InputTokens
 				usage.CompletionTokens += meta.Usage.OutputTokens
 				if len(meta.Id) > 0 { // only message_start has an id, otherwise it's a finish_reason event.
 					id = tracing.GenerateChatCompletionID(c)
 					return true
-				} else { // finish_reason case
-					if len(lastToolCallChoice.Delta.ToolCalls) > 0 {
-						lastArgs := lastToolCallChoice.Delta.ToolCalls[len(lastToolCallChoice.Delta.ToolCalls)-1].Function
-						// Safe type assertion for Arguments
-						if argsStr, ok := lastArgs.Arguments.(string); ok && len(argsStr) == 0 { // compatible with OpenAI sending an empty object `{}` when no arguments.
-							lastArgs.Arguments = "{}"
-							response.Choices[len(response.Choices)-1].Delta.Content = nil
-							response.Choices[len(response.Choices)-1].Delta.ToolCalls = lastToolCallChoice.Delta.ToolCalls
-						}
-					}
 				}
 			}
 			if response == nil {
@@ -283,11 +272,6 @@ func StreamHandler(c *gin.Context, awsCli *bedrockruntime.Client) (*relaymodel.E
 			response.Model = c.GetString(ctxkey.RequestModel)
 			response.Created = createdTime
 
-			for _, choice := range response.Choices {
-				if len(choice.Delta.ToolCalls) > 0 {
-					lastToolCallChoice = choice
-				}
-			}
 			jsonStr, err := json.Marshal(response)
 			if err != nil {
 				gmw.GetLogger(c).Error("error marshalling stream response", zap.Error(err))
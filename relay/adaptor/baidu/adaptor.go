@@ -1,16 +1,27 @@
 package baidu
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
 
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/client"
 	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/logger"
 	"github.com/songquanpeng/one-api/relay/adaptor"
 	"github.com/songquanpeng/one-api/relay/meta"
 	"github.com/songquanpeng/one-api/relay/model"
@@ -50,7 +61,7 @@ func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
 		suffix += "eb-instant"
 	case "ERNIE-Speed":
 		suffix += "ernie_speed"
-	case "ERNIE-4.0-8K":
+	case "ERNIE-4.0-8K", "ernie-4.0-8k":
 		suffix += "completions_pro"
 	case "ERNIE-3.5-8K":
 		suffix += "completions"
@@ -87,19 +98,26 @@ func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
 	default:
 		suffix += strings.ToLower(meta.ActualModelName)
 	}
-	fullRequestURL := fmt.Sprintf("%s/rpc/2.0/ai_custom/v1/wenxinworkshop/%s", meta.BaseURL, suffix)
-	var accessToken string
-	var err error
-	if accessToken, err = GetAccessToken(meta.APIKey); err != nil {
-		return "", err
-	}
-	fullRequestURL += "?access_token=" + accessToken
-	return fullRequestURL, nil
+	return fmt.Sprintf("%s/rpc/2.0/ai_custom/v1/wenxinworkshop/%s", meta.BaseURL, suffix), nil
 }
 
+// SetupRequestHeader authenticates the request. A "client_id|client_secret"
+// key is exchanged for an access token, which Baidu expects as the
+// access_token query parameter; any other key is a Qianfan API key sent as a
+// bearer token.
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Request, meta *meta.Meta) error {
 	adaptor.SetupCommonRequestHeader(c, req, meta)
-	req.Header.Set("Authorization", "Bearer "+meta.APIKey)
+	if !strings.Contains(meta.APIKey, "|") {
+		req.Header.Set("Authorization", "Bearer "+meta.APIKey)
+		return nil
+	}
+	accessToken, err := getAccessToken(req.Context(), meta.APIKey)
+	if err != nil {
+		return errors.Wrap(err, "get baidu access token")
+	}
+	query := req.URL.Query()
+	query.Set("access_token", accessToken)
+	req.URL.RawQuery = query.Encode()
 	return nil
 }
 
@@ -319,3 +337,112 @@ func (a *Adaptor) GetCompletionRatio(modelName string) float64 {
 func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return BaiduToolingDefaults
 }
+
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.StandardModelFeatures()
+}
+
+// accessTokenRefreshMargin is how long before expiry a cached access token is
+// replaced, so no request is sent with a token about to expire.
+const accessTokenRefreshMargin = 5 * time.Minute
+
+// accessTokenURL is Baidu's OAuth endpoint; tests point it at a fake server.
+var accessTokenURL = "https://aip.baidubce.com/oauth/2.0/token"
+
+// cachedAccessToken is an access token held in memory when Redis is disabled.
+type cachedAccessToken struct {
+	token  string
+	expiry time.Time
+}
+
+var (
+	accessTokens       sync.Map // cache key -> cachedAccessToken
+	accessTokenRefresh singleflight.Group
+)
+
+// RefreshAccessToken exchanges the application's client credentials for a new
+// access token, which Baidu issues for 30 days.
+func RefreshAccessToken(ctx context.Context, clientId, clientSecret string) (token string, expiry time.Time, err error) {
+	query := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {clientId},
+		"client_secret": {clientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, accessTokenURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "build baidu access token request")
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.ImpatientHTTPClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "request baidu access token")
+	}
+	defer resp.Body.Close()
+
+	var accessToken AccessToken
+	if err = json.NewDecoder(resp.Body).Decode(&accessToken); err != nil {
+		return "", time.Time{}, errors.Wrap(err, "decode baidu access token response")
+	}
+	if accessToken.Error != "" {
+		return "", time.Time{}, errors.Errorf("%s: %s", accessToken.Error, accessToken.ErrorDescription)
+	}
+	if accessToken.AccessToken == "" {
+		return "", time.Time{}, errors.New("baidu returned an empty access token")
+	}
+	return accessToken.AccessToken, time.Now().Add(time.Duration(accessToken.ExpiresIn) * time.Second), nil
+}
+
+// accessTokenCacheKey keys cached tokens by a digest of the client ID, so the
+// credential itself never appears in Redis.
+func accessTokenCacheKey(clientId string) string {
+	sum := sha256.Sum256([]byte(clientId))
+	return "baidu:access_token:" + hex.EncodeToString(sum[:])
+}
+
+// getAccessToken returns the access token of a "client_id|client_secret" API
+// key. Tokens are cached in Redis, or in memory when Redis is disabled, and
+// refreshed once they are within accessTokenRefreshMargin of expiry.
+func getAccessToken(ctx context.Context, apiKey string) (string, error) {
+	clientId, clientSecret, ok := strings.Cut(apiKey, "|")
+	if !ok || clientId == "" || clientSecret == "" {
+		return "", errors.New("invalid baidu apikey")
+	}
+
+	key := accessTokenCacheKey(clientId)
+	useRedis := common.IsRedisEnabled() && common.RDB != nil
+	if useRedis {
+		// The Redis TTL already ends accessTokenRefreshMargin before expiry
+		if token, err := common.RedisGet(ctx, key); err == nil && token != "" {
+			return token, nil
+		}
+	} else if v, ok := accessTokens.Load(key); ok {
+		if cached := v.(cachedAccessToken); time.Until(cached.expiry) > accessTokenRefreshMargin {
+			return cached.token, nil
+		}
+	}
+
+	v, err, _ := accessTokenRefresh.Do(key, func() (any, error) {
+		token, expiry, err := RefreshAccessToken(ctx, clientId, clientSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "refresh access token")
+		}
+		ttl := time.Until(expiry) - accessTokenRefreshMargin
+		if ttl <= 0 {
+			return token, nil
+		}
+		if useRedis {
+			if err := common.RedisSet(ctx, key, token, ttl); err != nil {
+				logger.Logger.Warn("failed to cache baidu access token", zap.Error(err))
+			}
+		} else {
+			accessTokens.Store(key, cachedAccessToken{token: token, expiry: expiry})
+		}
+		return token, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
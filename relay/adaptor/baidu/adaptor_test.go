@@ -0,0 +1,122 @@
+package baidu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/relay/meta"
+)
+
+// newTokenServer fakes Baidu's OAuth endpoint, issuing numbered tokens that
+// expire after expiresIn seconds.
+func newTokenServer(t *testing.T, expiresIn int64) *atomic.Int32 {
+	t.Helper()
+	var issued atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "client_credentials", r.URL.Query().Get("grant_type"))
+		if r.URL.Query().Get("client_secret") != "secret" {
+			_ = json.NewEncoder(w).Encode(AccessToken{Error: "invalid_client", ErrorDescription: "unknown client secret"})
+			return
+		}
+		n := issued.Add(1)
+		_ = json.NewEncoder(w).Encode(AccessToken{
+			AccessToken: fmt.Sprintf("token-%d", n),
+			ExpiresIn:   expiresIn,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	originalURL := accessTokenURL
+	originalClient := client.ImpatientHTTPClient
+	originalRedis := common.IsRedisEnabled()
+	accessTokenURL = server.URL
+	client.ImpatientHTTPClient = server.Client()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() {
+		accessTokenURL = originalURL
+		client.ImpatientHTTPClient = originalClient
+		common.SetRedisEnabled(originalRedis)
+		accessTokens.Clear()
+	})
+	return &issued
+}
+
+func TestRefreshAccessToken(t *testing.T) {
+	newTokenServer(t, 2592000)
+
+	token, expiry, err := RefreshAccessToken(context.Background(), "client", "secret")
+	require.NoError(t, err)
+	require.Equal(t, "token-1", token)
+	require.WithinDuration(t, time.Now().Add(30*24*time.Hour), expiry, time.Minute)
+
+	_, _, err = RefreshAccessToken(context.Background(), "client", "wrong")
+	require.ErrorContains(t, err, "invalid_client")
+}
+
+func TestGetAccessTokenCachesUntilExpiryApproaches(t *testing.T) {
+	issued := newTokenServer(t, 2592000)
+	ctx := context.Background()
+
+	token, err := getAccessToken(ctx, "client|secret")
+	require.NoError(t, err)
+	require.Equal(t, "token-1", token)
+	token, err = getAccessToken(ctx, "client|secret")
+	require.NoError(t, err)
+	require.Equal(t, "token-1", token)
+	require.Equal(t, int32(1), issued.Load())
+
+	// Within the refresh margin of expiry the token is replaced
+	key := accessTokenCacheKey("client")
+	accessTokens.Store(key, cachedAccessToken{token: "token-1", expiry: time.Now().Add(accessTokenRefreshMargin - time.Second)})
+	token, err = getAccessToken(ctx, "client|secret")
+	require.NoError(t, err)
+	require.Equal(t, "token-2", token)
+	require.Equal(t, int32(2), issued.Load())
+
+	_, err = getAccessToken(ctx, "client-without-secret")
+	require.ErrorContains(t, err, "invalid baidu apikey")
+}
+
+func TestAccessTokenCacheKeyHidesClientId(t *testing.T) {
+	key := accessTokenCacheKey("my-client-id")
+	require.NotContains(t, key, "my-client-id")
+	require.Len(t, key, len("baidu:access_token:")+64)
+	require.Equal(t, key, accessTokenCacheKey("my-client-id"))
+}
+
+func TestSetupRequestHeaderAddsAccessToken(t *testing.T) {
+	newTokenServer(t, 2592000)
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	a := &Adaptor{}
+	m := &meta.Meta{BaseURL: "https://aip.baidubce.com", ActualModelName: "ernie-speed-128k", APIKey: "client|secret"}
+	url, err := a.GetRequestURL(m)
+	require.NoError(t, err)
+	require.Equal(t, "https://aip.baidubce.com/rpc/2.0/ai_custom/v1/wenxinworkshop/chat/ernie-speed-128k", url)
+
+	req := httptest.NewRequest(http.MethodPost, url, nil)
+	require.NoError(t, a.SetupRequestHeader(c, req, m))
+	require.Equal(t, "token-1", req.URL.Query().Get("access_token"))
+	require.Empty(t, req.Header.Get("Authorization"))
+
+	// Qianfan API keys are sent as bearer tokens without an exchange
+	m.APIKey = "bce-v3/ALTAK-key"
+	req = httptest.NewRequest(http.MethodPost, url, nil)
+	require.NoError(t, a.SetupRequestHeader(c, req, m))
+	require.Equal(t, "Bearer bce-v3/ALTAK-key", req.Header.Get("Authorization"))
+	require.Empty(t, req.URL.Query().Get("access_token"))
+}
@@ -11,6 +11,7 @@ import (
 var ModelRatios = map[string]adaptor.ModelConfig{
 	// ERNIE 4.0 Models
 	"ERNIE-4.0-8K": {Ratio: 12 * ratio.MilliTokensRmb, CompletionRatio: 1},
+	"ernie-4.0-8k": {Ratio: 12 * ratio.MilliTokensRmb, CompletionRatio: 1},
 
 	// ERNIE 3.5 Models
 	"ERNIE-3.5-8K":      {Ratio: 1.2 * ratio.MilliTokensRmb, CompletionRatio: 1},
@@ -22,6 +23,7 @@ var ModelRatios = map[string]adaptor.ModelConfig{
 	// ERNIE Speed Models
 	"ERNIE-Speed-8K":   {Ratio: 0.4 * ratio.MilliTokensRmb, CompletionRatio: 1},
 	"ERNIE-Speed-128K": {Ratio: 0.4 * ratio.MilliTokensRmb, CompletionRatio: 1},
+	"ernie-speed-128k": {Ratio: 0.4 * ratio.MilliTokensRmb, CompletionRatio: 1},
 
 	// ERNIE Lite Models
 	"ERNIE-Lite-8K-0922": {Ratio: 0.8 * ratio.MilliTokensRmb, CompletionRatio: 1},
@@ -0,0 +1,19 @@
+package adaptor
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// countingBody counts the bytes read from an upstream response body.
+type countingBody struct {
+	io.ReadCloser
+	read *atomic.Int64
+}
+
+// Read reads from the body and adds the bytes read to the counter.
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read.Add(int64(n))
+	return n, err
+}
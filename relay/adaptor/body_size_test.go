@@ -0,0 +1,21 @@
+package adaptor
+
+import (
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountingBody_CountsUpstreamBytes(t *testing.T) {
+	read := new(atomic.Int64)
+	body := &countingBody{ReadCloser: io.NopCloser(strings.NewReader(`{"id":"chatcmpl-1"}`)), read: read}
+
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.EqualValues(t, len(data), read.Load())
+	require.EqualValues(t, 19, read.Load())
+}
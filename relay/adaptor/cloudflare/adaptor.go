@@ -277,3 +277,9 @@ func (a *Adaptor) GetCompletionRatio(modelName string) float64 {
 func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return CloudflareToolingDefaults
 }
+
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.StandardModelFeatures()
+}
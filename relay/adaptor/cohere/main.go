@@ -31,8 +31,12 @@ func stopReasonCohere2OpenAI(reason *string) string {
 		return ""
 	}
 	switch *reason {
-	case "COMPLETE":
+	case "COMPLETE", "STOP_SEQUENCE":
 		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "TOOL_CALL":
+		return "tool_calls"
 	default:
 		return *reason
 	}
@@ -61,27 +65,60 @@ func ConvertRequest(textRequest model.GeneralOpenAIRequest) *Request {
 		cohereRequest.Model = strings.TrimSuffix(cohereRequest.Model, "-internet")
 		cohereRequest.Connectors = append(cohereRequest.Connectors, WebSearchConnector)
 	}
-	for _, message := range textRequest.Messages {
+	cohereRequest.StopSequences = stopSequences(textRequest.Stop)
+
+	// Cohere takes the latest user turn as message and everything before it
+	// as chat_history
+	lastUser := -1
+	for i, message := range textRequest.Messages {
 		if message.Role == "user" {
-			cohereRequest.Message = message.Content.(string)
-		} else {
-			var role string
-			if message.Role == "assistant" {
-				role = "CHATBOT"
-			} else if message.Role == "system" {
-				role = "SYSTEM"
-			} else {
-				role = "USER"
-			}
-			cohereRequest.ChatHistory = append(cohereRequest.ChatHistory, ChatMessage{
-				Role:    role,
-				Message: message.Content.(string),
-			})
+			lastUser = i
 		}
 	}
+	for i, message := range textRequest.Messages {
+		if i == lastUser {
+			cohereRequest.Message = message.StringContent()
+			continue
+		}
+		var role string
+		switch message.Role {
+		case "assistant":
+			role = "CHATBOT"
+		case "system":
+			role = "SYSTEM"
+		default:
+			role = "USER"
+		}
+		cohereRequest.ChatHistory = append(cohereRequest.ChatHistory, ChatMessage{
+			Role:    role,
+			Message: message.StringContent(),
+		})
+	}
 	return &cohereRequest
 }
 
+// stopSequences normalizes the OpenAI stop parameter, a string or a list of
+// strings, into Cohere's stop_sequences.
+func stopSequences(stop any) []string {
+	switch v := stop.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []string:
+		return v
+	case []any:
+		sequences := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok && str != "" {
+				sequences = append(sequences, str)
+			}
+		}
+		return sequences
+	}
+	return nil
+}
+
 func StreamResponseCohere2OpenAI(cohereResponse *StreamResponse) (*openai.ChatCompletionsStreamResponse, *Response) {
 	var response *Response
 	var responseText string
@@ -93,6 +130,9 @@ func StreamResponseCohere2OpenAI(cohereResponse *StreamResponse) (*openai.ChatCo
 	case "text-generation":
 		responseText += cohereResponse.Text
 	case "stream-end":
+		if cohereResponse.Response == nil {
+			return nil, nil
+		}
 		usage := cohereResponse.Response.Meta.Tokens
 		response = &Response{
 			Meta: Meta{
@@ -102,7 +142,7 @@ func StreamResponseCohere2OpenAI(cohereResponse *StreamResponse) (*openai.ChatCo
 				},
 			},
 		}
-		finishReason = *cohereResponse.Response.FinishReason
+		finishReason = stopReasonCohere2OpenAI(cohereResponse.Response.FinishReason)
 	default:
 		return nil, nil
 	}
@@ -163,7 +203,6 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 		if meta != nil {
 			usage.PromptTokens += meta.Meta.Tokens.InputTokens
 			usage.CompletionTokens += meta.Meta.Tokens.OutputTokens
-			continue
 		}
 		if response == nil {
 			continue
@@ -184,6 +223,7 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 	}
 
 	render.Done(c)
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 
 	err := resp.Body.Close()
 	if err != nil {
@@ -0,0 +1,88 @@
+package cohere
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+func TestConvertRequestSplitsLatestUserTurn(t *testing.T) {
+	t.Parallel()
+
+	temperature := 0.2
+	req := ConvertRequest(model.GeneralOpenAIRequest{
+		Model:       "command-r-plus-internet",
+		MaxTokens:   256,
+		Temperature: &temperature,
+		Stop:        []any{"\n\n", "END"},
+		Messages: []model.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "hi"},
+			{Role: "assistant", Content: "hello"},
+			{Role: "user", Content: []any{map[string]any{"type": "text", "text": "what is 2+2?"}}},
+		},
+	})
+
+	require.Equal(t, "command-r-plus", req.Model)
+	require.Equal(t, []Connector{WebSearchConnector}, req.Connectors)
+	require.Equal(t, 256, req.MaxTokens)
+	require.Equal(t, &temperature, req.Temperature)
+	require.Equal(t, []string{"\n\n", "END"}, req.StopSequences)
+	require.Equal(t, "what is 2+2?", req.Message)
+	require.Equal(t, []ChatMessage{
+		{Role: "SYSTEM", Message: "be brief"},
+		{Role: "USER", Message: "hi"},
+		{Role: "CHATBOT", Message: "hello"},
+	}, req.ChatHistory)
+
+	require.Equal(t, []string{"###"}, ConvertRequest(model.GeneralOpenAIRequest{Stop: "###"}).StopSequences)
+}
+
+func TestResponseCohere2OpenAI(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	for reason, expected := range map[string]string{"COMPLETE": "stop", "MAX_TOKENS": "length", "ERROR": "ERROR"} {
+		resp := ResponseCohere2OpenAI(c, &Response{Text: "4", FinishReason: &reason})
+		require.Len(t, resp.Choices, 1)
+		require.Equal(t, "assistant", resp.Choices[0].Message.Role)
+		require.Equal(t, "4", resp.Choices[0].Message.StringContent())
+		require.Equal(t, expected, resp.Choices[0].FinishReason, reason)
+	}
+}
+
+func TestStreamHandlerEmitsTextAndFinishReason(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	events := strings.Join([]string{
+		`{"is_finished":false,"event_type":"stream-start","generation_id":"g1"}`,
+		`{"is_finished":false,"event_type":"text-generation","text":"Hel"}`,
+		`{"is_finished":false,"event_type":"text-generation","text":"lo"}`,
+		`{"is_finished":true,"event_type":"stream-end","finish_reason":"MAX_TOKENS","response":{"response_id":"r1","text":"Hello","finish_reason":"MAX_TOKENS","meta":{"tokens":{"input_tokens":7,"output_tokens":2}}}}`,
+	}, "\n")
+	resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(events))}
+
+	errResp, usage := StreamHandler(c, resp)
+	require.Nil(t, errResp)
+	require.Equal(t, 7, usage.PromptTokens)
+	require.Equal(t, 2, usage.CompletionTokens)
+	require.Equal(t, 9, usage.TotalTokens)
+
+	body := w.Body.String()
+	require.Contains(t, body, `"content":"Hel"`)
+	require.Contains(t, body, `"content":"lo"`)
+	require.Contains(t, body, `"finish_reason":"length"`)
+	require.True(t, strings.HasSuffix(strings.TrimSpace(body), "data: [DONE]"))
+}
@@ -1,9 +1,11 @@
 package adaptor
 
 import (
+	"bytes"
 	"io"
 	"net/http"
 	"strings"
+	"sync/atomic"
 
 	"github.com/Laisky/errors/v2"
 	gmw "github.com/Laisky/gin-middlewares/v7"
@@ -11,7 +13,9 @@ import (
 	"github.com/Laisky/zap"
 	"github.com/gin-gonic/gin"
 
+	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/tracing"
 	"github.com/songquanpeng/one-api/model"
@@ -44,6 +48,15 @@ func DoRequestHelper(a Adaptor, c *gin.Context, meta *meta.Meta, requestBody io.
 		return nil, errors.Wrap(err, "get request url failed")
 	}
 
+	// Buffer the body so that network retries can replay it
+	var retryBody []byte
+	if config.NetworkRetryEnabled && requestBody != nil {
+		if retryBody, err = io.ReadAll(requestBody); err != nil {
+			return nil, errors.Wrap(err, "buffer request body for network retry")
+		}
+		requestBody = bytes.NewReader(retryBody)
+	}
+
 	var (
 		preview   []byte
 		truncated bool
@@ -109,22 +122,35 @@ func DoRequestHelper(a Adaptor, c *gin.Context, meta *meta.Meta, requestBody io.
 		zap.String("method", req.Method),
 		zap.String("url", fullRequestURL),
 		zap.Bool("body_truncated", truncated),
-		zap.ByteString("body_preview", preview),
+		zap.ByteString("body_preview", common.RedactAll(preview, config.RequestRedactionPatterns)),
 	}
 	if bodySize >= 0 {
 		fields = append(fields, zap.Int("body_bytes", bodySize))
+		c.Set(ctxkey.UpstreamRequestBytes, int64(bodySize))
 	}
 	lg.Debug("forwarding request to upstream channel", fields...)
 
+	req, timeout, cancel := withAdaptiveTimeout(c, req)
+	if timeout > 0 {
+		lg.Debug("applying adaptive upstream timeout", zap.Duration("timeout", timeout))
+	}
+
 	// Optionally: Record when request is forwarded to upstream (non-standard event)
 	tracing.RecordTraceTimestamp(c, model.TimestampRequestForwarded)
 
-	resp, err := DoRequest(c, req)
+	resp, err := doRequestWithNetworkRetry(c, req, retryBody)
 	if err != nil {
+		cancel()
 		// Return error without logging - let the calling ErrorWrapper function handle logging
 		// This prevents duplicate logging when ErrorWrapper also logs the error
 		return nil, errors.Wrapf(err, "upstream request failed for channel %s (id: %d)", a.GetChannelName(), meta.ChannelId)
 	}
+	if timeout > 0 {
+		resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	}
+	responseBytes := new(atomic.Int64)
+	resp.Body = &countingBody{ReadCloser: resp.Body, read: responseBytes}
+	c.Set(ctxkey.UpstreamResponseBytes, responseBytes)
 	// Add debug log for non-200 statuses to help diagnose model mapping issues
 	if resp != nil && resp.StatusCode >= 400 {
 		lg.Debug("upstream returned error status",
@@ -147,6 +173,13 @@ func DoRequest(c *gin.Context, req *http.Request) (*http.Response, error) {
 			httpClient = http.DefaultClient
 		}
 	}
+	// Upstreams supplied by users must never reach internal addresses
+	if client.IsGuardedUpstream(req.Context()) {
+		if client.GuardedHTTPClient == nil {
+			client.Init()
+		}
+		httpClient = client.GuardedHTTPClient
+	}
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "perform upstream request")
@@ -17,6 +17,9 @@ import (
 
 type Adaptor struct {
 	adaptor.DefaultPricingMethods
+
+	// includeReasoning records that the client sent include_reasoning: true.
+	includeReasoning bool
 }
 
 func (a *Adaptor) GetChannelName() string {
@@ -87,6 +90,11 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 	if request.ReasoningEffort != nil {
 		request.ReasoningEffort = nil
 	}
+	// include_reasoning only decides whether reasoning_content reaches the client
+	if request.IncludeReasoning != nil {
+		a.includeReasoning = *request.IncludeReasoning
+		request.IncludeReasoning = nil
+	}
 
 	if request.ResponseFormat != nil {
 		if request.ResponseFormat.JsonSchema != nil {
@@ -114,7 +122,11 @@ func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Read
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
 	return openai_compatible.HandleClaudeMessagesResponse(c, resp, meta, func(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
 		if meta.IsStream {
-			return openai_compatible.StreamHandler(c, resp, promptTokens, modelName)
+			body := newReasoningStreamBody(resp.Body, stripsReasoningContent(c, meta, a.includeReasoning))
+			resp.Body = body
+			err, usage := openai_compatible.StreamHandler(c, resp, promptTokens, modelName)
+			body.applyReasoningUsage(usage, modelName)
+			return err, usage
 		}
 		return openai_compatible.Handler(c, resp, promptTokens, modelName)
 	})
@@ -0,0 +1,124 @@
+package deepseek
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	dbmodel "github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+const reasonerStream = `data: {"id":"1","object":"chat.completion.chunk","model":"deepseek-reasoner","choices":[{"index":0,"delta":{"role":"assistant","content":null,"reasoning_content":""},"finish_reason":null}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"deepseek-reasoner","choices":[{"index":0,"delta":{"content":null,"reasoning_content":"Let me think"},"finish_reason":null}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"deepseek-reasoner","choices":[{"index":0,"delta":{"content":"Hello","reasoning_content":null},"finish_reason":null}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"deepseek-reasoner","choices":[{"index":0,"delta":{"content":""},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":12,"total_tokens":17,"completion_tokens_details":{"reasoning_tokens":9}}}
+
+data: [DONE]
+
+`
+
+func doStream(t *testing.T, target string, header http.Header, config dbmodel.ChannelConfig, adaptor *Adaptor, upstream string) (string, *model.Usage) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, target, nil)
+	for key, values := range header {
+		c.Request.Header[key] = values
+	}
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(upstream)),
+	}
+	m := &meta.Meta{
+		IsStream:        true,
+		Mode:            relaymode.ChatCompletions,
+		RequestURLPath:  "/v1/chat/completions",
+		ActualModelName: "deepseek-reasoner",
+		PromptTokens:    5,
+		Config:          config,
+	}
+	usage, errResp := adaptor.DoResponse(c, resp, m)
+	require.Nil(t, errResp)
+	return w.Body.String(), usage
+}
+
+func TestStreamStripsReasoningContentByDefault(t *testing.T) {
+	body, usage := doStream(t, "/v1/chat/completions", nil, dbmodel.ChannelConfig{}, &Adaptor{}, reasonerStream)
+
+	require.NotContains(t, body, "reasoning_content")
+	require.NotContains(t, body, "Let me think")
+	require.Contains(t, body, "Hello")
+	require.Contains(t, body, `"role":"assistant"`)
+	require.Contains(t, body, "[DONE]")
+
+	require.NotNil(t, usage)
+	require.Equal(t, 12, usage.CompletionTokens)
+	require.NotNil(t, usage.CompletionTokensDetails)
+	require.Equal(t, 9, usage.CompletionTokensDetails.ReasoningTokens)
+}
+
+func TestStreamForwardsRequestedReasoningContent(t *testing.T) {
+	includeReasoning := true
+	requested := &Adaptor{}
+	_, err := requested.ConvertRequest(nil, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{
+		Model:            "deepseek-reasoner",
+		IncludeReasoning: &includeReasoning,
+	})
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		target  string
+		header  http.Header
+		config  dbmodel.ChannelConfig
+		adaptor *Adaptor
+	}{
+		"channel passthrough": {target: "/v1/chat/completions", config: dbmodel.ChannelConfig{PassthroughReasoningContent: true}, adaptor: &Adaptor{}},
+		"include_reasoning":   {target: "/v1/chat/completions", adaptor: requested},
+		"reasoning_format":    {target: "/v1/chat/completions?reasoning_format=reasoning_content", adaptor: &Adaptor{}},
+		"header":              {target: "/v1/chat/completions", header: http.Header{"X-Oneapi-Include-Reasoning": {"true"}}, adaptor: &Adaptor{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, usage := doStream(t, tc.target, tc.header, tc.config, tc.adaptor, reasonerStream)
+			require.Contains(t, body, "Let me think")
+			require.Equal(t, 9, usage.CompletionTokensDetails.ReasoningTokens)
+		})
+	}
+}
+
+func TestStreamCountsReasoningTokensWithoutUpstreamUsage(t *testing.T) {
+	upstream := strings.Replace(reasonerStream,
+		`,"usage":{"prompt_tokens":5,"completion_tokens":12,"total_tokens":17,"completion_tokens_details":{"reasoning_tokens":9}}`, "", 1)
+	_, usage := doStream(t, "/v1/chat/completions", nil, dbmodel.ChannelConfig{}, &Adaptor{}, upstream)
+
+	require.NotNil(t, usage.CompletionTokensDetails)
+	reasoningTokens := usage.CompletionTokensDetails.ReasoningTokens
+	require.Positive(t, reasoningTokens)
+	require.Greater(t, usage.CompletionTokens, reasoningTokens)
+	require.Equal(t, usage.PromptTokens+usage.CompletionTokens, usage.TotalTokens)
+}
+
+func TestConvertRequestDropsIncludeReasoning(t *testing.T) {
+	includeReasoning := true
+	adaptor := &Adaptor{}
+	converted, err := adaptor.ConvertRequest(nil, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{
+		Model:            "deepseek-reasoner",
+		IncludeReasoning: &includeReasoning,
+	})
+	require.NoError(t, err)
+	require.Nil(t, converted.(*model.GeneralOpenAIRequest).IncludeReasoning)
+	require.True(t, adaptor.includeReasoning)
+}
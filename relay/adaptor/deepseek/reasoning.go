@@ -0,0 +1,177 @@
+package deepseek
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+// reasoningContentField is the delta field deepseek-reasoner streams its
+// chain of thought in.
+const reasoningContentField = "reasoning_content"
+
+// stripsReasoningContent reports whether reasoning_content must be removed
+// from the response. Unless the channel enables PassthroughReasoningContent,
+// clients opt in with include_reasoning, the reasoning_format query parameter
+// or openai.IncludeReasoningHeader.
+func stripsReasoningContent(c *gin.Context, meta *meta.Meta, includeReasoning bool) bool {
+	if meta.Config.PassthroughReasoningContent || includeReasoning {
+		return false
+	}
+	if c.Query("reasoning_format") != "" {
+		return false
+	}
+	return !strings.EqualFold(strings.TrimSpace(c.GetHeader(openai.IncludeReasoningHeader)), "true")
+}
+
+// reasoningStreamBody reads the SSE stream of a DeepSeek response, collecting
+// the reasoning_content of every delta and the upstream usage, and removes
+// reasoning_content from the chunks it passes on when strip is set.
+type reasoningStreamBody struct {
+	io.ReadCloser
+
+	strip     bool
+	reasoning strings.Builder
+	usage     *model.Usage
+}
+
+func newReasoningStreamBody(body io.ReadCloser, strip bool) *reasoningStreamBody {
+	b := &reasoningStreamBody{strip: strip}
+	b.ReadCloser = openai_compatible.NewLineRewriter(body, b.processLine)
+	return b
+}
+
+// streamChunk holds the parts of a chunk reasoningStreamBody inspects.
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			ReasoningContent *string `json:"reasoning_content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *model.Usage `json:"usage"`
+}
+
+// processLine records the reasoning and usage carried by an SSE data line and
+// returns the line to forward, nil when nothing is left of it after stripping.
+func (b *reasoningStreamBody) processLine(line []byte) []byte {
+	content := bytes.TrimRight(line, "\r\n")
+	payload, ok := bytes.CutPrefix(content, []byte("data:"))
+	if !ok {
+		return line
+	}
+	payload = bytes.TrimLeft(payload, " ")
+	if !bytes.HasPrefix(payload, []byte("{")) {
+		return line
+	}
+
+	var chunk streamChunk
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return line
+	}
+	if chunk.Usage != nil {
+		b.usage = chunk.Usage
+	}
+	hasReasoning := false
+	for _, choice := range chunk.Choices {
+		if choice.Delta.ReasoningContent != nil {
+			b.reasoning.WriteString(*choice.Delta.ReasoningContent)
+			hasReasoning = true
+		}
+	}
+	if !b.strip || !hasReasoning {
+		return line
+	}
+
+	stripped, keep, err := stripReasoningContent(payload)
+	if err != nil {
+		return line
+	}
+	if !keep {
+		return nil
+	}
+	return append(append([]byte("data: "), stripped...), line[len(content):]...)
+}
+
+// stripReasoningContent removes reasoning_content from the deltas of a chunk.
+// keep is false when the chunk carried nothing but reasoning, so it can be
+// dropped instead of forwarding empty deltas.
+func stripReasoningContent(payload []byte) (stripped []byte, keep bool, err error) {
+	var chunk map[string]json.RawMessage
+	if err = json.Unmarshal(payload, &chunk); err != nil {
+		return nil, false, err
+	}
+	var choices []map[string]json.RawMessage
+	if err = json.Unmarshal(chunk["choices"], &choices); err != nil {
+		return nil, false, err
+	}
+
+	keep = hasValue(chunk["usage"])
+	for _, choice := range choices {
+		var delta map[string]json.RawMessage
+		if err = json.Unmarshal(choice["delta"], &delta); err != nil {
+			return nil, false, err
+		}
+		delete(delta, reasoningContentField)
+		for _, value := range delta {
+			if hasValue(value) {
+				keep = true
+			}
+		}
+		if hasValue(choice["finish_reason"]) {
+			keep = true
+		}
+		if choice["delta"], err = json.Marshal(delta); err != nil {
+			return nil, false, err
+		}
+	}
+	if chunk["choices"], err = json.Marshal(choices); err != nil {
+		return nil, false, err
+	}
+	if stripped, err = json.Marshal(chunk); err != nil {
+		return nil, false, err
+	}
+	return stripped, keep, nil
+}
+
+// hasValue reports whether a JSON value is present and neither null nor an
+// empty string.
+func hasValue(value json.RawMessage) bool {
+	value = bytes.TrimSpace(value)
+	return len(value) > 0 && !bytes.Equal(value, []byte("null")) && !bytes.Equal(value, []byte(`""`))
+}
+
+// applyReasoningUsage reports the reasoning tokens of the stream in usage.
+// DeepSeek counts them in completion_tokens_details when it sends usage;
+// otherwise they are counted from the collected reasoning and, since the
+// shared stream handler only counts visible content, added to the completion.
+func (b *reasoningStreamBody) applyReasoningUsage(usage *model.Usage, modelName string) {
+	if usage == nil {
+		return
+	}
+	reasoningTokens := 0
+	if b.usage != nil && b.usage.CompletionTokensDetails != nil {
+		reasoningTokens = b.usage.CompletionTokensDetails.ReasoningTokens
+	}
+	if reasoningTokens == 0 && b.reasoning.Len() > 0 {
+		reasoningTokens = openai_compatible.CountTokenText(b.reasoning.String(), modelName)
+		if b.usage == nil || b.usage.CompletionTokens == 0 {
+			usage.CompletionTokens += reasoningTokens
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		}
+	}
+	if reasoningTokens == 0 {
+		return
+	}
+	if usage.CompletionTokensDetails == nil {
+		usage.CompletionTokensDetails = &model.UsageCompletionTokensDetails{}
+	}
+	usage.CompletionTokensDetails.ReasoningTokens = reasoningTokens
+}
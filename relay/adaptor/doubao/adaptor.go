@@ -83,3 +83,9 @@ func (a *Adaptor) GetCompletionRatio(modelName string) float64 {
 func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return DoubaoToolingDefaults
 }
+
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureExtendedThinking)
+}
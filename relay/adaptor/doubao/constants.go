@@ -21,6 +21,16 @@ var ModelRatios = map[string]adaptor.ModelConfig{
 
 	// Embedding Models
 	"Doubao-embedding": {Ratio: 0.0002 * ratio.MilliTokensRmb, CompletionRatio: 1},
+
+	// Ark model names, priced in RMB per 1M tokens (lowest context tier).
+	// Channels serving them through endpoint IDs map names with endpoint_id_mapping.
+	// https://www.volcengine.com/docs/82379/1544106
+	// Input 0.8 RMB/1M tokens, output 2 RMB/1M tokens
+	"doubao-pro-32k": {Ratio: 0.8 * ratio.MilliTokensRmb, CompletionRatio: 2.5},
+	// Input 0.3 RMB/1M tokens, output 0.6 RMB/1M tokens
+	"doubao-lite-32k": {Ratio: 0.3 * ratio.MilliTokensRmb, CompletionRatio: 2},
+	// Input 3 RMB/1M tokens, output 9 RMB/1M tokens
+	"doubao-vision-pro-32k": {Ratio: 3 * ratio.MilliTokensRmb, CompletionRatio: 3},
 }
 
 // ModelList derived from ModelRatios for backward compatibility
@@ -0,0 +1,50 @@
+package doubao
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	dbmodel "github.com/songquanpeng/one-api/model"
+)
+
+// ResolveModel returns the Ark endpoint ID (ep-...) the channel in c maps
+// modelName to, or modelName unchanged when the channel has no matching entry.
+// Ark serves models through inference endpoints, so requests may have to name
+// an endpoint instead of a model.
+func ResolveModel(c *gin.Context, modelName string) string {
+	v, ok := c.Get(ctxkey.ChannelModel)
+	if !ok {
+		return modelName
+	}
+	channel, ok := v.(*dbmodel.Channel)
+	if !ok || channel == nil {
+		return modelName
+	}
+	if endpointId, ok := EndpointIdForModel(channel.GetEndpointIdMapping(), modelName); ok {
+		return endpointId
+	}
+	return modelName
+}
+
+// EndpointIdForModel looks modelName up in mapping. An exact key wins;
+// otherwise the longest key ending in "*" whose prefix matches modelName is
+// used, so "*" alone maps every model to one endpoint.
+func EndpointIdForModel(mapping map[string]string, modelName string) (string, bool) {
+	if endpointId, ok := mapping[modelName]; ok && endpointId != "" {
+		return endpointId, true
+	}
+
+	best, bestLen := "", -1
+	for pattern, endpointId := range mapping {
+		prefix, isWildcard := strings.CutSuffix(pattern, "*")
+		if !isWildcard || endpointId == "" || !strings.HasPrefix(modelName, prefix) {
+			continue
+		}
+		if len(prefix) > bestLen {
+			best, bestLen = endpointId, len(prefix)
+		}
+	}
+	return best, bestLen >= 0
+}
@@ -0,0 +1,72 @@
+package doubao
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	dbmodel "github.com/songquanpeng/one-api/model"
+)
+
+func TestEndpointIdForModel(t *testing.T) {
+	mapping := map[string]string{
+		"doubao-pro-32k":    "ep-20250101-pro32k",
+		"doubao-pro-*":      "ep-20250101-pro",
+		"doubao-*":          "ep-20250101-any",
+		"doubao-lite-32k-*": "",
+	}
+
+	tests := []struct {
+		model string
+		want  string
+		found bool
+	}{
+		{"doubao-pro-32k", "ep-20250101-pro32k", true},
+		{"doubao-pro-128k", "ep-20250101-pro", true},
+		{"doubao-lite-32k", "ep-20250101-any", true},
+		{"doubao-lite-32k-240828", "ep-20250101-any", true},
+		{"deepseek-v3", "", false},
+	}
+	for _, tt := range tests {
+		got, found := EndpointIdForModel(mapping, tt.model)
+		require.Equal(t, tt.found, found, tt.model)
+		require.Equal(t, tt.want, got, tt.model)
+	}
+
+	got, found := EndpointIdForModel(map[string]string{"*": "ep-catch-all"}, "deepseek-v3")
+	require.True(t, found)
+	require.Equal(t, "ep-catch-all", got)
+
+	_, found = EndpointIdForModel(nil, "doubao-pro-32k")
+	require.False(t, found)
+}
+
+func TestResolveModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mapping := `{"doubao-pro-32k":"ep-20250101-xxxx","doubao-vision-*":"ep-20250101-vision"}`
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(ctxkey.ChannelModel, &dbmodel.Channel{Id: 1, EndpointIdMapping: &mapping})
+
+	for modelName, want := range map[string]string{
+		"doubao-pro-32k":        "ep-20250101-xxxx",
+		"doubao-vision-pro-32k": "ep-20250101-vision",
+		"ep-20250101-direct":    "ep-20250101-direct",
+	} {
+		require.Equal(t, want, ResolveModel(c, modelName), modelName)
+	}
+
+	// Without a channel in the context the model name is forwarded as is
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	require.Equal(t, "doubao-pro-32k", ResolveModel(c, "doubao-pro-32k"))
+}
+
+func TestValidateEndpointIdMappingJSON(t *testing.T) {
+	require.NoError(t, dbmodel.ValidateEndpointIdMappingJSON(""))
+	require.NoError(t, dbmodel.ValidateEndpointIdMappingJSON(`{"doubao-pro-32k":"ep-1","doubao-*":"ep-2","*":"ep-3"}`))
+	require.Error(t, dbmodel.ValidateEndpointIdMappingJSON(`{"doubao-pro-32k":""}`))
+	require.Error(t, dbmodel.ValidateEndpointIdMappingJSON(`{"doubao-*-32k":"ep-1"}`))
+	require.Error(t, dbmodel.ValidateEndpointIdMappingJSON(`not json`))
+}
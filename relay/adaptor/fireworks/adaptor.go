@@ -0,0 +1,120 @@
+package fireworks
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/relay/adaptor"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+// https://docs.fireworks.ai/api-reference/post-chatcompletions
+
+// Adaptor relays requests to Fireworks AI. The API is OpenAI compatible, but
+// models are addressed by their full account path, such as
+// accounts/fireworks/models/llama-v3p1-405b-instruct, and usage carries the
+// time the request waited in queue.
+type Adaptor struct {
+	adaptor.DefaultPricingMethods
+}
+
+func (a *Adaptor) Init(meta *meta.Meta) {
+}
+
+func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
+	path := meta.RequestURLPath
+	if idx := strings.Index(path, "?"); idx >= 0 {
+		path = path[:idx]
+	}
+	if path == "/v1/messages" {
+		// Claude Messages requests are converted to chat completions
+		path = "/v1/chat/completions"
+	}
+	return openai_compatible.GetFullRequestURL(meta.BaseURL, path, meta.ChannelType), nil
+}
+
+func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Request, meta *meta.Meta) error {
+	adaptor.SetupCommonRequestHeader(c, req, meta)
+	req.Header.Set("Authorization", "Bearer "+meta.APIKey)
+	return nil
+}
+
+func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.GeneralOpenAIRequest) (any, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	// Fireworks does not accept reasoning_effort
+	request.ReasoningEffort = nil
+	request.Model = normalizeModelName(request.Model)
+	return request, nil
+}
+
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, request *model.ImageRequest) (any, error) {
+	return nil, errors.New("fireworks does not support image generation")
+}
+
+func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, request *model.ClaudeRequest) (any, error) {
+	converted, err := openai_compatible.ConvertClaudeRequest(c, request)
+	if err != nil {
+		return nil, err
+	}
+	if openaiRequest, ok := converted.(*model.GeneralOpenAIRequest); ok {
+		openaiRequest.Model = normalizeModelName(openaiRequest.Model)
+	}
+	return converted, nil
+}
+
+func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Reader) (*http.Response, error) {
+	return adaptor.DoRequestHelper(a, c, meta, requestBody)
+}
+
+func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
+	return openai_compatible.HandleClaudeMessagesResponse(c, resp, meta, func(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
+		body := newQueueDurationBody(resp.Body)
+		resp.Body = body
+		defer body.record(c)
+		if meta.IsStream {
+			return openai_compatible.StreamHandler(c, resp, promptTokens, modelName)
+		}
+		return openai_compatible.Handler(c, resp, promptTokens, modelName)
+	})
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return "fireworks"
+}
+
+// GetDefaultModelPricing returns the pricing information for Fireworks models
+func (a *Adaptor) GetDefaultModelPricing() map[string]adaptor.ModelConfig {
+	return ModelRatios
+}
+
+func (a *Adaptor) GetModelRatio(modelName string) float64 {
+	if price, exists := ModelRatios[strings.TrimPrefix(modelName, ModelPrefix)]; exists {
+		return price.Ratio
+	}
+	return a.DefaultPricingMethods.GetModelRatio(modelName)
+}
+
+func (a *Adaptor) GetCompletionRatio(modelName string) float64 {
+	if price, exists := ModelRatios[strings.TrimPrefix(modelName, ModelPrefix)]; exists {
+		return price.CompletionRatio
+	}
+	return a.DefaultPricingMethods.GetCompletionRatio(modelName)
+}
+
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureStructuredOutput)
+}
@@ -0,0 +1,113 @@
+package fireworks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+func TestGetRequestURL(t *testing.T) {
+	adaptor := &Adaptor{}
+	for path, expected := range map[string]string{
+		"/v1/chat/completions": "https://api.fireworks.ai/inference/v1/chat/completions",
+		"/v1/messages":         "https://api.fireworks.ai/inference/v1/chat/completions",
+		"/v1/embeddings":       "https://api.fireworks.ai/inference/v1/embeddings",
+	} {
+		url, err := adaptor.GetRequestURL(&meta.Meta{
+			BaseURL:        channeltype.ChannelBaseURLs[channeltype.Fireworks],
+			RequestURLPath: path,
+			ChannelType:    channeltype.Fireworks,
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, url, path)
+	}
+}
+
+func TestConvertRequestNormalizesModelName(t *testing.T) {
+	adaptor := &Adaptor{}
+	for name, expected := range map[string]string{
+		"llama-v3p1-405b-instruct":                           "accounts/fireworks/models/llama-v3p1-405b-instruct",
+		"accounts/fireworks/models/llama-v3p1-405b-instruct": "accounts/fireworks/models/llama-v3p1-405b-instruct",
+		"accounts/my-team/models/fine-tuned":                 "accounts/my-team/models/fine-tuned",
+	} {
+		converted, err := adaptor.ConvertRequest(nil, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{Model: name})
+		require.NoError(t, err)
+		require.Equal(t, expected, converted.(*model.GeneralOpenAIRequest).Model)
+	}
+}
+
+func TestPricingAcceptsPrefixedModelNames(t *testing.T) {
+	adaptor := &Adaptor{}
+	require.Contains(t, adaptor.GetModelList(), "llama-v3p1-405b-instruct")
+	require.Equal(t, ModelRatios["deepseek-r1"].Ratio, adaptor.GetModelRatio("deepseek-r1"))
+	require.Equal(t, ModelRatios["deepseek-r1"].Ratio, adaptor.GetModelRatio(ModelPrefix+"deepseek-r1"))
+	require.Equal(t, ModelRatios["deepseek-r1"].CompletionRatio, adaptor.GetCompletionRatio(ModelPrefix+"deepseek-r1"))
+}
+
+func newTestContext() (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	return c, w
+}
+
+func TestDoResponseRecordsQueueDuration(t *testing.T) {
+	c, w := newTestContext()
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body: io.NopCloser(strings.NewReader(`{
+  "id": "1",
+  "object": "chat.completion",
+  "choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi \"queue_duration_ms\": 1"}, "finish_reason": "stop"}],
+  "usage": {"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5, "queue_duration_ms": 41.5}
+}`)),
+	}
+	usage, errResp := (&Adaptor{}).DoResponse(c, resp, &meta.Meta{
+		Mode:            relaymode.ChatCompletions,
+		RequestURLPath:  "/v1/chat/completions",
+		ActualModelName: "llama-v3p1-8b-instruct",
+	})
+	require.Nil(t, errResp)
+	require.Equal(t, 5, usage.TotalTokens)
+	require.Contains(t, w.Body.String(), "Hi")
+	require.Equal(t, 41.5, c.GetFloat64(ctxkey.UpstreamQueueDurationMs))
+}
+
+func TestDoResponseRecordsStreamQueueDuration(t *testing.T) {
+	c, w := newTestContext()
+	upstream := `data: {"id":"1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":null}]}
+
+data: {"id":"1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5,"queue_duration_ms":12}}
+
+data: [DONE]
+
+`
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(upstream)),
+	}
+	usage, errResp := (&Adaptor{}).DoResponse(c, resp, &meta.Meta{
+		IsStream:        true,
+		Mode:            relaymode.ChatCompletions,
+		RequestURLPath:  "/v1/chat/completions",
+		ActualModelName: "llama-v3p1-8b-instruct",
+	})
+	require.Nil(t, errResp)
+	require.Equal(t, 5, usage.TotalTokens)
+	require.Contains(t, w.Body.String(), "[DONE]")
+	require.Equal(t, 12.0, c.GetFloat64(ctxkey.UpstreamQueueDurationMs))
+}
@@ -0,0 +1,56 @@
+package fireworks
+
+import (
+	"github.com/songquanpeng/one-api/relay/adaptor"
+	"github.com/songquanpeng/one-api/relay/billing/ratio"
+)
+
+// ModelPrefix is the account path Fireworks expects in front of the models it
+// hosts itself.
+const ModelPrefix = "accounts/fireworks/models/"
+
+// ModelRatios contains all supported models and their pricing ratios
+// Model list is derived from the keys of this map, eliminating redundancy
+// Keys are the short model names; ConvertRequest adds ModelPrefix upstream.
+// Based on Fireworks serverless pricing: https://fireworks.ai/pricing
+var ModelRatios = map[string]adaptor.ModelConfig{
+	// Meta Llama
+	"llama-v3p1-405b-instruct":       {Ratio: 3 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama-v3p1-70b-instruct":        {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama-v3p1-8b-instruct":         {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama-v3p3-70b-instruct":        {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama-v3p2-3b-instruct":         {Ratio: 0.1 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama-v3p2-11b-vision-instruct": {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama-v3p2-90b-vision-instruct": {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama4-maverick-instruct-basic": {Ratio: 0.22 * ratio.MilliTokensUsd, CompletionRatio: 0.88 / 0.22},
+	"llama4-scout-instruct-basic":    {Ratio: 0.15 * ratio.MilliTokensUsd, CompletionRatio: 0.6 / 0.15},
+
+	// DeepSeek
+	"deepseek-v3":      {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"deepseek-v3-0324": {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"deepseek-r1":      {Ratio: 3 * ratio.MilliTokensUsd, CompletionRatio: 8.0 / 3},
+	"deepseek-r1-0528": {Ratio: 3 * ratio.MilliTokensUsd, CompletionRatio: 8.0 / 3},
+
+	// Qwen
+	"qwen3-235b-a22b":            {Ratio: 0.22 * ratio.MilliTokensUsd, CompletionRatio: 0.88 / 0.22},
+	"qwen3-30b-a3b":              {Ratio: 0.15 * ratio.MilliTokensUsd, CompletionRatio: 0.6 / 0.15},
+	"qwen2p5-72b-instruct":       {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"qwen2p5-coder-32b-instruct": {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"qwq-32b":                    {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+
+	// Mistral
+	"mixtral-8x22b-instruct":          {Ratio: 1.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"mixtral-8x7b-instruct":           {Ratio: 0.5 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"mistral-small-24b-instruct-2501": {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+
+	// Moonshot and OpenAI open-weight models
+	"kimi-k2-instruct": {Ratio: 0.6 * ratio.MilliTokensUsd, CompletionRatio: 2.5 / 0.6},
+	"gpt-oss-120b":     {Ratio: 0.15 * ratio.MilliTokensUsd, CompletionRatio: 0.6 / 0.15},
+	"gpt-oss-20b":      {Ratio: 0.07 * ratio.MilliTokensUsd, CompletionRatio: 0.3 / 0.07},
+
+	// Fireworks
+	"firefunction-v2": {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+}
+
+// ModelList derived from ModelRatios for backward compatibility
+var ModelList = adaptor.GetModelListFromPricing(ModelRatios)
@@ -0,0 +1,76 @@
+package fireworks
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
+)
+
+// normalizeModelName adds ModelPrefix to model names that lack an account path.
+func normalizeModelName(name string) string {
+	if name == "" || strings.HasPrefix(name, "accounts/") {
+		return name
+	}
+	return ModelPrefix + name
+}
+
+// queueDurationKey is the non-standard usage field in which Fireworks reports
+// how long the request waited in queue.
+var queueDurationKey = []byte(`"queue_duration_ms"`)
+
+// queueDurationBody passes a Fireworks response through unchanged while
+// looking for usage.queue_duration_ms, in a JSON body or in SSE data lines.
+type queueDurationBody struct {
+	io.ReadCloser
+
+	queueDurationMs *float64
+}
+
+func newQueueDurationBody(body io.ReadCloser) *queueDurationBody {
+	b := &queueDurationBody{}
+	b.ReadCloser = openai_compatible.NewLineRewriter(body, b.inspect)
+	return b
+}
+
+// inspect records the queue duration carried by a line of the response and
+// returns the line unchanged. The
+// key is matched on its own, so the value is found in SSE data lines as well as
+// in pretty-printed JSON; quotes inside string values are escaped and cannot
+// produce a false match.
+func (b *queueDurationBody) inspect(line []byte) []byte {
+	_, rest, found := bytes.Cut(line, queueDurationKey)
+	if !found {
+		return line
+	}
+	rest = bytes.TrimLeft(rest, " \t")
+	rest, found = bytes.CutPrefix(rest, []byte(":"))
+	if !found {
+		return line
+	}
+	rest = bytes.TrimLeft(rest, " \t")
+	end := bytes.IndexFunc(rest, func(r rune) bool {
+		return !strings.ContainsRune("0123456789.eE+-", r)
+	})
+	if end >= 0 {
+		rest = rest[:end]
+	}
+	value, err := strconv.ParseFloat(string(rest), 64)
+	if err != nil {
+		return line
+	}
+	b.queueDurationMs = &value
+	return line
+}
+
+// record stores the queue duration for the consume log.
+func (b *queueDurationBody) record(c *gin.Context) {
+	if b.queueDurationMs != nil {
+		c.Set(ctxkey.UpstreamQueueDurationMs, *b.queueDurationMs)
+	}
+}
@@ -75,7 +75,13 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 		geminiEmbeddingRequest := ConvertEmbeddingRequest(*request)
 		return geminiEmbeddingRequest, nil
 	default:
-		geminiRequest := ConvertRequest(*request)
+		geminiRequest, err := ConvertRequest(*request)
+		if err != nil {
+			return nil, errors.Wrap(err, "convert request")
+		}
+		if pages := CountDocumentPages(c, request.Messages); pages > 0 {
+			c.Set(ctxkey.DocumentPages, pages)
+		}
 		return geminiRequest, nil
 	}
 }
@@ -652,6 +658,12 @@ func (a *Adaptor) DefaultToolingConfig() channelhelper.ChannelToolConfig {
 	return GeminiToolingDefaults
 }
 
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() channelhelper.ModelFeatureFlags {
+	return channelhelper.WithModelFeatures(channelhelper.FeatureVision, channelhelper.FeatureStructuredOutput)
+}
+
 func mapGeminiFinishReason(reason string) string {
 	switch reason {
 	case "MAX_TOKENS":
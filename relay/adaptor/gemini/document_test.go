@@ -0,0 +1,121 @@
+package gemini
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+// buildTestPDF returns an uncompressed PDF with the given number of pages.
+func buildTestPDF(pages int) []byte {
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	kids := make([]string, pages)
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", i+3)
+	}
+	fmt.Fprintf(&b, "2 0 obj << /Type /Pages /Kids [%s] /Count %d >> endobj\n", strings.Join(kids, " "), pages)
+	for i := range pages {
+		fmt.Fprintf(&b, "%d 0 obj << /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >> endobj\n", i+3)
+	}
+	b.WriteString("trailer << /Root 1 0 R >>\n%%EOF\n")
+	return []byte(b.String())
+}
+
+func documentRequest(data string) *model.GeneralOpenAIRequest {
+	return &model.GeneralOpenAIRequest{
+		Model: "gemini-2.5-flash",
+		Messages: []model.Message{{
+			Role: "user",
+			Content: []any{
+				map[string]any{"type": "text", "text": "Summarize this report"},
+				map[string]any{"type": "document", "document": map[string]any{"data": data}},
+			},
+		}},
+	}
+}
+
+func TestConvertRequestDocumentContent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.ApproximateTokenEnabled = true
+	pdf := buildTestPDF(5)
+	request := documentRequest("data:application/pdf;base64," + base64.StdEncoding.EncodeToString(pdf))
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	converted, err := (&Adaptor{}).ConvertRequest(c, relaymode.ChatCompletions, request)
+	require.NoError(t, err)
+
+	geminiRequest, ok := converted.(*ChatRequest)
+	require.True(t, ok)
+	require.Len(t, geminiRequest.Contents, 1)
+	parts := geminiRequest.Contents[0].Parts
+	require.Len(t, parts, 2)
+	require.Equal(t, "Summarize this report", parts[0].Text)
+	require.NotNil(t, parts[1].InlineData)
+	require.Equal(t, "application/pdf", parts[1].InlineData.MimeType)
+	require.Equal(t, base64.StdEncoding.EncodeToString(pdf), parts[1].InlineData.Data)
+	require.Equal(t, 5, c.GetInt(ctxkey.DocumentPages))
+
+	// Prompt tokens are approximated at 756 per page on top of the text
+	textOnly := openai.CountTokenMessages(context.Background(),
+		[]model.Message{{Role: "user", Content: []any{map[string]any{"type": "text", "text": "Summarize this report"}}}},
+		request.Model)
+	withDocument := openai.CountTokenMessages(context.Background(), request.Messages, request.Model)
+	require.Equal(t, 5*model.DocumentTokensPerPage, withDocument-textOnly)
+}
+
+func TestConvertDocumentContentValidation(t *testing.T) {
+	originalMaxSize := config.MaxInlineImageSizeMB
+	config.MaxInlineImageSizeMB = 1
+	defer func() { config.MaxInlineImageSizeMB = originalMaxSize }()
+
+	oversized := make([]byte, 1024*1024+1)
+	_, err := ConvertDocumentContent(&model.MessageContent{
+		Type:     model.ContentTypeDocument,
+		Document: &model.Document{MimeType: "application/pdf", Data: base64.StdEncoding.EncodeToString(oversized)},
+	})
+	require.ErrorContains(t, err, "should not exceed 1MB")
+
+	_, err = ConvertDocumentContent(&model.MessageContent{
+		Type:     model.ContentTypeDocument,
+		Document: &model.Document{MimeType: "text/html", Data: base64.StdEncoding.EncodeToString([]byte("<p>hi</p>"))},
+	})
+	require.ErrorContains(t, err, "unsupported document type")
+
+	_, err = ConvertRequest(*documentRequest("not base64!"))
+	require.Error(t, err)
+}
+
+func TestCountDocumentPagesReusesPromptCount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	request := documentRequest("data:application/pdf;base64," + base64.StdEncoding.EncodeToString(buildTestPDF(3)))
+
+	// Counting prompt tokens records the pages, which conversion then reuses
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	openai.CountTokenMessages(c, request.Messages, request.Model)
+	require.Equal(t, 3, c.GetInt(ctxkey.DocumentPages))
+	c.Set(ctxkey.DocumentPages, 7)
+	require.Equal(t, 7, CountDocumentPages(c, request.Messages))
+
+	// Oversized documents are neither counted nor parsed
+	originalMaxSize := config.MaxInlineImageSizeMB
+	config.MaxInlineImageSizeMB = 1
+	defer func() { config.MaxInlineImageSizeMB = originalMaxSize }()
+	oversized := append(buildTestPDF(2), make([]byte, 1024*1024)...)
+	request = documentRequest("data:application/pdf;base64," + base64.StdEncoding.EncodeToString(oversized))
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	require.Zero(t, CountDocumentPages(c, request.Messages))
+}
@@ -2,6 +2,7 @@ package gemini
 
 import (
 	"bufio"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/common/image"
 	"github.com/songquanpeng/one-api/common/logger"
@@ -195,7 +197,7 @@ func cleanFunctionParametersInternal(params any, isTopLevel bool) any {
 }
 
 // Setting safety to the lowest possible values since Gemini is already powerless enough
-func ConvertRequest(textRequest model.GeneralOpenAIRequest) *ChatRequest {
+func ConvertRequest(textRequest model.GeneralOpenAIRequest) (*ChatRequest, error) {
 	geminiRequest := ChatRequest{
 		Contents: make([]ChatContent, 0, len(textRequest.Messages)),
 		SafetySettings: []ChatSafetySettings{
@@ -375,6 +377,12 @@ func ConvertRequest(textRequest model.GeneralOpenAIRequest) *ChatRequest {
 						Data:     data,
 					},
 				})
+			} else if part.Type == model.ContentTypeDocument {
+				documentPart, err := ConvertDocumentContent(&part)
+				if err != nil {
+					return nil, errors.Wrap(err, "convert document content")
+				}
+				parts = append(parts, *documentPart)
 			}
 		}
 
@@ -431,7 +439,55 @@ func ConvertRequest(textRequest model.GeneralOpenAIRequest) *ChatRequest {
 		}
 	}
 
-	return &geminiRequest
+	return &geminiRequest, nil
+}
+
+// ConvertDocumentContent converts a document content block into an inline
+// data part. Gemini reads PDFs natively, so only PDFs within
+// MaxInlineImageSizeMB are accepted.
+func ConvertDocumentContent(content *model.MessageContent) (*Part, error) {
+	if content == nil || content.Document == nil {
+		return nil, errors.New("document content is empty")
+	}
+	mimeType, data, err := content.Document.Decode()
+	if err != nil {
+		return nil, errors.Wrap(err, "decode document")
+	}
+	if mimeType != "application/pdf" {
+		return nil, errors.Errorf("unsupported document type %q, only application/pdf is supported", mimeType)
+	}
+	if maxSize := int64(config.MaxInlineImageSizeMB) * 1024 * 1024; int64(len(data)) > maxSize {
+		return nil, errors.Errorf("document size should not exceed %dMB, size: %d", config.MaxInlineImageSizeMB, len(data))
+	}
+
+	return &Part{
+		InlineData: &InlineData{
+			MimeType: mimeType,
+			Data:     base64.StdEncoding.EncodeToString(data),
+		},
+	}, nil
+}
+
+// CountDocumentPages returns the total pages of the documents attached to
+// messages. Prompt token counting already stores the count in the context, in
+// which case the documents are not parsed again.
+func CountDocumentPages(c *gin.Context, messages []model.Message) int {
+	if pages := c.GetInt(ctxkey.DocumentPages); pages > 0 {
+		return pages
+	}
+	maxSize := int64(config.MaxInlineImageSizeMB) * 1024 * 1024
+	pages := 0
+	for _, message := range messages {
+		for _, content := range message.ParseContent() {
+			if content.Type != model.ContentTypeDocument || content.Document == nil {
+				continue
+			}
+			if n, err := content.Document.Pages(maxSize); err == nil {
+				pages += n
+			}
+		}
+	}
+	return pages
 }
 
 func ConvertEmbeddingRequest(request model.GeneralOpenAIRequest) *BatchEmbeddingRequest {
@@ -436,7 +436,10 @@ func TestConvertRequestWithToolsRegression(t *testing.T) {
 	}
 
 	startTime := time.Now()
-	geminiRequest := ConvertRequest(textRequest)
+	geminiRequest, err := ConvertRequest(textRequest)
+	if err != nil {
+		t.Fatalf("ConvertRequest failed: %v", err)
+	}
 	elapsed := time.Since(startTime)
 
 	if elapsed > 200*time.Millisecond {
@@ -735,7 +738,10 @@ func TestOriginalErrorScenario(t *testing.T) {
 	}
 
 	startTime := time.Now().UTC()
-	geminiRequest := ConvertRequest(textRequest)
+	geminiRequest, err := ConvertRequest(textRequest)
+	if err != nil {
+		t.Fatalf("ConvertRequest failed: %v", err)
+	}
 	elapsed := time.Since(startTime)
 
 	if elapsed > 200*time.Millisecond {
@@ -1047,7 +1053,10 @@ func TestOriginalLogErrorFixed(t *testing.T) {
 	}
 
 	// Convert the request - this should not panic or fail
-	geminiRequest := ConvertRequest(openAIRequest)
+	geminiRequest, err := ConvertRequest(openAIRequest)
+	if err != nil {
+		t.Fatalf("ConvertRequest failed: %v", err)
+	}
 
 	// Verify the conversion worked
 	if geminiRequest == nil {
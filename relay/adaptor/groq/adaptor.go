@@ -57,6 +57,12 @@ func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return GroqToolingDefaults
 }
 
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureStructuredOutput)
+}
+
 // Implement required adaptor interface methods (Groq uses OpenAI-compatible API)
 func (a *Adaptor) Init(meta *meta.Meta) {}
 
@@ -46,6 +46,106 @@ type ModelConfig struct {
 	Audio *AudioPricingConfig `json:"audio,omitempty"`
 	// Image captures pricing metadata for image prompt and render billing.
 	Image *ImagePricingConfig `json:"image,omitempty"`
+	// CharacterCountBilling marks speech synthesis models that are billed per
+	// input character instead of per token.
+	CharacterCountBilling bool `json:"character_count_billing,omitempty"`
+	// CharacterRatio is the quota charged per input character when
+	// CharacterCountBilling is set.
+	CharacterRatio float64 `json:"character_ratio,omitempty"`
+	// Features gates request features per model, see ModelFeatureFlags.
+	Features ModelFeatureFlags `json:"features,omitempty"`
+}
+
+// Model feature flag names used as ModelFeatureFlags keys.
+const (
+	FeatureVision           = model.FeatureVision
+	FeatureTools            = model.FeatureTools
+	FeatureStructuredOutput = model.FeatureStructuredOutput
+	FeatureJSONMode         = model.FeatureJSONMode
+	FeatureStreaming        = model.FeatureStreaming
+	FeatureExtendedThinking = model.FeatureExtendedThinking
+)
+
+// ModelFeatureFlags records which request features a model accepts. Features
+// are opt-in: a feature is only accepted when its flag is set to true, either
+// by the adaptor, see FeatureDefaultsProvider, by the adaptor's model config or
+// by the channel's model config.
+type ModelFeatureFlags map[string]bool
+
+// Supports reports whether the model accepts feature.
+func (f ModelFeatureFlags) Supports(feature string) bool {
+	return f[feature]
+}
+
+// StandardModelFeatures returns the flags of the features chat backends
+// accepted before feature flags existed: streaming, tool calls and JSON mode.
+// Vision, structured output and extended thinking must be declared on top.
+func StandardModelFeatures() ModelFeatureFlags {
+	return ModelFeatureFlags{
+		FeatureStreaming: true,
+		FeatureTools:     true,
+		FeatureJSONMode:  true,
+	}
+}
+
+// WithModelFeatures returns the standard model features plus features.
+func WithModelFeatures(features ...string) ModelFeatureFlags {
+	flags := StandardModelFeatures()
+	for _, feature := range features {
+		flags[feature] = true
+	}
+	return flags
+}
+
+// FeatureDefaultsProvider is implemented by adaptors that declare the request
+// features their models accept, see ModelFeatureFlags. Models of an adaptor
+// without it accept no gated feature unless a model config turns it on.
+type FeatureDefaultsProvider interface {
+	DefaultModelFeatures() ModelFeatureFlags
+}
+
+// RequestFeature is a gated feature used by a request, with the request
+// parameter that uses it.
+type RequestFeature = model.RequestFeature
+
+// ChatRequestFeatures lists the gated features a chat completion request uses.
+func ChatRequestFeatures(request *model.GeneralOpenAIRequest) []RequestFeature {
+	return request.RequestedFeatures()
+}
+
+// ClaudeRequestFeatures lists the gated features a Claude Messages request uses.
+func ClaudeRequestFeatures(request *model.ClaudeRequest) []RequestFeature {
+	var features []RequestFeature
+	if claudeRequestHasImage(request) {
+		features = append(features, RequestFeature{Flag: FeatureVision, Param: "messages"})
+	}
+	if len(request.Tools) > 0 {
+		features = append(features, RequestFeature{Flag: FeatureTools, Param: "tools"})
+	}
+	if request.Stream != nil && *request.Stream {
+		features = append(features, RequestFeature{Flag: FeatureStreaming, Param: "stream"})
+	}
+	if request.Thinking != nil && request.Thinking.Type != "disabled" {
+		features = append(features, RequestFeature{Flag: FeatureExtendedThinking, Param: "thinking"})
+	}
+	return features
+}
+
+// claudeRequestHasImage reports whether any message of request carries an
+// image content block.
+func claudeRequestHasImage(request *model.ClaudeRequest) bool {
+	for _, message := range request.Messages {
+		blocks, ok := message.Content.([]any)
+		if !ok {
+			continue
+		}
+		for _, block := range blocks {
+			if content, ok := block.(map[string]any); ok && content["type"] == "image" {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // VideoPricingConfig captures pricing metadata for video generation requests.
@@ -319,6 +419,12 @@ func (d *DefaultPricingMethods) DefaultToolingConfig() ChannelToolConfig {
 	return ChannelToolConfig{}
 }
 
+// DefaultModelFeatures returns the standard model features; adaptors whose
+// models accept more declare them by overriding it.
+func (d *DefaultPricingMethods) DefaultModelFeatures() ModelFeatureFlags {
+	return StandardModelFeatures()
+}
+
 func (d *DefaultPricingMethods) ConvertClaudeRequest(c *gin.Context, request *model.ClaudeRequest) (any, error) {
 	// Default implementation: not supported
 	return nil, errors.New("Claude Messages API not supported by this adaptor")
@@ -8,43 +8,74 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/songquanpeng/one-api/relay/adaptor"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
 	"github.com/songquanpeng/one-api/relay/meta"
 	"github.com/songquanpeng/one-api/relay/model"
 )
 
 type Adaptor struct {
 	adaptor.DefaultPricingMethods
+	meta *meta.Meta
 }
 
-func (a *Adaptor) Init(meta *meta.Meta) {}
+func (a *Adaptor) Init(meta *meta.Meta) {
+	a.meta = meta
+}
 
 func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
-	return "", nil
+	return GetRequestURL(meta)
 }
 
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Request, meta *meta.Meta) error {
+	adaptor.SetupCommonRequestHeader(c, req, meta)
+	req.Header.Set("Authorization", "Bearer "+meta.APIKey)
 	return nil
 }
 
 func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.GeneralOpenAIRequest) (any, error) {
-	return nil, nil
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	m := a.meta
+	if m == nil {
+		m = meta.GetByContext(c)
+	}
+	if usesChatProAPI(m) {
+		return ConvertRequest(*request), nil
+	}
+	// chatcompletion_v2 is OpenAI-compatible
+	return request, nil
 }
 
 func (a *Adaptor) ConvertImageRequest(c *gin.Context, request *model.ImageRequest) (any, error) {
-	return nil, nil
+	return nil, errors.New("minimax does not support image generation")
 }
 
 func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, request *model.ClaudeRequest) (any, error) {
-	// Minimax adapter is not fully implemented yet
-	return nil, errors.New("Claude Messages API not implemented for Minimax adapter")
+	// Claude Messages requests are served by the OpenAI-compatible chatcompletion_v2 API
+	return openai_compatible.ConvertClaudeRequest(c, request)
 }
 
 func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Reader) (*http.Response, error) {
-	return nil, nil
+	return adaptor.DoRequestHelper(a, c, meta, requestBody)
 }
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
-	return nil, nil
+	if usesChatProAPI(meta) {
+		if meta.IsStream {
+			err, usage = StreamHandler(c, resp, meta.PromptTokens, meta.ActualModelName)
+		} else {
+			err, usage = Handler(c, resp, meta.PromptTokens, meta.ActualModelName)
+		}
+		return usage, err
+	}
+
+	return openai_compatible.HandleClaudeMessagesResponse(c, resp, meta, func(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
+		if meta.IsStream {
+			return openai_compatible.StreamHandler(c, resp, promptTokens, modelName)
+		}
+		return openai_compatible.Handler(c, resp, promptTokens, modelName)
+	})
 }
 
 func (a *Adaptor) GetModelList() []string {
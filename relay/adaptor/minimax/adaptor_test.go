@@ -0,0 +1,248 @@
+package minimax
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	dbmodel "github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+func newChatProMeta(baseURL string, stream bool) *meta.Meta {
+	return &meta.Meta{
+		Mode:            relaymode.ChatCompletions,
+		ChannelType:     channeltype.Minimax,
+		BaseURL:         baseURL,
+		APIKey:          "sk-minimax",
+		ActualModelName: "abab6.5s-chat",
+		IsStream:        stream,
+		PromptTokens:    12,
+		Config:          dbmodel.ChannelConfig{UserID: "group-1"},
+	}
+}
+
+func TestGetRequestURL(t *testing.T) {
+	m := newChatProMeta("https://api.minimax.chat", false)
+	got, err := GetRequestURL(m)
+	require.NoError(t, err)
+	require.Equal(t, "https://api.minimax.chat/v1/text/chatcompletion_pro?GroupId=group-1", got)
+
+	// Without a group id, or for newer models, the OpenAI-compatible API is used
+	m.Config.UserID = ""
+	got, err = GetRequestURL(m)
+	require.NoError(t, err)
+	require.Equal(t, "https://api.minimax.chat/v1/text/chatcompletion_v2", got)
+	m.Config.UserID = "group-1"
+	m.ActualModelName = "MiniMax-Text-01"
+	got, err = GetRequestURL(m)
+	require.NoError(t, err)
+	require.Equal(t, "https://api.minimax.chat/v1/text/chatcompletion_v2", got)
+
+	m.Mode = relaymode.TextToSpeech
+	got, err = GetRequestURL(m)
+	require.NoError(t, err)
+	require.Equal(t, "https://api.minimax.chat/v1/text_to_speech?GroupId=group-1", got)
+	m.Config.UserID = ""
+	_, err = GetRequestURL(m)
+	require.Error(t, err)
+}
+
+func TestConvertRequest_MapsSystemMessagesToBotSetting(t *testing.T) {
+	maxTokens := 256
+	request := model.GeneralOpenAIRequest{
+		Model:               "abab6.5s-chat",
+		MaxCompletionTokens: &maxTokens,
+		Messages: []model.Message{
+			{Role: "system", Content: "You are a poet."},
+			{Role: "system", Content: "Answer briefly."},
+			{Role: "user", Content: "Write a haiku."},
+			{Role: "assistant", Content: "Autumn moonlight..."},
+			{Role: "user", Content: "Another one."},
+		},
+		ResponseFormat: &model.ResponseFormat{
+			Type: "json_schema",
+			JsonSchema: &model.JSONSchema{
+				Name:   "poem",
+				Schema: map[string]any{"type": "object", "properties": map[string]any{"poem": map[string]any{"type": "string"}}},
+			},
+		},
+		Tools: []model.Tool{{Type: "function", Function: &model.Function{Name: "get_weather", Parameters: map[string]any{"type": "object"}}}},
+	}
+
+	converted := ConvertRequest(request)
+	require.Equal(t, 256, converted.TokensToGenerate)
+	require.Equal(t, []BotSetting{{BotName: defaultBotName, Content: "You are a poet.\nAnswer briefly."}}, converted.BotSetting)
+	require.Len(t, converted.Messages, 3)
+	require.Equal(t, senderTypeUser, converted.Messages[0].SenderType)
+	require.Equal(t, senderTypeBot, converted.Messages[1].SenderType)
+	require.Equal(t, defaultBotName, converted.Messages[1].SenderName)
+	require.Equal(t, "Another one.", converted.Messages[2].Text)
+	require.Equal(t, senderTypeBot, converted.ReplyConstraints.SenderType)
+	require.Equal(t, defaultBotName, converted.ReplyConstraints.SenderName)
+	require.NotNil(t, converted.ReplyConstraints.Glyph)
+	require.Equal(t, "json_value", converted.ReplyConstraints.Glyph.Type)
+	require.Contains(t, converted.ReplyConstraints.Glyph.JsonProperties, "poem")
+	require.Len(t, converted.Functions, 1)
+	require.Equal(t, "get_weather", converted.Functions[0].Name)
+
+	// A bot setting is required even without system messages
+	converted = ConvertRequest(model.GeneralOpenAIRequest{Model: "abab6.5s-chat", Messages: []model.Message{{Role: "user", Content: "hi"}}})
+	require.Equal(t, defaultBotSetting, converted.BotSetting[0].Content)
+}
+
+// doChatPro sends request through the adaptor against a fake chatcompletion_pro server.
+func doChatPro(t *testing.T, stream bool, respond http.HandlerFunc) (*httptest.ResponseRecorder, *model.Usage, *model.ErrorWithStatusCode) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/text/chatcompletion_pro", r.URL.Path)
+		require.Equal(t, "group-1", r.URL.Query().Get("GroupId"))
+		require.Equal(t, "Bearer sk-minimax", r.Header.Get("Authorization"))
+
+		var body ChatProRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, "Be concise.", body.BotSetting[0].Content)
+		require.Equal(t, stream, body.Stream)
+		respond(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	m := newChatProMeta(server.URL, stream)
+	a := &Adaptor{}
+	a.Init(m)
+	converted, err := a.ConvertRequest(c, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{
+		Model:  "abab6.5s-chat",
+		Stream: stream,
+		Messages: []model.Message{
+			{Role: "system", Content: "Be concise."},
+			{Role: "user", Content: "Hello"},
+		},
+	})
+	require.NoError(t, err)
+	payload, err := json.Marshal(converted)
+	require.NoError(t, err)
+
+	// Send the request directly, DoRequestHelper records trace timestamps in the database
+	url, err := a.GetRequestURL(m)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(string(payload)))
+	req.RequestURI = ""
+	require.NoError(t, a.SetupRequestHeader(c, req, m))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	usage, relayErr := a.DoResponse(c, resp, m)
+	return recorder, usage, relayErr
+}
+
+func TestAdaptor_ChatCompletion(t *testing.T) {
+	recorder, usage, relayErr := doChatPro(t, false, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"id": "minimax-1", "created": 1700000000, "model": "abab6.5s-chat", "reply": "Hi there!",
+			"choices": [{"index": 0, "finish_reason": "stop", "messages": [{"sender_type": "BOT", "sender_name": "MM Assistant", "text": "Hi there!"}]}],
+			"usage": {"total_tokens": 20},
+			"base_resp": {"status_code": 0, "status_msg": "success"}
+		}`))
+	})
+	require.Nil(t, relayErr)
+	require.Equal(t, 12, usage.PromptTokens)
+	require.Equal(t, 8, usage.CompletionTokens)
+	require.Equal(t, 20, usage.TotalTokens)
+
+	var response model.GeneralOpenAIResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Equal(t, "chat.completion", response.Object)
+	require.Len(t, response.Choices, 1)
+	require.Equal(t, "assistant", response.Choices[0].Message.Role)
+	require.Equal(t, "Hi there!", response.Choices[0].Message.StringContent())
+	require.Equal(t, "stop", response.Choices[0].FinishReason)
+}
+
+func TestAdaptor_ChatCompletionBusinessError(t *testing.T) {
+	_, _, relayErr := doChatPro(t, false, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"base_resp": {"status_code": 1004, "status_msg": "authorization failed"}}`))
+	})
+	require.NotNil(t, relayErr)
+	require.Equal(t, http.StatusBadRequest, relayErr.StatusCode)
+	require.Equal(t, "authorization failed", relayErr.Message)
+}
+
+func TestAdaptor_ChatCompletionStream(t *testing.T) {
+	recorder, usage, relayErr := doChatPro(t, true, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"id":"minimax-2","created":1700000000,"model":"abab6.5s-chat","choices":[{"index":0,"messages":[{"sender_type":"BOT","sender_name":"MM Assistant","text":"Hel"}]}]}`,
+			`{"id":"minimax-2","created":1700000000,"model":"abab6.5s-chat","choices":[{"index":0,"messages":[{"sender_type":"BOT","sender_name":"MM Assistant","text":"lo!"}]}]}`,
+			`{"id":"minimax-2","created":1700000000,"model":"abab6.5s-chat","reply":"Hello!","choices":[{"index":0,"finish_reason":"stop","messages":[{"sender_type":"BOT","sender_name":"MM Assistant","text":"Hello!"}]}],"usage":{"total_tokens":15},"base_resp":{"status_code":0}}`,
+		} {
+			_, _ = w.Write([]byte("data: " + event + "\n\n"))
+		}
+	})
+	require.Nil(t, relayErr)
+	require.Equal(t, 15, usage.TotalTokens)
+	require.Equal(t, 3, usage.CompletionTokens)
+
+	var (
+		content       string
+		finishReasons []string
+	)
+	lines := strings.Split(recorder.Body.String(), "\n")
+	for _, line := range lines {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Object  string `json:"object"`
+			Choices []struct {
+				Delta        model.Message `json:"delta"`
+				FinishReason *string       `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		require.Equal(t, "chat.completion.chunk", chunk.Object)
+		content += chunk.Choices[0].Delta.StringContent()
+		if chunk.Choices[0].FinishReason != nil {
+			finishReasons = append(finishReasons, *chunk.Choices[0].FinishReason)
+		}
+	}
+	// The final event repeats the reply, which must not be forwarded twice
+	require.Equal(t, "Hello!", content)
+	require.Equal(t, []string{"stop"}, finishReasons)
+	require.Contains(t, recorder.Body.String(), "data: [DONE]")
+}
+
+func TestTextToSpeechErrorHandler(t *testing.T) {
+	audio := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"audio/mpeg"}},
+		Body:       io.NopCloser(strings.NewReader("ID3...")),
+	}
+	require.Nil(t, TextToSpeechErrorHandler(audio))
+	body, err := io.ReadAll(audio.Body)
+	require.NoError(t, err)
+	require.Equal(t, "ID3...", string(body))
+
+	failed := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(`{"base_resp":{"status_code":2013,"status_msg":"invalid voice_id"}}`)),
+	}
+	relayErr := TextToSpeechErrorHandler(failed)
+	require.NotNil(t, relayErr)
+	require.Equal(t, "invalid voice_id", relayErr.Message)
+	require.Equal(t, 2013, relayErr.Code)
+}
@@ -10,13 +10,17 @@ import (
 // Based on Minimax pricing: https://api.minimax.chat/document/price
 var ModelRatios = map[string]adaptor.ModelConfig{
 	// Minimax Models - Based on https://api.minimax.chat/document/price
-	"abab6.5-chat":    {Ratio: 0.03 * ratio.MilliTokensRmb, CompletionRatio: 1},
-	"abab6.5s-chat":   {Ratio: 0.01 * ratio.MilliTokensRmb, CompletionRatio: 1},
-	"abab6-chat":      {Ratio: 0.1 * ratio.MilliTokensRmb, CompletionRatio: 1},
-	"abab5.5-chat":    {Ratio: 0.015 * ratio.MilliTokensRmb, CompletionRatio: 1},
-	"abab5.5s-chat":   {Ratio: 0.005 * ratio.MilliTokensRmb, CompletionRatio: 1},
-	"MiniMax-VL-01":   {Ratio: 0.02 * ratio.MilliTokensRmb, CompletionRatio: 1},  // Estimated pricing
-	"MiniMax-Text-01": {Ratio: 0.015 * ratio.MilliTokensRmb, CompletionRatio: 1}, // Estimated pricing
+	// Prices are in RMB per 1M tokens; the price page lists them per 1K tokens
+	"abab6.5-chat":    {Ratio: 30 * ratio.MilliTokensRmb, CompletionRatio: 1},
+	"abab6.5s-chat":   {Ratio: 10 * ratio.MilliTokensRmb, CompletionRatio: 1},
+	"abab6-chat":      {Ratio: 100 * ratio.MilliTokensRmb, CompletionRatio: 1},
+	"abab5.5-chat":    {Ratio: 15 * ratio.MilliTokensRmb, CompletionRatio: 1},
+	"abab5.5s-chat":   {Ratio: 5 * ratio.MilliTokensRmb, CompletionRatio: 1},
+	"MiniMax-VL-01":   {Ratio: 1 * ratio.MilliTokensRmb, CompletionRatio: 8},
+	"MiniMax-Text-01": {Ratio: 1 * ratio.MilliTokensRmb, CompletionRatio: 8},
+
+	// Text to speech (T2A), billed per input character: 5 RMB per 10K characters
+	"speech-01": {Ratio: 500 * ratio.MilliTokensRmb, CompletionRatio: 1},
 }
 
 // ModelList derived from ModelRatios for backward compatibility
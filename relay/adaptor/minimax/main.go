@@ -1,17 +1,407 @@
 package minimax
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
 
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/random"
+	"github.com/songquanpeng/one-api/common/render"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
 	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
 	"github.com/songquanpeng/one-api/relay/relaymode"
 )
 
+const (
+	defaultBotName     = "MM Assistant"
+	defaultUserName    = "User"
+	defaultBotSetting  = "MM Assistant is a helpful AI assistant."
+	defaultFunctionRef = "function"
+)
+
+// GetRequestURL returns the upstream URL for the relay mode of meta.
+//
+// abab models are served by the native chatcompletion_pro API when the channel
+// configures its Minimax group id as user_id; other chat requests use the
+// OpenAI-compatible chatcompletion_v2 API.
 func GetRequestURL(meta *meta.Meta) (string, error) {
-	if meta.Mode == relaymode.ChatCompletions {
+	switch meta.Mode {
+	case relaymode.ChatCompletions, relaymode.ClaudeMessages:
+		if usesChatProAPI(meta) {
+			return withGroupId(fmt.Sprintf("%s/v1/text/chatcompletion_pro", meta.BaseURL), meta.Config.UserID), nil
+		}
 		return fmt.Sprintf("%s/v1/text/chatcompletion_v2", meta.BaseURL), nil
+	case relaymode.TextToSpeech:
+		if meta.Config.UserID == "" {
+			return "", errors.New("minimax text to speech requires the group id configured as the channel's user_id")
+		}
+		return withGroupId(fmt.Sprintf("%s/v1/text_to_speech", meta.BaseURL), meta.Config.UserID), nil
 	}
 	return "", errors.Errorf("unsupported relay mode %d for minimax", meta.Mode)
 }
+
+// usesChatProAPI reports whether the request is sent to chatcompletion_pro.
+func usesChatProAPI(meta *meta.Meta) bool {
+	return meta.Mode == relaymode.ChatCompletions &&
+		meta.Config.UserID != "" &&
+		strings.HasPrefix(meta.ActualModelName, "abab")
+}
+
+func withGroupId(requestURL string, groupId string) string {
+	return requestURL + "?GroupId=" + url.QueryEscape(groupId)
+}
+
+// ConvertRequest converts an OpenAI chat request to a chatcompletion_pro request.
+// System messages become the bot_setting, and json_schema response formats
+// become a json_value reply constraint.
+func ConvertRequest(request model.GeneralOpenAIRequest) *ChatProRequest {
+	proRequest := ChatProRequest{
+		Model:            request.Model,
+		Stream:           request.Stream,
+		TokensToGenerate: request.MaxTokens,
+		Temperature:      request.Temperature,
+		TopP:             request.TopP,
+		Messages:         make([]ChatProMessage, 0, len(request.Messages)),
+		ReplyConstraints: ReplyConstraints{SenderType: senderTypeBot, SenderName: defaultBotName},
+	}
+	if request.MaxCompletionTokens != nil && *request.MaxCompletionTokens > 0 {
+		proRequest.TokensToGenerate = *request.MaxCompletionTokens
+	}
+
+	var systemPrompts []string
+	for _, message := range request.Messages {
+		switch message.Role {
+		case "system", "developer":
+			if text := message.StringContent(); text != "" {
+				systemPrompts = append(systemPrompts, text)
+			}
+		case "assistant":
+			proMessage := ChatProMessage{
+				SenderType: senderTypeBot,
+				SenderName: defaultBotName,
+				Text:       message.StringContent(),
+			}
+			// chatcompletion_pro carries a single function call per message
+			if len(message.ToolCalls) > 0 && message.ToolCalls[0].Function != nil {
+				proMessage.FunctionCall = &FunctionCall{
+					Name:      message.ToolCalls[0].Function.Name,
+					Arguments: argumentsString(message.ToolCalls[0].Function.Arguments),
+				}
+			}
+			proRequest.Messages = append(proRequest.Messages, proMessage)
+		case "tool", "function":
+			senderName := defaultFunctionRef
+			if message.Name != nil && *message.Name != "" {
+				senderName = *message.Name
+			}
+			proRequest.Messages = append(proRequest.Messages, ChatProMessage{
+				SenderType: senderTypeFunction,
+				SenderName: senderName,
+				Text:       message.StringContent(),
+			})
+		default:
+			senderName := defaultUserName
+			if message.Name != nil && *message.Name != "" {
+				senderName = *message.Name
+			}
+			proRequest.Messages = append(proRequest.Messages, ChatProMessage{
+				SenderType: senderTypeUser,
+				SenderName: senderName,
+				Text:       message.StringContent(),
+			})
+		}
+	}
+
+	botSetting := defaultBotSetting
+	if len(systemPrompts) > 0 {
+		botSetting = strings.Join(systemPrompts, "\n")
+	}
+	proRequest.BotSetting = []BotSetting{{BotName: defaultBotName, Content: botSetting}}
+
+	if format := request.ResponseFormat; format != nil && format.JsonSchema != nil {
+		if properties, ok := format.JsonSchema.Schema["properties"].(map[string]any); ok {
+			proRequest.ReplyConstraints.Glyph = &Glyph{Type: "json_value", JsonProperties: properties}
+		}
+	}
+
+	for _, tool := range request.Tools {
+		if tool.Type != "function" || tool.Function == nil {
+			continue
+		}
+		proRequest.Functions = append(proRequest.Functions, model.Function{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  tool.Function.Parameters,
+		})
+	}
+	return &proRequest
+}
+
+// argumentsString returns OpenAI function call arguments as a JSON string.
+func argumentsString(arguments any) string {
+	switch v := arguments.(type) {
+	case nil:
+		return "{}"
+	case string:
+		return v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "{}"
+		}
+		return string(encoded)
+	}
+}
+
+// botMessage returns the last message sent by the bot in choice.
+func botMessage(choice ChatProChoice) *ChatProMessage {
+	for i := len(choice.Messages) - 1; i >= 0; i-- {
+		if choice.Messages[i].SenderType == senderTypeBot {
+			return &choice.Messages[i]
+		}
+	}
+	return nil
+}
+
+func toolCallsOf(message *ChatProMessage) []model.Tool {
+	if message == nil || message.FunctionCall == nil {
+		return nil
+	}
+	return []model.Tool{{
+		Id:   fmt.Sprintf("call_%s", random.GetUUID()),
+		Type: "function",
+		Function: &model.Function{
+			Name:      message.FunctionCall.Name,
+			Arguments: message.FunctionCall.Arguments,
+		},
+	}}
+}
+
+func finishReasonOf(response *ChatProResponse, choice ChatProChoice, hasToolCalls bool) string {
+	switch {
+	case response.OutputSensitive:
+		return "content_filter"
+	case hasToolCalls:
+		return "tool_calls"
+	case choice.FinishReason == "length" || choice.FinishReason == "max_output":
+		return "length"
+	default:
+		return "stop"
+	}
+}
+
+// usageOf derives OpenAI usage from the total reported by Minimax.
+func usageOf(response *ChatProResponse, promptTokens int) model.Usage {
+	usage := model.Usage{PromptTokens: promptTokens}
+	if response.Usage != nil {
+		usage.TotalTokens = response.Usage.TotalTokens
+		usage.CompletionTokens = max(response.Usage.TotalTokens-promptTokens, 0)
+	}
+	return usage
+}
+
+func responseChatPro2OpenAI(response *ChatProResponse, promptTokens int) *model.GeneralOpenAIResponse {
+	usage := usageOf(response, promptTokens)
+	openaiResponse := model.GeneralOpenAIResponse{
+		Id:      response.Id,
+		Object:  "chat.completion",
+		Created: response.Created,
+		Model:   response.Model,
+		Choices: make([]model.GeneralOpenAIResponseChoice, 0, len(response.Choices)),
+		Usage:   &usage,
+	}
+	for _, choice := range response.Choices {
+		message := botMessage(choice)
+		text := response.Reply
+		if message != nil {
+			text = message.Text
+		}
+		toolCalls := toolCallsOf(message)
+		openaiResponse.Choices = append(openaiResponse.Choices, model.GeneralOpenAIResponseChoice{
+			Index:        choice.Index,
+			Message:      model.Message{Role: "assistant", Content: text, ToolCalls: toolCalls},
+			FinishReason: finishReasonOf(response, choice, len(toolCalls) > 0),
+		})
+	}
+	return &openaiResponse
+}
+
+// streamResponseChatPro2OpenAI converts a stream event. The last event repeats
+// the whole reply along with the usage, so only its finish reason and function
+// call are forwarded.
+func streamResponseChatPro2OpenAI(response *ChatProResponse) *openai_compatible.ChatCompletionsStreamResponse {
+	final := response.Usage != nil || response.Reply != ""
+	streamResponse := openai_compatible.ChatCompletionsStreamResponse{
+		Id:      response.Id,
+		Object:  "chat.completion.chunk",
+		Created: response.Created,
+		Model:   response.Model,
+		Choices: make([]openai_compatible.ChatCompletionsStreamResponseChoice, 0, len(response.Choices)),
+	}
+	for _, choice := range response.Choices {
+		message := botMessage(choice)
+		streamChoice := openai_compatible.ChatCompletionsStreamResponseChoice{Index: choice.Index}
+		if !final {
+			if message != nil {
+				streamChoice.Delta.Content = message.Text
+			}
+		} else {
+			toolCalls := toolCallsOf(message)
+			for i := range toolCalls {
+				toolCalls[i].Index = &i
+			}
+			streamChoice.Delta.ToolCalls = toolCalls
+			finishReason := finishReasonOf(response, choice, len(toolCalls) > 0)
+			streamChoice.FinishReason = &finishReason
+		}
+		streamResponse.Choices = append(streamResponse.Choices, streamChoice)
+	}
+	return &streamResponse
+}
+
+// errorOf converts a failed BaseResp to an OpenAI error.
+func errorOf(baseResp BaseResp, statusCode int) *model.ErrorWithStatusCode {
+	if baseResp.StatusCode == 0 {
+		return nil
+	}
+	if statusCode == http.StatusOK {
+		statusCode = http.StatusBadRequest
+	}
+	return &model.ErrorWithStatusCode{
+		Error: model.Error{
+			Message:  baseResp.StatusMsg,
+			Type:     model.ErrorTypeUpstream,
+			Code:     baseResp.StatusCode,
+			RawError: errors.Errorf("minimax error %d: %s", baseResp.StatusCode, baseResp.StatusMsg),
+		},
+		StatusCode: statusCode,
+	}
+}
+
+// Handler converts a non-streaming chatcompletion_pro response to OpenAI format.
+func Handler(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return openai_compatible.ErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if err = resp.Body.Close(); err != nil {
+		return openai_compatible.ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+
+	var proResponse ChatProResponse
+	if err = json.Unmarshal(responseBody, &proResponse); err != nil {
+		return openai_compatible.ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if bizErr := errorOf(proResponse.BaseResp, resp.StatusCode); bizErr != nil {
+		return bizErr, nil
+	}
+
+	fullTextResponse := responseChatPro2OpenAI(&proResponse, promptTokens)
+	if fullTextResponse.Model == "" {
+		fullTextResponse.Model = modelName
+	}
+	jsonResponse, err := json.Marshal(fullTextResponse)
+	if err != nil {
+		return openai_compatible.ErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, _ = c.Writer.Write(jsonResponse)
+	return nil, fullTextResponse.Usage
+}
+
+// StreamHandler converts a streaming chatcompletion_pro response to OpenAI
+// chat completion chunks.
+func StreamHandler(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
+	lg := gmw.GetLogger(c)
+	usage := model.Usage{PromptTokens: promptTokens}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Split(bufio.ScanLines)
+	buffer := make([]byte, 10*1024*1024) // 10MB buffer
+	scanner.Buffer(buffer, len(buffer))
+
+	common.SetEventStreamHeaders(c)
+
+	for scanner.Scan() {
+		data := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(data, "data:") {
+			continue
+		}
+		data = strings.TrimSpace(strings.TrimPrefix(data, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		var proResponse ChatProResponse
+		if err := json.Unmarshal([]byte(data), &proResponse); err != nil {
+			lg.Error("error unmarshalling minimax stream response", zap.Error(err))
+			continue
+		}
+		if bizErr := errorOf(proResponse.BaseResp, resp.StatusCode); bizErr != nil {
+			lg.Warn("minimax stream returned an error", zap.Error(bizErr.RawError))
+			break
+		}
+		if proResponse.Usage != nil {
+			usage = usageOf(&proResponse, promptTokens)
+		}
+
+		response := streamResponseChatPro2OpenAI(&proResponse)
+		if response.Model == "" {
+			response.Model = modelName
+		}
+		if response.Created == 0 {
+			response.Created = helper.GetTimestamp()
+		}
+		if err := render.ObjectData(c, response); err != nil {
+			lg.Error("error rendering stream response", zap.Error(err))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		lg.Error("error reading stream", zap.Error(err))
+	}
+
+	render.Done(c)
+
+	if err := resp.Body.Close(); err != nil {
+		return openai_compatible.ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+	return nil, &usage
+}
+
+// TextToSpeechErrorHandler returns the error of a failed T2A response, or nil
+// when resp carries audio. Minimax reports T2A failures with HTTP 200 and a JSON
+// base_resp, so the status code alone is not enough.
+func TextToSpeechErrorHandler(resp *http.Response) *model.ErrorWithStatusCode {
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return openai_compatible.ErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		BaseResp BaseResp `json:"base_resp"`
+	}
+	if err = json.Unmarshal(body, &payload); err != nil {
+		return openai_compatible.ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+	}
+	return errorOf(payload.BaseResp, resp.StatusCode)
+}
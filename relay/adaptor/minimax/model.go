@@ -0,0 +1,104 @@
+package minimax
+
+import (
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+// https://platform.minimaxi.com/document/ChatCompletion%20Pro
+
+// Sender types of chatcompletion_pro messages.
+const (
+	senderTypeUser     = "USER"
+	senderTypeBot      = "BOT"
+	senderTypeFunction = "FUNCTION"
+)
+
+// ChatProRequest is the request body of the chatcompletion_pro API.
+type ChatProRequest struct {
+	Model            string           `json:"model"`
+	Stream           bool             `json:"stream,omitempty"`
+	TokensToGenerate int              `json:"tokens_to_generate,omitempty"`
+	Temperature      *float64         `json:"temperature,omitempty"`
+	TopP             *float64         `json:"top_p,omitempty"`
+	Messages         []ChatProMessage `json:"messages"`
+	// BotSetting replaces OpenAI's system messages.
+	BotSetting []BotSetting `json:"bot_setting"`
+	// ReplyConstraints names the bot that replies and optionally constrains the output format.
+	ReplyConstraints ReplyConstraints `json:"reply_constraints"`
+	Functions        []model.Function `json:"functions,omitempty"`
+}
+
+// ChatProMessage is a message of a chatcompletion_pro conversation.
+type ChatProMessage struct {
+	SenderType   string        `json:"sender_type"`
+	SenderName   string        `json:"sender_name"`
+	Text         string        `json:"text"`
+	FunctionCall *FunctionCall `json:"function_call,omitempty"`
+}
+
+// BotSetting describes the persona of a bot.
+type BotSetting struct {
+	BotName string `json:"bot_name"`
+	Content string `json:"content"`
+}
+
+// ReplyConstraints constrains the reply of a chatcompletion_pro request.
+type ReplyConstraints struct {
+	SenderType string `json:"sender_type"`
+	SenderName string `json:"sender_name"`
+	Glyph      *Glyph `json:"glyph,omitempty"`
+}
+
+// Glyph constrains the format of the reply.
+type Glyph struct {
+	Type           string         `json:"type"`
+	JsonProperties map[string]any `json:"json_properties,omitempty"`
+}
+
+// FunctionCall is a function call requested by the bot.
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ChatProResponse is the response of the chatcompletion_pro API, and also the
+// payload of each of its stream events.
+type ChatProResponse struct {
+	Id              string          `json:"id"`
+	Created         int64           `json:"created"`
+	Model           string          `json:"model"`
+	Reply           string          `json:"reply"`
+	Choices         []ChatProChoice `json:"choices"`
+	Usage           *ChatProUsage   `json:"usage,omitempty"`
+	InputSensitive  bool            `json:"input_sensitive"`
+	OutputSensitive bool            `json:"output_sensitive"`
+	BaseResp        BaseResp        `json:"base_resp"`
+}
+
+// ChatProChoice is a single choice of a ChatProResponse.
+type ChatProChoice struct {
+	Index        int              `json:"index"`
+	Messages     []ChatProMessage `json:"messages"`
+	FinishReason string           `json:"finish_reason"`
+}
+
+// ChatProUsage is the token usage of a ChatProResponse. Minimax only reports
+// the total.
+type ChatProUsage struct {
+	TotalTokens int `json:"total_tokens"`
+}
+
+// BaseResp carries the status of a Minimax API call. A non-zero StatusCode is
+// an error, even when the HTTP status is 200.
+type BaseResp struct {
+	StatusCode int    `json:"status_code"`
+	StatusMsg  string `json:"status_msg"`
+}
+
+// TextToSpeechRequest is the request body of the T2A API, reduced to the fields
+// needed for billing.
+type TextToSpeechRequest struct {
+	Model   string `json:"model"`
+	Text    string `json:"text"`
+	VoiceId string `json:"voice_id,omitempty"`
+}
@@ -38,6 +38,12 @@ func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return MistralToolingDefaults
 }
 
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureStructuredOutput)
+}
+
 func (a *Adaptor) GetModelRatio(modelName string) float64 {
 	pricing := a.GetDefaultModelPricing()
 	if price, exists := pricing[modelName]; exists {
@@ -0,0 +1,54 @@
+package mistral
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	"github.com/songquanpeng/one-api/relay/meta"
+)
+
+func TestGetRequestURL(t *testing.T) {
+	adaptor := &Adaptor{}
+	baseURL := channeltype.ChannelBaseURLs[channeltype.Mistral]
+
+	for path, expected := range map[string]string{
+		"/v1/chat/completions": "https://api.mistral.ai/v1/chat/completions",
+		"/v1/embeddings":       "https://api.mistral.ai/v1/embeddings",
+		"/v1/messages?beta=1":  "https://api.mistral.ai/v1/chat/completions",
+	} {
+		url, err := adaptor.GetRequestURL(&meta.Meta{
+			RequestURLPath: path,
+			BaseURL:        baseURL,
+			ChannelType:    channeltype.Mistral,
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, url, path)
+	}
+}
+
+func TestSetupRequestHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req := httptest.NewRequest(http.MethodPost, "https://api.mistral.ai/v1/chat/completions", nil)
+
+	adaptor := &Adaptor{}
+	require.NoError(t, adaptor.SetupRequestHeader(c, req, &meta.Meta{APIKey: "sk-mistral"}))
+	require.Equal(t, "Bearer sk-mistral", req.Header.Get("Authorization"))
+}
+
+func TestModelListAndPricing(t *testing.T) {
+	adaptor := &Adaptor{}
+	models := adaptor.GetModelList()
+	for _, name := range []string{"mistral-large-latest", "mistral-small-latest", "codestral-latest", "pixtral-12b-2409"} {
+		require.Contains(t, models, name)
+		require.Positive(t, adaptor.GetModelRatio(name), name)
+		require.Positive(t, adaptor.GetCompletionRatio(name), name)
+	}
+	require.Equal(t, "mistral", channeltype.IdToName(channeltype.Mistral))
+}
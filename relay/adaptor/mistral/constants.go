@@ -21,6 +21,7 @@ var ModelRatios = map[string]adaptor.ModelConfig{
 	"magistral-small-latest":  {Ratio: 0.5 * ratio.MilliTokensUsd, CompletionRatio: 3.0},  // $0.5 input, $1.5 output
 	"devstral-small-2507":     {Ratio: 0.1 * ratio.MilliTokensUsd, CompletionRatio: 3.0},  // $0.1 input, $0.3 output
 	"pixtral-12b":             {Ratio: 0.15 * ratio.MilliTokensUsd, CompletionRatio: 1.0}, // $0.15 input, $0.15 output
+	"pixtral-12b-2409":        {Ratio: 0.15 * ratio.MilliTokensUsd, CompletionRatio: 1.0}, // $0.15 input, $0.15 output
 	"open-mistral-7b":         {Ratio: 0.25 * ratio.MilliTokensUsd, CompletionRatio: 1.0}, // $0.25 input, $0.25 output
 	"open-mixtral-8x7b":       {Ratio: 0.7 * ratio.MilliTokensUsd, CompletionRatio: 1.0},  // $0.7 input, $0.7 output
 	"open-mixtral-8x22b":      {Ratio: 2.0 * ratio.MilliTokensUsd, CompletionRatio: 3.0},  // $2 input, $6 output
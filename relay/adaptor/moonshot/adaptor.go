@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
 	"github.com/gin-gonic/gin"
 
 	"github.com/songquanpeng/one-api/relay/adaptor"
@@ -15,6 +16,7 @@ import (
 
 type Adaptor struct {
 	adaptor.DefaultPricingMethods
+	meta *meta.Meta
 }
 
 func (a *Adaptor) GetChannelName() string {
@@ -54,8 +56,16 @@ func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return MoonshotToolingDefaults
 }
 
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision)
+}
+
 // Implement required adaptor interface methods (Moonshot uses OpenAI-compatible API)
-func (a *Adaptor) Init(meta *meta.Meta) {}
+func (a *Adaptor) Init(meta *meta.Meta) {
+	a.meta = meta
+}
 
 func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
 	// Handle Claude Messages requests - convert to OpenAI Chat Completions endpoint
@@ -81,6 +91,18 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 	if request.ReasoningEffort != nil {
 		request.ReasoningEffort = nil
 	}
+
+	// Moonshot has no file content part; upload attachments through its files API
+	m := a.meta
+	if m == nil {
+		m = meta.GetByContext(c)
+	}
+	if err := attachFiles(c, m, request); err != nil {
+		return nil, errors.Wrap(err, "convert file attachments")
+	}
+	if err := convertImageURLs(gmw.Ctx(c), request); err != nil {
+		return nil, errors.Wrap(err, "convert image urls")
+	}
 	return request, nil
 }
 
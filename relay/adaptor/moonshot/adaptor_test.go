@@ -0,0 +1,142 @@
+package moonshot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+// newMoonshotFilesServer fakes Moonshot's files API, recording uploaded and
+// deleted files.
+func newMoonshotFilesServer(t *testing.T, uploads map[string]string, deleted *[]string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/files", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer sk-moonshot", r.Header.Get("Authorization"))
+		require.Equal(t, fileExtractPurpose, r.FormValue("purpose"))
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+		uploads[header.Filename] = string(data)
+		_ = json.NewEncoder(w).Encode(uploadedFile{Id: "file-" + header.Filename, Filename: header.Filename, Status: "ok"})
+	})
+	mux.HandleFunc("GET /v1/files/{id}/content", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"content":"extracted ` + r.PathValue("id") + `","type":"file"}`))
+	})
+	mux.HandleFunc("DELETE /v1/files/{id}", func(w http.ResponseWriter, r *http.Request) {
+		*deleted = append(*deleted, r.PathValue("id"))
+		_, _ = w.Write([]byte(`{"deleted":true}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestConvertRequest_UploadsFileAttachments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	allowLoopbackUserContent(t)
+	uploads := map[string]string{}
+	var deleted []string
+	api := newMoonshotFilesServer(t, uploads, &deleted)
+	fetches := 0
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		_, _ = w.Write([]byte("quarterly report"))
+	}))
+	t.Cleanup(files.Close)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	a := &Adaptor{}
+	a.Init(&meta.Meta{BaseURL: api.URL, APIKey: "sk-moonshot", ChannelType: channeltype.Moonshot})
+
+	newRequest := func() *model.GeneralOpenAIRequest {
+		return &model.GeneralOpenAIRequest{
+			Model: "moonshot-v1-128k",
+			Messages: []model.Message{
+				{Role: "system", Content: "You are Kimi."},
+				{Role: "user", Content: []any{
+					map[string]any{"type": "file_url", "file_url": map[string]any{"url": files.URL + "/docs/report.pdf"}},
+					map[string]any{"type": "file_url", "file_url": map[string]any{"url": "data:text/plain;base64,bm90ZXM=", "filename": "notes.txt"}},
+					map[string]any{"type": "text", "text": "Summarize the files."},
+				}},
+			},
+		}
+	}
+
+	converted, err := a.ConvertRequest(c, relaymode.ChatCompletions, newRequest())
+	require.NoError(t, err)
+	messages := converted.(*model.GeneralOpenAIRequest).Messages
+
+	require.Equal(t, map[string]string{"report.pdf": "quarterly report", "notes.txt": "notes"}, uploads)
+	require.Len(t, messages, 4)
+	require.Equal(t, "You are Kimi.", messages[0].StringContent())
+	require.Equal(t, "system", messages[1].Role)
+	require.JSONEq(t, `{"content":"extracted file-report.pdf","type":"file"}`, messages[1].StringContent())
+	require.JSONEq(t, `{"content":"extracted file-notes.txt","type":"file"}`, messages[2].StringContent())
+	require.Equal(t, "user", messages[3].Role)
+	require.Equal(t, "Summarize the files.", messages[3].StringContent())
+	require.ElementsMatch(t, []string{"file-report.pdf", "file-notes.txt"}, deleted)
+
+	// A retry of the same request reuses the extracted content
+	retried, err := a.ConvertRequest(c, relaymode.ChatCompletions, newRequest())
+	require.NoError(t, err)
+	require.Equal(t, messages, retried.(*model.GeneralOpenAIRequest).Messages)
+	require.Equal(t, 1, fetches)
+	require.Len(t, deleted, 2)
+}
+
+func TestConvertRequest_RejectsUnsupportedFileURL(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	a := &Adaptor{}
+	a.Init(&meta.Meta{BaseURL: "http://127.0.0.1:0", ChannelType: channeltype.Moonshot})
+
+	_, err := a.ConvertRequest(c, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{
+		Messages: []model.Message{{Role: "user", Content: []any{
+			map[string]any{"type": "file_url", "file_url": "file:///etc/passwd"},
+		}}},
+	})
+	require.ErrorContains(t, err, "unsupported file url")
+}
+
+func TestConvertRequest_FileURLRefusesPrivateAddresses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("internal secrets"))
+	}))
+	t.Cleanup(files.Close)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	a := &Adaptor{}
+	a.Init(&meta.Meta{BaseURL: "http://127.0.0.1:0", ChannelType: channeltype.Moonshot})
+
+	_, err := a.ConvertRequest(c, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{
+		Messages: []model.Message{{Role: "user", Content: []any{
+			map[string]any{"type": "file_url", "file_url": map[string]any{"url": files.URL + "/secrets.txt"}},
+		}}},
+	})
+	require.ErrorContains(t, err, "non-public address")
+}
+
+func TestGetDefaultModelPricing_MoonshotV1(t *testing.T) {
+	pricing := (&Adaptor{}).GetDefaultModelPricing()
+	for _, name := range []string{"moonshot-v1-8k", "moonshot-v1-32k", "moonshot-v1-128k"} {
+		require.Contains(t, pricing, name)
+	}
+	require.Greater(t, pricing["moonshot-v1-128k"].Ratio, pricing["moonshot-v1-32k"].Ratio)
+	require.Greater(t, pricing["moonshot-v1-32k"].Ratio, pricing["moonshot-v1-8k"].Ratio)
+	require.NotEqual(t, pricing["moonshot-v1-128k"].CompletionRatio, pricing["moonshot-v1-8k"].CompletionRatio)
+}
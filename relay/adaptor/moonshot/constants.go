@@ -9,10 +9,36 @@ import (
 // Model list is derived from the keys of this map, eliminating redundancy
 // Based on Moonshot pricing: https://platform.moonshot.cn/docs/pricing
 var ModelRatios = map[string]adaptor.ModelConfig{
-	// Moonshot legacy models (keep for compatibility)
-	// "moonshot-v1-8k":   {Ratio: 12 * ratio.MilliTokensRmb, CompletionRatio: 1},
-	// "moonshot-v1-32k":  {Ratio: 24 * ratio.MilliTokensRmb, CompletionRatio: 1},
-	// "moonshot-v1-128k": {Ratio: 60 * ratio.MilliTokensRmb, CompletionRatio: 1},
+	// Moonshot v1 models, priced by context window
+	// All prices per 1M tokens, in RMB: input, output
+	"moonshot-v1-8k": {
+		Ratio:           2 * ratio.MilliTokensRmb,
+		CompletionRatio: 10.0 / 2,
+	},
+	"moonshot-v1-32k": {
+		Ratio:           5 * ratio.MilliTokensRmb,
+		CompletionRatio: 20.0 / 5,
+	},
+	// The 128k window is billed at its own, higher rate
+	"moonshot-v1-128k": {
+		Ratio:           10 * ratio.MilliTokensRmb,
+		CompletionRatio: 30.0 / 10,
+	},
+
+	// Kimi vision models, billed like the text models of the same window.
+	// Images are counted as prompt tokens.
+	"moonshot-v1-8k-vision-preview": {
+		Ratio:           2 * ratio.MilliTokensRmb,
+		CompletionRatio: 10.0 / 2,
+	},
+	"moonshot-v1-32k-vision-preview": {
+		Ratio:           5 * ratio.MilliTokensRmb,
+		CompletionRatio: 20.0 / 5,
+	},
+	"moonshot-v1-128k-vision-preview": {
+		Ratio:           10 * ratio.MilliTokensRmb,
+		CompletionRatio: 30.0 / 10,
+	},
 
 	// Kimi-K2 models (2025-11)
 	// All prices per 1M tokens, in RMB
@@ -0,0 +1,282 @@
+package moonshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+const (
+	// contentTypeFileURL is the message content part type carrying a file attachment.
+	contentTypeFileURL = "file_url"
+	// fileExtractPurpose asks Moonshot to extract the text of an uploaded file.
+	fileExtractPurpose = "file-extract"
+	// extractedFilesKey holds the file messages already extracted for the
+	// request, so retries do not upload the same attachments again.
+	extractedFilesKey = "moonshot_extracted_files"
+)
+
+// fileAttachment is a file_url content part of a chat message.
+type fileAttachment struct {
+	URL      string
+	Filename string
+}
+
+// uploadedFile is the response of Moonshot's POST /v1/files.
+type uploadedFile struct {
+	Id       string `json:"id"`
+	Filename string `json:"filename"`
+	Status   string `json:"status"`
+}
+
+// attachFiles replaces file_url content parts with Moonshot file references.
+// Each file is uploaded to Moonshot's files API for extraction, and the content
+// extracted for its file_id is inserted as a system message right before the
+// message that attached it, as Moonshot's long-context models expect.
+func attachFiles(c *gin.Context, m *meta.Meta, request *model.GeneralOpenAIRequest) error {
+	messages := make([]model.Message, 0, len(request.Messages))
+	for _, message := range request.Messages {
+		attachments, rest := splitFileAttachments(message)
+		for _, attachment := range attachments {
+			fileMessage, err := extractFile(c, m, attachment)
+			if err != nil {
+				return errors.Wrapf(err, "attach file %q", attachment.Filename)
+			}
+			messages = append(messages, fileMessage)
+		}
+		if len(attachments) > 0 {
+			if len(rest) == 0 {
+				continue
+			}
+			message.Content = rest
+		}
+		messages = append(messages, message)
+	}
+	request.Messages = messages
+	return nil
+}
+
+// splitFileAttachments separates the file_url parts of message from its other
+// content parts. It returns no attachments for plain string content.
+func splitFileAttachments(message model.Message) ([]fileAttachment, []any) {
+	parts, ok := message.Content.([]any)
+	if !ok {
+		return nil, nil
+	}
+
+	var attachments []fileAttachment
+	rest := make([]any, 0, len(parts))
+	for _, part := range parts {
+		partMap, ok := part.(map[string]any)
+		if !ok || partMap["type"] != contentTypeFileURL {
+			rest = append(rest, part)
+			continue
+		}
+
+		var attachment fileAttachment
+		switch fileURL := partMap[contentTypeFileURL].(type) {
+		case string:
+			attachment.URL = fileURL
+		case map[string]any:
+			attachment.URL, _ = fileURL["url"].(string)
+			attachment.Filename, _ = fileURL["filename"].(string)
+		}
+		if attachment.Filename == "" {
+			attachment.Filename = filenameFromURL(attachment.URL)
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rest
+}
+
+// filenameFromURL derives an upload filename from rawURL. Moonshot picks the
+// extraction method from the file extension.
+func filenameFromURL(rawURL string) string {
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Scheme != "data" {
+		if name := path.Base(parsed.Path); name != "." && name != "/" {
+			return name
+		}
+	}
+	return "attachment"
+}
+
+// extractFile uploads attachment to Moonshot and returns the system message
+// carrying its extracted content. The message is cached on the request, so a
+// retry reuses it instead of uploading the file again. The uploaded file is
+// deleted once its content has been read, the request only carries the text.
+func extractFile(c *gin.Context, m *meta.Meta, attachment fileAttachment) (model.Message, error) {
+	cacheKey := attachment.URL + "\x00" + attachment.Filename
+	extracted, _ := c.Get(extractedFilesKey)
+	cache, _ := extracted.(map[string]model.Message)
+	if message, ok := cache[cacheKey]; ok {
+		return message, nil
+	}
+
+	ctx := gmw.Ctx(c)
+	lg := gmw.GetLogger(c)
+	data, err := fetchAttachment(ctx, attachment.URL)
+	if err != nil {
+		return model.Message{}, errors.Wrap(err, "fetch file")
+	}
+
+	file, err := uploadFile(ctx, m, attachment.Filename, data)
+	if err != nil {
+		return model.Message{}, errors.Wrap(err, "upload file")
+	}
+	defer func() {
+		if _, err := doFilesRequest(ctx, m, http.MethodDelete, "/v1/files/"+url.PathEscape(file.Id), nil, ""); err != nil {
+			lg.Warn("failed to delete moonshot file", zap.String("file_id", file.Id), zap.Error(err))
+		}
+	}()
+
+	content, err := doFilesRequest(ctx, m, http.MethodGet, "/v1/files/"+url.PathEscape(file.Id)+"/content", nil, "")
+	if err != nil {
+		return model.Message{}, errors.Wrapf(err, "get content of file %s", file.Id)
+	}
+
+	lg.Debug("attached file to moonshot request",
+		zap.String("file_id", file.Id),
+		zap.String("filename", attachment.Filename),
+		zap.Int("file_bytes", len(data)),
+		zap.Int("content_bytes", len(content)))
+	message := model.Message{Role: "system", Content: string(content)}
+	if cache == nil {
+		cache = make(map[string]model.Message)
+		c.Set(extractedFilesKey, cache)
+	}
+	cache[cacheKey] = message
+	return message, nil
+}
+
+// fetchAttachment returns the bytes behind a data URL or an http(s) URL.
+func fetchAttachment(ctx context.Context, rawURL string) ([]byte, error) {
+	maxSize := int64(config.MoonshotMaxFileSizeMB) * 1024 * 1024
+	if payload, ok := strings.CutPrefix(rawURL, "data:"); ok {
+		_, encoded, found := strings.Cut(payload, ";base64,")
+		if !found {
+			return nil, errors.New("only base64 data URLs are supported")
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode data URL")
+		}
+		if int64(len(data)) > maxSize {
+			return nil, errors.Errorf("file size should not exceed %dMB", config.MoonshotMaxFileSizeMB)
+		}
+		return data, nil
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, errors.Errorf("unsupported file url: %q", rawURL)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(config.UserContentRequestTimeout)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "new request")
+	}
+	resp, err := userContentClient().Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "do request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("status code: %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, errors.Errorf("file size should not exceed %dMB", config.MoonshotMaxFileSizeMB)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "read body")
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errors.Errorf("file size should not exceed %dMB", config.MoonshotMaxFileSizeMB)
+	}
+	return data, nil
+}
+
+// uploadFile uploads data to Moonshot's files API for text extraction.
+func uploadFile(ctx context.Context, m *meta.Meta, filename string, data []byte) (*uploadedFile, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := writer.WriteField("purpose", fileExtractPurpose); err != nil {
+		return nil, errors.Wrap(err, "write purpose field")
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "create file field")
+	}
+	if _, err = part.Write(data); err != nil {
+		return nil, errors.Wrap(err, "write file field")
+	}
+	if err = writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "close multipart writer")
+	}
+
+	respBody, err := doFilesRequest(ctx, m, http.MethodPost, "/v1/files", body, writer.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	file := &uploadedFile{}
+	if err = json.Unmarshal(respBody, file); err != nil {
+		return nil, errors.Wrap(err, "unmarshal upload response")
+	}
+	if file.Id == "" {
+		return nil, errors.New("upload response has no file id")
+	}
+	return file, nil
+}
+
+// doFilesRequest calls a Moonshot files endpoint and returns the response body.
+func doFilesRequest(ctx context.Context, m *meta.Meta, method, requestPath string, body io.Reader, contentType string) ([]byte, error) {
+	fullURL := openai_compatible.GetFullRequestURL(m.BaseURL, requestPath, m.ChannelType)
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "new request")
+	}
+	req.Header.Set("Authorization", "Bearer "+m.APIKey)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s %s", method, requestPath)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read response body")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s %s: status code %d: %s", method, requestPath, resp.StatusCode, respBody)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,144 @@
+package moonshot
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+const (
+	// contentTypeImageURL is the message content part type carrying an image.
+	contentTypeImageURL = "image_url"
+	// fileReferenceScheme marks image URLs referencing a file already uploaded
+	// to Moonshot, as moonshot://<file_id>.
+	fileReferenceScheme = "moonshot://"
+	// upstreamFileReferenceScheme is how Moonshot's vision models reference an
+	// uploaded file.
+	upstreamFileReferenceScheme = "ms://"
+)
+
+// convertImageURLs rewrites the image_url content parts of request for
+// Moonshot's vision models. moonshot:// file references become Moonshot file
+// references and data URLs are checked against MaxInlineImageSizeMB. http(s)
+// URLs are left for Moonshot to fetch, unless MoonshotInlineImageURLs is set,
+// in which case they are downloaded, checked against MaxInlineImageSizeMB and
+// inlined as data URLs.
+func convertImageURLs(ctx context.Context, request *model.GeneralOpenAIRequest) error {
+	for i := range request.Messages {
+		parts, ok := request.Messages[i].Content.([]any)
+		if !ok {
+			continue
+		}
+		for _, part := range parts {
+			partMap, ok := part.(map[string]any)
+			if !ok || partMap["type"] != contentTypeImageURL {
+				continue
+			}
+
+			var rawURL string
+			imageURL, isObject := partMap[contentTypeImageURL].(map[string]any)
+			if isObject {
+				rawURL, _ = imageURL["url"].(string)
+			} else {
+				rawURL, _ = partMap[contentTypeImageURL].(string)
+			}
+			converted, err := convertImageURL(ctx, rawURL)
+			if err != nil {
+				return errors.Wrapf(err, "image of message %d", i)
+			}
+			if isObject {
+				imageURL["url"] = converted
+			} else {
+				partMap[contentTypeImageURL] = map[string]any{"url": converted}
+			}
+		}
+	}
+	return nil
+}
+
+// convertImageURL returns the URL Moonshot should receive for rawURL.
+func convertImageURL(ctx context.Context, rawURL string) (string, error) {
+	maxSize := int64(config.MaxInlineImageSizeMB) * 1024 * 1024
+	if fileId, ok := strings.CutPrefix(rawURL, fileReferenceScheme); ok {
+		if fileId == "" || strings.ContainsAny(fileId, "/?#") {
+			return "", errors.Errorf("invalid moonshot file reference: %q", rawURL)
+		}
+		return upstreamFileReferenceScheme + fileId, nil
+	}
+	if payload, ok := strings.CutPrefix(rawURL, "data:"); ok {
+		_, encoded, found := strings.Cut(payload, ";base64,")
+		if !found {
+			return "", errors.New("only base64 image data URLs are supported")
+		}
+		if int64(base64.StdEncoding.DecodedLen(len(encoded))) > maxSize {
+			return "", errors.Errorf("image size should not exceed %dMB", config.MaxInlineImageSizeMB)
+		}
+		return rawURL, nil
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", errors.Errorf("unsupported image url: %q", rawURL)
+	}
+	if config.MoonshotInlineImageURLs {
+		return inlineImage(ctx, rawURL, maxSize)
+	}
+	return rawURL, nil
+}
+
+// userContentClient returns the client for fetching user-supplied URLs, which
+// refuses connections to non-public addresses.
+func userContentClient() *http.Client {
+	if client.GuardedHTTPClient == nil {
+		client.Init()
+	}
+	return client.GuardedHTTPClient
+}
+
+// inlineImage downloads the image at rawURL and returns it as a base64 data URL.
+func inlineImage(ctx context.Context, rawURL string, maxSize int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(config.UserContentRequestTimeout)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "new request")
+	}
+	resp, err := userContentClient().Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "fetch image %s", rawURL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("fetch image %s: status code %d", rawURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return "", errors.Wrap(err, "read image")
+	}
+	if int64(len(data)) > maxSize {
+		return "", errors.Errorf("image size should not exceed %dMB: %s", config.MaxInlineImageSizeMB, rawURL)
+	}
+
+	mimeType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", errors.Errorf("invalid content type %q of image %s", mimeType, rawURL)
+	}
+	if semi := strings.IndexByte(mimeType, ';'); semi >= 0 {
+		mimeType = strings.TrimSpace(mimeType[:semi])
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,153 @@
+package moonshot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+// pngHeader is enough of a PNG for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// newImageServer serves /small.png within a 1MB limit and /large.png above it.
+func newImageServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	large := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 1<<20)...)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := pngHeader
+		if r.URL.Path == "/large.png" {
+			body = large
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(body)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// convertVisionRequest converts a single-image vision request and returns the
+// image URL sent upstream.
+func convertVisionRequest(t *testing.T, imageURL string) (string, error) {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	a := &Adaptor{}
+	a.Init(&meta.Meta{BaseURL: "http://127.0.0.1:0", ChannelType: channeltype.Moonshot})
+
+	converted, err := a.ConvertRequest(c, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{
+		Model: "moonshot-v1-8k-vision-preview",
+		Messages: []model.Message{{Role: "user", Content: []any{
+			map[string]any{"type": "image_url", "image_url": map[string]any{"url": imageURL}},
+			map[string]any{"type": "text", "text": "What is in the image?"},
+		}}},
+	})
+	if err != nil {
+		return "", err
+	}
+	parts := converted.(*model.GeneralOpenAIRequest).Messages[0].Content.([]any)
+	require.Len(t, parts, 2)
+	return parts[0].(map[string]any)["image_url"].(map[string]any)["url"].(string), nil
+}
+
+func setImageConfig(t *testing.T, maxSizeMB int, inline bool) {
+	t.Helper()
+	originalSize, originalInline := config.MaxInlineImageSizeMB, config.MoonshotInlineImageURLs
+	t.Cleanup(func() {
+		config.MaxInlineImageSizeMB, config.MoonshotInlineImageURLs = originalSize, originalInline
+	})
+	config.MaxInlineImageSizeMB, config.MoonshotInlineImageURLs = maxSizeMB, inline
+}
+
+func TestConvertRequest_MoonshotFileReference(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setImageConfig(t, 1, false)
+
+	url, err := convertVisionRequest(t, "moonshot://cqn1b2bhgb9v0ogfsnp0")
+	require.NoError(t, err)
+	require.Equal(t, "ms://cqn1b2bhgb9v0ogfsnp0", url)
+
+	_, err = convertVisionRequest(t, "moonshot://")
+	require.ErrorContains(t, err, "invalid moonshot file reference")
+}
+
+func TestConvertRequest_ImageURLPassthrough(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setImageConfig(t, 1, false)
+	images := newImageServer(t)
+
+	url, err := convertVisionRequest(t, images.URL+"/small.png")
+	require.NoError(t, err)
+	require.Equal(t, images.URL+"/small.png", url)
+
+	// Moonshot fetches and limits the images it is passed itself
+	url, err = convertVisionRequest(t, images.URL+"/large.png")
+	require.NoError(t, err)
+	require.Equal(t, images.URL+"/large.png", url)
+
+	_, err = convertVisionRequest(t, "ftp://example.com/cat.png")
+	require.ErrorContains(t, err, "unsupported image url")
+}
+
+// allowLoopbackUserContent swaps in an unguarded client so the loopback test
+// image and file servers can be fetched.
+func allowLoopbackUserContent(t *testing.T) {
+	t.Helper()
+	if client.GuardedHTTPClient == nil {
+		client.Init()
+	}
+	originalClient := client.GuardedHTTPClient
+	t.Cleanup(func() { client.GuardedHTTPClient = originalClient })
+	client.GuardedHTTPClient = &http.Client{}
+}
+
+func TestConvertRequest_ImageURLInlined(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setImageConfig(t, 1, true)
+	allowLoopbackUserContent(t)
+	images := newImageServer(t)
+
+	url, err := convertVisionRequest(t, images.URL+"/small.png")
+	require.NoError(t, err)
+	require.Equal(t, "data:image/png;base64,"+base64.StdEncoding.EncodeToString(pngHeader), url)
+
+	_, err = convertVisionRequest(t, images.URL+"/large.png")
+	require.ErrorContains(t, err, "image size should not exceed 1MB")
+}
+
+func TestConvertRequest_ImageURLInlinedRefusesPrivateAddresses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setImageConfig(t, 1, true)
+	images := newImageServer(t)
+
+	_, err := convertVisionRequest(t, images.URL+"/small.png")
+	require.ErrorContains(t, err, "non-public address")
+}
+
+func TestConvertRequest_ImageDataURLLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setImageConfig(t, 1, false)
+
+	small := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngHeader)
+	url, err := convertVisionRequest(t, small)
+	require.NoError(t, err)
+	require.Equal(t, small, url)
+
+	large := "data:image/png;base64," + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0}, 1<<20+3))
+	_, err = convertVisionRequest(t, large)
+	require.ErrorContains(t, err, "image size should not exceed 1MB")
+}
@@ -0,0 +1,82 @@
+package adaptor
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+// networkRetryBaseDelay is the base of the exponential backoff between
+// attempts of an upstream request that failed with a network error.
+const networkRetryBaseDelay = 100 * time.Millisecond
+
+// networkRetryDelay returns the backoff before retry number attempt (0-based):
+// base × 2^attempt plus a random jitter in [0, base).
+func networkRetryDelay(attempt int) time.Duration {
+	return networkRetryBaseDelay<<attempt + rand.N(networkRetryBaseDelay)
+}
+
+// isRetryableNetworkError reports whether err is a transient network error,
+// i.e. a net.Error that timed out or is temporary.
+func isRetryableNetworkError(err error) bool {
+	var netErr net.Error
+	if !errors.As(err, &netErr) {
+		return false
+	}
+	// Temporary is deprecated but still reports connection resets and temporary DNS failures
+	return netErr.Timeout() || netErr.Temporary()
+}
+
+// doRequestWithNetworkRetry sends req and, when config.NetworkRetryEnabled is
+// set, retries it on transient network errors up to
+// config.NetworkRetryMaxAttempts attempts in total. body is the buffered
+// request body replayed on each retry. HTTP error responses are returned as
+// is, and retries stop once the request context is done.
+func doRequestWithNetworkRetry(c *gin.Context, req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := DoRequest(c, req)
+	if !config.NetworkRetryEnabled {
+		return resp, err
+	}
+
+	ctx := req.Context()
+	lg := gmw.GetLogger(c)
+	for attempt := 0; err != nil && attempt+1 < config.NetworkRetryMaxAttempts; attempt++ {
+		if !isRetryableNetworkError(err) || ctx.Err() != nil {
+			break
+		}
+
+		delay := networkRetryDelay(attempt)
+		lg.Warn("retrying upstream request after network error",
+			zap.Int("attempt", attempt+2),
+			zap.Int("max_attempts", config.NetworkRetryMaxAttempts),
+			zap.Duration("delay", delay),
+			zap.Error(err))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Wrap(ctx.Err(), "wait for upstream request retry")
+		case <-timer.C:
+		}
+
+		retryReq := req.Clone(ctx)
+		if body != nil {
+			retryReq.Body = io.NopCloser(bytes.NewReader(body))
+			retryReq.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+		}
+		resp, err = DoRequest(c, retryReq)
+	}
+	return resp, err
+}
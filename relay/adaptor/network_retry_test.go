@@ -0,0 +1,131 @@
+package adaptor
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// flakyTransport fails the first failures round trips with err and answers
+// the rest with status, recording every request body it receives.
+type flakyTransport struct {
+	failures int
+	err      error
+	status   int
+	bodies   []string
+}
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	f.bodies = append(f.bodies, string(body))
+	if len(f.bodies) <= f.failures {
+		return nil, f.err
+	}
+	return &http.Response{
+		StatusCode: f.status,
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}, nil
+}
+
+func setupNetworkRetryTest(t *testing.T, transport *flakyTransport, enabled bool) *gin.Context {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Trace{}))
+	originalDB := model.DB
+	model.DB = db
+	originalClient := client.HTTPClient
+	originalEnabled := config.NetworkRetryEnabled
+	originalAttempts := config.NetworkRetryMaxAttempts
+	client.HTTPClient = &http.Client{Transport: transport}
+	config.NetworkRetryEnabled = enabled
+	config.NetworkRetryMaxAttempts = 3
+	t.Cleanup(func() {
+		model.DB = originalDB
+		client.HTTPClient = originalClient
+		config.NetworkRetryEnabled = originalEnabled
+		config.NetworkRetryMaxAttempts = originalAttempts
+	})
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	return c
+}
+
+func newNetworkRetryRequest(t *testing.T, body []byte) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://upstream.invalid/v1/chat/completions", bytes.NewReader(body))
+	require.NoError(t, err)
+	return req
+}
+
+func TestDoRequestWithNetworkRetry_RetriesTransientErrors(t *testing.T) {
+	transport := &flakyTransport{failures: 2, err: &net.DNSError{Err: "temporary failure", IsTemporary: true}, status: http.StatusOK}
+	c := setupNetworkRetryTest(t, transport, true)
+	body := []byte(`{"model":"gpt-4o"}`)
+
+	start := time.Now()
+	resp, err := doRequestWithNetworkRetry(c, newNetworkRetryRequest(t, body), body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{string(body), string(body), string(body)}, transport.bodies)
+	// Two backoffs of at least 100ms and 200ms
+	require.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+}
+
+func TestDoRequestWithNetworkRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	transport := &flakyTransport{failures: 5, err: &net.DNSError{Err: "temporary failure", IsTemporary: true}, status: http.StatusOK}
+	c := setupNetworkRetryTest(t, transport, true)
+
+	_, err := doRequestWithNetworkRetry(c, newNetworkRetryRequest(t, nil), nil)
+	require.Error(t, err)
+	require.Len(t, transport.bodies, 3)
+}
+
+func TestDoRequestWithNetworkRetry_SkipsNonRetryableFailures(t *testing.T) {
+	// HTTP error responses are returned without retrying
+	transport := &flakyTransport{status: http.StatusBadGateway}
+	c := setupNetworkRetryTest(t, transport, true)
+	resp, err := doRequestWithNetworkRetry(c, newNetworkRetryRequest(t, nil), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	require.Len(t, transport.bodies, 1)
+
+	// Errors that are not transient network errors fail immediately
+	transport = &flakyTransport{failures: 1, err: errors.New("boom"), status: http.StatusOK}
+	c = setupNetworkRetryTest(t, transport, true)
+	_, err = doRequestWithNetworkRetry(c, newNetworkRetryRequest(t, nil), nil)
+	require.Error(t, err)
+	require.Len(t, transport.bodies, 1)
+
+	// Retries are off unless enabled
+	transport = &flakyTransport{failures: 1, err: &net.DNSError{IsTimeout: true}, status: http.StatusOK}
+	c = setupNetworkRetryTest(t, transport, false)
+	_, err = doRequestWithNetworkRetry(c, newNetworkRetryRequest(t, nil), nil)
+	require.Error(t, err)
+	require.Len(t, transport.bodies, 1)
+}
+
+func TestNetworkRetryDelay(t *testing.T) {
+	for attempt := range 3 {
+		delay := networkRetryDelay(attempt)
+		require.GreaterOrEqual(t, delay, networkRetryBaseDelay<<attempt)
+		require.Less(t, delay, networkRetryBaseDelay<<attempt+networkRetryBaseDelay)
+	}
+}
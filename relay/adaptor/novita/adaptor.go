@@ -1,6 +1,7 @@
 package novita
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
 	"github.com/songquanpeng/one-api/relay/meta"
 	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
 )
 
 type Adaptor struct {
@@ -23,13 +25,11 @@ func (a *Adaptor) Init(meta *meta.Meta) {}
 func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
 	// Handle Claude Messages requests - convert to OpenAI Chat Completions endpoint
 	if meta.RequestURLPath == "/v1/messages" {
-		// Claude Messages requests should use OpenAI's chat completions endpoint
-		chatCompletionsPath := "/v1/chat/completions"
-		return openai_compatible.GetFullRequestURL(meta.BaseURL, chatCompletionsPath, meta.ChannelType), nil
+		return fmt.Sprintf("%s/chat/completions", meta.BaseURL), nil
 	}
 
-	// Novita uses OpenAI-compatible API endpoints
-	return openai_compatible.GetFullRequestURL(meta.BaseURL, meta.RequestURLPath, meta.ChannelType), nil
+	// The base URL already carries the OpenAI-compatible prefix, /v3/openai
+	return GetRequestURL(meta)
 }
 
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Request, meta *meta.Meta) error {
@@ -61,6 +61,11 @@ func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Read
 }
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
+	if meta.Mode == relaymode.Embeddings {
+		err, usage = openai_compatible.EmbeddingHandler(c, resp)
+		return usage, err
+	}
+
 	return openai_compatible.HandleClaudeMessagesResponse(c, resp, meta, func(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
 		if meta.IsStream {
 			return openai_compatible.StreamHandler(c, resp, promptTokens, modelName)
@@ -105,3 +110,9 @@ func (a *Adaptor) GetCompletionRatio(modelName string) float64 {
 func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return NovitaToolingDefaults
 }
+
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureStructuredOutput)
+}
@@ -0,0 +1,38 @@
+package novita
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+func TestGetRequestURL(t *testing.T) {
+	baseURL := channeltype.ChannelBaseURLs[channeltype.Novita]
+	for _, tc := range []struct {
+		mode     int
+		path     string
+		expected string
+	}{
+		{mode: relaymode.ChatCompletions, path: "/v1/chat/completions", expected: "https://api.novita.ai/v3/openai/chat/completions"},
+		{mode: relaymode.Embeddings, path: "/v1/embeddings", expected: "https://api.novita.ai/v3/openai/embeddings"},
+		{mode: relaymode.ClaudeMessages, path: "/v1/messages", expected: "https://api.novita.ai/v3/openai/chat/completions"},
+	} {
+		m := &meta.Meta{Mode: tc.mode, RequestURLPath: tc.path, BaseURL: baseURL, ChannelType: channeltype.Novita}
+		url, err := (&Adaptor{}).GetRequestURL(m)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, url, tc.path)
+	}
+
+	_, err := GetRequestURL(&meta.Meta{Mode: relaymode.ImagesGenerations, BaseURL: baseURL})
+	require.Error(t, err)
+}
+
+func TestModelListIncludesEmbeddingModels(t *testing.T) {
+	adaptor := &Adaptor{}
+	require.Contains(t, adaptor.GetModelList(), "e5-mistral-7b-instruct")
+	require.Contains(t, adaptor.GetDefaultModelPricing(), "e5-mistral-7b-instruct")
+}
@@ -35,6 +35,10 @@ var ModelRatios = map[string]adaptor.ModelConfig{
 	"lzlv_70b":                                    {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
 	"teknium/openhermes-2.5-mistral-7b":           {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
 	"microsoft/wizardlm-2-8x22b":                  {Ratio: 1.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+
+	// Embedding models
+	"e5-mistral-7b-instruct": {Ratio: 0.02 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"baai/bge-m3":            {Ratio: 0.01 * ratio.MilliTokensUsd, CompletionRatio: 1},
 }
 
 // ModelList derived from ModelRatios for backward compatibility
@@ -10,8 +10,11 @@ import (
 )
 
 func GetRequestURL(meta *meta.Meta) (string, error) {
-	if meta.Mode == relaymode.ChatCompletions {
+	switch meta.Mode {
+	case relaymode.ChatCompletions:
 		return fmt.Sprintf("%s/chat/completions", meta.BaseURL), nil
+	case relaymode.Embeddings:
+		return fmt.Sprintf("%s/embeddings", meta.BaseURL), nil
 	}
 	return "", errors.Errorf("unsupported relay mode %d for novita", meta.Mode)
 }
@@ -249,6 +249,9 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 	if err := a.applyRequestTransformations(metaInfo, request); err != nil {
 		return nil, errors.Wrap(err, "apply request transformations")
 	}
+	if metaInfo.ChannelType == channeltype.Doubao {
+		request.Model = doubao.ResolveModel(c, request.Model)
+	}
 
 	if (relayMode == relaymode.ChatCompletions || relayMode == relaymode.ClaudeMessages) &&
 		shouldForceResponseAPI(metaInfo) {
@@ -656,6 +659,9 @@ func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, request *model.ClaudeRequ
 	openaiRequest.Thinking = request.Thinking
 
 	metaInfo := meta.GetByContext(c)
+	if metaInfo.ChannelType == channeltype.Doubao {
+		openaiRequest.Model = doubao.ResolveModel(c, openaiRequest.Model)
+	}
 	if shouldForceResponseAPI(metaInfo) {
 		if err := a.applyRequestTransformations(metaInfo, openaiRequest); err != nil {
 			return nil, errors.Wrap(err, "apply request transformations for Claude conversion")
@@ -873,6 +879,12 @@ func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return OpenAIToolingDefaults
 }
 
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureStructuredOutput, adaptor.FeatureExtendedThinking)
+}
+
 // convertToClaudeResponse converts OpenAI response format to Claude Messages format
 func (a *Adaptor) convertToClaudeResponse(c *gin.Context, resp *http.Response) (*http.Response, *model.ErrorWithStatusCode) {
 	// Read the response body
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/songquanpeng/one-api/common/ctxkey"
+	dbmodel "github.com/songquanpeng/one-api/model"
 	"github.com/songquanpeng/one-api/relay/channeltype"
 	"github.com/songquanpeng/one-api/relay/meta"
 	"github.com/songquanpeng/one-api/relay/model"
@@ -215,3 +216,32 @@ func TestAzureGPT5ConversionToResponseAPI(t *testing.T) {
 	require.True(t, ok)
 	require.IsType(t, &ResponseAPIRequest{}, stored)
 }
+
+func TestDoubaoConvertRequest_ResolvesEndpointId(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = &http.Request{}
+
+	mapping := `{"doubao-pro-*":"ep-20250101-pro"}`
+	c.Set(ctxkey.ChannelModel, &dbmodel.Channel{Id: 1, EndpointIdMapping: &mapping})
+	doubaoMeta := &meta.Meta{
+		Mode:            relaymode.ChatCompletions,
+		ChannelType:     channeltype.Doubao,
+		BaseURL:         "https://ark.cn-beijing.volces.com",
+		RequestURLPath:  "/v1/chat/completions",
+		ActualModelName: "doubao-pro-32k",
+	}
+	c.Set(ctxkey.Meta, doubaoMeta)
+
+	adaptor := &Adaptor{}
+	adaptor.Init(doubaoMeta)
+
+	converted, err := adaptor.ConvertRequest(c, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{
+		Model:    "doubao-pro-32k",
+		Messages: []model.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ep-20250101-pro", converted.(*model.GeneralOpenAIRequest).Model)
+}
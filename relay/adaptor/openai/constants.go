@@ -104,10 +104,60 @@ var ModelRatios = map[string]adaptor.ModelConfig{
 	// Realtime Models
 	// -------------------------------------
 
-	"gpt-4o-realtime-preview":                 {Ratio: 5.0 * ratio.MilliTokensUsd, CompletionRatio: 4.0, CachedInputRatio: 2.5 * ratio.MilliTokensUsd},
-	"gpt-4o-realtime-preview-2025-06-03":      {Ratio: 5.0 * ratio.MilliTokensUsd, CompletionRatio: 4.0, CachedInputRatio: 2.5 * ratio.MilliTokensUsd},
-	"gpt-4o-mini-realtime-preview":            {Ratio: 0.6 * ratio.MilliTokensUsd, CompletionRatio: 4.0, CachedInputRatio: 0.3 * ratio.MilliTokensUsd},
-	"gpt-4o-mini-realtime-preview-2024-12-17": {Ratio: 0.6 * ratio.MilliTokensUsd, CompletionRatio: 4.0, CachedInputRatio: 0.3 * ratio.MilliTokensUsd},
+	"gpt-4o-realtime-preview": {
+		Ratio:            5.0 * ratio.MilliTokensUsd,
+		CompletionRatio:  4.0,
+		CachedInputRatio: 2.5 * ratio.MilliTokensUsd,
+		Audio: &adaptor.AudioPricingConfig{
+			PromptRatio:     8,
+			CompletionRatio: 2,
+		},
+	},
+	"gpt-4o-realtime-preview-2024-10-01": {
+		Ratio:            5.0 * ratio.MilliTokensUsd,
+		CompletionRatio:  4.0,
+		CachedInputRatio: 2.5 * ratio.MilliTokensUsd,
+		Audio: &adaptor.AudioPricingConfig{
+			PromptRatio:     20,
+			CompletionRatio: 2,
+		},
+	},
+	"gpt-4o-realtime-preview-2024-12-17": {
+		Ratio:            5.0 * ratio.MilliTokensUsd,
+		CompletionRatio:  4.0,
+		CachedInputRatio: 2.5 * ratio.MilliTokensUsd,
+		Audio: &adaptor.AudioPricingConfig{
+			PromptRatio:     8,
+			CompletionRatio: 2,
+		},
+	},
+	"gpt-4o-realtime-preview-2025-06-03": {
+		Ratio:            5.0 * ratio.MilliTokensUsd,
+		CompletionRatio:  4.0,
+		CachedInputRatio: 2.5 * ratio.MilliTokensUsd,
+		Audio: &adaptor.AudioPricingConfig{
+			PromptRatio:     8,
+			CompletionRatio: 2,
+		},
+	},
+	"gpt-4o-mini-realtime-preview": {
+		Ratio:            0.6 * ratio.MilliTokensUsd,
+		CompletionRatio:  4.0,
+		CachedInputRatio: 0.3 * ratio.MilliTokensUsd,
+		Audio: &adaptor.AudioPricingConfig{
+			PromptRatio:     10.0 / 0.6,
+			CompletionRatio: 2,
+		},
+	},
+	"gpt-4o-mini-realtime-preview-2024-12-17": {
+		Ratio:            0.6 * ratio.MilliTokensUsd,
+		CompletionRatio:  4.0,
+		CachedInputRatio: 0.3 * ratio.MilliTokensUsd,
+		Audio: &adaptor.AudioPricingConfig{
+			PromptRatio:     10.0 / 0.6,
+			CompletionRatio: 2,
+		},
+	},
 
 	// GPT-4.5 Models
 	// -------------------------------------
@@ -241,10 +291,10 @@ var ModelRatios = map[string]adaptor.ModelConfig{
 	// TTS Models
 	// -------------------------------------
 
-	"tts-1":         {Ratio: 15.0 * ratio.MilliTokensUsd, CompletionRatio: 1.0}, // $15.00 per 1M characters
-	"tts-1-1106":    {Ratio: 15.0 * ratio.MilliTokensUsd, CompletionRatio: 1.0}, // $15.00 per 1M characters
-	"tts-1-hd":      {Ratio: 30.0 * ratio.MilliTokensUsd, CompletionRatio: 1.0}, // $30.00 per 1M characters
-	"tts-1-hd-1106": {Ratio: 30.0 * ratio.MilliTokensUsd, CompletionRatio: 1.0}, // $30.00 per 1M characters
+	"tts-1":         {Ratio: 15.0 * ratio.MilliTokensUsd, CompletionRatio: 1.0, CharacterCountBilling: true, CharacterRatio: 15.0 * ratio.MilliTokensUsd}, // $15.00 per 1M characters
+	"tts-1-1106":    {Ratio: 15.0 * ratio.MilliTokensUsd, CompletionRatio: 1.0, CharacterCountBilling: true, CharacterRatio: 15.0 * ratio.MilliTokensUsd}, // $15.00 per 1M characters
+	"tts-1-hd":      {Ratio: 30.0 * ratio.MilliTokensUsd, CompletionRatio: 1.0, CharacterCountBilling: true, CharacterRatio: 30.0 * ratio.MilliTokensUsd}, // $30.00 per 1M characters
+	"tts-1-hd-1106": {Ratio: 30.0 * ratio.MilliTokensUsd, CompletionRatio: 1.0, CharacterCountBilling: true, CharacterRatio: 30.0 * ratio.MilliTokensUsd}, // $30.00 per 1M characters
 	"gpt-4o-transcribe": {
 		Ratio:           2.5 * ratio.MilliTokensUsd,
 		CompletionRatio: 4.0, // $2.50 input, $10.00 output per 1M tokens
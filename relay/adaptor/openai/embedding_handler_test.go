@@ -4,16 +4,20 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/json"
 	"io"
 	"math"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 
+	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/ctxkey"
+	relaymodel "github.com/songquanpeng/one-api/model"
 )
 
 func TestEmbeddingHandlerUsageFromResponse(t *testing.T) {
@@ -109,3 +113,80 @@ func encodeEmbeddingToBase64(values []float32) string {
 	}
 	return base64.StdEncoding.EncodeToString(buf)
 }
+
+func TestEmbeddingHandlerTruncatesToRequestedDimensions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/embeddings",
+		bytes.NewBufferString(`{"model":"text-embedding-3-small","input":"hi","dimensions":256}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(ctxkey.Config, relaymodel.ChannelConfig{TruncateEmbeddings: true})
+
+	full := make([]float64, 1536)
+	for i := range full {
+		full[i] = float64(i%7+1) / 100
+	}
+	body, err := json.Marshal(map[string]any{
+		"object": "list",
+		"data":   []map[string]any{{"object": "embedding", "index": 0, "embedding": full}},
+		"model":  "text-embedding-3-small",
+		"usage":  map[string]int{"prompt_tokens": 1, "total_tokens": 1},
+	})
+	require.NoError(t, err)
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Length": []string{strconv.Itoa(len(body))}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+
+	errResp, _ := EmbeddingHandler(c, resp, 1, "text-embedding-3-small")
+	require.Nil(t, errResp)
+	require.Empty(t, w.Header().Get("Content-Length"))
+
+	var out EmbeddingResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Len(t, out.Data, 1)
+	vector := out.Data[0].Embedding
+	require.Len(t, vector, 256)
+
+	var sumSquares, prefixSquares float64
+	for i, v := range vector {
+		sumSquares += v * v
+		prefixSquares += full[i] * full[i]
+	}
+	require.InDelta(t, 1.0, sumSquares, 1e-9)
+	for i, v := range vector {
+		require.InDelta(t, full[i]/math.Sqrt(prefixSquares), v, 1e-12)
+	}
+}
+
+func TestEmbeddingHandlerTruncationKeepsBase64Encoding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := config.EmbeddingTruncationEnabled
+	config.EmbeddingTruncationEnabled = true
+	t.Cleanup(func() { config.EmbeddingTruncationEnabled = original })
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/embeddings",
+		bytes.NewBufferString(`{"model":"text-embedding-3-small","input":"hi","dimensions":2,"encoding_format":"base64"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	body := `{"object":"list","data":[{"object":"embedding","index":0,"embedding":"` +
+		encodeBase64Embedding([]float64{3, 4, 12}) + `"}],"model":"text-embedding-3-small"}`
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+	}
+
+	errResp, _ := EmbeddingHandler(c, resp, 1, "text-embedding-3-small")
+	require.Nil(t, errResp)
+
+	var out EmbeddingResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.True(t, out.Data[0].Base64Encoded)
+	require.InDeltaSlice(t, []float64{0.6, 0.8}, out.Data[0].Embedding, 1e-6)
+}
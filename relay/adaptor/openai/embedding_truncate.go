@@ -0,0 +1,126 @@
+package openai
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	relaymodel "github.com/songquanpeng/one-api/model"
+)
+
+// embeddingTruncationDimensions returns the `dimensions` requested by the client
+// when embedding truncation is enabled globally or for the current channel, and 0
+// otherwise.
+func embeddingTruncationDimensions(c *gin.Context) int {
+	enabled := config.EmbeddingTruncationEnabled
+	if cfg, ok := c.Get(ctxkey.Config); ok {
+		if channelCfg, ok := cfg.(relaymodel.ChannelConfig); ok && channelCfg.TruncateEmbeddings {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return 0
+	}
+
+	var req struct {
+		Dimensions int `json:"dimensions"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return 0
+	}
+
+	return max(req.Dimensions, 0)
+}
+
+// truncateEmbedding shortens vector to dimensions and L2-normalizes the result,
+// following Matryoshka Representation Learning. Vectors that are already short
+// enough are returned unchanged.
+func truncateEmbedding(vector []float64, dimensions int) []float64 {
+	if dimensions <= 0 || len(vector) <= dimensions {
+		return vector
+	}
+
+	truncated := make([]float64, dimensions)
+	copy(truncated, vector[:dimensions])
+	var sumSquares float64
+	for _, v := range truncated {
+		sumSquares += v * v
+	}
+	if norm := math.Sqrt(sumSquares); norm > 0 {
+		for i := range truncated {
+			truncated[i] /= norm
+		}
+	}
+
+	return truncated
+}
+
+// truncateEmbeddingResponse truncates every vector in response to dimensions and
+// rewrites body accordingly. Fields other than the embeddings are preserved as
+// sent by upstream, and base64-encoded vectors stay base64-encoded. It reports
+// whether any vector was changed.
+func truncateEmbeddingResponse(body []byte, response *EmbeddingResponse, dimensions int) ([]byte, bool, error) {
+	changed := false
+	for i := range response.Data {
+		if len(response.Data[i].Embedding) > dimensions {
+			response.Data[i].Embedding = truncateEmbedding(response.Data[i].Embedding, dimensions)
+			changed = true
+		}
+	}
+	if !changed {
+		return body, false, nil
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, false, errors.Wrap(err, "unmarshal embedding response for truncation")
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(payload["data"], &items); err != nil {
+		return nil, false, errors.Wrap(err, "unmarshal embedding data for truncation")
+	}
+	if len(items) != len(response.Data) {
+		return nil, false, errors.Errorf("embedding data length mismatch: %d != %d", len(items), len(response.Data))
+	}
+
+	for i, item := range response.Data {
+		var encoded any = item.Embedding
+		if item.Base64Encoded {
+			encoded = encodeBase64Embedding(item.Embedding)
+		}
+		raw, err := json.Marshal(encoded)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "marshal truncated embedding")
+		}
+		items[i]["embedding"] = raw
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "marshal truncated embedding data")
+	}
+	payload["data"] = data
+	rewritten, err := json.Marshal(payload)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "marshal truncated embedding response")
+	}
+
+	return rewritten, true, nil
+}
+
+// encodeBase64Embedding is the inverse of decodeBase64Embedding, packing values
+// as little-endian float32.
+func encodeBase64Embedding(values []float64) string {
+	raw := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(raw[i*4:], math.Float32bits(float32(v)))
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
@@ -105,6 +105,7 @@ func StreamHandler(c *gin.Context, resp *http.Response, relayMode int) (*model.E
 	responseText := ""
 	reasoningText := ""
 	var usage *model.Usage
+	hideReasoning := hidesReasoning(c)
 
 	var streamRewriter openai_compatible.StreamRewriteHandler
 	if rewriteAny, exists := c.Get(ctxkey.ResponseStreamRewriteHandler); exists {
@@ -228,6 +229,10 @@ streamLoop:
 				}
 			}
 
+			if hideReasoning {
+				data = stripStreamReasoning(c, data, &streamResponse)
+			}
+
 			handledByRewriter := false
 			if streamRewriter != nil {
 				if handled, handledDone := streamRewriter.HandleChunk(c, &streamResponse); handled {
@@ -323,6 +328,10 @@ streamLoop:
 	// Record when upstream streaming is completed
 	recordUpstreamCompleted(c)
 
+	if metaInfo != nil {
+		saveReasoningTrace(c, metaInfo.ActualModelName, reasoningText)
+	}
+
 	combined := reasoningText + responseText
 	if combined != "" || usage != nil {
 		c.Set(ctxkey.ConvertedResponse, map[string]any{
@@ -337,6 +346,27 @@ streamLoop:
 	return nil, combined, usage
 }
 
+// stripStreamReasoning removes the reasoning from every choice of chunk and
+// returns the data line to forward. The original line is kept when the chunk
+// carries no reasoning or cannot be re-encoded.
+func stripStreamReasoning(c *gin.Context, data string, chunk *openai_compatible.ChatCompletionsStreamResponse) string {
+	stripped := false
+	for i := range chunk.Choices {
+		if extractReasoningContent(&chunk.Choices[i].Delta) != "" {
+			stripped = true
+		}
+	}
+	if !stripped {
+		return data
+	}
+	payload, err := json.Marshal(chunk)
+	if err != nil {
+		gmw.GetLogger(c).Warn("failed to re-encode stream chunk without reasoning", zap.Error(err))
+		return data
+	}
+	return dataPrefix + string(payload)
+}
+
 // Helper function to extract reasoning content from message delta
 func extractReasoningContent(delta *model.Message) string {
 	content := ""
@@ -424,15 +454,19 @@ func Handler(c *gin.Context, resp *http.Response, promptTokens int, modelName st
 
 	// Process reasoning content in each choice
 	reasoningFormat := c.Query("reasoning_format")
+	hideReasoning := hidesReasoning(c)
+	var reasoningTrace strings.Builder
 	for i := range textResponse.Choices {
 		choice := &textResponse.Choices[i]
 		reasoningContent := processReasoningContent(choice)
+		reasoningTrace.WriteString(reasoningContent)
 
 		// Set reasoning in requested format if content exists
-		if reasoningContent != "" {
+		if reasoningContent != "" && !hideReasoning {
 			choice.SetReasoningContent(reasoningFormat, reasoningContent)
 		}
 	}
+	saveReasoningTrace(c, modelName, reasoningTrace.String())
 
 	// Check if this is a Claude Messages conversion - if so, don't write response here
 	// The DoResponse method will handle the conversion and response writing
@@ -575,6 +609,20 @@ func EmbeddingHandler(c *gin.Context, resp *http.Response, promptTokens int, mod
 			zap.Int("dimensions", base64Dims))
 	}
 
+	if dimensions := embeddingTruncationDimensions(c); dimensions > 0 {
+		truncatedBody, truncated, err := truncateEmbeddingResponse(responseBody, &embeddingResponse, dimensions)
+		if err != nil {
+			return ErrorWrapper(err, "truncate_embedding_response_failed", http.StatusInternalServerError), nil
+		}
+		if truncated {
+			logger.Debug("truncated embeddings to requested dimensions",
+				zap.Int("dimensions", dimensions),
+				zap.Int("vectors", len(embeddingResponse.Data)))
+			responseBody = truncatedBody
+			resp.Header.Del("Content-Length")
+		}
+	}
+
 	usage := embeddingResponse.Usage
 	if usage.PromptTokens == 0 && promptTokens > 0 {
 		usage.PromptTokens = promptTokens
@@ -775,7 +823,12 @@ func ResponseAPIHandler(c *gin.Context, resp *http.Response, promptTokens int, m
 	if len(chatCompletionResp.Choices) > 0 {
 		choice := &chatCompletionResp.Choices[0]
 		if choice.Message.Reasoning != nil && *choice.Message.Reasoning != "" {
-			choice.Message.SetReasoningContent(c.Query("reasoning_format"), *choice.Message.Reasoning)
+			saveReasoningTrace(c, modelName, *choice.Message.Reasoning)
+			if hidesReasoning(c) {
+				choice.Message.Reasoning = nil
+			} else {
+				choice.Message.SetReasoningContent(c.Query("reasoning_format"), *choice.Message.Reasoning)
+			}
 		}
 	}
 
@@ -846,6 +899,7 @@ func ResponseAPIStreamHandler(c *gin.Context, resp *http.Response, relayMode int
 	responseText := ""
 	reasoningText := ""
 	var usage *model.Usage
+	hideReasoning := hidesReasoning(c)
 	var lastUsage *ResponseAPIUsage
 	webSearchSeen := make(map[string]struct{})
 	webSearchCount := 0
@@ -995,6 +1049,9 @@ func ResponseAPIStreamHandler(c *gin.Context, resp *http.Response, relayMode int
 
 		// Convert Response API chunk to ChatCompletion streaming format with proper index context
 		chatCompletionChunk := ConvertResponseAPIStreamToChatCompletionWithIndex(&responseAPIChunk, outputIndex)
+		if hideReasoning && len(chatCompletionChunk.Choices) > 0 {
+			chatCompletionChunk.Choices[0].Delta.Reasoning = nil
+		}
 
 		// If this is a done/complete event and the output item was already emitted
 		// from prior delta events, clear content to avoid duplicate text emission.
@@ -1282,6 +1339,10 @@ func ResponseAPIStreamHandler(c *gin.Context, resp *http.Response, relayMode int
 	// Record when upstream streaming is completed
 	recordUpstreamCompleted(c)
 
+	if metaInfo := metalib.GetByContext(c); metaInfo != nil {
+		saveReasoningTrace(c, metaInfo.ActualModelName, reasoningText)
+	}
+
 	return nil, responseText, usage
 }
 
@@ -1363,6 +1424,11 @@ func ResponseAPIDirectHandler(c *gin.Context, resp *http.Response, promptTokens
 		finalUsage = ResponseText2Usage(responseText, modelName, promptTokens)
 	}
 
+	saveReasoningTrace(c, modelName, responseAPIReasoning(&responseAPIResp))
+	if hidesReasoning(c) {
+		responseBody = stripResponseAPIReasoning(c, responseBody)
+	}
+
 	// Forward all response headers
 	for k, values := range resp.Header {
 		if strings.EqualFold(k, "Content-Length") ||
@@ -1397,7 +1463,9 @@ func ResponseAPIDirectHandler(c *gin.Context, resp *http.Response, promptTokens
 func ResponseAPIDirectStreamHandler(c *gin.Context, resp *http.Response, relayMode int) (*model.ErrorWithStatusCode, string, *model.Usage) {
 	// Initialize accumulators for the response
 	responseText := ""
+	reasoningText := ""
 	var usage *model.Usage
+	hideReasoning := hidesReasoning(c)
 	var lastUsage *ResponseAPIUsage
 	webSearchSeen := make(map[string]struct{})
 	webSearchCount := 0
@@ -1463,6 +1531,9 @@ func ResponseAPIDirectStreamHandler(c *gin.Context, resp *http.Response, relayMo
 			// Only accumulate content from delta events to prevent duplication
 			if delta := extractStringFromRaw(streamEvent.Delta, "partial_json", "json", "text", "delta"); delta != "" {
 				responseText += delta
+				if strings.Contains(streamEvent.Type, "reasoning_summary_text") {
+					reasoningText += delta
+				}
 			}
 		}
 
@@ -1474,6 +1545,15 @@ func ResponseAPIDirectStreamHandler(c *gin.Context, resp *http.Response, relayMo
 			}
 		}
 
+		if hideReasoning {
+			if isResponseAPIReasoningEvent(streamEvent) {
+				continue
+			}
+			if streamEvent == nil || streamEvent.Response != nil {
+				data = string(stripResponseAPIReasoning(c, []byte(data)))
+			}
+		}
+
 		// Pass through the original Response API event directly to client
 		c.Render(-1, common.CustomEvent{Data: "data: " + string(data)})
 	}
@@ -1502,6 +1582,10 @@ func ResponseAPIDirectStreamHandler(c *gin.Context, resp *http.Response, relayMo
 	// Record when upstream streaming is completed
 	recordUpstreamCompleted(c)
 
+	if metaInfo := metalib.GetByContext(c); metaInfo != nil {
+		saveReasoningTrace(c, metaInfo.ActualModelName, reasoningText)
+	}
+
 	if lastFullResponse != nil {
 		c.Set(ctxkey.ConvertedResponse, *lastFullResponse)
 	} else if responseText != "" || usage != nil {
@@ -0,0 +1,134 @@
+package openai
+
+import (
+	"encoding/json"
+	"strings"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/random"
+	dbmodel "github.com/songquanpeng/one-api/model"
+)
+
+// IncludeReasoningHeader opts a client into receiving the reasoning of models
+// served by channels that store reasoning traces.
+const IncludeReasoningHeader = "X-OneApi-Include-Reasoning"
+
+// storesReasoningTrace reports whether the channel serving c has
+// StoreReasoningTrace enabled.
+func storesReasoningTrace(c *gin.Context) bool {
+	channelModel, ok := c.Get(ctxkey.ChannelModel)
+	if !ok {
+		return false
+	}
+	channel, ok := channelModel.(*dbmodel.Channel)
+	return ok && channel != nil && channel.StoreReasoningTrace
+}
+
+// hidesReasoning reports whether reasoning must be removed from the response,
+// which is the case on channels storing reasoning traces unless the client
+// sent IncludeReasoningHeader.
+func hidesReasoning(c *gin.Context) bool {
+	return storesReasoningTrace(c) &&
+		!strings.EqualFold(strings.TrimSpace(c.GetHeader(IncludeReasoningHeader)), "true")
+}
+
+// saveReasoningTrace stores the reasoning of the current request in
+// request_traces when the channel has StoreReasoningTrace enabled. Storage is
+// best-effort and failures are only logged.
+func saveReasoningTrace(c *gin.Context, modelName, reasoning string) {
+	if reasoning == "" || !storesReasoningTrace(c) {
+		return
+	}
+
+	lg := gmw.GetLogger(c)
+	requestId := c.GetString(ctxkey.RequestId)
+	if requestId == "" {
+		lg.Debug("skip reasoning trace: missing request id")
+		return
+	}
+	// Reasoning rows are looked up by request ID. They get their own trace ID
+	// because trace_id is unique and a replay capture of the same request may
+	// already use the request's trace ID.
+	trace := &dbmodel.RequestTrace{
+		TraceId:        random.GetUUID(),
+		RequestId:      requestId,
+		UserId:         c.GetInt(ctxkey.Id),
+		TokenId:        c.GetInt(ctxkey.TokenId),
+		ChannelId:      c.GetInt(ctxkey.ChannelId),
+		ModelName:      modelName,
+		Method:         c.Request.Method,
+		URL:            c.Request.URL.RequestURI(),
+		ResponseStatus: c.Writer.Status(),
+		Reasoning:      reasoning,
+	}
+	if err := dbmodel.CreateRequestTrace(gmw.Ctx(c), trace); err != nil {
+		lg.Warn("failed to store reasoning trace", zap.Error(err))
+	}
+}
+
+// responseAPIReasoning returns the reasoning summary text of a Response API
+// response.
+func responseAPIReasoning(resp *ResponseAPIResponse) string {
+	var reasoning strings.Builder
+	for _, item := range resp.Output {
+		if item.Type != "reasoning" {
+			continue
+		}
+		for _, summary := range item.Summary {
+			if summary.Type == "summary_text" {
+				reasoning.WriteString(summary.Text)
+			}
+		}
+	}
+	return reasoning.String()
+}
+
+// isResponseAPIReasoningEvent reports whether a Response API stream event
+// only carries reasoning.
+func isResponseAPIReasoningEvent(event *ResponseAPIStreamEvent) bool {
+	if event == nil {
+		return false
+	}
+	return strings.Contains(event.Type, "reasoning") ||
+		(event.Item != nil && event.Item.Type == "reasoning")
+}
+
+// stripResponseAPIReasoning removes the reasoning output items from a
+// Response API response, or from the response of a response-level stream
+// event, and returns the body to forward. The original body is kept when it
+// carries no reasoning or cannot be re-encoded.
+func stripResponseAPIReasoning(c *gin.Context, body []byte) []byte {
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+	target := payload
+	if nested, ok := payload["response"].(map[string]any); ok {
+		target = nested
+	}
+	output, ok := target["output"].([]any)
+	if !ok {
+		return body
+	}
+	kept := make([]any, 0, len(output))
+	for _, item := range output {
+		if fields, ok := item.(map[string]any); ok && fields["type"] == "reasoning" {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	if len(kept) == len(output) {
+		return body
+	}
+	target["output"] = kept
+	stripped, err := json.Marshal(payload)
+	if err != nil {
+		gmw.GetLogger(c).Warn("failed to re-encode response without reasoning", zap.Error(err))
+		return body
+	}
+	return stripped
+}
@@ -0,0 +1,183 @@
+package openai
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	dbmodel "github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+const o3ReasoningResponse = `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"o3",` +
+	`"choices":[{"index":0,"message":{"role":"assistant","content":"42","reasoning":"six times seven"},"finish_reason":"stop"}],` +
+	`"usage":{"prompt_tokens":5,"completion_tokens":7,"total_tokens":12}}`
+
+const o3ReasoningStream = `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"o3","choices":[{"index":0,"delta":{"role":"assistant","reasoning":"six times "}}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"o3","choices":[{"index":0,"delta":{"reasoning":"seven"}}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"o3","choices":[{"index":0,"delta":{"content":"42"},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+
+// setupReasoningTraceTest points the model package at a fresh database and
+// returns a context served by a channel with StoreReasoningTrace set to store.
+func setupReasoningTraceTest(t *testing.T, store bool, includeHeader string) (*gin.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dsn := fmt.Sprintf("file:reasoning_trace_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&dbmodel.RequestTrace{}))
+	originalDB := dbmodel.DB
+	dbmodel.DB = db
+	t.Cleanup(func() { dbmodel.DB = originalDB })
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	if includeHeader != "" {
+		c.Request.Header.Set(IncludeReasoningHeader, includeHeader)
+	}
+	c.Set(ctxkey.ChannelModel, &dbmodel.Channel{Id: 3, StoreReasoningTrace: store})
+	c.Set(ctxkey.ChannelId, 3)
+	c.Set(ctxkey.Id, 9)
+	c.Set(ctxkey.RequestId, "req-reasoning")
+	return c, w
+}
+
+func newUpstreamResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestHandler_StoresAndHidesReasoning(t *testing.T) {
+	c, w := setupReasoningTraceTest(t, true, "")
+
+	errResp, usage := Handler(c, newUpstreamResponse(o3ReasoningResponse), 5, "o3")
+	require.Nil(t, errResp)
+	require.Equal(t, 12, usage.TotalTokens)
+	require.Contains(t, w.Body.String(), `"content":"42"`)
+	require.NotContains(t, w.Body.String(), "six times seven")
+
+	trace, err := dbmodel.GetReasoningTraceByRequestId(t.Context(), "req-reasoning")
+	require.NoError(t, err)
+	require.Equal(t, "six times seven", trace.Reasoning)
+	require.Equal(t, 9, trace.UserId)
+	require.Equal(t, 3, trace.ChannelId)
+	require.Equal(t, "o3", trace.ModelName)
+}
+
+func TestHandler_IncludesReasoningWhenRequested(t *testing.T) {
+	c, w := setupReasoningTraceTest(t, true, "true")
+
+	errResp, _ := Handler(c, newUpstreamResponse(o3ReasoningResponse), 5, "o3")
+	require.Nil(t, errResp)
+	require.Contains(t, w.Body.String(), "six times seven")
+
+	trace, err := dbmodel.GetReasoningTraceByRequestId(t.Context(), "req-reasoning")
+	require.NoError(t, err)
+	require.Equal(t, "six times seven", trace.Reasoning)
+}
+
+func TestHandler_ReasoningTraceDisabled(t *testing.T) {
+	c, w := setupReasoningTraceTest(t, false, "")
+
+	errResp, _ := Handler(c, newUpstreamResponse(o3ReasoningResponse), 5, "o3")
+	require.Nil(t, errResp)
+	require.Contains(t, w.Body.String(), "six times seven")
+
+	_, err := dbmodel.GetReasoningTraceByRequestId(t.Context(), "req-reasoning")
+	require.Error(t, err)
+}
+
+func TestStreamHandler_StoresAndHidesReasoning(t *testing.T) {
+	c, w := setupReasoningTraceTest(t, true, "")
+	c.Set(ctxkey.RequestModel, "o3")
+
+	errResp, text, _ := StreamHandler(c, newUpstreamResponse(o3ReasoningStream), relaymode.ChatCompletions)
+	require.Nil(t, errResp)
+	require.Equal(t, "six times seven42", text)
+	require.Contains(t, w.Body.String(), `"content":"42"`)
+	require.NotContains(t, w.Body.String(), "six times")
+	require.NotContains(t, w.Body.String(), `"reasoning"`)
+	require.Contains(t, w.Body.String(), "data: [DONE]")
+
+	trace, err := dbmodel.GetReasoningTraceByRequestId(t.Context(), "req-reasoning")
+	require.NoError(t, err)
+	require.Equal(t, "six times seven", trace.Reasoning)
+}
+
+func TestStreamHandler_IncludesReasoningWhenRequested(t *testing.T) {
+	c, w := setupReasoningTraceTest(t, true, "true")
+
+	errResp, _, _ := StreamHandler(c, newUpstreamResponse(o3ReasoningStream), relaymode.ChatCompletions)
+	require.Nil(t, errResp)
+	require.Contains(t, w.Body.String(), `"reasoning":"six times "`)
+
+	trace, err := dbmodel.GetReasoningTraceByRequestId(t.Context(), "req-reasoning")
+	require.NoError(t, err)
+	require.Equal(t, "six times seven", trace.Reasoning)
+}
+
+const o3ResponseAPIResponse = `{"id":"resp_1","object":"response","created_at":1,"status":"completed","model":"o3",` +
+	`"output":[{"id":"rs_1","type":"reasoning","summary":[{"type":"summary_text","text":"six times seven"}]},` +
+	`{"id":"msg_1","type":"message","status":"completed","role":"assistant","content":[{"type":"output_text","text":"42"}]}],` +
+	`"usage":{"input_tokens":5,"output_tokens":7,"total_tokens":12}}`
+
+func TestSaveReasoningTrace_RepeatedRequestIdGetsUniqueTraceIds(t *testing.T) {
+	c, _ := setupReasoningTraceTest(t, true, "")
+
+	saveReasoningTrace(c, "o3", "first")
+	saveReasoningTrace(c, "o3", "second")
+
+	var traces []dbmodel.RequestTrace
+	require.NoError(t, dbmodel.DB.Where("request_id = ?", "req-reasoning").Find(&traces).Error)
+	require.Len(t, traces, 2)
+	require.NotEqual(t, traces[0].TraceId, traces[1].TraceId)
+}
+
+func TestResponseAPIDirectHandler_StoresAndHidesReasoning(t *testing.T) {
+	c, w := setupReasoningTraceTest(t, true, "")
+
+	errResp, _ := ResponseAPIDirectHandler(c, newUpstreamResponse(o3ResponseAPIResponse), 5, "o3")
+	require.Nil(t, errResp)
+	require.Contains(t, w.Body.String(), `"text":"42"`)
+	require.NotContains(t, w.Body.String(), "six times seven")
+	require.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+
+	trace, err := dbmodel.GetReasoningTraceByRequestId(t.Context(), "req-reasoning")
+	require.NoError(t, err)
+	require.Equal(t, "six times seven", trace.Reasoning)
+}
+
+func TestResponseAPIHandler_StoresAndHidesReasoning(t *testing.T) {
+	c, w := setupReasoningTraceTest(t, true, "")
+
+	errResp, _ := ResponseAPIHandler(c, newUpstreamResponse(o3ResponseAPIResponse), 5, "o3")
+	require.Nil(t, errResp)
+	require.Contains(t, w.Body.String(), `"content":"42"`)
+	require.NotContains(t, w.Body.String(), "six times seven")
+
+	trace, err := dbmodel.GetReasoningTraceByRequestId(t.Context(), "req-reasoning")
+	require.NoError(t, err)
+	require.Equal(t, "six times seven", trace.Reasoning)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/pkoukk/tiktoken-go"
 
 	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/helper"
 	imgutil "github.com/songquanpeng/one-api/common/image"
 	"github.com/songquanpeng/one-api/relay/model"
@@ -105,6 +106,7 @@ func CountTokenMessages(ctx context.Context,
 	tokenNum := 0
 	var totalAudioTokens float64
 	var audioTokensPerSecond float64
+	documentPages := 0
 	audioTokensConfigured := false
 	for _, message := range messages {
 		tokenNum += tokensPerMessage
@@ -175,6 +177,19 @@ func CountTokenMessages(ctx context.Context,
 				} else {
 					totalAudioTokens += audioTokens
 				}
+			case model.ContentTypeDocument:
+				if content.Document == nil {
+					continue
+				}
+				// Oversized documents are refused by the adaptors, so they are
+				// not parsed here either
+				pages, err := content.Document.Pages(int64(config.MaxInlineImageSizeMB) * 1024 * 1024)
+				if err != nil {
+					lg.Error("error counting document pages", zap.Error(err))
+					continue
+				}
+				documentPages += pages
+				tokenNum += pages * model.DocumentTokensPerPage
 			}
 		}
 
@@ -187,6 +202,10 @@ func CountTokenMessages(ctx context.Context,
 		}
 	}
 	tokenNum += 3 // Every reply is primed with <|start|>assistant<|message|>
+	// Adaptors reuse the count rather than parsing the documents again
+	if c, ok := gmw.GetGinCtxFromStdCtx(ctx); ok && documentPages > 0 {
+		c.Set(ctxkey.DocumentPages, documentPages)
+	}
 	return tokenNum
 }
 
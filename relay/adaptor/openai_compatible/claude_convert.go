@@ -16,6 +16,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/relay/format"
 	relaymodel "github.com/songquanpeng/one-api/relay/model"
 )
 
@@ -35,11 +36,18 @@ func ConvertOpenAIResponseToClaudeResponse(_ *gin.Context, resp *http.Response)
 		return marshalClaudeHTTPResponse(resp, claudeResp)
 	}
 
-	// 2) Fallback: Chat Completions format
-	var chatResp chatTextResponse
-	if err := json.Unmarshal(body, &chatResp); err == nil && len(chatResp.Choices) > 0 {
-		claudeResp := chatResponseToClaude(&chatResp)
-		return marshalClaudeHTTPResponse(resp, claudeResp)
+	// 2) Fallback: Chat Completions format, converted with the shared function
+	// call normalizer so tool calls map to tool_use blocks
+	if detected, err := format.DetectResponseFormat(body); err == nil && detected == format.ChatCompletion {
+		normalized, err := format.NormalizeToOpenAI(body, format.ChatCompletion)
+		if err != nil {
+			return nil, ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+		}
+		claudeResp, err := format.FunctionCallNormalizer{}.OpenAIToClaude(normalized)
+		if err != nil {
+			return nil, ErrorWrapper(err, "convert_response_failed", http.StatusInternalServerError)
+		}
+		return marshalClaudeHTTPResponse(resp, *claudeResp)
 	}
 
 	// 3) Unknown format – return original payload (controller may handle error)
@@ -100,77 +108,6 @@ func responseAPIResponseToClaude(r *responseAPIResponse) relaymodel.ClaudeRespon
 	return out
 }
 
-// chatResponseToClaude maps OpenAI Chat Completion response to ClaudeMessages response
-func chatResponseToClaude(r *chatTextResponse) relaymodel.ClaudeResponse {
-	out := relaymodel.ClaudeResponse{
-		ID:         r.Id,
-		Type:       "message",
-		Role:       "assistant",
-		Model:      r.Model,
-		Content:    []relaymodel.ClaudeContent{},
-		StopReason: "end_turn",
-		Usage: relaymodel.ClaudeUsage{
-			InputTokens:  r.Usage.PromptTokens,
-			OutputTokens: r.Usage.CompletionTokens,
-		},
-	}
-
-	for _, choice := range r.Choices {
-		// Text content
-		if choice.Message.Content != nil {
-			switch content := choice.Message.Content.(type) {
-			case string:
-				if content != "" {
-					out.Content = append(out.Content, relaymodel.ClaudeContent{Type: "text", Text: content})
-				}
-			case []relaymodel.MessageContent:
-				for _, part := range content {
-					if part.Type == "text" && part.Text != nil && *part.Text != "" {
-						out.Content = append(out.Content, relaymodel.ClaudeContent{Type: "text", Text: *part.Text})
-					}
-				}
-			}
-		}
-
-		// Tool calls -> tool_use blocks
-		if len(choice.Message.ToolCalls) > 0 {
-			for _, tc := range choice.Message.ToolCalls {
-				var input json.RawMessage
-				if tc.Function.Arguments != nil {
-					switch v := tc.Function.Arguments.(type) {
-					case string:
-						input = json.RawMessage(v)
-					default:
-						if b, err := json.Marshal(v); err == nil {
-							input = json.RawMessage(b)
-						}
-					}
-				}
-				out.Content = append(out.Content, relaymodel.ClaudeContent{
-					Type:  "tool_use",
-					ID:    tc.Id,
-					Name:  tc.Function.Name,
-					Input: input,
-				})
-			}
-		}
-
-		// Map finish reason
-		switch choice.FinishReason {
-		case "stop":
-			out.StopReason = "end_turn"
-		case "length":
-			out.StopReason = "max_tokens"
-		case "tool_calls":
-			out.StopReason = "tool_use"
-		case "content_filter":
-			out.StopReason = "stop_sequence"
-		}
-	}
-
-	return out
-}
-
 func marshalClaudeHTTPResponse(orig *http.Response, payload relaymodel.ClaudeResponse) (*http.Response, *relaymodel.ErrorWithStatusCode) {
 	b, err := json.Marshal(payload)
 	if err != nil {
@@ -980,18 +917,3 @@ type responseAPIContent struct {
 	Text string          `json:"text,omitempty"`
 	JSON json.RawMessage `json:"json,omitempty"`
 }
-
-type chatTextResponse struct {
-	Id      string           `json:"id"`
-	Model   string           `json:"model"`
-	Object  string           `json:"object"`
-	Created int64            `json:"created"`
-	Choices []chatTextChoice `json:"choices"`
-	Usage   relaymodel.Usage `json:"usage"`
-}
-
-type chatTextChoice struct {
-	Index        int                `json:"index"`
-	Message      relaymodel.Message `json:"message"`
-	FinishReason string             `json:"finish_reason"`
-}
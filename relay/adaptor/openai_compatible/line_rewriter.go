@@ -0,0 +1,44 @@
+package openai_compatible
+
+import (
+	"bufio"
+	"io"
+)
+
+// lineRewriter passes a response body through line by line, replacing every
+// line with the result of rewrite.
+type lineRewriter struct {
+	src     *bufio.Reader
+	closer  io.Closer
+	rewrite func(line []byte) []byte
+	pending []byte
+	err     error
+}
+
+// NewLineRewriter wraps body so that each line read from it, including its
+// trailing newline, is passed to rewrite and replaced by the bytes it returns.
+// Returning the line unchanged forwards it as is; returning nil drops it. This
+// is meant for adaptors that normalize or inspect SSE data lines on the fly.
+func NewLineRewriter(body io.ReadCloser, rewrite func(line []byte) []byte) io.ReadCloser {
+	return &lineRewriter{src: bufio.NewReader(body), closer: body, rewrite: rewrite}
+}
+
+func (r *lineRewriter) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		var line []byte
+		line, r.err = r.src.ReadBytes('\n')
+		if len(line) > 0 {
+			r.pending = r.rewrite(line)
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *lineRewriter) Close() error {
+	return r.closer.Close()
+}
@@ -0,0 +1,29 @@
+package openai_compatible
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLineRewriter_RewritesAndDropsLines(t *testing.T) {
+	upstream := "data: {\"a\":1}\n\ndata: drop\n\ndata: [DONE]"
+	var seen []string
+	body := NewLineRewriter(io.NopCloser(strings.NewReader(upstream)), func(line []byte) []byte {
+		seen = append(seen, string(line))
+		if bytes.HasPrefix(line, []byte("data: drop")) {
+			return nil
+		}
+		return bytes.ToUpper(line)
+	})
+
+	out, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "DATA: {\"A\":1}\n\n\nDATA: [DONE]", string(out))
+	// the last line is passed on even without a trailing newline
+	require.Equal(t, []string{"data: {\"a\":1}\n", "\n", "data: drop\n", "\n", "data: [DONE]"}, seen)
+}
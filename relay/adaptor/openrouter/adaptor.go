@@ -288,6 +288,12 @@ func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return OpenRouterToolingDefaults
 }
 
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureStructuredOutput, adaptor.FeatureExtendedThinking)
+}
+
 func requiresStructuredDowngrade(modelName string) bool {
 	lower := strings.ToLower(strings.TrimSpace(modelName))
 	if lower == "" {
@@ -0,0 +1,112 @@
+package perplexity
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/relay/adaptor"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+// https://docs.perplexity.ai/api-reference/chat-completions
+
+// Adaptor relays requests to Perplexity. The API is OpenAI compatible, but
+// takes search options such as search_domain_filter, return_citations and
+// return_images, and answers with citations and images next to the choices.
+// Both are passed through untouched.
+type Adaptor struct {
+	adaptor.DefaultPricingMethods
+}
+
+func (a *Adaptor) Init(meta *meta.Meta) {
+}
+
+func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
+	path := meta.RequestURLPath
+	if idx := strings.Index(path, "?"); idx >= 0 {
+		path = path[:idx]
+	}
+	if path == "/v1/messages" {
+		// Claude Messages requests are converted to chat completions
+		path = "/v1/chat/completions"
+	}
+	// Perplexity serves the OpenAI routes without the /v1 prefix
+	path = strings.TrimPrefix(path, "/v1")
+	return openai_compatible.GetFullRequestURL(meta.BaseURL, path, meta.ChannelType), nil
+}
+
+func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Request, meta *meta.Meta) error {
+	adaptor.SetupCommonRequestHeader(c, req, meta)
+	req.Header.Set("Authorization", "Bearer "+meta.APIKey)
+	return nil
+}
+
+func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.GeneralOpenAIRequest) (any, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "get request body")
+	}
+	extra, err := extraFields(body)
+	if err != nil {
+		return nil, err
+	}
+	return Request{GeneralOpenAIRequest: request, Extra: extra}, nil
+}
+
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, request *model.ImageRequest) (any, error) {
+	return nil, errors.New("perplexity does not support image generation")
+}
+
+func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, request *model.ClaudeRequest) (any, error) {
+	return openai_compatible.ConvertClaudeRequest(c, request)
+}
+
+func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Reader) (*http.Response, error) {
+	return adaptor.DoRequestHelper(a, c, meta, requestBody)
+}
+
+func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
+	return openai_compatible.HandleClaudeMessagesResponse(c, resp, meta, func(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
+		if meta.IsStream {
+			return StreamHandler(c, resp, promptTokens, modelName)
+		}
+		return Handler(c, resp, promptTokens, modelName)
+	})
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return "perplexity"
+}
+
+// GetDefaultModelPricing returns the pricing information for Perplexity models
+func (a *Adaptor) GetDefaultModelPricing() map[string]adaptor.ModelConfig {
+	return ModelRatios
+}
+
+func (a *Adaptor) GetModelRatio(modelName string) float64 {
+	if price, exists := ModelRatios[modelName]; exists {
+		return price.Ratio
+	}
+	return a.DefaultPricingMethods.GetModelRatio(modelName)
+}
+
+func (a *Adaptor) GetCompletionRatio(modelName string) float64 {
+	if price, exists := ModelRatios[modelName]; exists {
+		return price.CompletionRatio
+	}
+	return a.DefaultPricingMethods.GetCompletionRatio(modelName)
+}
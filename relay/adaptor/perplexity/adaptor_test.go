@@ -0,0 +1,124 @@
+package perplexity
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+func newTestContext(t *testing.T, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	return c, w
+}
+
+func TestGetRequestURL(t *testing.T) {
+	t.Parallel()
+
+	adaptor := &Adaptor{}
+	for path, expected := range map[string]string{
+		"/v1/chat/completions":   "https://api.perplexity.ai/chat/completions",
+		"/v1/messages?beta=true": "https://api.perplexity.ai/chat/completions",
+	} {
+		url, err := adaptor.GetRequestURL(&meta.Meta{
+			RequestURLPath: path,
+			BaseURL:        channeltype.ChannelBaseURLs[channeltype.Perplexity],
+			ChannelType:    channeltype.Perplexity,
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, url, path)
+	}
+}
+
+func TestConvertRequestPassesThroughSearchOptions(t *testing.T) {
+	t.Parallel()
+
+	body := `{"model":"sonar-pro","messages":[{"role":"user","content":"news?"}],"reasoning_effort":"high",` +
+		`"search_domain_filter":["nature.com","-reddit.com"],"return_citations":true,"return_images":false,` +
+		`"search_recency_filter":{"unit":"week"}}`
+	c, _ := newTestContext(t, body)
+	var request model.GeneralOpenAIRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &request))
+	request.ReasoningEffort = nil
+
+	converted, err := (&Adaptor{}).ConvertRequest(c, relaymode.ChatCompletions, &request)
+	require.NoError(t, err)
+	payload, err := json.Marshal(converted)
+	require.NoError(t, err)
+
+	var sent map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(payload, &sent))
+	require.JSONEq(t, `["nature.com","-reddit.com"]`, string(sent["search_domain_filter"]))
+	require.JSONEq(t, `true`, string(sent["return_citations"]))
+	require.JSONEq(t, `false`, string(sent["return_images"]))
+	require.JSONEq(t, `{"unit":"week"}`, string(sent["search_recency_filter"]))
+	require.JSONEq(t, `"sonar-pro"`, string(sent["model"]))
+	// Known fields follow the converted request, not the raw body
+	require.NotContains(t, sent, "reasoning_effort")
+}
+
+func TestHandlerForwardsUnknownResponseFieldsUnchanged(t *testing.T) {
+	t.Parallel()
+
+	upstream := `{"id":"pplx-1","model":"sonar","created":1,"object":"chat.completion",` +
+		`"citations":["https://a.example","https://b.example"],` +
+		`"images":[{"image_url":"https://img.example/1.png","origin_url":"https://a.example"}],` +
+		`"search_results":[{"title":"A","url":"https://a.example"}],` +
+		`"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Answer [1][2]"}}],` +
+		`"usage":{"prompt_tokens":5,"completion_tokens":4,"total_tokens":9,"search_context_size":"low"}}`
+	c, w := newTestContext(t, "{}")
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(upstream)),
+	}
+
+	errResp, usage := Handler(c, resp, 1, "sonar")
+	require.Nil(t, errResp)
+	require.Equal(t, 5, usage.PromptTokens)
+	require.Equal(t, 4, usage.CompletionTokens)
+	require.Equal(t, upstream, w.Body.String())
+	require.Equal(t, 2, c.GetInt(ctxkey.CitationCount))
+}
+
+func TestStreamHandlerForwardsChunksUnchanged(t *testing.T) {
+	t.Parallel()
+
+	chunks := []string{
+		`{"id":"pplx-1","citations":["https://a.example"],"choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`,
+		`{"id":"pplx-1","citations":["https://a.example","https://b.example"],"images":[{"image_url":"https://img.example/1.png"}],"choices":[{"index":0,"delta":{"content":" there"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`,
+	}
+	var upstream strings.Builder
+	for _, chunk := range chunks {
+		upstream.WriteString("data: " + chunk + "\r\n\r\n")
+	}
+	c, w := newTestContext(t, "{}")
+	resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(upstream.String()))}
+
+	errResp, usage := StreamHandler(c, resp, 1, "sonar")
+	require.Nil(t, errResp)
+	require.Equal(t, 5, usage.PromptTokens)
+	require.Equal(t, 2, usage.CompletionTokens)
+	require.Equal(t, 2, c.GetInt(ctxkey.CitationCount))
+
+	body := w.Body.String()
+	for _, chunk := range chunks {
+		require.Contains(t, body, "data: "+chunk+"\n")
+	}
+	require.True(t, strings.HasSuffix(strings.TrimSpace(body), "data: [DONE]"))
+}
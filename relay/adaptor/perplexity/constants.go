@@ -0,0 +1,34 @@
+package perplexity
+
+import (
+	"github.com/songquanpeng/one-api/relay/adaptor"
+	"github.com/songquanpeng/one-api/relay/billing/ratio"
+)
+
+// ModelRatios contains all supported models and their pricing ratios
+// Model list is derived from the keys of this map, eliminating redundancy
+// Based on Perplexity pricing: https://docs.perplexity.ai/guides/pricing
+// Per-request search fees are not included.
+var ModelRatios = map[string]adaptor.ModelConfig{
+	// Sonar models
+	"sonar":               {Ratio: 1 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"sonar-pro":           {Ratio: 3 * ratio.MilliTokensUsd, CompletionRatio: 5},
+	"sonar-reasoning":     {Ratio: 1 * ratio.MilliTokensUsd, CompletionRatio: 5},
+	"sonar-reasoning-pro": {Ratio: 2 * ratio.MilliTokensUsd, CompletionRatio: 4},
+	"sonar-deep-research": {Ratio: 2 * ratio.MilliTokensUsd, CompletionRatio: 4},
+	"r1-1776":             {Ratio: 2 * ratio.MilliTokensUsd, CompletionRatio: 4},
+
+	// Legacy models, still accepted for existing integrations
+	"llama-3.1-sonar-small-128k-online": {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama-3.1-sonar-large-128k-online": {Ratio: 1 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama-3.1-sonar-huge-128k-online":  {Ratio: 5 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama-3.1-sonar-small-128k-chat":   {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"llama-3.1-sonar-large-128k-chat":   {Ratio: 1 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"sonar-small-chat":                  {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"sonar-small-online":                {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"sonar-medium-chat":                 {Ratio: 0.6 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"sonar-medium-online":               {Ratio: 0.6 * ratio.MilliTokensUsd, CompletionRatio: 1},
+}
+
+// ModelList derived from ModelRatios for backward compatibility
+var ModelList = adaptor.GetModelListFromPricing(ModelRatios)
@@ -0,0 +1,148 @@
+package perplexity
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/render"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+// Handler forwards a non-streaming Perplexity response to the client byte for
+// byte, so that citations, images and any fields added later reach it, and
+// returns the usage reported by upstream.
+func Handler(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return openai_compatible.ErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if err = resp.Body.Close(); err != nil {
+		return openai_compatible.ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+
+	var perplexityResponse Response
+	if err = json.Unmarshal(responseBody, &perplexityResponse); err != nil {
+		return openai_compatible.ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if perplexityResponse.Error != nil && perplexityResponse.Error.Type != "" {
+		if perplexityResponse.Error.RawError == nil {
+			perplexityResponse.Error.RawError = errors.New(perplexityResponse.Error.Message)
+		}
+		return &model.ErrorWithStatusCode{
+			Error:      *perplexityResponse.Error,
+			StatusCode: resp.StatusCode,
+		}, nil
+	}
+
+	var responseText strings.Builder
+	for _, choice := range perplexityResponse.Choices {
+		responseText.WriteString(choice.Message.StringContent())
+	}
+	usage := finalizeUsage(perplexityResponse.Usage, promptTokens, responseText.String(), modelName)
+	recordCitations(c, len(perplexityResponse.Citations))
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		c.Writer.Header().Set("Content-Type", contentType)
+	} else {
+		c.Writer.Header().Set("Content-Type", "application/json")
+	}
+	c.Writer.WriteHeader(resp.StatusCode)
+	if _, err = c.Writer.Write(responseBody); err != nil {
+		return openai_compatible.ErrorWrapper(err, "write_response_body_failed", http.StatusInternalServerError), nil
+	}
+	return nil, usage
+}
+
+// StreamHandler forwards the Perplexity SSE stream to the client chunk by
+// chunk, unchanged, and returns the usage of the last chunk that carried one.
+func StreamHandler(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
+	lg := gmw.GetLogger(c)
+	scanner := bufio.NewScanner(resp.Body)
+	buffer := make([]byte, 1024*1024) // 1MB buffer
+	scanner.Buffer(buffer, len(buffer))
+	scanner.Split(bufio.ScanLines)
+
+	common.SetEventStreamHeaders(c)
+	var upstreamUsage *model.Usage
+	var responseText strings.Builder
+	citations := 0
+	doneRendered := false
+
+	for scanner.Scan() {
+		data := openai_compatible.NormalizeDataLine(strings.TrimSuffix(scanner.Text(), "\r"))
+		if !strings.HasPrefix(data, openai_compatible.DataPrefix) {
+			continue
+		}
+		payload := data[openai_compatible.DataPrefixLength:]
+		if strings.HasPrefix(payload, openai_compatible.Done) {
+			render.StringData(c, data)
+			doneRendered = true
+			continue
+		}
+
+		var chunk StreamResponse
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			lg.Warn("failed to parse perplexity stream chunk, forwarding as is",
+				zap.String("chunk_data", payload),
+				zap.Error(err))
+		} else {
+			for _, choice := range chunk.Choices {
+				responseText.WriteString(choice.Delta.StringContent())
+			}
+			if chunk.Usage != nil {
+				upstreamUsage = chunk.Usage
+			}
+			citations = max(citations, len(chunk.Citations))
+		}
+		render.StringData(c, data)
+	}
+	if err := scanner.Err(); err != nil {
+		lg.Error("error reading perplexity stream", zap.Error(err))
+	}
+	if !doneRendered {
+		render.Done(c)
+	}
+	if err := resp.Body.Close(); err != nil {
+		return openai_compatible.ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+
+	recordCitations(c, citations)
+	return nil, finalizeUsage(upstreamUsage, promptTokens, responseText.String(), modelName)
+}
+
+// finalizeUsage returns the upstream usage, filling in the locally counted
+// tokens when upstream did not report them.
+func finalizeUsage(upstream *model.Usage, promptTokens int, responseText, modelName string) *model.Usage {
+	usage := &model.Usage{}
+	if upstream != nil {
+		*usage = *upstream
+	}
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = promptTokens
+	}
+	if usage.CompletionTokens == 0 {
+		usage.CompletionTokens = openai_compatible.CountTokenText(responseText, modelName)
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage
+}
+
+// recordCitations stores the citation count for the consume log.
+func recordCitations(c *gin.Context, citations int) {
+	if citations > 0 {
+		c.Set(ctxkey.CitationCount, citations)
+	}
+}
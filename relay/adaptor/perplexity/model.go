@@ -0,0 +1,96 @@
+package perplexity
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+// Request is a chat completion request together with the fields the client
+// sent that model.GeneralOpenAIRequest does not know, such as
+// search_domain_filter, return_citations and return_images. Extra is sent
+// upstream verbatim.
+type Request struct {
+	*model.GeneralOpenAIRequest
+	Extra map[string]json.RawMessage
+}
+
+// MarshalJSON writes the OpenAI fields followed by the extra fields. An extra
+// field never replaces an OpenAI field of the same name.
+func (r Request) MarshalJSON() ([]byte, error) {
+	body, err := json.Marshal(r.GeneralOpenAIRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal openai request")
+	}
+	if len(r.Extra) == 0 {
+		return body, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, errors.Wrap(err, "unmarshal openai request")
+	}
+	for key, value := range r.Extra {
+		if _, exists := fields[key]; !exists {
+			fields[key] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// knownRequestFields are the JSON names of the model.GeneralOpenAIRequest
+// fields, which are never copied into Request.Extra.
+var knownRequestFields = func() map[string]struct{} {
+	known := map[string]struct{}{}
+	t := reflect.TypeFor[model.GeneralOpenAIRequest]()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			known[name] = struct{}{}
+		}
+	}
+	return known
+}()
+
+// extraFields returns the top-level fields of the raw request body that
+// model.GeneralOpenAIRequest does not know.
+func extraFields(body []byte) (map[string]json.RawMessage, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, errors.Wrap(err, "unmarshal request body")
+	}
+	for key := range fields {
+		if _, known := knownRequestFields[key]; known {
+			delete(fields, key)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// Response holds the parts of a Perplexity chat completion the relay reads.
+// The response body itself is forwarded to the client unchanged.
+type Response struct {
+	Choices   []openai_compatible.TextResponseChoice `json:"choices"`
+	Usage     *model.Usage                           `json:"usage,omitempty"`
+	Error     *model.Error                           `json:"error,omitempty"`
+	Citations []json.RawMessage                      `json:"citations,omitempty"`
+}
+
+// StreamResponse holds the parts of a Perplexity stream chunk the relay reads.
+// Every chunk repeats the citations and the usage so far.
+type StreamResponse struct {
+	Choices   []openai_compatible.ChatCompletionsStreamResponseChoice `json:"choices"`
+	Usage     *model.Usage                                            `json:"usage,omitempty"`
+	Citations []json.RawMessage                                       `json:"citations,omitempty"`
+}
@@ -2,6 +2,7 @@ package replicate
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -96,11 +97,6 @@ func convertImageRemixRequest(c *gin.Context) (any, error) {
 
 // ConvertRequest converts the request to the format that the target API expects.
 func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.GeneralOpenAIRequest) (any, error) {
-	if !request.Stream {
-		// TODO: support non-stream mode
-		return nil, errors.Errorf("replicate models only support stream mode now, please set stream=true")
-	}
-
 	// Build the prompt from OpenAI messages
 	var promptBuilder strings.Builder
 	for _, message := range request.Messages {
@@ -115,6 +111,7 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 	}
 
 	replicateRequest := ReplicateChatRequest{
+		Stream: request.Stream,
 		Input: ChatInput{
 			Prompt:           promptBuilder.String(),
 			MaxTokens:        request.MaxTokens,
@@ -329,6 +326,90 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Met
 	return
 }
 
+// prediction is the state of a Replicate prediction shared by every model.
+//
+// https://replicate.com/docs/topics/predictions/lifecycle
+type prediction struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// recordPredictionID keeps the ID of the created prediction for the consume log.
+func recordPredictionID(c *gin.Context, id string) {
+	if id != "" {
+		c.Set(ctxkey.ReplicatePredictionID, id)
+	}
+}
+
+// pollPrediction gets the prediction at getURL every REPLICATE_POLL_INTERVAL_MS
+// until it succeeds, and returns the body of the succeeded prediction. It fails
+// when the prediction fails or is canceled, or is still running after
+// RelayTimeout seconds.
+func pollPrediction(c *gin.Context, getURL string) ([]byte, error) {
+	if getURL == "" {
+		return nil, errors.New("prediction get url is empty")
+	}
+	ctx := gmw.Ctx(c)
+	if config.RelayTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(config.RelayTimeout)*time.Second)
+		defer cancel()
+	}
+	interval := time.Duration(config.ReplicatePollIntervalMs) * time.Millisecond
+
+	status := new(prediction)
+	for {
+		body, err := getPrediction(ctx, c, getURL)
+		if ctx.Err() != nil {
+			return nil, errors.Wrapf(ctx.Err(), "prediction %s did not finish", status.ID)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "get prediction")
+		}
+		if err = json.Unmarshal(body, status); err != nil {
+			return nil, errors.Wrap(err, "decode prediction")
+		}
+
+		switch status.Status {
+		case "succeeded":
+			return body, nil
+		case "failed", "canceled":
+			return nil, errors.Errorf("prediction %s %s: %s", status.ID, status.Status, status.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "prediction %s did not finish", status.ID)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// getPrediction fetches the current state of a prediction.
+func getPrediction(ctx context.Context, c *gin.Context, getURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "new request")
+	}
+	req.Header.Set("Authorization", "Bearer "+meta.GetByContext(c).APIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "do request")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("bad status code [%d]%s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
 func (a *Adaptor) GetModelList() []string {
 	return adaptor.GetModelListFromPricing(ModelRatios)
 }
@@ -365,3 +446,9 @@ func (a *Adaptor) GetCompletionRatio(modelName string) float64 {
 func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return ReplicateToolingDefaults
 }
+
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.StandardModelFeatures()
+}
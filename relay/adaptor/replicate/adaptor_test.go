@@ -0,0 +1,120 @@
+package replicate
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/relay/meta"
+)
+
+// newPredictionServer serves a prediction that is processing for the first
+// two polls and then reaches finalStatus.
+func newPredictionServer(t *testing.T, finalStatus string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/predictions/pred-1", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer r8-key", r.Header.Get("Authorization"))
+		status := "processing"
+		if polls.Add(1) > 2 {
+			status = finalStatus
+		}
+		fmt.Fprintf(w, `{"id":"pred-1","status":%q,"error":"out of memory","output":["Hello",", world"],"created_at":"2024-01-01T00:00:00Z"}`, status)
+	})
+	mux.HandleFunc("/v1/predictions/pred-1/stream", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: output\nid: 1\ndata: Hello\n\nevent: output\nid: 2\ndata: , world\n\nevent: done\ndata: {}\n\n")
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &polls
+}
+
+func newReplicateTestContext(t *testing.T, server *httptest.Server, stream bool) (*gin.Context, *httptest.ResponseRecorder, *http.Response) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Set(ctxkey.Meta, &meta.Meta{APIKey: "r8-key", IsStream: stream, ActualModelName: "meta/meta-llama-3-8b-instruct"})
+
+	created := fmt.Sprintf(`{"id":"pred-1","status":"starting","urls":{"get":"%[1]s/v1/predictions/pred-1","stream":"%[1]s/v1/predictions/pred-1/stream"}}`, server.URL)
+	resp := &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(created))}
+	return c, recorder, resp
+}
+
+func withPollInterval(t *testing.T, relayTimeout int) {
+	t.Helper()
+	originalInterval, originalTimeout := config.ReplicatePollIntervalMs, config.RelayTimeout
+	originalApproximate := config.ApproximateTokenEnabled
+	config.ReplicatePollIntervalMs = 10
+	config.RelayTimeout = relayTimeout
+	// Count tokens without loading the tiktoken encoders
+	config.ApproximateTokenEnabled = true
+	t.Cleanup(func() {
+		config.ReplicatePollIntervalMs = originalInterval
+		config.RelayTimeout = originalTimeout
+		config.ApproximateTokenEnabled = originalApproximate
+	})
+}
+
+func TestChatHandlerPollsUntilSucceeded(t *testing.T) {
+	withPollInterval(t, 0)
+	server, polls := newPredictionServer(t, "succeeded")
+	c, recorder, resp := newReplicateTestContext(t, server, false)
+
+	srvErr, usage := ChatHandler(c, resp)
+	require.Nil(t, srvErr)
+	require.NotNil(t, usage)
+	require.EqualValues(t, 3, polls.Load())
+	require.Equal(t, "pred-1", c.GetString(ctxkey.ReplicatePredictionID))
+	require.Contains(t, recorder.Body.String(), `"content":"Hello, world"`)
+	require.Contains(t, recorder.Body.String(), `"object":"chat.completion"`)
+}
+
+func TestChatHandlerPredictionFailed(t *testing.T) {
+	withPollInterval(t, 0)
+	server, _ := newPredictionServer(t, "failed")
+	c, _, resp := newReplicateTestContext(t, server, false)
+
+	srvErr, _ := ChatHandler(c, resp)
+	require.NotNil(t, srvErr)
+	require.Contains(t, srvErr.Error.Message, "out of memory")
+	require.Equal(t, "pred-1", c.GetString(ctxkey.ReplicatePredictionID))
+}
+
+func TestChatHandlerPredictionTimeout(t *testing.T) {
+	withPollInterval(t, 1)
+	server, polls := newPredictionServer(t, "processing")
+	c, _, resp := newReplicateTestContext(t, server, false)
+
+	srvErr, _ := ChatHandler(c, resp)
+	require.NotNil(t, srvErr)
+	require.Contains(t, srvErr.Error.Message, "did not finish")
+	require.Greater(t, polls.Load(), int32(3))
+}
+
+func TestChatHandlerStream(t *testing.T) {
+	withPollInterval(t, 0)
+	server, polls := newPredictionServer(t, "succeeded")
+	c, recorder, resp := newReplicateTestContext(t, server, true)
+
+	srvErr, usage := ChatHandler(c, resp)
+	require.Nil(t, srvErr)
+	require.NotNil(t, usage)
+	// Streams start right away instead of waiting for the prediction
+	require.Zero(t, polls.Load())
+	require.Contains(t, recorder.Body.String(), "Hello")
+	require.Contains(t, recorder.Body.String(), "[DONE]")
+}
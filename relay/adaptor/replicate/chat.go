@@ -6,7 +6,6 @@ import (
 	"io"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/Laisky/errors/v2"
 	gmw "github.com/Laisky/gin-middlewares/v7"
@@ -19,6 +18,9 @@ import (
 	"github.com/songquanpeng/one-api/relay/model"
 )
 
+// ChatHandler handles a created chat prediction. Streaming requests relay the
+// prediction's server-sent events as they are generated; other requests wait
+// for the prediction to finish and answer with a chat completion.
 func ChatHandler(c *gin.Context, resp *http.Response) (
 	srvErr *model.ErrorWithStatusCode, usage *model.Usage) {
 	if resp.StatusCode != http.StatusCreated {
@@ -38,74 +40,44 @@ func ChatHandler(c *gin.Context, resp *http.Response) (
 	if err = json.Unmarshal(respBody, respData); err != nil {
 		return openai.ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
 	}
+	recordPredictionID(c, respData.ID)
 
-	for {
-		err = func() error {
-			// get task
-			taskReq, err := http.NewRequestWithContext(gmw.Ctx(c),
-				http.MethodGet, respData.URLs.Get, nil)
-			if err != nil {
-				return errors.Wrap(err, "new request")
-			}
-
-			taskReq.Header.Set("Authorization", "Bearer "+meta.GetByContext(c).APIKey)
-			taskResp, err := http.DefaultClient.Do(taskReq)
-			if err != nil {
-				return errors.Wrap(err, "get task")
-			}
-			defer taskResp.Body.Close()
-
-			if taskResp.StatusCode != http.StatusOK {
-				payload, _ := io.ReadAll(taskResp.Body)
-				return errors.Errorf("bad status code [%d]%s",
-					taskResp.StatusCode, string(payload))
-			}
-
-			taskBody, err := io.ReadAll(taskResp.Body)
-			if err != nil {
-				return errors.Wrap(err, "read task response")
-			}
-
-			taskData := new(ChatResponse)
-			if err = json.Unmarshal(taskBody, taskData); err != nil {
-				return errors.Wrap(err, "decode task response")
-			}
-
-			switch taskData.Status {
-			case "succeeded":
-			case "failed", "canceled":
-				return errors.Errorf("task failed, [%s]%s", taskData.Status, taskData.Error)
-			default:
-				time.Sleep(time.Second * 3)
-				return errNextLoop
-			}
-
-			if taskData.URLs.Stream == "" {
-				return errors.New("stream url is empty")
-			}
-
-			// request stream url
-			responseText, err := chatStreamHandler(c, taskData.URLs.Stream)
-			if err != nil {
-				return errors.Wrap(err, "chat stream handler")
-			}
-
-			ctxMeta := meta.GetByContext(c)
-			usage = openai.ResponseText2Usage(responseText,
-				ctxMeta.ActualModelName, ctxMeta.PromptTokens)
-			return nil
-		}()
+	ctxMeta := meta.GetByContext(c)
+	if ctxMeta.IsStream {
+		if respData.URLs.Stream == "" {
+			return openai.ErrorWrapper(errors.New("stream url is empty"), "chat_task_failed", http.StatusInternalServerError), nil
+		}
+		responseText, err := chatStreamHandler(c, respData.URLs.Stream)
 		if err != nil {
-			if errors.Is(err, errNextLoop) {
-				continue
-			}
+			return openai.ErrorWrapper(errors.Wrap(err, "chat stream handler"), "chat_task_failed", http.StatusInternalServerError), nil
+		}
+		return nil, openai.ResponseText2Usage(responseText, ctxMeta.ActualModelName, ctxMeta.PromptTokens)
+	}
 
+	if respData.Status != "succeeded" {
+		taskBody, err := pollPrediction(c, respData.URLs.Get)
+		if err != nil {
 			return openai.ErrorWrapper(err, "chat_task_failed", http.StatusInternalServerError), nil
 		}
-
-		break
+		respData = new(ChatResponse)
+		if err = json.Unmarshal(taskBody, respData); err != nil {
+			return openai.ErrorWrapper(errors.Wrap(err, "decode task response"), "chat_task_failed", http.StatusInternalServerError), nil
+		}
 	}
 
+	responseText := strings.Join(respData.Output, "")
+	usage = openai.ResponseText2Usage(responseText, ctxMeta.ActualModelName, ctxMeta.PromptTokens)
+	c.JSON(http.StatusOK, openai.TextResponse{
+		Id:      "chatcmpl-" + respData.ID,
+		Model:   ctxMeta.ActualModelName,
+		Object:  "chat.completion",
+		Created: respData.CreatedAt.Unix(),
+		Choices: []openai.TextResponseChoice{{
+			Message:      model.Message{Role: "assistant", Content: responseText},
+			FinishReason: "stop",
+		}},
+		Usage: *usage,
+	})
 	return nil, usage
 }
 
@@ -224,6 +224,8 @@ type FluxURLs struct {
 
 type ReplicateChatRequest struct {
 	Input ChatInput `json:"input" form:"input" binding:"required"`
+	// Stream asks Replicate for a server-sent events URL in the created prediction.
+	Stream bool `json:"stream,omitempty"`
 }
 
 // ChatInput is input of ChatByReplicateRequest
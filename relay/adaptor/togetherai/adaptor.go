@@ -7,10 +7,12 @@ import (
 	"github.com/Laisky/errors/v2"
 	"github.com/gin-gonic/gin"
 
+	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/relay/adaptor"
 	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
 	"github.com/songquanpeng/one-api/relay/meta"
 	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
 )
 
 type Adaptor struct {
@@ -44,16 +46,34 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 	if request.ReasoningEffort != nil {
 		request.ReasoningEffort = nil
 	}
+	if relayMode == relaymode.ChatCompletions || relayMode == relaymode.Completions {
+		if stop := mergeStopTokens(request.Stop, config.TogetherExtraStopTokens); stop != nil {
+			request.Stop = stop
+		}
+	}
 	return request, nil
 }
 
 func (a *Adaptor) ConvertImageRequest(c *gin.Context, request *model.ImageRequest) (any, error) {
-	return nil, errors.New("togetherai does not support image generation")
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	// TogetherAI serves image generation on the OpenAI-compatible endpoint
+	return request, nil
 }
 
 func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, request *model.ClaudeRequest) (any, error) {
 	// Use the shared OpenAI-compatible Claude Messages conversion
-	return openai_compatible.ConvertClaudeRequest(c, request)
+	converted, err := openai_compatible.ConvertClaudeRequest(c, request)
+	if err != nil {
+		return nil, err
+	}
+	if openaiRequest, ok := converted.(*model.GeneralOpenAIRequest); ok {
+		if stop := mergeStopTokens(openaiRequest.Stop, config.TogetherExtraStopTokens); stop != nil {
+			openaiRequest.Stop = stop
+		}
+	}
+	return converted, nil
 }
 
 func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Reader) (*http.Response, error) {
@@ -61,14 +81,43 @@ func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Read
 }
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
+	switch meta.Mode {
+	case relaymode.Embeddings:
+		err, usage = openai_compatible.EmbeddingHandler(c, resp)
+		return usage, err
+	case relaymode.ImagesGenerations:
+		return a.handleImageResponse(c, resp)
+	}
+
 	return openai_compatible.HandleClaudeMessagesResponse(c, resp, meta, func(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
 		if meta.IsStream {
+			resp.Body = newDoneNormalizingBody(resp.Body)
 			return openai_compatible.StreamHandler(c, resp, promptTokens, modelName)
 		}
 		return openai_compatible.Handler(c, resp, promptTokens, modelName)
 	})
 }
 
+// handleImageResponse passes the OpenAI-format image response through to the
+// client. Images are billed per image by the controller, so there is no usage.
+func (a *Adaptor) handleImageResponse(c *gin.Context, resp *http.Response) (usage *model.Usage, err *model.ErrorWithStatusCode) {
+	defer resp.Body.Close()
+	responseBody, readErr := io.ReadAll(resp.Body)
+	if readErr != nil {
+		return nil, openai_compatible.ErrorWrapper(readErr, "read_response_body_failed", http.StatusInternalServerError)
+	}
+
+	for k, v := range resp.Header {
+		c.Writer.Header().Set(k, v[0])
+	}
+	c.Writer.Header().Del("Content-Length")
+	c.Writer.WriteHeader(resp.StatusCode)
+	if _, writeErr := c.Writer.Write(responseBody); writeErr != nil {
+		return nil, openai_compatible.ErrorWrapper(writeErr, "write_response_body_failed", http.StatusInternalServerError)
+	}
+	return nil, nil
+}
+
 func (a *Adaptor) GetModelList() []string {
 	return ModelList
 }
@@ -106,3 +155,9 @@ func (a *Adaptor) GetCompletionRatio(modelName string) float64 {
 func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return TogetherAIToolingDefaults
 }
+
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureStructuredOutput)
+}
@@ -0,0 +1,121 @@
+package togetherai
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+func TestConvertRequestAppendsExtraStopTokens(t *testing.T) {
+	original := config.TogetherExtraStopTokens
+	config.TogetherExtraStopTokens = " <|eot_id|>, </s>,,"
+	t.Cleanup(func() { config.TogetherExtraStopTokens = original })
+
+	adaptor := &Adaptor{}
+	for _, tc := range []struct {
+		stop     any
+		expected []string
+	}{
+		{stop: nil, expected: []string{"<|eot_id|>", "</s>"}},
+		{stop: "###", expected: []string{"###", "<|eot_id|>", "</s>"}},
+		{stop: []any{"</s>", "END"}, expected: []string{"</s>", "END", "<|eot_id|>"}},
+	} {
+		converted, err := adaptor.ConvertRequest(nil, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{
+			Model: "mistralai/Mistral-7B-Instruct-v0.2",
+			Stop:  tc.stop,
+		})
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, converted.(*model.GeneralOpenAIRequest).Stop)
+	}
+
+	// All extra tokens already present leaves the request untouched
+	converted, err := adaptor.ConvertRequest(nil, relaymode.ChatCompletions, &model.GeneralOpenAIRequest{Stop: []string{"</s>", "<|eot_id|>"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"</s>", "<|eot_id|>"}, converted.(*model.GeneralOpenAIRequest).Stop)
+
+	// Embeddings have no stop parameter
+	converted, err = adaptor.ConvertRequest(nil, relaymode.Embeddings, &model.GeneralOpenAIRequest{})
+	require.NoError(t, err)
+	require.Nil(t, converted.(*model.GeneralOpenAIRequest).Stop)
+}
+
+func TestDoneNormalizingBody(t *testing.T) {
+	t.Parallel()
+
+	upstream := strings.Join([]string{
+		`data: {"choices":[{"delta":{"content":"hi"}}]}`,
+		``,
+		`data: [DONE] {"usage":{"prompt_tokens":3}}`,
+		``,
+		`data:[DONE]`,
+		``,
+	}, "\r\n")
+	body, err := io.ReadAll(newDoneNormalizingBody(io.NopCloser(strings.NewReader(upstream))))
+	require.NoError(t, err)
+	require.Equal(t, strings.Join([]string{
+		`data: {"choices":[{"delta":{"content":"hi"}}]}`,
+		``,
+		`data: [DONE]`,
+		``,
+		`data:[DONE]`,
+		``,
+	}, "\r\n"), string(body))
+}
+
+func TestModelCatalogue(t *testing.T) {
+	t.Parallel()
+
+	adaptor := &Adaptor{}
+	require.GreaterOrEqual(t, len(adaptor.GetModelList()), 70)
+	for name, cfg := range adaptor.GetDefaultModelPricing() {
+		require.Positive(t, cfg.Ratio, name)
+		require.Positive(t, cfg.CompletionRatio, name)
+	}
+	require.Contains(t, adaptor.GetModelList(), "mistralai/Mistral-7B-Instruct-v0.2")
+}
+
+func TestDoResponseRoutesEmbeddingsAndImages(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+	adaptor := &Adaptor{}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body: io.NopCloser(strings.NewReader(
+			`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]}],"model":"BAAI/bge-large-en-v1.5","usage":{"prompt_tokens":4,"total_tokens":4}}`)),
+	}
+	usage, errResp := adaptor.DoResponse(c, resp, &meta.Meta{Mode: relaymode.Embeddings})
+	require.Nil(t, errResp)
+	require.NotNil(t, usage)
+	require.Equal(t, 4, usage.PromptTokens)
+	require.Contains(t, w.Body.String(), `"embedding"`)
+
+	imageBody := `{"created":1,"data":[{"url":"https://example.com/a.png"}]}`
+	converted, err := adaptor.ConvertImageRequest(nil, &model.ImageRequest{Model: "black-forest-labs/FLUX.1-schnell", Prompt: "a cat"})
+	require.NoError(t, err)
+	require.Equal(t, "a cat", converted.(*model.ImageRequest).Prompt)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	resp = &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(imageBody)),
+	}
+	_, errResp = adaptor.DoResponse(c, resp, &meta.Meta{Mode: relaymode.ImagesGenerations})
+	require.Nil(t, errResp)
+	require.JSONEq(t, imageBody, w.Body.String())
+}
@@ -7,13 +7,103 @@ import (
 
 // ModelRatios contains all supported models and their pricing ratios
 // Model list is derived from the keys of this map, eliminating redundancy
-// Based on Together AI pricing: https://docs.together.ai/docs/inference-models
+// Based on Together AI serverless pricing: https://www.together.ai/pricing
 var ModelRatios = map[string]adaptor.ModelConfig{
-	// Together AI Models - Based on https://docs.together.ai/docs/inference-models
-	"meta-llama/Llama-3-70b-chat-hf":          {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
-	"deepseek-ai/deepseek-coder-33b-instruct": {Ratio: 0.8 * ratio.MilliTokensUsd, CompletionRatio: 1},
-	"mistralai/Mixtral-8x22B-Instruct-v0.1":   {Ratio: 1.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	// Meta Llama
+	"meta-llama/Llama-4-Maverick-17B-128E-Instruct-FP8": {Ratio: 0.27 * ratio.MilliTokensUsd, CompletionRatio: 0.85 / 0.27},
+	"meta-llama/Llama-4-Scout-17B-16E-Instruct":         {Ratio: 0.18 * ratio.MilliTokensUsd, CompletionRatio: 0.59 / 0.18},
+	"meta-llama/Llama-3.3-70B-Instruct-Turbo":           {Ratio: 0.88 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Meta-Llama-3.1-405B-Instruct-Turbo":     {Ratio: 3.5 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Meta-Llama-3.1-70B-Instruct-Turbo":      {Ratio: 0.88 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Meta-Llama-3.1-8B-Instruct-Turbo":       {Ratio: 0.18 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Llama-3.2-3B-Instruct-Turbo":            {Ratio: 0.06 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Llama-3.2-11B-Vision-Instruct-Turbo":    {Ratio: 0.18 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Llama-3.2-90B-Vision-Instruct-Turbo":    {Ratio: 1.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Meta-Llama-3-70B-Instruct-Turbo":        {Ratio: 0.88 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Meta-Llama-3-8B-Instruct-Turbo":         {Ratio: 0.18 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Meta-Llama-3-70B-Instruct-Lite":         {Ratio: 0.54 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Meta-Llama-3-8B-Instruct-Lite":          {Ratio: 0.1 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Llama-3-70b-chat-hf":                    {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Llama-3-8b-chat-hf":                     {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Llama-2-70b-hf":                         {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Llama-2-13b-chat-hf":                    {Ratio: 0.22 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Llama-2-7b-chat-hf":                     {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Llama-Guard-4-12B":                      {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Meta-Llama-Guard-3-8B":                  {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/Llama-Guard-3-11B-Vision-Turbo":         {Ratio: 0.18 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"meta-llama/LlamaGuard-2-8b":                        {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+
+	// DeepSeek
+	"deepseek-ai/DeepSeek-R1":                   {Ratio: 3 * ratio.MilliTokensUsd, CompletionRatio: 7.0 / 3},
+	"deepseek-ai/DeepSeek-R1-0528-tput":         {Ratio: 0.55 * ratio.MilliTokensUsd, CompletionRatio: 2.19 / 0.55},
+	"deepseek-ai/DeepSeek-V3":                   {Ratio: 1.25 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"deepseek-ai/DeepSeek-V3.1":                 {Ratio: 0.6 * ratio.MilliTokensUsd, CompletionRatio: 1.7 / 0.6},
+	"deepseek-ai/DeepSeek-R1-Distill-Llama-70B": {Ratio: 2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"deepseek-ai/DeepSeek-R1-Distill-Qwen-14B":  {Ratio: 1.6 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"deepseek-ai/DeepSeek-R1-Distill-Qwen-1.5B": {Ratio: 0.18 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"deepseek-ai/deepseek-coder-33b-instruct":   {Ratio: 0.8 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"deepseek-ai/deepseek-llm-67b-chat":         {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+
+	// Other open-weight chat models
+	"deepcogito/cogito-v2-preview-llama-70B":      {Ratio: 0.88 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"deepcogito/cogito-v2-preview-llama-405B":     {Ratio: 3.5 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"nvidia/Llama-3.1-Nemotron-70B-Instruct-HF":   {Ratio: 0.88 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"NousResearch/Nous-Hermes-2-Mixtral-8x7B-DPO": {Ratio: 0.6 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"marin-community/marin-8b-instruct":           {Ratio: 0.18 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"togethercomputer/StripedHyena-Nous-7B":       {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"upstage/SOLAR-10.7B-Instruct-v1.0":           {Ratio: 0.3 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"databricks/dbrx-instruct":                    {Ratio: 1.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"microsoft/WizardLM-2-8x22B":                  {Ratio: 1.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"Gryphe/MythoMax-L2-13b":                      {Ratio: 0.3 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"Gryphe/MythoMax-L2-13b-Lite":                 {Ratio: 0.1 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"arcee-ai/virtuoso-large":                     {Ratio: 0.75 * ratio.MilliTokensUsd, CompletionRatio: 1.2 / 0.75},
+	"arcee-ai/coder-large":                        {Ratio: 0.5 * ratio.MilliTokensUsd, CompletionRatio: 0.8 / 0.5},
+	"arcee-ai/maestro-reasoning":                  {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 3.3 / 0.9},
+
+	// Mistral
+	"mistralai/Mistral-7B-Instruct-v0.1":        {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"mistralai/Mistral-7B-Instruct-v0.2":        {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"mistralai/Mistral-7B-Instruct-v0.3":        {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"mistralai/Mixtral-8x7B-Instruct-v0.1":      {Ratio: 0.6 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"mistralai/Mixtral-8x22B-Instruct-v0.1":     {Ratio: 1.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"mistralai/Mistral-Small-24B-Instruct-2501": {Ratio: 0.8 * ratio.MilliTokensUsd, CompletionRatio: 1},
+
+	// Google Gemma
+	"google/gemma-2-27b-it":  {Ratio: 0.8 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"google/gemma-2-9b-it":   {Ratio: 0.3 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"google/gemma-2b-it":     {Ratio: 0.1 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"google/gemma-3n-E4B-it": {Ratio: 0.02 * ratio.MilliTokensUsd, CompletionRatio: 2},
+
+	// OpenAI, Moonshot and Z.ai open-weight models
+	"openai/gpt-oss-120b":              {Ratio: 0.15 * ratio.MilliTokensUsd, CompletionRatio: 4},
+	"openai/gpt-oss-20b":               {Ratio: 0.05 * ratio.MilliTokensUsd, CompletionRatio: 4},
+	"moonshotai/Kimi-K2-Instruct":      {Ratio: 1 * ratio.MilliTokensUsd, CompletionRatio: 3},
+	"moonshotai/Kimi-K2-Instruct-0905": {Ratio: 1 * ratio.MilliTokensUsd, CompletionRatio: 3},
+	"moonshotai/Kimi-K2-Thinking":      {Ratio: 1.2 * ratio.MilliTokensUsd, CompletionRatio: 4 / 1.2},
+	"zai-org/GLM-4.5-Air-FP8":          {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 1.1 / 0.2},
+	"zai-org/GLM-4.6":                  {Ratio: 0.6 * ratio.MilliTokensUsd, CompletionRatio: 2.2 / 0.6},
+
+	// Qwen
+	"Qwen/Qwen3-235B-A22B-Instruct-2507-tput": {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 3},
+	"Qwen/Qwen3-235B-A22B-Thinking-2507":      {Ratio: 0.65 * ratio.MilliTokensUsd, CompletionRatio: 3 / 0.65},
+	"Qwen/Qwen3-235B-A22B-fp8-tput":           {Ratio: 0.2 * ratio.MilliTokensUsd, CompletionRatio: 3},
+	"Qwen/Qwen3-Coder-480B-A35B-Instruct-FP8": {Ratio: 2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"Qwen/Qwen3-Next-80B-A3B-Instruct":        {Ratio: 0.15 * ratio.MilliTokensUsd, CompletionRatio: 10},
+	"Qwen/Qwen3-Next-80B-A3B-Thinking":        {Ratio: 0.15 * ratio.MilliTokensUsd, CompletionRatio: 10},
+	"Qwen/Qwen2.5-72B-Instruct-Turbo":         {Ratio: 1.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"Qwen/Qwen2.5-7B-Instruct-Turbo":          {Ratio: 0.3 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"Qwen/Qwen2.5-Coder-32B-Instruct":         {Ratio: 0.8 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"Qwen/Qwen2.5-VL-72B-Instruct":            {Ratio: 1.95 * ratio.MilliTokensUsd, CompletionRatio: 8 / 1.95},
+	"Qwen/QwQ-32B":                            {Ratio: 1.2 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"Qwen/Qwen2-72B-Instruct":                 {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"Qwen/Qwen1.5-110B-Chat":                  {Ratio: 1.8 * ratio.MilliTokensUsd, CompletionRatio: 1},
 	"Qwen/Qwen1.5-72B-Chat":                   {Ratio: 0.9 * ratio.MilliTokensUsd, CompletionRatio: 1},
+
+	// Embedding models
+	"BAAI/bge-large-en-v1.5":                     {Ratio: 0.02 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"BAAI/bge-base-en-v1.5":                      {Ratio: 0.008 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"togethercomputer/m2-bert-80M-32k-retrieval": {Ratio: 0.008 * ratio.MilliTokensUsd, CompletionRatio: 1},
+	"intfloat/multilingual-e5-large-instruct":    {Ratio: 0.02 * ratio.MilliTokensUsd, CompletionRatio: 1},
 }
 
 // ModelList derived from ModelRatios for backward compatibility
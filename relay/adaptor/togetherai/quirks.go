@@ -0,0 +1,66 @@
+package togetherai
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/songquanpeng/one-api/relay/adaptor/openai_compatible"
+)
+
+// mergeStopTokens returns the stop sequences of the request followed by the
+// comma-separated extra tokens that are not already present, or nil when there
+// are no extra tokens to add.
+func mergeStopTokens(stop any, extra string) []string {
+	var merged []string
+	switch v := stop.(type) {
+	case string:
+		if v != "" {
+			merged = append(merged, v)
+		}
+	case []string:
+		merged = append(merged, v...)
+	case []any:
+		for _, item := range v {
+			if str, ok := item.(string); ok && str != "" {
+				merged = append(merged, str)
+			}
+		}
+	}
+
+	added := false
+	for _, token := range strings.Split(extra, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" || slices.Contains(merged, token) {
+			continue
+		}
+		merged = append(merged, token)
+		added = true
+	}
+	if !added {
+		return nil
+	}
+	return merged
+}
+
+// newDoneNormalizingBody rewrites SSE lines such as `data: [DONE] {"usage":...}`,
+// which Together AI occasionally sends, into a bare `data: [DONE]` so clients
+// comparing the payload against [DONE] terminate the stream.
+func newDoneNormalizingBody(body io.ReadCloser) io.ReadCloser {
+	return openai_compatible.NewLineRewriter(body, normalizeDoneLine)
+}
+
+// normalizeDoneLine strips anything that follows [DONE] on a data line.
+func normalizeDoneLine(line []byte) []byte {
+	content := bytes.TrimRight(line, "\r\n")
+	payload, ok := bytes.CutPrefix(content, []byte("data:"))
+	if !ok {
+		return line
+	}
+	payload = bytes.TrimLeft(payload, " ")
+	if !bytes.HasPrefix(payload, []byte("[DONE]")) || len(payload) == len("[DONE]") {
+		return line
+	}
+	return append([]byte("data: [DONE]"), line[len(content):]...)
+}
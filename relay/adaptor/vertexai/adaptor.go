@@ -295,6 +295,12 @@ func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return VertexAIToolingDefaults
 }
 
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureStructuredOutput, adaptor.FeatureExtendedThinking)
+}
+
 // ModelEndpointType represents different endpoint types for VertexAI models
 type ModelEndpointType int
 
@@ -38,7 +38,13 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 		return nil, errors.New("request is nil")
 	}
 
-	geminiRequest := gemini.ConvertRequest(*request)
+	geminiRequest, err := gemini.ConvertRequest(*request)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert request")
+	}
+	if pages := gemini.CountDocumentPages(c, request.Messages); pages > 0 {
+		c.Set(ctxkey.DocumentPages, pages)
+	}
 	c.Set(ctxkey.RequestModel, request.Model)
 	c.Set(ctxkey.ConvertedRequest, geminiRequest)
 	return geminiRequest, nil
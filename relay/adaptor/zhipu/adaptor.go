@@ -6,9 +6,11 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Laisky/errors/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
 
 	"github.com/songquanpeng/one-api/common/ctxkey"
 	"github.com/songquanpeng/one-api/common/helper"
@@ -19,10 +21,41 @@ import (
 	"github.com/songquanpeng/one-api/relay/relaymode"
 )
 
+// jwtExpiry is the lifetime of the JWT signed for each upstream request.
+const jwtExpiry = 30 * time.Second
+
 type Adaptor struct {
 	APIVersion string
 }
 
+// GenerateJWT signs the short-lived token Zhipu expects in the Authorization
+// header. apiKey has the form "id.secret": the id is the issuer and the
+// secret the HMAC-SHA256 key. Tokens expire after jwtExpiry, so a new one is
+// generated for every request.
+func GenerateJWT(apiKey string) (string, error) {
+	id, secret, ok := strings.Cut(apiKey, ".")
+	if !ok || id == "" || secret == "" || strings.Contains(secret, ".") {
+		return "", errors.New("invalid zhipu api key, expected id.secret")
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":     id,
+		"api_key": id,
+		// Zhipu expects millisecond timestamps
+		"exp":       now.Add(jwtExpiry).UnixMilli(),
+		"timestamp": now.UnixMilli(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["sign_type"] = "SIGN"
+
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", errors.Wrap(err, "sign zhipu jwt")
+	}
+	return signed, nil
+}
+
 func (a *Adaptor) Init(meta *meta.Meta) {
 
 }
@@ -55,7 +88,10 @@ func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
 
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Request, meta *meta.Meta) error {
 	adaptor.SetupCommonRequestHeader(c, req, meta)
-	token := GetToken(meta.APIKey)
+	token, err := GenerateJWT(meta.APIKey)
+	if err != nil {
+		return errors.Wrap(err, "generate zhipu token")
+	}
 	req.Header.Set("Authorization", token)
 	return nil
 }
@@ -78,7 +114,7 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 		request.Temperature = helper.Float64PtrMin(request.Temperature, 0)
 		a.SetVersionByModeName(request.Model)
 		if a.APIVersion == "v4" {
-			return request, nil
+			return ConvertChatCompletionRequest(request), nil
 		}
 		return ConvertRequest(*request), nil
 	}
@@ -325,3 +361,9 @@ func (a *Adaptor) GetCompletionRatio(modelName string) float64 {
 func (a *Adaptor) DefaultToolingConfig() adaptor.ChannelToolConfig {
 	return ZhipuToolingDefaults
 }
+
+// DefaultModelFeatures declares the gated request features the models of
+// this adaptor accept.
+func (a *Adaptor) DefaultModelFeatures() adaptor.ModelFeatureFlags {
+	return adaptor.WithModelFeatures(adaptor.FeatureVision, adaptor.FeatureExtendedThinking)
+}
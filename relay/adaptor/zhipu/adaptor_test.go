@@ -1,11 +1,16 @@
 package zhipu
 
 import (
+	"encoding/json"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
 
+	"github.com/songquanpeng/one-api/relay/meta"
 	"github.com/songquanpeng/one-api/relay/model"
 	"github.com/songquanpeng/one-api/relay/relaymode"
 )
@@ -38,17 +43,18 @@ func TestConvertRequestClampsParametersV4(t *testing.T) {
 		t.Fatalf("ConvertRequest returned error: %v", err)
 	}
 
-	converted, ok := convertedAny.(*model.GeneralOpenAIRequest)
+	converted, ok := convertedAny.(*ChatCompletionRequest)
 	if !ok {
-		t.Fatalf("expected v4 conversion to return GeneralOpenAIRequest, got %T", convertedAny)
+		t.Fatalf("expected v4 conversion to return *ChatCompletionRequest, got %T", convertedAny)
 	}
 
 	if converted.TopP == nil || *converted.TopP != 1 {
 		t.Fatalf("expected TopP to be clamped to 1, got %v", converted.TopP)
 	}
 
-	if converted.Temperature == nil || *converted.Temperature != 0 {
-		t.Fatalf("expected Temperature to be clamped to 0, got %v", converted.Temperature)
+	// Temperature clamped to 0 becomes greedy decoding
+	if converted.Temperature != nil || converted.DoSample == nil || *converted.DoSample {
+		t.Fatalf("expected do_sample=false without temperature, got temperature %v do_sample %v", converted.Temperature, converted.DoSample)
 	}
 }
 
@@ -80,3 +86,116 @@ func TestConvertRequestClampsParametersV3(t *testing.T) {
 		t.Fatalf("expected Temperature to be clamped to 1, got %v", converted.Temperature)
 	}
 }
+
+func parseZhipuJWT(t *testing.T, token, secret string) jwt.MapClaims {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	parsed, err := parser.ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
+		return []byte(secret), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "HS256", parsed.Header["alg"])
+	require.Equal(t, "SIGN", parsed.Header["sign_type"])
+	return claims
+}
+
+func TestGenerateJWT(t *testing.T) {
+	before := time.Now().UnixMilli()
+	token, err := GenerateJWT("my-id.my-secret")
+	require.NoError(t, err)
+	after := time.Now().UnixMilli()
+
+	claims := parseZhipuJWT(t, token, "my-secret")
+	require.Equal(t, "my-id", claims["iss"])
+	require.Equal(t, "my-id", claims["api_key"])
+
+	issuedAt := int64(claims["timestamp"].(float64))
+	expiresAt := int64(claims["exp"].(float64))
+	require.GreaterOrEqual(t, issuedAt, before)
+	require.LessOrEqual(t, issuedAt, after)
+	require.Equal(t, jwtExpiry.Milliseconds(), expiresAt-issuedAt)
+
+	// Signatures made with another secret are rejected
+	_, err = (&jwt.Parser{SkipClaimsValidation: true}).Parse(token, func(*jwt.Token) (any, error) {
+		return []byte("other-secret"), nil
+	})
+	require.Error(t, err)
+}
+
+func TestGenerateJWT_FreshTokenPerRequest(t *testing.T) {
+	first, err := GenerateJWT("id.secret")
+	require.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+	second, err := GenerateJWT("id.secret")
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+
+	// Each token expires 30 seconds after it was issued, never later
+	for _, token := range []string{first, second} {
+		claims := parseZhipuJWT(t, token, "secret")
+		require.LessOrEqual(t, int64(claims["exp"].(float64)), time.Now().Add(jwtExpiry).UnixMilli())
+	}
+}
+
+func TestGenerateJWT_InvalidKeys(t *testing.T) {
+	for _, key := range []string{"", "no-separator", ".secret", "id.", "id.secret.extra"} {
+		_, err := GenerateJWT(key)
+		require.Error(t, err, key)
+	}
+}
+
+func TestSetupRequestHeaderSignsEveryRequest(t *testing.T) {
+	c := newZhipuContext()
+	req := httptest.NewRequest("POST", "/api/paas/v4/chat/completions", nil)
+	require.NoError(t, (&Adaptor{}).SetupRequestHeader(c, req, &meta.Meta{APIKey: "id.secret"}))
+	claims := parseZhipuJWT(t, req.Header.Get("Authorization"), "secret")
+	require.Equal(t, "id", claims["iss"])
+
+	require.Error(t, (&Adaptor{}).SetupRequestHeader(c, req, &meta.Meta{APIKey: "invalid"}))
+}
+
+func TestConvertChatCompletionRequest(t *testing.T) {
+	maxCompletion := 256
+	converted := ConvertChatCompletionRequest(&model.GeneralOpenAIRequest{
+		Model:               "glm-4-plus",
+		Messages:            []model.Message{{Role: "user", Content: "hi"}},
+		Temperature:         float64PtrZhipu(0),
+		TopP:                float64PtrZhipu(0.7),
+		MaxTokens:           100,
+		MaxCompletionTokens: &maxCompletion,
+		Stream:              true,
+		User:                "user-1",
+	})
+	body, err := json.Marshal(converted)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"model": "glm-4-plus",
+		"messages": [{"role": "user", "content": "hi"}],
+		"do_sample": false,
+		"top_p": 0.7,
+		"stream": true,
+		"max_tokens": 256,
+		"user_id": "user-1"
+	}`, string(body))
+
+	// Sampling is left to upstream defaults for any other temperature
+	converted = ConvertChatCompletionRequest(&model.GeneralOpenAIRequest{
+		Model:       "glm-4-plus",
+		Temperature: float64PtrZhipu(0.3),
+	})
+	require.Nil(t, converted.DoSample)
+	require.Equal(t, 0.3, *converted.Temperature)
+}
+
+func TestResponseZhipu2OpenAIMapsUsage(t *testing.T) {
+	response := &Response{Data: ResponseData{
+		TaskId:  "task-1",
+		Choices: []Message{{Role: "assistant", Content: `"hello"`}},
+		Usage:   model.Usage{PromptTokens: 12, CompletionTokens: 5, TotalTokens: 17},
+	}}
+	converted := responseZhipu2OpenAI(response)
+	require.Equal(t, model.Usage{PromptTokens: 12, CompletionTokens: 5, TotalTokens: 17}, converted.Usage)
+	require.Equal(t, "hello", converted.Choices[0].Message.Content)
+	require.Equal(t, "task-1", converted.Id)
+}
@@ -68,13 +68,16 @@ var ModelRatios = map[string]adaptor.ModelConfig{
 	// GLM Zero Models
 	"glm-zero-preview": {Ratio: 0.7 * ratio.MilliTokensRmb, CompletionRatio: 1},
 
+	// GLM-4 Models
+	"glm-4-plus": {Ratio: 5 * ratio.MilliTokensRmb, CompletionRatio: 1}, // CNY 5/1M tokens
+
 	// GLM-3 Models
 	"glm-3-turbo": {Ratio: 0.005 * ratio.MilliTokensRmb, CompletionRatio: 1},
 
 	// GLM Vision Models
 	"glm-4v-plus":  {Ratio: 0.1 * ratio.MilliTokensRmb, CompletionRatio: 1},
 	"glm-4v":       {Ratio: 0.05 * ratio.MilliTokensRmb, CompletionRatio: 1},
-	"glm-4v-flash": {Ratio: 0.001 * ratio.MilliTokensRmb, CompletionRatio: 1},
+	"glm-4v-flash": {Ratio: 0, CompletionRatio: 1}, // free
 
 	// CogView Image Models
 	"cogview-3-plus":  {Ratio: 0.08 * ratio.MilliTokensRmb, CompletionRatio: 1},
@@ -86,7 +89,7 @@ var ModelRatios = map[string]adaptor.ModelConfig{
 	// Character and Code Models
 	"charglm-4":  {Ratio: 0.1 * ratio.MilliTokensRmb, CompletionRatio: 1},
 	"emohaa":     {Ratio: 0.1 * ratio.MilliTokensRmb, CompletionRatio: 1},
-	"codegeex-4": {Ratio: 0.001 * ratio.MilliTokensRmb, CompletionRatio: 1},
+	"codegeex-4": {Ratio: 0.1 * ratio.MilliTokensRmb, CompletionRatio: 1}, // CNY 0.1/1M tokens
 
 	// Embedding Models
 	"embedding-3": {Ratio: 0.0005 * ratio.MilliTokensRmb, CompletionRatio: 1},
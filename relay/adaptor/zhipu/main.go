@@ -6,14 +6,11 @@ import (
 	"io"
 	"net/http"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/Laisky/errors/v2"
 	gmw "github.com/Laisky/gin-middlewares/v7"
 	"github.com/Laisky/zap"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 
 	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/helper"
@@ -28,56 +25,6 @@ import (
 // https://open.bigmodel.cn/api/paas/v3/model-api/chatglm_std/invoke
 // https://open.bigmodel.cn/api/paas/v3/model-api/chatglm_std/sse-invoke
 
-var zhipuTokens sync.Map
-var expSeconds int64 = 24 * 3600
-
-func GetToken(apikey string) string {
-	data, ok := zhipuTokens.Load(apikey)
-	if ok {
-		tokenData := data.(tokenData)
-		if time.Now().Before(tokenData.ExpiryTime) {
-			return tokenData.Token
-		}
-	}
-
-	split := strings.Split(apikey, ".")
-	if len(split) != 2 {
-		// invalid zhipu key
-		return ""
-	}
-
-	id := split[0]
-	secret := split[1]
-
-	expMillis := time.Now().Add(time.Duration(expSeconds)*time.Second).UnixNano() / 1e6
-	expiryTime := time.Now().Add(time.Duration(expSeconds) * time.Second)
-
-	timestamp := time.Now().UnixNano() / 1e6
-
-	payload := jwt.MapClaims{
-		"api_key":   id,
-		"exp":       expMillis,
-		"timestamp": timestamp,
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-
-	token.Header["alg"] = "HS256"
-	token.Header["sign_type"] = "SIGN"
-
-	tokenString, err := token.SignedString([]byte(secret))
-	if err != nil {
-		return ""
-	}
-
-	zhipuTokens.Store(apikey, tokenData{
-		Token:      tokenString,
-		ExpiryTime: expiryTime,
-	})
-
-	return tokenString
-}
-
 func ConvertRequest(request model.GeneralOpenAIRequest) *Request {
 	messages := make([]Message, 0, len(request.Messages))
 	for _, message := range request.Messages {
@@ -94,6 +41,35 @@ func ConvertRequest(request model.GeneralOpenAIRequest) *Request {
 	}
 }
 
+// ConvertChatCompletionRequest converts an OpenAI request into the v4 chat
+// completions format. A zero temperature asks for greedy decoding, which
+// Zhipu expresses as do_sample=false.
+func ConvertChatCompletionRequest(request *model.GeneralOpenAIRequest) *ChatCompletionRequest {
+	converted := &ChatCompletionRequest{
+		Model:          request.Model,
+		Messages:       request.Messages,
+		Stream:         request.Stream,
+		Temperature:    request.Temperature,
+		TopP:           request.TopP,
+		MaxTokens:      request.MaxTokens,
+		Stop:           request.Stop,
+		Tools:          request.Tools,
+		ToolChoice:     request.ToolChoice,
+		ResponseFormat: request.ResponseFormat,
+		Thinking:       request.Thinking,
+		UserId:         request.User,
+	}
+	if request.MaxCompletionTokens != nil {
+		converted.MaxTokens = *request.MaxCompletionTokens
+	}
+	if request.Temperature != nil && *request.Temperature == 0 {
+		doSample := false
+		converted.DoSample = &doSample
+		converted.Temperature = nil
+	}
+	return converted
+}
+
 func responseZhipu2OpenAI(response *Response) *openai.TextResponse {
 	fullTextResponse := openai.TextResponse{
 		Id:      response.Data.TaskId,
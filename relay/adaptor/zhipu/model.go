@@ -1,8 +1,6 @@
 package zhipu
 
 import (
-	"time"
-
 	"github.com/songquanpeng/one-api/relay/model"
 )
 
@@ -34,6 +32,26 @@ type Response struct {
 	Data    ResponseData `json:"data"`
 }
 
+// ChatCompletionRequest is the request body of the v4 chat completions API.
+// It mirrors the OpenAI format, except that greedy decoding is requested with
+// do_sample=false rather than temperature=0.
+type ChatCompletionRequest struct {
+	Model          string                `json:"model"`
+	Messages       []model.Message       `json:"messages"`
+	RequestId      string                `json:"request_id,omitempty"`
+	DoSample       *bool                 `json:"do_sample,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Stop           any                   `json:"stop,omitempty"`
+	Tools          []model.Tool          `json:"tools,omitempty"`
+	ToolChoice     any                   `json:"tool_choice,omitempty"`
+	ResponseFormat *model.ResponseFormat `json:"response_format,omitempty"`
+	Thinking       *model.Thinking       `json:"thinking,omitempty"`
+	UserId         string                `json:"user_id,omitempty"`
+}
+
 type StreamMetaResponse struct {
 	RequestId   string `json:"request_id"`
 	TaskId      string `json:"task_id"`
@@ -41,11 +59,6 @@ type StreamMetaResponse struct {
 	model.Usage `json:"usage"`
 }
 
-type tokenData struct {
-	Token      string
-	ExpiryTime time.Time
-}
-
 type EmbeddingRequest struct {
 	Model string `json:"model"`
 	Input string `json:"input"`
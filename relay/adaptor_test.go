@@ -6,6 +6,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/songquanpeng/one-api/relay/apitype"
+	"github.com/songquanpeng/one-api/relay/channeltype"
 )
 
 func TestGetAdaptor(t *testing.T) {
@@ -15,4 +16,8 @@ func TestGetAdaptor(t *testing.T) {
 			So(a, ShouldNotBeNil)
 		}
 	})
+	Convey("novita channels use the novita adaptor", t, func() {
+		a := GetAdaptor(channeltype.ToAPIType(channeltype.Novita))
+		So(a.GetChannelName(), ShouldEqual, "novita")
+	})
 }
@@ -26,6 +26,11 @@ const (
 	Moonshot
 	XAI
 	OpenRouter
+	Minimax
+	TogetherAI
+	Perplexity
+	Fireworks
+	Novita
 
 	Dummy // this one is only for count, do not add any channel after this
 )
@@ -0,0 +1,134 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+const (
+	// adaptiveQuotaWindowSize is the number of recent billed requests kept per model.
+	adaptiveQuotaWindowSize = 100
+	// adaptiveQuotaMultiplier is applied to the rolling mean to leave headroom
+	// for requests that are more expensive than average.
+	adaptiveQuotaMultiplier = 1.2
+	// adaptiveQuotaCapMultiplier bounds the adaptive value relative to config.PreConsumedQuota.
+	adaptiveQuotaCapMultiplier = 10
+	// adaptiveQuotaKeyExpiration drops the history of models that are no longer used.
+	adaptiveQuotaKeyExpiration = 7 * 24 * time.Hour
+)
+
+// memoryQuotaWindows holds the rolling windows used when Redis is disabled.
+var memoryQuotaWindows = struct {
+	sync.Mutex
+	samples map[string][]int64
+}{samples: make(map[string][]int64)}
+
+// adaptiveQuotaKey returns the Redis list key holding recent quota samples for modelName.
+func adaptiveQuotaKey(modelName string) string {
+	return fmt.Sprintf("preConsumeQuota:samples:%s", modelName)
+}
+
+// GetAdaptivePreConsumeQuota returns the quota to reserve before relaying a request
+// for modelName, computed as 1.2× the mean quota of the model's last 100 billed
+// requests and capped at 10× config.PreConsumedQuota. It falls back to
+// config.PreConsumedQuota when the model has no history.
+func GetAdaptivePreConsumeQuota(ctx context.Context, modelName string) int64 {
+	if quota, ok := AdaptivePreConsumeQuota(ctx, modelName); ok {
+		return quota
+	}
+
+	return config.PreConsumedQuota
+}
+
+// AdaptivePreConsumeQuota is like GetAdaptivePreConsumeQuota but reports whether
+// the model has any billing history instead of falling back to the fixed value.
+func AdaptivePreConsumeQuota(ctx context.Context, modelName string) (int64, bool) {
+	samples, err := loadQuotaSamples(ctx, modelName)
+	if err != nil {
+		logger.Logger.Warn("failed to load quota history for adaptive pre-consume",
+			zap.Error(err),
+			zap.String("model", modelName))
+		return 0, false
+	}
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	var sum int64
+	for _, sample := range samples {
+		sum += sample
+	}
+	mean := float64(sum) / float64(len(samples))
+	quota := int64(math.Round(mean * adaptiveQuotaMultiplier))
+	if limit := adaptiveQuotaCapMultiplier * config.PreConsumedQuota; limit > 0 && quota > limit {
+		quota = limit
+	}
+
+	return quota, true
+}
+
+// RecordModelQuotaSample appends the quota billed for one request of modelName to
+// the model's rolling window. Non-positive quotas (free requests) are ignored so
+// they do not drag the mean down.
+func RecordModelQuotaSample(ctx context.Context, modelName string, quota int64) {
+	if modelName == "" || quota <= 0 {
+		return
+	}
+
+	if common.IsRedisEnabled() && common.RDB != nil {
+		key := adaptiveQuotaKey(modelName)
+		pipe := common.RDB.TxPipeline()
+		pipe.LPush(ctx, key, quota)
+		pipe.LTrim(ctx, key, 0, adaptiveQuotaWindowSize-1)
+		pipe.Expire(ctx, key, adaptiveQuotaKeyExpiration)
+		if _, err := pipe.Exec(ctx); err != nil {
+			logger.Logger.Warn("failed to record quota sample for adaptive pre-consume",
+				zap.Error(err),
+				zap.String("model", modelName))
+		}
+		return
+	}
+
+	memoryQuotaWindows.Lock()
+	defer memoryQuotaWindows.Unlock()
+	window := append(memoryQuotaWindows.samples[modelName], quota)
+	if len(window) > adaptiveQuotaWindowSize {
+		window = window[len(window)-adaptiveQuotaWindowSize:]
+	}
+	memoryQuotaWindows.samples[modelName] = window
+}
+
+// loadQuotaSamples returns the recent quota samples recorded for modelName.
+func loadQuotaSamples(ctx context.Context, modelName string) ([]int64, error) {
+	if common.IsRedisEnabled() && common.RDB != nil {
+		key := adaptiveQuotaKey(modelName)
+		values, err := common.RDB.LRange(ctx, key, 0, adaptiveQuotaWindowSize-1).Result()
+		if err != nil {
+			return nil, errors.Wrapf(err, "read redis list %s", key)
+		}
+		samples := make([]int64, 0, len(values))
+		for _, v := range values {
+			sample, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse quota sample %q", v)
+			}
+			samples = append(samples, sample)
+		}
+		return samples, nil
+	}
+
+	memoryQuotaWindows.Lock()
+	defer memoryQuotaWindows.Unlock()
+	return append([]int64(nil), memoryQuotaWindows.samples[modelName]...), nil
+}
@@ -0,0 +1,54 @@
+package billing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+// useMemoryQuotaWindows forces the in-memory rolling window and clears it afterwards.
+func useMemoryQuotaWindows(t *testing.T) {
+	t.Helper()
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedis)
+		memoryQuotaWindows.Lock()
+		memoryQuotaWindows.samples = make(map[string][]int64)
+		memoryQuotaWindows.Unlock()
+	})
+}
+
+func TestGetAdaptivePreConsumeQuota_UsesRollingMean(t *testing.T) {
+	useMemoryQuotaWindows(t)
+	ctx := context.Background()
+
+	require.Equal(t, config.PreConsumedQuota, GetAdaptivePreConsumeQuota(ctx, "adaptive-model"))
+
+	// Older, more expensive samples fall out of the 100-sample window.
+	for range 50 {
+		RecordModelQuotaSample(ctx, "adaptive-model", 3000)
+	}
+	for i := range 100 {
+		RecordModelQuotaSample(ctx, "adaptive-model", int64(150+(i%2)*100)) // mean 200
+	}
+
+	require.EqualValues(t, 240, GetAdaptivePreConsumeQuota(ctx, "adaptive-model"))
+	require.Equal(t, config.PreConsumedQuota, GetAdaptivePreConsumeQuota(ctx, "other-model"))
+}
+
+func TestGetAdaptivePreConsumeQuota_CappedAndIgnoresFreeRequests(t *testing.T) {
+	useMemoryQuotaWindows(t)
+	ctx := context.Background()
+
+	RecordModelQuotaSample(ctx, "expensive-model", 0)
+	_, ok := AdaptivePreConsumeQuota(ctx, "expensive-model")
+	require.False(t, ok)
+
+	RecordModelQuotaSample(ctx, "expensive-model", 100*config.PreConsumedQuota)
+	require.Equal(t, 10*config.PreConsumedQuota, GetAdaptivePreConsumeQuota(ctx, "expensive-model"))
+}
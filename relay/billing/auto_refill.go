@@ -0,0 +1,107 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+)
+
+const (
+	// autoRefillSweepInterval is how often users are checked for a due refill.
+	autoRefillSweepInterval = time.Hour
+	// autoRefillLockKey is held by the node running the current sweep.
+	autoRefillLockKey = "refill_lock"
+	// autoRefillLockTTL keeps other nodes from sweeping until the next sweep
+	// is due. It expires a minute early so that tick drift never stops the next
+	// sweep from acquiring it. Refills are also claimed per user, so a sweep
+	// outliving the lock does not refill anyone twice.
+	autoRefillLockTTL = autoRefillSweepInterval - time.Minute
+)
+
+// StartAutoRefill launches a background worker that hourly adds the
+// configured auto refill quota to every user whose refill interval has
+// elapsed. With Redis enabled only the node holding the refill lock sweeps.
+func StartAutoRefill(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(autoRefillSweepInterval)
+		defer ticker.Stop()
+		for {
+			sweepAutoRefill(ctx)
+			select {
+			case <-ctx.Done():
+				logger.Logger.Info("auto refill worker stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	logger.Logger.Info("auto refill worker started")
+}
+
+func sweepAutoRefill(ctx context.Context) {
+	if common.IsRedisEnabled() && common.RDB != nil {
+		acquired, err := common.RDB.SetNX(ctx, autoRefillLockKey, "1", autoRefillLockTTL).Result()
+		if err != nil {
+			logger.Logger.Warn("failed to acquire auto refill lock", zap.Error(err))
+			return
+		}
+		if !acquired {
+			logger.Logger.Debug("auto refill sweep skipped, another node holds the lock")
+			return
+		}
+	}
+
+	refilled, err := RunAutoRefill(ctx, time.Now())
+	if err != nil {
+		logger.Logger.Warn("auto refill sweep failed", zap.Int("refilled_users", refilled), zap.Error(err))
+		return
+	}
+	if refilled > 0 {
+		logger.Logger.Info("auto refilled user quota", zap.Int("refilled_users", refilled))
+	} else {
+		logger.Logger.Debug("auto refill sweep completed")
+	}
+}
+
+// RunAutoRefill refills every user due at now and returns how many were
+// refilled. Each refill adds the user's auto refill quota and records a
+// system log entry. Failing users are skipped and reported in the returned
+// error, the others are still refilled.
+func RunAutoRefill(ctx context.Context, now time.Time) (refilled int, err error) {
+	users, err := model.GetUsersDueForAutoRefill(now.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for _, user := range users {
+		claimed, claimErr := model.ClaimUserAutoRefill(user.Id, user.LastRefillAt, now.Unix())
+		if claimErr != nil {
+			errs = append(errs, claimErr)
+			continue
+		}
+		if !claimed { // refilled by an overlapping sweep
+			continue
+		}
+		if increaseErr := model.IncreaseUserQuota(ctx, user.Id, user.AutoRefillQuota); increaseErr != nil {
+			// Give the refill back so the next sweep retries it
+			if _, revertErr := model.ClaimUserAutoRefill(user.Id, now.Unix(), user.LastRefillAt); revertErr != nil {
+				logger.Logger.Error("failed to revert auto refill claim", zap.Int("user_id", user.Id), zap.Error(revertErr))
+			}
+			errs = append(errs, errors.Wrapf(increaseErr, "auto refill user %d", user.Id))
+			continue
+		}
+		model.RecordLog(ctx, user.Id, model.LogTypeSystem,
+			fmt.Sprintf("Auto refill %s (every %d days)", common.LogQuota(user.AutoRefillQuota), user.AutoRefillIntervalDays))
+		refilled++
+	}
+	return refilled, errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+package billing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func TestRunAutoRefill(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Log{}))
+	originalDB, originalLogDB := model.DB, model.LOG_DB
+	originalRedisEnabled := common.IsRedisEnabled()
+	model.DB, model.LOG_DB = db, db
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() {
+		model.DB, model.LOG_DB = originalDB, originalLogDB
+		common.SetRedisEnabled(originalRedisEnabled)
+	})
+
+	now := time.Unix(1700000000, 0)
+	day := int64(24 * 60 * 60)
+	users := []*model.User{
+		// Never refilled
+		{Username: "new", AccessToken: "t1", AffCode: "a1", Status: model.UserStatusEnabled, Quota: 10, AutoRefillQuota: 100, AutoRefillIntervalDays: 7},
+		// Interval elapsed
+		{Username: "due", AccessToken: "t2", AffCode: "a2", Status: model.UserStatusEnabled, Quota: 10, AutoRefillQuota: 50, AutoRefillIntervalDays: 1, LastRefillAt: now.Unix() - day},
+		// Interval not elapsed yet
+		{Username: "recent", AccessToken: "t3", AffCode: "a3", Status: model.UserStatusEnabled, Quota: 10, AutoRefillQuota: 50, AutoRefillIntervalDays: 7, LastRefillAt: now.Unix() - day},
+		// Disabled user and user without auto refill
+		{Username: "disabled", AccessToken: "t4", AffCode: "a4", Status: model.UserStatusDisabled, Quota: 10, AutoRefillQuota: 50, AutoRefillIntervalDays: 1},
+		{Username: "off", AccessToken: "t5", AffCode: "a5", Status: model.UserStatusEnabled, Quota: 10},
+	}
+	require.NoError(t, db.Create(&users).Error)
+
+	refilled, err := RunAutoRefill(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 2, refilled)
+
+	expectedQuota := map[string]int64{"new": 110, "due": 60, "recent": 10, "disabled": 10, "off": 10}
+	for _, user := range users {
+		var stored model.User
+		require.NoError(t, db.First(&stored, user.Id).Error)
+		require.Equal(t, expectedQuota[user.Username], stored.Quota, user.Username)
+		if stored.Quota != 10 {
+			require.Equal(t, now.Unix(), stored.LastRefillAt)
+		}
+	}
+	var logs int64
+	require.NoError(t, db.Model(&model.Log{}).Where("type = ?", model.LogTypeSystem).Count(&logs).Error)
+	require.EqualValues(t, 2, logs)
+
+	// Nothing is due again within the interval
+	refilled, err = RunAutoRefill(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, refilled)
+}
@@ -41,6 +41,8 @@ func PostConsumeQuotaWithLog(ctx context.Context, tokenId int, quotaDelta int64,
 		return
 	}
 
+	quotaDelta, totalQuota = applyQuotaOverage(logEntry, quotaDelta, totalQuota)
+
 	// Consume remaining quota
 	if err := model.PostConsumeTokenQuota(ctx, tokenId, quotaDelta); err != nil {
 		logger.Logger.Error("CRITICAL: upstream request was sent but billing failed - unbilled request detected",
@@ -69,12 +71,14 @@ func PostConsumeQuotaWithLog(ctx context.Context, tokenId int, quotaDelta int64,
 	// Force quota onto log entry for consistency
 	logEntry.Quota = int(totalQuota)
 	model.RecordConsumeLog(ctx, logEntry)
+	model.RecordTokenTpmUsage(ctx, tokenId, int64(logEntry.PromptTokens+logEntry.CompletionTokens), time.Now())
 
 	// Update aggregates only when there is actual consumption.
 	// Zero totalQuota is allowed (e.g., free groups or zero ratios) and should not be treated as an error.
 	if totalQuota > 0 {
 		model.UpdateUserUsedQuotaAndRequestCount(logEntry.UserId, totalQuota)
 		model.UpdateChannelUsedQuota(logEntry.ChannelId, totalQuota)
+		RecordModelQuotaSample(ctx, logEntry.ModelName, totalQuota)
 	} else if totalQuota < 0 {
 		// Negative consumption should never happen; flag as error for diagnostics.
 		logger.Logger.Error("invalid negative totalQuota consumed",
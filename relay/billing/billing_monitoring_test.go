@@ -96,6 +96,19 @@ func (m *MockMetricsRecorder) UpdateActiveTokens(userId, tokenName string, count
 func (m *MockMetricsRecorder) RecordError(errorType, component string)                              {}
 func (m *MockMetricsRecorder) RecordModelUsage(modelName, channelType string, latency time.Duration) {
 }
+func (m *MockMetricsRecorder) RecordTokenCountDiscrepancy(modelName string, ratio float64, exceeded bool) {
+}
+func (m *MockMetricsRecorder) RecordUpstreamBodySizes(modelName string, channelId int, responseType string, requestBytes, responseBytes int64) {
+}
+func (m *MockMetricsRecorder) RecordConfigReload(success bool)                              {}
+func (m *MockMetricsRecorder) RecordEmbeddingCacheLookup(modelName string, hits, total int) {}
+func (m *MockMetricsRecorder) UpdateChannelConcurrentRequests(channelId int, delta float64) {}
+func (m *MockMetricsRecorder) UpdateLogQueueDepth(depth int)                                {}
+func (m *MockMetricsRecorder) RecordLogRetentionDeleted(logType string, deleted int64)      {}
+func (m *MockMetricsRecorder) RecordAutoDedupHit(modelName string)                          {}
+func (m *MockMetricsRecorder) RecordBlacklistLookup(result string)                          {}
+func (m *MockMetricsRecorder) RecordQuotaConsumed(userGroup, modelName string, quota int64) {}
+func (m *MockMetricsRecorder) UpdateActiveUsers(activeUsers map[string]int)                 {}
 func (m *MockMetricsRecorder) UpdateBillingStats(totalBillingOperations, successfulBillingOperations, failedBillingOperations int64) {
 }
 func (m *MockMetricsRecorder) InitSystemMetrics(version, buildTime, goVersion string, startTime time.Time) {
@@ -0,0 +1,61 @@
+package billing
+
+import (
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// quotaOverage describes how a request's cost relates to the user's quota
+// when config.QuotaOveragePercent allows spending past it.
+type quotaOverage struct {
+	// extra is the surcharge on the part of the cost past the remaining
+	// quota, see config.QuotaOverageMultiplier.
+	extra int64
+	// overdrawn is set when the request takes a non-negative balance negative.
+	overdrawn bool
+	// balance is the user's quota after the request, surcharge included.
+	balance int64
+}
+
+// computeQuotaOverage reports whether a request costing totalQuota spent past
+// the user's quota. remaining is the user's quota with the pre-consumed and
+// incrementally charged part of the cost, totalQuota-quotaDelta, already
+// taken off.
+func computeQuotaOverage(remaining int64, quotaDelta int64, totalQuota int64) (overage quotaOverage, ok bool) {
+	before := remaining + totalQuota - quotaDelta
+	over := min(totalQuota-max(before, 0), totalQuota)
+	if over <= 0 {
+		return overage, false
+	}
+	overage.extra = int64(float64(over) * (config.QuotaOverageMultiplier - 1))
+	overage.balance = before - totalQuota - overage.extra
+	overage.overdrawn = before >= 0 && overage.balance < 0
+	return overage, true
+}
+
+// applyQuotaOverage surcharges the part of a request's cost past the user's
+// quota and marks its consume log as overage. It returns the adjusted
+// quotaDelta and totalQuota.
+func applyQuotaOverage(logEntry *model.Log, quotaDelta int64, totalQuota int64) (int64, int64) {
+	if config.QuotaOveragePercent <= 0 || totalQuota <= 0 {
+		return quotaDelta, totalQuota
+	}
+	remaining, err := model.GetUserQuota(logEntry.UserId)
+	if err != nil {
+		logger.Logger.Warn("failed to get user quota for overage check", zap.Int("user_id", logEntry.UserId), zap.Error(err))
+		return quotaDelta, totalQuota
+	}
+	overage, ok := computeQuotaOverage(remaining, quotaDelta, totalQuota)
+	if !ok {
+		return quotaDelta, totalQuota
+	}
+
+	logEntry.Metadata = model.AppendQuotaOverageMetadata(logEntry.Metadata)
+	if overage.overdrawn {
+		model.NotifyQuotaOverdrawn(logEntry.UserId, overage.balance)
+	}
+	return quotaDelta + overage.extra, totalQuota + overage.extra
+}
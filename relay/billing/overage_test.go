@@ -0,0 +1,33 @@
+package billing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func TestComputeQuotaOverage(t *testing.T) {
+	originalMultiplier := config.QuotaOverageMultiplier
+	config.QuotaOverageMultiplier = 1.5
+	t.Cleanup(func() { config.QuotaOverageMultiplier = originalMultiplier })
+
+	// 1000 left before a request costing 800, 300 of it pre-consumed
+	_, ok := computeQuotaOverage(700, 500, 800)
+	require.False(t, ok)
+
+	// 1000 left before a request costing 1200: 200 past the quota
+	overage, ok := computeQuotaOverage(700, 900, 1200)
+	require.True(t, ok)
+	require.EqualValues(t, 100, overage.extra)
+	require.EqualValues(t, -300, overage.balance)
+	require.True(t, overage.overdrawn)
+
+	// Already negative: the whole cost is overage and no new notification
+	overage, ok = computeQuotaOverage(-300, 400, 400)
+	require.True(t, ok)
+	require.EqualValues(t, 200, overage.extra)
+	require.EqualValues(t, -900, overage.balance)
+	require.False(t, overage.overdrawn)
+}
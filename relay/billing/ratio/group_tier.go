@@ -0,0 +1,46 @@
+package ratio
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+var groupTierLock sync.RWMutex
+
+// GroupTier maps user group names to their subscription tier (see config.TierFree etc.).
+// Groups absent from the map carry no tier and are not subject to tier restrictions.
+var GroupTier = map[string]string{}
+
+// GroupTier2JSONString serializes the group tier table for persistence in the options table.
+func GroupTier2JSONString() string {
+	groupTierLock.RLock()
+	defer groupTierLock.RUnlock()
+	jsonBytes, err := json.Marshal(GroupTier)
+	if err != nil {
+		logger.Logger.Error("error marshalling group tier", zap.Error(err))
+	}
+	return string(jsonBytes)
+}
+
+// UpdateGroupTierByJSONString replaces the group tier table with the JSON object in jsonStr.
+func UpdateGroupTierByJSONString(jsonStr string) error {
+	groupTierLock.Lock()
+	defer groupTierLock.Unlock()
+	GroupTier = make(map[string]string)
+	if err := json.Unmarshal([]byte(jsonStr), &GroupTier); err != nil {
+		return errors.Wrap(err, "unmarshal group tier")
+	}
+	return nil
+}
+
+// GetGroupTier returns the tier assigned to group, or "" when none is assigned.
+func GetGroupTier(group string) string {
+	groupTierLock.RLock()
+	defer groupTierLock.RUnlock()
+	return GroupTier[group]
+}
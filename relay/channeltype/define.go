@@ -53,5 +53,7 @@ const (
 	AliBailian
 	OpenAICompatible
 	GeminiOpenAICompatible
+	Perplexity
+	Fireworks
 	Dummy
 )
@@ -51,6 +51,16 @@ func ToAPIType(channelType int) int {
 		apiType = apitype.Moonshot
 	case XAI:
 		apiType = apitype.XAI
+	case Minimax:
+		apiType = apitype.Minimax
+	case TogetherAI:
+		apiType = apitype.TogetherAI
+	case Perplexity:
+		apiType = apitype.Perplexity
+	case Fireworks:
+		apiType = apitype.Fireworks
+	case Novita:
+		apiType = apitype.Novita
 	}
 
 	return apiType
@@ -170,6 +180,10 @@ func IdToName(channelType int) string {
 		return "openaicompatible"
 	case GeminiOpenAICompatible:
 		return "geminiopenaicompatible"
+	case Perplexity:
+		return "perplexity"
+	case Fireworks:
+		return "fireworks"
 	case Dummy:
 		return "dummy"
 	default:
@@ -0,0 +1,349 @@
+package channeltype
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReadmeModel is a model of a channel readme with its default prices in USD
+// per 1M tokens. A zero price means the adaptor has no default for it.
+type ReadmeModel struct {
+	Name            string  `json:"name"`
+	InputUsdPer1M   float64 `json:"input_usd_per_1m"`
+	OutputUsdPer1M  float64 `json:"output_usd_per_1m"`
+	MaxOutputTokens int32   `json:"max_output_tokens,omitempty"`
+}
+
+// ReadmeMetadata is the adaptor metadata a channel readme is assembled from.
+type ReadmeMetadata struct {
+	ChannelName string
+	Models      []ReadmeModel
+	// SupportsClaudeMessages reports whether /v1/messages requests can be relayed.
+	SupportsClaudeMessages bool
+	// SupportsRerank reports whether /v1/rerank requests can be relayed.
+	SupportsRerank bool
+}
+
+// ReadmeMetadataProvider resolves the adaptor metadata of a channel type. It is
+// registered by the relay package, since channeltype cannot import adaptors.
+var ReadmeMetadataProvider func(channelType int) (ReadmeMetadata, bool)
+
+// channelKeyFormats documents API keys that are not a single bearer token.
+var channelKeyFormats = map[int]string{
+	Azure:      "The Azure OpenAI resource key; set the resource endpoint as base URL and the API version in the channel config.",
+	Baidu:      "`API_KEY|SECRET_KEY` of the Qianfan application, or a Qianfan API key sent as bearer token.",
+	Xunfei:     "`APPID|APISecret|APIKey` of the Spark application.",
+	Tencent:    "`AppId|SecretId|SecretKey` of the Hunyuan application.",
+	AwsClaude:  "`AccessKeyID|SecretAccessKey`; set the region in the channel config.",
+	VertextAI:  "The service account JSON; set the region and project id in the channel config.",
+	Cloudflare: "A Workers AI API token; set the account id in the channel config.",
+	Coze:       "A personal access token of the Coze workspace.",
+}
+
+// readmeExampleModelFallback is used in examples when the adaptor lists no models.
+const readmeExampleModelFallback = "<model>"
+
+// GenerateChannelReadme renders a markdown document describing the channel
+// type: supported models, required configuration, default pricing, known
+// limitations and example requests. It returns an empty string for channel
+// types without metadata.
+func GenerateChannelReadme(channelType int) string {
+	if ReadmeMetadataProvider == nil {
+		return ""
+	}
+	metadata, ok := ReadmeMetadataProvider(channelType)
+	if !ok {
+		return ""
+	}
+	models := sortedReadmeModels(metadata.Models)
+	baseURL := GetChannelBaseURLConfig(channelType)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s channel\n\n", metadata.ChannelName)
+	fmt.Fprintf(&b, "Channel type `%d`. This document is generated from the adaptor metadata.\n\n", channelType)
+
+	b.WriteString("## Supported models\n\n")
+	if len(models) == 0 {
+		b.WriteString("The adaptor does not list models; configure them on the channel.\n\n")
+	} else {
+		for _, m := range models {
+			fmt.Fprintf(&b, "- `%s`\n", m.Name)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Configuration\n\n")
+	keyFormat, ok := channelKeyFormats[channelType]
+	if !ok {
+		keyFormat = "The API key issued by the provider, sent as a bearer token."
+	}
+	fmt.Fprintf(&b, "- **API key**: %s\n", keyFormat)
+	switch {
+	case baseURL.URL == "":
+		b.WriteString("- **Base URL**: no default, it must be set on the channel.\n")
+	case baseURL.Editable:
+		fmt.Fprintf(&b, "- **Base URL**: `%s` by default, may be changed to a compatible endpoint.\n", baseURL.URL)
+	default:
+		fmt.Fprintf(&b, "- **Base URL**: `%s`.\n", baseURL.URL)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Pricing\n\n")
+	if len(models) == 0 {
+		b.WriteString("The adaptor has no default pricing; configure model prices on the channel.\n\n")
+	} else {
+		b.WriteString("Default prices in USD per 1M tokens; channel overrides take precedence.\n\n")
+		b.WriteString("| Model | Input | Output |\n")
+		b.WriteString("| --- | --- | --- |\n")
+		for _, m := range models {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", m.Name, formatReadmePrice(m.InputUsdPer1M), formatReadmePrice(m.OutputUsdPer1M))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Known limitations\n\n")
+	limitations := readmeLimitations(channelType, metadata, baseURL)
+	if len(limitations) == 0 {
+		b.WriteString("None known.\n\n")
+	} else {
+		for _, limitation := range limitations {
+			fmt.Fprintf(&b, "- %s\n", limitation)
+		}
+		b.WriteString("\n")
+	}
+
+	exampleModel := readmeExampleModelFallback
+	if len(models) > 0 {
+		exampleModel = models[0].Name
+	}
+	b.WriteString("## Examples\n\n")
+	b.WriteString("Chat completion through one-api:\n\n")
+	b.WriteString("```bash\n")
+	b.WriteString("curl \"$ONE_API_BASE_URL/v1/chat/completions\" \\\n")
+	b.WriteString("  -H \"Authorization: Bearer $ONE_API_KEY\" \\\n")
+	b.WriteString("  -H \"Content-Type: application/json\" \\\n")
+	fmt.Fprintf(&b, "  -d '{\"model\": \"%s\", \"messages\": [{\"role\": \"user\", \"content\": \"Hello\"}]}'\n", exampleModel)
+	b.WriteString("```\n")
+	if metadata.SupportsClaudeMessages {
+		b.WriteString("\nClaude Messages API:\n\n")
+		b.WriteString("```bash\n")
+		b.WriteString("curl \"$ONE_API_BASE_URL/v1/messages\" \\\n")
+		b.WriteString("  -H \"x-api-key: $ONE_API_KEY\" \\\n")
+		b.WriteString("  -H \"Content-Type: application/json\" \\\n")
+		fmt.Fprintf(&b, "  -d '{\"model\": \"%s\", \"max_tokens\": 256, \"messages\": [{\"role\": \"user\", \"content\": \"Hello\"}]}'\n", exampleModel)
+		b.WriteString("```\n")
+	}
+
+	return b.String()
+}
+
+// readmeLimitations lists what the channel type cannot do.
+func readmeLimitations(channelType int, metadata ReadmeMetadata, baseURL ChannelBaseURLConfig) []string {
+	var limitations []string
+	if !metadata.SupportsClaudeMessages {
+		limitations = append(limitations, "Claude Messages API (`/v1/messages`) requests are not supported.")
+	}
+	if !metadata.SupportsRerank {
+		limitations = append(limitations, "Rerank (`/v1/rerank`) requests are not supported.")
+	}
+	if !baseURL.Editable && baseURL.URL != "" {
+		limitations = append(limitations, "The base URL cannot be changed.")
+	}
+	for _, m := range metadata.Models {
+		if m.MaxOutputTokens > 0 {
+			limitations = append(limitations, fmt.Sprintf("`%s` generates at most %d tokens per request.", m.Name, m.MaxOutputTokens))
+		}
+	}
+	if channelType == Minimax {
+		limitations = append(limitations, "The chatcompletion_pro API needs the MiniMax group id in the channel config.")
+	}
+	return limitations
+}
+
+func sortedReadmeModels(models []ReadmeModel) []ReadmeModel {
+	sorted := append([]ReadmeModel(nil), models...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// formatReadmePrice renders a USD price, or "-" when unknown.
+func formatReadmePrice(usd float64) string {
+	if usd <= 0 {
+		return "-"
+	}
+	return "$" + trimFloat(usd)
+}
+
+// trimFloat formats f with up to 6 decimals and no trailing zeros.
+func trimFloat(f float64) string {
+	s := fmt.Sprintf("%.6f", math.Round(f*1e6)/1e6)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// GeneratePricingChangelog renders a CHANGELOG.md entry describing how the
+// default pricing of a channel type moved from previous to current, or an
+// empty string when nothing changed.
+func GeneratePricingChangelog(channelName string, previous, current []ReadmeModel, at time.Time) string {
+	before := make(map[string]ReadmeModel, len(previous))
+	for _, m := range previous {
+		before[m.Name] = m
+	}
+	after := make(map[string]ReadmeModel, len(current))
+	for _, m := range current {
+		after[m.Name] = m
+	}
+
+	var added, removed, changed []string
+	for _, m := range sortedReadmeModels(current) {
+		old, ok := before[m.Name]
+		switch {
+		case !ok:
+			added = append(added, fmt.Sprintf("- `%s`: input %s, output %s",
+				m.Name, formatReadmePrice(m.InputUsdPer1M), formatReadmePrice(m.OutputUsdPer1M)))
+		case old.InputUsdPer1M != m.InputUsdPer1M || old.OutputUsdPer1M != m.OutputUsdPer1M:
+			changed = append(changed, fmt.Sprintf("- `%s`: input %s → %s, output %s → %s", m.Name,
+				formatReadmePrice(old.InputUsdPer1M), formatReadmePrice(m.InputUsdPer1M),
+				formatReadmePrice(old.OutputUsdPer1M), formatReadmePrice(m.OutputUsdPer1M)))
+		}
+	}
+	for _, m := range sortedReadmeModels(previous) {
+		if _, ok := after[m.Name]; !ok {
+			removed = append(removed, fmt.Sprintf("- `%s`", m.Name))
+		}
+	}
+	if len(added)+len(removed)+len(changed) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s — %s pricing\n\n", at.UTC().Format("2006-01-02"), channelName)
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{{"Added", added}, {"Changed", changed}, {"Removed", removed}} {
+		if len(section.lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "### %s\n\n%s\n\n", section.title, strings.Join(section.lines, "\n"))
+	}
+	return b.String()
+}
+
+// ReadmeToHTML converts the markdown subset emitted by GenerateChannelReadme
+// and GeneratePricingChangelog (headings, lists, tables, code blocks and
+// paragraphs) to HTML.
+func ReadmeToHTML(markdown string) string {
+	var b strings.Builder
+	lines := strings.Split(markdown, "\n")
+	inList, inCode, inTable := false, false, false
+	closeBlocks := func() {
+		if inList {
+			b.WriteString("</ul>\n")
+			inList = false
+		}
+		if inTable {
+			b.WriteString("</tbody></table>\n")
+			inTable = false
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if inCode {
+			if strings.HasPrefix(line, "```") {
+				b.WriteString("</code></pre>\n")
+				inCode = false
+				continue
+			}
+			b.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "```"):
+			closeBlocks()
+			lang := strings.TrimPrefix(line, "```")
+			if lang != "" {
+				fmt.Fprintf(&b, "<pre><code class=\"language-%s\">", html.EscapeString(lang))
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			inCode = true
+		case strings.HasPrefix(line, "#"):
+			closeBlocks()
+			level := len(line) - len(strings.TrimLeft(line, "#"))
+			level = min(level, 6)
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", level, inlineMarkdownToHTML(strings.TrimSpace(line[level:])), level)
+		case strings.HasPrefix(line, "- "):
+			if !inList {
+				closeBlocks()
+				b.WriteString("<ul>\n")
+				inList = true
+			}
+			fmt.Fprintf(&b, "<li>%s</li>\n", inlineMarkdownToHTML(strings.TrimPrefix(line, "- ")))
+		case strings.HasPrefix(line, "|"):
+			cells := tableCells(line)
+			if !inTable {
+				closeBlocks()
+				b.WriteString("<table>\n<thead><tr>")
+				for _, cell := range cells {
+					fmt.Fprintf(&b, "<th>%s</th>", inlineMarkdownToHTML(cell))
+				}
+				b.WriteString("</tr></thead>\n<tbody>\n")
+				inTable = true
+				// Skip the delimiter row
+				if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "| ---") {
+					i++
+				}
+				continue
+			}
+			b.WriteString("<tr>")
+			for _, cell := range cells {
+				fmt.Fprintf(&b, "<td>%s</td>", inlineMarkdownToHTML(cell))
+			}
+			b.WriteString("</tr>\n")
+		case strings.TrimSpace(line) == "":
+			closeBlocks()
+		default:
+			closeBlocks()
+			fmt.Fprintf(&b, "<p>%s</p>\n", inlineMarkdownToHTML(line))
+		}
+	}
+	closeBlocks()
+	if inCode {
+		b.WriteString("</code></pre>\n")
+	}
+	return b.String()
+}
+
+func tableCells(line string) []string {
+	parts := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// inlineMarkdownToHTML escapes text and renders `code` and **bold** spans.
+func inlineMarkdownToHTML(text string) string {
+	var b strings.Builder
+	for i, segment := range strings.Split(text, "`") {
+		if i%2 == 1 {
+			b.WriteString("<code>" + html.EscapeString(segment) + "</code>")
+			continue
+		}
+		for j, part := range strings.Split(segment, "**") {
+			if j%2 == 1 {
+				b.WriteString("<strong>" + html.EscapeString(part) + "</strong>")
+			} else {
+				b.WriteString(html.EscapeString(part))
+			}
+		}
+	}
+	return b.String()
+}
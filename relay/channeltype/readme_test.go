@@ -0,0 +1,33 @@
+package channeltype
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePricingChangelog(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	previous := []ReadmeModel{
+		{Name: "model-a", InputUsdPer1M: 1, OutputUsdPer1M: 2},
+		{Name: "model-b", InputUsdPer1M: 3, OutputUsdPer1M: 6},
+	}
+	current := []ReadmeModel{
+		{Name: "model-a", InputUsdPer1M: 0.5, OutputUsdPer1M: 2},
+		{Name: "model-c", InputUsdPer1M: 4, OutputUsdPer1M: 8},
+	}
+
+	entry := GeneratePricingChangelog("demo", previous, current, at)
+	require.Equal(t, "## 2026-03-01 — demo pricing\n\n"+
+		"### Added\n\n- `model-c`: input $4, output $8\n\n"+
+		"### Changed\n\n- `model-a`: input $1 → $0.5, output $2 → $2\n\n"+
+		"### Removed\n\n- `model-b`\n\n", entry)
+
+	require.Empty(t, GeneratePricingChangelog("demo", current, current, at))
+}
+
+func TestReadmeToHTML_EscapesContent(t *testing.T) {
+	html := ReadmeToHTML("# A <b>\n\n- `x<y`\n\nplain & **bold**\n")
+	require.Equal(t, "<h1>A &lt;b&gt;</h1>\n<ul>\n<li><code>x&lt;y</code></li>\n</ul>\n<p>plain &amp; <strong>bold</strong></p>\n", html)
+}
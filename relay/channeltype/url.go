@@ -1,5 +1,7 @@
 package channeltype
 
+import "github.com/songquanpeng/one-api/common/config"
+
 // ChannelBaseURLConfig defines the configuration for a channel's base URL.
 // URL is the default base URL.
 // Editable indicates whether the user can modify the base URL.
@@ -28,7 +30,7 @@ var ChannelBaseURLConfigs = []ChannelBaseURLConfig{
 	{URL: "https://api.anthropic.com", Editable: true},                                 // 14 Anthropic
 	{URL: "https://aip.baidubce.com", Editable: false},                                 // 15 Baidu
 	{URL: "https://open.bigmodel.cn", Editable: false},                                 // 16 Zhipu
-	{URL: "https://dashscope.aliyuncs.com", Editable: false},                           // 17 Ali
+	{URL: config.DashScopeAPIBase, Editable: false},                                    // 17 Ali
 	{URL: "", Editable: false},                                                         // 18 Xunfei
 	{URL: "https://ai.360.cn", Editable: false},                                        // 19 AI360
 	{URL: "https://openrouter.ai/api", Editable: true},                                 // 20 OpenRouter
@@ -36,7 +38,7 @@ var ChannelBaseURLConfigs = []ChannelBaseURLConfig{
 	{URL: "https://fastgpt.run/api/openapi", Editable: true},                           // 22 FastGPT
 	{URL: "https://hunyuan.tencentcloudapi.com", Editable: false},                      // 23 Tencent
 	{URL: "https://generativelanguage.googleapis.com", Editable: false},                // 24 Gemini
-	{URL: "https://api.moonshot.cn", Editable: false},                                  // 25 Moonshot
+	{URL: config.MoonshotAPIBase, Editable: false},                                     // 25 Moonshot
 	{URL: "https://api.baichuan-ai.com", Editable: false},                              // 26 Baichuan
 	{URL: "https://api.minimax.chat", Editable: false},                                 // 27 Minimax
 	{URL: "https://api.mistral.ai", Editable: false},                                   // 28 Mistral
@@ -51,7 +53,7 @@ var ChannelBaseURLConfigs = []ChannelBaseURLConfig{
 	{URL: "https://api.cloudflare.com", Editable: false},                               // 37 Cloudflare
 	{URL: "https://api-free.deepl.com", Editable: true},                                // 38 DeepL
 	{URL: "https://api.together.xyz", Editable: false},                                 // 39 TogetherAI
-	{URL: "https://ark.cn-beijing.volces.com", Editable: true},                         // 40 Doubao
+	{URL: config.VolcengineAPIBase, Editable: true},                                    // 40 Doubao
 	{URL: "https://api.novita.ai/v3/openai", Editable: false},                          // 41 Novita
 	{URL: "", Editable: false},                                                         // 42 VertextAI - region-based
 	{URL: "", Editable: true},                                                          // 43 Proxy
@@ -63,6 +65,8 @@ var ChannelBaseURLConfigs = []ChannelBaseURLConfig{
 	{URL: "https://dashscope.aliyuncs.com", Editable: false},                           // 49 AliBailian
 	{URL: "", Editable: true},                                                          // 50 OpenAICompatible - user must provide
 	{URL: "https://generativelanguage.googleapis.com/v1beta/openai/", Editable: false}, // 51 GeminiOpenAICompatible
+	{URL: "https://api.perplexity.ai", Editable: false},                                // 52 Perplexity
+	{URL: "https://api.fireworks.ai/inference/v1", Editable: false},                    // 53 Fireworks
 }
 
 // ChannelBaseURLs provides backward compatibility by returning only the URL strings.
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Laisky/errors/v2"
 	gmw "github.com/Laisky/gin-middlewares/v7"
@@ -25,6 +26,7 @@ import (
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/model"
 	"github.com/songquanpeng/one-api/relay"
+	"github.com/songquanpeng/one-api/relay/adaptor/minimax"
 	"github.com/songquanpeng/one-api/relay/adaptor/openai"
 	"github.com/songquanpeng/one-api/relay/billing"
 	"github.com/songquanpeng/one-api/relay/channeltype"
@@ -95,19 +97,34 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	// group := c.GetString(ctxkey.Group)
 	tokenName := c.GetString(ctxkey.TokenName)
 
-	var ttsRequest openai.TextToSpeechRequest
+	// ttsInput is the text to synthesize, billed per character
+	var ttsInput string
 	if relayMode == relaymode.AudioSpeech {
-		// Read JSON
-		err := common.UnmarshalBodyReusable(c, &ttsRequest)
-		// Check if JSON is valid
-		if err != nil {
+		var ttsRequest openai.TextToSpeechRequest
+		if err := common.UnmarshalBodyReusable(c, &ttsRequest); err != nil {
 			return openai.ErrorWrapper(err, "invalid_json", http.StatusBadRequest)
 		}
+		if ttsRequest.Model == "" || ttsRequest.Input == "" {
+			return openai.ErrorWrapper(errors.New("model and input are required"), "invalid_request", http.StatusBadRequest)
+		}
+		if utf8.RuneCountInString(ttsRequest.Input) > maxTTSInputCharacters {
+			return openai.ErrorWrapper(errors.Errorf("input is too long (over %d characters)", maxTTSInputCharacters), "text_too_long", http.StatusBadRequest)
+		}
 		audioModel = ttsRequest.Model
-		// Check if text is too long 4096
-		if len(ttsRequest.Input) > 4096 {
-			return openai.ErrorWrapper(errors.New("input is too long (over 4096 characters)"), "text_too_long", http.StatusBadRequest)
+		ttsInput = ttsRequest.Input
+	} else if relayMode == relaymode.TextToSpeech {
+		if channelType != channeltype.Minimax {
+			return openai.ErrorWrapper(errors.New("text to speech is only supported by minimax channels"), "unsupported_channel", http.StatusBadRequest)
+		}
+		var t2aRequest minimax.TextToSpeechRequest
+		if err := common.UnmarshalBodyReusable(c, &t2aRequest); err != nil {
+			return openai.ErrorWrapper(err, "invalid_json", http.StatusBadRequest)
 		}
+		if t2aRequest.Text == "" {
+			return openai.ErrorWrapper(errors.New("text is required"), "invalid_request", http.StatusBadRequest)
+		}
+		audioModel = t2aRequest.Model
+		ttsInput = t2aRequest.Text
 	} else if relayMode == relaymode.AudioTranscription || relayMode == relaymode.AudioTranslation {
 		// Extract `model` from multipart form for transcription/translation
 		if m := extractAudioModelFromMultipart(c); m != "" {
@@ -127,10 +144,11 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	}
 
 	// Use three-layer pricing system
-	pricingAdaptor := relay.GetAdaptor(channelType)
+	pricingAdaptor := relay.GetAdaptor(channeltype.ToAPIType(channelType))
 	modelRatio := pricing.GetModelRatioWithThreeLayers(audioModel, channelModelRatio, pricingAdaptor)
 	groupRatio := c.GetFloat64(ctxkey.ChannelRatio)
 	ratio := modelRatio * groupRatio
+	logContent := fmt.Sprintf("model rate %.2f, group rate %.2f", modelRatio, groupRatio)
 
 	audioPricingCfg, hasAudioPricing := pricing.ResolveAudioPricing(audioModel, channelModelConfigs, pricingAdaptor)
 	tokensPerSecond := pricing.DefaultAudioPromptTokensPerSecond
@@ -141,7 +159,13 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	var preConsumedQuota int64
 	switch relayMode {
 	case relaymode.AudioSpeech:
-		preConsumedQuota = int64(float64(len(ttsRequest.Input)) * ratio)
+		characters := utf8.RuneCountInString(ttsInput)
+		characterRatio := ttsCharacterRatio(audioModel, modelRatio, channelModelConfigs, pricingAdaptor)
+		preConsumedQuota = ttsCharacterQuota(characters, characterRatio, groupRatio)
+		quota = preConsumedQuota
+		logContent = fmt.Sprintf("character rate %.4f, group rate %.2f, characters %d", characterRatio, groupRatio, characters)
+	case relaymode.TextToSpeech:
+		preConsumedQuota = int64(float64(utf8.RuneCountInString(ttsInput)) * ratio)
 		quota = preConsumedQuota
 	case relaymode.AudioTranscription,
 		relaymode.AudioTranslation:
@@ -164,15 +188,16 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	}
 
 	// Check if user quota is enough
-	if userQuota-preConsumedQuota < 0 {
+	if model.QuotaWithOverage(userQuota)-preConsumedQuota < 0 {
 		return openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
 	}
-	err = model.CacheDecreaseUserQuota(ctx, userId, preConsumedQuota)
+	trusted := userQuota > 100*preConsumedQuota &&
+		(tokenQuotaUnlimited || tokenQuota > 100*preConsumedQuota)
+	err = decreaseCachedUserQuota(ctx, userId, preConsumedQuota, !trusted && preConsumedQuota > 0)
 	if err != nil {
 		return openai.ErrorWrapper(err, "decrease_user_quota_failed", http.StatusInternalServerError)
 	}
-	if userQuota > 100*preConsumedQuota &&
-		(tokenQuotaUnlimited || tokenQuota > 100*preConsumedQuota) {
+	if trusted {
 		// in this case, we do not pre-consume quota
 		// because the user has enough quota
 		preConsumedQuota = 0
@@ -224,12 +249,24 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 			fullRequestURL = fmt.Sprintf("%s/openai/deployments/%s/audio/speech?api-version=%s", baseURL, audioModel, apiVersion)
 		}
 	}
+	if relayMode == relaymode.TextToSpeech {
+		meta.BaseURL = baseURL
+		if fullRequestURL, err = minimax.GetRequestURL(meta); err != nil {
+			return openai.ErrorWrapper(err, "get_request_url_failed", http.StatusBadRequest)
+		}
+	}
 
 	// Reconstruct the original request body from cache to ensure full payload is forwarded
 	rawBody, err := common.GetRequestBody(c)
 	if err != nil {
 		return openai.ErrorWrapper(err, "get_request_body_failed", http.StatusInternalServerError)
 	}
+	if relayMode == relaymode.AudioSpeech {
+		// Forward the mapped model while leaving optional fields untouched
+		if rawBody, err = rewriteSpeechModel(rawBody, audioModel); err != nil {
+			return openai.ErrorWrapper(err, "rewrite_request_body_failed", http.StatusInternalServerError)
+		}
+	}
 	requestBody := bytes.NewBuffer(rawBody)
 	// Reset gin Request.Body for any subsequent operations that may need it
 	c.Request.Body = io.NopCloser(bytes.NewReader(rawBody))
@@ -245,7 +282,9 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 		apiKey := c.Request.Header.Get("Authorization")
 		apiKey = strings.TrimPrefix(apiKey, "Bearer ")
 		req.Header.Set("api-key", apiKey)
-		req.ContentLength = c.Request.ContentLength
+		if relayMode == relaymode.AudioTranscription {
+			req.ContentLength = c.Request.ContentLength
+		}
 	} else {
 		req.Header.Set("Authorization", c.Request.Header.Get("Authorization"))
 	}
@@ -335,6 +374,14 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 		}
 		return RelayErrorHandler(resp)
 	}
+	if relayMode == relaymode.TextToSpeech {
+		if bizErr := minimax.TextToSpeechErrorHandler(resp); bizErr != nil {
+			if err := model.UpdateUserRequestCostQuotaByRequestID(userId, c.GetString(ctxkey.RequestId), 0); err != nil {
+				lg.Warn("update user request cost to zero failed", zap.Error(err))
+			}
+			return bizErr
+		}
+	}
 
 	succeed = true
 	quotaDelta := quota - preConsumedQuota
@@ -345,16 +392,21 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 		traceID = tid.String()
 	}
 
+	// audio APIs log the billed total as prompt tokens; speech logs the billed characters
+	promptTokens := int(quota)
+	if relayMode == relaymode.AudioSpeech {
+		promptTokens = utf8.RuneCountInString(ttsInput)
+	}
+
 	defer func() {
-		bgctx, cancel := context.WithTimeout(gmw.BackgroundCtx(c), time.Minute)
+		bgctx, cancel := context.WithTimeout(model.WithBodySizes(gmw.BackgroundCtx(c), model.CaptureBodySizes(c)), time.Minute)
 		defer cancel()
 
 		// Build a full log entry with IDs from gin.Context
-		logContent := fmt.Sprintf("model rate %.2f, group rate %.2f", modelRatio, groupRatio)
 		entry := &model.Log{
 			UserId:           userId,
 			ChannelId:        channelId,
-			PromptTokens:     int(quota), // audio API logs total as prompt tokens
+			PromptTokens:     promptTokens,
 			CompletionTokens: 0,
 			ModelName:        audioModel,
 			TokenName:        tokenName,
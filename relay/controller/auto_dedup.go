@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/metrics"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+const autoDedupKeyPrefix = "auto_dedup:"
+
+// autoDedupBackend stores the complete response of a chat completion by
+// request hash.
+type autoDedupBackend interface {
+	available() bool
+	// load returns the cached response of key, nil for a miss.
+	load(ctx context.Context, key string) ([]byte, error)
+	store(ctx context.Context, key string, response []byte, ttl time.Duration) error
+}
+
+// autoDedupStore is the backend used by the relay; tests replace it.
+var autoDedupStore autoDedupBackend = redisAutoDedup{}
+
+type redisAutoDedup struct{}
+
+func (redisAutoDedup) available() bool {
+	return common.IsRedisEnabled() && common.RDB != nil
+}
+
+func (redisAutoDedup) load(ctx context.Context, key string) ([]byte, error) {
+	response, err := common.RDB.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "get deduplicated response")
+	}
+	return response, nil
+}
+
+func (redisAutoDedup) store(ctx context.Context, key string, response []byte, ttl time.Duration) error {
+	if err := common.RDB.Set(ctx, key, response, ttl).Err(); err != nil {
+		return errors.Wrap(err, "store deduplicated response")
+	}
+	return nil
+}
+
+// autoDedupKey hashes the token, the model and the request body, messages
+// included, re-encoded with sorted keys, so requests differing only in key
+// order or whitespace share a key. It returns "" for requests whose response
+// may legitimately differ: streams, a temperature that is unset or above 0,
+// or no seed.
+func autoDedupKey(tokenId int, modelName string, body []byte) string {
+	var request map[string]any
+	if err := json.Unmarshal(body, &request); err != nil {
+		return ""
+	}
+	if temperature, ok := request["temperature"].(float64); !ok || temperature > 0 {
+		return ""
+	}
+	if request["seed"] == nil {
+		return ""
+	}
+	if stream, _ := request["stream"].(bool); stream {
+		return ""
+	}
+
+	canonical, err := json.Marshal(request)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strconv.Itoa(tokenId),
+		modelName,
+		string(canonical),
+	}, "\x00")))
+	return autoDedupKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// autoDedupLookup is the deduplication state of one chat completion.
+type autoDedupLookup struct {
+	key string
+	// cached is the response of an identical earlier request, nil on a miss.
+	cached []byte
+}
+
+// lookupAutoDedup looks up the response of an identical chat completion sent
+// with the same token within the deduplication window. It returns nil when
+// deduplication is disabled or the request is not eligible; a failing store is
+// treated as a miss.
+func lookupAutoDedup(c *gin.Context, meta *metalib.Meta) *autoDedupLookup {
+	if !config.AutoDedupEnabled || meta.Mode != relaymode.ChatCompletions || !autoDedupStore.available() {
+		return nil
+	}
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return nil
+	}
+	key := autoDedupKey(meta.TokenId, meta.OriginModelName, body)
+	if key == "" {
+		return nil
+	}
+
+	cached, err := autoDedupStore.load(gmw.Ctx(c), key)
+	if err != nil {
+		gmw.GetLogger(c).Warn("auto dedup lookup failed", zap.Error(err))
+		cached = nil
+	}
+	return &autoDedupLookup{key: key, cached: cached}
+}
+
+// hit reports whether the request is answered from the cache.
+func (l *autoDedupLookup) hit() bool {
+	return l.cached != nil
+}
+
+// respond writes the cached response and marks the request as a hit.
+func (l *autoDedupLookup) respond(c *gin.Context, modelName string) {
+	c.Set(ctxkey.AutoDedupHit, true)
+	metrics.GlobalRecorder.RecordAutoDedupHit(modelName)
+	c.Data(http.StatusOK, "application/json", l.cached)
+}
+
+// writeAutoDedupResponse forwards the response captured from the adaptor and
+// caches it for the deduplication window when the request succeeded.
+func writeAutoDedupResponse(c *gin.Context, lookup *autoDedupLookup, capture *responseCaptureWriter, succeeded bool) error {
+	body := capture.BodyBytes()
+	status := capture.StatusCode()
+	if !capture.HeaderWritten() && len(body) == 0 {
+		return nil
+	}
+	c.Writer.WriteHeader(status)
+	if _, err := c.Writer.Write(body); err != nil {
+		return errors.Wrap(err, "write chat completion response")
+	}
+
+	if succeeded && status == http.StatusOK {
+		ttl := time.Duration(config.AutoDedupWindowSeconds) * time.Second
+		if err := autoDedupStore.store(gmw.Ctx(c), lookup.key, body, ttl); err != nil {
+			gmw.GetLogger(c).Warn("auto dedup store failed", zap.Error(err))
+		}
+	}
+	return nil
+}
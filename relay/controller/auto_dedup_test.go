@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+type memoryAutoDedup struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	ttl    time.Duration
+}
+
+func (m *memoryAutoDedup) available() bool { return true }
+
+func (m *memoryAutoDedup) load(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[key], nil
+}
+
+func (m *memoryAutoDedup) store(_ context.Context, key string, response []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = response
+	m.ttl = ttl
+	return nil
+}
+
+func useMemoryAutoDedup(t *testing.T) *memoryAutoDedup {
+	t.Helper()
+	store := &memoryAutoDedup{values: map[string][]byte{}}
+	prevStore, prevEnabled := autoDedupStore, config.AutoDedupEnabled
+	autoDedupStore, config.AutoDedupEnabled = store, true
+	t.Cleanup(func() {
+		autoDedupStore, config.AutoDedupEnabled = prevStore, prevEnabled
+	})
+	return store
+}
+
+func TestAutoDedupKey(t *testing.T) {
+	body := `{"model":"gpt-4o","temperature":0,"seed":7,"messages":[{"role":"user","content":"hi"}]}`
+	key := autoDedupKey(1, "gpt-4o", []byte(body))
+	require.True(t, strings.HasPrefix(key, autoDedupKeyPrefix))
+
+	// Key order and whitespace do not matter
+	require.Equal(t, key, autoDedupKey(1, "gpt-4o",
+		[]byte(`{ "messages":[{"content":"hi","role":"user"}], "seed":7, "temperature":0, "model":"gpt-4o" }`)))
+
+	require.NotEqual(t, key, autoDedupKey(2, "gpt-4o", []byte(body)))
+	require.NotEqual(t, key, autoDedupKey(1, "gpt-4o-mini", []byte(body)))
+	require.NotEqual(t, key, autoDedupKey(1, "gpt-4o",
+		[]byte(`{"model":"gpt-4o","temperature":0,"seed":7,"messages":[{"role":"user","content":"hello"}]}`)))
+
+	// Requests that may legitimately differ are not deduplicated
+	for _, ineligible := range []string{
+		`{"model":"gpt-4o","temperature":0.7,"seed":7,"messages":[]}`,
+		`{"model":"gpt-4o","seed":7,"messages":[]}`,
+		`{"model":"gpt-4o","temperature":0,"messages":[]}`,
+		`{"model":"gpt-4o","temperature":0,"seed":7,"stream":true,"messages":[]}`,
+		`not json`,
+	} {
+		require.Empty(t, autoDedupKey(1, "gpt-4o", []byte(ineligible)), ineligible)
+	}
+}
+
+func TestRelayTextHelper_AutoDedup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ensureResponseFallbackFixtures(t)
+	store := useMemoryAutoDedup(t)
+
+	prevRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(prevRedis) })
+
+	prevLogConsume := config.IsLogConsumeEnabled()
+	config.SetLogConsumeEnabled(false)
+	t.Cleanup(func() { config.SetLogConsumeEnabled(prevLogConsume) })
+
+	var upstreamCalls int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"chatcmpl-%d","object":"chat.completion","created":1,"model":"gpt-4.1-nano",`+
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"Hello #%d"},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`, upstreamCalls, upstreamCalls)
+	}))
+	defer upstream.Close()
+
+	prevClient := client.HTTPClient
+	client.HTTPClient = upstream.Client()
+	t.Cleanup(func() { client.HTTPClient = prevClient })
+
+	send := func(body string) (*httptest.ResponseRecorder, *gin.Context) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer compat-key")
+		c.Request = req
+		gmw.SetLogger(c, logger.Logger)
+
+		c.Set(ctxkey.Channel, channeltype.OpenAICompatible)
+		c.Set(ctxkey.ChannelId, fallbackCompatibleChannelID)
+		c.Set(ctxkey.TokenId, fallbackTokenID)
+		c.Set(ctxkey.TokenName, "fallback-token")
+		c.Set(ctxkey.Id, fallbackUserID)
+		c.Set(ctxkey.Group, "default")
+		c.Set(ctxkey.ModelMapping, map[string]string{})
+		c.Set(ctxkey.ChannelRatio, 1.0)
+		c.Set(ctxkey.RequestModel, "gpt-4.1-nano")
+		c.Set(ctxkey.BaseURL, upstream.URL)
+		c.Set(ctxkey.ContentType, "application/json")
+		c.Set(ctxkey.RequestId, "req_auto_dedup")
+		c.Set(ctxkey.TokenQuotaUnlimited, true)
+		c.Set(ctxkey.TokenQuota, int64(0))
+		c.Set(ctxkey.Username, "response-fallback")
+		c.Set(ctxkey.UserQuota, int64(1_000_000))
+		c.Set(ctxkey.ChannelModel, &model.Channel{Id: fallbackCompatibleChannelID, Type: channeltype.OpenAICompatible})
+		c.Set(ctxkey.Config, model.ChannelConfig{})
+
+		require.Nil(t, RelayTextHelper(c))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		return recorder, c
+	}
+
+	body := `{"model":"gpt-4.1-nano","temperature":0,"seed":42,"messages":[{"role":"user","content":"Say hello"}]}`
+	first, c := send(body)
+	require.Equal(t, 1, upstreamCalls)
+	require.False(t, c.GetBool(ctxkey.AutoDedupHit))
+	require.Contains(t, first.Body.String(), "Hello #1")
+	require.Equal(t, 5*time.Second, store.ttl)
+
+	second, c := send(body)
+	require.Equal(t, 1, upstreamCalls)
+	require.True(t, c.GetBool(ctxkey.AutoDedupHit))
+	require.Equal(t, first.Body.String(), second.Body.String())
+
+	// A sampled request is always sent upstream
+	_, c = send(`{"model":"gpt-4.1-nano","temperature":1,"seed":42,"messages":[{"role":"user","content":"Say hello"}]}`)
+	require.Equal(t, 2, upstreamCalls)
+	require.False(t, c.GetBool(ctxkey.AutoDedupHit))
+}
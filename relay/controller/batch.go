@@ -0,0 +1,375 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+	"github.com/songquanpeng/one-api/relay/apitype"
+	"github.com/songquanpeng/one-api/relay/billing"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+// batchUpstream identifies the channel a Batch API request is sent to.
+type batchUpstream struct {
+	ChannelType int
+	BaseURL     string
+	APIKey      string
+}
+
+// RelayBatchHelper handles POST /v1/batches, GET /v1/batches/:batch_id and
+// POST /v1/batches/:batch_id/cancel. Created batches are recorded so that the
+// batch poller can bill them once they finish upstream.
+func RelayBatchHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
+	meta := metalib.GetByContext(c)
+	if bizErr := checkBatchChannel(meta.ChannelType); bizErr != nil {
+		return bizErr
+	}
+	upstream := batchUpstreamFromMeta(meta)
+
+	if c.Request.Method == http.MethodPost && c.Request.URL.Path == "/v1/batches" {
+		return createBatch(c, meta, upstream)
+	}
+
+	resp, err := doBatchUpstreamRequest(gmw.Ctx(c), upstream, c.Request.Method, c.Request.URL.Path, "", nil)
+	if err != nil {
+		return openai.ErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return RelayErrorHandlerWithContext(c, resp)
+	}
+	body, bizErr := readBatchResponse(resp)
+	if bizErr != nil {
+		return bizErr
+	}
+
+	if batch, err := model.GetRelayBatch(gmw.Ctx(c), c.Param("batch_id")); err == nil {
+		refreshRelayBatch(gmw.Ctx(c), batch, body)
+	} else {
+		gmw.GetLogger(c).Warn("relay batch missing while relaying batch request", zap.Error(err))
+	}
+	return writeBatchResponse(c, resp, body)
+}
+
+// createBatch pre-consumes the estimated quota of a batch, forwards the
+// creation request and records the new batch.
+func createBatch(c *gin.Context, meta *metalib.Meta, upstream batchUpstream) *relaymodel.ErrorWithStatusCode {
+	ctx := gmw.Ctx(c)
+	rawBody, err := common.GetRequestBody(c)
+	if err != nil {
+		return openai.ErrorWrapper(err, "get_request_body_failed", http.StatusInternalServerError)
+	}
+	var request struct {
+		InputFileID string `json:"input_file_id"`
+		Endpoint    string `json:"endpoint"`
+	}
+	if err := json.Unmarshal(rawBody, &request); err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "parse batch request"), "invalid_batch_request", http.StatusBadRequest)
+	}
+
+	binding, err := model.GetAsyncTaskBindingByTaskID(ctx, request.InputFileID)
+	if err != nil {
+		return openai.ErrorWrapper(err, "batch_input_file_not_found", http.StatusNotFound)
+	}
+	var input relaymodel.BatchInput
+	if err := json.Unmarshal([]byte(binding.RequestParams), &input); err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "parse batch input metadata"), "invalid_batch_input_file", http.StatusInternalServerError)
+	}
+	if request.Endpoint != input.Endpoint {
+		return openai.ErrorWrapper(errors.Errorf("endpoint must be %s to match the input file", input.Endpoint), "invalid_batch_request", http.StatusBadRequest)
+	}
+
+	batch := &model.RelayBatch{
+		UserId:       meta.UserId,
+		TokenId:      meta.TokenId,
+		TokenName:    meta.TokenName,
+		ChannelId:    meta.ChannelId,
+		OriginModel:  binding.OriginModel,
+		ActualModel:  binding.ActualModel,
+		Endpoint:     input.Endpoint,
+		GroupRatio:   c.GetFloat64(ctxkey.ChannelRatio),
+		InputFileId:  request.InputFileID,
+		RequestCount: input.RequestCount,
+	}
+	if bizErr := preConsumeBatchQuota(c, batch, &input); bizErr != nil {
+		return bizErr
+	}
+	succeed := false
+	defer func() {
+		if !succeed {
+			billing.ReturnPreConsumedQuota(ctx, batch.PreConsumedQuota, batch.TokenId)
+		}
+	}()
+
+	resp, err := doBatchUpstreamRequest(ctx, upstream, http.MethodPost, "/v1/batches", "application/json", bytes.NewReader(rawBody))
+	if err != nil {
+		return openai.ErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return RelayErrorHandlerWithContext(c, resp)
+	}
+	body, bizErr := readBatchResponse(resp)
+	if bizErr != nil {
+		return bizErr
+	}
+	var object relaymodel.BatchObject
+	if err := json.Unmarshal(body, &object); err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "parse upstream batch"), "invalid_upstream_response", http.StatusBadGateway)
+	}
+
+	batch.BatchId = object.ID
+	batch.OutputFileId = object.OutputFileID
+	batch.ErrorFileId = object.ErrorFileID
+	batch.Status = object.Status
+	if err := model.CreateRelayBatch(ctx, batch); err != nil {
+		return openai.ErrorWrapper(err, "create_relay_batch_failed", http.StatusInternalServerError)
+	}
+	succeed = true
+	gmw.GetLogger(c).Debug("relay batch created",
+		zap.String("batch_id", batch.BatchId),
+		zap.Int("channel_id", batch.ChannelId),
+		zap.Int("request_count", batch.RequestCount),
+		zap.Int64("pre_consumed_quota", batch.PreConsumedQuota))
+	return writeBatchResponse(c, resp, body)
+}
+
+// preConsumeBatchQuota reserves the estimated quota of a batch before it is
+// created upstream. The estimate prices the prompt tokens of the input file
+// plus the completion limits of its requests and config.PreConsumedQuota
+// tokens per request at the discounted batch price; settleRelayBatch
+// reconciles it with the actual usage.
+func preConsumeBatchQuota(c *gin.Context, batch *model.RelayBatch, input *relaymodel.BatchInput) *relaymodel.ErrorWithStatusCode {
+	ctx := gmw.Ctx(c)
+	channel, err := model.GetChannelById(batch.ChannelId, true)
+	if err != nil {
+		return openai.ErrorWrapper(err, "get_channel_failed", http.StatusInternalServerError)
+	}
+	estimate := &relaymodel.Usage{
+		PromptTokens:     input.PromptTokens,
+		CompletionTokens: input.MaxCompletionTokens + int(config.PreConsumedQuota)*input.RequestCount,
+	}
+	estimate.TotalTokens = estimate.PromptTokens + estimate.CompletionTokens
+	preConsumedQuota := computeBatchQuota(batch, channel, estimate)
+
+	userQuota, err := model.CacheGetUserQuota(ctx, batch.UserId)
+	if err != nil {
+		return openai.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
+	}
+	if userQuota <= 0 || model.QuotaWithOverage(userQuota)-preConsumedQuota < 0 {
+		return openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
+	}
+	if preConsumedQuota <= 0 {
+		return nil
+	}
+	if err := decreaseCachedUserQuota(ctx, batch.UserId, preConsumedQuota, true); err != nil {
+		return openai.ErrorWrapper(err, "decrease_user_quota_failed", http.StatusInternalServerError)
+	}
+	if err := model.PreConsumeTokenQuota(ctx, batch.TokenId, preConsumedQuota); err != nil {
+		return openai.ErrorWrapper(err, "pre_consume_token_quota_failed", http.StatusForbidden)
+	}
+	batch.PreConsumedQuota = preConsumedQuota
+	return nil
+}
+
+// RelayBatchFileHelper handles batch input uploads to POST /v1/files and
+// result downloads from GET /v1/files/:id/content. Uploads were validated by
+// middleware.BindBatchChannel; the file is bound to the channel it is
+// uploaded to so the batch using it is created on the same channel.
+func RelayBatchFileHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
+	meta := metalib.GetByContext(c)
+	if bizErr := checkBatchChannel(meta.ChannelType); bizErr != nil {
+		return bizErr
+	}
+	upstream := batchUpstreamFromMeta(meta)
+
+	if c.Request.Method == http.MethodGet && strings.HasSuffix(c.Request.URL.Path, "/content") {
+		resp, err := doBatchUpstreamRequest(gmw.Ctx(c), upstream, http.MethodGet, c.Request.URL.Path, "", nil)
+		if err != nil {
+			return openai.ErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return RelayErrorHandlerWithContext(c, resp)
+		}
+		defer resp.Body.Close()
+		copyBatchResponseHeader(c, resp)
+		c.Writer.WriteHeader(resp.StatusCode)
+		if _, err := io.Copy(c.Writer, resp.Body); err != nil {
+			return openai.ErrorWrapper(errors.Wrap(err, "copy batch file content"), "write_response_body_failed", http.StatusInternalServerError)
+		}
+		return nil
+	}
+
+	raw, ok := c.Get(ctxkey.BatchInput)
+	if !ok || c.Request.Method != http.MethodPost {
+		return openai.ErrorWrapper(errors.New("only batch input files can be uploaded"), "api_not_implemented", http.StatusNotImplemented)
+	}
+	return uploadBatchInput(c, meta, upstream, raw.(*relaymodel.BatchInput))
+}
+
+// uploadBatchInput forwards a validated batch input file, applying the
+// channel model mapping, and records which channel holds the file.
+func uploadBatchInput(c *gin.Context, meta *metalib.Meta, upstream batchUpstream, input *relaymodel.BatchInput) *relaymodel.ErrorWithStatusCode {
+	ctx := gmw.Ctx(c)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "get batch input file"), "invalid_batch_input_file", http.StatusBadRequest)
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "open batch input file"), "invalid_batch_input_file", http.StatusBadRequest)
+	}
+	data, err := io.ReadAll(file)
+	_ = file.Close()
+	if err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "read batch input file"), "invalid_batch_input_file", http.StatusBadRequest)
+	}
+
+	meta.OriginModelName = input.Model
+	meta.ActualModelName = metalib.GetMappedModelName(input.Model, meta.ModelMapping)
+	metalib.Set2Context(c, meta)
+	if meta.ActualModelName != input.Model {
+		if data, err = relaymodel.RewriteBatchInputModel(data, meta.ActualModelName); err != nil {
+			return openai.ErrorWrapper(err, "invalid_batch_input_file", http.StatusBadRequest)
+		}
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("purpose", "batch"); err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "write purpose field"), "build_request_failed", http.StatusInternalServerError)
+	}
+	part, err := writer.CreateFormFile("file", fileHeader.Filename)
+	if err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "create file field"), "build_request_failed", http.StatusInternalServerError)
+	}
+	if _, err := part.Write(data); err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "write file field"), "build_request_failed", http.StatusInternalServerError)
+	}
+	if err := writer.Close(); err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "close multipart writer"), "build_request_failed", http.StatusInternalServerError)
+	}
+
+	resp, err := doBatchUpstreamRequest(ctx, upstream, http.MethodPost, "/v1/files", writer.FormDataContentType(), &body)
+	if err != nil {
+		return openai.ErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return RelayErrorHandlerWithContext(c, resp)
+	}
+	respBody, bizErr := readBatchResponse(resp)
+	if bizErr != nil {
+		return bizErr
+	}
+	var uploaded struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &uploaded); err != nil || uploaded.ID == "" {
+		return openai.ErrorWrapper(errors.Errorf("upstream file upload returned no id: %v", err), "invalid_upstream_response", http.StatusBadGateway)
+	}
+
+	input.PromptTokens = openai.CountTokenText(string(data), meta.ActualModelName)
+	params, err := json.Marshal(input)
+	if err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "marshal batch input metadata"), "build_request_failed", http.StatusInternalServerError)
+	}
+	if err := model.SaveAsyncTaskBinding(ctx, &model.AsyncTaskBinding{
+		TaskID:        uploaded.ID,
+		TaskType:      model.AsyncTaskTypeBatchInputFile,
+		UserID:        meta.UserId,
+		TokenID:       meta.TokenId,
+		ChannelID:     meta.ChannelId,
+		ChannelType:   meta.ChannelType,
+		OriginModel:   meta.OriginModelName,
+		ActualModel:   meta.ActualModelName,
+		RequestMethod: c.Request.Method,
+		RequestPath:   c.Request.URL.Path,
+		RequestParams: string(params),
+	}); err != nil {
+		return openai.ErrorWrapper(err, "save_batch_input_binding_failed", http.StatusInternalServerError)
+	}
+	return writeBatchResponse(c, resp, respBody)
+}
+
+// checkBatchChannel rejects channels that do not speak the OpenAI Batch API.
+func checkBatchChannel(channelType int) *relaymodel.ErrorWithStatusCode {
+	if channeltype.ToAPIType(channelType) != apitype.OpenAI || channelType == channeltype.Azure {
+		return openai.ErrorWrapper(errors.New("Batch API is only supported for OpenAI compatible channels"), "unsupported_channel", http.StatusBadRequest)
+	}
+	return nil
+}
+
+func batchUpstreamFromMeta(meta *metalib.Meta) batchUpstream {
+	return batchUpstream{ChannelType: meta.ChannelType, BaseURL: meta.BaseURL, APIKey: meta.APIKey}
+}
+
+func batchUpstreamFromChannel(channel *model.Channel) batchUpstream {
+	baseURL := channel.GetBaseURL()
+	if baseURL == "" && channel.Type < len(channeltype.ChannelBaseURLs) {
+		baseURL = channeltype.ChannelBaseURLs[channel.Type]
+	}
+	return batchUpstream{ChannelType: channel.Type, BaseURL: baseURL, APIKey: channel.Key}
+}
+
+// doBatchUpstreamRequest sends a Batch API or file request to upstream.
+func doBatchUpstreamRequest(ctx context.Context, upstream batchUpstream, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	requestURL := openai.GetFullRequestURL(upstream.BaseURL, path, upstream.ChannelType)
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "build batch request %s %s", method, path)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Authorization", "Bearer "+upstream.APIKey)
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "do batch request %s %s", method, path)
+	}
+	return resp, nil
+}
+
+func readBatchResponse(resp *http.Response) ([]byte, *relaymodel.ErrorWithStatusCode) {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, openai.ErrorWrapper(errors.Wrap(err, "read batch response"), "read_response_body_failed", http.StatusInternalServerError)
+	}
+	return body, nil
+}
+
+func copyBatchResponseHeader(c *gin.Context, resp *http.Response) {
+	for _, key := range []string{"Content-Type", "Content-Disposition"} {
+		if value := resp.Header.Get(key); value != "" {
+			c.Writer.Header().Set(key, value)
+		}
+	}
+}
+
+func writeBatchResponse(c *gin.Context, resp *http.Response, body []byte) *relaymodel.ErrorWithStatusCode {
+	copyBatchResponseHeader(c, resp)
+	if resp.Header.Get("Content-Type") == "" {
+		c.Writer.Header().Set("Content-Type", "application/json")
+	}
+	c.Writer.WriteHeader(resp.StatusCode)
+	if _, err := c.Writer.Write(body); err != nil {
+		return openai.ErrorWrapper(errors.Wrap(err, "write batch response"), "write_response_body_failed", http.StatusInternalServerError)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	"github.com/Laisky/zap"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay"
+	"github.com/songquanpeng/one-api/relay/billing"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/pricing"
+	quotautil "github.com/songquanpeng/one-api/relay/quota"
+)
+
+// batchPollLimit bounds the batches checked upstream per poll.
+const batchPollLimit = 100
+
+// StartBatchPoller launches a background worker that polls unfinished Batch
+// API batches every config.BatchAPIPollInterval seconds and bills them once
+// they finish, see PollRelayBatches.
+func StartBatchPoller(ctx context.Context) {
+	if !config.BatchAPIEnabled {
+		logger.Logger.Debug("batch poller disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(config.BatchAPIPollInterval) * time.Second)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Logger.Info("batch poller stopped")
+				return
+			case <-ticker.C:
+				if err := PollRelayBatches(ctx); err != nil {
+					logger.Logger.Warn("batch poll failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// PollRelayBatches refreshes the status of unsettled batches and bills the
+// finished ones, least recently polled first. Failures of single batches are
+// logged and retried once the other pending batches had their turn.
+func PollRelayBatches(ctx context.Context) error {
+	batches, err := model.GetUnsettledRelayBatches(ctx, batchPollLimit)
+	if err != nil {
+		return errors.Wrap(err, "get unsettled relay batches")
+	}
+	for _, batch := range batches {
+		if err := pollRelayBatch(ctx, batch); err != nil {
+			logger.Logger.Warn("poll relay batch failed",
+				zap.String("batch_id", batch.BatchId),
+				zap.Int("channel_id", batch.ChannelId),
+				zap.Error(err))
+		}
+		if batch.SettledAt == 0 {
+			if err := model.TouchRelayBatch(ctx, batch); err != nil {
+				logger.Logger.Warn("touch relay batch failed", zap.String("batch_id", batch.BatchId), zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
+
+// pollRelayBatch fetches one batch from upstream and settles it when finished.
+// Batches whose channel was deleted or lost its key can no longer be fetched
+// and are settled at the quota reserved when they were created.
+func pollRelayBatch(ctx context.Context, batch *model.RelayBatch) error {
+	channel, err := model.GetChannelById(batch.ChannelId, true)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && channel.Key == "") {
+		return settleOrphanedRelayBatch(ctx, batch)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "get channel %d", batch.ChannelId)
+	}
+	upstream := batchUpstreamFromChannel(channel)
+
+	body, err := getBatchUpstream(ctx, upstream, "/v1/batches/"+batch.BatchId)
+	if err != nil {
+		return errors.Wrap(err, "retrieve batch")
+	}
+	refreshRelayBatch(ctx, batch, body)
+	if !batch.IsFinished() {
+		return nil
+	}
+
+	usage := &relaymodel.Usage{}
+	if batch.OutputFileId != "" {
+		output, err := getBatchUpstream(ctx, upstream, "/v1/files/"+batch.OutputFileId+"/content")
+		if err != nil {
+			return errors.Wrap(err, "download batch output")
+		}
+		if usage, _, err = relaymodel.SumBatchOutputUsage(output); err != nil {
+			return errors.Wrap(err, "sum batch output usage")
+		}
+	}
+	return settleRelayBatch(ctx, batch, channel, usage)
+}
+
+// refreshRelayBatch records the status and result files reported by an
+// upstream batch object.
+func refreshRelayBatch(ctx context.Context, batch *model.RelayBatch, body []byte) {
+	var object relaymodel.BatchObject
+	if err := json.Unmarshal(body, &object); err != nil {
+		logger.Logger.Warn("parse upstream batch failed", zap.String("batch_id", batch.BatchId), zap.Error(err))
+		return
+	}
+	if object.Status == batch.Status && object.OutputFileID == batch.OutputFileId && object.ErrorFileID == batch.ErrorFileId {
+		return
+	}
+	batch.Status = object.Status
+	batch.OutputFileId = object.OutputFileID
+	batch.ErrorFileId = object.ErrorFileID
+	if err := model.UpdateRelayBatchStatus(ctx, batch); err != nil {
+		logger.Logger.Warn("update relay batch status failed", zap.String("batch_id", batch.BatchId), zap.Error(err))
+	}
+}
+
+// settleRelayBatch bills the usage of a finished batch at the discounted
+// batch price, charging or refunding the difference to the quota
+// pre-consumed at creation. Batches already settled by another poller are
+// skipped.
+func settleRelayBatch(ctx context.Context, batch *model.RelayBatch, channel *model.Channel, usage *relaymodel.Usage) error {
+	batch.PromptTokens = usage.PromptTokens
+	batch.CompletionTokens = usage.CompletionTokens
+	batch.Quota = computeBatchQuota(batch, channel, usage)
+	return billRelayBatch(ctx, batch, fmt.Sprintf("batch %s %s, %d requests, discount ratio %.2f, group rate %.2f",
+		batch.BatchId, batch.Status, batch.RequestCount, config.BatchAPIDiscountRatio, batch.GroupRatio))
+}
+
+// settleOrphanedRelayBatch settles a batch whose channel is gone. Its usage
+// is unknown, so the quota reserved at creation is kept as the final charge.
+func settleOrphanedRelayBatch(ctx context.Context, batch *model.RelayBatch) error {
+	batch.Quota = batch.PreConsumedQuota
+	return billRelayBatch(ctx, batch, fmt.Sprintf("batch %s %s, %d requests, channel %d is gone, reserved quota kept",
+		batch.BatchId, batch.Status, batch.RequestCount, batch.ChannelId))
+}
+
+// billRelayBatch marks batch settled and reconciles its quota against the
+// reservation, unless another poller settled it first.
+func billRelayBatch(ctx context.Context, batch *model.RelayBatch, content string) error {
+	settled, err := model.SettleRelayBatch(ctx, batch)
+	if err != nil {
+		return errors.Wrap(err, "settle relay batch")
+	}
+	if !settled {
+		return nil
+	}
+
+	billing.PostConsumeQuotaWithLog(ctx, batch.TokenId, batch.Quota-batch.PreConsumedQuota, batch.Quota, &model.Log{
+		UserId:           batch.UserId,
+		ChannelId:        batch.ChannelId,
+		PromptTokens:     batch.PromptTokens,
+		CompletionTokens: batch.CompletionTokens,
+		ModelName:        batch.ActualModel,
+		TokenName:        batch.TokenName,
+		Content:          content,
+	})
+	logger.Logger.Info("relay batch settled",
+		zap.String("batch_id", batch.BatchId),
+		zap.String("status", batch.Status),
+		zap.Int64("quota", batch.Quota))
+	return nil
+}
+
+// computeBatchQuota prices usage like a synchronous request to the batch
+// model and applies config.BatchAPIDiscountRatio.
+func computeBatchQuota(batch *model.RelayBatch, channel *model.Channel, usage *relaymodel.Usage) int64 {
+	pricingAdaptor := relay.GetAdaptor(channeltype.ToAPIType(channel.Type))
+	result := quotautil.Compute(quotautil.ComputeInput{
+		Usage:                  usage,
+		ModelName:              batch.ActualModel,
+		ModelRatio:             pricing.GetModelRatioWithThreeLayers(batch.ActualModel, channel.GetModelRatioFromConfigs(), pricingAdaptor),
+		GroupRatio:             batch.GroupRatio,
+		ChannelCompletionRatio: channel.GetCompletionRatioFromConfigs(),
+		PricingAdaptor:         pricingAdaptor,
+	})
+	return int64(math.Ceil(float64(result.TotalQuota) * config.BatchAPIDiscountRatio))
+}
+
+// getBatchUpstream reads a successful GET response from upstream.
+func getBatchUpstream(ctx context.Context, upstream batchUpstream, path string) ([]byte, error) {
+	resp, err := doBatchUpstreamRequest(ctx, upstream, http.MethodGet, path, "", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "request upstream")
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s returned status %d", path, resp.StatusCode)
+	}
+	return body, nil
+}
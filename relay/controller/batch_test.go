@@ -0,0 +1,329 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay"
+	"github.com/songquanpeng/one-api/relay/apitype"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/pricing"
+	quotautil "github.com/songquanpeng/one-api/relay/quota"
+)
+
+const (
+	batchTestChannelID = 916401
+	batchTestUserID    = 916402
+	batchTestTokenID   = 916403
+	batchTestUserQuota = int64(10_000_000)
+)
+
+const batchTestOutput = `{"custom_id":"a","response":{"status_code":200,"body":{"usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}}}
+{"custom_id":"b","response":{"status_code":200,"body":{"usage":{"prompt_tokens":3000,"completion_tokens":1500,"total_tokens":4500}}}}
+`
+
+// fakeBatchUpstream mimics the OpenAI file and batch endpoints.
+type fakeBatchUpstream struct {
+	completed atomic.Bool
+	uploaded  atomic.Value
+}
+
+func (f *fakeBatchUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	status := "in_progress"
+	outputFile := ""
+	if f.completed.Load() {
+		status, outputFile = "completed", "file-out"
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/files":
+		if r.FormValue("purpose") != "batch" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		f.uploaded.Store(string(data))
+		_, _ = w.Write([]byte(`{"id":"file-in","object":"file","purpose":"batch"}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/batches":
+		_, _ = w.Write([]byte(`{"id":"batch_1","object":"batch","status":"validating","input_file_id":"file-in"}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/batches/batch_1":
+		_, _ = w.Write([]byte(`{"id":"batch_1","object":"batch","status":"` + status + `","output_file_id":"` + outputFile + `"}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/files/file-out/content":
+		w.Header().Set("Content-Type", "application/jsonl")
+		_, _ = w.Write([]byte(batchTestOutput))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func setupBatchTest(t *testing.T) (*fakeBatchUpstream, string) {
+	t.Helper()
+	ensureResponseFallbackDB(t)
+	require.NoError(t, model.DB.AutoMigrate(&model.User{}, &model.Token{}, &model.Channel{},
+		&model.Log{}, &model.AsyncTaskBinding{}, &model.RelayBatch{}))
+	if client.HTTPClient == nil {
+		client.Init()
+	}
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	originalRatio := config.BatchAPIDiscountRatio
+	config.BatchAPIDiscountRatio = 0.5
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedis)
+		config.BatchAPIDiscountRatio = originalRatio
+		model.DB.Unscoped().Where("id = ?", batchTestUserID).Delete(&model.User{})
+	})
+
+	upstream := &fakeBatchUpstream{}
+	server := httptest.NewServer(upstream)
+	t.Cleanup(server.Close)
+
+	require.NoError(t, model.DB.Where("id = ?", batchTestChannelID).Delete(&model.Channel{}).Error)
+	require.NoError(t, model.DB.Unscoped().Where("id = ?", batchTestUserID).Delete(&model.User{}).Error)
+	require.NoError(t, model.DB.Where("id = ?", batchTestTokenID).Delete(&model.Token{}).Error)
+	require.NoError(t, model.DB.Where("user_id = ?", batchTestUserID).Delete(&model.RelayBatch{}).Error)
+	require.NoError(t, model.DB.Where("user_id = ?", batchTestUserID).Delete(&model.AsyncTaskBinding{}).Error)
+	baseURL := server.URL
+	require.NoError(t, model.DB.Create(&model.Channel{
+		Id:      batchTestChannelID,
+		Type:    channeltype.OpenAI,
+		Name:    "batch",
+		Key:     "batch-key",
+		Status:  model.ChannelStatusEnabled,
+		BaseURL: &baseURL,
+		Models:  "gpt-4o-mini",
+	}).Error)
+	require.NoError(t, model.DB.Create(&model.User{
+		Id: batchTestUserID, Username: "batch-user", AccessToken: "batch-access-token", AffCode: "batch-aff",
+		Quota: batchTestUserQuota, Status: model.UserStatusEnabled,
+	}).Error)
+	require.NoError(t, model.DB.Create(&model.Token{
+		Id: batchTestTokenID, UserId: batchTestUserID, Key: "batch-token-key", Name: "batch-token",
+		Status: model.TokenStatusEnabled, UnlimitedQuota: true,
+	}).Error)
+	return upstream, server.URL
+}
+
+func newBatchTestContext(method, path, contentType string, body []byte, baseURL string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(method, path, bytes.NewReader(body))
+	if contentType != "" {
+		c.Request.Header.Set("Content-Type", contentType)
+	}
+	c.Set(ctxkey.ChannelRatio, 1.0)
+	metalib.Set2Context(c, &metalib.Meta{
+		ChannelType:  channeltype.OpenAI,
+		ChannelId:    batchTestChannelID,
+		UserId:       batchTestUserID,
+		TokenId:      batchTestTokenID,
+		TokenName:    "batch-token",
+		APIType:      apitype.OpenAI,
+		BaseURL:      baseURL,
+		APIKey:       "batch-key",
+		ModelMapping: map[string]string{"gpt-4o-mini": "gpt-4o-mini-2024-07-18"},
+	})
+	return c, recorder
+}
+
+func TestRelayBatch_LifecycleBillsDiscountedUsage(t *testing.T) {
+	upstream, baseURL := setupBatchTest(t)
+	ctx := context.Background()
+
+	// Upload the batch input file
+	input := `{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","messages":[]}}
+{"custom_id":"b","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","messages":[]}}
+`
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	require.NoError(t, writer.WriteField("purpose", "batch"))
+	part, err := writer.CreateFormFile("file", "input.jsonl")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(input))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	parsed, err := relaymodel.ParseBatchInput([]byte(input), 10)
+	require.NoError(t, err)
+	c, recorder := newBatchTestContext(http.MethodPost, "/v1/files", writer.FormDataContentType(), form.Bytes(), baseURL)
+	c.Set(ctxkey.BatchInput, parsed)
+	require.Nil(t, RelayBatchFileHelper(c))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, upstream.uploaded.Load().(string), `"model":"gpt-4o-mini-2024-07-18"`)
+
+	binding, err := model.GetAsyncTaskBindingByTaskID(ctx, "file-in")
+	require.NoError(t, err)
+	require.Equal(t, model.AsyncTaskTypeBatchInputFile, binding.TaskType)
+	require.Equal(t, batchTestChannelID, binding.ChannelID)
+	require.Equal(t, "gpt-4o-mini-2024-07-18", binding.ActualModel)
+
+	// Batches must target the endpoint of their input file
+	c, _ = newBatchTestContext(http.MethodPost, "/v1/batches", "application/json",
+		[]byte(`{"input_file_id":"file-in","endpoint":"/v1/embeddings","completion_window":"24h"}`), baseURL)
+	bizErr := RelayBatchHelper(c)
+	require.NotNil(t, bizErr)
+	require.Equal(t, http.StatusBadRequest, bizErr.StatusCode)
+
+	c, recorder = newBatchTestContext(http.MethodPost, "/v1/batches", "application/json",
+		[]byte(`{"input_file_id":"file-in","endpoint":"/v1/chat/completions","completion_window":"24h"}`), baseURL)
+	require.Nil(t, RelayBatchHelper(c))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	batch, err := model.GetRelayBatch(ctx, "batch_1")
+	require.NoError(t, err)
+	require.Equal(t, "validating", batch.Status)
+	require.Equal(t, 2, batch.RequestCount)
+	require.Equal(t, "gpt-4o-mini-2024-07-18", batch.ActualModel)
+
+	// The estimated cost is reserved when the batch is created
+	require.Positive(t, batch.PreConsumedQuota)
+	user, err := model.GetUserById(batchTestUserID, true)
+	require.NoError(t, err)
+	require.Equal(t, batchTestUserQuota-batch.PreConsumedQuota, user.Quota)
+
+	// Unfinished batches are tracked but not billed
+	require.NoError(t, PollRelayBatches(ctx))
+	batch, err = model.GetRelayBatch(ctx, "batch_1")
+	require.NoError(t, err)
+	require.Equal(t, "in_progress", batch.Status)
+	require.Zero(t, batch.SettledAt)
+
+	// Finished batches are billed once at the discounted price
+	upstream.completed.Store(true)
+	require.NoError(t, PollRelayBatches(ctx))
+	require.NoError(t, PollRelayBatches(ctx))
+	batch, err = model.GetRelayBatch(ctx, "batch_1")
+	require.NoError(t, err)
+	require.Equal(t, model.RelayBatchStatusCompleted, batch.Status)
+	require.Equal(t, "file-out", batch.OutputFileId)
+	require.NotZero(t, batch.SettledAt)
+	require.Equal(t, 4000, batch.PromptTokens)
+	require.Equal(t, 2000, batch.CompletionTokens)
+
+	pricingAdaptor := relay.GetAdaptor(apitype.OpenAI)
+	full := quotautil.Compute(quotautil.ComputeInput{
+		Usage:          &relaymodel.Usage{PromptTokens: 4000, CompletionTokens: 2000, TotalTokens: 6000},
+		ModelName:      batch.ActualModel,
+		ModelRatio:     pricing.GetModelRatioWithThreeLayers(batch.ActualModel, nil, pricingAdaptor),
+		GroupRatio:     1.0,
+		PricingAdaptor: pricingAdaptor,
+	}).TotalQuota
+	require.Positive(t, full)
+	require.Equal(t, int64(math.Ceil(float64(full)*0.5)), batch.Quota)
+
+	// The reservation is reconciled against the actual usage
+	user, err = model.GetUserById(batchTestUserID, true)
+	require.NoError(t, err)
+	require.Equal(t, batchTestUserQuota-batch.Quota, user.Quota)
+
+	var logs []model.Log
+	require.NoError(t, model.LOG_DB.Where("user_id = ?", batchTestUserID).Find(&logs).Error)
+	require.Len(t, logs, 1)
+	require.EqualValues(t, batch.Quota, logs[0].Quota)
+	require.True(t, strings.HasPrefix(logs[0].Content, "batch batch_1 completed"))
+
+	// Results are downloaded from the channel that ran the batch
+	c, recorder = newBatchTestContext(http.MethodGet, "/v1/files/file-out/content", "", nil, baseURL)
+	require.Nil(t, RelayBatchFileHelper(c))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, batchTestOutput, recorder.Body.String())
+}
+
+func TestPollRelayBatches_RotatesAndSettlesOrphans(t *testing.T) {
+	setupBatchTest(t)
+	ctx := context.Background()
+
+	// A batch upstream no longer knows fails on every poll
+	failing := &model.RelayBatch{BatchId: "batch_missing", UserId: batchTestUserID, TokenId: batchTestTokenID,
+		ChannelId: batchTestChannelID, ActualModel: "gpt-4o-mini", Status: "in_progress", UpdatedAt: 1}
+	// A batch whose channel was deleted cannot be fetched at all
+	orphan := &model.RelayBatch{BatchId: "batch_orphan", UserId: batchTestUserID, TokenId: batchTestTokenID,
+		ChannelId: batchTestChannelID + 1000, ActualModel: "gpt-4o-mini", Status: "in_progress",
+		RequestCount: 3, PreConsumedQuota: 1234, UpdatedAt: 2}
+	require.NoError(t, model.DB.Create(failing).Error)
+	require.NoError(t, model.DB.Create(orphan).Error)
+
+	require.NoError(t, PollRelayBatches(ctx))
+
+	orphan, err := model.GetRelayBatch(ctx, "batch_orphan")
+	require.NoError(t, err)
+	require.NotZero(t, orphan.SettledAt)
+	require.EqualValues(t, 1234, orphan.Quota)
+
+	var logs []model.Log
+	require.NoError(t, model.LOG_DB.Where("user_id = ? AND content LIKE ?", batchTestUserID, "batch batch_orphan %").Find(&logs).Error)
+	require.Len(t, logs, 1)
+	require.EqualValues(t, 1234, logs[0].Quota)
+	require.Contains(t, logs[0].Content, "channel 917401 is gone")
+
+	// The failed batch moves behind batches that were polled less recently
+	failing, err = model.GetRelayBatch(ctx, "batch_missing")
+	require.NoError(t, err)
+	require.Zero(t, failing.SettledAt)
+	require.Greater(t, failing.UpdatedAt, int64(2))
+
+	waiting := &model.RelayBatch{BatchId: "batch_waiting", UserId: batchTestUserID, TokenId: batchTestTokenID,
+		ChannelId: batchTestChannelID, Status: "in_progress", UpdatedAt: 3}
+	require.NoError(t, model.DB.Create(waiting).Error)
+	batches, err := model.GetUnsettledRelayBatches(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Equal(t, "batch_waiting", batches[0].BatchId)
+}
+
+func TestRelayBatch_ReturnsReservationWhenCreationFails(t *testing.T) {
+	setupBatchTest(t)
+	ctx := context.Background()
+	input, err := json.Marshal(relaymodel.BatchInput{
+		Model: "gpt-4o-mini", Endpoint: "/v1/chat/completions", RequestCount: 3, PromptTokens: 1000,
+	})
+	require.NoError(t, err)
+	require.NoError(t, model.SaveAsyncTaskBinding(ctx, &model.AsyncTaskBinding{
+		TaskID: "file-in", TaskType: model.AsyncTaskTypeBatchInputFile, UserID: batchTestUserID,
+		TokenID: batchTestTokenID, ChannelID: batchTestChannelID, ChannelType: channeltype.OpenAI,
+		OriginModel: "gpt-4o-mini", ActualModel: "gpt-4o-mini", RequestParams: string(input),
+	}))
+
+	// The upstream is gone, so the batch is never created
+	c, _ := newBatchTestContext(http.MethodPost, "/v1/batches", "application/json",
+		[]byte(`{"input_file_id":"file-in","endpoint":"/v1/chat/completions","completion_window":"24h"}`), "http://127.0.0.1:1")
+	require.NotNil(t, RelayBatchHelper(c))
+
+	user, err := model.GetUserById(batchTestUserID, true)
+	require.NoError(t, err)
+	require.Equal(t, batchTestUserQuota, user.Quota)
+}
+
+func TestRelayBatchFileHelper_RejectsNonBatchUploads(t *testing.T) {
+	_, baseURL := setupBatchTest(t)
+
+	c, _ := newBatchTestContext(http.MethodPost, "/v1/files", "application/json", []byte(`{}`), baseURL)
+	bizErr := RelayBatchFileHelper(c)
+	require.NotNil(t, bizErr)
+	require.Equal(t, http.StatusNotImplemented, bizErr.StatusCode)
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/metrics"
+	"github.com/songquanpeng/one-api/model"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+)
+
+// bodySizes holds the body sizes of a relay request, read on the handler
+// goroutine since gin recycles the context once the handler returns, while
+// they are recorded by the billing goroutine.
+type bodySizes struct {
+	modelName    string
+	channelId    int
+	responseType string
+	// upstreamRequestBytes and upstreamResponseBytes feed the body size
+	// metrics. For streams the response size is the total of the chunks read
+	// so far. Sizes that were not measured are -1.
+	upstreamRequestBytes  int64
+	upstreamResponseBytes int64
+	// client feeds the consume log metadata.
+	client model.BodySizes
+}
+
+// captureBodySizes reads the sizes of the request body sent upstream, of the
+// response body read from upstream and of the client bodies.
+func captureBodySizes(c *gin.Context, meta *metalib.Meta) *bodySizes {
+	sizes := &bodySizes{
+		modelName:             meta.ActualModelName,
+		channelId:             meta.ChannelId,
+		responseType:          "non_stream",
+		upstreamRequestBytes:  -1,
+		upstreamResponseBytes: -1,
+		client:                model.CaptureBodySizes(c),
+	}
+	if meta.IsStream {
+		sizes.responseType = "stream"
+	}
+	if size, ok := c.Get(ctxkey.UpstreamRequestBytes); ok {
+		if size, ok := size.(int64); ok {
+			sizes.upstreamRequestBytes = size
+		}
+	}
+	if counter, ok := c.Get(ctxkey.UpstreamResponseBytes); ok {
+		if counter, ok := counter.(*atomic.Int64); ok {
+			sizes.upstreamResponseBytes = counter.Load()
+		}
+	}
+	return sizes
+}
+
+// record reports the upstream body sizes to the metrics.
+func (s *bodySizes) record() {
+	metrics.GlobalRecorder.RecordUpstreamBodySizes(s.modelName, s.channelId, s.responseType, s.upstreamRequestBytes, s.upstreamResponseBytes)
+}
+
+// context returns ctx carrying the client body sizes for the consume log.
+func (s *bodySizes) context(ctx context.Context) context.Context {
+	return model.WithBodySizes(ctx, s.client)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/songquanpeng/one-api/common/tracing"
 	"github.com/songquanpeng/one-api/model"
 	"github.com/songquanpeng/one-api/relay"
+	"github.com/songquanpeng/one-api/relay/adaptor"
 	"github.com/songquanpeng/one-api/relay/adaptor/anthropic"
 	"github.com/songquanpeng/one-api/relay/adaptor/openai"
 	"github.com/songquanpeng/one-api/relay/billing"
@@ -63,6 +64,18 @@ func RelayClaudeMessagesHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 
 	sanitizeClaudeMessagesRequest(claudeRequest)
 
+	adaptorInstance := relay.GetAdaptor(meta.APIType)
+	if adaptorInstance == nil {
+		return openai.ErrorWrapper(errors.New("invalid api type"), "invalid_api_type", http.StatusBadRequest)
+	}
+	var channelRecord *model.Channel
+	if channelModel, ok := c.Get(ctxkey.ChannelModel); ok {
+		channelRecord, _ = channelModel.(*model.Channel)
+	}
+	if bizErr := validateModelFeatures(claudeRequest.Model, adaptor.ClaudeRequestFeatures(claudeRequest), channelRecord, adaptorInstance); bizErr != nil {
+		return bizErr
+	}
+
 	// get channel model ratio
 	channelModelRatio, channelCompletionRatio := getChannelRatios(c)
 
@@ -84,10 +97,6 @@ func RelayClaudeMessagesHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 		return bizErr
 	}
 
-	adaptorInstance := relay.GetAdaptor(meta.APIType)
-	if adaptorInstance == nil {
-		return openai.ErrorWrapper(errors.New("invalid api type"), "invalid_api_type", http.StatusBadRequest)
-	}
 	adaptorInstance.Init(meta)
 
 	// convert request using adaptor's ConvertClaudeRequest method
@@ -193,6 +202,8 @@ func RelayClaudeMessagesHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 
 	// Set context flag to indicate Claude Messages native mode
 	c.Set(ctxkey.ClaudeMessagesNative, true)
+	stopWatchdog := watchStreamIdleTimeout(c, meta, resp)
+	defer stopWatchdog()
 
 	// do response - for direct passthrough, forward upstream JSON verbatim; otherwise let adaptor convert
 	var usage *relaymodel.Usage
@@ -415,12 +426,15 @@ func RelayClaudeMessagesHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 
 	// Capture trace ID before launching goroutine
 	traceId := tracing.GetTraceID(c)
+	bodySizes := captureBodySizes(c, meta)
 	graceful.GoCritical(gmw.BackgroundCtx(c), "postBilling", func(ctx context.Context) {
+		bodySizes.record()
+
 		// Use configurable billing timeout with model-specific adjustments
 		baseBillingTimeout := time.Duration(config.BillingTimeoutSec) * time.Second
 		billingTimeout := baseBillingTimeout
 
-		ctx, cancel := context.WithTimeout(gmw.BackgroundCtx(c), billingTimeout)
+		ctx, cancel := context.WithTimeout(bodySizes.context(gmw.BackgroundCtx(c)), billingTimeout)
 		defer cancel()
 
 		// Monitor for timeout and log critical errors
@@ -880,15 +894,16 @@ func preConsumeClaudeMessagesQuota(c *gin.Context, request *ClaudeMessagesReques
 	if err != nil {
 		return baseQuota, openai.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
 	}
-	if userQuota-baseQuota < 0 {
+	if model.QuotaWithOverage(userQuota)-baseQuota < 0 {
 		return baseQuota, openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
 	}
-	err = model.CacheDecreaseUserQuota(ctx, meta.UserId, baseQuota)
+	trusted := userQuota > 100*baseQuota &&
+		(tokenQuotaUnlimited || tokenQuota > 100*baseQuota)
+	err = decreaseCachedUserQuota(ctx, meta.UserId, baseQuota, !trusted && baseQuota > 0)
 	if err != nil {
 		return baseQuota, openai.ErrorWrapper(err, "decrease_user_quota_failed", http.StatusInternalServerError)
 	}
-	if userQuota > 100*baseQuota &&
-		(tokenQuotaUnlimited || tokenQuota > 100*baseQuota) {
+	if trusted {
 		// in this case, we do not pre-consume quota
 		// because the user and token have enough quota
 		baseQuota = 0
@@ -951,6 +966,7 @@ func postConsumeClaudeMessagesQuotaWithTraceID(ctx context.Context, requestId st
 	cacheWrite5mTokens := usage.CacheWrite5mTokens
 	cacheWrite1hTokens := usage.CacheWrite1hTokens
 	metadata := model.AppendCacheWriteTokensMetadata(nil, cacheWrite5mTokens, cacheWrite1hTokens)
+	metadata = appendStreamingTimeoutMetadata(ctx, metadata)
 
 	// Use centralized detailed billing function with explicit trace ID
 	quotaDelta := quota - preConsumedQuota
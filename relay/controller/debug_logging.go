@@ -12,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/ctxkey"
 )
 
@@ -114,6 +115,7 @@ func logClientRequestPayload(c *gin.Context, label string) error {
 	if err != nil {
 		return err
 	}
+	body = common.RedactRequestBody(body, config.RequestRedactionPatterns)
 	preview, truncated := sanitizeRequestBodyForLogging(body, debugLogBodyLimit)
 	lg.Debug("client request received",
 		zap.String("label", label),
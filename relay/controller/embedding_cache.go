@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/metrics"
+	"github.com/songquanpeng/one-api/common/tracing"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/billing"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+const embeddingCacheKeyPrefix = "embedding_cache:"
+
+// embeddingCacheBackend stores embedding vectors as the raw JSON of the
+// response's embedding field, so base64 and float vectors are kept as returned.
+type embeddingCacheBackend interface {
+	available() bool
+	// load returns the cached values of keys in order, nil for misses.
+	load(ctx context.Context, keys []string) ([]json.RawMessage, error)
+	store(ctx context.Context, values map[string]json.RawMessage, ttl time.Duration) error
+}
+
+// embeddingCacheStore is the backend used by the relay; tests replace it.
+var embeddingCacheStore embeddingCacheBackend = redisEmbeddingCache{}
+
+type redisEmbeddingCache struct{}
+
+func (redisEmbeddingCache) available() bool {
+	return common.IsRedisEnabled() && common.RDB != nil
+}
+
+func (redisEmbeddingCache) load(ctx context.Context, keys []string) ([]json.RawMessage, error) {
+	raw, err := common.RDB.MGet(ctx, keys...).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, errors.Wrap(err, "mget cached embeddings")
+	}
+	values := make([]json.RawMessage, len(keys))
+	for i, value := range raw {
+		if s, ok := value.(string); ok && s != "" {
+			values[i] = json.RawMessage(s)
+		}
+	}
+	return values, nil
+}
+
+func (redisEmbeddingCache) store(ctx context.Context, values map[string]json.RawMessage, ttl time.Duration) error {
+	pipe := common.RDB.Pipeline()
+	for key, value := range values {
+		pipe.Set(ctx, key, string(value), ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.Wrap(err, "store cached embeddings")
+	}
+	return nil
+}
+
+// embeddingCacheKey derives the cache key of text from the model and the
+// output options that change the vector. Texts differing only in surrounding
+// or repeated whitespace share a key.
+func embeddingCacheKey(request *relaymodel.GeneralOpenAIRequest, text string) string {
+	normalized := strings.Join(strings.Fields(text), " ")
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		request.Model,
+		strconv.Itoa(request.Dimensions),
+		request.EncodingFormat,
+		normalized,
+	}, "\x00")))
+	return embeddingCacheKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// embeddingCacheLookup is the cache state of one embeddings request.
+type embeddingCacheLookup struct {
+	keys []string
+	// cached holds the vector of each input, nil for the misses.
+	cached []json.RawMessage
+	// misses maps the position of each input sent upstream to its index in
+	// the original request.
+	misses []int
+}
+
+// embeddingDataItem is an item of an OpenAI embeddings response.
+type embeddingDataItem struct {
+	Object    string          `json:"object"`
+	Index     int             `json:"index"`
+	Embedding json.RawMessage `json:"embedding"`
+}
+
+// lookupEmbeddingCache looks up every text of an embeddings request and
+// rewrites its input to the texts that missed, so only those are sent upstream
+// and billed. It returns nil when the cache is disabled or the input is not a
+// string or a list of strings; a failing cache is treated as a miss.
+func lookupEmbeddingCache(c *gin.Context, request *relaymodel.GeneralOpenAIRequest) *embeddingCacheLookup {
+	if !config.EmbeddingCacheEnabled || !embeddingCacheStore.available() {
+		return nil
+	}
+	var texts []string
+	switch input := request.Input.(type) {
+	case string:
+		texts = []string{input}
+	case []any:
+		for _, item := range input {
+			text, ok := item.(string)
+			if !ok {
+				return nil
+			}
+			texts = append(texts, text)
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	lookup := &embeddingCacheLookup{keys: make([]string, len(texts))}
+	for i, text := range texts {
+		lookup.keys[i] = embeddingCacheKey(request, text)
+	}
+	cached, err := embeddingCacheStore.load(gmw.Ctx(c), lookup.keys)
+	if err != nil {
+		gmw.GetLogger(c).Warn("embedding cache lookup failed", zap.Error(err))
+		cached = make([]json.RawMessage, len(texts))
+	}
+	lookup.cached = cached
+
+	var missed []any
+	for i, vector := range cached {
+		if vector == nil {
+			lookup.misses = append(lookup.misses, i)
+			missed = append(missed, texts[i])
+		}
+	}
+	hits := len(texts) - len(lookup.misses)
+	if hits > 0 && len(missed) > 0 {
+		request.Input = missed
+	}
+
+	c.Set(ctxkey.EmbeddingCacheInputs, len(texts))
+	c.Set(ctxkey.EmbeddingCacheHits, hits)
+	metrics.GlobalRecorder.RecordEmbeddingCacheLookup(request.Model, hits, len(texts))
+	return lookup
+}
+
+// complete reports whether every input was answered from the cache.
+func (l *embeddingCacheLookup) complete() bool {
+	return len(l.misses) == 0
+}
+
+// embeddingInputRewritten reports whether the request input was narrowed to the
+// misses, so the original body must not be forwarded as is.
+func embeddingInputRewritten(c *gin.Context) bool {
+	return c.GetInt(ctxkey.EmbeddingCacheHits) > 0
+}
+
+// respond writes the embeddings response of a fully cached request. No tokens
+// were sent upstream, so the usage is zero.
+func (l *embeddingCacheLookup) respond(c *gin.Context, modelName string) {
+	data := make([]embeddingDataItem, len(l.cached))
+	for i, vector := range l.cached {
+		data[i] = embeddingDataItem{Object: "embedding", Index: i, Embedding: vector}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data":   data,
+		"model":  modelName,
+		"usage":  relaymodel.Usage{},
+	})
+}
+
+// recordCachedResponseLog records the consume log of a request answered
+// entirely from a cache, such as fully cached embeddings or a deduplicated
+// chat completion; the cache hit is carried in its metadata. Nothing was sent
+// upstream, so nothing is charged.
+func recordCachedResponseLog(c *gin.Context, meta *metalib.Meta, modelName string) {
+	ctx := model.WithBodySizes(gmw.BackgroundCtx(c), model.CaptureBodySizes(c))
+	billing.PostConsumeQuotaDetailed(billing.QuotaConsumeDetail{
+		Ctx:       ctx,
+		TokenId:   meta.TokenId,
+		UserId:    meta.UserId,
+		ChannelId: meta.ChannelId,
+		ModelName: modelName,
+		TokenName: meta.TokenName,
+		StartTime: meta.StartTime,
+		RequestId: c.GetString(ctxkey.RequestId),
+		TraceId:   tracing.GetTraceIDFromContext(ctx),
+	})
+}
+
+// merge combines the upstream response to the missed inputs with the cached
+// vectors, in the order of the original request, and caches the new vectors.
+func (l *embeddingCacheLookup) merge(ctx context.Context, body []byte) ([]byte, error) {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "unmarshal embedding response")
+	}
+	var upstream []embeddingDataItem
+	if err := json.Unmarshal(response["data"], &upstream); err != nil {
+		return nil, errors.Wrap(err, "unmarshal embedding data")
+	}
+
+	fresh := make(map[string]json.RawMessage, len(upstream))
+	data := make([]embeddingDataItem, len(l.cached))
+	for i, vector := range l.cached {
+		data[i] = embeddingDataItem{Object: "embedding", Index: i, Embedding: vector}
+	}
+	for _, item := range upstream {
+		if item.Index < 0 || item.Index >= len(l.misses) {
+			return nil, errors.Errorf("embedding index %d out of range of %d inputs", item.Index, len(l.misses))
+		}
+		original := l.misses[item.Index]
+		data[original].Embedding = item.Embedding
+		if item.Object != "" {
+			data[original].Object = item.Object
+		}
+		fresh[l.keys[original]] = item.Embedding
+	}
+	for i, item := range data {
+		if item.Embedding == nil {
+			return nil, errors.Errorf("no embedding returned for input %d", i)
+		}
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal embedding data")
+	}
+	response["data"] = encoded
+	merged, err := json.Marshal(response)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal embedding response")
+	}
+
+	ttl := time.Duration(config.EmbeddingCacheTTLHours) * time.Hour
+	if err := embeddingCacheStore.store(ctx, fresh, ttl); err != nil {
+		gmw.GetLogger(ctx).Warn("embedding cache store failed", zap.Error(err))
+	}
+	return merged, nil
+}
+
+// writeEmbeddingCacheResponse replaces the embeddings response captured from
+// the adaptor with the merged response. Error responses are written unchanged.
+// Nothing is written when the merge fails, the caller reports the error.
+func writeEmbeddingCacheResponse(c *gin.Context, lookup *embeddingCacheLookup, capture *responseCaptureWriter) error {
+	body := capture.BodyBytes()
+	status := capture.StatusCode()
+	if status == http.StatusOK {
+		merged, err := lookup.merge(gmw.Ctx(c), body)
+		if err != nil {
+			return errors.Wrap(err, "merge cached embeddings")
+		}
+		body = merged
+	}
+	c.Writer.Header().Del("Content-Length")
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(status)
+	if _, err := c.Writer.Write(body); err != nil {
+		return errors.Wrap(err, "write embedding response")
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+type memoryEmbeddingCache struct {
+	mu     sync.Mutex
+	values map[string]json.RawMessage
+	ttl    time.Duration
+}
+
+func (m *memoryEmbeddingCache) available() bool { return true }
+
+func (m *memoryEmbeddingCache) load(_ context.Context, keys []string) ([]json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := make([]json.RawMessage, len(keys))
+	for i, key := range keys {
+		values[i] = m.values[key]
+	}
+	return values, nil
+}
+
+func (m *memoryEmbeddingCache) store(_ context.Context, values map[string]json.RawMessage, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, value := range values {
+		m.values[key] = value
+	}
+	m.ttl = ttl
+	return nil
+}
+
+func useMemoryEmbeddingCache(t *testing.T) *memoryEmbeddingCache {
+	t.Helper()
+	cache := &memoryEmbeddingCache{values: map[string]json.RawMessage{}}
+	prevStore, prevEnabled := embeddingCacheStore, config.EmbeddingCacheEnabled
+	embeddingCacheStore, config.EmbeddingCacheEnabled = cache, true
+	t.Cleanup(func() {
+		embeddingCacheStore, config.EmbeddingCacheEnabled = prevStore, prevEnabled
+	})
+	return cache
+}
+
+func TestEmbeddingCacheKey(t *testing.T) {
+	request := &relaymodel.GeneralOpenAIRequest{Model: "text-embedding-3-small"}
+	key := embeddingCacheKey(request, "hello world")
+	require.True(t, strings.HasPrefix(key, embeddingCacheKeyPrefix))
+	require.Equal(t, key, embeddingCacheKey(request, "  hello \n\t world "))
+	require.NotEqual(t, key, embeddingCacheKey(request, "Hello world"))
+	require.NotEqual(t, key, embeddingCacheKey(&relaymodel.GeneralOpenAIRequest{Model: "text-embedding-3-large"}, "hello world"))
+	require.NotEqual(t, key, embeddingCacheKey(&relaymodel.GeneralOpenAIRequest{Model: "text-embedding-3-small", Dimensions: 256}, "hello world"))
+	require.NotEqual(t, key, embeddingCacheKey(&relaymodel.GeneralOpenAIRequest{Model: "text-embedding-3-small", EncodingFormat: "base64"}, "hello world"))
+}
+
+func TestRelayTextHelper_EmbeddingCachePartialHit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ensureResponseFallbackFixtures(t)
+	cache := useMemoryEmbeddingCache(t)
+
+	prevRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(prevRedis) })
+
+	prevLogConsume := config.IsLogConsumeEnabled()
+	config.SetLogConsumeEnabled(false)
+	t.Cleanup(func() { config.SetLogConsumeEnabled(prevLogConsume) })
+
+	cacheRequest := &relaymodel.GeneralOpenAIRequest{Model: "text-embedding-3-small"}
+	cache.values[embeddingCacheKey(cacheRequest, "alpha")] = json.RawMessage(`[0.1,0.1]`)
+	cache.values[embeddingCacheKey(cacheRequest, "gamma")] = json.RawMessage(`[0.3,0.3]`)
+
+	var upstreamCalls int
+	var upstreamRequest relaymodel.GeneralOpenAIRequest
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls++
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &upstreamRequest))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.2,0.2]}],"model":"text-embedding-3-small","usage":{"prompt_tokens":1,"total_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	prevClient := client.HTTPClient
+	client.HTTPClient = upstream.Client()
+	t.Cleanup(func() { client.HTTPClient = prevClient })
+
+	send := func(input string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings",
+			strings.NewReader(`{"model":"text-embedding-3-small","input":`+input+`}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer compat-key")
+		c.Request = req
+		gmw.SetLogger(c, logger.Logger)
+
+		c.Set(ctxkey.Channel, channeltype.OpenAICompatible)
+		c.Set(ctxkey.ChannelId, fallbackCompatibleChannelID)
+		c.Set(ctxkey.TokenId, fallbackTokenID)
+		c.Set(ctxkey.TokenName, "fallback-token")
+		c.Set(ctxkey.Id, fallbackUserID)
+		c.Set(ctxkey.Group, "default")
+		c.Set(ctxkey.ModelMapping, map[string]string{})
+		c.Set(ctxkey.ChannelRatio, 1.0)
+		c.Set(ctxkey.RequestModel, "text-embedding-3-small")
+		c.Set(ctxkey.BaseURL, upstream.URL)
+		c.Set(ctxkey.ContentType, "application/json")
+		c.Set(ctxkey.RequestId, "req_embedding_cache")
+		c.Set(ctxkey.TokenQuotaUnlimited, true)
+		c.Set(ctxkey.TokenQuota, int64(0))
+		c.Set(ctxkey.Username, "response-fallback")
+		c.Set(ctxkey.UserQuota, int64(1_000_000))
+		c.Set(ctxkey.ChannelModel, &model.Channel{Id: fallbackCompatibleChannelID, Type: channeltype.OpenAICompatible})
+		c.Set(ctxkey.Config, model.ChannelConfig{})
+
+		require.Nil(t, RelayTextHelper(c))
+		require.Equal(t, 3, c.GetInt(ctxkey.EmbeddingCacheInputs))
+		return recorder
+	}
+	type embeddingResponse struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage relaymodel.Usage `json:"usage"`
+	}
+	decode := func(recorder *httptest.ResponseRecorder) embeddingResponse {
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var resp embeddingResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := decode(send(`["alpha","beta","gamma"]`))
+	require.Equal(t, 1, upstreamCalls)
+	require.Equal(t, []any{"beta"}, upstreamRequest.Input)
+	require.Len(t, resp.Data, 3)
+	for i, want := range [][]float64{{0.1, 0.1}, {0.2, 0.2}, {0.3, 0.3}} {
+		require.Equal(t, i, resp.Data[i].Index)
+		require.Equal(t, want, resp.Data[i].Embedding)
+	}
+	require.Equal(t, 1, resp.Usage.PromptTokens)
+	require.Equal(t, json.RawMessage(`[0.2,0.2]`), cache.values[embeddingCacheKey(cacheRequest, "beta")])
+	require.Equal(t, 24*time.Hour, cache.ttl)
+
+	// Now fully cached, whitespace differences included, so upstream is not called
+	resp = decode(send(`["gamma"," beta ","alpha"]`))
+	require.Equal(t, 1, upstreamCalls)
+	require.Len(t, resp.Data, 3)
+	require.Equal(t, []float64{0.3, 0.3}, resp.Data[0].Embedding)
+	require.Equal(t, []float64{0.2, 0.2}, resp.Data[1].Embedding)
+	require.Zero(t, resp.Usage.PromptTokens)
+}
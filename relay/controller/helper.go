@@ -55,20 +55,19 @@ func getAndValidateTextRequest(c *gin.Context, relayMode int) (*relaymodel.Gener
 	if err != nil {
 		return nil, errors.Wrap(err, "text request validation failed")
 	}
+	applyResponseLanguage(c, textRequest)
 	return textRequest, nil
 }
 
-// For Realtime websocket sessions, upgrade and proxy immediately.
-// This keeps the rest of the text pipeline unchanged for other modes.
-func maybeHandleRealtime(c *gin.Context) *relaymodel.ErrorWithStatusCode {
-	m := meta.GetByContext(c)
-	if m.Mode == relaymode.Realtime && m.ChannelType == channeltype.OpenAI {
-		if bizErr, _ := openai.RealtimeHandler(c, m); bizErr != nil {
-			return bizErr
-		}
-		return nil
+// applyResponseLanguage fills the request's language field with the preference
+// recorded by middleware.InjectResponseLanguage, unless the caller set one.
+func applyResponseLanguage(c *gin.Context, textRequest *relaymodel.GeneralOpenAIRequest) {
+	language := c.GetString(ctxkey.ResponseLanguage)
+	if language == "" || textRequest.Language != "" {
+		return
 	}
-	return nil
+	textRequest.Language = language
+	c.Set(ctxkey.ResponseLanguageApplied, true)
 }
 
 func getPromptTokens(ctx context.Context, textRequest *relaymodel.GeneralOpenAIRequest, relayMode int) int {
@@ -106,8 +105,11 @@ func getPromptTokens(ctx context.Context, textRequest *relaymodel.GeneralOpenAIR
 	return 0
 }
 
-func getPreConsumedQuota(textRequest *relaymodel.GeneralOpenAIRequest, promptTokens int, ratio float64) int64 {
-	preConsumedTokens := config.PreConsumedQuota + int64(promptTokens)
+// getPreConsumedQuota estimates the quota to reserve for a text request. The fixed
+// reservation comes from the model's billing history when available (see
+// billing.AdaptivePreConsumeQuota), otherwise from config.PreConsumedQuota tokens.
+func getPreConsumedQuota(ctx context.Context, textRequest *relaymodel.GeneralOpenAIRequest, promptTokens int, ratio float64) int64 {
+	preConsumedTokens := int64(promptTokens)
 	// Prefer max_completion_tokens; fall back to deprecated max_tokens
 	if textRequest.MaxCompletionTokens != nil && *textRequest.MaxCompletionTokens > 0 {
 		preConsumedTokens += int64(*textRequest.MaxCompletionTokens)
@@ -115,14 +117,18 @@ func getPreConsumedQuota(textRequest *relaymodel.GeneralOpenAIRequest, promptTok
 		preConsumedTokens += int64(textRequest.MaxTokens)
 	}
 
-	baseQuota := int64(float64(preConsumedTokens) * ratio)
+	if adaptiveQuota, ok := billing.AdaptivePreConsumeQuota(ctx, textRequest.Model); ok {
+		return int64(float64(preConsumedTokens)*ratio) + adaptiveQuota
+	}
+
+	baseQuota := int64(float64(config.PreConsumedQuota+preConsumedTokens) * ratio)
 	return baseQuota
 }
 
 func preConsumeQuota(c *gin.Context, textRequest *relaymodel.GeneralOpenAIRequest, promptTokens int, ratio float64, meta *meta.Meta) (int64, *relaymodel.ErrorWithStatusCode) {
 	ctx := gmw.Ctx(c)
 	lg := gmw.GetLogger(c)
-	preConsumedQuota := getPreConsumedQuota(textRequest, promptTokens, ratio)
+	preConsumedQuota := getPreConsumedQuota(ctx, textRequest, promptTokens, ratio)
 
 	tokenQuota := c.GetInt64(ctxkey.TokenQuota)
 	tokenQuotaUnlimited := c.GetBool(ctxkey.TokenQuotaUnlimited)
@@ -130,15 +136,16 @@ func preConsumeQuota(c *gin.Context, textRequest *relaymodel.GeneralOpenAIReques
 	if err != nil {
 		return preConsumedQuota, openai.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
 	}
-	if userQuota-preConsumedQuota < 0 {
+	if model.QuotaWithOverage(userQuota)-preConsumedQuota < 0 {
 		return preConsumedQuota, openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
 	}
-	err = model.CacheDecreaseUserQuota(ctx, meta.UserId, preConsumedQuota)
+	trusted := userQuota > 100*preConsumedQuota &&
+		(tokenQuotaUnlimited || tokenQuota > 100*preConsumedQuota)
+	err = decreaseCachedUserQuota(ctx, meta.UserId, preConsumedQuota, !trusted && preConsumedQuota > 0)
 	if err != nil {
 		return preConsumedQuota, openai.ErrorWrapper(err, "decrease_user_quota_failed", http.StatusInternalServerError)
 	}
-	if userQuota > 100*preConsumedQuota &&
-		(tokenQuotaUnlimited || tokenQuota > 100*preConsumedQuota) {
+	if trusted {
 		// in this case, we do not pre-consume quota
 		// because the user and token have enough quota
 		preConsumedQuota = 0
@@ -153,6 +160,16 @@ func preConsumeQuota(c *gin.Context, textRequest *relaymodel.GeneralOpenAIReques
 	return preConsumedQuota, nil
 }
 
+// decreaseCachedUserQuota takes a pre-consumed amount off the cached user
+// quota. Under hard quota protection PreConsumeTokenQuota reserves the amount
+// atomically instead, so requests that pre-consume skip it.
+func decreaseCachedUserQuota(ctx context.Context, userId int, quota int64, preConsumes bool) error {
+	if preConsumes && model.HardQuotaProtectionActive() {
+		return nil
+	}
+	return model.CacheDecreaseUserQuota(ctx, userId, quota)
+}
+
 func postConsumeQuota(ctx context.Context,
 	usage *relaymodel.Usage,
 	meta *meta.Meta,
@@ -184,6 +201,10 @@ func postConsumeQuota(ctx context.Context,
 	if totalTokens == 0 {
 		quota = 0
 	}
+	translation := systemPromptTranslationFromContext(ctx)
+	if translation != nil {
+		quota += translation.Quota
+	}
 
 	quotaDelta := quota - preConsumedQuota - incrementallyCharged
 	// Derive RequestId/TraceId from std context if possible (gin ctx embedded by gmw.BackgroundCtx)
@@ -203,6 +224,12 @@ func postConsumeQuota(ctx context.Context,
 		}
 		metadata := model.AppendToolUsageMetadata(nil, toolSummary)
 		metadata = model.AppendCacheWriteTokensMetadata(metadata, usage.CacheWrite5mTokens, usage.CacheWrite1hTokens)
+		metadata = appendStreamingTimeoutMetadata(ctx, metadata)
+		metadata = appendStreamTimingMetadata(ctx, metadata)
+		metadata = model.AppendTranslationCostMetadata(metadata, translation)
+		if usage.PromptTokensDetails != nil {
+			metadata = model.AppendImageTokensMetadata(metadata, usage.PromptTokensDetails.ImageTokens)
+		}
 
 		billing.PostConsumeQuotaDetailed(billing.QuotaConsumeDetail{
 			Ctx:                    ctx,
@@ -291,6 +318,7 @@ func postConsumeQuotaWithTraceID(ctx context.Context, traceId string,
 		}
 		metadata := model.AppendToolUsageMetadata(nil, toolSummary)
 		metadata = model.AppendCacheWriteTokensMetadata(metadata, usage.CacheWrite5mTokens, usage.CacheWrite1hTokens)
+		metadata = appendStreamingTimeoutMetadata(ctx, metadata)
 
 		billing.PostConsumeQuotaDetailed(billing.QuotaConsumeDetail{
 			Ctx:                    ctx,
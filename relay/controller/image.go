@@ -444,7 +444,7 @@ func RelayImageHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	}
 
 	var preConsumedQuota int64
-	if userQuota < usedQuota {
+	if model.QuotaWithOverage(userQuota) < usedQuota {
 		return openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
 	}
 
@@ -478,7 +478,7 @@ func RelayImageHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	requestId := c.GetString(ctxkey.RequestId)
 	traceId := tracing.GetTraceID(c)
 	defer func() {
-		bgCtx, cancel := context.WithTimeout(gmw.BackgroundCtx(c), time.Minute)
+		bgCtx, cancel := context.WithTimeout(model.WithBodySizes(gmw.BackgroundCtx(c), model.CaptureBodySizes(c)), time.Minute)
 		defer cancel()
 
 		if resp != nil &&
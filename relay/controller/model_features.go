@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/adaptor"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/pricing"
+)
+
+// validateModelFeatures rejects a request that uses features the model does
+// not accept, see pricing.ResolveModelFeatures, listing every unsupported
+// feature at once. The request parameters at fault are reported in the error's
+// param field. Every relay entry point checks its request with it, passing the
+// adaptor of the channel's API type.
+func validateModelFeatures(modelName string, features []adaptor.RequestFeature, channel *model.Channel, provider adaptor.Adaptor) *relaymodel.ErrorWithStatusCode {
+	if len(features) == 0 {
+		return nil
+	}
+	var channelConfigs map[string]model.ModelConfigLocal
+	if channel != nil {
+		channelConfigs = channel.GetModelPriceConfigs()
+	}
+	flags := pricing.ResolveModelFeatures(modelName, channelConfigs, provider)
+
+	var unsupported, params []string
+	for _, feature := range features {
+		if !flags.Supports(feature.Flag) {
+			unsupported = append(unsupported, feature.Flag)
+			params = append(params, feature.Param)
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+
+	return &relaymodel.ErrorWithStatusCode{
+		Error: relaymodel.Error{
+			Message: fmt.Sprintf("model %s does not support the requested features: %s",
+				modelName, strings.Join(unsupported, ", ")),
+			Type:  relaymodel.ErrorTypeInvalidRequest,
+			Param: strings.Join(params, ","),
+			Code:  "unsupported_model_features",
+		},
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// responseRequestFeatures lists the gated features a Response API request
+// uses, read from its ChatCompletion equivalent so both APIs gate alike.
+func responseRequestFeatures(request *openai.ResponseAPIRequest) []adaptor.RequestFeature {
+	chatRequest, err := openai.ConvertResponseAPIToChatCompletionRequest(request)
+	if err != nil {
+		return nil
+	}
+	return adaptor.ChatRequestFeatures(chatRequest)
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay"
+	"github.com/songquanpeng/one-api/relay/adaptor"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+	"github.com/songquanpeng/one-api/relay/apitype"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+func TestValidateModelFeatures(t *testing.T) {
+	channel := &model.Channel{}
+	require.NoError(t, channel.SetModelPriceConfigs(map[string]model.ModelConfigLocal{
+		"legacy-model": {Ratio: 1, Features: map[string]bool{"supports_tools": false, "Supports_Vision": false}},
+		"gpt-4o-mini":  {Ratio: 1},
+	}))
+	provider := relay.GetAdaptor(apitype.OpenAI)
+	validate := func(request *relaymodel.GeneralOpenAIRequest, channel *model.Channel) *relaymodel.ErrorWithStatusCode {
+		return validateModelFeatures(request.Model, adaptor.ChatRequestFeatures(request), channel, provider)
+	}
+
+	toolRequest := func(modelName string) *relaymodel.GeneralOpenAIRequest {
+		return &relaymodel.GeneralOpenAIRequest{
+			Model:    modelName,
+			Messages: []relaymodel.Message{{Role: "user", Content: "What's the weather in Paris?"}},
+			Tools: []relaymodel.Tool{{Type: "function", Function: &relaymodel.Function{
+				Name:       "get_weather",
+				Parameters: map[string]any{"type": "object"},
+			}}},
+		}
+	}
+
+	bizErr := validate(toolRequest("legacy-model"), channel)
+	require.NotNil(t, bizErr)
+	require.Equal(t, http.StatusBadRequest, bizErr.StatusCode)
+	require.Equal(t, "unsupported_model_features", bizErr.Code)
+	require.Equal(t, relaymodel.ErrorTypeInvalidRequest, bizErr.Type)
+	require.Equal(t, "tools", bizErr.Param)
+	require.Equal(t, "model legacy-model does not support the requested features: supports_tools", bizErr.Message)
+
+	// Every unsupported feature is listed
+	request := toolRequest("legacy-model")
+	imageURL := "https://example.com/cat.png"
+	request.Messages = []relaymodel.Message{{Role: "user", Content: []relaymodel.MessageContent{
+		{Type: relaymodel.ContentTypeImageURL, ImageURL: &relaymodel.ImageURL{Url: imageURL}},
+	}}}
+	bizErr = validate(request, channel)
+	require.NotNil(t, bizErr)
+	require.Equal(t, "messages,tools", bizErr.Param)
+	require.Contains(t, bizErr.Message, "supports_vision, supports_tools")
+
+	// Streaming stays declared by the adaptor, and so does every feature of models without flags
+	request = &relaymodel.GeneralOpenAIRequest{Model: "legacy-model", Stream: true,
+		Messages: []relaymodel.Message{{Role: "user", Content: "hi"}}}
+	require.Nil(t, validate(request, channel))
+	require.Nil(t, validate(toolRequest("gpt-4o-mini"), channel))
+	require.Nil(t, validate(toolRequest("gpt-4o-mini"), nil))
+}
+
+func TestModelFeatureFlagsSupports(t *testing.T) {
+	flags := adaptor.ModelFeatureFlags{adaptor.FeatureTools: false, adaptor.FeatureVision: true}
+	require.False(t, flags.Supports(adaptor.FeatureTools))
+	require.True(t, flags.Supports(adaptor.FeatureVision))
+	// Features are opt-in
+	require.False(t, flags.Supports(adaptor.FeatureStreaming))
+
+	channel := &model.Channel{}
+	require.ErrorContains(t, channel.SetModelPriceConfigs(map[string]model.ModelConfigLocal{
+		"legacy-model": {Ratio: 1, Features: map[string]bool{"supports_telepathy": true}},
+	}), "unknown model feature flag")
+}
+
+func TestValidateModelFeatures_OptIn(t *testing.T) {
+	imageRequest := &relaymodel.GeneralOpenAIRequest{Model: "deepseek-chat", Stream: true,
+		Messages: []relaymodel.Message{{Role: "user", Content: []relaymodel.MessageContent{
+			{Type: relaymodel.ContentTypeImageURL, ImageURL: &relaymodel.ImageURL{Url: "https://example.com/cat.png"}},
+		}}}}
+
+	// DeepSeek only declares the standard features
+	provider := relay.GetAdaptor(apitype.DeepSeek)
+	bizErr := validateModelFeatures(imageRequest.Model, adaptor.ChatRequestFeatures(imageRequest), nil, provider)
+	require.NotNil(t, bizErr)
+	require.Equal(t, "messages", bizErr.Param)
+	require.Equal(t, "model deepseek-chat does not support the requested features: supports_vision", bizErr.Message)
+
+	// A channel opts the model in
+	channel := &model.Channel{}
+	require.NoError(t, channel.SetModelPriceConfigs(map[string]model.ModelConfigLocal{
+		"deepseek-chat": {Ratio: 1, Features: map[string]bool{"supports_vision": true}},
+	}))
+	require.Nil(t, validateModelFeatures(imageRequest.Model, adaptor.ChatRequestFeatures(imageRequest), channel, provider))
+
+	// Claude Messages and Response API requests are gated alike
+	stream := true
+	claudeRequest := &relaymodel.ClaudeRequest{Model: "deepseek-chat", Stream: &stream,
+		Thinking: &relaymodel.Thinking{Type: "enabled", BudgetTokens: 1024},
+		Messages: []relaymodel.ClaudeMessage{{Role: "user", Content: []any{
+			map[string]any{"type": "image", "source": map[string]any{"type": "url", "url": "https://example.com/cat.png"}},
+		}}}}
+	bizErr = validateModelFeatures(claudeRequest.Model, adaptor.ClaudeRequestFeatures(claudeRequest), nil, provider)
+	require.NotNil(t, bizErr)
+	require.Equal(t, "messages,thinking", bizErr.Param)
+
+	responseRequest := &openai.ResponseAPIRequest{Model: "deepseek-chat", Stream: &stream,
+		Input: openai.ResponseAPIInput{map[string]any{"role": "user", "content": []any{
+			map[string]any{"type": "input_image", "image_url": "https://example.com/cat.png"},
+		}}}}
+	bizErr = validateModelFeatures(responseRequest.Model, responseRequestFeatures(responseRequest), nil, provider)
+	require.NotNil(t, bizErr)
+	require.Equal(t, "messages", bizErr.Param)
+}
@@ -56,8 +56,9 @@ func RelayProxyHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	isStream := meta.IsStream
 	modelName := "proxy"
 	elapsed := helper.CalcElapsedTime(meta.StartTime)
+	bodySizes := model.CaptureBodySizes(c)
 	go func() {
-		ctx, cancel := context.WithTimeout(gmw.BackgroundCtx(c), 30*time.Second)
+		ctx, cancel := context.WithTimeout(model.WithBodySizes(gmw.BackgroundCtx(c), bodySizes), 30*time.Second)
 		defer cancel()
 
 		// Log the proxy request with zero quota
@@ -0,0 +1,302 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/tracing"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+	"github.com/songquanpeng/one-api/relay/apitype"
+	"github.com/songquanpeng/one-api/relay/billing"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/pricing"
+	quotautil "github.com/songquanpeng/one-api/relay/quota"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+const realtimeHandshakeTimeout = 10 * time.Second
+
+// realtimeResponseDone is the subset of a Realtime response.done event used
+// for billing.
+type realtimeResponseDone struct {
+	Type     string `json:"type"`
+	Response struct {
+		Usage *struct {
+			InputTokenDetails struct {
+				CachedTokens        int `json:"cached_tokens"`
+				TextTokens          int `json:"text_tokens"`
+				AudioTokens         int `json:"audio_tokens"`
+				CachedTokensDetails struct {
+					TextTokens  int `json:"text_tokens"`
+					AudioTokens int `json:"audio_tokens"`
+				} `json:"cached_tokens_details"`
+			} `json:"input_token_details"`
+			OutputTokenDetails struct {
+				TextTokens  int `json:"text_tokens"`
+				AudioTokens int `json:"audio_tokens"`
+			} `json:"output_token_details"`
+		} `json:"usage"`
+	} `json:"response"`
+}
+
+// parseRealtimeUsage extracts the usage of a response.done event. It returns
+// nil for any other message.
+func parseRealtimeUsage(msg []byte) *realtimeUsage {
+	if !bytes.Contains(msg, []byte(`"response.done"`)) {
+		return nil
+	}
+	var event realtimeResponseDone
+	if err := json.Unmarshal(msg, &event); err != nil || event.Type != "response.done" || event.Response.Usage == nil {
+		return nil
+	}
+
+	input := event.Response.Usage.InputTokenDetails
+	output := event.Response.Usage.OutputTokenDetails
+	cachedAudio := min(input.CachedTokensDetails.AudioTokens, input.AudioTokens)
+	// Cached tokens without a breakdown are counted as text
+	cachedText := min(max(input.CachedTokens-cachedAudio, 0), input.TextTokens)
+	return &realtimeUsage{
+		InputTextTokens:        input.TextTokens,
+		InputAudioTokens:       input.AudioTokens,
+		CachedInputTextTokens:  cachedText,
+		CachedInputAudioTokens: cachedAudio,
+		OutputTextTokens:       output.TextTokens,
+		OutputAudioTokens:      output.AudioTokens,
+	}
+}
+
+// toBillingUsage converts session usage into text-equivalent tokens: uncached
+// audio prompt tokens are scaled by the audio prompt ratio and audio
+// completion tokens by the prompt and completion ratios. Cached tokens keep
+// their count and are priced at the cached input rate.
+func (u *realtimeUsage) toBillingUsage(audio *relayAudioRatios) *relaymodel.Usage {
+	cached := u.CachedInputTextTokens + u.CachedInputAudioTokens
+	promptTokens := u.InputTextTokens - u.CachedInputTextTokens + cached +
+		int(math.Ceil(float64(u.InputAudioTokens-u.CachedInputAudioTokens)*audio.prompt))
+	completionTokens := u.OutputTextTokens +
+		int(math.Ceil(float64(u.OutputAudioTokens)*audio.prompt*audio.completion))
+	usage := &relaymodel.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+	if cached > 0 {
+		usage.PromptTokensDetails = &relaymodel.UsagePromptTokensDetails{CachedTokens: cached}
+	}
+	return usage
+}
+
+// relayAudioRatios are the audio pricing ratios applied to a Realtime model.
+type relayAudioRatios struct {
+	prompt     float64
+	completion float64
+}
+
+// RelayRealtimeHelper proxies an OpenAI Realtime WebSocket session. It
+// upgrades the client connection, dials the upstream endpoint and copies
+// frames in both directions until either side closes. Usage reported by
+// response.done events is accumulated per session and billed once the
+// session ends.
+func RelayRealtimeHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
+	meta := metalib.GetByContext(c)
+	if meta.Mode != relaymode.Realtime {
+		return openai.ErrorWrapper(errors.New("invalid mode for realtime handler"), "invalid_mode", http.StatusBadRequest)
+	}
+	if meta.APIType != apitype.OpenAI {
+		return openai.ErrorWrapper(errors.Errorf("channel type %d does not support the realtime api", meta.ChannelType),
+			"realtime_not_supported", http.StatusBadRequest)
+	}
+
+	upstreamURL, err := realtimeUpstreamURL(meta.BaseURL, c.Request.URL.RawQuery, meta.ActualModelName)
+	if err != nil {
+		return openai.ErrorWrapper(err, "invalid_base_url", http.StatusInternalServerError)
+	}
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin:      func(r *http.Request) bool { return true },
+		HandshakeTimeout: realtimeHandshakeTimeout,
+	}
+	clientConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return openai.ErrorWrapper(err, "ws_upgrade_failed", http.StatusBadRequest)
+	}
+	defer func() { _ = clientConn.Close() }()
+
+	requestHeader := http.Header{}
+	if sp := c.GetHeader("Sec-WebSocket-Protocol"); sp != "" {
+		requestHeader.Set("Sec-WebSocket-Protocol", sp)
+	}
+	if beta := c.GetHeader("OpenAI-Beta"); beta != "" {
+		requestHeader.Set("OpenAI-Beta", beta)
+	} else {
+		requestHeader.Set("OpenAI-Beta", "realtime=v1")
+	}
+	requestHeader.Set("Authorization", "Bearer "+meta.APIKey)
+
+	dialer := websocket.Dialer{HandshakeTimeout: realtimeHandshakeTimeout, Proxy: http.ProxyFromEnvironment}
+	upstreamConn, _, err := dialer.DialContext(gmw.Ctx(c), upstreamURL, requestHeader)
+	if err != nil {
+		// The client connection is already upgraded, so report the failure as a close frame
+		_ = clientConn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "upstream connect failed"))
+		gmw.GetLogger(c).Warn("realtime upstream connect failed", zap.Error(err))
+		return nil
+	}
+	defer func() { _ = upstreamConn.Close() }()
+
+	sessionId := c.GetString(ctxkey.RequestId)
+	if sessionId == "" {
+		sessionId = fmt.Sprintf("%d-%d", meta.TokenId, meta.StartTime.UnixNano())
+	}
+
+	errc := make(chan error, 2)
+	go func() { errc <- copyRealtimeFrames(c, upstreamConn, clientConn, sessionId) }()
+	go func() { errc <- copyRealtimeFrames(c, clientConn, upstreamConn, "") }()
+	if err := <-errc; err != nil {
+		gmw.GetLogger(c).Debug("realtime session closed", zap.Error(err))
+	}
+	// Closing both connections unblocks the other copier; wait for it so no
+	// usage is recorded after the session is settled and c is not used after
+	// the handler returns
+	_ = clientConn.Close()
+	_ = upstreamConn.Close()
+	<-errc
+
+	settleRealtimeSession(c, meta, sessionId)
+	return nil
+}
+
+// realtimeUpstreamURL builds the upstream WebSocket URL from the channel base
+// URL, keeping the client query but pinning the model to the mapped name.
+func realtimeUpstreamURL(baseURL, rawQuery, actualModel string) (string, error) {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", errors.Wrap(err, "parse realtime base url")
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	default:
+		u.Scheme = "wss"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/realtime"
+
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", errors.Wrap(err, "parse realtime query")
+	}
+	if actualModel != "" {
+		q.Set("model", actualModel)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// copyRealtimeFrames forwards frames from src to dst. When sessionId is set,
+// the usage of response.done text frames is added to the session.
+func copyRealtimeFrames(c *gin.Context, src, dst *websocket.Conn, sessionId string) error {
+	for {
+		mt, msg, err := src.ReadMessage()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if sessionId != "" && mt == websocket.TextMessage {
+			if usage := parseRealtimeUsage(msg); usage != nil {
+				if err := addRealtimeUsage(gmw.Ctx(c), sessionId, usage); err != nil {
+					gmw.GetLogger(c).Error("failed to record realtime usage", zap.Error(err))
+				}
+			}
+		}
+		if err := dst.WriteMessage(mt, msg); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+}
+
+// settleRealtimeSession bills the usage accumulated by the session and
+// writes its consume log.
+func settleRealtimeSession(c *gin.Context, meta *metalib.Meta, sessionId string) {
+	lg := gmw.GetLogger(c)
+	ctx, cancel := context.WithTimeout(gmw.BackgroundCtx(c), time.Minute)
+	defer cancel()
+
+	usage, err := takeRealtimeUsage(ctx, sessionId)
+	if err != nil {
+		lg.Error("failed to load realtime session usage", zap.Error(err))
+		return
+	}
+	if usage.isZero() {
+		return
+	}
+
+	var channelModelRatio, channelCompletionRatio map[string]float64
+	var channelModelConfigs map[string]model.ModelConfigLocal
+	if channelModel, ok := c.Get(ctxkey.ChannelModel); ok {
+		if channel, ok := channelModel.(*model.Channel); ok {
+			channelModelRatio = channel.GetModelRatioFromConfigs()
+			channelCompletionRatio = channel.GetCompletionRatioFromConfigs()
+			channelModelConfigs = channel.GetModelPriceConfigs()
+		}
+	}
+
+	pricingAdaptor := relay.GetAdaptor(meta.APIType)
+	audio := &relayAudioRatios{prompt: pricing.DefaultAudioPromptRatio, completion: pricing.DefaultAudioCompletionRatio}
+	if cfg, ok := pricing.ResolveAudioPricing(meta.ActualModelName, channelModelConfigs, pricingAdaptor); ok {
+		if cfg.PromptRatio > 0 {
+			audio.prompt = cfg.PromptRatio
+		}
+		if cfg.CompletionRatio > 0 {
+			audio.completion = cfg.CompletionRatio
+		}
+	}
+
+	groupRatio := c.GetFloat64(ctxkey.ChannelRatio)
+	result := quotautil.Compute(quotautil.ComputeInput{
+		Usage:                  usage.toBillingUsage(audio),
+		ModelName:              meta.ActualModelName,
+		ModelRatio:             pricing.GetModelRatioWithThreeLayers(meta.ActualModelName, channelModelRatio, pricingAdaptor),
+		GroupRatio:             groupRatio,
+		ChannelCompletionRatio: channelCompletionRatio,
+		PricingAdaptor:         pricingAdaptor,
+	})
+
+	billing.PostConsumeQuotaWithLog(ctx, meta.TokenId, result.TotalQuota, result.TotalQuota, &model.Log{
+		UserId:           meta.UserId,
+		ChannelId:        meta.ChannelId,
+		PromptTokens:     usage.InputTextTokens + usage.InputAudioTokens,
+		CompletionTokens: usage.OutputTextTokens + usage.OutputAudioTokens,
+		ModelName:        meta.ActualModelName,
+		TokenName:        meta.TokenName,
+		Content: fmt.Sprintf("realtime session, input text %d, input audio %d, cached input %d, output text %d, output audio %d, group rate %.2f",
+			usage.InputTextTokens, usage.InputAudioTokens, usage.CachedInputTextTokens+usage.CachedInputAudioTokens,
+			usage.OutputTextTokens, usage.OutputAudioTokens, groupRatio),
+		IsStream:    true,
+		ElapsedTime: helper.CalcElapsedTime(meta.StartTime),
+		RequestId:   c.GetString(ctxkey.RequestId),
+		TraceId:     tracing.GetTraceID(c),
+	})
+	lg.Info("realtime session settled",
+		zap.String("session_id", sessionId),
+		zap.Int64("quota", result.TotalQuota))
+}
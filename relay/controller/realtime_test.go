@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay"
+	"github.com/songquanpeng/one-api/relay/apitype"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/pricing"
+	quotautil "github.com/songquanpeng/one-api/relay/quota"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+const (
+	realtimeTestChannelID = 916500
+	realtimeTestUserID    = 916501
+	realtimeTestTokenID   = 916502
+	realtimeTestUserQuota = int64(10_000_000)
+	realtimeTestModel     = "gpt-4o-realtime-preview"
+)
+
+const realtimeTestResponseDone = `{"type":"response.done","response":{"usage":{"total_tokens":1200,` +
+	`"input_tokens":700,"output_tokens":500,` +
+	`"input_token_details":{"cached_tokens":300,"text_tokens":200,"audio_tokens":500,"cached_tokens_details":{"text_tokens":100,"audio_tokens":200}},` +
+	`"output_token_details":{"text_tokens":100,"audio_tokens":400}}}}`
+
+func TestParseRealtimeUsage(t *testing.T) {
+	usage := parseRealtimeUsage([]byte(realtimeTestResponseDone))
+	require.Equal(t, &realtimeUsage{
+		InputTextTokens:        200,
+		InputAudioTokens:       500,
+		CachedInputTextTokens:  100,
+		CachedInputAudioTokens: 200,
+		OutputTextTokens:       100,
+		OutputAudioTokens:      400,
+	}, usage)
+
+	// Cached tokens without a breakdown count as text
+	usage = parseRealtimeUsage([]byte(`{"type":"response.done","response":{"usage":{"input_token_details":{"cached_tokens":50,"text_tokens":80,"audio_tokens":10}}}}`))
+	require.Equal(t, 50, usage.CachedInputTextTokens)
+	require.Zero(t, usage.CachedInputAudioTokens)
+
+	require.Nil(t, parseRealtimeUsage([]byte(`{"type":"response.created","response":{}}`)))
+	require.Nil(t, parseRealtimeUsage([]byte(`{"type":"response.done","response":{"status":"cancelled"}}`)))
+	require.Nil(t, parseRealtimeUsage([]byte(`not json "response.done"`)))
+}
+
+func TestRealtimeUsageToBillingUsage(t *testing.T) {
+	usage := (&realtimeUsage{
+		InputTextTokens:        200,
+		InputAudioTokens:       500,
+		CachedInputTextTokens:  100,
+		CachedInputAudioTokens: 200,
+		OutputTextTokens:       100,
+		OutputAudioTokens:      400,
+	}).toBillingUsage(&relayAudioRatios{prompt: 8, completion: 2})
+
+	// 100 uncached text + 300 cached + 300 uncached audio × 8
+	require.Equal(t, 2800, usage.PromptTokens)
+	// 100 text + 400 audio × 8 × 2
+	require.Equal(t, 6500, usage.CompletionTokens)
+	require.Equal(t, 9300, usage.TotalTokens)
+	require.Equal(t, 300, usage.PromptTokensDetails.CachedTokens)
+}
+
+func TestRealtimeUsageStore_Memory(t *testing.T) {
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(originalRedis) })
+	ctx := context.Background()
+
+	require.NoError(t, addRealtimeUsage(ctx, "session", &realtimeUsage{InputTextTokens: 1, OutputAudioTokens: 2}))
+	require.NoError(t, addRealtimeUsage(ctx, "session", &realtimeUsage{InputTextTokens: 3}))
+
+	usage, err := takeRealtimeUsage(ctx, "session")
+	require.NoError(t, err)
+	require.Equal(t, &realtimeUsage{InputTextTokens: 4, OutputAudioTokens: 2}, usage)
+
+	usage, err = takeRealtimeUsage(ctx, "session")
+	require.NoError(t, err)
+	require.True(t, usage.isZero())
+}
+
+func TestRelayRealtimeHelper_ProxiesAndBillsSession(t *testing.T) {
+	ensureResponseFallbackDB(t)
+	require.NoError(t, model.DB.AutoMigrate(&model.User{}, &model.Token{}, &model.Log{}))
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	require.NoError(t, model.DB.Unscoped().Where("id = ?", realtimeTestUserID).Delete(&model.User{}).Error)
+	require.NoError(t, model.DB.Where("id = ?", realtimeTestTokenID).Delete(&model.Token{}).Error)
+	require.NoError(t, model.DB.Create(&model.User{
+		Id: realtimeTestUserID, Username: "realtime-user", AccessToken: "realtime-access-token", AffCode: "realtime-aff",
+		Quota: realtimeTestUserQuota, Status: model.UserStatusEnabled,
+	}).Error)
+	require.NoError(t, model.DB.Create(&model.Token{
+		Id: realtimeTestTokenID, UserId: realtimeTestUserID, Key: "realtime-token-key", Name: "realtime-token",
+		Status: model.TokenStatusEnabled, UnlimitedQuota: true,
+	}).Error)
+	t.Cleanup(func() {
+		common.SetRedisEnabled(originalRedis)
+		model.DB.Unscoped().Where("id = ?", realtimeTestUserID).Delete(&model.User{})
+		model.DB.Where("id = ?", realtimeTestTokenID).Delete(&model.Token{})
+	})
+
+	// The upstream answers every client message with an echo and a response.done event
+	upgrader := websocket.Upgrader{}
+	var upstreamQuery, upstreamAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamQuery, upstreamAuth = r.URL.RawQuery, r.Header.Get("Authorization")
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = conn.WriteMessage(mt, msg)
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(realtimeTestResponseDone))
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	gin.SetMode(gin.TestMode)
+	done := make(chan *relaymodel.ErrorWithStatusCode, 1)
+	engine := gin.New()
+	engine.GET("/v1/realtime", func(c *gin.Context) {
+		c.Set(ctxkey.ChannelRatio, 1.0)
+		c.Set(ctxkey.RequestId, "realtime-test-session")
+		metalib.Set2Context(c, &metalib.Meta{
+			Mode:            relaymode.Realtime,
+			ChannelType:     channeltype.OpenAI,
+			APIType:         apitype.OpenAI,
+			ChannelId:       realtimeTestChannelID,
+			UserId:          realtimeTestUserID,
+			TokenId:         realtimeTestTokenID,
+			TokenName:       "realtime-token",
+			BaseURL:         upstream.URL,
+			APIKey:          "realtime-key",
+			ActualModelName: realtimeTestModel,
+			StartTime:       time.Now(),
+		})
+		done <- RelayRealtimeHelper(c)
+	})
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/realtime?model=alias"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	for range 2 {
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{1, 2, 3}))
+		mt, msg, err := conn.ReadMessage()
+		require.NoError(t, err)
+		require.Equal(t, websocket.BinaryMessage, mt)
+		require.Equal(t, []byte{1, 2, 3}, msg)
+		_, msg, err = conn.ReadMessage()
+		require.NoError(t, err)
+		require.JSONEq(t, realtimeTestResponseDone, string(msg))
+	}
+	require.NoError(t, conn.Close())
+
+	select {
+	case bizErr := <-done:
+		require.Nil(t, bizErr)
+	case <-time.After(5 * time.Second):
+		t.Fatal("realtime session did not finish")
+	}
+	require.Equal(t, "model="+realtimeTestModel, upstreamQuery)
+	require.Equal(t, "Bearer realtime-key", upstreamAuth)
+
+	// Two response.done events are billed together when the session closes
+	pricingAdaptor := relay.GetAdaptor(apitype.OpenAI)
+	sessionUsage := &realtimeUsage{
+		InputTextTokens: 400, InputAudioTokens: 1000, CachedInputTextTokens: 200, CachedInputAudioTokens: 400,
+		OutputTextTokens: 200, OutputAudioTokens: 800,
+	}
+	expected := quotautil.Compute(quotautil.ComputeInput{
+		Usage:          sessionUsage.toBillingUsage(&relayAudioRatios{prompt: 8, completion: 2}),
+		ModelName:      realtimeTestModel,
+		ModelRatio:     pricing.GetModelRatioWithThreeLayers(realtimeTestModel, nil, pricingAdaptor),
+		GroupRatio:     1.0,
+		PricingAdaptor: pricingAdaptor,
+	}).TotalQuota
+	require.Positive(t, expected)
+
+	var logs []model.Log
+	require.NoError(t, model.LOG_DB.Where("user_id = ?", realtimeTestUserID).Find(&logs).Error)
+	require.Len(t, logs, 1)
+	require.EqualValues(t, expected, logs[0].Quota)
+	require.Equal(t, 1400, logs[0].PromptTokens)
+	require.Equal(t, 1000, logs[0].CompletionTokens)
+	require.True(t, strings.HasPrefix(logs[0].Content, "realtime session"))
+
+	user, err := model.GetUserById(realtimeTestUserID, true)
+	require.NoError(t, err)
+	require.Equal(t, realtimeTestUserQuota-expected, user.Quota)
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/common"
+)
+
+// realtimeUsageTTL bounds how long the usage of a session is kept if the
+// session is never settled, e.g. because the instance crashed mid-session.
+const realtimeUsageTTL = 24 * time.Hour
+
+// realtimeUsage is the token usage of a Realtime session, summed over the
+// response.done events of the session.
+type realtimeUsage struct {
+	InputTextTokens        int
+	InputAudioTokens       int
+	CachedInputTextTokens  int
+	CachedInputAudioTokens int
+	OutputTextTokens       int
+	OutputAudioTokens      int
+}
+
+// fields lists the counters of u keyed by their Redis hash field.
+func (u *realtimeUsage) fields() map[string]*int {
+	return map[string]*int{
+		"input_text_tokens":         &u.InputTextTokens,
+		"input_audio_tokens":        &u.InputAudioTokens,
+		"cached_input_text_tokens":  &u.CachedInputTextTokens,
+		"cached_input_audio_tokens": &u.CachedInputAudioTokens,
+		"output_text_tokens":        &u.OutputTextTokens,
+		"output_audio_tokens":       &u.OutputAudioTokens,
+	}
+}
+
+func (u *realtimeUsage) add(delta *realtimeUsage) {
+	fields := u.fields()
+	for name, value := range delta.fields() {
+		*fields[name] += *value
+	}
+}
+
+func (u *realtimeUsage) isZero() bool {
+	return *u == realtimeUsage{}
+}
+
+func realtimeUsageKey(sessionId string) string {
+	return fmt.Sprintf("realtime:usage:%s", sessionId)
+}
+
+// memoryRealtimeUsage holds session usage when Redis is unavailable.
+var memoryRealtimeUsage = struct {
+	sync.Mutex
+	sessions map[string]*realtimeUsage
+}{sessions: make(map[string]*realtimeUsage)}
+
+// addRealtimeUsage adds delta to the usage stored for the session.
+func addRealtimeUsage(ctx context.Context, sessionId string, delta *realtimeUsage) error {
+	if common.IsRedisEnabled() && common.RDB != nil {
+		key := realtimeUsageKey(sessionId)
+		pipe := common.RDB.TxPipeline()
+		for name, value := range delta.fields() {
+			if *value != 0 {
+				pipe.HIncrBy(ctx, key, name, int64(*value))
+			}
+		}
+		pipe.Expire(ctx, key, realtimeUsageTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return errors.Wrapf(err, "add usage of realtime session %s", sessionId)
+		}
+		return nil
+	}
+
+	memoryRealtimeUsage.Lock()
+	defer memoryRealtimeUsage.Unlock()
+	usage, ok := memoryRealtimeUsage.sessions[sessionId]
+	if !ok {
+		usage = &realtimeUsage{}
+		memoryRealtimeUsage.sessions[sessionId] = usage
+	}
+	usage.add(delta)
+	return nil
+}
+
+// takeRealtimeUsage returns and removes the usage stored for the session.
+func takeRealtimeUsage(ctx context.Context, sessionId string) (*realtimeUsage, error) {
+	usage := &realtimeUsage{}
+	if common.IsRedisEnabled() && common.RDB != nil {
+		key := realtimeUsageKey(sessionId)
+		pipe := common.RDB.TxPipeline()
+		getCmd := pipe.HGetAll(ctx, key)
+		pipe.Del(ctx, key)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, errors.Wrapf(err, "take usage of realtime session %s", sessionId)
+		}
+		stored := getCmd.Val()
+		for name, value := range usage.fields() {
+			*value, _ = strconv.Atoi(stored[name])
+		}
+		return usage, nil
+	}
+
+	memoryRealtimeUsage.Lock()
+	defer memoryRealtimeUsage.Unlock()
+	if stored, ok := memoryRealtimeUsage.sessions[sessionId]; ok {
+		*usage = *stored
+		delete(memoryRealtimeUsage.sessions, sessionId)
+	}
+	return usage, nil
+}
@@ -187,9 +187,12 @@ func RelayRerankHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 		metrics.GlobalRecorder.RecordModelUsage(meta.ActualModelName, channeltype.IdToName(meta.ChannelType), time.Since(meta.StartTime))
 	}
 
+	bodySizes := captureBodySizes(c, meta)
 	graceful.GoCritical(gmw.BackgroundCtx(c), "postBillingRerank", func(bctx context.Context) {
+		bodySizes.record()
+
 		baseBillingTimeout := time.Duration(config.BillingTimeoutSec) * time.Second
-		bctx, cancel := context.WithTimeout(gmw.BackgroundCtx(c), baseBillingTimeout)
+		bctx, cancel := context.WithTimeout(bodySizes.context(gmw.BackgroundCtx(c)), baseBillingTimeout)
 		defer cancel()
 
 		done := make(chan bool, 1)
@@ -305,14 +308,15 @@ func preConsumeRerankQuota(c *gin.Context, perCallQuota int64, meta *metalib.Met
 	if err != nil {
 		return perCallQuota, openai.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
 	}
-	if userQuota-perCallQuota < 0 {
+	if model.QuotaWithOverage(userQuota)-perCallQuota < 0 {
 		return perCallQuota, openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
 	}
-	if err := model.CacheDecreaseUserQuota(ctx, meta.UserId, perCallQuota); err != nil {
+	trusted := userQuota > 100*perCallQuota && (tokenQuotaUnlimited || tokenQuota > 100*perCallQuota)
+	if err := decreaseCachedUserQuota(ctx, meta.UserId, perCallQuota, !trusted); err != nil {
 		return perCallQuota, openai.ErrorWrapper(err, "decrease_user_quota_failed", http.StatusInternalServerError)
 	}
 
-	if userQuota > 100*perCallQuota && (tokenQuotaUnlimited || tokenQuota > 100*perCallQuota) {
+	if trusted {
 		lg.Info("user has enough quota, trusted and no need to pre-consume", zap.Int("user_id", meta.UserId), zap.Int64("user_quota", userQuota))
 		return 0, nil
 	}
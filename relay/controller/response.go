@@ -98,6 +98,9 @@ func RelayResponseAPIHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 	if err := tooling.ValidateRequestedBuiltins(responseAPIRequest.Model, meta, channelRecord, requestAdaptor, requestedBuiltins); err != nil {
 		return openai.ErrorWrapper(err, "tool_not_allowed", http.StatusBadRequest)
 	}
+	if bizErr := validateModelFeatures(responseAPIRequest.Model, responseRequestFeatures(responseAPIRequest), channelRecord, requestAdaptor); bizErr != nil {
+		return bizErr
+	}
 
 	// get channel model ratio
 	channelModelRatio, channelCompletionRatio := getChannelRatios(c)
@@ -191,7 +194,9 @@ func RelayResponseAPIHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 
 	// do response
 	c.Set(ctxkey.SkipAdaptorResponseBodyLog, true)
+	stopWatchdog := watchStreamIdleTimeout(c, meta, resp)
 	usage, respErr := requestAdaptor.DoResponse(c, resp, meta)
+	stopWatchdog()
 	if upstreamCapture != nil {
 		logUpstreamResponseFromCapture(lg, resp, upstreamCapture, "response_api")
 	} else {
@@ -216,12 +221,15 @@ func RelayResponseAPIHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 	quotaId := c.GetInt(ctxkey.Id)
 	requestId := c.GetString(ctxkey.RequestId)
 
+	bodySizes := captureBodySizes(c, meta)
 	graceful.GoCritical(gmw.BackgroundCtx(c), "postBilling", func(ctx context.Context) {
+		bodySizes.record()
+
 		// Use configurable billing timeout with model-specific adjustments
 		baseBillingTimeout := time.Duration(config.BillingTimeoutSec) * time.Second
 		billingTimeout := baseBillingTimeout
 
-		ctx, cancel := context.WithTimeout(gmw.BackgroundCtx(c), billingTimeout)
+		ctx, cancel := context.WithTimeout(bodySizes.context(gmw.BackgroundCtx(c)), billingTimeout)
 		defer cancel()
 
 		// Monitor for timeout and log critical errors
@@ -339,6 +347,9 @@ func relayResponseAPIThroughChat(c *gin.Context, meta *metalib.Meta, responseAPI
 	if err := tooling.ValidateChatBuiltinTools(c, chatRequest, meta, channelRecord, requestAdaptor); err != nil {
 		return openai.ErrorWrapper(err, "tool_not_allowed", http.StatusBadRequest)
 	}
+	if bizErr := validateModelFeatures(chatRequest.Model, adaptor.ChatRequestFeatures(chatRequest), channelRecord, requestAdaptor); bizErr != nil {
+		return bizErr
+	}
 
 	channelModelRatio, channelCompletionRatio := getChannelRatios(c)
 	pricingAdaptor := relay.GetAdaptor(meta.ChannelType)
@@ -383,7 +394,7 @@ func relayResponseAPIThroughChat(c *gin.Context, meta *metalib.Meta, responseAPI
 	// Record provisional quota usage for reconciliation
 	if requestId := c.GetString(ctxkey.RequestId); requestId != "" {
 		quotaId := c.GetInt(ctxkey.Id)
-		estimated := getPreConsumedQuota(chatRequest, promptTokens, ratio)
+		estimated := getPreConsumedQuota(ctx, chatRequest, promptTokens, ratio)
 		if err := model.UpdateUserRequestCostQuotaByRequestID(quotaId, requestId, estimated); err != nil {
 			lg.Warn("record provisional user request cost failed", zap.Error(err), zap.String("request_id", requestId))
 		}
@@ -396,7 +407,9 @@ func relayResponseAPIThroughChat(c *gin.Context, meta *metalib.Meta, responseAPI
 		return RelayErrorHandlerWithContext(c, resp)
 	}
 
+	stopWatchdog := watchStreamIdleTimeout(c, meta, resp)
 	usage, respErr := requestAdaptor.DoResponse(c, resp, meta)
+	stopWatchdog()
 	if upstreamCapture != nil {
 		logUpstreamResponseFromCapture(lg, resp, upstreamCapture, "response_api_fallback")
 	} else {
@@ -484,16 +497,20 @@ func relayResponseAPIThroughChat(c *gin.Context, meta *metalib.Meta, responseAPI
 		)
 
 		metrics.GlobalRecorder.RecordModelUsage(meta.ActualModelName, channeltype.IdToName(meta.ChannelType), time.Since(meta.StartTime))
+		recordTokenCountDiscrepancy(c, meta.ActualModelName, meta.PromptTokens, usage.PromptTokens)
 	}
 
 	quotaId := c.GetInt(ctxkey.Id)
 	requestId := c.GetString(ctxkey.RequestId)
 
+	bodySizes := captureBodySizes(c, meta)
 	graceful.GoCritical(gmw.BackgroundCtx(c), "postBilling", func(ctx context.Context) {
+		bodySizes.record()
+
 		baseBillingTimeout := time.Duration(config.BillingTimeoutSec) * time.Second
 		billingTimeout := baseBillingTimeout
 
-		ctx, cancel := context.WithTimeout(gmw.BackgroundCtx(c), billingTimeout)
+		ctx, cancel := context.WithTimeout(bodySizes.context(gmw.BackgroundCtx(c)), billingTimeout)
 		defer cancel()
 
 		done := make(chan bool, 1)
@@ -1066,7 +1083,7 @@ func preConsumeResponseAPIQuota(
 	if err != nil {
 		return baseQuota, openai.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
 	}
-	if userQuota-baseQuota < 0 {
+	if model.QuotaWithOverage(userQuota)-baseQuota < 0 {
 		return baseQuota, openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
 	}
 
@@ -1170,6 +1187,7 @@ func postConsumeResponseAPIQuota(ctx context.Context,
 		}
 		metadata := model.AppendToolUsageMetadata(nil, toolSummary)
 		metadata = model.AppendCacheWriteTokensMetadata(metadata, usage.CacheWrite5mTokens, usage.CacheWrite1hTokens)
+		metadata = appendStreamingTimeoutMetadata(ctx, metadata)
 
 		billing.PostConsumeQuotaDetailed(billing.QuotaConsumeDetail{
 			Ctx:                    ctx,
@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/meta"
+)
+
+// responseMetadataKey is the top-level field carrying upstream attribution.
+const responseMetadataKey = "oneapi_metadata"
+
+// sseDoneMarker terminates OpenAI-compatible event streams.
+var sseDoneMarker = []byte("data: [DONE]")
+
+// ResponseMetadata describes which upstream served a relayed request.
+type ResponseMetadata struct {
+	Channel     string `json:"channel"`
+	ModelMapped string `json:"model_mapped"`
+	LatencyMs   int64  `json:"latency_ms"`
+}
+
+// responseAnnotationMode is how the annotation writer treats the response body.
+type responseAnnotationMode int
+
+const (
+	annotationUndecided responseAnnotationMode = iota
+	annotationPassthrough
+	annotationBufferJSON
+	annotationStream
+)
+
+// ResponseAnnotationWriter wraps gin.ResponseWriter and applies the selected
+// channel's ResponseAnnotations. The channel is resolved when the first byte is
+// written, so retries that switch channels are attributed correctly.
+type ResponseAnnotationWriter struct {
+	gin.ResponseWriter
+	c            *gin.Context
+	startTime    time.Time
+	mode         responseAnnotationMode
+	channel      *model.Channel
+	buffer       bytes.Buffer
+	metadataSent bool
+}
+
+// EnsureResponseAnnotationWriter installs a ResponseAnnotationWriter on c. The
+// returned writer's Finish must be called once the relay has completed.
+func EnsureResponseAnnotationWriter(c *gin.Context) *ResponseAnnotationWriter {
+	wrapper := &ResponseAnnotationWriter{
+		ResponseWriter: c.Writer,
+		c:              c,
+		startTime:      time.Now(),
+	}
+	c.Writer = wrapper
+	return wrapper
+}
+
+// decide picks the body handling mode once, based on the current channel and
+// the response status and content type.
+func (w *ResponseAnnotationWriter) decide() {
+	if w.mode != annotationUndecided {
+		return
+	}
+	w.mode = annotationPassthrough
+
+	v, ok := w.c.Get(ctxkey.ChannelModel)
+	if !ok {
+		return
+	}
+	channel, ok := v.(*model.Channel)
+	if !ok || channel == nil {
+		return
+	}
+	annotations := channel.GetResponseAnnotations()
+	if annotations == nil {
+		return
+	}
+	w.channel = channel
+
+	if annotations.InjectHeader != "" {
+		w.Header().Set(annotations.InjectHeader, channel.Name)
+	}
+	if !annotations.InjectMetadata {
+		return
+	}
+	status := w.ResponseWriter.Status()
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		return
+	}
+	contentType := w.Header().Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		w.mode = annotationBufferJSON
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		w.mode = annotationStream
+	}
+}
+
+// Write buffers JSON bodies for later injection and inserts the metadata event
+// ahead of the stream terminator for SSE bodies.
+func (w *ResponseAnnotationWriter) Write(data []byte) (int, error) {
+	w.decide()
+	switch w.mode {
+	case annotationBufferJSON:
+		return w.buffer.Write(data)
+	case annotationStream:
+		if !w.metadataSent {
+			if idx := bytes.Index(data, sseDoneMarker); idx >= 0 {
+				if _, err := w.ResponseWriter.Write(data[:idx]); err != nil {
+					return 0, err
+				}
+				if err := w.writeMetadataEvent(); err != nil {
+					return 0, err
+				}
+				if _, err := w.ResponseWriter.Write(data[idx:]); err != nil {
+					return 0, err
+				}
+				return len(data), nil
+			}
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString routes through Write so strings receive the same treatment.
+func (w *ResponseAnnotationWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow defers the header flush while a JSON body is being buffered.
+func (w *ResponseAnnotationWriter) WriteHeaderNow() {
+	w.decide()
+	if w.mode == annotationBufferJSON {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush is a no-op while a JSON body is being buffered.
+func (w *ResponseAnnotationWriter) Flush() {
+	w.decide()
+	if w.mode == annotationBufferJSON {
+		return
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Written reports true once a buffered body has started.
+func (w *ResponseAnnotationWriter) Written() bool {
+	return w.buffer.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Finish emits any buffered JSON body with metadata attached, or the trailing
+// metadata event for streams that ended without a terminator.
+func (w *ResponseAnnotationWriter) Finish() error {
+	switch w.mode {
+	case annotationBufferJSON:
+		body := w.buffer.Bytes()
+		if annotated, ok := injectResponseMetadata(body, w.metadata()); ok {
+			body = annotated
+		}
+		w.buffer.Reset()
+		w.mode = annotationPassthrough
+		w.Header().Del("Content-Length")
+		_, err := w.ResponseWriter.Write(body)
+		return err
+	case annotationStream:
+		if w.metadataSent {
+			return nil
+		}
+		if err := w.writeMetadataEvent(); err != nil {
+			return err
+		}
+		w.ResponseWriter.Flush()
+	}
+	return nil
+}
+
+// writeMetadataEvent sends the metadata as its own SSE data event.
+func (w *ResponseAnnotationWriter) writeMetadataEvent() error {
+	w.metadataSent = true
+	payload, err := json.Marshal(map[string]ResponseMetadata{responseMetadataKey: w.metadata()})
+	if err != nil {
+		return err
+	}
+	_, err = w.ResponseWriter.Write([]byte("data: " + string(payload) + "\n\n"))
+	return err
+}
+
+// metadata captures the attribution for the current response.
+func (w *ResponseAnnotationWriter) metadata() ResponseMetadata {
+	md := ResponseMetadata{
+		ModelMapped: w.c.GetString(ctxkey.RequestModel),
+		LatencyMs:   time.Since(w.startTime).Milliseconds(),
+	}
+	if w.channel != nil {
+		md.Channel = w.channel.Name
+	}
+	if v, ok := w.c.Get(ctxkey.Meta); ok {
+		if m, ok := v.(*meta.Meta); ok && m.ActualModelName != "" {
+			md.ModelMapped = m.ActualModelName
+		}
+	}
+	return md
+}
+
+// injectResponseMetadata appends metadata as a top-level field of the JSON
+// object in body without reordering existing fields. It reports false when body
+// is not a JSON object.
+func injectResponseMetadata(body []byte, md ResponseMetadata) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) < 2 || trimmed[0] != '{' || trimmed[len(trimmed)-1] != '}' || !json.Valid(trimmed) {
+		return nil, false
+	}
+	payload, err := json.Marshal(md)
+	if err != nil {
+		return nil, false
+	}
+
+	inner := bytes.TrimSpace(trimmed[1 : len(trimmed)-1])
+	out := make([]byte, 0, len(trimmed)+len(payload)+len(responseMetadataKey)+8)
+	out = append(out, '{')
+	if len(inner) > 0 {
+		out = append(out, inner...)
+		out = append(out, ',')
+	}
+	out = append(out, `"`+responseMetadataKey+`":`...)
+	out = append(out, payload...)
+	out = append(out, '}')
+	return out, true
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/meta"
+)
+
+// serveAnnotated runs handler behind a ResponseAnnotationWriter for a channel
+// configured with annotations.
+func serveAnnotated(t *testing.T, annotations string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		annotator := EnsureResponseAnnotationWriter(c)
+		c.Set(ctxkey.ChannelModel, &model.Channel{Id: 1, Name: "openai-prod", ResponseAnnotations: &annotations})
+		c.Set(ctxkey.RequestModel, "gpt-4o-latest")
+		c.Set(ctxkey.Meta, &meta.Meta{ActualModelName: "gpt-4o"})
+		handler(c)
+		require.NoError(t, annotator.Finish())
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	return w
+}
+
+func requireMetadata(t *testing.T, raw json.RawMessage) {
+	t.Helper()
+	var md ResponseMetadata
+	require.NoError(t, json.Unmarshal(raw, &md))
+	require.Equal(t, "openai-prod", md.Channel)
+	require.Equal(t, "gpt-4o", md.ModelMapped)
+	require.GreaterOrEqual(t, md.LatencyMs, int64(0))
+}
+
+func TestResponseAnnotations_NonStreamingJSON(t *testing.T) {
+	w := serveAnnotated(t, `{"inject_header":"X-OneApi-Channel","inject_metadata":true}`, func(c *gin.Context) {
+		c.Header("Content-Length", "64")
+		c.JSON(http.StatusOK, gin.H{"id": "chatcmpl-1", "object": "chat.completion"})
+	})
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "openai-prod", w.Header().Get("X-OneApi-Channel"))
+	require.Empty(t, w.Header().Get("Content-Length"))
+
+	var body map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.JSONEq(t, `"chatcmpl-1"`, string(body["id"]))
+	requireMetadata(t, body[responseMetadataKey])
+}
+
+func TestResponseAnnotations_StreamingInsertsEventBeforeDone(t *testing.T) {
+	w := serveAnnotated(t, `{"inject_metadata":true}`, func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.Write([]byte("data: {\"id\":\"chunk-1\"}\n\n"))
+		c.Writer.Flush()
+		_, _ = c.Writer.Write([]byte("data: [DONE]\n\n"))
+	})
+
+	events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+	require.Len(t, events, 3)
+	require.Equal(t, `data: {"id":"chunk-1"}`, events[0])
+	require.Equal(t, "data: [DONE]", events[2])
+
+	var event map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[1], "data: ")), &event))
+	requireMetadata(t, event[responseMetadataKey])
+}
+
+func TestResponseAnnotations_StreamingWithoutDoneAppendsEvent(t *testing.T) {
+	w := serveAnnotated(t, `{"inject_metadata":true}`, func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		_, _ = c.Writer.Write([]byte("event: message_stop\ndata: {}\n\n"))
+	})
+
+	require.True(t, strings.HasPrefix(w.Body.String(), "event: message_stop\ndata: {}\n\n"))
+	require.Contains(t, w.Body.String(), `data: {"oneapi_metadata":{"channel":"openai-prod"`)
+}
+
+func TestResponseAnnotations_ErrorsAndUnconfiguredChannelsPassThrough(t *testing.T) {
+	w := serveAnnotated(t, `{"inject_header":"X-OneApi-Channel","inject_metadata":true}`, func(c *gin.Context) {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "upstream failed"})
+	})
+	require.Equal(t, http.StatusBadGateway, w.Code)
+	require.Equal(t, "openai-prod", w.Header().Get("X-OneApi-Channel"))
+	require.JSONEq(t, `{"error":"upstream failed"}`, w.Body.String())
+
+	w = serveAnnotated(t, "", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": "chatcmpl-1"})
+	})
+	require.JSONEq(t, `{"id":"chatcmpl-1"}`, w.Body.String())
+	require.Empty(t, w.Header().Get("X-OneApi-Channel"))
+}
+
+func TestValidateResponseAnnotationsJSON(t *testing.T) {
+	require.NoError(t, model.ValidateResponseAnnotationsJSON(`{"inject_header":"X-OneApi-Channel","inject_metadata":true}`))
+	require.Error(t, model.ValidateResponseAnnotationsJSON(`{"inject_header":"Bad Header"}`))
+	require.Error(t, model.ValidateResponseAnnotationsJSON(`{"inject_headers":"X-Typo"}`))
+}
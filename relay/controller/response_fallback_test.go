@@ -807,7 +807,7 @@ func ensureResponseFallbackFixtures(t *testing.T) {
 		t.Fatalf("failed to migrate tables: %v", err)
 	}
 
-	if err := model.DB.Where("id = ?", fallbackUserID).Delete(&model.User{}).Error; err != nil {
+	if err := model.DB.Unscoped().Where("id = ?", fallbackUserID).Delete(&model.User{}).Error; err != nil {
 		t.Fatalf("failed to clean user fixture: %v", err)
 	}
 	user := &model.User{Id: fallbackUserID, Username: "response-fallback", Quota: 1_000_000, Status: model.UserStatusEnabled}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+func TestRelayTextHelper_ForwardsResponseLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ensureResponseFallbackFixtures(t)
+
+	prevRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(prevRedis) })
+
+	prevLogConsume := config.IsLogConsumeEnabled()
+	config.SetLogConsumeEnabled(false)
+	t.Cleanup(func() { config.SetLogConsumeEnabled(prevLogConsume) })
+
+	var upstreamBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		upstreamBody, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4.1-nano",`+
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"你好"},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	}))
+	defer upstream.Close()
+
+	prevClient := client.HTTPClient
+	client.HTTPClient = upstream.Client()
+	t.Cleanup(func() { client.HTTPClient = prevClient })
+
+	send := func(body, language string) map[string]any {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer compat-key")
+		c.Request = req
+		gmw.SetLogger(c, logger.Logger)
+
+		c.Set(ctxkey.Channel, channeltype.OpenAICompatible)
+		c.Set(ctxkey.ChannelId, fallbackCompatibleChannelID)
+		c.Set(ctxkey.TokenId, fallbackTokenID)
+		c.Set(ctxkey.TokenName, "fallback-token")
+		c.Set(ctxkey.Id, fallbackUserID)
+		c.Set(ctxkey.Group, "default")
+		c.Set(ctxkey.ModelMapping, map[string]string{})
+		c.Set(ctxkey.ChannelRatio, 1.0)
+		c.Set(ctxkey.RequestModel, "gpt-4.1-nano")
+		c.Set(ctxkey.BaseURL, upstream.URL)
+		c.Set(ctxkey.ContentType, "application/json")
+		c.Set(ctxkey.RequestId, "req_response_language")
+		c.Set(ctxkey.TokenQuotaUnlimited, true)
+		c.Set(ctxkey.TokenQuota, int64(0))
+		c.Set(ctxkey.Username, "response-fallback")
+		c.Set(ctxkey.UserQuota, int64(1_000_000))
+		c.Set(ctxkey.ChannelModel, &model.Channel{Id: fallbackCompatibleChannelID, Type: channeltype.OpenAICompatible})
+		c.Set(ctxkey.Config, model.ChannelConfig{})
+		if language != "" {
+			c.Set(ctxkey.ResponseLanguage, language)
+		}
+
+		require.Nil(t, RelayTextHelper(c))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		payload := make(map[string]any)
+		decoder := json.NewDecoder(strings.NewReader(string(upstreamBody)))
+		decoder.UseNumber()
+		require.NoError(t, decoder.Decode(&payload))
+		return payload
+	}
+
+	body := `{"model":"gpt-4.1-nano","num_ctx":9007199254740993,"messages":[{"role":"user","content":"hi"}]}`
+
+	payload := send(body, "zh-CN")
+	require.Equal(t, "zh-CN", payload["language"])
+	require.Equal(t, json.Number("9007199254740993"), payload["num_ctx"])
+
+	// A language sent by the caller is kept
+	payload = send(`{"model":"gpt-4.1-nano","language":"fr","messages":[{"role":"user","content":"hi"}]}`, "zh-CN")
+	require.Equal(t, "fr", payload["language"])
+
+	payload = send(body, "")
+	require.NotContains(t, payload, "language")
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+// streamTimingHeader opts a streaming request into server timing events.
+const streamTimingHeader = "X-OneApi-Timing"
+
+// Stages of the server timing events injected into timed streams.
+const (
+	streamTimingStageChannelSelected = "channel_selected"
+	streamTimingStageFirstToken      = "first_token"
+	streamTimingStageComplete        = "complete"
+)
+
+// streamTimingEvent is the SSE payload of a server timing event.
+type streamTimingEvent struct {
+	Type         string   `json:"type"`
+	Stage        string   `json:"stage"`
+	ElapsedMs    *int64   `json:"elapsed_ms,omitempty"`
+	TTFTMs       *int64   `json:"ttft_ms,omitempty"`
+	TotalMs      *int64   `json:"total_ms,omitempty"`
+	TokensPerSec *float64 `json:"tokens_per_sec,omitempty"`
+}
+
+// streamTimingWriter injects server timing events into an SSE response. The
+// channel_selected and first_token events precede the first data chunk, and
+// the [DONE] sentinel is held back until finish has emitted the complete event.
+type streamTimingWriter struct {
+	gin.ResponseWriter
+	receivedAt      time.Time
+	channelSelected time.Time
+	firstToken      time.Time
+	holdingDone     bool
+	finished        bool
+}
+
+// enableStreamTiming wraps c.Writer with a streamTimingWriter when a streaming
+// request sets the X-OneApi-Timing header, and returns nil otherwise.
+func enableStreamTiming(c *gin.Context, meta *metalib.Meta) *streamTimingWriter {
+	if !meta.IsStream {
+		return nil
+	}
+	if enabled, _ := strconv.ParseBool(c.GetHeader(streamTimingHeader)); !enabled {
+		return nil
+	}
+
+	w := &streamTimingWriter{
+		ResponseWriter:  c.Writer,
+		receivedAt:      meta.StartTime,
+		channelSelected: meta.StartTime,
+	}
+	if v, ok := c.Get(ctxkey.RequestReceivedAt); ok {
+		if t, ok := v.(time.Time); ok {
+			w.receivedAt = t
+		}
+	}
+	if v, ok := c.Get(ctxkey.ChannelSelectedAt); ok {
+		if t, ok := v.(time.Time); ok {
+			w.channelSelected = t
+		}
+	}
+	c.Writer = w
+	return w
+}
+
+// Write forwards data, injecting timing events around it, see streamTimingWriter.
+func (w *streamTimingWriter) Write(data []byte) (int, error) {
+	if w.intercept(string(data)) {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString forwards s, injecting timing events around it, see streamTimingWriter.
+func (w *streamTimingWriter) WriteString(s string) (int, error) {
+	if w.intercept(s) {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// intercept emits the events due before payload and reports whether payload
+// must be held back instead of forwarded.
+func (w *streamTimingWriter) intercept(payload string) bool {
+	if w.finished {
+		return false
+	}
+
+	trimmed := strings.TrimSpace(payload)
+	switch {
+	case trimmed == "data: [DONE]":
+		w.holdingDone = true
+		return true
+	case w.holdingDone && trimmed == "":
+		// The separator written after the held [DONE]
+		return true
+	case w.firstToken.IsZero() && strings.HasPrefix(trimmed, "data:"):
+		w.firstToken = time.Now()
+		w.emit(streamTimingEvent{
+			Stage:     streamTimingStageChannelSelected,
+			ElapsedMs: elapsedMs(w.receivedAt, w.channelSelected),
+		})
+		w.emit(streamTimingEvent{
+			Stage:     streamTimingStageFirstToken,
+			ElapsedMs: elapsedMs(w.receivedAt, w.firstToken),
+		})
+	}
+	return false
+}
+
+// finish emits the complete event followed by the held [DONE] sentinel, and
+// returns the measured timing, or nil when nothing was streamed.
+func (w *streamTimingWriter) finish(usage *relaymodel.Usage) *model.StreamTiming {
+	if w.finished {
+		return nil
+	}
+	w.finished = true
+
+	var timing *model.StreamTiming
+	if !w.firstToken.IsZero() {
+		now := time.Now()
+		timing = &model.StreamTiming{
+			TTFTMs:  *elapsedMs(w.receivedAt, w.firstToken),
+			TotalMs: *elapsedMs(w.receivedAt, now),
+		}
+		if seconds := now.Sub(w.firstToken).Seconds(); usage != nil && seconds > 0 {
+			timing.TokensPerSec = math.Round(float64(usage.CompletionTokens)/seconds*10) / 10
+		}
+		w.emit(streamTimingEvent{
+			Stage:        streamTimingStageComplete,
+			TTFTMs:       &timing.TTFTMs,
+			TotalMs:      &timing.TotalMs,
+			TokensPerSec: &timing.TokensPerSec,
+		})
+	}
+	if w.holdingDone {
+		_, _ = w.ResponseWriter.WriteString("data: [DONE]\n\n")
+	}
+	w.ResponseWriter.Flush()
+	return timing
+}
+
+func (w *streamTimingWriter) emit(event streamTimingEvent) {
+	event.Type = "timing"
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	_, _ = w.ResponseWriter.WriteString("data: " + string(payload) + "\n\n")
+}
+
+// finishStreamTiming completes the timed stream of c, if any, and stores the
+// measured timing under ctxkey.StreamTiming for the log metadata.
+func finishStreamTiming(c *gin.Context, w *streamTimingWriter, usage *relaymodel.Usage) {
+	if w == nil {
+		return
+	}
+	if timing := w.finish(usage); timing != nil {
+		c.Set(ctxkey.StreamTiming, timing)
+	}
+}
+
+// streamTimingFromContext returns the stream timing recorded for the request, if any.
+func streamTimingFromContext(c *gin.Context) *model.StreamTiming {
+	if raw, ok := c.Get(ctxkey.StreamTiming); ok {
+		if timing, ok := raw.(*model.StreamTiming); ok {
+			return timing
+		}
+	}
+	return nil
+}
+
+func elapsedMs(from, to time.Time) *int64 {
+	ms := max(to.Sub(from).Milliseconds(), 0)
+	return &ms
+}
+
+// appendStreamTimingMetadata adds the stream timing of the request behind ctx,
+// if any, to metadata.
+func appendStreamTimingMetadata(ctx context.Context, metadata model.LogMetadata) model.LogMetadata {
+	ginCtx, ok := gmw.GetGinCtxFromStdCtx(ctx)
+	if !ok {
+		return metadata
+	}
+	return model.AppendStreamTimingMetadata(metadata, streamTimingFromContext(ginCtx))
+}
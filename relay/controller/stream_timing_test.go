@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/render"
+	"github.com/songquanpeng/one-api/model"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+func newTimedStreamContext(t *testing.T, header string) (*gin.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	if header != "" {
+		c.Request.Header.Set(streamTimingHeader, header)
+	}
+	return c, recorder
+}
+
+// sseDataEvents returns the payloads of the data events in body, in order.
+func sseDataEvents(body string) []string {
+	var events []string
+	for line := range strings.SplitSeq(body, "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			events = append(events, data)
+		}
+	}
+	return events
+}
+
+func TestStreamTiming_EventOrdering(t *testing.T) {
+	c, recorder := newTimedStreamContext(t, "true")
+	receivedAt := time.Now().Add(-50 * time.Millisecond)
+	c.Set(ctxkey.RequestReceivedAt, receivedAt)
+	c.Set(ctxkey.ChannelSelectedAt, receivedAt.Add(10*time.Millisecond))
+
+	w := enableStreamTiming(c, &metalib.Meta{IsStream: true, StartTime: time.Now()})
+	require.NotNil(t, w)
+
+	render.StringData(c, `{"choices":[{"delta":{"content":"Hel"}}]}`)
+	time.Sleep(20 * time.Millisecond)
+	render.StringData(c, `{"choices":[{"delta":{"content":"lo"}}]}`)
+	render.Done(c)
+	finishStreamTiming(c, w, &relaymodel.Usage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7})
+
+	events := sseDataEvents(recorder.Body.String())
+	require.Len(t, events, 6)
+
+	var channelSelected, firstToken, complete streamTimingEvent
+	require.NoError(t, json.Unmarshal([]byte(events[0]), &channelSelected))
+	require.Equal(t, "timing", channelSelected.Type)
+	require.Equal(t, streamTimingStageChannelSelected, channelSelected.Stage)
+	require.Equal(t, int64(10), *channelSelected.ElapsedMs)
+
+	require.NoError(t, json.Unmarshal([]byte(events[1]), &firstToken))
+	require.Equal(t, streamTimingStageFirstToken, firstToken.Stage)
+	require.GreaterOrEqual(t, *firstToken.ElapsedMs, int64(50))
+
+	require.Contains(t, events[2], "Hel")
+	require.Contains(t, events[3], "lo")
+
+	require.NoError(t, json.Unmarshal([]byte(events[4]), &complete))
+	require.Equal(t, streamTimingStageComplete, complete.Stage)
+	require.Equal(t, *firstToken.ElapsedMs, *complete.TTFTMs)
+	require.GreaterOrEqual(t, *complete.TotalMs, *complete.TTFTMs+20)
+	require.Greater(t, *complete.TokensPerSec, 0.0)
+
+	// [DONE] stays the last event
+	require.Equal(t, "[DONE]", events[5])
+
+	timing := streamTimingFromContext(c)
+	require.NotNil(t, timing)
+	require.Equal(t, *complete.TTFTMs, timing.TTFTMs)
+
+	metadata := appendStreamTimingMetadata(gmw.BackgroundCtx(c), nil)
+	require.Equal(t, timing.TTFTMs, metadata[model.LogMetadataKeyStreamTiming].(map[string]any)["ttft_ms"])
+}
+
+func TestStreamTiming_DisabledWithoutHeader(t *testing.T) {
+	c, recorder := newTimedStreamContext(t, "")
+	require.Nil(t, enableStreamTiming(c, &metalib.Meta{IsStream: true}))
+
+	c, _ = newTimedStreamContext(t, "true")
+	require.Nil(t, enableStreamTiming(c, &metalib.Meta{IsStream: false}))
+
+	// finishStreamTiming tolerates the disabled writer
+	finishStreamTiming(c, nil, nil)
+	require.Empty(t, recorder.Body.String())
+	require.Nil(t, streamTimingFromContext(c))
+}
+
+func TestStreamTiming_NothingStreamed(t *testing.T) {
+	c, recorder := newTimedStreamContext(t, "1")
+	w := enableStreamTiming(c, &metalib.Meta{IsStream: true, StartTime: time.Now()})
+	require.NotNil(t, w)
+
+	finishStreamTiming(c, w, nil)
+	require.Empty(t, sseDataEvents(recorder.Body.String()))
+	require.Nil(t, streamTimingFromContext(c))
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+)
+
+// streamingTimeoutUnit is the unit of configured streaming timeouts. Tests
+// shrink it to keep timing-based cases fast.
+var streamingTimeoutUnit = time.Second
+
+// errStreamIdleTimeout is returned by reads after the watchdog closed the stream.
+var errStreamIdleTimeout = errors.New("upstream stream idle timeout: no tokens received in time")
+
+// resolveStreamingIdleTimeout returns the idle timeout, in seconds, that applies
+// to the current stream and whether it came from the channel's per-model
+// settings. The mapped model name takes precedence over the requested one.
+func resolveStreamingIdleTimeout(c *gin.Context, meta *metalib.Meta) (string, int, bool) {
+	modelName := meta.ActualModelName
+	if v, ok := c.Get(ctxkey.ChannelModel); ok {
+		if channel, ok := v.(*model.Channel); ok && channel != nil {
+			timeouts := channel.GetModelStreamingTimeouts()
+			for _, name := range []string{meta.ActualModelName, meta.OriginModelName} {
+				if seconds, ok := timeouts[name]; ok && seconds > 0 && name != "" {
+					return name, seconds, true
+				}
+			}
+		}
+	}
+	return modelName, config.StreamingTokenIdleTimeout, false
+}
+
+// watchStreamIdleTimeout guards a streaming upstream response with an idle
+// watchdog that closes resp.Body when no tokens arrive within the resolved
+// timeout, and records the event under ctxkey.StreamingTimeout. The returned
+// function stops the watchdog and must be called once the response is handled.
+func watchStreamIdleTimeout(c *gin.Context, meta *metalib.Meta, resp *http.Response) func() {
+	if !meta.IsStream || resp == nil || resp.Body == nil {
+		return func() {}
+	}
+	modelName, seconds, modelSpecific := resolveStreamingIdleTimeout(c, meta)
+	if seconds <= 0 {
+		return func() {}
+	}
+
+	lg := gmw.GetLogger(c)
+	body := newIdleTimeoutBody(resp.Body, time.Duration(seconds)*streamingTimeoutUnit, func() {
+		lg.Warn("closing upstream stream after token idle timeout",
+			zap.String("model", modelName),
+			zap.Int("timeout_seconds", seconds),
+			zap.Bool("model_specific", modelSpecific),
+		)
+		c.Set(ctxkey.StreamingTimeout, &model.StreamingTimeoutEvent{
+			Model:          modelName,
+			TimeoutSeconds: seconds,
+			ModelSpecific:  modelSpecific,
+		})
+	})
+	resp.Body = body
+	return body.stop
+}
+
+// streamingTimeoutFromContext returns the streaming timeout event recorded for
+// the request, if any.
+func streamingTimeoutFromContext(c *gin.Context) *model.StreamingTimeoutEvent {
+	if raw, ok := c.Get(ctxkey.StreamingTimeout); ok {
+		if event, ok := raw.(*model.StreamingTimeoutEvent); ok {
+			return event
+		}
+	}
+	return nil
+}
+
+// appendStreamingTimeoutMetadata adds the streaming timeout event of the request
+// behind ctx, if any, to metadata.
+func appendStreamingTimeoutMetadata(ctx context.Context, metadata model.LogMetadata) model.LogMetadata {
+	ginCtx, ok := gmw.GetGinCtxFromStdCtx(ctx)
+	if !ok {
+		return metadata
+	}
+	return model.AppendStreamingTimeoutMetadata(metadata, streamingTimeoutFromContext(ginCtx))
+}
+
+// idleTimeoutBody closes the wrapped body when no content arrives within
+// timeout. SSE comments and blank lines, used as keep-alives, do not reset it.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	timeout   time.Duration
+	timer     *time.Timer
+	timedOut  atomic.Bool
+	stopped   atomic.Bool
+	onTimeout func()
+}
+
+func newIdleTimeoutBody(body io.ReadCloser, timeout time.Duration, onTimeout func()) *idleTimeoutBody {
+	b := &idleTimeoutBody{ReadCloser: body, timeout: timeout, onTimeout: onTimeout}
+	b.timer = time.AfterFunc(timeout, b.fire)
+	return b
+}
+
+func (b *idleTimeoutBody) fire() {
+	if !b.timedOut.CompareAndSwap(false, true) {
+		return
+	}
+	b.onTimeout()
+	_ = b.ReadCloser.Close()
+}
+
+// Read resets the idle timer whenever the chunk carries stream content.
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.timedOut.Load() {
+		return n, errStreamIdleTimeout
+	}
+	if n > 0 && !b.stopped.Load() && carriesStreamContent(p[:n]) {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil {
+		b.stop()
+	}
+	return n, err
+}
+
+// Close stops the watchdog and closes the wrapped body.
+func (b *idleTimeoutBody) Close() error {
+	b.stop()
+	return b.ReadCloser.Close()
+}
+
+func (b *idleTimeoutBody) stop() {
+	b.stopped.Store(true)
+	b.timer.Stop()
+}
+
+// carriesStreamContent reports whether chunk has a line other than an SSE
+// comment or a blank line.
+func carriesStreamContent(chunk []byte) bool {
+	for line := range bytes.SplitSeq(chunk, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != ':' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+	metalib "github.com/songquanpeng/one-api/relay/meta"
+)
+
+// tokenIntervalUnits is how many timeout units the mock upstream waits between tokens.
+const tokenIntervalUnits = 20
+
+// newSlowStreamUpstream serves three SSE tokens spaced tokenIntervalUnits apart,
+// optionally sending keep-alive comments in between.
+func newSlowStreamUpstream(t *testing.T, keepAlive bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for i := range 3 {
+			deadline := time.Now().Add(tokenIntervalUnits * streamingTimeoutUnit)
+			for time.Now().Before(deadline) {
+				if keepAlive {
+					_, _ = fmt.Fprint(w, ": ping\n\n")
+					flusher.Flush()
+				}
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(streamingTimeoutUnit):
+				}
+			}
+			_, _ = fmt.Fprintf(w, "data: {\"token\":%d}\n\n", i)
+			flusher.Flush()
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// streamThroughWatchdog reads a stream from upstream for modelName behind the
+// watchdog and returns the body read and the read error.
+func streamThroughWatchdog(t *testing.T, upstream *httptest.Server, modelName string) (*gin.Context, string, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	gmw.SetLogger(c, logger.Logger)
+	timeouts := `{"sora": 300, "whisper-1": 60}`
+	c.Set(ctxkey.ChannelModel, &model.Channel{Id: 1, ModelStreamingTimeouts: &timeouts})
+
+	resp, err := http.Get(upstream.URL)
+	require.NoError(t, err)
+	meta := &metalib.Meta{IsStream: true, OriginModelName: modelName, ActualModelName: modelName}
+	stop := watchStreamIdleTimeout(c, meta, resp)
+	defer stop()
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return c, string(body), err
+}
+
+func useStreamingTimeouts(t *testing.T, globalSeconds int) {
+	t.Helper()
+	originalUnit, originalGlobal := streamingTimeoutUnit, config.StreamingTokenIdleTimeout
+	// Scale seconds down to 2ms so 20-"second" token gaps run quickly.
+	streamingTimeoutUnit = 2 * time.Millisecond
+	config.StreamingTokenIdleTimeout = globalSeconds
+	t.Cleanup(func() {
+		streamingTimeoutUnit, config.StreamingTokenIdleTimeout = originalUnit, originalGlobal
+	})
+}
+
+func TestStreamWatchdog_ModelSpecificTimeoutOutlastsSlowTokens(t *testing.T) {
+	useStreamingTimeouts(t, 10)
+	c, body, err := streamThroughWatchdog(t, newSlowStreamUpstream(t, false), "sora")
+
+	require.NoError(t, err)
+	require.Contains(t, body, `data: {"token":2}`)
+	require.Contains(t, body, "data: [DONE]")
+	require.Nil(t, streamingTimeoutFromContext(c))
+}
+
+func TestStreamWatchdog_GlobalTimeoutClosesSlowStream(t *testing.T) {
+	useStreamingTimeouts(t, 10)
+	c, body, err := streamThroughWatchdog(t, newSlowStreamUpstream(t, false), "gpt-4o")
+
+	require.ErrorIs(t, err, errStreamIdleTimeout)
+	require.NotContains(t, body, "data: [DONE]")
+	event := streamingTimeoutFromContext(c)
+	require.NotNil(t, event)
+	require.Equal(t, model.StreamingTimeoutEvent{Model: "gpt-4o", TimeoutSeconds: 10}, *event)
+
+	metadata := appendStreamingTimeoutMetadata(gmw.BackgroundCtx(c), nil)
+	require.Equal(t, map[string]any{"model": "gpt-4o", "timeout_seconds": 10, "model_specific": false},
+		metadata[model.LogMetadataKeyStreamingTimeout])
+}
+
+func TestStreamWatchdog_KeepAliveCommentsDoNotCountAsTokens(t *testing.T) {
+	useStreamingTimeouts(t, 10)
+	c, _, err := streamThroughWatchdog(t, newSlowStreamUpstream(t, true), "gpt-4o")
+
+	require.ErrorIs(t, err, errStreamIdleTimeout)
+	require.NotNil(t, streamingTimeoutFromContext(c))
+}
+
+func TestStreamWatchdog_DisabledWithoutTimeout(t *testing.T) {
+	useStreamingTimeouts(t, 0)
+	c, body, err := streamThroughWatchdog(t, newSlowStreamUpstream(t, false), "gpt-4o")
+
+	require.NoError(t, err)
+	require.Contains(t, body, "data: [DONE]")
+	require.Nil(t, streamingTimeoutFromContext(c))
+	require.Nil(t, appendStreamingTimeoutMetadata(context.Background(), nil))
+}
+
+func TestValidateModelStreamingTimeoutsJSON(t *testing.T) {
+	require.NoError(t, model.ValidateModelStreamingTimeoutsJSON(`{"sora": 300, "whisper-1": 60}`))
+	require.Error(t, model.ValidateModelStreamingTimeoutsJSON(`{"sora": 0}`))
+	require.Error(t, model.ValidateModelStreamingTimeoutsJSON(`{"sora": "300"}`))
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"testing"
 
 	"github.com/songquanpeng/one-api/common/config"
@@ -97,7 +98,7 @@ func TestPreConsumedQuotaWithStructuredOutput(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			preConsumedQuota := getPreConsumedQuota(tt.textRequest, tt.promptTokens, tt.ratio)
+			preConsumedQuota := getPreConsumedQuota(context.Background(), tt.textRequest, tt.promptTokens, tt.ratio)
 
 			// Calculate what the quota would be without structured output
 			basePreConsumedTokens := config.PreConsumedQuota + int64(tt.promptTokens)
@@ -136,7 +137,7 @@ func TestStructuredOutputQuotaConsistency(t *testing.T) {
 	modelRatio := getTestModelRatio("gpt-4o", channeltype.OpenAI)
 
 	// Calculate pre-consumed quota
-	preConsumedQuota := getPreConsumedQuota(textRequest, promptTokens, modelRatio)
+	preConsumedQuota := getPreConsumedQuota(context.Background(), textRequest, promptTokens, modelRatio)
 
 	// Simulate post-consumption calculation (no structured output surcharge)
 	// Get completion ratio using the new two-layer approach
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/random"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	relaymodel "github.com/songquanpeng/one-api/relay/model"
+)
+
+const translatorChannelID = 916201
+
+func setupSystemPromptTranslation(t *testing.T) (*model.Channel, *atomic.Int32) {
+	t.Helper()
+	ensureResponseFallbackDB(t)
+	require.NoError(t, model.DB.AutoMigrate(&model.Channel{}))
+	if client.HTTPClient == nil {
+		client.Init()
+	}
+	originalRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(originalRedis) })
+
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		require.Equal(t, "/v1/chat/completions", r.URL.Path)
+		require.Equal(t, "Bearer translator-key", r.Header.Get("Authorization"))
+		var req relaymodel.GeneralOpenAIRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "gpt-4o-mini", req.Model)
+		require.Contains(t, req.Messages[0].StringContent(), `"zh-CN"`)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"你是一个乐于助人的助手。"}}],` +
+			`"usage":{"prompt_tokens":40,"completion_tokens":10,"total_tokens":50}}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	require.NoError(t, model.DB.Where("id = ?", translatorChannelID).Delete(&model.Channel{}).Error)
+	baseURL := upstream.URL
+	translator := &model.Channel{
+		Id:      translatorChannelID,
+		Type:    channeltype.OpenAI,
+		Name:    "translator",
+		Key:     "translator-key",
+		Status:  model.ChannelStatusEnabled,
+		BaseURL: &baseURL,
+		Models:  "gpt-4o-mini",
+	}
+	require.NoError(t, model.DB.Create(translator).Error)
+
+	return &model.Channel{
+		Id:                        translatorChannelID + 1,
+		AutoTranslateSystemPrompt: true,
+		TranslateTargetLanguage:   "zh-CN",
+		TranslationChannelId:      translatorChannelID,
+	}, &calls
+}
+
+func newTranslationTestContext() *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Set(ctxkey.ChannelRatio, 1.0)
+	return c
+}
+
+func chatRequestWithSystemPrompt(prompt string) *relaymodel.GeneralOpenAIRequest {
+	return &relaymodel.GeneralOpenAIRequest{
+		Model: "ernie-4.0",
+		Messages: []relaymodel.Message{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: "hi"},
+		},
+	}
+}
+
+func TestTranslateSystemPrompt_TranslatesAndCaches(t *testing.T) {
+	channel, calls := setupSystemPromptTranslation(t)
+	prompt := "You are a helpful assistant. " + random.GetRandomString(8)
+
+	c := newTranslationTestContext()
+	request := chatRequestWithSystemPrompt(prompt)
+	translateSystemPrompt(c, request, channel)
+	require.Equal(t, "你是一个乐于助人的助手。", request.Messages[0].StringContent())
+	require.EqualValues(t, 1, calls.Load())
+
+	translation := systemPromptTranslationFromContext(gmw.BackgroundCtx(c))
+	require.NotNil(t, translation)
+	require.False(t, translation.Cached)
+	require.Equal(t, "en", translation.SourceLanguage)
+	require.Equal(t, "gpt-4o-mini", translation.Model)
+	require.Equal(t, 40, translation.PromptTokens)
+	require.Positive(t, translation.Quota)
+
+	metadata := model.AppendTranslationCostMetadata(nil, translation)
+	require.Equal(t, translation.Quota, metadata[model.LogMetadataKeyTranslationCost].(map[string]any)["quota"])
+
+	// The same prompt is served from the cache without calling or billing the translator
+	c = newTranslationTestContext()
+	request = chatRequestWithSystemPrompt(prompt)
+	translateSystemPrompt(c, request, channel)
+	require.Equal(t, "你是一个乐于助人的助手。", request.Messages[0].StringContent())
+	require.EqualValues(t, 1, calls.Load())
+	translation = systemPromptTranslationFromContext(gmw.BackgroundCtx(c))
+	require.True(t, translation.Cached)
+	require.Zero(t, translation.Quota)
+}
+
+func TestTranslateSystemPrompt_SkipsPromptsInTargetLanguage(t *testing.T) {
+	channel, calls := setupSystemPromptTranslation(t)
+
+	c := newTranslationTestContext()
+	request := chatRequestWithSystemPrompt("你是一个乐于助人的助手。")
+	translateSystemPrompt(c, request, channel)
+	require.Equal(t, "你是一个乐于助人的助手。", request.Messages[0].StringContent())
+	require.Zero(t, calls.Load())
+	require.Nil(t, systemPromptTranslationFromContext(gmw.BackgroundCtx(c)))
+
+	channel.AutoTranslateSystemPrompt = false
+	request = chatRequestWithSystemPrompt("You are a helpful assistant.")
+	translateSystemPrompt(c, request, channel)
+	require.Equal(t, "You are a helpful assistant.", request.Messages[0].StringContent())
+	require.Zero(t, calls.Load())
+}
+
+func TestTranslateSystemPrompt_FailureKeepsOriginal(t *testing.T) {
+	channel, calls := setupSystemPromptTranslation(t)
+	require.NoError(t, model.DB.Model(&model.Channel{}).Where("id = ?", translatorChannelID).
+		Update("status", model.ChannelStatusManuallyDisabled).Error)
+
+	c := newTranslationTestContext()
+	prompt := "You are a helpful assistant. " + random.GetRandomString(8)
+	request := chatRequestWithSystemPrompt(prompt)
+	translateSystemPrompt(c, request, channel)
+	require.Equal(t, prompt, request.Messages[0].StringContent())
+	require.Zero(t, calls.Load())
+	require.Nil(t, systemPromptTranslationFromContext(gmw.BackgroundCtx(c)))
+}
@@ -31,6 +31,7 @@ import (
 	metalib "github.com/songquanpeng/one-api/relay/meta"
 	relaymodel "github.com/songquanpeng/one-api/relay/model"
 	"github.com/songquanpeng/one-api/relay/pricing"
+	"github.com/songquanpeng/one-api/relay/relaymode"
 	"github.com/songquanpeng/one-api/relay/streaming"
 	"github.com/songquanpeng/one-api/relay/tooling"
 )
@@ -75,6 +76,7 @@ func RelayTextHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 			channelCompletionRatio = channel.GetCompletionRatioFromConfigs()
 		}
 	}
+	translateSystemPrompt(c, textRequest, channelRecord)
 
 	requestAdaptor := relay.GetAdaptor(meta.APIType)
 	if requestAdaptor == nil {
@@ -91,6 +93,28 @@ func RelayTextHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 	if err := tooling.ValidateChatBuiltinTools(c, textRequest, meta, channelRecord, requestAdaptor); err != nil {
 		return openai.ErrorWrapper(err, "tool_not_allowed", http.StatusBadRequest)
 	}
+	if bizErr := validateModelFeatures(textRequest.Model, adaptor.ChatRequestFeatures(textRequest), channelRecord, requestAdaptor); bizErr != nil {
+		return bizErr
+	}
+
+	// answer cached embeddings and narrow the request to the misses before billing
+	var embeddingCache *embeddingCacheLookup
+	if meta.Mode == relaymode.Embeddings {
+		embeddingCache = lookupEmbeddingCache(c, textRequest)
+		if embeddingCache != nil && embeddingCache.complete() {
+			embeddingCache.respond(c, textRequest.Model)
+			recordCachedResponseLog(c, meta, textRequest.Model)
+			return nil
+		}
+	}
+
+	// answer a repeated deterministic chat completion with the cached response
+	autoDedup := lookupAutoDedup(c, meta)
+	if autoDedup != nil && autoDedup.hit() {
+		autoDedup.respond(c, textRequest.Model)
+		recordCachedResponseLog(c, meta, textRequest.Model)
+		return nil
+	}
 
 	// pre-consume quota
 	promptTokens := getPromptTokens(gmw.Ctx(c), textRequest, meta.Mode)
@@ -164,7 +188,7 @@ func RelayTextHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 	{
 		quotaId := c.GetInt(ctxkey.Id)
 		requestId := c.GetString(ctxkey.RequestId)
-		estimated := getPreConsumedQuota(textRequest, promptTokens, ratio)
+		estimated := getPreConsumedQuota(ctx, textRequest, promptTokens, ratio)
 		if requestId == "" {
 			lg.Warn("request id missing when recording provisional user request cost",
 				zap.Int("user_id", quotaId))
@@ -188,7 +212,35 @@ func RelayTextHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 
 	// do response
 	c.Set(ctxkey.SkipAdaptorResponseBodyLog, true)
+	stopWatchdog := watchStreamIdleTimeout(c, meta, resp)
+	timingWriter := enableStreamTiming(c, meta)
+	var embeddingCapture *responseCaptureWriter
+	if embeddingCache != nil {
+		embeddingCapture = newResponseCaptureWriter(c.Writer)
+		c.Writer = embeddingCapture
+	}
+	var autoDedupCapture *responseCaptureWriter
+	if autoDedup != nil {
+		autoDedupCapture = newResponseCaptureWriter(c.Writer)
+		c.Writer = autoDedupCapture
+	}
 	usage, respErr := requestAdaptor.DoResponse(c, resp, meta)
+	stopWatchdog()
+	finishStreamTiming(c, timingWriter, usage)
+	if autoDedupCapture != nil {
+		c.Writer = autoDedupCapture.ResponseWriter
+		if err := writeAutoDedupResponse(c, autoDedup, autoDedupCapture, respErr == nil); err != nil && respErr == nil {
+			respErr = openai.ErrorWrapper(err, "auto_dedup_failed", http.StatusInternalServerError)
+		}
+	}
+	if embeddingCapture != nil {
+		c.Writer = embeddingCapture.ResponseWriter
+		if respErr == nil {
+			if err := writeEmbeddingCacheResponse(c, embeddingCache, embeddingCapture); err != nil {
+				respErr = openai.ErrorWrapper(err, "embedding_cache_failed", http.StatusInternalServerError)
+			}
+		}
+	}
 	if upstreamCapture != nil {
 		logUpstreamResponseFromCapture(lg, resp, upstreamCapture, "chat_completions")
 	} else {
@@ -264,14 +316,18 @@ func RelayTextHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 
 		// Record model usage metrics
 		metrics.GlobalRecorder.RecordModelUsage(meta.ActualModelName, channeltype.IdToName(meta.ChannelType), time.Since(meta.StartTime))
+		recordTokenCountDiscrepancy(c, meta.ActualModelName, promptTokens, usage.PromptTokens)
 	}
 
+	bodySizes := captureBodySizes(c, meta)
 	graceful.GoCritical(gmw.BackgroundCtx(c), "postBilling", func(ctx context.Context) {
+		bodySizes.record()
+
 		// Use configurable billing timeout with model-specific adjustments
 		baseBillingTimeout := time.Duration(config.BillingTimeoutSec) * time.Second
 		billingTimeout := baseBillingTimeout
 
-		ctx, cancel := context.WithTimeout(gmw.BackgroundCtx(c), billingTimeout)
+		ctx, cancel := context.WithTimeout(bodySizes.context(gmw.BackgroundCtx(c)), billingTimeout)
 		defer cancel()
 
 		// Monitor for timeout and log critical errors
@@ -328,7 +384,10 @@ func getRequestBody(c *gin.Context, meta *metalib.Meta, textRequest *relaymodel.
 		meta.OriginModelName == meta.ActualModelName &&
 		meta.ChannelType != channeltype.OpenAI &&
 		meta.ChannelType != channeltype.Baichuan &&
-		meta.ForcedSystemPrompt == "" {
+		meta.ChannelType != channeltype.Doubao &&
+		meta.ForcedSystemPrompt == "" &&
+		!embeddingInputRewritten(c) &&
+		!c.GetBool(ctxkey.ResponseLanguageApplied) {
 		c.Set(ctxkey.ConvertedRequest, textRequest)
 		return bytes.NewBuffer(originalBody), nil
 	}
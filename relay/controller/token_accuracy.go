@@ -0,0 +1,45 @@
+package controller
+
+import (
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/Laisky/zap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/metrics"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// tokenCountDiscrepancyRatio returns the larger of estimated and actual divided
+// by the smaller, and whether it exceeds config.TokenCountDiscrepancyThreshold.
+// It returns 0 when either count is not positive.
+func tokenCountDiscrepancyRatio(estimated, actual int) (float64, bool) {
+	if estimated <= 0 || actual <= 0 {
+		return 0, false
+	}
+	ratio := float64(max(estimated, actual)) / float64(min(estimated, actual))
+	return ratio, ratio > config.TokenCountDiscrepancyThreshold
+}
+
+// recordTokenCountDiscrepancy compares the locally estimated prompt tokens with
+// the upstream-reported ones, feeding the Prometheus metrics and the daily
+// accuracy statistics, and logs a warning when they diverge too much.
+func recordTokenCountDiscrepancy(c *gin.Context, modelName string, estimated, actual int) {
+	ratio, exceeded := tokenCountDiscrepancyRatio(estimated, actual)
+	if ratio == 0 {
+		return
+	}
+
+	metrics.GlobalRecorder.RecordTokenCountDiscrepancy(modelName, ratio, exceeded)
+	if exceeded {
+		gmw.GetLogger(c).Warn("prompt token estimate deviates from upstream usage",
+			zap.String("model", modelName),
+			zap.Int("estimated_prompt_tokens", estimated),
+			zap.Int("upstream_prompt_tokens", actual),
+			zap.Float64("ratio", ratio),
+			zap.Float64("threshold", config.TokenCountDiscrepancyThreshold),
+		)
+	}
+
+	model.RecordTokenCountAccuracy(modelName, estimated, actual, exceeded)
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+func TestTokenCountDiscrepancyRatio(t *testing.T) {
+	original := config.TokenCountDiscrepancyThreshold
+	config.TokenCountDiscrepancyThreshold = 1.5
+	t.Cleanup(func() { config.TokenCountDiscrepancyThreshold = original })
+
+	cases := []struct {
+		estimated, actual int
+		ratio             float64
+		exceeded          bool
+	}{
+		{estimated: 100, actual: 100, ratio: 1},
+		{estimated: 100, actual: 140, ratio: 1.4},
+		{estimated: 100, actual: 150, ratio: 1.5},
+		{estimated: 300, actual: 100, ratio: 3, exceeded: true},
+		{estimated: 100, actual: 200, ratio: 2, exceeded: true},
+		{estimated: 0, actual: 100},
+		{estimated: 100, actual: 0},
+	}
+	for _, tc := range cases {
+		ratio, exceeded := tokenCountDiscrepancyRatio(tc.estimated, tc.actual)
+		require.InDelta(t, tc.ratio, ratio, 1e-9, "estimated=%d actual=%d", tc.estimated, tc.actual)
+		require.Equal(t, tc.exceeded, exceeded, "estimated=%d actual=%d", tc.estimated, tc.actual)
+	}
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/adaptor"
+	"github.com/songquanpeng/one-api/relay/pricing"
+)
+
+// maxTTSInputCharacters is the longest input accepted by the OpenAI speech API.
+const maxTTSInputCharacters = 4096
+
+// ttsCharacterRatio returns the quota charged per input character of a speech
+// model. Models flagged with CharacterCountBilling use their CharacterRatio;
+// any other model, including one whose pricing is overridden by the channel,
+// is charged its model ratio per character.
+func ttsCharacterRatio(modelName string, modelRatio float64, channelConfigs map[string]model.ModelConfigLocal, provider adaptor.Adaptor) float64 {
+	if cfg, ok := pricing.ResolveModelConfig(modelName, channelConfigs, provider); ok &&
+		cfg.CharacterCountBilling && cfg.CharacterRatio > 0 {
+		return cfg.CharacterRatio
+	}
+	return modelRatio
+}
+
+// ttsCharacterQuota returns the quota of synthesizing characters input
+// characters, rounded up so that short inputs are never free.
+func ttsCharacterQuota(characters int, characterRatio, groupRatio float64) int64 {
+	return int64(math.Ceil(float64(characters) * characterRatio * groupRatio))
+}
+
+// rewriteSpeechModel replaces the model of a speech request body with the
+// mapped model name, keeping every other field as sent by the client.
+func rewriteSpeechModel(rawBody []byte, modelName string) ([]byte, error) {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(rawBody, &root); err != nil {
+		return nil, errors.Wrap(err, "unmarshal speech request")
+	}
+	modelBytes, err := json.Marshal(modelName)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal mapped speech model")
+	}
+	if bytes.Equal(root["model"], modelBytes) {
+		return rawBody, nil
+	}
+	root["model"] = modelBytes
+	body, err := json.Marshal(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal speech request")
+	}
+	return body, nil
+}
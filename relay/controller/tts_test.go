@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gmw "github.com/Laisky/gin-middlewares/v7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/client"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/adaptor/openai"
+	"github.com/songquanpeng/one-api/relay/billing/ratio"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+func TestTTSCharacterQuota_BillsPerCharacter(t *testing.T) {
+	provider := &openai.Adaptor{}
+	input := strings.Repeat("a", 1000)
+
+	characterRatio := ttsCharacterRatio("tts-1", 15.0*ratio.MilliTokensUsd, nil, provider)
+	require.Equal(t, 15.0*ratio.MilliTokensUsd, characterRatio)
+	require.Equal(t, int64(7500), ttsCharacterQuota(len(input), characterRatio, 1.0))
+
+	hdRatio := ttsCharacterRatio("tts-1-hd", 30.0*ratio.MilliTokensUsd, nil, provider)
+	require.Equal(t, int64(15000), ttsCharacterQuota(len(input), hdRatio, 1.0))
+	require.Equal(t, int64(30000), ttsCharacterQuota(len(input), hdRatio, 2.0))
+
+	// Multi-byte characters are counted once each
+	require.Equal(t, int64(8), ttsCharacterQuota(len([]rune("你好世界你好世界你好")), 0.75, 1.0))
+}
+
+func TestTTSCharacterRatio_FallsBackToModelRatio(t *testing.T) {
+	provider := &openai.Adaptor{}
+	channelConfigs := map[string]model.ModelConfigLocal{"tts-1": {Ratio: 3}}
+
+	// Channel overrides carry no character pricing, so their ratio applies per character
+	require.Equal(t, 3.0, ttsCharacterRatio("tts-1", 3, channelConfigs, provider))
+	// Token-billed speech models fall back to the model ratio too
+	require.Equal(t, 1.5, ttsCharacterRatio("gpt-4o-mini-tts", 1.5, nil, provider))
+}
+
+func TestRelayAudioHelper_SpeechForwardsMappedModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ensureResponseFallbackFixtures(t)
+
+	prevRedis := common.IsRedisEnabled()
+	common.SetRedisEnabled(false)
+	t.Cleanup(func() { common.SetRedisEnabled(prevRedis) })
+
+	prevLogConsume := config.IsLogConsumeEnabled()
+	config.SetLogConsumeEnabled(false)
+	t.Cleanup(func() { config.SetLogConsumeEnabled(prevLogConsume) })
+
+	audio := make([]byte, 64*1024)
+	for i := range audio {
+		audio[i] = byte(i * 31)
+	}
+
+	var upstreamBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		upstreamBody, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "audio/mpeg")
+		_, _ = w.Write(audio)
+	}))
+	defer upstream.Close()
+
+	prevClient := client.HTTPClient
+	client.HTTPClient = upstream.Client()
+	t.Cleanup(func() { client.HTTPClient = prevClient })
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	body := `{"model":"tts-1","input":"hello","voice":"alloy","speed":1.25}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer compat-key")
+	c.Request = req
+	gmw.SetLogger(c, logger.Logger)
+
+	c.Set(ctxkey.Channel, channeltype.OpenAICompatible)
+	c.Set(ctxkey.ChannelId, fallbackCompatibleChannelID)
+	c.Set(ctxkey.TokenId, fallbackTokenID)
+	c.Set(ctxkey.TokenName, "fallback-token")
+	c.Set(ctxkey.Id, fallbackUserID)
+	c.Set(ctxkey.Group, "default")
+	c.Set(ctxkey.ModelMapping, map[string]string{"tts-1": "tts-1-hd"})
+	c.Set(ctxkey.ChannelRatio, 1.0)
+	c.Set(ctxkey.RequestModel, "tts-1")
+	c.Set(ctxkey.BaseURL, upstream.URL)
+	c.Set(ctxkey.ContentType, "application/json")
+	c.Set(ctxkey.RequestId, "req_speech_mapping")
+	c.Set(ctxkey.TokenQuotaUnlimited, true)
+	c.Set(ctxkey.TokenQuota, int64(0))
+	c.Set(ctxkey.UserQuota, int64(1_000_000))
+	c.Set(ctxkey.ChannelModel, &model.Channel{Id: fallbackCompatibleChannelID, Type: channeltype.OpenAICompatible})
+	c.Set(ctxkey.Config, model.ChannelConfig{})
+
+	require.Nil(t, RelayAudioHelper(c, relaymode.AudioSpeech))
+
+	var forwarded map[string]any
+	require.NoError(t, json.Unmarshal(upstreamBody, &forwarded))
+	require.Equal(t, "tts-1-hd", forwarded["model"])
+	require.Equal(t, "alloy", forwarded["voice"])
+	require.Equal(t, 1.25, forwarded["speed"])
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "audio/mpeg", recorder.Header().Get("Content-Type"))
+	require.True(t, bytes.Equal(audio, recorder.Body.Bytes()))
+}
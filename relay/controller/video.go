@@ -134,17 +134,18 @@ func RelayVideoHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 	}
 
 	if usedQuota > 0 {
-		if userQuota-usedQuota < 0 {
+		if model.QuotaWithOverage(userQuota)-usedQuota < 0 {
 			return openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
 		}
-		if err := model.CacheDecreaseUserQuota(ctx, userId, usedQuota); err != nil {
+		tokenQuota := c.GetInt64(ctxkey.TokenQuota)
+		tokenQuotaUnlimited := c.GetBool(ctxkey.TokenQuotaUnlimited)
+		trusted := userQuota > 100*usedQuota && (tokenQuotaUnlimited || tokenQuota > 100*usedQuota)
+		if err := decreaseCachedUserQuota(ctx, userId, usedQuota, !trusted); err != nil {
 			return openai.ErrorWrapper(err, "decrease_user_quota_failed", http.StatusInternalServerError)
 		}
 
-		tokenQuota := c.GetInt64(ctxkey.TokenQuota)
-		tokenQuotaUnlimited := c.GetBool(ctxkey.TokenQuotaUnlimited)
 		preConsumedQuota = usedQuota
-		if userQuota > 100*usedQuota && (tokenQuotaUnlimited || tokenQuota > 100*usedQuota) {
+		if trusted {
 			preConsumedQuota = 0
 		}
 		if preConsumedQuota > 0 {
@@ -190,7 +191,7 @@ func RelayVideoHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
 			ElapsedTime: helper.CalcElapsedTime(meta.StartTime),
 		}
 
-		bgctx, cancel := context.WithTimeout(gmw.BackgroundCtx(c), time.Minute)
+		bgctx, cancel := context.WithTimeout(model.WithBodySizes(gmw.BackgroundCtx(c), model.CaptureBodySizes(c)), time.Minute)
 		defer cancel()
 		graceful.GoCritical(bgctx, "videoPostConsume", func(cctx context.Context) {
 			billing.PostConsumeQuotaWithLog(cctx, tokenId, quotaDelta, usedQuota, entry)
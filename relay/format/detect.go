@@ -6,6 +6,8 @@
 //
 // This package enables automatic detection of the request format based on the payload structure,
 // allowing the system to handle requests sent to incorrect endpoints transparently.
+// It also normalizes function calling responses between these formats and Gemini,
+// see FunctionCallNormalizer.
 package format
 
 import (
@@ -26,6 +28,10 @@ const (
 	ResponseAPI
 	// ClaudeMessages represents Claude Messages API format.
 	ClaudeMessages
+	// GeminiGenerateContent represents Gemini generateContent API format.
+	// It is never returned by DetectFormat or FormatFromPath, and is only used
+	// for response normalization.
+	GeminiGenerateContent
 )
 
 // String returns the string representation of the API format.
@@ -37,6 +43,8 @@ func (f APIFormat) String() string {
 		return "response_api"
 	case ClaudeMessages:
 		return "claude_messages"
+	case GeminiGenerateContent:
+		return "gemini_generate_content"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,524 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Laisky/errors/v2"
+
+	"github.com/songquanpeng/one-api/common/random"
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+// GeminiResponse is a non-streaming Gemini generateContent response, reduced to
+// the fields taking part in function call normalization.
+type GeminiResponse struct {
+	Candidates    []GeminiCandidate    `json:"candidates"`
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string               `json:"modelVersion,omitempty"`
+	ResponseId    string               `json:"responseId,omitempty"`
+}
+
+// GeminiCandidate is a single candidate of a GeminiResponse.
+type GeminiCandidate struct {
+	Content      GeminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+	Index        int           `json:"index"`
+}
+
+// GeminiContent holds the parts produced by the model.
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiPart is either text, a thought summary or a function call.
+type GeminiPart struct {
+	Text         string              `json:"text,omitempty"`
+	Thought      bool                `json:"thought,omitempty"`
+	FunctionCall *GeminiFunctionCall `json:"functionCall,omitempty"`
+}
+
+// GeminiFunctionCall is a function call requested by Gemini. Id is only set by
+// newer Gemini versions.
+type GeminiFunctionCall struct {
+	Id   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// GeminiUsageMetadata is the token usage of a GeminiResponse.
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// FunctionCallNormalizer converts non-streaming chat responses, including their
+// function calls, between API formats. OpenAI returns function calls as
+// tool_calls, Claude as tool_use content blocks and Gemini as functionCall
+// parts. Every conversion goes through model.GeneralOpenAIResponse, so any
+// source format can be converted to any destination format.
+//
+// The zero value is ready to use.
+type FunctionCallNormalizer struct {
+	// NewToolCallID generates ids for function calls that carry none, such as
+	// functionCall parts of older Gemini versions. Defaults to "call_" followed
+	// by a random UUID.
+	NewToolCallID func() string
+}
+
+// NormalizeToOpenAI converts resp, a response in srcFormat, to an OpenAI Chat
+// Completions response with the default FunctionCallNormalizer.
+func NormalizeToOpenAI(resp any, srcFormat APIFormat) (*model.GeneralOpenAIResponse, error) {
+	return FunctionCallNormalizer{}.NormalizeToOpenAI(resp, srcFormat)
+}
+
+// FromOpenAI converts an OpenAI Chat Completions response to dstFormat with the
+// default FunctionCallNormalizer.
+func FromOpenAI(resp *model.GeneralOpenAIResponse, dstFormat APIFormat) (any, error) {
+	return FunctionCallNormalizer{}.FromOpenAI(resp, dstFormat)
+}
+
+// ConvertResponse converts the JSON response body from srcFormat to dstFormat,
+// letting a client of one API format receive the function calls of a provider
+// speaking another. When srcFormat is Unknown it is detected with
+// DetectResponseFormat.
+func ConvertResponse(body []byte, srcFormat APIFormat, dstFormat APIFormat) ([]byte, error) {
+	if srcFormat == Unknown {
+		detected, err := DetectResponseFormat(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "detect response format")
+		}
+		srcFormat = detected
+	}
+	if srcFormat == dstFormat {
+		return body, nil
+	}
+
+	normalized, err := NormalizeToOpenAI(body, srcFormat)
+	if err != nil {
+		return nil, errors.Wrapf(err, "normalize %s response", srcFormat)
+	}
+	converted, err := FromOpenAI(normalized, dstFormat)
+	if err != nil {
+		return nil, errors.Wrapf(err, "convert response to %s", dstFormat)
+	}
+	out, err := json.Marshal(converted)
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshal %s response", dstFormat)
+	}
+	return out, nil
+}
+
+// DetectResponseFormat determines the API format of a non-streaming JSON
+// response body. It returns Unknown when the body matches none of the formats
+// supported by FunctionCallNormalizer.
+func DetectResponseFormat(body []byte) (APIFormat, error) {
+	if len(body) == 0 {
+		return Unknown, errors.New("empty response body")
+	}
+
+	var probe struct {
+		Object     string          `json:"object,omitempty"`
+		Type       string          `json:"type,omitempty"`
+		Choices    json.RawMessage `json:"choices,omitempty"`
+		Content    json.RawMessage `json:"content,omitempty"`
+		Candidates json.RawMessage `json:"candidates,omitempty"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return Unknown, errors.Wrap(err, "failed to parse response body for format detection")
+	}
+
+	switch {
+	case len(probe.Choices) > 0:
+		return ChatCompletion, nil
+	case probe.Type == "message" && len(probe.Content) > 0:
+		return ClaudeMessages, nil
+	case len(probe.Candidates) > 0:
+		return GeminiGenerateContent, nil
+	default:
+		return Unknown, nil
+	}
+}
+
+// NormalizeToOpenAI converts resp, a response in srcFormat, to an OpenAI Chat
+// Completions response. resp is either the raw JSON body, as []byte or
+// json.RawMessage, or any value marshaling to the JSON of srcFormat, such as
+// *model.ClaudeResponse.
+func (n FunctionCallNormalizer) NormalizeToOpenAI(resp any, srcFormat APIFormat) (*model.GeneralOpenAIResponse, error) {
+	body, err := responseJSON(resp)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal response")
+	}
+
+	switch srcFormat {
+	case ChatCompletion:
+		out := new(model.GeneralOpenAIResponse)
+		if err = json.Unmarshal(body, out); err != nil {
+			return nil, errors.Wrap(err, "unmarshal chat completion response")
+		}
+		return out, nil
+	case ClaudeMessages:
+		claudeResp := new(model.ClaudeResponse)
+		if err = json.Unmarshal(body, claudeResp); err != nil {
+			return nil, errors.Wrap(err, "unmarshal claude messages response")
+		}
+		return n.claudeToOpenAI(claudeResp), nil
+	case GeminiGenerateContent:
+		geminiResp := new(GeminiResponse)
+		if err = json.Unmarshal(body, geminiResp); err != nil {
+			return nil, errors.Wrap(err, "unmarshal gemini response")
+		}
+		return n.geminiToOpenAI(geminiResp), nil
+	default:
+		return nil, errors.Errorf("function call normalization is not supported for %s", srcFormat)
+	}
+}
+
+// FromOpenAI converts an OpenAI Chat Completions response to dstFormat. It is
+// the inverse of NormalizeToOpenAI and returns *model.GeneralOpenAIResponse,
+// *model.ClaudeResponse or *GeminiResponse.
+func (n FunctionCallNormalizer) FromOpenAI(resp *model.GeneralOpenAIResponse, dstFormat APIFormat) (any, error) {
+	switch dstFormat {
+	case ChatCompletion:
+		return resp, nil
+	case ClaudeMessages:
+		return n.OpenAIToClaude(resp)
+	case GeminiGenerateContent:
+		return n.OpenAIToGemini(resp)
+	default:
+		return nil, errors.Errorf("function call normalization is not supported for %s", dstFormat)
+	}
+}
+
+// OpenAIToClaude converts an OpenAI Chat Completions response to a Claude
+// Messages response. Claude responses carry a single message, so only the first
+// choice is converted.
+func (n FunctionCallNormalizer) OpenAIToClaude(resp *model.GeneralOpenAIResponse) (*model.ClaudeResponse, error) {
+	if resp == nil {
+		return nil, errors.New("response is nil")
+	}
+
+	out := &model.ClaudeResponse{
+		ID:         resp.Id,
+		Type:       "message",
+		Role:       "assistant",
+		Model:      resp.Model,
+		Content:    []model.ClaudeContent{},
+		StopReason: "end_turn",
+	}
+	if resp.Usage != nil {
+		out.Usage = model.ClaudeUsage{
+			InputTokens:  resp.Usage.PromptTokens,
+			OutputTokens: resp.Usage.CompletionTokens,
+		}
+	}
+	if len(resp.Choices) == 0 {
+		return out, nil
+	}
+
+	choice := resp.Choices[0]
+	if reasoning := reasoningOf(choice.Message); reasoning != "" {
+		out.Content = append(out.Content, model.ClaudeContent{Type: "thinking", Thinking: reasoning})
+	}
+	if text := choice.Message.StringContent(); text != "" {
+		out.Content = append(out.Content, model.ClaudeContent{Type: "text", Text: text})
+	}
+	for _, call := range choice.Message.ToolCalls {
+		if call.Function == nil {
+			continue
+		}
+		args, err := argumentsJSON(call.Function.Arguments)
+		if err != nil {
+			return nil, errors.Wrapf(err, "tool call %q", call.Function.Name)
+		}
+		id := call.Id
+		if id == "" {
+			id = n.newToolCallID()
+		}
+		out.Content = append(out.Content, model.ClaudeContent{
+			Type:  "tool_use",
+			ID:    id,
+			Name:  call.Function.Name,
+			Input: args,
+		})
+	}
+
+	switch choice.FinishReason {
+	case "length":
+		out.StopReason = "max_tokens"
+	case "tool_calls", "function_call":
+		out.StopReason = "tool_use"
+	case "content_filter":
+		out.StopReason = "refusal"
+	default:
+		if len(choice.Message.ToolCalls) > 0 {
+			out.StopReason = "tool_use"
+		}
+	}
+	return out, nil
+}
+
+// OpenAIToGemini converts an OpenAI Chat Completions response to a Gemini
+// generateContent response, one candidate per choice.
+func (n FunctionCallNormalizer) OpenAIToGemini(resp *model.GeneralOpenAIResponse) (*GeminiResponse, error) {
+	if resp == nil {
+		return nil, errors.New("response is nil")
+	}
+
+	out := &GeminiResponse{
+		Candidates:   make([]GeminiCandidate, 0, len(resp.Choices)),
+		ModelVersion: resp.Model,
+		ResponseId:   resp.Id,
+	}
+	if resp.Usage != nil {
+		out.UsageMetadata = &GeminiUsageMetadata{
+			PromptTokenCount:     resp.Usage.PromptTokens,
+			CandidatesTokenCount: resp.Usage.CompletionTokens,
+			TotalTokenCount:      resp.Usage.TotalTokens,
+		}
+	}
+
+	for _, choice := range resp.Choices {
+		candidate := GeminiCandidate{
+			Content:      GeminiContent{Role: "model", Parts: []GeminiPart{}},
+			FinishReason: "STOP",
+			Index:        choice.Index,
+		}
+		if reasoning := reasoningOf(choice.Message); reasoning != "" {
+			candidate.Content.Parts = append(candidate.Content.Parts, GeminiPart{Text: reasoning, Thought: true})
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			candidate.Content.Parts = append(candidate.Content.Parts, GeminiPart{Text: text})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			if call.Function == nil {
+				continue
+			}
+			args, err := argumentsJSON(call.Function.Arguments)
+			if err != nil {
+				return nil, errors.Wrapf(err, "tool call %q", call.Function.Name)
+			}
+			candidate.Content.Parts = append(candidate.Content.Parts, GeminiPart{
+				FunctionCall: &GeminiFunctionCall{Id: call.Id, Name: call.Function.Name, Args: args},
+			})
+		}
+
+		switch choice.FinishReason {
+		case "length":
+			candidate.FinishReason = "MAX_TOKENS"
+		case "content_filter":
+			candidate.FinishReason = "SAFETY"
+		}
+		out.Candidates = append(out.Candidates, candidate)
+	}
+	return out, nil
+}
+
+// claudeToOpenAI converts a Claude Messages response to a single-choice OpenAI
+// Chat Completions response.
+func (n FunctionCallNormalizer) claudeToOpenAI(resp *model.ClaudeResponse) *model.GeneralOpenAIResponse {
+	var (
+		text, reasoning string
+		toolCalls       []model.Tool
+	)
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text += block.Text
+		case "thinking":
+			reasoning += block.Thinking
+		case "tool_use":
+			id := block.ID
+			if id == "" {
+				id = n.newToolCallID()
+			}
+			toolCalls = append(toolCalls, model.Tool{
+				Id:   id,
+				Type: "function",
+				Function: &model.Function{
+					Name:      block.Name,
+					Arguments: argumentsString(block.Input),
+				},
+			})
+		}
+	}
+
+	finishReason := "stop"
+	switch resp.StopReason {
+	case "max_tokens":
+		finishReason = "length"
+	case "tool_use":
+		finishReason = "tool_calls"
+	case "refusal":
+		finishReason = "content_filter"
+	}
+
+	return &model.GeneralOpenAIResponse{
+		Id:      resp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().UTC().Unix(),
+		Model:   resp.Model,
+		Choices: []model.GeneralOpenAIResponseChoice{{
+			Index:        0,
+			Message:      assistantMessage(text, reasoning, toolCalls),
+			FinishReason: finishReason,
+		}},
+		Usage: &model.Usage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		},
+	}
+}
+
+// geminiToOpenAI converts a Gemini generateContent response to an OpenAI Chat
+// Completions response with one choice per candidate.
+func (n FunctionCallNormalizer) geminiToOpenAI(resp *GeminiResponse) *model.GeneralOpenAIResponse {
+	out := &model.GeneralOpenAIResponse{
+		Id:      resp.ResponseId,
+		Object:  "chat.completion",
+		Created: time.Now().UTC().Unix(),
+		Model:   resp.ModelVersion,
+		Choices: make([]model.GeneralOpenAIResponseChoice, 0, len(resp.Candidates)),
+	}
+	if resp.UsageMetadata != nil {
+		out.Usage = &model.Usage{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+		}
+	}
+
+	for _, candidate := range resp.Candidates {
+		var (
+			text, reasoning string
+			toolCalls       []model.Tool
+		)
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				id := part.FunctionCall.Id
+				if id == "" {
+					id = n.newToolCallID()
+				}
+				toolCalls = append(toolCalls, model.Tool{
+					Id:   id,
+					Type: "function",
+					Function: &model.Function{
+						Name:      part.FunctionCall.Name,
+						Arguments: argumentsString(part.FunctionCall.Args),
+					},
+				})
+			case part.Thought:
+				reasoning += part.Text
+			default:
+				text += part.Text
+			}
+		}
+
+		// Gemini reports STOP for function calls as well
+		finishReason := "stop"
+		switch candidate.FinishReason {
+		case "MAX_TOKENS":
+			finishReason = "length"
+		case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+			finishReason = "content_filter"
+		}
+		if len(toolCalls) > 0 && finishReason == "stop" {
+			finishReason = "tool_calls"
+		}
+
+		out.Choices = append(out.Choices, model.GeneralOpenAIResponseChoice{
+			Index:        candidate.Index,
+			Message:      assistantMessage(text, reasoning, toolCalls),
+			FinishReason: finishReason,
+		})
+	}
+	return out
+}
+
+func (n FunctionCallNormalizer) newToolCallID() string {
+	if n.NewToolCallID != nil {
+		return n.NewToolCallID()
+	}
+	return fmt.Sprintf("call_%s", random.GetUUID())
+}
+
+// assistantMessage builds the OpenAI assistant message of a normalized choice.
+func assistantMessage(text string, reasoning string, toolCalls []model.Tool) model.Message {
+	msg := model.Message{Role: "assistant", Content: text, ToolCalls: toolCalls}
+	if reasoning != "" {
+		msg.ReasoningContent = &reasoning
+	}
+	return msg
+}
+
+// reasoningOf returns the reasoning of msg in whichever field it was set.
+func reasoningOf(msg model.Message) string {
+	for _, field := range []*string{msg.ReasoningContent, msg.Reasoning, msg.Thinking} {
+		if field != nil && *field != "" {
+			return *field
+		}
+	}
+	return ""
+}
+
+// responseJSON returns resp as JSON without re-encoding raw bodies.
+func responseJSON(resp any) ([]byte, error) {
+	switch v := resp.(type) {
+	case nil:
+		return nil, errors.New("response is nil")
+	case []byte:
+		return v, nil
+	case json.RawMessage:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// argumentsJSON converts OpenAI function call arguments, normally a JSON
+// encoded string, to the JSON object expected by Claude and Gemini.
+func argumentsJSON(arguments any) (json.RawMessage, error) {
+	var raw []byte
+	switch v := arguments.(type) {
+	case nil:
+		return json.RawMessage("{}"), nil
+	case string:
+		raw = []byte(v)
+	case json.RawMessage:
+		raw = v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal arguments")
+		}
+		raw = encoded
+	}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return json.RawMessage("{}"), nil
+	}
+	if !json.Valid(raw) {
+		return nil, errors.New("arguments are not valid JSON")
+	}
+	return json.RawMessage(raw), nil
+}
+
+// argumentsString converts a Claude or Gemini function call input to the JSON
+// encoded string used by OpenAI.
+func argumentsString(input json.RawMessage) string {
+	if len(bytes.TrimSpace(input)) == 0 {
+		return "{}"
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, input); err != nil {
+		return string(input)
+	}
+	return compacted.String()
+}
@@ -0,0 +1,177 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/relay/model"
+)
+
+const multiToolCallResponse = `{
+	"id": "chatcmpl-123",
+	"object": "chat.completion",
+	"created": 1700000000,
+	"model": "gpt-4o",
+	"choices": [{
+		"index": 0,
+		"message": {
+			"role": "assistant",
+			"content": "Let me check both cities.",
+			"tool_calls": [
+				{"id": "call_weather", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\": \"Paris\", \"unit\": \"celsius\"}"}},
+				{"id": "call_time", "type": "function", "function": {"name": "get_time", "arguments": "{\"timezone\":\"Asia/Tokyo\"}"}}
+			]
+		},
+		"finish_reason": "tool_calls"
+	}],
+	"usage": {"prompt_tokens": 42, "completion_tokens": 17, "total_tokens": 59}
+}`
+
+func requireSameToolCalls(t *testing.T, want []model.Tool, got []model.Tool) {
+	t.Helper()
+	require.Len(t, got, len(want))
+	for i := range want {
+		require.Equal(t, want[i].Id, got[i].Id)
+		require.Equal(t, "function", got[i].Type)
+		require.Equal(t, want[i].Function.Name, got[i].Function.Name)
+		require.JSONEq(t, want[i].Function.Arguments.(string), got[i].Function.Arguments.(string))
+	}
+}
+
+func TestFunctionCallNormalizer_OpenAIClaudeRoundTrip(t *testing.T) {
+	original, err := NormalizeToOpenAI([]byte(multiToolCallResponse), ChatCompletion)
+	require.NoError(t, err)
+	require.Len(t, original.Choices[0].Message.ToolCalls, 2)
+
+	converted, err := FromOpenAI(original, ClaudeMessages)
+	require.NoError(t, err)
+	claudeResp, ok := converted.(*model.ClaudeResponse)
+	require.True(t, ok)
+	require.Equal(t, "tool_use", claudeResp.StopReason)
+	require.Equal(t, 42, claudeResp.Usage.InputTokens)
+	require.Len(t, claudeResp.Content, 3)
+	require.Equal(t, "text", claudeResp.Content[0].Type)
+	require.Equal(t, "tool_use", claudeResp.Content[1].Type)
+	require.Equal(t, "call_weather", claudeResp.Content[1].ID)
+	require.JSONEq(t, `{"city":"Paris","unit":"celsius"}`, string(claudeResp.Content[1].Input))
+	require.Equal(t, "get_time", claudeResp.Content[2].Name)
+
+	// Round-trip through the Claude wire format
+	claudeBody, err := json.Marshal(claudeResp)
+	require.NoError(t, err)
+	roundTripped, err := NormalizeToOpenAI(claudeBody, ClaudeMessages)
+	require.NoError(t, err)
+
+	require.Equal(t, original.Id, roundTripped.Id)
+	require.Equal(t, original.Model, roundTripped.Model)
+	require.Equal(t, original.Usage.TotalTokens, roundTripped.Usage.TotalTokens)
+	require.Len(t, roundTripped.Choices, 1)
+	choice := roundTripped.Choices[0]
+	require.Equal(t, "tool_calls", choice.FinishReason)
+	require.Equal(t, "assistant", choice.Message.Role)
+	require.Equal(t, original.Choices[0].Message.StringContent(), choice.Message.StringContent())
+	requireSameToolCalls(t, original.Choices[0].Message.ToolCalls, choice.Message.ToolCalls)
+}
+
+func TestFunctionCallNormalizer_OpenAIGeminiRoundTrip(t *testing.T) {
+	original, err := NormalizeToOpenAI([]byte(multiToolCallResponse), ChatCompletion)
+	require.NoError(t, err)
+
+	geminiResp, err := FunctionCallNormalizer{}.OpenAIToGemini(original)
+	require.NoError(t, err)
+	require.Len(t, geminiResp.Candidates, 1)
+	parts := geminiResp.Candidates[0].Content.Parts
+	require.Len(t, parts, 3)
+	require.Equal(t, "get_weather", parts[1].FunctionCall.Name)
+	require.JSONEq(t, `{"timezone":"Asia/Tokyo"}`, string(parts[2].FunctionCall.Args))
+
+	roundTripped, err := NormalizeToOpenAI(geminiResp, GeminiGenerateContent)
+	require.NoError(t, err)
+	require.Equal(t, "tool_calls", roundTripped.Choices[0].FinishReason)
+	requireSameToolCalls(t, original.Choices[0].Message.ToolCalls, roundTripped.Choices[0].Message.ToolCalls)
+}
+
+func TestFunctionCallNormalizer_GeminiWithoutIdsGetsGeneratedIds(t *testing.T) {
+	body := `{
+		"candidates": [{
+			"content": {"role": "model", "parts": [
+				{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}},
+				{"functionCall": {"name": "get_time", "args": {}}}
+			]},
+			"finishReason": "STOP"
+		}],
+		"usageMetadata": {"promptTokenCount": 5, "candidatesTokenCount": 3, "totalTokenCount": 8}
+	}`
+
+	n := 0
+	normalizer := FunctionCallNormalizer{NewToolCallID: func() string {
+		n++
+		return fmt.Sprintf("call_%d", n)
+	}}
+	resp, err := normalizer.NormalizeToOpenAI([]byte(body), GeminiGenerateContent)
+	require.NoError(t, err)
+
+	calls := resp.Choices[0].Message.ToolCalls
+	require.Len(t, calls, 2)
+	require.Equal(t, "call_1", calls[0].Id)
+	require.Equal(t, "call_2", calls[1].Id)
+	require.Equal(t, `{"city":"Paris"}`, calls[0].Function.Arguments)
+	require.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+	require.Equal(t, 8, resp.Usage.TotalTokens)
+}
+
+func TestConvertResponse_DetectsSourceFormat(t *testing.T) {
+	claudeBody := `{
+		"id": "msg_1",
+		"type": "message",
+		"role": "assistant",
+		"model": "claude-sonnet-4-5",
+		"content": [
+			{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}},
+			{"type": "tool_use", "id": "toolu_2", "name": "get_time", "input": {"timezone": "UTC"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 5}
+	}`
+
+	detected, err := DetectResponseFormat([]byte(claudeBody))
+	require.NoError(t, err)
+	require.Equal(t, ClaudeMessages, detected)
+
+	out, err := ConvertResponse([]byte(claudeBody), Unknown, GeminiGenerateContent)
+	require.NoError(t, err)
+	detected, err = DetectResponseFormat(out)
+	require.NoError(t, err)
+	require.Equal(t, GeminiGenerateContent, detected)
+
+	var geminiResp GeminiResponse
+	require.NoError(t, json.Unmarshal(out, &geminiResp))
+	parts := geminiResp.Candidates[0].Content.Parts
+	require.Len(t, parts, 2)
+	require.Equal(t, "toolu_1", parts[0].FunctionCall.Id)
+	require.Equal(t, "get_time", parts[1].FunctionCall.Name)
+}
+
+func TestFunctionCallNormalizer_Errors(t *testing.T) {
+	_, err := NormalizeToOpenAI([]byte(multiToolCallResponse), ResponseAPI)
+	require.Error(t, err)
+
+	_, err = NormalizeToOpenAI(nil, ChatCompletion)
+	require.Error(t, err)
+
+	resp := &model.GeneralOpenAIResponse{Choices: []model.GeneralOpenAIResponseChoice{{
+		Message: model.Message{ToolCalls: []model.Tool{{
+			Id:       "call_1",
+			Function: &model.Function{Name: "broken", Arguments: "{not json"},
+		}}},
+	}}}
+	_, err = FromOpenAI(resp, ClaudeMessages)
+	require.Error(t, err)
+
+	detected, err := DetectResponseFormat([]byte(`{"data": []}`))
+	require.NoError(t, err)
+	require.Equal(t, Unknown, detected)
+}
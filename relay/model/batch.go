@@ -0,0 +1,205 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+)
+
+// BatchEndpoints lists the endpoints the requests of a batch may target.
+var BatchEndpoints = []string{"/v1/chat/completions", "/v1/embeddings", "/v1/completions"}
+
+// BatchRequestLine is one line of a Batch API input file.
+type BatchRequestLine struct {
+	CustomID string         `json:"custom_id"`
+	Method   string         `json:"method"`
+	URL      string         `json:"url"`
+	Body     map[string]any `json:"body"`
+}
+
+// BatchInput summarizes a validated Batch API input file.
+type BatchInput struct {
+	// Model is the model shared by every request in the file.
+	Model string `json:"model"`
+	// Endpoint is the endpoint shared by every request in the file.
+	Endpoint     string `json:"endpoint"`
+	RequestCount int    `json:"request_count"`
+	// MaxCompletionTokens sums the completion token limits of the requests
+	// setting max_completion_tokens or max_tokens.
+	MaxCompletionTokens int `json:"max_completion_tokens"`
+	// PromptTokens estimates the prompt tokens of the whole file. It is set
+	// when the file is uploaded and used to pre-consume quota for the batch.
+	PromptTokens int `json:"prompt_tokens"`
+}
+
+// ParseBatchInput validates a JSONL batch input file: every non-empty line
+// must be a POST request to one of BatchEndpoints with a unique custom_id and
+// a body naming a model. All requests must share the endpoint and the model so
+// the batch can be routed to a single channel and billed at a single price.
+func ParseBatchInput(data []byte, maxRequests int) (*BatchInput, error) {
+	input := &BatchInput{}
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var line BatchRequestLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil, errors.Wrapf(err, "line %d is not valid JSON", lineNo)
+		}
+		if line.CustomID == "" {
+			return nil, errors.Errorf("line %d: custom_id is required", lineNo)
+		}
+		if _, ok := seen[line.CustomID]; ok {
+			return nil, errors.Errorf("line %d: duplicate custom_id %q", lineNo, line.CustomID)
+		}
+		seen[line.CustomID] = struct{}{}
+		if !strings.EqualFold(line.Method, http.MethodPost) {
+			return nil, errors.Errorf("line %d: method must be POST", lineNo)
+		}
+		if !slices.Contains(BatchEndpoints, line.URL) {
+			return nil, errors.Errorf("line %d: url must be one of %s", lineNo, strings.Join(BatchEndpoints, ", "))
+		}
+		modelName, _ := line.Body["model"].(string)
+		if modelName == "" {
+			return nil, errors.Errorf("line %d: body.model is required", lineNo)
+		}
+
+		if input.RequestCount == 0 {
+			input.Model = modelName
+			input.Endpoint = line.URL
+		} else if modelName != input.Model {
+			return nil, errors.Errorf("line %d: all requests must use model %q", lineNo, input.Model)
+		} else if line.URL != input.Endpoint {
+			return nil, errors.Errorf("line %d: all requests must target %s", lineNo, input.Endpoint)
+		}
+		input.RequestCount++
+		input.MaxCompletionTokens += batchLineMaxTokens(line.Body)
+		if input.RequestCount > maxRequests {
+			return nil, errors.Errorf("batch exceeds the limit of %d requests", maxRequests)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read batch input")
+	}
+	if input.RequestCount == 0 {
+		return nil, errors.New("batch input contains no requests")
+	}
+	return input, nil
+}
+
+// batchLineMaxTokens returns the completion token limit of a request body,
+// preferring max_completion_tokens over the deprecated max_tokens.
+func batchLineMaxTokens(body map[string]any) int {
+	for _, key := range []string{"max_completion_tokens", "max_tokens"} {
+		if limit, ok := body[key].(float64); ok && limit > 0 {
+			return int(limit)
+		}
+	}
+	return 0
+}
+
+// RewriteBatchInputModel replaces the model of every request in a batch input
+// file, used when the channel maps the requested model to another name.
+func RewriteBatchInputModel(data []byte, modelName string) ([]byte, error) {
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var line BatchRequestLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil, errors.Wrap(err, "unmarshal batch request")
+		}
+		line.Body["model"] = modelName
+		encoded, err := json.Marshal(line)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal batch request")
+		}
+		out.Write(encoded)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read batch input")
+	}
+	return out.Bytes(), nil
+}
+
+// BatchObject holds the fields of an upstream batch the relay tracks.
+type BatchObject struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	Endpoint      string `json:"endpoint"`
+	InputFileID   string `json:"input_file_id"`
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+}
+
+// BatchOutputLine is one line of a Batch API output file.
+type BatchOutputLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int `json:"status_code"`
+		Body       struct {
+			Usage *Usage `json:"usage"`
+		} `json:"body"`
+	} `json:"response"`
+}
+
+// SumBatchOutputUsage adds up the usage of the successful requests in a Batch
+// API output file. It returns the summed usage and the number of lines that
+// reported usage.
+func SumBatchOutputUsage(data []byte) (*Usage, int, error) {
+	usage := &Usage{}
+	cachedTokens := 0
+	counted := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var line BatchOutputLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil, 0, errors.Wrap(err, "unmarshal batch output line")
+		}
+		if line.Response == nil || line.Response.StatusCode != http.StatusOK || line.Response.Body.Usage == nil {
+			continue
+		}
+		lineUsage := line.Response.Body.Usage
+		usage.PromptTokens += lineUsage.PromptTokens
+		usage.CompletionTokens += lineUsage.CompletionTokens
+		usage.TotalTokens += lineUsage.TotalTokens
+		if lineUsage.PromptTokensDetails != nil {
+			cachedTokens += lineUsage.PromptTokensDetails.CachedTokens
+		}
+		counted++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, errors.Wrap(err, "read batch output")
+	}
+	if cachedTokens > 0 {
+		usage.PromptTokensDetails = &UsagePromptTokensDetails{CachedTokens: cachedTokens}
+	}
+	return usage, counted, nil
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func batchLine(customID, url, model string) string {
+	return `{"custom_id":"` + customID + `","method":"POST","url":"` + url + `","body":{"model":"` + model + `"}}`
+}
+
+func TestParseBatchInput(t *testing.T) {
+	input, err := ParseBatchInput([]byte(strings.Join([]string{
+		batchLine("a", "/v1/chat/completions", "gpt-4o-mini"),
+		"",
+		batchLine("b", "/v1/chat/completions", "gpt-4o-mini"),
+		`{"custom_id":"c","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","max_tokens":100}}`,
+		`{"custom_id":"d","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","max_tokens":100,"max_completion_tokens":50}}`,
+	}, "\n")), 10)
+	require.NoError(t, err)
+	require.Equal(t, "gpt-4o-mini", input.Model)
+	require.Equal(t, "/v1/chat/completions", input.Endpoint)
+	require.Equal(t, 4, input.RequestCount)
+	require.Equal(t, 150, input.MaxCompletionTokens)
+
+	invalid := map[string]string{
+		"not json":        `{"custom_id":`,
+		"missing id":      batchLine("", "/v1/chat/completions", "gpt-4o-mini"),
+		"duplicate id":    batchLine("a", "/v1/chat/completions", "gpt-4o-mini") + "\n" + batchLine("a", "/v1/chat/completions", "gpt-4o-mini"),
+		"bad endpoint":    batchLine("a", "/v1/images/generations", "dall-e-3"),
+		"missing model":   batchLine("a", "/v1/chat/completions", ""),
+		"mixed models":    batchLine("a", "/v1/chat/completions", "gpt-4o-mini") + "\n" + batchLine("b", "/v1/chat/completions", "gpt-4o"),
+		"mixed endpoints": batchLine("a", "/v1/chat/completions", "gpt-4o-mini") + "\n" + batchLine("b", "/v1/completions", "gpt-4o-mini"),
+		"too many":        batchLine("a", "/v1/embeddings", "m") + "\n" + batchLine("b", "/v1/embeddings", "m") + "\n" + batchLine("c", "/v1/embeddings", "m"),
+		"empty":           "\n\n",
+	}
+	for name, data := range invalid {
+		_, err := ParseBatchInput([]byte(data), 2)
+		require.Error(t, err, name)
+	}
+}
+
+func TestRewriteBatchInputModel(t *testing.T) {
+	out, err := RewriteBatchInputModel([]byte(batchLine("a", "/v1/embeddings", "m")+"\n"), "mapped")
+	require.NoError(t, err)
+	input, err := ParseBatchInput(out, 1)
+	require.NoError(t, err)
+	require.Equal(t, "mapped", input.Model)
+}
+
+func TestSumBatchOutputUsage(t *testing.T) {
+	usage, counted, err := SumBatchOutputUsage([]byte(strings.Join([]string{
+		`{"custom_id":"a","response":{"status_code":200,"body":{"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15,"prompt_tokens_details":{"cached_tokens":4}}}}}`,
+		`{"custom_id":"b","response":{"status_code":400,"body":{"usage":{"prompt_tokens":99}}}}`,
+		`{"custom_id":"c","response":null,"error":{"code":"server_error"}}`,
+		`{"custom_id":"d","response":{"status_code":200,"body":{"usage":{"prompt_tokens":20,"completion_tokens":7,"total_tokens":27}}}}`,
+	}, "\n")))
+	require.NoError(t, err)
+	require.Equal(t, 2, counted)
+	require.Equal(t, 30, usage.PromptTokens)
+	require.Equal(t, 12, usage.CompletionTokens)
+	require.Equal(t, 42, usage.TotalTokens)
+	require.Equal(t, 4, usage.PromptTokensDetails.CachedTokens)
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Laisky/errors/v2"
+)
+
+// DocumentTokensPerPage approximates the prompt tokens of one document page.
+const DocumentTokensPerPage = 756
+
+// Document is an inline document, such as a PDF, attached to a message.
+type Document struct {
+	// Data is the base64 encoded document, either raw or as a data URL
+	Data string `json:"data"`
+	// MimeType is the document type; a data URL carries its own
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// Decode returns the MIME type and the raw bytes of the document.
+func (d *Document) Decode() (mimeType string, data []byte, err error) {
+	mimeType, encoded := d.MimeType, d.Data
+	if rest, ok := strings.CutPrefix(encoded, "data:"); ok {
+		header, payload, found := strings.Cut(rest, ",")
+		if !found || !strings.HasSuffix(header, ";base64") {
+			return "", nil, errors.New("document data URL must be base64 encoded")
+		}
+		mimeType, encoded = strings.TrimSuffix(header, ";base64"), payload
+	}
+	if mimeType == "" {
+		return "", nil, errors.New("document mime_type is required")
+	}
+
+	data, err = base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "decode document data")
+	}
+	return mimeType, data, nil
+}
+
+// Pages decodes the document and counts its pages with PDFPageCount, refusing
+// documents larger than maxSize bytes before parsing them.
+func (d *Document) Pages(maxSize int64) (int, error) {
+	_, data, err := d.Decode()
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(data)) > maxSize {
+		return 0, errors.Errorf("document size should not exceed %d bytes, size: %d", maxSize, len(data))
+	}
+	return PDFPageCount(data), nil
+}
+
+const (
+	// pdfObjectStreamMaxSize bounds the decompressed size of a PDF object stream.
+	pdfObjectStreamMaxSize = 16 << 20
+	// pdfObjectStreamsMaxTotalSize bounds the decompressed size of all the
+	// object streams of a PDF, so a document made of many small streams that
+	// inflate well cannot exhaust memory or CPU either.
+	pdfObjectStreamsMaxTotalSize = 32 << 20
+)
+
+var (
+	// pdfPageObject matches the page objects of a PDF; the page tree nodes are
+	// typed /Pages instead.
+	pdfPageObject = regexp.MustCompile(`/Type\s*/Page\b`)
+	// pdfPagesNode matches a page tree node dictionary.
+	pdfPagesNode = regexp.MustCompile(`<<[^<>]*/Type\s*/Pages\b[^<>]*>>`)
+	// pdfPagesCount captures the page count of a page tree node.
+	pdfPagesCount = regexp.MustCompile(`/Count\s+(\d+)`)
+	// pdfObjectStream matches the dictionary of a compressed object stream up
+	// to the start of its data.
+	pdfObjectStream = regexp.MustCompile(`<<([^<>]*/Type\s*/ObjStm[^<>]*)>>\s*stream\r?\n`)
+)
+
+// PDFPageCount counts the pages of a PDF document. The /Count of the page
+// tree root is preferred, falling back to counting page objects; both are
+// also looked up in FlateDecode compressed object streams. Documents whose
+// pages cannot be found count as one page. Callers should enforce their
+// document size limit first, as this parses the whole document.
+func PDFPageCount(data []byte) int {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return 1
+	}
+
+	pages, rootCount := 0, 0
+	count := func(section []byte) {
+		pages += len(pdfPageObject.FindAllIndex(section, -1))
+		// The root holds the largest count, as every other node counts a subtree
+		for _, node := range pdfPagesNode.FindAll(section, -1) {
+			if match := pdfPagesCount.FindSubmatch(node); match != nil {
+				if n, err := strconv.Atoi(string(match[1])); err == nil {
+					rootCount = max(rootCount, n)
+				}
+			}
+		}
+	}
+	count(data)
+	forEachPDFObjectStream(data, count)
+	if rootCount > 0 {
+		return rootCount
+	}
+	return max(pages, 1)
+}
+
+// forEachPDFObjectStream calls fn with the decompressed content of each
+// FlateDecode object stream of a PDF in turn. The content is only valid
+// during the call, so a single buffer is held at any time. Streams that fail
+// to decompress are skipped, and decompression stops once
+// pdfObjectStreamsMaxTotalSize bytes were produced.
+func forEachPDFObjectStream(data []byte, fn func(content []byte)) {
+	var content bytes.Buffer
+	budget := int64(pdfObjectStreamsMaxTotalSize)
+	for _, loc := range pdfObjectStream.FindAllSubmatchIndex(data, -1) {
+		if budget <= 0 {
+			return
+		}
+		if !bytes.Contains(data[loc[2]:loc[3]], []byte("/FlateDecode")) {
+			continue
+		}
+		body := data[loc[1]:]
+		if end := bytes.Index(body, []byte("endstream")); end >= 0 {
+			body = body[:end]
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		content.Reset()
+		n, err := content.ReadFrom(io.LimitReader(reader, min(pdfObjectStreamMaxSize, budget)))
+		_ = reader.Close()
+		budget -= n
+		if err != nil && n == 0 {
+			continue
+		}
+		fn(content.Bytes())
+	}
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentDecode(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("%PDF-1.4"))
+
+	mimeType, data, err := (&Document{Data: "data:application/pdf;base64," + encoded}).Decode()
+	require.NoError(t, err)
+	require.Equal(t, "application/pdf", mimeType)
+	require.Equal(t, []byte("%PDF-1.4"), data)
+
+	mimeType, _, err = (&Document{Data: encoded, MimeType: "application/pdf"}).Decode()
+	require.NoError(t, err)
+	require.Equal(t, "application/pdf", mimeType)
+
+	_, _, err = (&Document{Data: encoded}).Decode()
+	require.Error(t, err)
+	_, _, err = (&Document{Data: "data:application/pdf,plain"}).Decode()
+	require.Error(t, err)
+}
+
+func TestPDFPageCount(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n2 0 obj << /Type /Pages /Count 2 >> endobj\n" +
+		"3 0 obj << /Type /Page >> endobj\n4 0 obj <</Type/Page/Parent 2 0 R>> endobj\n")
+	require.Equal(t, 2, PDFPageCount(pdf))
+	// Without a page tree count, the page objects are counted
+	pdf = []byte("%PDF-1.4\n3 0 obj << /Type /Page >> endobj\n4 0 obj <</Type/Page/Parent 2 0 R>> endobj\n")
+	require.Equal(t, 2, PDFPageCount(pdf))
+	require.Equal(t, 1, PDFPageCount([]byte("%PDF-1.7\ncompressed")))
+	require.Equal(t, 1, PDFPageCount([]byte("not a pdf")))
+}
+
+func TestPDFPageCount_ObjectStreams(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	_, err := writer.Write([]byte("2 0 3 40 4 80 " +
+		"<< /Type /Pages /Kids [3 0 R 4 0 R 5 0 R] /Count 3 >> " +
+		"<< /Type /Page /Parent 2 0 R >> " +
+		"<< /Type /Page /Parent 2 0 R >>"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	pdf := append([]byte("%PDF-1.5\n1 0 obj\n<< /Type /ObjStm /N 3 /First 14 /Filter /FlateDecode /Length 99 >>\nstream\n"),
+		compressed.Bytes()...)
+	pdf = append(pdf, []byte("\nendstream\nendobj\n")...)
+	require.Equal(t, 3, PDFPageCount(pdf))
+}
+
+func TestForEachPDFObjectStream_CapsTotalSize(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	_, err := writer.Write(make([]byte, pdfObjectStreamMaxSize))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	pdf := []byte("%PDF-1.5\n")
+	for i := range 4 {
+		pdf = append(pdf, []byte(fmt.Sprintf("%d 0 obj\n<< /Type /ObjStm /Filter /FlateDecode >>\nstream\n", i+1))...)
+		pdf = append(pdf, compressed.Bytes()...)
+		pdf = append(pdf, []byte("\nendstream\nendobj\n")...)
+	}
+
+	streams, total := 0, 0
+	forEachPDFObjectStream(pdf, func(content []byte) {
+		streams++
+		total += len(content)
+	})
+	require.Equal(t, 2, streams)
+	require.Equal(t, pdfObjectStreamsMaxTotalSize, total)
+}
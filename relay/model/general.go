@@ -91,6 +91,7 @@ type GeneralOpenAIRequest struct {
 	ToolChoice       any             `json:"tool_choice,omitempty"`
 	ParallelTooCalls *bool           `json:"parallel_tool_calls,omitempty"`
 	User             string          `json:"user,omitempty"`
+	Language         string          `json:"language,omitempty"` // preferred response language for language-aware models
 	FunctionCall     any             `json:"function_call,omitempty"`
 	Functions        []Function      `json:"functions,omitempty"`
 	// https://platform.openai.com/docs/api-reference/embeddings/create
@@ -196,6 +197,67 @@ func (r GeneralOpenAIRequest) ParseInput() []string {
 	return input
 }
 
+// HasImageContent reports whether any message carries an image part.
+func (r GeneralOpenAIRequest) HasImageContent() bool {
+	for _, message := range r.Messages {
+		if message.IsStringContent() {
+			continue
+		}
+		for _, part := range message.ParseContent() {
+			if part.Type == ContentTypeImageURL {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Feature flags gating request features per model, see
+// adaptor.ModelFeatureFlags.
+const (
+	FeatureVision           = "supports_vision"
+	FeatureTools            = "supports_tools"
+	FeatureStructuredOutput = "supports_structured_output"
+	FeatureJSONMode         = "supports_json_mode"
+	FeatureStreaming        = "supports_streaming"
+	FeatureExtendedThinking = "supports_extended_thinking"
+)
+
+// RequestFeature is a gated feature used by a request, with the request
+// parameter that uses it.
+type RequestFeature struct {
+	Flag  string
+	Param string
+}
+
+// RequestedFeatures lists the gated features the chat completion request uses.
+func (r *GeneralOpenAIRequest) RequestedFeatures() []RequestFeature {
+	var features []RequestFeature
+	if r.HasImageContent() {
+		features = append(features, RequestFeature{FeatureVision, "messages"})
+	}
+	if len(r.Tools) > 0 {
+		features = append(features, RequestFeature{FeatureTools, "tools"})
+	} else if len(r.Functions) > 0 {
+		features = append(features, RequestFeature{FeatureTools, "functions"})
+	}
+	if r.ResponseFormat != nil {
+		switch r.ResponseFormat.Type {
+		case "json_object":
+			features = append(features, RequestFeature{FeatureJSONMode, "response_format"})
+		case "json_schema":
+			features = append(features, RequestFeature{FeatureStructuredOutput, "response_format"})
+		}
+	}
+	if r.Stream {
+		features = append(features, RequestFeature{FeatureStreaming, "stream"})
+	}
+	if r.Thinking != nil && r.Thinking.Type != "disabled" {
+		features = append(features, RequestFeature{FeatureExtendedThinking, "thinking"})
+	}
+	return features
+}
+
 // OpenaiImageEditRequest is the request body for the OpenAI image edit API.
 type OpenaiImageEditRequest struct {
 	Image          *multipart.FileHeader `json:"image" form:"image" binding:"required"`
@@ -268,3 +330,22 @@ type ClaudeUsage struct {
 	InputTokens  int `json:"input_tokens"`
 	OutputTokens int `json:"output_tokens"`
 }
+
+// GeneralOpenAIResponse is a non-streaming OpenAI Chat Completions response.
+// It is the common representation that responses of other API formats are
+// normalized to, see relay/format.
+type GeneralOpenAIResponse struct {
+	Id      string                        `json:"id"`
+	Object  string                        `json:"object"`
+	Created int64                         `json:"created"`
+	Model   string                        `json:"model,omitempty"`
+	Choices []GeneralOpenAIResponseChoice `json:"choices"`
+	Usage   *Usage                        `json:"usage,omitempty"`
+}
+
+// GeneralOpenAIResponseChoice is a single choice of a GeneralOpenAIResponse.
+type GeneralOpenAIResponseChoice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
@@ -233,6 +233,16 @@ func (m Message) ParseContent() []MessageContent {
 						},
 					})
 				}
+			case ContentTypeDocument:
+				if subObj, ok := contentMap["document"].(map[string]any); ok {
+					document := &Document{}
+					document.Data, _ = subObj["data"].(string)
+					document.MimeType, _ = subObj["mime_type"].(string)
+					contentList = append(contentList, MessageContent{
+						Type:     ContentTypeDocument,
+						Document: document,
+					})
+				}
 			default:
 				logger.Logger.Warn("unknown content type", zap.Any("type", contentMap["type"]))
 			}
@@ -270,11 +280,12 @@ type ImageURL struct {
 }
 
 type MessageContent struct {
-	// Type should be one of the following: text/input_audio
+	// Type should be one of the following: text/image_url/input_audio/document
 	Type       string      `json:"type,omitempty"`
 	Text       *string     `json:"text,omitempty"`
 	ImageURL   *ImageURL   `json:"image_url,omitempty"`
 	InputAudio *InputAudio `json:"input_audio,omitempty"`
+	Document   *Document   `json:"document,omitempty"`
 	// -------------------------------------
 	// Anthropic
 	// -------------------------------------
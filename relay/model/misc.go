@@ -56,6 +56,8 @@ const (
 	ErrorTypeForbidden ErrorType = "forbidden"
 	// ErrorTypeRateLimit denotes rate limiting responses (typically HTTP 429).
 	ErrorTypeRateLimit ErrorType = "rate_limit_error"
+	// ErrorTypeChannelAtCapacity denotes a channel that reached its concurrency limit (HTTP 429).
+	ErrorTypeChannelAtCapacity ErrorType = "channel_at_capacity"
 	// ErrorTypeNotFound maps to missing resources such as jobs or files.
 	ErrorTypeNotFound ErrorType = "not_found_error"
 	// ErrorTypeTest is reserved for synthetic failures inside tests.
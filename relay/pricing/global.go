@@ -2,6 +2,7 @@ package pricing
 
 import (
 	"fmt"
+	"maps"
 	"sync"
 
 	"github.com/Laisky/zap"
@@ -276,9 +277,10 @@ func IsGlobalPricingInitialized() bool {
 
 // GetModelRatioWithThreeLayers implements the three-layer pricing fallback:
 // 1. Channel-specific overrides (highest priority)
-// 2. Adapter default pricing (second priority)
-// 3. Global pricing fallback (third priority)
-// 4. Final default (lowest priority)
+// 2. Admin pricing overrides from the model_pricing_overrides table
+// 3. Adapter default pricing
+// 4. Global pricing fallback
+// 5. Final default (lowest priority)
 func GetModelRatioWithThreeLayers(modelName string, channelOverrides map[string]float64, adaptor adaptor.Adaptor) float64 {
 	// Layer 1: User custom ratio (channel-specific overrides)
 	if channelOverrides != nil {
@@ -287,6 +289,11 @@ func GetModelRatioWithThreeLayers(modelName string, channelOverrides map[string]
 		}
 	}
 
+	// Admin pricing overrides replace the compiled adapter pricing
+	if override, ok := pricingOverride(modelName); ok {
+		return override.Ratio
+	}
+
 	// Layer 2: Channel default ratio (adapter's default pricing)
 	if adaptor != nil {
 		ratio := adaptor.GetModelRatio(modelName)
@@ -310,7 +317,8 @@ func GetModelRatioWithThreeLayers(modelName string, channelOverrides map[string]
 	return 2.5 * 0.000001 // 2.5 USD per million tokens
 }
 
-// GetCompletionRatioWithThreeLayers implements the three-layer completion ratio fallback
+// GetCompletionRatioWithThreeLayers implements the three-layer completion ratio
+// fallback, with the same precedence as GetModelRatioWithThreeLayers.
 func GetCompletionRatioWithThreeLayers(modelName string, channelOverrides map[string]float64, adaptor adaptor.Adaptor) float64 {
 	// Layer 1: User custom ratio (channel-specific overrides)
 	if channelOverrides != nil {
@@ -319,6 +327,11 @@ func GetCompletionRatioWithThreeLayers(modelName string, channelOverrides map[st
 		}
 	}
 
+	// Admin pricing overrides replace the compiled adapter pricing
+	if override, ok := pricingOverride(modelName); ok {
+		return override.CompletionRatio
+	}
+
 	// Layer 2: Channel default ratio (adapter's default pricing)
 	if adaptor != nil {
 		ratio := adaptor.GetCompletionRatio(modelName)
@@ -381,6 +394,9 @@ func cloneModelConfig(src adaptor.ModelConfig) adaptor.ModelConfig {
 	if src.Image != nil {
 		clone.Image = src.Image.Clone()
 	}
+	if src.Features != nil {
+		clone.Features = maps.Clone(src.Features)
+	}
 	return clone
 }
 
@@ -403,11 +419,19 @@ type EffectivePricing struct {
 // the per-token ratio before calling this if overrides apply globally.
 //
 // Behavior:
+// - An admin pricing override returns its flat ratios.
 // - If no tiers exist, returns base ratios.
 // - If tiers exist, finds the tier whose InputTokenThreshold <= inputTokens and is the highest such threshold.
 // - Optional tier fields inherit from base if zero. Negative cached ratios mean free.
 func ResolveEffectivePricing(modelName string, inputTokens int, adaptor adaptor.Adaptor) EffectivePricing {
 	eff := EffectivePricing{}
+	if override, ok := pricingOverride(modelName); ok {
+		// Admin overrides set a flat price without tiers
+		eff.InputRatio = override.Ratio
+		eff.OutputRatio = override.Ratio * override.CompletionRatio
+		eff.CachedInputRatio = override.CachedInputRatio
+		return eff
+	}
 	if adaptor == nil {
 		// Fallback to defaults if adaptor missing
 		baseIn := 2.5 * 0.000001
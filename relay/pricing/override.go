@@ -0,0 +1,41 @@
+package pricing
+
+import (
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/adaptor"
+)
+
+// pricingOverride returns the admin pricing override of modelName converted
+// to a model config, or false when the model keeps its compiled pricing.
+func pricingOverride(modelName string) (adaptor.ModelConfig, bool) {
+	override, ok := model.MatchModelPricingOverride(modelName)
+	if !ok {
+		return adaptor.ModelConfig{}, false
+	}
+	return adaptor.ModelConfig{
+		Ratio:            override.InputRatio,
+		CompletionRatio:  override.CompletionRatio,
+		CachedInputRatio: override.CachedInputRatio,
+		MaxTokens:        override.MaxTokens,
+	}, true
+}
+
+// applyPricingOverride replaces the prices of base with the admin override of
+// modelName. Tiers are dropped since the override sets a flat price, while
+// media pricing and feature flags are kept.
+func applyPricingOverride(modelName string, base adaptor.ModelConfig, found bool) (adaptor.ModelConfig, bool) {
+	override, ok := pricingOverride(modelName)
+	if !ok {
+		return base, found
+	}
+	base.Ratio = override.Ratio
+	base.CompletionRatio = override.CompletionRatio
+	base.CachedInputRatio = override.CachedInputRatio
+	base.CacheWrite5mRatio = 0
+	base.CacheWrite1hRatio = 0
+	base.Tiers = nil
+	if override.MaxTokens > 0 {
+		base.MaxTokens = override.MaxTokens
+	}
+	return base, true
+}
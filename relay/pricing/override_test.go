@@ -0,0 +1,72 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/adaptor"
+)
+
+func TestPricingOverrideTakesPrecedenceAfterSync(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.ModelPricingOverride{}))
+	originalDB := model.DB
+	model.DB = db
+	t.Cleanup(func() {
+		// Drop the loaded overrides before restoring the database
+		model.DB.Where("1 = 1").Delete(&model.ModelPricingOverride{})
+		require.NoError(t, model.LoadModelPricingOverrides())
+		model.DB = originalDB
+	})
+	require.NoError(t, model.LoadModelPricingOverrides())
+
+	provider := &MockAdaptor{
+		name: "openai",
+		pricing: map[string]adaptor.ModelConfig{
+			"gpt-4o": {
+				Ratio:            2.5 * 0.000001,
+				CompletionRatio:  4,
+				CachedInputRatio: 1.25 * 0.000001,
+				MaxTokens:        16384,
+				Features:         adaptor.ModelFeatureFlags{adaptor.FeatureVision: true},
+				Tiers:            []adaptor.ModelRatioTier{{InputTokenThreshold: 1000, Ratio: 5 * 0.000001}},
+			},
+		},
+	}
+	require.Equal(t, 2.5*0.000001, GetModelRatioWithThreeLayers("gpt-4o", nil, provider))
+
+	require.NoError(t, model.CreateModelPricingOverride(&model.ModelPricingOverride{
+		ModelPattern:     "gpt-4o",
+		InputRatio:       1 * 0.000001,
+		CompletionRatio:  3,
+		CachedInputRatio: 0.5 * 0.000001,
+	}))
+
+	// The compiled pricing stays in effect until the next sync
+	require.Equal(t, 2.5*0.000001, GetModelRatioWithThreeLayers("gpt-4o", nil, provider))
+	require.NoError(t, model.LoadModelPricingOverrides())
+
+	require.Equal(t, 1*0.000001, GetModelRatioWithThreeLayers("gpt-4o", nil, provider))
+	require.Equal(t, 3.0, GetCompletionRatioWithThreeLayers("gpt-4o", nil, provider))
+
+	eff := ResolveEffectivePricing("gpt-4o", 5000, provider)
+	require.Equal(t, 1*0.000001, eff.InputRatio)
+	require.InDelta(t, 3*0.000001, eff.OutputRatio, 1e-15)
+	require.Equal(t, 0.5*0.000001, eff.CachedInputRatio)
+	require.Zero(t, eff.AppliedTierThreshold)
+
+	cfg, ok := ResolveModelConfig("gpt-4o", nil, provider)
+	require.True(t, ok)
+	require.Equal(t, 1*0.000001, cfg.Ratio)
+	require.Equal(t, int32(16384), cfg.MaxTokens)
+	require.Empty(t, cfg.Tiers)
+	require.True(t, cfg.Features[adaptor.FeatureVision])
+
+	// Channel overrides still win over the admin override
+	require.Equal(t, 9*0.000001, GetModelRatioWithThreeLayers("gpt-4o", map[string]float64{"gpt-4o": 9 * 0.000001}, provider))
+}
@@ -1,6 +1,8 @@
 package pricing
 
 import (
+	"maps"
+
 	"github.com/songquanpeng/one-api/model"
 	"github.com/songquanpeng/one-api/relay/adaptor"
 )
@@ -15,8 +17,10 @@ const (
 )
 
 // ResolveModelConfig returns the effective model configuration by applying
-// channel overrides first, then adaptor defaults, then global fallbacks.
-// The returned configuration is a clone that callers can mutate safely.
+// channel overrides first, then adaptor defaults, then global fallbacks. The
+// prices of an admin pricing override replace those of the adaptor or global
+// configuration. The returned configuration is a clone that callers can
+// mutate safely.
 func ResolveModelConfig(modelName string, channelConfigs map[string]model.ModelConfigLocal, provider adaptor.Adaptor) (adaptor.ModelConfig, bool) {
 	if channelConfigs != nil {
 		if local, ok := channelConfigs[modelName]; ok {
@@ -28,16 +32,13 @@ func ResolveModelConfig(modelName string, channelConfigs map[string]model.ModelC
 	if provider != nil {
 		if defaults := provider.GetDefaultModelPricing(); defaults != nil {
 			if cfg, ok := defaults[modelName]; ok {
-				return cloneModelConfig(cfg), true
+				return applyPricingOverride(modelName, cloneModelConfig(cfg), true)
 			}
 		}
 	}
 
-	if cfg, ok := GetGlobalModelConfig(modelName); ok {
-		return cfg, true
-	}
-
-	return adaptor.ModelConfig{}, false
+	cfg, ok := GetGlobalModelConfig(modelName)
+	return applyPricingOverride(modelName, cfg, ok)
 }
 
 // ResolveAudioPricing resolves audio pricing metadata using the same precedence
@@ -66,12 +67,33 @@ func ResolveImagePricing(modelName string, channelConfigs map[string]model.Model
 	return nil, false
 }
 
+// ResolveModelFeatures returns the feature flags of a model: the features the
+// adaptor declares, overridden one by one by the flags of the adaptor's model
+// config and then by those of the channel's model configs, so a channel that
+// only prices a model keeps the adaptor's flags.
+func ResolveModelFeatures(modelName string, channelConfigs map[string]model.ModelConfigLocal, provider adaptor.Adaptor) adaptor.ModelFeatureFlags {
+	features := adaptor.ModelFeatureFlags{}
+	if declarer, ok := provider.(adaptor.FeatureDefaultsProvider); ok {
+		maps.Copy(features, declarer.DefaultModelFeatures())
+	}
+	if cfg, ok := ResolveModelConfig(modelName, nil, provider); ok {
+		maps.Copy(features, cfg.Features)
+	}
+	if local, ok := channelConfigs[modelName]; ok {
+		maps.Copy(features, local.Features)
+	}
+	return features
+}
+
 func convertLocalModelConfig(local model.ModelConfigLocal) adaptor.ModelConfig {
 	cfg := adaptor.ModelConfig{
 		Ratio:           local.Ratio,
 		CompletionRatio: local.CompletionRatio,
 		MaxTokens:       local.MaxTokens,
 	}
+	if len(local.Features) > 0 {
+		cfg.Features = maps.Clone(local.Features)
+	}
 	if local.Video != nil {
 		cfg.Video = convertLocalVideo(local.Video)
 	}
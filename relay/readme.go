@@ -0,0 +1,107 @@
+package relay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/songquanpeng/one-api/relay/adaptor"
+	"github.com/songquanpeng/one-api/relay/billing/ratio"
+	"github.com/songquanpeng/one-api/relay/channeltype"
+	"github.com/songquanpeng/one-api/relay/meta"
+	"github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/relaymode"
+)
+
+func init() {
+	channeltype.ReadmeMetadataProvider = ChannelReadmeMetadata
+}
+
+// ChannelReadmeMetadata collects the metadata of the adaptor serving
+// channelType for channeltype.GenerateChannelReadme.
+func ChannelReadmeMetadata(channelType int) (channeltype.ReadmeMetadata, bool) {
+	if channelType <= channeltype.Unknown || channelType >= channeltype.Dummy {
+		return channeltype.ReadmeMetadata{}, false
+	}
+	a := GetAdaptor(channeltype.ToAPIType(channelType))
+	if a == nil {
+		return channeltype.ReadmeMetadata{}, false
+	}
+	// Some adaptors derive their model list from the channel type
+	a.Init(&meta.Meta{
+		Mode:        relaymode.ChatCompletions,
+		ChannelType: channelType,
+		BaseURL:     channeltype.GetChannelBaseURLConfig(channelType).URL,
+	})
+
+	pricing := a.GetDefaultModelPricing()
+	names := a.GetModelList()
+	if len(names) == 0 {
+		names = adaptor.GetModelListFromPricing(pricing)
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	models := make([]channeltype.ReadmeModel, 0, len(names))
+	for _, name := range names {
+		m := channeltype.ReadmeModel{Name: name}
+		if cfg, ok := pricing[name]; ok {
+			m.InputUsdPer1M = ratioToUsdPer1M(cfg.Ratio)
+			completionRatio := cfg.CompletionRatio
+			if completionRatio == 0 {
+				completionRatio = 1
+			}
+			m.OutputUsdPer1M = m.InputUsdPer1M * completionRatio
+			m.MaxOutputTokens = cfg.MaxTokens
+		}
+		models = append(models, m)
+	}
+
+	_, supportsRerank := a.(adaptor.RerankAdaptor)
+	return channeltype.ReadmeMetadata{
+		ChannelName:            a.GetChannelName(),
+		Models:                 models,
+		SupportsClaudeMessages: supportsClaudeMessages(a),
+		SupportsRerank:         supportsRerank,
+	}, true
+}
+
+// ratioToUsdPer1M converts a per-token quota ratio to USD per 1M tokens.
+func ratioToUsdPer1M(r float64) float64 {
+	if r <= 0 {
+		return 0
+	}
+	return r * 1_000_000 / ratio.QuotaPerUsd
+}
+
+// supportsClaudeMessages probes ConvertClaudeRequest with a minimal request.
+// Adaptors without Claude Messages support reject it as not supported, while
+// configuration errors of supporting adaptors do not count.
+func supportsClaudeMessages(a adaptor.Adaptor) (supported bool) {
+	defer func() {
+		if recover() != nil {
+			supported = false
+		}
+	}()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	_, err := a.ConvertClaudeRequest(c, &model.ClaudeRequest{
+		Model:     "probe",
+		MaxTokens: 1,
+		Messages:  []model.ClaudeMessage{{Role: "user", Content: "probe"}},
+	})
+	if err == nil {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, indicator := range []string{"not supported", "not implemented", "not applicable"} {
+		if strings.Contains(msg, indicator) {
+			return false
+		}
+	}
+	return true
+}
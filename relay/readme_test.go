@@ -0,0 +1,76 @@
+package relay
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/songquanpeng/one-api/relay/channeltype"
+)
+
+// requireValidMarkdown checks that code fences are closed and table rows have
+// as many cells as their header.
+func requireValidMarkdown(t *testing.T, markdown string) {
+	t.Helper()
+	require.Zero(t, strings.Count(markdown, "```")%2, "unbalanced code fence")
+
+	headerCells := 0
+	inCode := false
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		if !strings.HasPrefix(line, "|") {
+			headerCells = 0
+			continue
+		}
+		require.True(t, strings.HasSuffix(line, "|"), "unterminated table row: %s", line)
+		cells := strings.Count(line, "|") - 1
+		if headerCells == 0 {
+			headerCells = cells
+		}
+		require.Equal(t, headerCells, cells, "table row with wrong cell count: %s", line)
+	}
+}
+
+func TestGenerateChannelReadme_OpenAI(t *testing.T) {
+	markdown := channeltype.GenerateChannelReadme(channeltype.OpenAI)
+	require.NotEmpty(t, markdown)
+	requireValidMarkdown(t, markdown)
+
+	require.True(t, strings.HasPrefix(markdown, "# openai channel\n"), markdown[:40])
+	for _, section := range []string{
+		"## Supported models",
+		"## Configuration",
+		"## Pricing",
+		"## Known limitations",
+		"## Examples",
+	} {
+		require.Contains(t, markdown, "\n"+section+"\n")
+	}
+	require.Contains(t, markdown, "- `gpt-4o`\n")
+	require.Contains(t, markdown, "**API key**")
+	require.Contains(t, markdown, "`https://api.openai.com`")
+	require.Contains(t, markdown, "| Model | Input | Output |")
+	require.Contains(t, markdown, "| `gpt-4o` | $2.5 | $10 |")
+	require.Contains(t, markdown, "/v1/chat/completions")
+	// The OpenAI adaptor relays Claude Messages requests
+	require.Contains(t, markdown, "/v1/messages")
+
+	html := channeltype.ReadmeToHTML(markdown)
+	require.Contains(t, html, "<h1>openai channel</h1>")
+	require.Contains(t, html, "<th>Model</th>")
+	require.Contains(t, html, "<td><code>gpt-4o</code></td>")
+	require.Contains(t, html, `<pre><code class="language-bash">`)
+	require.NotContains(t, html, "| ---")
+}
+
+func TestGenerateChannelReadme_UnknownType(t *testing.T) {
+	require.Empty(t, channeltype.GenerateChannelReadme(channeltype.Unknown))
+	require.Empty(t, channeltype.GenerateChannelReadme(channeltype.Dummy))
+}
@@ -23,4 +23,10 @@ const (
 	Realtime
 	// Videos handles OpenAI video generation endpoints (e.g., /v1/videos)
 	Videos
+	// TextToSpeech is for Minimax T2A requests (/v1/text_to_speech)
+	TextToSpeech
+	// Batches is for OpenAI Batch API requests (/v1/batches)
+	Batches
+	// Files is for OpenAI file uploads and downloads (/v1/files)
+	Files
 )
@@ -40,6 +40,12 @@ func GetByPath(path string) int {
 		return ImagesEdits
 	case strings.HasPrefix(path, "/v1/videos"):
 		return Videos
+	case strings.HasPrefix(path, "/v1/text_to_speech"):
+		return TextToSpeech
+	case strings.HasPrefix(path, "/v1/batches"):
+		return Batches
+	case strings.HasPrefix(path, "/v1/files"):
+		return Files
 	default:
 		return Unknown
 	}
@@ -19,3 +19,21 @@ func TestGetByPathVideos(t *testing.T) {
 		t.Fatalf("expected Videos with path segment, got %d", got)
 	}
 }
+
+func TestGetByPathTextToSpeech(t *testing.T) {
+	if got := GetByPath("/v1/text_to_speech"); got != TextToSpeech {
+		t.Fatalf("expected TextToSpeech, got %d", got)
+	}
+	if got := GetByPath("/v1/audio/speech"); got != AudioSpeech {
+		t.Fatalf("expected AudioSpeech, got %d", got)
+	}
+}
+
+func TestGetByPathBatches(t *testing.T) {
+	if got := GetByPath("/v1/batches/batch_123/cancel"); got != Batches {
+		t.Fatalf("expected Batches, got %d", got)
+	}
+	if got := GetByPath("/v1/files/file_123/content"); got != Files {
+		t.Fatalf("expected Files, got %d", got)
+	}
+}
@@ -238,7 +238,7 @@ func (t *QuotaTracker) ensureQuotaLocked(delta int64) error {
 	if err != nil {
 		return errors.Wrap(err, "get user quota during streaming flush")
 	}
-	if remaining < delta {
+	if model.QuotaWithOverage(remaining) < delta {
 		return ErrQuotaExceeded
 	}
 	return nil
@@ -30,6 +30,8 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/oauth/github", middleware.CriticalRateLimit(), auth.GitHubOAuth)
 		apiRouter.GET("/oauth/oidc", middleware.CriticalRateLimit(), auth.OidcAuth)
 		apiRouter.GET("/oauth/lark", middleware.CriticalRateLimit(), auth.LarkOAuth)
+		apiRouter.GET("/auth/google/login", middleware.CriticalRateLimit(), auth.GoogleOAuthLogin)
+		apiRouter.GET("/auth/google/callback", middleware.CriticalRateLimit(), auth.GoogleOAuthCallback)
 		apiRouter.GET("/oauth/state", middleware.CriticalRateLimit(), auth.GenerateOAuthCode)
 		apiRouter.GET("/oauth/wechat", middleware.CriticalRateLimit(), auth.WeChatAuth)
 		apiRouter.GET("/oauth/wechat/bind", middleware.CriticalRateLimit(), middleware.UserAuth(), auth.WeChatBind)
@@ -50,14 +52,24 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.GET("/self", controller.GetSelf)
 				selfRoute.PUT("/self", controller.UpdateSelf)
 				selfRoute.DELETE("/self", controller.DeleteSelf)
+				selfRoute.POST("/self/revoke-sessions", controller.RevokeSelfSessions)
+				selfRoute.PATCH("/self/ip-allowlist", controller.UpdateSelfIpAllowlist)
+				selfRoute.GET("/login-history", controller.GetSelfLoginHistory)
+				selfRoute.GET("/settings", controller.GetUserSettings)
+				selfRoute.PATCH("/settings", controller.UpdateUserSettings)
 				selfRoute.GET("/token", controller.GenerateAccessToken)
 				selfRoute.GET("/aff", controller.GetAffCode)
 				selfRoute.POST("/topup", controller.TopUp)
+				selfRoute.POST("/quota/transfer", controller.TransferSelfQuota)
+				selfRoute.POST("/parent/respond", controller.RespondUserParentOffer)
+				selfRoute.POST("/channel/test", controller.TestUserChannel)
 				selfRoute.GET("/available_models", controller.GetUserAvailableModels)
+				selfRoute.GET("/reasoning/:request_id", controller.GetReasoningTrace)
 				selfRoute.GET("/totp/status", controller.GetTotpStatus)
 				selfRoute.GET("/totp/setup", controller.SetupTotp)
 				selfRoute.POST("/totp/confirm", controller.ConfirmTotp)
 				selfRoute.POST("/totp/disable", controller.DisableTotp)
+				selfRoute.POST("/totp/backup_codes", controller.GenerateTotpBackupCodes)
 			}
 
 			adminRoute := userRoute.Group("/")
@@ -71,6 +83,8 @@ func SetApiRouter(router *gin.Engine) {
 				adminRoute.PUT("/", controller.UpdateUser)
 				adminRoute.DELETE("/:id", controller.DeleteUser)
 				adminRoute.POST("/totp/disable/:id", controller.AdminDisableUserTotp)
+				adminRoute.PATCH("/:id/auto-refill", controller.UpdateUserAutoRefill)
+				adminRoute.POST("/:id/revoke-sessions", controller.RevokeUserSessions)
 			}
 		}
 		optionRoute := apiRouter.Group("/option")
@@ -143,6 +157,7 @@ func SetApiRouter(router *gin.Engine) {
 		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), controller.SearchUserLogs)
+		apiRouter.GET("/logs/stats", middleware.AdminAuth(), controller.GetLogAggregates)
 
 		// Tracing routes
 		traceRoute := apiRouter.Group("/trace")
@@ -155,6 +170,38 @@ func SetApiRouter(router *gin.Engine) {
 		groupRoute.Use(middleware.AdminAuth())
 		{
 			groupRoute.GET("/", controller.GetGroups)
+			groupRoute.GET("/tier", controller.GetGroupTiers)
+			groupRoute.PUT("/tier", controller.UpdateGroupTier)
+		}
+		// Parent accounts may manage their own sub-accounts, so this only needs user auth
+		apiRouter.POST("/admin/users/:id/set-parent", middleware.UserAuth(), controller.SetUserParent)
+		adminOpsRoute := apiRouter.Group("/admin")
+		adminOpsRoute.Use(middleware.AdminAuth())
+		{
+			adminOpsRoute.POST("/replay/:trace_id", controller.ReplayRequest)
+			adminOpsRoute.POST("/users/:id/restore", controller.RestoreUser)
+			adminOpsRoute.GET("/users/:id/login-history", controller.GetUserLoginHistory)
+			adminOpsRoute.POST("/quota/transfer", controller.AdminTransferQuota)
+			adminOpsRoute.GET("/token-count-accuracy", controller.GetTokenCountAccuracy)
+			adminOpsRoute.GET("/channel/:channel_type/readme", controller.GetChannelReadme)
+			adminOpsRoute.GET("/channel/:channel_type/changelog", controller.GetChannelPricingChangelog)
+			adminOpsRoute.GET("/channels/queue-depth", controller.GetChannelQueueDepths)
+			adminOpsRoute.GET("/analytics/active-users", controller.GetActiveUsers)
+			adminOpsRoute.GET("/analytics/request-sizes", controller.GetRequestSizes)
+			adminOpsRoute.GET("/analytics/response-sizes", controller.GetResponseSizes)
+			adminOpsRoute.GET("/db/slow-queries", controller.GetSlowQueries)
+			adminOpsRoute.GET("/logs/export", controller.ExportLogs)
+			adminOpsRoute.GET("/audit", controller.GetAuditLogs)
+			adminOpsRoute.GET("/reports/cost/preview", controller.PreviewCostReport)
+			adminOpsRoute.GET("/anomalies/cost", controller.GetCostAnomalies)
+			adminOpsRoute.POST("/redemption/batch", controller.CreateRedemptionBatch)
+			adminOpsRoute.GET("/redemption/batch/:batch_id/stats", controller.GetRedemptionBatchStats)
+			adminOpsRoute.GET("/model-pricing", controller.GetModelPricingOverrides)
+			adminOpsRoute.POST("/model-pricing", controller.CreateModelPricingOverride)
+			adminOpsRoute.PUT("/model-pricing/:id", controller.UpdateModelPricingOverride)
+			adminOpsRoute.DELETE("/model-pricing/:id", controller.DeleteModelPricingOverride)
+			adminOpsRoute.POST("/blacklist/ip", controller.BanIP)
+			adminOpsRoute.DELETE("/blacklist/ip/:ip", controller.UnbanIP)
 		}
 	}
 }
@@ -3,6 +3,7 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/graceful"
 	"github.com/songquanpeng/one-api/controller"
 	"github.com/songquanpeng/one-api/middleware"
@@ -26,19 +27,33 @@ func SetRelayRouter(router *gin.Engine) {
 	router.Use(middleware.GzipDecodeMiddleware())
 	// https://platform.openai.com/docs/api-reference/introduction
 	modelsRouter := router.Group("/v1/models")
-	modelsRouter.Use(middleware.TokenAuth())
+	modelsRouter.Use(middleware.RejectBannedIP(), middleware.TokenAuth())
 	{
 		modelsRouter.GET("", controller.ListModels)
 		modelsRouter.GET("/:model", controller.RetrieveModel)
 	}
+	rateLimitRouter := router.Group("/v1/rate-limits")
+	rateLimitRouter.Use(middleware.RejectBannedIP(), middleware.TokenAuth())
+	{
+		rateLimitRouter.GET("", controller.GetRateLimitStatus)
+	}
 
 	relayMws := []gin.HandlerFunc{
 		// Track in-flight requests for graceful shutdown/drain
 		func(c *gin.Context) { done := graceful.BeginRequest(); defer done(); c.Next() },
-		middleware.RelayPanicRecover(), middleware.TokenAuth(),
+		middleware.RelayPanicRecover(), middleware.RejectBannedIP(), middleware.TokenAuth(),
+		middleware.StreamResume(),
+		middleware.RequestRedaction(),
+		middleware.CaptureFailedRequest(),
+		middleware.CaptureReplayCorpus(),
 		middleware.BindAsyncTaskChannel(),
+		middleware.BindBatchChannel(),
 		middleware.Distribute(),
+		middleware.TierGate(),
+		middleware.UserBudget(),
+		middleware.InjectResponseLanguage(),
 		middleware.GlobalRelayRateLimit(),
+		middleware.TokenRateLimit(),
 		middleware.ChannelRateLimit(),
 	}
 
@@ -46,10 +61,14 @@ func SetRelayRouter(router *gin.Engine) {
 	relayV1Router := router.Group("/v1")
 	relayV1Router.Use(relayMws...)
 
-	relayV1Router.GET("/realtime", controller.RelayRealtime)
+	realtimeHandler := controller.RelayNotImplemented
+	if config.RealtimeAPIEnabled {
+		realtimeHandler = controller.RelayRealtime
+	}
+	relayV1Router.GET("/realtime", realtimeHandler)
 	relayV1Router.Any("/oneapi/proxy/:channelid/*target", controller.Relay)
 	relayV1Router.POST("/completions", controller.Relay)
-	relayV1Router.POST("/chat/completions", controller.Relay)
+	relayV1Router.POST("/chat/completions", middleware.PlainTextResponse(), controller.Relay)
 	relayV1Router.POST("/responses", controller.Relay)
 	relayV1Router.GET("/responses/:response_id", controller.RelayResponseGet)
 	relayV1Router.DELETE("/responses/:response_id", controller.RelayResponseDelete)
@@ -70,11 +89,20 @@ func SetRelayRouter(router *gin.Engine) {
 	relayV1Router.POST("/audio/transcriptions", controller.Relay)
 	relayV1Router.POST("/audio/translations", controller.Relay)
 	relayV1Router.POST("/audio/speech", controller.Relay)
+	relayV1Router.POST("/text_to_speech", controller.Relay)
+	// Batch API: only batch input uploads and result downloads are relayed
+	batchHandler := controller.RelayNotImplemented
+	if config.BatchAPIEnabled {
+		batchHandler = controller.Relay
+	}
+	relayV1Router.POST("/batches", batchHandler)
+	relayV1Router.GET("/batches/:batch_id", batchHandler)
+	relayV1Router.POST("/batches/:batch_id/cancel", batchHandler)
 	relayV1Router.GET("/files", controller.RelayNotImplemented)
-	relayV1Router.POST("/files", controller.RelayNotImplemented)
+	relayV1Router.POST("/files", batchHandler)
 	relayV1Router.DELETE("/files/:id", controller.RelayNotImplemented)
 	relayV1Router.GET("/files/:id", controller.RelayNotImplemented)
-	relayV1Router.GET("/files/:id/content", controller.RelayNotImplemented)
+	relayV1Router.GET("/files/:id/content", batchHandler)
 	relayV1Router.POST("/fine_tuning/jobs", controller.RelayNotImplemented)
 	relayV1Router.GET("/fine_tuning/jobs", controller.RelayNotImplemented)
 	relayV1Router.GET("/fine_tuning/jobs/:id", controller.RelayNotImplemented)
@@ -364,11 +364,9 @@ func getAllAdapterTestCases() []AdapterTestCase {
 			Name:                   "Minimax",
 			Adapter:                &minimax.Adaptor{},
 			ChannelType:            channeltype.Minimax,
-			SupportsChatCompletion: false,
-			SupportsClaudeMessages: false,
-			TestModel:              "minimax-model",
-			ExpectedError:          "not implemented",
-			IsStubImplementation:   true,
+			SupportsChatCompletion: true,
+			SupportsClaudeMessages: true,
+			TestModel:              "abab6.5s-chat",
 		},
 		{
 			Name:                   "Baichuan",
@@ -399,7 +397,7 @@ func createTestMeta(channelType int, model string) *meta.Meta {
 		ChannelType:     channelType,
 		ActualModelName: model,
 		BaseURL:         "https://api.example.com",
-		APIKey:          "test-key",
+		APIKey:          "test-id.test-key", // Zhipu signs its JWT with an id.secret key
 		PromptTokens:    10,
 		IsStream:        false,
 	}
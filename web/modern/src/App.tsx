@@ -10,6 +10,7 @@ import { HomePage } from '@/pages/HomePage'
 import { NotFoundPage } from '@/pages/NotFoundPage'
 import { AboutPage } from '@/pages/about/AboutPage'
 import { GitHubOAuthPage } from '@/pages/auth/GitHubOAuthPage'
+import { GoogleOAuthPage } from '@/pages/auth/GoogleOAuthPage'
 import { LarkOAuthPage } from '@/pages/auth/LarkOAuthPage'
 import { LoginPage } from '@/pages/auth/LoginPage'
 import { PasswordResetConfirmPage } from '@/pages/auth/PasswordResetConfirmPage'
@@ -73,6 +74,7 @@ function App() {
                 <Route path="/reset" element={<PasswordResetPage />} />
                 <Route path="/user/reset" element={<PasswordResetConfirmPage />} />
                 <Route path="/oauth/github" element={<GitHubOAuthPage />} />
+                <Route path="/oauth/google" element={<GoogleOAuthPage />} />
                 <Route path="/oauth/lark" element={<LarkOAuthPage />} />
 
                 {/* Public route(s) with layout */}
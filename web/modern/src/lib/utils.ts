@@ -206,6 +206,7 @@ export interface SystemStatus {
   turnstile_site_key?: string
   github_oauth?: boolean
   github_client_id?: string
+  google_oauth?: boolean
   chat_link?: string
   [key: string]: any
 }
@@ -0,0 +1,83 @@
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
+import { api } from '@/lib/api'
+import { useAuthStore } from '@/lib/stores/auth'
+import { useCallback, useEffect, useState } from 'react'
+import { useTranslation } from 'react-i18next'
+import { useNavigate, useSearchParams } from 'react-router-dom'
+
+export function GoogleOAuthPage() {
+  const [searchParams] = useSearchParams()
+  const { t } = useTranslation()
+  const [prompt, setPrompt] = useState(() => t('auth.oauth.google.prompt.processing'))
+  const navigate = useNavigate()
+  const { login } = useAuthStore()
+
+  const sendCode = useCallback(async (code: string, state: string, retryCount = 0): Promise<void> => {
+    try {
+      // The backend verifies the state stored by /api/auth/google/login before exchanging the code
+      const response = await api.get(`/api/auth/google/callback?code=${encodeURIComponent(code)}&state=${encodeURIComponent(state)}`)
+      const { success, message, data } = response.data
+
+      if (success) {
+        login(data, '')
+        navigate('/', {
+          state: { message: t('auth.oauth.google.login_success') }
+        })
+      } else {
+        throw new Error(message || t('auth.oauth.google.failed'))
+      }
+    } catch (error) {
+      if (retryCount >= 3) {
+        setPrompt(t('auth.oauth.google.prompt.failed'))
+        setTimeout(() => {
+          navigate('/login', {
+            state: { message: t('auth.oauth.google.failed_redirect') }
+          })
+        }, 2000)
+        return
+      }
+
+      const nextRetry = retryCount + 1
+      setPrompt(t('auth.oauth.google.prompt.retry', { retry: nextRetry }))
+
+      // Exponential backoff
+      const delay = nextRetry * 2000
+      setTimeout(() => {
+        sendCode(code, state, nextRetry)
+      }, delay)
+    }
+  }, [login, navigate, t])
+
+  useEffect(() => {
+    const code = searchParams.get('code')
+    const state = searchParams.get('state')
+
+    if (!code || !state) {
+      navigate('/login', {
+        state: { message: t('auth.oauth.google.invalid_params') }
+      })
+      return
+    }
+
+    sendCode(code, state)
+  }, [searchParams, navigate, sendCode, t])
+
+  return (
+    <div className="min-h-screen flex items-center justify-center p-4">
+      <Card className="w-full max-w-md">
+        <CardHeader className="text-center">
+          <CardTitle className="text-2xl">{t('auth.oauth.google.title')}</CardTitle>
+          <CardDescription>{t('auth.oauth.google.description')}</CardDescription>
+        </CardHeader>
+        <CardContent>
+          <div className="flex items-center justify-center py-8">
+            <div className="animate-spin rounded-full h-8 w-8 border-b-2 border-primary"></div>
+            <span className="ml-3 text-sm text-muted-foreground">{prompt}</span>
+          </div>
+        </CardContent>
+      </Card>
+    </div>
+  )
+}
+
+export default GoogleOAuthPage
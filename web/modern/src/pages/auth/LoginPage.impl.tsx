@@ -98,6 +98,11 @@ export function LoginPage() {
     }
   };
 
+  const onGoogleOAuth = () => {
+    // The backend stores the OAuth state in the session and redirects to Google
+    window.location.href = "/api/auth/google/login";
+  };
+
   const onLarkOAuth = () => {
     if (systemStatus.lark_client_id) {
       const redirectUri = `${window.location.origin}/oauth/lark`;
@@ -192,6 +197,7 @@ export function LoginPage() {
 
   const hasOAuthOptions =
     systemStatus.github_oauth ||
+    systemStatus.google_oauth ||
     systemStatus.wechat_login ||
     systemStatus.lark_client_id;
 
@@ -374,6 +380,18 @@ export function LoginPage() {
                       GitHub
                     </Button>
                   )}
+                  {systemStatus.google_oauth && (
+                    <Button variant="outline" size="sm" onClick={onGoogleOAuth}>
+                      <svg
+                        className="w-4 h-4 mr-2"
+                        viewBox="0 0 24 24"
+                        fill="currentColor"
+                      >
+                        <path d="M21.35 11.1H12v2.98h5.35c-.23 1.43-1.66 4.2-5.35 4.2-3.22 0-5.85-2.67-5.85-5.96S8.78 6.36 12 6.36c1.83 0 3.06.78 3.76 1.45l2.56-2.47C16.68 3.8 14.55 2.9 12 2.9 6.98 2.9 2.9 6.98 2.9 12s4.08 9.1 9.1 9.1c5.25 0 8.73-3.69 8.73-8.89 0-.6-.07-1.05-.15-1.51z" />
+                      </svg>
+                      Google
+                    </Button>
+                  )}
                   {systemStatus.wechat_login && (
                     <Button
                       variant="outline"
@@ -32,8 +32,12 @@ interface Channel {
   used_quota?: number
   test_time?: number
   testing_model?: string | null
+  source?: string
 }
 
+// Channels loaded from CHANNEL_CONFIG_PATH are read-only in the UI
+const isReadOnlyChannel = (channel: Channel) => channel.source === 'file'
+
 /**
  * Channel options defined at relay/channeltype/define.go
  */
@@ -49,6 +53,8 @@ const CHANNEL_TYPES: Record<number, { name: string; color: string }> = {
   28: { name: 'Mistral AI', color: 'purple' },
   41: { name: 'Novita', color: 'purple' },
   40: { name: 'ByteDance Volcano', color: 'blue' },
+  52: { name: 'Perplexity', color: 'teal' },
+  53: { name: 'Fireworks AI', color: 'orange' },
   15: { name: 'Baidu Wenxin', color: 'blue' },
   47: { name: 'Baidu Wenxin V2', color: 'blue' },
   17: { name: 'Alibaba Qianwen', color: 'orange' },
@@ -385,7 +391,14 @@ export function ChannelsPage() {
       accessorKey: 'name',
       header: t('channels.columns.name'),
       cell: ({ row }) => (
-        <div className="font-medium">{row.original.name}</div>
+        <div className="font-medium flex items-center gap-2">
+          {row.original.name}
+          {isReadOnlyChannel(row.original) && (
+            <Badge variant="secondary" className="text-xs" title={t('channels.read_only.hint')}>
+              {t('channels.read_only.badge')}
+            </Badge>
+          )}
+        </div>
       ),
     },
     {
@@ -485,12 +498,15 @@ export function ChannelsPage() {
       header: t('channels.columns.actions'),
       cell: ({ row }) => {
         const channel = row.original
+        const readOnly = isReadOnlyChannel(channel)
         return (
           <ResponsiveActionGroup className="sm:items-center">
             <Button
               variant="outline"
               size="sm"
               onClick={() => navigate(`/channels/edit/${channel.id}`)}
+              disabled={readOnly}
+              title={readOnly ? t('channels.read_only.hint') : undefined}
               className="gap-1"
             >
               <Settings className="h-3 w-3" />
@@ -500,6 +516,7 @@ export function ChannelsPage() {
               variant="outline"
               size="sm"
               onClick={() => manage(channel.id, channel.status === 1 ? 'disable' : 'enable')}
+              disabled={readOnly}
               className={cn(
                 'gap-1',
                 channel.status === 1
@@ -522,6 +539,7 @@ export function ChannelsPage() {
               variant="destructive"
               size="sm"
               onClick={() => manage(channel.id, 'delete')}
+              disabled={readOnly}
               className="gap-1"
             >
               <Trash2 className="h-3 w-3" />
@@ -627,6 +645,7 @@ export function ChannelsPage() {
                   variant="ghost"
                   size="icon"
                   onClick={() => navigate(`/channels/edit/${row.id}`)}
+                  disabled={isReadOnlyChannel(row)}
                   title={t('channels.actions.edit')}
                   aria-label={t('channels.actions.edit')}
                 >
@@ -636,6 +655,7 @@ export function ChannelsPage() {
                   variant="ghost"
                   size="icon"
                   onClick={() => manage(row.id, row.status === 1 ? 'disable' : 'enable')}
+                  disabled={isReadOnlyChannel(row)}
                   title={row.status === 1 ? t('channels.actions.disable') : t('channels.actions.enable')}
                   aria-label={row.status === 1 ? t('channels.actions.disable') : t('channels.actions.enable')}
                   className={row.status === 1 ? 'text-orange-600 hover:text-orange-700' : 'text-green-600 hover:text-green-700'}
@@ -1,5 +1,6 @@
 import type { UseFormReturn } from "react-hook-form";
 import { Button } from "@/components/ui/button";
+import { Checkbox } from "@/components/ui/checkbox";
 import {
 	FormControl,
 	FormField,
@@ -45,6 +46,24 @@ export const ChannelAdvancedSettings = ({
 		form.setValue("inference_profile_arn_map", formatted);
 	};
 
+	const formatModelStreamingTimeouts = () => {
+		const current = form.getValues("model_streaming_timeouts");
+		const formatted = formatJSON(current);
+		form.setValue("model_streaming_timeouts", formatted);
+	};
+
+	const formatErrorResponseTemplate = () => {
+		const current = form.getValues("error_response_template");
+		const formatted = formatJSON(current);
+		form.setValue("error_response_template", formatted);
+	};
+
+	const formatResponseAnnotations = () => {
+		const current = form.getValues("response_annotations");
+		const formatted = formatJSON(current);
+		form.setValue("response_annotations", formatted);
+	};
+
 	return (
 		<div className="grid grid-cols-1 md:grid-cols-3 gap-6">
 			<FormField
@@ -120,6 +139,31 @@ export const ChannelAdvancedSettings = ({
 				)}
 			/>
 
+			<FormField
+				control={form.control}
+				name="max_concurrency"
+				render={({ field }) => (
+					<FormItem>
+						<LabelWithHelp
+							label={tr("max_concurrency.label", "Max Concurrency")}
+							help={tr(
+								"max_concurrency.help",
+								"Maximum requests in flight on this channel. Further requests are retried on other channels. 0 means unlimited.",
+							)}
+						/>
+						<FormControl>
+							<Input
+								type="number"
+								min="0"
+								className={errorClass("max_concurrency")}
+								{...field}
+							/>
+						</FormControl>
+						<FormMessage />
+					</FormItem>
+				)}
+			/>
+
 			<div className="col-span-1 md:col-span-3">
 				<FormField
 					control={form.control}
@@ -150,6 +194,111 @@ export const ChannelAdvancedSettings = ({
 				/>
 			</div>
 
+			<FormField
+				control={form.control}
+				name="auto_translate_system_prompt"
+				render={({ field }) => (
+					<FormItem>
+						<LabelWithHelp
+							label={tr(
+								"system_prompt_translation.enabled_label",
+								"Translate System Prompt",
+							)}
+							help={tr(
+								"system_prompt_translation.enabled_help",
+								"Translate system prompts that are not in the target language before forwarding. The translation is cached for 24 hours and billed with the request.",
+							)}
+						/>
+						<FormControl>
+							<Checkbox
+								checked={field.value}
+								onCheckedChange={(checked) => field.onChange(checked === true)}
+							/>
+						</FormControl>
+						<FormMessage />
+					</FormItem>
+				)}
+			/>
+
+			<FormField
+				control={form.control}
+				name="translate_target_language"
+				render={({ field }) => (
+					<FormItem>
+						<LabelWithHelp
+							label={tr(
+								"system_prompt_translation.language_label",
+								"Target Language",
+							)}
+							help={tr(
+								"system_prompt_translation.language_help",
+								"BCP 47 tag of the language this channel's models prefer, e.g. zh-CN.",
+							)}
+						/>
+						<FormControl>
+							<Input
+								placeholder="zh-CN"
+								className={errorClass("translate_target_language")}
+								{...field}
+								value={field.value || ""}
+							/>
+						</FormControl>
+						<FormMessage />
+					</FormItem>
+				)}
+			/>
+
+			<FormField
+				control={form.control}
+				name="translation_channel_id"
+				render={({ field }) => (
+					<FormItem>
+						<LabelWithHelp
+							label={tr(
+								"system_prompt_translation.channel_label",
+								"Translation Channel ID",
+							)}
+							help={tr(
+								"system_prompt_translation.channel_help",
+								"ID of an OpenAI-compatible channel whose testing model, or cheapest model, translates the prompt.",
+							)}
+						/>
+						<FormControl>
+							<Input
+								type="number"
+								min="0"
+								className={errorClass("translation_channel_id")}
+								{...field}
+							/>
+						</FormControl>
+						<FormMessage />
+					</FormItem>
+				)}
+			/>
+
+			<FormField
+				control={form.control}
+				name="store_reasoning_trace"
+				render={({ field }) => (
+					<FormItem>
+						<LabelWithHelp
+							label={tr("reasoning_trace.label", "Store Reasoning Trace")}
+							help={tr(
+								"reasoning_trace.help",
+								"Store model reasoning for later retrieval and hide it from responses unless the client sends X-OneApi-Include-Reasoning: true.",
+							)}
+						/>
+						<FormControl>
+							<Checkbox
+								checked={field.value}
+								onCheckedChange={(checked) => field.onChange(checked === true)}
+							/>
+						</FormControl>
+						<FormMessage />
+					</FormItem>
+				)}
+			/>
+
 			<div className="col-span-1 md:col-span-3">
 				<FormField
 					control={form.control}
@@ -200,6 +349,129 @@ export const ChannelAdvancedSettings = ({
 					)}
 				/>
 			</div>
+
+			<div className="col-span-1 md:col-span-3">
+				<FormField
+					control={form.control}
+					name="response_annotations"
+					render={({ field }) => (
+						<FormItem>
+							<div className="flex items-center justify-between">
+								<LabelWithHelp
+									label={tr("response_annotations.label", "Response Annotations")}
+									help={tr(
+										"response_annotations.help",
+										"Attribution added to responses served by this channel.",
+									)}
+								/>
+								<Button
+									type="button"
+									variant="ghost"
+									size="sm"
+									className="h-6 text-xs"
+									onClick={formatResponseAnnotations}
+								>
+									{tr("common.format_json", "Format JSON")}
+								</Button>
+							</div>
+							<FormControl>
+								<Textarea
+									placeholder={tr(
+										"response_annotations.placeholder",
+										'{"inject_header": "X-OneApi-Channel", "inject_metadata": true}',
+									)}
+									className={`font-mono text-xs min-h-[80px] ${errorClass("response_annotations")}`}
+									{...field}
+									value={field.value || ""}
+								/>
+							</FormControl>
+							<FormMessage />
+						</FormItem>
+					)}
+				/>
+			</div>
+
+			<div className="col-span-1 md:col-span-3">
+				<FormField
+					control={form.control}
+					name="model_streaming_timeouts"
+					render={({ field }) => (
+						<FormItem>
+							<div className="flex items-center justify-between">
+								<LabelWithHelp
+									label={tr("model_streaming_timeouts.label", "Model Streaming Timeouts")}
+									help={tr(
+										"model_streaming_timeouts.help",
+										"Seconds a stream of each model may go without new tokens before it is closed.",
+									)}
+								/>
+								<Button
+									type="button"
+									variant="ghost"
+									size="sm"
+									className="h-6 text-xs"
+									onClick={formatModelStreamingTimeouts}
+								>
+									{tr("common.format_json", "Format JSON")}
+								</Button>
+							</div>
+							<FormControl>
+								<Textarea
+									placeholder={tr(
+										"model_streaming_timeouts.placeholder",
+										'{"sora": 300, "whisper-1": 60}',
+									)}
+									className={`font-mono text-xs min-h-[80px] ${errorClass("model_streaming_timeouts")}`}
+									{...field}
+									value={field.value || ""}
+								/>
+							</FormControl>
+							<FormMessage />
+						</FormItem>
+					)}
+				/>
+			</div>
+
+			<div className="col-span-1 md:col-span-3">
+				<FormField
+					control={form.control}
+					name="error_response_template"
+					render={({ field }) => (
+						<FormItem>
+							<div className="flex items-center justify-between">
+								<LabelWithHelp
+									label={tr("error_response_template.label", "Error Response Template")}
+									help={tr(
+										"error_response_template.help",
+										"Messages returned to clients instead of the upstream error, keyed by HTTP status code. The upstream error is kept in the logs.",
+									)}
+								/>
+								<Button
+									type="button"
+									variant="ghost"
+									size="sm"
+									className="h-6 text-xs"
+									onClick={formatErrorResponseTemplate}
+								>
+									{tr("common.format_json", "Format JSON")}
+								</Button>
+							</div>
+							<FormControl>
+								<Textarea
+									placeholder={tr(
+										"error_response_template.placeholder",
+										'{"429": "Rate limit exceeded. Contact admin.", "503": "Service maintenance in progress."}',
+									)}
+									className={`font-mono text-xs min-h-[80px] ${errorClass("error_response_template")}`}
+									{...field}
+									value={field.value || ""}
+								/>
+							</FormControl>
+							<FormMessage />
+						</FormItem>
+					)}
+				/>
+			</div>
 		</div>
 	);
 };
@@ -643,6 +643,37 @@ export const ChannelSpecificConfig = ({
 				/>
 			);
 
+		case 40: // Doubao
+			return (
+				<FormField
+					control={form.control}
+					name="endpoint_id_mapping"
+					render={({ field }) => (
+						<FormItem>
+							<LabelWithHelp
+								label={tr("endpoint_id_mapping.label", "Endpoint ID Mapping")}
+								help={tr(
+									"endpoint_id_mapping.help",
+									"Ark endpoint ID serving each model. Keys ending in * match by prefix.",
+								)}
+							/>
+							<FormControl>
+								<Textarea
+									placeholder={tr(
+										"endpoint_id_mapping.placeholder",
+										'{"doubao-pro-32k": "ep-20250101-xxxx"}',
+									)}
+									className={`font-mono text-xs min-h-[80px] ${errorClass("endpoint_id_mapping")}`}
+									{...field}
+									value={field.value || ""}
+								/>
+							</FormControl>
+							<FormMessage />
+						</FormItem>
+					)}
+				/>
+			);
+
 		case 50: // OpenAI Compatible
 			return (
 				<div className="space-y-4 p-4 border rounded-lg bg-purple-50/50">
@@ -42,6 +42,8 @@ export const CHANNEL_TYPES: ChannelType[] = [
 		color: "blue",
 		description: "Formerly ByteDance Doubao",
 	},
+	{ key: 52, text: "Perplexity", value: 52, color: "teal" },
+	{ key: 53, text: "Fireworks AI", value: 53, color: "orange" },
 	{
 		key: 15,
 		text: "Baidu Wenxin Qianfan",
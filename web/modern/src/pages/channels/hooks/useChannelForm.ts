@@ -69,6 +69,7 @@ export const useChannelForm = () => {
 			priority: 0,
 			weight: 0,
 			ratelimit: 0,
+			max_concurrency: 0,
 			config: {
 				region: "",
 				ak: "",
@@ -80,6 +81,14 @@ export const useChannelForm = () => {
 				api_format: "chat_completion",
 			},
 			inference_profile_arn_map: "",
+			response_annotations: "",
+			model_streaming_timeouts: "",
+			endpoint_id_mapping: "",
+			error_response_template: "",
+			auto_translate_system_prompt: false,
+			translate_target_language: "",
+			translation_channel_id: 0,
+			store_reasoning_trace: false,
 		},
 	});
 
@@ -238,10 +247,25 @@ export const useChannelForm = () => {
 					priority: toInt(data.priority, 0),
 					weight: toInt(data.weight, 0),
 					ratelimit: toInt(data.ratelimit, 0),
+					max_concurrency: toInt(data.max_concurrency, 0),
 					config,
 					inference_profile_arn_map: formatJsonField(
 						data.inference_profile_arn_map,
 					),
+					response_annotations: formatJsonField(data.response_annotations),
+					model_streaming_timeouts: formatJsonField(
+						data.model_streaming_timeouts,
+					),
+					endpoint_id_mapping: formatJsonField(data.endpoint_id_mapping),
+					error_response_template: formatJsonField(
+						data.error_response_template,
+					),
+					auto_translate_system_prompt: Boolean(
+						data.auto_translate_system_prompt,
+					),
+					translate_target_language: data.translate_target_language || "",
+					translation_channel_id: toInt(data.translation_channel_id, 0),
+					store_reasoning_trace: Boolean(data.store_reasoning_trace),
 				};
 
 				console.debug('[EditChannel] Loaded channel payload', {
@@ -464,6 +488,75 @@ export const useChannelForm = () => {
 				return;
 			}
 
+			if (
+				data.response_annotations &&
+				!isValidJSON(data.response_annotations)
+			) {
+				form.setError("response_annotations", {
+					message: "Invalid JSON format in response annotations",
+				});
+				notify({
+					type: "error",
+					title: tr("validation.invalid_json_title", "Invalid JSON"),
+					message: tr(
+						"validation.response_annotations_invalid",
+						"Response Annotations has invalid JSON.",
+					),
+				});
+				return;
+			}
+
+			if (
+				data.model_streaming_timeouts &&
+				!isValidJSON(data.model_streaming_timeouts)
+			) {
+				form.setError("model_streaming_timeouts", {
+					message: "Invalid JSON format in model streaming timeouts",
+				});
+				notify({
+					type: "error",
+					title: tr("validation.invalid_json_title", "Invalid JSON"),
+					message: tr(
+						"validation.model_streaming_timeouts_invalid",
+						"Model Streaming Timeouts has invalid JSON.",
+					),
+				});
+				return;
+			}
+
+			if (data.endpoint_id_mapping && !isValidJSON(data.endpoint_id_mapping)) {
+				form.setError("endpoint_id_mapping", {
+					message: "Invalid JSON format in endpoint ID mapping",
+				});
+				notify({
+					type: "error",
+					title: tr("validation.invalid_json_title", "Invalid JSON"),
+					message: tr(
+						"validation.endpoint_id_mapping_invalid",
+						"Endpoint ID Mapping has invalid JSON.",
+					),
+				});
+				return;
+			}
+
+			if (
+				data.error_response_template &&
+				!isValidJSON(data.error_response_template)
+			) {
+				form.setError("error_response_template", {
+					message: "Invalid JSON format in error response template",
+				});
+				notify({
+					type: "error",
+					title: tr("validation.invalid_json_title", "Invalid JSON"),
+					message: tr(
+						"validation.error_response_template_invalid",
+						"Error Response Template has invalid JSON.",
+					),
+				});
+				return;
+			}
+
 			if (watchType === 34 && watchConfig.auth_type === "oauth_jwt") {
 				if (!isValidJSON(data.key)) {
 					form.setError("key", {
@@ -531,6 +624,11 @@ export const useChannelForm = () => {
 			payload.priority = toInt(payload.priority, 0);
 			payload.weight = toInt(payload.weight, 0);
 			payload.ratelimit = toInt(payload.ratelimit, 0);
+			payload.max_concurrency = toInt(payload.max_concurrency, 0);
+			payload.translation_channel_id = toInt(payload.translation_channel_id, 0);
+			payload.translate_target_language = (
+				payload.translate_target_language || ""
+			).trim();
 
 			payload.models = payload.models.join(",");
 			payload.group = payload.groups.join(",");
@@ -580,6 +678,10 @@ export const useChannelForm = () => {
 				"model_mapping",
 				"model_configs",
 				"inference_profile_arn_map",
+				"response_annotations",
+				"model_streaming_timeouts",
+				"endpoint_id_mapping",
+				"error_response_template",
 				"system_prompt",
 			];
 			jsonFields.forEach((field) => {
@@ -19,6 +19,7 @@ export const channelSchema = z.object({
 	priority: z.coerce.number().int().default(0),
 	weight: z.coerce.number().int().default(0),
 	ratelimit: z.coerce.number().int().min(0).default(0),
+	max_concurrency: z.coerce.number().int().min(0).default(0),
 	// AWS and Vertex AI specific config
 	config: z
 		.object({
@@ -35,6 +36,14 @@ export const channelSchema = z.object({
 		})
 		.default({}),
 	inference_profile_arn_map: z.string().optional(),
+	response_annotations: z.string().optional(),
+	model_streaming_timeouts: z.string().optional(),
+	endpoint_id_mapping: z.string().optional(),
+	error_response_template: z.string().optional(),
+	auto_translate_system_prompt: z.boolean().default(false),
+	translate_target_language: z.string().optional(),
+	translation_channel_id: z.coerce.number().int().min(0).default(0),
+	store_reasoning_trace: z.boolean().default(false),
 });
 
 export type ChannelForm = z.infer<typeof channelSchema>;
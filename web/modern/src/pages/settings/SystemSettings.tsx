@@ -33,6 +33,8 @@ const OPTION_GROUPS: OptionGroup[] = [
       'EmailVerificationEnabled',
       'EmailDomainRestrictionEnabled',
       'EmailDomainWhitelist',
+      'EmailDomainBlocklistEnabled',
+      'EmailDomainBlocklist',
     ],
   },
   {
@@ -43,6 +45,9 @@ const OPTION_GROUPS: OptionGroup[] = [
       'GitHubOAuthEnabled',
       'GitHubClientId',
       'GitHubClientSecret',
+      'GoogleOAuthEnabled',
+      'GoogleClientId',
+      'GoogleClientSecret',
       'OidcEnabled',
       'OidcClientId',
       'OidcClientSecret',
@@ -130,6 +135,7 @@ const OPTION_GROUPS: OptionGroup[] = [
       'AutomaticEnableChannelEnabled',
       'ChannelDisableThreshold',
       'RetryTimes',
+      'ChannelSSRFAllowedHosts',
     ],
   },
   {
@@ -140,6 +146,7 @@ const OPTION_GROUPS: OptionGroup[] = [
       'LogConsumeEnabled',
       'MessagePusherAddress',
       'MessagePusherToken',
+      'ExternalBillingWebhookURL',
     ],
   },
 ]
@@ -147,6 +154,7 @@ const OPTION_GROUPS: OptionGroup[] = [
 const SENSITIVE_OPTION_KEYS = new Set<string>([
   'SMTPToken',
   'GitHubClientSecret',
+  'GoogleClientSecret',
   'OidcClientSecret',
   'LarkClientSecret',
   'WeChatServerToken',
@@ -163,7 +171,9 @@ const BOOLEAN_OPTION_KEYS = new Set<string>([
   'RegisterEnabled',
   'EmailVerificationEnabled',
   'EmailDomainRestrictionEnabled',
+  'EmailDomainBlocklistEnabled',
   'GitHubOAuthEnabled',
+  'GoogleOAuthEnabled',
   'OidcEnabled',
   'WeChatAuthEnabled',
   'TurnstileCheckEnabled',
@@ -196,6 +206,8 @@ export function SystemSettings() {
         'EmailVerificationEnabled',
         'EmailDomainRestrictionEnabled',
         'EmailDomainWhitelist',
+        'EmailDomainBlocklistEnabled',
+        'EmailDomainBlocklist',
       ],
     },
     {
@@ -206,6 +218,9 @@ export function SystemSettings() {
         'GitHubOAuthEnabled',
         'GitHubClientId',
         'GitHubClientSecret',
+        'GoogleOAuthEnabled',
+        'GoogleClientId',
+        'GoogleClientSecret',
         'OidcEnabled',
         'OidcClientId',
         'OidcClientSecret',
@@ -293,6 +308,7 @@ export function SystemSettings() {
         'AutomaticEnableChannelEnabled',
         'ChannelDisableThreshold',
         'RetryTimes',
+        'ChannelSSRFAllowedHosts',
       ],
     },
     {
@@ -303,6 +319,7 @@ export function SystemSettings() {
         'LogConsumeEnabled',
         'MessagePusherAddress',
         'MessagePusherToken',
+        'ExternalBillingWebhookURL',
       ],
     },
   ], [t])
@@ -317,11 +334,16 @@ export function SystemSettings() {
       EmailVerificationEnabled: t('system_settings.descriptions.EmailVerificationEnabled'),
       EmailDomainRestrictionEnabled: t('system_settings.descriptions.EmailDomainRestrictionEnabled'),
       EmailDomainWhitelist: t('system_settings.descriptions.EmailDomainWhitelist'),
+      EmailDomainBlocklistEnabled: t('system_settings.descriptions.EmailDomainBlocklistEnabled'),
+      EmailDomainBlocklist: t('system_settings.descriptions.EmailDomainBlocklist'),
 
       // OAuth / SSO Providers
       GitHubOAuthEnabled: t('system_settings.descriptions.GitHubOAuthEnabled'),
       GitHubClientId: t('system_settings.descriptions.GitHubClientId'),
       GitHubClientSecret: t('system_settings.descriptions.GitHubClientSecret'),
+      GoogleOAuthEnabled: t('system_settings.descriptions.GoogleOAuthEnabled'),
+      GoogleClientId: t('system_settings.descriptions.GoogleClientId'),
+      GoogleClientSecret: t('system_settings.descriptions.GoogleClientSecret'),
       OidcEnabled: t('system_settings.descriptions.OidcEnabled'),
       OidcClientId: t('system_settings.descriptions.OidcClientId'),
       OidcClientSecret: t('system_settings.descriptions.OidcClientSecret'),
@@ -379,11 +401,13 @@ export function SystemSettings() {
       AutomaticEnableChannelEnabled: t('system_settings.descriptions.AutomaticEnableChannelEnabled'),
       ChannelDisableThreshold: t('system_settings.descriptions.ChannelDisableThreshold'),
       RetryTimes: t('system_settings.descriptions.RetryTimes'),
+      ChannelSSRFAllowedHosts: t('system_settings.descriptions.ChannelSSRFAllowedHosts'),
 
       // Logging / Metrics / Integrations
       LogConsumeEnabled: t('system_settings.descriptions.LogConsumeEnabled'),
       MessagePusherAddress: t('system_settings.descriptions.MessagePusherAddress'),
       MessagePusherToken: t('system_settings.descriptions.MessagePusherToken'),
+      ExternalBillingWebhookURL: t('system_settings.descriptions.ExternalBillingWebhookURL'),
     }),
     [t]
   )